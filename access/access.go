@@ -0,0 +1,235 @@
+// Package access grants users and groups roles in the clusters of a cluster manager, and
+// revokes them, through the Rancher API of the cluster manager.
+package access
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Returns a Rancher API client for the selected cluster manager, the name of the selected
+// cluster and its Rancher cluster id.
+func getRancherCluster(conf config.Config, remoteBackend backend.Backend) (*rancher.Client, string, string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if len(clusterManagers) == 0 {
+		return nil, "", "", fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return nil, "", "", util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return nil, "", "", err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, "", "", fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if len(clusters) == 0 {
+		return nil, "", "", fmt.Errorf("No clusters.")
+	}
+
+	clusterName := ""
+	if conf.IsSet("cluster_name") {
+		clusterName = conf.GetString("cluster_name")
+	} else if nonInteractiveMode {
+		return nil, "", "", util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return nil, "", "", err
+		}
+		clusterName = value
+	}
+
+	clusterKey, ok := clusters[clusterName]
+	if !ok {
+		return nil, "", "", fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return client, clusterName, rancherClusterID, nil
+}
+
+// Returns the binding of the role given by access_role to the user given by access_user or
+// the group given by access_group, in the cluster or, for a project role, in the project
+// given by access_project. The user, group and role are prompted for when not set.
+func getRoleTemplateBinding(conf config.Config, client *rancher.Client, clusterID string) (rancher.RoleTemplateBinding, string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	if conf.IsSet("access_user") && conf.IsSet("access_group") {
+		return rancher.RoleTemplateBinding{}, "", errors.New("Only one of access_user and access_group can be specified")
+	}
+
+	subjectType := "User"
+	if conf.IsSet("access_group") {
+		subjectType = "Group"
+	} else if !conf.IsSet("access_user") {
+		if nonInteractiveMode {
+			return rancher.RoleTemplateBinding{}, "", util.ConfigError(errors.New("access_user or access_group must be specified"))
+		}
+
+		prompt := promptui.Select{
+			Label: "Grant the role to a",
+			Items: []string{"User", "Group"},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return rancher.RoleTemplateBinding{}, "", err
+		}
+		subjectType = value
+	}
+
+	key := "access_user"
+	if subjectType == "Group" {
+		key = "access_group"
+	}
+	subject, err := promptForValue(conf, key, subjectType)
+	if err != nil {
+		return rancher.RoleTemplateBinding{}, "", err
+	}
+
+	role, err := promptForValue(conf, "access_role", "Role (e.g. cluster-member or project-member)")
+	if err != nil {
+		return rancher.RoleTemplateBinding{}, "", err
+	}
+
+	roleTemplate, err := client.RoleTemplate(role)
+	if err != nil {
+		return rancher.RoleTemplateBinding{}, "", err
+	}
+
+	binding := rancher.RoleTemplateBinding{RoleTemplateID: roleTemplate.ID}
+	description := fmt.Sprintf("role '%s' to %s '%s'", roleTemplate.ID, strings.ToLower(subjectType), subject)
+
+	if subjectType == "Group" {
+		binding.GroupPrincipalID, err = client.GroupPrincipalID(subject)
+	} else if strings.Contains(subject, "://") {
+		// A principal id of the authentication provider, e.g. github_user://1234
+		binding.UserPrincipalID = subject
+	} else {
+		var user rancher.User
+		user, err = client.User(subject)
+		binding.UserID = user.ID
+	}
+	if err != nil {
+		return rancher.RoleTemplateBinding{}, "", err
+	}
+
+	switch roleTemplate.Context {
+	case "cluster":
+		if conf.IsSet("access_project") {
+			return rancher.RoleTemplateBinding{}, "", fmt.Errorf("Role '%s' is a cluster role, access_project must not be specified", roleTemplate.ID)
+		}
+		binding.ClusterID = clusterID
+	case "project":
+		project, err := promptForValue(conf, "access_project", "Project")
+		if err != nil {
+			return rancher.RoleTemplateBinding{}, "", err
+		}
+
+		binding.ProjectID, err = client.ProjectID(clusterID, project)
+		if err != nil {
+			return rancher.RoleTemplateBinding{}, "", err
+		}
+		description = fmt.Sprintf("%s in project '%s'", description, project)
+	default:
+		return rancher.RoleTemplateBinding{}, "", fmt.Errorf("Role '%s' is neither a cluster nor a project role", roleTemplate.ID)
+	}
+
+	return binding, description, nil
+}
+
+// Returns the value of the given key, or prompts for it.
+func promptForValue(conf config.Config, key, label string) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	prompt := promptui.Prompt{
+		Label: label,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+
+	return prompt.Run()
+}
@@ -0,0 +1,158 @@
+package access
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+)
+
+// A cluster manager with user bob, a Default project in cluster c-abcde, and the
+// cluster-member and project-member roles.
+func newTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/users":
+			w.Write([]byte(`{"data": [{"id": "u-bob", "username": "bob"}]}`))
+		case "/v3/roleTemplates":
+			w.Write([]byte(`{"data": [
+				{"id": "cluster-member", "name": "Cluster Member", "context": "cluster"},
+				{"id": "project-member", "name": "Project Member", "context": "project"}
+			]}`))
+		case "/v3/projects":
+			if r.URL.Query().Get("name") == "Default" {
+				w.Write([]byte(`{"data": [{"id": "c-abcde:p-12345", "name": "Default"}]}`))
+				return
+			}
+			w.Write([]byte(`{"data": []}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+}
+
+func TestGetRoleTemplateBindingCluster(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("access_user", "bob")
+	conf.Set("access_role", "Cluster Member")
+
+	binding, description, err := getRoleTemplateBinding(conf, rancher.NewClient(server.URL, "access", "secret"), "c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := rancher.RoleTemplateBinding{ClusterID: "c-abcde", RoleTemplateID: "cluster-member", UserID: "u-bob"}
+	if binding.ClusterID != expected.ClusterID || binding.ProjectID != "" || binding.RoleTemplateID != expected.RoleTemplateID || binding.UserID != expected.UserID {
+		t.Errorf("Wrong output, expected %+v, received %+v", expected, binding)
+	}
+	if description != "role 'cluster-member' to user 'bob'" {
+		t.Errorf("Wrong description, received %q", description)
+	}
+
+	// A cluster role isn't granted in a project
+	conf.Set("access_project", "Default")
+	if _, _, err := getRoleTemplateBinding(conf, rancher.NewClient(server.URL, "access", "secret"), "c-abcde"); err == nil {
+		t.Error("Expected an error for a cluster role with a project")
+	}
+}
+
+func TestGetRoleTemplateBindingUserPrincipal(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("access_user", "github_user://1234")
+	conf.Set("access_role", "cluster-member")
+
+	binding, _, err := getRoleTemplateBinding(conf, rancher.NewClient(server.URL, "access", "secret"), "c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if binding.UserPrincipalID != "github_user://1234" || binding.UserID != "" || binding.ClusterID != "c-abcde" {
+		t.Errorf("Wrong output, expected a binding of principal github_user://1234, received %+v", binding)
+	}
+}
+
+func TestGetRoleTemplateBindingProject(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("access_user", "bob")
+	conf.Set("access_role", "project-member")
+
+	client := rancher.NewClient(server.URL, "access", "secret")
+	if _, _, err := getRoleTemplateBinding(conf, client, "c-abcde"); err == nil {
+		t.Error("Expected an error for a project role without a project")
+	}
+
+	conf.Set("access_project", "Default")
+	binding, _, err := getRoleTemplateBinding(conf, client, "c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binding.ProjectID != "c-abcde:p-12345" || binding.ClusterID != "" {
+		t.Errorf("Expected a binding in project c-abcde:p-12345, received %+v", binding)
+	}
+
+	conf.Set("access_project", "Staging")
+	if _, _, err := getRoleTemplateBinding(conf, client, "c-abcde"); err == nil {
+		t.Error("Expected an error for a project that doesn't exist")
+	}
+}
+
+func TestGetRoleTemplateBindingUserAndGroup(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("access_user", "bob")
+	conf.Set("access_group", "devs")
+
+	if _, _, err := getRoleTemplateBinding(conf, nil, "c-abcde"); err == nil {
+		t.Error("Expected an error for both a user and a group")
+	}
+}
+
+func TestGrantAlreadyGranted(t *testing.T) {
+	created := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			created = true
+		}
+		w.Write([]byte(`{"data": [{"id": "c-abcde:crtb-1", "clusterId": "c-abcde", "roleTemplateId": "cluster-member", "userId": "u-bob"}]}`))
+	}))
+	defer server.Close()
+
+	binding := rancher.RoleTemplateBinding{ClusterID: "c-abcde", RoleTemplateID: "cluster-member", UserID: "u-bob"}
+	err := grant(rancher.NewClient(server.URL, "access", "secret"), binding, "dev", "role 'cluster-member' to user 'bob'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Error("Expected an existing binding not to be created again")
+	}
+}
+
+func TestRevokeNotGranted(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	binding := rancher.RoleTemplateBinding{ClusterID: "c-abcde", RoleTemplateID: "cluster-member", UserID: "u-bob"}
+	err := revoke(rancher.NewClient(server.URL, "access", "secret"), binding, "dev", "role 'cluster-member' to user 'bob'")
+	if err == nil {
+		t.Error("Expected an error for a role that isn't granted")
+	}
+}
@@ -0,0 +1,79 @@
+package access
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+)
+
+// Grant grants a user or group a cluster or project role in the selected cluster. Granting a
+// role that's already granted does nothing.
+func Grant(conf config.Config, remoteBackend backend.Backend) error {
+	client, clusterName, clusterID, err := getRancherCluster(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	binding, description, err := getRoleTemplateBinding(conf, client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return grant(client, binding, clusterName, description)
+}
+
+func grant(client *rancher.Client, binding rancher.RoleTemplateBinding, clusterName, description string) error {
+	existing, err := client.RoleTemplateBindings(binding)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		fmt.Printf("Cluster '%s' already grants %s.\n", clusterName, description)
+		return nil
+	}
+
+	_, err = client.CreateRoleTemplateBinding(binding)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Granted %s in cluster '%s'.\n", description, clusterName)
+	return nil
+}
+
+// Revoke revokes a cluster or project role of a user or group in the selected cluster.
+func Revoke(conf config.Config, remoteBackend backend.Backend) error {
+	client, clusterName, clusterID, err := getRancherCluster(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	binding, description, err := getRoleTemplateBinding(conf, client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return revoke(client, binding, clusterName, description)
+}
+
+func revoke(client *rancher.Client, binding rancher.RoleTemplateBinding, clusterName, description string) error {
+	existing, err := client.RoleTemplateBindings(binding)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return fmt.Errorf("Cluster '%s' doesn't grant %s.", clusterName, description)
+	}
+
+	for _, b := range existing {
+		err = client.DeleteRoleTemplateBinding(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Revoked %s in cluster '%s'.\n", description, clusterName)
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package agent runs the operations scheduled in the `schedule` section of the config, e.g.
+//
+//	schedule:
+//	  - name: nightly-etcd-snapshot
+//	    cron: "0 2 * * *"
+//	    operation: etcd-snapshot
+//	    cluster_manager: dev-manager
+//	    cluster_name: dev-cluster
+//
+// Every other key of a job is a setting of its operation, using the keys of the silent
+// install yaml. Jobs run in non-interactive mode, one at a time.
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/journal"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Operation runs a job with the settings of the config.
+type Operation func(conf config.Config, remoteBackend backend.Backend) error
+
+// Operations that can be scheduled
+var operations = map[string]Operation{
+	"etcd-snapshot": snapshotEtcd,
+	"upgrade-nodes": func(conf config.Config, remoteBackend backend.Backend) error {
+		return create.UpgradeNodes(conf, remoteBackend, "")
+	},
+	"scale": func(conf config.Config, remoteBackend backend.Backend) error {
+		return create.ScaleNodePool(conf, remoteBackend, "")
+	},
+	"reconcile":    create.ReconcileNodePools,
+	"retry":        create.RetryFailedNodes,
+	"rotate-token": create.RotateRancherAPIToken,
+}
+
+// Keys of a job that aren't settings of its operation
+var jobKeys = map[string]bool{"name": true, "cron": true, "operation": true}
+
+// Job is an operation run on a schedule.
+type Job struct {
+	Name      string
+	Operation string
+	Schedule  *Schedule
+	Settings  map[string]interface{}
+}
+
+// LoadJobs returns the jobs of the `schedule` section of the config.
+func LoadJobs(conf config.Config) ([]Job, error) {
+	if !conf.IsSet("schedule") {
+		return nil, util.ConfigError(errors.New("schedule must be specified"))
+	}
+
+	entries, ok := conf.Get("schedule").([]interface{})
+	if !ok {
+		return nil, errors.New("Could not read 'schedule' configuration, it must be a list of jobs")
+	}
+
+	jobs := []Job{}
+	names := map[string]bool{}
+	for i, entry := range entries {
+		fields, ok := entry.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Could not read job %d of 'schedule' configuration", i+1)
+		}
+
+		job := Job{Settings: map[string]interface{}{}}
+		for key, value := range fields {
+			name := strings.ToLower(fmt.Sprint(key))
+			if !jobKeys[name] {
+				job.Settings[name] = value
+			}
+		}
+
+		job.Name, _ = fields["name"].(string)
+		if job.Name == "" {
+			return nil, fmt.Errorf("Job %d of 'schedule' must have a name", i+1)
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("Job name '%s' is used more than once", job.Name)
+		}
+		names[job.Name] = true
+
+		job.Operation, _ = fields["operation"].(string)
+		if _, ok := operations[job.Operation]; !ok {
+			return nil, util.ConfigError(fmt.Errorf("Invalid operation '%s' for job '%s', must be one of the following: %s", job.Operation, job.Name, strings.Join(operationNames(), ", ")))
+		}
+
+		cron, _ := fields["cron"].(string)
+		schedule, err := ParseSchedule(cron)
+		if err != nil {
+			return nil, fmt.Errorf("Job '%s': %v", job.Name, err)
+		}
+		job.Schedule = schedule
+
+		jobs = append(jobs, job)
+	}
+
+	if len(jobs) == 0 {
+		return nil, errors.New("schedule has no jobs")
+	}
+
+	return jobs, nil
+}
+
+// Run runs the jobs of the config on their schedule until stop is closed. A failed job is
+// logged and runs again at its next scheduled time.
+func Run(conf config.Config, remoteBackend backend.Backend, stop <-chan struct{}) error {
+	jobs, err := LoadJobs(conf)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	nextRuns := make([]time.Time, len(jobs))
+	for i, job := range jobs {
+		nextRuns[i] = job.Schedule.Next(now)
+		if nextRuns[i].IsZero() {
+			return fmt.Errorf("Job '%s' is never scheduled", job.Name)
+		}
+		log.Printf("Job '%s' (%s) scheduled at %s", job.Name, job.Operation, nextRuns[i].Format(time.RFC1123))
+	}
+
+	for {
+		// Wait for the earliest job
+		next := 0
+		for i := range jobs {
+			if nextRuns[i].Before(nextRuns[next]) {
+				next = i
+			}
+		}
+
+		timer := time.NewTimer(time.Until(nextRuns[next]))
+		select {
+		case <-stop:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		// Run every job due, the previous ones may have taken past the time of the others
+		for i, job := range jobs {
+			if time.Now().Before(nextRuns[i]) {
+				continue
+			}
+
+			log.Printf("Running job '%s' (%s)", job.Name, job.Operation)
+			err := RunJob(conf, remoteBackend, job)
+			if err != nil {
+				log.Printf("Job '%s' failed: %v", job.Name, err)
+			} else {
+				log.Printf("Job '%s' succeeded", job.Name)
+			}
+
+			nextRuns[i] = job.Schedule.Next(time.Now())
+			log.Printf("Job '%s' scheduled at %s", job.Name, nextRuns[i].Format(time.RFC1123))
+		}
+	}
+}
+
+// RunJob runs the operation of the job once, with the settings of the config overridden by the
+// settings of the job.
+func RunJob(conf config.Config, remoteBackend backend.Backend, job Job) error {
+	jobConf := config.New()
+
+	// Copy the top level settings, nested keys are copied along with their parent
+	for _, key := range conf.AllKeys() {
+		key = strings.SplitN(key, ".", 2)[0]
+		if key != "schedule" && !jobConf.IsSet(key) {
+			jobConf.Set(key, conf.Get(key))
+		}
+	}
+	for key, value := range job.Settings {
+		jobConf.Set(key, value)
+	}
+	jobConf.Set("non-interactive", true)
+
+	operation := operations[job.Operation]
+	// Jobs wait for their next run rather than forcing the lock of an operation in progress
+	command := "agent job " + job.Name
+	lockingBackend := backend.NewLockingBackend(remoteBackend, command, false)
+	err := journal.Run(jobConf, backend.NewTerraformConfigBackend(lockingBackend), command, func(b backend.Backend) error {
+		return operation(jobConf, b)
+	})
+	unlockErr := lockingBackend.Unlock()
+	if unlockErr != nil {
+		log.Printf("Job '%s': %v", job.Name, unlockErr)
+	}
+	return err
+}
+
+func operationNames() []string {
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestLoadJobs(t *testing.T) {
+	conf := config.New()
+	conf.Set("schedule", []interface{}{
+		map[interface{}]interface{}{
+			"name":            "nightly-etcd-snapshot",
+			"cron":            "0 2 * * *",
+			"operation":       "etcd-snapshot",
+			"cluster_manager": "dev-manager",
+			"cluster_name":    "dev-cluster",
+		},
+	})
+
+	jobs, err := LoadJobs(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 1 {
+		t.Fatalf("Wrong output, expected 1 job, received %d", len(jobs))
+	}
+	job := jobs[0]
+	if job.Name != "nightly-etcd-snapshot" || job.Operation != "etcd-snapshot" {
+		t.Errorf("Wrong output, received %+v", job)
+	}
+	if len(job.Settings) != 2 || job.Settings["cluster_name"] != "dev-cluster" {
+		t.Errorf("Wrong output, expected the cluster settings, received %v", job.Settings)
+	}
+}
+
+func TestLoadJobsInvalid(t *testing.T) {
+	tests := []struct {
+		testName string
+		schedule interface{}
+		expected string
+	}{
+		{"No jobs", []interface{}{}, "schedule has no jobs"},
+		{"Not a list", "0 2 * * *", "Could not read 'schedule' configuration, it must be a list of jobs"},
+		{
+			"Missing name",
+			[]interface{}{map[interface{}]interface{}{"cron": "@daily", "operation": "retry"}},
+			"Job 1 of 'schedule' must have a name",
+		},
+		{
+			"Duplicate name",
+			[]interface{}{
+				map[interface{}]interface{}{"name": "retry", "cron": "@daily", "operation": "retry"},
+				map[interface{}]interface{}{"name": "retry", "cron": "@hourly", "operation": "retry"},
+			},
+			"Job name 'retry' is used more than once",
+		},
+		{
+			"Invalid operation",
+			[]interface{}{map[interface{}]interface{}{"name": "backup", "cron": "@daily", "operation": "backup"}},
+			"Invalid operation 'backup' for job 'backup', must be one of the following: etcd-snapshot, reconcile, retry, rotate-token, scale, upgrade-nodes",
+		},
+		{
+			"Invalid cron",
+			[]interface{}{map[interface{}]interface{}{"name": "retry", "cron": "@daily 2", "operation": "retry"}},
+			"Job 'retry': Invalid cron expression '@daily 2', must have 5 fields: minute hour day-of-month month day-of-week",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			conf := config.New()
+			conf.Set("schedule", test.schedule)
+
+			_, err := LoadJobs(conf)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("Wrong output, expected %s, received %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadJobsMissing(t *testing.T) {
+	expected := "schedule must be specified"
+
+	_, err := LoadJobs(config.New())
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Schedule is a cron expression: minute, hour, day of month, month and day of week. Fields
+// are `*`, numbers, ranges and lists, with an optional `/step`, e.g. `*/15 2-4 * * 1,3,5`.
+// @hourly, @daily, @midnight, @weekly and @monthly are supported.
+type Schedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek map[int]bool
+
+	// Like cron, a job runs when either day field matches if both are restricted
+	daysOfMonthRestricted, daysOfWeekRestricted bool
+}
+
+var scheduleAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Schedules further away than this are considered to never match, e.g. February 30th
+const maxScheduleSearch = 5 * 366 * 24 * time.Hour
+
+// ParseSchedule parses a cron expression.
+func ParseSchedule(expression string) (*Schedule, error) {
+	if alias, ok := scheduleAliases[strings.TrimSpace(expression)]; ok {
+		expression = alias
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, util.ConfigError(fmt.Errorf("Invalid cron expression '%s', must have 5 fields: minute hour day-of-month month day-of-week", expression))
+	}
+
+	schedule := &Schedule{}
+	var err error
+	if schedule.minutes, err = parseScheduleField(fields[0], 0, 59); err != nil {
+		return nil, util.ConfigError(fmt.Errorf("Invalid minute in cron expression '%s': %v", expression, err))
+	}
+	if schedule.hours, err = parseScheduleField(fields[1], 0, 23); err != nil {
+		return nil, util.ConfigError(fmt.Errorf("Invalid hour in cron expression '%s': %v", expression, err))
+	}
+	if schedule.daysOfMonth, err = parseScheduleField(fields[2], 1, 31); err != nil {
+		return nil, util.ConfigError(fmt.Errorf("Invalid day of month in cron expression '%s': %v", expression, err))
+	}
+	if schedule.months, err = parseScheduleField(fields[3], 1, 12); err != nil {
+		return nil, util.ConfigError(fmt.Errorf("Invalid month in cron expression '%s': %v", expression, err))
+	}
+	// Sunday is both 0 and 7
+	if schedule.daysOfWeek, err = parseScheduleField(fields[4], 0, 7); err != nil {
+		return nil, util.ConfigError(fmt.Errorf("Invalid day of week in cron expression '%s': %v", expression, err))
+	}
+	if schedule.daysOfWeek[7] {
+		schedule.daysOfWeek[0] = true
+	}
+	schedule.daysOfMonthRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.daysOfWeekRestricted = !strings.HasPrefix(fields[4], "*")
+
+	return schedule, nil
+}
+
+// Returns the values matched by a field, a list of `*`, numbers or ranges with optional steps.
+func parseScheduleField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step '%s'", part[i+1:])
+			}
+			part = part[:i]
+		}
+
+		first, last := min, max
+		if part != "*" {
+			bounds := strings.Split(part, "-")
+			if len(bounds) > 2 {
+				return nil, fmt.Errorf("invalid range '%s'", part)
+			}
+
+			var err error
+			first, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s'", bounds[0])
+			}
+			last = first
+			if len(bounds) == 2 {
+				last, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid value '%s'", bounds[1])
+				}
+			} else if step != 1 {
+				// e.g. 5/15 is every 15 from 5
+				last = max
+			}
+		}
+
+		if first < min || last > max || first > last {
+			return nil, fmt.Errorf("'%s' is out of range %d-%d", part, min, max)
+		}
+
+		for value := first; value <= last; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// Next returns the first time after t matching the schedule, or the zero time if none does.
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	deadline := t.Add(maxScheduleSearch)
+
+	for next.Before(deadline) {
+		if !s.months[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.daysOfMonth[t.Day()]
+	dayOfWeek := s.daysOfWeek[int(t.Weekday())]
+
+	if s.daysOfMonthRestricted && s.daysOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Saturday
+	from := time.Date(2018, time.March, 3, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2018, time.March, 3, 10, 31, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2018, time.March, 3, 11, 0, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2018, time.March, 4, 2, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2018, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 3 * * 1", time.Date(2018, time.March, 5, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * 7", time.Date(2018, time.March, 4, 3, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2018, time.March, 3, 10, 40, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2018, time.March, 3, 13, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2018, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted
+		{"0 0 15 * 1", time.Date(2018, time.March, 5, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+
+		next := schedule.Next(from)
+		if !next.Equal(test.expected) {
+			t.Errorf("%s: Wrong output, expected %s, received %s", test.expr, test.expected, next)
+		}
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@yearly",
+	}
+
+	for _, expr := range tests {
+		_, err := ParseSchedule(expr)
+		if err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Takes a snapshot of the etcd of cluster_name, through the Rancher API of cluster_manager.
+func snapshotEtcd(conf config.Config, remoteBackend backend.Backend) error {
+	if !conf.IsSet("cluster_manager") {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	}
+	if !conf.IsSet("cluster_name") {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	}
+	clusterManager := conf.GetString("cluster_manager")
+	clusterName := conf.GetString("cluster_name")
+
+	currentState, err := remoteBackend.State(clusterManager)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+	clusterKey, ok := clusters[clusterName]
+	if !ok {
+		return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := client.Cluster(rancherClusterID)
+	if err != nil {
+		return err
+	}
+
+	return client.BackupEtcd(cluster)
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/viper"
+)
+
+const defaultCatalog = "library"
+
+// Returns a Rancher API client for the selected cluster manager and the id of the Default
+// project of the selected cluster, which apps are managed in.
+func getRancherClient(conf config.Config, remoteBackend backend.Backend) (*rancher.Client, string, error) {
+	nonInteractiveMode := viper.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(clusterManagers) == 0 {
+		return nil, "", fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if viper.IsSet("cluster_manager") {
+		selectedClusterManager = viper.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return nil, "", util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return nil, "", err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, "", fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	state, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Get existing clusters
+	clusters, err := state.Clusters()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(clusters) == 0 {
+		return nil, "", fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterKey := ""
+	if viper.IsSet("cluster_name") {
+		clusterName := viper.GetString("cluster_name")
+		clusterKey, ok := clusters[clusterName]
+		if !ok {
+			return nil, "", fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+
+		selectedClusterKey = clusterKey
+	} else if nonInteractiveMode {
+		return nil, "", util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return nil, "", err
+		}
+		selectedClusterKey = clusters[value]
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, state, selectedClusterKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	projectID, err := client.DefaultProjectID(rancherClusterID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, projectID, nil
+}
+
+// Returns the installed app given by name, the app_name config or a prompt.
+func getInstalledApp(client *rancher.Client, projectID, name string) (rancher.App, error) {
+	appName := name
+	if appName != "" {
+		// Name was given as an argument
+	} else if viper.IsSet("app_name") {
+		appName = viper.GetString("app_name")
+	} else if viper.GetBool("non-interactive") {
+		return rancher.App{}, util.ConfigError(errors.New("app_name must be specified"))
+	} else {
+		apps, err := client.Apps(projectID)
+		if err != nil {
+			return rancher.App{}, err
+		}
+
+		if len(apps) == 0 {
+			return rancher.App{}, fmt.Errorf("No apps.")
+		}
+
+		prompt := promptui.Select{
+			Label: "App",
+			Items: apps,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ .Name | underline }}", promptui.IconSelect),
+				Inactive: "  {{ .Name }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "App:" | bold}} {{ .Name }}`, promptui.IconGood),
+			},
+		}
+
+		i, _, err := prompt.Run()
+		if err != nil {
+			return rancher.App{}, err
+		}
+
+		return apps[i], nil
+	}
+
+	return client.App(projectID, appName)
+}
+
+// Returns the catalog template version given by app_version or a prompt. Defaults to the
+// template's default version in non-interactive mode.
+func getTemplateVersion(client *rancher.Client, catalog, templateName string) (string, error) {
+	if viper.IsSet("app_version") {
+		return viper.GetString("app_version"), nil
+	}
+
+	template, err := client.Template(catalog, templateName)
+	if err != nil {
+		return "", err
+	}
+
+	if viper.GetBool("non-interactive") {
+		return template.DefaultVersion, nil
+	}
+
+	versions := make([]string, 0, len(template.VersionLinks))
+	for version := range template.VersionLinks {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+
+	prompt := promptui.Select{
+		Label: "Version",
+		Items: versions,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}?",
+			Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+			Inactive: "  {{ . }}",
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Version:" | bold}} {{ . }}`, promptui.IconGood),
+		},
+	}
+
+	_, value, err := prompt.Run()
+	return value, err
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/viper"
+)
+
+// InstallApp installs a catalog app into a cluster.
+func InstallApp(conf config.Config, remoteBackend backend.Backend, name string) error {
+	nonInteractiveMode := viper.GetBool("non-interactive")
+
+	client, projectID, err := getRancherClient(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	catalog := defaultCatalog
+	if viper.IsSet("app_catalog") {
+		catalog = viper.GetString("app_catalog")
+	}
+
+	// App Template
+	templateName := ""
+	if viper.IsSet("app_template") {
+		templateName = viper.GetString("app_template")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("app_template must be specified"))
+	} else {
+		templates, err := client.Templates(catalog)
+		if err != nil {
+			return err
+		}
+
+		prompt := promptui.Select{
+			Label: "App Template",
+			Items: templates,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ .Name | underline }}", promptui.IconSelect),
+				Inactive: "  {{ .Name }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "App Template:" | bold}} {{ .Name }}`, promptui.IconGood),
+			},
+		}
+
+		i, _, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		templateName = templates[i].Name
+	}
+
+	version, err := getTemplateVersion(client, catalog, templateName)
+	if err != nil {
+		return err
+	}
+
+	// App Name, defaults to the template name
+	appName := name
+	if appName != "" {
+		// Name was given as an argument
+	} else if viper.IsSet("app_name") {
+		appName = viper.GetString("app_name")
+	} else if nonInteractiveMode {
+		appName = templateName
+	} else {
+		prompt := promptui.Prompt{
+			Label:   "App Name",
+			Default: templateName,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		appName = result
+	}
+
+	// App Namespace, defaults to the app name
+	namespace := appName
+	if viper.IsSet("app_namespace") {
+		namespace = viper.GetString("app_namespace")
+	}
+
+	app, err := client.InstallApp(projectID, rancher.App{
+		Name:            appName,
+		TargetNamespace: namespace,
+		ExternalID:      rancher.CatalogExternalID(catalog, templateName, version),
+		Answers:         viper.GetStringMapString("app_answers"),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Installing %s %s as app '%s' in namespace '%s'.\n", templateName, version, app.Name, namespace)
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// ListApps prints the catalog apps installed in a cluster.
+func ListApps(conf config.Config, remoteBackend backend.Backend) error {
+	client, projectID, err := getRancherClient(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	apps, err := client.Apps(projectID)
+	if err != nil {
+		return err
+	}
+
+	if len(apps) == 0 {
+		fmt.Println("No apps.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tNAMESPACE\tSTATE\tTEMPLATE")
+	for _, app := range apps {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", app.Name, app.TargetNamespace, app.State, app.ExternalID)
+	}
+
+	return nil
+}
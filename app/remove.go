@@ -0,0 +1,46 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/viper"
+)
+
+// RemoveApp removes an installed catalog app, and the resources it created, from a cluster.
+func RemoveApp(conf config.Config, remoteBackend backend.Backend, name string) error {
+	client, projectID, err := getRancherClient(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	app, err := getInstalledApp(client, projectID, name)
+	if err != nil {
+		return err
+	}
+
+	if !viper.GetBool("non-interactive") {
+		label := fmt.Sprintf("Are you sure you want to remove app '%s'", app.Name)
+		selected := fmt.Sprintf("Remove app '%s'", app.Name)
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("App removal canceled.")
+			return nil
+		}
+	}
+
+	err = client.DeleteApp(app)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removing app '%s'.\n", app.Name)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+
+	"github.com/spf13/viper"
+)
+
+// UpgradeApp upgrades an installed catalog app to a new template version and/or answers.
+func UpgradeApp(conf config.Config, remoteBackend backend.Backend, name string) error {
+	client, projectID, err := getRancherClient(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	app, err := getInstalledApp(client, projectID, name)
+	if err != nil {
+		return err
+	}
+
+	catalog, templateName, err := parseCatalogExternalID(app.ExternalID)
+	if err != nil {
+		return err
+	}
+
+	version, err := getTemplateVersion(client, catalog, templateName)
+	if err != nil {
+		return err
+	}
+
+	// Keep the current answers unless new ones are given
+	answers := app.Answers
+	if viper.IsSet("app_answers") {
+		answers = viper.GetStringMapString("app_answers")
+	}
+
+	err = client.UpgradeApp(app, rancher.CatalogExternalID(catalog, templateName, version), answers)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Upgrading app '%s' to %s %s.\n", app.Name, templateName, version)
+
+	return nil
+}
+
+// Returns the catalog and template of a catalog://?catalog=...&template=...&version=... externalId.
+func parseCatalogExternalID(externalID string) (string, string, error) {
+	if !strings.HasPrefix(externalID, "catalog://?") {
+		return "", "", fmt.Errorf("App was not installed from a catalog: '%s'", externalID)
+	}
+
+	query, err := url.ParseQuery(strings.TrimPrefix(externalID, "catalog://?"))
+	if err != nil {
+		return "", "", err
+	}
+
+	catalog, template := query.Get("catalog"), query.Get("template")
+	if catalog == "" || template == "" {
+		return "", "", fmt.Errorf("Invalid catalog externalId '%s'", externalID)
+	}
+
+	return catalog, template, nil
+}
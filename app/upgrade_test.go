@@ -0,0 +1,19 @@
+package app
+
+import "testing"
+
+func TestParseCatalogExternalID(t *testing.T) {
+	catalog, template, err := parseCatalogExternalID("catalog://?catalog=library&template=wordpress&version=2.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if catalog != "library" || template != "wordpress" {
+		t.Errorf("Wrong output, expected library/wordpress, received %s/%s", catalog, template)
+	}
+
+	_, _, err = parseCatalogExternalID("https://example.com/chart.tgz")
+	if err == nil {
+		t.Error("Expected an error for an app that wasn't installed from a catalog")
+	}
+}
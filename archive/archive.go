@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version of the archive layout, imports refuse archives of a later version
+const formatVersion = 1
+
+// Files of an archive. Kubeconfigs are kubeconfigs/{cluster}.kubeconfig.
+const (
+	manifestFileName       = "manifest.json"
+	configFileName         = "main.tf.json"
+	terraformStateFileName = "terraform.tfstate"
+	kubeconfigDirName      = "kubeconfigs"
+	kubeconfigExtension    = ".kubeconfig"
+)
+
+// Describes the cluster manager an archive was exported from.
+type manifest struct {
+	FormatVersion int       `json:"format_version"`
+	Name          string    `json:"name"`
+	ExportedAt    time.Time `json:"exported_at"`
+	Clusters      []string  `json:"clusters"`
+	Kubeconfigs   []string  `json:"kubeconfigs"`
+}
+
+// A cluster manager as bundled in an archive: its generated terraform config, terraform's own
+// state, which is empty when nothing was applied, and the kubeconfigs of its clusters by name.
+type environment struct {
+	Manifest       manifest
+	Config         []byte
+	TerraformState []byte
+	Kubeconfigs    map[string]string
+}
+
+type archiveFile struct {
+	name    string
+	content []byte
+}
+
+// Writes env as a gzipped tar to w, its manifest first. Every file is only readable by its owner,
+// the archive holds the secrets of the cluster manager.
+func writeArchive(w io.Writer, env environment) error {
+	env.Manifest.Kubeconfigs = sortedKeys(env.Kubeconfigs)
+	rawManifest, err := json.MarshalIndent(env.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	files := []archiveFile{
+		{manifestFileName, rawManifest},
+		{configFileName, env.Config},
+	}
+	if len(env.TerraformState) > 0 {
+		files = append(files, archiveFile{terraformStateFileName, env.TerraformState})
+	}
+	for _, clusterName := range env.Manifest.Kubeconfigs {
+		fileName := path.Join(kubeconfigDirName, clusterName+kubeconfigExtension)
+		files = append(files, archiveFile{fileName, []byte(env.Kubeconfigs[clusterName])})
+	}
+
+	for _, file := range files {
+		err = tarWriter.WriteHeader(&tar.Header{
+			Name:    file.name,
+			Mode:    0600,
+			Size:    int64(len(file.content)),
+			ModTime: env.Manifest.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(file.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// Reads an archive written by writeArchive. Files it doesn't know of are refused rather than
+// ignored, so a tampered archive can't smuggle anything in.
+func readArchive(r io.Reader) (environment, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return environment{}, fmt.Errorf("Not a triton-kubernetes archive: %s", err)
+	}
+	defer gzipReader.Close()
+
+	env := environment{Kubeconfigs: map[string]string{}}
+	foundManifest := false
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return environment{}, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			return environment{}, fmt.Errorf("Unexpected entry '%s' in archive.", header.Name)
+		}
+
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return environment{}, err
+		}
+
+		switch {
+		case header.Name == manifestFileName:
+			err = json.Unmarshal(content, &env.Manifest)
+			if err != nil {
+				return environment{}, fmt.Errorf("Invalid %s: %s", manifestFileName, err)
+			}
+			foundManifest = true
+		case header.Name == configFileName:
+			env.Config = content
+		case header.Name == terraformStateFileName:
+			env.TerraformState = content
+		case isKubeconfigFileName(header.Name):
+			clusterName := strings.TrimSuffix(path.Base(header.Name), kubeconfigExtension)
+			env.Kubeconfigs[clusterName] = string(content)
+		default:
+			return environment{}, fmt.Errorf("Unexpected file '%s' in archive.", header.Name)
+		}
+	}
+
+	if !foundManifest {
+		return environment{}, fmt.Errorf("Archive has no %s, it wasn't exported by triton-kubernetes.", manifestFileName)
+	}
+	if env.Manifest.FormatVersion > formatVersion {
+		return environment{}, fmt.Errorf("Archive format version %d is newer than this version of triton-kubernetes supports (%d), upgrade triton-kubernetes.", env.Manifest.FormatVersion, formatVersion)
+	}
+	if env.Manifest.Name == "" {
+		return environment{}, errors.New("Archive manifest has no cluster manager name.")
+	}
+	if len(env.Config) == 0 {
+		return environment{}, fmt.Errorf("Archive has no %s.", configFileName)
+	}
+
+	return env, nil
+}
+
+// Kubeconfigs are files directly in kubeconfigs/, their names can't reach outside of it.
+func isKubeconfigFileName(name string) bool {
+	dir, file := path.Split(name)
+	return dir == kubeconfigDirName+"/" &&
+		strings.HasSuffix(file, kubeconfigExtension) &&
+		file != kubeconfigExtension &&
+		!strings.Contains(file, "..")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,235 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const testConfig = `{"module":{"cluster-manager":{"name":"dev-manager"},"cluster_triton_dev":{"name":"dev"}}}`
+
+// memoryBackend keeps states in memory.
+type memoryBackend struct {
+	states map[string][]byte
+}
+
+func (backend *memoryBackend) State(name string) (state.State, error) {
+	content, ok := backend.states[name]
+	if !ok {
+		return state.New(name, []byte("{}"))
+	}
+	return state.New(name, content)
+}
+
+func (backend *memoryBackend) DeleteState(name string) error {
+	delete(backend.states, name)
+	return nil
+}
+
+func (backend *memoryBackend) PersistState(currentState state.State) error {
+	backend.states[currentState.Name] = currentState.Bytes()
+	return nil
+}
+
+func (backend *memoryBackend) States() ([]string, error) {
+	names := []string{}
+	for name := range backend.states {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (backend *memoryBackend) StateTerraformConfig(name string) (string, interface{}) {
+	return "terraform.backend.local", map[string]interface{}{
+		"path": "/backend/" + name + "/terraform.tfstate",
+	}
+}
+
+func testEnvironment() environment {
+	return environment{
+		Manifest: manifest{
+			FormatVersion: formatVersion,
+			Name:          "dev-manager",
+			ExportedAt:    time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC),
+			Clusters:      []string{"dev", "prod"},
+		},
+		Config:         []byte(testConfig),
+		TerraformState: []byte(`{"version": 3, "serial": 7}`),
+		Kubeconfigs: map[string]string{
+			"dev": "apiVersion: v1\nkind: Config\n",
+		},
+	}
+}
+
+// Writes a gzipped tar of the given files, in order.
+func newTestArchive(t *testing.T, files map[string]string, order ...string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, name := range order {
+		err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(files[name]))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tarWriter.Write([]byte(files[name]))
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+	return buf
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := writeArchive(buf, testEnvironment())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := readArchive(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := testEnvironment()
+	expected.Manifest.Kubeconfigs = []string{"dev"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, env)
+	}
+}
+
+func TestArchiveWithoutTerraformState(t *testing.T) {
+	buf := &bytes.Buffer{}
+	testEnv := testEnvironment()
+	testEnv.TerraformState = nil
+	err := writeArchive(buf, testEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := readArchive(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.TerraformState != nil {
+		t.Errorf("Expected no terraform state, got %s", env.TerraformState)
+	}
+}
+
+func TestReadArchiveInvalid(t *testing.T) {
+	manifest := `{"format_version": 1, "name": "dev-manager"}`
+	tests := []struct {
+		name     string
+		files    map[string]string
+		order    []string
+		expected string
+	}{
+		{
+			name:     "no manifest",
+			files:    map[string]string{configFileName: testConfig},
+			order:    []string{configFileName},
+			expected: "Archive has no manifest.json",
+		},
+		{
+			name:     "no config",
+			files:    map[string]string{manifestFileName: manifest},
+			order:    []string{manifestFileName},
+			expected: "Archive has no main.tf.json.",
+		},
+		{
+			name: "newer format",
+			files: map[string]string{
+				manifestFileName: `{"format_version": 2, "name": "dev-manager"}`,
+				configFileName:   testConfig,
+			},
+			order:    []string{manifestFileName, configFileName},
+			expected: "Archive format version 2 is newer",
+		},
+		{
+			name: "kubeconfig outside of kubeconfigs",
+			files: map[string]string{
+				manifestFileName:                manifest,
+				"kubeconfigs/../dev.kubeconfig": "",
+			},
+			order:    []string{manifestFileName, "kubeconfigs/../dev.kubeconfig"},
+			expected: "Unexpected file 'kubeconfigs/../dev.kubeconfig' in archive.",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := readArchive(newTestArchive(t, test.files, test.order...))
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("%s: expected error %q, got %v", test.name, test.expected, err)
+		}
+	}
+
+	_, err := readArchive(strings.NewReader("not an archive"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Not a triton-kubernetes archive") {
+		t.Errorf("Expected not an archive error, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testEnv := testEnvironment()
+	// Pushing terraform's state runs terraform
+	testEnv.TerraformState = nil
+	archivePath := filepath.Join(dir, "dev-manager.tar.gz")
+	buf := &bytes.Buffer{}
+	err = writeArchive(buf, testEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(archivePath, buf.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("import_kubeconfig_dir", filepath.Join(dir, "kubeconfigs"))
+	remoteBackend := &memoryBackend{states: map[string][]byte{}}
+
+	err = Import(conf, remoteBackend, archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importedState, err := remoteBackend.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := importedState.Get("module.cluster_triton_dev.name"); name != "dev" {
+		t.Errorf("Expected the imported config, got cluster name %q", name)
+	}
+	if path := importedState.Get("terraform.backend.local.path"); path != "/backend/dev-manager/terraform.tfstate" {
+		t.Errorf("Expected the terraform backend of the new backend, got path %q", path)
+	}
+
+	kubeconfig, err := ioutil.ReadFile(filepath.Join(dir, "kubeconfigs", "dev.kubeconfig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(kubeconfig) != testEnv.Kubeconfigs["dev"] {
+		t.Errorf("Expected kubeconfig %q, got %q", testEnv.Kubeconfigs["dev"], kubeconfig)
+	}
+
+	err = Import(conf, remoteBackend, archivePath)
+	expected := "A cluster manager named 'dev-manager' already exists."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Export bundles a cluster manager into a gzipped tar at export_file, {cluster manager}.tar.gz
+// by default: its generated terraform config, terraform's own state and a kubeconfig of each of
+// its clusters. Clusters whose kubeconfig can't be generated, e.g. because Rancher is down, are
+// exported without one.
+func Export(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	exportFile := conf.GetString("export_file")
+	if exportFile == "" {
+		exportFile = fmt.Sprintf("%s.tar.gz", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	rawTerraformState, err := shell.RunTerraformStatePullWithState(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	env := environment{
+		Manifest: manifest{
+			FormatVersion: formatVersion,
+			Name:          selectedClusterManager,
+			ExportedAt:    time.Now().UTC(),
+			Clusters:      sortedKeys(clusters),
+		},
+		Config:         currentState.Bytes(),
+		TerraformState: rawTerraformState,
+		Kubeconfigs:    exportKubeconfigs(conf, currentState, clusters),
+	}
+
+	file, err := os.OpenFile(exportFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = writeArchive(file, env)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(exportFile)
+		return err
+	}
+
+	fmt.Printf("Exported cluster manager '%s' to %s.\n", selectedClusterManager, exportFile)
+	return nil
+}
+
+// Returns the kubeconfigs of the clusters Rancher can generate one for, by cluster name. The
+// clusters it can't are reported on stderr.
+func exportKubeconfigs(conf config.Config, currentState state.State, clusters map[string]string) map[string]string {
+	kubeconfigs := map[string]string{}
+	if len(clusters) == 0 {
+		return kubeconfigs
+	}
+
+	client, err := rancher.NewClientFromState(conf, currentState)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to export the kubeconfigs of the clusters: %s\n", err)
+		return kubeconfigs
+	}
+
+	for _, clusterName := range sortedKeys(clusters) {
+		kubeconfig, err := generateKubeconfig(conf, client, currentState, clusters[clusterName])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to export the kubeconfig of cluster '%s': %s\n", clusterName, err)
+			continue
+		}
+		kubeconfigs[clusterName] = kubeconfig
+	}
+
+	return kubeconfigs
+}
+
+func generateKubeconfig(conf config.Config, client *rancher.Client, currentState state.State, clusterKey string) (string, error) {
+	clusterID, err := rancher.ClusterIDFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return "", err
+	}
+
+	cluster, err := client.Cluster(clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	return client.GenerateKubeconfig(cluster)
+}
@@ -0,0 +1,99 @@
+package archive
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// Import restores a cluster manager exported by Export into remoteBackend, under the name it was
+// exported with: its terraform config, with the terraform backend of remoteBackend, and
+// terraform's own state. The kubeconfigs of its clusters are saved to import_kubeconfig_dir,
+// the current directory by default, as {cluster}.kubeconfig.
+func Import(conf config.Config, remoteBackend backend.Backend, archivePath string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	env, err := readArchive(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	name := env.Manifest.Name
+
+	// Reading the state takes its lock when remoteBackend locks, so that no one creates the
+	// cluster manager between the check below and the import
+	_, err = remoteBackend.State(name)
+	if err != nil {
+		return err
+	}
+
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+	for _, clusterManager := range clusterManagers {
+		if clusterManager == name {
+			return fmt.Errorf("A cluster manager named '%s' already exists.", name)
+		}
+	}
+
+	kubeconfigDir := "."
+	if conf.IsSet("import_kubeconfig_dir") {
+		kubeconfigDir = conf.GetString("import_kubeconfig_dir")
+	}
+
+	currentState, err := state.New(name, env.Config)
+	if err != nil {
+		return fmt.Errorf("Invalid %s in archive: %s", configFileName, err)
+	}
+
+	// Terraform keeps its state where the new backend does
+	err = currentState.SetTerraformBackendConfig(remoteBackend.StateTerraformConfig(name))
+	if err != nil {
+		return err
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	if len(env.TerraformState) > 0 {
+		err = shell.RunTerraformStatePushWithState(conf, currentState, env.TerraformState)
+		if err != nil {
+			// Without its terraform state, the cluster manager would be created anew
+			deleteErr := remoteBackend.DeleteState(name)
+			if deleteErr != nil {
+				fmt.Fprintf(os.Stderr, "Unable to remove the partially imported cluster manager '%s': %s\n", name, deleteErr)
+			}
+			return fmt.Errorf("Unable to restore the terraform state of cluster manager '%s': %s", name, err)
+		}
+	}
+
+	if len(env.Kubeconfigs) > 0 {
+		err = os.MkdirAll(kubeconfigDir, 0700)
+		if err != nil {
+			return err
+		}
+	}
+	for _, clusterName := range sortedKeys(env.Kubeconfigs) {
+		kubeconfigPath := filepath.Join(kubeconfigDir, clusterName+kubeconfigExtension)
+		err = ioutil.WriteFile(kubeconfigPath, []byte(env.Kubeconfigs[clusterName]), 0600)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved the kubeconfig of cluster %s to %s.\n", clusterName, kubeconfigPath)
+	}
+
+	fmt.Printf("Imported cluster manager '%s', exported %s, with %d clusters.\n", name, env.Manifest.ExportedAt.Format("2006-01-02 15:04:05 MST"), len(env.Manifest.Clusters))
+	return nil
+}
@@ -1,6 +1,9 @@
 package backend
 
 import (
+	"errors"
+	"time"
+
 	"github.com/joyent/triton-kubernetes/state"
 )
 
@@ -25,3 +28,82 @@ type Backend interface {
 	// represents a terraform backend configuration
 	StateTerraformConfig(name string) (string, interface{})
 }
+
+// TerraformEnv is implemented by backends whose terraform backend reads settings from the
+// environment of terraform instead of the backend block, e.g. credentials.
+type TerraformEnv interface {
+	// StateTerraformEnv returns the KEY=value environment variables of terraform for the named
+	// state.
+	StateTerraformEnv(name string) []string
+}
+
+// RunPreparer is implemented by backends that need the environment of terraform runs before
+// they start, e.g. because terraform runs remotely without it.
+type RunPreparer interface {
+	// PrepareRun is given env, the KEY=value environment of a terraform run of the named state.
+	PrepareRun(name string, env []string) error
+}
+
+// StateTerraformEnv returns the environment variables of terraform for the named state of
+// remoteBackend, or nil if it doesn't implement TerraformEnv.
+func StateTerraformEnv(remoteBackend Backend, name string) []string {
+	b, ok := remoteBackend.(TerraformEnv)
+	if !ok {
+		return nil
+	}
+
+	return b.StateTerraformEnv(name)
+}
+
+// PrepareRun passes the environment of a terraform run of the named state to remoteBackend if it
+// implements RunPreparer.
+func PrepareRun(remoteBackend Backend, name string, env []string) error {
+	b, ok := remoteBackend.(RunPreparer)
+	if !ok {
+		return nil
+	}
+
+	return b.PrepareRun(name, env)
+}
+
+// History is implemented by backends that keep the previous versions of states.
+type History interface {
+	// StateVersions returns the saved versions of the named state, newest first.
+	StateVersions(name string) ([]StateVersion, error)
+
+	// StateAtVersion returns the named state as it was saved in the given version.
+	StateAtVersion(name, version string) (state.State, error)
+}
+
+// ErrNoHistory is returned for the versions of a state when the backend doesn't keep them.
+var ErrNoHistory = errors.New("The backend doesn't keep the previous versions of cluster managers.")
+
+// StateVersions returns the saved versions of the named state, newest first, if remoteBackend
+// implements History.
+func StateVersions(remoteBackend Backend, name string) ([]StateVersion, error) {
+	b, ok := remoteBackend.(History)
+	if !ok {
+		return nil, ErrNoHistory
+	}
+
+	return b.StateVersions(name)
+}
+
+// StateAtVersion returns the named state as it was saved in the given version, if remoteBackend
+// implements History.
+func StateAtVersion(remoteBackend Backend, name, version string) (state.State, error) {
+	b, ok := remoteBackend.(History)
+	if !ok {
+		return state.State{}, ErrNoHistory
+	}
+
+	return b.StateAtVersion(name, version)
+}
+
+// StateVersion is a saved version of a state.
+type StateVersion struct {
+	// ID of the version in the backend, e.g. the generation of an object
+	ID string `json:"id"`
+	// When the version was saved
+	Saved time.Time `json:"saved"`
+}
@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Outside of ~/.triton-kubernetes, whose directories are the states of the local backend
+const rootDirectory = "~/.triton-kubernetes-cache"
+
+const statesKey = "states"
+
+// cacheBackend keeps a copy of everything read through the backend it wraps, and answers from
+// that copy when the backend is unreachable. It is only meant for commands that read: a stale
+// state must never be written back. The copies of the states leave out their secrets.
+type cacheBackend struct {
+	backend backend.Backend
+	cache   *Cache
+}
+
+// Cache is a directory of JSON snapshots, each stored with the time it was taken. A nil Cache,
+// returned when disable_cache is set, stores nothing.
+type Cache struct {
+	dir string
+}
+
+// Settings disable_cache is read from, e.g. a config.Config.
+type settings interface {
+	GetBool(key string) bool
+}
+
+type snapshot struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// New returns a backend wrapping remoteBackend with the cache in ~/.triton-kubernetes-cache, or
+// remoteBackend itself when disable_cache is set.
+func New(conf settings, remoteBackend backend.Backend) (backend.Backend, error) {
+	cache, err := NewCache(conf)
+	if err != nil || cache == nil {
+		return remoteBackend, err
+	}
+
+	return cacheBackend{backend: remoteBackend, cache: cache}, nil
+}
+
+// NewCache returns the cache in ~/.triton-kubernetes-cache, or nil when disable_cache is set.
+func NewCache(conf settings) (*Cache, error) {
+	if conf.GetBool("disable_cache") {
+		return nil, nil
+	}
+
+	dir, err := homedir.Expand(rootDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCacheWithDir(dir)
+}
+
+// NewCacheWithDir returns a cache stored in the given directory. Outputs and node states hold
+// addresses of the clusters, so the directory is only readable by the user, also when an
+// earlier version created it.
+func NewCacheWithDir(dir string) (*Cache, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{dir: dir}, nil
+}
+
+func (backend cacheBackend) State(name string) (state.State, error) {
+	currentState, err := backend.backend.State(name)
+	if err == nil {
+		backend.cache.saveState(currentState)
+		return currentState, nil
+	}
+
+	raw := json.RawMessage{}
+	fetchedAt, cacheErr := backend.cache.Load(stateKey(name), &raw)
+	if cacheErr != nil {
+		return state.State{}, err
+	}
+
+	PrintStaleWarning(fmt.Sprintf("state of cluster manager '%s'", name), fetchedAt, err)
+	return state.New(name, raw)
+}
+
+func (backend cacheBackend) DeleteState(name string) error {
+	err := backend.backend.DeleteState(name)
+	if err != nil {
+		return err
+	}
+
+	backend.cache.Delete(stateKey(name))
+	return nil
+}
+
+func (backend cacheBackend) PersistState(currentState state.State) error {
+	err := backend.backend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	backend.cache.saveState(currentState)
+	return nil
+}
+
+func (backend cacheBackend) States() ([]string, error) {
+	states, err := backend.backend.States()
+	if err == nil {
+		backend.cache.Save(statesKey, states)
+		return states, nil
+	}
+
+	cachedStates := []string{}
+	fetchedAt, cacheErr := backend.cache.Load(statesKey, &cachedStates)
+	if cacheErr != nil {
+		return nil, err
+	}
+
+	PrintStaleWarning("list of cluster managers", fetchedAt, err)
+	return cachedStates, nil
+}
+
+func (backend cacheBackend) StateTerraformConfig(name string) (string, interface{}) {
+	return backend.backend.StateTerraformConfig(name)
+}
+
+// The receiver of these would shadow the backend package
+func (cached cacheBackend) StateTerraformEnv(name string) []string {
+	return backend.StateTerraformEnv(cached.backend, name)
+}
+
+func (cached cacheBackend) PrepareRun(name string, env []string) error {
+	return backend.PrepareRun(cached.backend, name, env)
+}
+
+func (cached cacheBackend) StateVersions(name string) ([]backend.StateVersion, error) {
+	return backend.StateVersions(cached.backend, name)
+}
+
+func (cached cacheBackend) StateAtVersion(name, version string) (state.State, error) {
+	return backend.StateAtVersion(cached.backend, name, version)
+}
+
+// Stores the state without its secrets.
+func (c *Cache) saveState(currentState state.State) error {
+	withoutSecrets, err := currentState.WithoutSecrets()
+	if err != nil {
+		return err
+	}
+
+	return c.Save(stateKey(currentState.Name), json.RawMessage(withoutSecrets.Bytes()))
+}
+
+// Save stores the JSON encoding of value under key, along with the current time. Failing to
+// update the cache doesn't fail the command that read the value, so errors are only returned.
+func (c *Cache) Save(key string, value interface{}) error {
+	if c == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(snapshot{FetchedAt: time.Now(), Value: raw})
+	if err != nil {
+		return err
+	}
+
+	// Written to a new file, created only readable by the user, and moved in place, so a file
+	// an earlier version left readable by others is replaced
+	file, err := ioutil.TempFile(c.dir, key+".json.")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), c.path(key))
+}
+
+// Load decodes the value stored under key into value, and returns the time it was stored.
+func (c *Cache) Load(key string, value interface{}) (time.Time, error) {
+	if c == nil {
+		return time.Time{}, os.ErrNotExist
+	}
+
+	content, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s := snapshot{}
+	err = json.Unmarshal(content, &s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.FetchedAt, json.Unmarshal(s.Value, value)
+}
+
+// Delete removes the value stored under key.
+func (c *Cache) Delete(key string) error {
+	if c == nil {
+		return nil
+	}
+
+	err := os.Remove(c.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// PrintStaleWarning tells the user that what follows comes from the cache, and why.
+func PrintStaleWarning(what string, fetchedAt time.Time, reason error) {
+	fmt.Fprintf(os.Stderr, "STALE: showing the cached %s from %s (%s ago), fetching it failed: %v\n", what, fetchedAt.Format(time.RFC1123), time.Since(fetchedAt).Round(time.Second), reason)
+}
+
+func stateKey(name string) string {
+	return "state_" + name
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/backend/mocks"
+	"github.com/joyent/triton-kubernetes/state"
+
+	"github.com/spf13/viper"
+)
+
+func newTestCache(t *testing.T) (*Cache, func()) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewCacheWithDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cache, func() { os.RemoveAll(dir) }
+}
+
+func TestStateFallsBackToCache(t *testing.T) {
+	cache, cleanup := newTestCache(t)
+	defer cleanup()
+
+	devManager, err := state.New("dev-manager", []byte(`{"module":{"cluster-manager":{"name":"dev-manager"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onlineBackend := &mocks.Backend{}
+	onlineBackend.On("States").Return([]string{"dev-manager"}, nil)
+	onlineBackend.On("State", "dev-manager").Return(devManager, nil)
+
+	online := cacheBackend{backend: onlineBackend, cache: cache}
+	_, err = online.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = online.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unreachable := errors.New("connection refused")
+	offlineBackend := &mocks.Backend{}
+	offlineBackend.On("States").Return(nil, unreachable)
+	offlineBackend.On("State", "dev-manager").Return(state.State{}, unreachable)
+	offlineBackend.On("State", "beta-manager").Return(state.State{}, unreachable)
+
+	offline := cacheBackend{backend: offlineBackend, cache: cache}
+	states, err := offline.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 1 || states[0] != "dev-manager" {
+		t.Errorf("Wrong output, expected [dev-manager], received %v", states)
+	}
+
+	cachedState, err := offline.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := cachedState.Get("module.cluster-manager.name"); name != "dev-manager" {
+		t.Errorf("Wrong output, expected dev-manager, received %s", name)
+	}
+
+	_, err = offline.State("beta-manager")
+	if err != unreachable {
+		t.Errorf("Wrong output, expected %v, received %v", unreachable, err)
+	}
+}
+
+func TestDeleteStateRemovesCache(t *testing.T) {
+	cache, cleanup := newTestCache(t)
+	defer cleanup()
+
+	err := cache.Save(stateKey("dev-manager"), map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localBackend := &mocks.Backend{}
+	localBackend.On("DeleteState", "dev-manager").Return(nil)
+
+	err = cacheBackend{backend: localBackend, cache: cache}.DeleteState("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cache.Load(stateKey("dev-manager"), &map[string]string{})
+	if !os.IsNotExist(err) {
+		t.Errorf("Expected the cached state to be removed, received %v", err)
+	}
+}
+
+func TestCachedStateLeavesOutSecrets(t *testing.T) {
+	cache, cleanup := newTestCache(t)
+	defer cleanup()
+
+	devManager, err := state.New("dev-manager", []byte(`{"module":{"cluster-manager":{"name":"dev-manager","rancher_admin_password":"pa$$word"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remoteBackend := &mocks.Backend{}
+	remoteBackend.On("State", "dev-manager").Return(devManager, nil)
+
+	_, err = cacheBackend{backend: remoteBackend, cache: cache}.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(cache.path(stateKey("dev-manager")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the cached state to only be readable by the user, received %v", info.Mode().Perm())
+	}
+
+	content, err := ioutil.ReadFile(cache.path(stateKey("dev-manager")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "pa$$word") || !strings.Contains(string(content), "dev-manager") {
+		t.Errorf("Expected the cached state without its secrets, received %s", content)
+	}
+}
+
+func TestDisableCache(t *testing.T) {
+	conf := viper.New()
+	conf.Set("disable_cache", true)
+
+	remoteBackend := &mocks.Backend{}
+	wrapped, err := New(conf, remoteBackend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapped != remoteBackend {
+		t.Errorf("Expected the backend itself when the cache is disabled, received %T", wrapped)
+	}
+
+	outputCache, err := NewCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outputCache.Save("outputs", "address"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outputCache.Load("outputs", new(string)); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be cached, received %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	storage "google.golang.org/api/storage/v1"
+)
+
+const (
+	defaultPrefix   = "triton-kubernetes"
+	defaultEndpoint = "https://storage.googleapis.com"
+
+	terraformConfigObjectFormat = "%s/%s/main.tf.json"
+	lockObjectFormat            = "%s/%s/main.tf.json.lock"
+	// Terraform's gcs backend stores the default workspace as default.tfstate under its prefix
+	terraformStatePrefixFormat = "%s/%s"
+	terraformStateObjectFormat = "%s/%s/default.tfstate"
+)
+
+// errObjectNotFound is returned when an object, or a generation of it, doesn't exist.
+var errObjectNotFound = errors.New("object not found")
+
+// errPreconditionFailed is returned when an object is created but already exists.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// Stores terraform json configuration files for all cluster managers in a Google Cloud Storage
+// bucket, mirroring the layout of the S3 backend. Each cluster manager has a separate prefix
+// with a main.tf.json object managed by triton-kubernetes and the terraform state managed by
+// terraform. With object versioning enabled on the bucket, the previous versions of main.tf.json
+// are kept as the history of the cluster manager.
+// Object Name: ${PREFIX}/${CLUSTER_MANAGER_NAME}/main.tf.json
+type gcsBackend struct {
+	bucket  string
+	options Options
+
+	service *storage.Service
+	// IDs of the locks taken through this backend, by cluster manager
+	heldLocks map[string]string
+}
+
+// Options of the GCS backend.
+type Options struct {
+	// Object prefix of the cluster managers in the bucket, defaults to triton-kubernetes
+	Prefix string
+	// Path of the JSON key of a service account, the application default credentials are used
+	// when it's empty
+	CredentialsPath string
+	// Endpoint of the storage API, defaults to https://storage.googleapis.com
+	Endpoint string
+}
+
+type gcsTerraformBackendConfig struct {
+	Bucket      string `json:"bucket"`
+	Prefix      string `json:"prefix"`
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// Sends the uploads of the storage client to the upload path of the endpoint. The client only
+// knows the upload path of its default endpoint, www.googleapis.com.
+type uploadTransport struct {
+	transport http.RoundTripper
+}
+
+func (t uploadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Query().Get("uploadType") == "" || !strings.HasPrefix(req.URL.Path, "/storage/v1/") {
+		return t.transport.RoundTrip(req)
+	}
+
+	uploadURL := *req.URL
+	uploadURL.Path = "/upload" + uploadURL.Path
+	if uploadURL.RawPath != "" {
+		uploadURL.RawPath = "/upload" + uploadURL.RawPath
+	}
+	uploadReq := *req
+	uploadReq.URL = &uploadURL
+	return t.transport.RoundTrip(&uploadReq)
+}
+
+func New(bucket string, options Options) (backend.Backend, error) {
+	var client *http.Client
+	if options.CredentialsPath != "" {
+		credentialsPath, err := homedir.Expand(options.CredentialsPath)
+		if err != nil {
+			return nil, err
+		}
+		options.CredentialsPath = credentialsPath
+
+		credentials, err := ioutil.ReadFile(credentialsPath)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read GCS credentials '%s': %v", credentialsPath, err)
+		}
+
+		jwtConfig, err := google.JWTConfigFromJSON(credentials, storage.DevstorageReadWriteScope)
+		if err != nil {
+			return nil, err
+		}
+		client = jwtConfig.Client(context.Background())
+	} else {
+		defaultClient, err := google.DefaultClient(context.Background(), storage.DevstorageReadWriteScope)
+		if err != nil {
+			return nil, err
+		}
+		client = defaultClient
+	}
+
+	return newWithClient(bucket, options, client)
+}
+
+func newWithClient(bucket string, options Options, client *http.Client) (backend.Backend, error) {
+	if options.Prefix == "" {
+		options.Prefix = defaultPrefix
+	}
+	options.Prefix = strings.Trim(options.Prefix, "/")
+	if options.Endpoint == "" {
+		options.Endpoint = defaultEndpoint
+	}
+	options.Endpoint = strings.TrimSuffix(options.Endpoint, "/")
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	uploadClient := *client
+	uploadClient.Transport = uploadTransport{transport: transport}
+
+	service, err := storage.New(&uploadClient)
+	if err != nil {
+		return nil, err
+	}
+	service.BasePath = options.Endpoint + "/storage/v1/"
+
+	b := &gcsBackend{
+		bucket:    bucket,
+		options:   options,
+		service:   service,
+		heldLocks: map[string]string{},
+	}
+
+	// Fail early if the bucket doesn't exist or isn't accessible
+	metadata, err := service.Buckets.Get(bucket).Fields("versioning").Do()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to access GCS bucket '%s': %v", bucket, err)
+	}
+	if metadata.Versioning == nil || !metadata.Versioning.Enabled {
+		fmt.Printf("Object versioning is disabled on GCS bucket '%s', the history of cluster managers isn't kept. Enable it with `gsutil versioning set on gs://%s`.\n", bucket, bucket)
+	}
+
+	return b, nil
+}
+
+func (backend *gcsBackend) States() ([]string, error) {
+	states := []string{}
+	call := backend.service.Objects.List(backend.bucket).Prefix(backend.options.Prefix + "/").Delimiter("/")
+	err := call.Pages(context.Background(), func(objects *storage.Objects) error {
+		for _, prefix := range objects.Prefixes {
+			name := strings.TrimPrefix(prefix, backend.options.Prefix+"/")
+			states = append(states, strings.TrimSuffix(name, "/"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (backend *gcsBackend) State(name string) (state.State, error) {
+	content, err := backend.getObject(backend.configObject(name), 0)
+	if err == errObjectNotFound {
+		// Since no state exists, lets create an empty one
+		return state.New(name, []byte("{}"))
+	}
+	if err != nil {
+		return state.State{}, err
+	}
+
+	return state.New(name, content)
+}
+
+func (backend *gcsBackend) PersistState(state state.State) error {
+	unlock, err := backend.lock(state.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	object := &storage.Object{Name: backend.configObject(state.Name), ContentType: "application/json"}
+	_, err = backend.service.Objects.Insert(backend.bucket, object).Media(bytes.NewReader(state.Bytes())).Do()
+	return objectError(err)
+}
+
+func (backend *gcsBackend) DeleteState(name string) error {
+	unlock, err := backend.lock(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// With object versioning the objects become noncurrent versions, deleting a missing object
+	// succeeds
+	for _, object := range []string{backend.configObject(name), fmt.Sprintf(terraformStateObjectFormat, backend.options.Prefix, name)} {
+		err := objectError(backend.service.Objects.Delete(backend.bucket, object).Do())
+		if err != nil && err != errObjectNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (backend *gcsBackend) StateTerraformConfig(name string) (string, interface{}) {
+	terraformBackendConfig := gcsTerraformBackendConfig{
+		Bucket:      backend.bucket,
+		Prefix:      fmt.Sprintf(terraformStatePrefixFormat, backend.options.Prefix, name),
+		Credentials: backend.options.CredentialsPath,
+	}
+
+	return "terraform.backend.gcs", terraformBackendConfig
+}
+
+// StateVersions returns the versions of main.tf.json kept by object versioning, newest first.
+// The current version is the first one, unless the cluster manager was deleted.
+func (b *gcsBackend) StateVersions(name string) ([]backend.StateVersion, error) {
+	object := b.configObject(name)
+	generations := []int64{}
+	saved := map[int64]time.Time{}
+	call := b.service.Objects.List(b.bucket).Prefix(object).Versions(true)
+	err := call.Pages(context.Background(), func(objects *storage.Objects) error {
+		for _, item := range objects.Items {
+			// The prefix also matches the lock object
+			if item.Name != object {
+				continue
+			}
+
+			updated, err := time.Parse(time.RFC3339, item.Updated)
+			if err != nil {
+				return fmt.Errorf("Invalid update time of version %d of '%s': %v", item.Generation, object, err)
+			}
+			generations = append(generations, item.Generation)
+			saved[item.Generation] = updated
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Generations increase with each version
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i] > generations[j]
+	})
+	versions := []backend.StateVersion{}
+	for _, generation := range generations {
+		versions = append(versions, backend.StateVersion{ID: strconv.FormatInt(generation, 10), Saved: saved[generation]})
+	}
+	return versions, nil
+}
+
+// StateAtVersion returns the state as it was saved in the given version of main.tf.json.
+func (b *gcsBackend) StateAtVersion(name, version string) (state.State, error) {
+	missingErr := fmt.Errorf("Version %s of cluster manager '%s' does not exist.", version, name)
+	generation, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return state.State{}, missingErr
+	}
+
+	content, err := b.getObject(b.configObject(name), generation)
+	if err == errObjectNotFound {
+		return state.State{}, missingErr
+	}
+	if err != nil {
+		return state.State{}, err
+	}
+
+	return state.New(name, content)
+}
+
+// Locks a cluster manager with a lock object next to its main.tf.json, which can only be created
+// if it doesn't exist.
+func (b *gcsBackend) Lock(name string, info backend.LockInfo) error {
+	content, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	object := &storage.Object{Name: b.lockObject(name), ContentType: "application/json"}
+	_, err = b.service.Objects.Insert(b.bucket, object).IfGenerationMatch(0).Media(bytes.NewReader(content)).Do()
+	err = objectError(err)
+	if err == errPreconditionFailed {
+		holder, _, _ := b.lockInfo(name)
+		return &backend.LockedError{Name: name, Info: holder}
+	}
+	if err != nil {
+		return err
+	}
+
+	b.heldLocks[name] = info.ID
+	return nil
+}
+
+// Deletes the lock object only if it's still the generation whose holder was read.
+func (b *gcsBackend) Unlock(name, id string) error {
+	holder, generation, err := b.lockInfo(name)
+	if err != nil {
+		return err
+	}
+	if holder.ID != id {
+		return fmt.Errorf("Lock of cluster manager '%s' is held by %s@%s.", name, holder.User, holder.Host)
+	}
+
+	err = objectError(b.service.Objects.Delete(b.bucket, b.lockObject(name)).IfGenerationMatch(generation).Do())
+	if err == errPreconditionFailed {
+		return fmt.Errorf("Lock of cluster manager '%s' was taken over.", name)
+	}
+	if err != nil && err != errObjectNotFound {
+		return err
+	}
+
+	delete(b.heldLocks, name)
+	return nil
+}
+
+func (b *gcsBackend) ForceUnlock(name string) error {
+	err := objectError(b.service.Objects.Delete(b.bucket, b.lockObject(name)).Do())
+	if err != nil && err != errObjectNotFound {
+		return err
+	}
+
+	delete(b.heldLocks, name)
+	return nil
+}
+
+// Locks a cluster manager while it's changed, unless the lock is already held, and returns the
+// function that unlocks it.
+func (b *gcsBackend) lock(name string) (func(), error) {
+	if _, ok := b.heldLocks[name]; ok {
+		return func() {}, nil
+	}
+
+	info := backend.NewLockInfo("save cluster manager " + name)
+	err := b.Lock(name, info)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := func() {
+		err := b.Unlock(name, info.ID)
+		if err != nil {
+			fmt.Printf("Unable to unlock cluster manager '%s', delete the object '%s' from GCS bucket '%s': %v\n", name, b.lockObject(name), b.bucket, err)
+		}
+	}
+	return unlock, nil
+}
+
+// Returns the holder of the lock of a state and the generation of the lock object.
+func (b *gcsBackend) lockInfo(name string) (backend.LockInfo, int64, error) {
+	info := backend.LockInfo{}
+	object, err := b.service.Objects.Get(b.bucket, b.lockObject(name)).Do()
+	if err != nil {
+		return info, 0, objectError(err)
+	}
+
+	content, err := b.getObject(b.lockObject(name), object.Generation)
+	if err != nil {
+		return info, 0, err
+	}
+
+	err = json.Unmarshal(content, &info)
+	return info, object.Generation, err
+}
+
+func (backend *gcsBackend) configObject(name string) string {
+	return fmt.Sprintf(terraformConfigObjectFormat, backend.options.Prefix, name)
+}
+
+func (backend *gcsBackend) lockObject(name string) string {
+	return fmt.Sprintf(lockObjectFormat, backend.options.Prefix, name)
+}
+
+// Returns the content of an object, of the given generation if it isn't 0.
+func (backend *gcsBackend) getObject(object string, generation int64) ([]byte, error) {
+	call := backend.service.Objects.Get(backend.bucket, object)
+	if generation != 0 {
+		call = call.Generation(generation)
+	}
+
+	resp, err := call.Download()
+	if err != nil {
+		return nil, objectError(err)
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Returns errObjectNotFound or errPreconditionFailed for the errors of the storage API meaning
+// them, and the other errors as they are.
+func objectError(err error) error {
+	apiErr, ok := err.(*googleapi.Error)
+	if !ok {
+		return err
+	}
+
+	switch apiErr.Code {
+	case http.StatusNotFound:
+		return errObjectNotFound
+	case http.StatusPreconditionFailed:
+		return errPreconditionFailed
+	}
+	return err
+}
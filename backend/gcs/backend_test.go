@@ -0,0 +1,371 @@
+package gcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+
+	storage "google.golang.org/api/storage/v1"
+)
+
+type fakeObjectVersion struct {
+	generation int64
+	content    []byte
+	updated    time.Time
+	// Noncurrent versions are kept by object versioning after an overwrite or delete
+	live bool
+}
+
+// fakeGCS serves the storage API calls of the backend from memory, with object versioning.
+type fakeGCS struct {
+	objects    map[string][]*fakeObjectVersion
+	generation int64
+	// Called before an object is deleted, e.g. to replace it in the meantime
+	beforeDelete func(name string)
+}
+
+func (f *fakeGCS) current(name string) *fakeObjectVersion {
+	versions := f.objects[name]
+	if len(versions) > 0 && versions[len(versions)-1].live {
+		return versions[len(versions)-1]
+	}
+	return nil
+}
+
+func (f *fakeGCS) put(name string, content []byte) {
+	if current := f.current(name); current != nil {
+		current.live = false
+	}
+	f.generation++
+	f.objects[name] = append(f.objects[name], &fakeObjectVersion{
+		generation: f.generation,
+		content:    content,
+		updated:    time.Unix(f.generation, 0).UTC(),
+		live:       true,
+	})
+}
+
+// Fails the request unless the current generation of the object matches ifGenerationMatch.
+func (f *fakeGCS) preconditionFailed(w http.ResponseWriter, r *http.Request, name string) bool {
+	expected := r.URL.Query().Get("ifGenerationMatch")
+	if expected == "" {
+		return false
+	}
+
+	generation := "0"
+	if current := f.current(name); current != nil {
+		generation = fmt.Sprint(current.generation)
+	}
+	if generation == expected {
+		return false
+	}
+	w.WriteHeader(http.StatusPreconditionFailed)
+	fmt.Fprint(w, `{"error":{"code":412,"message":"At least one of the pre-conditions you specified did not hold."}}`)
+	return true
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	path := r.URL.EscapedPath()
+	switch {
+	case path == "/storage/v1/b/bucket":
+		fmt.Fprint(w, `{"versioning":{"enabled":true}}`)
+	case r.Method == http.MethodGet && path == "/storage/v1/b/bucket/o":
+		list := storage.Objects{}
+		prefixes := map[string]bool{}
+		for name, versions := range f.objects {
+			if !strings.HasPrefix(name, query.Get("prefix")) {
+				continue
+			}
+			if query.Get("delimiter") == "/" {
+				if f.current(name) == nil {
+					continue
+				}
+				rest := strings.TrimPrefix(name, query.Get("prefix"))
+				if i := strings.Index(rest, "/"); i >= 0 {
+					prefixes[query.Get("prefix")+rest[:i+1]] = true
+				}
+				continue
+			}
+			for _, version := range versions {
+				if !version.live && query.Get("versions") != "true" {
+					continue
+				}
+				list.Items = append(list.Items, &storage.Object{
+					Name:       name,
+					Generation: version.generation,
+					Updated:    version.updated.Format(time.RFC3339),
+				})
+			}
+		}
+		for prefix := range prefixes {
+			list.Prefixes = append(list.Prefixes, prefix)
+		}
+		json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/storage/v1/b/bucket/o/"):
+		name, _ := url.PathUnescape(strings.TrimPrefix(path, "/storage/v1/b/bucket/o/"))
+		for _, version := range f.objects[name] {
+			if (query.Get("generation") == "" && version.live) || query.Get("generation") == fmt.Sprint(version.generation) {
+				if query.Get("alt") == "media" {
+					w.Write(version.content)
+				} else {
+					json.NewEncoder(w).Encode(storage.Object{Name: name, Generation: version.generation})
+				}
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodPost && path == "/upload/storage/v1/b/bucket/o":
+		// Multipart uploads send the metadata of the object, then its content
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		metadata := storage.Object{}
+		part, err := reader.NextPart()
+		if err != nil || json.NewDecoder(part).Decode(&metadata) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		part, err = reader.NextPart()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := ioutil.ReadAll(part)
+
+		if f.preconditionFailed(w, r, metadata.Name) {
+			return
+		}
+		f.put(metadata.Name, content)
+		json.NewEncoder(w).Encode(storage.Object{Name: metadata.Name, Generation: f.generation})
+	case r.Method == http.MethodDelete:
+		name, _ := url.PathUnescape(strings.TrimPrefix(path, "/storage/v1/b/bucket/o/"))
+		if f.beforeDelete != nil {
+			f.beforeDelete(name)
+		}
+		current := f.current(name)
+		if current == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if f.preconditionFailed(w, r, name) {
+			return
+		}
+		current.live = false
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newTestBackend(t *testing.T) (*gcsBackend, *fakeGCS) {
+	fake := &fakeGCS{objects: map[string][]*fakeObjectVersion{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	b, err := newWithClient("bucket", Options{Endpoint: server.URL}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.(*gcsBackend), fake
+}
+
+func TestStates(t *testing.T) {
+	b, fake := newTestBackend(t)
+
+	for _, name := range []string{"dev", "prod"} {
+		s, err := b.State(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Set("name", name)
+		if err := b.PersistState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := fake.objects["triton-kubernetes/dev/main.tf.json"]; !ok {
+		t.Fatalf("expected main.tf.json under the prefix, got %v", fake.objects)
+	}
+
+	states, err := b.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(states)
+	if strings.Join(states, ",") != "dev,prod" {
+		t.Fatalf("expected states dev and prod, got %v", states)
+	}
+
+	s, err := b.State("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Get("name") != "dev" {
+		t.Fatalf("expected the persisted state, got %s", s.Bytes())
+	}
+
+	if err := b.DeleteState("dev"); err != nil {
+		t.Fatal(err)
+	}
+	states, err = b.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(states, ",") != "prod" {
+		t.Fatalf("expected only prod after delete, got %v", states)
+	}
+}
+
+func TestStateVersions(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	s, err := b.State("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"1.10.1", "1.11.2"} {
+		s.Set("k8s_version", version)
+		if err := b.PersistState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The lock object shares the prefix of main.tf.json
+	if err := b.Lock("dev", backend.LockInfo{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := b.StateVersions("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %v", versions)
+	}
+	if !versions[0].Saved.After(versions[1].Saved) {
+		t.Fatalf("expected the newest version first, got %v", versions)
+	}
+
+	previous, err := b.StateAtVersion("dev", versions[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.Get("k8s_version") != "1.10.1" {
+		t.Fatalf("expected the first version, got %s", previous.Bytes())
+	}
+
+	if _, err := b.StateAtVersion("dev", "999"); err == nil {
+		t.Fatal("expected an error for a missing version")
+	}
+}
+
+func TestLock(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	if err := b.Lock("dev", backend.LockInfo{ID: "1", User: "alice", Host: "laptop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := b.Lock("dev", backend.LockInfo{ID: "2"})
+	lockedErr, ok := err.(*backend.LockedError)
+	if !ok {
+		t.Fatalf("expected a LockedError, got %v", err)
+	}
+	if lockedErr.Info.User != "alice" {
+		t.Fatalf("expected the holder of the lock, got %+v", lockedErr.Info)
+	}
+
+	if err := b.Unlock("dev", "2"); err == nil {
+		t.Fatal("expected unlocking with another ID to fail")
+	}
+	if err := b.Unlock("dev", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Lock("dev", backend.LockInfo{ID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ForceUnlock("dev"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnlockTakenOver(t *testing.T) {
+	b, fake := newTestBackend(t)
+
+	if err := b.Lock("dev", backend.LockInfo{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone else removes the lock and takes it with the same content between the read of the
+	// holder and the delete
+	fake.beforeDelete = func(name string) {
+		fake.beforeDelete = nil
+		fake.put(name, fake.current(name).content)
+	}
+	err := b.Unlock("dev", "1")
+	if err == nil || !strings.Contains(err.Error(), "taken over") {
+		t.Fatalf("expected the unlock to fail, got %v", err)
+	}
+	if fake.current("triton-kubernetes/dev/main.tf.json.lock") == nil {
+		t.Fatal("expected the new lock to be kept")
+	}
+}
+
+func TestPersistStateLocks(t *testing.T) {
+	b, fake := newTestBackend(t)
+
+	s, err := b.State("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PersistState(s); err != nil {
+		t.Fatal(err)
+	}
+	if fake.current("triton-kubernetes/dev/main.tf.json.lock") != nil {
+		t.Fatal("expected the lock taken to persist the state to be released")
+	}
+
+	// A lock held by this backend is kept
+	if err := b.Lock("dev", backend.LockInfo{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PersistState(s); err != nil {
+		t.Fatal(err)
+	}
+	if fake.current("triton-kubernetes/dev/main.tf.json.lock") == nil {
+		t.Fatal("expected the held lock to be kept")
+	}
+
+	// A lock held by someone else fails, as for another process sharing the bucket
+	delete(b.heldLocks, "dev")
+	err = b.PersistState(s)
+	if _, ok := err.(*backend.LockedError); !ok {
+		t.Fatalf("expected a LockedError, got %v", err)
+	}
+}
+
+func TestStateTerraformConfig(t *testing.T) {
+	b, _ := newTestBackend(t)
+
+	path, value := b.StateTerraformConfig("dev")
+	if path != "terraform.backend.gcs" {
+		t.Fatalf("unexpected path %s", path)
+	}
+	config := value.(gcsTerraformBackendConfig)
+	if config.Bucket != "bucket" || config.Prefix != "triton-kubernetes/dev" {
+		t.Fatalf("unexpected config %+v", config)
+	}
+}
@@ -0,0 +1,437 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	terraformConfigFileName = "main.tf.json"
+	terraformStateFileName  = "terraform.tfstate"
+	lockRefFormat           = "refs/triton-kubernetes/locks/%s"
+
+	// Pushes rejected because the remote branch moved are retried on top of it this many times
+	maxPushAttempts = 5
+)
+
+// Stores terraform json configuration files for all cluster managers in a git repository.
+// Each cluster manager has a separate directory at the root of the repository with a main.tf.json
+// file and a terraform.tfstate file, mirroring the layout of the local and manta backends.
+// The repository is cloned to localPath, pulled before every read and every change is
+// committed (with the operation that caused it) and pushed to the remote. States are locked with
+// refs/triton-kubernetes/locks/${CLUSTER_MANAGER_NAME} refs, which aren't part of the branch.
+// Directory Path: ${REPOSITORY}/${CLUSTER_MANAGER_NAME}/main.tf.json
+type gitBackend struct {
+	remoteURL string
+	branch    string
+	localPath string
+}
+
+type localTerraformBackendConfig struct {
+	Path string `json:"path"`
+}
+
+func New(remoteURL, branch, localPath string) (backend.Backend, error) {
+	expandedLocalPath, err := homedir.Expand(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &gitBackend{
+		remoteURL: remoteURL,
+		branch:    branch,
+		localPath: expandedLocalPath,
+	}
+
+	// Clone the repository if it hasn't been cloned yet
+	_, err = os.Stat(filepath.Join(expandedLocalPath, ".git"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		err = os.MkdirAll(filepath.Dir(expandedLocalPath), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = runGitCommand("", "clone", remoteURL, expandedLocalPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = b.pull()
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (backend *gitBackend) States() ([]string, error) {
+	err := backend.pull()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(backend.localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	states := []string{}
+	for _, f := range files {
+		if f.IsDir() && !strings.HasPrefix(f.Name(), ".") {
+			states = append(states, f.Name())
+		}
+	}
+
+	return states, nil
+}
+
+func (backend *gitBackend) State(name string) (state.State, error) {
+	err := backend.pull()
+	if err != nil {
+		return state.State{}, err
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(backend.localPath, name, terraformConfigFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Since no state exists, lets create an empty one
+			return state.New(name, []byte("{}"))
+		}
+		return state.State{}, err
+	}
+
+	return state.New(name, content)
+}
+
+func (backend *gitBackend) PersistState(state state.State) error {
+	err := backend.pull()
+	if err != nil {
+		return err
+	}
+
+	statePath := filepath.Join(backend.localPath, state.Name)
+	err = os.MkdirAll(statePath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(statePath, terraformConfigFileName), state.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	// Stage the whole directory so the terraform.tfstate written by terraform is committed as well
+	_, err = runGitCommand(backend.localPath, "add", "--all", "--", state.Name)
+	if err != nil {
+		return err
+	}
+
+	return backend.commitAndPush(fmt.Sprintf("Update %s", state.Name))
+}
+
+func (backend *gitBackend) DeleteState(name string) error {
+	err := backend.pull()
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(filepath.Join(backend.localPath, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = runGitCommand(backend.localPath, "rm", "-r", "-f", "--quiet", "--ignore-unmatch", "--", name)
+	if err != nil {
+		return err
+	}
+
+	// Remove any untracked files git rm left behind
+	err = os.RemoveAll(filepath.Join(backend.localPath, name))
+	if err != nil {
+		return err
+	}
+
+	return backend.commitAndPush(fmt.Sprintf("Delete %s", name))
+}
+
+func (backend *gitBackend) StateTerraformConfig(name string) (string, interface{}) {
+	terraformBackendConfig := localTerraformBackendConfig{
+		Path: filepath.Join(backend.localPath, name, terraformStateFileName),
+	}
+
+	return "terraform.backend.local", terraformBackendConfig
+}
+
+// pull brings the local clone up to date with the remote branch. Changes terraform made to the
+// terraform.tfstate files since the last commit are committed first and, like any other local
+// commit, rebased onto the remote branch and pushed, so they're never discarded. An empty remote
+// (no commits on the branch yet) is not treated as an error.
+func (backend *gitBackend) pull() error {
+	err := backend.checkoutBranch()
+	if err != nil {
+		return err
+	}
+
+	_, err = runGitCommand(backend.localPath, "add", "--all")
+	if err != nil {
+		return err
+	}
+	err = backend.commit("Update terraform state")
+	if err != nil {
+		return err
+	}
+
+	return backend.sync()
+}
+
+// checkoutBranch switches the clone to the configured branch if it's on another one, e.g. the
+// default branch of the remote after cloning. A branch the remote doesn't have yet starts empty.
+func (backend *gitBackend) checkoutBranch() error {
+	output, err := runGitCommand(backend.localPath, "symbolic-ref", "--quiet", "HEAD")
+	if err == nil && strings.TrimSpace(output) == "refs/heads/"+backend.branch {
+		return nil
+	}
+
+	remoteHead, err := backend.fetch()
+	if err != nil {
+		return err
+	}
+	if remoteHead != "" {
+		_, err = runGitCommand(backend.localPath, "checkout", "--quiet", "-B", backend.branch, remoteHead)
+		return err
+	}
+
+	_, err = runGitCommand(backend.localPath, "checkout", "--quiet", "--orphan", backend.branch)
+	if err != nil {
+		return err
+	}
+	_, err = runGitCommand(backend.localPath, "rm", "-r", "-f", "--quiet", "--cached", "--ignore-unmatch", ".")
+	if err != nil {
+		return err
+	}
+	_, err = runGitCommand(backend.localPath, "clean", "-d", "-f", "--quiet")
+	return err
+}
+
+// commit commits the staged changes, recording the command that triggered the change.
+func (backend *gitBackend) commit(summary string) error {
+	// Nothing to commit
+	_, err := runGitCommand(backend.localPath, "diff", "--cached", "--quiet")
+	if err == nil {
+		return nil
+	}
+
+	message := fmt.Sprintf("%s\n\n%s", summary, operationMetadata())
+	_, err = runGitCommand(backend.localPath, "commit", "--quiet", "-m", message)
+	return err
+}
+
+// commitAndPush commits the staged changes and pushes them to the remote branch.
+func (backend *gitBackend) commitAndPush(summary string) error {
+	err := backend.commit(summary)
+	if err != nil {
+		return err
+	}
+
+	return backend.sync()
+}
+
+// sync rebases the local commits onto the remote branch and pushes them. If the push is rejected
+// because another clone pushed in the meantime, it's tried again on top of the new commits. The
+// clone is only reset to the remote branch when it has no commits of its own.
+func (backend *gitBackend) sync() error {
+	var err error
+	for attempt := 0; attempt < maxPushAttempts; attempt++ {
+		var remoteHead string
+		remoteHead, err = backend.fetch()
+		if err != nil {
+			return err
+		}
+
+		_, headErr := runGitCommand(backend.localPath, "rev-parse", "--verify", "--quiet", "HEAD")
+		if headErr != nil {
+			// No local commits, e.g. a clone of an empty branch, there is nothing to push
+			if remoteHead == "" {
+				return nil
+			}
+			_, err = runGitCommand(backend.localPath, "reset", "--hard", "--quiet", remoteHead)
+			return err
+		}
+
+		if remoteHead != "" {
+			_, err = runGitCommand(backend.localPath, "rebase", "--quiet", remoteHead)
+			if err != nil {
+				runGitCommand(backend.localPath, "rebase", "--abort")
+				return fmt.Errorf("Unable to rebase the changes of %s onto the '%s' branch of %s, resolve the conflict and push them: %v", backend.localPath, backend.branch, backend.remoteURL, err)
+			}
+
+			ahead, err := runGitCommand(backend.localPath, "rev-list", "--count", remoteHead+"..HEAD")
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(ahead) == "0" {
+				return nil
+			}
+		}
+
+		_, err = runGitCommand(backend.localPath, "push", "--quiet", "origin", fmt.Sprintf("HEAD:refs/heads/%s", backend.branch))
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// fetch fetches the remote branch and returns the name of its remote-tracking ref, or an empty
+// string if the remote doesn't have the branch yet.
+func (backend *gitBackend) fetch() (string, error) {
+	output, err := runGitCommand(backend.localPath, "ls-remote", "--heads", "origin", backend.branch)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(output) == "" {
+		return "", nil
+	}
+
+	remoteHead := "refs/remotes/origin/" + backend.branch
+	_, err = runGitCommand(backend.localPath, "fetch", "--quiet", "origin", fmt.Sprintf("+refs/heads/%s:%s", backend.branch, remoteHead))
+	if err != nil {
+		return "", err
+	}
+
+	return remoteHead, nil
+}
+
+// Lock pushes a ref for the named state, only if the remote doesn't have it yet. The ref points
+// to a commit whose message is the lock info, it isn't part of the history of the branch.
+func (b *gitBackend) Lock(name string, info backend.LockInfo) error {
+	content, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	emptyTree, err := runGitCommand(b.localPath, "mktree")
+	if err != nil {
+		return err
+	}
+	commit, err := runGitCommand(b.localPath, "commit-tree", strings.TrimSpace(emptyTree), "-m", string(content))
+	if err != nil {
+		return err
+	}
+
+	ref := fmt.Sprintf(lockRefFormat, name)
+	_, err = runGitCommand(b.localPath, "push", "--quiet", "--force-with-lease="+ref+":", "origin", strings.TrimSpace(commit)+":"+ref)
+	if err != nil {
+		holder, _, holderErr := b.lockInfo(name)
+		if holderErr == nil && holder.ID != "" {
+			return &backend.LockedError{Name: name, Info: holder}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Deletes the lock ref only if it wasn't replaced since its holder was read.
+func (b *gitBackend) Unlock(name, id string) error {
+	holder, commit, err := b.lockInfo(name)
+	if err != nil {
+		return err
+	}
+	if commit == "" {
+		return nil
+	}
+	if holder.ID != id {
+		return fmt.Errorf("Lock of cluster manager '%s' is held by %s@%s.", name, holder.User, holder.Host)
+	}
+
+	ref := fmt.Sprintf(lockRefFormat, name)
+	_, err = runGitCommand(b.localPath, "push", "--quiet", "--force-with-lease="+ref+":"+commit, "origin", ":"+ref)
+	if err != nil {
+		return fmt.Errorf("Lock of cluster manager '%s' was taken over: %v", name, err)
+	}
+	return nil
+}
+
+func (b *gitBackend) ForceUnlock(name string) error {
+	ref := fmt.Sprintf(lockRefFormat, name)
+	output, err := runGitCommand(b.localPath, "ls-remote", "origin", ref)
+	if err != nil || strings.TrimSpace(output) == "" {
+		return err
+	}
+
+	_, err = runGitCommand(b.localPath, "push", "--quiet", "origin", ":"+ref)
+	return err
+}
+
+// Returns the holder of the lock of a state and the commit of the lock ref, which is empty if the
+// state isn't locked.
+func (b *gitBackend) lockInfo(name string) (backend.LockInfo, string, error) {
+	info := backend.LockInfo{}
+	ref := fmt.Sprintf(lockRefFormat, name)
+
+	output, err := runGitCommand(b.localPath, "ls-remote", "origin", ref)
+	if err != nil || strings.TrimSpace(output) == "" {
+		return info, "", err
+	}
+
+	_, err = runGitCommand(b.localPath, "fetch", "--quiet", "origin", "+"+ref+":"+ref)
+	if err != nil {
+		return info, "", err
+	}
+	commit, err := runGitCommand(b.localPath, "rev-parse", ref)
+	if err != nil {
+		return info, "", err
+	}
+	message, err := runGitCommand(b.localPath, "show", "--no-patch", "--format=%B", ref)
+	if err != nil {
+		return info, "", err
+	}
+
+	err = json.Unmarshal([]byte(message), &info)
+	return info, strings.TrimSpace(commit), err
+}
+
+// operationMetadata describes who ran which command, so state changes can be attributed.
+func operationMetadata() string {
+	hostname, _ := os.Hostname()
+	username := os.Getenv("USER")
+
+	return fmt.Sprintf("Command: %s\nUser: %s\nHost: %s", strings.Join(os.Args, " "), username, hostname)
+}
+
+func runGitCommand(workingDir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = workingDir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
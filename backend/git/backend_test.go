@@ -0,0 +1,284 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// Returns a bare repository to use as the remote, and a directory for the clones.
+func newTestRemote(t *testing.T) (string, string, func()) {
+	if _, err := runGitCommand("", "--version"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "triton-kubernetes-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commits need an identity, the test machine may not have one
+	identity := map[string]string{
+		"GIT_AUTHOR_NAME":     "test",
+		"GIT_AUTHOR_EMAIL":    "test@example.com",
+		"GIT_COMMITTER_NAME":  "test",
+		"GIT_COMMITTER_EMAIL": "test@example.com",
+	}
+	previous := map[string]string{}
+	for key, value := range identity {
+		if previousValue, ok := os.LookupEnv(key); ok {
+			previous[key] = previousValue
+		}
+		os.Setenv(key, value)
+	}
+	cleanup := func() {
+		for key := range identity {
+			if previousValue, ok := previous[key]; ok {
+				os.Setenv(key, previousValue)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+
+	remote := filepath.Join(dir, "remote.git")
+	_, err = runGitCommand("", "init", "--quiet", "--bare", remote)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return remote, dir, cleanup
+}
+
+func persistTestState(t *testing.T, b backend.Backend, name, content string) {
+	currentState, err := state.New(name, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.PersistState(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitBackend(t *testing.T) {
+	remote, dir, cleanup := newTestRemote(t)
+	defer cleanup()
+
+	first, err := New(remote, "prod", filepath.Join(dir, "first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	persistTestState(t, first, "dev-manager", `{"module":{"cluster-manager":{"name":"dev-manager"}}}`)
+
+	second, err := New(remote, "prod", filepath.Join(dir, "second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	states, err := second.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(states, []string{"dev-manager"}) {
+		t.Errorf("Wrong output, expected [dev-manager], received %v", states)
+	}
+
+	// Changes pushed by another clone are read
+	persistTestState(t, second, "dev-manager", `{"module":{"cluster-manager":{"name":"dev-manager","rancher_server_image":"rancher/rancher:v2.5.8"}}}`)
+	currentState, err := first.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := currentState.Get("module.cluster-manager.rancher_server_image"); image != "rancher/rancher:v2.5.8" {
+		t.Errorf("Wrong output, expected the image set by the other clone, received '%s'", image)
+	}
+
+	// Both clones are on the configured branch, which is the only one of the remote
+	for _, clone := range []string{"first", "second"} {
+		branch, err := runGitCommand(filepath.Join(dir, clone), "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(branch) != "prod" {
+			t.Errorf("%s: expected branch prod, received %s", clone, branch)
+		}
+	}
+	branches, err := runGitCommand(remote, "for-each-ref", "--format=%(refname)", "refs/heads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(branches) != "refs/heads/prod" {
+		t.Errorf("Wrong output, expected only refs/heads/prod, received %q", branches)
+	}
+
+	err = first.DeleteState("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	states, err = second.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 0 {
+		t.Errorf("Wrong output, expected no states, received %v", states)
+	}
+}
+
+func TestGitBackendKeepsTerraformState(t *testing.T) {
+	remote, dir, cleanup := newTestRemote(t)
+	defer cleanup()
+
+	first, err := New(remote, "master", filepath.Join(dir, "first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	persistTestState(t, first, "dev-manager", `{}`)
+
+	// Terraform writes its state to the clone between two operations of the backend
+	name, _ := first.StateTerraformConfig("dev-manager")
+	if name != "terraform.backend.local" {
+		t.Fatalf("Wrong output, expected terraform.backend.local, received %s", name)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "first", "dev-manager", terraformStateFileName), []byte(`{"version":3}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = first.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "first", "dev-manager", terraformStateFileName))
+	if err != nil || string(content) != `{"version":3}` {
+		t.Errorf("Expected the terraform state to be kept, received %q, %v", content, err)
+	}
+
+	_, err = New(remote, "master", filepath.Join(dir, "second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = ioutil.ReadFile(filepath.Join(dir, "second", "dev-manager", terraformStateFileName))
+	if err != nil || string(content) != `{"version":3}` {
+		t.Errorf("Expected the terraform state to be pushed, received %q, %v", content, err)
+	}
+}
+
+func TestGitBackendRebasesOnRejectedPush(t *testing.T) {
+	remote, dir, cleanup := newTestRemote(t)
+	defer cleanup()
+
+	first, err := New(remote, "master", filepath.Join(dir, "first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	persistTestState(t, first, "dev-manager", `{}`)
+
+	second, err := New(remote, "master", filepath.Join(dir, "second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first clone commits the state terraform wrote, but the other clone pushes before it
+	err = ioutil.WriteFile(filepath.Join(dir, "first", "dev-manager", terraformStateFileName), []byte(`{"version":3}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = runGitCommand(filepath.Join(dir, "first"), "add", "--all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = first.(*gitBackend).commit("Update terraform state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	persistTestState(t, second, "prod-manager", `{}`)
+
+	states, err := first.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(states, []string{"dev-manager", "prod-manager"}) {
+		t.Errorf("Wrong output, expected [dev-manager prod-manager], received %v", states)
+	}
+
+	// The commit of the first clone was rebased and pushed, not discarded
+	_, err = second.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "second", "dev-manager", terraformStateFileName))
+	if err != nil || string(content) != `{"version":3}` {
+		t.Errorf("Expected the terraform state to be pushed, received %q, %v", content, err)
+	}
+}
+
+func TestGitBackendLock(t *testing.T) {
+	remote, dir, cleanup := newTestRemote(t)
+	defer cleanup()
+
+	first, err := New(remote, "master", filepath.Join(dir, "first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(remote, "master", filepath.Join(dir, "second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstLocker := first.(backend.Locker)
+	secondLocker := second.(backend.Locker)
+
+	info := backend.NewLockInfo("create cluster")
+	err = firstLocker.Lock("dev-manager", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = secondLocker.Lock("dev-manager", backend.NewLockInfo("destroy manager"))
+	lockedErr, ok := err.(*backend.LockedError)
+	if !ok {
+		t.Fatalf("Expected a LockedError, received %v", err)
+	}
+	if lockedErr.Info.ID != info.ID || lockedErr.Info.Operation != "create cluster" {
+		t.Errorf("Wrong output, expected the holder %v, received %v", info, lockedErr.Info)
+	}
+
+	// Only the holder of the lock releases it
+	err = secondLocker.Unlock("dev-manager", "other")
+	if err == nil {
+		t.Error("Expected an error unlocking with another id")
+	}
+	err = firstLocker.Unlock("dev-manager", info.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = secondLocker.Lock("dev-manager", backend.NewLockInfo("destroy manager"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = firstLocker.ForceUnlock("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = firstLocker.Lock("dev-manager", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lock isn't part of the branch
+	states, err := second.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 0 {
+		t.Errorf("Wrong output, expected no states, received %v", states)
+	}
+}
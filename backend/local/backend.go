@@ -1,9 +1,11 @@
 package local
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"syscall"
 
 	"github.com/joyent/triton-kubernetes/backend"
 	"github.com/joyent/triton-kubernetes/state"
@@ -16,9 +18,23 @@ const (
 	rootPathFormat            = rootDirectory + "/%s"
 	terraformConfigPathFormat = rootDirectory + "/%s/main.tf.json"
 	terraformStatePathFormat  = rootDirectory + "/%s/terraform.tfstate"
+
+	// Lock files are next to the state directories, which are the only directories listed
+	lockPathFormat = rootDirectory + "/%s.lock"
 )
 
+// Directories of the root directory that aren't states: the terraform binaries downloaded by
+// the shell and the SSH keys generated for clusters
+var nonStateDirectories = map[string]bool{
+	"bin":  true,
+	"keys": true,
+}
+
+// States are locked with flock, which the kernel releases when the process holding the lock
+// exits. The lock file holds the LockInfo of the holder.
 type localBackend struct {
+	// Open lock files, by state name
+	lockFiles map[string]*os.File
 }
 
 type localTerraformBackendConfig struct {
@@ -29,15 +45,15 @@ func New() (backend.Backend, error) {
 	// Create root directory
 	expandedRootDirectory, err := homedir.Expand(rootDirectory)
 	if err != nil {
-		return localBackend{}, err
+		return nil, err
 	}
 
 	err = os.MkdirAll(expandedRootDirectory, os.ModePerm)
 	if err != nil {
-		return localBackend{}, err
+		return nil, err
 	}
 
-	return localBackend{}, nil
+	return localBackend{lockFiles: map[string]*os.File{}}, nil
 }
 
 func (backend localBackend) State(name string) (state.State, error) {
@@ -66,6 +82,10 @@ func (backend localBackend) State(name string) (state.State, error) {
 }
 
 func (backend localBackend) DeleteState(name string) error {
+	if nonStateDirectories[name] {
+		return reservedNameError(name)
+	}
+
 	rootPath := fmt.Sprintf(rootPathFormat, name)
 
 	expandedRootPath, err := homedir.Expand(rootPath)
@@ -77,6 +97,10 @@ func (backend localBackend) DeleteState(name string) error {
 }
 
 func (backend localBackend) PersistState(state state.State) error {
+	if nonStateDirectories[state.Name] {
+		return reservedNameError(state.Name)
+	}
+
 	rootPath := fmt.Sprintf(rootPathFormat, state.Name)
 	expandedRootPath, err := homedir.Expand(rootPath)
 	if err != nil {
@@ -112,7 +136,7 @@ func (backend localBackend) States() ([]string, error) {
 
 	states := []string{}
 	for _, f := range files {
-		if f.IsDir() {
+		if f.IsDir() && !nonStateDirectories[f.Name()] {
 			states = append(states, f.Name())
 		}
 	}
@@ -120,6 +144,10 @@ func (backend localBackend) States() ([]string, error) {
 	return states, nil
 }
 
+func reservedNameError(name string) error {
+	return fmt.Errorf("'%s' can't be the name of a cluster manager stored in the local backend.", name)
+}
+
 func (backend localBackend) StateTerraformConfig(name string) (string, interface{}) {
 	terraformStatePath := fmt.Sprintf(terraformStatePathFormat, name)
 	expandedTerraformStatePath, _ := homedir.Expand(terraformStatePath)
@@ -130,3 +158,70 @@ func (backend localBackend) StateTerraformConfig(name string) (string, interface
 
 	return "terraform.backend.local", terraformBackendConfig
 }
+
+func (b localBackend) Lock(name string, info backend.LockInfo) error {
+	lockPath, err := homedir.Expand(fmt.Sprintf(lockPathFormat, name))
+	if err != nil {
+		return err
+	}
+
+	lockFile, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		holder := backend.LockInfo{}
+		content, _ := ioutil.ReadAll(lockFile)
+		json.Unmarshal(content, &holder)
+		lockFile.Close()
+		return &backend.LockedError{Name: name, Info: holder}
+	}
+	if err != nil {
+		lockFile.Close()
+		return err
+	}
+
+	content, err := json.Marshal(info)
+	if err == nil {
+		err = lockFile.Truncate(0)
+	}
+	if err == nil {
+		_, err = lockFile.WriteAt(content, 0)
+	}
+	if err != nil {
+		lockFile.Close()
+		return err
+	}
+
+	b.lockFiles[name] = lockFile
+	return nil
+}
+
+func (b localBackend) Unlock(name, id string) error {
+	lockFile, ok := b.lockFiles[name]
+	if !ok {
+		return nil
+	}
+	delete(b.lockFiles, name)
+
+	// The file is kept, removing it would let another process lock a file that is about to be
+	// replaced
+	lockFile.Truncate(0)
+	return lockFile.Close()
+}
+
+func (b localBackend) ForceUnlock(name string) error {
+	lockPath, err := homedir.Expand(fmt.Sprintf(lockPathFormat, name))
+	if err != nil {
+		return err
+	}
+
+	// The holder keeps its lock of the removed file, the next lock is taken on a new file
+	err = os.Remove(lockPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package backend
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"syscall"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// Locks taken on another host are only considered stale after this long, terraform runs can
+// take a while
+const StaleLockAge = 24 * time.Hour
+
+// Locker is implemented by backends that can lock a state for the duration of an operation, so
+// that two invocations sharing the backend can't overwrite each other's changes.
+type Locker interface {
+	// Lock locks the named state. If it is already locked, it returns a *LockedError with the
+	// holder of the lock.
+	Lock(name string, info LockInfo) error
+
+	// Unlock releases the lock of the named state taken with the given id. The lock is only
+	// deleted if it still has that id, even if it's taken over concurrently.
+	Unlock(name, id string) error
+
+	// ForceUnlock releases the lock of the named state, whoever holds it.
+	ForceUnlock(name string) error
+}
+
+// LockInfo describes who holds the lock of a state.
+type LockInfo struct {
+	ID        string    `json:"id"`
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Operation string    `json:"operation"`
+	Created   time.Time `json:"created"`
+}
+
+// LockedError is returned when a state is locked by another operation.
+type LockedError struct {
+	Name string
+	Info LockInfo
+}
+
+func (err *LockedError) Error() string {
+	return fmt.Sprintf("Cluster manager '%s' is locked by %s@%s running '%s' since %s. If no other operation is running, run the command again with --force-unlock.", err.Name, err.Info.User, err.Info.Host, err.Info.Operation, err.Info.Created.Format(time.RFC1123))
+}
+
+// NewLockInfo returns the lock info of an operation run by this process.
+func NewLockInfo(operation string) LockInfo {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+
+	return LockInfo{
+		ID:        hex.EncodeToString(id),
+		User:      name,
+		Host:      host,
+		PID:       os.Getpid(),
+		Operation: operation,
+		Created:   time.Now().UTC(),
+	}
+}
+
+// IsStale returns whether the process that took the lock is gone. Processes on this host are
+// looked up, locks taken on other hosts are stale once they're older than StaleLockAge. Locks
+// whose holder couldn't be read are never stale.
+func (info LockInfo) IsStale() bool {
+	if info.ID == "" || info.Created.IsZero() {
+		return false
+	}
+
+	host, _ := os.Hostname()
+	if info.Host != "" && info.Host == host && info.PID > 0 {
+		// Signal 0 only checks that the process exists
+		err := syscall.Kill(info.PID, syscall.Signal(0))
+		return err == syscall.ESRCH
+	}
+
+	return time.Since(info.Created) > StaleLockAge
+}
+
+// LockingBackend locks every state an operation reads or changes through it, until Unlock is
+// called. Backends that aren't a Locker aren't locked. Stale locks are taken over, only if they
+// weren't taken over by someone else in the meantime, and any lock is with forceUnlock.
+type LockingBackend struct {
+	backend     Backend
+	operation   string
+	forceUnlock bool
+
+	// Locks held, by state name
+	locks map[string]LockInfo
+}
+
+// NewLockingBackend wraps remoteBackend, recording operation as the reason for the locks.
+func NewLockingBackend(remoteBackend Backend, operation string, forceUnlock bool) *LockingBackend {
+	return &LockingBackend{
+		backend:     remoteBackend,
+		operation:   operation,
+		forceUnlock: forceUnlock,
+		locks:       map[string]LockInfo{},
+	}
+}
+
+func (backend *LockingBackend) State(name string) (state.State, error) {
+	err := backend.lock(name)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	return backend.backend.State(name)
+}
+
+func (backend *LockingBackend) DeleteState(name string) error {
+	err := backend.lock(name)
+	if err != nil {
+		return err
+	}
+
+	return backend.backend.DeleteState(name)
+}
+
+func (backend *LockingBackend) PersistState(currentState state.State) error {
+	err := backend.lock(currentState.Name)
+	if err != nil {
+		return err
+	}
+
+	return backend.backend.PersistState(currentState)
+}
+
+func (backend *LockingBackend) States() ([]string, error) {
+	return backend.backend.States()
+}
+
+func (backend *LockingBackend) StateTerraformConfig(name string) (string, interface{}) {
+	return backend.backend.StateTerraformConfig(name)
+}
+
+func (backend *LockingBackend) StateTerraformEnv(name string) []string {
+	return StateTerraformEnv(backend.backend, name)
+}
+
+func (backend *LockingBackend) PrepareRun(name string, env []string) error {
+	return PrepareRun(backend.backend, name, env)
+}
+
+// Previous versions of a state aren't changed by other operations, they're read without a lock.
+func (backend *LockingBackend) StateVersions(name string) ([]StateVersion, error) {
+	return StateVersions(backend.backend, name)
+}
+
+func (backend *LockingBackend) StateAtVersion(name, version string) (state.State, error) {
+	return StateAtVersion(backend.backend, name, version)
+}
+
+// Unlock releases every lock taken through the backend. All of them are released even if some
+// fail, and the first error is returned.
+func (backend *LockingBackend) Unlock() error {
+	locker, ok := backend.backend.(Locker)
+	if !ok {
+		return nil
+	}
+
+	var firstErr error
+	for name, info := range backend.locks {
+		err := locker.Unlock(name, info.ID)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("Unable to unlock cluster manager '%s', run the next command with --force-unlock: %v", name, err)
+		}
+		delete(backend.locks, name)
+	}
+	return firstErr
+}
+
+func (backend *LockingBackend) lock(name string) error {
+	locker, ok := backend.backend.(Locker)
+	if !ok {
+		return nil
+	}
+	if _, ok := backend.locks[name]; ok {
+		return nil
+	}
+
+	info := NewLockInfo(backend.operation)
+	err := locker.Lock(name, info)
+	if lockedErr, ok := err.(*LockedError); ok {
+		holder := fmt.Sprintf("%s@%s running '%s' since %s", lockedErr.Info.User, lockedErr.Info.Host, lockedErr.Info.Operation, lockedErr.Info.Created.Format(time.RFC1123))
+		if backend.forceUnlock {
+			fmt.Printf("Force unlocking cluster manager '%s', locked by %s.\n", name, holder)
+			err = locker.ForceUnlock(name)
+		} else if lockedErr.Info.IsStale() {
+			fmt.Printf("Removing the stale lock of cluster manager '%s', taken by %s.\n", name, holder)
+			// Only the stale lock is deleted, not one taken since it was read
+			err = locker.Unlock(name, lockedErr.Info.ID)
+			if err != nil {
+				return fmt.Errorf("Unable to remove the stale lock of cluster manager '%s': %w", name, err)
+			}
+		} else {
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		err = locker.Lock(name, info)
+	}
+	if err != nil {
+		return err
+	}
+
+	backend.locks[name] = info
+	return nil
+}
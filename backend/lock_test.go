@@ -0,0 +1,180 @@
+package backend
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// lockerBackend is an in-memory backend that can be locked.
+type lockerBackend struct {
+	states map[string][]byte
+	locks  map[string]LockInfo
+	// Holders returned by Lock instead of the actual ones, as if the lock was taken over after
+	// its holder was read
+	readHolders map[string]LockInfo
+}
+
+func (b *lockerBackend) State(name string) (state.State, error) {
+	content, ok := b.states[name]
+	if !ok {
+		content = []byte("{}")
+	}
+	return state.New(name, content)
+}
+
+func (b *lockerBackend) DeleteState(name string) error {
+	delete(b.states, name)
+	return nil
+}
+
+func (b *lockerBackend) PersistState(currentState state.State) error {
+	b.states[currentState.Name] = currentState.Bytes()
+	return nil
+}
+
+func (b *lockerBackend) States() ([]string, error) {
+	names := []string{}
+	for name := range b.states {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (b *lockerBackend) StateTerraformConfig(name string) (string, interface{}) {
+	return "terraform.backend.local", nil
+}
+
+func (b *lockerBackend) Lock(name string, info LockInfo) error {
+	if holder, ok := b.locks[name]; ok {
+		if readHolder, ok := b.readHolders[name]; ok {
+			holder = readHolder
+		}
+		return &LockedError{Name: name, Info: holder}
+	}
+	b.locks[name] = info
+	return nil
+}
+
+func (b *lockerBackend) Unlock(name, id string) error {
+	if b.locks[name].ID == id {
+		delete(b.locks, name)
+	}
+	return nil
+}
+
+func (b *lockerBackend) ForceUnlock(name string) error {
+	delete(b.locks, name)
+	return nil
+}
+
+func TestLockingBackend(t *testing.T) {
+	remoteBackend := &lockerBackend{states: map[string][]byte{}, locks: map[string]LockInfo{}}
+
+	first := NewLockingBackend(remoteBackend, "create cluster", false)
+	currentState, err := first.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteBackend.locks["dev-manager"].Operation != "create cluster" {
+		t.Fatalf("Expected reading the state to lock it, got %v", remoteBackend.locks)
+	}
+
+	// Persisting a state already locked by the operation doesn't lock it again
+	err = first.PersistState(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another operation can't lock it, unless forced to
+	second := NewLockingBackend(remoteBackend, "scale nodepool dev-w", false)
+	_, err = second.State("dev-manager")
+	if _, ok := err.(*LockedError); !ok {
+		t.Fatalf("Expected a locked error, got %v", err)
+	}
+
+	forced := NewLockingBackend(remoteBackend, "destroy cluster", true)
+	_, err = forced.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteBackend.locks["dev-manager"].Operation != "destroy cluster" {
+		t.Fatalf("Expected the lock to be taken over, got %v", remoteBackend.locks)
+	}
+
+	// Unlocking the lock that was taken over leaves the new lock alone
+	err = first.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := remoteBackend.locks["dev-manager"]; !ok {
+		t.Fatal("Expected the lock of the forced operation to be kept")
+	}
+
+	err = forced.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remoteBackend.locks) != 0 {
+		t.Errorf("Expected no locks, got %v", remoteBackend.locks)
+	}
+}
+
+func TestLockingBackendStaleLock(t *testing.T) {
+	stale := NewLockInfo("create cluster")
+	stale.PID = 1 << 30
+	remoteBackend := &lockerBackend{states: map[string][]byte{}, locks: map[string]LockInfo{"dev-manager": stale}}
+
+	// The stale lock is taken over
+	first := NewLockingBackend(remoteBackend, "scale nodepool dev-w", false)
+	_, err := first.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteBackend.locks["dev-manager"].Operation != "scale nodepool dev-w" {
+		t.Fatalf("Expected the stale lock to be taken over, got %v", remoteBackend.locks)
+	}
+
+	// A lock taken over since its stale holder was read is left alone
+	remoteBackend.readHolders = map[string]LockInfo{"dev-manager": stale}
+	second := NewLockingBackend(remoteBackend, "destroy cluster", false)
+	_, err = second.State("dev-manager")
+	if _, ok := err.(*LockedError); !ok {
+		t.Fatalf("Expected a locked error, got %v", err)
+	}
+	if remoteBackend.locks["dev-manager"].Operation != "scale nodepool dev-w" {
+		t.Errorf("Expected the live lock to be kept, got %v", remoteBackend.locks)
+	}
+}
+
+func TestLockInfoIsStale(t *testing.T) {
+	host, _ := os.Hostname()
+
+	running := NewLockInfo("create cluster")
+	if running.IsStale() {
+		t.Error("Expected the lock of a running process not to be stale")
+	}
+
+	exited := NewLockInfo("create cluster")
+	exited.PID = 1 << 30
+	if !exited.IsStale() {
+		t.Error("Expected the lock of an exited process to be stale")
+	}
+
+	remote := LockInfo{ID: "abc", Host: host + "-other", PID: 1 << 30, Created: time.Now()}
+	if remote.IsStale() {
+		t.Error("Expected a recent lock of another host not to be stale")
+	}
+
+	remote.Created = time.Now().Add(-StaleLockAge - time.Minute)
+	if !remote.IsStale() {
+		t.Error("Expected an old lock of another host to be stale")
+	}
+
+	// The holder of the lock couldn't be read
+	if (LockInfo{}).IsStale() {
+		t.Error("Expected a lock without a holder not to be stale")
+	}
+}
@@ -3,8 +3,11 @@ package manta
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
@@ -22,6 +25,10 @@ const (
 	terraformStatePathFormat  = rootDirectory + "/%s/terraform.tfstate"
 
 	terraformBackendRootPathFormat = "/triton-kubernetes/%s"
+
+	// Lock objects aren't under the root directory, whose entries are the states
+	lockDirectory  = "/stor/triton-kubernetes-locks"
+	lockPathFormat = lockDirectory + "/%s.lock"
 )
 
 // Stores terraform json configuration files for all cluster managers in Manta
@@ -29,38 +36,97 @@ const (
 // and a terraform.tfstate file.
 // triton-kubernetes manages the main.tf.json file and terraform manages the terraform.tfstate file
 // Directory Path: /stor/triton-kubernetes/${CLUSTER_MANAGER_NAME}/main.tf.json
-// TODO: Lock terraform json configuration similar to how terraform locks tfstate file.
+// main.tf.json is locked with an object in /stor/triton-kubernetes-locks, which is only created
+// if it doesn't exist yet.
 type mantaBackend struct {
 	tritonAccount string
 	tritonKeyPath string
 	tritonKeyID   string
 	tritonURL     string
 	mantaURL      string
+	options       Options
 
 	tritonStorageClient *storage.StorageClient
 }
 
+// Options for Manta deployments other than the Joyent public cloud, e.g. an on-prem Manta
+// accessed by a subuser.
+type Options struct {
+	// Subuser of the account whose key signs the requests
+	User string
+	// Skips verifying the certificate of Manta, for lab installs with self-signed certificates
+	InsecureSkipTLSVerify bool
+	// Roles to assume for every request, instead of the default roles of the subuser
+	Roles []string
+	// Roles given access to the objects and directories created, so that every member of
+	// those roles can manage the cluster managers
+	RoleTags []string
+	// Manta auth token, e.g. a temporary token issued to a CI system, which authenticates
+	// requests instead of a key. Terraform doesn't support tokens, it signs its requests with
+	// the key of the key id held by the SSH agent.
+	Token string
+}
+
 type mantaTerraformBackendConfig struct {
-	Account     string `json:"account"`
-	KeyMaterial string `json:"key_material"`
-	KeyID       string `json:"key_id"`
-	Path        string `json:"path"`
+	Account               string `json:"account"`
+	User                  string `json:"user,omitempty"`
+	URL                   string `json:"url,omitempty"`
+	KeyMaterial           string `json:"key_material,omitempty"`
+	KeyID                 string `json:"key_id"`
+	InsecureSkipTLSVerify bool   `json:"insecure_skip_tls_verify,omitempty"`
+	Path                  string `json:"path"`
 }
 
-func New(tritonAccount, tritonKeyPath, tritonKeyID, tritonURL, mantaURL string) (backend.Backend, error) {
-	keyMaterial, err := ioutil.ReadFile(tritonKeyPath)
-	if err != nil {
-		return nil, err
-	}
+// tokenSigner authenticates requests with a Manta auth token instead of signing them.
+type tokenSigner struct {
+	token string
+}
 
-	privateKeySignerInput := authentication.PrivateKeySignerInput{
-		KeyID:              tritonKeyID,
-		PrivateKeyMaterial: keyMaterial,
-		AccountName:        tritonAccount,
-	}
-	sshKeySigner, err := authentication.NewPrivateKeySigner(privateKeySignerInput)
-	if err != nil {
-		return nil, err
+func (signer tokenSigner) DefaultAlgorithm() string {
+	return ""
+}
+
+func (signer tokenSigner) KeyFingerprint() string {
+	return ""
+}
+
+func (signer tokenSigner) Sign(dateHeader string) (string, error) {
+	return "Token " + signer.token, nil
+}
+
+func (signer tokenSigner) SignRaw(toSign string) (string, string, error) {
+	return "", "", errors.New("Manta auth tokens can't sign requests")
+}
+
+// roleTransport sends the Manta RBAC headers with every request.
+type roleTransport struct {
+	transport http.RoundTripper
+	roles     string
+	roleTags  string
+}
+
+// New returns the Manta backend. Requests are signed with the key at tritonKeyPath, unless
+// options.Token is set, in which case tritonKeyPath may be empty.
+func New(tritonAccount, tritonKeyPath, tritonKeyID, tritonURL, mantaURL string, options Options) (backend.Backend, error) {
+	var signer authentication.Signer
+	if options.Token != "" {
+		signer = tokenSigner{token: options.Token}
+	} else {
+		keyMaterial, err := ioutil.ReadFile(tritonKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		privateKeySignerInput := authentication.PrivateKeySignerInput{
+			KeyID:              tritonKeyID,
+			PrivateKeyMaterial: keyMaterial,
+			AccountName:        tritonAccount,
+			Username:           options.User,
+		}
+		signer, err = authentication.NewPrivateKeySigner(privateKeySignerInput)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Create manta client
@@ -68,13 +134,29 @@ func New(tritonAccount, tritonKeyPath, tritonKeyID, tritonURL, mantaURL string)
 		TritonURL:   tritonURL,
 		MantaURL:    mantaURL,
 		AccountName: tritonAccount,
-		Signers:     []authentication.Signer{sshKeySigner},
+		Username:    options.User,
+		Signers:     []authentication.Signer{signer},
 	}
 	tritonStorageClient, err := storage.NewClient(config)
 	if err != nil {
 		return nil, err
 	}
 
+	if options.InsecureSkipTLSVerify {
+		tritonStorageClient.Client.InsecureSkipTLSVerify()
+	}
+	if len(options.Roles) > 0 || len(options.RoleTags) > 0 {
+		transport := tritonStorageClient.Client.HTTPClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		tritonStorageClient.Client.HTTPClient.Transport = &roleTransport{
+			transport: transport,
+			roles:     strings.Join(options.Roles, ","),
+			roleTags:  strings.Join(options.RoleTags, ","),
+		}
+	}
+
 	// Create root directory if it doesn't exist
 	putDirInput := &storage.PutDirectoryInput{
 		DirectoryName: rootDirectory,
@@ -90,6 +172,7 @@ func New(tritonAccount, tritonKeyPath, tritonKeyID, tritonURL, mantaURL string)
 		tritonKeyID:         tritonKeyID,
 		tritonURL:           tritonURL,
 		mantaURL:            mantaURL,
+		options:             options,
 		tritonStorageClient: tritonStorageClient,
 	}, nil
 }
@@ -194,12 +277,120 @@ func (backend *mantaBackend) DeleteState(name string) error {
 }
 
 func (backend *mantaBackend) StateTerraformConfig(name string) (string, interface{}) {
+	// Terraform's Manta backend has no support for roles, the subuser's default roles apply.
+	// Without a key path, e.g. with a token, terraform signs with the SSH agent.
 	terraformBackendConfig := mantaTerraformBackendConfig{
-		Account:     backend.tritonAccount,
-		KeyMaterial: backend.tritonKeyPath,
-		KeyID:       backend.tritonKeyID,
-		Path:        fmt.Sprintf(terraformBackendRootPathFormat, name),
+		Account:               backend.tritonAccount,
+		User:                  backend.options.User,
+		URL:                   backend.mantaURL,
+		KeyMaterial:           backend.tritonKeyPath,
+		KeyID:                 backend.tritonKeyID,
+		InsecureSkipTLSVerify: backend.options.InsecureSkipTLSVerify,
+		Path:                  fmt.Sprintf(terraformBackendRootPathFormat, name),
 	}
 
 	return "terraform.backend.manta", terraformBackendConfig
 }
+
+func (b *mantaBackend) Lock(name string, info backend.LockInfo) error {
+	err := b.tritonStorageClient.Dir().Put(context.Background(), &storage.PutDirectoryInput{
+		DirectoryName: lockDirectory,
+	})
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	err = b.tritonStorageClient.Objects().Put(context.Background(), &storage.PutObjectInput{
+		ObjectPath:   fmt.Sprintf(lockPathFormat, name),
+		ContentType:  "application/json",
+		ObjectReader: bytes.NewReader(content),
+		Headers:      map[string]string{"If-None-Match": "*"},
+	})
+	if err != nil {
+		// TODO: Find a better way to determine this error
+		if strings.Contains(err.Error(), "PreconditionFailed") {
+			holder, _, _ := b.lockInfo(name)
+			return &backend.LockedError{Name: name, Info: holder}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Deletes the lock object only if it wasn't replaced since its holder was read.
+func (b *mantaBackend) Unlock(name, id string) error {
+	holder, etag, err := b.lockInfo(name)
+	if err != nil {
+		return err
+	}
+	if holder.ID != id {
+		return fmt.Errorf("Lock of cluster manager '%s' is held by %s@%s.", name, holder.User, holder.Host)
+	}
+
+	err = b.tritonStorageClient.Objects().Delete(context.Background(), &storage.DeleteObjectInput{
+		ObjectPath: fmt.Sprintf(lockPathFormat, name),
+		Headers:    map[string]string{"If-Match": etag},
+	})
+	if err != nil && strings.Contains(err.Error(), "PreconditionFailed") {
+		return fmt.Errorf("Lock of cluster manager '%s' was taken over.", name)
+	}
+	if err != nil && !strings.Contains(err.Error(), "ResourceNotFound") {
+		return err
+	}
+	return nil
+}
+
+func (b *mantaBackend) ForceUnlock(name string) error {
+	err := b.tritonStorageClient.Objects().Delete(context.Background(), &storage.DeleteObjectInput{
+		ObjectPath: fmt.Sprintf(lockPathFormat, name),
+	})
+	if err != nil && !strings.Contains(err.Error(), "ResourceNotFound") {
+		return err
+	}
+	return nil
+}
+
+// Returns the holder of the lock of a state and the etag of the lock object.
+func (b *mantaBackend) lockInfo(name string) (backend.LockInfo, string, error) {
+	info := backend.LockInfo{}
+	output, err := b.tritonStorageClient.Objects().Get(context.Background(), &storage.GetObjectInput{
+		ObjectPath: fmt.Sprintf(lockPathFormat, name),
+	})
+	if err != nil {
+		return info, "", err
+	}
+	defer output.ObjectReader.Close()
+
+	err = json.NewDecoder(output.ObjectReader).Decode(&info)
+	return info, output.ETag, err
+}
+
+func (t *roleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrip must not modify the request
+	req = req.WithContext(req.Context())
+	req.Header = cloneHeader(req.Header)
+
+	if t.roles != "" {
+		req.Header.Set("Role", t.roles)
+	}
+	// Role tags are set on what is created, they are ignored on other requests
+	if t.roleTags != "" && req.Method == http.MethodPut {
+		req.Header.Set("Role-Tag", t.roleTags)
+	}
+
+	return t.transport.RoundTrip(req)
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
@@ -0,0 +1,95 @@
+package manta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoleTransport(t *testing.T) {
+	received := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received[r.Method] = r.Header
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &roleTransport{
+			transport: http.DefaultTransport,
+			roles:     "operators,auditors",
+			roleTags:  "operators",
+		},
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		req, err := http.NewRequest(method, server.URL+"/account/stor/triton-kubernetes", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if req.Header.Get("Role") != "" {
+			t.Errorf("%s: the request was modified", method)
+		}
+	}
+
+	if received[http.MethodGet].Get("Role") != "operators,auditors" || received[http.MethodPut].Get("Role") != "operators,auditors" {
+		t.Errorf("Wrong output, expected the Role header on every request, received %v", received)
+	}
+	if received[http.MethodGet].Get("Role-Tag") != "" {
+		t.Errorf("Wrong output, expected no Role-Tag header on GET, received %s", received[http.MethodGet].Get("Role-Tag"))
+	}
+	if received[http.MethodPut].Get("Role-Tag") != "operators" {
+		t.Errorf("Wrong output, expected the Role-Tag header on PUT, received %s", received[http.MethodPut].Get("Role-Tag"))
+	}
+}
+
+func TestStateTerraformConfig(t *testing.T) {
+	backend := &mantaBackend{
+		tritonAccount: "myaccount",
+		tritonKeyPath: "/home/me/.ssh/id_rsa",
+		tritonKeyID:   "2c:53:bc:63:97:9e:79:3f:91:35:5e:f4:c8:23:88:37",
+		mantaURL:      "https://manta.lab.example.com",
+		options:       Options{User: "ops", InsecureSkipTLSVerify: true},
+	}
+
+	path, config := backend.StateTerraformConfig("dev-manager")
+	if path != "terraform.backend.manta" {
+		t.Errorf("Wrong output, expected terraform.backend.manta, received %s", path)
+	}
+
+	expected := mantaTerraformBackendConfig{
+		Account:               "myaccount",
+		User:                  "ops",
+		URL:                   "https://manta.lab.example.com",
+		KeyMaterial:           "/home/me/.ssh/id_rsa",
+		KeyID:                 "2c:53:bc:63:97:9e:79:3f:91:35:5e:f4:c8:23:88:37",
+		InsecureSkipTLSVerify: true,
+		Path:                  "/triton-kubernetes/dev-manager",
+	}
+	if config != expected {
+		t.Errorf("Wrong output, expected %+v, received %+v", expected, config)
+	}
+}
+
+func TestTokenSigner(t *testing.T) {
+	signer := tokenSigner{token: "c2VjcmV0"}
+
+	header, err := signer.Sign("Mon, 02 Jan 2006 15:04:05 GMT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != "Token c2VjcmV0" {
+		t.Errorf("Wrong output, expected Token c2VjcmV0, received %s", header)
+	}
+
+	_, _, err = signer.SignRaw("content")
+	if err == nil {
+		t.Error("Expected tokens not to sign raw content")
+	}
+}
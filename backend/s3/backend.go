@@ -0,0 +1,393 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	defaultPrefix = "triton-kubernetes"
+
+	// Objects are stored without an encryption header, and encrypted by the default encryption of
+	// the bucket, if it has one
+	SSENone = "none"
+
+	terraformConfigKeyFormat = "%s/%s/main.tf.json"
+	terraformStateKeyFormat  = "%s/%s/terraform.tfstate"
+)
+
+// Stores terraform json configuration files for all cluster managers in an S3 bucket.
+// Each cluster manager has a separate prefix with a main.tf.json object and a terraform.tfstate
+// object, mirroring the layout of the local and manta backends.
+// triton-kubernetes manages the main.tf.json object and terraform manages the terraform.tfstate
+// object. With a DynamoDB table, main.tf.json is locked the same way terraform locks its state,
+// in the same table.
+// Object Key: ${PREFIX}/${CLUSTER_MANAGER_NAME}/main.tf.json
+type s3Backend struct {
+	bucket  string
+	region  string
+	options Options
+
+	s3Client       *awss3.S3
+	dynamoDBClient *dynamodb.DynamoDB
+
+	// IDs of the locks held, by state name
+	heldLocks map[string]string
+}
+
+// Options of the S3 backend.
+type Options struct {
+	// Key prefix of the cluster managers in the bucket, defaults to triton-kubernetes
+	Prefix string
+	// Credentials of an IAM user, the ambient AWS credentials are used when they're empty
+	AccessKey string
+	SecretKey string
+	// DynamoDB table to lock the states with, which must have a string hash key named LockID
+	DynamoDBTable string
+	// Endpoint of an S3 compatible service, addressed with path style URLs
+	Endpoint string
+	// Server side encryption of the objects, AES256, aws:kms or none, defaults to AES256, or to
+	// aws:kms with a KMSKeyID
+	SSE string
+	// ID or ARN of the KMS key objects are encrypted with, required by aws:kms as terraform
+	// can't encrypt its state with the AWS managed key
+	KMSKeyID string
+}
+
+type s3TerraformBackendConfig struct {
+	Bucket         string `json:"bucket"`
+	Key            string `json:"key"`
+	Region         string `json:"region"`
+	Encrypt        bool   `json:"encrypt"`
+	KMSKeyID       string `json:"kms_key_id,omitempty"`
+	DynamoDBTable  string `json:"dynamodb_table,omitempty"`
+	Endpoint       string `json:"endpoint,omitempty"`
+	ForcePathStyle bool   `json:"force_path_style,omitempty"`
+}
+
+func New(bucket, region string, options Options) (backend.Backend, error) {
+	if options.Prefix == "" {
+		options.Prefix = defaultPrefix
+	}
+	options.Prefix = strings.Trim(options.Prefix, "/")
+
+	if options.SSE == "" && options.KMSKeyID != "" {
+		options.SSE = awss3.ServerSideEncryptionAwsKms
+	} else if options.SSE == "" {
+		options.SSE = awss3.ServerSideEncryptionAes256
+	}
+	switch options.SSE {
+	case awss3.ServerSideEncryptionAes256, SSENone:
+		if options.KMSKeyID != "" {
+			return nil, fmt.Errorf("A KMS key can't be used with %s server side encryption, only with %s.", options.SSE, awss3.ServerSideEncryptionAwsKms)
+		}
+	case awss3.ServerSideEncryptionAwsKms:
+		if options.KMSKeyID == "" {
+			return nil, fmt.Errorf("%s server side encryption requires a KMS key.", awss3.ServerSideEncryptionAwsKms)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown server side encryption '%s', use %s, %s or %s.", options.SSE, awss3.ServerSideEncryptionAes256, awss3.ServerSideEncryptionAwsKms, SSENone)
+	}
+
+	awsConfig := aws.NewConfig().WithRegion(region)
+	if options.AccessKey != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(options.AccessKey, options.SecretKey, ""))
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s3Config := aws.NewConfig()
+	if options.Endpoint != "" {
+		s3Config = s3Config.WithEndpoint(options.Endpoint).WithS3ForcePathStyle(true)
+	}
+
+	b := &s3Backend{
+		bucket:   bucket,
+		region:   region,
+		options:  options,
+		s3Client: awss3.New(sess, s3Config),
+	}
+	if options.DynamoDBTable != "" {
+		b.dynamoDBClient = dynamodb.New(sess)
+	}
+
+	// Fail early if the bucket doesn't exist or isn't accessible
+	_, err = b.s3Client.HeadBucket(&awss3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to access S3 bucket '%s': %v", bucket, err)
+	}
+
+	return b, nil
+}
+
+func (backend *s3Backend) States() ([]string, error) {
+	input := &awss3.ListObjectsV2Input{
+		Bucket:    aws.String(backend.bucket),
+		Prefix:    aws.String(backend.options.Prefix + "/"),
+		Delimiter: aws.String("/"),
+	}
+
+	states := []string{}
+	err := backend.s3Client.ListObjectsV2Pages(input, func(page *awss3.ListObjectsV2Output, lastPage bool) bool {
+		for _, commonPrefix := range page.CommonPrefixes {
+			name := strings.TrimPrefix(aws.StringValue(commonPrefix.Prefix), backend.options.Prefix+"/")
+			states = append(states, strings.TrimSuffix(name, "/"))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (backend *s3Backend) State(name string) (state.State, error) {
+	output, err := backend.s3Client.GetObject(&awss3.GetObjectInput{
+		Bucket: aws.String(backend.bucket),
+		Key:    aws.String(backend.configKey(name)),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == awss3.ErrCodeNoSuchKey {
+			// Since no state exists, lets create an empty one
+			return state.New(name, []byte("{}"))
+		}
+		return state.State{}, err
+	}
+	defer output.Body.Close()
+
+	currentConfigBytes, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	return state.New(name, currentConfigBytes)
+}
+
+func (backend *s3Backend) PersistState(state state.State) error {
+	unlock, err := backend.lock(state.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	input := &awss3.PutObjectInput{
+		Bucket:      aws.String(backend.bucket),
+		Key:         aws.String(backend.configKey(state.Name)),
+		ContentType: aws.String("application/json"),
+		Body:        bytes.NewReader(state.Bytes()),
+	}
+	if backend.options.SSE != SSENone {
+		input.ServerSideEncryption = aws.String(backend.options.SSE)
+	}
+	if backend.options.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(backend.options.KMSKeyID)
+	}
+
+	_, err = backend.s3Client.PutObject(input)
+	return err
+}
+
+func (backend *s3Backend) DeleteState(name string) error {
+	unlock, err := backend.lock(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Deleting the main.tf.json and terraform.tfstate objects, deleting a missing object succeeds
+	for _, key := range []string{backend.configKey(name), backend.terraformStateKey(name)} {
+		_, err := backend.s3Client.DeleteObject(&awss3.DeleteObjectInput{
+			Bucket: aws.String(backend.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (backend *s3Backend) StateTerraformConfig(name string) (string, interface{}) {
+	terraformBackendConfig := s3TerraformBackendConfig{
+		Bucket:         backend.bucket,
+		Key:            backend.terraformStateKey(name),
+		Region:         backend.region,
+		Encrypt:        backend.options.SSE != SSENone,
+		KMSKeyID:       backend.options.KMSKeyID,
+		DynamoDBTable:  backend.options.DynamoDBTable,
+		Endpoint:       backend.options.Endpoint,
+		ForcePathStyle: backend.options.Endpoint != "",
+	}
+
+	return "terraform.backend.s3", terraformBackendConfig
+}
+
+// StateTerraformEnv returns the keys of the IAM user for the s3 backend of terraform, so they're
+// never written to main.tf.json. Without them terraform uses the ambient AWS credentials too.
+func (backend *s3Backend) StateTerraformEnv(name string) []string {
+	if backend.options.AccessKey == "" {
+		return nil
+	}
+
+	return []string{
+		"AWS_ACCESS_KEY_ID=" + backend.options.AccessKey,
+		"AWS_SECRET_ACCESS_KEY=" + backend.options.SecretKey,
+	}
+}
+
+func (backend *s3Backend) configKey(name string) string {
+	return fmt.Sprintf(terraformConfigKeyFormat, backend.options.Prefix, name)
+}
+
+func (backend *s3Backend) terraformStateKey(name string) string {
+	return fmt.Sprintf(terraformStateKeyFormat, backend.options.Prefix, name)
+}
+
+// Locks the main.tf.json object of a cluster manager in the DynamoDB table, if there is one. The
+// lock ID follows terraform's bucket/key format, so locks are listed alongside terraform's.
+func (b *s3Backend) Lock(name string, info backend.LockInfo) error {
+	if b.dynamoDBClient == nil {
+		return nil
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.dynamoDBClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(b.options.DynamoDBTable),
+		Item: map[string]*dynamodb.AttributeValue{
+			"LockID": {S: aws.String(b.lockID(name))},
+			"Info":   {S: aws.String(string(content))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(LockID)"),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			holder, _ := b.lockInfo(name)
+			return &backend.LockedError{Name: name, Info: holder}
+		}
+		return err
+	}
+
+	if b.heldLocks == nil {
+		b.heldLocks = map[string]string{}
+	}
+	b.heldLocks[name] = info.ID
+	return nil
+}
+
+func (b *s3Backend) Unlock(name, id string) error {
+	if b.dynamoDBClient == nil {
+		return nil
+	}
+
+	// Only the lock with the given id is deleted
+	_, err := b.dynamoDBClient.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(b.options.DynamoDBTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"LockID": {S: aws.String(b.lockID(name))},
+		},
+		ConditionExpression:       aws.String("contains(Info, :id)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":id": {S: aws.String(id)}},
+	})
+	if err != nil {
+		return err
+	}
+
+	delete(b.heldLocks, name)
+	return nil
+}
+
+func (b *s3Backend) ForceUnlock(name string) error {
+	if b.dynamoDBClient == nil {
+		return nil
+	}
+
+	_, err := b.dynamoDBClient.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(b.options.DynamoDBTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"LockID": {S: aws.String(b.lockID(name))},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	delete(b.heldLocks, name)
+	return nil
+}
+
+// Locks the main.tf.json object of a cluster manager while it's changed, unless the lock is
+// already held, and returns the function that unlocks it.
+func (b *s3Backend) lock(name string) (func(), error) {
+	if _, ok := b.heldLocks[name]; ok || b.dynamoDBClient == nil {
+		return func() {}, nil
+	}
+
+	info := backend.NewLockInfo("save cluster manager " + name)
+	err := b.Lock(name, info)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := func() {
+		err := b.Unlock(name, info.ID)
+		if err != nil {
+			fmt.Printf("Unable to unlock cluster manager '%s', delete the item '%s' from DynamoDB table '%s': %v\n", name, b.lockID(name), b.options.DynamoDBTable, err)
+		}
+	}
+	return unlock, nil
+}
+
+// Returns the holder of the lock of a state. Locks taken by older versions only describe the
+// holder in text, which is kept as the user.
+func (b *s3Backend) lockInfo(name string) (backend.LockInfo, error) {
+	info := backend.LockInfo{}
+	output, err := b.dynamoDBClient.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(b.options.DynamoDBTable),
+		Key:            map[string]*dynamodb.AttributeValue{"LockID": {S: aws.String(b.lockID(name))}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return info, err
+	}
+
+	// The lock may have been released since
+	attribute, ok := output.Item["Info"]
+	if !ok {
+		return info, nil
+	}
+
+	content := aws.StringValue(attribute.S)
+	if json.Unmarshal([]byte(content), &info) != nil {
+		info.User = content
+	}
+	return info, nil
+}
+
+func (b *s3Backend) lockID(name string) string {
+	return fmt.Sprintf("%s/%s", b.bucket, b.configKey(name))
+}
@@ -0,0 +1,301 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+)
+
+// fakeAWS serves the S3 and DynamoDB calls of the backend from memory.
+type fakeAWS struct {
+	objects map[string][]byte
+	// Headers of the last put of each object
+	putHeaders map[string]http.Header
+	// Info of the locks, by lock ID
+	locks map[string]string
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		f.serveDynamoDB(w, r, target)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		prefix := r.URL.Query().Get("prefix")
+		names := map[string]bool{}
+		for objectKey := range f.objects {
+			if strings.HasPrefix(objectKey, prefix) {
+				names[strings.SplitN(strings.TrimPrefix(objectKey, prefix), "/", 2)[0]] = true
+			}
+		}
+		fmt.Fprint(w, `<ListBucketResult>`)
+		for name := range names {
+			fmt.Fprintf(w, `<CommonPrefixes><Prefix>%s%s/</Prefix></CommonPrefixes>`, prefix, name)
+		}
+		fmt.Fprint(w, `<IsTruncated>false</IsTruncated></ListBucketResult>`)
+	case r.Method == http.MethodHead:
+	case r.Method == http.MethodGet:
+		content, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		w.Write(content)
+	case r.Method == http.MethodPut:
+		content, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = content
+		if f.putHeaders != nil {
+			f.putHeaders[key] = r.Header
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeAWS) serveDynamoDB(w http.ResponseWriter, r *http.Request, target string) {
+	input := struct {
+		Item map[string]map[string]string
+		Key  map[string]map[string]string
+	}{}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	switch target {
+	case "DynamoDB_20120810.PutItem":
+		lockID := input.Item["LockID"]["S"]
+		if _, ok := f.locks[lockID]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException", "message": "The conditional request failed"}`)
+			return
+		}
+		f.locks[lockID] = input.Item["Info"]["S"]
+	case "DynamoDB_20120810.GetItem":
+		info, ok := f.locks[input.Key["LockID"]["S"]]
+		if ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"Item": map[string]interface{}{"Info": map[string]string{"S": info}}})
+			return
+		}
+	case "DynamoDB_20120810.DeleteItem":
+		delete(f.locks, input.Key["LockID"]["S"])
+	}
+	fmt.Fprint(w, `{}`)
+}
+
+func newTestBackend(t *testing.T, server *httptest.Server) *s3Backend {
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-west-2").
+		WithCredentials(credentials.NewStaticCredentials("access", "secret", "")).
+		WithEndpoint(server.URL).
+		WithDisableSSL(true).
+		WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &s3Backend{
+		bucket:         "bucket",
+		region:         "us-west-2",
+		options:        Options{Prefix: defaultPrefix, DynamoDBTable: "locks", SSE: awss3.ServerSideEncryptionAes256},
+		s3Client:       awss3.New(sess, aws.NewConfig().WithS3ForcePathStyle(true)),
+		dynamoDBClient: dynamodb.New(sess),
+	}
+}
+
+func TestBackend(t *testing.T) {
+	fake := &fakeAWS{objects: map[string][]byte{}, locks: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	b := newTestBackend(t, server)
+
+	// A cluster manager that doesn't exist has an empty state
+	emptyState, err := b.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(emptyState.Bytes()) != "{}" {
+		t.Errorf("Expected an empty state, got %s", emptyState.Bytes())
+	}
+
+	for _, name := range []string{"dev-manager", "prod-manager"} {
+		currentState, err := state.New(name, []byte(fmt.Sprintf(`{"module":{"cluster-manager":{"name":"%s"}}}`, name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = b.PersistState(currentState)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fake.locks) != 0 {
+		t.Errorf("Expected the locks to be released, got %v", fake.locks)
+	}
+
+	states, err := b.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(states)
+	if strings.Join(states, ",") != "dev-manager,prod-manager" {
+		t.Errorf("Unexpected states %v", states)
+	}
+
+	devState, err := b.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devState.Get("module.cluster-manager.name") != "dev-manager" {
+		t.Errorf("Unexpected state %s", devState.Bytes())
+	}
+
+	// Changes are refused while another operation holds the lock
+	fake.locks["bucket/triton-kubernetes/dev-manager/main.tf.json"] = `{"id":"other","user":"alice","host":"ops","operation":"scale nodepool dev-w"}`
+	err = b.PersistState(devState)
+	if lockedErr, ok := err.(*backend.LockedError); !ok || lockedErr.Info.User != "alice" || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("Expected a locked error, got %v", err)
+	}
+	delete(fake.locks, "bucket/triton-kubernetes/dev-manager/main.tf.json")
+
+	// Changes made while holding the lock don't lock again
+	info := backend.NewLockInfo("scale nodepool dev-w")
+	err = b.Lock("dev-manager", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.PersistState(devState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Unlock("dev-manager", info.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.locks) != 0 {
+		t.Errorf("Expected the locks to be released, got %v", fake.locks)
+	}
+
+	fake.objects["triton-kubernetes/dev-manager/terraform.tfstate"] = []byte("{}")
+	err = b.DeleteState("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.objects) != 1 {
+		t.Errorf("Expected only the prod-manager objects to be left, got %d objects", len(fake.objects))
+	}
+}
+
+func TestStateTerraformConfig(t *testing.T) {
+	b := &s3Backend{
+		bucket:  "bucket",
+		region:  "us-west-2",
+		options: Options{Prefix: "clusters", DynamoDBTable: "locks", AccessKey: "AKIA", SecretKey: "s3cret"},
+	}
+
+	path, config := b.StateTerraformConfig("dev-manager")
+	if path != "terraform.backend.s3" {
+		t.Errorf("Unexpected path %s", path)
+	}
+
+	expected := s3TerraformBackendConfig{
+		Bucket:        "bucket",
+		Key:           "clusters/dev-manager/terraform.tfstate",
+		Region:        "us-west-2",
+		Encrypt:       true,
+		DynamoDBTable: "locks",
+	}
+	if config != expected {
+		t.Errorf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestStateTerraformEnv(t *testing.T) {
+	b := &s3Backend{options: Options{AccessKey: "AKIA", SecretKey: "s3cret"}}
+	env := b.StateTerraformEnv("dev-manager")
+	if len(env) != 2 || env[0] != "AWS_ACCESS_KEY_ID=AKIA" || env[1] != "AWS_SECRET_ACCESS_KEY=s3cret" {
+		t.Errorf("Wrong output, expected the keys as AWS_ environment variables, received %v", env)
+	}
+
+	b = &s3Backend{}
+	if env := b.StateTerraformEnv("dev-manager"); len(env) != 0 {
+		t.Errorf("Expected no environment variables for the ambient credentials, received %v", env)
+	}
+}
+
+func TestPersistStateEncryption(t *testing.T) {
+	testCases := []struct {
+		options     Options
+		sse         string
+		kmsKeyID    string
+		tfEncrypted bool
+	}{
+		{Options{SSE: awss3.ServerSideEncryptionAes256}, "AES256", "", true},
+		{Options{SSE: awss3.ServerSideEncryptionAwsKms, KMSKeyID: "alias/states"}, "aws:kms", "alias/states", true},
+		{Options{SSE: SSENone}, "", "", false},
+	}
+
+	for _, tc := range testCases {
+		fake := &fakeAWS{objects: map[string][]byte{}, putHeaders: map[string]http.Header{}, locks: map[string]string{}}
+		server := httptest.NewServer(fake)
+
+		b := newTestBackend(t, server)
+		b.options.SSE = tc.options.SSE
+		b.options.KMSKeyID = tc.options.KMSKeyID
+
+		currentState, err := state.New("dev-manager", []byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = b.PersistState(currentState)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.Close()
+
+		headers := fake.putHeaders[b.configKey("dev-manager")]
+		if headers.Get("X-Amz-Server-Side-Encryption") != tc.sse || headers.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != tc.kmsKeyID {
+			t.Errorf("Wrong encryption headers for %+v, received %v", tc.options, headers)
+		}
+
+		_, config := b.StateTerraformConfig("dev-manager")
+		tfConfig := config.(s3TerraformBackendConfig)
+		if tfConfig.Encrypt != tc.tfEncrypted || tfConfig.KMSKeyID != tc.kmsKeyID {
+			t.Errorf("Wrong terraform backend encryption for %+v, received %+v", tc.options, tfConfig)
+		}
+	}
+}
+
+func TestNewEncryptionOptions(t *testing.T) {
+	testCases := []struct {
+		options  Options
+		expected string
+	}{
+		{Options{SSE: "aws:kms"}, "aws:kms server side encryption requires a KMS key."},
+		{Options{SSE: "AES256", KMSKeyID: "alias/states"}, "A KMS key can't be used with AES256 server side encryption, only with aws:kms."},
+		{Options{SSE: "kms"}, "Unknown server side encryption 'kms', use AES256, aws:kms or none."},
+	}
+
+	for _, tc := range testCases {
+		_, err := New("bucket", "us-west-2", tc.options)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Wrong error for %+v, expected %q, received %v", tc.options, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// TerraformBackendType returns the terraform backend type of a path returned by
+// StateTerraformConfig, e.g. s3 for `terraform.backend.s3`.
+func TerraformBackendType(tfBackendPath string) string {
+	return strings.TrimPrefix(tfBackendPath, "terraform.backend.")
+}
+
+// terraformConfigBackend sets the terraform backend block of every state read through the
+// backend it wraps, so terraform keeps its own state where the backend does even when the block
+// stored with the cluster manager is stale, e.g. after moving to another bucket or backend. The
+// states also get what terraform runs need from the backend, see State.SetTerraformRun.
+type terraformConfigBackend struct {
+	backend Backend
+}
+
+// NewTerraformConfigBackend wraps remoteBackend, configuring the terraform backend of its
+// states with remoteBackend's StateTerraformConfig.
+func NewTerraformConfigBackend(remoteBackend Backend) Backend {
+	return terraformConfigBackend{backend: remoteBackend}
+}
+
+func (backend terraformConfigBackend) State(name string) (state.State, error) {
+	currentState, err := backend.backend.State(name)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	err = currentState.SetTerraformBackendConfig(backend.backend.StateTerraformConfig(name))
+	if err != nil {
+		return state.State{}, err
+	}
+
+	currentState.SetTerraformRun(StateTerraformEnv(backend.backend, name), func(env []string) error {
+		return PrepareRun(backend.backend, name, env)
+	})
+
+	return currentState, nil
+}
+
+func (backend terraformConfigBackend) DeleteState(name string) error {
+	return backend.backend.DeleteState(name)
+}
+
+func (backend terraformConfigBackend) PersistState(currentState state.State) error {
+	return backend.backend.PersistState(currentState)
+}
+
+func (backend terraformConfigBackend) States() ([]string, error) {
+	return backend.backend.States()
+}
+
+func (backend terraformConfigBackend) StateTerraformConfig(name string) (string, interface{}) {
+	return backend.backend.StateTerraformConfig(name)
+}
+
+func (backend terraformConfigBackend) StateTerraformEnv(name string) []string {
+	return StateTerraformEnv(backend.backend, name)
+}
+
+func (backend terraformConfigBackend) PrepareRun(name string, env []string) error {
+	return PrepareRun(backend.backend, name, env)
+}
+
+func (backend terraformConfigBackend) StateVersions(name string) ([]StateVersion, error) {
+	return StateVersions(backend.backend, name)
+}
+
+func (backend terraformConfigBackend) StateAtVersion(name, version string) (state.State, error) {
+	return StateAtVersion(backend.backend, name, version)
+}
@@ -0,0 +1,33 @@
+package backend
+
+import (
+	"testing"
+)
+
+func TestTerraformConfigBackend(t *testing.T) {
+	remoteBackend := NewTerraformConfigBackend(&lockerBackend{
+		states: map[string][]byte{
+			"dev-manager": []byte(`{"terraform":{"backend":{"manta":{"path":"/triton-kubernetes/dev-manager"}}}}`),
+		},
+		locks: map[string]LockInfo{},
+	})
+
+	currentState, err := remoteBackend.State("dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backends := currentState.GetMap("terraform.backend")
+	if len(backends) != 1 {
+		t.Fatalf("Expected one terraform backend, received %v", backends)
+	}
+	if _, ok := backends["local"]; !ok {
+		t.Errorf("Expected the local terraform backend, received %v", backends)
+	}
+}
+
+func TestTerraformBackendType(t *testing.T) {
+	if output := TerraformBackendType("terraform.backend.s3"); output != "s3" {
+		t.Errorf("Wrong output, expected s3, received %s", output)
+	}
+}
@@ -0,0 +1,662 @@
+package tfc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	defaultHostname        = "app.terraform.io"
+	defaultWorkspacePrefix = "triton-kubernetes-"
+	defaultExecutionMode   = "local"
+
+	// Name of the terraform json configuration file in the configuration versions
+	configFileName = "main.tf.json"
+
+	// Environment variable of the workspace that held the main.tf.json of cluster managers saved
+	// before it was uploaded as a configuration version
+	configVariableKey = "TRITON_KUBERNETES_CONFIG"
+
+	jsonAPIContentType = "application/vnd.api+json"
+
+	configVersionPollInterval = 2 * time.Second
+	configVersionPollAttempts = 30
+)
+
+// Stores terraform json configuration files for all cluster managers in Terraform Cloud or
+// Terraform Enterprise. Each cluster manager has a workspace named ${PREFIX}${CLUSTER_MANAGER_NAME},
+// created when the cluster manager is first saved. triton-kubernetes uploads the main.tf.json as
+// a configuration version of the workspace every time it's saved, and terraform keeps its state
+// in the workspace, running plans and applies on this machine unless the execution mode is remote.
+type tfcBackend struct {
+	organization string
+	token        string
+	options      Options
+
+	// URL of the API, e.g. https://app.terraform.io/api/v2
+	apiURL string
+	client *http.Client
+
+	// Interval between the checks of a configuration version being processed
+	pollInterval time.Duration
+	// Environment last set on the workspace of each state, by state name
+	preparedEnv map[string]string
+}
+
+// Options of the Terraform Cloud backend.
+type Options struct {
+	// Hostname of Terraform Enterprise, defaults to app.terraform.io
+	Hostname string
+	// Prefix of the workspace names, defaults to triton-kubernetes-
+	WorkspacePrefix string
+	// Execution mode of the workspaces, remote or local, defaults to local
+	ExecutionMode string
+	// Environment variables set on every workspace as sensitive variables, as KEY=value, e.g.
+	// the credentials of the clouds for remote runs. TF_VAR_ variables are set as terraform
+	// variables.
+	EnvVars []string
+}
+
+type tfcTerraformBackendConfig struct {
+	Hostname     string                      `json:"hostname"`
+	Organization string                      `json:"organization"`
+	Workspaces   tfcTerraformWorkspaceConfig `json:"workspaces"`
+}
+
+type tfcTerraformWorkspaceConfig struct {
+	Name string `json:"name"`
+}
+
+type workspace struct {
+	ID         string `json:"id,omitempty"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Name          string `json:"name"`
+		ExecutionMode string `json:"execution-mode,omitempty"`
+	} `json:"attributes"`
+}
+
+type configurationVersion struct {
+	ID         string `json:"id,omitempty"`
+	Type       string `json:"type"`
+	Attributes struct {
+		AutoQueueRuns bool   `json:"auto-queue-runs"`
+		Status        string `json:"status,omitempty"`
+		UploadURL     string `json:"upload-url,omitempty"`
+	} `json:"attributes"`
+}
+
+type variable struct {
+	ID         string             `json:"id,omitempty"`
+	Type       string             `json:"type"`
+	Attributes variableAttributes `json:"attributes"`
+}
+
+type variableAttributes struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	// terraform or env
+	Category  string `json:"category"`
+	HCL       bool   `json:"hcl"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// apiError is an error response of the API.
+type apiError struct {
+	StatusCode int
+	Errors     []struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+func (err *apiError) Error() string {
+	messages := []string{}
+	for _, e := range err.Errors {
+		if e.Detail != "" {
+			messages = append(messages, e.Detail)
+		} else {
+			messages = append(messages, e.Title)
+		}
+	}
+	if len(messages) == 0 {
+		return fmt.Sprintf("Terraform Cloud returned status %d", err.StatusCode)
+	}
+	return fmt.Sprintf("Terraform Cloud returned status %d: %s", err.StatusCode, strings.Join(messages, ", "))
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+func New(organization, token string, options Options) (backend.Backend, error) {
+	hostname := options.Hostname
+	if hostname == "" {
+		hostname = defaultHostname
+	}
+
+	return newWithClient(organization, token, options, fmt.Sprintf("https://%s/api/v2", hostname), http.DefaultClient)
+}
+
+func newWithClient(organization, token string, options Options, apiURL string, client *http.Client) (backend.Backend, error) {
+	if options.Hostname == "" {
+		options.Hostname = defaultHostname
+	}
+	if options.WorkspacePrefix == "" {
+		options.WorkspacePrefix = defaultWorkspacePrefix
+	}
+	if options.ExecutionMode == "" {
+		options.ExecutionMode = defaultExecutionMode
+	}
+	if options.ExecutionMode != "remote" && options.ExecutionMode != "local" {
+		return nil, fmt.Errorf("Invalid tfc_execution_mode '%s', must be 'remote' or 'local'.", options.ExecutionMode)
+	}
+
+	b := &tfcBackend{
+		organization: organization,
+		token:        token,
+		options:      options,
+		apiURL:       apiURL,
+		client:       client,
+		pollInterval: configVersionPollInterval,
+		preparedEnv:  map[string]string{},
+	}
+
+	// Fail early if the organization doesn't exist or the token can't access it
+	err := b.do(http.MethodGet, "/organizations/"+url.PathEscape(organization), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to access Terraform Cloud organization '%s': %v", organization, err)
+	}
+
+	return b, nil
+}
+
+func (backend *tfcBackend) States() ([]string, error) {
+	states := []string{}
+	for page := 1; page != 0; {
+		query := url.Values{}
+		query.Set("search[name]", backend.options.WorkspacePrefix)
+		query.Set("page[number]", fmt.Sprint(page))
+		query.Set("page[size]", "100")
+
+		response := struct {
+			Data []workspace `json:"data"`
+			Meta struct {
+				Pagination struct {
+					NextPage int `json:"next-page"`
+				} `json:"pagination"`
+			} `json:"meta"`
+		}{}
+		err := backend.do(http.MethodGet, backend.workspacesPath()+"?"+query.Encode(), nil, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		// The search matches names containing the prefix anywhere
+		for _, ws := range response.Data {
+			if strings.HasPrefix(ws.Attributes.Name, backend.options.WorkspacePrefix) {
+				states = append(states, strings.TrimPrefix(ws.Attributes.Name, backend.options.WorkspacePrefix))
+			}
+		}
+		page = response.Meta.Pagination.NextPage
+	}
+
+	return states, nil
+}
+
+func (backend *tfcBackend) State(name string) (state.State, error) {
+	ws, err := backend.workspace(name)
+	if isNotFound(err) {
+		// Since no state exists, lets create an empty one
+		return state.New(name, []byte("{}"))
+	}
+	if err != nil {
+		return state.State{}, err
+	}
+
+	config, err := backend.latestConfig(ws.ID)
+	if err != nil {
+		return state.State{}, err
+	}
+	if config != nil {
+		return state.New(name, config)
+	}
+
+	configVariable, err := backend.variable(ws.ID, configVariableKey)
+	if err != nil {
+		return state.State{}, err
+	}
+	if configVariable != nil {
+		return state.New(name, []byte(configVariable.Attributes.Value))
+	}
+
+	// The workspace was created by terraform, the cluster manager wasn't saved yet
+	return state.New(name, []byte("{}"))
+}
+
+func (backend *tfcBackend) PersistState(state state.State) error {
+	ws, err := backend.ensureWorkspace(state.Name)
+	if err != nil {
+		return err
+	}
+
+	err = backend.uploadConfig(ws.ID, state.Bytes())
+	if err != nil {
+		return fmt.Errorf("Unable to upload the configuration of cluster manager '%s' to Terraform Cloud: %w", state.Name, err)
+	}
+
+	// Every run got the variable holding the configuration of older cluster managers
+	configVariable, err := backend.variable(ws.ID, configVariableKey)
+	if err != nil {
+		return err
+	}
+	if configVariable != nil {
+		err = backend.do(http.MethodDelete, fmt.Sprintf("/workspaces/%s/vars/%s", ws.ID, configVariable.ID), nil, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return backend.setVariables(ws.ID, envVariableAttributes(backend.options.EnvVars))
+}
+
+func (backend *tfcBackend) DeleteState(name string) error {
+	// Deleting the workspace deletes the terraform state, deleting a missing workspace succeeds
+	err := backend.do(http.MethodDelete, backend.workspacePath(name), nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (backend *tfcBackend) StateTerraformConfig(name string) (string, interface{}) {
+	terraformBackendConfig := tfcTerraformBackendConfig{
+		Hostname:     backend.options.Hostname,
+		Organization: backend.organization,
+		Workspaces: tfcTerraformWorkspaceConfig{
+			Name: backend.options.WorkspacePrefix + name,
+		},
+	}
+
+	return "terraform.backend.remote", terraformBackendConfig
+}
+
+// StateTerraformEnv returns the API token for the remote backend of terraform, which reads the
+// token of a host from TF_TOKEN_{host}, with the dots of the host as underscores and its dashes
+// as double underscores.
+func (backend *tfcBackend) StateTerraformEnv(name string) []string {
+	host := strings.NewReplacer(".", "_", "-", "__").Replace(backend.options.Hostname)
+	return []string{fmt.Sprintf("TF_TOKEN_%s=%s", host, backend.token)}
+}
+
+// PrepareRun sets env as sensitive variables of the workspace of the state when terraform runs in
+// Terraform Cloud, as remote runs don't get the environment terraform is run with. The API token
+// isn't set, remote runs have their own, and variables are only set again once they change.
+func (backend *tfcBackend) PrepareRun(name string, env []string) error {
+	if backend.options.ExecutionMode != "remote" {
+		return nil
+	}
+
+	tokenEnv := backend.StateTerraformEnv(name)
+	runEnv := []string{}
+	for _, entry := range env {
+		if !containsEntry(tokenEnv, entry) {
+			runEnv = append(runEnv, entry)
+		}
+	}
+	key := strings.Join(runEnv, "\n")
+	if len(runEnv) == 0 || backend.preparedEnv[name] == key {
+		return nil
+	}
+
+	ws, err := backend.ensureWorkspace(name)
+	if err != nil {
+		return err
+	}
+
+	err = backend.setVariables(ws.ID, envVariableAttributes(runEnv))
+	if err != nil {
+		return err
+	}
+
+	backend.preparedEnv[name] = key
+	return nil
+}
+
+func containsEntry(entries []string, entry string) bool {
+	for _, e := range entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the sensitive workspace variables of KEY=value environment variables. TF_VAR_{name}
+// variables stay environment variables too, terraform reads them as variables and the scripts
+// of the modules read the Rancher API token from them.
+func envVariableAttributes(env []string) []variableAttributes {
+	attributes := []variableAttributes{}
+	for _, entry := range env {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		attributes = append(attributes, variableAttributes{Key: parts[0], Value: parts[1], Category: "env", Sensitive: true})
+	}
+
+	return attributes
+}
+
+func (backend *tfcBackend) workspacesPath() string {
+	return fmt.Sprintf("/organizations/%s/workspaces", url.PathEscape(backend.organization))
+}
+
+func (backend *tfcBackend) workspacePath(name string) string {
+	return backend.workspacesPath() + "/" + url.PathEscape(backend.options.WorkspacePrefix+name)
+}
+
+func (backend *tfcBackend) workspace(name string) (workspace, error) {
+	response := struct {
+		Data workspace `json:"data"`
+	}{}
+	err := backend.do(http.MethodGet, backend.workspacePath(name), nil, &response)
+	if err != nil {
+		return workspace{}, err
+	}
+
+	return response.Data, nil
+}
+
+// Returns the workspace of a cluster manager, creating it if it doesn't exist yet. The
+// execution mode of an existing workspace is updated to the one of the options.
+func (backend *tfcBackend) ensureWorkspace(name string) (workspace, error) {
+	path := backend.workspacePath(name)
+	response := struct {
+		Data workspace `json:"data"`
+	}{}
+	err := backend.do(http.MethodGet, path, nil, &response)
+	if err != nil && !isNotFound(err) {
+		return workspace{}, err
+	}
+
+	input := workspace{Type: "workspaces"}
+	input.Attributes.Name = backend.options.WorkspacePrefix + name
+	input.Attributes.ExecutionMode = backend.options.ExecutionMode
+	body := map[string]interface{}{"data": input}
+
+	if err != nil {
+		err = backend.do(http.MethodPost, backend.workspacesPath(), body, &response)
+	} else if response.Data.Attributes.ExecutionMode != backend.options.ExecutionMode {
+		err = backend.do(http.MethodPatch, path, body, &response)
+	}
+	if err != nil {
+		return workspace{}, err
+	}
+
+	return response.Data, nil
+}
+
+func (backend *tfcBackend) variables(workspaceID string) ([]variable, error) {
+	response := struct {
+		Data []variable `json:"data"`
+	}{}
+	err := backend.do(http.MethodGet, fmt.Sprintf("/workspaces/%s/vars", workspaceID), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+// Returns the environment variable of a workspace with the given key, or nil if there is none.
+func (backend *tfcBackend) variable(workspaceID, key string) (*variable, error) {
+	variables, err := backend.variables(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range variables {
+		if v.Attributes.Key == key && v.Attributes.Category == "env" {
+			return &v, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Returns the main.tf.json of the newest uploaded configuration version of a workspace, or nil if
+// none was uploaded. The API lists the newest versions first.
+func (backend *tfcBackend) latestConfig(workspaceID string) ([]byte, error) {
+	response := struct {
+		Data []configurationVersion `json:"data"`
+	}{}
+	err := backend.do(http.MethodGet, fmt.Sprintf("/workspaces/%s/configuration-versions?page%%5Bsize%%5D=20", workspaceID), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, version := range response.Data {
+		if version.Attributes.Status != "uploaded" {
+			continue
+		}
+
+		// The download redirects to a signed URL of the archive, the token isn't sent there
+		archive, err := backend.send(http.MethodGet, fmt.Sprintf("%s/configuration-versions/%s/download", backend.apiURL, version.ID), "", nil, true)
+		if err != nil {
+			return nil, err
+		}
+
+		// Remote runs upload their working directory, which has the main.tf.json too
+		config, err := readConfigArchive(archive)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read configuration version '%s': %w", version.ID, err)
+		}
+		if config != nil {
+			return config, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Uploads config as a new configuration version of a workspace, which doesn't queue a run, and
+// waits for Terraform Cloud to process it.
+func (backend *tfcBackend) uploadConfig(workspaceID string, config []byte) error {
+	archive, err := configArchive(config)
+	if err != nil {
+		return err
+	}
+
+	input := configurationVersion{Type: "configuration-versions"}
+	input.Attributes.AutoQueueRuns = false
+	response := struct {
+		Data configurationVersion `json:"data"`
+	}{}
+	err = backend.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/configuration-versions", workspaceID), map[string]interface{}{"data": input}, &response)
+	if err != nil {
+		return err
+	}
+
+	// The upload URL is signed, it doesn't take the token
+	_, err = backend.send(http.MethodPut, response.Data.Attributes.UploadURL, "application/octet-stream", archive, false)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < configVersionPollAttempts; attempt++ {
+		err = backend.do(http.MethodGet, "/configuration-versions/"+response.Data.ID, nil, &response)
+		if err != nil {
+			return err
+		}
+
+		switch response.Data.Attributes.Status {
+		case "uploaded":
+			return nil
+		case "errored":
+			return fmt.Errorf("Terraform Cloud couldn't process configuration version '%s'", response.Data.ID)
+		}
+		time.Sleep(backend.pollInterval)
+	}
+
+	return fmt.Errorf("Timed out waiting for Terraform Cloud to process configuration version '%s'", response.Data.ID)
+}
+
+// Returns a tar.gz archive holding config as the main.tf.json.
+func configArchive(config []byte) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name:     configFileName,
+		Mode:     0600,
+		Size:     int64(len(config)),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = tarWriter.Write(config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = gzipWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Returns the main.tf.json of a tar.gz archive, or nil if it has none.
+func readConfigArchive(archive []byte) ([]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg && path.Clean(header.Name) == configFileName {
+			return ioutil.ReadAll(tarReader)
+		}
+	}
+}
+
+// Creates the variables of a workspace, or updates them if a variable with the same key and
+// category already exists.
+func (backend *tfcBackend) setVariables(workspaceID string, attributes []variableAttributes) error {
+	existing, err := backend.variables(workspaceID)
+	if err != nil {
+		return err
+	}
+
+	for _, attrs := range attributes {
+		body := map[string]interface{}{"data": variable{Type: "vars", Attributes: attrs}}
+
+		method, path := http.MethodPost, fmt.Sprintf("/workspaces/%s/vars", workspaceID)
+		for _, v := range existing {
+			if v.Attributes.Key == attrs.Key && v.Attributes.Category == attrs.Category {
+				method, path = http.MethodPatch, fmt.Sprintf("/workspaces/%s/vars/%s", workspaceID, v.ID)
+				body["data"] = variable{ID: v.ID, Type: "vars", Attributes: attrs}
+				break
+			}
+		}
+
+		err := backend.do(method, path, body, nil)
+		if err != nil {
+			return fmt.Errorf("Unable to set variable '%s' of Terraform Cloud workspace: %v", attrs.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// Sends a request to the API, encoding in and decoding the response into out as JSON when they
+// aren't nil.
+func (backend *tfcBackend) do(method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		content, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = content
+	}
+
+	content, err := backend.send(method, backend.apiURL+path, jsonAPIContentType, body, true)
+	if err != nil {
+		return err
+	}
+
+	if out != nil && len(content) > 0 {
+		return json.Unmarshal(content, out)
+	}
+	return nil
+}
+
+// Sends a request to a URL, with the API token when authorized, and returns the body of the
+// response.
+func (backend *tfcBackend) send(method, requestURL, contentType string, body []byte, authorized bool) ([]byte, error) {
+	req, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if authorized {
+		req.Header.Set("Authorization", "Bearer "+backend.token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := backend.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &apiError{StatusCode: resp.StatusCode}
+		json.Unmarshal(content, apiErr)
+		return nil, apiErr
+	}
+
+	return content, nil
+}
@@ -0,0 +1,450 @@
+package tfc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeTFC serves the workspace, configuration version and variable calls of the backend from
+// memory.
+type fakeTFC struct {
+	// Workspaces by name
+	workspaces map[string]*workspace
+	// Variables by workspace ID
+	variables map[string][]variable
+	// Configuration versions by workspace ID, newest first
+	configVersions map[string][]*configurationVersion
+	// Uploaded archives by configuration version ID
+	archives map[string][]byte
+	// Workspace names of the first page of the listing, the others are on the second page
+	firstPage []string
+}
+
+func newFakeTFC() *fakeTFC {
+	return &fakeTFC{
+		workspaces:     map[string]*workspace{},
+		variables:      map[string][]variable{},
+		configVersions: map[string][]*configurationVersion{},
+		archives:       map[string][]byte{},
+	}
+}
+
+func (f *fakeTFC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Archives are uploaded to signed URLs, without the token
+	if strings.HasPrefix(r.URL.Path, "/upload/") && r.Method == http.MethodPut {
+		id := strings.TrimPrefix(r.URL.Path, "/upload/")
+		f.archives[id], _ = ioutil.ReadAll(r.Body)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"errors": [{"status": "401", "title": "unauthorized"}]}`)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v2/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "organizations":
+		if parts[1] != "example" {
+			f.notFound(w)
+		}
+	case len(parts) == 3 && parts[2] == "workspaces" && r.Method == http.MethodGet:
+		page := r.URL.Query().Get("page[number]")
+		names := []string{}
+		for name := range f.workspaces {
+			if strings.Contains(name, r.URL.Query().Get("search[name]")) && (page == "1") == containsName(f.firstPage, name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		data := []workspace{}
+		for _, name := range names {
+			data = append(data, *f.workspaces[name])
+		}
+		var nextPage interface{}
+		if page == "1" {
+			nextPage = 2
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": data,
+			"meta": map[string]interface{}{"pagination": map[string]interface{}{"next-page": nextPage}},
+		})
+	case len(parts) == 3 && parts[2] == "workspaces" && r.Method == http.MethodPost:
+		input := struct{ Data workspace }{}
+		json.NewDecoder(r.Body).Decode(&input)
+		ws := input.Data
+		ws.ID = "ws-" + ws.Attributes.Name
+		f.workspaces[ws.Attributes.Name] = &ws
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": ws})
+	case len(parts) == 4 && parts[2] == "workspaces":
+		ws, ok := f.workspaces[parts[3]]
+		if !ok {
+			f.notFound(w)
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			input := struct{ Data workspace }{}
+			json.NewDecoder(r.Body).Decode(&input)
+			ws.Attributes.ExecutionMode = input.Data.Attributes.ExecutionMode
+		case http.MethodDelete:
+			delete(f.workspaces, parts[3])
+			delete(f.variables, ws.ID)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": ws})
+	case len(parts) == 3 && parts[0] == "workspaces" && parts[2] == "vars":
+		if r.Method == http.MethodPost {
+			input := struct{ Data variable }{}
+			json.NewDecoder(r.Body).Decode(&input)
+			input.Data.ID = fmt.Sprintf("var-%d", len(f.variables[parts[1]]))
+			f.variables[parts[1]] = append(f.variables[parts[1]], input.Data)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": f.variables[parts[1]]})
+	case len(parts) == 4 && parts[0] == "workspaces" && parts[2] == "vars" && r.Method == http.MethodPatch:
+		input := struct{ Data variable }{}
+		json.NewDecoder(r.Body).Decode(&input)
+		for i, v := range f.variables[parts[1]] {
+			if v.ID == parts[3] {
+				f.variables[parts[1]][i].Attributes = input.Data.Attributes
+			}
+		}
+	case len(parts) == 4 && parts[0] == "workspaces" && parts[2] == "vars" && r.Method == http.MethodDelete:
+		variables := []variable{}
+		for _, v := range f.variables[parts[1]] {
+			if v.ID != parts[3] {
+				variables = append(variables, v)
+			}
+		}
+		f.variables[parts[1]] = variables
+	case len(parts) == 3 && parts[0] == "workspaces" && parts[2] == "configuration-versions":
+		if r.Method == http.MethodPost {
+			input := struct{ Data configurationVersion }{}
+			json.NewDecoder(r.Body).Decode(&input)
+			version := input.Data
+			version.ID = fmt.Sprintf("cv-%d", len(f.archives))
+			version.Attributes.Status = "pending"
+			version.Attributes.UploadURL = fmt.Sprintf("https://%s/upload/%s", r.Host, version.ID)
+			f.archives[version.ID] = nil
+			f.configVersions[parts[1]] = append([]*configurationVersion{&version}, f.configVersions[parts[1]]...)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": version})
+			return
+		}
+		data := []configurationVersion{}
+		for _, version := range f.configVersions[parts[1]] {
+			data = append(data, *version)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	case len(parts) == 2 && parts[0] == "configuration-versions":
+		// Uploaded archives are processed by the time they're checked again
+		for _, versions := range f.configVersions {
+			for _, version := range versions {
+				if version.ID != parts[1] {
+					continue
+				}
+				if version.Attributes.Status == "pending" && f.archives[version.ID] != nil {
+					version.Attributes.Status = "uploaded"
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"data": version})
+				return
+			}
+		}
+		f.notFound(w)
+	case len(parts) == 3 && parts[0] == "configuration-versions" && parts[2] == "download":
+		archive, ok := f.archives[parts[1]]
+		if !ok {
+			f.notFound(w)
+			return
+		}
+		w.Write(archive)
+	default:
+		f.notFound(w)
+	}
+}
+
+func (f *fakeTFC) notFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"errors": [{"status": "404", "title": "not found"}]}`)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestBackend(t *testing.T, server *httptest.Server, options Options) *tfcBackend {
+	b, err := newWithClient("example", "token", options, server.URL+"/api/v2", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.(*tfcBackend).pollInterval = 0
+	return b.(*tfcBackend)
+}
+
+func TestBackend(t *testing.T) {
+	fake := newFakeTFC()
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+
+	b := newTestBackend(t, server, Options{Hostname: "app.terraform.io", EnvVars: []string{"AWS_ACCESS_KEY_ID=AKIAEXAMPLE", "TF_VAR_region=us-west-2"}})
+
+	// A cluster manager that wasn't saved yet has an empty state
+	currentState, err := b.State("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(currentState.Bytes()) != "{}" {
+		t.Errorf("Expected an empty state, got %s", currentState.Bytes())
+	}
+
+	err = currentState.Set("module.cluster-manager.name", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.PersistState(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saving again uploads a new configuration version
+	err = currentState.Set("module.cluster-manager.name", "dev-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.PersistState(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws, ok := fake.workspaces["triton-kubernetes-dev"]
+	if !ok || ws.Attributes.ExecutionMode != "local" {
+		t.Fatalf("Expected a local workspace, got %+v", ws)
+	}
+	versions := fake.configVersions[ws.ID]
+	if len(versions) != 2 || versions[0].Attributes.Status != "uploaded" || versions[0].Attributes.AutoQueueRuns {
+		t.Fatalf("Expected 2 uploaded configuration versions without runs, got %+v", versions)
+	}
+	config, err := readConfigArchive(fake.archives[versions[0].ID])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(config) != string(currentState.Bytes()) {
+		t.Errorf("Expected the main.tf.json %s, got %s", currentState.Bytes(), config)
+	}
+	// The configuration isn't kept in a variable
+	expected := []variableAttributes{
+		{Key: "AWS_ACCESS_KEY_ID", Value: "AKIAEXAMPLE", Category: "env", Sensitive: true},
+		{Key: "TF_VAR_region", Value: "us-west-2", Category: "env", Sensitive: true},
+	}
+	variables := []variableAttributes{}
+	for _, v := range fake.variables[ws.ID] {
+		variables = append(variables, v.Attributes)
+	}
+	if !reflect.DeepEqual(variables, expected) {
+		t.Errorf("Expected variables %+v, got %+v", expected, variables)
+	}
+
+	savedState, err := b.State("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedState.Get("module.cluster-manager.name") != "dev-2" {
+		t.Errorf("Expected the saved state, got %s", savedState.Bytes())
+	}
+
+	// Workspaces of other tools, or with the prefix elsewhere in their name, aren't cluster managers
+	fake.workspaces["network"] = &workspace{ID: "ws-network"}
+	fake.workspaces["network"].Attributes.Name = "network"
+	fake.workspaces["old-triton-kubernetes-prod"] = &workspace{ID: "ws-old"}
+	fake.workspaces["old-triton-kubernetes-prod"].Attributes.Name = "old-triton-kubernetes-prod"
+	fake.workspaces["triton-kubernetes-prod"] = &workspace{ID: "ws-prod"}
+	fake.workspaces["triton-kubernetes-prod"].Attributes.Name = "triton-kubernetes-prod"
+	fake.firstPage = []string{"triton-kubernetes-prod"}
+
+	states, err := b.States()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(states, []string{"prod", "dev"}) {
+		t.Errorf("Expected the states of both pages, got %v", states)
+	}
+
+	err = b.DeleteState("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.workspaces["triton-kubernetes-dev"]; ok {
+		t.Error("Expected the workspace to be deleted")
+	}
+	err = b.DeleteState("dev")
+	if err != nil {
+		t.Errorf("Expected deleting a missing workspace to succeed, got %v", err)
+	}
+}
+
+func TestLegacyConfigVariable(t *testing.T) {
+	fake := newFakeTFC()
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+
+	b := newTestBackend(t, server, Options{})
+	ws, err := b.ensureWorkspace("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.setVariables(ws.ID, []variableAttributes{{Key: configVariableKey, Value: `{"module": {"cluster-manager": {"name": "dev"}}}`, Category: "env"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cluster managers saved before configuration versions are read from the variable
+	currentState, err := b.State("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if currentState.Get("module.cluster-manager.name") != "dev" {
+		t.Fatalf("Expected the state of the variable, got %s", currentState.Bytes())
+	}
+
+	// and the variable is deleted once they're saved again
+	err = b.PersistState(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.variables[ws.ID]) != 0 {
+		t.Errorf("Expected the variable to be deleted, got %+v", fake.variables[ws.ID])
+	}
+	savedState, err := b.State("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if savedState.Get("module.cluster-manager.name") != "dev" {
+		t.Errorf("Expected the saved state, got %s", savedState.Bytes())
+	}
+}
+
+func TestPrepareRun(t *testing.T) {
+	fake := newFakeTFC()
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+
+	env := []string{"TF_VAR_rancher_access_key=token-abc", "TF_VAR_rancher_secret_key=secret", "TF_TOKEN_app_terraform_io=token"}
+
+	// Local runs get the environment terraform is run with
+	b := newTestBackend(t, server, Options{})
+	err := b.PrepareRun("dev", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.workspaces) != 0 {
+		t.Errorf("Expected no workspace for local runs, got %+v", fake.workspaces)
+	}
+
+	b = newTestBackend(t, server, Options{ExecutionMode: "remote"})
+	err = b.PrepareRun("dev", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws := fake.workspaces["triton-kubernetes-dev"]
+	if ws == nil || ws.Attributes.ExecutionMode != "remote" {
+		t.Fatalf("Expected a remote workspace, got %+v", ws)
+	}
+	// Remote runs have their own API token
+	expected := []variableAttributes{
+		{Key: "TF_VAR_rancher_access_key", Value: "token-abc", Category: "env", Sensitive: true},
+		{Key: "TF_VAR_rancher_secret_key", Value: "secret", Category: "env", Sensitive: true},
+	}
+	variables := []variableAttributes{}
+	for _, v := range fake.variables[ws.ID] {
+		variables = append(variables, v.Attributes)
+	}
+	if !reflect.DeepEqual(variables, expected) {
+		t.Errorf("Expected variables %+v, got %+v", expected, variables)
+	}
+
+	// Variables are only set again once they change
+	fake.variables[ws.ID][0].Attributes.Value = "changed"
+	err = b.PrepareRun("dev", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.variables[ws.ID][0].Attributes.Value != "changed" {
+		t.Errorf("Expected the unchanged environment to be skipped, got %+v", fake.variables[ws.ID])
+	}
+	err = b.PrepareRun("dev", []string{"TF_VAR_rancher_access_key=token-def", "TF_VAR_rancher_secret_key=secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.variables[ws.ID][0].Attributes.Value != "token-def" {
+		t.Errorf("Expected the new environment to be set, got %+v", fake.variables[ws.ID])
+	}
+}
+
+func TestStateTerraformEnv(t *testing.T) {
+	b := &tfcBackend{token: "token", options: Options{Hostname: "tfe.my-example.com"}}
+
+	env := b.StateTerraformEnv("dev")
+	expected := []string{"TF_TOKEN_tfe_my__example_com=token"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %v, got %v", expected, env)
+	}
+}
+
+func TestStateTerraformConfig(t *testing.T) {
+	b := &tfcBackend{
+		organization: "example",
+		token:        "token",
+		options:      Options{Hostname: "tfe.example.com", WorkspacePrefix: "k8s-"},
+	}
+
+	path, config := b.StateTerraformConfig("dev")
+	if path != "terraform.backend.remote" {
+		t.Errorf("Expected the remote backend, got %s", path)
+	}
+	content, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The token is passed through the environment, see StateTerraformEnv
+	expected := `{"hostname":"tfe.example.com","organization":"example","workspaces":{"name":"k8s-dev"}}`
+	if string(content) != expected {
+		t.Errorf("Expected %s, got %s", expected, content)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	fake := newFakeTFC()
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+
+	testCases := []struct {
+		organization string
+		token        string
+		options      Options
+		expected     string
+	}{
+		{"example", "token", Options{ExecutionMode: "agent"}, "Invalid tfc_execution_mode 'agent', must be 'remote' or 'local'."},
+		{"missing", "token", Options{}, "Unable to access Terraform Cloud organization 'missing': Terraform Cloud returned status 404: not found"},
+		{"example", "wrong", Options{}, "Unable to access Terraform Cloud organization 'example': Terraform Cloud returned status 401: unauthorized"},
+	}
+	for _, tc := range testCases {
+		_, err := newWithClient(tc.organization, tc.token, tc.options, server.URL+"/api/v2", server.Client())
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Expected error %q, got %v", tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joyent/triton-kubernetes/agent"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run the operations scheduled in the config",
+	Long: `Agent runs the jobs of the schedule section of the config, e.g. nightly etcd snapshots
+or weekly node image upgrades, until it is stopped. Each job has a cron expression, an
+operation and the settings of that operation. Jobs never prompt.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return errors.New(`"triton-kubernetes agent" accepts no arguments`)
+		}
+		return nil
+	},
+	Run: agentCmdFunc,
+}
+
+func agentCmdFunc(cmd *cobra.Command, args []string) {
+	// Nobody answers prompts of a background process
+	viper.Set("non-interactive", true)
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	err = agent.Run(config.Global(), remoteBackend, stop)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/app"
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// appCmd represents the app command
+var appCmd = &cobra.Command{
+	Use:   "app [list or install or upgrade or remove] [name]",
+	Short: "Manage Rancher catalog apps of a cluster",
+	Long: `App lists, installs, upgrades and removes Rancher catalog apps on a cluster
+through the API of its cluster manager.`,
+	ValidArgs: []string{"list", "install", "upgrade", "remove"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes app" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes app"`, args[0])
+	},
+	Run: appCmdFunc,
+}
+
+func appCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Terraform runs of the operation get the credentials of the backend
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	appAction := args[0]
+	switch appAction {
+	case "list":
+		err = app.ListApps(config.Global(), remoteBackend)
+	case "install":
+		err = app.InstallApp(config.Global(), remoteBackend, name)
+	case "upgrade":
+		err = app.UpgradeApp(config.Global(), remoteBackend, name)
+	case "remove":
+		err = app.RemoveApp(config.Global(), remoteBackend, name)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(appCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// buildCmd represents the build command
+var buildCmd = &cobra.Command{
+	Use:   "build [image] [image name]",
+	Short: "Build a golden node image with packer",
+	Long: `Build image runs packer to bake an image for the nodes of a cluster, with Docker
+installed and the Rancher agent image pulled, using the credentials and region of the
+cluster. The image is recorded under its name, nodes created with golden_image set to
+that name run it and start much faster.`,
+	ValidArgs: []string{"image"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes build" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes build"`, args[0])
+	},
+	Run: buildCmdFunc,
+}
+
+func buildCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		return create.BuildImage(config.Global(), b, name)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(buildCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/config"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config [init] [path]",
+	Short: "Generate a config file for non-interactive mode",
+	Long: `Config init asks which backend, resource and cloud provider the config is for and
+writes a complete, commented YAML config that can be used with --non-interactive.
+Secrets are written as ${VAR} placeholders that are read from the environment.`,
+	ValidArgs: []string{"init"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes config" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes config"`, args[0])
+	},
+	Run: configCmdFunc,
+}
+
+func configCmdFunc(cmd *cobra.Command, args []string) {
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	}
+
+	err := config.InitConfig(path)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
@@ -3,21 +3,44 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"os"
 
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/create"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // createCmd represents the create command
 var createCmd = &cobra.Command{
-	Use:       "create [manager or cluster or node]",
-	Short:     "Create cluster managers, kubernetes clusters or individual kubernetes cluster nodes.",
-	Long:      `Create allows you to create a new cluster manager or a new kubernetes cluster or an individual kubernetes cluster node.`,
-	ValidArgs: []string{"manager", "cluster", "node"},
+	Use:   "create [manager or cluster or node or cluster-template]",
+	Short: "Create cluster managers, kubernetes clusters, individual kubernetes cluster nodes or cluster templates.",
+	Long: `Create allows you to create a new cluster manager or a new kubernetes cluster or an individual kubernetes cluster node.
+
+Without an argument, create reads an environment spec from the config file: a cluster manager
+under "manager" and its clusters and their nodes under "clusters", which are created in that
+order without prompting.
+
+With --quickstart, create only asks for a cloud provider, a name and credentials, then creates
+a small development environment with opinionated defaults: a cluster manager and a cluster
+with one node of each role, on the smallest machines that can run them, from Ubuntu LTS images.
+
+create cluster-template creates a Rancher cluster template, also known as an RKE template, in
+a cluster manager from the cluster config in cluster_template_file, or adds a revision to an
+existing one. Clusters created with cluster_template get their Kubernetes config from it.`,
+	ValidArgs: []string{"manager", "cluster", "node", "cluster-template"},
 	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && create.IsEnvironmentSpec(config.Global()) {
+			return nil
+		}
+		if quickstart, _ := cmd.Flags().GetBool("quickstart"); quickstart {
+			if len(args) != 0 {
+				return errors.New(`"triton-kubernetes create --quickstart" takes no arguments`)
+			}
+			return nil
+		}
 		if len(args) != 1 {
 			return errors.New(`"triton-kubernetes create" requires one argument`)
 		}
@@ -34,34 +57,73 @@ var createCmd = &cobra.Command{
 }
 
 func createCmdFunc(cmd *cobra.Command, args []string) {
+	// Both create and scale have an --ignore-budget flag, bind the one being run
+	viper.BindPFlag("ignore_budget", cmd.Flags().Lookup("ignore-budget"))
+	viper.BindPFlag("plan_only", cmd.Flags().Lookup("plan-only"))
+	// Only override node_count when given, so the config file or prompt sets it otherwise
+	if cmd.Flags().Changed("count") {
+		count, _ := cmd.Flags().GetInt("count")
+		viper.Set("node_count", count)
+	}
+
 	remoteBackend, err := util.PromptForBackend()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(err)
+	}
+
+	if quickstart, _ := cmd.Flags().GetBool("quickstart"); quickstart {
+		fmt.Println("create quickstart called")
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return create.NewQuickstart(config.Global(), b)
+		})
+		if err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	if len(args) == 0 {
+		fmt.Println("create environment called")
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return create.NewEnvironment(config.Global(), b)
+		})
+		if err != nil {
+			exitWithError(err)
+		}
+		return
 	}
 
 	createType := args[0]
 	switch createType {
 	case "manager":
 		fmt.Println("create manager called")
-		err := create.NewManager(remoteBackend)
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return create.NewManager(config.Global(), b)
+		})
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	case "cluster":
 		fmt.Println("create cluster called")
-		err := create.NewCluster(remoteBackend)
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return create.NewCluster(config.Global(), b)
+		})
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	case "node":
 		fmt.Println("create node called")
-		err := create.NewNode(remoteBackend)
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return create.NewNode(config.Global(), b)
+		})
+		if err != nil {
+			exitWithError(err)
+		}
+	case "cluster-template":
+		fmt.Println("create cluster-template called")
+		err := create.NewClusterTemplate(config.Global(), backend.NewTerraformConfigBackend(remoteBackend))
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	}
 }
@@ -80,5 +142,9 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// createCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	createCmd.Flags().Bool("ignore-budget", false, "Create nodes even if the estimated monthly cost exceeds the cluster's budget")
+	createCmd.Flags().Bool("plan-only", false, "Show the terraform plan without applying it")
+	createCmd.Flags().Int("count", 0, "Number of nodes to create in one terraform run, the same as node_count")
+	createCmd.Flags().Bool("quickstart", false, "Create a small development cluster manager and cluster, only asking for a cloud provider, a name and credentials")
 
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/describe"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe [manager or cluster or node] [name]",
+	Short: "Display full details of a single resource",
+	Long: `Describe prints every stored config field, the terraform outputs, the creation
+timestamp and live cloud provider facts of a cluster manager, cluster or node.`,
+	ValidArgs: []string{"manager", "cluster", "node"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes describe" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes describe"`, args[0])
+	},
+	Run: describeCmdFunc,
+}
+
+func describeCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Terraform runs of the operation get the credentials of the backend
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	describeType := args[0]
+	switch describeType {
+	case "manager":
+		err = describe.DescribeManager(config.Global(), remoteBackend, name)
+	case "cluster":
+		err = describe.DescribeCluster(config.Global(), remoteBackend, name)
+	case "node":
+		err = describe.DescribeNode(config.Global(), remoteBackend, name)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+}
@@ -3,12 +3,14 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"os"
 
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/destroy"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // destroyCmd represents the destroy command
@@ -34,34 +36,42 @@ var destroyCmd = &cobra.Command{
 }
 
 func destroyCmdFunc(cmd *cobra.Command, args []string) {
+	// Both destroy and scale have a --force flag, bind the one being run
+	viper.BindPFlag("force", cmd.Flags().Lookup("force"))
+	viper.BindPFlag("plan_only", cmd.Flags().Lookup("plan-only"))
+	viper.BindPFlag("dry_run", cmd.Flags().Lookup("dry-run"))
+	viper.BindPFlag("orphan_clusters", cmd.Flags().Lookup("orphan-clusters"))
+
 	remoteBackend, err := util.PromptForBackend()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
 	destroyType := args[0]
 	switch destroyType {
 	case "manager":
 		fmt.Println("destroy manager called")
-		err := destroy.DeleteManager(remoteBackend)
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return destroy.DeleteManager(config.Global(), b)
+		})
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	case "cluster":
 		fmt.Println("destroy cluster called")
-		err := destroy.DeleteCluster(remoteBackend)
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return destroy.DeleteCluster(config.Global(), b)
+		})
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	case "node":
 		fmt.Println("destroy node called")
-		err := destroy.DeleteNode(remoteBackend)
+		err := runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+			return destroy.DeleteNode(config.Global(), b)
+		})
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	}
 }
@@ -69,6 +79,11 @@ func destroyCmdFunc(cmd *cobra.Command, args []string) {
 func init() {
 	rootCmd.AddCommand(destroyCmd)
 
+	destroyCmd.Flags().Bool("force", false, "Destroy nodes even if it breaks etcd quorum or removes the last control plane node")
+	destroyCmd.Flags().Bool("plan-only", false, "Show the terraform plan without applying it")
+	destroyCmd.Flags().Bool("dry-run", false, "Show the resources that would be destroyed, by cluster manager, cluster and node, without destroying them")
+	destroyCmd.Flags().Bool("orphan-clusters", false, "Keep the clusters of the destroyed cluster manager running, saving their kubeconfigs")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
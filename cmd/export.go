@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/joyent/triton-kubernetes/archive"
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a cluster manager as a portable archive",
+	Long: `Export bundles a cluster manager into a single tar.gz: its generated terraform
+configuration, terraform's own state, a kubeconfig of each of its clusters and a manifest.
+Import restores it into another backend, e.g. for disaster recovery or to hand an environment
+over to another team. The archive holds the credentials of the cluster manager, keep it safe.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return errors.New(`"triton-kubernetes export" takes no arguments`)
+		}
+		return nil
+	},
+	Run: exportCmdFunc,
+}
+
+func exportCmdFunc(cmd *cobra.Command, args []string) {
+	viper.BindPFlag("export_file", cmd.Flags().Lookup("file"))
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Archives use the terraform backend of the backend they're exported from
+	err = archive.Export(config.Global(), backend.NewTerraformConfigBackend(remoteBackend))
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringP("file", "f", "", "Archive to write, {cluster manager}.tar.gz by default")
+}
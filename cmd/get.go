@@ -3,20 +3,29 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"os"
 
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/backend/cache"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/get"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // getCmd represents the get command
 var getCmd = &cobra.Command{
-	Use:       "get [manager or cluster]",
-	Short:     "Display resource information",
-	Long:      `Get allows you to get cluster manager details.`,
-	ValidArgs: []string{"manager", "cluster"},
+	Use:   "get [manager or cluster or clusters or nodes or tf-config or tf-backend or events or inventory]",
+	Short: "Display resource information",
+	Long: `Get allows you to get cluster manager details. Get clusters lists the clusters of a
+cluster manager and get nodes the nodes of a cluster, with their live state in Rancher, as a
+table, JSON or YAML. Get tf-config prints the terraform configuration of a cluster manager, as
+JSON or as human editable HCL. Get tf-backend prints only its terraform backend block, where
+the terraform state is kept. Get events lists the operations recorded in the journal of a
+cluster manager, newest first. Get inventory prints an Ansible inventory of the cluster
+managers and their nodes, as the JSON of a dynamic inventory script or as an ini file.`,
+	ValidArgs: []string{"manager", "cluster", "clusters", "nodes", "tf-config", "tf-backend", "events", "inventory"},
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) != 1 {
 			return errors.New(`"triton-kubernetes get" requires one argument`)
@@ -34,27 +43,69 @@ var getCmd = &cobra.Command{
 }
 
 func getCmdFunc(cmd *cobra.Command, args []string) {
+	viper.BindPFlag("tf_config_format", cmd.Flags().Lookup("format"))
+	viper.BindPFlag("inventory_format", cmd.Flags().Lookup("format"))
+	viper.BindPFlag("tf_config_dir", cmd.Flags().Lookup("output-dir"))
+	viper.BindPFlag("events_limit", cmd.Flags().Lookup("limit"))
+	viper.BindPFlag("get_output", cmd.Flags().Lookup("output"))
+
 	remoteBackend, err := util.PromptForBackend()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
+	// Reads are answered from the local cache when the backend is unreachable
+	remoteBackend, err = cache.New(config.Global(), remoteBackend)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Exported configs use the terraform backend of the backend they're read from
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
 	getType := args[0]
 	switch getType {
 	case "manager":
 		fmt.Println("get manager called")
-		err := get.GetManager(remoteBackend)
+		err := get.GetManager(config.Global(), remoteBackend)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	case "cluster":
 		fmt.Println("get cluster called")
-		err := get.GetCluster(remoteBackend)
+		err := get.GetCluster(config.Global(), remoteBackend)
+		if err != nil {
+			exitWithError(err)
+		}
+	case "clusters":
+		err := get.GetClusters(config.Global(), remoteBackend)
+		if err != nil {
+			exitWithError(err)
+		}
+	case "nodes":
+		err := get.GetNodes(config.Global(), remoteBackend)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitWithError(err)
+		}
+	case "tf-config":
+		err := get.GetTerraformConfig(config.Global(), remoteBackend)
+		if err != nil {
+			exitWithError(err)
+		}
+	case "tf-backend":
+		err := get.GetTerraformBackend(config.Global(), remoteBackend)
+		if err != nil {
+			exitWithError(err)
+		}
+	case "events":
+		err := get.GetEvents(config.Global(), remoteBackend)
+		if err != nil {
+			exitWithError(err)
+		}
+	case "inventory":
+		err := get.GetInventory(config.Global(), remoteBackend)
+		if err != nil {
+			exitWithError(err)
 		}
 	}
 }
@@ -62,6 +113,11 @@ func getCmdFunc(cmd *cobra.Command, args []string) {
 func init() {
 	rootCmd.AddCommand(getCmd)
 
+	getCmd.Flags().String("format", "json", "Format of tf-config and tf-backend, json or hcl, and of inventory, json or ini")
+	getCmd.Flags().String("output-dir", "", "Directory to write tf-config to, instead of printing it")
+	getCmd.Flags().Int("limit", 20, "Number of events to show, 0 for all")
+	getCmd.Flags().StringP("output", "o", "table", "Format of clusters and nodes, table, json or yaml")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"github.com/joyent/triton-kubernetes/access"
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// grantCmd represents the grant command
+var grantCmd = &cobra.Command{
+	Use:   "grant",
+	Short: "Grant a user or group a role in a cluster",
+	Long: `Grant gives a user or group of the cluster manager a cluster role, e.g. cluster-member,
+or a role in one of the cluster's projects, e.g. project-member with --project, through
+the Rancher API:
+
+    triton-kubernetes grant --cluster dev --user bob --role cluster-member
+
+Groups are given by name or by the principal id of the authentication provider, e.g.
+github_team://1234. Roles are given by id or by name.`,
+	Args: cobra.NoArgs,
+	Run:  grantCmdFunc,
+}
+
+func grantCmdFunc(cmd *cobra.Command, args []string) {
+	bindAccessFlags(cmd)
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Terraform runs of the operation get the credentials of the backend
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
+	err = access.Grant(config.Global(), remoteBackend)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+// Binds the flags of grant and revoke that were given, unset flags leave the config as is.
+func bindAccessFlags(cmd *cobra.Command) {
+	flags := map[string]string{
+		"cluster-manager": "cluster_manager",
+		"cluster":         "cluster_name",
+		"user":            "access_user",
+		"group":           "access_group",
+		"role":            "access_role",
+		"project":         "access_project",
+	}
+	for flag, key := range flags {
+		if cmd.Flags().Changed(flag) {
+			viper.BindPFlag(key, cmd.Flags().Lookup(flag))
+		}
+	}
+}
+
+func addAccessFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cluster-manager", "", "Cluster manager of the cluster")
+	cmd.Flags().String("cluster", "", "Name of the cluster")
+	cmd.Flags().String("user", "", "Username or principal id of the user")
+	cmd.Flags().String("group", "", "Name or principal id of the group")
+	cmd.Flags().String("role", "", "Cluster or project role, e.g. cluster-member or project-member")
+	cmd.Flags().String("project", "", "Project of a project role")
+}
+
+func init() {
+	rootCmd.AddCommand(grantCmd)
+
+	addAccessFlags(grantCmd)
+}
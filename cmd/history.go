@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/backend/cache"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/get"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the audit log of a cluster manager",
+	Long: `History shows the audit log of a cluster manager: every create, destroy, scale and other
+operation that changed it, with who ran it and when, its result, the exit status of terraform,
+what it changed and the diff of the configuration, with secrets redacted. The log is kept in
+the backend along with the state of the cluster manager.
+
+With --versions, it lists the previous versions of the configuration of the cluster manager
+instead, for backends that keep them (gcs with object versioning).`,
+	Args: cobra.NoArgs,
+	Run:  historyCmdFunc,
+}
+
+func historyCmdFunc(cmd *cobra.Command, args []string) {
+	viper.BindPFlag("events_limit", cmd.Flags().Lookup("limit"))
+	viper.BindPFlag("get_output", cmd.Flags().Lookup("output"))
+	viper.BindPFlag("history_versions", cmd.Flags().Lookup("versions"))
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Reads are answered from the local cache when the backend is unreachable
+	remoteBackend, err = cache.New(config.Global(), remoteBackend)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Terraform runs of the operation get the credentials of the backend
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
+	err = get.GetHistory(config.Global(), remoteBackend)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().Int("limit", 20, "Number of operations to show, 0 for all")
+	historyCmd.Flags().StringP("output", "o", "table", "Format of the log, table, json or yaml")
+	historyCmd.Flags().Bool("versions", false, "List the versions of the cluster manager kept by the backend")
+}
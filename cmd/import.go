@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/archive"
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [archive]",
+	Short: "Import a cluster manager from an archive made by export",
+	Long: `Import restores a cluster manager exported with "triton-kubernetes export" into the
+configured backend, under the name it was exported with, and saves the kubeconfigs of its
+clusters. No infrastructure is changed.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New(`"triton-kubernetes import" requires one argument`)
+		}
+		return nil
+	},
+	Run: importCmdFunc,
+}
+
+func importCmdFunc(cmd *cobra.Command, args []string) {
+	viper.BindPFlag("import_kubeconfig_dir", cmd.Flags().Lookup("kubeconfig-dir"))
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	command := strings.Join(append([]string{cmd.Name()}, args...), " ")
+	lockingBackend := backend.NewLockingBackend(remoteBackend, command, config.Global().GetBool("force_unlock"))
+	err = archive.Import(config.Global(), backend.NewTerraformConfigBackend(lockingBackend), args[0])
+	unlockErr := lockingBackend.Unlock()
+	if unlockErr != nil {
+		fmt.Println(unlockErr)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().String("kubeconfig-dir", ".", "Directory to save the kubeconfigs of the clusters to")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/journal"
+	"github.com/joyent/triton-kubernetes/shell"
+
+	"github.com/spf13/cobra"
+)
+
+// Runs the operation of a command, recording it in the journal of the cluster managers it
+// targets, e.g. as "scale nodepool dev-w". The cluster managers are locked until it's done.
+func runJournaled(cmd *cobra.Command, args []string, remoteBackend backend.Backend, operation func(backend.Backend) error) error {
+	command := strings.Join(append([]string{cmd.Name()}, args...), " ")
+	lockingBackend := backend.NewLockingBackend(remoteBackend, command, config.Global().GetBool("force_unlock"))
+	err := journal.Run(config.Global(), backend.NewTerraformConfigBackend(lockingBackend), command, operation)
+	unlockErr := lockingBackend.Unlock()
+	if unlockErr != nil {
+		fmt.Println(unlockErr)
+	}
+	if errors.Is(err, shell.ErrPlanNotApplied) {
+		// Previewing a plan with --plan-only, or declining it, isn't a failure
+		fmt.Println(err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// promoteCmd represents the promote command
+var promoteCmd = &cobra.Command{
+	Use:   "promote [node] [hostname]",
+	Short: "Change the role of a worker node to control or etcd",
+	Long: `Promote node changes the role of a worker node to control or etcd, given by --role,
+e.g. to grow the control plane of a cluster from 1 to 3 nodes. A node with the new role is
+created, and the worker is drained and destroyed once the new node is active.`,
+	ValidArgs: []string{"node"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes promote" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes promote"`, args[0])
+	},
+	Run: promoteCmdFunc,
+}
+
+func promoteCmdFunc(cmd *cobra.Command, args []string) {
+	if cmd.Flags().Changed("role") {
+		viper.BindPFlag("node_role", cmd.Flags().Lookup("role"))
+	}
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	hostname := ""
+	if len(args) == 2 {
+		hostname = args[1]
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		return create.PromoteNode(config.Global(), b, hostname)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().String("role", "", "Role to promote the node to, control or etcd")
+}
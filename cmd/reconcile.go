@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile [nodepools|state]",
+	Short: "Reconcile node pools with Rancher, or the state with the infrastructure",
+	Long: `Instances of node pools backed by an Auto Scaling Group, a VM Scale Set or a managed
+instance group are replaced by the cloud provider. Reconcile nodepools removes the nodes of
+replaced instances from the cluster in Rancher.
+
+Reconcile state refreshes the terraform state of a cluster manager against the real
+infrastructure, which updates its outputs, e.g. the IPs of replaced instances, and reports the
+attributes that changed out-of-band.`,
+	ValidArgs: []string{"nodepools", "state"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New(`"triton-kubernetes reconcile" requires one argument`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes reconcile"`, args[0])
+	},
+	Run: reconcileCmdFunc,
+}
+
+func reconcileCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		switch args[0] {
+		case "nodepools":
+			return create.ReconcileNodePools(config.Global(), b)
+		case "state":
+			return create.ReconcileState(config.Global(), b)
+		}
+		return nil
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume an operation that failed part way",
+	Long: `When terraform fails part way through creating a manager, cluster or nodes, the configuration
+it was applying is saved along with a checkpoint, so the resources it did create aren't orphaned.
+Resume applies the configuration of the cluster manager again from the checkpoint, in the working
+directory kept by the failed apply when it's still there.`,
+	Args: cobra.NoArgs,
+	Run:  resumeCmdFunc,
+}
+
+func resumeCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		return create.ResumeApply(config.Global(), b)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
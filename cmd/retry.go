@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// retryCmd represents the retry command
+var retryCmd = &cobra.Command{
+	Use:   "retry [failed]",
+	Short: "Retry nodes that failed to be created",
+	Long: `When some of the nodes being created fail, the nodes that were created are kept and the
+others are marked as failed. Retry failed re-applies the failed nodes of a cluster.`,
+	ValidArgs: []string{"failed"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New(`"triton-kubernetes retry" requires one argument`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes retry"`, args[0])
+	},
+	Run: retryCmdFunc,
+}
+
+func retryCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		return create.RetryFailedNodes(config.Global(), b)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(retryCmd)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/joyent/triton-kubernetes/access"
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// revokeCmd represents the revoke command
+var revokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke a role of a user or group in a cluster",
+	Long: `Revoke takes a cluster or project role given with grant away from a user or group:
+
+    triton-kubernetes revoke --cluster dev --user bob --role cluster-member`,
+	Args: cobra.NoArgs,
+	Run:  revokeCmdFunc,
+}
+
+func revokeCmdFunc(cmd *cobra.Command, args []string) {
+	bindAccessFlags(cmd)
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Terraform runs of the operation get the credentials of the backend
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
+	err = access.Revoke(config.Global(), remoteBackend)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(revokeCmd)
+
+	addAccessFlags(revokeCmd)
+}
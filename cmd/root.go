@@ -1,15 +1,26 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/fips"
+	"github.com/joyent/triton-kubernetes/util"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
 
+// Variables of config templates
+var templateVarFile string
+var templateVars []string
+
 // This represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "triton-kubernetes",
@@ -24,9 +35,24 @@ For an example set up, look at the How-To section.`,
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
+		// Unknown commands and flags
+		exitWithError(util.ConfigError(err))
+	}
+}
+
+// Prints the error of a command and exits with its exit code, see util.ExitCode. Interrupting a
+// prompt with Ctrl-C exits like an interrupted process, with what was changed instead of the
+// raw error of the prompt.
+func exitWithError(err error) {
+	if util.IsInterrupt(err) {
+		var interrupted *util.InterruptedError
+		if !errors.As(err, &interrupted) {
+			err = &util.InterruptedError{}
+		}
 	}
+
+	fmt.Println(err)
+	os.Exit(util.ExitCode(err))
 }
 
 func init() {
@@ -38,6 +64,13 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.triton-kubernetes.yaml)")
 	rootCmd.PersistentFlags().Bool("non-interactive", false, "Prevent interactive prompts")
+	rootCmd.PersistentFlags().Bool("fips", false, "Only use FIPS-approved crypto and FedRAMP authorized clouds")
+	rootCmd.PersistentFlags().Bool("force-unlock", false, "Remove the lock of a cluster manager held by another operation")
+	rootCmd.PersistentFlags().String("backend", "", "Name of the backend to use, from the backends section of the config")
+	rootCmd.PersistentFlags().Bool("quiet", false, "Only print the errors of terraform")
+	rootCmd.PersistentFlags().Bool("verbose", false, "Print the whole output of terraform instead of a line per resource")
+	rootCmd.PersistentFlags().StringVar(&templateVarFile, "var-file", "", "YAML file of variables for a config template")
+	rootCmd.PersistentFlags().StringArrayVar(&templateVars, "var", []string{}, "Variable for a config template, e.g. --var env=prod")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -48,7 +81,7 @@ func init() {
 func initConfig() {
 	viper.BindPFlag("non-interactive", rootCmd.Flags().Lookup("non-interactive"))
 	if viper.GetBool("non-interactive") {
-		fmt.Println("Running in non interactive mode")
+		fmt.Fprintln(os.Stderr, "Running in non interactive mode")
 	}
 
 	if cfgFile != "" { // enable ability to specify config file via flag
@@ -60,8 +93,61 @@ func initConfig() {
 
 	viper.AutomaticEnv() // read in environment variables that match
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	// If a config file is found, read it in. Templates usually aren't valid YAML until they
+	// are rendered.
+	err := viper.ReadInConfig()
+	if _, isParseError := err.(viper.ConfigParseError); err == nil || isParseError {
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+
+		// Re-read the config rendered as a template, with ${VAR} placeholders replaced by
+		// environment variables
+		content, err := ioutil.ReadFile(viper.ConfigFileUsed())
+		if err == nil {
+			content, err = renderConfigTemplate(content)
+		}
+		if err == nil {
+			content, err = util.ExpandEnvPlaceholders(content)
+		}
+		if err == nil {
+			err = viper.ReadConfig(bytes.NewReader(content))
+		}
+		if err != nil {
+			exitWithError(util.ConfigError(err))
+		}
+	}
+
+	// A named backend, only set when given since bound flags are always set
+	if backendFlag := rootCmd.PersistentFlags().Lookup("backend"); backendFlag.Changed {
+		viper.Set("backend", backendFlag.Value.String())
+	}
+
+	// Escape hatch for locks left behind by an operation that can't be detected as stale
+	viper.BindPFlag("force_unlock", rootCmd.Flags().Lookup("force-unlock"))
+
+	// Terraform output, a progress line per resource unless quiet or verbose
+	viper.BindPFlag("quiet", rootCmd.Flags().Lookup("quiet"))
+	viper.BindPFlag("verbose", rootCmd.Flags().Lookup("verbose"))
+
+	// FIPS mode, set by --fips, fips_mode or always on in BoringCrypto builds
+	viper.BindPFlag("fips_mode", rootCmd.Flags().Lookup("fips"))
+	if viper.GetBool("fips_mode") {
+		fips.Enable()
+	}
+	if fips.Enabled() {
+		fmt.Fprintln(os.Stderr, "Running in FIPS mode")
+	}
+
+	// Replace aws-ssm:// and aws-sm:// references with the secrets they point to
+	if err := util.ResolveAWSSecretReferences(); err != nil {
+		exitWithError(err)
 	}
 }
+
+func renderConfigTemplate(content []byte) ([]byte, error) {
+	variables, err := config.LoadTemplateVariables(templateVarFile, templateVars)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.RenderTemplate(content, variables)
+}
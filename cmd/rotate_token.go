@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// rotateTokenCmd represents the rotate-token command
+var rotateTokenCmd = &cobra.Command{
+	Use:   "rotate-token",
+	Short: "Rotate the Rancher API token of a cluster manager",
+	Long: `Rotate-token creates a new Rancher API token for the admin user of a cluster manager,
+stores it encrypted in the state, applies it to the manager's clusters and deletes the old
+token, which invalidates it.
+
+The token is encrypted with the state_encryption_key setting (or the STATE_ENCRYPTION_KEY
+environment variable). When it isn't set, a key is generated in
+~/.triton-kubernetes/state_encryption_key. The key is needed by every command that uses the
+cluster manager afterwards.`,
+	Run: rotateTokenCmdFunc,
+}
+
+func rotateTokenCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		return create.RotateRancherAPIToken(config.Global(), b)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(rotateTokenCmd)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// scaleCmd represents the scale command
+var scaleCmd = &cobra.Command{
+	Use:   "scale [nodepool] [name]",
+	Short: "Change the number of nodes of a node pool",
+	Long: `Scale nodepool changes the capacity of a node pool backed by an AWS Auto Scaling Group,
+an Azure VM Scale Set or a GCP managed instance group. Azure instances being removed are
+drained first, while the others are protected from scale in.
+
+Nodes sharing a hostname prefix, e.g. dev-w-1 to dev-w-3, form node pool dev-w. Scaling
+it adds nodes with the settings of its last node, or removes the nodes with the highest
+numbers.`,
+	ValidArgs: []string{"nodepool"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes scale" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes scale"`, args[0])
+	},
+	Run: scaleCmdFunc,
+}
+
+func scaleCmdFunc(cmd *cobra.Command, args []string) {
+	// Both destroy and scale have a --force flag, bind the one being run
+	viper.BindPFlag("force", cmd.Flags().Lookup("force"))
+	viper.BindPFlag("ignore_budget", cmd.Flags().Lookup("ignore-budget"))
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		return create.ScaleNodePool(config.Global(), b, name)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(scaleCmd)
+
+	scaleCmd.Flags().Bool("force", false, "Remove nodes even if it breaks etcd quorum or removes the last control plane node")
+	scaleCmd.Flags().Bool("ignore-budget", false, "Scale even if the estimated monthly cost exceeds the cluster's budget")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/status"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Check the health of a cluster manager and its clusters",
+	Long: `Status probes the Rancher health endpoint of a cluster manager and the agents of its
+clusters. It tells a cluster manager whose host is down from one whose Rancher server is down,
+and a cluster whose agent is disconnected from a healthy one. It exits with an error unless
+everything is healthy.`,
+	Args: cobra.NoArgs,
+	Run:  statusCmdFunc,
+}
+
+func statusCmdFunc(cmd *cobra.Command, args []string) {
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Terraform runs of the operation get the credentials of the backend
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
+	err = status.Status(config.Global(), remoteBackend)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/conformance"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test [cluster] [name]",
+	Short: "Run the Kubernetes conformance tests against a cluster",
+	Long: `Test cluster runs the Kubernetes conformance tests against a cluster with sonobuoy, which
+must be installed, and summarizes how many passed and failed. The non-disruptive conformance
+tests run by default, --mode quick runs a single test and --mode certified-conformance the full
+suite. It exits with an error if any test failed.`,
+	ValidArgs: []string{"cluster"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes test" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes test"`, args[0])
+	},
+	Run: testCmdFunc,
+}
+
+func testCmdFunc(cmd *cobra.Command, args []string) {
+	viper.BindPFlag("conformance_mode", cmd.Flags().Lookup("mode"))
+	viper.BindPFlag("conformance_timeout", cmd.Flags().Lookup("timeout"))
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// Terraform runs of the operation get the credentials of the backend
+	remoteBackend = backend.NewTerraformConfigBackend(remoteBackend)
+
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	err = conformance.TestCluster(config.Global(), remoteBackend, name)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+
+	testCmd.Flags().String("mode", "non-disruptive-conformance", "Tests to run: quick, non-disruptive-conformance or certified-conformance")
+	testCmd.Flags().Int("timeout", 180, "Minutes to wait for the tests to finish")
+}
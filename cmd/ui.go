@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/joyent/triton-kubernetes/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// uiCmd represents the ui command
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Serve a local web interface",
+	Long: `UI serves a web interface on localhost with forms for creating, getting, scaling and
+destroying clusters and nodes. Each form runs the same command as the terminal would, in
+non-interactive mode, and shows its output.`,
+	Args: cobra.NoArgs,
+	Run:  uiCmdFunc,
+}
+
+func uiCmdFunc(cmd *cobra.Command, args []string) {
+	port, err := cmd.Flags().GetInt("port")
+	if err != nil {
+		exitWithError(err)
+	}
+
+	err = ui.Serve(port)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+
+	uiCmd.Flags().Int("port", 8080, "Port of localhost to serve the web interface on")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [nodes|cluster] [hostname prefix|cluster name]",
+	Short: "Upgrade the nodes or the Kubernetes version of a cluster",
+	Long: `Upgrade nodes replaces the nodes sharing a hostname prefix with nodes running the image
+given by --image, one at a time. Each new node has to become active in Rancher before the
+node it replaces is drained and destroyed.
+
+Upgrade cluster upgrades a cluster to the Kubernetes version given by --k8s-version, or
+picked from the newer versions the cluster manager supports. The nodes are upgraded a few
+at a time, so the cluster doesn't have to be recreated.`,
+	ValidArgs: []string{"nodes", "cluster"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New(`"triton-kubernetes upgrade" requires one or two arguments`)
+		}
+
+		for _, validArg := range cmd.ValidArgs {
+			if validArg == args[0] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(`invalid argument "%s" for "triton-kubernetes upgrade"`, args[0])
+	},
+	Run: upgradeCmdFunc,
+}
+
+func upgradeCmdFunc(cmd *cobra.Command, args []string) {
+	viper.BindPFlag("node_image", cmd.Flags().Lookup("image"))
+	if cmd.Flags().Changed("k8s-version") {
+		viper.BindPFlag("k8s_version", cmd.Flags().Lookup("k8s-version"))
+	}
+
+	remoteBackend, err := util.PromptForBackend()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	err = runJournaled(cmd, args, remoteBackend, func(b backend.Backend) error {
+		if args[0] == "cluster" {
+			return create.UpgradeCluster(config.Global(), b, name)
+		}
+		return create.UpgradeNodes(config.Global(), b, name)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().String("image", "", "Image to run, e.g. name@version on Triton, an AMI id on AWS or publisher:offer:sku:version on Azure")
+	upgradeCmd.Flags().String("k8s-version", "", "Kubernetes version to upgrade the cluster to, e.g. v1.11.6-rancher1-1")
+}
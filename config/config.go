@@ -0,0 +1,35 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+)
+
+// Config holds the settings of an operation: the config file, flags and the answers to
+// prompts. The create, destroy and get flows read and write their settings through the
+// Config they are given instead of the global viper instance, so operations running in the
+// same process don't share settings.
+//
+// *viper.Viper implements Config. The CLI passes the global instance, Go programs and tests
+// pass their own, e.g. New().
+type Config interface {
+	AllKeys() []string
+	Get(key string) interface{}
+	GetBool(key string) bool
+	GetInt(key string) int
+	GetString(key string) string
+	GetStringMapString(key string) map[string]string
+	GetStringSlice(key string) []string
+	IsSet(key string) bool
+	Set(key string, value interface{})
+}
+
+// New returns an empty Config.
+func New() Config {
+	return viper.New()
+}
+
+// Global returns the Config of the CLI, the global viper instance holding the config file
+// and flags.
+func Global() Config {
+	return viper.GetViper()
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Writes YAML line by line, so every parameter can be documented with a comment.
+type configWriter struct {
+	buf    bytes.Buffer
+	indent string
+}
+
+func (w *configWriter) section(title string) {
+	if w.buf.Len() > 0 {
+		w.buf.WriteString("\n")
+	}
+	fmt.Fprintf(&w.buf, "%s# %s\n", w.indent, title)
+}
+
+// set writes a parameter. Values are strings, ints, bools or string slices.
+func (w *configWriter) set(key string, value interface{}, comment string) {
+	fmt.Fprintf(&w.buf, "%s%s: %s%s\n", w.indent, key, formatValue(value), formatComment(comment))
+}
+
+// secret writes a parameter whose value is read from the envVar environment variable.
+func (w *configWriter) secret(key, envVar, comment string) {
+	fmt.Fprintf(&w.buf, "%s%s: \"${%s}\"%s\n", w.indent, key, envVar, formatComment(comment))
+}
+
+// optional writes a commented out parameter.
+func (w *configWriter) optional(key string, example interface{}, comment string) {
+	fmt.Fprintf(&w.buf, "%s# %s: %s%s\n", w.indent, key, formatValue(example), formatComment(comment))
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		quoted := make([]string, 0, len(v))
+		for _, item := range v {
+			quoted = append(quoted, strconv.Quote(item))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(quoted, ", "))
+	default:
+		return strconv.Quote(fmt.Sprint(v))
+	}
+}
+
+func formatComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return "  # " + comment
+}
+
+// Returns the YAML config for the wizard answers.
+func generateConfig(answers wizardAnswers) []byte {
+	w := &configWriter{}
+
+	if answers.Resource == "manager" {
+		fmt.Fprintf(&w.buf, "# Creates the cluster manager '%s', run with:\n", answers.Name)
+	} else {
+		fmt.Fprintf(&w.buf, "# Creates the cluster '%s', run with:\n", answers.Name)
+	}
+	fmt.Fprintf(&w.buf, "#   triton-kubernetes create %s --non-interactive --config <this file>\n", answers.Resource)
+	w.buf.WriteString("# Values written as \"${VAR}\" are read from the VAR environment variable.\n")
+
+	writeBackendConfig(w, answers)
+
+	if answers.Resource == "manager" {
+		writeManagerConfig(w, answers)
+	} else {
+		writeClusterConfig(w, answers)
+	}
+
+	return w.buf.Bytes()
+}
+
+func writeBackendConfig(w *configWriter, answers wizardAnswers) {
+	w.section("Backend")
+	w.set("backend_provider", answers.BackendProvider, "where the configuration of cluster managers is stored: local, manta or git")
+	switch answers.BackendProvider {
+	case "manta":
+		w.set("manta_url", answers.MantaURL, "")
+		if answers.CloudProvider != "triton" {
+			writeTritonCredentials(w, answers)
+		}
+	case "git":
+		w.set("git_remote_url", answers.GitRemoteURL, "every change is committed and pushed to this repository")
+		w.set("git_branch", "master", "")
+		w.set("git_local_path", "~/.triton-kubernetes-git", "")
+	}
+}
+
+func writeTritonCredentials(w *configWriter, answers wizardAnswers) {
+	w.set("triton_account", answers.TritonAccount, "")
+	w.set("triton_key_path", answers.TritonKeyPath, "")
+	w.optional("triton_key_id", "", "MD5 or SHA256 fingerprint, defaults to the fingerprint of triton_key_path")
+	w.set("triton_url", answers.TritonURL, "")
+}
+
+func writeRegistryConfig(w *configWriter, prefix string) {
+	w.optional(prefix+"_registry", "", "private registry hosting the rancher containers")
+	w.optional(prefix+"_registry_username", "", "")
+	w.optional(prefix+"_registry_password", "${"+strings.ToUpper(prefix)+"_REGISTRY_PASSWORD}", "")
+}
+
+func writeManagerConfig(w *configWriter, answers wizardAnswers) {
+	w.section("Cluster Manager")
+	w.set("manager_cloud_provider", answers.CloudProvider, "")
+	w.set("name", answers.Name, "")
+	w.secret("rancher_admin_password", "RANCHER_ADMIN_PASSWORD", "UI password of the admin user")
+	writeRegistryConfig(w, "private")
+	w.optional("rancher_server_image", "", "rancher/server image in the private registry")
+	w.optional("rancher_agent_image", "", "rancher/agent image in the private registry")
+	w.optional("policy_path", "~/policies", "Rego policies checked before apply")
+
+	switch answers.CloudProvider {
+	case "triton":
+		w.section("Triton")
+		writeTritonCredentials(w, answers)
+		w.set("triton_network_names", []string{"Joyent-SDC-Public"}, "")
+		w.set("triton_image_name", "ubuntu-certified-16.04", "")
+		w.set("triton_image_version", "20180109", "")
+		w.set("triton_ssh_user", "ubuntu", "")
+		w.set("master_triton_machine_package", "k4-highcpu-kvm-1.75G", "")
+	case "aws":
+		w.section("AWS")
+		writeAWSCredentials(w, answers)
+		w.set("aws_private_key_path", "~/.ssh/id_rsa", "")
+		w.set("aws_ssh_user", "ubuntu", "")
+		w.set("aws_ami_id", "", "REQUIRED: Ubuntu 16.04 AMI available in aws_region")
+		w.set("aws_instance_type", "t2.micro", "")
+		w.optional("manager_host_count", 3, "Rancher on 3 hosts behind a load balancer")
+	case "gcp":
+		w.section("GCP")
+		writeGCPCredentials(w, answers)
+		w.set("gcp_instance_zone", answers.GCPComputeRegion+"-a", "")
+		w.set("gcp_machine_type", "n1-standard-1", "")
+		w.set("gcp_image", "ubuntu-1604-xenial-v20180424", "")
+		w.set("gcp_public_key_path", "~/.ssh/id_rsa.pub", "")
+		w.set("gcp_private_key_path", "~/.ssh/id_rsa", "")
+		w.set("gcp_ssh_user", "ubuntu", "")
+		w.optional("manager_host_count", 3, "Rancher on 3 hosts behind a load balancer")
+	case "azure":
+		w.section("Azure")
+		writeAzureCredentials(w, answers)
+		w.set("azure_size", "Standard_A1", "")
+		w.set("azure_ssh_user", "ubuntu", "")
+		w.set("azure_public_key_path", "~/.ssh/id_rsa.pub", "")
+		w.set("azure_private_key_path", "~/.ssh/id_rsa", "")
+		w.optional("manager_host_count", 3, "Rancher on 3 hosts behind a load balancer")
+	case "digitalocean":
+		w.section("DigitalOcean")
+		writeDigitalOceanCredentials(w, answers)
+		w.set("digitalocean_droplet_size", "s-2vcpu-4gb", "")
+		w.set("digitalocean_image", "ubuntu-16-04-x64", "")
+		w.set("digitalocean_ssh_key_fingerprint", "", "REQUIRED: fingerprint of an SSH key of the DigitalOcean account")
+		w.set("digitalocean_private_key_path", "~/.ssh/id_rsa", "private key of the SSH key")
+		w.optional("manager_host_count", 3, "Rancher on 3 hosts behind a load balancer")
+	case "libvirt":
+		w.section("Libvirt")
+		writeLibvirtHost(w, answers)
+		w.set("libvirt_ssh_user", "ubuntu", "")
+		w.set("libvirt_key_path", "~/.ssh/id_rsa", "the public key is read from the same path with a .pub extension")
+		w.set("master_libvirt_vcpu", 2, "")
+		w.set("master_libvirt_memory", 4096, "megabytes")
+		w.set("master_libvirt_disk_size", 20, "gigabytes")
+	}
+}
+
+func writeAWSCredentials(w *configWriter, answers wizardAnswers) {
+	w.secret("aws_access_key", "AWS_ACCESS_KEY_ID", "")
+	w.secret("aws_secret_key", "AWS_SECRET_ACCESS_KEY", "")
+	w.set("aws_region", answers.AWSRegion, "")
+	w.set("aws_key_name", "triton-kubernetes_public_key", "name of the key pair created from aws_public_key_path")
+	w.set("aws_public_key_path", "~/.ssh/id_rsa.pub", "")
+	w.set("aws_vpc_cidr", "10.0.0.0/16", "")
+	w.set("aws_subnet_cidr", "10.0.2.0/24", "")
+}
+
+func writeGCPCredentials(w *configWriter, answers wizardAnswers) {
+	w.set("gcp_path_to_credentials", answers.GCPCredentialsPath, "service account key file")
+	w.set("gcp_compute_region", answers.GCPComputeRegion, "")
+}
+
+func writeAzureCredentials(w *configWriter, answers wizardAnswers) {
+	w.secret("azure_subscription_id", "ARM_SUBSCRIPTION_ID", "")
+	w.secret("azure_client_id", "ARM_CLIENT_ID", "")
+	w.secret("azure_client_secret", "ARM_CLIENT_SECRET", "")
+	w.secret("azure_tenant_id", "ARM_TENANT_ID", "")
+	w.set("azure_environment", "public", "public, government, german or china")
+	w.set("azure_location", answers.AzureLocation, "")
+}
+
+func writeDigitalOceanCredentials(w *configWriter, answers wizardAnswers) {
+	w.secret("digitalocean_api_token", "DIGITALOCEAN_TOKEN", "")
+	w.set("digitalocean_region", answers.DigitalOceanRegion, "")
+}
+
+func writeClusterConfig(w *configWriter, answers wizardAnswers) {
+	w.section("Cluster")
+	w.set("cluster_manager", answers.ClusterManager, "")
+	w.set("cluster_cloud_provider", answers.CloudProvider, "")
+	w.set("name", answers.Name, "")
+	w.set("k8s_version", "v1.10.0-rancher1-1", "v1.8.10-rancher1-1, v1.9.5-rancher1-1 or v1.10.0-rancher1-1")
+	w.set("k8s_network_provider", "calico", "calico or flannel")
+	w.optional("k8s_network_mtu", 1450, "MTU of the pod network, below the MTU of the nodes' interfaces")
+	writeRegistryConfig(w, "private")
+	writeRegistryConfig(w, "k8s")
+	w.optional("cert_manager", true, "install cert-manager once the cluster is active")
+	w.optional("k8s_audit_log", true, "write the API server audit log on the control nodes")
+	w.optional("policy_path", "~/policies", "Rego policies checked before apply")
+
+	switch answers.CloudProvider {
+	case "triton":
+		w.section("Triton")
+		writeTritonCredentials(w, answers)
+		w.optional("ingress_load_balancer", true, "HAProxy in front of the worker nodes' ingress ports")
+	case "aws":
+		w.section("AWS")
+		writeAWSCredentials(w, answers)
+		w.optional("ingress_load_balancer", true, "network load balancer in front of the worker nodes' ingress ports")
+	case "gcp":
+		w.section("GCP")
+		writeGCPCredentials(w, answers)
+	case "azure":
+		w.section("Azure")
+		writeAzureCredentials(w, answers)
+	case "digitalocean":
+		w.section("DigitalOcean")
+		writeDigitalOceanCredentials(w, answers)
+	case "libvirt":
+		w.section("Libvirt")
+		writeLibvirtHost(w, answers)
+	}
+
+	w.section("Nodes, use 3 etcd and 3 control nodes for a highly available cluster")
+	w.buf.WriteString("nodes:\n")
+	for _, node := range []struct {
+		Label string
+		Count int
+	}{
+		{"etcd", 1},
+		{"control", 1},
+		{"worker", 2},
+	} {
+		fmt.Fprintf(&w.buf, "  - rancher_host_label: %s\n", formatValue(node.Label))
+		w.indent = "    "
+		w.set("node_count", node.Count, "")
+		w.set("hostname", fmt.Sprintf("%s-%s", answers.Name, node.Label[:1]), "hostnames are suffixed with a number")
+		writeNodeConfig(w, answers)
+		w.indent = ""
+	}
+}
+
+func writeLibvirtHost(w *configWriter, answers wizardAnswers) {
+	w.set("libvirt_uri", answers.LibvirtURI, "qemu:///system or qemu+ssh://user@host/system")
+	w.set("libvirt_pool_name", "default", "")
+	w.set("libvirt_network_name", "default", "must be reachable from this machine")
+	w.set("libvirt_image_source", "https://cloud-images.ubuntu.com/xenial/current/xenial-server-cloudimg-amd64-disk1.img", "URL or path of a cloud-init enabled qcow2 image")
+}
+
+func writeNodeConfig(w *configWriter, answers wizardAnswers) {
+	switch answers.CloudProvider {
+	case "triton":
+		w.set("triton_network_names", []string{"Joyent-SDC-Public"}, "")
+		w.set("triton_image_name", "ubuntu-certified-16.04", "")
+		w.set("triton_image_version", "20180109", "")
+		w.set("triton_ssh_user", "ubuntu", "")
+		w.set("triton_machine_package", "k4-highcpu-kvm-1.75G", "")
+	case "aws":
+		w.set("aws_ami_id", "", "REQUIRED: Ubuntu 16.04 AMI available in aws_region")
+		w.set("aws_instance_type", "t2.medium", "")
+	case "gcp":
+		w.set("gcp_instance_zone", answers.GCPComputeRegion+"-a", "")
+		w.set("gcp_machine_type", "n1-standard-2", "")
+		w.set("gcp_image", "ubuntu-1604-xenial-v20180424", "")
+	case "azure":
+		w.set("azure_size", "Standard_A2", "")
+		w.set("azure_ssh_user", "ubuntu", "")
+		w.set("azure_public_key_path", "~/.ssh/id_rsa.pub", "")
+	case "digitalocean":
+		w.set("digitalocean_droplet_size", "s-2vcpu-4gb", "")
+		w.set("digitalocean_image", "ubuntu-16-04-x64", "")
+		w.set("digitalocean_ssh_key_fingerprint", "", "REQUIRED: fingerprint of an SSH key of the DigitalOcean account")
+	case "libvirt":
+		w.set("libvirt_vcpu", 2, "")
+		w.set("libvirt_memory", 2048, "megabytes")
+		w.set("libvirt_disk_size", 20, "gigabytes")
+		w.set("libvirt_ssh_user", "ubuntu", "")
+		w.set("libvirt_key_path", "~/.ssh/id_rsa", "")
+	}
+}
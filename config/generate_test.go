@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGenerateClusterConfig(t *testing.T) {
+	answers := wizardAnswers{
+		Resource:        "cluster",
+		BackendProvider: "local",
+		CloudProvider:   "aws",
+		Name:            "dev",
+		ClusterManager:  "dev-manager",
+		AWSRegion:       "us-west-2",
+	}
+
+	content := generateConfig(answers)
+
+	// The generated config must be readable by the CLI
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Generated config is invalid YAML: %v\n%s", err, content)
+	}
+
+	expected := map[string]string{
+		"backend_provider":       "local",
+		"cluster_manager":        "dev-manager",
+		"cluster_cloud_provider": "aws",
+		"name":                   "dev",
+		"aws_region":             "us-west-2",
+		"aws_secret_key":         "${AWS_SECRET_ACCESS_KEY}",
+	}
+	for key, value := range expected {
+		if v.GetString(key) != value {
+			t.Errorf("Wrong value for %s, expected %q, received %q", key, value, v.GetString(key))
+		}
+	}
+
+	if v.IsSet("private_registry") {
+		t.Error("Optional parameters should be commented out")
+	}
+
+	nodes, ok := v.Get("nodes").([]interface{})
+	if !ok || len(nodes) != 3 {
+		t.Fatalf("Expected 3 node pools, received %v", v.Get("nodes"))
+	}
+
+	worker, ok := nodes[2].(map[interface{}]interface{})
+	if !ok || worker["rancher_host_label"] != "worker" || worker["node_count"] != 2 || worker["hostname"] != "dev-w" {
+		t.Errorf("Unexpected worker node pool %v", nodes[2])
+	}
+}
+
+func TestGenerateManagerConfigWithMantaBackend(t *testing.T) {
+	answers := wizardAnswers{
+		Resource:         "manager",
+		BackendProvider:  "manta",
+		CloudProvider:    "gcp",
+		Name:             "global",
+		TritonAccount:    "account",
+		TritonKeyPath:    "~/.ssh/id_rsa",
+		TritonURL:        "https://us-east-1.api.joyent.com",
+		MantaURL:         "https://us-east.manta.joyent.com",
+		GCPComputeRegion: "us-west1",
+	}
+
+	content := string(generateConfig(answers))
+
+	for _, line := range []string{
+		`manta_url: "https://us-east.manta.joyent.com"`,
+		`triton_account: "account"`,
+		`manager_cloud_provider: "gcp"`,
+		`gcp_instance_zone: "us-west1-a"`,
+		`rancher_admin_password: "${RANCHER_ADMIN_PASSWORD}"`,
+	} {
+		if !strings.Contains(content, line) {
+			t.Errorf("Expected generated config to contain %s\n%s", line, content)
+		}
+	}
+}
+
+func TestGenerateManagerConfigWithLibvirt(t *testing.T) {
+	answers := wizardAnswers{
+		Resource:        "manager",
+		BackendProvider: "local",
+		CloudProvider:   "libvirt",
+		Name:            "laptop",
+		LibvirtURI:      "qemu+ssh://root@kvm-host/system",
+	}
+
+	content := string(generateConfig(answers))
+
+	for _, line := range []string{
+		`manager_cloud_provider: "libvirt"`,
+		`libvirt_uri: "qemu+ssh://root@kvm-host/system"`,
+		`libvirt_key_path: "~/.ssh/id_rsa"`,
+		`master_libvirt_memory: 4096`,
+	} {
+		if !strings.Contains(content, line) {
+			t.Errorf("Expected generated config to contain %s\n%s", line, content)
+		}
+	}
+}
+
+func TestGenerateClusterConfigWithDigitalOcean(t *testing.T) {
+	answers := wizardAnswers{
+		Resource:           "cluster",
+		BackendProvider:    "local",
+		CloudProvider:      "digitalocean",
+		ClusterManager:     "global",
+		Name:               "dev",
+		DigitalOceanRegion: "sfo2",
+	}
+
+	content := string(generateConfig(answers))
+
+	for _, line := range []string{
+		`cluster_cloud_provider: "digitalocean"`,
+		`digitalocean_api_token: "${DIGITALOCEAN_TOKEN}"`,
+		`digitalocean_region: "sfo2"`,
+		`    digitalocean_droplet_size: "s-2vcpu-4gb"`,
+	} {
+		if !strings.Contains(content, line) {
+			t.Errorf("Expected generated config to contain %s\n%s", line, content)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+const defaultConfigPath = "triton-kubernetes.yaml"
+
+// Answers to the wizard questions, everything else in the generated config uses defaults.
+type wizardAnswers struct {
+	Resource        string
+	BackendProvider string
+	CloudProvider   string
+	Name            string
+	ClusterManager  string
+
+	TritonAccount string
+	TritonKeyPath string
+	TritonURL     string
+	MantaURL      string
+	GitRemoteURL  string
+
+	AWSRegion          string
+	GCPCredentialsPath string
+	GCPComputeRegion   string
+	AzureLocation      string
+	DigitalOceanRegion string
+	LibvirtURI         string
+}
+
+// InitConfig asks which resource, backend and cloud provider a config is for, then writes a
+// complete and commented YAML config for non-interactive mode to path (or config_output).
+// Secrets are written as ${VAR} placeholders that are read from the environment.
+func InitConfig(path string) error {
+	if viper.GetBool("non-interactive") {
+		return errors.New("config init is an interactive wizard, it can't run in non-interactive mode")
+	}
+
+	outputPath := path
+	if outputPath == "" {
+		outputPath = defaultConfigPath
+		if viper.IsSet("config_output") {
+			outputPath = viper.GetString("config_output")
+		}
+	}
+
+	expandedOutputPath, err := homedir.Expand(outputPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(expandedOutputPath)
+	if err == nil {
+		label := fmt.Sprintf("Overwrite existing file '%s'", outputPath)
+		confirmed, err := util.PromptForConfirmation(label, "Overwrite")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Config init canceled.")
+			return nil
+		}
+	}
+
+	answers, err := askWizardQuestions()
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(expandedOutputPath, generateConfig(answers), 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Config written to %s. Review it, export the environment variables it references, then run:\n", outputPath)
+	fmt.Printf("  triton-kubernetes create %s --non-interactive --config %s\n", answers.Resource, outputPath)
+
+	return nil
+}
+
+func askWizardQuestions() (wizardAnswers, error) {
+	answers := wizardAnswers{}
+
+	resource, err := selectOption("Generate config for", []string{"manager", "cluster"})
+	if err != nil {
+		return answers, err
+	}
+	answers.Resource = resource
+
+	answers.BackendProvider, err = selectOption("Backend Provider", []string{"local", "manta", "git"})
+	if err != nil {
+		return answers, err
+	}
+
+	answers.CloudProvider, err = selectOption("Cloud Provider", []string{"triton", "aws", "gcp", "azure", "digitalocean", "libvirt"})
+	if err != nil {
+		return answers, err
+	}
+
+	if answers.Resource == "manager" {
+		answers.Name, err = promptString("Cluster Manager Name", "")
+	} else {
+		answers.ClusterManager, err = promptString("Cluster Manager to create the cluster in", "")
+		if err != nil {
+			return answers, err
+		}
+		answers.Name, err = promptString("Cluster Name", "")
+	}
+	if err != nil {
+		return answers, err
+	}
+
+	// Triton credentials are needed by the manta backend and triton resources
+	if answers.BackendProvider == "manta" || answers.CloudProvider == "triton" {
+		answers.TritonAccount, err = promptString("Triton Account Name", "")
+		if err != nil {
+			return answers, err
+		}
+		answers.TritonKeyPath, err = promptString("Triton Key Path", "~/.ssh/id_rsa")
+		if err != nil {
+			return answers, err
+		}
+		answers.TritonURL, err = promptString("Triton URL", "https://us-east-1.api.joyent.com")
+		if err != nil {
+			return answers, err
+		}
+	}
+
+	switch answers.BackendProvider {
+	case "manta":
+		answers.MantaURL, err = promptString("Manta URL", "https://us-east.manta.joyent.com")
+	case "git":
+		answers.GitRemoteURL, err = promptString("Git Remote URL", "")
+	}
+	if err != nil {
+		return answers, err
+	}
+
+	switch answers.CloudProvider {
+	case "aws":
+		answers.AWSRegion, err = promptString("AWS Region", "us-west-2")
+	case "gcp":
+		answers.GCPCredentialsPath, err = promptString("GCP Path to Credentials", "~/gcp.json")
+		if err != nil {
+			return answers, err
+		}
+		answers.GCPComputeRegion, err = promptString("GCP Compute Region", "us-west1")
+	case "azure":
+		answers.AzureLocation, err = promptString("Azure Location", "West US 2")
+	case "digitalocean":
+		answers.DigitalOceanRegion, err = promptString("DigitalOcean Region", "nyc3")
+	case "libvirt":
+		answers.LibvirtURI, err = promptString("Libvirt URI", "qemu:///system")
+	}
+	if err != nil {
+		return answers, err
+	}
+
+	return answers, nil
+}
+
+func selectOption(label string, options []string) (string, error) {
+	prompt := promptui.Select{
+		Label: label,
+		Items: options,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}?",
+			Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+			Inactive: `  {{ . }}`,
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "%s:" | bold}} {{ . }}`, promptui.IconGood, label),
+		},
+	}
+
+	_, value, err := prompt.Run()
+	return value, err
+}
+
+func promptString(label, defaultValue string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: defaultValue,
+		Validate: func(input string) error {
+			if strings.TrimSpace(input) == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+
+	return prompt.Run()
+}
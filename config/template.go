@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/cast"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config files containing `{{` are Go templates, rendered before they are parsed, so a single
+// file can describe several environments, e.g.
+//
+//	name: {{ .env }}-cluster
+//	nodes:
+//	{{- range .pools }}
+//	  - hostname: {{ $.env }}-{{ .name }}
+//	    node_count: {{ .count }}
+//	{{- end }}
+//
+// Variables come from --var-file and --var, see LoadTemplateVariables.
+var templateFuncs = template.FuncMap{
+	// Value of an environment variable, or of the fallback when it is unset
+	"env": func(name string, fallback ...string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return strings.Join(fallback, "")
+	},
+	// Value, or fallback when the value is empty: {{ .count | default 3 }}
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+	// Fails the rendering with the message when the value is empty
+	"required": func(message string, value interface{}) (interface{}, error) {
+		if value == nil || value == "" {
+			return nil, errors.New(message)
+		}
+		return value, nil
+	},
+	// Numbers from 1 to n, e.g. to name nodes
+	"seq": func(n interface{}) ([]int, error) {
+		count, err := toInt(n)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]int, count)
+		for i := range result {
+			result[i] = i + 1
+		}
+		return result, nil
+	},
+	"add": func(a, b interface{}) (int, error) {
+		x, err := toInt(a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := toInt(b)
+		return x + y, err
+	},
+	"mul": func(a, b interface{}) (int, error) {
+		x, err := toInt(a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := toInt(b)
+		return x * y, err
+	},
+	// Value quoted as a YAML string
+	"quote": func(value interface{}) string {
+		return fmt.Sprintf("%q", fmt.Sprint(value))
+	},
+}
+
+// Converts a number of a template to an int. Variables set with --var are strings, those of
+// --var-file are decoded from YAML.
+func toInt(value interface{}) (int, error) {
+	if text, ok := value.(string); ok {
+		number, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not a number", text)
+		}
+		return number, nil
+	}
+
+	return cast.ToIntE(value)
+}
+
+// RenderTemplate renders the content of a config file with the given variables. Content
+// without `{{` is returned as is. Printing a variable that isn't set is an error, optional
+// variables go through default.
+func RenderTemplate(content []byte, variables map[string]interface{}) ([]byte, error) {
+	if !bytes.Contains(content, []byte("{{")) {
+		return content, nil
+	}
+
+	tmpl, err := template.New("config").Funcs(templateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse the config template: %v", err)
+	}
+
+	rendered := bytes.Buffer{}
+	err = tmpl.Execute(&rendered, variables)
+	if err != nil {
+		return nil, fmt.Errorf("Could not render the config template: %v", err)
+	}
+
+	// Missing map keys are nil, which templates print as <no value>
+	if index := bytes.Index(rendered.Bytes(), []byte("<no value>")); index >= 0 {
+		lineNumber := bytes.Count(rendered.Bytes()[:index], []byte("\n")) + 1
+		return nil, fmt.Errorf("Could not render the config template: line %d of the rendered config uses a variable that isn't set", lineNumber)
+	}
+
+	return rendered.Bytes(), nil
+}
+
+// LoadTemplateVariables returns the variables of a config template: the YAML map of
+// varFile, if given, overridden by `name=value` assignments.
+func LoadTemplateVariables(varFile string, assignments []string) (map[string]interface{}, error) {
+	variables := map[string]interface{}{}
+
+	if varFile != "" {
+		content, err := ioutil.ReadFile(varFile)
+		if err != nil {
+			return nil, err
+		}
+
+		err = yaml.Unmarshal(content, &variables)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read variables file '%s': %v", varFile, err)
+		}
+		for name, value := range variables {
+			variables[name] = stringKeys(value)
+		}
+	}
+
+	for _, assignment := range assignments {
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, util.ConfigError(fmt.Errorf("Invalid variable '%s', must be name=value", assignment))
+		}
+		variables[parts[0]] = parts[1]
+	}
+
+	return variables, nil
+}
+
+// Converts the maps decoded from YAML to maps with string keys, whose fields templates can
+// access, e.g. {{ .name }} in {{ range .pools }}.
+func stringKeys(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range value {
+			result[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = stringKeys(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const clusterTemplate = `name: {{ .env }}-cluster
+cluster_manager: {{ env "TK_TEST_MANAGER" "dev-manager" }}
+nodes:
+{{- range .pools }}
+  - hostname: {{ $.env }}-{{ .name }}
+    rancher_host_label: {{ .role }}
+    node_count: {{ .count | default 1 }}
+{{- end }}
+`
+
+func TestRenderTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	varFile := filepath.Join(dir, "prod.yaml")
+	err = ioutil.WriteFile(varFile, []byte(`env: stage
+pools:
+  - name: e
+    role: etcd
+    count: 3
+  - name: w
+    role: worker
+    count: ""
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assignments override the variables file
+	variables, err := LoadTemplateVariables(varFile, []string{"env=prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := RenderTemplate([]byte(clusterTemplate), variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err = v.ReadConfig(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Rendered config is invalid YAML: %v\n%s", err, content)
+	}
+
+	if v.GetString("name") != "prod-cluster" || v.GetString("cluster_manager") != "dev-manager" {
+		t.Errorf("Wrong output, received\n%s", content)
+	}
+
+	nodes, ok := v.Get("nodes").([]interface{})
+	if !ok || len(nodes) != 2 {
+		t.Fatalf("Expected 2 node pools, received %v", v.Get("nodes"))
+	}
+	etcd := nodes[0].(map[interface{}]interface{})
+	worker := nodes[1].(map[interface{}]interface{})
+	if etcd["hostname"] != "prod-e" || etcd["node_count"] != 3 || worker["hostname"] != "prod-w" || worker["node_count"] != 1 {
+		t.Errorf("Wrong output, received\n%s", content)
+	}
+}
+
+func TestRenderTemplateNumberVariables(t *testing.T) {
+	// Variables assigned with --var are strings
+	variables, err := LoadTemplateVariables("", []string{"count=3", "first=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte(`nodes:
+{{- range seq .count }}
+  - hostname: dev-w-{{ add . $.first }}
+    disk: {{ mul . 10 }}
+{{- end }}
+`)
+	expected := `nodes:
+  - hostname: dev-w-3
+    disk: 10
+  - hostname: dev-w-4
+    disk: 20
+  - hostname: dev-w-5
+    disk: 30
+`
+
+	rendered, err := RenderTemplate(content, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rendered) != expected {
+		t.Errorf("Wrong output, expected %s, received %s", expected, rendered)
+	}
+
+	_, err = RenderTemplate([]byte("{{ range seq .count }}{{ . }}{{ end }}"), map[string]interface{}{"count": "three"})
+	if err == nil || !strings.Contains(err.Error(), "'three' is not a number") {
+		t.Errorf("Expected an error for a variable that isn't a number, received %v", err)
+	}
+}
+
+func TestRenderTemplateWithoutActions(t *testing.T) {
+	content := []byte("name: dev-cluster\nrancher_admin_password: ${RANCHER_PASSWORD}\n")
+
+	rendered, err := RenderTemplate(content, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rendered, content) {
+		t.Errorf("Wrong output, expected %s, received %s", content, rendered)
+	}
+}
+
+func TestRenderTemplateInvalid(t *testing.T) {
+	tests := []struct {
+		testName  string
+		template  string
+		variables map[string]interface{}
+	}{
+		{"Missing variable", "name: {{ .env }}-cluster", map[string]interface{}{}},
+		{"Required", `name: {{ required "name is required" .name }}`, map[string]interface{}{"name": ""}},
+		{"Syntax", "name: {{ .env ", map[string]interface{}{"env": "dev"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			_, err := RenderTemplate([]byte(test.template), test.variables)
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadTemplateVariablesInvalid(t *testing.T) {
+	expected := "Invalid variable 'env', must be name=value"
+
+	_, err := LoadTemplateVariables("", []string{"env"})
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+)
+
+// LoadTFVars returns the variables of a terraform variables file, in HCL (.tfvars) or JSON
+// (.tfvars.json) depending on its extension.
+func LoadTFVars(path string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := map[string]interface{}{}
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(content, &variables)
+	} else {
+		err = hcl.Unmarshal(content, &variables)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not read terraform variables file '%s': %v", path, err)
+	}
+
+	for name, value := range variables {
+		variables[name] = flattenHCLMaps(value)
+	}
+	return variables, nil
+}
+
+// HCL decodes a map variable, e.g. `tags = { env = "prod" }`, as a list holding the map.
+func flattenHCLMaps(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []map[string]interface{}:
+		if len(value) == 1 {
+			return flattenHCLMaps(value[0])
+		}
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = flattenHCLMaps(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range value {
+			result[key] = flattenHCLMaps(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = flattenHCLMaps(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadTFVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"hcl.tfvars": `
+triton_machine_package = "k4-highcpu-kvm-1.75G"
+node_count = 3
+triton_network_names = ["Joyent-SDC-Public"]
+triton_tags = {
+  env = "prod"
+}
+`,
+		"json.tfvars.json": `{
+  "triton_machine_package": "k4-highcpu-kvm-1.75G",
+  "node_count": 3,
+  "triton_network_names": ["Joyent-SDC-Public"],
+  "triton_tags": {"env": "prod"}
+}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err = ioutil.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		variables, err := LoadTFVars(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if variables["triton_machine_package"] != "k4-highcpu-kvm-1.75G" {
+			t.Errorf("%s: unexpected triton_machine_package %v", name, variables["triton_machine_package"])
+		}
+		if !reflect.DeepEqual(variables["triton_network_names"], []interface{}{"Joyent-SDC-Public"}) {
+			t.Errorf("%s: unexpected triton_network_names %#v", name, variables["triton_network_names"])
+		}
+		if !reflect.DeepEqual(variables["triton_tags"], map[string]interface{}{"env": "prod"}) {
+			t.Errorf("%s: unexpected triton_tags %#v", name, variables["triton_tags"])
+		}
+	}
+}
+
+func TestLoadTFVarsInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "invalid.tfvars")
+	err = ioutil.WriteFile(path, []byte(`node_count = [`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadTFVars(path)
+	if err == nil {
+		t.Error("Expected an error for an invalid variables file")
+	}
+}
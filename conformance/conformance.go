@@ -0,0 +1,320 @@
+// Package conformance verifies that a cluster conforms to Kubernetes by running the
+// conformance tests against it with sonobuoy.
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	defaultMode = "non-disruptive-conformance"
+
+	// Minutes to wait for the tests to finish, the full conformance suite takes 1 to 2 hours
+	defaultTimeout = 180
+)
+
+// Sonobuoy modes: a single conformance test, every conformance test that doesn't disrupt
+// running workloads, and the full suite needed for certification.
+var modes = []string{"quick", "non-disruptive-conformance", "certified-conformance"}
+
+// Results are the results of the conformance tests, as summarized by `sonobuoy results`.
+type Results struct {
+	Status  string
+	Total   int
+	Passed  int
+	Failed  int
+	Skipped int
+
+	FailedTests []string
+}
+
+// TestCluster runs the Kubernetes conformance tests against a cluster with sonobuoy, which must
+// be installed, and prints how many passed and failed. The results tarball is saved to
+// conformance_results_dir, the current directory by default. It returns an error if any test
+// failed.
+func TestCluster(conf config.Config, remoteBackend backend.Backend, clusterName string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	mode := defaultMode
+	if conf.IsSet("conformance_mode") {
+		mode = conf.GetString("conformance_mode")
+	}
+	err := validateMode(mode)
+	if err != nil {
+		return err
+	}
+
+	timeout := defaultTimeout
+	if conf.IsSet("conformance_timeout") {
+		timeout = conf.GetInt("conformance_timeout")
+		if timeout <= 0 {
+			return errors.New("conformance_timeout must be a number of minutes greater than 0")
+		}
+	}
+
+	resultsDir := "."
+	if conf.IsSet("conformance_results_dir") {
+		resultsDir = conf.GetString("conformance_results_dir")
+	}
+	resultsDir, err = homedir.Expand(resultsDir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(resultsDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	// The name given as an argument takes precedence over cluster_name
+	if clusterName == "" && conf.IsSet("cluster_name") {
+		clusterName = conf.GetString("cluster_name")
+	}
+	if clusterName == "" && nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else if clusterName == "" {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to test",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		clusterName = value
+	}
+
+	clusterKey, ok := clusters[clusterName]
+	if !ok {
+		return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+	}
+
+	client, clusterID, err := rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+	cluster, err := client.Cluster(clusterID)
+	if err != nil {
+		return err
+	}
+	if cluster.State != "active" {
+		return fmt.Errorf("Cluster '%s' is %s, only active clusters can be tested.", clusterName, cluster.State)
+	}
+
+	kubeconfig, err := client.GenerateKubeconfig(cluster)
+	if err != nil {
+		return err
+	}
+
+	// The kubeconfig holds a Rancher API token, it's removed with the working directory
+	tempDir, cleanup, err := shell.NewWorkingDir(conf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	kubeconfigPath := filepath.Join(tempDir, "kubeconfig")
+	err = ioutil.WriteFile(kubeconfigPath, []byte(kubeconfig), 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Running the %s tests against cluster '%s', this can take a while.\n", mode, clusterName)
+
+	// Sonobuoy's namespace is removed however the tests end, so the next run can start
+	defer func() {
+		err := shell.RunShellCommand(nil, "sonobuoy", "delete", "--kubeconfig", kubeconfigPath, "--wait")
+		if err != nil {
+			fmt.Printf("Unable to remove sonobuoy from cluster '%s': %s\n", clusterName, err)
+		}
+	}()
+
+	err = shell.RunShellCommand(nil, "sonobuoy", "run", "--kubeconfig", kubeconfigPath, "--mode", mode, fmt.Sprintf("--wait=%d", timeout))
+	if err != nil {
+		return fmt.Errorf("Unable to run the conformance tests of cluster '%s': %s", clusterName, err)
+	}
+
+	output, err := shell.RunShellCommandWithOutput(nil, "sonobuoy", "retrieve", "--kubeconfig", kubeconfigPath, resultsDir)
+	if err != nil {
+		return err
+	}
+	tarball := strings.TrimSpace(string(output))
+
+	output, err = shell.RunShellCommandWithOutput(nil, "sonobuoy", "results", tarball)
+	if err != nil {
+		return err
+	}
+	results := parseResults(string(output))
+
+	printResults(clusterName, mode, results)
+	fmt.Printf("Results saved to %s\n", tarball)
+
+	if results.Failed > 0 {
+		return fmt.Errorf("Cluster '%s' failed %d of %d conformance tests.", clusterName, results.Failed, results.Total)
+	}
+	if results.Status != "passed" {
+		return fmt.Errorf("The conformance tests of cluster '%s' ended with status '%s'.", clusterName, results.Status)
+	}
+
+	return nil
+}
+
+func validateMode(mode string) error {
+	for _, m := range modes {
+		if m == mode {
+			return nil
+		}
+	}
+	return util.ConfigError(fmt.Errorf("Invalid conformance_mode '%s', must be one of %s", mode, strings.Join(modes, ", ")))
+}
+
+// Parses the output of `sonobuoy results` for the e2e plugin, e.g.
+//
+//	Plugin: e2e
+//	Status: failed
+//	Total: 5233
+//	Passed: 272
+//	Failed: 1
+//	Skipped: 4960
+//
+//	Failed tests:
+//	[sig-network] Services should serve a basic endpoint from pods  [Conformance]
+func parseResults(output string) Results {
+	results := Results{}
+	inFailedTests := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if inFailedTests {
+			results.FailedTests = append(results.FailedTests, line)
+			continue
+		}
+		if line == "Failed tests:" {
+			inFailedTests = true
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		count, _ := strconv.Atoi(value)
+		switch parts[0] {
+		case "Status":
+			results.Status = value
+		case "Total":
+			results.Total = count
+		case "Passed":
+			results.Passed = count
+		case "Failed":
+			results.Failed = count
+		case "Skipped":
+			results.Skipped = count
+		}
+	}
+	return results
+}
+
+func printResults(clusterName, mode string, results Results) {
+	fmt.Printf("Conformance tests of cluster '%s' (%s): %s\n", clusterName, mode, results.Status)
+	fmt.Printf("  Passed:  %d\n", results.Passed)
+	fmt.Printf("  Failed:  %d\n", results.Failed)
+	fmt.Printf("  Skipped: %d\n", results.Skipped)
+	if len(results.FailedTests) > 0 {
+		fmt.Println("Failed tests:")
+		for _, test := range results.FailedTests {
+			fmt.Printf("  %s\n", test)
+		}
+	}
+}
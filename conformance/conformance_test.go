@@ -0,0 +1,55 @@
+package conformance
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResults(t *testing.T) {
+	output := `Plugin: e2e
+Status: failed
+Total: 5233
+Passed: 272
+Failed: 2
+Skipped: 4959
+
+Failed tests:
+[sig-network] Services should serve a basic endpoint from pods  [Conformance]
+[sig-apps] Deployment deployment should support rollover [Conformance]
+`
+
+	expected := Results{
+		Status:  "failed",
+		Total:   5233,
+		Passed:  272,
+		Failed:  2,
+		Skipped: 4959,
+		FailedTests: []string{
+			"[sig-network] Services should serve a basic endpoint from pods  [Conformance]",
+			"[sig-apps] Deployment deployment should support rollover [Conformance]",
+		},
+	}
+
+	results := parseResults(output)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Wrong output, expected %+v, received %+v", expected, results)
+	}
+}
+
+func TestParseResultsPassed(t *testing.T) {
+	results := parseResults("Plugin: e2e\nStatus: passed\nTotal: 1\nPassed: 1\nFailed: 0\nSkipped: 0\n")
+	if results.Status != "passed" || results.Passed != 1 || results.Failed != 0 || len(results.FailedTests) != 0 {
+		t.Errorf("Wrong output, expected 1 passed test, received %+v", results)
+	}
+}
+
+func TestValidateMode(t *testing.T) {
+	for _, mode := range modes {
+		if err := validateMode(mode); err != nil {
+			t.Errorf("Unexpected error for %q: %v", mode, err)
+		}
+	}
+	if err := validateMode("lite"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
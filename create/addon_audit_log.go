@@ -0,0 +1,294 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	auditLogShippingTerraformModulePath = "terraform/modules/k8s-audit-log-shipping"
+	auditLogShippingAddonName           = "audit-log-shipping"
+
+	defaultAuditLogMantaURL          = "https://us-east.manta.joyent.com"
+	defaultAuditLogElasticsearchPort = "9200"
+)
+
+type auditLogShippingTerraformConfig struct {
+	Source string `json:"source"`
+
+	RancherAPIURL    string `json:"rancher_api_url"`
+	RancherAccessKey string `json:"rancher_access_key"`
+	RancherSecretKey string `json:"rancher_secret_key"`
+	RancherClusterID string `json:"rancher_cluster_id"`
+
+	FluentBitVersion string `json:"fluent_bit_version,omitempty"`
+
+	Destination string `json:"audit_log_destination"`
+
+	S3Bucket    string `json:"audit_log_s3_bucket,omitempty"`
+	S3Region    string `json:"audit_log_s3_region,omitempty"`
+	S3AccessKey string `json:"audit_log_s3_access_key,omitempty"`
+	S3SecretKey string `json:"audit_log_s3_secret_key,omitempty"`
+
+	ElasticsearchHost     string `json:"audit_log_elasticsearch_host,omitempty"`
+	ElasticsearchPort     string `json:"audit_log_elasticsearch_port,omitempty"`
+	ElasticsearchTLS      string `json:"audit_log_elasticsearch_tls,omitempty"`
+	ElasticsearchUsername string `json:"audit_log_elasticsearch_username,omitempty"`
+	ElasticsearchPassword string `json:"audit_log_elasticsearch_password,omitempty"`
+
+	MantaURL     string `json:"audit_log_manta_url,omitempty"`
+	MantaAccount string `json:"audit_log_manta_account,omitempty"`
+	MantaKeyPath string `json:"audit_log_manta_key_path,omitempty"`
+	MantaKeyID   string `json:"audit_log_manta_key_id,omitempty"`
+	MantaPath    string `json:"audit_log_manta_path,omitempty"`
+}
+
+// Optionally adds a fluent-bit deployment to the control nodes of the given cluster, which
+// ships the API server's audit log to S3, Manta or Elasticsearch.
+func newAuditLogShippingAddon(conf config.Config, selectedClusterKey string, currentState state.State) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	if currentState.Get(fmt.Sprintf("module.%s.k8s_audit_log", selectedClusterKey)) != "true" {
+		if conf.IsSet("audit_log_destination") && conf.GetString("audit_log_destination") != "none" {
+			return errors.New("audit_log_destination requires k8s_audit_log")
+		}
+		return nil
+	}
+
+	rancherAccessKey, rancherSecretKey := currentState.RancherAPIKeyReferences()
+	cfg := auditLogShippingTerraformConfig{
+		RancherAPIURL:    "${module.cluster-manager.rancher_url}",
+		RancherAccessKey: rancherAccessKey,
+		RancherSecretKey: rancherSecretKey,
+		RancherClusterID: fmt.Sprintf("${module.%s.rancher_cluster_id}", selectedClusterKey),
+	}
+
+	baseSource := defaultSourceURL
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
+	}
+
+	baseSourceRef := defaultSourceRef
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
+	}
+
+	cfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, auditLogShippingTerraformModulePath, baseSourceRef)
+
+	// fluent-bit Version
+	if conf.IsSet("fluent_bit_version") {
+		cfg.FluentBitVersion = conf.GetString("fluent_bit_version")
+	}
+
+	// Audit Log Destination
+	destinationOptions := []struct {
+		Name  string
+		Value string
+	}{
+		{"Keep on the control nodes", "none"},
+		{"Amazon S3", "s3"},
+		{"Manta", "manta"},
+		{"Elasticsearch", "elasticsearch"},
+	}
+	if conf.IsSet("audit_log_destination") {
+		cfg.Destination = conf.GetString("audit_log_destination")
+	} else if nonInteractiveMode {
+		cfg.Destination = "none"
+	} else {
+		prompt := promptui.Select{
+			Label: "Ship the audit log to",
+			Items: destinationOptions,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ .Name }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Ship the audit log to:" | bold}} {{ .Name }}`, promptui.IconGood),
+			},
+		}
+
+		i, _, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		cfg.Destination = destinationOptions[i].Value
+	}
+
+	var err error
+	switch cfg.Destination {
+	case "none":
+		return nil
+	case "s3":
+		err = getAuditLogS3Config(conf, &cfg)
+	case "manta":
+		err = getAuditLogMantaConfig(conf, &cfg)
+	case "elasticsearch":
+		err = getAuditLogElasticsearchConfig(conf, &cfg)
+	default:
+		return util.ConfigError(fmt.Errorf("Invalid audit_log_destination '%s', must be 'none', 's3', 'manta' or 'elasticsearch'", cfg.Destination))
+	}
+	if err != nil {
+		return err
+	}
+
+	return currentState.AddAddon(selectedClusterKey, auditLogShippingAddonName, &cfg)
+}
+
+func getAuditLogS3Config(conf config.Config, cfg *auditLogShippingTerraformConfig) error {
+	var err error
+	cfg.S3Bucket, err = promptForAuditLogValue(conf, "audit_log_s3_bucket", "S3 Bucket", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.S3Region, err = promptForAuditLogValue(conf, "audit_log_s3_region", "S3 Region", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.S3AccessKey, err = promptForAuditLogValue(conf, "audit_log_s3_access_key", "AWS Access Key", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.S3SecretKey, err = promptForAuditLogValue(conf, "audit_log_s3_secret_key", "AWS Secret Key", true)
+	return err
+}
+
+func getAuditLogMantaConfig(conf config.Config, cfg *auditLogShippingTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	cfg.MantaURL = defaultAuditLogMantaURL
+	if conf.IsSet("audit_log_manta_url") {
+		cfg.MantaURL = conf.GetString("audit_log_manta_url")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label:   "Manta URL",
+			Default: cfg.MantaURL,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		cfg.MantaURL = result
+	}
+
+	var err error
+	cfg.MantaAccount, err = promptForAuditLogValue(conf, "audit_log_manta_account", "Manta Account Name", false)
+	if err != nil {
+		return err
+	}
+
+	// Manta Key Path
+	keyPath := ""
+	if conf.IsSet("audit_log_manta_key_path") {
+		keyPath = conf.GetString("audit_log_manta_key_path")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("audit_log_manta_key_path must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Manta Key Path",
+			Validate: func(input string) error {
+				expandedPath, err := homedir.Expand(input)
+				if err != nil {
+					return err
+				}
+
+				_, err = os.Stat(expandedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return errors.New("File not found")
+					}
+				}
+				return nil
+			},
+			Default: "~/.ssh/id_rsa",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		keyPath = result
+	}
+
+	cfg.MantaKeyPath, err = homedir.Expand(keyPath)
+	if err != nil {
+		return err
+	}
+
+	// Manta Key ID
+	cfg.MantaKeyID, err = util.TritonKeyID("audit_log_manta_key_id", conf.GetString("audit_log_manta_key_id"), cfg.MantaKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// Manta Path
+	cfg.MantaPath = fmt.Sprintf("/%s/stor/kube-audit", cfg.MantaAccount)
+	if conf.IsSet("audit_log_manta_path") {
+		cfg.MantaPath = conf.GetString("audit_log_manta_path")
+	}
+
+	return nil
+}
+
+func getAuditLogElasticsearchConfig(conf config.Config, cfg *auditLogShippingTerraformConfig) error {
+	var err error
+	cfg.ElasticsearchHost, err = promptForAuditLogValue(conf, "audit_log_elasticsearch_host", "Elasticsearch Host", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.ElasticsearchPort = defaultAuditLogElasticsearchPort
+	if conf.IsSet("audit_log_elasticsearch_port") {
+		cfg.ElasticsearchPort = conf.GetString("audit_log_elasticsearch_port")
+	}
+
+	cfg.ElasticsearchTLS = "false"
+	if conf.GetBool("audit_log_elasticsearch_tls") {
+		cfg.ElasticsearchTLS = "true"
+	}
+
+	// Credentials are optional
+	if conf.IsSet("audit_log_elasticsearch_username") {
+		cfg.ElasticsearchUsername = conf.GetString("audit_log_elasticsearch_username")
+		cfg.ElasticsearchPassword, err = promptForAuditLogValue(conf, "audit_log_elasticsearch_password", "Elasticsearch Password", true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func promptForAuditLogValue(conf config.Config, key, label string, secret bool) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	prompt := promptui.Prompt{
+		Label: label,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+	if secret {
+		prompt.Mask = '*'
+	}
+
+	return prompt.Run()
+}
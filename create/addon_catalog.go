@@ -0,0 +1,215 @@
+package create
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+const (
+	clusterAddonTerraformModulePath = "terraform/modules/k8s-addon"
+)
+
+// An addon of the curated set, installed from manifests applied with kubectl or from a Helm
+// chart. %s in ManifestURL is replaced with the version.
+type clusterAddon struct {
+	Name           string
+	DefaultVersion string
+	// Oldest Kubernetes version DefaultVersion runs on
+	MinKubernetesVersion string
+
+	ManifestURL string
+
+	HelmRepo      string
+	HelmChart     string
+	HelmNamespace string
+	HelmValues    map[string]string
+}
+
+type clusterAddonTerraformConfig struct {
+	Source string `json:"source"`
+
+	Name string `json:"name"`
+
+	RancherAPIURL    string `json:"rancher_api_url"`
+	RancherAccessKey string `json:"rancher_access_key"`
+	RancherSecretKey string `json:"rancher_secret_key"`
+	RancherClusterID string `json:"rancher_cluster_id"`
+
+	ManifestURL string `json:"manifest_url,omitempty"`
+
+	HelmRepo      string            `json:"helm_repo,omitempty"`
+	HelmChart     string            `json:"helm_chart,omitempty"`
+	HelmVersion   string            `json:"helm_version,omitempty"`
+	HelmNamespace string            `json:"helm_namespace,omitempty"`
+	HelmValues    map[string]string `json:"helm_values,omitempty"`
+}
+
+// The addons that can be installed after a cluster is created, in the order they're offered.
+// cert-manager is installed by its own addon, which also sets up a Let's Encrypt ClusterIssuer.
+var clusterAddons = []clusterAddon{
+	{
+		Name:                 "metrics-server",
+		DefaultVersion:       "v0.3.7",
+		MinKubernetesVersion: "v1.11",
+		ManifestURL:          "https://github.com/kubernetes-sigs/metrics-server/releases/download/%s/components.yaml",
+	},
+	{
+		Name:                 "ingress-nginx",
+		DefaultVersion:       "2.11.1",
+		MinKubernetesVersion: "v1.14",
+		HelmRepo:             "https://kubernetes.github.io/ingress-nginx",
+		HelmChart:            "ingress-nginx",
+		HelmNamespace:        "ingress-nginx",
+		// The nginx ingress controller of RKE keeps serving the nginx class
+		HelmValues: map[string]string{"controller.ingressClass": "ingress-nginx"},
+	},
+	{
+		Name: certManagerAddonName,
+	},
+	{
+		Name:                 "dashboard",
+		DefaultVersion:       "v2.0.0",
+		MinKubernetesVersion: "v1.18",
+		ManifestURL:          "https://raw.githubusercontent.com/kubernetes/dashboard/%s/aio/deploy/recommended.yaml",
+	},
+}
+
+func getClusterAddon(name string) (clusterAddon, bool) {
+	for _, addon := range clusterAddons {
+		if addon.Name == name {
+			return addon, true
+		}
+	}
+	return clusterAddon{}, false
+}
+
+// Returns whether the default version of the addon runs on the given Kubernetes version. A
+// version given in cluster_addon_versions is up to the user, as is a cluster whose version
+// comes from a cluster template.
+func supportsKubernetesVersion(addon clusterAddon, versions map[string]string, kubernetesVersion string) bool {
+	if _, ok := versions[addon.Name]; ok || addon.MinKubernetesVersion == "" || kubernetesVersion == "" {
+		return true
+	}
+	return rancher.CompareKubernetesVersions(kubernetesVersion, addon.MinKubernetesVersion) >= 0
+}
+
+// Returns the names of the addons given by cluster_addons, or the addons the user confirms of
+// those that run on the cluster's Kubernetes version. The cert-manager addon prompts for itself.
+func getSelectedClusterAddons(conf config.Config, versions map[string]string, kubernetesVersion string) ([]string, error) {
+	if conf.IsSet("cluster_addons") {
+		selected := conf.GetStringSlice("cluster_addons")
+		for _, name := range selected {
+			addon, ok := getClusterAddon(name)
+			if !ok {
+				names := []string{}
+				for _, addon := range clusterAddons {
+					names = append(names, addon.Name)
+				}
+				return nil, util.ConfigError(fmt.Errorf("Invalid cluster addon '%s', must be one of %s", name, strings.Join(names, ", ")))
+			}
+			if !supportsKubernetesVersion(addon, versions, kubernetesVersion) {
+				return nil, util.ConfigError(fmt.Errorf("Cluster addon '%s' %s requires Kubernetes %s or later, the cluster runs %s. Set the version of %s in cluster_addon_versions to one that supports it.", addon.Name, addon.DefaultVersion, addon.MinKubernetesVersion, kubernetesVersion, addon.Name))
+			}
+		}
+		return selected, nil
+	} else if conf.GetBool("non-interactive") {
+		return []string{}, nil
+	}
+
+	selected := []string{}
+	for _, addon := range clusterAddons {
+		if addon.Name == certManagerAddonName {
+			continue
+		}
+		if !supportsKubernetesVersion(addon, versions, kubernetesVersion) {
+			fmt.Printf("Cluster addon '%s' %s requires Kubernetes %s or later, it can't be installed on %s.\n", addon.Name, addon.DefaultVersion, addon.MinKubernetesVersion, kubernetesVersion)
+			continue
+		}
+
+		label := fmt.Sprintf("Install %s", addon.Name)
+		confirmed, err := util.PromptForConfirmation(label, label)
+		if err != nil {
+			return nil, err
+		}
+		if confirmed {
+			selected = append(selected, addon.Name)
+		}
+	}
+	return selected, nil
+}
+
+// Adds the selected addons of the curated set to the given cluster. They're installed once the
+// cluster is active, through a kubeconfig of the cluster generated by Rancher. Versions are
+// overridden with cluster_addon_versions, e.g. {metrics-server: v0.3.6}.
+func newClusterAddons(conf config.Config, selectedClusterKey string, currentState state.State) error {
+	versions := map[string]string{}
+	if conf.IsSet("cluster_addon_versions") {
+		versions = conf.GetStringMapString("cluster_addon_versions")
+	}
+
+	kubernetesVersion := currentState.Get(fmt.Sprintf("module.%s.k8s_version", selectedClusterKey))
+	selected, err := getSelectedClusterAddons(conf, versions, kubernetesVersion)
+	if err != nil {
+		return err
+	}
+
+	baseSource := defaultSourceURL
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
+	}
+
+	baseSourceRef := defaultSourceRef
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
+	}
+
+	rancherAccessKey, rancherSecretKey := currentState.RancherAPIKeyReferences()
+	for _, name := range selected {
+		addon, _ := getClusterAddon(name)
+
+		if addon.Name == certManagerAddonName {
+			// Installed by the cert-manager addon, unless cert_manager says otherwise
+			if !conf.IsSet("cert_manager") {
+				conf.Set("cert_manager", true)
+			}
+			continue
+		}
+
+		version := addon.DefaultVersion
+		if v, ok := versions[addon.Name]; ok {
+			version = v
+		}
+
+		cfg := clusterAddonTerraformConfig{
+			Source: fmt.Sprintf("%s//%s?ref=%s", baseSource, clusterAddonTerraformModulePath, baseSourceRef),
+			Name:   addon.Name,
+
+			RancherAPIURL:    "${module.cluster-manager.rancher_url}",
+			RancherAccessKey: rancherAccessKey,
+			RancherSecretKey: rancherSecretKey,
+			RancherClusterID: fmt.Sprintf("${module.%s.rancher_cluster_id}", selectedClusterKey),
+		}
+
+		if addon.ManifestURL != "" {
+			cfg.ManifestURL = fmt.Sprintf(addon.ManifestURL, version)
+		} else {
+			cfg.HelmRepo = addon.HelmRepo
+			cfg.HelmChart = addon.HelmChart
+			cfg.HelmVersion = version
+			cfg.HelmNamespace = addon.HelmNamespace
+			cfg.HelmValues = addon.HelmValues
+		}
+
+		err = currentState.AddAddon(selectedClusterKey, addon.Name, &cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestNewClusterAddons(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_addons", []string{"metrics-server", "ingress-nginx", "cert-manager"})
+	conf.Set("cluster_addon_versions", map[string]string{"metrics-server": "v0.3.6"})
+
+	currentState, err := state.New("test", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentState.AddCluster("aws", "dev", map[string]string{"name": "dev"})
+
+	err = newClusterAddons(conf, "cluster_aws_dev", currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The addons can only be looked up once the state is reloaded
+	currentState, err = state.New(currentState.Name, currentState.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if manifest := currentState.Get("module.addon_aws_dev_metrics-server.manifest_url"); manifest != "https://github.com/kubernetes-sigs/metrics-server/releases/download/v0.3.6/components.yaml" {
+		t.Errorf("Wrong manifest of metrics-server, received %s", manifest)
+	}
+	if chart := currentState.Get("module.addon_aws_dev_ingress-nginx.helm_chart"); chart != "ingress-nginx" {
+		t.Errorf("Wrong Helm chart of ingress-nginx, received %s", chart)
+	}
+	if version := currentState.Get("module.addon_aws_dev_ingress-nginx.helm_version"); version != "2.11.1" {
+		t.Errorf("Expected the default version of ingress-nginx, received %s", version)
+	}
+	if clusterID := currentState.Get("module.addon_aws_dev_ingress-nginx.rancher_cluster_id"); clusterID != "${module.cluster_aws_dev.rancher_cluster_id}" {
+		t.Errorf("Wrong cluster id, received %s", clusterID)
+	}
+	if currentState.Get("module.addon_aws_dev_dashboard.manifest_url") != "" {
+		t.Error("Expected the dashboard not to be installed")
+	}
+
+	// cert-manager is left to its own addon
+	if currentState.Get("module.addon_aws_dev_cert-manager.name") != "" {
+		t.Error("Expected no cert-manager module from the addon catalog")
+	}
+	if !conf.GetBool("cert_manager") {
+		t.Error("Expected cert_manager to be set")
+	}
+}
+
+func TestNewClusterAddonsInvalid(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_addons", []string{"metrics-server", "istio"})
+
+	currentState, err := state.New("test", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newClusterAddons(conf, "cluster_aws_dev", currentState)
+	expected := "Invalid cluster addon 'istio', must be one of metrics-server, ingress-nginx, cert-manager, dashboard"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestNewClusterAddonsKubernetesVersion(t *testing.T) {
+	newState := func() state.State {
+		currentState, err := state.New("test", []byte("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		currentState.AddCluster("aws", "dev", map[string]string{"name": "dev", "k8s_version": "v1.10.0-rancher1-1"})
+		return currentState
+	}
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_addons", []string{"metrics-server", "dashboard"})
+
+	err := newClusterAddons(conf, "cluster_aws_dev", newState())
+	expected := "Cluster addon 'metrics-server' v0.3.7 requires Kubernetes v1.11 or later, the cluster runs v1.10.0-rancher1-1. Set the version of metrics-server in cluster_addon_versions to one that supports it."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+
+	// Versions given by the user aren't checked
+	conf.Set("cluster_addon_versions", map[string]string{"metrics-server": "v0.3.1", "dashboard": "v1.10.1"})
+	err = newClusterAddons(conf, "cluster_aws_dev", newState())
+	if err != nil {
+		t.Errorf("Expected the given versions to be installed, received %v", err)
+	}
+
+	addon, _ := getClusterAddon("dashboard")
+	if !supportsKubernetesVersion(addon, map[string]string{}, "v1.18.3-rancher1-1") || supportsKubernetesVersion(addon, map[string]string{}, "v1.17.6-rancher2-1") {
+		t.Error("Expected dashboard v2.0.0 to only run on Kubernetes v1.18 and later")
+	}
+}
@@ -0,0 +1,274 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	certManagerTerraformModulePath = "terraform/modules/k8s-cert-manager"
+	certManagerAddonName           = "cert-manager"
+)
+
+type certManagerTerraformConfig struct {
+	Source string `json:"source"`
+
+	RancherAPIURL    string `json:"rancher_api_url"`
+	RancherAccessKey string `json:"rancher_access_key"`
+	RancherSecretKey string `json:"rancher_secret_key"`
+	RancherClusterID string `json:"rancher_cluster_id"`
+
+	CertManagerVersion string `json:"cert_manager_version,omitempty"`
+
+	LetsEncryptChallenge   string `json:"letsencrypt_challenge"`
+	LetsEncryptEmail       string `json:"letsencrypt_email,omitempty"`
+	LetsEncryptEnvironment string `json:"letsencrypt_environment,omitempty"`
+	LetsEncryptDNSProvider string `json:"letsencrypt_dns_provider,omitempty"`
+
+	Route53AccessKey string `json:"route53_access_key,omitempty"`
+	Route53SecretKey string `json:"route53_secret_key,omitempty"`
+	Route53Region    string `json:"route53_region,omitempty"`
+
+	CloudflareEmail  string `json:"cloudflare_email,omitempty"`
+	CloudflareAPIKey string `json:"cloudflare_api_key,omitempty"`
+}
+
+// Optionally adds the cert-manager addon, and a Let's Encrypt ClusterIssuer, to the given cluster.
+func newCertManagerAddon(conf config.Config, selectedClusterKey string, currentState state.State) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	// Install cert-manager
+	installCertManager := false
+	if conf.IsSet("cert_manager") {
+		installCertManager = conf.GetBool("cert_manager")
+	} else if !nonInteractiveMode {
+		confirmed, err := util.PromptForConfirmation("Install cert-manager", "Install cert-manager")
+		if err != nil {
+			return err
+		}
+		installCertManager = confirmed
+	}
+
+	if !installCertManager {
+		return nil
+	}
+
+	rancherAccessKey, rancherSecretKey := currentState.RancherAPIKeyReferences()
+	cfg := certManagerTerraformConfig{
+		RancherAPIURL:    "${module.cluster-manager.rancher_url}",
+		RancherAccessKey: rancherAccessKey,
+		RancherSecretKey: rancherSecretKey,
+		RancherClusterID: fmt.Sprintf("${module.%s.rancher_cluster_id}", selectedClusterKey),
+	}
+
+	baseSource := defaultSourceURL
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
+	}
+
+	baseSourceRef := defaultSourceRef
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
+	}
+
+	cfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, certManagerTerraformModulePath, baseSourceRef)
+
+	// cert-manager Version
+	if conf.IsSet("cert_manager_version") {
+		cfg.CertManagerVersion = conf.GetString("cert_manager_version")
+	}
+
+	// Let's Encrypt ACME Challenge
+	challengeOptions := []struct {
+		Name  string
+		Value string
+	}{
+		{"None", "none"},
+		{"HTTP-01", "http01"},
+		{"DNS-01", "dns01"},
+	}
+	if conf.IsSet("letsencrypt_challenge") {
+		cfg.LetsEncryptChallenge = conf.GetString("letsencrypt_challenge")
+	} else if nonInteractiveMode {
+		cfg.LetsEncryptChallenge = "none"
+	} else {
+		prompt := promptui.Select{
+			Label: "Let's Encrypt ClusterIssuer",
+			Items: challengeOptions,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ .Name }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Let's Encrypt ClusterIssuer:" | bold}} {{ .Name }}`, promptui.IconGood),
+			},
+		}
+
+		i, _, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		cfg.LetsEncryptChallenge = challengeOptions[i].Value
+	}
+
+	switch cfg.LetsEncryptChallenge {
+	case "none":
+		return currentState.AddAddon(selectedClusterKey, certManagerAddonName, &cfg)
+	case "http01", "dns01":
+	default:
+		return util.ConfigError(fmt.Errorf("Invalid letsencrypt_challenge '%s', must be 'none', 'http01' or 'dns01'", cfg.LetsEncryptChallenge))
+	}
+
+	// Let's Encrypt Email
+	if conf.IsSet("letsencrypt_email") {
+		cfg.LetsEncryptEmail = conf.GetString("letsencrypt_email")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("letsencrypt_email must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Let's Encrypt Email",
+			Validate: func(input string) error {
+				if input == "" {
+					return errors.New("email cannot be blank")
+				}
+				return nil
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		cfg.LetsEncryptEmail = result
+	}
+
+	// Let's Encrypt Environment
+	if conf.IsSet("letsencrypt_environment") {
+		cfg.LetsEncryptEnvironment = conf.GetString("letsencrypt_environment")
+	} else if nonInteractiveMode {
+		cfg.LetsEncryptEnvironment = "staging"
+	} else {
+		prompt := promptui.Select{
+			Label: "Let's Encrypt Environment",
+			Items: []string{"staging", "production"},
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Let's Encrypt Environment:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		cfg.LetsEncryptEnvironment = value
+	}
+
+	if cfg.LetsEncryptEnvironment != "staging" && cfg.LetsEncryptEnvironment != "production" {
+		return util.ConfigError(fmt.Errorf("Invalid letsencrypt_environment '%s', must be 'staging' or 'production'", cfg.LetsEncryptEnvironment))
+	}
+
+	if cfg.LetsEncryptChallenge == "dns01" {
+		err := getCertManagerDNSProviderConfig(conf, &cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return currentState.AddAddon(selectedClusterKey, certManagerAddonName, &cfg)
+}
+
+// Prompts for the DNS provider and credentials used to solve DNS-01 challenges.
+func getCertManagerDNSProviderConfig(conf config.Config, cfg *certManagerTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	// DNS Provider
+	if conf.IsSet("letsencrypt_dns_provider") {
+		cfg.LetsEncryptDNSProvider = conf.GetString("letsencrypt_dns_provider")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("letsencrypt_dns_provider must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "DNS Provider",
+			Items: []string{"route53", "cloudflare"},
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "DNS Provider:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		cfg.LetsEncryptDNSProvider = value
+	}
+
+	switch cfg.LetsEncryptDNSProvider {
+	case "route53":
+		accessKey, err := promptForCertManagerValue(conf, "route53_access_key", "Route53 Access Key", false)
+		if err != nil {
+			return err
+		}
+		cfg.Route53AccessKey = accessKey
+
+		secretKey, err := promptForCertManagerValue(conf, "route53_secret_key", "Route53 Secret Key", true)
+		if err != nil {
+			return err
+		}
+		cfg.Route53SecretKey = secretKey
+
+		if conf.IsSet("route53_region") {
+			cfg.Route53Region = conf.GetString("route53_region")
+		}
+	case "cloudflare":
+		email, err := promptForCertManagerValue(conf, "cloudflare_email", "Cloudflare Email", false)
+		if err != nil {
+			return err
+		}
+		cfg.CloudflareEmail = email
+
+		apiKey, err := promptForCertManagerValue(conf, "cloudflare_api_key", "Cloudflare API Key", true)
+		if err != nil {
+			return err
+		}
+		cfg.CloudflareAPIKey = apiKey
+	default:
+		return util.ConfigError(fmt.Errorf("Invalid letsencrypt_dns_provider '%s', must be 'route53' or 'cloudflare'", cfg.LetsEncryptDNSProvider))
+	}
+
+	return nil
+}
+
+func promptForCertManagerValue(conf config.Config, key, label string, secret bool) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	prompt := promptui.Prompt{
+		Label: label,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+	if secret {
+		prompt.Mask = '*'
+	}
+
+	return prompt.Run()
+}
@@ -0,0 +1,226 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	tritonIngressLoadBalancerTerraformModulePath = "terraform/modules/triton-ingress-lb"
+	awsIngressLoadBalancerTerraformModulePath    = "terraform/modules/aws-ingress-lb"
+	ingressLoadBalancerAddonName                 = "ingress-lb"
+
+	defaultIngressLoadBalancerTritonNetwork = "Joyent-SDC-Public"
+
+	// AWS load balancer and target group names are at most 32 characters, the longest
+	// is {name}-ingress-https
+	maxAWSIngressLoadBalancerNameLength = 32 - len("-ingress-https")
+)
+
+// HAProxy on a Triton instance, which sends traffic to the worker nodes of the cluster's CNS
+// service.
+type tritonIngressLoadBalancerTerraformConfig struct {
+	Source string `json:"source"`
+
+	Name string `json:"name"`
+
+	TritonAccount string `json:"triton_account"`
+	TritonKeyPath string `json:"triton_key_path"`
+	TritonKeyID   string `json:"triton_key_id"`
+	TritonURL     string `json:"triton_url,omitempty"`
+
+	TritonNetworkNames   []string `json:"triton_network_names,omitempty"`
+	TritonImageName      string   `json:"triton_image_name,omitempty"`
+	TritonImageVersion   string   `json:"triton_image_version,omitempty"`
+	TritonMachinePackage string   `json:"triton_machine_package,omitempty"`
+
+	TritonCNSService string `json:"triton_cns_service"`
+	TritonCNSSuffix  string `json:"triton_cns_suffix,omitempty"`
+}
+
+// A network load balancer, whose target groups the worker nodes register with.
+type awsIngressLoadBalancerTerraformConfig struct {
+	Source string `json:"source"`
+
+	Name string `json:"name"`
+
+	AWSAccessKey string `json:"aws_access_key"`
+	AWSSecretKey string `json:"aws_secret_key"`
+	awsRoleConfig
+	AWSRegion string `json:"aws_region"`
+
+	AWSSubnetID        string `json:"aws_subnet_id"`
+	AWSSecurityGroupID string `json:"aws_security_group_id"`
+}
+
+// Optionally adds a load balancer in front of the ingress ports (80 and 443) of the given
+// cluster's worker nodes. It must be added before the nodes, which add themselves to it.
+func newIngressLoadBalancerAddon(conf config.Config, selectedClusterKey string, currentState state.State) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	// clusterKey is `cluster_{provider}_{clusterName}`
+	parts := strings.Split(selectedClusterKey, "_")
+	if len(parts) < 3 {
+		return fmt.Errorf("Could not determine cloud provider for cluster '%s'", selectedClusterKey)
+	}
+	provider := parts[1]
+	supported := provider == "triton" || provider == "aws"
+
+	addLoadBalancer := false
+	if conf.IsSet("ingress_load_balancer") {
+		addLoadBalancer = conf.GetBool("ingress_load_balancer")
+	} else if !nonInteractiveMode && supported {
+		confirmed, err := util.PromptForConfirmation("Add an ingress load balancer in front of the worker nodes", "Add an ingress load balancer")
+		if err != nil {
+			return err
+		}
+		addLoadBalancer = confirmed
+	}
+
+	if !addLoadBalancer {
+		return nil
+	}
+
+	if !supported {
+		return fmt.Errorf("ingress_load_balancer is only supported for triton and aws clusters, not %s", provider)
+	}
+
+	baseSource := defaultSourceURL
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
+	}
+
+	baseSourceRef := defaultSourceRef
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
+	}
+
+	// Cluster names may contain dots, which load balancer and CNS service names can't
+	name := strings.Replace(currentState.Get(fmt.Sprintf("module.%s.name", selectedClusterKey)), ".", "-", -1)
+
+	if provider == "aws" {
+		if len(name) > maxAWSIngressLoadBalancerNameLength {
+			return fmt.Errorf("The ingress load balancer's name is based on the cluster name, which must be at most %d characters long for AWS", maxAWSIngressLoadBalancerNameLength)
+		}
+
+		clusterCreds, clusterRole := getAWSRoleConfigFromState(currentState, selectedClusterKey)
+		cfg := awsIngressLoadBalancerTerraformConfig{
+			Name: name,
+
+			// Grab variables from cluster config
+			AWSAccessKey:  clusterCreds.AccessKey,
+			AWSSecretKey:  clusterCreds.SecretKey,
+			awsRoleConfig: awsRoleConfig{AWSSessionToken: clusterRole.AWSSessionToken},
+			AWSRegion:     currentState.Get(fmt.Sprintf("module.%s.aws_region", selectedClusterKey)),
+
+			// Reference terraform output variables from cluster module
+			AWSSubnetID:        fmt.Sprintf("${module.%s.aws_subnet_id}", selectedClusterKey),
+			AWSSecurityGroupID: fmt.Sprintf("${module.%s.aws_security_group_id}", selectedClusterKey),
+		}
+		cfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, awsIngressLoadBalancerTerraformModulePath, baseSourceRef)
+
+		return currentState.AddAddon(selectedClusterKey, ingressLoadBalancerAddonName, &cfg)
+	}
+
+	cfg := tritonIngressLoadBalancerTerraformConfig{
+		Name: name,
+
+		// Grab variables from cluster config
+		TritonAccount: currentState.Get(fmt.Sprintf("module.%s.triton_account", selectedClusterKey)),
+		TritonKeyPath: currentState.Get(fmt.Sprintf("module.%s.triton_key_path", selectedClusterKey)),
+		TritonKeyID:   currentState.Get(fmt.Sprintf("module.%s.triton_key_id", selectedClusterKey)),
+		TritonURL:     currentState.Get(fmt.Sprintf("module.%s.triton_url", selectedClusterKey)),
+
+		TritonCNSService: name + "-ingress",
+		TritonCNSSuffix:  conf.GetString("ingress_lb_triton_cns_suffix"),
+	}
+	cfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, tritonIngressLoadBalancerTerraformModulePath, baseSourceRef)
+
+	// Triton Network Names, one of them must be shared with the worker nodes
+	if conf.IsSet("ingress_lb_triton_network_names") {
+		cfg.TritonNetworkNames = conf.GetStringSlice("ingress_lb_triton_network_names")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label:   "Load Balancer Triton Networks (comma separated)",
+			Default: defaultIngressLoadBalancerTritonNetwork,
+			Validate: func(input string) error {
+				if strings.TrimSpace(input) == "" {
+					return errors.New("At least one network must be specified")
+				}
+				return nil
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		for _, network := range strings.Split(result, ",") {
+			cfg.TritonNetworkNames = append(cfg.TritonNetworkNames, strings.TrimSpace(network))
+		}
+	}
+
+	// Triton Image Name and Triton Image Version, an Ubuntu image by default
+	imageName, err := promptForIngressLoadBalancerValue(conf, "ingress_lb_triton_image_name", "Load Balancer Triton Image Name", "ubuntu-certified-16.04")
+	if err != nil {
+		return err
+	}
+	imageVersion, err := promptForIngressLoadBalancerValue(conf, "ingress_lb_triton_image_version", "Load Balancer Triton Image Version", "20170619.1")
+	if err != nil {
+		return err
+	}
+	if (imageName == "") != (imageVersion == "") {
+		return util.ConfigError(errors.New("Both ingress_lb_triton_image_name and ingress_lb_triton_image_version must be specified"))
+	}
+	cfg.TritonImageName = imageName
+	cfg.TritonImageVersion = imageVersion
+
+	// Triton Machine Package
+	cfg.TritonMachinePackage, err = promptForIngressLoadBalancerValue(conf, "ingress_lb_triton_machine_package", "Load Balancer Triton Machine Package", "k4-highcpu-kvm-1.75G")
+	if err != nil {
+		return err
+	}
+
+	return currentState.AddAddon(selectedClusterKey, ingressLoadBalancerAddonName, &cfg)
+}
+
+// Returns the key of the given cluster's ingress load balancer addon, if it has one.
+func getIngressLoadBalancerKey(currentState state.State, clusterKey string) (string, bool) {
+	// addonKey is `addon_{provider}_{clusterName}_{addonName}`
+	addonKey := fmt.Sprintf("%s_%s", strings.Replace(clusterKey, "cluster_", "addon_", 1), ingressLoadBalancerAddonName)
+	if currentState.Get(fmt.Sprintf("module.%s.source", addonKey)) == "" {
+		return "", false
+	}
+
+	return addonKey, true
+}
+
+// In non-interactive mode unset values are left to the terraform module's defaults.
+func promptForIngressLoadBalancerValue(conf config.Config, key, label, defaultValue string) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", nil
+	}
+
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: defaultValue,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+
+	return prompt.Run()
+}
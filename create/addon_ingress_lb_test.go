@@ -0,0 +1,64 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestNewIngressLoadBalancerAddon(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+
+	currentState, err := state.New("test", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentState.AddCluster("aws", "dev", map[string]string{"name": "dev", "aws_region": "us-west-2"})
+	currentState.AddCluster("gcp", "dev", map[string]string{"name": "dev"})
+	currentState.AddCluster("aws", "development-cluster", map[string]string{"name": "development-cluster"})
+
+	// Not requested
+	err = newIngressLoadBalancerAddon(conf, "cluster_aws_dev", currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := getIngressLoadBalancerKey(currentState, "cluster_aws_dev"); ok {
+		t.Error("Expected no ingress load balancer")
+	}
+
+	conf.Set("ingress_load_balancer", true)
+	err = newIngressLoadBalancerAddon(conf, "cluster_aws_dev", currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The addon can only be looked up once the state is reloaded
+	currentState, err = state.New(currentState.Name, currentState.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, ok := getIngressLoadBalancerKey(currentState, "cluster_aws_dev")
+	if !ok || key != "addon_aws_dev_ingress-lb" {
+		t.Fatalf("Wrong output, expected addon_aws_dev_ingress-lb, received %q", key)
+	}
+	if name := currentState.Get("module.addon_aws_dev_ingress-lb.name"); name != "dev" {
+		t.Errorf("Wrong output, expected dev, received %s", name)
+	}
+	if subnet := currentState.Get("module.addon_aws_dev_ingress-lb.aws_subnet_id"); subnet != "${module.cluster_aws_dev.aws_subnet_id}" {
+		t.Errorf("Wrong output, received %s", subnet)
+	}
+
+	err = newIngressLoadBalancerAddon(conf, "cluster_aws_development-cluster", currentState)
+	if err == nil {
+		t.Error("Expected the cluster name to be too long for an AWS load balancer")
+	}
+
+	err = newIngressLoadBalancerAddon(conf, "cluster_gcp_dev", currentState)
+	expected := "ingress_load_balancer is only supported for triton and aws clusters, not gcp"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
@@ -0,0 +1,127 @@
+package create
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Logs who changed what, without the contents of secrets, and leaves out the read only
+// requests system components make all the time.
+const defaultKubernetesAuditPolicy = `apiVersion: audit.k8s.io/v1beta1
+kind: Policy
+omitStages:
+- RequestReceived
+rules:
+- level: None
+  users: ["system:kube-proxy"]
+  verbs: ["watch"]
+- level: None
+  userGroups: ["system:nodes"]
+  verbs: ["get", "list", "watch"]
+- level: None
+  nonResourceURLs: ["/healthz*", "/version", "/swagger*"]
+- level: Metadata
+  resources:
+  - group: ""
+    resources: ["secrets", "configmaps"]
+  - group: authentication.k8s.io
+    resources: ["tokenreviews"]
+- level: Request
+  verbs: ["create", "update", "patch", "delete", "deletecollection"]
+- level: Metadata
+`
+
+// Asks whether the API server writes an audit log, and with which policy. The policy is
+// written to the cluster's control nodes.
+func getKubernetesAuditLogConfig(conf config.Config, cfg *baseClusterTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	enabled := false
+	if conf.IsSet("k8s_audit_log") {
+		enabled = conf.GetBool("k8s_audit_log")
+	} else if !nonInteractiveMode {
+		confirmed, err := util.PromptForConfirmation("Enable the Kubernetes audit log", "Audit log")
+		if err != nil {
+			return err
+		}
+		enabled = confirmed
+	}
+
+	if !enabled {
+		return nil
+	}
+	cfg.KubernetesAuditLog = "true"
+
+	// Audit Policy
+	policyPath := ""
+	if conf.IsSet("k8s_audit_policy_path") {
+		policyPath = conf.GetString("k8s_audit_policy_path")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label: "Audit policy file (leave empty for the default policy)",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		policyPath = result
+	}
+
+	policy := []byte(defaultKubernetesAuditPolicy)
+	if policyPath != "" {
+		expandedPolicyPath, err := homedir.Expand(policyPath)
+		if err != nil {
+			return err
+		}
+
+		policy, err = ioutil.ReadFile(expandedPolicyPath)
+		if err != nil {
+			return fmt.Errorf("Unable to read k8s_audit_policy_path '%s': %s", policyPath, err)
+		}
+	}
+	cfg.KubernetesAuditPolicy = base64.StdEncoding.EncodeToString(policy)
+
+	// Log rotation
+	var err error
+	cfg.KubernetesAuditLogMaxAge, err = getAuditLogLimit(conf, "k8s_audit_log_max_age")
+	if err != nil {
+		return err
+	}
+
+	cfg.KubernetesAuditLogMaxBackups, err = getAuditLogLimit(conf, "k8s_audit_log_max_backups")
+	if err != nil {
+		return err
+	}
+
+	cfg.KubernetesAuditLogMaxSize, err = getAuditLogLimit(conf, "k8s_audit_log_max_size")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Returns the given log rotation limit, or an empty string for the module's default.
+func getAuditLogLimit(conf config.Config, key string) (string, error) {
+	if !conf.IsSet(key) {
+		return "", nil
+	}
+
+	value := conf.GetString(key)
+	num, err := strconv.Atoi(value)
+	if err != nil || num <= 0 {
+		return "", errors.New(key + " must be a number greater than 0")
+	}
+
+	return value, nil
+}
@@ -0,0 +1,212 @@
+package create
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/manifoldco/promptui"
+)
+
+const defaultAWSRoleSessionName = "triton-kubernetes"
+
+var (
+	awsRoleARNRegexp         = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+	awsRoleSessionNameRegexp = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+)
+
+// The IAM role an AWS module assumes. Terraform can't prompt for an MFA token, so the role is
+// assumed again before every terraform run, and the module references its temporary credentials,
+// see state.SetAWSRoleKeys. The session token is the reference, it's never stored.
+type awsRoleConfig struct {
+	AWSSessionToken    string `json:"aws_session_token,omitempty"`
+	AWSRoleARN         string `json:"aws_role_arn,omitempty"`
+	AWSExternalID      string `json:"aws_external_id,omitempty"`
+	AWSRoleSessionName string `json:"aws_role_session_name,omitempty"`
+	AWSMFASerial       string `json:"aws_mfa_serial,omitempty"`
+}
+
+// Asks for the IAM role to assume with the user's keys, aws_role_arn. Without a role the
+// keys are used as they are, with one the temporary credentials of the role are returned.
+func getAWSRoleConfig(conf config.Config, creds awsCredentials) (awsCredentials, awsRoleConfig, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	role := awsRoleConfig{}
+
+	// AWS Role ARN
+	if conf.IsSet("aws_role_arn") {
+		role.AWSRoleARN = conf.GetString("aws_role_arn")
+	} else if !nonInteractiveMode {
+		assumeRole, err := util.PromptForConfirmation("Assume an IAM role with these keys", "Assume an IAM role")
+		if err != nil {
+			return awsCredentials{}, awsRoleConfig{}, err
+		}
+
+		if assumeRole {
+			prompt := promptui.Prompt{
+				Label:    "AWS Role ARN",
+				Validate: validateAWSRoleARN,
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return awsCredentials{}, awsRoleConfig{}, err
+			}
+			role.AWSRoleARN = result
+		}
+	}
+
+	if role.AWSRoleARN == "" {
+		return creds, awsRoleConfig{}, nil
+	}
+	err := validateAWSRoleARN(role.AWSRoleARN)
+	if err != nil {
+		return awsCredentials{}, awsRoleConfig{}, err
+	}
+
+	// AWS External ID
+	if conf.IsSet("aws_external_id") {
+		role.AWSExternalID = conf.GetString("aws_external_id")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label: "AWS External ID (leave empty if the role doesn't require one)",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return awsCredentials{}, awsRoleConfig{}, err
+		}
+		role.AWSExternalID = result
+	}
+
+	// AWS Role Session Name
+	if conf.IsSet("aws_role_session_name") {
+		role.AWSRoleSessionName = conf.GetString("aws_role_session_name")
+	} else if nonInteractiveMode {
+		role.AWSRoleSessionName = defaultAWSRoleSessionName
+	} else {
+		prompt := promptui.Prompt{
+			Label:    "AWS Role Session Name",
+			Validate: validateAWSRoleSessionName,
+			Default:  defaultAWSRoleSessionName,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return awsCredentials{}, awsRoleConfig{}, err
+		}
+		role.AWSRoleSessionName = result
+	}
+	err = validateAWSRoleSessionName(role.AWSRoleSessionName)
+	if err != nil {
+		return awsCredentials{}, awsRoleConfig{}, err
+	}
+
+	// AWS MFA Serial
+	if conf.IsSet("aws_mfa_serial") {
+		role.AWSMFASerial = conf.GetString("aws_mfa_serial")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label: "AWS MFA Device ARN (leave empty if the role doesn't require MFA)",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return awsCredentials{}, awsRoleConfig{}, err
+		}
+		role.AWSMFASerial = result
+	}
+
+	roleCreds, err := assumeAWSRole(conf, creds, role)
+	if err != nil {
+		return awsCredentials{}, awsRoleConfig{}, err
+	}
+
+	return roleCreds, role, nil
+}
+
+// Returns the role of the given module, with the module's credentials as they're stored. Those of
+// a module assuming a role reference the role's temporary credentials, see shell.AWSCredentials.
+func getAWSRoleConfigFromState(currentState state.State, moduleKey string) (awsCredentials, awsRoleConfig) {
+	get := func(key string) string {
+		return currentState.Get(fmt.Sprintf("module.%s.%s", moduleKey, key))
+	}
+
+	creds := awsCredentials{
+		AccessKey:    get("aws_access_key"),
+		SecretKey:    get("aws_secret_key"),
+		SessionToken: get("aws_session_token"),
+	}
+	role := awsRoleConfig{
+		AWSSessionToken:    creds.SessionToken,
+		AWSRoleARN:         get("aws_role_arn"),
+		AWSExternalID:      get("aws_external_id"),
+		AWSRoleSessionName: get("aws_role_session_name"),
+		AWSMFASerial:       get("aws_mfa_serial"),
+	}
+	return creds, role
+}
+
+// Stores the keys assuming the module's role, the module then references the role's temporary
+// credentials. Modules without a role keep their keys.
+func setAWSRoleKeys(conf config.Config, currentState state.State, moduleKey, accessKey, secretKey string, role awsRoleConfig) error {
+	if role.AWSRoleARN == "" {
+		return nil
+	}
+
+	encryptedSecretKey, err := util.EncryptSecret(conf, secretKey)
+	if err != nil {
+		return err
+	}
+
+	return currentState.SetAWSRoleKeys(moduleKey, accessKey, encryptedSecretKey)
+}
+
+// Assumes the role with creds, with an MFA token, aws_mfa_token, when the role has an MFA device.
+func assumeAWSRole(conf config.Config, creds awsCredentials, role awsRoleConfig) (awsCredentials, error) {
+	assumed, err := util.AssumeAWSRole(conf, creds.AccessKey, creds.SecretKey, util.AWSRole{
+		ARN:         role.AWSRoleARN,
+		ExternalID:  role.AWSExternalID,
+		SessionName: role.AWSRoleSessionName,
+		MFASerial:   role.AWSMFASerial,
+	})
+	if _, ok := err.(awserr.Error); ok {
+		return awsCredentials{}, awsAssumeRoleError(role, err)
+	} else if err != nil {
+		return awsCredentials{}, err
+	}
+
+	return awsCredentials{AccessKey: assumed.AccessKey, SecretKey: assumed.SecretKey, SessionToken: assumed.SessionToken}, nil
+}
+
+func awsAssumeRoleError(role awsRoleConfig, err error) error {
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "AccessDenied" {
+		hint := "Check that the role trusts the user of aws_access_key"
+		if role.AWSExternalID != "" {
+			hint += " and that aws_external_id is the role's external ID"
+		}
+		if role.AWSMFASerial != "" {
+			hint += ", and that aws_mfa_token is a current token of aws_mfa_serial"
+		}
+		return fmt.Errorf("AWS refused to let aws_access_key assume role '%s': %s. %s.", role.AWSRoleARN, strings.TrimSuffix(awsErr.Message(), "."), hint)
+	}
+	return awsCredentialsError(err)
+}
+
+func validateAWSRoleARN(input string) error {
+	if !awsRoleARNRegexp.MatchString(input) {
+		return util.ConfigError(fmt.Errorf("Invalid aws_role_arn '%s', must be the ARN of an IAM role, e.g. arn:aws:iam::123456789012:role/deploy.", input))
+	}
+	return nil
+}
+
+func validateAWSRoleSessionName(input string) error {
+	if !awsRoleSessionNameRegexp.MatchString(input) {
+		return util.ConfigError(fmt.Errorf("Invalid aws_role_session_name '%s', must be 2 to 64 letters, digits or any of _+=,.@-", input))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package create
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestGetAWSRoleConfigWithoutRole(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+
+	userCreds := awsCredentials{AccessKey: "AKIAEXAMPLE", SecretKey: "secret"}
+	creds, role, err := getAWSRoleConfig(conf, userCreds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds != userCreds {
+		t.Errorf("Expected the user's credentials, got %#v", creds)
+	}
+	if role != (awsRoleConfig{}) {
+		t.Errorf("Expected no role, got %#v", role)
+	}
+}
+
+func TestGetAWSRoleConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		settings map[string]string
+		expected string
+	}{
+		{map[string]string{"aws_role_arn": "deploy"}, "Invalid aws_role_arn 'deploy'"},
+		{map[string]string{"aws_role_arn": "arn:aws:iam::123456789012:user/deploy"}, "Invalid aws_role_arn"},
+		{map[string]string{"aws_role_arn": "arn:aws:iam::123456789012:role/deploy", "aws_role_session_name": "my session"}, "Invalid aws_role_session_name 'my session'"},
+		{map[string]string{"aws_role_arn": "arn:aws:iam::123456789012:role/deploy", "aws_mfa_serial": "arn:aws:iam::123456789012:mfa/jane"}, "aws_mfa_token must be specified"},
+		{map[string]string{"aws_role_arn": "arn:aws:iam::123456789012:role/deploy", "aws_mfa_serial": "arn:aws:iam::123456789012:mfa/jane", "aws_mfa_token": "12345"}, "aws_mfa_token must be the 6 digit code"},
+	}
+
+	for _, tc := range testCases {
+		conf := config.New()
+		conf.Set("non-interactive", true)
+		for key, value := range tc.settings {
+			conf.Set(key, value)
+		}
+
+		_, _, err := getAWSRoleConfig(conf, awsCredentials{AccessKey: "AKIAEXAMPLE", SecretKey: "secret"})
+		if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+			t.Errorf("Wrong error for %v, expected %q, received %v", tc.settings, tc.expected, err)
+		}
+	}
+}
+
+func TestGetAWSRoleConfigFromState(t *testing.T) {
+	currentState, err := state.New("test", []byte(`{"module":{"cluster_aws_dev":{
+		"aws_access_key": "ASIAEXAMPLE",
+		"aws_secret_key": "secret",
+		"aws_session_token": "token",
+		"aws_role_arn": "arn:aws:iam::123456789012:role/deploy",
+		"aws_role_session_name": "triton-kubernetes"
+	}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, role := getAWSRoleConfigFromState(currentState, "cluster_aws_dev")
+	expectedCreds := awsCredentials{AccessKey: "ASIAEXAMPLE", SecretKey: "secret", SessionToken: "token"}
+	if creds != expectedCreds {
+		t.Errorf("Expected %#v, got %#v", expectedCreds, creds)
+	}
+	expectedRole := awsRoleConfig{
+		AWSSessionToken:    "token",
+		AWSRoleARN:         "arn:aws:iam::123456789012:role/deploy",
+		AWSRoleSessionName: "triton-kubernetes",
+	}
+	if role != expectedRole {
+		t.Errorf("Expected %#v, got %#v", expectedRole, role)
+	}
+}
+
+func TestAWSAssumeRoleError(t *testing.T) {
+	role := awsRoleConfig{
+		AWSRoleARN:    "arn:aws:iam::123456789012:role/deploy",
+		AWSExternalID: "example",
+		AWSMFASerial:  "arn:aws:iam::123456789012:mfa/jane",
+	}
+	err := awsAssumeRoleError(role, awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code.", nil))
+	expected := "AWS refused to let aws_access_key assume role 'arn:aws:iam::123456789012:role/deploy': MultiFactorAuthentication failed with invalid MFA one time pass code. Check that the role trusts the user of aws_access_key and that aws_external_id is the role's external ID, and that aws_mfa_token is a current token of aws_mfa_serial."
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err)
+	}
+
+	err = awsAssumeRoleError(role, awserr.New("InvalidClientTokenId", "The security token included in the request is invalid.", nil))
+	if !strings.HasPrefix(err.Error(), "AWS rejected aws_access_key") {
+		t.Errorf("Expected the error of the access key, got %q", err)
+	}
+}
@@ -0,0 +1,358 @@
+package create
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/manifoldco/promptui"
+)
+
+// Asks which availability zones of the region the nodes of the cluster are spread across, so
+// the cluster survives the loss of a zone. Each zone gets a subnet, aws_zone_subnet_cidrs or
+// the blocks of the VPC that follow aws_subnet_cidr. Without zones, every node is in the
+// cluster's subnet.
+func getAWSAvailabilityZonesConfig(conf config.Config, ec2Client *ec2.EC2, cfg *awsClusterTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	availableZones, err := getAWSAvailableZones(ec2Client)
+	if err != nil {
+		return err
+	}
+
+	// AWS Availability Zones
+	zones := []string{}
+	if conf.IsSet("aws_availability_zones") {
+		// A list, or a comma separated string
+		zones = splitCommaSeparated(strings.Join(conf.GetStringSlice("aws_availability_zones"), ","))
+	} else if !nonInteractiveMode {
+		spread, err := util.PromptForConfirmation("Spread nodes across availability zones", "Availability Zones")
+		if err != nil {
+			return err
+		}
+
+		if spread {
+			prompt := promptui.Prompt{
+				Label: "AWS Availability Zones, comma separated",
+				Validate: func(input string) error {
+					return validateAWSAvailabilityZones(splitCommaSeparated(input), availableZones)
+				},
+				Default: strings.Join(availableZones, ","),
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return err
+			}
+			zones = splitCommaSeparated(result)
+		}
+	}
+
+	err = validateAWSAvailabilityZones(zones, availableZones)
+	if err != nil {
+		return err
+	}
+	if len(zones) == 0 {
+		return nil
+	}
+
+	// AWS Zone Subnet CIDRs
+	cidrs := []string{}
+	if conf.IsSet("aws_zone_subnet_cidrs") {
+		cidrs = splitCommaSeparated(strings.Join(conf.GetStringSlice("aws_zone_subnet_cidrs"), ","))
+	} else {
+		cidrs, err = getAWSZoneSubnetCIDRs("aws_zone_subnet_cidrs", cfg.AWSVPCCIDR, cfg.AWSSubnetCIDR, len(zones))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validateAWSZoneSubnetCIDRs("aws_zone_subnet_cidrs", cidrs, len(zones), cfg.AWSVPCCIDR, cfg.AWSSubnetCIDR)
+	if err != nil {
+		return err
+	}
+
+	cfg.AWSAvailabilityZones = strings.Join(zones, ",")
+	cfg.AWSZoneSubnetCIDRs = strings.Join(cidrs, ",")
+
+	return nil
+}
+
+// Returns the names of the available zones of the client's region
+func getAWSAvailableZones(ec2Client *ec2.EC2) ([]string, error) {
+	zonesResult, err := ec2Client.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: []*string{aws.String("available")},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	availableZones := []string{}
+	for _, zone := range zonesResult.AvailabilityZones {
+		if zone.ZoneName != nil {
+			availableZones = append(availableZones, *zone.ZoneName)
+		}
+	}
+	return availableZones, nil
+}
+
+// Puts each host of an HA cluster manager in its own availability zone and subnet, so the
+// manager and its load balancer survive the loss of a zone. The hosts go to the first zones of
+// the region, in regions with fewer zones than hosts they share zones. The subnets are
+// aws_ha_subnet_cidrs or the blocks of the VPC that follow aws_subnet_cidr.
+func getAWSManagerHAZonesConfig(conf config.Config, availableZones []string, cfg *awsManagerTerraformConfig) error {
+	if cfg.ManagerHostCount <= 1 {
+		return nil
+	}
+	if len(availableZones) == 0 {
+		return fmt.Errorf("AWS region '%s' has no available zones.", cfg.AWSRegion)
+	}
+
+	zones := []string{}
+	for len(zones) < cfg.ManagerHostCount {
+		zones = append(zones, availableZones[len(zones)%len(availableZones)])
+	}
+
+	cidrs := []string{}
+	var err error
+	if conf.IsSet("aws_ha_subnet_cidrs") {
+		cidrs = splitCommaSeparated(strings.Join(conf.GetStringSlice("aws_ha_subnet_cidrs"), ","))
+	} else {
+		cidrs, err = getAWSZoneSubnetCIDRs("aws_ha_subnet_cidrs", cfg.AWSVPCCIDR, cfg.AWSSubnetCIDR, len(zones))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validateAWSZoneSubnetCIDRs("aws_ha_subnet_cidrs", cidrs, len(zones), cfg.AWSVPCCIDR, cfg.AWSSubnetCIDR)
+	if err != nil {
+		return err
+	}
+
+	cfg.AWSHAAvailabilityZones = strings.Join(zones, ",")
+	cfg.AWSHASubnetCIDRs = strings.Join(cidrs, ",")
+
+	return nil
+}
+
+func validateAWSAvailabilityZones(zones, availableZones []string) error {
+	seen := map[string]bool{}
+	for _, zone := range zones {
+		if !containsString(availableZones, zone) {
+			return util.ConfigError(fmt.Errorf("Invalid AWS availability zone '%s', must be one of the following: %s", zone, strings.Join(availableZones, ", ")))
+		}
+		if seen[zone] {
+			return fmt.Errorf("AWS availability zone '%s' is listed twice.", zone)
+		}
+		seen[zone] = true
+	}
+	return nil
+}
+
+// Returns count subnet CIDRs of the size of the cluster's subnet, the blocks of the VPC that
+// follow it, e.g. 10.0.3.0/24 and 10.0.4.0/24 after 10.0.2.0/24. key is the setting that
+// overrides them.
+func getAWSZoneSubnetCIDRs(key, vpcCIDR, subnetCIDR string, count int) ([]string, error) {
+	_, vpcIPNet, err := net.ParseCIDR(vpcCIDR)
+	if err != nil {
+		return nil, err
+	}
+	_, subnetIPNet, err := net.ParseCIDR(subnetCIDR)
+	if err != nil {
+		return nil, err
+	}
+	if subnetIPNet.IP.To4() == nil {
+		return nil, fmt.Errorf("Subnet CIDR '%s' must be an IPv4 CIDR.", subnetCIDR)
+	}
+
+	prefix, bits := subnetIPNet.Mask.Size()
+	size := uint32(1) << uint(bits-prefix)
+	next := binary.BigEndian.Uint32(subnetIPNet.IP.To4())
+
+	cidrs := []string{}
+	for len(cidrs) < count {
+		next += size
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, next)
+		if next == 0 || !vpcIPNet.Contains(ip) {
+			return nil, fmt.Errorf("VPC CIDR '%s' has no room for %d subnets of /%d after subnet CIDR '%s', set %s.", vpcCIDR, count, prefix, subnetCIDR, key)
+		}
+		cidrs = append(cidrs, fmt.Sprintf("%s/%d", ip, prefix))
+	}
+
+	return cidrs, nil
+}
+
+// Verifies there's a CIDR for each zone, and that they're within the VPC and don't overlap
+// each other or the cluster's subnet. key is the setting the CIDRs come from.
+func validateAWSZoneSubnetCIDRs(key string, cidrs []string, zoneCount int, vpcCIDR, subnetCIDR string) error {
+	if len(cidrs) != zoneCount {
+		return fmt.Errorf("%s must have a CIDR for each of the %d availability zones. Found %d.", key, zoneCount, len(cidrs))
+	}
+
+	_, vpcIPNet, err := net.ParseCIDR(vpcCIDR)
+	if err != nil {
+		return err
+	}
+	vpcPrefix, _ := vpcIPNet.Mask.Size()
+
+	_, subnetIPNet, err := net.ParseCIDR(subnetCIDR)
+	if err != nil {
+		return err
+	}
+
+	previous := []*net.IPNet{subnetIPNet}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+
+		prefix, _ := ipNet.Mask.Size()
+		if !vpcIPNet.Contains(ipNet.IP) || prefix < vpcPrefix {
+			return fmt.Errorf("Subnet CIDR '%s' is not within bounds of VPC CIDR '%s'.", cidr, vpcCIDR)
+		}
+
+		for _, previousIPNet := range previous {
+			if previousIPNet.Contains(ipNet.IP) || ipNet.Contains(previousIPNet.IP) {
+				return fmt.Errorf("Subnet CIDR '%s' overlaps subnet CIDR '%s'.", cidr, previousIPNet)
+			}
+		}
+		previous = append(previous, ipNet)
+	}
+
+	return nil
+}
+
+// Returns the availability zone of each of count new nodes of an AWS cluster with availability
+// zones: aws_availability_zone, or the zone the user picks, or else the zones with the fewest
+// nodes. Nodes in another account than the cluster use the subnet they're given instead.
+func getAWSNodeAvailabilityZones(conf config.Config, currentState state.State, clusterKey string, cfg awsNodeTerraformConfig, count int) ([]string, error) {
+	clusterZones := splitCommaSeparated(currentState.Get(fmt.Sprintf("module.%s.aws_availability_zones", clusterKey)))
+	if len(clusterZones) == 0 || cfg.AWSSubnetID != fmt.Sprintf("${module.%s.aws_subnet_id}", clusterKey) {
+		return nil, nil
+	}
+
+	selectedZone, err := getAWSAvailabilityZone(conf, clusterZones)
+	if err != nil {
+		return nil, err
+	}
+	if selectedZone != "" {
+		zones := make([]string, count)
+		for i := range zones {
+			zones[i] = selectedZone
+		}
+		return zones, nil
+	}
+
+	nodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return nil, err
+	}
+	nodesPerZone := map[string]int{}
+	for _, nodeKey := range nodes {
+		zone := currentState.Get(fmt.Sprintf("module.%s.aws_availability_zone", nodeKey))
+		if zone != "" {
+			nodesPerZone[zone]++
+		}
+	}
+
+	return spreadAWSAvailabilityZones(clusterZones, nodesPerZone, count), nil
+}
+
+// Returns the subnets of an Auto Scaling Group of a cluster with availability zones: the subnet
+// of aws_availability_zone or of the zone the user picks, or else the subnets of all the zones,
+// AWS balances the instances across them. Pools in another account than the cluster use the
+// subnet they're given instead.
+func getAWSNodePoolSubnetIDs(conf config.Config, currentState state.State, clusterKey string, cfg awsNodeTerraformConfig) ([]string, error) {
+	clusterZones := splitCommaSeparated(currentState.Get(fmt.Sprintf("module.%s.aws_availability_zones", clusterKey)))
+	if len(clusterZones) == 0 || cfg.AWSSubnetID != fmt.Sprintf("${module.%s.aws_subnet_id}", clusterKey) {
+		return nil, nil
+	}
+
+	selectedZone, err := getAWSAvailabilityZone(conf, clusterZones)
+	if err != nil {
+		return nil, err
+	}
+	if selectedZone != "" {
+		return []string{awsZoneSubnetID(clusterKey, selectedZone)}, nil
+	}
+
+	subnetIDs := []string{}
+	for _, zone := range clusterZones {
+		subnetIDs = append(subnetIDs, awsZoneSubnetID(clusterKey, zone))
+	}
+	return subnetIDs, nil
+}
+
+// Returns aws_availability_zone or the zone the user picks among the cluster's zones, or an
+// empty string to spread the nodes across them.
+func getAWSAvailabilityZone(conf config.Config, clusterZones []string) (string, error) {
+	selectedZone := ""
+	if conf.IsSet("aws_availability_zone") {
+		selectedZone = conf.GetString("aws_availability_zone")
+		if !containsString(clusterZones, selectedZone) {
+			return "", util.ConfigError(fmt.Errorf("Invalid aws_availability_zone '%s', must be one of the cluster's availability zones: %s", selectedZone, strings.Join(clusterZones, ", ")))
+		}
+	} else if !conf.GetBool("non-interactive") {
+		spreadOption := "Spread across the availability zones"
+		prompt := promptui.Select{
+			Label: "AWS Availability Zone",
+			Items: append([]string{spreadOption}, clusterZones...),
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "AWS Availability Zone:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		i, value, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			selectedZone = value
+		}
+	}
+
+	return selectedZone, nil
+}
+
+// Returns the zone of each of count new nodes, each going to the zone with the fewest nodes,
+// the first of them on a tie.
+func spreadAWSAvailabilityZones(zones []string, nodesPerZone map[string]int, count int) []string {
+	counts := map[string]int{}
+	for zone, nodeCount := range nodesPerZone {
+		counts[zone] = nodeCount
+	}
+
+	result := []string{}
+	for len(result) < count {
+		selected := zones[0]
+		for _, zone := range zones[1:] {
+			if counts[zone] < counts[selected] {
+				selected = zone
+			}
+		}
+		counts[selected]++
+		result = append(result, selected)
+	}
+	return result
+}
+
+// The subnet of an availability zone is an output of the cluster module
+func awsZoneSubnetID(clusterKey, zone string) string {
+	return fmt.Sprintf(`${lookup(module.%s.aws_zone_subnet_ids, "%s")}`, clusterKey, zone)
+}
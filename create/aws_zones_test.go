@@ -0,0 +1,194 @@
+package create
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestGetAWSZoneSubnetCIDRs(t *testing.T) {
+	cidrs, err := getAWSZoneSubnetCIDRs("aws_zone_subnet_cidrs", "10.0.0.0/16", "10.0.2.0/24", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"10.0.3.0/24", "10.0.4.0/24", "10.0.5.0/24"}
+	if !reflect.DeepEqual(cidrs, expected) {
+		t.Errorf("Wrong CIDRs, expected %v, received %v", expected, cidrs)
+	}
+
+	_, err = getAWSZoneSubnetCIDRs("aws_zone_subnet_cidrs", "10.0.0.0/23", "10.0.0.0/24", 2)
+	expectedErr := "VPC CIDR '10.0.0.0/23' has no room for 2 subnets of /24 after subnet CIDR '10.0.0.0/24', set aws_zone_subnet_cidrs."
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error %q, received %v", expectedErr, err)
+	}
+}
+
+func TestValidateAWSZoneSubnetCIDRs(t *testing.T) {
+	tests := []struct {
+		cidrs    []string
+		expected string
+	}{
+		{[]string{"10.0.3.0/24", "10.0.4.0/24"}, ""},
+		{[]string{"10.0.3.0/24"}, "aws_zone_subnet_cidrs must have a CIDR for each of the 2 availability zones. Found 1."},
+		{[]string{"10.0.3.0/24", "10.1.0.0/24"}, "Subnet CIDR '10.1.0.0/24' is not within bounds of VPC CIDR '10.0.0.0/16'."},
+		{[]string{"10.0.2.128/25", "10.0.4.0/24"}, "Subnet CIDR '10.0.2.128/25' overlaps subnet CIDR '10.0.2.0/24'."},
+		{[]string{"10.0.4.0/24", "10.0.0.0/21"}, "Subnet CIDR '10.0.0.0/21' overlaps subnet CIDR '10.0.2.0/24'."},
+	}
+
+	for _, test := range tests {
+		err := validateAWSZoneSubnetCIDRs("aws_zone_subnet_cidrs", test.cidrs, 2, "10.0.0.0/16", "10.0.2.0/24")
+		if test.expected == "" && err != nil {
+			t.Errorf("%v: expected no error, received %v", test.cidrs, err)
+		}
+		if test.expected != "" && (err == nil || err.Error() != test.expected) {
+			t.Errorf("%v: expected error %q, received %v", test.cidrs, test.expected, err)
+		}
+	}
+}
+
+func TestValidateAWSAvailabilityZones(t *testing.T) {
+	available := []string{"us-west-2a", "us-west-2b", "us-west-2c"}
+
+	err := validateAWSAvailabilityZones([]string{"us-west-2a", "us-west-2c"}, available)
+	if err != nil {
+		t.Errorf("Expected no error, received %v", err)
+	}
+
+	err = validateAWSAvailabilityZones([]string{"us-east-1a"}, available)
+	expected := "Invalid AWS availability zone 'us-east-1a', must be one of the following: us-west-2a, us-west-2b, us-west-2c"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, received %v", expected, err)
+	}
+
+	err = validateAWSAvailabilityZones([]string{"us-west-2a", "us-west-2a"}, available)
+	expected = "AWS availability zone 'us-west-2a' is listed twice."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, received %v", expected, err)
+	}
+}
+
+func TestGetAWSNodeAvailabilityZones(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module": {
+		"cluster_aws_dev": {"name": "dev", "aws_availability_zones": "us-west-2a,us-west-2b,us-west-2c"},
+		"node_aws_dev_w-1": {"hostname": "w-1", "cluster_id": "${module.cluster_aws_dev.rancher_cluster_id}", "aws_availability_zone": "us-west-2a"},
+		"node_aws_dev_w-2": {"hostname": "w-2", "cluster_id": "${module.cluster_aws_dev.rancher_cluster_id}", "aws_availability_zone": "us-west-2a"},
+		"node_aws_dev_w-3": {"hostname": "w-3", "cluster_id": "${module.cluster_aws_dev.rancher_cluster_id}", "aws_availability_zone": "us-west-2b"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := awsNodeTerraformConfig{AWSSubnetID: "${module.cluster_aws_dev.aws_subnet_id}"}
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	zones, err := getAWSNodeAvailabilityZones(conf, currentState, "cluster_aws_dev", cfg, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"us-west-2c", "us-west-2b", "us-west-2c", "us-west-2a"}
+	if !reflect.DeepEqual(zones, expected) {
+		t.Errorf("Wrong zones, expected %v, received %v", expected, zones)
+	}
+
+	conf.Set("aws_availability_zone", "us-west-2b")
+	zones, err = getAWSNodeAvailabilityZones(conf, currentState, "cluster_aws_dev", cfg, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"us-west-2b", "us-west-2b"}
+	if !reflect.DeepEqual(zones, expected) {
+		t.Errorf("Wrong zones, expected %v, received %v", expected, zones)
+	}
+
+	conf.Set("aws_availability_zone", "us-east-1a")
+	_, err = getAWSNodeAvailabilityZones(conf, currentState, "cluster_aws_dev", cfg, 1)
+	expectedErr := "Invalid aws_availability_zone 'us-east-1a', must be one of the cluster's availability zones: us-west-2a, us-west-2b, us-west-2c"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error %q, received %v", expectedErr, err)
+	}
+
+	// Nodes in another account use the subnet they're given
+	cfg.AWSSubnetID = "subnet-12345"
+	zones, err = getAWSNodeAvailabilityZones(conf, currentState, "cluster_aws_dev", cfg, 1)
+	if err != nil || zones != nil {
+		t.Errorf("Expected no zones, received %v (%v)", zones, err)
+	}
+}
+
+func TestGetAWSNodePoolSubnetIDs(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module": {
+		"cluster_aws_dev": {"name": "dev", "aws_availability_zones": "us-west-2a,us-west-2b"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := awsNodeTerraformConfig{AWSSubnetID: "${module.cluster_aws_dev.aws_subnet_id}"}
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	subnetIDs, err := getAWSNodePoolSubnetIDs(conf, currentState, "cluster_aws_dev", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`${lookup(module.cluster_aws_dev.aws_zone_subnet_ids, "us-west-2a")}`,
+		`${lookup(module.cluster_aws_dev.aws_zone_subnet_ids, "us-west-2b")}`,
+	}
+	if !reflect.DeepEqual(subnetIDs, expected) {
+		t.Errorf("Wrong subnets, expected %v, received %v", expected, subnetIDs)
+	}
+
+	conf.Set("aws_availability_zone", "us-west-2b")
+	subnetIDs, err = getAWSNodePoolSubnetIDs(conf, currentState, "cluster_aws_dev", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{`${lookup(module.cluster_aws_dev.aws_zone_subnet_ids, "us-west-2b")}`}
+	if !reflect.DeepEqual(subnetIDs, expected) {
+		t.Errorf("Wrong subnets, expected %v, received %v", expected, subnetIDs)
+	}
+
+	// Pools in another account use the subnet they're given
+	cfg.AWSSubnetID = "subnet-12345"
+	subnetIDs, err = getAWSNodePoolSubnetIDs(conf, currentState, "cluster_aws_dev", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subnetIDs != nil {
+		t.Errorf("Expected the given subnet, received %v", subnetIDs)
+	}
+}
+
+func TestGetAWSManagerHAZonesConfig(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+
+	cfg := awsManagerTerraformConfig{AWSRegion: "us-west-1", AWSVPCCIDR: "10.0.0.0/16", AWSSubnetCIDR: "10.0.2.0/24"}
+	cfg.ManagerHostCount = 3
+	err := getAWSManagerHAZonesConfig(conf, []string{"us-west-1a", "us-west-1c"}, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AWSHAAvailabilityZones != "us-west-1a,us-west-1c,us-west-1a" || cfg.AWSHASubnetCIDRs != "10.0.3.0/24,10.0.4.0/24,10.0.5.0/24" {
+		t.Errorf("Wrong zones and subnets, received %s and %s", cfg.AWSHAAvailabilityZones, cfg.AWSHASubnetCIDRs)
+	}
+
+	conf.Set("aws_ha_subnet_cidrs", "10.0.10.0/24,10.0.11.0/24")
+	expectedErr := "aws_ha_subnet_cidrs must have a CIDR for each of the 3 availability zones. Found 2."
+	err = getAWSManagerHAZonesConfig(conf, []string{"us-west-2a", "us-west-2b", "us-west-2c"}, &cfg)
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error %q, received %v", expectedErr, err)
+	}
+
+	// A single host stays in the manager's subnet
+	cfg = awsManagerTerraformConfig{AWSVPCCIDR: "10.0.0.0/16", AWSSubnetCIDR: "10.0.2.0/24"}
+	err = getAWSManagerHAZonesConfig(conf, []string{"us-west-2a"}, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AWSHAAvailabilityZones != "" || cfg.AWSHASubnetCIDRs != "" {
+		t.Errorf("Expected no zones, received %+v", cfg)
+	}
+}
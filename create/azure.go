@@ -0,0 +1,446 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/config"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/azure-sdk-for-go/arm/resources/subscriptions"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	// Listing calls in large tenants are slow and sometimes hang, so give up on a request
+	// after azureRequestTimeout and retry it.
+	azureRequestTimeout       = 30 * time.Second
+	azureRequestRetryAttempts = 3
+	azureRequestRetryBackoff  = 5 * time.Second
+
+	// Usage name of the total regional vCPU quota
+	azureRegionalCoresUsageName = "cores"
+
+	// Image the terraform modules default to
+	defaultAzureImagePublisher = "Canonical"
+	defaultAzureImageOffer     = "UbuntuServer"
+	defaultAzureImageSKU       = "16.04-LTS"
+	defaultAzureImageVersion   = "latest"
+
+	azureResourceGroupsAPIVersion = "2019-10-01"
+)
+
+// azureImage is a marketplace image, its version may be latest.
+type azureImage struct {
+	Publisher string
+	Offer     string
+	SKU       string
+	Version   string
+}
+
+// azureResourceGroup is a resource group of a subscription, its location is a location name,
+// e.g. westus2.
+type azureResourceGroup struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
+
+// Returns the client with a sender that times out requests and retries them when they fail,
+// time out or are throttled.
+func withAzureRetries(client autorest.Client) autorest.Client {
+	client.Sender = autorest.DecorateSender(&http.Client{Timeout: azureRequestTimeout},
+		autorest.DoRetryForStatusCodes(azureRequestRetryAttempts, azureRequestRetryBackoff, autorest.StatusCodesForRetry...))
+	return client
+}
+
+// Returns the display names of the locations available to the subscription.
+func getAzureLocations(azureEnv azure.Environment, azureSPT *adal.ServicePrincipalToken, subscriptionID string) ([]string, error) {
+	azureGroupClient := subscriptions.NewGroupClientWithBaseURI(azureEnv.ResourceManagerEndpoint)
+	azureGroupClient.Client = withAzureRetries(azureGroupClient.Client)
+	azureGroupClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
+
+	// Locations are returned in a single page
+	azureRawLocations, err := azureGroupClient.ListLocations(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	azureLocations := []string{}
+	if azureRawLocations.Value != nil {
+		for _, loc := range *azureRawLocations.Value {
+			if loc.DisplayName != nil {
+				azureLocations = append(azureLocations, *loc.DisplayName)
+			}
+		}
+	}
+
+	return azureLocations, nil
+}
+
+// Returns the resource groups of the subscription. The vendored Azure SDK has no resource group
+// client, so they're listed from the API directly.
+func getAzureResourceGroups(azureEnv azure.Environment, azureSPT *adal.ServicePrincipalToken, subscriptionID string) ([]azureResourceGroup, error) {
+	azureClient := withAzureRetries(autorest.NewClientWithUserAgent(""))
+	azureClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
+
+	pageURL := fmt.Sprintf("%s/subscriptions/%s/resourcegroups?api-version=%s",
+		strings.TrimSuffix(azureEnv.ResourceManagerEndpoint, "/"), url.PathEscape(subscriptionID), azureResourceGroupsAPIVersion)
+
+	groups := []azureResourceGroup{}
+	for pageURL != "" {
+		page, nextURL, err := getAzureResourceGroupsPage(azureClient, pageURL)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, page...)
+		pageURL = nextURL
+	}
+
+	return groups, nil
+}
+
+// Returns the resource groups of a page of the listing and the URL of the next page, which is
+// empty on the last page.
+func getAzureResourceGroupsPage(azureClient autorest.Client, pageURL string) ([]azureResourceGroup, string, error) {
+	req, err := autorest.Prepare(&http.Request{},
+		autorest.AsGet(),
+		autorest.WithBaseURL(pageURL),
+		azureClient.WithAuthorization())
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := autorest.SendWithSender(azureClient, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	page := struct {
+		Value    []azureResourceGroup `json:"value"`
+		NextLink string               `json:"nextLink"`
+	}{}
+	err = autorest.Respond(resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&page),
+		autorest.ByClosing())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return page.Value, page.NextLink, nil
+}
+
+// Returns the sorted names of the resource groups in the location, a location name or display
+// name, e.g. westus2 or West US 2.
+func getAzureResourceGroupNames(groups []azureResourceGroup, location string) []string {
+	location = strings.Replace(strings.ToLower(location), " ", "", -1)
+
+	names := []string{}
+	for _, group := range groups {
+		if strings.EqualFold(group.Location, location) {
+			names = append(names, group.Name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Returns the name of the resource group, which Azure compares case-insensitively, as it
+// exists. The resource group must be in the location.
+func findAzureResourceGroup(groups []azureResourceGroup, name, location string) (string, error) {
+	normalizedLocation := strings.Replace(strings.ToLower(location), " ", "", -1)
+	for _, group := range groups {
+		if !strings.EqualFold(group.Name, name) {
+			continue
+		}
+		if !strings.EqualFold(group.Location, normalizedLocation) {
+			return "", fmt.Errorf("Azure resource group '%s' is in %s, it must be in azure_location '%s'.", group.Name, group.Location, location)
+		}
+		return group.Name, nil
+	}
+	return "", fmt.Errorf("Selected Azure resource group '%s' does not exist.", name)
+}
+
+// Returns the names of the virtual machine sizes the subscription can create in the location.
+// Sizes restricted for the subscription are left out, as are sizes that don't fit in the
+// remaining vCPU quota when withinQuota is true.
+func getAzureVMSizes(azureEnv azure.Environment, azureSPT *adal.ServicePrincipalToken, subscriptionID, location string, withinQuota bool) ([]string, error) {
+	authorizer := autorest.NewBearerAuthorizer(azureSPT)
+	location = strings.Replace(strings.ToLower(location), " ", "", -1)
+
+	azureVMSizesClient := compute.NewVirtualMachineSizesClientWithBaseURI(azureEnv.ResourceManagerEndpoint, subscriptionID)
+	azureVMSizesClient.Client = withAzureRetries(azureVMSizesClient.Client)
+	azureVMSizesClient.Authorizer = authorizer
+
+	// Sizes are returned in a single page
+	azureRawVMSizes, err := azureVMSizesClient.List(location)
+	if err != nil {
+		return nil, err
+	}
+	sizes := []compute.VirtualMachineSize{}
+	if azureRawVMSizes.Value != nil {
+		sizes = *azureRawVMSizes.Value
+	}
+
+	azureSkusClient := compute.NewResourceSkusClientWithBaseURI(azureEnv.ResourceManagerEndpoint, subscriptionID)
+	azureSkusClient.Client = withAzureRetries(azureSkusClient.Client)
+	azureSkusClient.Authorizer = authorizer
+
+	skus := []compute.ResourceSku{}
+	skuPage, err := azureSkusClient.List()
+	for {
+		if err != nil {
+			return nil, err
+		}
+		if skuPage.Value != nil {
+			skus = append(skus, *skuPage.Value...)
+		}
+		if skuPage.NextLink == nil || *skuPage.NextLink == "" {
+			break
+		}
+		skuPage, err = azureSkusClient.ListNextResults(skuPage)
+	}
+
+	usages := []compute.Usage{}
+	if withinQuota {
+		azureUsageClient := compute.NewUsageClientWithBaseURI(azureEnv.ResourceManagerEndpoint, subscriptionID)
+		azureUsageClient.Client = withAzureRetries(azureUsageClient.Client)
+		azureUsageClient.Authorizer = authorizer
+
+		usagePage, err := azureUsageClient.List(location)
+		for {
+			if err != nil {
+				return nil, err
+			}
+			if usagePage.Value != nil {
+				usages = append(usages, *usagePage.Value...)
+			}
+			if usagePage.NextLink == nil || *usagePage.NextLink == "" {
+				break
+			}
+			usagePage, err = azureUsageClient.ListNextResults(usagePage)
+		}
+	}
+
+	return filterAzureVMSizes(sizes, skus, usages, location, withinQuota), nil
+}
+
+// Returns the sorted names of the sizes that aren't restricted in the location. When
+// withinQuota is true, sizes with more vCPUs than remain in the regional quota or the quota
+// of their family are left out too. Sizes without SKU information are kept.
+func filterAzureVMSizes(sizes []compute.VirtualMachineSize, skus []compute.ResourceSku, usages []compute.Usage, location string, withinQuota bool) []string {
+	restricted := map[string]bool{}
+	families := map[string]string{}
+	for _, sku := range skus {
+		if sku.ResourceType == nil || *sku.ResourceType != "virtualMachines" || sku.Name == nil {
+			continue
+		}
+		if sku.Family != nil {
+			families[*sku.Name] = *sku.Family
+		}
+		if sku.Restrictions == nil {
+			continue
+		}
+		for _, restriction := range *sku.Restrictions {
+			if restriction.Type != compute.Location || restriction.Values == nil {
+				continue
+			}
+			for _, value := range *restriction.Values {
+				if strings.EqualFold(value, location) {
+					restricted[*sku.Name] = true
+				}
+			}
+		}
+	}
+
+	// Remaining vCPUs by usage name
+	remaining := map[string]int64{}
+	for _, usage := range usages {
+		if usage.Name == nil || usage.Name.Value == nil || usage.Limit == nil || usage.CurrentValue == nil {
+			continue
+		}
+		remaining[*usage.Name.Value] = *usage.Limit - int64(*usage.CurrentValue)
+	}
+
+	fitsQuota := func(size compute.VirtualMachineSize) bool {
+		if size.NumberOfCores == nil {
+			return true
+		}
+		cores := int64(*size.NumberOfCores)
+		if left, ok := remaining[azureRegionalCoresUsageName]; ok && left < cores {
+			return false
+		}
+		if left, ok := remaining[families[*size.Name]]; ok && left < cores {
+			return false
+		}
+		return true
+	}
+
+	result := []string{}
+	for _, size := range sizes {
+		if size.Name == nil || restricted[*size.Name] {
+			continue
+		}
+		if withinQuota && !fitsQuota(size) {
+			continue
+		}
+		result = append(result, *size.Name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Returns the image picked by the azure_image_publisher, azure_image_offer, azure_image_sku
+// and azure_image_version settings, each of which must exist in the location. The user picks
+// the ones that aren't set, which default to the Ubuntu image of the terraform modules in
+// non-interactive mode.
+func getAzureImage(conf config.Config, azureEnv azure.Environment, azureSPT *adal.ServicePrincipalToken, subscriptionID, location string) (azureImage, error) {
+	azureImagesClient := compute.NewVirtualMachineImagesClientWithBaseURI(azureEnv.ResourceManagerEndpoint, subscriptionID)
+	azureImagesClient.Client = withAzureRetries(azureImagesClient.Client)
+	azureImagesClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
+	location = strings.Replace(strings.ToLower(location), " ", "", -1)
+
+	image := azureImage{}
+
+	// Each list is returned in a single page
+	publishers, err := azureImagesClient.ListPublishers(location)
+	if err != nil {
+		return image, err
+	}
+	image.Publisher, err = getAzureImagePart(conf, "azure_image_publisher", "Azure Image Publisher", getAzureImageResourceNames(publishers), defaultAzureImagePublisher)
+	if err != nil {
+		return image, err
+	}
+
+	offers, err := azureImagesClient.ListOffers(location, image.Publisher)
+	if err != nil {
+		return image, err
+	}
+	image.Offer, err = getAzureImagePart(conf, "azure_image_offer", "Azure Image Offer", getAzureImageResourceNames(offers), defaultAzureImageOffer)
+	if err != nil {
+		return image, err
+	}
+
+	skus, err := azureImagesClient.ListSkus(location, image.Publisher, image.Offer)
+	if err != nil {
+		return image, err
+	}
+	image.SKU, err = getAzureImagePart(conf, "azure_image_sku", "Azure Image SKU", getAzureImageResourceNames(skus), defaultAzureImageSKU)
+	if err != nil {
+		return image, err
+	}
+
+	versions, err := azureImagesClient.List(location, image.Publisher, image.Offer, image.SKU, "", nil, "")
+	if err != nil {
+		return image, err
+	}
+	versionNames := getAzureImageResourceNames(versions)
+	sortAzureImageVersions(versionNames)
+	image.Version, err = getAzureImagePart(conf, "azure_image_version", "Azure Image Version", append([]string{defaultAzureImageVersion}, versionNames...), defaultAzureImageVersion)
+	if err != nil {
+		return image, err
+	}
+
+	return image, nil
+}
+
+// Returns the setting, which must be one of names, or else the name the user picks. The
+// default is listed first, and used in non-interactive mode when it is one of names.
+func getAzureImagePart(conf config.Config, key, label string, names []string, defaultName string) (string, error) {
+	if conf.IsSet(key) {
+		value := conf.GetString(key)
+		for _, name := range names {
+			if name == value {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("Selected %s '%s' does not exist.", label, value)
+	}
+
+	items := []string{}
+	for _, name := range names {
+		if name == defaultName {
+			items = append([]string{name}, items...)
+		} else {
+			items = append(items, name)
+		}
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("No %ss.", label)
+	}
+
+	if conf.GetBool("non-interactive") {
+		if items[0] == defaultName {
+			return defaultName, nil
+		}
+		return "", errors.New(key + " must be specified")
+	}
+
+	prompt := promptui.Select{
+		Label: label,
+		Items: items,
+		Searcher: func(input string, index int) bool {
+			name := strings.Replace(strings.ToLower(items[index]), " ", "", -1)
+			input = strings.Replace(strings.ToLower(input), " ", "", -1)
+			return strings.Contains(name, input)
+		},
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}?",
+			Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+			Inactive: `  {{ . }}`,
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "%s:" | bold}} {{ . }}`, promptui.IconGood, label),
+		},
+	}
+
+	_, value, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// Returns the sorted names of the publishers, offers, SKUs or versions of a listing.
+func getAzureImageResourceNames(result compute.ListVirtualMachineImageResource) []string {
+	names := []string{}
+	if result.Value != nil {
+		for _, resource := range *result.Value {
+			if resource.Name != nil {
+				names = append(names, *resource.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Sorts image versions, e.g. 16.04.201808140, newest first.
+func sortAzureImageVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		a := strings.Split(versions[i], ".")
+		b := strings.Split(versions[j], ".")
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] == b[k] {
+				continue
+			}
+			numA, errA := strconv.Atoi(a[k])
+			numB, errB := strconv.Atoi(b[k])
+			if errA != nil || errB != nil {
+				return a[k] > b[k]
+			}
+			return numA > numB
+		}
+		return len(a) > len(b)
+	})
+}
@@ -0,0 +1,175 @@
+package create
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+func TestFilterAzureVMSizes(t *testing.T) {
+	sizes := []compute.VirtualMachineSize{
+		{Name: to.StringPtr("Standard_D2_v2"), NumberOfCores: to.Int32Ptr(2)},
+		{Name: to.StringPtr("Standard_D16_v2"), NumberOfCores: to.Int32Ptr(16)},
+		{Name: to.StringPtr("Standard_A1"), NumberOfCores: to.Int32Ptr(1)},
+		{Name: to.StringPtr("Standard_G5"), NumberOfCores: to.Int32Ptr(32)},
+	}
+	skus := []compute.ResourceSku{
+		{ResourceType: to.StringPtr("virtualMachines"), Name: to.StringPtr("Standard_D2_v2"), Family: to.StringPtr("standardDv2Family")},
+		{ResourceType: to.StringPtr("virtualMachines"), Name: to.StringPtr("Standard_D16_v2"), Family: to.StringPtr("standardDv2Family")},
+		{ResourceType: to.StringPtr("virtualMachines"), Name: to.StringPtr("Standard_A1"), Family: to.StringPtr("standardA0_A7Family")},
+		{
+			ResourceType: to.StringPtr("virtualMachines"),
+			Name:         to.StringPtr("Standard_G5"),
+			Family:       to.StringPtr("standardGSFamily"),
+			Restrictions: &[]compute.ResourceSkuRestrictions{
+				{Type: compute.Location, Values: &[]string{"WestUS"}, ReasonCode: compute.NotAvailableForSubscription},
+			},
+		},
+	}
+	usages := []compute.Usage{
+		{Name: &compute.UsageName{Value: to.StringPtr("cores")}, CurrentValue: to.Int32Ptr(4), Limit: to.Int64Ptr(100)},
+		{Name: &compute.UsageName{Value: to.StringPtr("standardDv2Family")}, CurrentValue: to.Int32Ptr(4), Limit: to.Int64Ptr(10)},
+	}
+
+	result := filterAzureVMSizes(sizes, skus, usages, "westus", false)
+	expected := []string{"Standard_A1", "Standard_D16_v2", "Standard_D2_v2"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Wrong sizes, expected %v, received %v", expected, result)
+	}
+
+	result = filterAzureVMSizes(sizes, skus, usages, "westus", true)
+	expected = []string{"Standard_A1", "Standard_D2_v2"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Wrong sizes within quota, expected %v, received %v", expected, result)
+	}
+
+	result = filterAzureVMSizes(sizes, skus, usages, "eastus", false)
+	expected = []string{"Standard_A1", "Standard_D16_v2", "Standard_D2_v2", "Standard_G5"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Wrong sizes in another location, expected %v, received %v", expected, result)
+	}
+}
+
+func TestGetAzureImageResourceNames(t *testing.T) {
+	result := compute.ListVirtualMachineImageResource{
+		Value: &[]compute.VirtualMachineImageResource{
+			{Name: to.StringPtr("UbuntuServer")},
+			{Name: to.StringPtr("Debian")},
+			{},
+		},
+	}
+
+	names := getAzureImageResourceNames(result)
+	expected := []string{"Debian", "UbuntuServer"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Wrong names, expected %v, received %v", expected, names)
+	}
+}
+
+func TestSortAzureImageVersions(t *testing.T) {
+	versions := []string{"16.04.201712120", "16.04.201808140", "16.04.20180130", "18.04.201804262"}
+	sortAzureImageVersions(versions)
+
+	expected := []string{"18.04.201804262", "16.04.201808140", "16.04.201712120", "16.04.20180130"}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Wrong order, expected %v, received %v", expected, versions)
+	}
+}
+
+func TestGetAzureImagePartNonInteractive(t *testing.T) {
+	skus := []string{"14.04.5-LTS", "16.04-LTS", "18.04-LTS"}
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	value, err := getAzureImagePart(conf, "azure_image_sku", "Azure Image SKU", skus, defaultAzureImageSKU)
+	if err != nil || value != "16.04-LTS" {
+		t.Errorf("Wrong output, expected the default 16.04-LTS, received %s (%v)", value, err)
+	}
+
+	_, err = getAzureImagePart(conf, "azure_image_sku", "Azure Image SKU", []string{"9"}, defaultAzureImageSKU)
+	if err == nil || err.Error() != "azure_image_sku must be specified" {
+		t.Errorf("Wrong output, expected azure_image_sku must be specified, received %v", err)
+	}
+
+	conf.Set("azure_image_sku", "18.04-LTS")
+	value, err = getAzureImagePart(conf, "azure_image_sku", "Azure Image SKU", skus, defaultAzureImageSKU)
+	if err != nil || value != "18.04-LTS" {
+		t.Errorf("Wrong output, expected 18.04-LTS, received %s (%v)", value, err)
+	}
+
+	conf.Set("azure_image_sku", "17.10")
+	_, err = getAzureImagePart(conf, "azure_image_sku", "Azure Image SKU", skus, defaultAzureImageSKU)
+	if err == nil || err.Error() != "Selected Azure Image SKU '17.10' does not exist." {
+		t.Errorf("Wrong output, expected an error for a SKU that does not exist, received %v", err)
+	}
+}
+
+func TestGetAzureResourceGroups(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subscriptions/sub-1/resourcegroups" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"value": [{"name": "shared", "location": "eastus"}]}`))
+			return
+		}
+		w.Write([]byte(`{"value": [{"name": "network", "location": "westus2"}], "nextLink": "http://` + r.Host + r.URL.Path + `?page=2"}`))
+	}))
+	defer server.Close()
+
+	oauthConfig, err := adal.NewOAuthConfig(server.URL, "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	azureSPT, err := adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, "client", server.URL, adal.Token{AccessToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	azureSPT.SetAutoRefresh(false)
+
+	groups, err := getAzureResourceGroups(azure.Environment{ResourceManagerEndpoint: server.URL + "/"}, azureSPT, "sub-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []azureResourceGroup{{Name: "network", Location: "westus2"}, {Name: "shared", Location: "eastus"}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Wrong resource groups, expected %v, received %v", expected, groups)
+	}
+}
+
+func TestFindAzureResourceGroup(t *testing.T) {
+	groups := []azureResourceGroup{
+		{Name: "Network", Location: "westus2"},
+		{Name: "shared", Location: "westus2"},
+		{Name: "legacy", Location: "eastus"},
+	}
+
+	names := getAzureResourceGroupNames(groups, "West US 2")
+	expected := []string{"Network", "shared"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Wrong names, expected %v, received %v", expected, names)
+	}
+
+	name, err := findAzureResourceGroup(groups, "network", "West US 2")
+	if err != nil || name != "Network" {
+		t.Errorf("Wrong output, expected Network, received %s (%v)", name, err)
+	}
+
+	_, err = findAzureResourceGroup(groups, "legacy", "West US 2")
+	if err == nil || err.Error() != "Azure resource group 'legacy' is in eastus, it must be in azure_location 'West US 2'." {
+		t.Errorf("Wrong output, expected an error for a resource group in another location, received %v", err)
+	}
+
+	_, err = findAzureResourceGroup(groups, "missing", "West US 2")
+	if err == nil || err.Error() != "Selected Azure resource group 'missing' does not exist." {
+		t.Errorf("Wrong output, expected an error for a resource group that does not exist, received %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package create
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// Keys of the node module parameters that determine what a node costs. Bare metal, vSphere,
+// Proxmox VE, Nutanix AHV and libvirt nodes run on hosts that are paid for separately, they have
+// none of these.
+var nodeSizeKeys = []string{
+	"triton_machine_package",
+	"aws_instance_type",
+	"gcp_machine_type",
+	"azure_size",
+	"digitalocean_droplet_size",
+	"equinix_metal_plan",
+	"openstack_flavor_name",
+}
+
+// Stores the cluster's `monthly_budget`, if the config sets one. The budget is kept in the
+// state, so later operations on the cluster are checked against it without repeating it.
+func setMonthlyBudget(conf config.Config, currentState state.State, clusterKey string) error {
+	if !conf.IsSet("monthly_budget") {
+		return nil
+	}
+
+	budget, err := strconv.ParseFloat(conf.GetString("monthly_budget"), 64)
+	if err != nil {
+		return fmt.Errorf("monthly_budget must be a valid number. Found '%s'.", conf.GetString("monthly_budget"))
+	}
+	if budget < 0 {
+		return fmt.Errorf("monthly_budget must not be negative. Found '%s'.", conf.GetString("monthly_budget"))
+	}
+
+	return currentState.SetMonthlyBudget(clusterKey, budget)
+}
+
+// Refuses an operation that raises the estimated monthly cost of the cluster by delta to
+// total, if total exceeds the cluster's budget. `ignore_budget` overrides the check. Clusters
+// without a budget are never checked.
+func checkMonthlyBudget(conf config.Config, currentState state.State, clusterKey string, total, delta float64) error {
+	budget, ok := currentState.MonthlyBudget(clusterKey)
+	if !ok {
+		return nil
+	}
+
+	fmt.Printf("Estimated monthly cost of cluster '%s': %.2f (%+.2f), budget: %.2f\n", clusterKey, total, delta, budget)
+
+	// Reducing the cost of a cluster over its budget is always allowed
+	if delta <= 0 || total <= budget {
+		return nil
+	}
+
+	if conf.GetBool("ignore_budget") {
+		fmt.Printf("The estimated monthly cost exceeds the budget of cluster '%s', continuing because ignore_budget is set.\n", clusterKey)
+		return nil
+	}
+
+	return fmt.Errorf("The estimated monthly cost of cluster '%s', %.2f, would exceed its budget of %.2f. Use --ignore-budget to proceed anyway.", clusterKey, total, budget)
+}
+
+// Returns the estimated monthly cost of the cluster's nodes. Prices are only needed once a
+// cluster has a budget, the cost of clusters without one is 0.
+func estimateMonthlyCost(conf config.Config, currentState state.State, clusterKey string) (float64, error) {
+	if _, ok := currentState.MonthlyBudget(clusterKey); !ok {
+		return 0, nil
+	}
+
+	// Modules added in this run are structs until the state is parsed again
+	parsedState, err := state.New(currentState.Name, currentState.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	nodes, err := parsedState.Nodes(clusterKey)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0.0
+	for _, nodeKey := range nodes {
+		price, err := getNodeMonthlyPrice(conf, parsedState, nodeKey)
+		if err != nil {
+			return 0, err
+		}
+
+		count := 1
+		if provider, ok := getNodePoolProvider(parsedState, nodeKey); ok {
+			count = parsedState.GetInt(fmt.Sprintf("module.%s.%s", nodeKey, provider.CapacityKey))
+		}
+
+		total += price * float64(count)
+	}
+
+	return total, nil
+}
+
+// Returns the monthly price of a single instance of the node module, from the
+// `node_monthly_prices` map of the config, keyed by machine package, instance type, machine
+// type, VM size or plan.
+func getNodeMonthlyPrice(conf config.Config, currentState state.State, nodeKey string) (float64, error) {
+	size := ""
+	for _, key := range nodeSizeKeys {
+		size = currentState.Get(fmt.Sprintf("module.%s.%s", nodeKey, key))
+		if size != "" {
+			break
+		}
+	}
+	if size == "" {
+		return 0, nil
+	}
+
+	// Keys of config maps may have been lowercased
+	prices := conf.GetStringMapString("node_monthly_prices")
+	value, ok := prices[size]
+	if !ok {
+		value, ok = prices[strings.ToLower(size)]
+	}
+	if !ok {
+		return 0, fmt.Errorf("No monthly price for '%s', add it to node_monthly_prices to estimate the cost of the cluster.", size)
+	}
+
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("The monthly price of '%s' in node_monthly_prices must be a valid number. Found '%s'.", size, value)
+	}
+
+	return price, nil
+}
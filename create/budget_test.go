@@ -0,0 +1,106 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const budgetTestState = `{
+	"locals": {"triton_kubernetes_monthly_budget": {"cluster_aws_dev": 200}},
+	"module": {
+		"cluster_aws_dev": {"name": "dev"},
+		"node_aws_dev_dev-e-1": {"hostname": "dev-e-1", "aws_instance_type": "t2.large"},
+		"node_aws_dev_dev-w": {
+			"hostname": "dev-w",
+			"source": "github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher-k8s-asg?ref=master",
+			"aws_instance_type": "t2.medium",
+			"aws_asg_desired_capacity": 2
+		}
+	}
+}`
+
+func TestEstimateMonthlyCost(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(budgetTestState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("node_monthly_prices", map[string]interface{}{"t2.large": "67.5", "t2.medium": 33.75})
+
+	cost, err := estimateMonthlyCost(conf, currentState, "cluster_aws_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := 67.5 + 2*33.75
+	if cost != expected {
+		t.Errorf("Wrong output, expected %.2f, received %.2f", expected, cost)
+	}
+}
+
+func TestEstimateMonthlyCostMissingPrice(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(budgetTestState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("node_monthly_prices", map[string]interface{}{"t2.large": 67.5})
+
+	expected := "No monthly price for 't2.medium', add it to node_monthly_prices to estimate the cost of the cluster."
+
+	_, err = estimateMonthlyCost(conf, currentState, "cluster_aws_dev")
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestCheckMonthlyBudget(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(budgetTestState))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName     string
+		total        float64
+		delta        float64
+		ignoreBudget bool
+		expected     string
+	}{
+		{"Within budget", 180, 40, false, ""},
+		{"Over budget", 240, 40, false, "The estimated monthly cost of cluster 'cluster_aws_dev', 240.00, would exceed its budget of 200.00. Use --ignore-budget to proceed anyway."},
+		{"Over budget ignored", 240, 40, true, ""},
+		{"Reduced over budget", 240, -40, false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			conf := config.New()
+			conf.Set("ignore_budget", test.ignoreBudget)
+
+			err := checkMonthlyBudget(conf, currentState, "cluster_aws_dev", test.total, test.delta)
+			if test.expected == "" && err != nil {
+				t.Errorf("Wrong output, expected no error, received %v", err)
+			}
+			if test.expected != "" && (err == nil || err.Error() != test.expected) {
+				t.Errorf("Wrong output, expected %s, received %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckMonthlyBudgetWithoutBudget(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module": {"cluster_aws_dev": {"name": "dev"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = checkMonthlyBudget(config.New(), currentState, "cluster_aws_dev", 1000, 1000)
+	if err != nil {
+		t.Errorf("Wrong output, expected no error, received %v", err)
+	}
+}
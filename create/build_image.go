@@ -0,0 +1,366 @@
+package create
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	defaultImageName = "triton-kubernetes-node"
+
+	// Default of the rancher_agent_image variable of the modules
+	defaultRancherAgentImage = "rancher/agent:v2.0.0-beta2"
+
+	defaultPackerTritonPackage  = "k4-highcpu-kvm-1.75G"
+	defaultPackerAWSInstance    = "t2.medium"
+	defaultPackerGCPMachineType = "n1-standard-1"
+)
+
+// Image names are part of AMI and GCP image names, which GCP restricts the most
+var imageNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// BuildImage bakes a golden node image for the nodes of a cluster with packer: an Ubuntu image of
+// the cluster's cloud provider with Docker installed and the Rancher agent image pulled. It's
+// built with the credentials and in the region of the cluster, and recorded in the state under
+// its name, so nodes created with `golden_image: {name}` run it and skip installing Docker.
+func BuildImage(conf config.Config, remoteBackend backend.Backend, imageName string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterKey := ""
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
+		clusterKey, ok := clusters[clusterName]
+		if !ok {
+			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+
+		selectedClusterKey = clusterKey
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to build a node image for",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedClusterKey = clusters[value]
+	}
+
+	selectedImageName := imageName
+	if selectedImageName != "" {
+		// Name was given as an argument
+	} else if conf.IsSet("image_name") {
+		selectedImageName = conf.GetString("image_name")
+	} else if nonInteractiveMode {
+		selectedImageName = defaultImageName
+	} else {
+		prompt := promptui.Prompt{
+			Label:   "Image Name",
+			Default: defaultImageName,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedImageName = result
+	}
+
+	if !imageNameRegexp.MatchString(selectedImageName) {
+		return util.ConfigError(fmt.Errorf("Invalid image name '%s', it must start with a lowercase letter and only contain lowercase letters, digits and dashes.", selectedImageName))
+	}
+
+	dockerEngineInstallURL := dockerEngineInstallURLs[defaultDockerEngineVersion]
+	if conf.IsSet("docker_engine_version") {
+		kubernetesVersion := currentState.Get(fmt.Sprintf("module.%s.k8s_version", selectedClusterKey))
+		dockerEngineInstallURL, err = getDockerEngineInstallURL(conf.GetString("docker_engine_version"), kubernetesVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	rancherAgentImage := currentState.Get("module.cluster-manager.rancher_agent_image")
+	if rancherAgentImage == "" {
+		rancherAgentImage = defaultRancherAgentImage
+	}
+
+	// Versions sort by build time
+	imageVersion := time.Now().UTC().Format("20060102150405")
+	template, err := newPackerTemplate(conf, currentState, selectedClusterKey, selectedImageName, imageVersion, dockerEngineInstallURL, rancherAgentImage)
+	if err != nil {
+		return err
+	}
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Build image '%s' for the nodes of cluster '%s'", selectedImageName, currentState.Get(fmt.Sprintf("module.%s.name", selectedClusterKey)))
+		selected := "Build"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Build image canceled.")
+			return nil
+		}
+	}
+
+	rawTemplate, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+
+	artifactIDs, err := shell.RunPackerBuild(conf, rawTemplate)
+	if err != nil {
+		return err
+	}
+
+	cloudProvider := strings.Split(selectedClusterKey, "_")[1]
+	nodeImage, err := getBuiltNodeImage(cloudProvider, selectedImageName, imageVersion, artifactIDs[len(artifactIDs)-1])
+	if err != nil {
+		return err
+	}
+
+	err = currentState.SetImage(selectedClusterKey, selectedImageName, state.Image{
+		Image:   nodeImage,
+		BuiltAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Image '%s' built: %s. Set golden_image to '%s' to create nodes from it, or run `triton-kubernetes upgrade nodes --image %s` to replace existing nodes.\n", selectedImageName, nodeImage, selectedImageName, nodeImage)
+	return nil
+}
+
+// Returns the packer template building the image with the builder of the cluster's cloud
+// provider, using the credentials and region of the cluster.
+func newPackerTemplate(conf config.Config, currentState state.State, clusterKey, imageName, imageVersion, dockerEngineInstallURL, rancherAgentImage string) (map[string]interface{}, error) {
+	cloudProvider := strings.Split(clusterKey, "_")[1]
+	clusterSetting := func(key string) string {
+		return currentState.Get(fmt.Sprintf("module.%s.%s", clusterKey, key))
+	}
+	setting := func(key, defaultValue string) string {
+		if conf.IsSet(key) {
+			return conf.GetString(key)
+		}
+		return defaultValue
+	}
+
+	var builder map[string]interface{}
+	switch cloudProvider {
+	case "triton":
+		tritonURL := clusterSetting("triton_url")
+		if tritonURL == "" {
+			tritonURL = "https://us-east-1.api.joyent.com"
+		}
+		builder = map[string]interface{}{
+			"type":                "triton",
+			"triton_url":          tritonURL,
+			"triton_account":      clusterSetting("triton_account"),
+			"triton_key_id":       clusterSetting("triton_key_id"),
+			"triton_key_material": clusterSetting("triton_key_path"),
+			"source_machine_name": fmt.Sprintf("packer-%s-%s", imageName, imageVersion),
+			"source_machine_image_filter": map[string]interface{}{
+				"name":        "ubuntu-certified-16.04",
+				"type":        "zvol",
+				"most_recent": "true",
+			},
+			"source_machine_package": setting("packer_triton_package", defaultPackerTritonPackage),
+			"ssh_username":           "ubuntu",
+			"image_name":             imageName,
+			"image_version":          imageVersion,
+		}
+	case "aws":
+		// The cluster's credentials may reference the temporary credentials of its role
+		creds, err := shell.AWSCredentials(conf, currentState, clusterSetting("aws_access_key"), clusterSetting("aws_secret_key"), clusterSetting("aws_session_token"))
+		if err != nil {
+			return nil, err
+		}
+		builder = map[string]interface{}{
+			"type":       "amazon-ebs",
+			"access_key": creds.AccessKey,
+			"secret_key": creds.SecretKey,
+			"token":      creds.SessionToken,
+			"region":     clusterSetting("aws_region"),
+			"source_ami_filter": map[string]interface{}{
+				"filters": map[string]string{
+					"name":                "ubuntu/images/hvm-ssd/ubuntu-xenial-16.04-amd64-server-*",
+					"virtualization-type": "hvm",
+					"root-device-type":    "ebs",
+				},
+				// Canonical
+				"owners":      []string{"099720109477"},
+				"most_recent": true,
+			},
+			"instance_type": setting("packer_aws_instance_type", defaultPackerAWSInstance),
+			"ssh_username":  "ubuntu",
+			"ami_name":      fmt.Sprintf("%s-%s", imageName, imageVersion),
+		}
+	case "gcp":
+		builder = map[string]interface{}{
+			"type":                "googlecompute",
+			"account_file":        clusterSetting("gcp_path_to_credentials"),
+			"project_id":          clusterSetting("gcp_project_id"),
+			"zone":                setting("gcp_instance_zone", clusterSetting("gcp_compute_region")+"-b"),
+			"source_image_family": "ubuntu-1604-lts",
+			"machine_type":        setting("packer_gcp_machine_type", defaultPackerGCPMachineType),
+			"ssh_username":        "ubuntu",
+			"image_name":          fmt.Sprintf("%s-%s", imageName, imageVersion),
+		}
+	default:
+		return nil, fmt.Errorf("Building images for %s nodes is not supported", cloudProvider)
+	}
+
+	return map[string]interface{}{
+		"builders": []interface{}{builder},
+		"provisioners": []interface{}{
+			map[string]interface{}{
+				"type": "shell",
+				"inline": []string{
+					fmt.Sprintf("curl -sSL %s | sudo sh", dockerEngineInstallURL),
+					fmt.Sprintf("sudo docker pull %s", rancherAgentImage),
+				},
+			},
+		},
+	}, nil
+}
+
+// Returns the image of a packer artifact in the format of `upgrade nodes --image`.
+func getBuiltNodeImage(cloudProvider, imageName, imageVersion, artifactID string) (string, error) {
+	switch cloudProvider {
+	case "triton":
+		// The artifact is the image's id, nodes refer to images by name and version
+		return fmt.Sprintf("%s@%s", imageName, imageVersion), nil
+	case "aws":
+		// AMI artifacts are `{region}:{ami id}`
+		parts := strings.SplitN(artifactID, ":", 2)
+		return parts[len(parts)-1], nil
+	case "gcp":
+		return artifactID, nil
+	}
+
+	return "", fmt.Errorf("Building images for %s nodes is not supported", cloudProvider)
+}
+
+// Sets the image settings of the cluster's image named by golden_image, if it's set.
+func setGoldenImageSettings(conf config.Config, currentState state.State, clusterKey string) error {
+	if !conf.IsSet("golden_image") {
+		return nil
+	}
+
+	imageName := conf.GetString("golden_image")
+	images, err := currentState.Images(clusterKey)
+	if err != nil {
+		return err
+	}
+
+	image, ok := images[imageName]
+	if !ok {
+		return fmt.Errorf("Selected golden image '%s' does not exist, build it with `triton-kubernetes build image`.", imageName)
+	}
+
+	cloudProvider := strings.Split(clusterKey, "_")[1]
+	imageSettings, err := getNodeImageSettings(cloudProvider, image.Image)
+	if err != nil {
+		return err
+	}
+	for key, value := range imageSettings {
+		conf.Set(key, value)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestNewPackerTemplate(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module":{
+		"cluster_aws_dev":{"name":"dev","aws_access_key":"access","aws_secret_key":"secret","aws_region":"us-west-2"},
+		"cluster_digitalocean_dev":{"name":"dev"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("packer_aws_instance_type", "m4.large")
+	template, err := newPackerTemplate(conf, currentState, "cluster_aws_dev", "k8s-node", "20181017120000", "https://example.com/docker.sh", "rancher/rancher-agent:v2.0.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := template["builders"].([]interface{})[0].(map[string]interface{})
+	expected := map[string]interface{}{
+		"type":          "amazon-ebs",
+		"access_key":    "access",
+		"secret_key":    "secret",
+		"region":        "us-west-2",
+		"instance_type": "m4.large",
+		"ami_name":      "k8s-node-20181017120000",
+	}
+	for key, value := range expected {
+		if builder[key] != value {
+			t.Errorf("Wrong %s, expected %v, received %v", key, value, builder[key])
+		}
+	}
+
+	inline := template["provisioners"].([]interface{})[0].(map[string]interface{})["inline"].([]string)
+	if len(inline) != 2 || inline[1] != "sudo docker pull rancher/rancher-agent:v2.0.8" {
+		t.Errorf("Wrong provisioner commands, received %v", inline)
+	}
+
+	_, err = newPackerTemplate(conf, currentState, "cluster_digitalocean_dev", "k8s-node", "20181017120000", "", "")
+	if err == nil {
+		t.Error("Expected an error for a digitalocean cluster")
+	}
+}
+
+func TestGetBuiltNodeImage(t *testing.T) {
+	tests := []struct {
+		cloudProvider string
+		artifactID    string
+		expected      string
+	}{
+		{"triton", "0b8df2b6-0a82-4d8c-9e5a-9e3b5e1b5a8c", "k8s-node@20181017120000"},
+		{"aws", "us-west-2:ami-0def3275", "ami-0def3275"},
+		{"gcp", "k8s-node-20181017120000", "k8s-node-20181017120000"},
+	}
+
+	for _, test := range tests {
+		image, err := getBuiltNodeImage(test.cloudProvider, "k8s-node", "20181017120000", test.artifactID)
+		if err != nil || image != test.expected {
+			t.Errorf("getBuiltNodeImage(%q, %q), got: %q %v, want: %q", test.cloudProvider, test.artifactID, image, err, test.expected)
+		}
+	}
+}
+
+func TestSetGoldenImageSettings(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module":{"cluster_triton_dev":{"name":"dev"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = currentState.SetImage("cluster_triton_dev", "k8s-node", state.Image{Image: "k8s-node@20181017120000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("golden_image", "k8s-node")
+	err = setGoldenImageSettings(conf, currentState, "cluster_triton_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.GetString("triton_image_name") != "k8s-node" || conf.GetString("triton_image_version") != "20181017120000" {
+		t.Errorf("Wrong image settings, received %s@%s", conf.GetString("triton_image_name"), conf.GetString("triton_image_version"))
+	}
+
+	conf.Set("golden_image", "missing")
+	err = setGoldenImageSettings(conf, currentState, "cluster_triton_dev")
+	if err == nil {
+		t.Error("Expected an error for an image that wasn't built")
+	}
+}
@@ -0,0 +1,165 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Called when terraform apply fails. Terraform keeps whatever it did create in its state, so the
+// configuration that was applied is persisted with a checkpoint, rather than dropped and the
+// resources orphaned. `triton-kubernetes resume` applies it again from there.
+func recordApplyCheckpoint(remoteBackend backend.Backend, currentState state.State, operation string, args []string, applyErr error) error {
+	// Nothing was applied when the plan was only previewed, declined or interrupted
+	if errors.Is(applyErr, shell.ErrPlanNotApplied) || util.IsInterrupt(applyErr) {
+		return applyErr
+	}
+
+	checkpoint := state.Checkpoint{
+		Operation: operation,
+		Error:     applyErr.Error(),
+		FailedAt:  time.Now().UTC().Format(time.RFC3339),
+		Args:      args,
+	}
+	var shellErr *shell.ApplyError
+	if errors.As(applyErr, &shellErr) {
+		checkpoint.WorkingDir = shellErr.WorkingDir
+	}
+
+	err := currentState.SetCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return fmt.Errorf("%w\nUnable to save the state of cluster manager '%s' after the failure: %v", applyErr, currentState.Name, err)
+	}
+
+	return fmt.Errorf("%w\nThe state of cluster manager '%s' was saved. Run `triton-kubernetes resume` to resume %s.", applyErr, currentState.Name, operation)
+}
+
+// ResumeApply applies the configuration of a cluster manager again, from the checkpoint of the
+// apply that failed.
+func ResumeApply(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	checkpoint, ok := currentState.Checkpoint()
+	if !ok {
+		fmt.Println("Nothing to resume.")
+		return nil
+	}
+
+	fmt.Printf("%s failed at %s: %s\n", checkpoint.Operation, checkpoint.FailedAt, checkpoint.Error)
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Resume %s", checkpoint.Operation)
+		selected := "Resume"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Resume canceled.")
+			return nil
+		}
+	}
+
+	// The keys generated for clusters were removed when the apply failed
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+	clusterKeys := []string{}
+	for _, clusterKey := range clusters {
+		clusterKeys = append(clusterKeys, clusterKey)
+	}
+	sort.Strings(clusterKeys)
+
+	err = applyWithTritonClusterSSHKeys(currentState, clusterKeys, func() error {
+		return shell.ResumeTerraformApplyWithState(conf, currentState, checkpoint.WorkingDir, checkpoint.Args)
+	})
+	if err != nil {
+		return recordApplyCheckpoint(remoteBackend, currentState, checkpoint.Operation, checkpoint.Args, err)
+	}
+
+	// Applying the whole configuration converges the nodes that previously failed too
+	if len(checkpoint.Args) == 0 {
+		for _, clusterKey := range clusterKeys {
+			err = clearFailedNodes(currentState, clusterKey)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	currentState.ClearCheckpoint()
+
+	// After terraform succeeds, commit state
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Resumed %s.\n", checkpoint.Operation)
+	return nil
+}
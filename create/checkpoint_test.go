@@ -0,0 +1,81 @@
+package create
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/backend/mocks"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRecordApplyCheckpoint(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module":{"cluster-manager":{"name":"dev-manager"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localBackend := &mocks.Backend{}
+	localBackend.On("PersistState", mock.Anything).Return(nil)
+
+	applyErr := &shell.ApplyError{Err: errors.New("Error applying plan"), WorkingDir: "/tmp/triton-kubernetes-1"}
+	err = recordApplyCheckpoint(localBackend, currentState, "create cluster dev", nil, applyErr)
+	if err == nil || !strings.Contains(err.Error(), "triton-kubernetes resume") {
+		t.Errorf("Expected an error telling to resume, got %v", err)
+	}
+	if code := util.ExitCode(err); code != util.ExitCodeTerraform {
+		t.Errorf("Expected the exit code of terraform failures, got %d", code)
+	}
+	localBackend.AssertCalled(t, "PersistState", mock.Anything)
+
+	checkpoint, ok := currentState.Checkpoint()
+	if !ok {
+		t.Fatal("Expected a checkpoint in the state")
+	}
+	if checkpoint.Operation != "create cluster dev" || checkpoint.Error != "Error applying plan" || checkpoint.WorkingDir != "/tmp/triton-kubernetes-1" {
+		t.Errorf("Unexpected checkpoint %+v", checkpoint)
+	}
+}
+
+func TestRecordApplyCheckpointPlanNotApplied(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A declined plan changed nothing, there's nothing to persist
+	localBackend := &mocks.Backend{}
+	err = recordApplyCheckpoint(localBackend, currentState, "create cluster dev", nil, shell.ErrPlanNotApplied)
+	if err != shell.ErrPlanNotApplied {
+		t.Errorf("Expected the plan not to be applied, got %v", err)
+	}
+	if _, ok := currentState.Checkpoint(); ok {
+		t.Error("Expected no checkpoint")
+	}
+}
+
+func TestResumeApplyNothingToResume(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
+
+	currentState, err := state.New("dev-manager", []byte(`{"module":{"cluster-manager":{"name":"dev-manager"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{"dev-manager"}, nil)
+	localBackend.On("State", "dev-manager").Return(currentState, nil)
+
+	err = ResumeApply(conf, localBackend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localBackend.AssertNotCalled(t, "PersistState", mock.Anything)
+}
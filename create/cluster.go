@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/shell"
 	"github.com/joyent/triton-kubernetes/state"
 	"github.com/joyent/triton-kubernetes/util"
@@ -13,7 +14,6 @@ import (
 	"github.com/joyent/triton-kubernetes/backend"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -21,6 +21,9 @@ const (
 	defaultSourceRef = "master"
 )
 
+// Cluster names must be DNS names
+var clusterNameRegexp = regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$")
+
 type baseClusterTerraformConfig struct {
 	Source string `json:"source"`
 
@@ -30,8 +33,17 @@ type baseClusterTerraformConfig struct {
 	RancherAccessKey string `json:"rancher_access_key"`
 	RancherSecretKey string `json:"rancher_secret_key"`
 
+	RancherClusterTemplateID         string `json:"rancher_cluster_template_id,omitempty"`
+	RancherClusterTemplateRevisionID string `json:"rancher_cluster_template_revision_id,omitempty"`
+
 	KubernetesVersion         string `json:"k8s_version,omitempty"`
 	KubernetesNetworkProvider string `json:"k8s_network_provider,omitempty"`
+	KubernetesNetworkMTU      string `json:"k8s_network_mtu,omitempty"`
+	KubernetesNetworkBackend  string `json:"k8s_network_backend,omitempty"`
+
+	KubernetesNodeLocalDNS               string `json:"k8s_nodelocal_dns,omitempty"`
+	KubernetesCoreDNSMinReplicas         string `json:"k8s_coredns_min_replicas,omitempty"`
+	KubernetesCoreDNSUpstreamNameservers string `json:"k8s_coredns_upstream_nameservers,omitempty"`
 
 	RancherRegistry         string `json:"rancher_registry,omitempty"`
 	RancherRegistryUsername string `json:"rancher_registry_username,omitempty"`
@@ -40,10 +52,21 @@ type baseClusterTerraformConfig struct {
 	KubernetesRegistry         string `json:"k8s_registry,omitempty"`
 	KubernetesRegistryUsername string `json:"k8s_registry_username,omitempty"`
 	KubernetesRegistryPassword string `json:"k8s_registry_password,omitempty"`
+
+	KubernetesAuditLog           string `json:"k8s_audit_log,omitempty"`
+	KubernetesAuditPolicy        string `json:"k8s_audit_policy,omitempty"`
+	KubernetesAuditLogMaxAge     string `json:"k8s_audit_log_max_age,omitempty"`
+	KubernetesAuditLogMaxBackups string `json:"k8s_audit_log_max_backups,omitempty"`
+	KubernetesAuditLogMaxSize    string `json:"k8s_audit_log_max_size,omitempty"`
+
+	KubernetesSecretsEncryption       string `json:"k8s_secrets_encryption,omitempty"`
+	KubernetesSecretsEncryptionConfig string `json:"k8s_secrets_encryption_config,omitempty"`
+	KubernetesKMSPluginImage          string `json:"k8s_kms_plugin_image,omitempty"`
+	KubernetesKMSPluginArgs           string `json:"k8s_kms_plugin_args,omitempty"`
 }
 
-func NewCluster(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func NewCluster(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	clusterManagers, err := remoteBackend.States()
 	if err != nil {
 		return err
@@ -54,10 +77,10 @@ func NewCluster(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterManager := ""
-	if viper.IsSet("cluster_manager") {
-		selectedClusterManager = viper.GetString("cluster_manager")
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_manager must be specified")
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Cluster Manager",
@@ -97,14 +120,14 @@ func NewCluster(remoteBackend backend.Backend) error {
 
 	// Ask user what cloud provider the new cluster should be created in
 	selectedCloudProvider := ""
-	if viper.IsSet("cluster_cloud_provider") {
-		selectedCloudProvider = viper.GetString("cluster_cloud_provider")
+	if conf.IsSet("cluster_cloud_provider") {
+		selectedCloudProvider = conf.GetString("cluster_cloud_provider")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_cloud_provider must be specified")
+		return util.ConfigError(errors.New("cluster_cloud_provider must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Create Cluster in which Cloud Provider",
-			Items: []string{"Triton", "AWS", "GCP", "Azure", "BareMetal", "vSphere"},
+			Items: []string{"Triton", "AWS", "GCP", "Azure", "DigitalOcean", "EquinixMetal", "OpenStack", "BareMetal", "vSphere", "Proxmox", "Nutanix", "Libvirt"},
 			Templates: &promptui.SelectTemplates{
 				Label:    "{{ . }}?",
 				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
@@ -121,21 +144,44 @@ func NewCluster(remoteBackend backend.Backend) error {
 		selectedCloudProvider = strings.ToLower(value)
 	}
 
+	err = checkGenerateSSHKeySupported(conf, selectedCloudProvider)
+	if err != nil {
+		return err
+	}
+
+	tfVars, err := loadTFVarsFile(conf, "tfvars_file")
+	if err != nil {
+		return err
+	}
+	defer tfVars.restore()
+
 	var clusterName string
 	switch selectedCloudProvider {
 	case "triton":
 		// We pass the same Triton credentials used to get the cluster manager state to create the cluster.
-		clusterName, err = newTritonCluster(remoteBackend, currentState)
+		clusterName, err = newTritonCluster(conf, remoteBackend, currentState)
 	case "aws":
-		clusterName, err = newAWSCluster(remoteBackend, currentState)
+		clusterName, err = newAWSCluster(conf, remoteBackend, currentState)
 	case "gcp":
-		clusterName, err = newGCPCluster(remoteBackend, currentState)
+		clusterName, err = newGCPCluster(conf, remoteBackend, currentState)
 	case "azure":
-		clusterName, err = newAzureCluster(remoteBackend, currentState)
+		clusterName, err = newAzureCluster(conf, remoteBackend, currentState)
+	case "digitalocean":
+		clusterName, err = newDigitalOceanCluster(conf, remoteBackend, currentState)
+	case "equinixmetal":
+		clusterName, err = newEquinixMetalCluster(conf, remoteBackend, currentState)
+	case "openstack":
+		clusterName, err = newOpenStackCluster(conf, remoteBackend, currentState)
 	case "baremetal":
-		clusterName, err = newBareMetalCluster(remoteBackend, currentState)
+		clusterName, err = newBareMetalCluster(conf, remoteBackend, currentState)
 	case "vsphere":
-		clusterName, err = newVSphereCluster(remoteBackend, currentState)
+		clusterName, err = newVSphereCluster(conf, remoteBackend, currentState)
+	case "proxmox":
+		clusterName, err = newProxmoxCluster(conf, remoteBackend, currentState)
+	case "nutanix":
+		clusterName, err = newNutanixCluster(conf, remoteBackend, currentState)
+	case "libvirt":
+		clusterName, err = newLibvirtCluster(conf, remoteBackend, currentState)
 	default:
 		return fmt.Errorf("Unsupported cloud provider '%s', cannot create cluster", selectedCloudProvider)
 	}
@@ -145,8 +191,8 @@ func NewCluster(remoteBackend backend.Backend) error {
 
 	// TODO: Find a fix - state.Clusters() doesn't return any clusters added via state.Add().
 	// However, the new clusters appear in the result of state.Bytes(). The current workaround
-	// is to create a new state object that has the same bytes as the previous state object.
-	currentState, err = state.New(currentState.Name, currentState.Bytes())
+	// is to parse the bytes of the state again.
+	err = currentState.Reload()
 	if err != nil {
 		return err
 	}
@@ -161,9 +207,43 @@ func NewCluster(remoteBackend backend.Backend) error {
 		return fmt.Errorf("Couldn't find cluster key for cluster '%s'.\n", clusterName)
 	}
 
+	err = tfVars.merge(currentState, []string{clusterKey})
+	if err != nil {
+		return err
+	}
+	// The nodes have their own variables file
+	tfVars.restore()
+
+	// The secrets encryption config holds the encryption key, only its encrypted form is kept in the state
+	secretsEncryptionConfig := currentState.Get(fmt.Sprintf("module.%s.k8s_secrets_encryption_config", clusterKey))
+	if secretsEncryptionConfig != "" {
+		encryptedConfig, err := util.EncryptSecret(conf, secretsEncryptionConfig)
+		if err != nil {
+			return err
+		}
+
+		err = currentState.SetSecretsEncryptionConfig(clusterKey, encryptedConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Worker nodes add themselves to the ingress load balancer, so it's added before them
+	err = newIngressLoadBalancerAddon(conf, clusterKey, currentState)
+	if err != nil {
+		return err
+	}
+
+	// Same workaround as above, the nodes look up the load balancer in the state
+	err = currentState.Reload()
+	if err != nil {
+		return err
+	}
+
 	// Add nodes from config
-	if viper.IsSet("nodes") {
-		nodesToAdd, ok := viper.Get("nodes").([]interface{})
+	allNewHostnames := []string{}
+	if conf.IsSet("nodes") {
+		nodesToAdd, ok := conf.Get("nodes").([]interface{})
 		if !ok {
 			return errors.New("Could not read 'nodes' configuration")
 		}
@@ -173,45 +253,135 @@ func NewCluster(remoteBackend backend.Backend) error {
 				return errors.New("Could not read node configuration")
 			}
 
-			// Add all variables to viper
-			viper.Set("rancher_host_label", nodeToAdd["rancher_host_label"])
-			viper.Set("node_count", nodeToAdd["node_count"])
-			viper.Set("hostname", nodeToAdd["hostname"])
+			// Add all variables to the config
+			conf.Set("rancher_host_label", nodeToAdd["rancher_host_label"])
+			conf.Set("node_count", nodeToAdd["node_count"])
+			conf.Set("hostname", nodeToAdd["hostname"])
+			conf.Set("ntp_servers", nodeToAdd["ntp_servers"])
+			conf.Set("timezone", nodeToAdd["timezone"])
+			conf.Set("sysctls", nodeToAdd["sysctls"])
+			conf.Set("kube_reserved", nodeToAdd["kube_reserved"])
+			conf.Set("system_reserved", nodeToAdd["system_reserved"])
+			conf.Set("docker_engine_version", nodeToAdd["docker_engine_version"])
+			conf.Set("node_tfvars_file", nodeToAdd["tfvars_file"])
 
 			// Figure out cloud provider
 			if selectedCloudProvider == "aws" {
-				// Copy aws node variables to viper
-				viper.Set("aws_ami_id", nodeToAdd["aws_ami_id"])
-				viper.Set("aws_instance_type", nodeToAdd["aws_instance_type"])
+				// Copy aws node variables to the config
+				conf.Set("aws_ami_id", nodeToAdd["aws_ami_id"])
+				conf.Set("aws_instance_type", nodeToAdd["aws_instance_type"])
+				conf.Set("node_aws_access_key", nodeToAdd["aws_access_key"])
+				conf.Set("node_aws_secret_key", nodeToAdd["aws_secret_key"])
+				conf.Set("node_aws_region", nodeToAdd["aws_region"])
+				conf.Set("aws_subnet_id", nodeToAdd["aws_subnet_id"])
+				conf.Set("aws_security_group_id", nodeToAdd["aws_security_group_id"])
+				conf.Set("aws_key_name", nodeToAdd["aws_key_name"])
+				conf.Set("node_aws_key_name", nodeToAdd["aws_key_name"])
+				conf.Set("aws_autoscaling", nodeToAdd["aws_autoscaling"])
+				conf.Set("aws_asg_min_size", nodeToAdd["aws_asg_min_size"])
+				conf.Set("aws_asg_max_size", nodeToAdd["aws_asg_max_size"])
+				conf.Set("aws_spot", nodeToAdd["aws_spot"])
+				conf.Set("aws_spot_max_price", nodeToAdd["aws_spot_max_price"])
+				conf.Set("aws_availability_zone", nodeToAdd["aws_availability_zone"])
 			} else if selectedCloudProvider == "triton" {
-				// Copy triton variables to viper
-				viper.Set("triton_network_names", nodeToAdd["triton_network_names"])
-				viper.Set("triton_image_name", nodeToAdd["triton_image_name"])
-				viper.Set("triton_image_version", nodeToAdd["triton_image_version"])
-				viper.Set("triton_ssh_user", nodeToAdd["triton_ssh_user"])
-				viper.Set("triton_machine_package", nodeToAdd["triton_machine_package"])
+				// Copy triton variables to the config
+				conf.Set("triton_network_names", nodeToAdd["triton_network_names"])
+				conf.Set("triton_image_name", nodeToAdd["triton_image_name"])
+				conf.Set("triton_image_version", nodeToAdd["triton_image_version"])
+				conf.Set("triton_ssh_user", nodeToAdd["triton_ssh_user"])
+				conf.Set("triton_machine_package", nodeToAdd["triton_machine_package"])
+				conf.Set("triton_tags", nodeToAdd["triton_tags"])
+				conf.Set("triton_metadata", nodeToAdd["triton_metadata"])
+				conf.Set("triton_ssh_key_fingerprints", nodeToAdd["triton_ssh_key_fingerprints"])
+				conf.Set("triton_hugepages", nodeToAdd["triton_hugepages"])
+				conf.Set("triton_isolated_cpus", nodeToAdd["triton_isolated_cpus"])
+				conf.Set("node_triton_account", nodeToAdd["triton_account"])
+				conf.Set("node_triton_key_path", nodeToAdd["triton_key_path"])
+				conf.Set("node_triton_key_id", nodeToAdd["triton_key_id"])
+				conf.Set("node_triton_url", nodeToAdd["triton_url"])
 			} else if selectedCloudProvider == "gcp" {
-				// Copy gcp variables to viper
-				viper.Set("gcp_instance_zone", nodeToAdd["gcp_instance_zone"])
-				viper.Set("gcp_machine_type", nodeToAdd["gcp_machine_type"])
-				viper.Set("gcp_image", nodeToAdd["gcp_image"])
+				// Copy gcp variables to the config
+				conf.Set("gcp_instance_zone", nodeToAdd["gcp_instance_zone"])
+				conf.Set("gcp_machine_type", nodeToAdd["gcp_machine_type"])
+				conf.Set("gcp_image", nodeToAdd["gcp_image"])
+				conf.Set("gcp_mig", nodeToAdd["gcp_mig"])
+				conf.Set("gcp_autoscaling", nodeToAdd["gcp_autoscaling"])
+				conf.Set("gcp_autoscaler_min_replicas", nodeToAdd["gcp_autoscaler_min_replicas"])
+				conf.Set("gcp_autoscaler_max_replicas", nodeToAdd["gcp_autoscaler_max_replicas"])
+				conf.Set("gcp_autoscaler_cpu_target", nodeToAdd["gcp_autoscaler_cpu_target"])
+				conf.Set("gcp_autoscaler_cooldown_period", nodeToAdd["gcp_autoscaler_cooldown_period"])
+				conf.Set("gcp_health_check_port", nodeToAdd["gcp_health_check_port"])
+				conf.Set("gcp_health_check_initial_delay", nodeToAdd["gcp_health_check_initial_delay"])
 			} else if selectedCloudProvider == "azure" {
-				// Copy azure variables to viper
-				viper.Set("azure_size", nodeToAdd["azure_size"])
-				viper.Set("azure_ssh_user", nodeToAdd["azure_ssh_user"])
-				viper.Set("azure_public_key_path", nodeToAdd["azure_public_key_path"])
+				// Copy azure variables to the config
+				conf.Set("azure_size", nodeToAdd["azure_size"])
+				conf.Set("azure_ssh_user", nodeToAdd["azure_ssh_user"])
+				conf.Set("azure_public_key_path", nodeToAdd["azure_public_key_path"])
+				conf.Set("azure_ssh_public_key_id", nodeToAdd["azure_ssh_public_key_id"])
+				conf.Set("node_azure_subscription_id", nodeToAdd["azure_subscription_id"])
+				conf.Set("node_azure_client_id", nodeToAdd["azure_client_id"])
+				conf.Set("node_azure_client_secret", nodeToAdd["azure_client_secret"])
+				conf.Set("node_azure_tenant_id", nodeToAdd["azure_tenant_id"])
+				conf.Set("azure_resource_group_name", nodeToAdd["azure_resource_group_name"])
+				conf.Set("azure_network_security_group_id", nodeToAdd["azure_network_security_group_id"])
+				conf.Set("azure_subnet_id", nodeToAdd["azure_subnet_id"])
+				conf.Set("azure_vmss", nodeToAdd["azure_vmss"])
+				conf.Set("azure_spot", nodeToAdd["azure_spot"])
+				conf.Set("azure_spot_max_price", nodeToAdd["azure_spot_max_price"])
+			} else if selectedCloudProvider == "digitalocean" {
+				conf.Set("digitalocean_droplet_size", nodeToAdd["digitalocean_droplet_size"])
+				conf.Set("digitalocean_image", nodeToAdd["digitalocean_image"])
+				conf.Set("digitalocean_ssh_key_fingerprint", nodeToAdd["digitalocean_ssh_key_fingerprint"])
+			} else if selectedCloudProvider == "equinixmetal" {
+				conf.Set("equinix_metal_plan", nodeToAdd["equinix_metal_plan"])
+				conf.Set("equinix_metal_operating_system", nodeToAdd["equinix_metal_operating_system"])
+			} else if selectedCloudProvider == "openstack" {
+				conf.Set("openstack_flavor_name", nodeToAdd["openstack_flavor_name"])
+				conf.Set("openstack_image_name", nodeToAdd["openstack_image_name"])
+				conf.Set("openstack_network_name", nodeToAdd["openstack_network_name"])
+				conf.Set("openstack_key_pair", nodeToAdd["openstack_key_pair"])
+				// Nodes are reached at their fixed IP without a floating IP pool
+				if floatingIPPool, ok := nodeToAdd["openstack_floating_ip_pool"]; ok {
+					conf.Set("openstack_floating_ip_pool", floatingIPPool)
+				}
 			} else if selectedCloudProvider == "baremetal" {
-				viper.Set("ssh_user", nodeToAdd["ssh_user"])
-				viper.Set("key_path", nodeToAdd["key_path"])
-				viper.Set("bastion_host", nodeToAdd["bastion_host"])
-				viper.Set("hosts", nodeToAdd["hosts"])
+				conf.Set("ssh_user", nodeToAdd["ssh_user"])
+				conf.Set("key_path", nodeToAdd["key_path"])
+				conf.Set("bastion_host", nodeToAdd["bastion_host"])
+				conf.Set("hosts", nodeToAdd["hosts"])
+			} else if selectedCloudProvider == "proxmox" {
+				conf.Set("proxmox_node", nodeToAdd["proxmox_node"])
+				conf.Set("proxmox_template_name", nodeToAdd["proxmox_template_name"])
+				conf.Set("proxmox_storage", nodeToAdd["proxmox_storage"])
+				conf.Set("proxmox_bridge", nodeToAdd["proxmox_bridge"])
+				conf.Set("proxmox_cores", nodeToAdd["proxmox_cores"])
+				conf.Set("proxmox_memory", nodeToAdd["proxmox_memory"])
+				conf.Set("proxmox_disk_size", nodeToAdd["proxmox_disk_size"])
+				conf.Set("proxmox_ssh_user", nodeToAdd["proxmox_ssh_user"])
+				conf.Set("proxmox_key_path", nodeToAdd["proxmox_key_path"])
+			} else if selectedCloudProvider == "nutanix" {
+				conf.Set("nutanix_cluster", nodeToAdd["nutanix_cluster"])
+				conf.Set("nutanix_subnet", nodeToAdd["nutanix_subnet"])
+				conf.Set("nutanix_image", nodeToAdd["nutanix_image"])
+				conf.Set("nutanix_vcpus", nodeToAdd["nutanix_vcpus"])
+				conf.Set("nutanix_memory", nodeToAdd["nutanix_memory"])
+				conf.Set("nutanix_disk_size", nodeToAdd["nutanix_disk_size"])
+				conf.Set("nutanix_ssh_user", nodeToAdd["nutanix_ssh_user"])
+				conf.Set("nutanix_key_path", nodeToAdd["nutanix_key_path"])
+			} else if selectedCloudProvider == "libvirt" {
+				conf.Set("libvirt_vcpu", nodeToAdd["libvirt_vcpu"])
+				conf.Set("libvirt_memory", nodeToAdd["libvirt_memory"])
+				conf.Set("libvirt_disk_size", nodeToAdd["libvirt_disk_size"])
+				conf.Set("libvirt_ssh_user", nodeToAdd["libvirt_ssh_user"])
+				conf.Set("libvirt_key_path", nodeToAdd["libvirt_key_path"])
 			}
 
 			// Create the new node
-			newHostnames, err := newNode(selectedClusterManager, clusterKey, remoteBackend, currentState)
+			newHostnames, err := newNode(conf, selectedClusterManager, clusterKey, remoteBackend, currentState)
 			if err != nil {
 				return err
 			}
+			allNewHostnames = append(allNewHostnames, newHostnames...)
 			printNodesAddedMessage(newHostnames)
 		}
 	}
@@ -246,10 +416,11 @@ func NewCluster(remoteBackend backend.Backend) error {
 
 		for shouldCreateNode {
 			// Add new nodes to the state
-			newHostnames, err := newNode(selectedClusterManager, clusterKey, remoteBackend, currentState)
+			newHostnames, err := newNode(conf, selectedClusterManager, clusterKey, remoteBackend, currentState)
 			if err != nil {
 				return err
 			}
+			allNewHostnames = append(allNewHostnames, newHostnames...)
 
 			printNodesAddedMessage(newHostnames)
 
@@ -260,7 +431,44 @@ func NewCluster(remoteBackend backend.Backend) error {
 			}
 			shouldCreateNode = createNodeOptions[i].Value
 		}
+	}
+
+	err = recordNodePools(currentState, clusterKey, allNewHostnames)
+	if err != nil {
+		return err
+	}
+
+	// Refuse nodes that would exceed the cluster's budget
+	err = setMonthlyBudget(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+	cost, err := estimateMonthlyCost(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+	err = checkMonthlyBudget(conf, currentState, clusterKey, cost, cost)
+	if err != nil {
+		return err
+	}
+
+	// Add cluster addons
+	err = newClusterAddons(conf, clusterKey, currentState)
+	if err != nil {
+		return err
+	}
+
+	err = newCertManagerAddon(conf, clusterKey, currentState)
+	if err != nil {
+		return err
+	}
+
+	err = newAuditLogShippingAddon(conf, clusterKey, currentState)
+	if err != nil {
+		return err
+	}
 
+	if !nonInteractiveMode {
 		// Confirmation
 		label := "Proceed with cluster creation"
 		selected := "Proceed"
@@ -274,48 +482,78 @@ func NewCluster(remoteBackend backend.Backend) error {
 		}
 	}
 
-	// Run terraform apply with state
-	err = shell.RunTerraformApplyWithState(currentState)
+	// Block on configurations that violate the user's policies
+	err = checkPolicies(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the new nodes will be able to register with the cluster manager
+	err = checkRancherConnectivity(conf, currentState)
 	if err != nil {
 		return err
 	}
 
+	// Run terraform apply with state, with the key generated for the cluster registered
+	err = applyWithTritonClusterSSHKeys(currentState, []string{clusterKey}, func() error {
+		return shell.RunTerraformApplyWithState(conf, currentState, []string{})
+	})
+	if err != nil {
+		err = recordApplyCheckpoint(remoteBackend, currentState, fmt.Sprintf("create cluster %s", clusterName), nil, err)
+		return recordNodeApplyFailure(conf, remoteBackend, currentState, clusterKey, allNewHostnames, err)
+	}
+	currentState.ClearCheckpoint()
+
 	// After terraform succeeds, commit state
 	err = remoteBackend.PersistState(currentState)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = waitForClusterNodes(conf, currentState, clusterKey, allNewHostnames)
+	if err != nil {
+		return fmt.Errorf("Cluster '%s' was created, but its nodes aren't healthy. %s", clusterKey, err)
+	}
+
+	// The nodes registered with the cluster's own token, unless they weren't waited for
+	if len(allNewHostnames) == 0 || !useEphemeralRegistrationTokens(conf) {
+		return nil
+	}
+	if getNodeRegistrationTimeout(conf) <= 0 {
+		fmt.Println("Kept the registration token of the cluster, node_registration_timeout is 0 so its nodes may still be registering.")
+		return nil
+	}
+	return rotateClusterRegistrationToken(conf, remoteBackend, currentState, clusterKey)
+
 }
 
-func getBaseClusterTerraformConfig(terraformModulePath string) (baseClusterTerraformConfig, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func getBaseClusterTerraformConfig(conf config.Config, currentState state.State, terraformModulePath string) (baseClusterTerraformConfig, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	rancherAccessKey, rancherSecretKey := currentState.RancherAPIKeyReferences()
 	cfg := baseClusterTerraformConfig{
 		RancherAPIURL:    "${module.cluster-manager.rancher_url}",
-		RancherAccessKey: "${module.cluster-manager.rancher_access_key}",
-		RancherSecretKey: "${module.cluster-manager.rancher_secret_key}",
+		RancherAccessKey: rancherAccessKey,
+		RancherSecretKey: rancherSecretKey,
 	}
 
 	baseSource := defaultSourceURL
-	if viper.IsSet("source_url") {
-		baseSource = viper.GetString("source_url")
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
 	}
 
 	baseSourceRef := defaultSourceRef
-	if viper.IsSet("source_ref") {
-		baseSourceRef = viper.GetString("source_ref")
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
 	}
 
 	// Module Source location e.g. github.com/joyent/triton-kubernetes//terraform/modules/azure-rancher-k8s?ref=master
 	cfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, terraformModulePath, baseSourceRef)
 
 	// Name
-	clusterNameRegexp := regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$")
-	if viper.IsSet("name") {
-		cfg.Name = viper.GetString("name")
+	if conf.IsSet("name") {
+		cfg.Name = conf.GetString("name")
 	} else if nonInteractiveMode {
-		return baseClusterTerraformConfig{}, errors.New("name must be specified")
+		return baseClusterTerraformConfig{}, util.ConfigError(errors.New("name must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Cluster Name",
@@ -336,14 +574,29 @@ func getBaseClusterTerraformConfig(terraformModulePath string) (baseClusterTerra
 	}
 
 	if cfg.Name == "" || !clusterNameRegexp.MatchString(cfg.Name) {
-		return baseClusterTerraformConfig{}, errors.New("Invalid Cluster Name")
+		return baseClusterTerraformConfig{}, util.ConfigError(errors.New("Invalid Cluster Name"))
+	}
+
+	err := getClusterTemplateConfig(conf, currentState, &cfg)
+	if err != nil {
+		return baseClusterTerraformConfig{}, err
+	}
+
+	// The cluster template defines the cluster's Kubernetes config, only the registry of
+	// Rancher's agents is still needed
+	if cfg.RancherClusterTemplateRevisionID != "" {
+		err = getRancherRegistryConfig(conf, &cfg)
+		if err != nil {
+			return baseClusterTerraformConfig{}, err
+		}
+		return cfg, nil
 	}
 
 	// Kubernetes Version
-	if viper.IsSet("k8s_version") {
-		cfg.KubernetesVersion = viper.GetString("k8s_version")
+	if conf.IsSet("k8s_version") {
+		cfg.KubernetesVersion = conf.GetString("k8s_version")
 	} else if nonInteractiveMode {
-		return baseClusterTerraformConfig{}, errors.New("k8s_version must be specified")
+		return baseClusterTerraformConfig{}, util.ConfigError(errors.New("k8s_version must be specified"))
 	} else {
 
 		var kubernetesVersions = []struct {
@@ -374,10 +627,10 @@ func getBaseClusterTerraformConfig(terraformModulePath string) (baseClusterTerra
 	}
 
 	// Kubernetes Network Provider
-	if viper.IsSet("k8s_network_provider") {
-		cfg.KubernetesNetworkProvider = viper.GetString("k8s_network_provider")
+	if conf.IsSet("k8s_network_provider") {
+		cfg.KubernetesNetworkProvider = conf.GetString("k8s_network_provider")
 	} else if nonInteractiveMode {
-		return baseClusterTerraformConfig{}, errors.New("k8s_network_provider must be specified")
+		return baseClusterTerraformConfig{}, util.ConfigError(errors.New("k8s_network_provider must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Kubernetes Network Provider",
@@ -398,12 +651,27 @@ func getBaseClusterTerraformConfig(terraformModulePath string) (baseClusterTerra
 		cfg.KubernetesNetworkProvider = value
 	}
 
-	// Rancher Docker Registry
-	if viper.IsSet("private_registry") {
-		cfg.RancherRegistry = viper.GetString("private_registry")
+	err = getKubernetesNetworkConfig(conf, &cfg)
+	if err != nil {
+		return baseClusterTerraformConfig{}, err
+	}
+
+	err = getKubernetesDNSConfig(conf, getManagerRancherVersion(currentState), &cfg)
+	if err != nil {
+		return baseClusterTerraformConfig{}, err
+	}
+
+	err = getRancherRegistryConfig(conf, &cfg)
+	if err != nil {
+		return baseClusterTerraformConfig{}, err
+	}
+
+	// k8s Docker Registry
+	if conf.IsSet("k8s_registry") {
+		cfg.KubernetesRegistry = conf.GetString("k8s_registry")
 	} else if !nonInteractiveMode {
 		prompt := promptui.Prompt{
-			Label:   "Private Registry",
+			Label:   "k8s Registry",
 			Default: "None",
 		}
 
@@ -413,37 +681,37 @@ func getBaseClusterTerraformConfig(terraformModulePath string) (baseClusterTerra
 		}
 
 		if result != "None" {
-			cfg.RancherRegistry = result
+			cfg.KubernetesRegistry = result
 		}
 	}
 
-	// Ask for rancher registry username/password only if rancher registry is given
-	if cfg.RancherRegistry != "" {
-		// Rancher Registry Username
-		if viper.IsSet("private_registry_username") {
-			cfg.RancherRegistryUsername = viper.GetString("private_registry_username")
+	// Ask for k8s registry username/password only if k8s registry is given
+	if cfg.KubernetesRegistry != "" {
+		// k8s Registry Username
+		if conf.IsSet("k8s_registry_username") {
+			cfg.KubernetesRegistryUsername = conf.GetString("k8s_registry_username")
 		} else if nonInteractiveMode {
-			return baseClusterTerraformConfig{}, errors.New("private_registry_username must be specified")
+			return baseClusterTerraformConfig{}, util.ConfigError(errors.New("k8s_registry_username must be specified"))
 		} else {
 			prompt := promptui.Prompt{
-				Label: "Private Registry Username",
+				Label: "k8s Registry Username",
 			}
 
 			result, err := prompt.Run()
 			if err != nil {
 				return baseClusterTerraformConfig{}, err
 			}
-			cfg.RancherRegistryUsername = result
+			cfg.KubernetesRegistryUsername = result
 		}
 
 		// Rancher Registry Password
-		if viper.IsSet("private_registry_password") {
-			cfg.RancherRegistryPassword = viper.GetString("private_registry_password")
+		if conf.IsSet("k8s_registry_password") {
+			cfg.KubernetesRegistryPassword = conf.GetString("k8s_registry_password")
 		} else if nonInteractiveMode {
-			return baseClusterTerraformConfig{}, errors.New("private_registry_password must be specified")
+			return baseClusterTerraformConfig{}, util.ConfigError(errors.New("k8s_registry_password must be specified"))
 		} else {
 			prompt := promptui.Prompt{
-				Label: "Private Registry Password",
+				Label: "k8s Registry Password",
 				Mask:  '*',
 			}
 
@@ -451,68 +719,85 @@ func getBaseClusterTerraformConfig(terraformModulePath string) (baseClusterTerra
 			if err != nil {
 				return baseClusterTerraformConfig{}, err
 			}
-			cfg.RancherRegistryPassword = result
+			cfg.KubernetesRegistryPassword = result
 		}
 	}
 
-	// k8s Docker Registry
-	if viper.IsSet("k8s_registry") {
-		cfg.KubernetesRegistry = viper.GetString("k8s_registry")
+	err = getKubernetesAuditLogConfig(conf, &cfg)
+	if err != nil {
+		return baseClusterTerraformConfig{}, err
+	}
+
+	err = getKubernetesSecretsEncryptionConfig(conf, &cfg)
+	if err != nil {
+		return baseClusterTerraformConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// Asks for the registry the images of Rancher's agents are pulled from, private_registry.
+func getRancherRegistryConfig(conf config.Config, cfg *baseClusterTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	// Rancher Docker Registry
+	if conf.IsSet("private_registry") {
+		cfg.RancherRegistry = conf.GetString("private_registry")
 	} else if !nonInteractiveMode {
 		prompt := promptui.Prompt{
-			Label:   "k8s Registry",
+			Label:   "Private Registry",
 			Default: "None",
 		}
 
 		result, err := prompt.Run()
 		if err != nil {
-			return baseClusterTerraformConfig{}, err
+			return err
 		}
 
 		if result != "None" {
-			cfg.KubernetesRegistry = result
+			cfg.RancherRegistry = result
 		}
 	}
 
-	// Ask for k8s registry username/password only if k8s registry is given
-	if cfg.KubernetesRegistry != "" {
-		// k8s Registry Username
-		if viper.IsSet("k8s_registry_username") {
-			cfg.KubernetesRegistryUsername = viper.GetString("k8s_registry_username")
+	// Ask for rancher registry username/password only if rancher registry is given
+	if cfg.RancherRegistry != "" {
+		// Rancher Registry Username
+		if conf.IsSet("private_registry_username") {
+			cfg.RancherRegistryUsername = conf.GetString("private_registry_username")
 		} else if nonInteractiveMode {
-			return baseClusterTerraformConfig{}, errors.New("k8s_registry_username must be specified")
+			return util.ConfigError(errors.New("private_registry_username must be specified"))
 		} else {
 			prompt := promptui.Prompt{
-				Label: "k8s Registry Username",
+				Label: "Private Registry Username",
 			}
 
 			result, err := prompt.Run()
 			if err != nil {
-				return baseClusterTerraformConfig{}, err
+				return err
 			}
-			cfg.KubernetesRegistryUsername = result
+			cfg.RancherRegistryUsername = result
 		}
 
 		// Rancher Registry Password
-		if viper.IsSet("k8s_registry_password") {
-			cfg.KubernetesRegistryPassword = viper.GetString("k8s_registry_password")
+		if conf.IsSet("private_registry_password") {
+			cfg.RancherRegistryPassword = conf.GetString("private_registry_password")
 		} else if nonInteractiveMode {
-			return baseClusterTerraformConfig{}, errors.New("k8s_registry_password must be specified")
+			return util.ConfigError(errors.New("private_registry_password must be specified"))
 		} else {
 			prompt := promptui.Prompt{
-				Label: "k8s Registry Password",
+				Label: "Private Registry Password",
 				Mask:  '*',
 			}
 
 			result, err := prompt.Run()
 			if err != nil {
-				return baseClusterTerraformConfig{}, err
+				return err
 			}
-			cfg.KubernetesRegistryPassword = result
+			cfg.RancherRegistryPassword = result
 		}
 	}
 
-	return cfg, nil
+	return nil
 }
 
 func printNodesAddedMessage(newHostnames []string) {
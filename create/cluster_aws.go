@@ -8,7 +8,9 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -17,7 +19,6 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -31,18 +32,22 @@ type awsClusterTerraformConfig struct {
 
 	AWSAccessKey string `json:"aws_access_key"`
 	AWSSecretKey string `json:"aws_secret_key"`
+	awsRoleConfig
 
 	AWSRegion        string `json:"aws_region"`
 	AWSVPCCIDR       string `json:"aws_vpc_cidr"`
 	AWSSubnetCIDR    string `json:"aws_subnet_cidr"`
 	AWSPublicKeyPath string `json:"aws_public_key_path"`
 	AWSKeyName       string `json:"aws_key_name"`
+
+	AWSAvailabilityZones string `json:"aws_availability_zones,omitempty"`
+	AWSZoneSubnetCIDRs   string `json:"aws_zone_subnet_cidrs,omitempty"`
 }
 
 // Returns the name of the cluster that was created and the new state.
-func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseClusterTerraformConfig(awsRancherKubernetesTerraformModulePath)
+func newAWSCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, awsRancherKubernetesTerraformModulePath)
 	if err != nil {
 		return "", err
 	}
@@ -52,10 +57,10 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 	}
 
 	// AWS Access Key
-	if viper.IsSet("aws_access_key") {
-		cfg.AWSAccessKey = viper.GetString("aws_access_key")
+	if conf.IsSet("aws_access_key") {
+		cfg.AWSAccessKey = conf.GetString("aws_access_key")
 	} else if nonInteractiveMode {
-		return "", errors.New("aws_access_key must be specified")
+		return "", util.ConfigError(errors.New("aws_access_key must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "AWS Access Key",
@@ -75,10 +80,10 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 	}
 
 	// AWS Secret Key
-	if viper.IsSet("aws_secret_key") {
-		cfg.AWSSecretKey = viper.GetString("aws_secret_key")
+	if conf.IsSet("aws_secret_key") {
+		cfg.AWSSecretKey = conf.GetString("aws_secret_key")
 	} else if nonInteractiveMode {
-		return "", errors.New("aws_secret_key must be specified")
+		return "", util.ConfigError(errors.New("aws_secret_key must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "AWS Secret Key",
@@ -97,8 +102,22 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 		cfg.AWSSecretKey = result
 	}
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	userCreds := awsCredentials{AccessKey: cfg.AWSAccessKey, SecretKey: cfg.AWSSecretKey}
+	err = userCreds.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	// With aws_role_arn, terraform uses the temporary credentials of the role
+	roleCreds, role, err := getAWSRoleConfig(conf, userCreds)
+	if err != nil {
+		return "", err
+	}
+	cfg.awsRoleConfig = role
+
 	// We now have enough information to init an aws client
-	creds := credentials.NewStaticCredentials(cfg.AWSAccessKey, cfg.AWSSecretKey, "")
+	creds := credentials.NewStaticCredentials(roleCreds.AccessKey, roleCreds.SecretKey, roleCreds.SessionToken)
 
 	// Using us-west-1 region by default. The configuration needs a region set to
 	// get all regions available to the aws user.
@@ -117,8 +136,8 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 	regions := regionsResult.Regions
 
 	// AWS Region
-	if viper.IsSet("aws_region") {
-		cfg.AWSRegion = viper.GetString("aws_region")
+	if conf.IsSet("aws_region") {
+		cfg.AWSRegion = conf.GetString("aws_region")
 		// Validate the AWS Region
 		found := false
 		for _, region := range regions {
@@ -132,7 +151,7 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 			return "", fmt.Errorf("Selected AWS Region '%s' does not exist.", cfg.AWSRegion)
 		}
 	} else if nonInteractiveMode {
-		return "", errors.New("aws_region must be specified")
+		return "", util.ConfigError(errors.New("aws_region must be specified"))
 	} else {
 		// Building an array of strings that will be given to the SelectPrompt.
 		// The SelectTemplate has problems displaying struct fields that are string pointers.
@@ -181,20 +200,32 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 	}
 	ec2Client = ec2.New(sess)
 
+	// A key pair generated for the cluster is uploaded as its AWS key pair
+	sshKey, err := generateClusterSSHKey(conf, currentState.Name, cfg.Name)
+	if err != nil {
+		return "", err
+	}
+
 	// AWS Key
 	// If either aws_key_name or aws_public_key_path is set use it
 	// Otherwise ask the user if they'd like to upload a key or use an existing key
-	if viper.IsSet("aws_key_name") {
-		cfg.AWSKeyName = viper.GetString("aws_key_name")
-		if viper.IsSet("aws_public_key_path") {
-			expandedAWSPublicKeyPath, err := homedir.Expand(viper.GetString("aws_public_key_path"))
+	if sshKey != nil {
+		cfg.AWSKeyName = fmt.Sprintf("triton-kubernetes-%s-%s", currentState.Name, cfg.Name)
+		if conf.IsSet("aws_key_name") {
+			cfg.AWSKeyName = conf.GetString("aws_key_name")
+		}
+		cfg.AWSPublicKeyPath = sshKey.PublicKeyPath
+	} else if conf.IsSet("aws_key_name") {
+		cfg.AWSKeyName = conf.GetString("aws_key_name")
+		if conf.IsSet("aws_public_key_path") {
+			expandedAWSPublicKeyPath, err := homedir.Expand(conf.GetString("aws_public_key_path"))
 			if err != nil {
 				return "", err
 			}
 			cfg.AWSPublicKeyPath = expandedAWSPublicKeyPath
 		}
 	} else if nonInteractiveMode {
-		return "", errors.New("aws_key_name must be specified")
+		return "", util.ConfigError(errors.New("aws_key_name must be specified"))
 	} else {
 		// List all available aws keys
 		input := ec2.DescribeKeyPairsInput{}
@@ -281,10 +312,10 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 	}
 
 	// AWS VPC CIDR
-	if viper.IsSet("aws_vpc_cidr") {
-		cfg.AWSVPCCIDR = viper.GetString("aws_vpc_cidr")
+	if conf.IsSet("aws_vpc_cidr") {
+		cfg.AWSVPCCIDR = conf.GetString("aws_vpc_cidr")
 	} else if nonInteractiveMode {
-		return "", errors.New("aws_vpc_cidr must be specified")
+		return "", util.ConfigError(errors.New("aws_vpc_cidr must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "AWS VPC CIDR",
@@ -313,10 +344,10 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 	}
 
 	// AWS Subnet CIDR
-	if viper.IsSet("aws_subnet_cidr") {
-		cfg.AWSSubnetCIDR = viper.GetString("aws_subnet_cidr")
+	if conf.IsSet("aws_subnet_cidr") {
+		cfg.AWSSubnetCIDR = conf.GetString("aws_subnet_cidr")
 	} else if nonInteractiveMode {
-		return "", errors.New("aws_subnet_cidr must be specified")
+		return "", util.ConfigError(errors.New("aws_subnet_cidr must be specified"))
 	} else {
 		// Parsing VPC CIDR to prepare for subnet validation
 		_, vpcIPNet, err := net.ParseCIDR(cfg.AWSVPCCIDR)
@@ -354,11 +385,30 @@ func newAWSCluster(remoteBackend backend.Backend, currentState state.State) (str
 		cfg.AWSSubnetCIDR = result
 	}
 
+	// Nodes can be spread across a subnet in each of several availability zones
+	err = getAWSAvailabilityZonesConfig(conf, ec2Client, &cfg)
+	if err != nil {
+		return "", err
+	}
+
 	// Add new cluster to terraform config
 	err = currentState.AddCluster("aws", cfg.Name, &cfg)
 	if err != nil {
 		return "", err
 	}
 
+	// The cluster's module references the role's temporary credentials, which its nodes copy
+	err = setAWSRoleKeys(conf, currentState, fmt.Sprintf("cluster_aws_%s", cfg.Name), cfg.AWSAccessKey, cfg.AWSSecretKey, cfg.awsRoleConfig)
+	if err != nil {
+		return "", err
+	}
+
+	if sshKey != nil {
+		err = currentState.SetSSHKey(fmt.Sprintf("cluster_aws_%s", cfg.Name), *sshKey)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return cfg.Name, nil
 }
@@ -6,14 +6,13 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 
-	"github.com/Azure/azure-sdk-for-go/arm/resources/subscriptions"
-	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -34,9 +33,9 @@ type azureClusterTerraformConfig struct {
 }
 
 // Returns the name of the cluster that was created and the new state.
-func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseClusterTerraformConfig(azureRancherKubernetesTerraformModulePath)
+func newAzureCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, azureRancherKubernetesTerraformModulePath)
 	if err != nil {
 		return "", err
 	}
@@ -46,10 +45,10 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 	}
 
 	// Azure Subscription ID
-	if viper.IsSet("azure_subscription_id") {
-		cfg.AzureSubscriptionID = viper.GetString("azure_subscription_id")
+	if conf.IsSet("azure_subscription_id") {
+		cfg.AzureSubscriptionID = conf.GetString("azure_subscription_id")
 	} else if nonInteractiveMode {
-		return "", errors.New("azure_subscription_id must be specified")
+		return "", util.ConfigError(errors.New("azure_subscription_id must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Subscription ID",
@@ -69,10 +68,10 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 	}
 
 	// Azure Client ID
-	if viper.IsSet("azure_client_id") {
-		cfg.AzureClientID = viper.GetString("azure_client_id")
+	if conf.IsSet("azure_client_id") {
+		cfg.AzureClientID = conf.GetString("azure_client_id")
 	} else if nonInteractiveMode {
-		return "", errors.New("azure_client_id must be specified")
+		return "", util.ConfigError(errors.New("azure_client_id must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Client ID",
@@ -92,10 +91,10 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 	}
 
 	// Azure Client Secret
-	if viper.IsSet("azure_client_secret") {
-		cfg.AzureClientSecret = viper.GetString("azure_client_secret")
+	if conf.IsSet("azure_client_secret") {
+		cfg.AzureClientSecret = conf.GetString("azure_client_secret")
 	} else if nonInteractiveMode {
-		return "", errors.New("azure_client_secret must be specified")
+		return "", util.ConfigError(errors.New("azure_client_secret must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Client Secret",
@@ -115,10 +114,10 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 	}
 
 	// Azure Tenant ID
-	if viper.IsSet("azure_tenant_id") {
-		cfg.AzureTenantID = viper.GetString("azure_tenant_id")
+	if conf.IsSet("azure_tenant_id") {
+		cfg.AzureTenantID = conf.GetString("azure_tenant_id")
 	} else if nonInteractiveMode {
-		return "", errors.New("azure_tenant_id must be specified")
+		return "", util.ConfigError(errors.New("azure_tenant_id must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Tenant ID",
@@ -138,10 +137,10 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 	}
 
 	// Azure Environment
-	if viper.IsSet("azure_environment") {
-		cfg.AzureEnvironment = viper.GetString("azure_environment")
+	if conf.IsSet("azure_environment") {
+		cfg.AzureEnvironment = conf.GetString("azure_environment")
 	} else if nonInteractiveMode {
-		return "", errors.New("azure_environment must be specified")
+		return "", util.ConfigError(errors.New("azure_environment must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Azure Environment",
@@ -164,7 +163,7 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 
 	// Verify selected azure environment is valid
 	if cfg.AzureEnvironment != "public" && cfg.AzureEnvironment != "government" && cfg.AzureEnvironment != "german" && cfg.AzureEnvironment != "china" {
-		return "", fmt.Errorf("Invalid azure_environment '%s', must be one of the following: 'public', 'government', 'german', or 'china'", cfg.AzureEnvironment)
+		return "", util.ConfigError(fmt.Errorf("Invalid azure_environment '%s', must be one of the following: 'public', 'government', 'german', or 'china'", cfg.AzureEnvironment))
 	}
 
 	// Terraform expects public/government/german/china for azure environment
@@ -174,6 +173,18 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 		return "", err
 	}
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = azureCredentials{
+		Environment:    azureEnv,
+		SubscriptionID: cfg.AzureSubscriptionID,
+		TenantID:       cfg.AzureTenantID,
+		ClientID:       cfg.AzureClientID,
+		ClientSecret:   cfg.AzureClientSecret,
+	}.Validate()
+	if err != nil {
+		return "", err
+	}
+
 	// We now have enough information to init an azure client
 	oauthConfig, err := adal.NewOAuthConfig(azureEnv.ActiveDirectoryEndpoint, cfg.AzureTenantID)
 	if err != nil {
@@ -185,22 +196,14 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 		return "", err
 	}
 
-	azureGroupClient := subscriptions.NewGroupClientWithBaseURI(azureEnv.ResourceManagerEndpoint)
-	azureGroupClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
-
-	azureRawLocations, err := azureGroupClient.ListLocations(cfg.AzureSubscriptionID)
+	azureLocations, err := getAzureLocations(azureEnv, azureSPT, cfg.AzureSubscriptionID)
 	if err != nil {
 		return "", err
 	}
 
-	azureLocations := []string{}
-	for _, loc := range *azureRawLocations.Value {
-		azureLocations = append(azureLocations, *loc.DisplayName)
-	}
-
 	// Azure Location
-	if viper.IsSet("azure_location") {
-		cfg.AzureLocation = viper.GetString("azure_location")
+	if conf.IsSet("azure_location") {
+		cfg.AzureLocation = conf.GetString("azure_location")
 
 		// Verify selected azure location exists
 		found := false
@@ -211,10 +214,10 @@ func newAzureCluster(remoteBackend backend.Backend, currentState state.State) (s
 			}
 		}
 		if !found {
-			return "", fmt.Errorf("Invalid azure_location '%s', must be one of the following: %s", cfg.AzureLocation, strings.Join(azureLocations, ", "))
+			return "", util.ConfigError(fmt.Errorf("Invalid azure_location '%s', must be one of the following: %s", cfg.AzureLocation, strings.Join(azureLocations, ", ")))
 		}
 	} else if nonInteractiveMode {
-		return "", errors.New("azure_location must be specified")
+		return "", util.ConfigError(errors.New("azure_location must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Azure Location",
@@ -2,6 +2,7 @@ package create
 
 import (
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
 )
 
@@ -16,8 +17,8 @@ type bareMetalClusterTerraformConfig struct {
 }
 
 // Returns the name of the cluster that was created and the new state.
-func newBareMetalCluster(remoteBackend backend.Backend, currentState state.State) (string, error) {
-	baseConfig, err := getBaseClusterTerraformConfig(bareMetalRancherKubernetesTerraformModulePath)
+func newBareMetalCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, bareMetalRancherKubernetesTerraformModulePath)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,57 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	digitalOceanRancherKubernetesTerraformModulePath = "terraform/modules/digitalocean-rancher-k8s"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type digitalOceanClusterTerraformConfig struct {
+	baseClusterTerraformConfig
+
+	DigitalOceanAPIToken string `json:"digitalocean_api_token"`
+	DigitalOceanRegion   string `json:"digitalocean_region"`
+}
+
+// Returns the name of the cluster that was created and the new state.
+func newDigitalOceanCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, digitalOceanRancherKubernetesTerraformModulePath)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := digitalOceanClusterTerraformConfig{
+		baseClusterTerraformConfig: baseConfig,
+	}
+
+	cfg.DigitalOceanAPIToken, err = getDigitalOceanAPIToken(conf)
+	if err != nil {
+		return "", err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = digitalOceanCredentials{APIToken: cfg.DigitalOceanAPIToken}.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	// Every droplet of the cluster is created in this region
+	cfg.DigitalOceanRegion, err = getDigitalOceanRegion(conf, cfg.DigitalOceanAPIToken)
+	if err != nil {
+		return "", err
+	}
+
+	// Add new cluster to terraform config
+	err = currentState.AddCluster("digitalocean", cfg.Name, &cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.Name, nil
+}
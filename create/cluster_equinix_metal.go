@@ -0,0 +1,63 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	equinixMetalRancherKubernetesTerraformModulePath = "terraform/modules/equinix-metal-rancher-k8s"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type equinixMetalClusterTerraformConfig struct {
+	baseClusterTerraformConfig
+
+	EquinixMetalAPIToken  string `json:"equinix_metal_api_token"`
+	EquinixMetalProjectID string `json:"equinix_metal_project_id"`
+	EquinixMetalMetro     string `json:"equinix_metal_metro"`
+}
+
+// Returns the name of the cluster that was created and the new state.
+func newEquinixMetalCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, equinixMetalRancherKubernetesTerraformModulePath)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := equinixMetalClusterTerraformConfig{
+		baseClusterTerraformConfig: baseConfig,
+	}
+
+	cfg.EquinixMetalAPIToken, err = getEquinixMetalAPIToken(conf)
+	if err != nil {
+		return "", err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = equinixMetalCredentials{APIToken: cfg.EquinixMetalAPIToken}.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	cfg.EquinixMetalProjectID, err = getEquinixMetalProjectID(conf, cfg.EquinixMetalAPIToken)
+	if err != nil {
+		return "", err
+	}
+
+	// Every device of the cluster is created in this metro, nodes talk over its network
+	cfg.EquinixMetalMetro, err = getEquinixMetalMetro(conf, cfg.EquinixMetalAPIToken)
+	if err != nil {
+		return "", err
+	}
+
+	// Add new cluster to terraform config
+	err = currentState.AddCluster("equinixmetal", cfg.Name, &cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.Name, nil
+}
@@ -10,11 +10,12 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 	"golang.org/x/oauth2/google"
 	compute "google.golang.org/api/compute/v1"
 )
@@ -34,9 +35,9 @@ type gcpClusterTerraformConfig struct {
 }
 
 // Returns the name of the cluster that was created and the new state.
-func newGCPCluster(remoteBackend backend.Backend, currentState state.State) (string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseClusterTerraformConfig(gcpRancherKubernetesTerraformModulePath)
+func newGCPCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, gcpRancherKubernetesTerraformModulePath)
 	if err != nil {
 		return "", err
 	}
@@ -47,10 +48,10 @@ func newGCPCluster(remoteBackend backend.Backend, currentState state.State) (str
 
 	// GCP path_to_credentials
 	rawGCPPathToCredentials := ""
-	if viper.IsSet("gcp_path_to_credentials") {
-		rawGCPPathToCredentials = viper.GetString("gcp_path_to_credentials")
+	if conf.IsSet("gcp_path_to_credentials") {
+		rawGCPPathToCredentials = conf.GetString("gcp_path_to_credentials")
 	} else if nonInteractiveMode {
-		return "", errors.New("gcp_path_to_credentials must be specified")
+		return "", util.ConfigError(errors.New("gcp_path_to_credentials must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Path to Google Cloud Platform Credentials File",
@@ -102,6 +103,12 @@ func newGCPCluster(remoteBackend backend.Backend, currentState state.State) (str
 	}
 	cfg.GCPProjectID = pid.ProjectID
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = gcpProjectCredentials{PathToCredentials: cfg.GCPPathToCredentials, ProjectID: cfg.GCPProjectID}.Validate()
+	if err != nil {
+		return "", err
+	}
+
 	service, err := compute.New(jwtCfg.Client(context.Background()))
 	if err != nil {
 		return "", err
@@ -113,8 +120,8 @@ func newGCPCluster(remoteBackend backend.Backend, currentState state.State) (str
 	}
 
 	// GCP Compute Region
-	if viper.IsSet("gcp_compute_region") {
-		cfg.GCPComputeRegion = viper.GetString("gcp_compute_region")
+	if conf.IsSet("gcp_compute_region") {
+		cfg.GCPComputeRegion = conf.GetString("gcp_compute_region")
 
 		found := false
 		for _, region := range regions.Items {
@@ -128,7 +135,7 @@ func newGCPCluster(remoteBackend backend.Backend, currentState state.State) (str
 		}
 
 	} else if nonInteractiveMode {
-		return "", errors.New("gcp_compute_region must be specified")
+		return "", util.ConfigError(errors.New("gcp_compute_region must be specified"))
 	} else {
 		searcher := func(input string, index int) bool {
 			region := regions.Items[index]
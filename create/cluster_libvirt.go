@@ -0,0 +1,44 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	libvirtRancherKubernetesTerraformModulePath = "terraform/modules/libvirt-rancher-k8s"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type libvirtClusterTerraformConfig struct {
+	baseClusterTerraformConfig
+	libvirtTerraformConfig
+}
+
+// Returns the name of the cluster that was created and the new state.
+func newLibvirtCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, libvirtRancherKubernetesTerraformModulePath)
+	if err != nil {
+		return "", err
+	}
+
+	libvirtConfig, err := getLibvirtTerraformConfig(conf)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := libvirtClusterTerraformConfig{
+		baseClusterTerraformConfig: baseConfig,
+		libvirtTerraformConfig:     libvirtConfig,
+	}
+
+	// Add new cluster to terraform config
+	err = currentState.AddCluster("libvirt", cfg.Name, &cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.Name, nil
+}
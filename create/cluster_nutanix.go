@@ -0,0 +1,50 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	nutanixRancherKubernetesTerraformModulePath = "terraform/modules/nutanix-rancher-k8s"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type nutanixClusterTerraformConfig struct {
+	baseClusterTerraformConfig
+	nutanixAuth
+}
+
+// Returns the name of the cluster that was created and the new state.
+func newNutanixCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, nutanixRancherKubernetesTerraformModulePath)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := nutanixClusterTerraformConfig{
+		baseClusterTerraformConfig: baseConfig,
+	}
+
+	// Every VM of the cluster is created with this account, each node picks its AHV cluster
+	cfg.nutanixAuth, err = getNutanixAuth(conf)
+	if err != nil {
+		return "", err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = nutanixCredentials{cfg.nutanixAuth}.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	// Add new cluster to terraform config
+	err = currentState.AddCluster("nutanix", cfg.Name, &cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.Name, nil
+}
@@ -0,0 +1,50 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	openStackRancherKubernetesTerraformModulePath = "terraform/modules/openstack-rancher-k8s"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type openStackClusterTerraformConfig struct {
+	baseClusterTerraformConfig
+	openStackAuth
+}
+
+// Returns the name of the cluster that was created and the new state.
+func newOpenStackCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, openStackRancherKubernetesTerraformModulePath)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := openStackClusterTerraformConfig{
+		baseClusterTerraformConfig: baseConfig,
+	}
+
+	// Every instance of the cluster is created in this project and region
+	cfg.openStackAuth, err = getOpenStackAuth(conf)
+	if err != nil {
+		return "", err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = openStackCredentials{cfg.openStackAuth}.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	// Add new cluster to terraform config
+	err = currentState.AddCluster("openstack", cfg.Name, &cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.Name, nil
+}
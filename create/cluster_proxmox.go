@@ -0,0 +1,50 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	proxmoxRancherKubernetesTerraformModulePath = "terraform/modules/proxmox-rancher-k8s"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type proxmoxClusterTerraformConfig struct {
+	baseClusterTerraformConfig
+	proxmoxAuth
+}
+
+// Returns the name of the cluster that was created and the new state.
+func newProxmoxCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, proxmoxRancherKubernetesTerraformModulePath)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := proxmoxClusterTerraformConfig{
+		baseClusterTerraformConfig: baseConfig,
+	}
+
+	// Every VM of the cluster is cloned with this API token, each node picks its Proxmox node
+	cfg.proxmoxAuth, err = getProxmoxAuth(conf)
+	if err != nil {
+		return "", err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = proxmoxCredentials{cfg.proxmoxAuth}.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	// Add new cluster to terraform config
+	err = currentState.AddCluster("proxmox", cfg.Name, &cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.Name, nil
+}
@@ -0,0 +1,159 @@
+package create
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const defaultSSHKeyDir = "~/.triton-kubernetes/keys"
+
+// Only Triton and AWS clusters register a generated key pair with their provider.
+func checkGenerateSSHKeySupported(conf config.Config, cloudProvider string) error {
+	if !conf.GetBool("generate_ssh_key") || cloudProvider == "triton" || cloudProvider == "aws" {
+		return nil
+	}
+	return util.ConfigError(fmt.Errorf("generate_ssh_key is only supported by triton and aws clusters, not %s.", cloudProvider))
+}
+
+// Returns the SSH key pair generated for a new cluster, or nil when the cluster uses existing
+// keys. With generate_ssh_key, an ssh_key_type (rsa or ed25519) key pair is written to
+// ssh_key_dir as {manager}_{cluster} and {manager}_{cluster}.pub. A pair left there by an
+// earlier attempt to create the cluster is reused.
+func generateClusterSSHKey(conf config.Config, managerName, clusterName string) (*state.SSHKey, error) {
+	generate := false
+	if conf.IsSet("generate_ssh_key") {
+		generate = conf.GetBool("generate_ssh_key")
+	} else if !conf.GetBool("non-interactive") {
+		result, err := util.PromptForConfirmation("Generate a new SSH key pair for this cluster", "Generate SSH key pair")
+		if err != nil {
+			return nil, err
+		}
+		generate = result
+	}
+	if !generate {
+		return nil, nil
+	}
+
+	keyType := "rsa"
+	if conf.IsSet("ssh_key_type") {
+		keyType = conf.GetString("ssh_key_type")
+	}
+
+	keyDir := defaultSSHKeyDir
+	if conf.IsSet("ssh_key_dir") {
+		keyDir = conf.GetString("ssh_key_dir")
+	}
+	expandedKeyDir, err := homedir.Expand(keyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyPath := filepath.Join(expandedKeyDir, fmt.Sprintf("%s_%s", managerName, clusterName))
+	publicKeyPath := privateKeyPath + ".pub"
+	_, privateKeyErr := os.Stat(privateKeyPath)
+	_, publicKeyErr := os.Stat(publicKeyPath)
+	if privateKeyErr != nil || publicKeyErr != nil {
+		comment := fmt.Sprintf("triton-kubernetes %s/%s", managerName, clusterName)
+		publicKeyPath, err = util.GenerateSSHKeyPair(keyType, privateKeyPath, comment)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Generated SSH key pair %s\n", privateKeyPath)
+	}
+
+	fingerprint, err := util.GetPublicKeyFingerprintFromPrivateKey(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state.SSHKey{
+		PrivateKeyPath: privateKeyPath,
+		PublicKeyPath:  publicKeyPath,
+		Fingerprint:    fingerprint,
+	}, nil
+}
+
+// Adds the key generated for a Triton cluster to the keys of its account, which machines
+// created afterwards accept for root. Returns whether it was added, rather than already there,
+// so it can be removed again when the apply fails or isn't applied.
+func registerTritonClusterSSHKey(currentState state.State, clusterKey string) (bool, error) {
+	sshKey, ok := currentState.SSHKey(clusterKey)
+	if !ok || sshKey.TritonKeyName == "" {
+		return false, nil
+	}
+
+	account := clusterTritonAccount(currentState, clusterKey)
+	exists, err := util.TritonAccountKeyExists(account, sshKey.TritonKeyName)
+	if err != nil || exists {
+		return false, err
+	}
+
+	publicKey, err := ioutil.ReadFile(sshKey.PublicKeyPath)
+	if err != nil {
+		return false, err
+	}
+	err = util.RegisterTritonAccountKey(account, sshKey.TritonKeyName, string(publicKey))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Removes the key of a Triton cluster from its account after an apply that failed or wasn't
+// applied. The apply's error is what's returned, so a failure is only reported.
+func deleteTritonClusterSSHKey(currentState state.State, clusterKey string) {
+	sshKey, ok := currentState.SSHKey(clusterKey)
+	if !ok || sshKey.TritonKeyName == "" {
+		return
+	}
+
+	err := util.DeleteTritonAccountKey(clusterTritonAccount(currentState, clusterKey), sshKey.TritonKeyName)
+	if err != nil {
+		fmt.Printf("Warning: %s\n", err)
+	}
+}
+
+// Registers the keys of the clusters before an apply and runs it. Keys it registered are
+// removed again when it fails or isn't applied, a resumed apply registers them again.
+func applyWithTritonClusterSSHKeys(currentState state.State, clusterKeys []string, apply func() error) error {
+	registered := []string{}
+	deleteRegistered := func() {
+		for _, clusterKey := range registered {
+			deleteTritonClusterSSHKey(currentState, clusterKey)
+		}
+	}
+
+	for _, clusterKey := range clusterKeys {
+		ok, err := registerTritonClusterSSHKey(currentState, clusterKey)
+		if err != nil {
+			deleteRegistered()
+			return err
+		}
+		if ok {
+			registered = append(registered, clusterKey)
+		}
+	}
+
+	err := apply()
+	if err != nil {
+		deleteRegistered()
+	}
+	return err
+}
+
+func clusterTritonAccount(currentState state.State, clusterKey string) util.TritonAccount {
+	return util.TritonAccount{
+		Account: currentState.Get(fmt.Sprintf("module.%s.triton_account", clusterKey)),
+		KeyID:   currentState.Get(fmt.Sprintf("module.%s.triton_key_id", clusterKey)),
+		KeyPath: currentState.Get(fmt.Sprintf("module.%s.triton_key_path", clusterKey)),
+		URL:     currentState.Get(fmt.Sprintf("module.%s.triton_url", clusterKey)),
+	}
+}
@@ -0,0 +1,92 @@
+package create
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestGenerateClusterSSHKey(t *testing.T) {
+	keyDir, err := ioutil.TempDir("", "triton-kubernetes-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(keyDir)
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("ssh_key_dir", keyDir)
+
+	// Clusters use existing keys unless generate_ssh_key says otherwise
+	sshKey, err := generateClusterSSHKey(conf, "dev", "blue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sshKey != nil {
+		t.Errorf("Expected no key pair, received %+v", sshKey)
+	}
+
+	conf.Set("generate_ssh_key", true)
+	conf.Set("ssh_key_type", "ed25519")
+	sshKey, err = generateClusterSSHKey(conf, "dev", "blue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPath := filepath.Join(keyDir, "dev_blue")
+	if sshKey == nil || sshKey.PrivateKeyPath != expectedPath || sshKey.PublicKeyPath != expectedPath+".pub" || sshKey.Fingerprint == "" {
+		t.Fatalf("Expected a key pair at %s, received %+v", expectedPath, sshKey)
+	}
+
+	// Creating the cluster again reuses the key pair
+	reused, err := generateClusterSSHKey(conf, "dev", "blue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *reused != *sshKey {
+		t.Errorf("Expected the key pair %+v to be reused, received %+v", sshKey, reused)
+	}
+}
+
+func TestGenerateClusterSSHKeyInvalidType(t *testing.T) {
+	keyDir, err := ioutil.TempDir("", "triton-kubernetes-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(keyDir)
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("ssh_key_dir", keyDir)
+	conf.Set("generate_ssh_key", true)
+	conf.Set("ssh_key_type", "dsa")
+
+	expected := "Invalid ssh_key_type 'dsa', must be 'rsa' or 'ed25519'."
+	_, err = generateClusterSSHKey(conf, "dev", "blue")
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestCheckGenerateSSHKeySupported(t *testing.T) {
+	conf := config.New()
+	conf.Set("generate_ssh_key", true)
+
+	for _, cloudProvider := range []string{"triton", "aws"} {
+		if err := checkGenerateSSHKeySupported(conf, cloudProvider); err != nil {
+			t.Errorf("Expected %s to support generate_ssh_key, received %v", cloudProvider, err)
+		}
+	}
+
+	expected := "generate_ssh_key is only supported by triton and aws clusters, not gcp."
+	if err := checkGenerateSSHKeySupported(conf, "gcp"); err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+
+	conf.Set("generate_ssh_key", false)
+	if err := checkGenerateSSHKeySupported(conf, "gcp"); err != nil {
+		t.Errorf("Expected no error without generate_ssh_key, received %v", err)
+	}
+}
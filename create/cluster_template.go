@@ -0,0 +1,398 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Cluster templates were added in Rancher v2.3
+const minClusterTemplateRancherVersion = "v2.3"
+
+// Settings of the Kubernetes config of a cluster, which a cluster template defines instead
+var clusterTemplateConflictingKeys = []string{
+	"k8s_version",
+	"k8s_network_provider",
+	"k8s_network_mtu",
+	"k8s_network_backend",
+	"k8s_nodelocal_dns",
+	"k8s_coredns_min_replicas",
+	"k8s_coredns_upstream_nameservers",
+	"k8s_registry",
+	"k8s_audit_log",
+	"k8s_secrets_encryption",
+}
+
+// NewClusterTemplate creates a Rancher cluster template in a cluster manager, with the cluster
+// config in cluster_template_file as its first revision. When the template already exists,
+// the config is added as a new revision, which clusters are then created from by default.
+// With cluster_template_enforce, users other than admins can only create clusters from
+// cluster templates.
+func NewClusterTemplate(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers, please create a cluster manager before creating a cluster template.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	// Cluster Template Name
+	templateName := ""
+	if conf.IsSet("cluster_template_name") {
+		templateName = conf.GetString("cluster_template_name")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_template_name must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Cluster Template Name",
+			Validate: func(input string) error {
+				if input == "" {
+					return errors.New("Cluster Template Name cannot be blank")
+				}
+				return nil
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		templateName = result
+	}
+
+	// Cluster Template File
+	templateFile := ""
+	if conf.IsSet("cluster_template_file") {
+		templateFile = conf.GetString("cluster_template_file")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_template_file must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Cluster config file (YAML or JSON)",
+			Validate: func(input string) error {
+				if input == "" {
+					return errors.New("Cluster config file cannot be blank")
+				}
+				return nil
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		templateFile = result
+	}
+
+	clusterConfig, err := readClusterTemplateFile(templateFile)
+	if err != nil {
+		return err
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	client, err := rancher.NewClientFromState(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	templates, err := client.ClusterTemplates()
+	if err != nil {
+		return err
+	}
+
+	template, exists := findClusterTemplate(templates, templateName)
+	revisions := []rancher.ClusterTemplateRevision{}
+	if exists {
+		revisions, err = client.ClusterTemplateRevisions(template.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Cluster Template Revision, v1, v2... by default
+	revisionName := getClusterTemplateRevisionName(revisions)
+	if conf.IsSet("cluster_template_revision") {
+		revisionName = conf.GetString("cluster_template_revision")
+	}
+	if _, taken := findClusterTemplateRevision(revisions, revisionName); taken {
+		return fmt.Errorf("Cluster template '%s' already has a revision named '%s'.", templateName, revisionName)
+	}
+
+	if !exists {
+		template, err = client.CreateClusterTemplate(templateName, conf.GetString("cluster_template_description"))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = client.CreateClusterTemplateRevision(template, revisionName, clusterConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created revision %s of cluster template '%s', clusters are created from it by default.\n", revisionName, templateName)
+
+	if conf.IsSet("cluster_template_enforce") {
+		enforce := conf.GetBool("cluster_template_enforce")
+		err = client.EnforceClusterTemplates(enforce)
+		if err != nil {
+			return err
+		}
+		if enforce {
+			fmt.Printf("Users other than admins of cluster manager '%s' can only create clusters from cluster templates.\n", selectedClusterManager)
+		}
+	}
+
+	return nil
+}
+
+// Reads Rancher's cluster config from a YAML or JSON file. It has the fields of Rancher's
+// clusters, e.g. rancherKubernetesEngineConfig, in the same format as the Rancher API.
+func readClusterTemplateFile(path string) (map[string]interface{}, error) {
+	expandedPath, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(expandedPath)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read cluster_template_file '%s': %s", path, err)
+	}
+
+	var raw interface{}
+	err = yaml.Unmarshal(content, &raw)
+	if err != nil {
+		return nil, util.ConfigError(fmt.Errorf("Invalid cluster_template_file '%s': %s", path, err))
+	}
+
+	clusterConfig, ok := stringKeyMaps(raw).(map[string]interface{})
+	if !ok {
+		return nil, util.ConfigError(fmt.Errorf("Invalid cluster_template_file '%s', it must hold Rancher's cluster config.", path))
+	}
+	if _, ok := clusterConfig["rancherKubernetesEngineConfig"].(map[string]interface{}); !ok {
+		return nil, util.ConfigError(fmt.Errorf("Invalid cluster_template_file '%s', it has no rancherKubernetesEngineConfig.", path))
+	}
+
+	return clusterConfig, nil
+}
+
+// Converts the maps decoded from YAML to maps with string keys, which can be encoded as JSON.
+func stringKeyMaps(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range value {
+			result[fmt.Sprint(key)] = stringKeyMaps(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = stringKeyMaps(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// Returns the first of v1, v2... that isn't the name of a revision yet.
+func getClusterTemplateRevisionName(revisions []rancher.ClusterTemplateRevision) string {
+	for i := len(revisions) + 1; ; i++ {
+		name := fmt.Sprintf("v%d", i)
+		if _, taken := findClusterTemplateRevision(revisions, name); !taken {
+			return name
+		}
+	}
+}
+
+func findClusterTemplate(templates []rancher.ClusterTemplate, name string) (rancher.ClusterTemplate, bool) {
+	for _, template := range templates {
+		if template.Name == name {
+			return template, true
+		}
+	}
+	return rancher.ClusterTemplate{}, false
+}
+
+func findClusterTemplateRevision(revisions []rancher.ClusterTemplateRevision, name string) (rancher.ClusterTemplateRevision, bool) {
+	for _, revision := range revisions {
+		if revision.Name == name {
+			return revision, true
+		}
+	}
+	return rancher.ClusterTemplateRevision{}, false
+}
+
+// Asks for the cluster template the new cluster is created from, cluster_template, and its
+// revision, cluster_template_revision, which defaults to the template's default revision.
+// Without a template, the cluster's Kubernetes config is asked for as usual. The Kubernetes
+// version is the revision's.
+func getClusterTemplateConfig(conf config.Config, currentState state.State, cfg *baseClusterTerraformConfig) error {
+	rancherVersion := getManagerRancherVersion(currentState)
+	rancherSupported := rancherVersionAtLeast(rancherVersion, minClusterTemplateRancherVersion)
+
+	templateName := ""
+	if conf.IsSet("cluster_template") {
+		templateName = conf.GetString("cluster_template")
+		if !rancherSupported {
+			return util.ConfigError(fmt.Errorf("cluster_template requires Rancher %s or later, the cluster manager runs '%s'.", minClusterTemplateRancherVersion, rancherVersion))
+		}
+	} else if conf.GetBool("non-interactive") || !rancherSupported {
+		return nil
+	} else {
+		useTemplate, err := util.PromptForConfirmation("Create the cluster from a cluster template", "Cluster template")
+		if err != nil || !useTemplate {
+			return err
+		}
+	}
+
+	for _, key := range clusterTemplateConflictingKeys {
+		if conf.IsSet(key) {
+			return fmt.Errorf("%s can't be set with cluster_template, the cluster template defines the cluster's Kubernetes config.", key)
+		}
+	}
+
+	client, err := rancher.NewClientFromState(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	templates, err := client.ClusterTemplates()
+	if err != nil {
+		return err
+	}
+
+	// Cluster Template
+	if templateName == "" {
+		if len(templates) == 0 {
+			return fmt.Errorf("Cluster manager '%s' has no cluster templates, create one with `triton-kubernetes create cluster-template`.", currentState.Name)
+		}
+
+		prompt := promptui.Select{
+			Label: "Cluster Template",
+			Items: templates,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ .Name }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Template:" | bold}} {{ .Name }}`, promptui.IconGood),
+			},
+		}
+
+		i, _, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		templateName = templates[i].Name
+	}
+
+	template, ok := findClusterTemplate(templates, templateName)
+	if !ok {
+		return fmt.Errorf("Selected cluster template '%s' does not exist.", templateName)
+	}
+
+	revisions, err := client.ClusterTemplateRevisions(template.ID)
+	if err != nil {
+		return err
+	}
+
+	revision, err := selectClusterTemplateRevision(template, revisions, conf.GetString("cluster_template_revision"))
+	if err != nil {
+		return err
+	}
+
+	cfg.RancherClusterTemplateID = template.ID
+	cfg.RancherClusterTemplateRevisionID = revision.ID
+	cfg.KubernetesVersion = getClusterTemplateKubernetesVersion(revision)
+	return nil
+}
+
+// Returns the Kubernetes version of the revision's RKE config, empty when it leaves it to
+// Rancher's default.
+func getClusterTemplateKubernetesVersion(revision rancher.ClusterTemplateRevision) string {
+	rkeConfig, _ := revision.ClusterConfig["rancherKubernetesEngineConfig"].(map[string]interface{})
+	version, _ := rkeConfig["kubernetesVersion"].(string)
+	return version
+}
+
+// Returns the revision of the template with the given name, or the template's default revision
+// when the name is empty. Disabled revisions can't be used.
+func selectClusterTemplateRevision(template rancher.ClusterTemplate, revisions []rancher.ClusterTemplateRevision, name string) (rancher.ClusterTemplateRevision, error) {
+	var revision rancher.ClusterTemplateRevision
+	found := false
+	if name == "" {
+		for _, r := range revisions {
+			if r.ID == template.DefaultRevisionID {
+				revision, found = r, true
+			}
+		}
+		if !found {
+			return rancher.ClusterTemplateRevision{}, fmt.Errorf("Cluster template '%s' has no default revision, set cluster_template_revision.", template.Name)
+		}
+	} else {
+		revision, found = findClusterTemplateRevision(revisions, name)
+		if !found {
+			return rancher.ClusterTemplateRevision{}, fmt.Errorf("Cluster template '%s' has no revision named '%s'.", template.Name, name)
+		}
+	}
+
+	if !revision.Enabled {
+		return rancher.ClusterTemplateRevision{}, fmt.Errorf("Revision %s of cluster template '%s' is disabled.", revision.Name, template.Name)
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,185 @@
+package create
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestReadClusterTemplateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-cluster-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"hardened.yaml": "rancherKubernetesEngineConfig:\n  kubernetesVersion: v1.17.4-rancher1-2\n  services:\n    kubeApi:\n      podSecurityPolicy: true\n  addonsInclude:\n    - https://example.com/psp.yaml\n",
+		"hardened.json": `{"rancherKubernetesEngineConfig": {"kubernetesVersion": "v1.17.4-rancher1-2"}}`,
+		"empty.yaml":    "enableNetworkPolicy: true\n",
+		"list.yaml":     "- rancherKubernetesEngineConfig\n",
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clusterConfig, err := readClusterTemplateFile(filepath.Join(dir, "hardened.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"rancherKubernetesEngineConfig": map[string]interface{}{
+			"kubernetesVersion": "v1.17.4-rancher1-2",
+			"services": map[string]interface{}{
+				"kubeApi": map[string]interface{}{"podSecurityPolicy": true},
+			},
+			"addonsInclude": []interface{}{"https://example.com/psp.yaml"},
+		},
+	}
+	if !reflect.DeepEqual(clusterConfig, expected) {
+		t.Errorf("Expected %v, got %v", expected, clusterConfig)
+	}
+
+	_, err = readClusterTemplateFile(filepath.Join(dir, "hardened.json"))
+	if err != nil {
+		t.Errorf("Expected a JSON file to be read, got %v", err)
+	}
+
+	testCases := []struct {
+		file     string
+		expected string
+	}{
+		{"empty.yaml", "it has no rancherKubernetesEngineConfig"},
+		{"list.yaml", "it must hold Rancher's cluster config"},
+		{"missing.yaml", "Unable to read cluster_template_file"},
+	}
+	for _, tc := range testCases {
+		_, err := readClusterTemplateFile(filepath.Join(dir, tc.file))
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("Wrong error for %s, expected %q, received %v", tc.file, tc.expected, err)
+		}
+	}
+}
+
+func TestGetClusterTemplateRevisionName(t *testing.T) {
+	testCases := []struct {
+		revisions []string
+		expected  string
+	}{
+		{[]string{}, "v1"},
+		{[]string{"v1"}, "v2"},
+		{[]string{"initial", "v2"}, "v3"},
+		{[]string{"v2", "v3"}, "v4"},
+	}
+
+	for _, tc := range testCases {
+		revisions := []rancher.ClusterTemplateRevision{}
+		for _, name := range tc.revisions {
+			revisions = append(revisions, rancher.ClusterTemplateRevision{Name: name})
+		}
+
+		if name := getClusterTemplateRevisionName(revisions); name != tc.expected {
+			t.Errorf("Wrong name for %v, expected %s, received %s", tc.revisions, tc.expected, name)
+		}
+	}
+}
+
+func TestSelectClusterTemplateRevision(t *testing.T) {
+	template := rancher.ClusterTemplate{ID: "ct-abcde", Name: "hardened", DefaultRevisionID: "ctr-2"}
+	revisions := []rancher.ClusterTemplateRevision{
+		{ID: "ctr-1", Name: "v1", Enabled: false},
+		{ID: "ctr-2", Name: "v2", Enabled: true},
+	}
+
+	revision, err := selectClusterTemplateRevision(template, revisions, "")
+	if err != nil || revision.ID != "ctr-2" {
+		t.Errorf("Expected the default revision, got %+v, %v", revision, err)
+	}
+
+	revision, err = selectClusterTemplateRevision(template, revisions, "v2")
+	if err != nil || revision.ID != "ctr-2" {
+		t.Errorf("Expected revision v2, got %+v, %v", revision, err)
+	}
+
+	testCases := []struct {
+		template rancher.ClusterTemplate
+		name     string
+		expected string
+	}{
+		{template, "v1", "Revision v1 of cluster template 'hardened' is disabled."},
+		{template, "v3", "Cluster template 'hardened' has no revision named 'v3'."},
+		{rancher.ClusterTemplate{Name: "hardened"}, "", "Cluster template 'hardened' has no default revision, set cluster_template_revision."},
+	}
+	for _, tc := range testCases {
+		_, err := selectClusterTemplateRevision(tc.template, revisions, tc.name)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Wrong error for %q, expected %q, received %v", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestGetClusterTemplateConfig(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	cfg := baseClusterTerraformConfig{}
+	err = getClusterTemplateConfig(conf, currentState, &cfg)
+	if err != nil || cfg.RancherClusterTemplateRevisionID != "" {
+		t.Errorf("Expected no cluster template, got %q, %v", cfg.RancherClusterTemplateRevisionID, err)
+	}
+
+	conf.Set("cluster_template", "hardened")
+	conf.Set("k8s_network_provider", "calico")
+	err = getClusterTemplateConfig(conf, currentState, &cfg)
+	expected := "k8s_network_provider can't be set with cluster_template, the cluster template defines the cluster's Kubernetes config."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+
+	conf = config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_template", "hardened")
+	err = currentState.SetManager(map[string]interface{}{
+		"source":               "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher",
+		"rancher_server_image": "rancher/rancher:v2.2.9",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = getClusterTemplateConfig(conf, currentState, &cfg)
+	expected = "cluster_template requires Rancher v2.3 or later, the cluster manager runs 'v2.2.9'."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestGetClusterTemplateKubernetesVersion(t *testing.T) {
+	revision := rancher.ClusterTemplateRevision{
+		ClusterConfig: map[string]interface{}{
+			"rancherKubernetesEngineConfig": map[string]interface{}{
+				"kubernetesVersion": "v1.20.6-rancher1-1",
+			},
+		},
+	}
+	if version := getClusterTemplateKubernetesVersion(revision); version != "v1.20.6-rancher1-1" {
+		t.Errorf("Expected v1.20.6-rancher1-1, got %q", version)
+	}
+
+	if version := getClusterTemplateKubernetesVersion(rancher.ClusterTemplateRevision{}); version != "" {
+		t.Errorf("Expected no version, got %q", version)
+	}
+}
@@ -2,14 +2,15 @@ package create
 
 import (
 	"errors"
+	"fmt"
 	"os"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
 	"github.com/joyent/triton-kubernetes/util"
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -28,9 +29,9 @@ type tritonClusterTerraformConfig struct {
 }
 
 // Returns the name of the cluster that was created and the new state.
-func newTritonCluster(remoteBackend backend.Backend, currentState state.State) (string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseClusterTerraformConfig(tritonRancherKubernetesTerraformModulePath)
+func newTritonCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, tritonRancherKubernetesTerraformModulePath)
 	if err != nil {
 		return "", err
 	}
@@ -39,11 +40,17 @@ func newTritonCluster(remoteBackend backend.Backend, currentState state.State) (
 		baseClusterTerraformConfig: baseConfig,
 	}
 
+	// Triton account, key and URL can come from a profile of the triton CLI
+	err = util.ApplyTritonProfile(conf, nonInteractiveMode)
+	if err != nil {
+		return "", err
+	}
+
 	// Triton Account
-	if viper.IsSet("triton_account") {
-		cfg.TritonAccount = viper.GetString("triton_account")
+	if conf.IsSet("triton_account") {
+		cfg.TritonAccount = conf.GetString("triton_account")
 	} else if nonInteractiveMode {
-		return "", errors.New("triton_account must be specified")
+		return "", util.ConfigError(errors.New("triton_account must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Triton Account Name",
@@ -64,10 +71,10 @@ func newTritonCluster(remoteBackend backend.Backend, currentState state.State) (
 
 	// Triton Key Path
 	rawTritonKeyPath := ""
-	if viper.IsSet("triton_key_path") {
-		rawTritonKeyPath = viper.GetString("triton_key_path")
+	if conf.IsSet("triton_key_path") {
+		rawTritonKeyPath = conf.GetString("triton_key_path")
 	} else if nonInteractiveMode {
-		return "", errors.New("triton_key_path must be specified")
+		return "", util.ConfigError(errors.New("triton_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Triton Key Path",
@@ -101,22 +108,18 @@ func newTritonCluster(remoteBackend backend.Backend, currentState state.State) (
 	}
 	cfg.TritonKeyPath = expandedTritonKeyPath
 
-	// Triton Key ID
-	if viper.IsSet("triton_key_id") {
-		cfg.TritonKeyID = viper.GetString("triton_key_id")
-	} else {
-		keyID, err := util.GetPublicKeyFingerprintFromPrivateKey(cfg.TritonKeyPath)
-		if err != nil {
-			return "", err
-		}
-		cfg.TritonKeyID = keyID
+	// Triton Key ID, an MD5 or SHA256 fingerprint of the key or computed from it
+	keyID, err := util.TritonKeyID("triton_key_id", conf.GetString("triton_key_id"), cfg.TritonKeyPath)
+	if err != nil {
+		return "", err
 	}
+	cfg.TritonKeyID = keyID
 
 	// Triton URL
-	if viper.IsSet("triton_url") {
-		cfg.TritonURL = viper.GetString("triton_url")
+	if conf.IsSet("triton_url") {
+		cfg.TritonURL = conf.GetString("triton_url")
 	} else if nonInteractiveMode {
-		return "", errors.New("triton_url must be specified")
+		return "", util.ConfigError(errors.New("triton_url must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Triton URL",
@@ -130,11 +133,39 @@ func newTritonCluster(remoteBackend backend.Backend, currentState state.State) (
 		cfg.TritonURL = result
 	}
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = tritonCredentials{
+		Account: cfg.TritonAccount,
+		KeyID:   cfg.TritonKeyID,
+		KeyPath: cfg.TritonKeyPath,
+		URL:     cfg.TritonURL,
+	}.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	// A key pair generated for the cluster is added to the account's keys, which the nodes
+	// accept for root, right before the apply creating them
+	sshKey, err := generateClusterSSHKey(conf, currentState.Name, cfg.Name)
+	if err != nil {
+		return "", err
+	}
+	if sshKey != nil {
+		sshKey.TritonKeyName = fmt.Sprintf("triton-kubernetes-%s-%s", currentState.Name, cfg.Name)
+	}
+
 	// Add new cluster to terraform config
 	err = currentState.AddCluster("triton", cfg.Name, &cfg)
 	if err != nil {
 		return "", err
 	}
 
+	if sshKey != nil {
+		err = currentState.SetSSHKey(fmt.Sprintf("cluster_triton_%s", cfg.Name), *sshKey)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return cfg.Name, nil
 }
@@ -4,9 +4,10 @@ import (
 	"errors"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -29,9 +30,9 @@ type vSphereClusterTerraformConfig struct {
 }
 
 // Returns the name of the cluster that was created and the new state.
-func newVSphereCluster(remoteBackend backend.Backend, currentState state.State) (string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseClusterTerraformConfig(vSphereRancherKubernetesTerraformModulePath)
+func newVSphereCluster(conf config.Config, remoteBackend backend.Backend, currentState state.State) (string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseClusterTerraformConfig(conf, currentState, vSphereRancherKubernetesTerraformModulePath)
 	if err != nil {
 		return "", err
 	}
@@ -41,10 +42,10 @@ func newVSphereCluster(remoteBackend backend.Backend, currentState state.State)
 	}
 
 	// vSphere User
-	if viper.IsSet("vsphere_user") {
-		cfg.VSphereUser = viper.GetString("vsphere_user")
+	if conf.IsSet("vsphere_user") {
+		cfg.VSphereUser = conf.GetString("vsphere_user")
 	} else if nonInteractiveMode {
-		return "", errors.New("vsphere_user must be specified.")
+		return "", util.ConfigError(errors.New("vsphere_user must be specified."))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "vSphere User",
@@ -64,10 +65,10 @@ func newVSphereCluster(remoteBackend backend.Backend, currentState state.State)
 	}
 
 	// vSphere Password
-	if viper.IsSet("vsphere_password") {
-		cfg.VSpherePassword = viper.GetString("vsphere_password")
+	if conf.IsSet("vsphere_password") {
+		cfg.VSpherePassword = conf.GetString("vsphere_password")
 	} else if nonInteractiveMode {
-		return "", errors.New("vsphere_password must be specified.")
+		return "", util.ConfigError(errors.New("vsphere_password must be specified."))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "vSphere Password",
@@ -82,10 +83,10 @@ func newVSphereCluster(remoteBackend backend.Backend, currentState state.State)
 	}
 
 	// vSphere Server
-	if viper.IsSet("vsphere_server") {
-		cfg.VSphereServer = viper.GetString("vsphere_server")
+	if conf.IsSet("vsphere_server") {
+		cfg.VSphereServer = conf.GetString("vsphere_server")
 	} else if nonInteractiveMode {
-		return "", errors.New("vsphere_server must be specified.")
+		return "", util.ConfigError(errors.New("vsphere_server must be specified."))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "vSphere Server",
@@ -104,12 +105,18 @@ func newVSphereCluster(remoteBackend backend.Backend, currentState state.State)
 		cfg.VSphereServer = result
 	}
 
+	// Fail before anything is written to the state on credentials vSphere rejects
+	err = vSphereCredentials{User: cfg.VSphereUser, Password: cfg.VSpherePassword, Server: cfg.VSphereServer}.Validate()
+	if err != nil {
+		return "", err
+	}
+
 	// vSphere Datacenter Name
 	// TODO Fetch datacenters
-	if viper.IsSet("vsphere_datacenter_name") {
-		cfg.VSphereDatacenterName = viper.GetString("vsphere_datacenter_name")
+	if conf.IsSet("vsphere_datacenter_name") {
+		cfg.VSphereDatacenterName = conf.GetString("vsphere_datacenter_name")
 	} else if nonInteractiveMode {
-		return "", errors.New("vsphere_datacenter_name must be specified.")
+		return "", util.ConfigError(errors.New("vsphere_datacenter_name must be specified."))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "vSphere Datacenter Name",
@@ -130,10 +137,10 @@ func newVSphereCluster(remoteBackend backend.Backend, currentState state.State)
 
 	// vSphere Datastore Name
 	// TODO Fetch datastores
-	if viper.IsSet("vsphere_datastore_name") {
-		cfg.VSphereDatastoreName = viper.GetString("vsphere_datastore_name")
+	if conf.IsSet("vsphere_datastore_name") {
+		cfg.VSphereDatastoreName = conf.GetString("vsphere_datastore_name")
 	} else if nonInteractiveMode {
-		return "", errors.New("vsphere_datastore_name must be specified.")
+		return "", util.ConfigError(errors.New("vsphere_datastore_name must be specified."))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "vSphere Datastore Name",
@@ -154,10 +161,10 @@ func newVSphereCluster(remoteBackend backend.Backend, currentState state.State)
 
 	// vSphere Resource Pool Name
 	// TODO Fetch clusters from vsphere
-	if viper.IsSet("vsphere_resource_pool_name") {
-		cfg.VSphereResourcePoolName = viper.GetString("vsphere_resource_pool_name")
+	if conf.IsSet("vsphere_resource_pool_name") {
+		cfg.VSphereResourcePoolName = conf.GetString("vsphere_resource_pool_name")
 	} else if nonInteractiveMode {
-		return "", errors.New("vsphere_resource_pool_name must be specified.")
+		return "", util.ConfigError(errors.New("vsphere_resource_pool_name must be specified."))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "vSphere Resource Pool Name",
@@ -178,10 +185,10 @@ func newVSphereCluster(remoteBackend backend.Backend, currentState state.State)
 
 	// vSphere Network Name
 	// TODO Fetch Networks from vsphere
-	if viper.IsSet("vsphere_network_name") {
-		cfg.VSphereNetworkName = viper.GetString("vsphere_network_name")
+	if conf.IsSet("vsphere_network_name") {
+		cfg.VSphereNetworkName = conf.GetString("vsphere_network_name")
 	} else if nonInteractiveMode {
-		return "", errors.New("vsphere_network_name must be specified.")
+		return "", util.ConfigError(errors.New("vsphere_network_name must be specified."))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "vSphere Network Name",
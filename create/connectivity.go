@@ -0,0 +1,73 @@
+package create
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+const rancherConnectivityTimeout = 10 * time.Second
+
+// Verifies the Rancher manager can be reached on the port of its URL before nodes are registered
+// to it.
+// Nodes that can't reach the manager fail to join silently, so we'd rather fail before apply.
+// The nodes run the same check from within their own network before starting the Rancher agent.
+func checkRancherConnectivity(conf config.Config, currentState state.State) error {
+	if conf.GetBool("skip_connectivity_check") {
+		return nil
+	}
+
+	outputs, err := shell.RunTerraformOutputWithState(conf, currentState, "cluster-manager")
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve the Rancher URL of cluster manager '%s': %v", currentState.Name, err)
+	}
+
+	rancherURL, ok := outputs["rancher_url"].(string)
+	if !ok || rancherURL == "" {
+		fmt.Printf("Skipping connectivity check, cluster manager '%s' has no Rancher URL yet.\n", currentState.Name)
+		return nil
+	}
+
+	fmt.Printf("Checking connectivity to the Rancher manager at %s...\n", rancherURL)
+	return checkURLConnectivity(rancherURL, rancherConnectivityTimeout)
+}
+
+// Returns an error unless a TCP connection can be opened to the host of rawURL on the port of
+// the URL, or the default port of its scheme when it doesn't specify one.
+func checkURLConnectivity(rawURL string, timeout time.Duration) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := parsedURL.Hostname()
+	if host == "" {
+		return util.ConfigError(fmt.Errorf("Invalid Rancher URL '%s'", rawURL))
+	}
+
+	port := parsedURL.Port()
+	if port == "" {
+		switch parsedURL.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		default:
+			return util.ConfigError(fmt.Errorf("Invalid Rancher URL '%s'", rawURL))
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), timeout)
+	if err != nil {
+		return fmt.Errorf("Unable to reach the Rancher manager at %s on port %s, nodes will not be able to register: %v. Set skip_connectivity_check to skip this check.", host, port, err)
+	}
+	conn.Close()
+
+	return nil
+}
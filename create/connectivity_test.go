@@ -0,0 +1,39 @@
+package create
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCheckURLConnectivity(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	reachableURL := fmt.Sprintf("https://%s", listener.Addr().String())
+	err = checkURLConnectivity(reachableURL, time.Second)
+	if err != nil {
+		t.Errorf("Expected %s to be reachable, received %s", reachableURL, err)
+	}
+
+	// Nothing listens on the port once the listener is closed
+	listener.Close()
+	err = checkURLConnectivity(reachableURL, time.Second)
+	if err == nil {
+		t.Errorf("Expected %s to be unreachable", reachableURL)
+	}
+
+	err = checkURLConnectivity("ftp://127.0.0.1", time.Second)
+	if err == nil {
+		t.Error("Expected a URL without a port of its scheme to fail")
+	}
+
+	err = checkURLConnectivity("not a url", time.Second)
+	if err == nil {
+		t.Error("Expected an invalid URL to fail")
+	}
+}
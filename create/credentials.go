@@ -0,0 +1,452 @@
+package create
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/Azure/azure-sdk-for-go/arm/resources/subscriptions"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	triton "github.com/joyent/triton-go"
+	"github.com/joyent/triton-go/authentication"
+	"github.com/joyent/triton-go/compute"
+	"golang.org/x/oauth2/google"
+	gcpcompute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// providerCredentials are the credentials of a cloud provider. They're validated as soon as
+// they're known, before anything is written to the state or terraform runs.
+type providerCredentials interface {
+	// Validate makes a cheap read-only call to the provider's API, returning an error saying
+	// which setting to fix when the credentials are rejected.
+	Validate() error
+}
+
+type tritonCredentials struct {
+	Account string
+	KeyID   string
+	KeyPath string
+	URL     string
+}
+
+type awsCredentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+type gcpProjectCredentials struct {
+	PathToCredentials string
+	ProjectID         string
+}
+
+type azureCredentials struct {
+	Environment    azure.Environment
+	SubscriptionID string
+	TenantID       string
+	ClientID       string
+	ClientSecret   string
+}
+
+type digitalOceanCredentials struct {
+	APIToken string
+}
+
+type equinixMetalCredentials struct {
+	APIToken string
+}
+
+type openStackCredentials struct {
+	openStackAuth
+}
+
+type proxmoxCredentials struct {
+	proxmoxAuth
+}
+
+type nutanixCredentials struct {
+	nutanixAuth
+}
+
+type vSphereCredentials struct {
+	User     string
+	Password string
+	Server   string
+}
+
+type libvirtCredentials struct {
+	URI string
+}
+
+// Every provider's credentials are validated
+var (
+	_ providerCredentials = tritonCredentials{}
+	_ providerCredentials = awsCredentials{}
+	_ providerCredentials = gcpProjectCredentials{}
+	_ providerCredentials = azureCredentials{}
+	_ providerCredentials = digitalOceanCredentials{}
+	_ providerCredentials = equinixMetalCredentials{}
+	_ providerCredentials = openStackCredentials{}
+	_ providerCredentials = proxmoxCredentials{}
+	_ providerCredentials = nutanixCredentials{}
+	_ providerCredentials = vSphereCredentials{}
+	_ providerCredentials = libvirtCredentials{}
+)
+
+// Lists the data centers of the account, which any key of the account may do.
+func (c tritonCredentials) Validate() error {
+	keyMaterial, err := ioutil.ReadFile(c.KeyPath)
+	if err != nil {
+		return fmt.Errorf("Unable to read triton_key_path: %s", err)
+	}
+
+	signer, err := authentication.NewPrivateKeySigner(authentication.PrivateKeySignerInput{
+		KeyID:              c.KeyID,
+		PrivateKeyMaterial: keyMaterial,
+		AccountName:        c.Account,
+	})
+	if err != nil {
+		return util.ConfigError(fmt.Errorf("Invalid key at triton_key_path: %s", err))
+	}
+
+	computeClient, err := compute.NewClient(&triton.ClientConfig{
+		TritonURL:   c.URL,
+		AccountName: c.Account,
+		Signers:     []authentication.Signer{signer},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = computeClient.Datacenters().List(context.Background(), &compute.ListDataCentersInput{})
+	if err != nil {
+		return util.AuthError(fmt.Errorf("Triton at %s rejected the credentials of account '%s': %s. Check that the key %s is a key of the account, or of one of its users.", c.URL, c.Account, err, c.KeyID))
+	}
+	return nil
+}
+
+// Gets the identity of the access key, which needs no permissions.
+func (c awsCredentials) Validate() error {
+	awsConfig := aws.NewConfig().
+		WithCredentials(credentials.NewStaticCredentials(c.AccessKey, c.SecretKey, c.SessionToken)).
+		WithRegion(endpoints.UsEast1RegionID)
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return err
+	}
+
+	_, err = sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return awsCredentialsError(err)
+	}
+	return nil
+}
+
+func awsCredentialsError(err error) error {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case "InvalidClientTokenId":
+			return util.AuthError(fmt.Errorf("AWS rejected aws_access_key, it isn't an active access key: %s", awsErr.Message()))
+		case "SignatureDoesNotMatch":
+			return util.AuthError(fmt.Errorf("AWS rejected aws_secret_key, it isn't the secret of aws_access_key: %s", awsErr.Message()))
+		case "ExpiredToken":
+			return util.AuthError(fmt.Errorf("AWS rejected aws_session_token, the temporary credentials expired: %s", awsErr.Message()))
+		}
+	}
+	return fmt.Errorf("Unable to validate the AWS credentials: %s", err)
+}
+
+// Gets the project of the service account, which the compute.readonly scope allows.
+func (c gcpProjectCredentials) Validate() error {
+	rawCredentials, err := ioutil.ReadFile(c.PathToCredentials)
+	if err != nil {
+		return fmt.Errorf("Unable to read gcp_path_to_credentials: %s", err)
+	}
+
+	jwtCfg, err := google.JWTConfigFromJSON(rawCredentials, "https://www.googleapis.com/auth/compute.readonly")
+	if err != nil {
+		return fmt.Errorf("gcp_path_to_credentials isn't the JSON key of a service account: %s", err)
+	}
+
+	service, err := gcpcompute.New(jwtCfg.Client(context.Background()))
+	if err != nil {
+		return err
+	}
+
+	_, err = service.Projects.Get(c.ProjectID).Do()
+	if err != nil {
+		return gcpCredentialsError(err, jwtCfg.Email, c.ProjectID)
+	}
+	return nil
+}
+
+func gcpCredentialsError(err error, email, projectID string) error {
+	if apiErr, ok := err.(*googleapi.Error); ok {
+		switch apiErr.Code {
+		case http.StatusForbidden:
+			return util.AuthError(fmt.Errorf("The service account %s of gcp_path_to_credentials can't read project '%s', grant it the Compute Viewer role or enable the Compute Engine API: %s", email, projectID, apiErr.Message))
+		case http.StatusNotFound:
+			return fmt.Errorf("The project '%s' of gcp_path_to_credentials does not exist.", projectID)
+		}
+	}
+	return fmt.Errorf("Unable to validate the GCP credentials of %s: %s", email, err)
+}
+
+// Gets a token for the service principal, then the subscription with it.
+func (c azureCredentials) Validate() error {
+	oauthConfig, err := adal.NewOAuthConfig(c.Environment.ActiveDirectoryEndpoint, c.TenantID)
+	if err != nil {
+		return util.ConfigError(fmt.Errorf("Invalid azure_tenant_id: %s", err))
+	}
+
+	azureSPT, err := adal.NewServicePrincipalToken(*oauthConfig, c.ClientID, c.ClientSecret, c.Environment.ResourceManagerEndpoint)
+	if err != nil {
+		return err
+	}
+
+	err = azureSPT.Refresh()
+	if err != nil {
+		return util.AuthError(fmt.Errorf("Azure Active Directory rejected the service principal, check azure_tenant_id, azure_client_id and azure_client_secret: %s", err))
+	}
+
+	client := subscriptions.NewGroupClientWithBaseURI(c.Environment.ResourceManagerEndpoint)
+	client.Client = withAzureRetries(client.Client)
+	client.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
+
+	_, err = client.Get(c.SubscriptionID)
+	if err != nil {
+		return util.AuthError(fmt.Errorf("The service principal %s can't read subscription '%s', check azure_subscription_id and that the principal has a role in it: %s", c.ClientID, c.SubscriptionID, err))
+	}
+	return nil
+}
+
+// Gets the account of the token, which read-only tokens may do.
+func (c digitalOceanCredentials) Validate() error {
+	req, err := http.NewRequest("GET", digitalOceanAPIURL+"/account", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return util.AuthError(errors.New("DigitalOcean rejected digitalocean_api_token, it's invalid, expired or revoked."))
+	}
+
+	apiErr := struct {
+		Message string `json:"message"`
+	}{}
+	if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = resp.Status
+	}
+	return fmt.Errorf("Unable to validate digitalocean_api_token: %s", apiErr.Message)
+}
+
+// Gets the user of the token, which project tokens can't.
+func (c equinixMetalCredentials) Validate() error {
+	req, err := http.NewRequest("GET", equinixMetalAPIURL+"/user", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", c.APIToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return util.AuthError(errors.New("Equinix Metal rejected equinix_metal_api_token, it's invalid or revoked."))
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("Unable to validate equinix_metal_api_token: %s", equinixMetalErrorMessage(body, resp.Status))
+}
+
+// Gets a token scoped to the project, and checks the catalog has the region's endpoints.
+func (c openStackCredentials) Validate() error {
+	_, err := newOpenStackSession(c.openStackAuth)
+	if err != nil {
+		return util.AuthError(fmt.Errorf("Unable to authenticate with OpenStack at %s as '%s' in project '%s', check the openstack_ settings: %s", c.AuthURL, c.UserName, c.TenantName, err))
+	}
+	return nil
+}
+
+// Gets the version of Proxmox VE, which any API token may do.
+func (c proxmoxCredentials) Validate() error {
+	version := struct {
+		Version string `json:"version"`
+	}{}
+	err := getProxmox(c.proxmoxAuth, "/version", &version)
+	if err != nil {
+		return util.AuthError(fmt.Errorf("Unable to authenticate with Proxmox at %s with token '%s', check proxmox_api_token_id and proxmox_api_token_secret, or set proxmox_tls_insecure for a self-signed certificate: %s", c.APIURL, c.APITokenID, err))
+	}
+	return nil
+}
+
+// Lists a cluster of Prism Central, which any user may do.
+func (c nutanixCredentials) Validate() error {
+	page := struct{}{}
+	err := postNutanix(c.nutanixAuth, "/clusters/list", &nutanixListInput{Kind: "cluster", Length: 1}, &page)
+	if err != nil {
+		return util.AuthError(fmt.Errorf("Unable to authenticate with Prism Central at %s as '%s', check nutanix_username and nutanix_password, or set nutanix_insecure for a self-signed certificate: %s", c.Endpoint, c.Username, err))
+	}
+	return nil
+}
+
+// Opens a session of the vCenter REST API, then closes it. Like the terraform modules, the
+// certificate of the server isn't verified.
+func (c vSphereCredentials) Validate() error {
+	sessionURL := vSphereAPIURL(c.Server) + "/rest/com/vmware/cis/session"
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	req, err := http.NewRequest("POST", sessionURL, nil)
+	if err != nil {
+		return util.ConfigError(fmt.Errorf("Invalid vsphere_server '%s': %s", c.Server, err))
+	}
+	req.SetBasicAuth(c.User, c.Password)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to reach vsphere_server '%s': %s", c.Server, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return util.AuthError(fmt.Errorf("vSphere at %s rejected vsphere_user '%s' and vsphere_password.", c.Server, c.User))
+	case http.StatusNotFound:
+		// vCenter before 6.5 has no REST API, terraform authenticates through the SOAP API
+		return nil
+	default:
+		return fmt.Errorf("Unable to validate the vSphere credentials: %s", resp.Status)
+	}
+
+	session := struct {
+		Value string `json:"value"`
+	}{}
+	if json.NewDecoder(resp.Body).Decode(&session) != nil || session.Value == "" {
+		return nil
+	}
+
+	req, err = http.NewRequest("DELETE", sessionURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("vmware-api-session-id", session.Value)
+	logoutResp, err := client.Do(req)
+	if err == nil {
+		logoutResp.Body.Close()
+	}
+	return nil
+}
+
+// vsphere_server is a host name, as the terraform provider takes it, or a URL
+func vSphereAPIURL(server string) string {
+	if strings.HasPrefix(server, "https://") || strings.HasPrefix(server, "http://") {
+		return strings.TrimSuffix(server, "/")
+	}
+	return "https://" + strings.TrimSuffix(server, "/")
+}
+
+// Connects to libvirtd the way the URI's transport does: through its unix socket, over SSH
+// without prompting, which is how libvirt's client authenticates, or over TCP.
+func (c libvirtCredentials) Validate() error {
+	uri, err := url.Parse(c.URI)
+	if err != nil {
+		return util.ConfigError(fmt.Errorf("Invalid libvirt_uri '%s': %s", c.URI, err))
+	}
+
+	transport := ""
+	if i := strings.Index(uri.Scheme, "+"); i >= 0 {
+		transport = uri.Scheme[i+1:]
+	}
+
+	switch {
+	case (transport == "" || transport == "unix") && uri.Host == "":
+		// The session daemons of qemu:///session are started on demand
+		if uri.Path != "/system" {
+			return nil
+		}
+		socket := uri.Query().Get("socket")
+		if socket == "" {
+			socket = "/var/run/libvirt/libvirt-sock"
+		}
+		conn, err := net.DialTimeout("unix", socket, 10*time.Second)
+		if err != nil {
+			return fmt.Errorf("Unable to connect to libvirtd at %s for libvirt_uri '%s', check that it runs and that this user may use its socket: %s", socket, c.URI, err)
+		}
+		return conn.Close()
+	case transport == "ssh":
+		args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+		if uri.Port() != "" {
+			args = append(args, "-p", uri.Port())
+		}
+		if uri.User != nil {
+			args = append(args, "-l", uri.User.Username())
+		}
+		if keyFile := uri.Query().Get("keyfile"); keyFile != "" {
+			args = append(args, "-i", keyFile)
+		}
+		if uri.Query().Get("no_verify") == "1" {
+			args = append(args, "-o", "StrictHostKeyChecking=no")
+		}
+		args = append(args, uri.Hostname(), "true")
+
+		output, err := exec.Command("ssh", args...).CombinedOutput()
+		if err != nil {
+			return util.AuthError(fmt.Errorf("Unable to connect over SSH to the host of libvirt_uri '%s', check that its key is in the SSH agent or the keyfile parameter of the URI: %s", c.URI, strings.TrimSpace(string(output))))
+		}
+		return nil
+	case transport == "tcp" || transport == "tls" || transport == "" && uri.Host != "":
+		port := uri.Port()
+		if port == "" {
+			port = "16514"
+			if transport == "tcp" {
+				port = "16509"
+			}
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(uri.Hostname(), port), 10*time.Second)
+		if err != nil {
+			return fmt.Errorf("Unable to connect to libvirtd for libvirt_uri '%s': %s", c.URI, err)
+		}
+		return conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package create
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"google.golang.org/api/googleapi"
+)
+
+func TestDigitalOceanCredentialsValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/account" {
+			t.Errorf("Wrong path, received %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"id": "unauthorized", "message": "Unable to authenticate you"}`))
+			return
+		}
+		w.Write([]byte(`{"account": {"email": "dev@example.com", "status": "active"}}`))
+	}))
+	defer server.Close()
+
+	defaultURL := digitalOceanAPIURL
+	digitalOceanAPIURL = server.URL
+	defer func() { digitalOceanAPIURL = defaultURL }()
+
+	err := digitalOceanCredentials{APIToken: "valid"}.Validate()
+	if err != nil {
+		t.Errorf("Expected the token to be valid, received %v", err)
+	}
+
+	err = digitalOceanCredentials{APIToken: "revoked"}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "digitalocean_api_token") {
+		t.Errorf("Expected an error naming digitalocean_api_token, received %v", err)
+	}
+}
+
+func TestEquinixMetalCredentialsValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("Wrong path, received %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Auth-Token") != "valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": ["Invalid authentication token"]}`))
+			return
+		}
+		w.Write([]byte(`{"id": "u1", "email": "dev@example.com"}`))
+	}))
+	defer server.Close()
+
+	defaultURL := equinixMetalAPIURL
+	equinixMetalAPIURL = server.URL
+	defer func() { equinixMetalAPIURL = defaultURL }()
+
+	err := equinixMetalCredentials{APIToken: "valid"}.Validate()
+	if err != nil {
+		t.Errorf("Expected the token to be valid, received %v", err)
+	}
+
+	err = equinixMetalCredentials{APIToken: "revoked"}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "equinix_metal_api_token") {
+		t.Errorf("Expected an error naming equinix_metal_api_token, received %v", err)
+	}
+}
+
+func TestOpenStackCredentialsValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"code": 401, "message": "The request you have made requires authentication.", "title": "Unauthorized"}}`))
+	}))
+	defer server.Close()
+
+	err := openStackCredentials{openStackAuth{AuthURL: server.URL + "/v3", UserName: "admin", Password: "secret", TenantName: "k8s", DomainName: "Default", Region: "RegionOne"}}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "as 'admin' in project 'k8s'") || !strings.Contains(err.Error(), "requires authentication") {
+		t.Errorf("Expected the user, project and API error, received %v", err)
+	}
+}
+
+func TestVSphereCredentialsValidate(t *testing.T) {
+	loggedOut := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/com/vmware/cis/session" {
+			t.Errorf("Wrong path, received %s", r.URL.Path)
+		}
+		if r.Method == "DELETE" {
+			loggedOut = r.Header.Get("vmware-api-session-id") == "s1"
+			return
+		}
+		user, password, _ := r.BasicAuth()
+		if user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"value": "s1"}`))
+	}))
+	defer server.Close()
+
+	err := vSphereCredentials{User: "admin", Password: "secret", Server: server.URL}.Validate()
+	if err != nil {
+		t.Errorf("Expected the credentials to be valid, received %v", err)
+	}
+	if !loggedOut {
+		t.Error("Expected the session to be closed")
+	}
+
+	err = vSphereCredentials{User: "admin", Password: "wrong", Server: server.URL}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "vsphere_password") {
+		t.Errorf("Expected an error naming vsphere_password, received %v", err)
+	}
+}
+
+func TestLibvirtCredentialsValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "libvirt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "libvirt-sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	err = libvirtCredentials{URI: "qemu:///system?socket=" + socket}.Validate()
+	if err != nil {
+		t.Errorf("Expected libvirtd to be reachable, received %v", err)
+	}
+
+	err = libvirtCredentials{URI: "qemu:///system?socket=" + filepath.Join(dir, "missing")}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "libvirt_uri") {
+		t.Errorf("Expected an error naming libvirt_uri, received %v", err)
+	}
+
+	// Session daemons are started on demand
+	err = libvirtCredentials{URI: "qemu:///session"}.Validate()
+	if err != nil {
+		t.Errorf("Expected session URIs to be skipped, received %v", err)
+	}
+}
+
+func TestAWSCredentialsError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{awserr.New("InvalidClientTokenId", "The security token included in the request is invalid.", nil), "AWS rejected aws_access_key"},
+		{awserr.New("SignatureDoesNotMatch", "The request signature we calculated does not match.", nil), "AWS rejected aws_secret_key"},
+		{awserr.New("ExpiredToken", "The security token included in the request is expired.", nil), "AWS rejected aws_session_token"},
+		{errors.New("dial tcp: i/o timeout"), "Unable to validate the AWS credentials: dial tcp: i/o timeout"},
+	}
+
+	for _, tc := range testCases {
+		err := awsCredentialsError(tc.err)
+		if !strings.HasPrefix(err.Error(), tc.expected) {
+			t.Errorf("Wrong error for %v, expected %q, received %q", tc.err, tc.expected, err)
+		}
+	}
+}
+
+func TestGCPCredentialsError(t *testing.T) {
+	err := gcpCredentialsError(&googleapi.Error{Code: http.StatusForbidden, Message: "Required 'compute.projects.get' permission"}, "k8s@dev.iam.gserviceaccount.com", "dev")
+	if !strings.Contains(err.Error(), "k8s@dev.iam.gserviceaccount.com") || !strings.Contains(err.Error(), "Compute Viewer") {
+		t.Errorf("Expected the service account and the role to grant, received %v", err)
+	}
+
+	err = gcpCredentialsError(&googleapi.Error{Code: http.StatusNotFound}, "k8s@dev.iam.gserviceaccount.com", "dev")
+	if err.Error() != "The project 'dev' of gcp_path_to_credentials does not exist." {
+		t.Errorf("Wrong error, received %v", err)
+	}
+}
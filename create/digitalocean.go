@@ -0,0 +1,265 @@
+package create
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Overridden in tests
+var digitalOceanAPIURL = "https://api.digitalocean.com/v2"
+
+type digitalOceanRegion struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+}
+
+type digitalOceanSize struct {
+	Slug         string   `json:"slug"`
+	Memory       int      `json:"memory"`
+	VCPUs        int      `json:"vcpus"`
+	Disk         int      `json:"disk"`
+	PriceMonthly float64  `json:"price_monthly"`
+	Regions      []string `json:"regions"`
+	Available    bool     `json:"available"`
+}
+
+type digitalOceanImage struct {
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	Distribution string   `json:"distribution"`
+	Regions      []string `json:"regions"`
+}
+
+type digitalOceanSSHKey struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+type digitalOceanLinks struct {
+	Pages struct {
+		Next string `json:"next"`
+	} `json:"pages"`
+}
+
+// Requests every page of a DigitalOcean API list. collect is called with the body of each page
+// and returns the URL of the next page, if any.
+func listDigitalOcean(token, path string, collect func(body []byte) (string, error)) error {
+	url := digitalOceanAPIURL + path
+	for url != "" {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			apiErr := struct {
+				Message string `json:"message"`
+			}{}
+			if json.Unmarshal(body, &apiErr) != nil || apiErr.Message == "" {
+				apiErr.Message = resp.Status
+			}
+			return fmt.Errorf("DigitalOcean API request %s failed: %s", path, apiErr.Message)
+		}
+
+		url, err = collect(body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listDigitalOceanRegions(token string) ([]digitalOceanRegion, error) {
+	regions := []digitalOceanRegion{}
+	err := listDigitalOcean(token, "/regions?per_page=200", func(body []byte) (string, error) {
+		page := struct {
+			Regions []digitalOceanRegion `json:"regions"`
+			Links   digitalOceanLinks    `json:"links"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		regions = append(regions, page.Regions...)
+		return page.Links.Pages.Next, err
+	})
+	return regions, err
+}
+
+func listDigitalOceanSizes(token string) ([]digitalOceanSize, error) {
+	sizes := []digitalOceanSize{}
+	err := listDigitalOcean(token, "/sizes?per_page=200", func(body []byte) (string, error) {
+		page := struct {
+			Sizes []digitalOceanSize `json:"sizes"`
+			Links digitalOceanLinks  `json:"links"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		sizes = append(sizes, page.Sizes...)
+		return page.Links.Pages.Next, err
+	})
+	return sizes, err
+}
+
+func listDigitalOceanImages(token string) ([]digitalOceanImage, error) {
+	images := []digitalOceanImage{}
+	err := listDigitalOcean(token, "/images?type=distribution&per_page=200", func(body []byte) (string, error) {
+		page := struct {
+			Images []digitalOceanImage `json:"images"`
+			Links  digitalOceanLinks   `json:"links"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		images = append(images, page.Images...)
+		return page.Links.Pages.Next, err
+	})
+	return images, err
+}
+
+func listDigitalOceanSSHKeys(token string) ([]digitalOceanSSHKey, error) {
+	keys := []digitalOceanSSHKey{}
+	err := listDigitalOcean(token, "/account/keys?per_page=200", func(body []byte) (string, error) {
+		page := struct {
+			SSHKeys []digitalOceanSSHKey `json:"ssh_keys"`
+			Links   digitalOceanLinks    `json:"links"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		keys = append(keys, page.SSHKeys...)
+		return page.Links.Pages.Next, err
+	})
+	return keys, err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the regions droplets can currently be created in.
+func digitalOceanRegionOptions(regions []digitalOceanRegion) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, region := range regions {
+		if !region.Available {
+			continue
+		}
+		options = append(options, util.PromptOption{Value: region.Slug, Label: fmt.Sprintf("%s (%s)", region.Slug, region.Name)})
+	}
+	return options
+}
+
+// Returns the sizes available in the given region, cheapest first.
+func digitalOceanSizeOptions(sizes []digitalOceanSize, region string) []util.PromptOption {
+	available := []digitalOceanSize{}
+	for _, size := range sizes {
+		if size.Available && containsString(size.Regions, region) {
+			available = append(available, size)
+		}
+	}
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].PriceMonthly < available[j].PriceMonthly
+	})
+
+	options := []util.PromptOption{}
+	for _, size := range available {
+		label := fmt.Sprintf("%s (%d vCPUs, %d MB memory, %d GB disk, $%.2f/month)", size.Slug, size.VCPUs, size.Memory, size.Disk, size.PriceMonthly)
+		options = append(options, util.PromptOption{Value: size.Slug, Label: label})
+	}
+	return options
+}
+
+// Returns the distribution images available in the given region. Images without a slug can't
+// be referenced by name and are left out.
+func digitalOceanImageOptions(images []digitalOceanImage, region string) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, image := range images {
+		if image.Slug == "" || !containsString(image.Regions, region) {
+			continue
+		}
+		options = append(options, util.PromptOption{Value: image.Slug, Label: fmt.Sprintf("%s (%s %s)", image.Slug, image.Distribution, image.Name)})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Value < options[j].Value
+	})
+	return options
+}
+
+func digitalOceanSSHKeyOptions(keys []digitalOceanSSHKey) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, key := range keys {
+		options = append(options, util.PromptOption{Value: key.Fingerprint, Label: fmt.Sprintf("%s (%s)", key.Name, key.Fingerprint)})
+	}
+	return options
+}
+
+func getDigitalOceanAPIToken(conf config.Config) (string, error) {
+	if conf.IsSet("digitalocean_api_token") {
+		return conf.GetString("digitalocean_api_token"), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(errors.New("digitalocean_api_token must be specified"))
+	}
+
+	prompt := promptui.Prompt{
+		Label: "DigitalOcean API Token",
+		Mask:  '*',
+		Validate: func(input string) error {
+			if input == "" {
+				return errors.New("DigitalOcean API Token cannot be blank")
+			}
+			return nil
+		},
+	}
+
+	return prompt.Run()
+}
+
+func getDigitalOceanRegion(conf config.Config, token string) (string, error) {
+	regions, err := listDigitalOceanRegions(token)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "digitalocean_region", "DigitalOcean Region", digitalOceanRegionOptions(regions))
+}
+
+func getDigitalOceanDropletSize(conf config.Config, token, region string) (string, error) {
+	sizes, err := listDigitalOceanSizes(token)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "digitalocean_droplet_size", "DigitalOcean Droplet Size", digitalOceanSizeOptions(sizes, region))
+}
+
+func getDigitalOceanImage(conf config.Config, token, region string) (string, error) {
+	images, err := listDigitalOceanImages(token)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "digitalocean_image", "DigitalOcean Image", digitalOceanImageOptions(images, region))
+}
+
+func getDigitalOceanSSHKey(conf config.Config, token string) (string, error) {
+	keys, err := listDigitalOceanSSHKeys(token)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "digitalocean_ssh_key_fingerprint", "DigitalOcean SSH Key", digitalOceanSSHKeyOptions(keys))
+}
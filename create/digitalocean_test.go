@@ -0,0 +1,93 @@
+package create
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListDigitalOceanRegionsFollowsPages(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Wrong Authorization header, received %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `{"regions": [{"slug": "sfo2", "name": "San Francisco 2", "available": true}], "links": {}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"regions": [{"slug": "nyc3", "name": "New York 3", "available": true}], "links": {"pages": {"next": "%s/regions?page=2"}}}`, server.URL)
+	}))
+	defer server.Close()
+
+	originalURL := digitalOceanAPIURL
+	digitalOceanAPIURL = server.URL
+	defer func() { digitalOceanAPIURL = originalURL }()
+
+	regions, err := listDigitalOceanRegions("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 2 || regions[0].Slug != "nyc3" || regions[1].Slug != "sfo2" {
+		t.Errorf("Wrong output, expected nyc3 and sfo2, received %v", regions)
+	}
+}
+
+func TestListDigitalOceanAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"id": "unauthorized", "message": "Unable to authenticate you"}`)
+	}))
+	defer server.Close()
+
+	originalURL := digitalOceanAPIURL
+	digitalOceanAPIURL = server.URL
+	defer func() { digitalOceanAPIURL = originalURL }()
+
+	_, err := listDigitalOceanSSHKeys("invalid")
+	if err == nil || !strings.Contains(err.Error(), "Unable to authenticate you") {
+		t.Errorf("Expected the API error message, received %v", err)
+	}
+}
+
+func TestDigitalOceanRegionOptions(t *testing.T) {
+	regions := []digitalOceanRegion{
+		{Slug: "nyc3", Name: "New York 3", Available: true},
+		{Slug: "nyc2", Name: "New York 2", Available: false},
+	}
+
+	options := digitalOceanRegionOptions(regions)
+	if len(options) != 1 || options[0].Value != "nyc3" {
+		t.Errorf("Wrong output, expected [nyc3], received %v", options)
+	}
+}
+
+func TestDigitalOceanSizeOptions(t *testing.T) {
+	sizes := []digitalOceanSize{
+		{Slug: "s-4vcpu-8gb", PriceMonthly: 40, Regions: []string{"nyc3", "sfo2"}, Available: true},
+		{Slug: "s-2vcpu-4gb", PriceMonthly: 20, Regions: []string{"nyc3"}, Available: true},
+		{Slug: "s-1vcpu-1gb", PriceMonthly: 5, Regions: []string{"sfo2"}, Available: true},
+		{Slug: "c-2", PriceMonthly: 40, Regions: []string{"nyc3"}, Available: false},
+	}
+
+	options := digitalOceanSizeOptions(sizes, "nyc3")
+	if len(options) != 2 || options[0].Value != "s-2vcpu-4gb" || options[1].Value != "s-4vcpu-8gb" {
+		t.Errorf("Wrong output, expected [s-2vcpu-4gb s-4vcpu-8gb], received %v", options)
+	}
+}
+
+func TestDigitalOceanImageOptions(t *testing.T) {
+	images := []digitalOceanImage{
+		{Slug: "ubuntu-18-04-x64", Distribution: "Ubuntu", Name: "18.04 x64", Regions: []string{"nyc3"}},
+		{Slug: "centos-7-x64", Distribution: "CentOS", Name: "7.6 x64", Regions: []string{"nyc3"}},
+		{Slug: "", Distribution: "Ubuntu", Name: "16.04 x32", Regions: []string{"nyc3"}},
+		{Slug: "ubuntu-16-04-x64", Distribution: "Ubuntu", Name: "16.04 x64", Regions: []string{"sfo2"}},
+	}
+
+	options := digitalOceanImageOptions(images, "nyc3")
+	if len(options) != 2 || options[0].Value != "centos-7-x64" || options[1].Value != "ubuntu-18-04-x64" {
+		t.Errorf("Wrong output, expected [centos-7-x64 ubuntu-18-04-x64], received %v", options)
+	}
+}
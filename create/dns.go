@@ -0,0 +1,191 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/terraform"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// NodeLocal DNSCache became available in Kubernetes v1.15
+const minNodeLocalDNSKubernetesMinorVersion = 15
+
+// RKE's nodelocal, linearAutoscalerParams and upstreamnameservers DNS options are understood by
+// Rancher v2.4 and later
+const minDNSConfigRancherVersion = "v2.4"
+
+// Asks whether pods resolve names through NodeLocal DNSCache, a DNS cache on every node, and
+// how CoreDNS is scaled and which nameservers it forwards to. DNS is usually the first thing
+// to fail when nodes are far apart, e.g. in different clouds. Everything defaults to what
+// Rancher picks. The settings are only asked for when the Kubernetes version of the cluster and
+// the version of Rancher running the cluster manager, empty if unknown, support them.
+func getKubernetesDNSConfig(conf config.Config, rancherVersion string, cfg *baseClusterTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	rancherSupported := rancherVersionAtLeast(rancherVersion, minDNSConfigRancherVersion)
+
+	// NodeLocal DNSCache
+	kubernetesSupported := false
+	if minorVersion, err := getKubernetesMinorVersion(cfg.KubernetesVersion); err == nil {
+		kubernetesSupported = minorVersion >= minNodeLocalDNSKubernetesMinorVersion
+	}
+
+	nodeLocalDNS := false
+	if conf.IsSet("k8s_nodelocal_dns") {
+		nodeLocalDNS = conf.GetBool("k8s_nodelocal_dns")
+	} else if !nonInteractiveMode && kubernetesSupported && rancherSupported {
+		confirmed, err := util.PromptForConfirmation("Enable NodeLocal DNSCache", "NodeLocal DNSCache")
+		if err != nil {
+			return err
+		}
+		nodeLocalDNS = confirmed
+	}
+
+	if nodeLocalDNS {
+		if !kubernetesSupported {
+			return util.ConfigError(fmt.Errorf("k8s_nodelocal_dns requires Kubernetes v1.%d or later, found '%s'.", minNodeLocalDNSKubernetesMinorVersion, cfg.KubernetesVersion))
+		}
+		if !rancherSupported {
+			return util.ConfigError(fmt.Errorf("k8s_nodelocal_dns requires Rancher %s or later, the cluster manager runs '%s'.", minDNSConfigRancherVersion, rancherVersion))
+		}
+		cfg.KubernetesNodeLocalDNS = "true"
+	}
+
+	// CoreDNS Replicas
+	minReplicas := ""
+	if conf.IsSet("k8s_coredns_min_replicas") {
+		minReplicas = conf.GetString("k8s_coredns_min_replicas")
+	} else if !nonInteractiveMode && rancherSupported {
+		prompt := promptui.Prompt{
+			Label:    "Minimum CoreDNS replicas (leave empty for Rancher's default)",
+			Validate: validateCoreDNSMinReplicas,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		minReplicas = result
+	}
+
+	if validateCoreDNSMinReplicas(minReplicas) != nil {
+		return fmt.Errorf("k8s_coredns_min_replicas must be a number greater than 0. Found '%s'.", minReplicas)
+	}
+	if minReplicas != "" && !rancherSupported {
+		return util.ConfigError(fmt.Errorf("k8s_coredns_min_replicas requires Rancher %s or later, the cluster manager runs '%s'.", minDNSConfigRancherVersion, rancherVersion))
+	}
+	cfg.KubernetesCoreDNSMinReplicas = minReplicas
+
+	// CoreDNS Forwarders
+	nameservers := []string{}
+	if conf.IsSet("k8s_coredns_upstream_nameservers") {
+		// A list, or a comma separated string
+		nameservers = splitCommaSeparated(strings.Join(conf.GetStringSlice("k8s_coredns_upstream_nameservers"), ","))
+	} else if !nonInteractiveMode && rancherSupported {
+		prompt := promptui.Prompt{
+			Label: "CoreDNS upstream nameservers, comma separated (leave empty for the nodes' nameservers)",
+			Validate: func(input string) error {
+				return validateUpstreamNameservers(splitCommaSeparated(input))
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		nameservers = splitCommaSeparated(result)
+	}
+
+	err := validateUpstreamNameservers(nameservers)
+	if err != nil {
+		return util.ConfigError(fmt.Errorf("Invalid k8s_coredns_upstream_nameservers: %s", err))
+	}
+	if len(nameservers) > 0 && !rancherSupported {
+		return util.ConfigError(fmt.Errorf("k8s_coredns_upstream_nameservers requires Rancher %s or later, the cluster manager runs '%s'.", minDNSConfigRancherVersion, rancherVersion))
+	}
+	cfg.KubernetesCoreDNSUpstreamNameservers = strings.Join(nameservers, ",")
+
+	return nil
+}
+
+// Returns the version of Rancher the cluster manager runs: the version of its Helm chart when
+// it runs on 3 hosts, the tag of its rancher_server_image otherwise. Variables the manager
+// leaves unset are read from the defaults of its module. Empty when it isn't known.
+func getManagerRancherVersion(currentState state.State) string {
+	source, err := currentState.ModuleSource("cluster-manager")
+	if err != nil {
+		return ""
+	}
+	variable := func(name string) string {
+		if value := currentState.Get("module.cluster-manager." + name); value != "" {
+			return value
+		}
+		value, _, _ := terraform.ModuleVariableDefault(source, name)
+		return value
+	}
+
+	if currentState.GetInt("module.cluster-manager.manager_host_count") == 3 {
+		return variable("rancher_chart_version")
+	}
+
+	// e.g. rancher/server:v2.0.0-beta2 or registry.example.com:5000/rancher/rancher:v2.5.8,
+	// images pinned by digest have no version
+	image := variable("rancher_server_image")
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i+1:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
+
+// Versions without numbers, e.g. the latest and stable tags, are assumed to be recent
+func rancherVersionAtLeast(rancherVersion, minVersion string) bool {
+	if !strings.ContainsAny(rancherVersion, "0123456789") {
+		return true
+	}
+	return rancher.CompareKubernetesVersions(rancherVersion, minVersion) >= 0
+}
+
+func validateCoreDNSMinReplicas(input string) error {
+	if input == "" {
+		return nil
+	}
+
+	num, err := strconv.Atoi(input)
+	if err != nil || num <= 0 {
+		return errors.New("Replicas must be a number greater than 0")
+	}
+	return nil
+}
+
+// Returns the trimmed, non-empty items of a comma separated list.
+func splitCommaSeparated(input string) []string {
+	items := []string{}
+	for _, item := range strings.Split(input, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// CoreDNS forwards to nameservers by IP address
+func validateUpstreamNameservers(nameservers []string) error {
+	for _, nameserver := range nameservers {
+		if net.ParseIP(nameserver) == nil {
+			return fmt.Errorf("'%s' is not an IP address", nameserver)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestGetKubernetesDNSConfig(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("k8s_nodelocal_dns", true)
+	conf.Set("k8s_coredns_min_replicas", "3")
+	conf.Set("k8s_coredns_upstream_nameservers", "8.8.8.8, 1.1.1.1")
+
+	cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.15.12-rancher2-2"}
+	err := getKubernetesDNSConfig(conf, "v2.5.8", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.KubernetesNodeLocalDNS != "true" || cfg.KubernetesCoreDNSMinReplicas != "3" || cfg.KubernetesCoreDNSUpstreamNameservers != "8.8.8.8,1.1.1.1" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+}
+
+func TestGetKubernetesDNSConfigNameserverList(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("k8s_coredns_upstream_nameservers", []string{"8.8.8.8", "2001:4860:4860::8888"})
+
+	cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.13.5-rancher1-2"}
+	err := getKubernetesDNSConfig(conf, "v2.5.8", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.KubernetesNodeLocalDNS != "" || cfg.KubernetesCoreDNSUpstreamNameservers != "8.8.8.8,2001:4860:4860::8888" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+}
+
+func TestGetKubernetesDNSConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		key, value, expected string
+	}{
+		{"k8s_nodelocal_dns", "true", "k8s_nodelocal_dns requires Kubernetes v1.15 or later, found 'v1.13.5-rancher1-2'."},
+		{"k8s_coredns_min_replicas", "0", "k8s_coredns_min_replicas must be a number greater than 0. Found '0'."},
+		{"k8s_coredns_upstream_nameservers", "dns.google", "Invalid k8s_coredns_upstream_nameservers: 'dns.google' is not an IP address"},
+	} {
+		conf := config.New()
+		conf.Set("non-interactive", true)
+		conf.Set(tc.key, tc.value)
+
+		cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.13.5-rancher1-2"}
+		err := getKubernetesDNSConfig(conf, "v2.5.8", &cfg)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Wrong output for %s, expected '%s', received %v", tc.key, tc.expected, err)
+		}
+	}
+}
+
+func TestGetKubernetesDNSConfigRancherVersion(t *testing.T) {
+	for _, tc := range []struct {
+		key, value, expected string
+	}{
+		{"k8s_nodelocal_dns", "true", "k8s_nodelocal_dns requires Rancher v2.4 or later, the cluster manager runs 'v2.0.0-beta2'."},
+		{"k8s_coredns_min_replicas", "3", "k8s_coredns_min_replicas requires Rancher v2.4 or later, the cluster manager runs 'v2.0.0-beta2'."},
+		{"k8s_coredns_upstream_nameservers", "8.8.8.8", "k8s_coredns_upstream_nameservers requires Rancher v2.4 or later, the cluster manager runs 'v2.0.0-beta2'."},
+	} {
+		conf := config.New()
+		conf.Set("non-interactive", true)
+		conf.Set(tc.key, tc.value)
+
+		cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.15.12-rancher2-2"}
+		err := getKubernetesDNSConfig(conf, "v2.0.0-beta2", &cfg)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Wrong output for %s, expected '%s', received %v", tc.key, tc.expected, err)
+		}
+	}
+
+	// Unset and disabled settings don't need a recent Rancher
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("k8s_nodelocal_dns", false)
+
+	cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.10.1-rancher1"}
+	err := getKubernetesDNSConfig(conf, "v2.0.0-beta2", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetManagerRancherVersion(t *testing.T) {
+	for _, tc := range []struct {
+		manager  map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher"}, "v2.0.0-beta2"},
+		{map[string]interface{}{"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher", "rancher_server_image": "registry.example.com:5000/rancher/rancher:v2.5.8"}, "v2.5.8"},
+		{map[string]interface{}{"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher", "rancher_server_image": "registry.example.com:5000/rancher/rancher"}, ""},
+		{map[string]interface{}{"source": "github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher", "manager_host_count": 3}, "2.5.8"},
+	} {
+		currentState, err := state.New("test", []byte("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = currentState.SetManager(tc.manager)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		version := getManagerRancherVersion(currentState)
+		if version != tc.expected {
+			t.Errorf("%v: expected '%s', got '%s'", tc.manager, tc.expected, version)
+		}
+	}
+
+	for version, expected := range map[string]bool{"v2.0.0-beta2": false, "v2.4.0": true, "2.5.8": true, "latest": true, "": true} {
+		if rancherVersionAtLeast(version, minDNSConfigRancherVersion) != expected {
+			t.Errorf("%s: expected %t", version, expected)
+		}
+	}
+}
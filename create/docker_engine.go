@@ -0,0 +1,56 @@
+package create
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+const defaultDockerEngineVersion = "17.03"
+
+// Install scripts for each supported Docker engine version. 17.03 is the default of the host modules.
+var dockerEngineInstallURLs = map[string]string{
+	"17.03": "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh",
+	"1.13":  "https://releases.rancher.com/install-docker/1.13.sh",
+	"1.12":  "https://releases.rancher.com/install-docker/1.12.sh",
+}
+
+// Docker engine versions validated by Rancher for each Kubernetes minor version. Nodes running
+// other versions frequently fail to register.
+var kubernetesDockerEngineVersions = map[string][]string{
+	"v1.8":  {"17.03", "1.13", "1.12"},
+	"v1.9":  {"17.03", "1.13", "1.12"},
+	"v1.10": {"17.03", "1.13", "1.12"},
+}
+
+var kubernetesMinorVersionRegexp = regexp.MustCompile(`^v\d+\.\d+`)
+
+// Returns the Docker engine versions compatible with the given Kubernetes version, e.g. v1.10.0-rancher1-1.
+func getDockerEngineVersions(kubernetesVersion string) ([]string, error) {
+	minorVersion := kubernetesMinorVersionRegexp.FindString(kubernetesVersion)
+	versions, ok := kubernetesDockerEngineVersions[minorVersion]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported Kubernetes version '%s', cannot determine compatible Docker engine versions", kubernetesVersion)
+	}
+
+	return versions, nil
+}
+
+// Returns the install script URL of the given Docker engine version, after verifying
+// it is compatible with the given Kubernetes version.
+func getDockerEngineInstallURL(dockerEngineVersion, kubernetesVersion string) (string, error) {
+	versions, err := getDockerEngineVersions(kubernetesVersion)
+	if err != nil {
+		return "", err
+	}
+
+	for _, version := range versions {
+		if version == dockerEngineVersion {
+			return dockerEngineInstallURLs[version], nil
+		}
+	}
+
+	return "", util.ConfigError(fmt.Errorf("Invalid docker_engine_version '%s' for Kubernetes %s, must be one of the following: %s", dockerEngineVersion, kubernetesVersion, strings.Join(versions, ", ")))
+}
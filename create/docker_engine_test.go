@@ -0,0 +1,27 @@
+package create
+
+import "testing"
+
+var getDockerEngineInstallURLTestCases = []struct {
+	DockerEngineVersion string
+	KubernetesVersion   string
+	ExpectedURL         string
+	ExpectError         bool
+}{
+	{"17.03", "v1.10.0-rancher1-1", "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh", false},
+	{"1.12", "v1.8.10-rancher1-1", "https://releases.rancher.com/install-docker/1.12.sh", false},
+	{"18.09", "v1.9.5-rancher1-1", "", true},
+	{"17.03", "v1.6.0", "", true},
+}
+
+func TestGetDockerEngineInstallURL(t *testing.T) {
+	for _, tc := range getDockerEngineInstallURLTestCases {
+		url, err := getDockerEngineInstallURL(tc.DockerEngineVersion, tc.KubernetesVersion)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for (%q, %q), received %q", tc.DockerEngineVersion, tc.KubernetesVersion, url)
+		}
+		if !tc.ExpectError && (err != nil || url != tc.ExpectedURL) {
+			t.Errorf("Wrong output for (%q, %q), expected %q, received %q, %v", tc.DockerEngineVersion, tc.KubernetesVersion, tc.ExpectedURL, url, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Settings of an environment spec that describe what to create, the other settings are
+// shared by everything it creates
+var environmentSpecKeys = []string{"manager", "clusters"}
+
+// IsEnvironmentSpec returns whether the config describes a whole environment, a cluster
+// manager under `manager` and its clusters under `clusters`.
+func IsEnvironmentSpec(conf config.Config) bool {
+	for _, key := range environmentSpecKeys {
+		if conf.IsSet(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEnvironment creates the cluster manager and the clusters of an environment spec, in that
+// order, without prompting. Each cluster is created with its own settings, cloud provider and
+// node pools (`nodes`), and without `manager` the clusters are added to the existing
+// `cluster_manager`. The cluster manager and clusters that already exist are skipped, so a
+// failed run can be run again.
+//
+//	manager:
+//	  name: dev-manager
+//	  manager_cloud_provider: triton
+//	clusters:
+//	  - name: dev-eu
+//	    cluster_cloud_provider: aws
+//	    nodes:
+//	      - hostname: dev-eu-w
+//	        rancher_host_label: worker
+//	        node_count: 3
+func NewEnvironment(conf config.Config, remoteBackend backend.Backend) error {
+	existingClusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	selectedClusterManager := conf.GetString("cluster_manager")
+	if conf.IsSet("manager") {
+		managerSettings, ok := toStringMap(conf.Get("manager"))
+		if !ok {
+			return errors.New("Could not read 'manager' configuration")
+		}
+
+		managerConf := newEnvironmentConfig(conf, managerSettings)
+		selectedClusterManager = managerConf.GetString("name")
+		if selectedClusterManager == "" {
+			return util.ConfigError(errors.New("manager name must be specified"))
+		}
+
+		found := false
+		for _, clusterManager := range existingClusterManagers {
+			if clusterManager == selectedClusterManager {
+				found = true
+				break
+			}
+		}
+		if found {
+			fmt.Printf("Cluster manager '%s' already exists, skipping.\n", selectedClusterManager)
+		} else {
+			fmt.Printf("Creating cluster manager '%s'.\n", selectedClusterManager)
+			err = NewManager(managerConf, remoteBackend)
+			if err != nil {
+				return fmt.Errorf("Could not create cluster manager '%s': %v", selectedClusterManager, err)
+			}
+		}
+	}
+
+	if !conf.IsSet("clusters") {
+		return nil
+	}
+	if selectedClusterManager == "" {
+		return util.ConfigError(errors.New("manager or cluster_manager must be specified"))
+	}
+
+	clusters, ok := conf.Get("clusters").([]interface{})
+	if !ok {
+		return errors.New("Could not read 'clusters' configuration")
+	}
+
+	for i, cluster := range clusters {
+		clusterSettings, ok := toStringMap(cluster)
+		if !ok {
+			return fmt.Errorf("Could not read configuration of cluster %d", i+1)
+		}
+
+		clusterConf := newEnvironmentConfig(conf, clusterSettings)
+		clusterConf.Set("cluster_manager", selectedClusterManager)
+		clusterName := clusterConf.GetString("name")
+		if clusterName == "" {
+			return util.ConfigError(fmt.Errorf("name of cluster %d must be specified", i+1))
+		}
+
+		// The state is read again for each cluster, the previous one was added to it
+		currentState, err := remoteBackend.State(selectedClusterManager)
+		if err != nil {
+			return err
+		}
+		existingClusters, err := currentState.Clusters()
+		if err != nil {
+			return err
+		}
+		if _, ok := existingClusters[clusterName]; ok {
+			fmt.Printf("Cluster '%s' already exists, skipping.\n", clusterName)
+			continue
+		}
+
+		fmt.Printf("Creating cluster '%s'.\n", clusterName)
+		err = NewCluster(clusterConf, remoteBackend)
+		if err != nil {
+			return fmt.Errorf("Could not create cluster '%s': %v", clusterName, err)
+		}
+	}
+
+	return nil
+}
+
+// Returns a non-interactive config with the shared settings of the environment spec and the
+// given settings of one of its cluster manager or clusters.
+func newEnvironmentConfig(conf config.Config, settings map[string]interface{}) config.Config {
+	result := config.New()
+	for _, key := range conf.AllKeys() {
+		// Nested settings are listed as `{key}.{nested key}`
+		root := strings.SplitN(key, ".", 2)[0]
+		isSpecKey := false
+		for _, specKey := range environmentSpecKeys {
+			if root == specKey {
+				isSpecKey = true
+				break
+			}
+		}
+		if !isSpecKey {
+			result.Set(key, conf.Get(key))
+		}
+	}
+
+	for key, value := range settings {
+		result.Set(key, value)
+	}
+	result.Set("non-interactive", true)
+
+	return result
+}
+
+// Maps decoded from YAML have interface{} keys, unless viper converted them.
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value, true
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range value {
+			result[fmt.Sprint(key)] = item
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,58 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestIsEnvironmentSpec(t *testing.T) {
+	conf := config.New()
+	conf.Set("name", "dev-manager")
+	if IsEnvironmentSpec(conf) {
+		t.Error("Expected a config without manager or clusters not to be an environment spec")
+	}
+
+	conf.Set("clusters", []interface{}{})
+	if !IsEnvironmentSpec(conf) {
+		t.Error("Expected a config with clusters to be an environment spec")
+	}
+}
+
+func TestNewEnvironmentConfig(t *testing.T) {
+	conf := config.New()
+	conf.Set("backend_provider", "local")
+	conf.Set("confirm_plan", false)
+	conf.Set("manager", map[string]interface{}{"name": "dev-manager"})
+	conf.Set("clusters", []interface{}{
+		map[interface{}]interface{}{"name": "dev-eu"},
+	})
+
+	cluster, ok := toStringMap(conf.Get("clusters").([]interface{})[0])
+	if !ok {
+		t.Fatal("Expected the cluster settings to be a map")
+	}
+	nodes := []interface{}{
+		map[interface{}]interface{}{"hostname": "dev-eu-w", "node_count": 3},
+	}
+	cluster["nodes"] = nodes
+	cluster["cluster_cloud_provider"] = "aws"
+
+	clusterConf := newEnvironmentConfig(conf, cluster)
+
+	if clusterConf.GetString("backend_provider") != "local" {
+		t.Errorf("Expected the shared settings, got backend_provider '%s'", clusterConf.GetString("backend_provider"))
+	}
+	if clusterConf.GetString("name") != "dev-eu" || clusterConf.GetString("cluster_cloud_provider") != "aws" {
+		t.Errorf("Expected the cluster settings, got name '%s'", clusterConf.GetString("name"))
+	}
+	if clusterConf.IsSet("manager") || clusterConf.IsSet("clusters") {
+		t.Error("Expected the environment spec to be left out")
+	}
+	if !clusterConf.GetBool("non-interactive") {
+		t.Error("Expected the config to be non-interactive")
+	}
+	if clusterNodes, ok := clusterConf.Get("nodes").([]interface{}); !ok || len(clusterNodes) != 1 {
+		t.Errorf("Expected the nodes of the cluster, got %v", clusterConf.Get("nodes"))
+	}
+}
@@ -0,0 +1,280 @@
+package create
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Overridden in tests
+var equinixMetalAPIURL = "https://api.equinix.com/metal/v1"
+
+type equinixMetalProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type equinixMetalMetro struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Country string `json:"country"`
+}
+
+type equinixMetalPlan struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Line        string `json:"line"`
+	Description string `json:"description"`
+	Pricing     struct {
+		Hour float64 `json:"hour"`
+	} `json:"pricing"`
+	AvailableInMetros []struct {
+		Code string `json:"code"`
+	} `json:"available_in_metros"`
+}
+
+type equinixMetalOperatingSystem struct {
+	Slug            string   `json:"slug"`
+	Name            string   `json:"name"`
+	Distro          string   `json:"distro"`
+	Version         string   `json:"version"`
+	ProvisionableOn []string `json:"provisionable_on"`
+}
+
+type equinixMetalMeta struct {
+	Next *struct {
+		Href string `json:"href"`
+	} `json:"next"`
+}
+
+// Requests every page of an Equinix Metal API list. collect is called with the body of each
+// page and returns the path of the next page, if any.
+func listEquinixMetal(token, path string, collect func(body []byte) (string, error)) error {
+	for path != "" {
+		req, err := http.NewRequest("GET", equinixMetalAPIURL+path, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Auth-Token", token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Equinix Metal API request %s failed: %s", path, equinixMetalErrorMessage(body, resp.Status))
+		}
+
+		path, err = collect(body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the errors of an Equinix Metal API response, or status if it has none.
+func equinixMetalErrorMessage(body []byte, status string) string {
+	apiErr := struct {
+		Errors []string `json:"errors"`
+	}{}
+	if json.Unmarshal(body, &apiErr) != nil || len(apiErr.Errors) == 0 {
+		return status
+	}
+	return strings.Join(apiErr.Errors, ", ")
+}
+
+// Returns the path of the next page, which the API gives relative to its root.
+func equinixMetalNextPage(meta equinixMetalMeta) string {
+	if meta.Next == nil {
+		return ""
+	}
+	return strings.TrimPrefix(meta.Next.Href, "/metal/v1")
+}
+
+func listEquinixMetalProjects(token string) ([]equinixMetalProject, error) {
+	projects := []equinixMetalProject{}
+	err := listEquinixMetal(token, "/projects?per_page=100", func(body []byte) (string, error) {
+		page := struct {
+			Projects []equinixMetalProject `json:"projects"`
+			Meta     equinixMetalMeta      `json:"meta"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		projects = append(projects, page.Projects...)
+		return equinixMetalNextPage(page.Meta), err
+	})
+	return projects, err
+}
+
+func listEquinixMetalMetros(token string) ([]equinixMetalMetro, error) {
+	metros := []equinixMetalMetro{}
+	err := listEquinixMetal(token, "/locations/metros", func(body []byte) (string, error) {
+		page := struct {
+			Metros []equinixMetalMetro `json:"metros"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		metros = append(metros, page.Metros...)
+		return "", err
+	})
+	return metros, err
+}
+
+func listEquinixMetalPlans(token, projectID string) ([]equinixMetalPlan, error) {
+	plans := []equinixMetalPlan{}
+	err := listEquinixMetal(token, fmt.Sprintf("/projects/%s/plans?include=available_in_metros", projectID), func(body []byte) (string, error) {
+		page := struct {
+			Plans []equinixMetalPlan `json:"plans"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		plans = append(plans, page.Plans...)
+		return "", err
+	})
+	return plans, err
+}
+
+func listEquinixMetalOperatingSystems(token string) ([]equinixMetalOperatingSystem, error) {
+	operatingSystems := []equinixMetalOperatingSystem{}
+	err := listEquinixMetal(token, "/operating-systems", func(body []byte) (string, error) {
+		page := struct {
+			OperatingSystems []equinixMetalOperatingSystem `json:"operating_systems"`
+		}{}
+		err := json.Unmarshal(body, &page)
+		operatingSystems = append(operatingSystems, page.OperatingSystems...)
+		return "", err
+	})
+	return operatingSystems, err
+}
+
+func equinixMetalProjectOptions(projects []equinixMetalProject) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, project := range projects {
+		options = append(options, util.PromptOption{Value: project.ID, Label: fmt.Sprintf("%s (%s)", project.Name, project.ID)})
+	}
+	return options
+}
+
+func equinixMetalMetroOptions(metros []equinixMetalMetro) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, metro := range metros {
+		options = append(options, util.PromptOption{Value: metro.Code, Label: fmt.Sprintf("%s (%s, %s)", metro.Code, metro.Name, metro.Country)})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Value < options[j].Value
+	})
+	return options
+}
+
+// Returns whether the plan is a bare metal one that can be deployed in the given metro.
+func equinixMetalPlanAvailable(plan equinixMetalPlan, metro string) bool {
+	if plan.Line != "baremetal" {
+		return false
+	}
+	for _, availableMetro := range plan.AvailableInMetros {
+		if availableMetro.Code == metro {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the bare metal plans available in the given metro, cheapest first.
+func equinixMetalPlanOptions(plans []equinixMetalPlan, metro string) []util.PromptOption {
+	available := []equinixMetalPlan{}
+	for _, plan := range plans {
+		if equinixMetalPlanAvailable(plan, metro) {
+			available = append(available, plan)
+		}
+	}
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].Pricing.Hour < available[j].Pricing.Hour
+	})
+
+	options := []util.PromptOption{}
+	for _, plan := range available {
+		label := fmt.Sprintf("%s (%s, $%.2f/hour)", plan.Slug, plan.Description, plan.Pricing.Hour)
+		options = append(options, util.PromptOption{Value: plan.Slug, Label: label})
+	}
+	return options
+}
+
+// Returns the operating systems the given plan can be provisioned with.
+func equinixMetalOperatingSystemOptions(operatingSystems []equinixMetalOperatingSystem, plan string) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, operatingSystem := range operatingSystems {
+		if !containsString(operatingSystem.ProvisionableOn, plan) {
+			continue
+		}
+		options = append(options, util.PromptOption{Value: operatingSystem.Slug, Label: fmt.Sprintf("%s (%s)", operatingSystem.Slug, operatingSystem.Name)})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Value < options[j].Value
+	})
+	return options
+}
+
+func getEquinixMetalAPIToken(conf config.Config) (string, error) {
+	return util.PromptForValue(conf, "equinix_metal_api_token", "Equinix Metal API Token", "", true)
+}
+
+func getEquinixMetalProjectID(conf config.Config, token string) (string, error) {
+	projects, err := listEquinixMetalProjects(token)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "equinix_metal_project_id", "Equinix Metal Project", equinixMetalProjectOptions(projects))
+}
+
+func getEquinixMetalMetro(conf config.Config, token string) (string, error) {
+	metros, err := listEquinixMetalMetros(token)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "equinix_metal_metro", "Equinix Metal Metro", equinixMetalMetroOptions(metros))
+}
+
+func getEquinixMetalPlan(conf config.Config, token, projectID, metro string) (string, error) {
+	plans, err := listEquinixMetalPlans(token, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	// A plan of another metro would only fail once terraform creates the device
+	if conf.IsSet("equinix_metal_plan") {
+		slug := conf.GetString("equinix_metal_plan")
+		for _, plan := range plans {
+			if plan.Slug == slug && !equinixMetalPlanAvailable(plan, metro) {
+				return "", util.ConfigError(fmt.Errorf("Equinix Metal Plan '%s' isn't available in metro '%s'.", slug, metro))
+			}
+		}
+	}
+	return util.PromptForOption(conf, "equinix_metal_plan", "Equinix Metal Plan", equinixMetalPlanOptions(plans, metro))
+}
+
+func getEquinixMetalOperatingSystem(conf config.Config, token, plan string) (string, error) {
+	operatingSystems, err := listEquinixMetalOperatingSystems(token)
+	if err != nil {
+		return "", err
+	}
+
+	if conf.IsSet("equinix_metal_operating_system") {
+		slug := conf.GetString("equinix_metal_operating_system")
+		for _, operatingSystem := range operatingSystems {
+			if operatingSystem.Slug == slug && !containsString(operatingSystem.ProvisionableOn, plan) {
+				return "", util.ConfigError(fmt.Errorf("Equinix Metal Operating System '%s' can't be provisioned on plan '%s'.", slug, plan))
+			}
+		}
+	}
+	return util.PromptForOption(conf, "equinix_metal_operating_system", "Equinix Metal Operating System", equinixMetalOperatingSystemOptions(operatingSystems, plan))
+}
@@ -0,0 +1,140 @@
+package create
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// Serves the metros, the plans of project p1 and the operating systems.
+func newTestEquinixMetalServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != "token" {
+			t.Errorf("Wrong X-Auth-Token header, received %q", r.Header.Get("X-Auth-Token"))
+		}
+		switch r.URL.Path {
+		case "/locations/metros":
+			fmt.Fprint(w, `{"metros": [{"code": "sv", "name": "Silicon Valley", "country": "US"}, {"code": "da", "name": "Dallas", "country": "US"}]}`)
+		case "/projects/p1/plans":
+			fmt.Fprint(w, `{"plans": [
+				{"slug": "m3.large.x86", "line": "baremetal", "pricing": {"hour": 3.1}, "available_in_metros": [{"code": "da"}, {"code": "sv"}]},
+				{"slug": "c3.small.x86", "line": "baremetal", "pricing": {"hour": 0.75}, "available_in_metros": [{"code": "da"}]},
+				{"slug": "c3.medium.x86", "line": "baremetal", "pricing": {"hour": 1.5}, "available_in_metros": [{"code": "sv"}]},
+				{"slug": "storage.block", "line": "storage", "available_in_metros": [{"code": "da"}]}
+			]}`)
+		case "/operating-systems":
+			fmt.Fprint(w, `{"operating_systems": [
+				{"slug": "ubuntu_20_04", "name": "Ubuntu 20.04 LTS", "provisionable_on": ["c3.small.x86", "m3.large.x86"]},
+				{"slug": "centos_7", "name": "CentOS 7", "provisionable_on": ["c3.small.x86"]},
+				{"slug": "windows_2019", "name": "Windows 2019", "provisionable_on": ["m3.large.x86"]}
+			]}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors": ["You are not authorized to view this project"]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalURL := equinixMetalAPIURL
+	equinixMetalAPIURL = server.URL
+	t.Cleanup(func() { equinixMetalAPIURL = originalURL })
+}
+
+func TestGetEquinixMetalMetro(t *testing.T) {
+	newTestEquinixMetalServer(t)
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("equinix_metal_metro", "da")
+	metro, err := getEquinixMetalMetro(conf, "token")
+	if err != nil || metro != "da" {
+		t.Errorf("Expected metro da, received %q, %v", metro, err)
+	}
+
+	conf.Set("equinix_metal_metro", "Dallas")
+	_, err = getEquinixMetalMetro(conf, "token")
+	if err == nil || !strings.Contains(err.Error(), "'Dallas' does not exist") {
+		t.Errorf("Expected an error for a metro given by name, received %v", err)
+	}
+}
+
+func TestGetEquinixMetalPlan(t *testing.T) {
+	newTestEquinixMetalServer(t)
+
+	testCases := []struct {
+		plan     string
+		expected string
+	}{
+		{"c3.small.x86", ""},
+		{"m3.large.x86", ""},
+		{"c3.medium.x86", "Equinix Metal Plan 'c3.medium.x86' isn't available in metro 'da'."},
+		{"storage.block", "Equinix Metal Plan 'storage.block' isn't available in metro 'da'."},
+		{"n2.xlarge.x86", "Selected Equinix Metal Plan 'n2.xlarge.x86' does not exist."},
+	}
+
+	for _, tc := range testCases {
+		conf := config.New()
+		conf.Set("non-interactive", true)
+		conf.Set("equinix_metal_plan", tc.plan)
+
+		plan, err := getEquinixMetalPlan(conf, "token", "p1", "da")
+		if tc.expected == "" {
+			if err != nil || plan != tc.plan {
+				t.Errorf("Expected plan %s, received %q, %v", tc.plan, plan, err)
+			}
+		} else if err == nil || err.Error() != tc.expected {
+			t.Errorf("Wrong error for plan %s, expected %q, received %v", tc.plan, tc.expected, err)
+		}
+	}
+}
+
+func TestGetEquinixMetalPlanAPIError(t *testing.T) {
+	newTestEquinixMetalServer(t)
+
+	conf := config.New()
+	conf.Set("equinix_metal_plan", "c3.small.x86")
+	_, err := getEquinixMetalPlan(conf, "token", "p2", "da")
+	if err == nil || !strings.Contains(err.Error(), "You are not authorized to view this project") {
+		t.Errorf("Expected the API error message, received %v", err)
+	}
+}
+
+func TestGetEquinixMetalOperatingSystem(t *testing.T) {
+	newTestEquinixMetalServer(t)
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("equinix_metal_operating_system", "centos_7")
+	operatingSystem, err := getEquinixMetalOperatingSystem(conf, "token", "c3.small.x86")
+	if err != nil || operatingSystem != "centos_7" {
+		t.Errorf("Expected operating system centos_7, received %q, %v", operatingSystem, err)
+	}
+
+	_, err = getEquinixMetalOperatingSystem(conf, "token", "m3.large.x86")
+	if err == nil || err.Error() != "Equinix Metal Operating System 'centos_7' can't be provisioned on plan 'm3.large.x86'." {
+		t.Errorf("Wrong error for an operating system of another plan, received %v", err)
+	}
+}
+
+func TestEquinixMetalPlanOptions(t *testing.T) {
+	plans := []equinixMetalPlan{
+		{Slug: "m3.large.x86", Line: "baremetal"},
+		{Slug: "c3.small.x86", Line: "baremetal"},
+	}
+	plans[0].Pricing.Hour = 3.1
+	plans[1].Pricing.Hour = 0.75
+	for i := range plans {
+		plans[i].AvailableInMetros = append(plans[i].AvailableInMetros, struct {
+			Code string `json:"code"`
+		}{"da"})
+	}
+
+	options := equinixMetalPlanOptions(plans, "da")
+	if len(options) != 2 || options[0].Value != "c3.small.x86" || options[1].Value != "m3.large.x86" {
+		t.Errorf("Wrong output, expected the cheapest plan first, received %v", options)
+	}
+}
@@ -0,0 +1,246 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// Project of the Ubuntu images nodes and managers are created from
+const gcpImageProject = "ubuntu-os-cloud"
+
+// Returns the zones of the region that are up.
+func getGCPZones(service *compute.Service, projectID, region string) ([]*compute.Zone, error) {
+	zones := []*compute.Zone{}
+	filter := fmt.Sprintf("region eq https://www.googleapis.com/compute/v1/projects/%s/regions/%s", projectID, region)
+	err := service.Zones.List(projectID).Filter(filter).Pages(context.Background(), func(page *compute.ZoneList) error {
+		for _, zone := range page.Items {
+			if zone.Status == "UP" {
+				zones = append(zones, zone)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(zones, func(i, j int) bool {
+		return zones[i].Name < zones[j].Name
+	})
+	return zones, nil
+}
+
+// Returns the machine types of the zone that aren't deprecated, smallest first.
+func getGCPMachineTypes(service *compute.Service, projectID, zone string) ([]*compute.MachineType, error) {
+	machineTypes := []*compute.MachineType{}
+	err := service.MachineTypes.List(projectID, zone).Pages(context.Background(), func(page *compute.MachineTypeList) error {
+		for _, machineType := range page.Items {
+			if machineType.Deprecated == nil || machineType.Deprecated.State == "" {
+				machineTypes = append(machineTypes, machineType)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortGCPMachineTypes(machineTypes)
+	return machineTypes, nil
+}
+
+func sortGCPMachineTypes(machineTypes []*compute.MachineType) {
+	sort.SliceStable(machineTypes, func(i, j int) bool {
+		if machineTypes[i].GuestCpus != machineTypes[j].GuestCpus {
+			return machineTypes[i].GuestCpus < machineTypes[j].GuestCpus
+		}
+		if machineTypes[i].MemoryMb != machineTypes[j].MemoryMb {
+			return machineTypes[i].MemoryMb < machineTypes[j].MemoryMb
+		}
+		return machineTypes[i].Name < machineTypes[j].Name
+	})
+}
+
+// Returns the images of the project that aren't deprecated, newest first.
+func getGCPImages(service *compute.Service, project string) ([]*compute.Image, error) {
+	images := []*compute.Image{}
+	err := service.Images.List(project).Pages(context.Background(), func(page *compute.ImageList) error {
+		for _, image := range page.Items {
+			if image.Deprecated == nil || image.Deprecated.State == "" {
+				images = append(images, image)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort images by created timestamp in reverse chronological order
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].CreationTimestamp > images[j].CreationTimestamp
+	})
+	return images, nil
+}
+
+// Returns the gcp_instance_zone, which the user picks from the zones of the region when it
+// isn't set.
+func getGCPInstanceZone(conf config.Config, service *compute.Service, projectID, region string) (string, error) {
+	zones, err := getGCPZones(service, projectID, region)
+	if err != nil {
+		return "", err
+	}
+
+	if conf.IsSet("gcp_instance_zone") {
+		selectedZone := conf.GetString("gcp_instance_zone")
+		for _, zone := range zones {
+			if zone.Name == selectedZone {
+				return selectedZone, nil
+			}
+		}
+		return "", fmt.Errorf("Selected GCP Instance Zone '%s' does not exist.", selectedZone)
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(errors.New("gcp_instance_zone must be specified"))
+	}
+
+	searcher := func(input string, index int) bool {
+		zone := zones[index]
+		name := strings.Replace(strings.ToLower(zone.Name), " ", "", -1)
+		input = strings.Replace(strings.ToLower(input), " ", "", -1)
+
+		return strings.Contains(name, input)
+	}
+
+	prompt := promptui.Select{
+		Label: "GCP Instance Zone",
+		Items: zones,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ .Name }}?",
+			Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
+			Inactive: `  {{ .Name }}`,
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Instance Zone:" | bold}} {{ .Name }}`, promptui.IconGood),
+		},
+		Searcher: searcher,
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return zones[i].Name, nil
+}
+
+// Returns the gcp_machine_type, which the user picks from the machine types of the zone when
+// it isn't set. The machine types are listed with their vCPUs and memory.
+func getGCPMachineType(conf config.Config, service *compute.Service, projectID, zone string) (string, error) {
+	machineTypes, err := getGCPMachineTypes(service, projectID, zone)
+	if err != nil {
+		return "", err
+	}
+
+	if conf.IsSet("gcp_machine_type") {
+		selectedMachineType := conf.GetString("gcp_machine_type")
+		for _, machineType := range machineTypes {
+			if machineType.Name == selectedMachineType {
+				return selectedMachineType, nil
+			}
+		}
+		return "", fmt.Errorf("Selected GCP Machine Type '%s' does not exist.", selectedMachineType)
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(errors.New("gcp_machine_type must be specified"))
+	}
+
+	searcher := func(input string, index int) bool {
+		machineType := machineTypes[index]
+		name := strings.Replace(strings.ToLower(machineType.Name), " ", "", -1)
+		input = strings.Replace(strings.ToLower(input), " ", "", -1)
+
+		return strings.Contains(name, input)
+	}
+
+	prompt := promptui.Select{
+		Label: "GCP Machine Type",
+		Items: machineTypes,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ .Name }}?",
+			Active:   fmt.Sprintf(`%s {{ .Name | underline }} ({{ .GuestCpus }} vCPUs, {{ .MemoryMb }} MB)`, promptui.IconSelect),
+			Inactive: `  {{ .Name }} ({{ .GuestCpus }} vCPUs, {{ .MemoryMb }} MB)`,
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Machine Type:" | bold}} {{ .Name }}`, promptui.IconGood),
+		},
+		Searcher: searcher,
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return machineTypes[i].Name, nil
+}
+
+// Returns the gcp_image, which the user picks from the Ubuntu images and the images of the
+// project, e.g. built by `triton-kubernetes build image`, when it isn't set.
+func getGCPImage(conf config.Config, service *compute.Service, projectID string) (string, error) {
+	images, err := getGCPImages(service, gcpImageProject)
+	if err != nil {
+		return "", err
+	}
+
+	projectImages, err := getGCPImages(service, projectID)
+	if err != nil {
+		return "", err
+	}
+	images = append(projectImages, images...)
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].CreationTimestamp > images[j].CreationTimestamp
+	})
+
+	if conf.IsSet("gcp_image") {
+		selectedImage := conf.GetString("gcp_image")
+		for _, image := range images {
+			if image.Name == selectedImage {
+				return selectedImage, nil
+			}
+		}
+		return "", fmt.Errorf("Selected GCP Image '%s' does not exist.", selectedImage)
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(errors.New("gcp_image must be specified"))
+	}
+
+	searcher := func(input string, index int) bool {
+		image := images[index]
+		name := strings.Replace(strings.ToLower(image.Name), " ", "", -1)
+		input = strings.Replace(strings.ToLower(input), " ", "", -1)
+
+		return strings.Contains(name, input)
+	}
+
+	prompt := promptui.Select{
+		Label: "GCP Image",
+		Items: images,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ .Name }}?",
+			Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
+			Inactive: `  {{ .Name }}`,
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Image:" | bold}} {{ .Name }}`, promptui.IconGood),
+		},
+		Searcher: searcher,
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return images[i].Name, nil
+}
@@ -0,0 +1,55 @@
+package create
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestGetGCPMachineTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/projects/dev/zones/us-east1-b/machineTypes") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Machine types are listed in two pages
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"items":[
+				{"name":"n1-standard-4","guestCpus":4,"memoryMb":15360},
+				{"name":"n1-highmem-2","guestCpus":2,"memoryMb":13312},
+				{"name":"n1-standard-2","guestCpus":2,"memoryMb":7680}
+			],"nextPageToken":"2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[
+			{"name":"f1-micro","guestCpus":1,"memoryMb":614},
+			{"name":"n1-legacy-1","guestCpus":1,"memoryMb":3840,"deprecated":{"state":"DEPRECATED"}}
+		]}`)
+	}))
+	defer server.Close()
+
+	service, err := compute.New(server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.BasePath = server.URL + "/compute/v1/projects/"
+
+	machineTypes, err := getGCPMachineTypes(service, "dev", "us-east1-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, machineType := range machineTypes {
+		names = append(names, machineType.Name)
+	}
+	expected := "f1-micro,n1-standard-2,n1-highmem-2,n1-standard-4"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected machine types %s, received %s", expected, strings.Join(names, ","))
+	}
+}
@@ -0,0 +1,185 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	defaultLibvirtURI         = "qemu:///system"
+	defaultLibvirtPoolName    = "default"
+	defaultLibvirtNetworkName = "default"
+	defaultLibvirtImageSource = "https://cloud-images.ubuntu.com/xenial/current/xenial-server-cloudimg-amd64-disk1.img"
+	defaultLibvirtSSHUser     = "ubuntu"
+)
+
+// The libvirt host the manager or cluster VMs are created on. The manager and clusters
+// can use different hosts.
+type libvirtTerraformConfig struct {
+	LibvirtURI         string `json:"libvirt_uri"`
+	LibvirtPoolName    string `json:"libvirt_pool_name"`
+	LibvirtNetworkName string `json:"libvirt_network_name"`
+	LibvirtImageSource string `json:"libvirt_image_source"`
+}
+
+func getLibvirtTerraformConfig(conf config.Config) (libvirtTerraformConfig, error) {
+	cfg := libvirtTerraformConfig{}
+
+	// Libvirt URI
+	uri, err := promptForLibvirtValue(conf, "libvirt_uri", "Libvirt URI", defaultLibvirtURI)
+	if err != nil {
+		return libvirtTerraformConfig{}, err
+	}
+	err = validateLibvirtURI(uri)
+	if err != nil {
+		return libvirtTerraformConfig{}, err
+	}
+	cfg.LibvirtURI = uri
+
+	// Fail before anything is written to the state when libvirtd can't be reached
+	err = libvirtCredentials{URI: cfg.LibvirtURI}.Validate()
+	if err != nil {
+		return libvirtTerraformConfig{}, err
+	}
+
+	// Libvirt Storage Pool
+	cfg.LibvirtPoolName, err = promptForLibvirtValue(conf, "libvirt_pool_name", "Libvirt Storage Pool", defaultLibvirtPoolName)
+	if err != nil {
+		return libvirtTerraformConfig{}, err
+	}
+
+	// Libvirt Network
+	cfg.LibvirtNetworkName, err = promptForLibvirtValue(conf, "libvirt_network_name", "Libvirt Network", defaultLibvirtNetworkName)
+	if err != nil {
+		return libvirtTerraformConfig{}, err
+	}
+
+	// Libvirt Image
+	cfg.LibvirtImageSource, err = promptForLibvirtValue(conf, "libvirt_image_source", "Cloud Image URL or Path", defaultLibvirtImageSource)
+	if err != nil {
+		return libvirtTerraformConfig{}, err
+	}
+
+	// Images on this machine are uploaded to the libvirt host by terraform
+	imageURL, err := url.Parse(cfg.LibvirtImageSource)
+	if err != nil || imageURL.Scheme == "" {
+		expandedImagePath, err := homedir.Expand(cfg.LibvirtImageSource)
+		if err != nil {
+			return libvirtTerraformConfig{}, err
+		}
+		_, err = os.Stat(expandedImagePath)
+		if err != nil {
+			return libvirtTerraformConfig{}, fmt.Errorf("Unable to read libvirt_image_source '%s': %s", cfg.LibvirtImageSource, err)
+		}
+		cfg.LibvirtImageSource = expandedImagePath
+	}
+
+	return cfg, nil
+}
+
+// Returns the user cloud-init creates on the VMs and the path of its private key. The public
+// key must be next to the private key, with a .pub extension.
+func getLibvirtSSHConfig(conf config.Config) (string, string, error) {
+	sshUser, err := promptForLibvirtValue(conf, "libvirt_ssh_user", "SSH User", defaultLibvirtSSHUser)
+	if err != nil {
+		return "", "", err
+	}
+
+	rawKeyPath := ""
+	if conf.IsSet("libvirt_key_path") {
+		rawKeyPath = conf.GetString("libvirt_key_path")
+	} else if conf.GetBool("non-interactive") {
+		return "", "", util.ConfigError(errors.New("libvirt_key_path must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Private Key Path",
+			Validate: func(input string) error {
+				expandedPath, err := homedir.Expand(input)
+				if err != nil {
+					return err
+				}
+
+				_, err = os.Stat(expandedPath + ".pub")
+				if err != nil {
+					if os.IsNotExist(err) {
+						return errors.New("Public key not found")
+					}
+				}
+				return nil
+			},
+			Default: "~/.ssh/id_rsa",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return "", "", err
+		}
+		rawKeyPath = result
+	}
+
+	keyPath, err := homedir.Expand(rawKeyPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = os.Stat(keyPath + ".pub")
+	if err != nil {
+		return "", "", fmt.Errorf("Public key of libvirt_key_path '%s' not found, expected it at '%s.pub'", rawKeyPath, rawKeyPath)
+	}
+
+	return sshUser, keyPath, nil
+}
+
+// Returns the given VM size setting, which must be a number greater than 0.
+func getLibvirtVMSize(conf config.Config, key, label, defaultValue string) (string, error) {
+	value, err := promptForLibvirtValue(conf, key, label, defaultValue)
+	if err != nil {
+		return "", err
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil || num <= 0 {
+		return "", errors.New(key + " must be a number greater than 0")
+	}
+
+	return value, nil
+}
+
+// Libvirt URIs are e.g. qemu:///system for this machine and qemu+ssh://user@host/system for
+// a remote host.
+func validateLibvirtURI(uri string) error {
+	parsedURI, err := url.Parse(uri)
+	if err != nil || parsedURI.Scheme == "" {
+		return util.ConfigError(fmt.Errorf("Invalid libvirt_uri '%s', expected e.g. '%s' or 'qemu+ssh://user@host/system'", uri, defaultLibvirtURI))
+	}
+	return nil
+}
+
+func promptForLibvirtValue(conf config.Config, key, label, defaultValue string) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return defaultValue, nil
+	}
+
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: defaultValue,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+
+	return prompt.Run()
+}
@@ -0,0 +1,60 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateLibvirtURI(t *testing.T) {
+	for _, uri := range []string{"qemu:///system", "qemu:///session", "qemu+ssh://root@kvm-host/system"} {
+		err := validateLibvirtURI(uri)
+		if err != nil {
+			t.Errorf("Expected %q to be valid, received %v", uri, err)
+		}
+	}
+
+	for _, uri := range []string{"", "kvm-host", "/var/run/libvirt/libvirt-sock"} {
+		err := validateLibvirtURI(uri)
+		if err == nil {
+			t.Errorf("Expected %q to be invalid", uri)
+		}
+	}
+}
+
+func TestLibvirtSSHConfigRequiresPublicKey(t *testing.T) {
+	conf := config.New()
+	dir, err := ioutil.TempDir("", "libvirt-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyPath := filepath.Join(dir, "id_rsa")
+	err = ioutil.WriteFile(keyPath, []byte("private"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Set("non-interactive", true)
+	conf.Set("libvirt_key_path", keyPath)
+
+	_, _, err = getLibvirtSSHConfig(conf)
+	if err == nil {
+		t.Error("Expected an error for a private key without a public key")
+	}
+
+	err = ioutil.WriteFile(keyPath+".pub", []byte("public"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshUser, expandedKeyPath, err := getLibvirtSSHConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sshUser != defaultLibvirtSSHUser || expandedKeyPath != keyPath {
+		t.Errorf("Wrong output, expected (%q, %q), received (%q, %q)", defaultLibvirtSSHUser, keyPath, sshUser, expandedKeyPath)
+	}
+}
@@ -6,11 +6,11 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/shell"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 type baseManagerTerraformConfig struct {
@@ -24,20 +24,25 @@ type baseManagerTerraformConfig struct {
 	RancherRegistry         string `json:"rancher_registry,omitempty"`
 	RancherRegistryUsername string `json:"rancher_registry_username,omitempty"`
 	RancherRegistryPassword string `json:"rancher_registry_password,omitempty"`
+
+	RancherExternalURL    string `json:"rancher_external_url,omitempty"`
+	RancherHTTPSPort      string `json:"rancher_https_port,omitempty"`
+	RancherHTTPPort       string `json:"rancher_http_port,omitempty"`
+	RancherTLSTermination string `json:"rancher_tls_termination,omitempty"`
 }
 
-func NewManager(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func NewManager(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 
 	selectedCloudProvider := ""
-	if viper.IsSet("manager_cloud_provider") {
-		selectedCloudProvider = viper.GetString("manager_cloud_provider")
+	if conf.IsSet("manager_cloud_provider") {
+		selectedCloudProvider = conf.GetString("manager_cloud_provider")
 	} else if nonInteractiveMode {
-		return errors.New("manager_cloud_provider must be specified")
+		return util.ConfigError(errors.New("manager_cloud_provider must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Create Manager in which Cloud Provider",
-			Items: []string{"Triton", "AWS", "GCP", "Azure", "BareMetal"},
+			Items: []string{"Triton", "AWS", "GCP", "Azure", "DigitalOcean", "EquinixMetal", "OpenStack", "BareMetal", "Proxmox", "Nutanix", "Libvirt"},
 			Templates: &promptui.SelectTemplates{
 				Label:    "{{ . }}?",
 				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
@@ -56,10 +61,10 @@ func NewManager(remoteBackend backend.Backend) error {
 
 	// Name
 	name := ""
-	if viper.IsSet("name") {
-		name = viper.GetString("name")
+	if conf.IsSet("name") {
+		name = conf.GetString("name")
 	} else if nonInteractiveMode {
-		return errors.New("name must be specified")
+		return util.ConfigError(errors.New("name must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Cluster Manager Name",
@@ -80,7 +85,7 @@ func NewManager(remoteBackend backend.Backend) error {
 	}
 
 	if name == "" {
-		return errors.New("Invalid Cluster Manager Name")
+		return util.ConfigError(errors.New("Invalid Cluster Manager Name"))
 	}
 
 	// Validate that a cluster manager with the same name doesn't already exist.
@@ -100,22 +105,45 @@ func NewManager(remoteBackend backend.Backend) error {
 		return fmt.Errorf("A Cluster Manager with the name '%s' already exists.", name)
 	}
 
+	err = checkManagerHostCountSupported(conf, selectedCloudProvider)
+	if err != nil {
+		return err
+	}
+
 	currentState, err := remoteBackend.State(name)
 	if err != nil {
 		return err
 	}
 
+	tfVars, err := loadTFVarsFile(conf, "tfvars_file")
+	if err != nil {
+		return err
+	}
+	defer tfVars.restore()
+
 	switch selectedCloudProvider {
 	case "triton":
-		err = newTritonManager(currentState, name)
+		err = newTritonManager(conf, currentState, name)
 	case "aws":
-		err = newAWSManager(currentState, name)
+		err = newAWSManager(conf, currentState, name)
 	case "gcp":
-		err = newGCPManager(currentState, name)
+		err = newGCPManager(conf, currentState, name)
 	case "azure":
-		err = newAzureManager(currentState, name)
+		err = newAzureManager(conf, currentState, name)
+	case "digitalocean":
+		err = newDigitalOceanManager(conf, currentState, name)
+	case "equinixmetal":
+		err = newEquinixMetalManager(conf, currentState, name)
+	case "openstack":
+		err = newOpenStackManager(conf, currentState, name)
 	case "baremetal":
-		err = newBareMetalManager(currentState, name)
+		err = newBareMetalManager(conf, currentState, name)
+	case "proxmox":
+		err = newProxmoxManager(conf, currentState, name)
+	case "nutanix":
+		err = newNutanixManager(conf, currentState, name)
+	case "libvirt":
+		err = newLibvirtManager(conf, currentState, name)
 	// case "vsphere":
 	default:
 		return fmt.Errorf("Unsupported cloud provider '%s', cannot create manager", selectedCloudProvider)
@@ -124,6 +152,25 @@ func NewManager(remoteBackend backend.Backend) error {
 		return err
 	}
 
+	err = tfVars.merge(currentState, []string{"cluster-manager"})
+	if err != nil {
+		return err
+	}
+
+	// The hosts of an HA manager join its k3s cluster with the token, only its encrypted form is kept in the state
+	haToken := currentState.Get("module.cluster-manager.manager_ha_token")
+	if haToken != "" {
+		encryptedToken, err := util.EncryptSecret(conf, haToken)
+		if err != nil {
+			return err
+		}
+
+		err = currentState.SetManagerHAToken(encryptedToken)
+		if err != nil {
+			return err
+		}
+	}
+
 	if !nonInteractiveMode {
 		label := "Proceed with the manager creation"
 		selected := "Proceed"
@@ -139,11 +186,18 @@ func NewManager(remoteBackend backend.Backend) error {
 
 	currentState.SetTerraformBackendConfig(remoteBackend.StateTerraformConfig(name))
 
-	err = shell.RunTerraformApplyWithState(currentState)
+	// Block on configurations that violate the user's policies
+	err = checkPolicies(conf, currentState)
 	if err != nil {
 		return err
 	}
 
+	err = shell.RunTerraformApplyWithState(conf, currentState, []string{})
+	if err != nil {
+		return recordApplyCheckpoint(remoteBackend, currentState, fmt.Sprintf("create manager %s", name), nil, err)
+	}
+	currentState.ClearCheckpoint()
+
 	// After terraform succeeds, commit state
 	err = remoteBackend.PersistState(currentState)
 	if err != nil {
@@ -153,18 +207,18 @@ func NewManager(remoteBackend backend.Backend) error {
 	return nil
 }
 
-func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManagerTerraformConfig, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func getBaseManagerTerraformConfig(conf config.Config, terraformModulePath, name string) (baseManagerTerraformConfig, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	cfg := baseManagerTerraformConfig{}
 
 	baseSource := defaultSourceURL
-	if viper.IsSet("source_url") {
-		baseSource = viper.GetString("source_url")
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
 	}
 
 	baseSourceRef := defaultSourceRef
-	if viper.IsSet("source_ref") {
-		baseSourceRef = viper.GetString("source_ref")
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
 	}
 
 	// Module Source location e.g. github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher?ref=master
@@ -173,8 +227,8 @@ func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManage
 	cfg.Name = name
 
 	// Rancher Docker Registry
-	if viper.IsSet("private_registry") {
-		cfg.RancherRegistry = viper.GetString("private_registry")
+	if conf.IsSet("private_registry") {
+		cfg.RancherRegistry = conf.GetString("private_registry")
 	} else if !nonInteractiveMode {
 		prompt := promptui.Prompt{
 			Label:   "Private Registry",
@@ -194,10 +248,10 @@ func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManage
 	// Ask for rancher registry username/password only if rancher registry is given
 	if cfg.RancherRegistry != "" {
 		// Rancher Registry Username
-		if viper.IsSet("private_registry_username") {
-			cfg.RancherRegistryUsername = viper.GetString("private_registry_username")
+		if conf.IsSet("private_registry_username") {
+			cfg.RancherRegistryUsername = conf.GetString("private_registry_username")
 		} else if nonInteractiveMode {
-			return baseManagerTerraformConfig{}, errors.New("private_registry_username must be specified")
+			return baseManagerTerraformConfig{}, util.ConfigError(errors.New("private_registry_username must be specified"))
 		} else {
 			prompt := promptui.Prompt{
 				Label: "Private Registry Username",
@@ -211,10 +265,10 @@ func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManage
 		}
 
 		// Rancher Registry Password
-		if viper.IsSet("private_registry_password") {
-			cfg.RancherRegistryPassword = viper.GetString("private_registry_password")
+		if conf.IsSet("private_registry_password") {
+			cfg.RancherRegistryPassword = conf.GetString("private_registry_password")
 		} else if nonInteractiveMode {
-			return baseManagerTerraformConfig{}, errors.New("private_registry_password must be specified")
+			return baseManagerTerraformConfig{}, util.ConfigError(errors.New("private_registry_password must be specified"))
 		} else {
 			prompt := promptui.Prompt{
 				Label: "Private Registry Password",
@@ -230,8 +284,8 @@ func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManage
 	}
 
 	// Rancher Server Image
-	if viper.IsSet("rancher_server_image") {
-		cfg.RancherServerImage = viper.GetString("rancher_server_image")
+	if conf.IsSet("rancher_server_image") {
+		cfg.RancherServerImage = conf.GetString("rancher_server_image")
 	} else if !nonInteractiveMode {
 		prompt := promptui.Prompt{
 			Label:   "Rancher Server Image",
@@ -249,8 +303,8 @@ func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManage
 	}
 
 	// Rancher Agent Image
-	if viper.IsSet("rancher_agent_image") {
-		cfg.RancherAgentImage = viper.GetString("rancher_agent_image")
+	if conf.IsSet("rancher_agent_image") {
+		cfg.RancherAgentImage = conf.GetString("rancher_agent_image")
 	} else if !nonInteractiveMode {
 		prompt := promptui.Prompt{
 			Label:   "Rancher Agent Image",
@@ -268,10 +322,10 @@ func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManage
 	}
 
 	// Rancher Admin Password
-	if viper.IsSet("rancher_admin_password") {
-		cfg.RancherAdminPassword = viper.GetString("rancher_admin_password")
+	if conf.IsSet("rancher_admin_password") {
+		cfg.RancherAdminPassword = conf.GetString("rancher_admin_password")
 	} else if nonInteractiveMode {
-		return baseManagerTerraformConfig{}, errors.New("UI Admin Password must be specified")
+		return baseManagerTerraformConfig{}, util.ConfigError(errors.New("UI Admin Password must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Set UI Admin Password",
@@ -293,7 +347,12 @@ func getBaseManagerTerraformConfig(terraformModulePath, name string) (baseManage
 	}
 
 	if cfg.RancherAdminPassword == "" {
-		return baseManagerTerraformConfig{}, errors.New("Invalid UI Admin password")
+		return baseManagerTerraformConfig{}, util.ConfigError(errors.New("Invalid UI Admin password"))
+	}
+
+	err := setRancherEndpointConfig(conf, &cfg)
+	if err != nil {
+		return baseManagerTerraformConfig{}, err
 	}
 
 	return cfg, nil
@@ -8,7 +8,9 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -17,7 +19,6 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -31,6 +32,7 @@ type awsManagerTerraformConfig struct {
 
 	AWSAccessKey string `json:"aws_access_key"`
 	AWSSecretKey string `json:"aws_secret_key"`
+	awsRoleConfig
 
 	AWSRegion         string `json:"aws_region"`
 	AWSVPCCIDR        string `json:"aws_vpc_cidr"`
@@ -42,12 +44,18 @@ type awsManagerTerraformConfig struct {
 
 	AWSAMIID        string `json:"aws_ami_id"`
 	AWSInstanceType string `json:"aws_instance_type"`
+
+	managerHAConfig
+
+	// With more than one host, each host's zone and the CIDR of its subnet
+	AWSHAAvailabilityZones string `json:"aws_ha_availability_zones,omitempty"`
+	AWSHASubnetCIDRs       string `json:"aws_ha_subnet_cidrs,omitempty"`
 }
 
-func newAWSManager(currentState state.State, name string) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func newAWSManager(conf config.Config, currentState state.State, name string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 
-	baseConfig, err := getBaseManagerTerraformConfig(awsRancherTerraformModulePath, name)
+	baseConfig, err := getBaseManagerTerraformConfig(conf, awsRancherTerraformModulePath, name)
 	if err != nil {
 		return err
 	}
@@ -57,10 +65,10 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 
 	// AWS Access Key
-	if viper.IsSet("aws_access_key") {
-		cfg.AWSAccessKey = viper.GetString("aws_access_key")
+	if conf.IsSet("aws_access_key") {
+		cfg.AWSAccessKey = conf.GetString("aws_access_key")
 	} else if nonInteractiveMode {
-		return errors.New("aws_access_key must be specified")
+		return util.ConfigError(errors.New("aws_access_key must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "AWS Access Key",
@@ -80,10 +88,10 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 
 	// AWS Secret Key
-	if viper.IsSet("aws_secret_key") {
-		cfg.AWSSecretKey = viper.GetString("aws_secret_key")
+	if conf.IsSet("aws_secret_key") {
+		cfg.AWSSecretKey = conf.GetString("aws_secret_key")
 	} else if nonInteractiveMode {
-		return errors.New("aws_secret_key must be specified")
+		return util.ConfigError(errors.New("aws_secret_key must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "AWS Secret Key",
@@ -102,8 +110,22 @@ func newAWSManager(currentState state.State, name string) error {
 		cfg.AWSSecretKey = result
 	}
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	userCreds := awsCredentials{AccessKey: cfg.AWSAccessKey, SecretKey: cfg.AWSSecretKey}
+	err = userCreds.Validate()
+	if err != nil {
+		return err
+	}
+
+	// With aws_role_arn, terraform uses the temporary credentials of the role
+	roleCreds, role, err := getAWSRoleConfig(conf, userCreds)
+	if err != nil {
+		return err
+	}
+	cfg.awsRoleConfig = role
+
 	// We now have enough information to init an aws client
-	creds := credentials.NewStaticCredentials(cfg.AWSAccessKey, cfg.AWSSecretKey, "")
+	creds := credentials.NewStaticCredentials(roleCreds.AccessKey, roleCreds.SecretKey, roleCreds.SessionToken)
 
 	// Using us-west-1 region by default. The configuration needs a region set to
 	// get all regions available to the aws user.
@@ -122,8 +144,8 @@ func newAWSManager(currentState state.State, name string) error {
 	regions := regionsResult.Regions
 
 	// AWS Region
-	if viper.IsSet("aws_region") {
-		cfg.AWSRegion = viper.GetString("aws_region")
+	if conf.IsSet("aws_region") {
+		cfg.AWSRegion = conf.GetString("aws_region")
 		// Validate the AWS Region
 		found := false
 		for _, region := range regions {
@@ -137,7 +159,7 @@ func newAWSManager(currentState state.State, name string) error {
 			return fmt.Errorf("Selected AWS Region '%s' does not exist.", cfg.AWSRegion)
 		}
 	} else if nonInteractiveMode {
-		return errors.New("aws_region must be specified")
+		return util.ConfigError(errors.New("aws_region must be specified"))
 	} else {
 		// Building an array of strings that will be given to the SelectPrompt.
 		// The SelectTemplate has problems displaying struct fields that are string pointers.
@@ -189,17 +211,17 @@ func newAWSManager(currentState state.State, name string) error {
 	// AWS Key
 	// If either aws_key_name or aws_public_key_path is set use it
 	// Otherwise ask the user if they'd like to upload a key or use an existing key
-	if viper.IsSet("aws_key_name") {
-		cfg.AWSKeyName = viper.GetString("aws_key_name")
-		if viper.IsSet("aws_public_key_path") {
-			expandedAWSPublicKeyPath, err := homedir.Expand(viper.GetString("aws_public_key_path"))
+	if conf.IsSet("aws_key_name") {
+		cfg.AWSKeyName = conf.GetString("aws_key_name")
+		if conf.IsSet("aws_public_key_path") {
+			expandedAWSPublicKeyPath, err := homedir.Expand(conf.GetString("aws_public_key_path"))
 			if err != nil {
 				return err
 			}
 			cfg.AWSPublicKeyPath = expandedAWSPublicKeyPath
 		}
 	} else if nonInteractiveMode {
-		return errors.New("aws_key_name must be specified")
+		return util.ConfigError(errors.New("aws_key_name must be specified"))
 	} else {
 		// List all available aws keys
 		input := ec2.DescribeKeyPairsInput{}
@@ -286,10 +308,10 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 
 	rawAWSPrivateKeyPath := ""
-	if viper.IsSet("aws_private_key_path") {
-		rawAWSPrivateKeyPath = viper.GetString("aws_private_key_path")
+	if conf.IsSet("aws_private_key_path") {
+		rawAWSPrivateKeyPath = conf.GetString("aws_private_key_path")
 	} else if nonInteractiveMode {
-		return errors.New("aws_private_key_path must be specified")
+		return util.ConfigError(errors.New("aws_private_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "AWS Private Key Path",
@@ -323,10 +345,10 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 	cfg.AWSPrivateKeyPath = expandedAWSPrivateKeyPath
 
-	if viper.IsSet("aws_ssh_user") {
-		cfg.AWSSSHUser = viper.GetString("aws_ssh_user")
+	if conf.IsSet("aws_ssh_user") {
+		cfg.AWSSSHUser = conf.GetString("aws_ssh_user")
 	} else if nonInteractiveMode {
-		return errors.New("aws_ssh_user must be specified")
+		return util.ConfigError(errors.New("aws_ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "AWS SSH User",
@@ -341,10 +363,10 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 
 	// AWS VPC CIDR
-	if viper.IsSet("aws_vpc_cidr") {
-		cfg.AWSVPCCIDR = viper.GetString("aws_vpc_cidr")
+	if conf.IsSet("aws_vpc_cidr") {
+		cfg.AWSVPCCIDR = conf.GetString("aws_vpc_cidr")
 	} else if nonInteractiveMode {
-		return errors.New("aws_vpc_cidr must be specified")
+		return util.ConfigError(errors.New("aws_vpc_cidr must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "AWS VPC CIDR",
@@ -373,10 +395,10 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 
 	// AWS Subnet CIDR
-	if viper.IsSet("aws_subnet_cidr") {
-		cfg.AWSSubnetCIDR = viper.GetString("aws_subnet_cidr")
+	if conf.IsSet("aws_subnet_cidr") {
+		cfg.AWSSubnetCIDR = conf.GetString("aws_subnet_cidr")
 	} else if nonInteractiveMode {
-		return errors.New("aws_subnet_cidr must be specified")
+		return util.ConfigError(errors.New("aws_subnet_cidr must be specified"))
 	} else {
 		// Parsing VPC CIDR to prepare for subnet validation
 		_, vpcIPNet, err := net.ParseCIDR(cfg.AWSVPCCIDR)
@@ -415,12 +437,12 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 
 	// AWS AMI ID
-	if viper.IsSet("aws_ami_id") {
-		cfg.AWSAMIID = viper.GetString("aws_ami_id")
+	if conf.IsSet("aws_ami_id") {
+		cfg.AWSAMIID = conf.GetString("aws_ami_id")
 
 		// TODO: Verify aws_ami_id
 	} else if nonInteractiveMode {
-		return errors.New("aws_ami_id must be specified")
+		return util.ConfigError(errors.New("aws_ami_id must be specified"))
 	} else {
 		// TODO: Ask the user for a search term
 		describeImagesInput := ec2.DescribeImagesInput{
@@ -484,10 +506,10 @@ func newAWSManager(currentState state.State, name string) error {
 	}
 
 	// AWS Instance Type
-	if viper.IsSet("aws_instance_type") {
-		cfg.AWSInstanceType = viper.GetString("aws_instance_type")
+	if conf.IsSet("aws_instance_type") {
+		cfg.AWSInstanceType = conf.GetString("aws_instance_type")
 	} else if nonInteractiveMode {
-		return errors.New("aws_instance_type must be specified")
+		return util.ConfigError(errors.New("aws_instance_type must be specified"))
 	} else {
 		// AWS doesn't have an API to get a list of available instance types
 		// Ask the user to free form input it
@@ -509,7 +531,24 @@ func newAWSManager(currentState state.State, name string) error {
 		cfg.AWSInstanceType = result
 	}
 
+	// Rancher on one host, or three behind a load balancer
+	cfg.managerHAConfig, err = getManagerHAConfig(conf, baseConfig, maxAWSHAManagerNameLength)
+	if err != nil {
+		return err
+	}
+	if cfg.ManagerHostCount > 1 {
+		availableZones, err := getAWSAvailableZones(ec2Client)
+		if err != nil {
+			return err
+		}
+		err = getAWSManagerHAZonesConfig(conf, availableZones, &cfg)
+		if err != nil {
+			return err
+		}
+	}
+
 	currentState.SetManager(&cfg)
 
-	return nil
+	// The manager's module references the role's temporary credentials
+	return setAWSRoleKeys(conf, currentState, "cluster-manager", cfg.AWSAccessKey, cfg.AWSSecretKey, cfg.awsRoleConfig)
 }
@@ -6,16 +6,14 @@ import (
 	"os"
 	"strings"
 
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 	homedir "github.com/mitchellh/go-homedir"
 
-	"github.com/Azure/azure-sdk-for-go/arm/compute"
-	"github.com/Azure/azure-sdk-for-go/arm/resources/subscriptions"
-	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -44,12 +42,14 @@ type azureManagerTerraformConfig struct {
 	AzureSSHUser        string `json:"azure_ssh_user"`
 	AzurePublicKeyPath  string `json:"azure_public_key_path"`
 	AzurePrivateKeyPath string `json:"azure_private_key_path"`
+
+	managerHAConfig
 }
 
-func newAzureManager(currentState state.State, name string) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func newAzureManager(conf config.Config, currentState state.State, name string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 
-	baseConfig, err := getBaseManagerTerraformConfig(azureRancherTerraformModulePath, name)
+	baseConfig, err := getBaseManagerTerraformConfig(conf, azureRancherTerraformModulePath, name)
 	if err != nil {
 		return err
 	}
@@ -59,10 +59,10 @@ func newAzureManager(currentState state.State, name string) error {
 	}
 
 	// Azure Subscription ID
-	if viper.IsSet("azure_subscription_id") {
-		cfg.AzureSubscriptionID = viper.GetString("azure_subscription_id")
+	if conf.IsSet("azure_subscription_id") {
+		cfg.AzureSubscriptionID = conf.GetString("azure_subscription_id")
 	} else if nonInteractiveMode {
-		return errors.New("azure_subscription_id must be specified")
+		return util.ConfigError(errors.New("azure_subscription_id must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Subscription ID",
@@ -82,10 +82,10 @@ func newAzureManager(currentState state.State, name string) error {
 	}
 
 	// Azure Client ID
-	if viper.IsSet("azure_client_id") {
-		cfg.AzureClientID = viper.GetString("azure_client_id")
+	if conf.IsSet("azure_client_id") {
+		cfg.AzureClientID = conf.GetString("azure_client_id")
 	} else if nonInteractiveMode {
-		return errors.New("azure_client_id must be specified")
+		return util.ConfigError(errors.New("azure_client_id must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Client ID",
@@ -105,10 +105,10 @@ func newAzureManager(currentState state.State, name string) error {
 	}
 
 	// Azure Client Secret
-	if viper.IsSet("azure_client_secret") {
-		cfg.AzureClientSecret = viper.GetString("azure_client_secret")
+	if conf.IsSet("azure_client_secret") {
+		cfg.AzureClientSecret = conf.GetString("azure_client_secret")
 	} else if nonInteractiveMode {
-		return errors.New("azure_client_secret must be specified")
+		return util.ConfigError(errors.New("azure_client_secret must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Client Secret",
@@ -128,10 +128,10 @@ func newAzureManager(currentState state.State, name string) error {
 	}
 
 	// Azure Tenant ID
-	if viper.IsSet("azure_tenant_id") {
-		cfg.AzureTenantID = viper.GetString("azure_tenant_id")
+	if conf.IsSet("azure_tenant_id") {
+		cfg.AzureTenantID = conf.GetString("azure_tenant_id")
 	} else if nonInteractiveMode {
-		return errors.New("azure_tenant_id must be specified")
+		return util.ConfigError(errors.New("azure_tenant_id must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Tenant ID",
@@ -151,10 +151,10 @@ func newAzureManager(currentState state.State, name string) error {
 	}
 
 	// Azure Environment
-	if viper.IsSet("azure_environment") {
-		cfg.AzureEnvironment = viper.GetString("azure_environment")
+	if conf.IsSet("azure_environment") {
+		cfg.AzureEnvironment = conf.GetString("azure_environment")
 	} else if nonInteractiveMode {
-		return errors.New("azure_environment must be specified")
+		return util.ConfigError(errors.New("azure_environment must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Azure Environment",
@@ -177,7 +177,7 @@ func newAzureManager(currentState state.State, name string) error {
 
 	// Verify selected azure environment is valid
 	if cfg.AzureEnvironment != "public" && cfg.AzureEnvironment != "government" && cfg.AzureEnvironment != "german" && cfg.AzureEnvironment != "china" {
-		return fmt.Errorf("Invalid azure_environment '%s', must be one of the following: 'public', 'government', 'german', or 'china'", cfg.AzureEnvironment)
+		return util.ConfigError(fmt.Errorf("Invalid azure_environment '%s', must be one of the following: 'public', 'government', 'german', or 'china'", cfg.AzureEnvironment))
 	}
 
 	// Terraform expects public/government/german/china for azure environment
@@ -187,6 +187,18 @@ func newAzureManager(currentState state.State, name string) error {
 		return err
 	}
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = azureCredentials{
+		Environment:    azureEnv,
+		SubscriptionID: cfg.AzureSubscriptionID,
+		TenantID:       cfg.AzureTenantID,
+		ClientID:       cfg.AzureClientID,
+		ClientSecret:   cfg.AzureClientSecret,
+	}.Validate()
+	if err != nil {
+		return err
+	}
+
 	// We now have enough information to init an azure client
 	oauthConfig, err := adal.NewOAuthConfig(azureEnv.ActiveDirectoryEndpoint, cfg.AzureTenantID)
 	if err != nil {
@@ -198,22 +210,14 @@ func newAzureManager(currentState state.State, name string) error {
 		return err
 	}
 
-	azureGroupClient := subscriptions.NewGroupClientWithBaseURI(azureEnv.ResourceManagerEndpoint)
-	azureGroupClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
-
-	azureRawLocations, err := azureGroupClient.ListLocations(cfg.AzureSubscriptionID)
+	azureLocations, err := getAzureLocations(azureEnv, azureSPT, cfg.AzureSubscriptionID)
 	if err != nil {
 		return err
 	}
 
-	azureLocations := []string{}
-	for _, loc := range *azureRawLocations.Value {
-		azureLocations = append(azureLocations, *loc.DisplayName)
-	}
-
 	// Azure Location
-	if viper.IsSet("azure_location") {
-		cfg.AzureLocation = viper.GetString("azure_location")
+	if conf.IsSet("azure_location") {
+		cfg.AzureLocation = conf.GetString("azure_location")
 
 		// Verify selected azure location exists
 		found := false
@@ -224,10 +228,10 @@ func newAzureManager(currentState state.State, name string) error {
 			}
 		}
 		if !found {
-			return fmt.Errorf("Invalid azure_location '%s', must be one of the following: %s", cfg.AzureLocation, strings.Join(azureLocations, ", "))
+			return util.ConfigError(fmt.Errorf("Invalid azure_location '%s', must be one of the following: %s", cfg.AzureLocation, strings.Join(azureLocations, ", ")))
 		}
 	} else if nonInteractiveMode {
-		return errors.New("azure_location must be specified")
+		return util.ConfigError(errors.New("azure_location must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Azure Location",
@@ -253,22 +257,19 @@ func newAzureManager(currentState state.State, name string) error {
 		cfg.AzureLocation = value
 	}
 
-	azureVMSizesClient := compute.NewVirtualMachineSizesClientWithBaseURI(azureEnv.ResourceManagerEndpoint, cfg.AzureSubscriptionID)
-	azureVMSizesClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
-
-	azureRawVMSizes, err := azureVMSizesClient.List(strings.Replace(strings.ToLower(cfg.AzureLocation), " ", "", -1))
+	err = getAzureManagerResourceGroup(conf, &cfg, azureEnv, azureSPT)
 	if err != nil {
 		return err
 	}
 
-	azureVMSizes := []string{}
-	for _, size := range *azureRawVMSizes.Value {
-		azureVMSizes = append(azureVMSizes, *size.Name)
+	azureVMSizes, err := getAzureVMSizes(azureEnv, azureSPT, cfg.AzureSubscriptionID, cfg.AzureLocation, conf.GetBool("azure_size_within_quota"))
+	if err != nil {
+		return err
 	}
 
 	// Azure Size
-	if viper.IsSet("azure_size") {
-		cfg.AzureSize = viper.GetString("azure_size")
+	if conf.IsSet("azure_size") {
+		cfg.AzureSize = conf.GetString("azure_size")
 
 		// Verify selected azure size exists
 		found := false
@@ -279,10 +280,10 @@ func newAzureManager(currentState state.State, name string) error {
 			}
 		}
 		if !found {
-			return fmt.Errorf("Invalid azure_size '%s', must be one of the following: %s", cfg.AzureSize, strings.Join(azureVMSizes, ", "))
+			return util.ConfigError(fmt.Errorf("Invalid azure_size '%s', must be one of the following: %s", cfg.AzureSize, strings.Join(azureVMSizes, ", ")))
 		}
 	} else if nonInteractiveMode {
-		return errors.New("azure_size must be specified")
+		return util.ConfigError(errors.New("azure_size must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Azure Size",
@@ -308,28 +309,20 @@ func newAzureManager(currentState state.State, name string) error {
 		cfg.AzureSize = value
 	}
 
-	azureImagesClient := compute.NewVirtualMachineImagesClientWithBaseURI(azureEnv.ResourceManagerEndpoint, cfg.AzureSubscriptionID)
-	azureImagesClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
-
-	// imageResults, err := azureImagesClient.List("westus", "Canonical", "UbuntuServer", "16.04-LTS", "", nil, "")
-	// if err != nil {
-	// 	return []string{}, err
-	// }
-
-	// for _, x := range *imageResults.Value {
-	// 	fmt.Println(*x.Name)
-	// }
-
-	// cfg.AzureImagePublisher = "Canonical"
-	// cfg.AzureImageOffer = "UbuntuServer"
-	// cfg.AzureImageSKU = "16.04-LTS"
-	// cfg.AzureImageVersion = ""
+	image, err := getAzureImage(conf, azureEnv, azureSPT, cfg.AzureSubscriptionID, cfg.AzureLocation)
+	if err != nil {
+		return err
+	}
+	cfg.AzureImagePublisher = image.Publisher
+	cfg.AzureImageOffer = image.Offer
+	cfg.AzureImageSKU = image.SKU
+	cfg.AzureImageVersion = image.Version
 
 	// Azure SSH User
-	if viper.IsSet("azure_ssh_user") {
-		cfg.AzureSSHUser = viper.GetString("azure_ssh_user")
+	if conf.IsSet("azure_ssh_user") {
+		cfg.AzureSSHUser = conf.GetString("azure_ssh_user")
 	} else if nonInteractiveMode {
-		return errors.New("azure_ssh_user must be specified")
+		return util.ConfigError(errors.New("azure_ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Azure SSH User",
@@ -344,8 +337,8 @@ func newAzureManager(currentState state.State, name string) error {
 	}
 
 	// Azure Public Key Path
-	if viper.IsSet("azure_public_key_path") {
-		expandedPublicKeyPath, err := homedir.Expand(viper.GetString("azure_public_key_path"))
+	if conf.IsSet("azure_public_key_path") {
+		expandedPublicKeyPath, err := homedir.Expand(conf.GetString("azure_public_key_path"))
 		if err != nil {
 			return err
 		}
@@ -353,7 +346,7 @@ func newAzureManager(currentState state.State, name string) error {
 		cfg.AzurePublicKeyPath = expandedPublicKeyPath
 
 	} else if nonInteractiveMode {
-		return errors.New("azure_public_key_path must be specified")
+		return util.ConfigError(errors.New("azure_public_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Public Key Path",
@@ -388,8 +381,8 @@ func newAzureManager(currentState state.State, name string) error {
 	}
 
 	// Azure Private Key Path
-	if viper.IsSet("azure_private_key_path") {
-		expandedPrivateKeyPath, err := homedir.Expand(viper.GetString("azure_private_key_path"))
+	if conf.IsSet("azure_private_key_path") {
+		expandedPrivateKeyPath, err := homedir.Expand(conf.GetString("azure_private_key_path"))
 		if err != nil {
 			return err
 		}
@@ -397,7 +390,7 @@ func newAzureManager(currentState state.State, name string) error {
 		cfg.AzurePrivateKeyPath = expandedPrivateKeyPath
 
 	} else if nonInteractiveMode {
-		return errors.New("azure_private_key_path must be specified")
+		return util.ConfigError(errors.New("azure_private_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Private Key Path",
@@ -431,7 +424,81 @@ func newAzureManager(currentState state.State, name string) error {
 		cfg.AzurePrivateKeyPath = expandedPrivateKeyPath
 	}
 
+	// Rancher on one host, or three behind a load balancer
+	cfg.managerHAConfig, err = getManagerHAConfig(conf, baseConfig, 0)
+	if err != nil {
+		return err
+	}
+
 	currentState.SetManager(&cfg)
 
 	return nil
 }
+
+// Asks whether the cluster manager goes in an existing resource group of the subscription, and
+// which one, e.g. when the service principal may only create resources in given groups. The
+// resource group must be in the cluster manager's location. By default the terraform module
+// creates a resource group for the cluster manager.
+func getAzureManagerResourceGroup(conf config.Config, cfg *azureManagerTerraformConfig, azureEnv azure.Environment, azureSPT *adal.ServicePrincipalToken) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	useExistingResourceGroup := false
+	if conf.IsSet("azure_use_existing_resource_group") {
+		useExistingResourceGroup = conf.GetBool("azure_use_existing_resource_group")
+	} else if !nonInteractiveMode {
+		confirmed, err := util.PromptForConfirmation("Use an existing Azure Resource Group", "Existing Azure Resource Group")
+		if err != nil {
+			return err
+		}
+		useExistingResourceGroup = confirmed
+	}
+
+	if !useExistingResourceGroup {
+		return nil
+	}
+
+	groups, err := getAzureResourceGroups(azureEnv, azureSPT, cfg.AzureSubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if conf.IsSet("azure_resource_group_name") {
+		name, err := findAzureResourceGroup(groups, conf.GetString("azure_resource_group_name"), cfg.AzureLocation)
+		if err != nil {
+			return err
+		}
+		cfg.AzureResourceGroupName = name
+		return nil
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("azure_resource_group_name must be specified"))
+	}
+
+	names := getAzureResourceGroupNames(groups, cfg.AzureLocation)
+	if len(names) == 0 {
+		return fmt.Errorf("No Azure Resource Groups in %s.", cfg.AzureLocation)
+	}
+
+	prompt := promptui.Select{
+		Label: "Azure Resource Group",
+		Items: names,
+		Searcher: func(input string, index int) bool {
+			name := strings.Replace(strings.ToLower(names[index]), " ", "", -1)
+			input = strings.Replace(strings.ToLower(input), " ", "", -1)
+			return strings.Contains(name, input)
+		},
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}?",
+			Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+			Inactive: `  {{ . }}`,
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Azure Resource Group:" | bold}} {{ . }}`, promptui.IconGood),
+		},
+	}
+
+	_, value, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	cfg.AzureResourceGroupName = value
+
+	return nil
+}
@@ -4,11 +4,12 @@ import (
 	"errors"
 	"os"
 
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -26,10 +27,10 @@ type bareMetalManagerTerraformConfig struct {
 	KeyPath     string `json:"key_path,omitempty"`
 }
 
-func newBareMetalManager(currentState state.State, name string) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func newBareMetalManager(conf config.Config, currentState state.State, name string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 
-	baseConfig, err := getBaseManagerTerraformConfig(bareMetalRancherTerraformModulePath, name)
+	baseConfig, err := getBaseManagerTerraformConfig(conf, bareMetalRancherTerraformModulePath, name)
 	if err != nil {
 		return err
 	}
@@ -39,10 +40,10 @@ func newBareMetalManager(currentState state.State, name string) error {
 	}
 
 	host := ""
-	if viper.IsSet("host") {
-		host = viper.GetString("host")
+	if conf.IsSet("host") {
+		host = conf.GetString("host")
 	} else if nonInteractiveMode {
-		return errors.New("host must be specified")
+		return util.ConfigError(errors.New("host must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Host/IP for cluster manager",
@@ -63,10 +64,10 @@ func newBareMetalManager(currentState state.State, name string) error {
 	cfg.Host = host
 
 	ssh_user := ""
-	if viper.IsSet("ssh_user") {
-		ssh_user = viper.GetString("ssh_user")
+	if conf.IsSet("ssh_user") {
+		ssh_user = conf.GetString("ssh_user")
 	} else if nonInteractiveMode {
-		return errors.New("ssh_user must be specified")
+		return util.ConfigError(errors.New("ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "SSH User",
@@ -87,10 +88,10 @@ func newBareMetalManager(currentState state.State, name string) error {
 	cfg.SSHUser = ssh_user
 
 	bastion_host := ""
-	if viper.IsSet("bastion_host") {
-		bastion_host = viper.GetString("bastion_host")
+	if conf.IsSet("bastion_host") {
+		bastion_host = conf.GetString("bastion_host")
 	} else if nonInteractiveMode {
-		return errors.New("bastion_host must be specified")
+		return util.ConfigError(errors.New("bastion_host must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Bastion Host",
@@ -113,10 +114,10 @@ func newBareMetalManager(currentState state.State, name string) error {
 	cfg.BastionHost = bastion_host
 
 	key_path := ""
-	if viper.IsSet("key_path") {
-		key_path = viper.GetString("key_path")
+	if conf.IsSet("key_path") {
+		key_path = conf.GetString("key_path")
 	} else if nonInteractiveMode {
-		return errors.New("key_path must be specified")
+		return util.ConfigError(errors.New("key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Key Path",
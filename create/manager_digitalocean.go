@@ -0,0 +1,124 @@
+package create
+
+import (
+	"errors"
+	"os"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	digitalOceanRancherTerraformModulePath = "terraform/modules/digitalocean-rancher"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type digitalOceanManagerTerraformConfig struct {
+	baseManagerTerraformConfig
+
+	DigitalOceanAPIToken string `json:"digitalocean_api_token"`
+	DigitalOceanRegion   string `json:"digitalocean_region"`
+
+	DigitalOceanDropletSize string `json:"digitalocean_droplet_size"`
+	DigitalOceanImage       string `json:"digitalocean_image"`
+
+	DigitalOceanSSHKeyFingerprint string `json:"digitalocean_ssh_key_fingerprint"`
+	DigitalOceanPrivateKeyPath    string `json:"digitalocean_private_key_path"`
+
+	managerHAConfig
+}
+
+func newDigitalOceanManager(conf config.Config, currentState state.State, name string) error {
+	baseConfig, err := getBaseManagerTerraformConfig(conf, digitalOceanRancherTerraformModulePath, name)
+	if err != nil {
+		return err
+	}
+
+	cfg := digitalOceanManagerTerraformConfig{
+		baseManagerTerraformConfig: baseConfig,
+	}
+
+	cfg.DigitalOceanAPIToken, err = getDigitalOceanAPIToken(conf)
+	if err != nil {
+		return err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = digitalOceanCredentials{APIToken: cfg.DigitalOceanAPIToken}.Validate()
+	if err != nil {
+		return err
+	}
+
+	cfg.DigitalOceanRegion, err = getDigitalOceanRegion(conf, cfg.DigitalOceanAPIToken)
+	if err != nil {
+		return err
+	}
+
+	cfg.DigitalOceanDropletSize, err = getDigitalOceanDropletSize(conf, cfg.DigitalOceanAPIToken, cfg.DigitalOceanRegion)
+	if err != nil {
+		return err
+	}
+
+	cfg.DigitalOceanImage, err = getDigitalOceanImage(conf, cfg.DigitalOceanAPIToken, cfg.DigitalOceanRegion)
+	if err != nil {
+		return err
+	}
+
+	cfg.DigitalOceanSSHKeyFingerprint, err = getDigitalOceanSSHKey(conf, cfg.DigitalOceanAPIToken)
+	if err != nil {
+		return err
+	}
+
+	// The manager is set up over SSH with the private key of the selected SSH key
+	rawPrivateKeyPath := ""
+	if conf.IsSet("digitalocean_private_key_path") {
+		rawPrivateKeyPath = conf.GetString("digitalocean_private_key_path")
+	} else if conf.GetBool("non-interactive") {
+		return util.ConfigError(errors.New("digitalocean_private_key_path must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "DigitalOcean Private Key Path",
+			Validate: func(input string) error {
+				expandedPath, err := homedir.Expand(input)
+				if err != nil {
+					return err
+				}
+
+				_, err = os.Stat(expandedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return errors.New("File not found")
+					}
+				}
+				return nil
+			},
+			Default: "~/.ssh/id_rsa",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		rawPrivateKeyPath = result
+	}
+
+	cfg.DigitalOceanPrivateKeyPath, err = homedir.Expand(rawPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// Rancher on one host, or three behind a load balancer
+	cfg.managerHAConfig, err = getManagerHAConfig(conf, baseConfig, 0)
+	if err != nil {
+		return err
+	}
+
+	currentState.SetManager(&cfg)
+
+	return nil
+}
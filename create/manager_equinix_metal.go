@@ -0,0 +1,117 @@
+package create
+
+import (
+	"errors"
+	"os"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	equinixMetalRancherTerraformModulePath = "terraform/modules/equinix-metal-rancher"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type equinixMetalManagerTerraformConfig struct {
+	baseManagerTerraformConfig
+
+	EquinixMetalAPIToken  string `json:"equinix_metal_api_token"`
+	EquinixMetalProjectID string `json:"equinix_metal_project_id"`
+	EquinixMetalMetro     string `json:"equinix_metal_metro"`
+
+	EquinixMetalPlan            string `json:"equinix_metal_plan"`
+	EquinixMetalOperatingSystem string `json:"equinix_metal_operating_system"`
+
+	EquinixMetalPrivateKeyPath string `json:"equinix_metal_private_key_path"`
+}
+
+func newEquinixMetalManager(conf config.Config, currentState state.State, name string) error {
+	baseConfig, err := getBaseManagerTerraformConfig(conf, equinixMetalRancherTerraformModulePath, name)
+	if err != nil {
+		return err
+	}
+
+	cfg := equinixMetalManagerTerraformConfig{
+		baseManagerTerraformConfig: baseConfig,
+	}
+
+	cfg.EquinixMetalAPIToken, err = getEquinixMetalAPIToken(conf)
+	if err != nil {
+		return err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = equinixMetalCredentials{APIToken: cfg.EquinixMetalAPIToken}.Validate()
+	if err != nil {
+		return err
+	}
+
+	cfg.EquinixMetalProjectID, err = getEquinixMetalProjectID(conf, cfg.EquinixMetalAPIToken)
+	if err != nil {
+		return err
+	}
+
+	cfg.EquinixMetalMetro, err = getEquinixMetalMetro(conf, cfg.EquinixMetalAPIToken)
+	if err != nil {
+		return err
+	}
+
+	cfg.EquinixMetalPlan, err = getEquinixMetalPlan(conf, cfg.EquinixMetalAPIToken, cfg.EquinixMetalProjectID, cfg.EquinixMetalMetro)
+	if err != nil {
+		return err
+	}
+
+	cfg.EquinixMetalOperatingSystem, err = getEquinixMetalOperatingSystem(conf, cfg.EquinixMetalAPIToken, cfg.EquinixMetalPlan)
+	if err != nil {
+		return err
+	}
+
+	// The manager is set up over SSH with the private key of an SSH key of the project, which
+	// Equinix Metal adds to every device
+	rawPrivateKeyPath := ""
+	if conf.IsSet("equinix_metal_private_key_path") {
+		rawPrivateKeyPath = conf.GetString("equinix_metal_private_key_path")
+	} else if conf.GetBool("non-interactive") {
+		return util.ConfigError(errors.New("equinix_metal_private_key_path must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Equinix Metal Private Key Path",
+			Validate: func(input string) error {
+				expandedPath, err := homedir.Expand(input)
+				if err != nil {
+					return err
+				}
+
+				_, err = os.Stat(expandedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return errors.New("File not found")
+					}
+				}
+				return nil
+			},
+			Default: "~/.ssh/id_rsa",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		rawPrivateKeyPath = result
+	}
+
+	cfg.EquinixMetalPrivateKeyPath, err = homedir.Expand(rawPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	currentState.SetManager(&cfg)
+
+	return nil
+}
@@ -7,13 +7,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"sort"
 	"strings"
 
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 	"golang.org/x/oauth2/google"
 	compute "google.golang.org/api/compute/v1"
 )
@@ -38,12 +38,14 @@ type gcpManagerTerraformConfig struct {
 	GCPPublicKeyPath  string `json:"gcp_public_key_path"`
 	GCPPrivateKeyPath string `json:"gcp_private_key_path"`
 	GCPSSHUser        string `json:"gcp_ssh_user"`
+
+	managerHAConfig
 }
 
-func newGCPManager(currentState state.State, name string) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func newGCPManager(conf config.Config, currentState state.State, name string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 
-	baseConfig, err := getBaseManagerTerraformConfig(gcpRancherTerraformModulePath, name)
+	baseConfig, err := getBaseManagerTerraformConfig(conf, gcpRancherTerraformModulePath, name)
 	if err != nil {
 		return err
 	}
@@ -54,10 +56,10 @@ func newGCPManager(currentState state.State, name string) error {
 
 	// GCP path_to_credentials
 	rawGCPPathToCredentials := ""
-	if viper.IsSet("gcp_path_to_credentials") {
-		rawGCPPathToCredentials = viper.GetString("gcp_path_to_credentials")
+	if conf.IsSet("gcp_path_to_credentials") {
+		rawGCPPathToCredentials = conf.GetString("gcp_path_to_credentials")
 	} else if nonInteractiveMode {
-		return errors.New("gcp_path_to_credentials must be specified")
+		return util.ConfigError(errors.New("gcp_path_to_credentials must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Path to Google Cloud Platform Credentials File",
@@ -109,6 +111,12 @@ func newGCPManager(currentState state.State, name string) error {
 	}
 	cfg.GCPProjectID = pid.ProjectID
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = gcpProjectCredentials{PathToCredentials: cfg.GCPPathToCredentials, ProjectID: cfg.GCPProjectID}.Validate()
+	if err != nil {
+		return err
+	}
+
 	service, err := compute.New(jwtCfg.Client(context.Background()))
 	if err != nil {
 		return err
@@ -120,8 +128,8 @@ func newGCPManager(currentState state.State, name string) error {
 	}
 
 	// GCP Compute Region
-	if viper.IsSet("gcp_compute_region") {
-		cfg.GCPComputeRegion = viper.GetString("gcp_compute_region")
+	if conf.IsSet("gcp_compute_region") {
+		cfg.GCPComputeRegion = conf.GetString("gcp_compute_region")
 
 		found := false
 		for _, region := range regions.Items {
@@ -135,7 +143,7 @@ func newGCPManager(currentState state.State, name string) error {
 		}
 
 	} else if nonInteractiveMode {
-		return errors.New("gcp_compute_region must be specified")
+		return util.ConfigError(errors.New("gcp_compute_region must be specified"))
 	} else {
 		searcher := func(input string, index int) bool {
 			region := regions.Items[index]
@@ -165,169 +173,29 @@ func newGCPManager(currentState state.State, name string) error {
 		cfg.GCPComputeRegion = regions.Items[i].Name
 	}
 
-	zones, err := service.Zones.List(cfg.GCPProjectID).Filter(fmt.Sprintf("region eq https://www.googleapis.com/compute/v1/projects/%s/regions/%s", cfg.GCPProjectID, cfg.GCPComputeRegion)).Do()
-	if err != nil {
-		return err
-	}
-
 	// GCP Instance Zone
-	if viper.IsSet("gcp_instance_zone") {
-		cfg.GCPInstanceZone = viper.GetString("gcp_instance_zone")
-
-		found := false
-		for _, zone := range zones.Items {
-			if zone.Name == cfg.GCPInstanceZone {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("Selected GCP Instance Zone '%s' does not exist.", cfg.GCPInstanceZone)
-		}
-
-	} else if nonInteractiveMode {
-		return errors.New("gcp_instance_zone must be specified")
-	} else {
-		searcher := func(input string, index int) bool {
-			zone := zones.Items[index]
-			name := strings.Replace(strings.ToLower(zone.Name), " ", "", -1)
-			input = strings.Replace(strings.ToLower(input), " ", "", -1)
-
-			return strings.Contains(name, input)
-		}
-
-		prompt := promptui.Select{
-			Label: "GCP Instance Zone",
-			Items: zones.Items,
-			Templates: &promptui.SelectTemplates{
-				Label:    "{{ .Name }}?",
-				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
-				Inactive: `  {{ .Name }}`,
-				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Instance Zone:" | bold}} {{ .Name }}`, promptui.IconGood),
-			},
-			Searcher: searcher,
-		}
-
-		i, _, err := prompt.Run()
-		if err != nil {
-			return err
-		}
-
-		cfg.GCPInstanceZone = zones.Items[i].Name
-	}
-
-	machineTypes, err := service.MachineTypes.List(cfg.GCPProjectID, cfg.GCPInstanceZone).Do()
+	cfg.GCPInstanceZone, err = getGCPInstanceZone(conf, service, cfg.GCPProjectID, cfg.GCPComputeRegion)
 	if err != nil {
 		return err
 	}
 
 	// GCP Machine Type
-	if viper.IsSet("gcp_machine_type") {
-		cfg.GCPMachineType = viper.GetString("gcp_machine_type")
-
-		found := false
-		for _, machineType := range machineTypes.Items {
-			if machineType.Name == cfg.GCPMachineType {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("Selected GCP Machine Type '%s' does not exist.", cfg.GCPMachineType)
-		}
-
-	} else if nonInteractiveMode {
-		return errors.New("gcp_machine_type must be specified")
-	} else {
-		searcher := func(input string, index int) bool {
-			machineType := machineTypes.Items[index]
-			name := strings.Replace(strings.ToLower(machineType.Name), " ", "", -1)
-			input = strings.Replace(strings.ToLower(input), " ", "", -1)
-
-			return strings.Contains(name, input)
-		}
-
-		prompt := promptui.Select{
-			Label: "GCP Machine Type",
-			Items: machineTypes.Items,
-			Templates: &promptui.SelectTemplates{
-				Label:    "{{ .Name }}?",
-				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
-				Inactive: `  {{ .Name }}`,
-				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Machine Type:" | bold}} {{ .Name }}`, promptui.IconGood),
-			},
-			Searcher: searcher,
-		}
-
-		i, _, err := prompt.Run()
-		if err != nil {
-			return err
-		}
-
-		cfg.GCPMachineType = machineTypes.Items[i].Name
-	}
-
-	images, err := service.Images.List("ubuntu-os-cloud").Do()
+	cfg.GCPMachineType, err = getGCPMachineType(conf, service, cfg.GCPProjectID, cfg.GCPInstanceZone)
 	if err != nil {
 		return err
 	}
 
-	// Sort images by created timestamp in reverse chronological order
-	sort.SliceStable(images.Items, func(i, j int) bool {
-		return images.Items[i].CreationTimestamp > images.Items[j].CreationTimestamp
-	})
-
 	// GCP Image
-	if viper.IsSet("gcp_image") {
-		cfg.GCPImage = viper.GetString("gcp_image")
-
-		found := false
-		for _, image := range images.Items {
-			if image.Name == cfg.GCPImage {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("Selected GCP Image '%s' does not exist.", cfg.GCPImage)
-		}
-
-	} else if nonInteractiveMode {
-		return errors.New("gcp_image must be specified")
-	} else {
-		searcher := func(input string, index int) bool {
-			image := images.Items[index]
-			name := strings.Replace(strings.ToLower(image.Name), " ", "", -1)
-			input = strings.Replace(strings.ToLower(input), " ", "", -1)
-
-			return strings.Contains(name, input)
-		}
-
-		prompt := promptui.Select{
-			Label: "GCP Image",
-			Items: images.Items,
-			Templates: &promptui.SelectTemplates{
-				Label:    "{{ .Name }}?",
-				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
-				Inactive: `  {{ .Name }}`,
-				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Image:" | bold}} {{ .Name }}`, promptui.IconGood),
-			},
-			Searcher: searcher,
-		}
-
-		i, _, err := prompt.Run()
-		if err != nil {
-			return err
-		}
-
-		cfg.GCPImage = images.Items[i].Name
+	cfg.GCPImage, err = getGCPImage(conf, service, cfg.GCPProjectID)
+	if err != nil {
+		return err
 	}
 
 	rawGCPPublicKeyPath := ""
-	if viper.IsSet("gcp_public_key_path") {
-		rawGCPPublicKeyPath = viper.GetString("gcp_public_key_path")
+	if conf.IsSet("gcp_public_key_path") {
+		rawGCPPublicKeyPath = conf.GetString("gcp_public_key_path")
 	} else if nonInteractiveMode {
-		return errors.New("gcp_public_key_path must be specified")
+		return util.ConfigError(errors.New("gcp_public_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "GCP Public Key Path",
@@ -362,10 +230,10 @@ func newGCPManager(currentState state.State, name string) error {
 	cfg.GCPPublicKeyPath = expandedGCPPublicKeyPath
 
 	rawGCPPrivateKeyPath := ""
-	if viper.IsSet("gcp_private_key_path") {
-		rawGCPPrivateKeyPath = viper.GetString("gcp_private_key_path")
+	if conf.IsSet("gcp_private_key_path") {
+		rawGCPPrivateKeyPath = conf.GetString("gcp_private_key_path")
 	} else if nonInteractiveMode {
-		return errors.New("gcp_private_key_path must be specified")
+		return util.ConfigError(errors.New("gcp_private_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "GCP Private Key Path",
@@ -399,10 +267,10 @@ func newGCPManager(currentState state.State, name string) error {
 	}
 	cfg.GCPPrivateKeyPath = expandedGCPPrivateKeyPath
 
-	if viper.IsSet("gcp_ssh_user") {
-		cfg.GCPSSHUser = viper.GetString("gcp_ssh_user")
+	if conf.IsSet("gcp_ssh_user") {
+		cfg.GCPSSHUser = conf.GetString("gcp_ssh_user")
 	} else if nonInteractiveMode {
-		return errors.New("gcp_ssh_user must be specified")
+		return util.ConfigError(errors.New("gcp_ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "GCP SSH User",
@@ -416,6 +284,17 @@ func newGCPManager(currentState state.State, name string) error {
 		cfg.GCPSSHUser = result
 	}
 
+	// Rancher on one host, or three behind a load balancer
+	cfg.managerHAConfig, err = getManagerHAConfig(conf, baseConfig, 0)
+	if err != nil {
+		return err
+	}
+
+	// The network load balancer forwards the ports it receives, those of the ingress controller
+	if cfg.ManagerHostCount > 1 && (baseConfig.RancherHTTPSPort != "" && baseConfig.RancherHTTPSPort != "443" || baseConfig.RancherHTTPPort != "" && baseConfig.RancherHTTPPort != "80") {
+		return util.ConfigError(errors.New("rancher_https_port and rancher_http_port can't be changed when a gcp cluster manager has manager_host_count 3, its load balancer forwards ports 443 and 80 to the hosts."))
+	}
+
 	currentState.SetManager(&cfg)
 
 	return nil
@@ -0,0 +1,89 @@
+package create
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// The longest name of an aws HA manager, the target groups of its load balancer are named
+// {name}-rancher-https and AWS names are at most 32 characters.
+const maxAWSHAManagerNameLength = 18
+
+// The cloud providers whose cluster managers can run Rancher on 3 hosts behind a load balancer
+var haManagerCloudProviders = []string{"aws", "azure", "digitalocean", "gcp"}
+
+// The HA topology of a cluster manager. With 3 hosts, Rancher runs on a k3s cluster whose
+// hosts share its etcd, behind a load balancer that is the cluster manager's rancher_url, so
+// nodes register through it.
+type managerHAConfig struct {
+	ManagerHostCount int    `json:"manager_host_count,omitempty"`
+	ManagerHAToken   string `json:"manager_ha_token,omitempty"`
+}
+
+// Fails for manager_host_count on cloud providers that only run Rancher on a single host.
+func checkManagerHostCountSupported(conf config.Config, cloudProvider string) error {
+	if !conf.IsSet("manager_host_count") || conf.GetInt("manager_host_count") == 1 {
+		return nil
+	}
+	for _, haCloudProvider := range haManagerCloudProviders {
+		if cloudProvider == haCloudProvider {
+			return nil
+		}
+	}
+	return fmt.Errorf("manager_host_count %s is only supported by %s cluster managers.", conf.GetString("manager_host_count"), strings.Join(haManagerCloudProviders, ", "))
+}
+
+// Returns the HA topology of a new cluster manager. Without manager_host_count in
+// non-interactive mode, the cluster manager has a single host. maxNameLength is the longest
+// name the load balancer allows, 0 when it doesn't limit it.
+func getManagerHAConfig(conf config.Config, baseConfig baseManagerTerraformConfig, maxNameLength int) (managerHAConfig, error) {
+	hostCount := 1
+	if conf.IsSet("manager_host_count") {
+		hostCount = conf.GetInt("manager_host_count")
+		if hostCount != 1 && hostCount != 3 {
+			return managerHAConfig{}, util.ConfigError(fmt.Errorf("Invalid manager_host_count '%s', must be 1 or 3.", conf.GetString("manager_host_count")))
+		}
+	} else if !conf.GetBool("non-interactive") {
+		prompt := promptui.Select{
+			Label: "Number of manager hosts",
+			Items: []string{"1 (single host)", "3 (HA behind a load balancer)"},
+		}
+
+		i, _, err := prompt.Run()
+		if err != nil {
+			return managerHAConfig{}, err
+		}
+		if i == 1 {
+			hostCount = 3
+		}
+	}
+
+	if hostCount == 1 {
+		return managerHAConfig{}, nil
+	}
+
+	// The load balancer is where nodes and the CLI reach Rancher
+	if baseConfig.RancherExternalURL != "" {
+		return managerHAConfig{}, errors.New("rancher_external_url can't be set when manager_host_count is 3, Rancher is reached through the load balancer of the cluster manager")
+	}
+	if maxNameLength > 0 && len(baseConfig.Name) > maxNameLength {
+		return managerHAConfig{}, fmt.Errorf("Cluster manager name '%s' is too long for manager_host_count 3, the names of its load balancer allow up to %d characters.", baseConfig.Name, maxNameLength)
+	}
+
+	// The hosts join the k3s cluster with a shared secret
+	token := make([]byte, 32)
+	_, err := rand.Read(token)
+	if err != nil {
+		return managerHAConfig{}, err
+	}
+
+	return managerHAConfig{ManagerHostCount: hostCount, ManagerHAToken: hex.EncodeToString(token)}, nil
+}
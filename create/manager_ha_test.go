@@ -0,0 +1,100 @@
+package create
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestGetManagerHAConfig(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+
+	// A single host unless manager_host_count says otherwise
+	cfg, err := getManagerHAConfig(conf, baseManagerTerraformConfig{Name: "dev"}, maxAWSHAManagerNameLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != (managerHAConfig{}) {
+		t.Errorf("Expected a single host, received %+v", cfg)
+	}
+
+	conf.Set("manager_host_count", 3)
+	cfg, err = getManagerHAConfig(conf, baseManagerTerraformConfig{Name: "dev"}, maxAWSHAManagerNameLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ManagerHostCount != 3 || len(cfg.ManagerHAToken) != 64 {
+		t.Errorf("Expected 3 hosts and a token, received %+v", cfg)
+	}
+
+	// Load balancers that don't limit names take any cluster manager name
+	cfg, err = getManagerHAConfig(conf, baseManagerTerraformConfig{Name: strings.Repeat("a", 40)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ManagerHostCount != 3 {
+		t.Errorf("Expected 3 hosts, received %+v", cfg)
+	}
+}
+
+func TestGetManagerHAConfigInvalid(t *testing.T) {
+	tests := []struct {
+		testName   string
+		hostCount  interface{}
+		baseConfig baseManagerTerraformConfig
+		expected   string
+	}{
+		{
+			"Two hosts",
+			2,
+			baseManagerTerraformConfig{Name: "dev"},
+			"Invalid manager_host_count '2', must be 1 or 3.",
+		},
+		{
+			"External URL",
+			3,
+			baseManagerTerraformConfig{Name: "dev", RancherExternalURL: "https://rancher.example.com"},
+			"rancher_external_url can't be set when manager_host_count is 3, Rancher is reached through the load balancer of the cluster manager",
+		},
+		{
+			"Long name",
+			"3",
+			baseManagerTerraformConfig{Name: strings.Repeat("a", 19)},
+			"Cluster manager name 'aaaaaaaaaaaaaaaaaaa' is too long for manager_host_count 3, the names of its load balancer allow up to 18 characters.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			conf := config.New()
+			conf.Set("non-interactive", true)
+			conf.Set("manager_host_count", test.hostCount)
+
+			_, err := getManagerHAConfig(conf, test.baseConfig, maxAWSHAManagerNameLength)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("Wrong output, expected %s, received %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckManagerHostCountSupported(t *testing.T) {
+	conf := config.New()
+	conf.Set("manager_host_count", 1)
+	if err := checkManagerHostCountSupported(conf, "triton"); err != nil {
+		t.Errorf("Expected a single host to be supported, received %v", err)
+	}
+
+	conf.Set("manager_host_count", 3)
+	for _, cloudProvider := range []string{"aws", "azure", "digitalocean", "gcp"} {
+		if err := checkManagerHostCountSupported(conf, cloudProvider); err != nil {
+			t.Errorf("Expected %s to support 3 hosts, received %v", cloudProvider, err)
+		}
+	}
+	expected := "manager_host_count 3 is only supported by aws, azure, digitalocean, gcp cluster managers."
+	if err := checkManagerHostCountSupported(conf, "triton"); err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
@@ -0,0 +1,70 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	libvirtRancherTerraformModulePath = "terraform/modules/libvirt-rancher"
+
+	defaultLibvirtMasterVCPU     = "2"
+	defaultLibvirtMasterMemory   = "4096"
+	defaultLibvirtMasterDiskSize = "20"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type libvirtManagerTerraformConfig struct {
+	baseManagerTerraformConfig
+	libvirtTerraformConfig
+
+	LibvirtSSHUser string `json:"libvirt_ssh_user"`
+	LibvirtKeyPath string `json:"libvirt_key_path"`
+
+	MasterLibvirtVCPU     string `json:"master_libvirt_vcpu"`
+	MasterLibvirtMemory   string `json:"master_libvirt_memory"`
+	MasterLibvirtDiskSize string `json:"master_libvirt_disk_size"`
+}
+
+func newLibvirtManager(conf config.Config, currentState state.State, name string) error {
+	baseConfig, err := getBaseManagerTerraformConfig(conf, libvirtRancherTerraformModulePath, name)
+	if err != nil {
+		return err
+	}
+
+	libvirtConfig, err := getLibvirtTerraformConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	cfg := libvirtManagerTerraformConfig{
+		baseManagerTerraformConfig: baseConfig,
+		libvirtTerraformConfig:     libvirtConfig,
+	}
+
+	cfg.LibvirtSSHUser, cfg.LibvirtKeyPath, err = getLibvirtSSHConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	// VM Size
+	cfg.MasterLibvirtVCPU, err = getLibvirtVMSize(conf, "master_libvirt_vcpu", "Virtual CPUs", defaultLibvirtMasterVCPU)
+	if err != nil {
+		return err
+	}
+
+	cfg.MasterLibvirtMemory, err = getLibvirtVMSize(conf, "master_libvirt_memory", "Memory (MB)", defaultLibvirtMasterMemory)
+	if err != nil {
+		return err
+	}
+
+	cfg.MasterLibvirtDiskSize, err = getLibvirtVMSize(conf, "master_libvirt_disk_size", "Disk Size (GB)", defaultLibvirtMasterDiskSize)
+	if err != nil {
+		return err
+	}
+
+	currentState.SetManager(&cfg)
+
+	return nil
+}
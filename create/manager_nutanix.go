@@ -0,0 +1,81 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	nutanixRancherTerraformModulePath = "terraform/modules/nutanix-rancher"
+
+	defaultNutanixMasterVCPUs    = "2"
+	defaultNutanixMasterMemory   = "4096"
+	defaultNutanixMasterDiskSize = "40"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type nutanixManagerTerraformConfig struct {
+	baseManagerTerraformConfig
+	nutanixAuth
+	nutanixVMConfig
+
+	NutanixSSHUser string `json:"nutanix_ssh_user"`
+	NutanixKeyPath string `json:"nutanix_key_path"`
+
+	MasterNutanixVCPUs    string `json:"master_nutanix_vcpus"`
+	MasterNutanixMemory   string `json:"master_nutanix_memory"`
+	MasterNutanixDiskSize string `json:"master_nutanix_disk_size"`
+}
+
+func newNutanixManager(conf config.Config, currentState state.State, name string) error {
+	baseConfig, err := getBaseManagerTerraformConfig(conf, nutanixRancherTerraformModulePath, name)
+	if err != nil {
+		return err
+	}
+
+	cfg := nutanixManagerTerraformConfig{
+		baseManagerTerraformConfig: baseConfig,
+	}
+
+	cfg.nutanixAuth, err = getNutanixAuth(conf)
+	if err != nil {
+		return err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = nutanixCredentials{cfg.nutanixAuth}.Validate()
+	if err != nil {
+		return err
+	}
+
+	cfg.nutanixVMConfig, err = getNutanixVMConfig(conf, cfg.nutanixAuth)
+	if err != nil {
+		return err
+	}
+
+	cfg.NutanixSSHUser, cfg.NutanixKeyPath, err = getNutanixSSHConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	// VM Size
+	cfg.MasterNutanixVCPUs, err = getNutanixVMSize(conf, "master_nutanix_vcpus", "vCPUs", defaultNutanixMasterVCPUs)
+	if err != nil {
+		return err
+	}
+
+	cfg.MasterNutanixMemory, err = getNutanixVMSize(conf, "master_nutanix_memory", "Memory (MiB)", defaultNutanixMasterMemory)
+	if err != nil {
+		return err
+	}
+
+	cfg.MasterNutanixDiskSize, err = getNutanixVMSize(conf, "master_nutanix_disk_size", "Disk Size (GiB)", defaultNutanixMasterDiskSize)
+	if err != nil {
+		return err
+	}
+
+	currentState.SetManager(&cfg)
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package create
+
+import (
+	"errors"
+	"os"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	openStackRancherTerraformModulePath = "terraform/modules/openstack-rancher"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type openStackManagerTerraformConfig struct {
+	baseManagerTerraformConfig
+	openStackAuth
+
+	OpenStackFlavorName     string `json:"openstack_flavor_name"`
+	OpenStackImageName      string `json:"openstack_image_name"`
+	OpenStackNetworkName    string `json:"openstack_network_name"`
+	OpenStackFloatingIPPool string `json:"openstack_floating_ip_pool"`
+
+	OpenStackKeyPair        string `json:"openstack_key_pair"`
+	OpenStackSSHUser        string `json:"openstack_ssh_user"`
+	OpenStackPrivateKeyPath string `json:"openstack_private_key_path"`
+}
+
+func newOpenStackManager(conf config.Config, currentState state.State, name string) error {
+	baseConfig, err := getBaseManagerTerraformConfig(conf, openStackRancherTerraformModulePath, name)
+	if err != nil {
+		return err
+	}
+
+	cfg := openStackManagerTerraformConfig{
+		baseManagerTerraformConfig: baseConfig,
+	}
+
+	cfg.openStackAuth, err = getOpenStackAuth(conf)
+	if err != nil {
+		return err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = openStackCredentials{cfg.openStackAuth}.Validate()
+	if err != nil {
+		return err
+	}
+
+	session, err := newOpenStackSession(cfg.openStackAuth)
+	if err != nil {
+		return err
+	}
+
+	cfg.OpenStackFlavorName, err = getOpenStackFlavor(conf, session)
+	if err != nil {
+		return err
+	}
+
+	cfg.OpenStackImageName, err = getOpenStackImage(conf, session)
+	if err != nil {
+		return err
+	}
+
+	cfg.OpenStackNetworkName, cfg.OpenStackFloatingIPPool, err = getOpenStackNetworks(conf, session)
+	if err != nil {
+		return err
+	}
+
+	cfg.OpenStackKeyPair, err = getOpenStackKeyPair(conf, session)
+	if err != nil {
+		return err
+	}
+
+	// The user the manager is set up as depends on the image, e.g. ubuntu or centos
+	cfg.OpenStackSSHUser, err = util.PromptForValue(conf, "openstack_ssh_user", "OpenStack SSH User", "ubuntu", false)
+	if err != nil {
+		return err
+	}
+
+	// The manager is set up over SSH with the private key of the selected key pair
+	rawPrivateKeyPath := ""
+	if conf.IsSet("openstack_private_key_path") {
+		rawPrivateKeyPath = conf.GetString("openstack_private_key_path")
+	} else if conf.GetBool("non-interactive") {
+		return util.ConfigError(errors.New("openstack_private_key_path must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "OpenStack Private Key Path",
+			Validate: func(input string) error {
+				expandedPath, err := homedir.Expand(input)
+				if err != nil {
+					return err
+				}
+
+				_, err = os.Stat(expandedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return errors.New("File not found")
+					}
+				}
+				return nil
+			},
+			Default: "~/.ssh/id_rsa",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		rawPrivateKeyPath = result
+	}
+
+	cfg.OpenStackPrivateKeyPath, err = homedir.Expand(rawPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	currentState.SetManager(&cfg)
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	proxmoxRancherTerraformModulePath = "terraform/modules/proxmox-rancher"
+
+	defaultProxmoxMasterCores    = "2"
+	defaultProxmoxMasterMemory   = "4096"
+	defaultProxmoxMasterDiskSize = "20"
+)
+
+// This struct represents the definition of a Terraform .tf file.
+// Marshalled into json this struct can be passed directly to Terraform.
+type proxmoxManagerTerraformConfig struct {
+	baseManagerTerraformConfig
+	proxmoxAuth
+	proxmoxVMConfig
+
+	ProxmoxSSHUser string `json:"proxmox_ssh_user"`
+	ProxmoxKeyPath string `json:"proxmox_key_path"`
+
+	MasterProxmoxCores    string `json:"master_proxmox_cores"`
+	MasterProxmoxMemory   string `json:"master_proxmox_memory"`
+	MasterProxmoxDiskSize string `json:"master_proxmox_disk_size"`
+}
+
+func newProxmoxManager(conf config.Config, currentState state.State, name string) error {
+	baseConfig, err := getBaseManagerTerraformConfig(conf, proxmoxRancherTerraformModulePath, name)
+	if err != nil {
+		return err
+	}
+
+	cfg := proxmoxManagerTerraformConfig{
+		baseManagerTerraformConfig: baseConfig,
+	}
+
+	cfg.proxmoxAuth, err = getProxmoxAuth(conf)
+	if err != nil {
+		return err
+	}
+
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = proxmoxCredentials{cfg.proxmoxAuth}.Validate()
+	if err != nil {
+		return err
+	}
+
+	cfg.proxmoxVMConfig, err = getProxmoxVMConfig(conf, cfg.proxmoxAuth)
+	if err != nil {
+		return err
+	}
+
+	cfg.ProxmoxSSHUser, cfg.ProxmoxKeyPath, err = getProxmoxSSHConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	// VM Size
+	cfg.MasterProxmoxCores, err = getProxmoxVMSize(conf, "master_proxmox_cores", "CPU Cores", defaultProxmoxMasterCores)
+	if err != nil {
+		return err
+	}
+
+	cfg.MasterProxmoxMemory, err = getProxmoxVMSize(conf, "master_proxmox_memory", "Memory (MB)", defaultProxmoxMasterMemory)
+	if err != nil {
+		return err
+	}
+
+	cfg.MasterProxmoxDiskSize, err = getProxmoxVMSize(conf, "master_proxmox_disk_size", "Disk Size (GB)", defaultProxmoxMasterDiskSize)
+	if err != nil {
+		return err
+	}
+
+	currentState.SetManager(&cfg)
+
+	return nil
+}
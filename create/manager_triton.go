@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
 	"github.com/joyent/triton-kubernetes/util"
 
@@ -18,7 +19,6 @@ import (
 	"github.com/joyent/triton-go/network"
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -42,10 +42,10 @@ type tritonManagerTerraformConfig struct {
 	MasterTritonMachinePackage string   `json:"master_triton_machine_package,omitempty"`
 }
 
-func newTritonManager(currentState state.State, name string) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func newTritonManager(conf config.Config, currentState state.State, name string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 
-	baseConfig, err := getBaseManagerTerraformConfig(tritonRancherTerraformModulePath, name)
+	baseConfig, err := getBaseManagerTerraformConfig(conf, tritonRancherTerraformModulePath, name)
 	if err != nil {
 		return err
 	}
@@ -54,11 +54,17 @@ func newTritonManager(currentState state.State, name string) error {
 		baseManagerTerraformConfig: baseConfig,
 	}
 
+	// Triton account, key and URL can come from a profile of the triton CLI
+	err = util.ApplyTritonProfile(conf, nonInteractiveMode)
+	if err != nil {
+		return err
+	}
+
 	// Triton Account
-	if viper.IsSet("triton_account") {
-		cfg.TritonAccount = viper.GetString("triton_account")
+	if conf.IsSet("triton_account") {
+		cfg.TritonAccount = conf.GetString("triton_account")
 	} else if nonInteractiveMode {
-		return errors.New("triton_account must be specified")
+		return util.ConfigError(errors.New("triton_account must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Triton Account Name",
@@ -79,10 +85,10 @@ func newTritonManager(currentState state.State, name string) error {
 
 	// Triton Key Path
 	rawTritonKeyPath := ""
-	if viper.IsSet("triton_key_path") {
-		rawTritonKeyPath = viper.GetString("triton_key_path")
+	if conf.IsSet("triton_key_path") {
+		rawTritonKeyPath = conf.GetString("triton_key_path")
 	} else if nonInteractiveMode {
-		return errors.New("triton_key_path must be specified")
+		return util.ConfigError(errors.New("triton_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Triton Key Path",
@@ -116,22 +122,18 @@ func newTritonManager(currentState state.State, name string) error {
 	}
 	cfg.TritonKeyPath = expandedTritonKeyPath
 
-	// Triton Key ID
-	if viper.IsSet("triton_key_id") {
-		cfg.TritonKeyID = viper.GetString("triton_key_id")
-	} else {
-		keyID, err := util.GetPublicKeyFingerprintFromPrivateKey(cfg.TritonKeyPath)
-		if err != nil {
-			return err
-		}
-		cfg.TritonKeyID = keyID
+	// Triton Key ID, an MD5 or SHA256 fingerprint of the key or computed from it
+	keyID, err := util.TritonKeyID("triton_key_id", conf.GetString("triton_key_id"), cfg.TritonKeyPath)
+	if err != nil {
+		return err
 	}
+	cfg.TritonKeyID = keyID
 
 	// Triton URL
-	if viper.IsSet("triton_url") {
-		cfg.TritonURL = viper.GetString("triton_url")
+	if conf.IsSet("triton_url") {
+		cfg.TritonURL = conf.GetString("triton_url")
 	} else if nonInteractiveMode {
-		return errors.New("triton_url must be specified")
+		return util.ConfigError(errors.New("triton_url must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Triton URL",
@@ -145,6 +147,17 @@ func newTritonManager(currentState state.State, name string) error {
 		cfg.TritonURL = result
 	}
 
+	// Fail before anything is written to the state on credentials the provider rejects
+	err = tritonCredentials{
+		Account: cfg.TritonAccount,
+		KeyID:   cfg.TritonKeyID,
+		KeyPath: cfg.TritonKeyPath,
+		URL:     cfg.TritonURL,
+	}.Validate()
+	if err != nil {
+		return err
+	}
+
 	keyMaterial, err := ioutil.ReadFile(cfg.TritonKeyPath)
 	if err != nil {
 		return err
@@ -189,8 +202,8 @@ func newTritonManager(currentState state.State, name string) error {
 	}
 
 	// Triton Network Names
-	if viper.IsSet("triton_network_names") {
-		cfg.TritonNetworkNames = viper.GetStringSlice("triton_network_names")
+	if conf.IsSet("triton_network_names") {
+		cfg.TritonNetworkNames = conf.GetStringSlice("triton_network_names")
 
 		// Verify triton network names
 		for _, network := range cfg.TritonNetworkNames {
@@ -199,7 +212,7 @@ func newTritonManager(currentState state.State, name string) error {
 			}
 		}
 	} else if nonInteractiveMode {
-		return errors.New("triton_network_names must be specified")
+		return util.ConfigError(errors.New("triton_network_names must be specified"))
 	} else {
 		networkPrompt := promptui.Select{
 			Label: "Triton Networks to attach",
@@ -274,22 +287,22 @@ func newTritonManager(currentState state.State, name string) error {
 	})
 
 	// Triton Image
-	if viper.IsSet("triton_image_name") && viper.IsSet("triton_image_version") {
-		cfg.TritonImageName = viper.GetString("triton_image_name")
-		cfg.TritonImageVersion = viper.GetString("triton_image_version")
+	var selectedImage *compute.Image
+	if conf.IsSet("triton_image_name") && conf.IsSet("triton_image_version") {
+		cfg.TritonImageName = conf.GetString("triton_image_name")
+		cfg.TritonImageVersion = conf.GetString("triton_image_version")
 		// Verify triton image name and version
-		found := false
 		for _, image := range images {
 			if image.Name == cfg.TritonImageName && image.Version == cfg.TritonImageVersion {
-				found = true
+				selectedImage = image
 				break
 			}
 		}
-		if !found {
-			return fmt.Errorf("Invalid Triton Image Name and Version '%s@%s'", cfg.TritonImageName, cfg.TritonImageVersion)
+		if selectedImage == nil {
+			return util.ConfigError(fmt.Errorf("Invalid Triton Image Name and Version '%s@%s'", cfg.TritonImageName, cfg.TritonImageVersion))
 		}
 	} else if nonInteractiveMode {
-		return errors.New("Both triton_image_name and triton_image_version must be specified")
+		return util.ConfigError(errors.New("Both triton_image_name and triton_image_version must be specified"))
 	} else {
 		searcher := func(input string, index int) bool {
 			image := images[index]
@@ -316,14 +329,15 @@ func newTritonManager(currentState state.State, name string) error {
 			return err
 		}
 
+		selectedImage = images[i]
 		cfg.TritonImageName = images[i].Name
 		cfg.TritonImageVersion = images[i].Version
 	}
 
-	if viper.IsSet("triton_ssh_user") {
-		cfg.TritonSSHUser = viper.GetString("triton_ssh_user")
+	if conf.IsSet("triton_ssh_user") {
+		cfg.TritonSSHUser = conf.GetString("triton_ssh_user")
 	} else if nonInteractiveMode {
-		return errors.New("triton_ssh_user must be specified")
+		return util.ConfigError(errors.New("triton_ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Triton SSH User",
@@ -349,22 +363,33 @@ func newTritonManager(currentState state.State, name string) error {
 		return packages[i].Memory < packages[j].Memory
 	})
 
-	if viper.IsSet("master_triton_machine_package") {
-		cfg.MasterTritonMachinePackage = viper.GetString("master_triton_machine_package")
+	if conf.IsSet("master_triton_machine_package") {
+		cfg.MasterTritonMachinePackage = conf.GetString("master_triton_machine_package")
 		// Verify master triton machine package
-		found := false
+		var selectedPackage *compute.Package
 		for _, pkg := range packages {
 			if cfg.MasterTritonMachinePackage == pkg.Name {
-				found = true
+				selectedPackage = pkg
 				break
 			}
 		}
-		if !found {
-			return fmt.Errorf("Invalid Master Triton Machine Package '%s'", cfg.MasterTritonMachinePackage)
+		if selectedPackage == nil {
+			return util.ConfigError(fmt.Errorf("Invalid Master Triton Machine Package '%s'", cfg.MasterTritonMachinePackage))
+		}
+
+		err = validateTritonImagePackage(*selectedImage, *selectedPackage)
+		if err != nil {
+			return err
 		}
 	} else if nonInteractiveMode {
-		return errors.New("master_triton_machine_package must be specified")
+		return util.ConfigError(errors.New("master_triton_machine_package must be specified"))
 	} else {
+		// Only offer the packages the image can be provisioned with
+		packages = getCompatibleTritonPackages(*selectedImage, packages)
+		if len(packages) == 0 {
+			return fmt.Errorf("No Triton machine packages are compatible with image '%s@%s'", cfg.TritonImageName, cfg.TritonImageVersion)
+		}
+
 		searcher := func(input string, index int) bool {
 			pkg := packages[index]
 			name := strings.Replace(strings.ToLower(pkg.Name), " ", "", -1)
@@ -0,0 +1,103 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	minKubernetesNetworkMTU = 576
+	maxKubernetesNetworkMTU = 9000
+)
+
+var kubernetesNetworkBackends = []string{"vxlan", "host-gw"}
+
+// Asks for the MTU of the pod network and the flannel backend. Overlays need an MTU below the
+// MTU of the nodes' interfaces, e.g. Triton fabric networks, or packets are silently dropped.
+// Both default to what the network provider picks.
+func getKubernetesNetworkConfig(conf config.Config, cfg *baseClusterTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	// Network MTU
+	mtu := ""
+	if conf.IsSet("k8s_network_mtu") {
+		mtu = conf.GetString("k8s_network_mtu")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label: "Kubernetes Network MTU (leave empty for the network provider's default)",
+			Validate: func(input string) error {
+				return validateKubernetesNetworkMTU(input)
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		mtu = result
+	}
+
+	err := validateKubernetesNetworkMTU(mtu)
+	if err != nil {
+		return err
+	}
+	cfg.KubernetesNetworkMTU = mtu
+
+	// Flannel Backend
+	if conf.IsSet("k8s_network_backend") {
+		backend := conf.GetString("k8s_network_backend")
+		if cfg.KubernetesNetworkProvider != "flannel" {
+			return fmt.Errorf("k8s_network_backend is only supported by the flannel network provider, found '%s'.", cfg.KubernetesNetworkProvider)
+		}
+
+		found := false
+		for _, validBackend := range kubernetesNetworkBackends {
+			if backend == validBackend {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return util.ConfigError(fmt.Errorf("Invalid k8s_network_backend '%s', must be 'vxlan' or 'host-gw'.", backend))
+		}
+		cfg.KubernetesNetworkBackend = backend
+	} else if cfg.KubernetesNetworkProvider == "flannel" && !nonInteractiveMode {
+		prompt := promptui.Select{
+			Label: "Flannel Backend",
+			Items: kubernetesNetworkBackends,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Flannel Backend:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		cfg.KubernetesNetworkBackend = value
+	}
+
+	return nil
+}
+
+// An empty MTU leaves it to the network provider.
+func validateKubernetesNetworkMTU(mtu string) error {
+	if mtu == "" {
+		return nil
+	}
+
+	num, err := strconv.Atoi(mtu)
+	if err != nil || num < minKubernetesNetworkMTU || num > maxKubernetesNetworkMTU {
+		return errors.New("k8s_network_mtu must be a number between 576 and 9000")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package create
+
+import "testing"
+
+var validateKubernetesNetworkMTUTestCases = []struct {
+	MTU         string
+	ExpectError bool
+}{
+	{"", false},
+	{"1450", false},
+	{"8950", false},
+	{"575", true},
+	{"9001", true},
+	{"jumbo", true},
+}
+
+func TestValidateKubernetesNetworkMTU(t *testing.T) {
+	for _, tc := range validateKubernetesNetworkMTUTestCases {
+		err := validateKubernetesNetworkMTU(tc.MTU)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for %q", tc.MTU)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.MTU, err)
+		}
+	}
+}
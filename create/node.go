@@ -3,19 +3,25 @@ package create
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
 	"github.com/joyent/triton-kubernetes/shell"
 	"github.com/joyent/triton-kubernetes/state"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
+// A hostname template numbers the nodes with a printf verb instead of the default -1, -2...,
+// e.g. worker-%02d names them worker-01, worker-02...
+var hostnameTemplateRegexp = regexp.MustCompile(`^([^%]+)-(%(0[1-9])?d)$`)
+
 type baseNodeTerraformConfig struct {
 	Source string `json:"source"`
 
@@ -32,6 +38,21 @@ type baseNodeTerraformConfig struct {
 	RancherRegistry         string `json:"rancher_registry,omitempty"`
 	RancherRegistryUsername string `json:"rancher_registry_username,omitempty"`
 	RancherRegistryPassword string `json:"rancher_registry_password,omitempty"`
+
+	NTPServers []string          `json:"ntp_servers,omitempty"`
+	Timezone   string            `json:"timezone,omitempty"`
+	Sysctls    map[string]string `json:"sysctls,omitempty"`
+
+	KubeReserved   map[string]string `json:"kube_reserved,omitempty"`
+	SystemReserved map[string]string `json:"system_reserved,omitempty"`
+
+	DockerEngineInstallURL string `json:"docker_engine_install_url,omitempty"`
+
+	KubernetesAuditPolicy string `json:"k8s_audit_policy,omitempty"`
+
+	KubernetesSecretsEncryptionConfig string `json:"k8s_secrets_encryption_config,omitempty"`
+	KubernetesKMSPluginImage          string `json:"k8s_kms_plugin_image,omitempty"`
+	KubernetesKMSPluginArgs           string `json:"k8s_kms_plugin_args,omitempty"`
 }
 
 type rancherHostLabelsConfig struct {
@@ -40,8 +61,8 @@ type rancherHostLabelsConfig struct {
 	Worker  string `json:"worker,omitempty"`
 }
 
-func NewNode(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func NewNode(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	clusterManagers, err := remoteBackend.States()
 	if err != nil {
 		return err
@@ -52,10 +73,10 @@ func NewNode(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterManager := ""
-	if viper.IsSet("cluster_manager") {
-		selectedClusterManager = viper.GetString("cluster_manager")
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_manager must be specified")
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Cluster Manager",
@@ -100,8 +121,8 @@ func NewNode(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterKey := ""
-	if viper.IsSet("cluster_name") {
-		clusterName := viper.GetString("cluster_name")
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
 		clusterKey, ok := clusters[clusterName]
 		if !ok {
 			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
@@ -109,7 +130,7 @@ func NewNode(remoteBackend backend.Backend) error {
 
 		selectedClusterKey = clusterKey
 	} else if nonInteractiveMode {
-		return errors.New("cluster_name must be specified")
+		return util.ConfigError(errors.New("cluster_name must be specified"))
 	} else {
 		clusterNames := make([]string, 0, len(clusters))
 		for name := range clusters {
@@ -134,7 +155,32 @@ func NewNode(remoteBackend backend.Backend) error {
 		selectedClusterKey = clusters[value]
 	}
 
-	_, err = newNode(selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	err = setMonthlyBudget(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	previousCost, err := estimateMonthlyCost(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	newHostnames, err := newNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	if err != nil {
+		return err
+	}
+
+	err = recordNodePools(currentState, selectedClusterKey, newHostnames)
+	if err != nil {
+		return err
+	}
+
+	// Refuse nodes that would exceed the cluster's budget
+	cost, err := estimateMonthlyCost(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+	err = checkMonthlyBudget(conf, currentState, selectedClusterKey, cost, cost-previousCost)
 	if err != nil {
 		return err
 	}
@@ -153,8 +199,73 @@ func NewNode(remoteBackend backend.Backend) error {
 		}
 	}
 
-	// Get the new state and run terraform apply
-	err = shell.RunTerraformApplyWithState(currentState)
+	// Block on configurations that violate the user's policies
+	err = checkPolicies(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the new nodes will be able to register with the cluster manager
+	err = checkRancherConnectivity(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	// The new nodes register with a token of their own instead of the cluster's
+	tokenHostnames := []string{}
+	tokenNodeKeys := []string{}
+	poolKeys := []string{}
+	if useEphemeralRegistrationTokens(conf) {
+		tokenHostnames, tokenNodeKeys, poolKeys = ephemeralTokenNodes(currentState, selectedClusterKey, newHostnames)
+	}
+	var client *rancher.Client
+	var rancherClusterID string
+	var registrationToken rancher.ClusterRegistrationToken
+	issuedTokens := []string{}
+	activeNodes := 0
+	if len(tokenNodeKeys) > 0 || len(poolKeys) > 0 {
+		client, rancherClusterID, err = rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+		if err != nil {
+			return err
+		}
+
+		activeNodes, err = countActiveNodes(client, rancherClusterID, tokenHostnames)
+		if err != nil {
+			return err
+		}
+	}
+	if len(tokenNodeKeys) > 0 {
+		registrationToken, err = issueRegistrationToken(client, rancherClusterID, currentState, tokenNodeKeys)
+		if err != nil {
+			return err
+		}
+		issuedTokens = append(issuedTokens, registrationToken.Token)
+	}
+	// Instance groups register their replacement instances with the token of their pool, which
+	// is kept
+	for _, poolKey := range poolKeys {
+		poolToken, err := issueRegistrationToken(client, rancherClusterID, currentState, []string{poolKey})
+		if err != nil {
+			return err
+		}
+		issuedTokens = append(issuedTokens, poolToken.Token)
+	}
+
+	// Get the new state and run terraform apply. The key generated for the cluster is
+	// registered again if an apply that failed removed it.
+	err = applyWithTritonClusterSSHKeys(currentState, []string{selectedClusterKey}, func() error {
+		return shell.RunTerraformApplyWithState(conf, currentState, []string{})
+	})
+	if err != nil {
+		err = revokeUnappliedRegistrationTokens(client, rancherClusterID, issuedTokens, err)
+		err = recordApplyCheckpoint(remoteBackend, currentState, fmt.Sprintf("create node %s", strings.Join(newHostnames, ", ")), nil, err)
+		// The token is kept for `triton-kubernetes retry`, which deletes it
+		return recordNodeApplyFailure(conf, remoteBackend, currentState, selectedClusterKey, newHostnames, err)
+	}
+	currentState.ClearCheckpoint()
+
+	// Every node of the cluster converged, including previously failed ones
+	err = clearFailedNodes(currentState, selectedClusterKey)
 	if err != nil {
 		return err
 	}
@@ -165,10 +276,44 @@ func NewNode(remoteBackend backend.Backend) error {
 		return err
 	}
 
+	if len(tokenNodeKeys) > 0 {
+		return revokeRegistrationTokensOnceActive(client, rancherClusterID, []string{registrationToken.Token}, activeNodes+len(tokenHostnames), tokenHostnames, getNodeRegistrationTimeout(conf))
+	}
+
 	return nil
 }
 
-func newNode(selectedClusterManager, selectedClusterKey string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+func newNode(conf config.Config, selectedClusterManager, selectedClusterKey string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	err := setGoldenImageSettings(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return []string{}, err
+	}
+
+	tfVars, err := loadTFVarsFile(conf, "node_tfvars_file")
+	if err != nil {
+		return []string{}, err
+	}
+	defer tfVars.restore()
+
+	newHostnames, err := newProviderNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Node keys are `node_{provider}_{clusterName}_{hostname}`
+	nodeKeys := make([]string, 0, len(newHostnames))
+	for _, hostname := range newHostnames {
+		nodeKeys = append(nodeKeys, strings.Replace(selectedClusterKey, "cluster_", "node_", 1)+"_"+hostname)
+	}
+	err = tfVars.merge(currentState, nodeKeys)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return newHostnames, nil
+}
+
+func newProviderNode(conf config.Config, selectedClusterManager, selectedClusterKey string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
 	// Determine which cloud the selected cluster is in and call the appropriate newNode func
 	parts := strings.Split(selectedClusterKey, "_")
 	if len(parts) < 3 {
@@ -178,23 +323,36 @@ func newNode(selectedClusterManager, selectedClusterKey string, remoteBackend ba
 
 	switch parts[1] {
 	case "triton":
-		return newTritonNode(selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+		return newTritonNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
 	case "aws":
-		return newAWSNode(selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+		return newAWSNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
 	case "gcp":
-		return newGCPNode(selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+		return newGCPNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
 	case "azure":
-		return newAzureNode(selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+		return newAzureNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	case "digitalocean":
+		return newDigitalOceanNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	case "equinixmetal":
+		return newEquinixMetalNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	case "openstack":
+		return newOpenStackNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
 	case "baremetal":
-		return newBareMetalNode(selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+		return newBareMetalNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
 	case "vsphere":
-		return newVSphereNode(selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+		return newVSphereNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	case "proxmox":
+		return newProxmoxNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	case "nutanix":
+		return newNutanixNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
+	case "libvirt":
+		return newLibvirtNode(conf, selectedClusterManager, selectedClusterKey, remoteBackend, currentState)
 	default:
 		return []string{}, fmt.Errorf("Unsupported cloud provider '%s', cannot create node", parts[1])
 	}
 }
 
-func getBaseNodeTerraformConfig(terraformModulePath, selectedCluster string, currentState state.State) (baseNodeTerraformConfig, error) {
+func getBaseNodeTerraformConfig(conf config.Config, terraformModulePath, selectedCluster string, currentState state.State) (baseNodeTerraformConfig, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	cfg := baseNodeTerraformConfig{
 		RancherAPIURL:                   "${module.cluster-manager.rancher_url}",
 		RancherClusterRegistrationToken: fmt.Sprintf("${module.%s.rancher_cluster_registration_token}", selectedCluster),
@@ -209,13 +367,13 @@ func getBaseNodeTerraformConfig(terraformModulePath, selectedCluster string, cur
 	}
 
 	baseSource := defaultSourceURL
-	if viper.IsSet("source_url") {
-		baseSource = viper.GetString("source_url")
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
 	}
 
 	baseSourceRef := defaultSourceRef
-	if viper.IsSet("source_ref") {
-		baseSourceRef = viper.GetString("source_ref")
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
 	}
 
 	cfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, terraformModulePath, baseSourceRef)
@@ -227,8 +385,8 @@ func getBaseNodeTerraformConfig(terraformModulePath, selectedCluster string, cur
 		"etcd",
 		"control",
 	}
-	if viper.IsSet("rancher_host_label") {
-		selectedHostLabel = viper.GetString("rancher_host_label")
+	if conf.IsSet("rancher_host_label") {
+		selectedHostLabel = conf.GetString("rancher_host_label")
 	} else {
 		prompt := promptui.Select{
 			Label: "Which type of node?",
@@ -257,13 +415,28 @@ func getBaseNodeTerraformConfig(terraformModulePath, selectedCluster string, cur
 	case "control":
 		cfg.RancherHostLabels.Control = "true"
 	default:
-		return baseNodeTerraformConfig{}, fmt.Errorf("Invalid rancher_host_label '%s', must be 'worker', 'etcd' or 'control'", selectedHostLabel)
+		return baseNodeTerraformConfig{}, util.ConfigError(fmt.Errorf("Invalid rancher_host_label '%s', must be 'worker', 'etcd' or 'control'", selectedHostLabel))
+	}
+
+	// The API server runs on control nodes, which need the cluster's audit policy
+	if cfg.RancherHostLabels.Control == "true" && currentState.Get(fmt.Sprintf("module.%s.k8s_audit_log", selectedCluster)) == "true" {
+		cfg.KubernetesAuditPolicy = fmt.Sprintf("${module.%s.k8s_audit_policy}", selectedCluster)
+	}
+
+	// The API server also needs the cluster's secrets encryption config, and the KMS plugin of clusters encrypting with a KMS
+	secretsEncryption := currentState.Get(fmt.Sprintf("module.%s.k8s_secrets_encryption", selectedCluster))
+	if cfg.RancherHostLabels.Control == "true" && secretsEncryption != "" {
+		cfg.KubernetesSecretsEncryptionConfig = fmt.Sprintf("${var.k8s_secrets_encryption_config_%s}", selectedCluster)
+		if secretsEncryption == "kms" {
+			cfg.KubernetesKMSPluginImage = fmt.Sprintf("${module.%s.k8s_kms_plugin_image}", selectedCluster)
+			cfg.KubernetesKMSPluginArgs = fmt.Sprintf("${module.%s.k8s_kms_plugin_args}", selectedCluster)
+		}
 	}
 
 	// Allow user to specify number of nodes to be created.
 	var countInput string
-	if viper.IsSet("node_count") {
-		countInput = viper.GetString("node_count")
+	if conf.IsSet("node_count") {
+		countInput = conf.GetString("node_count")
 	} else if cfg.RancherHostLabels.Worker == "true" {
 		prompt := promptui.Prompt{
 			Label: "Number of nodes to create",
@@ -318,17 +491,17 @@ func getBaseNodeTerraformConfig(terraformModulePath, selectedCluster string, cur
 	cfg.NodeCount = nodeCount
 
 	// hostname
-	if viper.IsSet("hostname") {
-		cfg.Hostname = viper.GetString("hostname")
+	if conf.IsSet("hostname") {
+		cfg.Hostname = conf.GetString("hostname")
 	} else {
 		prompt := promptui.Prompt{
-			Label: "Hostname prefix",
+			Label: "Hostname prefix (or template e.g. worker-%02d)",
 			Validate: func(input string) error {
 				if input == "" {
 					return errors.New("hostname prefix cannot be blank")
 				}
 
-				return nil
+				return validateHostnameTemplate(input)
 			},
 		}
 
@@ -340,18 +513,148 @@ func getBaseNodeTerraformConfig(terraformModulePath, selectedCluster string, cur
 	}
 
 	if cfg.Hostname == "" {
-		return baseNodeTerraformConfig{}, errors.New("Invalid Hostname")
+		return baseNodeTerraformConfig{}, util.ConfigError(errors.New("Invalid Hostname"))
+	}
+	err = validateHostnameTemplate(cfg.Hostname)
+	if err != nil {
+		return baseNodeTerraformConfig{}, err
+	}
+
+	// NTP Servers
+	if conf.IsSet("ntp_servers") {
+		cfg.NTPServers = conf.GetStringSlice("ntp_servers")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label:   "NTP Servers (comma separated)",
+			Default: "Default",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
+
+		if result != "Default" {
+			for _, ntpServer := range strings.Split(result, ",") {
+				ntpServer = strings.TrimSpace(ntpServer)
+				if ntpServer != "" {
+					cfg.NTPServers = append(cfg.NTPServers, ntpServer)
+				}
+			}
+		}
+	}
+
+	// Timezone
+	if conf.IsSet("timezone") {
+		cfg.Timezone = conf.GetString("timezone")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Prompt{
+			Label:   "Timezone",
+			Default: "Default",
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
+
+		if result != "Default" {
+			cfg.Timezone = result
+		}
+	}
+
+	// Extra sysctls are optional and only read from the config file. Swap, kernel modules and
+	// the sysctls Kubernetes requires are always configured by the host modules.
+	if conf.IsSet("sysctls") {
+		cfg.Sysctls = conf.GetStringMapString("sysctls")
+		err := validateSysctls(cfg.Sysctls)
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
+	}
+
+	// Resource reservations are optional and only read from the config file. Small instance
+	// types need them for the kubelet and system daemons not to be OOM killed by pods.
+	if conf.IsSet("kube_reserved") {
+		cfg.KubeReserved = conf.GetStringMapString("kube_reserved")
+		err := validateResourceReservation("kube_reserved", cfg.KubeReserved)
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
+	}
+	if conf.IsSet("system_reserved") {
+		cfg.SystemReserved = conf.GetStringMapString("system_reserved")
+		err := validateResourceReservation("system_reserved", cfg.SystemReserved)
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
+	}
+
+	// Docker Engine Version, must be validated by Rancher for the cluster's Kubernetes version
+	kubernetesVersion := currentState.Get(fmt.Sprintf("module.%s.k8s_version", selectedCluster))
+	dockerEngineVersion := defaultDockerEngineVersion
+	if conf.IsSet("docker_engine_version") {
+		dockerEngineVersion = conf.GetString("docker_engine_version")
+	} else if !nonInteractiveMode && kubernetesVersion != "" {
+		versions, err := getDockerEngineVersions(kubernetesVersion)
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
+
+		prompt := promptui.Select{
+			Label: "Docker Engine Version",
+			Items: versions,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: "  {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Docker Engine Version:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
+		dockerEngineVersion = value
+	}
+
+	// The host modules install the default version unless told otherwise
+	if dockerEngineVersion != defaultDockerEngineVersion {
+		if kubernetesVersion == "" {
+			return baseNodeTerraformConfig{}, fmt.Errorf("Cannot verify docker_engine_version '%s', the Kubernetes version of the cluster is unknown", dockerEngineVersion)
+		}
+
+		cfg.DockerEngineInstallURL, err = getDockerEngineInstallURL(dockerEngineVersion, kubernetesVersion)
+		if err != nil {
+			return baseNodeTerraformConfig{}, err
+		}
 	}
 
 	return cfg, nil
 }
 
+// Hostnames with a % must be a template ending in -%d, or -%0Nd to pad the numbers to N digits,
+// so the nodes still make up a node pool named after the prefix.
+func validateHostnameTemplate(hostname string) error {
+	if strings.Contains(hostname, "%") && !hostnameTemplateRegexp.MatchString(hostname) {
+		return util.ConfigError(fmt.Errorf("Invalid hostname '%s', a hostname template must end in -%%d or -%%0Nd e.g. worker-%%02d.", hostname))
+	}
+	return nil
+}
+
 // Returns the hostnames that should be used when adding new nodes. Prevents naming collisions.
+// The node name is either a hostname prefix or a hostname template e.g. worker-%02d.
 func getNewHostnames(existingNames []string, nodeName string, nodesToAdd int) []string {
 	if nodesToAdd < 1 {
 		return []string{}
 	}
 
+	numberFormat := "%d"
+	if match := hostnameTemplateRegexp.FindStringSubmatch(nodeName); match != nil {
+		nodeName, numberFormat = match[1], match[2]
+	}
+
 	// Find the number at which the series of hostnames should start.
 	startNum := 1
 	targetPrefix := nodeName + "-"
@@ -373,7 +676,7 @@ func getNewHostnames(existingNames []string, nodeName string, nodesToAdd int) []
 	// Build the list of hostnames
 	result := []string{}
 	for i := 0; i < nodesToAdd; i++ {
-		result = append(result, fmt.Sprintf("%s-%d", nodeName, startNum+i))
+		result = append(result, targetPrefix+fmt.Sprintf(numberFormat, startNum+i))
 	}
 
 	return result
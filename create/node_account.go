@@ -0,0 +1,185 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Node pools can be created in a different cloud account than their cluster, e.g. to split
+// billing or blast radius. The account is configured with node_ prefixed keys so it doesn't
+// collide with the cluster's own credentials, and is stored in the node module config so each
+// node pool in the state keeps track of the account it lives in.
+
+// Returns true if any of the given account keys are set or, in interactive mode, if the user
+// chooses not to use the cluster's account for the new nodes.
+func useSeparateNodeAccount(conf config.Config, providerName string, accountKeys ...string) (bool, error) {
+	for _, key := range accountKeys {
+		if conf.IsSet(key) {
+			return true, nil
+		}
+	}
+
+	if conf.GetBool("non-interactive") {
+		return false, nil
+	}
+
+	useClusterAccount, err := util.PromptForConfirmation(fmt.Sprintf("Use the cluster's %s account for these nodes", providerName), fmt.Sprintf("Use the cluster's %s account", providerName))
+	if err != nil {
+		return false, err
+	}
+
+	return !useClusterAccount, nil
+}
+
+// Overrides the cluster's Triton account with the node_triton_* keys.
+func getTritonNodeAccountConfig(conf config.Config, cfg *tritonNodeTerraformConfig) error {
+	separateAccount, err := useSeparateNodeAccount(conf, "Triton", "node_triton_account", "node_triton_key_path", "node_triton_key_id", "node_triton_url")
+	if err != nil || !separateAccount {
+		return err
+	}
+
+	cfg.TritonAccount, err = promptForNodeAccountValue(conf, "node_triton_account", "Triton Account Name (for these nodes)", false)
+	if err != nil {
+		return err
+	}
+
+	keyPath, err := promptForNodeAccountValue(conf, "node_triton_key_path", "Triton Key Path (for these nodes)", false)
+	if err != nil {
+		return err
+	}
+	cfg.TritonKeyPath, err = homedir.Expand(keyPath)
+	if err != nil {
+		return err
+	}
+
+	cfg.TritonKeyID, err = util.TritonKeyID("node_triton_key_id", conf.GetString("node_triton_key_id"), cfg.TritonKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// The Triton URL defaults to the cluster's data center
+	if conf.IsSet("node_triton_url") {
+		cfg.TritonURL = conf.GetString("node_triton_url")
+	}
+
+	return nil
+}
+
+// Overrides the cluster's AWS account with the node_aws_* keys. The cluster's subnet,
+// security group and key pair don't exist in another account, so they must be given as well.
+func getAWSNodeAccountConfig(conf config.Config, cfg *awsNodeTerraformConfig) error {
+	separateAccount, err := useSeparateNodeAccount(conf, "AWS", "node_aws_access_key", "node_aws_secret_key", "node_aws_region")
+	if err != nil || !separateAccount {
+		return err
+	}
+
+	cfg.AWSAccessKey, err = promptForNodeAccountValue(conf, "node_aws_access_key", "AWS Access Key (for these nodes)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AWSSecretKey, err = promptForNodeAccountValue(conf, "node_aws_secret_key", "AWS Secret Key (for these nodes)", true)
+	if err != nil {
+		return err
+	}
+
+	// The keys of the other account are used as they are, not as the cluster's role
+	cfg.awsRoleConfig = awsRoleConfig{}
+
+	// The region defaults to the cluster's region
+	if conf.IsSet("node_aws_region") {
+		cfg.AWSRegion = conf.GetString("node_aws_region")
+	}
+
+	cfg.AWSSubnetID, err = promptForNodeAccountValue(conf, "aws_subnet_id", "AWS Subnet ID (in the nodes' account)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AWSSecurityGroupID, err = promptForNodeAccountValue(conf, "aws_security_group_id", "AWS Security Group ID (in the nodes' account)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AWSKeyName, err = promptForNodeAccountValue(conf, "aws_key_name", "AWS Key Pair Name (in the nodes' account)", false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Overrides the cluster's Azure subscription with the node_azure_* keys. The cluster's resource
+// group, network security group and subnet don't exist in another subscription, so they must be
+// given as well.
+func getAzureNodeAccountConfig(conf config.Config, cfg *azureNodeTerraformConfig) error {
+	separateAccount, err := useSeparateNodeAccount(conf, "Azure", "node_azure_subscription_id", "node_azure_client_id", "node_azure_client_secret", "node_azure_tenant_id")
+	if err != nil || !separateAccount {
+		return err
+	}
+
+	cfg.AzureSubscriptionID, err = promptForNodeAccountValue(conf, "node_azure_subscription_id", "Azure Subscription ID (for these nodes)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AzureClientID, err = promptForNodeAccountValue(conf, "node_azure_client_id", "Azure Client ID (for these nodes)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AzureClientSecret, err = promptForNodeAccountValue(conf, "node_azure_client_secret", "Azure Client Secret (for these nodes)", true)
+	if err != nil {
+		return err
+	}
+
+	cfg.AzureTenantID, err = promptForNodeAccountValue(conf, "node_azure_tenant_id", "Azure Tenant ID (for these nodes)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AzureResourceGroupName, err = promptForNodeAccountValue(conf, "azure_resource_group_name", "Azure Resource Group Name (in the nodes' subscription)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AzureNetworkSecurityGroupID, err = promptForNodeAccountValue(conf, "azure_network_security_group_id", "Azure Network Security Group ID (in the nodes' subscription)", false)
+	if err != nil {
+		return err
+	}
+
+	cfg.AzureSubnetID, err = promptForNodeAccountValue(conf, "azure_subnet_id", "Azure Subnet ID (in the nodes' subscription)", false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func promptForNodeAccountValue(conf config.Config, key, label string, secret bool) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	prompt := promptui.Prompt{
+		Label: label,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+	if secret {
+		prompt.Mask = '*'
+	}
+
+	return prompt.Run()
+}
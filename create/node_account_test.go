@@ -0,0 +1,63 @@
+package create
+
+import (
+	"github.com/joyent/triton-kubernetes/config"
+	"testing"
+)
+
+func TestAWSNodeAccountDefaultsToClusterAccount(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+
+	cfg := awsNodeTerraformConfig{
+		AWSAccessKey: "cluster-access-key",
+		AWSSubnetID:  "${module.cluster_aws_test.aws_subnet_id}",
+	}
+
+	err := getAWSNodeAccountConfig(conf, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AWSAccessKey != "cluster-access-key" || cfg.AWSSubnetID != "${module.cluster_aws_test.aws_subnet_id}" {
+		t.Errorf("Expected the cluster account to be kept, got %+v", cfg)
+	}
+}
+
+func TestAWSNodeAccountRequiresNetworkNonInteractiveMode(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("node_aws_access_key", "pool-access-key")
+	conf.Set("node_aws_secret_key", "pool-secret-key")
+
+	cfg := awsNodeTerraformConfig{}
+
+	expected := "aws_subnet_id must be specified"
+	err := getAWSNodeAccountConfig(conf, &cfg)
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestAzureNodeAccountOverridesSubscription(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("node_azure_subscription_id", "pool-subscription")
+	conf.Set("node_azure_client_id", "pool-client")
+	conf.Set("node_azure_client_secret", "pool-secret")
+	conf.Set("node_azure_tenant_id", "pool-tenant")
+	conf.Set("azure_resource_group_name", "pool-rg")
+	conf.Set("azure_network_security_group_id", "pool-nsg")
+	conf.Set("azure_subnet_id", "pool-subnet")
+
+	cfg := azureNodeTerraformConfig{AzureSubscriptionID: "cluster-subscription"}
+
+	err := getAzureNodeAccountConfig(conf, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.AzureSubscriptionID != "pool-subscription" || cfg.AzureSubnetID != "pool-subnet" {
+		t.Errorf("Expected the node pool subscription to be used, got %+v", cfg)
+	}
+}
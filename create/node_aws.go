@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
 	"github.com/joyent/triton-kubernetes/state"
 	"github.com/joyent/triton-kubernetes/util"
 
@@ -17,7 +19,6 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -42,20 +43,32 @@ type awsNodeTerraformConfig struct {
 
 	AWSAccessKey string `json:"aws_access_key"`
 	AWSSecretKey string `json:"aws_secret_key"`
+	awsRoleConfig
 
 	AWSRegion          string `json:"aws_region"`
 	AWSSubnetID        string `json:"aws_subnet_id"`
 	AWSSecurityGroupID string `json:"aws_security_group_id"`
 	AWSKeyName         string `json:"aws_key_name"`
 
+	AWSSSHUser        string `json:"aws_ssh_user,omitempty"`
+	AWSPrivateKeyPath string `json:"aws_private_key_path,omitempty"`
+
+	AWSAvailabilityZone string `json:"aws_availability_zone,omitempty"`
+
 	AWSAMIID        string `json:"aws_ami_id"`
 	AWSInstanceType string `json:"aws_instance_type"`
 
+	AWSSpot         string `json:"aws_spot,omitempty"`
+	AWSSpotMaxPrice string `json:"aws_spot_max_price,omitempty"`
+
 	EBSVolumeDeviceName string `json:"ebs_volume_device_name,omitempty"`
 	EBSVolumeMountPath  string `json:"ebs_volume_mount_path,omitempty"`
 	EBSVolumeType       string `json:"ebs_volume_type,omitempty"`
 	EBSVolumeIOPS       string `json:"ebs_volume_iops,omitempty"`
 	EBSVolumeSize       string `json:"ebs_volume_size,omitempty"`
+
+	AWSIngressLoadBalancer    string   `json:"aws_ingress_load_balancer,omitempty"`
+	AWSIngressTargetGroupARNs []string `json:"aws_ingress_target_group_arns,omitempty"`
 }
 
 // Adds new AWS nodes to the given cluster and manager.
@@ -63,20 +76,25 @@ type awsNodeTerraformConfig struct {
 // - a slice of the hostnames added
 // - the new state
 // - error or nil
-func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseNodeTerraformConfig(awsRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+func newAWSNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseNodeTerraformConfig(conf, awsRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
 	if err != nil {
 		return []string{}, err
 	}
 
+	// The cluster's credentials may reference the temporary credentials of its role, the nodes
+	// reference them too
+	clusterCreds, clusterRole := getAWSRoleConfigFromState(currentState, selectedCluster)
+
 	cfg := awsNodeTerraformConfig{
 		baseNodeTerraformConfig: baseConfig,
 
 		// Grab variables from cluster config
-		AWSAccessKey: currentState.Get(fmt.Sprintf("module.%s.aws_access_key", selectedCluster)),
-		AWSSecretKey: currentState.Get(fmt.Sprintf("module.%s.aws_secret_key", selectedCluster)),
-		AWSRegion:    currentState.Get(fmt.Sprintf("module.%s.aws_region", selectedCluster)),
+		AWSAccessKey:  clusterCreds.AccessKey,
+		AWSSecretKey:  clusterCreds.SecretKey,
+		awsRoleConfig: awsRoleConfig{AWSSessionToken: clusterRole.AWSSessionToken},
+		AWSRegion:     currentState.Get(fmt.Sprintf("module.%s.aws_region", selectedCluster)),
 
 		// Reference terraform output variables from cluster module
 		AWSSubnetID:        fmt.Sprintf("${module.%s.aws_subnet_id}", selectedCluster),
@@ -84,7 +102,27 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 		AWSKeyName:         fmt.Sprintf("${module.%s.aws_key_name}", selectedCluster),
 	}
 
-	creds := credentials.NewStaticCredentials(cfg.AWSAccessKey, cfg.AWSSecretKey, "")
+	// Node pools may use a different account than the cluster
+	err = getAWSNodeAccountConfig(conf, &cfg)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Worker nodes are added to the target groups of the cluster's ingress load balancer
+	ingressLoadBalancerKey, ok := getIngressLoadBalancerKey(currentState, selectedCluster)
+	if ok && cfg.RancherHostLabels.Worker == "true" {
+		cfg.AWSIngressLoadBalancer = "true"
+		cfg.AWSIngressTargetGroupARNs = []string{
+			fmt.Sprintf("${module.%s.aws_ingress_http_target_group_arn}", ingressLoadBalancerKey),
+			fmt.Sprintf("${module.%s.aws_ingress_https_target_group_arn}", ingressLoadBalancerKey),
+		}
+	}
+
+	apiCreds, err := shell.AWSCredentials(conf, currentState, cfg.AWSAccessKey, cfg.AWSSecretKey, cfg.AWSSessionToken)
+	if err != nil {
+		return []string{}, err
+	}
+	creds := credentials.NewStaticCredentials(apiCreds.AccessKey, apiCreds.SecretKey, apiCreds.SessionToken)
 
 	awsConfig := aws.NewConfig().WithCredentials(creds).WithRegion(cfg.AWSRegion)
 	sess, err := session.NewSession(awsConfig)
@@ -93,13 +131,36 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 	}
 	ec2Client := ec2.New(sess)
 
+	// Nodes use the cluster's key pair unless node_aws_key_name references another key pair.
+	// aws_key_name is the cluster's key pair, which may not exist yet.
+	if conf.IsSet("node_aws_key_name") {
+		cfg.AWSKeyName = conf.GetString("node_aws_key_name")
+		err = verifyAWSKeyPair(ec2Client, cfg.AWSKeyName)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// Control nodes of clusters encrypting secrets at rest get the encryption config over SSH
+	if cfg.KubernetesSecretsEncryptionConfig != "" {
+		cfg.AWSPrivateKeyPath, err = getNodeSSHKeyPath(conf, "aws_private_key_path", "AWS Private Key Path", "~/.ssh/id_rsa")
+		if err != nil {
+			return []string{}, err
+		}
+
+		cfg.AWSSSHUser, err = getNodeSSHUser(conf, "aws_ssh_user", "AWS SSH User", "ubuntu")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
 	// AWS AMI ID
-	if viper.IsSet("aws_ami_id") {
-		cfg.AWSAMIID = viper.GetString("aws_ami_id")
+	if conf.IsSet("aws_ami_id") {
+		cfg.AWSAMIID = conf.GetString("aws_ami_id")
 
 		// TODO: Verify aws_ami_id
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("aws_ami_id must be specified")
+		return []string{}, util.ConfigError(errors.New("aws_ami_id must be specified"))
 	} else {
 		// TODO: Ask the user for a search term
 		describeImagesInput := ec2.DescribeImagesInput{
@@ -163,10 +224,10 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 	}
 
 	// AWS Instance Type
-	if viper.IsSet("aws_instance_type") {
-		cfg.AWSInstanceType = viper.GetString("aws_instance_type")
+	if conf.IsSet("aws_instance_type") {
+		cfg.AWSInstanceType = conf.GetString("aws_instance_type")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("aws_instance_type must be specified")
+		return []string{}, util.ConfigError(errors.New("aws_instance_type must be specified"))
 	} else {
 		// AWS doesn't have an API to get a list of available instance types
 		// Ask the user to free form input it
@@ -188,16 +249,38 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 		cfg.AWSInstanceType = result
 	}
 
+	// Worker nodes can be spot instances, which AWS may reclaim when it needs the capacity back
+	useSpot, err := useSpotInstances(conf, cfg.baseNodeTerraformConfig, "aws_spot", "Create these nodes as spot instances")
+	if err != nil {
+		return []string{}, err
+	}
+	if useSpot {
+		cfg.AWSSpot = "true"
+		cfg.AWSSpotMaxPrice, err = getSpotMaxPrice(conf, "aws_spot_max_price")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// Worker nodes can be created as an Auto Scaling Group instead of individual instances
+	useAutoScaling, err := useAWSAutoScaling(conf, cfg.baseNodeTerraformConfig)
+	if err != nil {
+		return []string{}, err
+	}
+	if useAutoScaling {
+		return newAWSNodePool(conf, cfg, selectedCluster, currentState)
+	}
+
 	// EBS Volume
-	deviceNameIsSet := viper.IsSet("ebs_volume_device_name")
-	mountPathIsSet := viper.IsSet("ebs_volume_mount_path")
-	volumeSizeIsSet := viper.IsSet("ebs_volume_size")
-	volumeTypeIsSet := viper.IsSet("ebs_volume_type")
+	deviceNameIsSet := conf.IsSet("ebs_volume_device_name")
+	mountPathIsSet := conf.IsSet("ebs_volume_mount_path")
+	volumeSizeIsSet := conf.IsSet("ebs_volume_size")
+	volumeTypeIsSet := conf.IsSet("ebs_volume_type")
 	if nonInteractiveMode && deviceNameIsSet {
-		cfg.EBSVolumeDeviceName = viper.GetString("ebs_volume_device_name")
+		cfg.EBSVolumeDeviceName = conf.GetString("ebs_volume_device_name")
 		// Volume Type
 		if volumeTypeIsSet {
-			cfg.EBSVolumeType = viper.GetString("ebs_volume_type")
+			cfg.EBSVolumeType = conf.GetString("ebs_volume_type")
 		}
 
 		// Validating Volume Type
@@ -213,7 +296,7 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 		}
 
 		if volumeSizeIsSet {
-			cfg.EBSVolumeSize = viper.GetString("ebs_volume_size")
+			cfg.EBSVolumeSize = conf.GetString("ebs_volume_size")
 		} else {
 			// If volume size is not defined, use the default value
 			for _, volumeType := range ebsVolumeTypes {
@@ -233,7 +316,7 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 		if shouldCreateVolume {
 			// EBS device name
 			if deviceNameIsSet {
-				cfg.EBSVolumeDeviceName = viper.GetString("ebs_volume_device_name")
+				cfg.EBSVolumeDeviceName = conf.GetString("ebs_volume_device_name")
 			} else {
 				prompt := promptui.Prompt{
 					Label: "EBS Volume Device Name",
@@ -259,7 +342,7 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 
 			// Mount Path
 			if mountPathIsSet {
-				cfg.EBSVolumeMountPath = viper.GetString("ebs_volume_mount_path")
+				cfg.EBSVolumeMountPath = conf.GetString("ebs_volume_mount_path")
 			} else {
 				prompt := promptui.Prompt{
 					Label: "EBS Volume Mount Path",
@@ -273,7 +356,7 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 			}
 
 			if volumeTypeIsSet {
-				cfg.EBSVolumeType = viper.GetString("ebs_volume_type")
+				cfg.EBSVolumeType = conf.GetString("ebs_volume_type")
 			} else {
 				prompt := promptui.Select{
 					Label: "EBS Volume Type",
@@ -295,7 +378,7 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 
 			// EBS Volume Size
 			if volumeSizeIsSet {
-				cfg.EBSVolumeSize = viper.GetString("ebs_volume_size")
+				cfg.EBSVolumeSize = conf.GetString("ebs_volume_size")
 			} else {
 				defaultSize := ""
 				for _, volumeType := range ebsVolumeTypes {
@@ -340,10 +423,20 @@ func newAWSNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 	// Determine what the hostnames should be for the new node(s)
 	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
 
+	// Nodes of clusters with availability zones go in the subnet of their zone
+	zones, err := getAWSNodeAvailabilityZones(conf, currentState, selectedCluster, cfg, len(newHostnames))
+	if err != nil {
+		return []string{}, err
+	}
+
 	// Add new node to terraform config with the new hostnames
-	for _, newHostname := range newHostnames {
+	for i, newHostname := range newHostnames {
 		cfgCopy := cfg
 		cfgCopy.Hostname = newHostname
+		if len(zones) > 0 {
+			cfgCopy.AWSAvailabilityZone = zones[i]
+			cfgCopy.AWSSubnetID = awsZoneSubnetID(selectedCluster, zones[i])
+		}
 		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
 		if err != nil {
 			return []string{}, err
@@ -0,0 +1,258 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	awsRancherKubernetesASGTerraformModulePath = "terraform/modules/aws-rancher-k8s-asg"
+)
+
+// Worker pools can be backed by an Auto Scaling Group instead of one EC2 instance per node
+// module, so AWS replaces unhealthy instances. The whole pool is a single node module named
+// after the hostname prefix, its instances are named {hostname}-{instance id}. The group is
+// named {cluster manager}-{cluster}-{hostname}.
+type awsNodePoolTerraformConfig struct {
+	baseNodeTerraformConfig
+
+	AWSAccessKey string `json:"aws_access_key"`
+	AWSSecretKey string `json:"aws_secret_key"`
+	awsRoleConfig
+
+	AWSRegion          string `json:"aws_region"`
+	AWSSubnetID        string `json:"aws_subnet_id"`
+	AWSSecurityGroupID string `json:"aws_security_group_id"`
+	AWSKeyName         string `json:"aws_key_name"`
+
+	// The subnets of the cluster's availability zones the group spans, instead of aws_subnet_id
+	AWSSubnetIDs []string `json:"aws_subnet_ids,omitempty"`
+
+	AWSAMIID        string `json:"aws_ami_id"`
+	AWSInstanceType string `json:"aws_instance_type"`
+
+	AWSSpot         string `json:"aws_spot,omitempty"`
+	AWSSpotMaxPrice string `json:"aws_spot_max_price,omitempty"`
+
+	AWSASGName            string `json:"aws_asg_name,omitempty"`
+	AWSASGMinSize         int    `json:"aws_asg_min_size"`
+	AWSASGMaxSize         int    `json:"aws_asg_max_size"`
+	AWSASGDesiredCapacity int    `json:"aws_asg_desired_capacity"`
+
+	AWSIngressTargetGroupARNs []string `json:"aws_ingress_target_group_arns,omitempty"`
+}
+
+// Returns true if the nodes should be created as an Auto Scaling Group. Only worker nodes
+// can be, etcd and control nodes need stable identities.
+func useAWSAutoScaling(conf config.Config, cfg baseNodeTerraformConfig) (bool, error) {
+	if cfg.RancherHostLabels.Worker != "true" {
+		if conf.GetBool("aws_autoscaling") {
+			return false, errors.New("aws_autoscaling is only supported for worker nodes")
+		}
+		return false, nil
+	}
+
+	if conf.IsSet("aws_autoscaling") {
+		return conf.GetBool("aws_autoscaling"), nil
+	} else if conf.GetBool("non-interactive") {
+		return false, nil
+	}
+
+	return util.PromptForConfirmation("Create these nodes as an Auto Scaling Group", "Auto Scaling Group")
+}
+
+// Adds the nodes as a single Auto Scaling Group node module. The desired capacity is the node
+// count, the minimum and maximum size default to it.
+// Returns:
+// - a slice with the name of the node pool
+// - error or nil
+func newAWSNodePool(conf config.Config, cfg awsNodeTerraformConfig, selectedCluster string, currentState state.State) ([]string, error) {
+	baseSource := defaultSourceURL
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
+	}
+
+	baseSourceRef := defaultSourceRef
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
+	}
+
+	poolCfg := awsNodePoolTerraformConfig{
+		baseNodeTerraformConfig: cfg.baseNodeTerraformConfig,
+
+		AWSAccessKey:       cfg.AWSAccessKey,
+		AWSSecretKey:       cfg.AWSSecretKey,
+		awsRoleConfig:      cfg.awsRoleConfig,
+		AWSRegion:          cfg.AWSRegion,
+		AWSSubnetID:        cfg.AWSSubnetID,
+		AWSSecurityGroupID: cfg.AWSSecurityGroupID,
+		AWSKeyName:         cfg.AWSKeyName,
+		AWSAMIID:           cfg.AWSAMIID,
+		AWSInstanceType:    cfg.AWSInstanceType,
+
+		AWSSpot:         cfg.AWSSpot,
+		AWSSpotMaxPrice: cfg.AWSSpotMaxPrice,
+
+		AWSASGDesiredCapacity: cfg.NodeCount,
+
+		AWSIngressTargetGroupARNs: cfg.AWSIngressTargetGroupARNs,
+	}
+	poolCfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, awsRancherKubernetesASGTerraformModulePath, baseSourceRef)
+
+	var err error
+	poolCfg.AWSASGMinSize, err = getAutoScalingSize(conf, "aws_asg_min_size", "Minimum number of nodes", cfg.NodeCount)
+	if err != nil {
+		return []string{}, err
+	}
+
+	poolCfg.AWSASGMaxSize, err = getAutoScalingSize(conf, "aws_asg_max_size", "Maximum number of nodes", cfg.NodeCount)
+	if err != nil {
+		return []string{}, err
+	}
+
+	if poolCfg.AWSASGMinSize > poolCfg.AWSASGDesiredCapacity || poolCfg.AWSASGDesiredCapacity > poolCfg.AWSASGMaxSize {
+		return []string{}, fmt.Errorf("node_count must be between aws_asg_min_size and aws_asg_max_size. Found %d, %d and %d.", poolCfg.AWSASGDesiredCapacity, poolCfg.AWSASGMinSize, poolCfg.AWSASGMaxSize)
+	}
+
+	// Pools of clusters with availability zones span the subnets of the zones
+	poolCfg.AWSSubnetIDs, err = getAWSNodePoolSubnetIDs(conf, currentState, selectedCluster, cfg)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// The pool is named after the hostname prefix, which must not already be in use
+	if hostnameTemplateRegexp.MatchString(poolCfg.Hostname) {
+		return []string{}, fmt.Errorf("hostname can't be a template with aws_autoscaling, the cloud names the instances of the pool.")
+	}
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	if _, ok := nodes[poolCfg.Hostname]; ok {
+		return []string{}, fmt.Errorf("A node pool named '%s' already exists.", poolCfg.Hostname)
+	}
+
+	poolCfg.AWSASGName = newNodePoolGroupName(currentState, selectedCluster, poolCfg.Hostname)
+
+	err = currentState.AddNode(selectedCluster, poolCfg.Hostname, poolCfg)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return []string{poolCfg.Hostname}, nil
+}
+
+func getAutoScalingSize(conf config.Config, key, label string, nodeCount int) (int, error) {
+	sizeInput := strconv.Itoa(nodeCount)
+	if conf.IsSet(key) {
+		sizeInput = conf.GetString(key)
+	} else if !conf.GetBool("non-interactive") {
+		prompt := promptui.Prompt{
+			Label: label,
+			Validate: func(input string) error {
+				_, err := strconv.Atoi(input)
+				if err != nil {
+					return errors.New("Invalid number")
+				}
+				return nil
+			},
+			Default: sizeInput,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return 0, err
+		}
+		sizeInput = result
+	}
+
+	size, err := strconv.Atoi(sizeInput)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a valid number. Found '%s'.", key, sizeInput)
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("%s must not be negative. Found '%d'.", key, size)
+	}
+
+	return size, nil
+}
+
+// Returns the hostnames of the running instances of the Auto Scaling Group of the given node module.
+func awsNodePoolHostnames(conf config.Config, currentState state.State, nodeKey string) ([]string, error) {
+	accessKey := currentState.Get(fmt.Sprintf("module.%s.aws_access_key", nodeKey))
+	secretKey := currentState.Get(fmt.Sprintf("module.%s.aws_secret_key", nodeKey))
+	sessionToken := currentState.Get(fmt.Sprintf("module.%s.aws_session_token", nodeKey))
+	region := currentState.Get(fmt.Sprintf("module.%s.aws_region", nodeKey))
+	hostname := currentState.Get(fmt.Sprintf("module.%s.hostname", nodeKey))
+	groupName := nodePoolGroupName(currentState, nodeKey, "aws_asg_name")
+
+	apiCreds, err := shell.AWSCredentials(conf, currentState, accessKey, secretKey, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	creds := credentials.NewStaticCredentials(apiCreds.AccessKey, apiCreds.SecretKey, apiCreds.SessionToken)
+	awsConfig := aws.NewConfig().WithCredentials(creds).WithRegion(region)
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	ec2Client := ec2.New(sess)
+
+	// Instances launched by an Auto Scaling Group are tagged with its name
+	input := ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:aws:autoscaling:groupName"),
+				Values: []*string{aws.String(groupName)},
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{"pending", "running"}),
+			},
+		},
+	}
+
+	hostnames := []string{}
+	err = ec2Client.DescribeInstancesPages(&input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				hostnames = append(hostnames, fmt.Sprintf("%s-%s", hostname, aws.StringValue(instance.InstanceId)))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hostnames, nil
+}
+
+// Returns true if the hostname belongs to an instance of the given Auto Scaling Group.
+func isAWSNodePoolMember(poolName, hostname string) bool {
+	return strings.HasPrefix(hostname, poolName+"-i-")
+}
+
+// Returns an error if the capacity is outside of the Auto Scaling Group's size limits.
+func validateAWSNodePoolCapacity(currentState state.State, nodeKey string, capacity int) error {
+	minSize := currentState.GetInt(fmt.Sprintf("module.%s.aws_asg_min_size", nodeKey))
+	maxSize := currentState.GetInt(fmt.Sprintf("module.%s.aws_asg_max_size", nodeKey))
+	if capacity < minSize || capacity > maxSize {
+		return fmt.Errorf("node_count must be between aws_asg_min_size and aws_asg_max_size. Found %d, %d and %d.", capacity, minSize, maxSize)
+	}
+
+	return nil
+}
@@ -8,16 +8,14 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
 	"github.com/joyent/triton-kubernetes/util"
 
-	"github.com/Azure/azure-sdk-for-go/arm/compute"
-	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -45,9 +43,14 @@ type azureNodeTerraformConfig struct {
 	AzureImageVersion   string `json:"azure_image_version,omitempty"`
 	AzureSSHUser        string `json:"azure_ssh_user"`
 	AzurePublicKeyPath  string `json:"azure_public_key_path"`
+	AzurePublicKey      string `json:"azure_public_key,omitempty"`
+	AzurePrivateKeyPath string `json:"azure_private_key_path,omitempty"`
 
 	AzureDiskMountPath string `json:"azure_disk_mount_path"`
 	AzureDiskSize      string `json:"azure_disk_size"`
+
+	AzurePriority    string `json:"azure_priority,omitempty"`
+	AzureMaxBidPrice string `json:"azure_max_bid_price,omitempty"`
 }
 
 // Adds new Azure nodes to the given cluster and manager.
@@ -55,9 +58,9 @@ type azureNodeTerraformConfig struct {
 // - a slice of the hostnames added
 // - the new state
 // - error or nil
-func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseNodeTerraformConfig(azureRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+func newAzureNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseNodeTerraformConfig(conf, azureRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
 	if err != nil {
 		return []string{}, err
 	}
@@ -79,6 +82,12 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 		AzureSubnetID:               fmt.Sprintf("${module.%s.azure_subnet_id}", selectedCluster),
 	}
 
+	// Node pools may use a different subscription than the cluster
+	err = getAzureNodeAccountConfig(conf, &cfg)
+	if err != nil {
+		return []string{}, err
+	}
+
 	// Terraform expects public/government/german/china for azure environment
 	// Azure SDK expects `Azure{Environment}Cloud`
 	azureEnv, err := azure.EnvironmentFromName(fmt.Sprintf("Azure%sCloud", cfg.AzureEnvironment))
@@ -96,22 +105,14 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 		return []string{}, err
 	}
 
-	azureVMSizesClient := compute.NewVirtualMachineSizesClientWithBaseURI(azureEnv.ResourceManagerEndpoint, cfg.AzureSubscriptionID)
-	azureVMSizesClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
-
-	azureRawVMSizes, err := azureVMSizesClient.List(strings.Replace(strings.ToLower(cfg.AzureLocation), " ", "", -1))
+	azureVMSizes, err := getAzureVMSizes(azureEnv, azureSPT, cfg.AzureSubscriptionID, cfg.AzureLocation, conf.GetBool("azure_size_within_quota"))
 	if err != nil {
 		return []string{}, err
 	}
 
-	azureVMSizes := []string{}
-	for _, size := range *azureRawVMSizes.Value {
-		azureVMSizes = append(azureVMSizes, *size.Name)
-	}
-
 	// Azure Size
-	if viper.IsSet("azure_size") {
-		cfg.AzureSize = viper.GetString("azure_size")
+	if conf.IsSet("azure_size") {
+		cfg.AzureSize = conf.GetString("azure_size")
 
 		// Verify selected azure size exists
 		found := false
@@ -122,10 +123,10 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 			}
 		}
 		if !found {
-			return []string{}, fmt.Errorf("Invalid azure_size '%s', must be one of the following: %s", cfg.AzureSize, strings.Join(azureVMSizes, ", "))
+			return []string{}, util.ConfigError(fmt.Errorf("Invalid azure_size '%s', must be one of the following: %s", cfg.AzureSize, strings.Join(azureVMSizes, ", ")))
 		}
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("azure_size must be specified")
+		return []string{}, util.ConfigError(errors.New("azure_size must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Azure Size",
@@ -151,28 +152,20 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 		cfg.AzureSize = value
 	}
 
-	azureImagesClient := compute.NewVirtualMachineImagesClientWithBaseURI(azureEnv.ResourceManagerEndpoint, cfg.AzureSubscriptionID)
-	azureImagesClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
-
-	// imageResults, err := azureImagesClient.List("westus", "Canonical", "UbuntuServer", "16.04-LTS", "", nil, "")
-	// if err != nil {
-	// 	return []string{}, err
-	// }
-
-	// for _, x := range *imageResults.Value {
-	// 	fmt.Println(*x.Name)
-	// }
-
-	// cfg.AzureImagePublisher = "Canonical"
-	// cfg.AzureImageOffer = "UbuntuServer"
-	// cfg.AzureImageSKU = "16.04-LTS"
-	// cfg.AzureImageVersion = ""
+	image, err := getAzureImage(conf, azureEnv, azureSPT, cfg.AzureSubscriptionID, cfg.AzureLocation)
+	if err != nil {
+		return []string{}, err
+	}
+	cfg.AzureImagePublisher = image.Publisher
+	cfg.AzureImageOffer = image.Offer
+	cfg.AzureImageSKU = image.SKU
+	cfg.AzureImageVersion = image.Version
 
 	// Azure SSH User
-	if viper.IsSet("azure_ssh_user") {
-		cfg.AzureSSHUser = viper.GetString("azure_ssh_user")
+	if conf.IsSet("azure_ssh_user") {
+		cfg.AzureSSHUser = conf.GetString("azure_ssh_user")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("azure_ssh_user must be specified")
+		return []string{}, util.ConfigError(errors.New("azure_ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Azure SSH User",
@@ -186,9 +179,14 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 		cfg.AzureSSHUser = result
 	}
 
-	// Azure Public Key Path
-	if viper.IsSet("azure_public_key_path") {
-		expandedPublicKeyPath, err := homedir.Expand(viper.GetString("azure_public_key_path"))
+	// Azure Public Key Path, unless the key of an Azure SSH public key resource is used
+	if conf.IsSet("azure_ssh_public_key_id") {
+		cfg.AzurePublicKey, err = getAzureSSHPublicKey(azureEnv, azureSPT, conf.GetString("azure_ssh_public_key_id"))
+		if err != nil {
+			return []string{}, err
+		}
+	} else if conf.IsSet("azure_public_key_path") {
+		expandedPublicKeyPath, err := homedir.Expand(conf.GetString("azure_public_key_path"))
 		if err != nil {
 			return []string{}, err
 		}
@@ -196,7 +194,7 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 		cfg.AzurePublicKeyPath = expandedPublicKeyPath
 
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("azure_public_key_path must be specified")
+		return []string{}, util.ConfigError(errors.New("azure_public_key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Azure Public Key Path",
@@ -230,15 +228,45 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 		cfg.AzurePublicKeyPath = expandedPublicKeyPath
 	}
 
+	// Control nodes of clusters encrypting secrets at rest get the encryption config over SSH
+	if cfg.KubernetesSecretsEncryptionConfig != "" {
+		cfg.AzurePrivateKeyPath, err = getNodeSSHKeyPath(conf, "azure_private_key_path", "Azure Private Key Path", "~/.ssh/id_rsa")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// Worker nodes can be spot VMs, which Azure may evict when it needs the capacity back
+	useSpot, err := useSpotInstances(conf, cfg.baseNodeTerraformConfig, "azure_spot", "Create these nodes as spot VMs")
+	if err != nil {
+		return []string{}, err
+	}
+	if useSpot {
+		cfg.AzurePriority = "Spot"
+		cfg.AzureMaxBidPrice, err = getSpotMaxPrice(conf, "azure_spot_max_price")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// Worker nodes can be created as a VM Scale Set instead of individual virtual machines
+	useScaleSet, err := useAzureScaleSet(conf, cfg.baseNodeTerraformConfig)
+	if err != nil {
+		return []string{}, err
+	}
+	if useScaleSet {
+		return newAzureNodePool(conf, cfg, selectedCluster, currentState)
+	}
+
 	// Azure Disk
-	diskMountPathIsSet := viper.IsSet("azure_disk_mount_path")
-	diskSizeIsSet := viper.IsSet("azure_disk_size")
+	diskMountPathIsSet := conf.IsSet("azure_disk_mount_path")
+	diskSizeIsSet := conf.IsSet("azure_disk_size")
 	if nonInteractiveMode {
 		if diskMountPathIsSet && !diskSizeIsSet {
 			return nil, errors.New("If azure_disk_mount_path is set, then azure_disk_size must also be set.")
 		} else if diskMountPathIsSet {
-			cfg.AzureDiskMountPath = viper.GetString("azure_disk_mount_path")
-			cfg.AzureDiskSize = viper.GetString("azure_disk_size")
+			cfg.AzureDiskMountPath = conf.GetString("azure_disk_mount_path")
+			cfg.AzureDiskSize = conf.GetString("azure_disk_size")
 		}
 	} else {
 		shouldCreateDisk, err := util.PromptForConfirmation("Create a disk for this node", "Disk created")
@@ -249,7 +277,7 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 		if shouldCreateDisk {
 			// GCP Disk Mount path
 			if diskMountPathIsSet {
-				cfg.AzureDiskMountPath = viper.GetString("azure_disk_mount_path")
+				cfg.AzureDiskMountPath = conf.GetString("azure_disk_mount_path")
 			} else {
 				prompt := promptui.Prompt{
 					Label: "Azure Disk Mount Path",
@@ -263,7 +291,7 @@ func newAzureNode(selectedClusterManager, selectedCluster string, remoteBackend
 			}
 
 			if diskSizeIsSet {
-				cfg.AzureDiskSize = viper.GetString("azure_disk_size")
+				cfg.AzureDiskSize = conf.GetString("azure_disk_size")
 			} else {
 				prompt := promptui.Prompt{
 					Label: "Azure Disk Size in GB",
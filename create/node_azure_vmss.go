@@ -0,0 +1,327 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+const (
+	azureRancherKubernetesVMSSTerraformModulePath = "terraform/modules/azure-rancher-k8s-vmss"
+
+	// Instance protection isn't part of the vendored compute API version
+	azureInstanceProtectionAPIVersion = "2019-03-01"
+
+	// Azure limits the names of Linux scale sets to 64 characters
+	maxAzureVMSSNameLength = 64
+)
+
+// Worker pools can be backed by a VM Scale Set, so scaling the pool is a single capacity
+// change. The whole pool is a single node module named after the hostname prefix, Azure names
+// its instances {hostname}-{instance id in base 36}. The scale set is named
+// {cluster manager}-{cluster}-{hostname}.
+type azureNodePoolTerraformConfig struct {
+	baseNodeTerraformConfig
+
+	AzureSubscriptionID string `json:"azure_subscription_id"`
+	AzureClientID       string `json:"azure_client_id"`
+	AzureClientSecret   string `json:"azure_client_secret"`
+	AzureTenantID       string `json:"azure_tenant_id"`
+	AzureEnvironment    string `json:"azure_environment"`
+
+	AzureLocation               string `json:"azure_location"`
+	AzureResourceGroupName      string `json:"azure_resource_group_name"`
+	AzureNetworkSecurityGroupID string `json:"azure_network_security_group_id"`
+	AzureSubnetID               string `json:"azure_subnet_id"`
+
+	AzureSize           string `json:"azure_size"`
+	AzureImagePublisher string `json:"azure_image_publisher,omitempty"`
+	AzureImageOffer     string `json:"azure_image_offer,omitempty"`
+	AzureImageSKU       string `json:"azure_image_sku,omitempty"`
+	AzureImageVersion   string `json:"azure_image_version,omitempty"`
+	AzureSSHUser        string `json:"azure_ssh_user"`
+	AzurePublicKeyPath  string `json:"azure_public_key_path"`
+	AzurePublicKey      string `json:"azure_public_key,omitempty"`
+
+	AzureVMSSName     string `json:"azure_vmss_name,omitempty"`
+	AzureVMSSCapacity int    `json:"azure_vmss_capacity"`
+
+	AzurePriority    string `json:"azure_priority,omitempty"`
+	AzureMaxBidPrice string `json:"azure_max_bid_price,omitempty"`
+}
+
+// Returns true if the nodes should be created as a VM Scale Set. Only worker nodes can be,
+// etcd and control nodes need stable identities.
+func useAzureScaleSet(conf config.Config, cfg baseNodeTerraformConfig) (bool, error) {
+	if cfg.RancherHostLabels.Worker != "true" {
+		if conf.GetBool("azure_vmss") {
+			return false, errors.New("azure_vmss is only supported for worker nodes")
+		}
+		return false, nil
+	}
+
+	if conf.IsSet("azure_vmss") {
+		return conf.GetBool("azure_vmss"), nil
+	} else if conf.GetBool("non-interactive") {
+		return false, nil
+	}
+
+	return util.PromptForConfirmation("Create these nodes as a VM Scale Set", "VM Scale Set")
+}
+
+// Adds the nodes as a single VM Scale Set node module with the node count as capacity.
+// Returns:
+// - a slice with the name of the node pool
+// - error or nil
+func newAzureNodePool(conf config.Config, cfg azureNodeTerraformConfig, selectedCluster string, currentState state.State) ([]string, error) {
+	if conf.IsSet("azure_disk_mount_path") {
+		return []string{}, errors.New("azure_disk_mount_path is not supported for VM Scale Sets")
+	}
+
+	baseSource := defaultSourceURL
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
+	}
+
+	baseSourceRef := defaultSourceRef
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
+	}
+
+	poolCfg := azureNodePoolTerraformConfig{
+		baseNodeTerraformConfig: cfg.baseNodeTerraformConfig,
+
+		AzureSubscriptionID: cfg.AzureSubscriptionID,
+		AzureClientID:       cfg.AzureClientID,
+		AzureClientSecret:   cfg.AzureClientSecret,
+		AzureTenantID:       cfg.AzureTenantID,
+		AzureEnvironment:    cfg.AzureEnvironment,
+
+		AzureLocation:               cfg.AzureLocation,
+		AzureResourceGroupName:      cfg.AzureResourceGroupName,
+		AzureNetworkSecurityGroupID: cfg.AzureNetworkSecurityGroupID,
+		AzureSubnetID:               cfg.AzureSubnetID,
+
+		AzureSize:           cfg.AzureSize,
+		AzureImagePublisher: cfg.AzureImagePublisher,
+		AzureImageOffer:     cfg.AzureImageOffer,
+		AzureImageSKU:       cfg.AzureImageSKU,
+		AzureImageVersion:   cfg.AzureImageVersion,
+		AzureSSHUser:        cfg.AzureSSHUser,
+		AzurePublicKeyPath:  cfg.AzurePublicKeyPath,
+		AzurePublicKey:      cfg.AzurePublicKey,
+
+		AzureVMSSCapacity: cfg.NodeCount,
+
+		AzurePriority:    cfg.AzurePriority,
+		AzureMaxBidPrice: cfg.AzureMaxBidPrice,
+	}
+	poolCfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, azureRancherKubernetesVMSSTerraformModulePath, baseSourceRef)
+
+	// The pool is named after the hostname prefix, which must not already be in use
+	if hostnameTemplateRegexp.MatchString(poolCfg.Hostname) {
+		return []string{}, fmt.Errorf("hostname can't be a template with azure_vmss, the cloud names the instances of the pool.")
+	}
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	if _, ok := nodes[poolCfg.Hostname]; ok {
+		return []string{}, fmt.Errorf("A node pool named '%s' already exists.", poolCfg.Hostname)
+	}
+
+	poolCfg.AzureVMSSName = newNodePoolGroupName(currentState, selectedCluster, poolCfg.Hostname)
+	if len(poolCfg.AzureVMSSName) > maxAzureVMSSNameLength {
+		return []string{}, util.ConfigError(fmt.Errorf("The scale set name '%s' is longer than %d characters, use a shorter hostname.", poolCfg.AzureVMSSName, maxAzureVMSSNameLength))
+	}
+
+	err = currentState.AddNode(selectedCluster, poolCfg.Hostname, poolCfg)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return []string{poolCfg.Hostname}, nil
+}
+
+// Returns true if the hostname belongs to an instance of the given scale set.
+func isAzureNodePoolMember(poolName, hostname string) bool {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(poolName) + "-[0-9a-z]{6}$").MatchString(hostname)
+}
+
+type azureNodePoolClient struct {
+	vmsClient         compute.VirtualMachineScaleSetVMsClient
+	resourceGroupName string
+	scaleSetName      string
+}
+
+// Returns a client for the instances of the VM Scale Set of the given node module. The
+// resource group is read from the cluster's outputs when the pool uses the cluster's.
+func getAzureNodePoolClient(conf config.Config, currentState state.State, nodeKey string) (azureNodePoolClient, error) {
+	environment := currentState.Get(fmt.Sprintf("module.%s.azure_environment", nodeKey))
+	subscriptionID := currentState.Get(fmt.Sprintf("module.%s.azure_subscription_id", nodeKey))
+	tenantID := currentState.Get(fmt.Sprintf("module.%s.azure_tenant_id", nodeKey))
+	clientID := currentState.Get(fmt.Sprintf("module.%s.azure_client_id", nodeKey))
+	clientSecret := currentState.Get(fmt.Sprintf("module.%s.azure_client_secret", nodeKey))
+
+	resourceGroupName := currentState.Get(fmt.Sprintf("module.%s.azure_resource_group_name", nodeKey))
+	if strings.HasPrefix(resourceGroupName, "${module.") {
+		clusterKey := strings.TrimSuffix(strings.TrimPrefix(resourceGroupName, "${module."), ".azure_resource_group_name}")
+		outputs, err := shell.RunTerraformOutputWithState(conf, currentState, clusterKey)
+		if err != nil {
+			return azureNodePoolClient{}, err
+		}
+		resourceGroupName, _ = outputs["azure_resource_group_name"].(string)
+	}
+
+	azureEnv, err := azure.EnvironmentFromName(fmt.Sprintf("Azure%sCloud", environment))
+	if err != nil {
+		return azureNodePoolClient{}, err
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(azureEnv.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return azureNodePoolClient{}, err
+	}
+
+	azureSPT, err := adal.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, azureEnv.ResourceManagerEndpoint)
+	if err != nil {
+		return azureNodePoolClient{}, err
+	}
+
+	vmsClient := compute.NewVirtualMachineScaleSetVMsClientWithBaseURI(azureEnv.ResourceManagerEndpoint, subscriptionID)
+	vmsClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
+
+	return azureNodePoolClient{
+		vmsClient:         vmsClient,
+		resourceGroupName: resourceGroupName,
+		scaleSetName:      nodePoolGroupName(currentState, nodeKey, "azure_vmss_name"),
+	}, nil
+}
+
+// Returns the instances of the scale set by hostname.
+func (client azureNodePoolClient) instances() (map[string]compute.VirtualMachineScaleSetVM, error) {
+	result := map[string]compute.VirtualMachineScaleSetVM{}
+
+	list, err := client.vmsClient.List(client.resourceGroupName, client.scaleSetName, "", "", "")
+	for {
+		if err != nil {
+			return nil, err
+		}
+
+		if list.Value != nil {
+			for _, vm := range *list.Value {
+				if vm.VirtualMachineScaleSetVMProperties == nil || vm.OsProfile == nil || vm.OsProfile.ComputerName == nil {
+					continue
+				}
+				result[*vm.OsProfile.ComputerName] = vm
+			}
+		}
+
+		if list.NextLink == nil || *list.NextLink == "" {
+			return result, nil
+		}
+		list, err = client.vmsClient.ListNextResults(list)
+	}
+}
+
+// Sets whether the instance may be removed when the capacity of the scale set is reduced.
+func (client azureNodePoolClient) protectFromScaleIn(instanceID string, protect bool) error {
+	body := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"protectionPolicy": map[string]interface{}{
+				"protectFromScaleIn": protect,
+			},
+		},
+	}
+
+	pathParameters := map[string]interface{}{
+		"instanceId":        autorest.Encode("path", instanceID),
+		"resourceGroupName": autorest.Encode("path", client.resourceGroupName),
+		"subscriptionId":    autorest.Encode("path", client.vmsClient.SubscriptionID),
+		"VMScaleSetName":    autorest.Encode("path", client.scaleSetName),
+	}
+	queryParameters := map[string]interface{}{
+		"api-version": azureInstanceProtectionAPIVersion,
+	}
+
+	req, err := autorest.Prepare(&http.Request{},
+		autorest.AsJSON(),
+		autorest.AsPut(),
+		autorest.WithBaseURL(client.vmsClient.BaseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/virtualMachineScaleSets/{VMScaleSetName}/virtualmachines/{instanceId}", pathParameters),
+		autorest.WithJSON(body),
+		autorest.WithQueryParameters(queryParameters))
+	if err != nil {
+		return err
+	}
+
+	resp, err := autorest.SendWithSender(client.vmsClient, req, azure.DoPollForAsynchronous(client.vmsClient.PollingDelay))
+	if err != nil {
+		return err
+	}
+
+	return autorest.Respond(resp,
+		client.vmsClient.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK, http.StatusAccepted),
+		autorest.ByClosing())
+}
+
+// Returns the hostnames of the instances of the VM Scale Set of the given node module.
+func azureNodePoolHostnames(conf config.Config, currentState state.State, nodeKey string) ([]string, error) {
+	client, err := getAzureNodePoolClient(conf, currentState, nodeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := client.instances()
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames := make([]string, 0, len(instances))
+	for hostname := range instances {
+		hostnames = append(hostnames, hostname)
+	}
+
+	return hostnames, nil
+}
+
+// Protects or unprotects the given instances of the VM Scale Set of the node module from
+// scale in.
+func azureNodePoolProtectFromScaleIn(conf config.Config, currentState state.State, nodeKey string, hostnames []string, protect bool) error {
+	client, err := getAzureNodePoolClient(conf, currentState, nodeKey)
+	if err != nil {
+		return err
+	}
+
+	instances, err := client.instances()
+	if err != nil {
+		return err
+	}
+
+	for _, hostname := range hostnames {
+		instance, ok := instances[hostname]
+		if !ok || instance.InstanceID == nil {
+			return fmt.Errorf("Instance '%s' of scale set '%s' does not exist.", hostname, client.scaleSetName)
+		}
+
+		err = client.protectFromScaleIn(*instance.InstanceID, protect)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
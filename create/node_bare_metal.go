@@ -6,11 +6,12 @@ import (
 	"os"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -31,9 +32,9 @@ type bareMetalNodeTerraformConfig struct {
 // - a slice of the hostnames added
 // - the new state
 // - error or nil
-func newBareMetalNode(selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseNodeTerraformConfig(bareMetalRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+func newBareMetalNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseNodeTerraformConfig(conf, bareMetalRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
 	if err != nil {
 		return []string{}, err
 	}
@@ -43,10 +44,10 @@ func newBareMetalNode(selectedClusterManager, selectedCluster string, remoteBack
 	}
 
 	ssh_user := ""
-	if viper.IsSet("ssh_user") {
-		ssh_user = viper.GetString("ssh_user")
+	if conf.IsSet("ssh_user") {
+		ssh_user = conf.GetString("ssh_user")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("ssh_user must be specified")
+		return []string{}, util.ConfigError(errors.New("ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "SSH User",
@@ -67,10 +68,10 @@ func newBareMetalNode(selectedClusterManager, selectedCluster string, remoteBack
 	cfg.SSHUser = ssh_user
 
 	bastion_host := ""
-	if viper.IsSet("bastion_host") {
-		bastion_host = viper.GetString("bastion_host")
+	if conf.IsSet("bastion_host") {
+		bastion_host = conf.GetString("bastion_host")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("bastion_host must be specified")
+		return []string{}, util.ConfigError(errors.New("bastion_host must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Bastion Host",
@@ -93,10 +94,10 @@ func newBareMetalNode(selectedClusterManager, selectedCluster string, remoteBack
 	cfg.BastionHost = bastion_host
 
 	key_path := ""
-	if viper.IsSet("key_path") {
-		key_path = viper.GetString("key_path")
+	if conf.IsSet("key_path") {
+		key_path = conf.GetString("key_path")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("key_path must be specified")
+		return []string{}, util.ConfigError(errors.New("key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Key Path",
@@ -137,16 +138,36 @@ func newBareMetalNode(selectedClusterManager, selectedCluster string, remoteBack
 	// Determine what the hostnames should be for the new node(s)
 	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
 
-	// Bare metal node creation requires 1 host/ip address per node.
+	hosts, err := getBareMetalHosts(conf, newHostnames)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Add new node to terraform config with the new hostnames
+	for i, newHostname := range newHostnames {
+		cfgCopy := cfg
+		cfgCopy.Hostname = newHostname
+		cfgCopy.Host = hosts[i]
+		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	return newHostnames, nil
+}
+
+// Bare metal node creation requires 1 host/ip address per node.
+func getBareMetalHosts(conf config.Config, newHostnames []string) ([]string, error) {
 	hosts := []string{}
-	if viper.IsSet("hosts") {
-		hosts = viper.GetStringSlice("hosts")
-	} else if nonInteractiveMode {
-		return []string{}, errors.New("hosts must be specified")
+	if conf.IsSet("hosts") {
+		hosts = conf.GetStringSlice("hosts")
+	} else if conf.GetBool("non-interactive") {
+		return []string{}, util.ConfigError(errors.New("hosts must be specified"))
 	} else {
-		for i := 0; i < cfg.NodeCount; i++ {
+		for _, hostname := range newHostnames {
 			prompt := promptui.Prompt{
-				Label: fmt.Sprintf("Host/IP for %s", newHostnames[i]),
+				Label: fmt.Sprintf("Host/IP for %s", hostname),
 				Validate: func(input string) error {
 					if input == "" {
 						return errors.New("Invalid host/ip")
@@ -162,20 +183,9 @@ func newBareMetalNode(selectedClusterManager, selectedCluster string, remoteBack
 		}
 	}
 
-	if len(hosts) != cfg.NodeCount {
+	if len(hosts) != len(newHostnames) {
 		return []string{}, errors.New("not enough hosts")
 	}
 
-	// Add new node to terraform config with the new hostnames
-	for i, newHostname := range newHostnames {
-		cfgCopy := cfg
-		cfgCopy.Hostname = newHostname
-		cfgCopy.Host = hosts[i]
-		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
-		if err != nil {
-			return []string{}, err
-		}
-	}
-
-	return newHostnames, nil
+	return hosts, nil
 }
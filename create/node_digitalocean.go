@@ -0,0 +1,99 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	digitalOceanRancherKubernetesHostTerraformModulePath = "terraform/modules/digitalocean-rancher-k8s-host"
+)
+
+type digitalOceanNodeTerraformConfig struct {
+	baseNodeTerraformConfig
+
+	DigitalOceanAPIToken string `json:"digitalocean_api_token"`
+	DigitalOceanRegion   string `json:"digitalocean_region"`
+
+	DigitalOceanNodeTag string `json:"digitalocean_node_tag"`
+
+	DigitalOceanDropletSize       string `json:"digitalocean_droplet_size"`
+	DigitalOceanImage             string `json:"digitalocean_image"`
+	DigitalOceanSSHKeyFingerprint string `json:"digitalocean_ssh_key_fingerprint"`
+
+	DigitalOceanPrivateKeyPath string `json:"digitalocean_private_key_path,omitempty"`
+}
+
+// Adds new DigitalOcean nodes to the given cluster and manager.
+// Returns:
+// - a slice of the hostnames added
+// - the new state
+// - error or nil
+func newDigitalOceanNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	baseConfig, err := getBaseNodeTerraformConfig(conf, digitalOceanRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg := digitalOceanNodeTerraformConfig{
+		baseNodeTerraformConfig: baseConfig,
+
+		// Grab variables from cluster config
+		DigitalOceanAPIToken: currentState.Get(fmt.Sprintf("module.%s.digitalocean_api_token", selectedCluster)),
+		DigitalOceanRegion:   currentState.Get(fmt.Sprintf("module.%s.digitalocean_region", selectedCluster)),
+
+		// Reference terraform output variables from cluster module
+		DigitalOceanNodeTag: fmt.Sprintf("${module.%s.digitalocean_node_tag}", selectedCluster),
+	}
+
+	cfg.DigitalOceanDropletSize, err = getDigitalOceanDropletSize(conf, cfg.DigitalOceanAPIToken, cfg.DigitalOceanRegion)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.DigitalOceanImage, err = getDigitalOceanImage(conf, cfg.DigitalOceanAPIToken, cfg.DigitalOceanRegion)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.DigitalOceanSSHKeyFingerprint, err = getDigitalOceanSSHKey(conf, cfg.DigitalOceanAPIToken)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Control nodes of clusters encrypting secrets at rest get the encryption config over SSH
+	if cfg.KubernetesSecretsEncryptionConfig != "" {
+		cfg.DigitalOceanPrivateKeyPath, err = getNodeSSHKeyPath(conf, "digitalocean_private_key_path", "DigitalOcean Private Key Path", "~/.ssh/id_rsa")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// Get existing node names
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	existingNames := []string{}
+	for nodeName := range nodes {
+		existingNames = append(existingNames, nodeName)
+	}
+
+	// Determine what the hostnames should be for the new node(s)
+	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
+
+	// Add new node to terraform config with the new hostnames
+	for _, newHostname := range newHostnames {
+		cfgCopy := cfg
+		cfgCopy.Hostname = newHostname
+		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	return newHostnames, nil
+}
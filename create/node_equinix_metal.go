@@ -0,0 +1,90 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	equinixMetalRancherKubernetesHostTerraformModulePath = "terraform/modules/equinix-metal-rancher-k8s-host"
+)
+
+type equinixMetalNodeTerraformConfig struct {
+	baseNodeTerraformConfig
+
+	EquinixMetalAPIToken  string `json:"equinix_metal_api_token"`
+	EquinixMetalProjectID string `json:"equinix_metal_project_id"`
+	EquinixMetalMetro     string `json:"equinix_metal_metro"`
+
+	EquinixMetalPlan            string `json:"equinix_metal_plan"`
+	EquinixMetalOperatingSystem string `json:"equinix_metal_operating_system"`
+
+	EquinixMetalPrivateKeyPath string `json:"equinix_metal_private_key_path,omitempty"`
+}
+
+// Adds new Equinix Metal nodes to the given cluster and manager.
+// Returns:
+// - a slice of the hostnames added
+// - the new state
+// - error or nil
+func newEquinixMetalNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	baseConfig, err := getBaseNodeTerraformConfig(conf, equinixMetalRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg := equinixMetalNodeTerraformConfig{
+		baseNodeTerraformConfig: baseConfig,
+
+		// Grab variables from cluster config
+		EquinixMetalAPIToken:  currentState.Get(fmt.Sprintf("module.%s.equinix_metal_api_token", selectedCluster)),
+		EquinixMetalProjectID: currentState.Get(fmt.Sprintf("module.%s.equinix_metal_project_id", selectedCluster)),
+		EquinixMetalMetro:     currentState.Get(fmt.Sprintf("module.%s.equinix_metal_metro", selectedCluster)),
+	}
+
+	cfg.EquinixMetalPlan, err = getEquinixMetalPlan(conf, cfg.EquinixMetalAPIToken, cfg.EquinixMetalProjectID, cfg.EquinixMetalMetro)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.EquinixMetalOperatingSystem, err = getEquinixMetalOperatingSystem(conf, cfg.EquinixMetalAPIToken, cfg.EquinixMetalPlan)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Control nodes of clusters encrypting secrets at rest get the encryption config over SSH
+	if cfg.KubernetesSecretsEncryptionConfig != "" {
+		cfg.EquinixMetalPrivateKeyPath, err = getNodeSSHKeyPath(conf, "equinix_metal_private_key_path", "Equinix Metal Private Key Path", "~/.ssh/id_rsa")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// Get existing node names
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	existingNames := []string{}
+	for nodeName := range nodes {
+		existingNames = append(existingNames, nodeName)
+	}
+
+	// Determine what the hostnames should be for the new node(s)
+	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
+
+	// Add new node to terraform config with the new hostnames
+	for _, newHostname := range newHostnames {
+		cfgCopy := cfg
+		cfgCopy.Hostname = newHostname
+		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	return newHostnames, nil
+}
@@ -2,17 +2,14 @@ package create
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io/ioutil"
-	"sort"
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
 
-	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 	"golang.org/x/oauth2/google"
 	compute "google.golang.org/api/compute/v1"
 )
@@ -38,6 +35,10 @@ type gcpNodeTerraformConfig struct {
 	GCPDiskType      string `json:"gcp_disk_type"`
 	GCPDiskSize      string `json:"gcp_disk_size"`
 	GCPDiskMountPath string `json:"gcp_disk_mount_path"`
+
+	GCPSSHUser        string `json:"gcp_ssh_user,omitempty"`
+	GCPPublicKey      string `json:"gcp_public_key,omitempty"`
+	GCPPrivateKeyPath string `json:"gcp_private_key_path,omitempty"`
 }
 
 // Adds new GCP nodes to the given cluster and manager.
@@ -45,9 +46,8 @@ type gcpNodeTerraformConfig struct {
 // - a slice of the hostnames added
 // - the new state
 // - error or nil
-func newGCPNode(selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseNodeTerraformConfig(gcpRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+func newGCPNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	baseConfig, err := getBaseNodeTerraformConfig(conf, gcpRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
 	if err != nil {
 		return []string{}, err
 	}
@@ -80,162 +80,55 @@ func newGCPNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 		return []string{}, err
 	}
 
-	zones, err := service.Zones.List(cfg.GCPProjectID).Filter(fmt.Sprintf("region eq https://www.googleapis.com/compute/v1/projects/%s/regions/%s", cfg.GCPProjectID, cfg.GCPComputeRegion)).Do()
+	// GCP Instance Zone
+	cfg.GCPInstanceZone, err = getGCPInstanceZone(conf, service, cfg.GCPProjectID, cfg.GCPComputeRegion)
 	if err != nil {
 		return []string{}, err
 	}
 
-	// GCP Instance Zone
-	if viper.IsSet("gcp_instance_zone") {
-		cfg.GCPInstanceZone = viper.GetString("gcp_instance_zone")
-
-		found := false
-		for _, zone := range zones.Items {
-			if zone.Name == cfg.GCPInstanceZone {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return []string{}, fmt.Errorf("Selected GCP Instance Zone '%s' does not exist.", cfg.GCPInstanceZone)
-		}
-
-	} else if nonInteractiveMode {
-		return []string{}, errors.New("gcp_instance_zone must be specified")
-	} else {
-		searcher := func(input string, index int) bool {
-			zone := zones.Items[index]
-			name := strings.Replace(strings.ToLower(zone.Name), " ", "", -1)
-			input = strings.Replace(strings.ToLower(input), " ", "", -1)
-
-			return strings.Contains(name, input)
-		}
-
-		prompt := promptui.Select{
-			Label: "GCP Instance Zone",
-			Items: zones.Items,
-			Templates: &promptui.SelectTemplates{
-				Label:    "{{ .Name }}?",
-				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
-				Inactive: `  {{ .Name }}`,
-				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Instance Zone:" | bold}} {{ .Name }}`, promptui.IconGood),
-			},
-			Searcher: searcher,
-		}
-
-		i, _, err := prompt.Run()
-		if err != nil {
-			return []string{}, err
-		}
-
-		cfg.GCPInstanceZone = zones.Items[i].Name
+	// GCP Machine Type
+	cfg.GCPMachineType, err = getGCPMachineType(conf, service, cfg.GCPProjectID, cfg.GCPInstanceZone)
+	if err != nil {
+		return []string{}, err
 	}
 
-	machineTypes, err := service.MachineTypes.List(cfg.GCPProjectID, cfg.GCPInstanceZone).Do()
+	// GCP Image
+	cfg.GCPImage, err = getGCPImage(conf, service, cfg.GCPProjectID)
 	if err != nil {
 		return []string{}, err
 	}
 
-	// GCP Machine Type
-	if viper.IsSet("gcp_machine_type") {
-		cfg.GCPMachineType = viper.GetString("gcp_machine_type")
-
-		found := false
-		for _, machineType := range machineTypes.Items {
-			if machineType.Name == cfg.GCPMachineType {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return []string{}, fmt.Errorf("Selected GCP Machine Type '%s' does not exist.", cfg.GCPMachineType)
+	// Control nodes of clusters encrypting secrets at rest get the encryption config over SSH,
+	// with a key added to their metadata
+	if cfg.KubernetesSecretsEncryptionConfig != "" {
+		cfg.GCPSSHUser, err = getNodeSSHUser(conf, "gcp_ssh_user", "GCP SSH User", "ubuntu")
+		if err != nil {
+			return []string{}, err
 		}
 
-	} else if nonInteractiveMode {
-		return []string{}, errors.New("gcp_machine_type must be specified")
-	} else {
-		searcher := func(input string, index int) bool {
-			machineType := machineTypes.Items[index]
-			name := strings.Replace(strings.ToLower(machineType.Name), " ", "", -1)
-			input = strings.Replace(strings.ToLower(input), " ", "", -1)
-
-			return strings.Contains(name, input)
+		publicKeyPath, err := getNodeSSHKeyPath(conf, "gcp_public_key_path", "GCP Public Key Path", "~/.ssh/id_rsa.pub")
+		if err != nil {
+			return []string{}, err
 		}
-
-		prompt := promptui.Select{
-			Label: "GCP Machine Type",
-			Items: machineTypes.Items,
-			Templates: &promptui.SelectTemplates{
-				Label:    "{{ .Name }}?",
-				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
-				Inactive: `  {{ .Name }}`,
-				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Machine Type:" | bold}} {{ .Name }}`, promptui.IconGood),
-			},
-			Searcher: searcher,
+		publicKey, err := ioutil.ReadFile(publicKeyPath)
+		if err != nil {
+			return []string{}, err
 		}
+		cfg.GCPPublicKey = strings.TrimSpace(string(publicKey))
 
-		i, _, err := prompt.Run()
+		cfg.GCPPrivateKeyPath, err = getNodeSSHKeyPath(conf, "gcp_private_key_path", "GCP Private Key Path", "~/.ssh/id_rsa")
 		if err != nil {
 			return []string{}, err
 		}
-
-		cfg.GCPMachineType = machineTypes.Items[i].Name
 	}
 
-	images, err := service.Images.List("ubuntu-os-cloud").Do()
+	// Worker nodes can be created as a managed instance group instead of individual instances
+	useManagedInstanceGroup, err := useGCPManagedInstanceGroup(conf, cfg.baseNodeTerraformConfig)
 	if err != nil {
 		return []string{}, err
 	}
-
-	// Sort images by created timestamp in reverse chronological order
-	sort.SliceStable(images.Items, func(i, j int) bool {
-		return images.Items[i].CreationTimestamp > images.Items[j].CreationTimestamp
-	})
-
-	// GCP Image
-	if viper.IsSet("gcp_image") {
-		cfg.GCPImage = viper.GetString("gcp_image")
-
-		found := false
-		for _, image := range images.Items {
-			if image.Name == cfg.GCPImage {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return []string{}, fmt.Errorf("Selected GCP Image '%s' does not exist.", cfg.GCPImage)
-		}
-
-	} else if nonInteractiveMode {
-		return []string{}, errors.New("gcp_image must be specified")
-	} else {
-		searcher := func(input string, index int) bool {
-			image := images.Items[index]
-			name := strings.Replace(strings.ToLower(image.Name), " ", "", -1)
-			input = strings.Replace(strings.ToLower(input), " ", "", -1)
-
-			return strings.Contains(name, input)
-		}
-
-		prompt := promptui.Select{
-			Label: "GCP Image",
-			Items: images.Items,
-			Templates: &promptui.SelectTemplates{
-				Label:    "{{ .Name }}?",
-				Active:   fmt.Sprintf(`%s {{ .Name | underline }}`, promptui.IconSelect),
-				Inactive: `  {{ .Name }}`,
-				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "GCP Image:" | bold}} {{ .Name }}`, promptui.IconGood),
-			},
-			Searcher: searcher,
-		}
-
-		i, _, err := prompt.Run()
-		if err != nil {
-			return []string{}, err
-		}
-
-		cfg.GCPImage = images.Items[i].Name
+	if useManagedInstanceGroup {
+		return newGCPNodePool(conf, cfg, selectedCluster, currentState)
 	}
 
 	// Get list of GCP permanent disk types
@@ -251,21 +144,21 @@ func newGCPNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 	// }
 
 	// // GCP Disk
-	// diskTypeIsSet := viper.IsSet("gcp_disk_type")
-	// diskSizeIsSet := viper.IsSet("gcp_disk_size")
-	// diskMountPathIsSet := viper.IsSet("gcp_disk_mount_path")
+	// diskTypeIsSet := conf.IsSet("gcp_disk_type")
+	// diskSizeIsSet := conf.IsSet("gcp_disk_size")
+	// diskMountPathIsSet := conf.IsSet("gcp_disk_mount_path")
 	// if nonInteractiveMode {
 	// 	// If disk type is defined, assume the user's intent is to add
 	// 	// a disk to the host and throw an error if neither size nor mount path is set.
 	// 	if diskTypeIsSet && !(diskSizeIsSet || diskMountPathIsSet) {
 	// 		return nil, errors.New("If gcp_disk_type is set, gcp_disk_size and gcp_disk_mount must also be set.")
 	// 	} else if diskTypeIsSet {
-	// 		cfg.GCPDiskType = viper.GetString("gcp_disk_type")
+	// 		cfg.GCPDiskType = conf.GetString("gcp_disk_type")
 	// 		if !isValidDiskType(permanentDiskTypes, cfg.GCPDiskType) {
 	// 			return nil, fmt.Errorf("gcp_disk_type must be valid. Found '%s'.", cfg.GCPDiskType)
 	// 		}
-	// 		cfg.GCPDiskSize = viper.GetString("gcp_disk_size")
-	// 		cfg.GCPDiskMountPath = viper.GetString("gcp_disk_mount_path")
+	// 		cfg.GCPDiskSize = conf.GetString("gcp_disk_size")
+	// 		cfg.GCPDiskMountPath = conf.GetString("gcp_disk_mount_path")
 	// 	}
 	// } else {
 	// 	shouldCreateDisk, err := util.PromptForConfirmation("Create a disk for this node", "Disk created")
@@ -276,7 +169,7 @@ func newGCPNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 	// 	if shouldCreateDisk {
 	// 		// GCP Disk Type
 	// 		if diskTypeIsSet {
-	// 			cfg.GCPDiskType = viper.GetString("gcp_disk_type")
+	// 			cfg.GCPDiskType = conf.GetString("gcp_disk_type")
 	// 			if !isValidDiskType(permanentDiskTypes, cfg.GCPDiskType) {
 	// 				return nil, fmt.Errorf("gcp_disk_type must be valid. Found '%s'.", cfg.GCPDiskType)
 	// 			}
@@ -302,7 +195,7 @@ func newGCPNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 
 	// 		// GCP Disk Size
 	// 		if diskSizeIsSet {
-	// 			cfg.GCPDiskSize = viper.GetString("gcp_disk_size")
+	// 			cfg.GCPDiskSize = conf.GetString("gcp_disk_size")
 	// 		} else {
 	// 			prompt := promptui.Prompt{
 	// 				Label: "GCP Disk Size in GB",
@@ -326,7 +219,7 @@ func newGCPNode(selectedClusterManager, selectedCluster string, remoteBackend ba
 
 	// 		// GCP Disk Mount path
 	// 		if diskMountPathIsSet {
-	// 			cfg.GCPDiskMountPath = viper.GetString("gcp_disk_mount_path")
+	// 			cfg.GCPDiskMountPath = conf.GetString("gcp_disk_mount_path")
 	// 		} else {
 	// 			prompt := promptui.Prompt{
 	// 				Label: "GCP Disk Mount Path",
@@ -0,0 +1,257 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+)
+
+const (
+	gcpRancherKubernetesMIGTerraformModulePath = "terraform/modules/gcp-rancher-k8s-mig"
+
+	defaultGCPAutoscalerCPUTarget = "0.6"
+)
+
+// Worker pools can be backed by a managed instance group, created from an instance template
+// and auto-healed by a health check. Its size is either fixed or set by an autoscaler. The
+// whole pool is a single node module named after the hostname prefix, GCP names its
+// instances {hostname}-{4 random characters}.
+type gcpNodePoolTerraformConfig struct {
+	baseNodeTerraformConfig
+
+	GCPPathToCredentials string `json:"gcp_path_to_credentials"`
+	GCPProjectID         string `json:"gcp_project_id"`
+	GCPComputeRegion     string `json:"gcp_compute_region"`
+
+	GCPComputeNetworkName     string `json:"gcp_compute_network_name"`
+	GCPComputeFirewallHostTag string `json:"gcp_compute_firewall_host_tag"`
+
+	GCPMachineType  string `json:"gcp_machine_type"`
+	GCPInstanceZone string `json:"gcp_instance_zone"`
+	GCPImage        string `json:"gcp_image"`
+
+	GCPMIGTargetSize int `json:"gcp_mig_target_size"`
+
+	GCPAutoscaling              bool   `json:"gcp_autoscaling,omitempty"`
+	GCPAutoscalerMinReplicas    int    `json:"gcp_autoscaler_min_replicas,omitempty"`
+	GCPAutoscalerMaxReplicas    int    `json:"gcp_autoscaler_max_replicas,omitempty"`
+	GCPAutoscalerCPUTarget      string `json:"gcp_autoscaler_cpu_target,omitempty"`
+	GCPAutoscalerCooldownPeriod string `json:"gcp_autoscaler_cooldown_period,omitempty"`
+
+	GCPHealthCheckPort         string `json:"gcp_health_check_port,omitempty"`
+	GCPHealthCheckInitialDelay string `json:"gcp_health_check_initial_delay,omitempty"`
+}
+
+// Returns true if the nodes should be created as a managed instance group. Only worker nodes
+// can be, etcd and control nodes need stable identities.
+func useGCPManagedInstanceGroup(conf config.Config, cfg baseNodeTerraformConfig) (bool, error) {
+	if cfg.RancherHostLabels.Worker != "true" {
+		if conf.GetBool("gcp_mig") {
+			return false, errors.New("gcp_mig is only supported for worker nodes")
+		}
+		return false, nil
+	}
+
+	if conf.IsSet("gcp_mig") {
+		return conf.GetBool("gcp_mig"), nil
+	} else if conf.GetBool("non-interactive") {
+		return false, nil
+	}
+
+	return util.PromptForConfirmation("Create these nodes as a managed instance group", "Managed instance group")
+}
+
+// Adds the nodes as a single managed instance group node module. The node count is the size
+// of the group, or the default autoscaler limits when it is autoscaled.
+// Returns:
+// - a slice with the name of the node pool
+// - error or nil
+func newGCPNodePool(conf config.Config, cfg gcpNodeTerraformConfig, selectedCluster string, currentState state.State) ([]string, error) {
+	baseSource := defaultSourceURL
+	if conf.IsSet("source_url") {
+		baseSource = conf.GetString("source_url")
+	}
+
+	baseSourceRef := defaultSourceRef
+	if conf.IsSet("source_ref") {
+		baseSourceRef = conf.GetString("source_ref")
+	}
+
+	poolCfg := gcpNodePoolTerraformConfig{
+		baseNodeTerraformConfig: cfg.baseNodeTerraformConfig,
+
+		GCPPathToCredentials: cfg.GCPPathToCredentials,
+		GCPProjectID:         cfg.GCPProjectID,
+		GCPComputeRegion:     cfg.GCPComputeRegion,
+
+		GCPComputeNetworkName:     cfg.GCPComputeNetworkName,
+		GCPComputeFirewallHostTag: cfg.GCPComputeFirewallHostTag,
+
+		GCPMachineType:  cfg.GCPMachineType,
+		GCPInstanceZone: cfg.GCPInstanceZone,
+		GCPImage:        cfg.GCPImage,
+
+		GCPMIGTargetSize: cfg.NodeCount,
+
+		GCPAutoscalerCooldownPeriod: conf.GetString("gcp_autoscaler_cooldown_period"),
+		GCPHealthCheckPort:          conf.GetString("gcp_health_check_port"),
+		GCPHealthCheckInitialDelay:  conf.GetString("gcp_health_check_initial_delay"),
+	}
+	poolCfg.Source = fmt.Sprintf("%s//%s?ref=%s", baseSource, gcpRancherKubernetesMIGTerraformModulePath, baseSourceRef)
+
+	// Autoscaler
+	var err error
+	if conf.IsSet("gcp_autoscaling") {
+		poolCfg.GCPAutoscaling = conf.GetBool("gcp_autoscaling")
+	} else if !conf.GetBool("non-interactive") {
+		poolCfg.GCPAutoscaling, err = util.PromptForConfirmation("Scale these nodes with an autoscaler", "Autoscaled")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	if poolCfg.GCPAutoscaling {
+		poolCfg.GCPAutoscalerMinReplicas, err = getAutoScalingSize(conf, "gcp_autoscaler_min_replicas", "Minimum number of nodes", cfg.NodeCount)
+		if err != nil {
+			return []string{}, err
+		}
+
+		poolCfg.GCPAutoscalerMaxReplicas, err = getAutoScalingSize(conf, "gcp_autoscaler_max_replicas", "Maximum number of nodes", cfg.NodeCount)
+		if err != nil {
+			return []string{}, err
+		}
+
+		if poolCfg.GCPAutoscalerMaxReplicas <= 0 {
+			return []string{}, fmt.Errorf("gcp_autoscaler_max_replicas must be greater than 0. Found '%d'.", poolCfg.GCPAutoscalerMaxReplicas)
+		}
+		if poolCfg.GCPAutoscalerMinReplicas > poolCfg.GCPAutoscalerMaxReplicas {
+			return []string{}, fmt.Errorf("gcp_autoscaler_min_replicas must not be greater than gcp_autoscaler_max_replicas. Found %d and %d.", poolCfg.GCPAutoscalerMinReplicas, poolCfg.GCPAutoscalerMaxReplicas)
+		}
+
+		poolCfg.GCPAutoscalerCPUTarget, err = getGCPAutoscalerCPUTarget(conf)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// The pool is named after the hostname prefix, which must not already be in use
+	if hostnameTemplateRegexp.MatchString(poolCfg.Hostname) {
+		return []string{}, fmt.Errorf("hostname can't be a template with gcp_mig, the cloud names the instances of the pool.")
+	}
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	if _, ok := nodes[poolCfg.Hostname]; ok {
+		return []string{}, fmt.Errorf("A node pool named '%s' already exists.", poolCfg.Hostname)
+	}
+
+	err = currentState.AddNode(selectedCluster, poolCfg.Hostname, poolCfg)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return []string{poolCfg.Hostname}, nil
+}
+
+func getGCPAutoscalerCPUTarget(conf config.Config) (string, error) {
+	validate := func(input string) error {
+		target, err := strconv.ParseFloat(input, 64)
+		if err != nil || target <= 0 || target > 1 {
+			return errors.New("CPU utilization target must be a number greater than 0 and at most 1")
+		}
+		return nil
+	}
+
+	cpuTarget := defaultGCPAutoscalerCPUTarget
+	if conf.IsSet("gcp_autoscaler_cpu_target") {
+		cpuTarget = conf.GetString("gcp_autoscaler_cpu_target")
+	} else if !conf.GetBool("non-interactive") {
+		prompt := promptui.Prompt{
+			Label:    "Target CPU utilization of the nodes",
+			Validate: validate,
+			Default:  cpuTarget,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		cpuTarget = result
+	}
+
+	if validate(cpuTarget) != nil {
+		return "", fmt.Errorf("gcp_autoscaler_cpu_target must be greater than 0 and at most 1. Found '%s'.", cpuTarget)
+	}
+
+	return cpuTarget, nil
+}
+
+// Returns true if the hostname belongs to an instance of the given managed instance group.
+func isGCPNodePoolMember(poolName, hostname string) bool {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(poolName) + "-[0-9a-z]{4}$").MatchString(hostname)
+}
+
+// Returns the hostnames of the instances of the managed instance group of the given node
+// module, except instances that are being deleted.
+func gcpNodePoolHostnames(conf config.Config, currentState state.State, nodeKey string) ([]string, error) {
+	pathToCredentials := currentState.Get(fmt.Sprintf("module.%s.gcp_path_to_credentials", nodeKey))
+	projectID := currentState.Get(fmt.Sprintf("module.%s.gcp_project_id", nodeKey))
+	zone := currentState.Get(fmt.Sprintf("module.%s.gcp_instance_zone", nodeKey))
+	groupName := currentState.Get(fmt.Sprintf("module.%s.hostname", nodeKey))
+
+	gcpCredentials, err := ioutil.ReadFile(pathToCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtCfg, err := google.JWTConfigFromJSON(gcpCredentials, "https://www.googleapis.com/auth/compute.readonly")
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := compute.New(jwtCfg.Client(context.Background()))
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := service.InstanceGroupManagers.ListManagedInstances(projectID, zone, groupName).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames := []string{}
+	for _, instance := range response.ManagedInstances {
+		if instance.CurrentAction == "DELETING" || instance.CurrentAction == "ABANDONING" {
+			continue
+		}
+		// Instances are referred to by URL, the hostname is the instance name
+		hostnames = append(hostnames, path.Base(instance.Instance))
+	}
+
+	return hostnames, nil
+}
+
+// Autoscaled managed instance groups are sized by their autoscaler.
+func validateGCPNodePoolCapacity(currentState state.State, nodeKey string, capacity int) error {
+	autoscaling, _ := currentState.GetMap(fmt.Sprintf("module.%s", nodeKey))["gcp_autoscaling"].(bool)
+	if autoscaling {
+		minReplicas := currentState.GetInt(fmt.Sprintf("module.%s.gcp_autoscaler_min_replicas", nodeKey))
+		maxReplicas := currentState.GetInt(fmt.Sprintf("module.%s.gcp_autoscaler_max_replicas", nodeKey))
+		return fmt.Errorf("The node pool is sized by its autoscaler, between %d and %d nodes.", minReplicas, maxReplicas)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package create
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+const defaultNodeRegistrationTimeout = 15 // minutes
+
+// How often the nodes of a new cluster are checked while waiting for them to become active
+var nodeRegistrationPollInterval = 15 * time.Second
+
+// Terraform is done once the nodes have started the Rancher agent, which doesn't mean they
+// registered. Waits until the expected number of nodes are active in Rancher, so a cluster
+// whose nodes never joined isn't reported as created.
+func waitForClusterNodes(conf config.Config, currentState state.State, clusterKey string, newHostnames []string) error {
+	if len(newHostnames) == 0 {
+		return nil
+	}
+
+	timeout := defaultNodeRegistrationTimeout
+	if conf.IsSet("node_registration_timeout") {
+		timeout = conf.GetInt("node_registration_timeout")
+	}
+	if timeout <= 0 {
+		return nil
+	}
+
+	// Node pools are a single hostname in the state, but have as many nodes as their capacity
+	expectedNodes := 0
+	hostnames := []string{}
+	for _, hostname := range newHostnames {
+		nodeKey := strings.Replace(clusterKey, "cluster_", "node_", 1) + "_" + hostname
+		provider, ok := getNodePoolProvider(currentState, nodeKey)
+		if !ok {
+			expectedNodes++
+			hostnames = append(hostnames, hostname)
+			continue
+		}
+
+		capacity := currentState.GetInt(fmt.Sprintf("module.%s.%s", nodeKey, provider.CapacityKey))
+		expectedNodes += capacity
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Waiting up to %d minutes for %d nodes to become active...\n", timeout, expectedNodes)
+	return waitForActiveNodes(client, rancherClusterID, expectedNodes, hostnames, time.Duration(timeout)*time.Minute)
+}
+
+// Polls the cluster until at least expectedNodes of its nodes are active. On timeout the error
+// lists the state of every node, and the given hostnames that never registered.
+func waitForActiveNodes(client *rancher.Client, clusterID string, expectedNodes int, hostnames []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		nodes, err := client.Nodes(clusterID)
+		if err != nil {
+			return err
+		}
+
+		activeNodes := 0
+		for _, node := range nodes {
+			if node.State == "active" {
+				activeNodes++
+			}
+		}
+
+		if activeNodes >= expectedNodes {
+			fmt.Printf("All %d nodes are active.\n", activeNodes)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return util.TimeoutError(fmt.Errorf("Only %d of %d nodes became active after %s:\n%s", activeNodes, expectedNodes, timeout, formatNodeStates(nodes, hostnames)))
+		}
+
+		time.Sleep(nodeRegistrationPollInterval)
+	}
+}
+
+// Returns one line per node with its state, sorted by hostname.
+func formatNodeStates(nodes []rancher.Node, hostnames []string) string {
+	states := map[string]string{}
+	for _, node := range nodes {
+		states[node.Hostname] = node.State
+	}
+	for _, hostname := range hostnames {
+		if _, ok := states[hostname]; !ok {
+			states[hostname] = "not registered"
+		}
+	}
+
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, states[name]))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,60 @@
+package create
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/rancher"
+)
+
+func TestWaitForActiveNodes(t *testing.T) {
+	nodeRegistrationPollInterval = time.Millisecond
+
+	polls := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		state := "registering"
+		if polls > 2 {
+			state = "active"
+		}
+		fmt.Fprintf(w, `{"data": [{"id": "m-1", "hostname": "worker-1", "state": "active"}, {"id": "m-2", "hostname": "worker-2", "state": "%s"}]}`, state)
+	}))
+	defer server.Close()
+
+	client := rancher.NewClient(server.URL, "access", "secret")
+	err := waitForActiveNodes(client, "c-abcde", 2, []string{"worker-1", "worker-2"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 3 {
+		t.Errorf("Expected 3 polls, received %d", polls)
+	}
+
+	// worker-3 never registers
+	err = waitForActiveNodes(client, "c-abcde", 3, []string{"worker-1", "worker-2", "worker-3"}, 0)
+	if err == nil {
+		t.Fatal("Expected the nodes not to become active")
+	}
+	expected := "Only 2 of 3 nodes became active after 0s:\n  worker-1: active\n  worker-2: active\n  worker-3: not registered"
+	if err.Error() != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, err.Error())
+	}
+}
+
+func TestFormatNodeStates(t *testing.T) {
+	nodes := []rancher.Node{
+		{Hostname: "pool-i-0abc", State: "unavailable"},
+		{Hostname: "etcd-1", State: "active"},
+	}
+
+	output := formatNodeStates(nodes, []string{"etcd-1", "control-1"})
+	lines := strings.Split(output, "\n")
+	expected := []string{"  control-1: not registered", "  etcd-1: active", "  pool-i-0abc: unavailable"}
+	if !isEqual(expected, lines) {
+		t.Errorf("Wrong output, expected %q, received %q", expected, lines)
+	}
+}
@@ -0,0 +1,173 @@
+package create
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/joyent/triton-go/client"
+	"github.com/joyent/triton-go/compute"
+	"golang.org/x/crypto/ssh"
+)
+
+// Nodes can be given SSH keys that are managed by their cloud instead of a local key file, so
+// they follow the keys an organization already uses:
+// - node_aws_key_name, an EC2 key pair, which replaces the cluster's key pair
+// - azure_ssh_public_key_id, the resource id of an Azure SSH public key
+// - triton_ssh_key_fingerprints, keys of the Triton account, which replace the account's
+//   other keys in the nodes' root_authorized_keys
+
+const azureSSHPublicKeysAPIVersion = "2019-12-01"
+
+type tritonAccountKey struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	Key         string `json:"key"`
+}
+
+// Verifies the EC2 key pair exists in the region of the client.
+func verifyAWSKeyPair(ec2Client *ec2.EC2, keyName string) error {
+	output, err := ec2Client.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("key-name"),
+				Values: []*string{aws.String(keyName)},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(output.KeyPairs) == 0 {
+		return fmt.Errorf("AWS key pair '%s' does not exist.", keyName)
+	}
+	return nil
+}
+
+// Verifies the id is the resource id of an Azure SSH public key, e.g.
+// /subscriptions/{id}/resourceGroups/{group}/providers/Microsoft.Compute/sshPublicKeys/{name}
+func validateAzureSSHPublicKeyID(id string) error {
+	parts := strings.Split(strings.TrimPrefix(id, "/"), "/")
+	if len(parts) != 8 ||
+		!strings.EqualFold(parts[0], "subscriptions") ||
+		!strings.EqualFold(parts[2], "resourceGroups") ||
+		!strings.EqualFold(parts[4], "providers") ||
+		!strings.EqualFold(parts[5], "Microsoft.Compute") ||
+		!strings.EqualFold(parts[6], "sshPublicKeys") ||
+		parts[1] == "" || parts[3] == "" || parts[7] == "" {
+		return util.ConfigError(fmt.Errorf("Invalid azure_ssh_public_key_id '%s', must be the id of an SSH public key e.g. /subscriptions/{id}/resourceGroups/{group}/providers/Microsoft.Compute/sshPublicKeys/{name}", id))
+	}
+	return nil
+}
+
+// Returns the public key of the Azure SSH public key resource with the given id. The vendored
+// Azure SDK predates SSH public key resources, so the resource is read from the API directly.
+func getAzureSSHPublicKey(azureEnv azure.Environment, azureSPT *adal.ServicePrincipalToken, id string) (string, error) {
+	err := validateAzureSSHPublicKeyID(id)
+	if err != nil {
+		return "", err
+	}
+
+	azureClient := withAzureRetries(autorest.NewClientWithUserAgent(""))
+	azureClient.Authorizer = autorest.NewBearerAuthorizer(azureSPT)
+
+	req, err := autorest.Prepare(&http.Request{},
+		autorest.AsGet(),
+		autorest.WithBaseURL(azureEnv.ResourceManagerEndpoint),
+		autorest.WithPath(id),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": azureSSHPublicKeysAPIVersion}),
+		azureClient.WithAuthorization())
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := autorest.SendWithSender(azureClient, req)
+	if err != nil {
+		return "", err
+	}
+
+	sshPublicKey := struct {
+		Properties struct {
+			PublicKey string `json:"publicKey"`
+		} `json:"properties"`
+	}{}
+	err = autorest.Respond(resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&sshPublicKey),
+		autorest.ByClosing())
+	if err != nil {
+		return "", err
+	}
+
+	if sshPublicKey.Properties.PublicKey == "" {
+		return "", fmt.Errorf("Azure SSH public key '%s' has no public key.", id)
+	}
+	return sshPublicKey.Properties.PublicKey, nil
+}
+
+// Returns the SSH keys of the Triton account. The vendored triton-go has no account client, so
+// they're read with the compute client's connection.
+func listTritonAccountKeys(tritonComputeClient *compute.ComputeClient) ([]tritonAccountKey, error) {
+	reqInputs := client.RequestInput{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("/%s/keys", tritonComputeClient.Client.AccountName),
+	}
+	respReader, err := tritonComputeClient.Client.ExecuteRequest(context.Background(), reqInputs)
+	if respReader != nil {
+		defer respReader.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []tritonAccountKey{}
+	err = json.NewDecoder(respReader).Decode(&keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Returns the root_authorized_keys of nodes that may only be accessed with the account keys
+// with the given fingerprints, one key per line. Fingerprints are MD5 fingerprints, with or without
+// the MD5: prefix, or SHA256 fingerprints as printed by ssh-keygen -l.
+func getTritonAuthorizedKeys(keys []tritonAccountKey, fingerprints []string) (string, error) {
+	authorizedKeys := []string{}
+	for _, fingerprint := range fingerprints {
+		found := false
+		for _, key := range keys {
+			if tritonAccountKeyHasFingerprint(key, fingerprint) {
+				authorizedKeys = append(authorizedKeys, strings.TrimSpace(key.Key))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("Triton account key with fingerprint '%s' does not exist.", fingerprint)
+		}
+	}
+	return strings.Join(authorizedKeys, "\n"), nil
+}
+
+// Triton only lists the MD5 fingerprint of account keys, SHA256 fingerprints are computed from
+// the key.
+func tritonAccountKeyHasFingerprint(key tritonAccountKey, fingerprint string) bool {
+	if !strings.HasPrefix(fingerprint, "SHA256:") {
+		return strings.EqualFold(strings.TrimPrefix(fingerprint, "MD5:"), key.Fingerprint)
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.Key))
+	if err != nil {
+		return false
+	}
+	return ssh.FingerprintSHA256(publicKey) == fingerprint
+}
@@ -0,0 +1,87 @@
+package create
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestValidateAzureSSHPublicKeyID(t *testing.T) {
+	valid := []string{
+		"/subscriptions/0000/resourceGroups/keys/providers/Microsoft.Compute/sshPublicKeys/ops",
+		"/subscriptions/0000/resourcegroups/keys/providers/microsoft.compute/sshpublickeys/ops",
+	}
+	for _, id := range valid {
+		if err := validateAzureSSHPublicKeyID(id); err != nil {
+			t.Errorf("validateAzureSSHPublicKeyID(%q) returned %v", id, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"ops",
+		"/subscriptions/0000/resourceGroups/keys/providers/Microsoft.Compute/virtualMachines/ops",
+		"/subscriptions/0000/resourceGroups//providers/Microsoft.Compute/sshPublicKeys/ops",
+		"/subscriptions/0000/resourceGroups/keys/providers/Microsoft.Compute/sshPublicKeys/ops/extra",
+	}
+	for _, id := range invalid {
+		if err := validateAzureSSHPublicKeyID(id); err == nil {
+			t.Errorf("validateAzureSSHPublicKeyID(%q) should have returned an error", id)
+		}
+	}
+}
+
+func TestGetTritonAuthorizedKeys(t *testing.T) {
+	keys := []tritonAccountKey{
+		{Name: "ops", Fingerprint: "aa:bb", Key: "ssh-rsa AAAA ops\n"},
+		{Name: "ci", Fingerprint: "cc:dd", Key: "ssh-rsa BBBB ci"},
+		{Name: "laptop", Fingerprint: "ee:ff", Key: "ssh-rsa CCCC laptop"},
+	}
+
+	authorizedKeys, err := getTritonAuthorizedKeys(keys, []string{"cc:dd", "aa:bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ssh-rsa BBBB ci\nssh-rsa AAAA ops"
+	if authorizedKeys != expected {
+		t.Errorf("Expected %q, got %q", expected, authorizedKeys)
+	}
+
+	_, err = getTritonAuthorizedKeys(keys, []string{"aa:bb", "00:11"})
+	if err == nil {
+		t.Error("Expected an error for a fingerprint that isn't an account key")
+	}
+}
+
+func TestGetTritonAuthorizedKeysSHA256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey)))
+	keys := []tritonAccountKey{
+		{Name: "ops", Fingerprint: "aa:bb", Key: "ssh-rsa AAAA ops"},
+		{Name: "ci", Fingerprint: ssh.FingerprintLegacyMD5(publicKey), Key: authorizedKey},
+	}
+
+	for _, fingerprint := range []string{ssh.FingerprintSHA256(publicKey), "MD5:" + strings.ToUpper(ssh.FingerprintLegacyMD5(publicKey))} {
+		authorizedKeys, err := getTritonAuthorizedKeys(keys, []string{fingerprint})
+		if err != nil {
+			t.Errorf("%s: %v", fingerprint, err)
+		} else if authorizedKeys != authorizedKey {
+			t.Errorf("%s: expected %q, got %q", fingerprint, authorizedKey, authorizedKeys)
+		}
+	}
+
+	_, err = getTritonAuthorizedKeys(keys, []string{"SHA256:uc8IWGtOoL8dmvJOq8vFi1ekR/v5mGGHQvrDrVNXBC4"})
+	if err == nil {
+		t.Error("Expected an error for a fingerprint that isn't an account key")
+	}
+}
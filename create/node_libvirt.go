@@ -0,0 +1,103 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	libvirtRancherKubernetesHostTerraformModulePath = "terraform/modules/libvirt-rancher-k8s-host"
+
+	defaultLibvirtVCPU     = "2"
+	defaultLibvirtMemory   = "2048"
+	defaultLibvirtDiskSize = "20"
+)
+
+type libvirtNodeTerraformConfig struct {
+	baseNodeTerraformConfig
+
+	LibvirtURI          string `json:"libvirt_uri"`
+	LibvirtPoolName     string `json:"libvirt_pool_name"`
+	LibvirtNetworkName  string `json:"libvirt_network_name"`
+	LibvirtBaseVolumeID string `json:"libvirt_base_volume_id"`
+
+	LibvirtVCPU     string `json:"libvirt_vcpu"`
+	LibvirtMemory   string `json:"libvirt_memory"`
+	LibvirtDiskSize string `json:"libvirt_disk_size"`
+
+	LibvirtSSHUser string `json:"libvirt_ssh_user"`
+	LibvirtKeyPath string `json:"libvirt_key_path"`
+}
+
+// Adds new libvirt nodes to the given cluster and manager.
+// Returns:
+// - a slice of the hostnames added
+// - the new state
+// - error or nil
+func newLibvirtNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	baseConfig, err := getBaseNodeTerraformConfig(conf, libvirtRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg := libvirtNodeTerraformConfig{
+		baseNodeTerraformConfig: baseConfig,
+
+		// Grab variables from cluster config
+		LibvirtURI: currentState.Get(fmt.Sprintf("module.%s.libvirt_uri", selectedCluster)),
+
+		// Reference terraform output variables from cluster module
+		LibvirtPoolName:     fmt.Sprintf("${module.%s.libvirt_pool_name}", selectedCluster),
+		LibvirtNetworkName:  fmt.Sprintf("${module.%s.libvirt_network_name}", selectedCluster),
+		LibvirtBaseVolumeID: fmt.Sprintf("${module.%s.libvirt_base_volume_id}", selectedCluster),
+	}
+
+	// VM Size
+	cfg.LibvirtVCPU, err = getLibvirtVMSize(conf, "libvirt_vcpu", "Virtual CPUs", defaultLibvirtVCPU)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.LibvirtMemory, err = getLibvirtVMSize(conf, "libvirt_memory", "Memory (MB)", defaultLibvirtMemory)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.LibvirtDiskSize, err = getLibvirtVMSize(conf, "libvirt_disk_size", "Disk Size (GB)", defaultLibvirtDiskSize)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.LibvirtSSHUser, cfg.LibvirtKeyPath, err = getLibvirtSSHConfig(conf)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Get existing node names
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	existingNames := []string{}
+	for nodeName := range nodes {
+		existingNames = append(existingNames, nodeName)
+	}
+
+	// Determine what the hostnames should be for the new node(s)
+	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
+
+	// Add new node to terraform config with the new hostnames
+	for _, newHostname := range newHostnames {
+		cfgCopy := cfg
+		cfgCopy.Hostname = newHostname
+		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	return newHostnames, nil
+}
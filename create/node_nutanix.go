@@ -0,0 +1,108 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	nutanixRancherKubernetesHostTerraformModulePath = "terraform/modules/nutanix-rancher-k8s-host"
+
+	defaultNutanixVCPUs    = "2"
+	defaultNutanixMemory   = "2048"
+	defaultNutanixDiskSize = "40"
+)
+
+type nutanixNodeTerraformConfig struct {
+	baseNodeTerraformConfig
+	nutanixAuth
+	nutanixVMConfig
+
+	NutanixVCPUs    string `json:"nutanix_vcpus"`
+	NutanixMemory   string `json:"nutanix_memory"`
+	NutanixDiskSize string `json:"nutanix_disk_size"`
+
+	NutanixSSHUser string `json:"nutanix_ssh_user"`
+	NutanixKeyPath string `json:"nutanix_key_path"`
+}
+
+// Adds new Nutanix AHV nodes to the given cluster and manager.
+// Returns:
+// - a slice of the hostnames added
+// - the new state
+// - error or nil
+func newNutanixNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	baseConfig, err := getBaseNodeTerraformConfig(conf, nutanixRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+	if err != nil {
+		return []string{}, err
+	}
+
+	insecure, _ := currentState.GetMap(fmt.Sprintf("module.%s", selectedCluster))["nutanix_insecure"].(bool)
+
+	cfg := nutanixNodeTerraformConfig{
+		baseNodeTerraformConfig: baseConfig,
+
+		// Grab variables from cluster config
+		nutanixAuth: nutanixAuth{
+			Endpoint: currentState.Get(fmt.Sprintf("module.%s.nutanix_endpoint", selectedCluster)),
+			Port:     currentState.Get(fmt.Sprintf("module.%s.nutanix_port", selectedCluster)),
+			Username: currentState.Get(fmt.Sprintf("module.%s.nutanix_username", selectedCluster)),
+			Password: currentState.Get(fmt.Sprintf("module.%s.nutanix_password", selectedCluster)),
+			Insecure: insecure,
+		},
+	}
+
+	cfg.nutanixVMConfig, err = getNutanixVMConfig(conf, cfg.nutanixAuth)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// VM Size
+	cfg.NutanixVCPUs, err = getNutanixVMSize(conf, "nutanix_vcpus", "vCPUs", defaultNutanixVCPUs)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.NutanixMemory, err = getNutanixVMSize(conf, "nutanix_memory", "Memory (MiB)", defaultNutanixMemory)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.NutanixDiskSize, err = getNutanixVMSize(conf, "nutanix_disk_size", "Disk Size (GiB)", defaultNutanixDiskSize)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.NutanixSSHUser, cfg.NutanixKeyPath, err = getNutanixSSHConfig(conf)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Get existing node names
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	existingNames := []string{}
+	for nodeName := range nodes {
+		existingNames = append(existingNames, nodeName)
+	}
+
+	// Determine what the hostnames should be for the new node(s)
+	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
+
+	// Add new node to terraform config with the new hostnames
+	for _, newHostname := range newHostnames {
+		cfgCopy := cfg
+		cfgCopy.Hostname = newHostname
+		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	return newHostnames, nil
+}
@@ -0,0 +1,121 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	openStackRancherKubernetesHostTerraformModulePath = "terraform/modules/openstack-rancher-k8s-host"
+)
+
+type openStackNodeTerraformConfig struct {
+	baseNodeTerraformConfig
+	openStackAuth
+
+	OpenStackSecgroupName string `json:"openstack_secgroup_name"`
+
+	OpenStackFlavorName     string `json:"openstack_flavor_name"`
+	OpenStackImageName      string `json:"openstack_image_name"`
+	OpenStackNetworkName    string `json:"openstack_network_name"`
+	OpenStackFloatingIPPool string `json:"openstack_floating_ip_pool"`
+	OpenStackKeyPair        string `json:"openstack_key_pair"`
+
+	OpenStackSSHUser        string `json:"openstack_ssh_user,omitempty"`
+	OpenStackPrivateKeyPath string `json:"openstack_private_key_path,omitempty"`
+}
+
+// Adds new OpenStack nodes to the given cluster and manager.
+// Returns:
+// - a slice of the hostnames added
+// - the new state
+// - error or nil
+func newOpenStackNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	baseConfig, err := getBaseNodeTerraformConfig(conf, openStackRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg := openStackNodeTerraformConfig{
+		baseNodeTerraformConfig: baseConfig,
+
+		// Grab variables from cluster config
+		openStackAuth: openStackAuth{
+			AuthURL:    currentState.Get(fmt.Sprintf("module.%s.openstack_auth_url", selectedCluster)),
+			UserName:   currentState.Get(fmt.Sprintf("module.%s.openstack_user_name", selectedCluster)),
+			Password:   currentState.Get(fmt.Sprintf("module.%s.openstack_password", selectedCluster)),
+			TenantName: currentState.Get(fmt.Sprintf("module.%s.openstack_tenant_name", selectedCluster)),
+			DomainName: currentState.Get(fmt.Sprintf("module.%s.openstack_domain_name", selectedCluster)),
+			Region:     currentState.Get(fmt.Sprintf("module.%s.openstack_region", selectedCluster)),
+		},
+
+		// Reference terraform output variables from cluster module
+		OpenStackSecgroupName: fmt.Sprintf("${module.%s.openstack_secgroup_name}", selectedCluster),
+	}
+
+	session, err := newOpenStackSession(cfg.openStackAuth)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.OpenStackFlavorName, err = getOpenStackFlavor(conf, session)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.OpenStackImageName, err = getOpenStackImage(conf, session)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.OpenStackNetworkName, cfg.OpenStackFloatingIPPool, err = getOpenStackNetworks(conf, session)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.OpenStackKeyPair, err = getOpenStackKeyPair(conf, session)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Control nodes of clusters encrypting secrets at rest get the encryption config over SSH
+	if cfg.KubernetesSecretsEncryptionConfig != "" {
+		cfg.OpenStackPrivateKeyPath, err = getNodeSSHKeyPath(conf, "openstack_private_key_path", "OpenStack Private Key Path", "~/.ssh/id_rsa")
+		if err != nil {
+			return []string{}, err
+		}
+
+		cfg.OpenStackSSHUser, err = getNodeSSHUser(conf, "openstack_ssh_user", "OpenStack SSH User", "ubuntu")
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	// Get existing node names
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	existingNames := []string{}
+	for nodeName := range nodes {
+		existingNames = append(existingNames, nodeName)
+	}
+
+	// Determine what the hostnames should be for the new node(s)
+	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
+
+	// Add new node to terraform config with the new hostnames
+	for _, newHostname := range newHostnames {
+		cfgCopy := cfg
+		cfgCopy.Hostname = newHostname
+		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	return newHostnames, nil
+}
@@ -0,0 +1,175 @@
+package create
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Node pools are backed by a cloud provider's instance group (AWS Auto Scaling Groups, Azure VM
+// Scale Sets and GCP managed instance groups).
+// The whole pool is a single node module named after the hostname prefix, the provider
+// creates, names and replaces its instances.
+type nodePoolProvider struct {
+	// Terraform module of the node pools
+	ModulePath string
+	// Config key of the number of instances in the node pool
+	CapacityKey string
+	// Returns true if the hostname belongs to an instance of the node pool
+	IsMember func(poolName, hostname string) bool
+	// Returns the hostnames of the live instances of the node pool
+	Hostnames func(conf config.Config, currentState state.State, nodeKey string) ([]string, error)
+	// Returns an error if the node pool can't have the given number of instances. Optional.
+	ValidateCapacity func(currentState state.State, nodeKey string, capacity int) error
+	// Sets whether the given instances may be removed when the capacity is reduced. Optional,
+	// providers without instance protection choose the instances to remove themselves.
+	ProtectFromScaleIn func(conf config.Config, currentState state.State, nodeKey string, hostnames []string, protect bool) error
+}
+
+var nodePoolProviders = []nodePoolProvider{
+	{
+		ModulePath:       awsRancherKubernetesASGTerraformModulePath,
+		CapacityKey:      "aws_asg_desired_capacity",
+		IsMember:         isAWSNodePoolMember,
+		Hostnames:        awsNodePoolHostnames,
+		ValidateCapacity: validateAWSNodePoolCapacity,
+	},
+	{
+		ModulePath:         azureRancherKubernetesVMSSTerraformModulePath,
+		CapacityKey:        "azure_vmss_capacity",
+		IsMember:           isAzureNodePoolMember,
+		Hostnames:          azureNodePoolHostnames,
+		ProtectFromScaleIn: azureNodePoolProtectFromScaleIn,
+	},
+	{
+		ModulePath:       gcpRancherKubernetesMIGTerraformModulePath,
+		CapacityKey:      "gcp_mig_target_size",
+		IsMember:         isGCPNodePoolMember,
+		Hostnames:        gcpNodePoolHostnames,
+		ValidateCapacity: validateGCPNodePoolCapacity,
+	},
+}
+
+// Returns the provider of the node module, if it is a node pool.
+func getNodePoolProvider(currentState state.State, nodeKey string) (nodePoolProvider, bool) {
+	source := currentState.Get(fmt.Sprintf("module.%s.source", nodeKey))
+	for _, provider := range nodePoolProviders {
+		if strings.Contains(source, provider.ModulePath) {
+			return provider, true
+		}
+	}
+
+	return nodePoolProvider{}, false
+}
+
+// Returns the name of the instance group of a new node pool. Auto Scaling Groups and scale sets
+// are named uniquely per region or resource group, which other clusters and cluster managers
+// share, so the name includes the cluster manager and cluster names.
+func newNodePoolGroupName(currentState state.State, clusterKey, hostname string) string {
+	clusterName := currentState.Get(fmt.Sprintf("module.%s.name", clusterKey))
+	return fmt.Sprintf("%s-%s-%s", currentState.Name, clusterName, hostname)
+}
+
+// Returns the name of the instance group of a node pool module, stored in the given module
+// variable. Pools created before it was set are named after their hostname prefix.
+func nodePoolGroupName(currentState state.State, nodeKey, variable string) string {
+	if name := currentState.Get(fmt.Sprintf("module.%s.%s", nodeKey, variable)); name != "" {
+		return name
+	}
+	return currentState.Get(fmt.Sprintf("module.%s.hostname", nodeKey))
+}
+
+// Nodes that aren't backed by an instance group are node modules of their own. The nodes
+// created together share a hostname prefix, e.g. dev-w-1 and dev-w-2, and form a node pool
+// named after the prefix, which is stored in the state with the number of nodes it has.
+
+// Node module variables that belong to a single machine rather than to its pool, e.g. the address
+// of a bare metal host. New nodes of a pool get values of their own instead of copying them.
+type nodeMachineInput struct {
+	// Terraform module of the nodes
+	ModulePath string
+	// Node module variable of the machine
+	Variable string
+	// Returns the values of the variable for the new nodes, in order
+	Values func(conf config.Config, newHostnames []string) ([]string, error)
+}
+
+var nodeMachineInputs = []nodeMachineInput{
+	{
+		ModulePath: bareMetalRancherKubernetesHostTerraformModulePath,
+		Variable:   "host",
+		Values:     getBareMetalHosts,
+	},
+}
+
+// Returns the per machine variables of the node module.
+func getNodeMachineInputs(currentState state.State, nodeKey string) []nodeMachineInput {
+	source := currentState.Get(fmt.Sprintf("module.%s.source", nodeKey))
+	inputs := []nodeMachineInput{}
+	for _, input := range nodeMachineInputs {
+		if strings.Contains(source, input.ModulePath) {
+			inputs = append(inputs, input)
+		}
+	}
+
+	return inputs
+}
+
+// Returns the hostnames of the nodes of the pool, ordered by their number.
+func getNodePoolMembers(nodes map[string]string, poolName string) []string {
+	members := []string{}
+	for hostname := range nodes {
+		if hostname != poolName && util.NodeHostnameSuffixRegexp.ReplaceAllString(hostname, "") == poolName {
+			members = append(members, hostname)
+		}
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return getHostnameNumber(members[i]) < getHostnameNumber(members[j])
+	})
+	return members
+}
+
+func getHostnameNumber(hostname string) int {
+	number, _ := strconv.Atoi(strings.TrimPrefix(util.NodeHostnameSuffixRegexp.FindString(hostname), "-"))
+	return number
+}
+
+// Stores the node pools the given hostnames belong to with the number of nodes they have now.
+// Pools without nodes are removed.
+func recordNodePools(currentState state.State, clusterKey string, hostnames []string) error {
+	nodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return err
+	}
+
+	poolNames := map[string]bool{}
+	for _, hostname := range hostnames {
+		if nodeKey, ok := nodes[hostname]; ok {
+			if _, ok := getNodePoolProvider(currentState, nodeKey); ok {
+				continue
+			}
+		}
+		poolNames[util.NodeHostnameSuffixRegexp.ReplaceAllString(hostname, "")] = true
+	}
+
+	for poolName := range poolNames {
+		members := getNodePoolMembers(nodes, poolName)
+		if len(members) == 0 {
+			currentState.DeleteNodePool(clusterKey, poolName)
+			continue
+		}
+
+		err = currentState.SetNodePool(clusterKey, poolName, state.NodePool{Count: len(members)})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
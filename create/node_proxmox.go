@@ -0,0 +1,107 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const (
+	proxmoxRancherKubernetesHostTerraformModulePath = "terraform/modules/proxmox-rancher-k8s-host"
+
+	defaultProxmoxCores    = "2"
+	defaultProxmoxMemory   = "2048"
+	defaultProxmoxDiskSize = "20"
+)
+
+type proxmoxNodeTerraformConfig struct {
+	baseNodeTerraformConfig
+	proxmoxAuth
+	proxmoxVMConfig
+
+	ProxmoxCores    string `json:"proxmox_cores"`
+	ProxmoxMemory   string `json:"proxmox_memory"`
+	ProxmoxDiskSize string `json:"proxmox_disk_size"`
+
+	ProxmoxSSHUser string `json:"proxmox_ssh_user"`
+	ProxmoxKeyPath string `json:"proxmox_key_path"`
+}
+
+// Adds new Proxmox VE nodes to the given cluster and manager.
+// Returns:
+// - a slice of the hostnames added
+// - the new state
+// - error or nil
+func newProxmoxNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	baseConfig, err := getBaseNodeTerraformConfig(conf, proxmoxRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+	if err != nil {
+		return []string{}, err
+	}
+
+	tlsInsecure, _ := currentState.GetMap(fmt.Sprintf("module.%s", selectedCluster))["proxmox_tls_insecure"].(bool)
+
+	cfg := proxmoxNodeTerraformConfig{
+		baseNodeTerraformConfig: baseConfig,
+
+		// Grab variables from cluster config
+		proxmoxAuth: proxmoxAuth{
+			APIURL:         currentState.Get(fmt.Sprintf("module.%s.proxmox_api_url", selectedCluster)),
+			APITokenID:     currentState.Get(fmt.Sprintf("module.%s.proxmox_api_token_id", selectedCluster)),
+			APITokenSecret: currentState.Get(fmt.Sprintf("module.%s.proxmox_api_token_secret", selectedCluster)),
+			TLSInsecure:    tlsInsecure,
+		},
+	}
+
+	cfg.proxmoxVMConfig, err = getProxmoxVMConfig(conf, cfg.proxmoxAuth)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// VM Size
+	cfg.ProxmoxCores, err = getProxmoxVMSize(conf, "proxmox_cores", "CPU Cores", defaultProxmoxCores)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.ProxmoxMemory, err = getProxmoxVMSize(conf, "proxmox_memory", "Memory (MB)", defaultProxmoxMemory)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.ProxmoxDiskSize, err = getProxmoxVMSize(conf, "proxmox_disk_size", "Disk Size (GB)", defaultProxmoxDiskSize)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cfg.ProxmoxSSHUser, cfg.ProxmoxKeyPath, err = getProxmoxSSHConfig(conf)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Get existing node names
+	nodes, err := currentState.Nodes(selectedCluster)
+	if err != nil {
+		return []string{}, err
+	}
+	existingNames := []string{}
+	for nodeName := range nodes {
+		existingNames = append(existingNames, nodeName)
+	}
+
+	// Determine what the hostnames should be for the new node(s)
+	newHostnames := getNewHostnames(existingNames, cfg.Hostname, cfg.NodeCount)
+
+	// Add new node to terraform config with the new hostnames
+	for _, newHostname := range newHostnames {
+		cfgCopy := cfg
+		cfgCopy.Hostname = newHostname
+		err = currentState.AddNode(selectedCluster, newHostname, cfgCopy)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	return newHostnames, nil
+}
@@ -0,0 +1,76 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Returns true if the nodes should be spot instances, or spot VMs on Azure. They cost a
+// fraction of the regular price, but the cloud provider can reclaim them at any time, so only
+// worker nodes can be, losing an etcd or control node breaks the cluster.
+func useSpotInstances(conf config.Config, cfg baseNodeTerraformConfig, key, label string) (bool, error) {
+	if cfg.RancherHostLabels.Worker != "true" {
+		if conf.GetBool(key) {
+			return false, fmt.Errorf("%s is only supported for worker nodes", key)
+		}
+		return false, nil
+	}
+
+	if conf.IsSet(key) {
+		return conf.GetBool(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return false, nil
+	}
+
+	return util.PromptForConfirmation(label, label)
+}
+
+// Returns the maximum hourly price of a spot instance in USD set at the given key, or an empty
+// string to pay at most the on-demand price of the instance type.
+func getSpotMaxPrice(conf config.Config, key string) (string, error) {
+	maxPrice := ""
+	if conf.IsSet(key) {
+		maxPrice = conf.GetString(key)
+	} else if !conf.GetBool("non-interactive") {
+		prompt := promptui.Prompt{
+			Label: "Maximum hourly price in USD (empty for the on-demand price)",
+			Validate: func(input string) error {
+				if input == "" {
+					return nil
+				}
+				return validateSpotMaxPrice(input)
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		maxPrice = result
+	}
+
+	if maxPrice == "" {
+		return "", nil
+	}
+	if validateSpotMaxPrice(maxPrice) != nil {
+		return "", fmt.Errorf("%s must be a price in USD greater than 0. Found '%s'.", key, maxPrice)
+	}
+	return maxPrice, nil
+}
+
+func validateSpotMaxPrice(input string) error {
+	price, err := strconv.ParseFloat(input, 64)
+	if err != nil {
+		return util.ConfigError(errors.New("Invalid price"))
+	}
+	if price <= 0 {
+		return errors.New("Price must be greater than 0")
+	}
+	return nil
+}
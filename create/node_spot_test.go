@@ -0,0 +1,69 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestUseSpotInstancesOnlyForWorkerNodes(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("aws_spot", true)
+
+	cfg := baseNodeTerraformConfig{}
+	cfg.RancherHostLabels.Etcd = "true"
+
+	expected := "aws_spot is only supported for worker nodes"
+	_, err := useSpotInstances(conf, cfg, "aws_spot", "")
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+
+	cfg = baseNodeTerraformConfig{}
+	cfg.RancherHostLabels.Worker = "true"
+
+	useSpot, err := useSpotInstances(conf, cfg, "aws_spot", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !useSpot {
+		t.Error("Expected worker nodes to be spot instances")
+	}
+}
+
+func TestGetSpotMaxPrice(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+
+	maxPrice, err := getSpotMaxPrice(conf, "aws_spot_max_price")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxPrice != "" {
+		t.Errorf("Expected the on-demand price by default, received %q", maxPrice)
+	}
+
+	conf.Set("aws_spot_max_price", "0.02")
+	maxPrice, err = getSpotMaxPrice(conf, "aws_spot_max_price")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxPrice != "0.02" {
+		t.Errorf("Wrong output, expected 0.02, received %q", maxPrice)
+	}
+
+	conf.Set("aws_spot_max_price", "-1")
+	expected := "aws_spot_max_price must be a price in USD greater than 0. Found '-1'."
+	_, err = getSpotMaxPrice(conf, "aws_spot_max_price")
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+
+	conf.Set("azure_spot_max_price", "free")
+	expected = "azure_spot_max_price must be a price in USD greater than 0. Found 'free'."
+	_, err = getSpotMaxPrice(conf, "azure_spot_max_price")
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
@@ -21,6 +21,10 @@ var getNewHostnamesTestCases = []struct {
 	{[]string{"foo", "bar"}, "test", 3, []string{"test-1", "test-2", "test-3"}},
 	{[]string{"test"}, "test", 3, []string{"test-1", "test-2", "test-3"}},
 	{[]string{"test-1", "test-2", "bar-3", "bar-4"}, "test", 3, []string{"test-3", "test-4", "test-5"}},
+	// hostname templates
+	{[]string{}, "worker-%02d", 3, []string{"worker-01", "worker-02", "worker-03"}},
+	{[]string{"worker-01", "worker-09"}, "worker-%02d", 2, []string{"worker-10", "worker-11"}},
+	{[]string{"worker-2"}, "worker-%d", 1, []string{"worker-3"}},
 }
 
 func TestGetNewHostnames(t *testing.T) {
@@ -46,3 +50,19 @@ func isEqual(expected, actual []string) bool {
 	}
 	return true
 }
+
+func TestValidateHostnameTemplate(t *testing.T) {
+	for _, hostname := range []string{"worker", "worker-%d", "worker-%02d", "eu-worker-%03d"} {
+		if err := validateHostnameTemplate(hostname); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", hostname, err)
+		}
+	}
+
+	for _, hostname := range []string{"worker%d", "worker-%s", "%d-worker", "worker-%d-%d", "worker-%2d"} {
+		err := validateHostnameTemplate(hostname)
+		expected := fmt.Sprintf("Invalid hostname '%s', a hostname template must end in -%%d or -%%0Nd e.g. worker-%%02d.", hostname)
+		if err == nil || err.Error() != expected {
+			t.Errorf("Wrong error for %q, expected %q, received %v", hostname, expected, err)
+		}
+	}
+}
@@ -9,14 +9,15 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 
 	triton "github.com/joyent/triton-go"
 	"github.com/joyent/triton-go/authentication"
 	"github.com/joyent/triton-go/compute"
 	"github.com/joyent/triton-go/network"
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -31,11 +32,21 @@ type tritonNodeTerraformConfig struct {
 	TritonKeyID   string `json:"triton_key_id"`
 	TritonURL     string `json:"triton_url,omitempty"`
 
+	TritonSSHKeyPath string `json:"triton_ssh_key_path,omitempty"`
+
 	TritonNetworkNames   []string `json:"triton_network_names,omitempty"`
 	TritonImageName      string   `json:"triton_image_name,omitempty"`
 	TritonImageVersion   string   `json:"triton_image_version,omitempty"`
 	TritonSSHUser        string   `json:"triton_ssh_user,omitempty"`
 	TritonMachinePackage string   `json:"triton_machine_package,omitempty"`
+
+	TritonTags     map[string]string `json:"triton_tags,omitempty"`
+	TritonMetadata map[string]string `json:"triton_metadata,omitempty"`
+
+	TritonHugepages    int    `json:"triton_hugepages,omitempty"`
+	TritonIsolatedCPUs string `json:"triton_isolated_cpus,omitempty"`
+
+	TritonIngressCNSService string `json:"triton_ingress_cns_service,omitempty"`
 }
 
 // Adds new Triton nodes to the given cluster and manager.
@@ -43,9 +54,9 @@ type tritonNodeTerraformConfig struct {
 // - a slice of the hostnames added
 // - the new state
 // - error or nil
-func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseNodeTerraformConfig(tritonRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+func newTritonNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseNodeTerraformConfig(conf, tritonRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
 	if err != nil {
 		return []string{}, err
 	}
@@ -60,6 +71,24 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 		TritonURL:     currentState.Get(fmt.Sprintf("module.%s.triton_url", selectedCluster)),
 	}
 
+	// Node pools may use a different account than the cluster
+	err = getTritonNodeAccountConfig(conf, &cfg)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// The key generated for the cluster is one of its account's keys, which the machines accept
+	sshKey, ok := currentState.SSHKey(selectedCluster)
+	if ok && sshKey.TritonKeyName != "" && cfg.TritonAccount == currentState.Get(fmt.Sprintf("module.%s.triton_account", selectedCluster)) {
+		cfg.TritonSSHKeyPath = sshKey.PrivateKeyPath
+	}
+
+	// Worker nodes join the CNS service the cluster's ingress load balancer sends traffic to
+	ingressLoadBalancerKey, ok := getIngressLoadBalancerKey(currentState, selectedCluster)
+	if ok && cfg.RancherHostLabels.Worker == "true" {
+		cfg.TritonIngressCNSService = currentState.Get(fmt.Sprintf("module.%s.triton_cns_service", ingressLoadBalancerKey))
+	}
+
 	keyMaterial, err := ioutil.ReadFile(cfg.TritonKeyPath)
 	if err != nil {
 		return []string{}, err
@@ -92,8 +121,8 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 	}
 
 	// Triton Network Names
-	if viper.IsSet("triton_network_names") {
-		cfg.TritonNetworkNames = viper.GetStringSlice("triton_network_names")
+	if conf.IsSet("triton_network_names") {
+		cfg.TritonNetworkNames = conf.GetStringSlice("triton_network_names")
 
 		// Verify triton network names
 		validNetworksMap := map[string]struct{}{}
@@ -105,11 +134,11 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 
 		for _, network := range cfg.TritonNetworkNames {
 			if _, ok := validNetworksMap[network]; !ok {
-				return []string{}, fmt.Errorf("Invalid Triton Network '%s', must be one of the following: %s", network, strings.Join(validNetworksSlice, ", "))
+				return []string{}, util.ConfigError(fmt.Errorf("Invalid Triton Network '%s', must be one of the following: %s", network, strings.Join(validNetworksSlice, ", ")))
 			}
 		}
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("triton_network_names must be specified")
+		return []string{}, util.ConfigError(errors.New("triton_network_names must be specified"))
 	} else {
 		networkPrompt := promptui.Select{
 			Label: "Triton Networks to attach",
@@ -178,13 +207,22 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 	}
 
 	// Triton Image Name and Triton Image Version
-	if viper.IsSet("triton_image_name") && viper.IsSet("triton_image_version") {
-		cfg.TritonImageName = viper.GetString("triton_image_name")
-		cfg.TritonImageVersion = viper.GetString("triton_image_version")
+	var selectedImage *compute.Image
+	if conf.IsSet("triton_image_name") && conf.IsSet("triton_image_version") {
+		cfg.TritonImageName = conf.GetString("triton_image_name")
+		cfg.TritonImageVersion = conf.GetString("triton_image_version")
 
-		// TODO: Verify Triton Image Name/Version
+		// Verify Triton Image Name/Version
+		images, err := tritonComputeClient.Images().List(context.Background(), &compute.ListImagesInput{Name: cfg.TritonImageName, Version: cfg.TritonImageVersion})
+		if err != nil {
+			return []string{}, err
+		}
+		if len(images) == 0 {
+			return []string{}, util.ConfigError(fmt.Errorf("Invalid Triton Image Name and Version '%s@%s'", cfg.TritonImageName, cfg.TritonImageVersion))
+		}
+		selectedImage = images[0]
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("Both triton_image_name and triton_image_version must be specified")
+		return []string{}, util.ConfigError(errors.New("Both triton_image_name and triton_image_version must be specified"))
 	} else {
 		listImageInput := compute.ListImagesInput{}
 		images, err := tritonComputeClient.Images().List(context.Background(), &listImageInput)
@@ -222,15 +260,16 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 			return []string{}, err
 		}
 
+		selectedImage = images[i]
 		cfg.TritonImageName = images[i].Name
 		cfg.TritonImageVersion = images[i].Version
 	}
 
 	// Triton SSH User
-	if viper.IsSet("triton_ssh_user") {
-		cfg.TritonSSHUser = viper.GetString("triton_ssh_user")
+	if conf.IsSet("triton_ssh_user") {
+		cfg.TritonSSHUser = conf.GetString("triton_ssh_user")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("triton_ssh_user must be specified")
+		return []string{}, util.ConfigError(errors.New("triton_ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label:   "Triton SSH User",
@@ -245,12 +284,26 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 	}
 
 	// Triton Machine Package
-	if viper.IsSet("triton_machine_package") {
-		cfg.TritonMachinePackage = viper.GetString("triton_machine_package")
+	var selectedPackage *compute.Package
+	if conf.IsSet("triton_machine_package") {
+		cfg.TritonMachinePackage = conf.GetString("triton_machine_package")
 
-		// TODO: Verify triton_machine_package
+		// Verify triton_machine_package
+		packages, err := tritonComputeClient.Packages().List(context.Background(), &compute.ListPackagesInput{Name: cfg.TritonMachinePackage})
+		if err != nil {
+			return []string{}, err
+		}
+		if len(packages) == 0 {
+			return []string{}, fmt.Errorf("Triton machine package '%s' does not exist", cfg.TritonMachinePackage)
+		}
+		selectedPackage = packages[0]
+
+		err = validateTritonImagePackage(*selectedImage, *selectedPackage)
+		if err != nil {
+			return []string{}, err
+		}
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("triton_machine_package must be specified")
+		return []string{}, util.ConfigError(errors.New("triton_machine_package must be specified"))
 	} else {
 		listPackageInput := compute.ListPackagesInput{}
 		packages, err := tritonComputeClient.Packages().List(context.Background(), &listPackageInput)
@@ -258,6 +311,12 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 			return []string{}, err
 		}
 
+		// Only offer the packages the image can be provisioned with
+		packages = getCompatibleTritonPackages(*selectedImage, packages)
+		if len(packages) == 0 {
+			return []string{}, fmt.Errorf("No Triton machine packages are compatible with image '%s@%s'", cfg.TritonImageName, cfg.TritonImageVersion)
+		}
+
 		// Sort packages by memory size in increasing order
 		sort.SliceStable(packages, func(i, j int) bool {
 			return packages[i].Memory < packages[j].Memory
@@ -288,9 +347,58 @@ func newTritonNode(selectedClusterManager, selectedCluster string, remoteBackend
 			return []string{}, err
 		}
 
+		selectedPackage = packages[i]
 		cfg.TritonMachinePackage = packages[i].Name
 	}
 
+	// Triton Tags and Metadata are optional and only read from the config file
+	if conf.IsSet("triton_tags") {
+		cfg.TritonTags = conf.GetStringMapString("triton_tags")
+		if _, ok := cfg.TritonTags["role"]; ok {
+			return []string{}, errors.New("triton_tags can't set the 'role' tag, it is set to the rancher_host_label of the node")
+		}
+	}
+
+	if conf.IsSet("triton_metadata") {
+		cfg.TritonMetadata = conf.GetStringMapString("triton_metadata")
+		if _, ok := cfg.TritonMetadata["user-script"]; ok {
+			return []string{}, errors.New("triton_metadata can't set 'user-script', it is used to install the Rancher agent")
+		}
+	}
+
+	// Nodes can be restricted to some of the account's keys, which are otherwise all authorized
+	if conf.IsSet("triton_ssh_key_fingerprints") {
+		if _, ok := cfg.TritonMetadata["root_authorized_keys"]; ok {
+			return []string{}, errors.New("triton_metadata can't set 'root_authorized_keys' when triton_ssh_key_fingerprints is set")
+		}
+
+		keys, err := listTritonAccountKeys(tritonComputeClient)
+		if err != nil {
+			return []string{}, err
+		}
+
+		authorizedKeys, err := getTritonAuthorizedKeys(keys, conf.GetStringSlice("triton_ssh_key_fingerprints"))
+		if err != nil {
+			return []string{}, err
+		}
+
+		if cfg.TritonMetadata == nil {
+			cfg.TritonMetadata = map[string]string{}
+		}
+		cfg.TritonMetadata["root_authorized_keys"] = authorizedKeys
+	}
+
+	// Triton Hugepages and Isolated CPUs are optional and only read from the config file
+	if conf.IsSet("triton_hugepages") || conf.IsSet("triton_isolated_cpus") {
+		cfg.TritonHugepages = conf.GetInt("triton_hugepages")
+		cfg.TritonIsolatedCPUs = conf.GetString("triton_isolated_cpus")
+
+		err = validateTritonNodeTuning(cfg.TritonHugepages, cfg.TritonIsolatedCPUs, *selectedPackage)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
 	// Get existing node names
 	nodes, err := currentState.Nodes(selectedCluster)
 	if err != nil {
@@ -6,11 +6,12 @@ import (
 	"os"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 	homedir "github.com/mitchellh/go-homedir"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
 const (
@@ -39,9 +40,9 @@ type vSphereNodeTerraformConfig struct {
 // - a slice of the hostnames added
 // - the new state
 // - error or nil
-func newVSphereNode(selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
-	nonInteractiveMode := viper.GetBool("non-interactive")
-	baseConfig, err := getBaseNodeTerraformConfig(vSphereRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
+func newVSphereNode(conf config.Config, selectedClusterManager, selectedCluster string, remoteBackend backend.Backend, currentState state.State) ([]string, error) {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	baseConfig, err := getBaseNodeTerraformConfig(conf, vSphereRancherKubernetesHostTerraformModulePath, selectedCluster, currentState)
 	if err != nil {
 		return []string{}, err
 	}
@@ -61,10 +62,10 @@ func newVSphereNode(selectedClusterManager, selectedCluster string, remoteBacken
 		VSphereNetworkName:      fmt.Sprintf("${module.%s.vsphere_network_name}", selectedCluster),
 	}
 
-	if viper.IsSet("vsphere_template_name") {
-		cfg.VSphereTemplateName = viper.GetString("vsphere_template_name")
+	if conf.IsSet("vsphere_template_name") {
+		cfg.VSphereTemplateName = conf.GetString("vsphere_template_name")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("vsphere_template_name must be specified")
+		return []string{}, util.ConfigError(errors.New("vsphere_template_name must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "VM Template Name",
@@ -83,10 +84,10 @@ func newVSphereNode(selectedClusterManager, selectedCluster string, remoteBacken
 	}
 
 	// SSH User
-	if viper.IsSet("ssh_user") {
-		cfg.SSHUser = viper.GetString("ssh_user")
+	if conf.IsSet("ssh_user") {
+		cfg.SSHUser = conf.GetString("ssh_user")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("ssh_user must be specified")
+		return []string{}, util.ConfigError(errors.New("ssh_user must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "SSH User",
@@ -106,10 +107,10 @@ func newVSphereNode(selectedClusterManager, selectedCluster string, remoteBacken
 
 	// Private Key Path
 	rawKeyPath := ""
-	if viper.IsSet("key_path") {
-		rawKeyPath = viper.GetString("key_path")
+	if conf.IsSet("key_path") {
+		rawKeyPath = conf.GetString("key_path")
 	} else if nonInteractiveMode {
-		return []string{}, errors.New("key_path must be specified")
+		return []string{}, util.ConfigError(errors.New("key_path must be specified"))
 	} else {
 		prompt := promptui.Prompt{
 			Label: "Private Key Path",
@@ -0,0 +1,324 @@
+package create
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	defaultNutanixPort    = "9440"
+	defaultNutanixSSHUser = "ubuntu"
+
+	// Entities listed per request to Prism Central
+	nutanixListLength = 100
+)
+
+// The Prism Central account of a Nutanix deployment, shared by the manager, the cluster and its
+// nodes. Prism Central often has a self-signed certificate, Insecure skips its verification.
+type nutanixAuth struct {
+	Endpoint string `json:"nutanix_endpoint"`
+	Port     string `json:"nutanix_port"`
+	Username string `json:"nutanix_username"`
+	Password string `json:"nutanix_password"`
+	Insecure bool   `json:"nutanix_insecure,omitempty"`
+}
+
+// Where a VM is created: the AHV cluster it runs on, the subnet of its network interface and the
+// disk image its disk is cloned from.
+type nutanixVMConfig struct {
+	NutanixClusterUUID string `json:"nutanix_cluster_uuid"`
+	NutanixSubnetUUID  string `json:"nutanix_subnet_uuid"`
+	NutanixImageUUID   string `json:"nutanix_image_uuid"`
+}
+
+// An entity of the Prism Central v3 API: a cluster, a subnet or an image.
+type nutanixEntity struct {
+	Metadata struct {
+		UUID string `json:"uuid"`
+	} `json:"metadata"`
+	Status struct {
+		Name             string `json:"name"`
+		ClusterReference struct {
+			UUID string `json:"uuid"`
+		} `json:"cluster_reference"`
+		Resources struct {
+			Config struct {
+				ServiceList []string `json:"service_list"`
+			} `json:"config"`
+			VLANID    int    `json:"vlan_id"`
+			ImageType string `json:"image_type"`
+			SizeBytes int64  `json:"size_bytes"`
+		} `json:"resources"`
+	} `json:"status"`
+}
+
+type nutanixListInput struct {
+	Kind   string `json:"kind"`
+	Length int    `json:"length"`
+	Offset int    `json:"offset"`
+}
+
+// Returns the URL of the Prism Central v3 API.
+func nutanixAPIURL(auth nutanixAuth) string {
+	return fmt.Sprintf("https://%s:%s/api/nutanix/v3", auth.Endpoint, auth.Port)
+}
+
+// Sends a POST request to the Prism Central v3 API, authenticated with the account, and decodes
+// the response into v.
+func postNutanix(auth nutanixAuth, path string, body, v interface{}) error {
+	rawBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", nutanixAPIURL(auth)+path, bytes.NewReader(rawBody))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(auth.Username, auth.Password)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := http.DefaultClient
+	if auth.Insecure {
+		client = &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// Prism gives the reasons of an error in its message list
+		apiErr := struct {
+			MessageList []struct {
+				Message string `json:"message"`
+			} `json:"message_list"`
+		}{}
+		messages := []string{}
+		if json.Unmarshal(respBody, &apiErr) == nil {
+			for _, message := range apiErr.MessageList {
+				messages = append(messages, message.Message)
+			}
+		}
+		if len(messages) == 0 {
+			messages = append(messages, resp.Status)
+		}
+		return fmt.Errorf("Prism API request %s failed: %s", path, strings.Join(messages, ", "))
+	}
+
+	return json.Unmarshal(respBody, v)
+}
+
+// Returns every entity of the given kind, a page at a time.
+func listNutanixEntities(auth nutanixAuth, kind string) ([]nutanixEntity, error) {
+	entities := []nutanixEntity{}
+	for {
+		page := struct {
+			Metadata struct {
+				TotalMatches int `json:"total_matches"`
+			} `json:"metadata"`
+			Entities []nutanixEntity `json:"entities"`
+		}{}
+		input := nutanixListInput{Kind: kind, Length: nutanixListLength, Offset: len(entities)}
+		err := postNutanix(auth, fmt.Sprintf("/%ss/list", kind), &input, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		entities = append(entities, page.Entities...)
+		if len(page.Entities) == 0 || len(entities) >= page.Metadata.TotalMatches {
+			return entities, nil
+		}
+	}
+}
+
+// Returns the AHV clusters by name. Prism Central lists itself as a cluster, VMs can't run on it.
+func nutanixClusterOptions(clusters []nutanixEntity) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, cluster := range clusters {
+		isPrismCentral := false
+		for _, service := range cluster.Status.Resources.Config.ServiceList {
+			if service == "PRISM_CENTRAL" {
+				isPrismCentral = true
+			}
+		}
+		if isPrismCentral {
+			continue
+		}
+		options = append(options, util.PromptOption{Value: cluster.Metadata.UUID, Name: cluster.Status.Name, Label: cluster.Status.Name})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Name < options[j].Name
+	})
+	return options
+}
+
+// Returns the subnets of the given cluster by name.
+func nutanixSubnetOptions(subnets []nutanixEntity, clusterUUID string) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, subnet := range subnets {
+		if subnet.Status.ClusterReference.UUID != clusterUUID {
+			continue
+		}
+		label := fmt.Sprintf("%s (VLAN %d)", subnet.Status.Name, subnet.Status.Resources.VLANID)
+		options = append(options, util.PromptOption{Value: subnet.Metadata.UUID, Name: subnet.Status.Name, Label: label})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Name < options[j].Name
+	})
+	return options
+}
+
+// Returns the disk images by name, ISO images can't be cloned into a disk.
+func nutanixImageOptions(images []nutanixEntity) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, image := range images {
+		if image.Status.Resources.ImageType != "DISK_IMAGE" {
+			continue
+		}
+		label := fmt.Sprintf("%s (%.1f GB)", image.Status.Name, float64(image.Status.Resources.SizeBytes)/(1024*1024*1024))
+		options = append(options, util.PromptOption{Value: image.Metadata.UUID, Name: image.Status.Name, Label: label})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Name < options[j].Name
+	})
+	return options
+}
+
+func getNutanixAuth(conf config.Config) (nutanixAuth, error) {
+	auth := nutanixAuth{}
+
+	var err error
+	auth.Endpoint, err = util.PromptForValue(conf, "nutanix_endpoint", "Prism Central Address", "", false)
+	if err != nil {
+		return nutanixAuth{}, err
+	}
+	// Only the host is kept, e.g. of https://pc.example.com:9440/
+	if strings.Contains(auth.Endpoint, "://") {
+		endpointURL, err := url.Parse(auth.Endpoint)
+		if err != nil || endpointURL.Hostname() == "" {
+			return nutanixAuth{}, util.ConfigError(fmt.Errorf("Invalid nutanix_endpoint '%s', expected the address of Prism Central, e.g. 'pc.example.com'", auth.Endpoint))
+		}
+		auth.Endpoint = endpointURL.Hostname()
+	}
+
+	auth.Port, err = util.PromptForValue(conf, "nutanix_port", "Prism Central Port", defaultNutanixPort, false)
+	if err != nil {
+		return nutanixAuth{}, err
+	}
+
+	auth.Username, err = util.PromptForValue(conf, "nutanix_username", "Prism Central Username", "", false)
+	if err != nil {
+		return nutanixAuth{}, err
+	}
+
+	auth.Password, err = util.PromptForValue(conf, "nutanix_password", "Prism Central Password", "", true)
+	if err != nil {
+		return nutanixAuth{}, err
+	}
+
+	auth.Insecure = conf.GetBool("nutanix_insecure")
+
+	return auth, nil
+}
+
+// Returns the cluster, subnet and image of a VM. The subnets offered are the ones of the selected
+// cluster.
+func getNutanixVMConfig(conf config.Config, auth nutanixAuth) (nutanixVMConfig, error) {
+	cfg := nutanixVMConfig{}
+
+	clusters, err := listNutanixEntities(auth, "cluster")
+	if err != nil {
+		return nutanixVMConfig{}, err
+	}
+	cfg.NutanixClusterUUID, err = util.PromptForOption(conf, "nutanix_cluster", "Nutanix Cluster", nutanixClusterOptions(clusters))
+	if err != nil {
+		return nutanixVMConfig{}, err
+	}
+
+	subnets, err := listNutanixEntities(auth, "subnet")
+	if err != nil {
+		return nutanixVMConfig{}, err
+	}
+	cfg.NutanixSubnetUUID, err = util.PromptForOption(conf, "nutanix_subnet", "Nutanix Subnet", nutanixSubnetOptions(subnets, cfg.NutanixClusterUUID))
+	if err != nil {
+		return nutanixVMConfig{}, err
+	}
+
+	images, err := listNutanixEntities(auth, "image")
+	if err != nil {
+		return nutanixVMConfig{}, err
+	}
+	cfg.NutanixImageUUID, err = util.PromptForOption(conf, "nutanix_image", "Nutanix Image", nutanixImageOptions(images))
+	if err != nil {
+		return nutanixVMConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// Returns the user cloud-init creates on the VMs and the path of its private key. The public
+// key must be next to the private key, with a .pub extension.
+func getNutanixSSHConfig(conf config.Config) (string, string, error) {
+	sshUser, err := util.PromptForValue(conf, "nutanix_ssh_user", "SSH User", defaultNutanixSSHUser, false)
+	if err != nil {
+		return "", "", err
+	}
+
+	rawKeyPath, err := util.PromptForValue(conf, "nutanix_key_path", "Private Key Path", "~/.ssh/id_rsa", false)
+	if err != nil {
+		return "", "", err
+	}
+
+	keyPath, err := homedir.Expand(rawKeyPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = os.Stat(keyPath + ".pub")
+	if err != nil {
+		return "", "", fmt.Errorf("Public key of nutanix_key_path '%s' not found, expected it at '%s.pub'", rawKeyPath, rawKeyPath)
+	}
+
+	return sshUser, keyPath, nil
+}
+
+// Returns the given VM size setting, which must be a number greater than 0.
+func getNutanixVMSize(conf config.Config, key, label, defaultValue string) (string, error) {
+	value, err := util.PromptForValue(conf, key, label, defaultValue, false)
+	if err != nil {
+		return "", err
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil || num <= 0 {
+		return "", errors.New(key + " must be a number greater than 0")
+	}
+
+	return value, nil
+}
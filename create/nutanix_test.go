@@ -0,0 +1,122 @@
+package create
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// Returns a config with the Prism Central account of the given TLS test server.
+func nutanixTestConfig(t *testing.T, server *httptest.Server) config.Config {
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("nutanix_endpoint", server.URL)
+	conf.Set("nutanix_port", serverURL.Port())
+	conf.Set("nutanix_username", "admin")
+	conf.Set("nutanix_password", "secret")
+	conf.Set("nutanix_insecure", true)
+	return conf
+}
+
+func TestGetNutanixVMConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if username != "admin" || password != "secret" {
+			t.Errorf("Wrong credentials, received %q and %q", username, password)
+		}
+
+		input := nutanixListInput{}
+		json.NewDecoder(r.Body).Decode(&input)
+		if input.Offset != 0 {
+			t.Errorf("Expected a single page, received offset %d", input.Offset)
+		}
+
+		switch r.URL.Path {
+		case "/api/nutanix/v3/clusters/list":
+			fmt.Fprint(w, `{"metadata": {"total_matches": 2}, "entities": [
+				{"metadata": {"uuid": "pc-uuid"}, "status": {"name": "pc", "resources": {"config": {"service_list": ["PRISM_CENTRAL"]}}}},
+				{"metadata": {"uuid": "ahv-uuid"}, "status": {"name": "ahv1", "resources": {"config": {"service_list": ["AOS"]}}}}
+			]}`)
+		case "/api/nutanix/v3/subnets/list":
+			fmt.Fprint(w, `{"metadata": {"total_matches": 3}, "entities": [
+				{"metadata": {"uuid": "vm-net-uuid"}, "status": {"name": "vm-net", "cluster_reference": {"uuid": "ahv-uuid"}, "resources": {"vlan_id": 10}}},
+				{"metadata": {"uuid": "other-vm-net-uuid"}, "status": {"name": "vm-net", "cluster_reference": {"uuid": "other-uuid"}, "resources": {"vlan_id": 10}}},
+				{"metadata": {"uuid": "mgmt-uuid"}, "status": {"name": "mgmt", "cluster_reference": {"uuid": "ahv-uuid"}, "resources": {"vlan_id": 0}}}
+			]}`)
+		case "/api/nutanix/v3/images/list":
+			fmt.Fprint(w, `{"metadata": {"total_matches": 3}, "entities": [
+				{"metadata": {"uuid": "image-1"}, "status": {"name": "ubuntu-cloud", "resources": {"image_type": "DISK_IMAGE"}}},
+				{"metadata": {"uuid": "image-2"}, "status": {"name": "ubuntu-cloud", "resources": {"image_type": "DISK_IMAGE"}}},
+				{"metadata": {"uuid": "iso-1"}, "status": {"name": "ubuntu-iso", "resources": {"image_type": "ISO_IMAGE"}}}
+			]}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	conf := nutanixTestConfig(t, server)
+	conf.Set("nutanix_cluster", "ahv1")
+	conf.Set("nutanix_subnet", "vm-net")
+	conf.Set("nutanix_image", "image-2")
+
+	auth, err := getNutanixAuth(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Endpoint != "127.0.0.1" {
+		t.Errorf("Expected the host of nutanix_endpoint to be kept, received %q", auth.Endpoint)
+	}
+
+	cfg, err := getNutanixVMConfig(conf, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := nutanixVMConfig{NutanixClusterUUID: "ahv-uuid", NutanixSubnetUUID: "vm-net-uuid", NutanixImageUUID: "image-2"}
+	if cfg != expected {
+		t.Errorf("Wrong output, expected %+v, received %+v", expected, cfg)
+	}
+
+	// Two images share the name
+	conf.Set("nutanix_image", "ubuntu-cloud")
+	_, err = getNutanixVMConfig(conf, auth)
+	if err == nil || !strings.Contains(err.Error(), "image-1, image-2") {
+		t.Errorf("Expected an ambiguous image name to be rejected, received %v", err)
+	}
+
+	// Prism Central itself can't run VMs
+	conf.Set("nutanix_cluster", "pc")
+	_, err = getNutanixVMConfig(conf, auth)
+	if err == nil || !strings.Contains(err.Error(), "'pc' does not exist") {
+		t.Errorf("Expected Prism Central to be rejected as a cluster, received %v", err)
+	}
+}
+
+func TestNutanixCredentialsValidate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"state": "ERROR", "message_list": [{"message": "Authentication required."}]}`)
+	}))
+	defer server.Close()
+
+	auth, err := getNutanixAuth(nutanixTestConfig(t, server))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = nutanixCredentials{auth}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "nutanix_password") || !strings.Contains(err.Error(), "Authentication required.") {
+		t.Errorf("Expected an error naming the account settings, received %v", err)
+	}
+}
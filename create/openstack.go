@@ -0,0 +1,267 @@
+package create
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/external"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+)
+
+// The credentials of an OpenStack project, shared by the manager, the cluster and its nodes.
+type openStackAuth struct {
+	AuthURL    string `json:"openstack_auth_url"`
+	UserName   string `json:"openstack_user_name"`
+	Password   string `json:"openstack_password"`
+	TenantName string `json:"openstack_tenant_name"`
+	DomainName string `json:"openstack_domain_name"`
+	Region     string `json:"openstack_region"`
+}
+
+// Clients of the public endpoints of the compute, image and network services in the region,
+// given by the service catalog of a Keystone token.
+type openStackSession struct {
+	Compute *gophercloud.ServiceClient
+	Image   *gophercloud.ServiceClient
+	Network *gophercloud.ServiceClient
+}
+
+// A network with whether floating IPs are allocated from it.
+type openStackNetwork struct {
+	networks.Network
+	external.NetworkExternalExt
+}
+
+// Gets a token of the project from Keystone, with the endpoints of the region.
+func newOpenStackSession(auth openStackAuth) (openStackSession, error) {
+	provider, err := openstack.AuthenticatedClient(gophercloud.AuthOptions{
+		IdentityEndpoint: auth.AuthURL,
+		Username:         auth.UserName,
+		Password:         auth.Password,
+		TenantName:       auth.TenantName,
+		DomainName:       auth.DomainName,
+	})
+	if err != nil {
+		return openStackSession{}, fmt.Errorf("OpenStack authentication with %s failed: %v", auth.AuthURL, openStackError(err))
+	}
+
+	endpointOpts := gophercloud.EndpointOpts{Region: auth.Region, Availability: gophercloud.AvailabilityPublic}
+	session := openStackSession{}
+	session.Compute, err = openstack.NewComputeV2(provider, endpointOpts)
+	if err == nil {
+		session.Image, err = openstack.NewImageServiceV2(provider, endpointOpts)
+	}
+	if err == nil {
+		session.Network, err = openstack.NewNetworkV2(provider, endpointOpts)
+	}
+	if err != nil {
+		return openStackSession{}, fmt.Errorf("OpenStack region '%s' has no public compute, image or network endpoint: %v", auth.Region, err)
+	}
+
+	return session, nil
+}
+
+// Returns the message of the error body of an unexpected response, which gophercloud replaces
+// with a generic one for some status codes, e.g. "Authentication failed".
+func openStackError(err error) error {
+	var responseErr gophercloud.ErrUnexpectedResponseCode
+	if !errors.As(err, &responseErr) {
+		return err
+	}
+
+	body := struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	if json.Unmarshal(responseErr.Body, &body) != nil || body.Error.Message == "" {
+		return err
+	}
+	return errors.New(body.Error.Message)
+}
+
+func listOpenStackFlavors(session openStackSession) ([]flavors.Flavor, error) {
+	pages, err := flavors.ListDetail(session.Compute, flavors.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return flavors.ExtractFlavors(pages)
+}
+
+func listOpenStackKeyPairs(session openStackSession) ([]keypairs.KeyPair, error) {
+	pages, err := keypairs.List(session.Compute, keypairs.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return keypairs.ExtractKeyPairs(pages)
+}
+
+func listOpenStackImages(session openStackSession) ([]images.Image, error) {
+	pages, err := images.List(session.Image, images.ListOpts{Status: images.ImageStatusActive, Limit: 200}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return images.ExtractImages(pages)
+}
+
+func listOpenStackNetworks(session openStackSession) ([]openStackNetwork, error) {
+	pages, err := networks.List(session.Network, networks.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+
+	list := []openStackNetwork{}
+	err = networks.ExtractNetworksInto(pages, &list)
+	return list, err
+}
+
+// Returns the flavors, smallest first.
+func openStackFlavorOptions(flavorList []flavors.Flavor) []util.PromptOption {
+	sorted := append([]flavors.Flavor{}, flavorList...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].RAM != sorted[j].RAM {
+			return sorted[i].RAM < sorted[j].RAM
+		}
+		return sorted[i].VCPUs < sorted[j].VCPUs
+	})
+
+	options := []util.PromptOption{}
+	for _, flavor := range sorted {
+		label := fmt.Sprintf("%s (%d vCPUs, %d MB memory, %d GB disk)", flavor.Name, flavor.VCPUs, flavor.RAM, flavor.Disk)
+		options = append(options, util.PromptOption{Value: flavor.Name, Label: label})
+	}
+	return options
+}
+
+// Returns the active images by name. Instances are created from an image by name, so images
+// without one are left out.
+func openStackImageOptions(imageList []images.Image) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, image := range imageList {
+		if image.Name == "" || image.Status != images.ImageStatusActive {
+			continue
+		}
+		options = append(options, util.PromptOption{Value: image.Name, Label: fmt.Sprintf("%s (%s)", image.Name, image.ID)})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Value < options[j].Value
+	})
+	return options
+}
+
+// Returns the networks instances can be attached to, or with external, the networks floating
+// IPs are allocated from.
+func openStackNetworkOptions(networkList []openStackNetwork, external bool) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, network := range networkList {
+		if network.Name == "" || network.External != external {
+			continue
+		}
+		options = append(options, util.PromptOption{Value: network.Name, Label: fmt.Sprintf("%s (%s)", network.Name, network.ID)})
+	}
+	return options
+}
+
+func openStackKeyPairOptions(keyPairs []keypairs.KeyPair) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, keyPair := range keyPairs {
+		options = append(options, util.PromptOption{Value: keyPair.Name, Label: fmt.Sprintf("%s (%s)", keyPair.Name, keyPair.Fingerprint)})
+	}
+	return options
+}
+
+func getOpenStackAuth(conf config.Config) (openStackAuth, error) {
+	auth := openStackAuth{}
+
+	var err error
+	auth.AuthURL, err = util.PromptForValue(conf, "openstack_auth_url", "OpenStack Identity (Keystone v3) URL", "", false)
+	if err != nil {
+		return openStackAuth{}, err
+	}
+
+	auth.UserName, err = util.PromptForValue(conf, "openstack_user_name", "OpenStack User Name", "", false)
+	if err != nil {
+		return openStackAuth{}, err
+	}
+
+	auth.Password, err = util.PromptForValue(conf, "openstack_password", "OpenStack Password", "", true)
+	if err != nil {
+		return openStackAuth{}, err
+	}
+
+	auth.TenantName, err = util.PromptForValue(conf, "openstack_tenant_name", "OpenStack Project (Tenant) Name", "", false)
+	if err != nil {
+		return openStackAuth{}, err
+	}
+
+	auth.DomainName, err = util.PromptForValue(conf, "openstack_domain_name", "OpenStack Domain Name", "Default", false)
+	if err != nil {
+		return openStackAuth{}, err
+	}
+
+	auth.Region, err = util.PromptForValue(conf, "openstack_region", "OpenStack Region", "RegionOne", false)
+	if err != nil {
+		return openStackAuth{}, err
+	}
+
+	return auth, nil
+}
+
+func getOpenStackFlavor(conf config.Config, session openStackSession) (string, error) {
+	flavors, err := listOpenStackFlavors(session)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "openstack_flavor_name", "OpenStack Flavor", openStackFlavorOptions(flavors))
+}
+
+func getOpenStackImage(conf config.Config, session openStackSession) (string, error) {
+	images, err := listOpenStackImages(session)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "openstack_image_name", "OpenStack Image", openStackImageOptions(images))
+}
+
+func getOpenStackKeyPair(conf config.Config, session openStackSession) (string, error) {
+	keyPairs, err := listOpenStackKeyPairs(session)
+	if err != nil {
+		return "", err
+	}
+	return util.PromptForOption(conf, "openstack_key_pair", "OpenStack Key Pair", openStackKeyPairOptions(keyPairs))
+}
+
+// Returns the network instances are attached to, and the pool their floating IPs are allocated
+// from. Without a pool, instances are reached at their fixed IP on the network.
+func getOpenStackNetworks(conf config.Config, session openStackSession) (string, string, error) {
+	networks, err := listOpenStackNetworks(session)
+	if err != nil {
+		return "", "", err
+	}
+
+	network, err := util.PromptForOption(conf, "openstack_network_name", "OpenStack Network", openStackNetworkOptions(networks, false))
+	if err != nil {
+		return "", "", err
+	}
+
+	floatingIPPool := ""
+	if conf.IsSet("openstack_floating_ip_pool") || !conf.GetBool("non-interactive") {
+		options := append([]util.PromptOption{{Value: "", Label: "none (use the fixed IP)"}}, openStackNetworkOptions(networks, true)...)
+		floatingIPPool, err = util.PromptForOption(conf, "openstack_floating_ip_pool", "OpenStack Floating IP Pool", options)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return network, floatingIPPool, nil
+}
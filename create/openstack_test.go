@@ -0,0 +1,199 @@
+package create
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+)
+
+// Serves Keystone v3 tokens with a catalog of the compute, image and network services of the
+// server, and the lists of those services.
+func newTestOpenStackServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v3/auth/tokens" {
+			request := struct {
+				Auth struct {
+					Identity struct {
+						Password struct {
+							User struct {
+								Name     string `json:"name"`
+								Password string `json:"password"`
+							} `json:"user"`
+						} `json:"password"`
+					} `json:"identity"`
+					Scope struct {
+						Project struct {
+							Name string `json:"name"`
+						} `json:"project"`
+					} `json:"scope"`
+				} `json:"auth"`
+			}{}
+			json.NewDecoder(r.Body).Decode(&request)
+			if request.Auth.Identity.Password.User.Name != "admin" || request.Auth.Scope.Project.Name != "k8s" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error": {"code": 401, "message": "The request you have made requires authentication.", "title": "Unauthorized"}}`)
+				return
+			}
+
+			w.Header().Set("X-Subject-Token", "token")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": {"catalog": [
+				{"type": "compute", "endpoints": [
+					{"interface": "internal", "region": "RegionOne", "url": "http://internal/compute/v2.1"},
+					{"interface": "public", "region": "RegionTwo", "url": "http://two/compute/v2.1"},
+					{"interface": "public", "region": "RegionOne", "url": "%[1]s/compute/v2.1/"}
+				]},
+				{"type": "image", "endpoints": [{"interface": "public", "region_id": "RegionOne", "url": "%[1]s/image"}]},
+				{"type": "network", "endpoints": [{"interface": "public", "region": "RegionOne", "url": "%[1]s/network"}]}
+			]}}`, server.URL)
+			return
+		}
+
+		if r.Header.Get("X-Auth-Token") != "token" {
+			t.Errorf("Wrong X-Auth-Token header, received %q", r.Header.Get("X-Auth-Token"))
+		}
+		switch r.URL.Path {
+		case "/compute/v2.1/os-keypairs":
+			fmt.Fprint(w, `{"keypairs": [{"keypair": {"name": "deploy", "fingerprint": "aa:bb"}}]}`)
+		case "/image/v2/images":
+			if r.URL.Query().Get("marker") == "1" {
+				fmt.Fprint(w, `{"images": [{"id": "2", "name": "centos-7", "status": "active"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"images": [{"id": "1", "name": "ubuntu-16.04", "status": "active"}], "next": "/v2/images?marker=1"}`)
+		case "/network/v2.0/networks":
+			fmt.Fprint(w, `{"networks": [{"id": "1", "name": "private"}, {"id": "2", "name": "public", "router:external": true}]}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testOpenStackAuth(server *httptest.Server) openStackAuth {
+	return openStackAuth{
+		AuthURL:    server.URL + "/v3/",
+		UserName:   "admin",
+		Password:   "secret",
+		TenantName: "k8s",
+		DomainName: "Default",
+		Region:     "RegionOne",
+	}
+}
+
+func TestNewOpenStackSession(t *testing.T) {
+	server := newTestOpenStackServer(t)
+
+	auth := testOpenStackAuth(server)
+	session, err := newOpenStackSession(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Compute.ResourceBaseURL() != server.URL+"/compute/v2.1/" || session.Image.ResourceBaseURL() != server.URL+"/image/v2/" || session.Network.ResourceBaseURL() != server.URL+"/network/v2.0/" {
+		t.Errorf("Wrong endpoints, received %s %s %s", session.Compute.ResourceBaseURL(), session.Image.ResourceBaseURL(), session.Network.ResourceBaseURL())
+	}
+
+	auth.Region = "RegionThree"
+	if _, err := newOpenStackSession(auth); err == nil {
+		t.Error("Expected an error for a region without endpoints")
+	}
+}
+
+func TestNewOpenStackSessionUnauthorized(t *testing.T) {
+	server := newTestOpenStackServer(t)
+
+	auth := testOpenStackAuth(server)
+	auth.UserName = "guest"
+	_, err := newOpenStackSession(auth)
+	if err == nil || !strings.Contains(err.Error(), "OpenStack authentication with") {
+		t.Errorf("Expected an authentication error, received %v", err)
+	}
+}
+
+func TestListOpenStack(t *testing.T) {
+	server := newTestOpenStackServer(t)
+	session, err := newOpenStackSession(testOpenStackAuth(server))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageList, err := listOpenStackImages(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imageList) != 2 || imageList[0].Name != "ubuntu-16.04" || imageList[1].Name != "centos-7" {
+		t.Errorf("Wrong images, expected ubuntu-16.04 and centos-7, received %v", imageList)
+	}
+
+	keyPairs, err := listOpenStackKeyPairs(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyPairs) != 1 || keyPairs[0].Name != "deploy" {
+		t.Errorf("Wrong key pairs, expected [deploy], received %v", keyPairs)
+	}
+
+	networkList, err := listOpenStackNetworks(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networkList) != 2 || networkList[0].External || !networkList[1].External {
+		t.Errorf("Wrong networks, expected private and external public, received %v", networkList)
+	}
+}
+
+func TestOpenStackFlavorOptions(t *testing.T) {
+	flavorList := []flavors.Flavor{
+		{Name: "m1.large", RAM: 8192, VCPUs: 4},
+		{Name: "m1.medium", RAM: 4096, VCPUs: 2},
+		{Name: "c1.medium", RAM: 4096, VCPUs: 4},
+	}
+
+	options := openStackFlavorOptions(flavorList)
+	if len(options) != 3 || options[0].Value != "m1.medium" || options[1].Value != "c1.medium" || options[2].Value != "m1.large" {
+		t.Errorf("Wrong output, expected [m1.medium c1.medium m1.large], received %v", options)
+	}
+}
+
+func TestOpenStackImageOptions(t *testing.T) {
+	imageList := []images.Image{
+		{ID: "1", Name: "ubuntu-16.04", Status: images.ImageStatusActive},
+		{ID: "2", Name: "centos-7", Status: images.ImageStatusActive},
+		{ID: "3", Name: "", Status: images.ImageStatusActive},
+		{ID: "4", Name: "ubuntu-18.04", Status: images.ImageStatusQueued},
+	}
+
+	options := openStackImageOptions(imageList)
+	if len(options) != 2 || options[0].Value != "centos-7" || options[1].Value != "ubuntu-16.04" {
+		t.Errorf("Wrong output, expected [centos-7 ubuntu-16.04], received %v", options)
+	}
+}
+
+func TestOpenStackNetworkOptions(t *testing.T) {
+	networkList := []openStackNetwork{
+		{Network: networks.Network{ID: "1", Name: "private"}},
+		{Network: networks.Network{ID: "2", Name: "public"}},
+	}
+	networkList[1].External = true
+
+	options := openStackNetworkOptions(networkList, false)
+	if len(options) != 1 || options[0].Value != "private" {
+		t.Errorf("Wrong output, expected [private], received %v", options)
+	}
+
+	options = openStackNetworkOptions(networkList, true)
+	if len(options) != 1 || options[0].Value != "public" {
+		t.Errorf("Wrong output, expected [public], received %v", options)
+	}
+}
@@ -0,0 +1,140 @@
+package create
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Terraform 0.11 state, only what's needed to find out which modules converged and what
+// changed between two states.
+type terraformState struct {
+	Modules []struct {
+		Path    []string `json:"path"`
+		Outputs map[string]struct {
+			Sensitive bool        `json:"sensitive"`
+			Value     interface{} `json:"value"`
+		} `json:"outputs"`
+		Resources map[string]struct {
+			Primary struct {
+				ID         string            `json:"id"`
+				Attributes map[string]string `json:"attributes"`
+				Tainted    bool              `json:"tainted"`
+			} `json:"primary"`
+		} `json:"resources"`
+	} `json:"modules"`
+}
+
+// Returns the top level modules that have resources in the terraform state, none of which are tainted.
+func convergedModules(rawState []byte) (map[string]bool, error) {
+	tfState := terraformState{}
+	err := json.Unmarshal(rawState, &tfState)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]bool{}
+	tainted := map[string]bool{}
+	for _, module := range tfState.Modules {
+		// Paths are ["root", "{module}", "{nested module}"...]
+		if len(module.Path) < 2 || len(module.Resources) == 0 {
+			continue
+		}
+
+		name := module.Path[1]
+		result[name] = true
+		for _, resource := range module.Resources {
+			if resource.Primary.Tainted {
+				tainted[name] = true
+			}
+		}
+	}
+
+	for name := range tainted {
+		delete(result, name)
+	}
+
+	return result, nil
+}
+
+// Called when terraform apply fails after new nodes were added to the state. Terraform keeps
+// whatever it did create, so rather than dropping the state the nodes that converged are kept,
+// the others are marked failed and the state is persisted, so they can be retried with
+// `triton-kubernetes retry failed`.
+func recordNodeApplyFailure(conf config.Config, remoteBackend backend.Backend, currentState state.State, clusterKey string, newHostnames []string, applyErr error) error {
+	// Nothing was applied when the plan was only previewed, declined or interrupted
+	if len(newHostnames) == 0 || errors.Is(applyErr, shell.ErrPlanNotApplied) || util.IsInterrupt(applyErr) {
+		return applyErr
+	}
+
+	rawState, err := shell.RunTerraformStatePullWithState(conf, currentState)
+	if err != nil {
+		// Can't tell what converged, leave the stored state as it was
+		return applyErr
+	}
+
+	converged, err := convergedModules(rawState)
+	if err != nil {
+		return applyErr
+	}
+
+	// Nodes can't be retried without their cluster
+	if !converged[clusterKey] {
+		return applyErr
+	}
+
+	nodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return err
+	}
+
+	failedHostnames := []string{}
+	for _, hostname := range newHostnames {
+		nodeKey, ok := nodes[hostname]
+		if !ok {
+			continue
+		}
+
+		failed := !converged[nodeKey]
+		err = currentState.SetNodeFailed(nodeKey, failed)
+		if err != nil {
+			return err
+		}
+		if failed {
+			failedHostnames = append(failedHostnames, hostname)
+		}
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(failedHostnames)
+	return util.PartialSuccessError(fmt.Errorf("%v\n%d of %d new nodes failed (%s) and were marked as failed, the other nodes were created. Run `triton-kubernetes retry failed` to retry the failed nodes.", applyErr, len(failedHostnames), len(newHostnames), strings.Join(failedHostnames, ", ")))
+}
+
+// Clears the failed mark of every node in the cluster, after a successful apply converged them.
+func clearFailedNodes(currentState state.State, clusterKey string) error {
+	failedNodes, err := currentState.FailedNodes(clusterKey)
+	if err != nil {
+		return err
+	}
+
+	for _, nodeKey := range failedNodes {
+		err = currentState.SetNodeFailed(nodeKey, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package create
+
+import "testing"
+
+func TestConvergedModules(t *testing.T) {
+	rawState := []byte(`{
+		"version": 3,
+		"modules": [
+			{"path": ["root"], "resources": {}},
+			{"path": ["root", "cluster_aws_dev"], "resources": {"aws_vpc.default": {"primary": {"id": "vpc-1"}}}},
+			{"path": ["root", "node_aws_dev_dev-w-1"], "resources": {"aws_instance.host": {"primary": {"id": "i-1"}}}},
+			{"path": ["root", "node_aws_dev_dev-w-2"], "resources": {"aws_instance.host": {"primary": {"id": "i-2", "tainted": true}}}},
+			{"path": ["root", "node_aws_dev_dev-w-3"], "resources": {}}
+		]
+	}`)
+
+	converged, err := convergedModules(rawState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{
+		"cluster_aws_dev":      true,
+		"node_aws_dev_dev-w-1": true,
+		"node_aws_dev_dev-w-2": false,
+		"node_aws_dev_dev-w-3": false,
+	}
+	for module, want := range expected {
+		if converged[module] != want {
+			t.Errorf("Wrong output for %s, expected %t, received %t", module, want, converged[module])
+		}
+	}
+}
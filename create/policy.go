@@ -0,0 +1,61 @@
+package create
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/fips"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Evaluates the generated terraform config against the Rego policies in policy_path before
+// apply, so platform teams can block configurations they don't allow (public IPs, unapproved
+// instance types...). Nothing is checked if policy_path isn't set. In FIPS mode, the config
+// must also only deploy to FedRAMP authorized clouds.
+func checkPolicies(conf config.Config, currentState state.State) error {
+	if fips.Enabled() {
+		err := fips.ValidateState(currentState)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !conf.IsSet("policy_path") {
+		return nil
+	}
+
+	policyPath, err := homedir.Expand(conf.GetString("policy_path"))
+	if err != nil {
+		return err
+	}
+	// conftest runs in the terraform working directory, relative paths are relative to ours
+	policyPath, err = filepath.Abs(policyPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(policyPath)
+	if err != nil {
+		return util.ConfigError(fmt.Errorf("Invalid policy_path '%s': %v", policyPath, err))
+	}
+
+	_, err = exec.LookPath("conftest")
+	if err != nil {
+		return fmt.Errorf("conftest must be installed to check the policies in '%s'", policyPath)
+	}
+
+	fmt.Printf("Checking terraform configuration against the policies in %s...\n", policyPath)
+	err = shell.RunConftestWithState(conf, currentState, policyPath)
+	if err != nil {
+		return fmt.Errorf("Terraform configuration violates the policies in '%s', nothing was applied", policyPath)
+	}
+
+	return nil
+}
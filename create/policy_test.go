@@ -0,0 +1,85 @@
+package create
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestCheckPoliciesWithoutPolicyPath(t *testing.T) {
+	conf := config.New()
+	currentState, err := state.New("test", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = checkPolicies(conf, currentState)
+	if err != nil {
+		t.Errorf("Expected no policy check, received %v", err)
+	}
+}
+
+func TestCheckPoliciesWithInvalidPolicyPath(t *testing.T) {
+	conf := config.New()
+	conf.Set("policy_path", "/does/not/exist")
+
+	currentState, err := state.New("test", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = checkPolicies(conf, currentState)
+	if err == nil || !strings.HasPrefix(err.Error(), "Invalid policy_path '/does/not/exist'") {
+		t.Errorf("Expected invalid policy_path error, received %v", err)
+	}
+}
+
+func TestCheckPoliciesWithRelativePolicyPath(t *testing.T) {
+	if _, err := exec.LookPath("conftest"); err == nil {
+		t.Skip("conftest is installed")
+	}
+
+	dir, err := ioutil.TempDir("", "triton-kubernetes-policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.Mkdir(filepath.Join(dir, "policies"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The temporary directory may be behind a symlink, e.g. on macOS
+	dir, err = os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.Set("policy_path", "policies")
+	currentState, err := state.New("test", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = checkPolicies(conf, currentState)
+	expected := "conftest must be installed to check the policies in '" + filepath.Join(dir, "policies") + "'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, received %v", expected, err)
+	}
+}
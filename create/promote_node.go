@@ -0,0 +1,326 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Roles a worker node can be promoted to
+var nodePromotionRoles = []string{"control", "etcd"}
+
+// PromoteNode changes the role of a worker node to control or etcd, e.g. to grow the control
+// plane from 1 to 3 nodes. RKE only configures the components of a node when it registers, so
+// the worker is replaced: a node with the new role is created and has to become active before
+// the worker is drained and destroyed.
+func PromoteNode(conf config.Config, remoteBackend backend.Backend, hostname string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterKey := ""
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
+		clusterKey, ok := clusters[clusterName]
+		if !ok {
+			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+
+		selectedClusterKey = clusterKey
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to promote a node of",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedClusterKey = clusters[value]
+	}
+
+	// Worker nodes backed by an instance group can't be promoted, their instances are
+	// replaced by the cloud provider
+	workerNodes, err := currentState.NodesWithRole(selectedClusterKey, "worker")
+	if err != nil {
+		return err
+	}
+	for name, nodeKey := range workerNodes {
+		if _, ok := getNodePoolProvider(currentState, nodeKey); ok {
+			delete(workerNodes, name)
+		}
+	}
+
+	selectedHostname := hostname
+	if selectedHostname != "" {
+		// Hostname was given as an argument
+	} else if conf.IsSet("hostname") {
+		selectedHostname = conf.GetString("hostname")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("hostname must be specified"))
+	} else {
+		if len(workerNodes) == 0 {
+			return fmt.Errorf("No worker nodes.")
+		}
+
+		nodeNames := make([]string, 0, len(workerNodes))
+		for name := range workerNodes {
+			nodeNames = append(nodeNames, name)
+		}
+		sort.Strings(nodeNames)
+		prompt := promptui.Select{
+			Label: "Worker node to promote",
+			Items: nodeNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Node:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedHostname = value
+	}
+
+	selectedNodeKey, ok := workerNodes[selectedHostname]
+	if !ok {
+		return fmt.Errorf("A worker node named '%s', does not exist.", selectedHostname)
+	}
+
+	selectedRole := ""
+	if conf.IsSet("node_role") {
+		selectedRole = conf.GetString("node_role")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("node_role must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Role to promote the node to",
+			Items: nodePromotionRoles,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Role:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedRole = value
+	}
+
+	if selectedRole != "control" && selectedRole != "etcd" {
+		return util.ConfigError(fmt.Errorf("Invalid node_role '%s', must be 'control' or 'etcd'.", selectedRole))
+	}
+
+	if selectedRole == "etcd" {
+		etcdNodes, err := currentState.NodesWithRole(selectedClusterKey, "etcd")
+		if err != nil {
+			return err
+		}
+		if (len(etcdNodes)+1)%2 == 0 {
+			fmt.Printf("Warning: the cluster will have %d etcd nodes, an odd number of etcd nodes tolerates as many failures with one node less.\n", len(etcdNodes)+1)
+		}
+	}
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Promote worker node %s to a %s node", selectedHostname, selectedRole)
+		selected := "Promote"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Promote node canceled.")
+			return nil
+		}
+	}
+
+	settings, prefix, err := getPromotedNodeSettings(currentState, selectedClusterKey, selectedHostname, selectedRole)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the new node will be able to register with the cluster manager
+	err = checkRancherConnectivity(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	newHostname, err := replaceNode(conf, remoteBackend, currentState, client, rancherClusterID, selectedClusterKey, selectedHostname, selectedNodeKey, prefix, settings, getNodeRegistrationTimeout(conf))
+	if err != nil {
+		return err
+	}
+
+	// The worker's pool lost a node, the pool of the new node gained one
+	err = recordNodePools(currentState, selectedClusterKey, []string{selectedHostname, newHostname})
+	if err != nil {
+		return err
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Worker node %s was replaced by %s node %s.\n", selectedHostname, selectedRole, newHostname)
+	return nil
+}
+
+// Node module settings only control nodes have: the cluster's audit policy, secrets encryption
+// config and KMS plugin for the API server, and the SSH login the encryption config is copied
+// over.
+var controlNodeSettingKeys = []string{
+	"k8s_audit_policy",
+	"k8s_secrets_encryption_config",
+	"k8s_kms_plugin_image",
+	"k8s_kms_plugin_args",
+	"aws_ssh_user",
+	"aws_private_key_path",
+	"azure_private_key_path",
+	"digitalocean_private_key_path",
+	"equinix_metal_private_key_path",
+	"gcp_ssh_user",
+	"gcp_public_key",
+	"gcp_private_key_path",
+	"openstack_ssh_user",
+	"openstack_private_key_path",
+}
+
+// Returns the settings of the node replacing the worker node with one of the given role, and the
+// hostname prefix of the new node. It joins the pool of the cluster's existing nodes with the
+// role, or a pool named after the worker's pool and the role. A new control node copies the
+// control node settings of an existing control node.
+func getPromotedNodeSettings(currentState state.State, clusterKey, hostname, role string) (map[string]interface{}, string, error) {
+	settings := map[string]interface{}{
+		"rancher_host_labels": map[string]interface{}{role: "true"},
+	}
+
+	roleNodes, err := currentState.NodesWithRole(clusterKey, role)
+	if err != nil {
+		return nil, "", err
+	}
+	roleNames := []string{}
+	for name, nodeKey := range roleNodes {
+		if _, ok := getNodePoolProvider(currentState, nodeKey); !ok {
+			roleNames = append(roleNames, name)
+		}
+	}
+	sort.Strings(roleNames)
+
+	prefix := fmt.Sprintf("%s-%s", util.NodeHostnameSuffixRegexp.ReplaceAllString(hostname, ""), role)
+	if len(roleNames) > 0 {
+		prefix = util.NodeHostnameSuffixRegexp.ReplaceAllString(roleNames[0], "")
+	}
+
+	if role != "control" {
+		return settings, prefix, nil
+	}
+
+	if len(roleNames) == 0 {
+		if currentState.Get(fmt.Sprintf("module.%s.k8s_audit_log", clusterKey)) == "true" || currentState.Get(fmt.Sprintf("module.%s.k8s_secrets_encryption", clusterKey)) != "" {
+			return nil, "", errors.New("The cluster has no control node to copy the audit policy and secrets encryption settings of, create a control node with `triton-kubernetes create node`.")
+		}
+		return settings, prefix, nil
+	}
+
+	for _, key := range controlNodeSettingKeys {
+		if value := currentState.Get(fmt.Sprintf("module.%s.%s", roleNodes[roleNames[0]], key)); value != "" {
+			settings[key] = value
+		}
+	}
+
+	return settings, prefix, nil
+}
@@ -0,0 +1,56 @@
+package create
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestGetPromotedNodeSettings(t *testing.T) {
+	currentState, err := state.New("PromoteState", []byte(`{"module":{
+		"cluster_aws_dev":{"name":"dev","k8s_secrets_encryption":"aescbc"},
+		"node_aws_dev_dev-c-1":{"hostname":"dev-c-1","rancher_host_labels":{"control":"true"},"k8s_secrets_encryption_config":"${var.k8s_secrets_encryption_config_cluster_aws_dev}","aws_ssh_user":"ubuntu","aws_private_key_path":"/home/dev/.ssh/id_rsa"},
+		"node_aws_dev_dev-w-1":{"hostname":"dev-w-1","rancher_host_labels":{"worker":"true"}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings, prefix, err := getPromotedNodeSettings(currentState, "cluster_aws_dev", "dev-w-1", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"rancher_host_labels":           map[string]interface{}{"control": "true"},
+		"k8s_secrets_encryption_config": "${var.k8s_secrets_encryption_config_cluster_aws_dev}",
+		"aws_ssh_user":                  "ubuntu",
+		"aws_private_key_path":          "/home/dev/.ssh/id_rsa",
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("Expected settings %v, received %v", expected, settings)
+	}
+	if prefix != "dev-c" {
+		t.Errorf("Expected prefix dev-c, received %s", prefix)
+	}
+
+	settings, prefix, err = getPromotedNodeSettings(currentState, "cluster_aws_dev", "dev-w-1", "etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]interface{}{
+		"rancher_host_labels": map[string]interface{}{"etcd": "true"},
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("Expected settings %v, received %v", expected, settings)
+	}
+	if prefix != "dev-w-etcd" {
+		t.Errorf("Expected prefix dev-w-etcd, received %s", prefix)
+	}
+
+	currentState.Delete("module.node_aws_dev_dev-c-1")
+	_, _, err = getPromotedNodeSettings(currentState, "cluster_aws_dev", "dev-w-1", "control")
+	if err == nil {
+		t.Error("Expected an error without a control node to copy the secrets encryption settings of")
+	}
+}
@@ -0,0 +1,322 @@
+package create
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	defaultProxmoxSSHUser = "ubuntu"
+)
+
+// The API token of a Proxmox VE cluster, shared by the manager, the cluster and its nodes.
+// Proxmox hosts often have self-signed certificates, TLSInsecure skips their verification.
+type proxmoxAuth struct {
+	APIURL         string `json:"proxmox_api_url"`
+	APITokenID     string `json:"proxmox_api_token_id"`
+	APITokenSecret string `json:"proxmox_api_token_secret"`
+	TLSInsecure    bool   `json:"proxmox_tls_insecure,omitempty"`
+}
+
+// Where a VM is cloned: the Proxmox node it runs on, the template it's cloned from, the
+// storage of its disk and the bridge of its network interface.
+type proxmoxVMConfig struct {
+	ProxmoxNode         string `json:"proxmox_node"`
+	ProxmoxTemplateName string `json:"proxmox_template_name"`
+	ProxmoxStorage      string `json:"proxmox_storage"`
+	ProxmoxBridge       string `json:"proxmox_bridge"`
+}
+
+type proxmoxNode struct {
+	Node   string `json:"node"`
+	Status string `json:"status"`
+	MaxCPU int    `json:"maxcpu"`
+	MaxMem int64  `json:"maxmem"`
+}
+
+type proxmoxVM struct {
+	VMID     int    `json:"vmid"`
+	Name     string `json:"name"`
+	Node     string `json:"node"`
+	Template int    `json:"template"`
+}
+
+type proxmoxStorage struct {
+	Storage string `json:"storage"`
+	Type    string `json:"type"`
+	Avail   int64  `json:"avail"`
+	Active  int    `json:"active"`
+}
+
+type proxmoxBridge struct {
+	Iface  string `json:"iface"`
+	Type   string `json:"type"`
+	Active int    `json:"active"`
+}
+
+// Sends a GET request to the Proxmox API, authenticated with the API token, and decodes the
+// data of the response into v.
+func getProxmox(auth proxmoxAuth, path string, v interface{}) error {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(auth.APIURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", auth.APITokenID, auth.APITokenSecret))
+
+	client := http.DefaultClient
+	if auth.TLSInsecure {
+		client = &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	// Proxmox gives the reason of an error in the status line
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Proxmox API request %s failed: %s", path, resp.Status)
+	}
+
+	data := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data.Data, v)
+}
+
+func listProxmoxNodes(auth proxmoxAuth) ([]proxmoxNode, error) {
+	nodes := []proxmoxNode{}
+	err := getProxmox(auth, "/nodes", &nodes)
+	return nodes, err
+}
+
+// Returns the VMs and templates of every node of the Proxmox cluster.
+func listProxmoxVMs(auth proxmoxAuth) ([]proxmoxVM, error) {
+	vms := []proxmoxVM{}
+	err := getProxmox(auth, "/cluster/resources?type=vm", &vms)
+	return vms, err
+}
+
+// Returns the storages of the node VM disks can be created in.
+func listProxmoxStorages(auth proxmoxAuth, node string) ([]proxmoxStorage, error) {
+	storages := []proxmoxStorage{}
+	err := getProxmox(auth, fmt.Sprintf("/nodes/%s/storage?content=images&enabled=1", url.PathEscape(node)), &storages)
+	return storages, err
+}
+
+// Returns the Linux and Open vSwitch bridges of the node.
+func listProxmoxBridges(auth proxmoxAuth, node string) ([]proxmoxBridge, error) {
+	bridges := []proxmoxBridge{}
+	err := getProxmox(auth, fmt.Sprintf("/nodes/%s/network?type=any_bridge", url.PathEscape(node)), &bridges)
+	return bridges, err
+}
+
+// Returns the online nodes by name.
+func proxmoxNodeOptions(nodes []proxmoxNode) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, node := range nodes {
+		if node.Status != "online" {
+			continue
+		}
+		label := fmt.Sprintf("%s (%d CPUs, %d GB memory)", node.Node, node.MaxCPU, node.MaxMem/(1024*1024*1024))
+		options = append(options, util.PromptOption{Value: node.Node, Label: label})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Value < options[j].Value
+	})
+	return options
+}
+
+// Returns the templates by name. VMs are cloned from a template by name, so templates without
+// one are left out. Templates on other nodes are cloned to the VM's node.
+func proxmoxTemplateOptions(vms []proxmoxVM) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, vm := range vms {
+		if vm.Template != 1 || vm.Name == "" {
+			continue
+		}
+		options = append(options, util.PromptOption{Value: vm.Name, Label: fmt.Sprintf("%s (%d on %s)", vm.Name, vm.VMID, vm.Node)})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Value < options[j].Value
+	})
+	return options
+}
+
+// Returns the active storages, the one with the most space available first.
+func proxmoxStorageOptions(storages []proxmoxStorage) []util.PromptOption {
+	sorted := []proxmoxStorage{}
+	for _, storage := range storages {
+		if storage.Active == 1 {
+			sorted = append(sorted, storage)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Avail > sorted[j].Avail
+	})
+
+	options := []util.PromptOption{}
+	for _, storage := range sorted {
+		label := fmt.Sprintf("%s (%s, %d GB available)", storage.Storage, storage.Type, storage.Avail/(1024*1024*1024))
+		options = append(options, util.PromptOption{Value: storage.Storage, Label: label})
+	}
+	return options
+}
+
+func proxmoxBridgeOptions(bridges []proxmoxBridge) []util.PromptOption {
+	options := []util.PromptOption{}
+	for _, bridge := range bridges {
+		options = append(options, util.PromptOption{Value: bridge.Iface, Label: fmt.Sprintf("%s (%s)", bridge.Iface, bridge.Type)})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Value < options[j].Value
+	})
+	return options
+}
+
+func getProxmoxAuth(conf config.Config) (proxmoxAuth, error) {
+	auth := proxmoxAuth{}
+
+	var err error
+	auth.APIURL, err = util.PromptForValue(conf, "proxmox_api_url", "Proxmox API URL", "", false)
+	if err != nil {
+		return proxmoxAuth{}, err
+	}
+	apiURL, err := url.Parse(auth.APIURL)
+	if err != nil || apiURL.Scheme == "" || apiURL.Host == "" {
+		return proxmoxAuth{}, util.ConfigError(fmt.Errorf("Invalid proxmox_api_url '%s', expected e.g. 'https://pve.example.com:8006/api2/json'", auth.APIURL))
+	}
+	// The API is served under /api2/json
+	if !strings.HasSuffix(strings.TrimSuffix(apiURL.Path, "/"), "/api2/json") {
+		apiURL.Path = strings.TrimSuffix(apiURL.Path, "/") + "/api2/json"
+	}
+	auth.APIURL = apiURL.String()
+
+	auth.APITokenID, err = util.PromptForValue(conf, "proxmox_api_token_id", "Proxmox API Token ID (user@realm!token)", "", false)
+	if err != nil {
+		return proxmoxAuth{}, err
+	}
+	if !strings.Contains(auth.APITokenID, "!") {
+		return proxmoxAuth{}, util.ConfigError(fmt.Errorf("Invalid proxmox_api_token_id '%s', expected {user}@{realm}!{token name}, e.g. 'root@pam!triton-kubernetes'", auth.APITokenID))
+	}
+
+	auth.APITokenSecret, err = util.PromptForValue(conf, "proxmox_api_token_secret", "Proxmox API Token Secret", "", true)
+	if err != nil {
+		return proxmoxAuth{}, err
+	}
+
+	auth.TLSInsecure = conf.GetBool("proxmox_tls_insecure")
+
+	return auth, nil
+}
+
+// Returns the node, template, storage and bridge of a VM. The storages and bridges offered are
+// the ones of the selected node.
+func getProxmoxVMConfig(conf config.Config, auth proxmoxAuth) (proxmoxVMConfig, error) {
+	cfg := proxmoxVMConfig{}
+
+	nodes, err := listProxmoxNodes(auth)
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+	cfg.ProxmoxNode, err = util.PromptForOption(conf, "proxmox_node", "Proxmox Node", proxmoxNodeOptions(nodes))
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+
+	vms, err := listProxmoxVMs(auth)
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+	cfg.ProxmoxTemplateName, err = util.PromptForOption(conf, "proxmox_template_name", "Proxmox Template", proxmoxTemplateOptions(vms))
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+
+	storages, err := listProxmoxStorages(auth, cfg.ProxmoxNode)
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+	cfg.ProxmoxStorage, err = util.PromptForOption(conf, "proxmox_storage", "Proxmox Storage", proxmoxStorageOptions(storages))
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+
+	bridges, err := listProxmoxBridges(auth, cfg.ProxmoxNode)
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+	cfg.ProxmoxBridge, err = util.PromptForOption(conf, "proxmox_bridge", "Proxmox Network Bridge", proxmoxBridgeOptions(bridges))
+	if err != nil {
+		return proxmoxVMConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// Returns the user cloud-init creates on the VMs and the path of its private key. The public
+// key must be next to the private key, with a .pub extension.
+func getProxmoxSSHConfig(conf config.Config) (string, string, error) {
+	sshUser, err := util.PromptForValue(conf, "proxmox_ssh_user", "SSH User", defaultProxmoxSSHUser, false)
+	if err != nil {
+		return "", "", err
+	}
+
+	rawKeyPath, err := util.PromptForValue(conf, "proxmox_key_path", "Private Key Path", "~/.ssh/id_rsa", false)
+	if err != nil {
+		return "", "", err
+	}
+
+	keyPath, err := homedir.Expand(rawKeyPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = os.Stat(keyPath + ".pub")
+	if err != nil {
+		return "", "", fmt.Errorf("Public key of proxmox_key_path '%s' not found, expected it at '%s.pub'", rawKeyPath, rawKeyPath)
+	}
+
+	return sshUser, keyPath, nil
+}
+
+// Returns the given VM size setting, which must be a number greater than 0.
+func getProxmoxVMSize(conf config.Config, key, label, defaultValue string) (string, error) {
+	value, err := util.PromptForValue(conf, key, label, defaultValue, false)
+	if err != nil {
+		return "", err
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil || num <= 0 {
+		return "", errors.New(key + " must be a number greater than 0")
+	}
+
+	return value, nil
+}
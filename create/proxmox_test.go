@@ -0,0 +1,107 @@
+package create
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestGetProxmoxVMConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "PVEAPIToken=root@pam!tk=secret" {
+			t.Errorf("Wrong Authorization header, received %q", r.Header.Get("Authorization"))
+		}
+
+		switch r.URL.Path {
+		case "/api2/json/nodes":
+			fmt.Fprint(w, `{"data": [{"node": "pve1", "status": "online", "maxcpu": 8, "maxmem": 34359738368}]}`)
+		case "/api2/json/cluster/resources":
+			fmt.Fprint(w, `{"data": [{"vmid": 9000, "name": "ubuntu-cloud", "node": "pve2", "template": 1}, {"vmid": 100, "name": "db", "node": "pve1", "template": 0}]}`)
+		case "/api2/json/nodes/pve1/storage":
+			if r.URL.Query().Get("content") != "images" {
+				t.Errorf("Expected the storages of VM disks, received %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"data": [{"storage": "local-lvm", "type": "lvmthin", "avail": 107374182400, "active": 1}]}`)
+		case "/api2/json/nodes/pve1/network":
+			fmt.Fprint(w, `{"data": [{"iface": "vmbr0", "type": "bridge", "active": 1}]}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("proxmox_api_url", server.URL)
+	conf.Set("proxmox_api_token_id", "root@pam!tk")
+	conf.Set("proxmox_api_token_secret", "secret")
+	conf.Set("proxmox_node", "pve1")
+	conf.Set("proxmox_template_name", "ubuntu-cloud")
+	conf.Set("proxmox_storage", "local-lvm")
+	conf.Set("proxmox_bridge", "vmbr0")
+
+	auth, err := getProxmoxAuth(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.APIURL != server.URL+"/api2/json" {
+		t.Errorf("Expected the API path to be added to proxmox_api_url, received %q", auth.APIURL)
+	}
+
+	cfg, err := getProxmoxVMConfig(conf, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := proxmoxVMConfig{ProxmoxNode: "pve1", ProxmoxTemplateName: "ubuntu-cloud", ProxmoxStorage: "local-lvm", ProxmoxBridge: "vmbr0"}
+	if cfg != expected {
+		t.Errorf("Wrong output, expected %+v, received %+v", expected, cfg)
+	}
+
+	conf.Set("proxmox_template_name", "db")
+	_, err = getProxmoxVMConfig(conf, auth)
+	if err == nil || !strings.Contains(err.Error(), "'db' does not exist") {
+		t.Errorf("Expected a VM that isn't a template to be rejected, received %v", err)
+	}
+}
+
+func TestProxmoxCredentialsValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := proxmoxCredentials{proxmoxAuth{APIURL: server.URL + "/api2/json", APITokenID: "root@pam!tk"}}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "proxmox_api_token_secret") || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an error naming the token settings, received %v", err)
+	}
+}
+
+func TestGetProxmoxAuthRequiresTokenName(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("proxmox_api_url", "https://pve.example.com:8006")
+	conf.Set("proxmox_api_token_id", "root@pam")
+	conf.Set("proxmox_api_token_secret", "secret")
+
+	_, err := getProxmoxAuth(conf)
+	if err == nil || !strings.Contains(err.Error(), "proxmox_api_token_id") {
+		t.Errorf("Expected an error for a token id without a token name, received %v", err)
+	}
+}
+
+func TestProxmoxStorageOptions(t *testing.T) {
+	storages := []proxmoxStorage{
+		{Storage: "local", Type: "dir", Avail: 10 * 1024 * 1024 * 1024, Active: 1},
+		{Storage: "nfs", Type: "nfs", Avail: 500 * 1024 * 1024 * 1024, Active: 0},
+		{Storage: "local-lvm", Type: "lvmthin", Avail: 100 * 1024 * 1024 * 1024, Active: 1},
+	}
+
+	options := proxmoxStorageOptions(storages)
+	if len(options) != 2 || options[0].Value != "local-lvm" || options[1].Value != "local" {
+		t.Errorf("Wrong output, expected [local-lvm local], received %v", options)
+	}
+}
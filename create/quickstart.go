@@ -0,0 +1,752 @@
+package create
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	triton "github.com/joyent/triton-go"
+	"github.com/joyent/triton-go/authentication"
+	tritoncompute "github.com/joyent/triton-go/compute"
+	"github.com/joyent/triton-go/network"
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+)
+
+const (
+	// Memory in MB of the smallest machines a quickstart creates. Rancher server needs 4 GB, a
+	// node running a single Kubernetes role gets by with 2 GB.
+	quickstartManagerMemory = 4096
+	quickstartNodeMemory    = 2048
+
+	quickstartKubernetesVersion         = "v1.10.0-rancher1-1"
+	quickstartKubernetesNetworkProvider = "calico"
+
+	// Ubuntu 16.04 LTS, which the install scripts of the terraform modules target
+	quickstartTritonImageName    = "ubuntu-certified-16.04"
+	quickstartAWSAMINameFilter   = "ubuntu/images/hvm-ssd/ubuntu-xenial-16.04-amd64-server-*"
+	quickstartAWSAMIOwner        = "099720109477"
+	quickstartGCPImageFamily     = "ubuntu-1604-lts"
+	quickstartDigitalOceanImage  = "ubuntu-16-04-x64"
+	quickstartSSHUser            = "ubuntu"
+	quickstartPrivateKeyPath     = "~/.ssh/id_rsa"
+	quickstartPublicKeyPath      = "~/.ssh/id_rsa.pub"
+	quickstartTritonURL          = "https://us-east-1.api.joyent.com"
+	quickstartAWSRegion          = "us-west-2"
+	quickstartAWSManagerType     = "t2.medium"
+	quickstartAWSNodeType        = "t2.small"
+	quickstartGCPRegion          = "us-central1"
+	quickstartDigitalOceanRegion = "nyc3"
+)
+
+// The nodes of a quickstart cluster, one of each role. Every node runs a single role, so this
+// is the smallest cluster Rancher can run.
+var quickstartNodeRoles = []string{"etcd", "control", "worker"}
+
+// Settings of what a quickstart creates on a cloud provider. Settings that are already set in
+// the config are kept, so any default can be overridden.
+type quickstartProfile struct {
+	// Settings shared by the cluster manager and the cluster, e.g. credentials
+	Shared map[string]interface{}
+
+	Manager map[string]interface{}
+	Cluster map[string]interface{}
+	// Settings of each node
+	Node map[string]interface{}
+}
+
+// NewQuickstart creates a small development environment, a cluster manager and a cluster with
+// one node of each role, only asking for the cloud provider, a name and the credentials. The
+// other settings use opinionated defaults: the smallest machines that can run Rancher and
+// Kubernetes and Ubuntu LTS images.
+func NewQuickstart(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	selectedCloudProvider := ""
+	if conf.IsSet("manager_cloud_provider") {
+		selectedCloudProvider = conf.GetString("manager_cloud_provider")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("manager_cloud_provider must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Create a quickstart cluster in which Cloud Provider",
+			Items: []string{"Triton", "AWS", "GCP", "DigitalOcean"},
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cloud Provider:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedCloudProvider = strings.ToLower(value)
+	}
+
+	// The cluster manager and the cluster share the name
+	name := ""
+	if conf.IsSet("name") {
+		name = conf.GetString("name")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("name must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Name",
+			Validate: func(input string) error {
+				if !clusterNameRegexp.MatchString(input) {
+					return errors.New("A DNS-1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character")
+				}
+				return nil
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		name = result
+	}
+	if !clusterNameRegexp.MatchString(name) {
+		return util.ConfigError(fmt.Errorf("Invalid name '%s', it must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character.", name))
+	}
+
+	var profile quickstartProfile
+	var err error
+	switch selectedCloudProvider {
+	case "triton":
+		profile, err = getTritonQuickstartProfile(conf)
+	case "aws":
+		profile, err = getAWSQuickstartProfile(conf, name)
+	case "gcp":
+		profile, err = getGCPQuickstartProfile(conf)
+	case "digitalocean":
+		profile, err = getDigitalOceanQuickstartProfile(conf)
+	default:
+		return fmt.Errorf("Unsupported cloud provider '%s', quickstart supports 'triton', 'aws', 'gcp' and 'digitalocean'.", selectedCloudProvider)
+	}
+	if err != nil {
+		return err
+	}
+
+	generatedPassword := ""
+	if !conf.IsSet("rancher_admin_password") {
+		generatedPassword, err = newQuickstartPassword()
+		if err != nil {
+			return err
+		}
+		profile.Manager["rancher_admin_password"] = generatedPassword
+	}
+
+	setQuickstartSpec(conf, selectedCloudProvider, name, profile)
+
+	fmt.Printf("Creating cluster manager '%s' and cluster '%s' with %d nodes (%s) on %s.\n", name, name, len(quickstartNodeRoles), strings.Join(quickstartNodeRoles, ", "), selectedCloudProvider)
+	err = NewEnvironment(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	if generatedPassword != "" {
+		fmt.Printf("Log in to Rancher as admin with the password %s\n", generatedPassword)
+	}
+
+	return nil
+}
+
+// Sets the environment spec of the quickstart in the config: the shared settings, the cluster
+// manager under `manager` and the cluster and its nodes under `clusters`.
+func setQuickstartSpec(conf config.Config, cloudProvider, name string, profile quickstartProfile) {
+	for key, value := range profile.Shared {
+		if !conf.IsSet(key) {
+			conf.Set(key, value)
+		}
+	}
+
+	manager := withQuickstartOverrides(conf, profile.Manager)
+	manager["name"] = name
+	manager["manager_cloud_provider"] = cloudProvider
+
+	// Node settings are read from the nodes of the spec only, so the overrides are copied there
+	nodes := []interface{}{}
+	for _, role := range quickstartNodeRoles {
+		node := map[interface{}]interface{}{}
+		for key, value := range withQuickstartOverrides(conf, profile.Node) {
+			node[key] = value
+		}
+		node["hostname"] = fmt.Sprintf("%s-%s", name, role)
+		node["rancher_host_label"] = role
+		node["node_count"] = 1
+		nodes = append(nodes, node)
+	}
+
+	cluster := withQuickstartOverrides(conf, profile.Cluster)
+	cluster["name"] = name
+	cluster["cluster_cloud_provider"] = cloudProvider
+	cluster["nodes"] = nodes
+	if !conf.IsSet("k8s_version") {
+		cluster["k8s_version"] = quickstartKubernetesVersion
+	}
+	if !conf.IsSet("k8s_network_provider") {
+		cluster["k8s_network_provider"] = quickstartKubernetesNetworkProvider
+	}
+
+	conf.Set("manager", manager)
+	conf.Set("clusters", []interface{}{cluster})
+}
+
+// Returns the given defaults, with the values of the keys that are set in the config instead.
+func withQuickstartOverrides(conf config.Config, defaults map[string]interface{}) map[string]interface{} {
+	settings := map[string]interface{}{}
+	for key, value := range defaults {
+		if conf.IsSet(key) {
+			value = conf.Get(key)
+		}
+		settings[key] = value
+	}
+	return settings
+}
+
+// Returns the value of a credential, which the user enters when it isn't set.
+func promptForQuickstartValue(conf config.Config, key, label, defaultValue string, secret bool) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: defaultValue,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+	if secret {
+		prompt.Mask = '*'
+	}
+
+	return prompt.Run()
+}
+
+// Returns the expanded path of a key file, which must exist.
+func getQuickstartKeyPath(conf config.Config, key, defaultPath string) (string, error) {
+	path := defaultPath
+	if conf.IsSet(key) {
+		path = conf.GetString(key)
+	}
+
+	expandedPath, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = os.Stat(expandedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("Key '%s' does not exist, create one with ssh-keygen or set %s.", path, key)
+		}
+		return "", err
+	}
+
+	return expandedPath, nil
+}
+
+func newQuickstartPassword() (string, error) {
+	b := make([]byte, 12)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func getTritonQuickstartProfile(conf config.Config) (quickstartProfile, error) {
+	// Triton account, key and URL can come from a profile of the triton CLI
+	err := util.ApplyTritonProfile(conf, conf.GetBool("non-interactive"))
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	account, err := promptForQuickstartValue(conf, "triton_account", "Triton Account Name", "", false)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	rawKeyPath, err := promptForQuickstartValue(conf, "triton_key_path", "Triton Key Path", quickstartPrivateKeyPath, false)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	keyPath, err := homedir.Expand(rawKeyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	keyID, err := util.TritonKeyID("triton_key_id", conf.GetString("triton_key_id"), keyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	tritonURL := quickstartTritonURL
+	if conf.IsSet("triton_url") {
+		tritonURL = conf.GetString("triton_url")
+	}
+
+	keyMaterial, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	sshKeySigner, err := authentication.NewPrivateKeySigner(authentication.PrivateKeySignerInput{
+		KeyID:              keyID,
+		PrivateKeyMaterial: keyMaterial,
+		AccountName:        account,
+	})
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	tritonConfig := &triton.ClientConfig{
+		TritonURL:   tritonURL,
+		AccountName: account,
+		Signers:     []authentication.Signer{sshKeySigner},
+	}
+
+	networkClient, err := network.NewClient(tritonConfig)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	computeClient, err := tritoncompute.NewClient(tritonConfig)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	// Machines are reachable on the public networks
+	networks, err := networkClient.List(context.Background(), nil)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	networkNames := []string{}
+	for _, tritonNetwork := range networks {
+		if tritonNetwork.Public {
+			networkNames = append(networkNames, tritonNetwork.Name)
+		}
+	}
+	if len(networkNames) == 0 {
+		return quickstartProfile{}, errors.New("No public Triton network to attach the machines to, set triton_network_names.")
+	}
+
+	images, err := computeClient.Images().List(context.Background(), &tritoncompute.ListImagesInput{Name: quickstartTritonImageName})
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	if len(images) == 0 {
+		return quickstartProfile{}, fmt.Errorf("Triton image '%s' does not exist, set triton_image_name and triton_image_version.", quickstartTritonImageName)
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].PublishedAt.After(images[j].PublishedAt)
+	})
+	image := images[0]
+
+	packages, err := computeClient.Packages().List(context.Background(), &tritoncompute.ListPackagesInput{})
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	packages = getCompatibleTritonPackages(*image, packages)
+	managerPackage, err := getSmallestTritonPackage(packages, quickstartManagerMemory)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	nodePackage, err := getSmallestTritonPackage(packages, quickstartNodeMemory)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	machine := map[string]interface{}{
+		"triton_network_names": networkNames,
+		"triton_image_name":    image.Name,
+		"triton_image_version": image.Version,
+		"triton_ssh_user":      quickstartSSHUser,
+	}
+
+	profile := quickstartProfile{
+		Shared: map[string]interface{}{
+			"triton_account":  account,
+			"triton_key_path": keyPath,
+			"triton_key_id":   keyID,
+			"triton_url":      tritonURL,
+		},
+		Manager: map[string]interface{}{"master_triton_machine_package": managerPackage},
+		Cluster: map[string]interface{}{},
+		Node:    map[string]interface{}{"triton_machine_package": nodePackage},
+	}
+	for key, value := range machine {
+		profile.Manager[key] = value
+		profile.Node[key] = value
+	}
+
+	return profile, nil
+}
+
+// Returns the name of the package with the least memory, of at least the given amount of MB.
+func getSmallestTritonPackage(packages []*tritoncompute.Package, minMemory int64) (string, error) {
+	var smallest *tritoncompute.Package
+	for _, pkg := range packages {
+		if pkg.Memory >= minMemory && (smallest == nil || pkg.Memory < smallest.Memory) {
+			smallest = pkg
+		}
+	}
+	if smallest == nil {
+		return "", fmt.Errorf("No Triton machine package has %d MB of memory, set triton_machine_package and master_triton_machine_package.", minMemory)
+	}
+	return smallest.Name, nil
+}
+
+func getAWSQuickstartProfile(conf config.Config, name string) (quickstartProfile, error) {
+	accessKey, err := promptForQuickstartValue(conf, "aws_access_key", "AWS Access Key", "", false)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	secretKey, err := promptForQuickstartValue(conf, "aws_secret_key", "AWS Secret Key", "", true)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	region := quickstartAWSRegion
+	if conf.IsSet("aws_region") {
+		region = conf.GetString("aws_region")
+	}
+
+	privateKeyPath, err := getQuickstartKeyPath(conf, "aws_private_key_path", quickstartPrivateKeyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	publicKeyPath, err := getQuickstartKeyPath(conf, "aws_public_key_path", quickstartPublicKeyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	// The newest Ubuntu AMI published by Canonical in the region
+	awsConfig := aws.NewConfig().WithCredentials(credentials.NewStaticCredentials(accessKey, secretKey, "")).WithRegion(region)
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	describeImagesResponse, err := ec2.New(sess).DescribeImages(&ec2.DescribeImagesInput{
+		Owners: []*string{aws.String(quickstartAWSAMIOwner)},
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("name"),
+				Values: []*string{aws.String(quickstartAWSAMINameFilter)},
+			},
+		},
+	})
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	amiID := getNewestAWSImage(describeImagesResponse.Images)
+	if amiID == "" {
+		return quickstartProfile{}, fmt.Errorf("No Ubuntu AMI found in AWS region '%s', set aws_ami_id.", region)
+	}
+
+	// The cluster manager and the cluster each upload the public key as a key pair of their own
+	return quickstartProfile{
+		Shared: map[string]interface{}{
+			"aws_access_key":       accessKey,
+			"aws_secret_key":       secretKey,
+			"aws_region":           region,
+			"aws_private_key_path": privateKeyPath,
+			"aws_ssh_user":         quickstartSSHUser,
+			"aws_vpc_cidr":         "10.0.0.0/16",
+			"aws_subnet_cidr":      "10.0.2.0/24",
+		},
+		Manager: map[string]interface{}{
+			"aws_key_name":        fmt.Sprintf("%s-manager", name),
+			"aws_public_key_path": publicKeyPath,
+			"aws_ami_id":          amiID,
+			"aws_instance_type":   quickstartAWSManagerType,
+		},
+		Cluster: map[string]interface{}{
+			"aws_key_name":        fmt.Sprintf("%s-cluster", name),
+			"aws_public_key_path": publicKeyPath,
+		},
+		Node: map[string]interface{}{
+			"aws_ami_id":        amiID,
+			"aws_instance_type": quickstartAWSNodeType,
+		},
+	}, nil
+}
+
+// Returns the ID of the most recently created image.
+func getNewestAWSImage(images []*ec2.Image) string {
+	newest := ""
+	newestCreationDate := ""
+	for _, image := range images {
+		if image.ImageId == nil || image.CreationDate == nil {
+			continue
+		}
+		if *image.CreationDate > newestCreationDate {
+			newest = *image.ImageId
+			newestCreationDate = *image.CreationDate
+		}
+	}
+	return newest
+}
+
+func getGCPQuickstartProfile(conf config.Config) (quickstartProfile, error) {
+	rawCredentialsPath, err := promptForQuickstartValue(conf, "gcp_path_to_credentials", "Path to Google Cloud Platform Credentials File", "", false)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	credentialsPath, err := homedir.Expand(rawCredentialsPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	region := quickstartGCPRegion
+	if conf.IsSet("gcp_compute_region") {
+		region = conf.GetString("gcp_compute_region")
+	}
+
+	privateKeyPath, err := getQuickstartKeyPath(conf, "gcp_private_key_path", quickstartPrivateKeyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	publicKeyPath, err := getQuickstartKeyPath(conf, "gcp_public_key_path", quickstartPublicKeyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	gcpCredentials, err := ioutil.ReadFile(credentialsPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	jwtCfg, err := google.JWTConfigFromJSON(gcpCredentials, "https://www.googleapis.com/auth/compute.readonly")
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	// jwt.Config does not expose the project ID, so re-unmarshal to get it.
+	var pid struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.Unmarshal(gcpCredentials, &pid); err != nil {
+		return quickstartProfile{}, err
+	}
+
+	service, err := compute.New(jwtCfg.Client(context.Background()))
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	zones, err := getGCPZones(service, pid.ProjectID, region)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	if len(zones) == 0 {
+		return quickstartProfile{}, fmt.Errorf("No zones are up in GCP region '%s', set gcp_compute_region.", region)
+	}
+	zone := zones[0].Name
+
+	machineTypes, err := getGCPMachineTypes(service, pid.ProjectID, zone)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	managerMachineType, err := getSmallestGCPMachineType(machineTypes, quickstartManagerMemory)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	nodeMachineType, err := getSmallestGCPMachineType(machineTypes, quickstartNodeMemory)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	images, err := getGCPImages(service, gcpImageProject)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	image := ""
+	for _, candidate := range images {
+		if candidate.Family == quickstartGCPImageFamily {
+			image = candidate.Name
+			break
+		}
+	}
+	if image == "" {
+		return quickstartProfile{}, fmt.Errorf("No image of the '%s' family in project '%s', set gcp_image.", quickstartGCPImageFamily, gcpImageProject)
+	}
+
+	return quickstartProfile{
+		Shared: map[string]interface{}{
+			"gcp_path_to_credentials": credentialsPath,
+			"gcp_compute_region":      region,
+		},
+		Manager: map[string]interface{}{
+			"gcp_public_key_path":  publicKeyPath,
+			"gcp_private_key_path": privateKeyPath,
+			"gcp_ssh_user":         quickstartSSHUser,
+			"gcp_instance_zone":    zone,
+			"gcp_machine_type":     managerMachineType,
+			"gcp_image":            image,
+		},
+		Cluster: map[string]interface{}{},
+		Node: map[string]interface{}{
+			"gcp_instance_zone": zone,
+			"gcp_machine_type":  nodeMachineType,
+			"gcp_image":         image,
+		},
+	}, nil
+}
+
+// Returns the name of the machine type with the least memory, of at least the given amount of
+// MB, and the fewest CPUs.
+func getSmallestGCPMachineType(machineTypes []*compute.MachineType, minMemory int64) (string, error) {
+	var smallest *compute.MachineType
+	for _, machineType := range machineTypes {
+		if machineType.MemoryMb < minMemory {
+			continue
+		}
+		if smallest == nil || machineType.MemoryMb < smallest.MemoryMb || (machineType.MemoryMb == smallest.MemoryMb && machineType.GuestCpus < smallest.GuestCpus) {
+			smallest = machineType
+		}
+	}
+	if smallest == nil {
+		return "", fmt.Errorf("No GCP machine type has %d MB of memory, set gcp_machine_type.", minMemory)
+	}
+	return smallest.Name, nil
+}
+
+func getDigitalOceanQuickstartProfile(conf config.Config) (quickstartProfile, error) {
+	token, err := getDigitalOceanAPIToken(conf)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	region := quickstartDigitalOceanRegion
+	if conf.IsSet("digitalocean_region") {
+		region = conf.GetString("digitalocean_region")
+	}
+
+	privateKeyPath, err := getQuickstartKeyPath(conf, "digitalocean_private_key_path", quickstartPrivateKeyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	sizes, err := listDigitalOceanSizes(token)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	managerSize, err := getCheapestDigitalOceanSize(sizes, region, quickstartManagerMemory)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+	nodeSize, err := getCheapestDigitalOceanSize(sizes, region, quickstartNodeMemory)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	// Droplets are created with the account's SSH key of digitalocean_private_key_path, which
+	// terraform connects with
+	fingerprint, err := getQuickstartDigitalOceanSSHKey(conf, token, privateKeyPath)
+	if err != nil {
+		return quickstartProfile{}, err
+	}
+
+	machine := map[string]interface{}{
+		"digitalocean_image":               quickstartDigitalOceanImage,
+		"digitalocean_ssh_key_fingerprint": fingerprint,
+	}
+
+	profile := quickstartProfile{
+		Shared: map[string]interface{}{
+			"digitalocean_api_token": token,
+			"digitalocean_region":    region,
+		},
+		Manager: map[string]interface{}{
+			"digitalocean_droplet_size":     managerSize,
+			"digitalocean_private_key_path": privateKeyPath,
+		},
+		Cluster: map[string]interface{}{},
+		Node:    map[string]interface{}{"digitalocean_droplet_size": nodeSize},
+	}
+	for key, value := range machine {
+		profile.Manager[key] = value
+		profile.Node[key] = value
+	}
+
+	return profile, nil
+}
+
+// Returns the fingerprint of digitalocean_ssh_key_fingerprint, which must be a key of the
+// DigitalOcean account, or else of the account's key that is the public key of privateKeyPath.
+func getQuickstartDigitalOceanSSHKey(conf config.Config, token, privateKeyPath string) (string, error) {
+	keys, err := listDigitalOceanSSHKeys(token)
+	if err != nil {
+		return "", err
+	}
+	if conf.IsSet("digitalocean_ssh_key_fingerprint") {
+		return util.PromptForOption(conf, "digitalocean_ssh_key_fingerprint", "DigitalOcean SSH Key", digitalOceanSSHKeyOptions(keys))
+	}
+
+	publicKeyFingerprint, err := util.GetPublicKeyFingerprintFromPrivateKey(privateKeyPath)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, ok := findDigitalOceanSSHKey(keys, publicKeyFingerprint)
+	if !ok {
+		return "", util.ConfigError(fmt.Errorf("The public key of %s, %s, isn't an SSH key of the DigitalOcean account. Add it to the account, or set digitalocean_ssh_key_fingerprint and digitalocean_private_key_path to one that is.", privateKeyPath, publicKeyFingerprint))
+	}
+	return fingerprint, nil
+}
+
+// Returns the fingerprint of the key with the given MD5 fingerprint, as DigitalOcean lists it.
+func findDigitalOceanSSHKey(keys []digitalOceanSSHKey, fingerprint string) (string, bool) {
+	for _, key := range keys {
+		if strings.EqualFold(key.Fingerprint, fingerprint) {
+			return key.Fingerprint, true
+		}
+	}
+	return "", false
+}
+
+// Returns the slug of the cheapest size available in the region, with at least the given
+// amount of MB of memory.
+func getCheapestDigitalOceanSize(sizes []digitalOceanSize, region string, minMemory int) (string, error) {
+	var cheapest *digitalOceanSize
+	for i, size := range sizes {
+		if !size.Available || size.Memory < minMemory || !containsString(size.Regions, region) {
+			continue
+		}
+		if cheapest == nil || size.PriceMonthly < cheapest.PriceMonthly {
+			cheapest = &sizes[i]
+		}
+	}
+	if cheapest == nil {
+		return "", fmt.Errorf("No DigitalOcean droplet size in region '%s' has %d MB of memory, set digitalocean_droplet_size.", region, minMemory)
+	}
+	return cheapest.Slug, nil
+}
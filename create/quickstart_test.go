@@ -0,0 +1,168 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	tritoncompute "github.com/joyent/triton-go/compute"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestSetQuickstartSpec(t *testing.T) {
+	conf := config.New()
+	conf.Set("aws_region", "eu-west-1")
+	conf.Set("aws_instance_type", "m5.large")
+
+	profile := quickstartProfile{
+		Shared:  map[string]interface{}{"aws_access_key": "key", "aws_region": "us-west-2"},
+		Manager: map[string]interface{}{"aws_instance_type": "t2.medium", "aws_key_name": "dev-manager"},
+		Cluster: map[string]interface{}{"aws_key_name": "dev-cluster"},
+		Node:    map[string]interface{}{"aws_instance_type": "t2.small", "aws_ami_id": "ami-1"},
+	}
+	setQuickstartSpec(conf, "aws", "dev", profile)
+
+	if conf.GetString("aws_access_key") != "key" || conf.GetString("aws_region") != "eu-west-1" {
+		t.Errorf("Expected the shared settings without overriding the config, got region '%s'", conf.GetString("aws_region"))
+	}
+
+	manager, ok := toStringMap(conf.Get("manager"))
+	if !ok {
+		t.Fatal("Expected the manager settings to be a map")
+	}
+	if manager["name"] != "dev" || manager["manager_cloud_provider"] != "aws" || manager["aws_key_name"] != "dev-manager" {
+		t.Errorf("Unexpected manager settings %v", manager)
+	}
+	if manager["aws_instance_type"] != "m5.large" {
+		t.Errorf("Expected the instance type of the config, got %v", manager["aws_instance_type"])
+	}
+
+	clusters := conf.Get("clusters").([]interface{})
+	if len(clusters) != 1 {
+		t.Fatalf("Expected one cluster, got %d", len(clusters))
+	}
+	cluster, _ := toStringMap(clusters[0])
+	if cluster["name"] != "dev" || cluster["cluster_cloud_provider"] != "aws" || cluster["k8s_version"] != quickstartKubernetesVersion {
+		t.Errorf("Unexpected cluster settings %v", cluster)
+	}
+
+	// Each node is read by NewCluster as a map[interface{}]interface{}
+	nodes := cluster["nodes"].([]interface{})
+	if len(nodes) != len(quickstartNodeRoles) {
+		t.Fatalf("Expected a node of each role, got %d", len(nodes))
+	}
+	for i, role := range quickstartNodeRoles {
+		node, ok := nodes[i].(map[interface{}]interface{})
+		if !ok {
+			t.Fatalf("Expected node %d to be a map", i)
+		}
+		if node["rancher_host_label"] != role || node["hostname"] != "dev-"+role || node["node_count"] != 1 {
+			t.Errorf("Unexpected node settings %v", node)
+		}
+		if node["aws_instance_type"] != "m5.large" || node["aws_ami_id"] != "ami-1" {
+			t.Errorf("Expected the node defaults with the overrides of the config, got %v", node)
+		}
+	}
+}
+
+func TestGetSmallestTritonPackage(t *testing.T) {
+	packages := []*tritoncompute.Package{
+		{Name: "k4-highcpu-kvm-7.75G", Memory: 7936},
+		{Name: "k4-highcpu-kvm-1.75G", Memory: 1792},
+		{Name: "k4-highcpu-kvm-3.75G", Memory: 3840},
+		{Name: "k4-general-kvm-3.75G", Memory: 3840},
+	}
+
+	name, err := getSmallestTritonPackage(packages, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "k4-highcpu-kvm-3.75G" {
+		t.Errorf("Expected the first package with the least memory, got %s", name)
+	}
+
+	if _, err := getSmallestTritonPackage(packages, 16384); err == nil {
+		t.Error("Expected an error when no package has enough memory")
+	}
+}
+
+func TestGetSmallestGCPMachineType(t *testing.T) {
+	machineTypes := []*compute.MachineType{
+		{Name: "n1-standard-1", GuestCpus: 1, MemoryMb: 3840},
+		{Name: "e2-small", GuestCpus: 2, MemoryMb: 2048},
+		{Name: "e2-medium", GuestCpus: 2, MemoryMb: 4096},
+		{Name: "n1-highcpu-4", GuestCpus: 4, MemoryMb: 3686},
+		{Name: "g1-small", GuestCpus: 1, MemoryMb: 1740},
+	}
+
+	for _, test := range []struct {
+		minMemory int64
+		expected  string
+	}{
+		{2048, "e2-small"},
+		{4096, "e2-medium"},
+	} {
+		name, err := getSmallestGCPMachineType(machineTypes, test.minMemory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != test.expected {
+			t.Errorf("Expected %s for %d MB, got %s", test.expected, test.minMemory, name)
+		}
+	}
+}
+
+func TestGetCheapestDigitalOceanSize(t *testing.T) {
+	sizes := []digitalOceanSize{
+		{Slug: "s-1vcpu-2gb", Memory: 2048, PriceMonthly: 10, Regions: []string{"nyc3"}, Available: true},
+		{Slug: "s-2vcpu-4gb", Memory: 4096, PriceMonthly: 20, Regions: []string{"nyc3"}, Available: true},
+		{Slug: "s-1vcpu-3gb", Memory: 3072, PriceMonthly: 15, Regions: []string{"sfo2"}, Available: true},
+		{Slug: "c-2", Memory: 4096, PriceMonthly: 40, Regions: []string{"nyc3"}, Available: true},
+		{Slug: "s-4vcpu-4gb", Memory: 4096, PriceMonthly: 5, Regions: []string{"nyc3"}, Available: false},
+	}
+
+	name, err := getCheapestDigitalOceanSize(sizes, "nyc3", 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "s-2vcpu-4gb" {
+		t.Errorf("Expected the cheapest available size in the region, got %s", name)
+	}
+
+	if _, err := getCheapestDigitalOceanSize(sizes, "ams3", 2048); err == nil {
+		t.Error("Expected an error when no size is available in the region")
+	}
+}
+
+func TestFindDigitalOceanSSHKey(t *testing.T) {
+	keys := []digitalOceanSSHKey{
+		{Name: "laptop", Fingerprint: "3b:16:bf:e4:8b:00:8b:b8:59:8c:a9:d3:f0:19:45:fa"},
+		{Name: "ci", Fingerprint: "c1:5c:8e:0c:5a:3c:6b:39:7d:0f:4e:64:a2:93:31:f4"},
+	}
+
+	fingerprint, ok := findDigitalOceanSSHKey(keys, "C1:5C:8E:0C:5A:3C:6B:39:7D:0F:4E:64:A2:93:31:F4")
+	if !ok || fingerprint != "c1:5c:8e:0c:5a:3c:6b:39:7d:0f:4e:64:a2:93:31:f4" {
+		t.Errorf("Expected the ci key, got %q, %t", fingerprint, ok)
+	}
+
+	if _, ok := findDigitalOceanSSHKey(keys, "00:11:22:33:44:55:66:77:88:99:aa:bb:cc:dd:ee:ff"); ok {
+		t.Error("Expected no key for a fingerprint that isn't in the account")
+	}
+}
+
+func TestGetNewestAWSImage(t *testing.T) {
+	images := []*ec2.Image{
+		{ImageId: aws.String("ami-old"), CreationDate: aws.String("2018-01-10T00:00:00.000Z")},
+		{ImageId: aws.String("ami-new"), CreationDate: aws.String("2018-04-02T00:00:00.000Z")},
+		{ImageId: aws.String("ami-mid"), CreationDate: aws.String("2018-02-20T00:00:00.000Z")},
+	}
+
+	if id := getNewestAWSImage(images); id != "ami-new" {
+		t.Errorf("Expected the newest image, got %s", id)
+	}
+	if id := getNewestAWSImage(nil); id != "" {
+		t.Errorf("Expected no image, got %s", id)
+	}
+}
@@ -0,0 +1,77 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Sets how Rancher is exposed when it lives behind an existing load balancer or reverse proxy,
+// or on other ports than 443 and 80. These are only read from the config, the defaults expose
+// Rancher directly on the master.
+func setRancherEndpointConfig(conf config.Config, cfg *baseManagerTerraformConfig) error {
+	if conf.IsSet("rancher_external_url") {
+		externalURL, err := validateRancherExternalURL(conf.GetString("rancher_external_url"))
+		if err != nil {
+			return err
+		}
+		cfg.RancherExternalURL = externalURL
+	}
+
+	for key, port := range map[string]*string{"rancher_https_port": &cfg.RancherHTTPSPort, "rancher_http_port": &cfg.RancherHTTPPort} {
+		if !conf.IsSet(key) {
+			continue
+		}
+
+		value := conf.GetString(key)
+		num, err := strconv.Atoi(value)
+		if err != nil || num < 1 || num > 65535 {
+			return fmt.Errorf("%s must be a port between 1 and 65535. Found '%s'.", key, value)
+		}
+		*port = value
+	}
+	if cfg.RancherHTTPSPort != "" && cfg.RancherHTTPSPort == cfg.RancherHTTPPort {
+		return errors.New("rancher_https_port and rancher_http_port must be different")
+	}
+
+	if conf.IsSet("rancher_tls_termination") {
+		cfg.RancherTLSTermination = conf.GetString("rancher_tls_termination")
+		switch cfg.RancherTLSTermination {
+		case "rancher":
+		case "proxy":
+			// Nodes would otherwise connect over HTTPS to a port serving HTTP
+			if cfg.RancherExternalURL == "" {
+				return util.ConfigError(errors.New("rancher_external_url must be specified when rancher_tls_termination is 'proxy'"))
+			}
+		default:
+			return util.ConfigError(fmt.Errorf("Invalid rancher_tls_termination '%s', must be 'rancher' or 'proxy'", cfg.RancherTLSTermination))
+		}
+	}
+
+	return nil
+}
+
+// Returns the external URL of Rancher without a trailing slash. Nodes and the Rancher API
+// client only connect to Rancher over HTTPS.
+func validateRancherExternalURL(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", util.ConfigError(fmt.Errorf("Invalid rancher_external_url '%s': %v", rawURL, err))
+	}
+	if parsedURL.Scheme != "https" {
+		return "", util.ConfigError(fmt.Errorf("Invalid rancher_external_url '%s', it must be an https URL", rawURL))
+	}
+	if parsedURL.Hostname() == "" {
+		return "", util.ConfigError(fmt.Errorf("Invalid rancher_external_url '%s', it must have a host", rawURL))
+	}
+	if strings.Trim(parsedURL.Path, "/") != "" || parsedURL.RawQuery != "" {
+		return "", util.ConfigError(fmt.Errorf("Invalid rancher_external_url '%s', Rancher must be served at the root of the URL", rawURL))
+	}
+
+	return strings.TrimSuffix(rawURL, "/"), nil
+}
@@ -0,0 +1,81 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestSetRancherEndpointConfig(t *testing.T) {
+	conf := config.New()
+	conf.Set("rancher_external_url", "https://rancher.example.com:8443/")
+	conf.Set("rancher_https_port", 8443)
+	conf.Set("rancher_http_port", "8080")
+	conf.Set("rancher_tls_termination", "proxy")
+
+	cfg := baseManagerTerraformConfig{}
+	err := setRancherEndpointConfig(conf, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.RancherExternalURL != "https://rancher.example.com:8443" {
+		t.Errorf("Wrong output, expected https://rancher.example.com:8443, received %s", cfg.RancherExternalURL)
+	}
+	if cfg.RancherHTTPSPort != "8443" || cfg.RancherHTTPPort != "8080" || cfg.RancherTLSTermination != "proxy" {
+		t.Errorf("Wrong output, received %+v", cfg)
+	}
+}
+
+func TestSetRancherEndpointConfigInvalid(t *testing.T) {
+	tests := []struct {
+		testName string
+		settings map[string]interface{}
+		expected string
+	}{
+		{
+			"HTTP external URL",
+			map[string]interface{}{"rancher_external_url": "http://rancher.example.com"},
+			"Invalid rancher_external_url 'http://rancher.example.com', it must be an https URL",
+		},
+		{
+			"External URL with path",
+			map[string]interface{}{"rancher_external_url": "https://example.com/rancher"},
+			"Invalid rancher_external_url 'https://example.com/rancher', Rancher must be served at the root of the URL",
+		},
+		{
+			"Invalid port",
+			map[string]interface{}{"rancher_https_port": 70000},
+			"rancher_https_port must be a port between 1 and 65535. Found '70000'.",
+		},
+		{
+			"Same ports",
+			map[string]interface{}{"rancher_https_port": 8443, "rancher_http_port": 8443},
+			"rancher_https_port and rancher_http_port must be different",
+		},
+		{
+			"Proxy without external URL",
+			map[string]interface{}{"rancher_tls_termination": "proxy"},
+			"rancher_external_url must be specified when rancher_tls_termination is 'proxy'",
+		},
+		{
+			"Invalid TLS termination",
+			map[string]interface{}{"rancher_tls_termination": "lb"},
+			"Invalid rancher_tls_termination 'lb', must be 'rancher' or 'proxy'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			conf := config.New()
+			for key, value := range test.settings {
+				conf.Set(key, value)
+			}
+
+			err := setRancherEndpointConfig(conf, &baseManagerTerraformConfig{})
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("Wrong output, expected %s, received %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// ReconcileNodePools removes the nodes of a cluster's node pools from Rancher whose
+// instances no longer exist.
+func ReconcileNodePools(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterKey := ""
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
+		clusterKey, ok := clusters[clusterName]
+		if !ok {
+			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+
+		selectedClusterKey = clusterKey
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to reconcile node pools of",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedClusterKey = clusters[value]
+	}
+
+	nodes, err := currentState.Nodes(selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	rancherNodes, err := client.Nodes(rancherClusterID)
+	if err != nil {
+		return err
+	}
+
+	staleNodes := []rancher.Node{}
+	for poolName, nodeKey := range nodes {
+		provider, ok := getNodePoolProvider(currentState, nodeKey)
+		if !ok {
+			continue
+		}
+
+		hostnames, err := provider.Hostnames(conf, currentState, nodeKey)
+		if err != nil {
+			return err
+		}
+
+		staleNodes = append(staleNodes, getStaleNodePoolNodes(poolName, provider.IsMember, hostnames, rancherNodes)...)
+	}
+
+	if len(staleNodes) == 0 {
+		fmt.Println("Node pools are in sync.")
+		return nil
+	}
+
+	staleHostnames := make([]string, 0, len(staleNodes))
+	for _, node := range staleNodes {
+		staleHostnames = append(staleHostnames, node.Hostname)
+	}
+	sort.Strings(staleHostnames)
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Remove replaced nodes %s from Rancher", strings.Join(staleHostnames, ", "))
+		selected := "Remove"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Reconcile canceled.")
+			return nil
+		}
+	}
+
+	for _, node := range staleNodes {
+		err = client.DeleteNode(node)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed node %s.\n", node.Hostname)
+	}
+
+	return nil
+}
+
+// Returns the Rancher nodes of the node pool whose instances are not in liveHostnames.
+func getStaleNodePoolNodes(poolName string, isMember func(poolName, hostname string) bool, liveHostnames []string, rancherNodes []rancher.Node) []rancher.Node {
+	liveInstances := map[string]bool{}
+	for _, hostname := range liveHostnames {
+		liveInstances[hostname] = true
+	}
+
+	result := []rancher.Node{}
+	for _, node := range rancherNodes {
+		// Skips nodes of other pools and individual nodes sharing the hostname prefix
+		if !isMember(poolName, node.Hostname) {
+			continue
+		}
+
+		if !liveInstances[node.Hostname] {
+			result = append(result, node)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,259 @@
+package create
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// A resource attribute or module output whose value changed outside of triton-kubernetes, or a
+// resource that no longer exists when Name is empty.
+type stateDrift struct {
+	Module    string
+	Address   string
+	Name      string
+	OldValue  string
+	NewValue  string
+	Sensitive bool
+}
+
+// A resource of a top level module, or its outputs when Address is empty.
+type stateValuesKey struct {
+	Module  string
+	Address string
+}
+
+type stateValue struct {
+	Value     string
+	Sensitive bool
+}
+
+// ReconcileState refreshes the terraform state of a cluster manager against the real
+// infrastructure, e.g. after the cloud provider replaced an instance, which updates the stored
+// outputs, and reports what changed out-of-band.
+func ReconcileState(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	before, after, err := shell.RunTerraformRefreshWithState(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	drifts, err := getStateDrift(before, after)
+	if err != nil {
+		return err
+	}
+
+	if len(drifts) == 0 {
+		fmt.Printf("Cluster manager '%s' is in sync with its infrastructure.\n", selectedClusterManager)
+		return nil
+	}
+
+	fmt.Printf("Changed out-of-band, the state of cluster manager '%s' was updated:\n", selectedClusterManager)
+	for _, drift := range drifts {
+		fmt.Println("  " + drift.String())
+	}
+
+	return nil
+}
+
+func (d stateDrift) String() string {
+	subject := fmt.Sprintf("module.%s.%s", d.Module, d.Address)
+	if d.Name == "" {
+		return subject + " no longer exists"
+	}
+	if d.Address == "" {
+		subject = fmt.Sprintf("module.%s output", d.Module)
+	}
+	subject = fmt.Sprintf("%s %s", subject, d.Name)
+
+	switch {
+	case d.Sensitive || isLongValue(d.OldValue) || isLongValue(d.NewValue):
+		return subject + " changed"
+	case d.OldValue == "":
+		return fmt.Sprintf("%s set to %s", subject, d.NewValue)
+	case d.NewValue == "":
+		return fmt.Sprintf("%s removed, was %s", subject, d.OldValue)
+	}
+	return fmt.Sprintf("%s changed from %s to %s", subject, d.OldValue, d.NewValue)
+}
+
+// Values too long to be read on one line, e.g. user data, are only reported as changed.
+func isLongValue(value string) bool {
+	return len(value) > 80 || strings.Contains(value, "\n")
+}
+
+// Returns the resource attributes and outputs of the top level modules that differ between the
+// terraform states from before and after a refresh, sorted by module. Resources that no longer
+// exist are reported once rather than attribute by attribute.
+func getStateDrift(rawBefore, rawAfter []byte) ([]stateDrift, error) {
+	before, err := stateValues(rawBefore)
+	if err != nil {
+		return nil, err
+	}
+	after, err := stateValues(rawAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []stateDrift{}
+	for key, oldValues := range before {
+		newValues, ok := after[key]
+		if !ok && key.Address != "" {
+			result = append(result, stateDrift{Module: key.Module, Address: key.Address})
+			continue
+		}
+
+		for name, oldValue := range oldValues {
+			newValue, ok := newValues[name]
+			if ok && newValue.Value == oldValue.Value {
+				continue
+			}
+			result = append(result, stateDrift{
+				Module:    key.Module,
+				Address:   key.Address,
+				Name:      name,
+				OldValue:  oldValue.Value,
+				NewValue:  newValue.Value,
+				Sensitive: oldValue.Sensitive || newValue.Sensitive,
+			})
+		}
+		for name, newValue := range newValues {
+			if _, ok := oldValues[name]; !ok {
+				result = append(result, stateDrift{
+					Module:    key.Module,
+					Address:   key.Address,
+					Name:      name,
+					NewValue:  newValue.Value,
+					Sensitive: newValue.Sensitive,
+				})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Module != result[j].Module {
+			return result[i].Module < result[j].Module
+		}
+		if result[i].Address != result[j].Address {
+			return result[i].Address < result[j].Address
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Returns the attributes of the resources and the outputs of the top level modules of a terraform
+// state. Resources of nested modules are prefixed by their module path.
+func stateValues(rawState []byte) (map[stateValuesKey]map[string]stateValue, error) {
+	tfState := terraformState{}
+	err := json.Unmarshal(rawState, &tfState)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[stateValuesKey]map[string]stateValue{}
+	for _, module := range tfState.Modules {
+		// Paths are ["root", "{module}", "{nested module}"...]
+		if len(module.Path) < 2 {
+			continue
+		}
+
+		name := module.Path[1]
+		prefix := ""
+		for _, nested := range module.Path[2:] {
+			prefix += fmt.Sprintf("module.%s.", nested)
+		}
+
+		for address, resource := range module.Resources {
+			attributes := map[string]stateValue{}
+			for key, value := range resource.Primary.Attributes {
+				attributes[key] = stateValue{Value: value}
+			}
+			result[stateValuesKey{Module: name, Address: prefix + address}] = attributes
+		}
+
+		// Outputs of nested modules are only read by their parent module
+		if prefix != "" || len(module.Outputs) == 0 {
+			continue
+		}
+		outputs := map[string]stateValue{}
+		for key, output := range module.Outputs {
+			value := ""
+			switch v := output.Value.(type) {
+			case string:
+				value = v
+			default:
+				rawValue, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				value = string(rawValue)
+			}
+			outputs[key] = stateValue{Value: value, Sensitive: output.Sensitive}
+		}
+		result[stateValuesKey{Module: name}] = outputs
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package create
+
+import (
+	"testing"
+)
+
+func TestGetStateDrift(t *testing.T) {
+	before := []byte(`{"modules": [
+		{"path": ["root"], "resources": {}},
+		{"path": ["root", "cluster-manager"], "outputs": {
+			"rancher_url": {"sensitive": false, "value": "https://10.0.0.1"},
+			"rancher_secret_key": {"sensitive": true, "value": "old-secret"}
+		}, "resources": {
+			"triton_machine.rancher_master": {"primary": {"id": "a", "attributes": {"id": "a", "primaryip": "10.0.0.1", "tags.%": "1"}}}
+		}},
+		{"path": ["root", "node_triton_dev_dev-w-1"], "resources": {
+			"triton_machine.host": {"primary": {"id": "b", "attributes": {"id": "b", "primaryip": "10.0.0.2"}}}
+		}},
+		{"path": ["root", "node_triton_dev_dev-w-2"], "resources": {
+			"triton_machine.host": {"primary": {"id": "c", "attributes": {"id": "c", "primaryip": "10.0.0.3"}}}
+		}}
+	]}`)
+	after := []byte(`{"modules": [
+		{"path": ["root"], "resources": {}},
+		{"path": ["root", "cluster-manager"], "outputs": {
+			"rancher_url": {"sensitive": false, "value": "https://10.0.0.9"},
+			"rancher_secret_key": {"sensitive": true, "value": "new-secret"}
+		}, "resources": {
+			"triton_machine.rancher_master": {"primary": {"id": "a", "attributes": {"id": "a", "primaryip": "10.0.0.9", "tags.%": "1"}}}
+		}},
+		{"path": ["root", "node_triton_dev_dev-w-1"], "resources": {
+			"triton_machine.host": {"primary": {"id": "b", "attributes": {"id": "b", "primaryip": "10.0.0.2", "firewall_enabled": "true"}}}
+		}},
+		{"path": ["root", "node_triton_dev_dev-w-2"], "resources": {}}
+	]}`)
+
+	drifts, err := getStateDrift(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"module.cluster-manager output rancher_secret_key changed",
+		"module.cluster-manager output rancher_url changed from https://10.0.0.1 to https://10.0.0.9",
+		"module.cluster-manager.triton_machine.rancher_master primaryip changed from 10.0.0.1 to 10.0.0.9",
+		"module.node_triton_dev_dev-w-1.triton_machine.host firewall_enabled set to true",
+		"module.node_triton_dev_dev-w-2.triton_machine.host no longer exists",
+	}
+	if len(drifts) != len(expected) {
+		t.Fatalf("Wrong output, expected %d changes, received %v", len(expected), drifts)
+	}
+	for i, drift := range drifts {
+		if drift.String() != expected[i] {
+			t.Errorf("Wrong output, expected '%s', received '%s'", expected[i], drift.String())
+		}
+	}
+}
+
+func TestGetStateDriftInSync(t *testing.T) {
+	rawState := []byte(`{"modules": [{"path": ["root", "cluster-manager"], "outputs": {"rancher_url": {"value": "https://10.0.0.1"}}}]}`)
+
+	drifts, err := getStateDrift(rawState, rawState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("Wrong output, expected no changes, received %v", drifts)
+	}
+}
@@ -0,0 +1,72 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestGetStaleNodePoolNodes(t *testing.T) {
+	rancherNodes := []rancher.Node{
+		{ID: "m-1", Hostname: "w-i-0aaa"},
+		{ID: "m-2", Hostname: "w-i-0bbb"},
+		{ID: "m-3", Hostname: "w-1"},
+		{ID: "m-4", Hostname: "web-i-0ccc"},
+		{ID: "m-5", Hostname: "e-1"},
+	}
+
+	staleNodes := getStaleNodePoolNodes("w", isAWSNodePoolMember, []string{"w-i-0bbb", "w-i-0ddd"}, rancherNodes)
+
+	if len(staleNodes) != 1 || staleNodes[0].ID != "m-1" {
+		t.Errorf("Wrong output, expected node m-1, received %+v", staleNodes)
+	}
+}
+
+func TestIsAzureNodePoolMember(t *testing.T) {
+	for hostname, expected := range map[string]bool{
+		"w-00000a":   true,
+		"w-1":        false,
+		"w-00000a-1": false,
+		"web-00000a": false,
+	} {
+		if isAzureNodePoolMember("w", hostname) != expected {
+			t.Errorf("Wrong output for %s, expected %t", hostname, expected)
+		}
+	}
+}
+
+func TestIsGCPNodePoolMember(t *testing.T) {
+	for hostname, expected := range map[string]bool{
+		"w-x7k2":   true,
+		"w-1":      false,
+		"w-x7k2-1": false,
+		"web-x7k2": false,
+	} {
+		if isGCPNodePoolMember("w", hostname) != expected {
+			t.Errorf("Wrong output for %s, expected %t", hostname, expected)
+		}
+	}
+}
+
+func TestNodePoolGroupName(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module":{
+		"cluster_aws_dev":{"name":"dev"},
+		"node_aws_dev_w":{"hostname":"w","aws_asg_name":"dev-manager-dev-w"},
+		"node_aws_dev_old":{"hostname":"old"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := newNodePoolGroupName(currentState, "cluster_aws_dev", "w"); name != "dev-manager-dev-w" {
+		t.Errorf("Expected dev-manager-dev-w, received %s", name)
+	}
+	if name := nodePoolGroupName(currentState, "node_aws_dev_w", "aws_asg_name"); name != "dev-manager-dev-w" {
+		t.Errorf("Expected dev-manager-dev-w, received %s", name)
+	}
+	// Pools created before their group names included the cluster keep their name
+	if name := nodePoolGroupName(currentState, "node_aws_dev_old", "aws_asg_name"); name != "old" {
+		t.Errorf("Expected old, received %s", name)
+	}
+}
@@ -0,0 +1,242 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// Nodes register with their cluster with a Rancher registration token, which never expires and
+// ends up in the provisioning config of every node. The nodes added by every command get a
+// registration token of their own, which is deleted once they're active so their config can't be
+// used to register other machines. The cluster's own token, which the nodes created with the
+// cluster register with, is deleted once they're active as well. Node pools backed by an instance
+// group get a token of their own, which the group registers its replacement instances with, and
+// a new one every time they're scaled out. Setting ephemeral_registration_token to false keeps
+// the cluster's token for every node instead.
+
+// Returns whether the nodes added by a command get a registration token of their own. No token is
+// issued for plan_only runs, nothing registers with a plan that's only shown.
+func useEphemeralRegistrationTokens(conf config.Config) bool {
+	if conf.GetBool("plan_only") {
+		return false
+	}
+	return !conf.IsSet("ephemeral_registration_token") || conf.GetBool("ephemeral_registration_token")
+}
+
+// Returns the hostnames of the given hostnames that are nodes rather than node pools and the keys
+// of their node modules, and the keys of the node modules of the node pools.
+func ephemeralTokenNodes(currentState state.State, clusterKey string, hostnames []string) ([]string, []string, []string) {
+	tokenHostnames := []string{}
+	nodeKeys := []string{}
+	poolKeys := []string{}
+	for _, hostname := range hostnames {
+		nodeKey := strings.Replace(clusterKey, "cluster_", "node_", 1) + "_" + hostname
+		if _, ok := getNodePoolProvider(currentState, nodeKey); ok {
+			poolKeys = append(poolKeys, nodeKey)
+			continue
+		}
+		tokenHostnames = append(tokenHostnames, hostname)
+		nodeKeys = append(nodeKeys, nodeKey)
+	}
+	return tokenHostnames, nodeKeys, poolKeys
+}
+
+// Returns the reference to the cluster's own registration token in the config of its nodes.
+func clusterRegistrationTokenReference(clusterKey string) string {
+	return fmt.Sprintf("${module.%s.rancher_cluster_registration_token}", clusterKey)
+}
+
+// Sets the cluster's own registration token on the given node modules, which copied the settings
+// of another node, as the token issued to that node may be deleted.
+func setClusterRegistrationToken(currentState state.State, clusterKey string, nodeKeys []string) error {
+	for _, nodeKey := range nodeKeys {
+		err := currentState.Set(fmt.Sprintf("module.%s.rancher_cluster_registration_token", nodeKey), clusterRegistrationTokenReference(clusterKey))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the registration token of the node module if it was issued to the node, rather than
+// a reference to the cluster's token.
+func nodeEphemeralRegistrationToken(currentState state.State, nodeKey string) (string, bool) {
+	token := currentState.Get(fmt.Sprintf("module.%s.rancher_cluster_registration_token", nodeKey))
+	if token == "" || strings.HasPrefix(token, "${") {
+		return "", false
+	}
+	return token, true
+}
+
+// Creates a registration token and sets it on the given node modules.
+func issueRegistrationToken(client *rancher.Client, clusterID string, currentState state.State, nodeKeys []string) (rancher.ClusterRegistrationToken, error) {
+	token, err := client.CreateClusterRegistrationToken(clusterID)
+	if err != nil {
+		return rancher.ClusterRegistrationToken{}, err
+	}
+
+	for _, nodeKey := range nodeKeys {
+		err = currentState.Set(fmt.Sprintf("module.%s.rancher_cluster_registration_token", nodeKey), token.Token)
+		if err != nil {
+			return rancher.ClusterRegistrationToken{}, err
+		}
+	}
+
+	return token, nil
+}
+
+// Deletes the registration tokens of the cluster with the given values. Tokens that were
+// already deleted are skipped.
+func revokeRegistrationTokens(client *rancher.Client, clusterID string, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	tokens, err := client.ClusterRegistrationTokens(clusterID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if !containsString(values, token.Token) {
+			continue
+		}
+		err = client.DeleteClusterRegistrationToken(token.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deletes the registration tokens with the given values when the plan they were issued for was
+// declined, and returns applyErr. Tokens of failed applies are kept for `triton-kubernetes retry`.
+func revokeUnappliedRegistrationTokens(client *rancher.Client, clusterID string, values []string, applyErr error) error {
+	if len(values) == 0 || !errors.Is(applyErr, shell.ErrPlanNotApplied) {
+		return applyErr
+	}
+
+	err := revokeRegistrationTokens(client, clusterID, values)
+	if err != nil {
+		return fmt.Errorf("%w\nUnable to delete the registration token issued for the plan, delete it in Rancher: %v", applyErr, err)
+	}
+	return applyErr
+}
+
+// Deletes the cluster's own registration token once the nodes created with the cluster are active.
+// Their node modules keep the value of the deleted token, so terraform doesn't replace them when
+// the cluster gets a new one. The token is kept while a node pool's instance group registers its
+// instances with it.
+func rotateClusterRegistrationToken(conf config.Config, remoteBackend backend.Backend, currentState state.State, clusterKey string) error {
+	nodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return err
+	}
+
+	reference := clusterRegistrationTokenReference(clusterKey)
+	nodeKeys := []string{}
+	for hostname, nodeKey := range nodes {
+		if currentState.Get(fmt.Sprintf("module.%s.rancher_cluster_registration_token", nodeKey)) != reference {
+			continue
+		}
+		if _, ok := getNodePoolProvider(currentState, nodeKey); ok {
+			fmt.Printf("Kept the registration token of the cluster, node pool %s registers its instances with it. Scaling the pool out gives it a token of its own.\n", hostname)
+			return nil
+		}
+		nodeKeys = append(nodeKeys, nodeKey)
+	}
+	if len(nodeKeys) == 0 {
+		return nil
+	}
+
+	outputs, err := shell.RunTerraformOutputWithState(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+	token, _ := outputs["rancher_cluster_registration_token"].(string)
+	if token == "" {
+		return fmt.Errorf("Unable to read the registration token of cluster '%s'", clusterKey)
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+
+	for _, nodeKey := range nodeKeys {
+		err = currentState.Set(fmt.Sprintf("module.%s.rancher_cluster_registration_token", nodeKey), token)
+		if err != nil {
+			return err
+		}
+	}
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	err = revokeRegistrationTokens(client, rancherClusterID, []string{token})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Deleted the registration token the nodes of the cluster registered with.")
+	return nil
+}
+
+// Returns the number of active nodes of the cluster, other than the given hostnames.
+func countActiveNodes(client *rancher.Client, clusterID string, excludedHostnames []string) (int, error) {
+	nodes, err := client.Nodes(clusterID)
+	if err != nil {
+		return 0, err
+	}
+
+	activeNodes := 0
+	for _, node := range nodes {
+		if node.State == "active" && !containsString(excludedHostnames, node.Hostname) {
+			activeNodes++
+		}
+	}
+	return activeNodes, nil
+}
+
+// Waits until expectedNodes nodes of the cluster are active, then deletes the registration
+// tokens with the given values. They're kept when the nodes aren't active in time, since the
+// nodes may still be registering with them.
+func revokeRegistrationTokensOnceActive(client *rancher.Client, clusterID string, values []string, expectedNodes int, hostnames []string, timeout time.Duration) error {
+	if timeout <= 0 {
+		fmt.Printf("Kept the registration token of %s, node_registration_timeout is 0 so they may still be registering.\n", strings.Join(hostnames, ", "))
+		return nil
+	}
+
+	fmt.Printf("Waiting up to %s for %s to become active...\n", timeout, strings.Join(hostnames, ", "))
+	err := waitForActiveNodes(client, clusterID, expectedNodes, hostnames, timeout)
+	if err != nil {
+		return fmt.Errorf("%w\nKept the registration token of the new nodes so they can still register, delete it in Rancher once they're active.", err)
+	}
+
+	err = revokeRegistrationTokens(client, clusterID, values)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Deleted the registration token of the new nodes.")
+	return nil
+}
+
+// Returns how long to wait for new nodes to become active.
+func getNodeRegistrationTimeout(conf config.Config) time.Duration {
+	timeout := defaultNodeRegistrationTimeout
+	if conf.IsSet("node_registration_timeout") {
+		timeout = conf.GetInt("node_registration_timeout")
+	}
+	return time.Duration(timeout) * time.Minute
+}
@@ -0,0 +1,143 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestEphemeralTokenNodes(t *testing.T) {
+	currentState, err := state.New("test", []byte(`{"module": {
+		"node_aws_dev_dev-w-1": {"source": "github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher-k8s-host?ref=master"},
+		"node_aws_dev_dev-pool": {"source": "github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher-k8s-asg?ref=master"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostnames, nodeKeys, poolKeys := ephemeralTokenNodes(currentState, "cluster_aws_dev", []string{"dev-w-1", "dev-pool"})
+	if !isEqual([]string{"dev-w-1"}, hostnames) || !isEqual([]string{"node_aws_dev_dev-w-1"}, nodeKeys) {
+		t.Errorf("Node pools get a token of their own, received %v %v", hostnames, nodeKeys)
+	}
+	if !isEqual([]string{"node_aws_dev_dev-pool"}, poolKeys) {
+		t.Errorf("Wrong output, expected [node_aws_dev_dev-pool], received %v", poolKeys)
+	}
+
+	if _, ok := nodeEphemeralRegistrationToken(currentState, "node_aws_dev_dev-w-1"); ok {
+		t.Error("Node without a token shouldn't have an ephemeral token")
+	}
+
+	currentState.Set("module.node_aws_dev_dev-pool.rancher_cluster_registration_token", "${module.cluster_aws_dev.rancher_cluster_registration_token}")
+	if _, ok := nodeEphemeralRegistrationToken(currentState, "node_aws_dev_dev-pool"); ok {
+		t.Error("The cluster's token isn't an ephemeral token")
+	}
+
+	currentState.Set("module.node_aws_dev_dev-w-1.rancher_cluster_registration_token", "s3cr3t")
+	token, ok := nodeEphemeralRegistrationToken(currentState, "node_aws_dev_dev-w-1")
+	if !ok || token != "s3cr3t" {
+		t.Errorf("Expected ephemeral token s3cr3t, received %q", token)
+	}
+}
+
+func TestUseEphemeralRegistrationTokens(t *testing.T) {
+	testCases := []struct {
+		settings map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{}, true},
+		{map[string]interface{}{"ephemeral_registration_token": false}, false},
+		{map[string]interface{}{"confirm_plan": true}, true},
+		{map[string]interface{}{"plan_only": true}, false},
+	}
+	for _, tc := range testCases {
+		conf := config.New()
+		for key, value := range tc.settings {
+			conf.Set(key, value)
+		}
+		if useEphemeralRegistrationTokens(conf) != tc.expected {
+			t.Errorf("Expected %v for %v", tc.expected, tc.settings)
+		}
+	}
+}
+
+func TestSetClusterRegistrationToken(t *testing.T) {
+	currentState, err := state.New("test", []byte(`{"module": {"node_aws_dev_dev-w-2": {"rancher_cluster_registration_token": "d3leted"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = setClusterRegistrationToken(currentState, "cluster_aws_dev", []string{"node_aws_dev_dev-w-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := currentState.Get("module.node_aws_dev_dev-w-2.rancher_cluster_registration_token")
+	if token != "${module.cluster_aws_dev.rancher_cluster_registration_token}" {
+		t.Errorf("Expected the cluster's token, received %s", token)
+	}
+}
+
+func TestRevokeUnappliedRegistrationTokens(t *testing.T) {
+	deleted := []string{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"data": [{"id": "c-abcde:crt-1", "token": "s3cr3t"}]}`)
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := rancher.NewClient(server.URL, "access", "secret")
+
+	// Tokens of failed applies are kept for retry
+	applyErr := errors.New("terraform apply failed")
+	err := revokeUnappliedRegistrationTokens(client, "c-abcde", []string{"s3cr3t"}, applyErr)
+	if err != applyErr || len(deleted) != 0 {
+		t.Errorf("Expected the token to be kept, received %v %v", err, deleted)
+	}
+
+	err = revokeUnappliedRegistrationTokens(client, "c-abcde", []string{"s3cr3t"}, shell.ErrPlanNotApplied)
+	if err != shell.ErrPlanNotApplied {
+		t.Errorf("Expected the plan error, received %v", err)
+	}
+	if !isEqual([]string{"/v3/clusterregistrationtokens/c-abcde:crt-1"}, deleted) {
+		t.Errorf("Expected the token of the declined plan to be deleted, received %v", deleted)
+	}
+}
+
+func TestRevokeRegistrationTokens(t *testing.T) {
+	deleted := []string{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"data": [{"id": "c-abcde:default-token", "token": "d3fault"}, {"id": "c-abcde:crt-1", "token": "s3cr3t"}]}`)
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := rancher.NewClient(server.URL, "access", "secret")
+
+	// Tokens that were already deleted are skipped
+	err := revokeRegistrationTokens(client, "c-abcde", []string{"s3cr3t", "g0ne"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"/v3/clusterregistrationtokens/c-abcde:crt-1"}
+	if !isEqual(expected, deleted) {
+		t.Errorf("Wrong output, expected %v, received %v", expected, deleted)
+	}
+}
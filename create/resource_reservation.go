@@ -0,0 +1,34 @@
+package create
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Kubernetes quantities, e.g. 250m, 0.5, 512Mi or 1G
+var resourceQuantityRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|Ki|M|Mi|G|Gi|T|Ti)?$`)
+
+// Resources the kubelet can reserve for daemons.
+var reservableResources = map[string]bool{
+	"cpu":               true,
+	"memory":            true,
+	"ephemeral-storage": true,
+	"pid":               true,
+}
+
+// Verifies the resources of the kube_reserved or system_reserved setting can be passed to the
+// kubelet, e.g. {cpu: 250m, memory: 512Mi}.
+func validateResourceReservation(setting string, reserved map[string]string) error {
+	for resource, quantity := range reserved {
+		if !reservableResources[resource] {
+			return util.ConfigError(fmt.Errorf("Invalid resource '%s' in %s, must be one of cpu, memory, ephemeral-storage or pid", resource, setting))
+		}
+		if !resourceQuantityRegexp.MatchString(quantity) {
+			return util.ConfigError(fmt.Errorf("Invalid quantity '%s' for resource '%s' in %s, must be a Kubernetes quantity e.g. 250m or 512Mi", quantity, resource, setting))
+		}
+	}
+
+	return nil
+}
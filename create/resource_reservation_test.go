@@ -0,0 +1,28 @@
+package create
+
+import "testing"
+
+var validateResourceReservationTestCases = []struct {
+	Reserved    map[string]string
+	ExpectError bool
+}{
+	{map[string]string{}, false},
+	{map[string]string{"cpu": "250m", "memory": "512Mi"}, false},
+	{map[string]string{"cpu": "0.5", "ephemeral-storage": "1Gi", "pid": "1000"}, false},
+	{map[string]string{"gpu": "1"}, true},
+	{map[string]string{"memory": ""}, true},
+	{map[string]string{"memory": "512 MB"}, true},
+	{map[string]string{"cpu": "250m,memory=1Gi"}, true},
+}
+
+func TestValidateResourceReservation(t *testing.T) {
+	for _, tc := range validateResourceReservationTestCases {
+		err := validateResourceReservation("kube_reserved", tc.Reserved)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for %v", tc.Reserved)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("Unexpected error for %v: %v", tc.Reserved, err)
+		}
+	}
+}
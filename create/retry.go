@@ -0,0 +1,202 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// RetryFailedNodes re-applies the nodes of a cluster that were marked as failed when they were created.
+func RetryFailedNodes(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterKey := ""
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
+		clusterKey, ok := clusters[clusterName]
+		if !ok {
+			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+
+		selectedClusterKey = clusterKey
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to retry failed nodes of",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedClusterKey = clusters[value]
+	}
+
+	failedNodes, err := currentState.FailedNodes(selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	if len(failedNodes) == 0 {
+		fmt.Println("No failed nodes.")
+		return nil
+	}
+
+	failedHostnames := make([]string, 0, len(failedNodes))
+	targetArgs := make([]string, 0, len(failedNodes))
+	for hostname, nodeKey := range failedNodes {
+		failedHostnames = append(failedHostnames, hostname)
+		targetArgs = append(targetArgs, fmt.Sprintf("-target=module.%s", nodeKey))
+	}
+	sort.Strings(failedHostnames)
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Retry failed nodes %s", strings.Join(failedHostnames, ", "))
+		selected := "Retry"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Retry canceled.")
+			return nil
+		}
+	}
+
+	// Failed nodes with a registration token of their own still use it, it's deleted once
+	// they're active
+	tokenHostnames := []string{}
+	tokens := []string{}
+	for _, hostname := range failedHostnames {
+		token, ok := nodeEphemeralRegistrationToken(currentState, failedNodes[hostname])
+		if !ok {
+			continue
+		}
+		tokenHostnames = append(tokenHostnames, hostname)
+		if !containsString(tokens, token) {
+			tokens = append(tokens, token)
+		}
+	}
+	var client *rancher.Client
+	var rancherClusterID string
+	activeNodes := 0
+	if len(tokens) > 0 {
+		client, rancherClusterID, err = rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+		if err != nil {
+			return err
+		}
+
+		activeNodes, err = countActiveNodes(client, rancherClusterID, tokenHostnames)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Tainted resources of the failed nodes are replaced by terraform
+	err = shell.RunTerraformApplyWithState(conf, currentState, targetArgs)
+	if err != nil {
+		return recordNodeApplyFailure(conf, remoteBackend, currentState, selectedClusterKey, failedHostnames, err)
+	}
+
+	for _, nodeKey := range failedNodes {
+		err = currentState.SetNodeFailed(nodeKey, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	// After terraform succeeds, commit state
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) > 0 {
+		return revokeRegistrationTokensOnceActive(client, rancherClusterID, tokens, activeNodes+len(tokenHostnames), tokenHostnames, getNodeRegistrationTimeout(conf))
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// RotateRancherAPIToken replaces the Rancher API token of a cluster manager. The new token is
+// stored encrypted in the state and applied to the manager's clusters before the old token is
+// deleted, which invalidates it.
+func RotateRancherAPIToken(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	client, err := rancher.NewClientFromState(conf, currentState)
+	if err != nil {
+		return err
+	}
+	oldAccessKey := client.AccessKey
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Rotate the Rancher API token of cluster manager '%s'", selectedClusterManager)
+		selected := "Rotate"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Token rotation canceled.")
+			return nil
+		}
+	}
+
+	newToken, err := client.CreateToken(fmt.Sprintf("triton-kubernetes, rotated %s", time.Now().UTC().Format(time.RFC3339)))
+	if err != nil {
+		return err
+	}
+
+	encryptedToken, err := util.EncryptSecret(conf, newToken.Token)
+	if err == nil {
+		err = currentState.SetRancherAPIToken(encryptedToken)
+	}
+	if err == nil {
+		// Block on configurations that violate the user's policies
+		err = checkPolicies(conf, currentState)
+	}
+	if err == nil {
+		err = shell.RunTerraformApplyWithState(conf, currentState, []string{})
+	}
+	if err != nil {
+		// The old token is still in use, don't leave the new one behind
+		deleteErr := client.DeleteToken(newToken.AccessKey())
+		if deleteErr != nil {
+			fmt.Printf("Unable to delete the new token '%s': %s\n", newToken.AccessKey(), deleteErr)
+		}
+		return err
+	}
+
+	// After terraform succeeds, commit state
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	newClient := rancher.NewClient(client.URL, newToken.AccessKey(), newToken.SecretKey())
+	err = newClient.DeleteToken(oldAccessKey)
+	if err != nil {
+		return fmt.Errorf("The new token is in use, but the old token '%s' couldn't be deleted: %s", oldAccessKey, err)
+	}
+
+	fmt.Printf("Rotated the Rancher API token of cluster manager '%s', token '%s' is no longer valid.\n", selectedClusterManager, oldAccessKey)
+
+	return nil
+}
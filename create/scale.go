@@ -0,0 +1,448 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// How long the nodes removed from a node pool may take to drain
+const nodePoolDrainTimeout = 10 * time.Minute
+
+// ScaleNodePool changes the number of instances of a node pool. When the provider supports
+// instance protection, the instances to remove are drained first while the others are
+// protected from scale in, so the provider removes exactly the drained instances.
+func ScaleNodePool(conf config.Config, remoteBackend backend.Backend, poolName string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterKey := ""
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
+		clusterKey, ok := clusters[clusterName]
+		if !ok {
+			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+
+		selectedClusterKey = clusterKey
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to scale a node pool of",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedClusterKey = clusters[value]
+	}
+
+	nodes, err := currentState.Nodes(selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	// Node pools backed by an instance group are a single node module
+	pools := map[string]string{}
+	for name, nodeKey := range nodes {
+		if _, ok := getNodePoolProvider(currentState, nodeKey); ok {
+			pools[name] = nodeKey
+		}
+	}
+
+	// The other node pools are a node module per node
+	nodeModulePools, err := getNodeModulePools(currentState, selectedClusterKey, nodes)
+	if err != nil {
+		return err
+	}
+
+	if len(pools)+len(nodeModulePools) == 0 {
+		return fmt.Errorf("No node pools.")
+	}
+
+	selectedPool := poolName
+	if selectedPool != "" {
+		// Name was given as an argument
+	} else if conf.IsSet("node_pool") {
+		selectedPool = conf.GetString("node_pool")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("node_pool must be specified"))
+	} else {
+		poolNames := make([]string, 0, len(pools)+len(nodeModulePools))
+		for name := range pools {
+			poolNames = append(poolNames, name)
+		}
+		for name := range nodeModulePools {
+			poolNames = append(poolNames, name)
+		}
+		sort.Strings(poolNames)
+		prompt := promptui.Select{
+			Label: "Node pool to scale",
+			Items: poolNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Node pool:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedPool = value
+	}
+
+	if _, ok := nodeModulePools[selectedPool]; ok {
+		return scaleNodeModulePool(conf, remoteBackend, currentState, selectedClusterKey, selectedPool, nodes)
+	}
+
+	nodeKey, ok := pools[selectedPool]
+	if !ok {
+		return fmt.Errorf("A node pool named '%s', does not exist.", selectedPool)
+	}
+	provider, _ := getNodePoolProvider(currentState, nodeKey)
+
+	capacityPath := fmt.Sprintf("module.%s.%s", nodeKey, provider.CapacityKey)
+	currentCapacity := currentState.GetInt(capacityPath)
+
+	capacity, err := getNodePoolCount(conf, currentCapacity)
+	if err != nil {
+		return err
+	}
+
+	if capacity == currentCapacity {
+		fmt.Printf("Node pool '%s' already has %d nodes.\n", selectedPool, capacity)
+		return nil
+	}
+
+	if provider.ValidateCapacity != nil {
+		err = provider.ValidateCapacity(currentState, nodeKey, capacity)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Refuse to break etcd quorum or remove the last control plane node. Every instance of
+	// the pool has the pool's roles.
+	if capacity < currentCapacity && !conf.GetBool("force") {
+		etcdNodes, err := currentState.NodesWithRole(selectedClusterKey, "etcd")
+		if err != nil {
+			return err
+		}
+		controlNodes, err := currentState.NodesWithRole(selectedClusterKey, "control")
+		if err != nil {
+			return err
+		}
+
+		etcdCount, etcdRemoved := len(etcdNodes), 0
+		if _, ok := etcdNodes[selectedPool]; ok {
+			etcdCount += currentCapacity - 1
+			etcdRemoved = currentCapacity - capacity
+		}
+		controlCount, controlRemoved := len(controlNodes), 0
+		if _, ok := controlNodes[selectedPool]; ok {
+			controlCount += currentCapacity - 1
+			controlRemoved = currentCapacity - capacity
+		}
+
+		operation := fmt.Sprintf("Scaling node pool '%s' to %d nodes", selectedPool, capacity)
+		err = util.CheckNodeRemoval(operation, etcdCount, controlCount, etcdRemoved, controlRemoved)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Refuse to grow the pool past the cluster's budget
+	err = setMonthlyBudget(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+	if _, ok := currentState.MonthlyBudget(selectedClusterKey); ok {
+		cost, err := estimateMonthlyCost(conf, currentState, selectedClusterKey)
+		if err != nil {
+			return err
+		}
+		price, err := getNodeMonthlyPrice(conf, currentState, nodeKey)
+		if err != nil {
+			return err
+		}
+		delta := price * float64(capacity-currentCapacity)
+		err = checkMonthlyBudget(conf, currentState, selectedClusterKey, cost+delta, delta)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Scale node pool '%s' from %d to %d nodes", selectedPool, currentCapacity, capacity)
+		selected := "Scale"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Scale canceled.")
+			return nil
+		}
+	}
+
+	// Choose and drain the instances to remove, while protecting the others from scale in
+	var client *rancher.Client
+	drainedNodes := []rancher.Node{}
+	protectedHostnames := []string{}
+	if capacity < currentCapacity && provider.ProtectFromScaleIn != nil {
+		hostnames, err := provider.Hostnames(conf, currentState, nodeKey)
+		if err != nil {
+			return err
+		}
+		sort.Strings(hostnames)
+
+		if capacity < len(hostnames) {
+			protectedHostnames = hostnames[:capacity]
+			removedHostnames := hostnames[capacity:]
+
+			err = provider.ProtectFromScaleIn(conf, currentState, nodeKey, protectedHostnames, true)
+			if err != nil {
+				return err
+			}
+
+			var rancherClusterID string
+			client, rancherClusterID, err = rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+			if err != nil {
+				return err
+			}
+
+			drainedNodes, err = drainNodePoolNodes(client, rancherClusterID, removedHostnames)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = currentState.Set(capacityPath, capacity)
+	if err != nil {
+		return err
+	}
+
+	// The new instances register with a new token of the pool, the previous one is deleted once
+	// the pool's template no longer has it
+	rotateToken := capacity > currentCapacity && useEphemeralRegistrationTokens(conf)
+	var tokenClient *rancher.Client
+	var rancherClusterID string
+	var registrationToken rancher.ClusterRegistrationToken
+	previousToken, ephemeralToken := nodeEphemeralRegistrationToken(currentState, nodeKey)
+	if rotateToken {
+		tokenClient, rancherClusterID, err = rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+		if err != nil {
+			return err
+		}
+
+		registrationToken, err = issueRegistrationToken(tokenClient, rancherClusterID, currentState, []string{nodeKey})
+		if err != nil {
+			return err
+		}
+	}
+
+	applyErr := shell.RunTerraformApplyWithState(conf, currentState, []string{fmt.Sprintf("-target=module.%s", nodeKey)})
+	if rotateToken {
+		applyErr = revokeUnappliedRegistrationTokens(tokenClient, rancherClusterID, []string{registrationToken.Token}, applyErr)
+	}
+
+	if len(protectedHostnames) > 0 {
+		err = provider.ProtectFromScaleIn(conf, currentState, nodeKey, protectedHostnames, false)
+		if err != nil && applyErr == nil {
+			return err
+		}
+	}
+
+	if applyErr != nil {
+		return applyErr
+	}
+
+	// After terraform succeeds, commit state
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range drainedNodes {
+		err = client.DeleteNode(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	if capacity < currentCapacity && provider.ProtectFromScaleIn == nil {
+		fmt.Println("Run `triton-kubernetes reconcile nodepools` once the removed instances are terminated to remove them from Rancher.")
+	}
+
+	if !rotateToken {
+		return nil
+	}
+	if !ephemeralToken {
+		// The pool registered with the cluster's token until now
+		return rotateClusterRegistrationToken(conf, remoteBackend, currentState, selectedClusterKey)
+	}
+	err = revokeRegistrationTokens(tokenClient, rancherClusterID, []string{previousToken})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted the previous registration token of node pool '%s'.\n", selectedPool)
+	return nil
+}
+
+// Returns the node_count to scale a node pool to, which the user is asked for when it isn't set.
+func getNodePoolCount(conf config.Config, currentCount int) (int, error) {
+	countInput := ""
+	if conf.IsSet("node_count") {
+		countInput = conf.GetString("node_count")
+	} else if conf.GetBool("non-interactive") {
+		return 0, util.ConfigError(errors.New("node_count must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Number of nodes",
+			Validate: func(input string) error {
+				num, err := strconv.ParseInt(input, 10, 64)
+				if err != nil {
+					return errors.New("Invalid number")
+				}
+				if num < 0 {
+					return errors.New("Number must not be negative")
+				}
+				return nil
+			},
+			Default: strconv.Itoa(currentCount),
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return 0, err
+		}
+		countInput = result
+	}
+
+	count, err := strconv.Atoi(countInput)
+	if err != nil {
+		return 0, fmt.Errorf("node_count must be a valid number. Found '%s'.", countInput)
+	}
+	if count < 0 {
+		return 0, fmt.Errorf("node_count must not be negative. Found '%d'.", count)
+	}
+
+	return count, nil
+}
+
+// Drains the Rancher nodes with the given hostnames and returns them.
+func drainNodePoolNodes(client *rancher.Client, rancherClusterID string, hostnames []string) ([]rancher.Node, error) {
+	rancherNodes, err := client.Nodes(rancherClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	toDrain := map[string]bool{}
+	for _, hostname := range hostnames {
+		toDrain[hostname] = true
+	}
+
+	drained := []rancher.Node{}
+	for _, node := range rancherNodes {
+		if !toDrain[node.Hostname] {
+			continue
+		}
+
+		fmt.Printf("Draining node %s.\n", node.Hostname)
+		err = client.DrainNode(node, nodePoolDrainTimeout)
+		if err != nil {
+			return nil, err
+		}
+		drained = append(drained, node)
+	}
+
+	return drained, nil
+}
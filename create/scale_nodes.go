@@ -0,0 +1,303 @@
+package create
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Returns the node pools of the cluster that are a node module per node. Nodes created before
+// node pools were stored in the state form a pool with their hostname prefix as well.
+func getNodeModulePools(currentState state.State, clusterKey string, nodes map[string]string) (map[string]state.NodePool, error) {
+	pools, err := currentState.NodePools(clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for hostname, nodeKey := range nodes {
+		if _, ok := getNodePoolProvider(currentState, nodeKey); ok {
+			continue
+		}
+
+		poolName := util.NodeHostnameSuffixRegexp.ReplaceAllString(hostname, "")
+		if _, ok := pools[poolName]; ok || poolName == hostname {
+			continue
+		}
+
+		pools[poolName] = state.NodePool{Count: len(getNodePoolMembers(nodes, poolName))}
+	}
+
+	return pools, nil
+}
+
+// Scales a node pool that is a node module per node. New nodes copy the settings of the pool's
+// last node, and the nodes with the highest numbers are drained and destroyed when the pool
+// shrinks.
+func scaleNodeModulePool(conf config.Config, remoteBackend backend.Backend, currentState state.State, clusterKey, poolName string, nodes map[string]string) error {
+	members := getNodePoolMembers(nodes, poolName)
+	currentCount := len(members)
+
+	count, err := getNodePoolCount(conf, currentCount)
+	if err != nil {
+		return err
+	}
+
+	if count == currentCount {
+		fmt.Printf("Node pool '%s' already has %d nodes.\n", poolName, count)
+		return nil
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("Node pool '%s' has no nodes to copy the settings of, create its nodes with `triton-kubernetes create node`.", poolName)
+	}
+	templateKey := nodes[members[len(members)-1]]
+
+	removedHostnames := []string{}
+	if count < currentCount {
+		removedHostnames = members[count:]
+	}
+
+	// Refuse to break etcd quorum or remove the last control plane node
+	if len(removedHostnames) > 0 && !conf.GetBool("force") {
+		etcdNodes, err := currentState.NodesWithRole(clusterKey, "etcd")
+		if err != nil {
+			return err
+		}
+		controlNodes, err := currentState.NodesWithRole(clusterKey, "control")
+		if err != nil {
+			return err
+		}
+
+		etcdRemoved, controlRemoved := 0, 0
+		for _, hostname := range removedHostnames {
+			if _, ok := etcdNodes[hostname]; ok {
+				etcdRemoved++
+			}
+			if _, ok := controlNodes[hostname]; ok {
+				controlRemoved++
+			}
+		}
+
+		operation := fmt.Sprintf("Scaling node pool '%s' to %d nodes", poolName, count)
+		err = util.CheckNodeRemoval(operation, len(etcdNodes), len(controlNodes), etcdRemoved, controlRemoved)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Refuse to grow the pool past the cluster's budget
+	err = setMonthlyBudget(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+	if _, ok := currentState.MonthlyBudget(clusterKey); ok {
+		cost, err := estimateMonthlyCost(conf, currentState, clusterKey)
+		if err != nil {
+			return err
+		}
+		price, err := getNodeMonthlyPrice(conf, currentState, templateKey)
+		if err != nil {
+			return err
+		}
+		delta := price * float64(count-currentCount)
+		err = checkMonthlyBudget(conf, currentState, clusterKey, cost+delta, delta)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Confirmation Prompt
+	if !conf.GetBool("non-interactive") {
+		label := fmt.Sprintf("Scale node pool '%s' from %d to %d nodes", poolName, currentCount, count)
+		if len(removedHostnames) > 0 {
+			label = fmt.Sprintf("%s, destroying %s", label, strings.Join(removedHostnames, ", "))
+		}
+		selected := "Scale"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Scale canceled.")
+			return nil
+		}
+	}
+
+	pool := state.NodePool{Count: count}
+
+	if len(removedHostnames) > 0 {
+		return removeNodePoolNodes(conf, remoteBackend, currentState, clusterKey, poolName, pool, removedHostnames, nodes)
+	}
+	return addNodePoolNodes(conf, remoteBackend, currentState, clusterKey, poolName, pool, templateKey, count-currentCount, nodes)
+}
+
+// Adds nodes with the pool settings of the template node module to the pool. The settings of
+// the template's machine aren't copied, the new nodes require their own.
+func addNodePoolNodes(conf config.Config, remoteBackend backend.Backend, currentState state.State, clusterKey, poolName string, pool state.NodePool, templateKey string, nodesToAdd int, nodes map[string]string) error {
+	existingNames := make([]string, 0, len(nodes))
+	for name := range nodes {
+		existingNames = append(existingNames, name)
+	}
+	newHostnames := getNewHostnames(existingNames, poolName, nodesToAdd)
+
+	machineInputs := getNodeMachineInputs(currentState, templateKey)
+	machineValues := make([][]string, len(machineInputs))
+	for i, input := range machineInputs {
+		values, err := input.Values(conf, newHostnames)
+		if err != nil {
+			return err
+		}
+		machineValues[i] = values
+	}
+
+	template := currentState.GetMap(fmt.Sprintf("module.%s", templateKey))
+	newNodeKeys := []string{}
+	for i, hostname := range newHostnames {
+		newNode := map[string]interface{}{}
+		for key, value := range template {
+			newNode[key] = value
+		}
+		newNode["hostname"] = hostname
+		for j, input := range machineInputs {
+			newNode[input.Variable] = machineValues[j][i]
+		}
+
+		err := currentState.AddNode(clusterKey, hostname, newNode)
+		if err != nil {
+			return err
+		}
+		newNodeKeys = append(newNodeKeys, strings.Replace(clusterKey, "cluster_", "node_", 1)+"_"+hostname)
+	}
+
+	err := currentState.SetNodePool(clusterKey, poolName, pool)
+	if err != nil {
+		return err
+	}
+
+	// Block on configurations that violate the user's policies
+	err = checkPolicies(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the new nodes will be able to register with the cluster manager
+	err = checkRancherConnectivity(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	// The registration token issued to the pool's nodes was deleted once they were active, so
+	// the new nodes get a token of their own, or the cluster's
+	ephemeralToken := useEphemeralRegistrationTokens(conf)
+	var client *rancher.Client
+	var rancherClusterID string
+	var registrationToken rancher.ClusterRegistrationToken
+	activeNodes := 0
+	if !ephemeralToken {
+		err = setClusterRegistrationToken(currentState, clusterKey, newNodeKeys)
+		if err != nil {
+			return err
+		}
+	} else {
+		client, rancherClusterID, err = rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+		if err != nil {
+			return err
+		}
+
+		activeNodes, err = countActiveNodes(client, rancherClusterID, newHostnames)
+		if err != nil {
+			return err
+		}
+
+		registrationToken, err = issueRegistrationToken(client, rancherClusterID, currentState, newNodeKeys)
+		if err != nil {
+			return err
+		}
+	}
+
+	targetArgs := []string{}
+	for _, nodeKey := range newNodeKeys {
+		targetArgs = append(targetArgs, fmt.Sprintf("-target=module.%s", nodeKey))
+	}
+	err = shell.RunTerraformApplyWithState(conf, currentState, targetArgs)
+	if err != nil {
+		if ephemeralToken {
+			err = revokeUnappliedRegistrationTokens(client, rancherClusterID, []string{registrationToken.Token}, err)
+		}
+		return recordNodeApplyFailure(conf, remoteBackend, currentState, clusterKey, newHostnames, err)
+	}
+
+	// After terraform succeeds, commit state
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	printNodesAddedMessage(newHostnames)
+
+	if ephemeralToken {
+		return revokeRegistrationTokensOnceActive(client, rancherClusterID, []string{registrationToken.Token}, activeNodes+len(newHostnames), newHostnames, getNodeRegistrationTimeout(conf))
+	}
+
+	return nil
+}
+
+// Drains and destroys the given nodes of the pool, then removes them from Rancher.
+func removeNodePoolNodes(conf config.Config, remoteBackend backend.Backend, currentState state.State, clusterKey, poolName string, pool state.NodePool, removedHostnames []string, nodes map[string]string) error {
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return err
+	}
+
+	drainedNodes, err := drainNodePoolNodes(client, rancherClusterID, removedHostnames)
+	if err != nil {
+		return err
+	}
+
+	targetArgs := []string{}
+	for _, hostname := range removedHostnames {
+		targetArgs = append(targetArgs, fmt.Sprintf("-target=module.%s", nodes[hostname]))
+	}
+
+	fmt.Printf("Destroying nodes %s.\n", strings.Join(removedHostnames, ", "))
+	err = shell.RunTerraformDestroyWithState(conf, currentState, targetArgs)
+	if err != nil {
+		return err
+	}
+
+	for _, hostname := range removedHostnames {
+		err = currentState.Delete(fmt.Sprintf("module.%s", nodes[hostname]))
+		if err != nil {
+			return err
+		}
+	}
+
+	if pool.Count == 0 {
+		currentState.DeleteNodePool(clusterKey, poolName)
+	} else {
+		err = currentState.SetNodePool(clusterKey, poolName, pool)
+		if err != nil {
+			return err
+		}
+	}
+
+	// After terraform succeeds, commit state
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range drainedNodes {
+		err = client.DeleteNode(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
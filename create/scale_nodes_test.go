@@ -0,0 +1,92 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestGetNodeModulePools(t *testing.T) {
+	currentState, err := state.New("ScaleState", []byte(`{
+		"locals":{"triton_kubernetes_node_pools":{"cluster_aws_dev":{"dev-e":{"count":3}}}},
+		"module":{
+			"node_aws_dev_dev-e-1":{"hostname":"dev-e-1","aws_instance_type":"t2.medium","rancher_host_labels":{"etcd":"true"}},
+			"node_aws_dev_dev-w-1":{"hostname":"dev-w-1","aws_instance_type":"t2.large","rancher_host_labels":{"worker":"true"}},
+			"node_aws_dev_dev-w-3":{"hostname":"dev-w-3","aws_instance_type":"t2.large","rancher_host_labels":{"worker":"true"}},
+			"node_aws_dev_bastion":{"hostname":"bastion"},
+			"node_aws_dev_dev-asg":{"hostname":"dev-asg","source":"github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher-k8s-asg"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := map[string]string{
+		"dev-e-1": "node_aws_dev_dev-e-1",
+		"dev-w-1": "node_aws_dev_dev-w-1",
+		"dev-w-3": "node_aws_dev_dev-w-3",
+		"bastion": "node_aws_dev_bastion",
+		"dev-asg": "node_aws_dev_dev-asg",
+	}
+
+	pools, err := getNodeModulePools(currentState, "cluster_aws_dev", nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]state.NodePool{
+		"dev-e": {Count: 3},
+		"dev-w": {Count: 2},
+	}
+	if len(pools) != len(expected) {
+		t.Fatalf("Expected pools %v, received %v", expected, pools)
+	}
+	for name, pool := range expected {
+		if pools[name] != pool {
+			t.Errorf("Expected pool '%s' to be %v, received %v", name, pool, pools[name])
+		}
+	}
+}
+
+func TestGetNodePoolMembers(t *testing.T) {
+	nodes := map[string]string{
+		"dev-w-10": "node_aws_dev_dev-w-10",
+		"dev-w-2":  "node_aws_dev_dev-w-2",
+		"dev-w-1":  "node_aws_dev_dev-w-1",
+		"dev-w":    "node_aws_dev_dev-w",
+		"dev-e-1":  "node_aws_dev_dev-e-1",
+	}
+
+	members := getNodePoolMembers(nodes, "dev-w")
+	expected := []string{"dev-w-1", "dev-w-2", "dev-w-10"}
+	if len(members) != len(expected) {
+		t.Fatalf("Expected members %v, received %v", expected, members)
+	}
+	for i := range expected {
+		if members[i] != expected[i] {
+			t.Errorf("Expected members %v, received %v", expected, members)
+		}
+	}
+}
+
+func TestGetNodeMachineInputs(t *testing.T) {
+	currentState, err := state.New("ScaleState", []byte(`{
+		"module":{
+			"node_bare-metal_dev_dev-w-1":{"hostname":"dev-w-1","host":"10.0.0.1","source":"github.com/joyent/triton-kubernetes//terraform/modules/bare-metal-rancher-k8s-host"},
+			"node_aws_dev_dev-w-1":{"hostname":"dev-w-1","source":"github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher-k8s-host"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := getNodeMachineInputs(currentState, "node_bare-metal_dev_dev-w-1")
+	if len(inputs) != 1 || inputs[0].Variable != "host" {
+		t.Errorf("Expected the host of bare metal nodes to be a machine input, received %v", inputs)
+	}
+
+	inputs = getNodeMachineInputs(currentState, "node_aws_dev_dev-w-1")
+	if len(inputs) != 0 {
+		t.Errorf("Expected AWS nodes to have no machine inputs, received %v", inputs)
+	}
+}
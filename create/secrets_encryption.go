@@ -0,0 +1,233 @@
+package create
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Secrets encryption providers of the API server. aescbc and secretbox encrypt with a local key
+// written to the control nodes, kms asks a KMS plugin running on each control node to encrypt
+// with a key kept by a cloud KMS.
+var secretsEncryptionProviders = []string{"aescbc", "secretbox", "kms"}
+
+// The KMS plugin listens on this socket, /var/run/kmsplugin is mounted into the API server container
+const kmsPluginEndpoint = "unix:///var/run/kmsplugin/socket.sock"
+
+// KMS providers are supported by the API server from Kubernetes v1.10
+const minKMSKubernetesMinorVersion = 10
+
+// Asks whether the API server encrypts secrets in etcd, and with which provider. The encryption
+// config is written to the cluster's control nodes.
+func getKubernetesSecretsEncryptionConfig(conf config.Config, cfg *baseClusterTerraformConfig) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	provider := "none"
+	if conf.IsSet("k8s_secrets_encryption") {
+		provider = conf.GetString("k8s_secrets_encryption")
+	} else if !nonInteractiveMode {
+		prompt := promptui.Select{
+			Label: "Encrypt Kubernetes secrets at rest",
+			Items: append([]string{"none"}, secretsEncryptionProviders...),
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: "  {{ . }}",
+				Selected: "  Secrets Encryption: {{ . }}",
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		provider = value
+	}
+
+	if provider == "none" {
+		return nil
+	}
+
+	var providerConfig string
+	switch provider {
+	case "aescbc", "secretbox":
+		key, err := getSecretsEncryptionKey(conf)
+		if err != nil {
+			return err
+		}
+		providerConfig = fmt.Sprintf("  - %s:\n      keys:\n      - name: key1\n        secret: %s\n", provider, key)
+	case "kms":
+		minorVersion, err := getKubernetesMinorVersion(cfg.KubernetesVersion)
+		if err != nil {
+			return err
+		}
+		if minorVersion < minKMSKubernetesMinorVersion {
+			return fmt.Errorf("KMS secrets encryption requires Kubernetes v1.%d or later, got %s", minKMSKubernetesMinorVersion, cfg.KubernetesVersion)
+		}
+
+		// KMS Plugin Image
+		if conf.IsSet("k8s_kms_plugin_image") {
+			cfg.KubernetesKMSPluginImage = conf.GetString("k8s_kms_plugin_image")
+		} else if nonInteractiveMode {
+			return util.ConfigError(errors.New("k8s_kms_plugin_image must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label: "KMS Plugin Image",
+				Validate: func(input string) error {
+					if input == "" {
+						return errors.New("Invalid KMS plugin image")
+					}
+					return nil
+				},
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return err
+			}
+			cfg.KubernetesKMSPluginImage = result
+		}
+
+		// KMS Plugin Arguments, e.g. the key of the cloud KMS
+		if conf.IsSet("k8s_kms_plugin_args") {
+			cfg.KubernetesKMSPluginArgs = conf.GetString("k8s_kms_plugin_args")
+		} else if !nonInteractiveMode {
+			prompt := promptui.Prompt{
+				Label: "KMS Plugin Arguments (e.g. the key to encrypt with)",
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return err
+			}
+			cfg.KubernetesKMSPluginArgs = result
+		}
+
+		providerConfig = fmt.Sprintf("  - kms:\n      name: kms-plugin\n      endpoint: %s\n      cachesize: 1000\n", kmsPluginEndpoint)
+	default:
+		return util.ConfigError(fmt.Errorf("Invalid k8s_secrets_encryption '%s', must be 'none', 'aescbc', 'secretbox' or 'kms'", provider))
+	}
+
+	encryptionConfig, err := getSecretsEncryptionConfigYAML(cfg.KubernetesVersion, providerConfig)
+	if err != nil {
+		return err
+	}
+
+	cfg.KubernetesSecretsEncryption = provider
+	cfg.KubernetesSecretsEncryptionConfig = base64.StdEncoding.EncodeToString([]byte(encryptionConfig))
+
+	return nil
+}
+
+// Returns the key of the aescbc and secretbox providers, a base64 encoded 32 byte key. A new key
+// is generated unless one is given.
+func getSecretsEncryptionKey(conf config.Config) (string, error) {
+	if conf.IsSet("k8s_secrets_encryption_key") {
+		key := conf.GetString("k8s_secrets_encryption_key")
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(decoded) != 32 {
+			return "", errors.New("k8s_secrets_encryption_key must be a base64 encoded 32 byte key")
+		}
+		return key, nil
+	}
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Returns the encryption config of the API server. Secrets written before encryption was enabled
+// stay readable through the identity provider.
+func getSecretsEncryptionConfigYAML(kubernetesVersion, providerConfig string) (string, error) {
+	minorVersion, err := getKubernetesMinorVersion(kubernetesVersion)
+	if err != nil {
+		return "", err
+	}
+
+	// The config became apiserver.config.k8s.io/v1 in Kubernetes v1.13
+	header := "kind: EncryptionConfig\napiVersion: v1\n"
+	if minorVersion >= 13 {
+		header = "kind: EncryptionConfiguration\napiVersion: apiserver.config.k8s.io/v1\n"
+	}
+
+	return header + "resources:\n- resources:\n  - secrets\n  providers:\n" + providerConfig + "  - identity: {}\n", nil
+}
+
+// Returns the minor version of the given Kubernetes version, e.g. 10 for v1.10.0-rancher1-1.
+func getKubernetesMinorVersion(kubernetesVersion string) (int, error) {
+	minorVersion := kubernetesMinorVersionRegexp.FindString(kubernetesVersion)
+	if minorVersion == "" {
+		return 0, util.ConfigError(fmt.Errorf("Invalid Kubernetes version '%s'", kubernetesVersion))
+	}
+
+	return strconv.Atoi(minorVersion[strings.Index(minorVersion, ".")+1:])
+}
+
+// Control nodes of clusters encrypting secrets at rest are logged into over SSH, the encryption
+// config is copied to them instead of being rendered into their user data. Returns the expanded
+// path of the key file at the given setting, e.g. the private key to log in with.
+func getNodeSSHKeyPath(conf config.Config, key, label, defaultPath string) (string, error) {
+	rawKeyPath := ""
+	if conf.IsSet(key) {
+		rawKeyPath = conf.GetString(key)
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(fmt.Errorf("%s must be specified", key))
+	} else {
+		prompt := promptui.Prompt{
+			Label: label,
+			Validate: func(input string) error {
+				expandedPath, err := homedir.Expand(input)
+				if err != nil {
+					return err
+				}
+
+				_, err = os.Stat(expandedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return errors.New("File not found")
+					}
+				}
+				return nil
+			},
+			Default: defaultPath,
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		rawKeyPath = result
+	}
+
+	return homedir.Expand(rawKeyPath)
+}
+
+// Returns the SSH user control nodes are logged into with, see getNodeSSHKeyPath.
+func getNodeSSHUser(conf config.Config, key, label, defaultUser string) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: defaultUser,
+	}
+
+	return prompt.Run()
+}
@@ -0,0 +1,125 @@
+package create
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestGetKubernetesSecretsEncryptionConfig(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("k8s_secrets_encryption", "aescbc")
+	conf.Set("k8s_secrets_encryption_key", key)
+
+	cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.10.0-rancher1-1"}
+	err := getKubernetesSecretsEncryptionConfig(conf, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.KubernetesSecretsEncryption != "aescbc" {
+		t.Errorf("Expected the aescbc provider, got '%s'", cfg.KubernetesSecretsEncryption)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(cfg.KubernetesSecretsEncryptionConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptionConfig := string(decoded)
+	if !strings.HasPrefix(encryptionConfig, "kind: EncryptionConfig\napiVersion: v1\n") {
+		t.Errorf("Expected the v1 EncryptionConfig of Kubernetes v1.10, got %s", encryptionConfig)
+	}
+	if !strings.Contains(encryptionConfig, "  - aescbc:\n") || !strings.Contains(encryptionConfig, "secret: "+key) {
+		t.Errorf("Expected the aescbc provider with the given key, got %s", encryptionConfig)
+	}
+	if !strings.HasSuffix(encryptionConfig, "  - identity: {}\n") {
+		t.Errorf("Expected the identity provider last, got %s", encryptionConfig)
+	}
+}
+
+func TestGetKubernetesSecretsEncryptionConfigKMS(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("k8s_secrets_encryption", "kms")
+
+	cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.10.0-rancher1-1"}
+	if err := getKubernetesSecretsEncryptionConfig(conf, &cfg); err == nil {
+		t.Error("Expected an error without k8s_kms_plugin_image")
+	}
+
+	conf.Set("k8s_kms_plugin_image", "example/kms-plugin:1.0")
+	conf.Set("k8s_kms_plugin_args", "--key-uri=projects/p/locations/global/keyRings/r/cryptoKeys/k")
+	err := getKubernetesSecretsEncryptionConfig(conf, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.KubernetesKMSPluginImage != "example/kms-plugin:1.0" || cfg.KubernetesKMSPluginArgs == "" {
+		t.Errorf("Expected the KMS plugin settings, got %+v", cfg)
+	}
+
+	decoded, _ := base64.StdEncoding.DecodeString(cfg.KubernetesSecretsEncryptionConfig)
+	if !strings.Contains(string(decoded), "endpoint: "+kmsPluginEndpoint) {
+		t.Errorf("Expected the endpoint of the KMS plugin, got %s", decoded)
+	}
+
+	// The API server of v1.9 has no KMS provider
+	cfg = baseClusterTerraformConfig{KubernetesVersion: "v1.9.5-rancher1-1"}
+	if err := getKubernetesSecretsEncryptionConfig(conf, &cfg); err == nil {
+		t.Error("Expected an error for Kubernetes v1.9")
+	}
+}
+
+func TestGetKubernetesSecretsEncryptionConfigNone(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+
+	cfg := baseClusterTerraformConfig{KubernetesVersion: "v1.10.0-rancher1-1"}
+	err := getKubernetesSecretsEncryptionConfig(conf, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.KubernetesSecretsEncryption != "" || cfg.KubernetesSecretsEncryptionConfig != "" {
+		t.Errorf("Expected secrets to be left unencrypted by default, got %+v", cfg)
+	}
+
+	conf.Set("k8s_secrets_encryption", "aesgcm")
+	if err := getKubernetesSecretsEncryptionConfig(conf, &cfg); err == nil {
+		t.Error("Expected an error for an unsupported provider")
+	}
+}
+
+func TestGetSecretsEncryptionKey(t *testing.T) {
+	conf := config.New()
+
+	key, err := getSecretsEncryptionKey(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 32 {
+		t.Errorf("Expected a generated 32 byte key, got '%s'", key)
+	}
+
+	conf.Set("k8s_secrets_encryption_key", base64.StdEncoding.EncodeToString([]byte("too short")))
+	if _, err := getSecretsEncryptionKey(conf); err == nil {
+		t.Error("Expected an error for a key that isn't 32 bytes")
+	}
+}
+
+func TestGetSecretsEncryptionConfigYAML(t *testing.T) {
+	encryptionConfig, err := getSecretsEncryptionConfigYAML("v1.13.4", "  - secretbox: {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encryptionConfig, "kind: EncryptionConfiguration\napiVersion: apiserver.config.k8s.io/v1\n") {
+		t.Errorf("Expected the apiserver.config.k8s.io/v1 EncryptionConfiguration of Kubernetes v1.13, got %s", encryptionConfig)
+	}
+
+	if _, err := getSecretsEncryptionConfigYAML("latest", ""); err == nil {
+		t.Error("Expected an error for an invalid Kubernetes version")
+	}
+}
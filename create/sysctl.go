@@ -0,0 +1,35 @@
+package create
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+var sysctlNameRegexp = regexp.MustCompile(`^[a-z0-9_\-]+(\.[a-zA-Z0-9_\-]+)+$`)
+
+// Sysctls set by the host modules on every node. Overriding them breaks pod networking.
+var requiredSysctls = map[string]string{
+	"net.bridge.bridge-nf-call-iptables":  "1",
+	"net.bridge.bridge-nf-call-ip6tables": "1",
+	"net.ipv4.ip_forward":                 "1",
+}
+
+// Verifies the extra sysctls of the sysctls setting can be written to a sysctl.d file.
+func validateSysctls(sysctls map[string]string) error {
+	for name, value := range sysctls {
+		if !sysctlNameRegexp.MatchString(name) {
+			return util.ConfigError(fmt.Errorf("Invalid sysctl name '%s', must be a dotted name e.g. vm.max_map_count", name))
+		}
+		if value == "" || strings.ContainsAny(value, "\n\"$`\\") {
+			return util.ConfigError(fmt.Errorf("Invalid value '%s' for sysctl '%s'", value, name))
+		}
+		if required, ok := requiredSysctls[name]; ok && value != required {
+			return fmt.Errorf("sysctl '%s' is required by Kubernetes and must be %s", name, required)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package create
+
+import "testing"
+
+var validateSysctlsTestCases = []struct {
+	Sysctls     map[string]string
+	ExpectError bool
+}{
+	{map[string]string{}, false},
+	{map[string]string{"vm.max_map_count": "262144", "net.ipv4.ip_local_port_range": "32768 60999"}, false},
+	{map[string]string{"net.ipv4.ip_forward": "1"}, false},
+	{map[string]string{"net.ipv4.ip_forward": "0"}, true},
+	{map[string]string{"swappiness": "0"}, true},
+	{map[string]string{"vm.max_map_count": ""}, true},
+	{map[string]string{"vm.max_map_count": "1\nkernel.panic = 1"}, true},
+	{map[string]string{"vm.max_map_count": "$(reboot)"}, true},
+}
+
+func TestValidateSysctls(t *testing.T) {
+	for _, tc := range validateSysctlsTestCases {
+		err := validateSysctls(tc.Sysctls)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for %v", tc.Sysctls)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("Unexpected error for %v: %v", tc.Sysctls, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package create
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/terraform"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// The variables of a terraform variables file, e.g. bringing over the settings of a hand-rolled
+// terraform setup of the same modules.
+type tfVars struct {
+	conf      config.Config
+	path      string
+	variables map[string]interface{}
+	// Names of the variables loaded into conf, which weren't set in it
+	loaded []string
+}
+
+// Loads the variables of the terraform variables file given by the setting into conf, so they
+// answer the prompts for the settings of the same names and replace their defaults. Settings
+// given explicitly keep their value.
+func loadTFVarsFile(conf config.Config, setting string) (*tfVars, error) {
+	vars := &tfVars{conf: conf, path: conf.GetString(setting)}
+	if vars.path == "" {
+		return vars, nil
+	}
+
+	variables, err := config.LoadTFVars(vars.path)
+	if err != nil {
+		return nil, err
+	}
+	vars.variables = variables
+
+	ignored := []string{}
+	for name, value := range variables {
+		if conf.IsSet(name) {
+			ignored = append(ignored, name)
+			continue
+		}
+		conf.Set(name, value)
+		vars.loaded = append(vars.loaded, name)
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		fmt.Printf("Ignoring %s from '%s', set in the configuration.\n", strings.Join(ignored, ", "), vars.path)
+	}
+
+	return vars, nil
+}
+
+// Adds the loaded variables the generated configs of the modules don't set yet to them, after
+// checking that the modules declare every variable of the file.
+func (vars *tfVars) merge(currentState state.State, moduleKeys []string) error {
+	if vars.path == "" {
+		return nil
+	}
+
+	loaded := map[string]interface{}{}
+	for _, name := range vars.loaded {
+		loaded[name] = vars.variables[name]
+	}
+
+	for _, moduleKey := range moduleKeys {
+		source, err := currentState.ModuleSource(moduleKey)
+		if err != nil {
+			return err
+		}
+		declared, err := terraform.ModuleVariables(source)
+		if err != nil {
+			return err
+		}
+
+		undeclared := []string{}
+		for name := range vars.variables {
+			index := sort.SearchStrings(declared, name)
+			if index == len(declared) || declared[index] != name {
+				undeclared = append(undeclared, name)
+			}
+		}
+		if len(undeclared) > 0 {
+			sort.Strings(undeclared)
+			return util.ConfigError(fmt.Errorf("'%s' sets %s, which module %s doesn't declare.", vars.path, strings.Join(undeclared, ", "), moduleKey))
+		}
+
+		// The variables the prompts read were turned into the values of the generated config
+		_, err = currentState.MergeModuleVariables(moduleKey, loaded)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Removes the loaded variables from conf, so they don't answer the prompts of other modules.
+func (vars *tfVars) restore() {
+	for _, name := range vars.loaded {
+		vars.conf.Set(name, nil)
+	}
+	vars.loaded = nil
+}
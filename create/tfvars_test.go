@@ -0,0 +1,100 @@
+package create
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func writeTestTFVars(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "manager.tfvars")
+	err = ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTFVarsFile(t *testing.T) {
+	path := writeTestTFVars(t, `
+triton_account = "from-file"
+triton_image_name = "ubuntu-certified-18.04"
+docker_engine_install_url = "https://releases.rancher.com/install-docker/17.03.sh"
+`)
+
+	conf := config.New()
+	conf.Set("tfvars_file", path)
+	conf.Set("triton_account", "explicit")
+
+	tfVars, err := loadTFVarsFile(conf, "tfvars_file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The variables answer the prompts before the module is generated
+	if conf.GetString("triton_account") != "explicit" {
+		t.Errorf("Expected the explicit setting to be kept, got %s", conf.GetString("triton_account"))
+	}
+	if conf.GetString("triton_image_name") != "ubuntu-certified-18.04" {
+		t.Errorf("Expected the variable to be loaded, got %s", conf.GetString("triton_image_name"))
+	}
+
+	currentState, err := state.New("dev", []byte(`{"module": {"cluster-manager": {
+		"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher?ref=master",
+		"triton_account": "explicit",
+		"triton_image_name": "ubuntu-certified-18.04"
+	}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tfVars.merge(currentState, []string{"cluster-manager"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := currentState.Get("module.cluster-manager.triton_account"); value != "explicit" {
+		t.Errorf("Expected the explicit setting to be kept, got %s", value)
+	}
+	if value := currentState.Get("module.cluster-manager.docker_engine_install_url"); value != "https://releases.rancher.com/install-docker/17.03.sh" {
+		t.Errorf("Expected the variable without a setting to be added, got %s", value)
+	}
+
+	tfVars.restore()
+	if conf.IsSet("triton_image_name") || conf.GetString("triton_account") != "explicit" {
+		t.Errorf("Expected only the loaded variables to be removed, got %v", conf.AllKeys())
+	}
+}
+
+func TestTFVarsFileUndeclaredVariable(t *testing.T) {
+	path := writeTestTFVars(t, `
+triton_account = "from-file"
+triton_acount = "typo"
+`)
+
+	conf := config.New()
+	conf.Set("tfvars_file", path)
+	tfVars, err := loadTFVarsFile(conf, "tfvars_file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentState, err := state.New("dev", []byte(`{"module": {"cluster-manager": {
+		"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher?ref=master"
+	}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tfVars.merge(currentState, []string{"cluster-manager"})
+	if err == nil || !strings.Contains(err.Error(), "triton_acount") || strings.Contains(err.Error(), "triton_account,") {
+		t.Errorf("Expected only the undeclared variable to be rejected, got %v", err)
+	}
+}
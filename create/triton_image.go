@@ -0,0 +1,63 @@
+package create
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-go/compute"
+)
+
+// Image types that Triton provisions as hardware virtual machines, which need a KVM or bhyve
+// machine package.
+var tritonHardwareVirtualMachineImageTypes = map[string]bool{
+	"zvol": true,
+}
+
+// Verifies an image can be provisioned with a machine package. Triton only reports an
+// incompatible combination once the machine fails to provision, after terraform has started.
+//
+// Images can require a minimum and maximum amount of memory, hardware virtual machine images
+// need a package with vCPUs.
+func validateTritonImagePackage(image compute.Image, pkg compute.Package) error {
+	imageName := fmt.Sprintf("%s@%s", image.Name, image.Version)
+
+	if minRAM, ok := getTritonImageRequirement(image, "min_ram"); ok && pkg.Memory < minRAM {
+		return fmt.Errorf("Triton image '%s' requires at least %d MB of memory, machine package '%s' has %d MB", imageName, minRAM, pkg.Name, pkg.Memory)
+	}
+	if maxRAM, ok := getTritonImageRequirement(image, "max_ram"); ok && pkg.Memory > maxRAM {
+		return fmt.Errorf("Triton image '%s' supports at most %d MB of memory, machine package '%s' has %d MB", imageName, maxRAM, pkg.Name, pkg.Memory)
+	}
+
+	hardwareVirtualMachine := tritonHardwareVirtualMachineImageTypes[image.Type]
+	if brand, ok := image.Requirements["brand"].(string); ok && (brand == "kvm" || brand == "bhyve") {
+		hardwareVirtualMachine = true
+	}
+	if hardwareVirtualMachine && pkg.VCPUs == 0 {
+		return fmt.Errorf("Triton image '%s' is a hardware virtual machine image and requires a KVM machine package, '%s' is not one", imageName, pkg.Name)
+	}
+
+	return nil
+}
+
+// Returns a numeric requirement of an image. Requirements decoded from the API are float64.
+func getTritonImageRequirement(image compute.Image, key string) (int64, bool) {
+	switch value := image.Requirements[key].(type) {
+	case float64:
+		return int64(value), true
+	case int:
+		return int64(value), true
+	case int64:
+		return value, true
+	}
+	return 0, false
+}
+
+// Returns the packages the image can be provisioned with.
+func getCompatibleTritonPackages(image compute.Image, packages []*compute.Package) []*compute.Package {
+	compatible := []*compute.Package{}
+	for _, pkg := range packages {
+		if validateTritonImagePackage(image, *pkg) == nil {
+			compatible = append(compatible, pkg)
+		}
+	}
+	return compatible
+}
@@ -0,0 +1,47 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-go/compute"
+)
+
+var ubuntuKVMImage = compute.Image{Name: "ubuntu-certified-16.04", Version: "20180222", Type: "zvol", Requirements: map[string]interface{}{"min_ram": float64(1024)}}
+var ubuntuLXImage = compute.Image{Name: "ubuntu-16.04", Version: "20170403", Type: "lx-dataset", Requirements: map[string]interface{}{"brand": "lx", "max_ram": float64(2048)}}
+var bhyveImage = compute.Image{Name: "ubuntu-bhyve", Version: "20180222", Type: "lx-dataset", Requirements: map[string]interface{}{"brand": "bhyve"}}
+var smallKVMPackage = compute.Package{Name: "k4-highcpu-kvm-750M", Memory: 768, VCPUs: 1}
+
+var validateTritonImagePackageTestCases = []struct {
+	Image       compute.Image
+	Package     compute.Package
+	ExpectError bool
+}{
+	{ubuntuKVMImage, kvmPackage, false},
+	{ubuntuKVMImage, smallKVMPackage, true},
+	{ubuntuKVMImage, joyentPackage, true},
+	{ubuntuLXImage, joyentPackage, false},
+	{ubuntuLXImage, compute.Package{Name: "g4-highcpu-4G", Memory: 4096}, true},
+	{bhyveImage, joyentPackage, true},
+	{bhyveImage, kvmPackage, false},
+}
+
+func TestValidateTritonImagePackage(t *testing.T) {
+	for _, tc := range validateTritonImagePackageTestCases {
+		err := validateTritonImagePackage(tc.Image, tc.Package)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for (%q, %q)", tc.Image.Name, tc.Package.Name)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("Unexpected error for (%q, %q): %v", tc.Image.Name, tc.Package.Name, err)
+		}
+	}
+}
+
+func TestGetCompatibleTritonPackages(t *testing.T) {
+	packages := []*compute.Package{&joyentPackage, &smallKVMPackage, &kvmPackage}
+
+	compatible := getCompatibleTritonPackages(ubuntuKVMImage, packages)
+	if len(compatible) != 1 || compatible[0].Name != kvmPackage.Name {
+		t.Errorf("Wrong output, expected [%s], received %v", kvmPackage.Name, compatible)
+	}
+}
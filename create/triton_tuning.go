@@ -0,0 +1,92 @@
+package create
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/joyent/triton-go/compute"
+)
+
+// Size of the hugepages reserved on Triton nodes, the x86 default.
+const tritonHugepageSizeMB = 2
+
+// Triton doesn't let machines pin their vCPUs or back their memory with hugepages on the
+// hypervisor, so the tuning is done in the guest of KVM nodes: triton_hugepages 2 MiB pages are
+// reserved with vm.nr_hugepages, and triton_isolated_cpus are removed from the kernel scheduler
+// with isolcpus, leaving them to pods pinned by the kubelet CPU manager.
+//
+// Verifies the tuning fits the given machine package. Only KVM packages have vCPUs, at least one
+// of which has to stay with the system, and at most half of the memory can be hugepages.
+func validateTritonNodeTuning(hugepages int, isolatedCPUs string, pkg compute.Package) error {
+	if hugepages == 0 && isolatedCPUs == "" {
+		return nil
+	}
+
+	if pkg.VCPUs == 0 {
+		return fmt.Errorf("triton_hugepages and triton_isolated_cpus require a KVM machine package, '%s' is not one", pkg.Name)
+	}
+
+	if hugepages < 0 {
+		return util.ConfigError(fmt.Errorf("Invalid triton_hugepages '%d', must not be negative", hugepages))
+	}
+	if int64(hugepages*tritonHugepageSizeMB) > pkg.Memory/2 {
+		return fmt.Errorf("triton_hugepages '%d' reserves %d MB, more than half of the %d MB of machine package '%s'", hugepages, hugepages*tritonHugepageSizeMB, pkg.Memory, pkg.Name)
+	}
+
+	if isolatedCPUs == "" {
+		return nil
+	}
+
+	cpus, err := parseCPUList(isolatedCPUs)
+	if err != nil {
+		return err
+	}
+	for _, cpu := range cpus {
+		if int64(cpu) >= pkg.VCPUs {
+			return util.ConfigError(fmt.Errorf("Invalid triton_isolated_cpus '%s', machine package '%s' only has CPUs 0-%d", isolatedCPUs, pkg.Name, pkg.VCPUs-1))
+		}
+	}
+	if int64(len(cpus)) >= pkg.VCPUs {
+		return util.ConfigError(fmt.Errorf("Invalid triton_isolated_cpus '%s', at least one CPU of machine package '%s' must not be isolated", isolatedCPUs, pkg.Name))
+	}
+
+	return nil
+}
+
+// Returns the distinct CPUs of a list in the kernel's format, e.g. 2-3,6.
+func parseCPUList(list string) ([]int, error) {
+	invalid := util.ConfigError(fmt.Errorf("Invalid triton_isolated_cpus '%s', must be a list of CPUs and CPU ranges e.g. 2-3,6", list))
+
+	seen := map[int]struct{}{}
+	cpus := []int{}
+	for _, part := range strings.Split(list, ",") {
+		bounds := strings.Split(strings.TrimSpace(part), "-")
+		if len(bounds) > 2 {
+			return nil, invalid
+		}
+
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, invalid
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, invalid
+			}
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			if _, ok := seen[cpu]; !ok {
+				seen[cpu] = struct{}{}
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+
+	return cpus, nil
+}
@@ -0,0 +1,57 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-go/compute"
+)
+
+var kvmPackage = compute.Package{Name: "k4-highcpu-kvm-3.75G", Memory: 3840, VCPUs: 2}
+var joyentPackage = compute.Package{Name: "g4-highcpu-1G", Memory: 1024}
+
+var validateTritonNodeTuningTestCases = []struct {
+	Hugepages    int
+	IsolatedCPUs string
+	Package      compute.Package
+	ExpectError  bool
+}{
+	{0, "", joyentPackage, false},
+	{512, "1", kvmPackage, false},
+	{960, "", kvmPackage, false},
+	{961, "", kvmPackage, true},
+	{-1, "", kvmPackage, true},
+	{128, "", joyentPackage, true},
+	{0, "0-1", kvmPackage, true},
+	{0, "2", kvmPackage, true},
+	{0, "1,a", kvmPackage, true},
+	{0, "1-0", kvmPackage, true},
+}
+
+func TestValidateTritonNodeTuning(t *testing.T) {
+	for _, tc := range validateTritonNodeTuningTestCases {
+		err := validateTritonNodeTuning(tc.Hugepages, tc.IsolatedCPUs, tc.Package)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for (%d, %q, %q)", tc.Hugepages, tc.IsolatedCPUs, tc.Package.Name)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("Unexpected error for (%d, %q, %q): %v", tc.Hugepages, tc.IsolatedCPUs, tc.Package.Name, err)
+		}
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("2-4, 6,3")
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := []int{2, 3, 4, 6}
+	if len(cpus) != len(expected) {
+		t.Fatalf("Wrong output, expected %v, received %v", expected, cpus)
+	}
+	for i := range expected {
+		if cpus[i] != expected[i] {
+			t.Errorf("Wrong output, expected %v, received %v", expected, cpus)
+		}
+	}
+}
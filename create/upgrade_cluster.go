@@ -0,0 +1,267 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	// How long a cluster may take to upgrade, in minutes
+	defaultClusterUpgradeTimeout = 60
+
+	// Worker nodes are upgraded a few at a time, control plane nodes one at a time
+	defaultUpgradeMaxUnavailableWorker       = "10%"
+	defaultUpgradeMaxUnavailableControlplane = "1"
+)
+
+// UpgradeCluster upgrades a cluster to one of the newer Kubernetes versions the cluster manager
+// supports. Rancher rolls the version out to the nodes a few at a time, draining each worker
+// node first, and the new version is stored in the cluster's module once the cluster is
+// active again, so the cluster doesn't have to be recreated.
+func UpgradeCluster(conf config.Config, remoteBackend backend.Backend, clusterName string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterName := clusterName
+	if selectedClusterName != "" {
+		// Name was given as an argument
+	} else if conf.IsSet("cluster_name") {
+		selectedClusterName = conf.GetString("cluster_name")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to upgrade",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedClusterName = value
+	}
+
+	selectedClusterKey, ok := clusters[selectedClusterName]
+	if !ok {
+		return fmt.Errorf("A cluster named '%s', does not exist.", selectedClusterName)
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := client.Cluster(rancherClusterID)
+	if err != nil {
+		return err
+	}
+	currentVersion := currentState.Get(fmt.Sprintf("module.%s.k8s_version", selectedClusterKey))
+	if cluster.RKEConfig != nil && cluster.RKEConfig.KubernetesVersion != "" {
+		currentVersion = cluster.RKEConfig.KubernetesVersion
+	}
+
+	availableVersions, err := client.KubernetesVersions()
+	if err != nil {
+		return err
+	}
+	newerVersions := getNewerKubernetesVersions(availableVersions, currentVersion)
+
+	selectedVersion := ""
+	if conf.IsSet("k8s_version") {
+		selectedVersion = conf.GetString("k8s_version")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("k8s_version must be specified"))
+	} else {
+		if len(newerVersions) == 0 {
+			fmt.Printf("Cluster '%s' runs %s, the newest version the cluster manager supports.\n", selectedClusterName, currentVersion)
+			return nil
+		}
+
+		prompt := promptui.Select{
+			Label: fmt.Sprintf("Kubernetes version to upgrade from %s to", currentVersion),
+			Items: newerVersions,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Kubernetes Version:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedVersion = value
+	}
+
+	if selectedVersion == currentVersion {
+		fmt.Printf("Cluster '%s' already runs %s.\n", selectedClusterName, currentVersion)
+		return nil
+	}
+	found = false
+	for _, version := range newerVersions {
+		if version == selectedVersion {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Kubernetes version '%s' isn't a version newer than %s the cluster manager supports.", selectedVersion, currentVersion)
+	}
+
+	strategy := rancher.UpgradeStrategy{
+		MaxUnavailableWorker:       defaultUpgradeMaxUnavailableWorker,
+		MaxUnavailableControlplane: defaultUpgradeMaxUnavailableControlplane,
+		Drain:                      true,
+	}
+	if conf.IsSet("upgrade_max_unavailable_worker") {
+		strategy.MaxUnavailableWorker = conf.GetString("upgrade_max_unavailable_worker")
+	}
+	if conf.IsSet("upgrade_max_unavailable_controlplane") {
+		strategy.MaxUnavailableControlplane = conf.GetString("upgrade_max_unavailable_controlplane")
+	}
+	if conf.IsSet("upgrade_drain") {
+		strategy.Drain = conf.GetBool("upgrade_drain")
+	}
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Upgrade cluster '%s' from %s to %s, %s worker nodes at a time", selectedClusterName, currentVersion, selectedVersion, strategy.MaxUnavailableWorker)
+		selected := "Upgrade"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Upgrade canceled.")
+			return nil
+		}
+	}
+
+	timeout := defaultClusterUpgradeTimeout
+	if conf.IsSet("cluster_upgrade_timeout") {
+		timeout = conf.GetInt("cluster_upgrade_timeout")
+	}
+
+	err = client.UpgradeKubernetes(cluster, selectedVersion, strategy)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Waiting up to %d minutes for cluster '%s' to be upgraded to %s...\n", timeout, selectedClusterName, selectedVersion)
+	err = client.WaitForKubernetesVersion(rancherClusterID, selectedVersion, time.Duration(timeout)*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	// The cluster module only creates clusters, recording the version keeps it in line with
+	// the cluster
+	err = currentState.Set(fmt.Sprintf("module.%s.k8s_version", selectedClusterKey), selectedVersion)
+	if err != nil {
+		return err
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Cluster '%s' runs %s.\n", selectedClusterName, selectedVersion)
+	return nil
+}
+
+// Returns the versions newer than the current one, newest first.
+func getNewerKubernetesVersions(versions []string, currentVersion string) []string {
+	result := []string{}
+	for _, version := range versions {
+		if rancher.CompareKubernetesVersions(version, currentVersion) > 0 {
+			result = append(result, version)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return rancher.CompareKubernetesVersions(result[i], result[j]) > 0
+	})
+	return result
+}
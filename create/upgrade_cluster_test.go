@@ -0,0 +1,21 @@
+package create
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetNewerKubernetesVersions(t *testing.T) {
+	versions := []string{"v1.10.0-rancher1-1", "v1.11.6-rancher1-1", "v1.9.5-rancher1-1", "v1.11.6-rancher1-2", "v1.10.5-rancher1-2"}
+
+	result := getNewerKubernetesVersions(versions, "v1.10.0-rancher1-1")
+	expected := []string{"v1.11.6-rancher1-2", "v1.11.6-rancher1-1", "v1.10.5-rancher1-2"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Wrong output, expected %v, received %v", expected, result)
+	}
+
+	result = getNewerKubernetesVersions(versions, "v1.11.6-rancher1-2")
+	if len(result) != 0 {
+		t.Errorf("Expected no newer versions, received %v", result)
+	}
+}
@@ -0,0 +1,469 @@
+package create
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// UpgradeNodes replaces every node of a pool with a node running the given image, one node at
+// a time. The new node is created and has to become active in Rancher before the node it
+// replaces is drained and destroyed, so the pool never has fewer nodes than before.
+// Nodes already running the image are skipped, so a failed upgrade can be run again.
+func UpgradeNodes(conf config.Config, remoteBackend backend.Backend, poolName string) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	// Get existing clusters
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedClusterKey := ""
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
+		clusterKey, ok := clusters[clusterName]
+		if !ok {
+			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+
+		selectedClusterKey = clusterKey
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to upgrade nodes of",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedClusterKey = clusters[value]
+	}
+
+	// clusterKey is `cluster_{provider}_{clusterName}`
+	cloudProvider := strings.Split(selectedClusterKey, "_")[1]
+
+	nodes, err := currentState.Nodes(selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	pools := map[string][]string{}
+	instanceGroups := map[string]bool{}
+	for hostname, nodeKey := range nodes {
+		// Instance groups are a single node module, their provider replaces the instances
+		if _, ok := getNodePoolProvider(currentState, nodeKey); ok {
+			instanceGroups[hostname] = true
+			continue
+		}
+
+		prefix := util.NodeHostnameSuffixRegexp.ReplaceAllString(hostname, "")
+		pools[prefix] = append(pools[prefix], hostname)
+	}
+
+	selectedPool := poolName
+	if selectedPool != "" {
+		// Name was given as an argument
+	} else if conf.IsSet("node_pool") {
+		selectedPool = conf.GetString("node_pool")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("node_pool must be specified"))
+	} else {
+		if len(pools) == 0 {
+			return fmt.Errorf("No nodes.")
+		}
+
+		poolNames := make([]string, 0, len(pools))
+		for name := range pools {
+			poolNames = append(poolNames, name)
+		}
+		sort.Strings(poolNames)
+		prompt := promptui.Select{
+			Label: "Hostname prefix of the nodes to upgrade",
+			Items: poolNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Node pool:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedPool = value
+	}
+
+	if instanceGroups[selectedPool] {
+		return fmt.Errorf("Node pool '%s' is an instance group, its instances are replaced by the cloud provider.", selectedPool)
+	}
+	hostnames, ok := pools[selectedPool]
+	if !ok {
+		return fmt.Errorf("A node pool named '%s', does not exist.", selectedPool)
+	}
+	sort.Strings(hostnames)
+
+	// The --image flag makes node_image always set, it is empty when not given
+	image := conf.GetString("node_image")
+	if image != "" {
+		// Image was given as a flag or in the config file
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("node_image must be specified"))
+	} else {
+		prompt := promptui.Prompt{
+			Label: "Image to upgrade the nodes to",
+			Validate: func(input string) error {
+				_, err := getNodeImageSettings(cloudProvider, input)
+				return err
+			},
+		}
+
+		result, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		image = result
+	}
+
+	imageSettings, err := getNodeImageSettings(cloudProvider, image)
+	if err != nil {
+		return err
+	}
+
+	// Nodes already running the image were upgraded by a previous run
+	outdatedHostnames := []string{}
+	for _, hostname := range hostnames {
+		if !nodeHasImageSettings(currentState, nodes[hostname], imageSettings) {
+			outdatedHostnames = append(outdatedHostnames, hostname)
+		}
+	}
+
+	if len(outdatedHostnames) == 0 {
+		fmt.Printf("The nodes of pool '%s' already run image '%s'.\n", selectedPool, image)
+		return nil
+	}
+
+	// Confirmation Prompt
+	if !nonInteractiveMode {
+		label := fmt.Sprintf("Replace %s with nodes running image '%s'", strings.Join(outdatedHostnames, ", "), image)
+		selected := "Upgrade"
+		confirmed, err := util.PromptForConfirmation(label, selected)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Upgrade canceled.")
+			return nil
+		}
+	}
+
+	// Make sure the new nodes will be able to register with the cluster manager
+	err = checkRancherConnectivity(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, selectedClusterKey)
+	if err != nil {
+		return err
+	}
+
+	timeout := defaultNodeRegistrationTimeout
+	if conf.IsSet("node_registration_timeout") {
+		timeout = conf.GetInt("node_registration_timeout")
+	}
+
+	settings := map[string]interface{}{}
+	for key, value := range imageSettings {
+		settings[key] = value
+	}
+
+	for _, hostname := range outdatedHostnames {
+		prefix := util.NodeHostnameSuffixRegexp.ReplaceAllString(hostname, "")
+		_, err = replaceNode(conf, remoteBackend, currentState, client, rancherClusterID, selectedClusterKey, hostname, nodes[hostname], prefix, settings, time.Duration(timeout)*time.Minute)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("All nodes of pool '%s' run image '%s'.\n", selectedPool, image)
+	return nil
+}
+
+// Creates a copy of the node with the given settings, e.g. a new image or roles, and a hostname
+// with the given prefix, waits for it to become active, then drains and destroys the node. The
+// state is persisted after each step. Returns the hostname of the new node.
+func replaceNode(conf config.Config, remoteBackend backend.Backend, currentState state.State, client *rancher.Client, rancherClusterID, clusterKey, hostname, nodeKey, prefix string, settings map[string]interface{}, timeout time.Duration) (string, error) {
+	existingNodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return "", err
+	}
+	existingNames := []string{}
+	for name := range existingNodes {
+		existingNames = append(existingNames, name)
+	}
+
+	newHostname := getNewHostnames(existingNames, prefix, 1)[0]
+
+	// The new node has the same settings, except for its hostname and the given settings
+	newNode := map[string]interface{}{}
+	for key, value := range currentState.GetMap(fmt.Sprintf("module.%s", nodeKey)) {
+		newNode[key] = value
+	}
+	newNode["hostname"] = newHostname
+	for key, value := range settings {
+		newNode[key] = value
+	}
+	// The new node runs on a machine of its own
+	for _, input := range getNodeMachineInputs(currentState, nodeKey) {
+		values, err := input.Values(conf, []string{newHostname})
+		if err != nil {
+			return "", err
+		}
+		newNode[input.Variable] = values[0]
+	}
+
+	err = currentState.AddNode(clusterKey, newHostname, newNode)
+	if err != nil {
+		return "", err
+	}
+	// Node keys are `node_{provider}_{clusterName}_{hostname}`
+	newNodeKey := strings.TrimSuffix(nodeKey, hostname) + newHostname
+
+	// The registration token issued to the node was deleted once it was active, so the new
+	// node gets a token of its own, or the cluster's
+	ephemeralToken := useEphemeralRegistrationTokens(conf)
+	var registrationToken rancher.ClusterRegistrationToken
+	if ephemeralToken {
+		registrationToken, err = issueRegistrationToken(client, rancherClusterID, currentState, []string{newNodeKey})
+	} else {
+		err = setClusterRegistrationToken(currentState, clusterKey, []string{newNodeKey})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Block on configurations that violate the user's policies
+	err = checkPolicies(conf, currentState)
+	if err != nil {
+		return "", err
+	}
+
+	rancherNodes, err := client.Nodes(rancherClusterID)
+	if err != nil {
+		return "", err
+	}
+	activeNodes := 0
+	for _, node := range rancherNodes {
+		if node.State == "active" {
+			activeNodes++
+		}
+	}
+
+	fmt.Printf("Creating node %s to replace %s.\n", newHostname, hostname)
+	err = shell.RunTerraformApplyWithState(conf, currentState, []string{fmt.Sprintf("-target=module.%s", newNodeKey)})
+	if err != nil {
+		if ephemeralToken {
+			err = revokeUnappliedRegistrationTokens(client, rancherClusterID, []string{registrationToken.Token}, err)
+		}
+		return "", recordNodeApplyFailure(conf, remoteBackend, currentState, clusterKey, []string{newHostname}, err)
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return "", err
+	}
+
+	if ephemeralToken {
+		err = revokeRegistrationTokensOnceActive(client, rancherClusterID, []string{registrationToken.Token}, activeNodes+1, []string{newHostname}, timeout)
+		if err != nil {
+			return "", fmt.Errorf("%w\nNode %s was kept, destroy it once %s is active.", err, hostname, newHostname)
+		}
+	} else if timeout > 0 {
+		fmt.Printf("Waiting up to %s for %s to become active...\n", timeout, newHostname)
+		err = waitForActiveNodes(client, rancherClusterID, activeNodes+1, []string{newHostname}, timeout)
+		if err != nil {
+			return "", fmt.Errorf("%w\nNode %s was kept, destroy it once %s is active.", err, hostname, newHostname)
+		}
+	}
+
+	drainedNodes, err := drainNodePoolNodes(client, rancherClusterID, []string{hostname})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Destroying node %s.\n", hostname)
+	err = shell.RunTerraformDestroyWithState(conf, currentState, []string{fmt.Sprintf("-target=module.%s", nodeKey)})
+	if err != nil {
+		return "", err
+	}
+
+	err = currentState.Delete(fmt.Sprintf("module.%s", nodeKey))
+	if err != nil {
+		return "", err
+	}
+
+	err = remoteBackend.PersistState(currentState)
+	if err != nil {
+		return "", err
+	}
+
+	for _, node := range drainedNodes {
+		err = client.DeleteNode(node)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return newHostname, nil
+}
+
+// Returns the node module settings that select the given image on a cloud provider:
+// - triton: {image name}@{image version}
+// - aws: an AMI id
+// - gcp: an image name or self link
+// - azure: {publisher}:{offer}:{sku}:{version}
+// - digitalocean: an image slug or id
+// - equinixmetal: an operating system slug
+// - openstack: an image name
+// - vsphere: a template name
+// - proxmox: a template name
+// - nutanix: the UUID of a disk image
+// - libvirt: the id of a base volume
+func getNodeImageSettings(cloudProvider, image string) (map[string]string, error) {
+	if image == "" {
+		return nil, errors.New("Image must not be blank")
+	}
+
+	switch cloudProvider {
+	case "triton":
+		parts := strings.Split(image, "@")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, util.ConfigError(fmt.Errorf("Invalid Triton image '%s', must be {name}@{version}", image))
+		}
+		return map[string]string{"triton_image_name": parts[0], "triton_image_version": parts[1]}, nil
+	case "aws":
+		return map[string]string{"aws_ami_id": image}, nil
+	case "gcp":
+		return map[string]string{"gcp_image": image}, nil
+	case "azure":
+		parts := strings.Split(image, ":")
+		if len(parts) != 4 {
+			return nil, util.ConfigError(fmt.Errorf("Invalid Azure image '%s', must be {publisher}:{offer}:{sku}:{version}", image))
+		}
+		return map[string]string{
+			"azure_image_publisher": parts[0],
+			"azure_image_offer":     parts[1],
+			"azure_image_sku":       parts[2],
+			"azure_image_version":   parts[3],
+		}, nil
+	case "digitalocean":
+		return map[string]string{"digitalocean_image": image}, nil
+	case "equinixmetal":
+		return map[string]string{"equinix_metal_operating_system": image}, nil
+	case "openstack":
+		return map[string]string{"openstack_image_name": image}, nil
+	case "vsphere":
+		return map[string]string{"vsphere_template_name": image}, nil
+	case "proxmox":
+		return map[string]string{"proxmox_template_name": image}, nil
+	case "nutanix":
+		return map[string]string{"nutanix_image_uuid": image}, nil
+	case "libvirt":
+		return map[string]string{"libvirt_base_volume_id": image}, nil
+	}
+
+	return nil, fmt.Errorf("Upgrading the image of %s nodes is not supported", cloudProvider)
+}
+
+// Returns true if the node module has all the given settings.
+func nodeHasImageSettings(currentState state.State, nodeKey string, imageSettings map[string]string) bool {
+	for key, value := range imageSettings {
+		if currentState.Get(fmt.Sprintf("module.%s.%s", nodeKey, key)) != value {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,62 @@
+package create
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+var getNodeImageSettingsTestCases = []struct {
+	CloudProvider string
+	Image         string
+	Expected      map[string]string
+	ExpectError   bool
+}{
+	{"triton", "ubuntu-certified-16.04@20180222", map[string]string{"triton_image_name": "ubuntu-certified-16.04", "triton_image_version": "20180222"}, false},
+	{"triton", "ubuntu-certified-16.04", nil, true},
+	{"aws", "ami-0def3275", map[string]string{"aws_ami_id": "ami-0def3275"}, false},
+	{"azure", "Canonical:UbuntuServer:16.04-LTS:latest", map[string]string{"azure_image_publisher": "Canonical", "azure_image_offer": "UbuntuServer", "azure_image_sku": "16.04-LTS", "azure_image_version": "latest"}, false},
+	{"azure", "Canonical:UbuntuServer", nil, true},
+	{"digitalocean", "ubuntu-20-04-x64", map[string]string{"digitalocean_image": "ubuntu-20-04-x64"}, false},
+	{"equinixmetal", "ubuntu_22_04", map[string]string{"equinix_metal_operating_system": "ubuntu_22_04"}, false},
+	{"gcp", "", nil, true},
+	{"baremetal", "ubuntu", nil, true},
+}
+
+func TestGetNodeImageSettings(t *testing.T) {
+	for _, tc := range getNodeImageSettingsTestCases {
+		settings, err := getNodeImageSettings(tc.CloudProvider, tc.Image)
+		if tc.ExpectError {
+			if err == nil {
+				t.Errorf("Expected an error for (%q, %q), received %v", tc.CloudProvider, tc.Image, settings)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for (%q, %q): %v", tc.CloudProvider, tc.Image, err)
+			continue
+		}
+		if len(settings) != len(tc.Expected) {
+			t.Errorf("Wrong output for (%q, %q), expected %v, received %v", tc.CloudProvider, tc.Image, tc.Expected, settings)
+		}
+		for key, value := range tc.Expected {
+			if settings[key] != value {
+				t.Errorf("Wrong output for (%q, %q), expected %v, received %v", tc.CloudProvider, tc.Image, tc.Expected, settings)
+			}
+		}
+	}
+}
+
+func TestNodeHasImageSettings(t *testing.T) {
+	currentState, err := state.New("UpgradeState", []byte(`{"module":{"node_aws_dev_dev-w-1":{"hostname":"dev-w-1","aws_ami_id":"ami-old"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nodeHasImageSettings(currentState, "node_aws_dev_dev-w-1", map[string]string{"aws_ami_id": "ami-new"}) {
+		t.Error("Expected node running ami-old not to have image ami-new")
+	}
+	if !nodeHasImageSettings(currentState, "node_aws_dev_dev-w-1", map[string]string{"aws_ami_id": "ami-old"}) {
+		t.Error("Expected node running ami-old to have image ami-old")
+	}
+}
@@ -0,0 +1,40 @@
+package describe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// DescribeCluster prints the stored config, terraform outputs and nodes of a cluster.
+func DescribeCluster(conf config.Config, remoteBackend backend.Backend, name string) error {
+	currentState, err := getClusterManagerState(remoteBackend, "")
+	if err != nil {
+		return err
+	}
+
+	clusterKey, err := getClusterKey(currentState, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Cluster: %s\n", currentState.Get(fmt.Sprintf("module.%s.name", clusterKey)))
+	fmt.Printf("Cluster Manager: %s\n", currentState.Name)
+	printModule(conf, currentState, clusterKey)
+
+	nodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return err
+	}
+	nodeNames := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	fmt.Printf("Nodes: %s\n", strings.Join(nodeNames, ", "))
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package describe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/viper"
+)
+
+// Returns the state of the cluster manager given by name, the cluster_manager config or a prompt.
+func getClusterManagerState(remoteBackend backend.Backend, name string) (state.State, error) {
+	nonInteractiveMode := viper.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return state.State{}, err
+	}
+
+	if len(clusterManagers) == 0 {
+		return state.State{}, fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := name
+	if selectedClusterManager != "" {
+		// Name was given as an argument
+	} else if viper.IsSet("cluster_manager") {
+		selectedClusterManager = viper.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return state.State{}, util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		sort.Strings(clusterManagers)
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster Manager:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return state.State{}, err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return state.State{}, fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	return remoteBackend.State(selectedClusterManager)
+}
+
+// Returns the key of the cluster given by name, the cluster_name config or a prompt.
+func getClusterKey(currentState state.State, name string) (string, error) {
+	nonInteractiveMode := viper.GetBool("non-interactive")
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return "", err
+	}
+
+	if len(clusters) == 0 {
+		return "", fmt.Errorf("No clusters.")
+	}
+
+	clusterName := name
+	if clusterName != "" {
+		// Name was given as an argument
+	} else if viper.IsSet("cluster_name") {
+		clusterName = viper.GetString("cluster_name")
+	} else if nonInteractiveMode {
+		return "", util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		clusterNames := make([]string, 0, len(clusters))
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+		prompt := promptui.Select{
+			Label: "Cluster to describe",
+			Items: clusterNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Cluster:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		clusterName = value
+	}
+
+	clusterKey, ok := clusters[clusterName]
+	if !ok {
+		return "", fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+	}
+
+	return clusterKey, nil
+}
+
+// Prints the stored config, creation timestamp and terraform outputs of a module.
+func printModule(conf config.Config, currentState state.State, moduleKey string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	createdAt := currentState.CreatedAt(moduleKey)
+	if createdAt == "" {
+		createdAt = "unknown"
+	}
+	fmt.Fprintf(w, "Created:\t%s\n", createdAt)
+
+	fmt.Fprintln(w, "Config:")
+	printFields(w, currentState.GetMap(fmt.Sprintf("module.%s", moduleKey)))
+
+	fmt.Fprintln(w, "Outputs:")
+	outputs, err := shell.RunTerraformOutputWithState(conf, currentState, moduleKey)
+	if err != nil || len(outputs) == 0 {
+		fmt.Fprintln(w, "  none")
+		return
+	}
+	printFields(w, outputs)
+}
+
+// Prints a map of fields sorted by name, masking anything that looks like a secret.
+func printFields(w *tabwriter.Writer, fields map[string]interface{}) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := fields[name]
+		if isSecretField(name) && value != "" {
+			value = "********"
+		}
+		fmt.Fprintf(w, "  %s:\t%v\n", name, value)
+	}
+}
+
+func isSecretField(name string) bool {
+	return strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token")
+}
+
+// Prints live facts about the named instance, looked up from the cloud provider.
+func printInstanceFacts(conf config.Config, currentState state.State, provider, instanceName string, cfg map[string]interface{}) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "Live:")
+	facts, err := getInstanceFacts(conf, currentState, provider, instanceName, cfg)
+	if err != nil {
+		fmt.Fprintf(w, "  error:\t%s\n", err)
+		return
+	}
+	if facts == nil {
+		fmt.Fprintf(w, "  not available for %s\n", provider)
+		return
+	}
+
+	fmt.Fprintf(w, "  id:\t%s\n", facts.ID)
+	fmt.Fprintf(w, "  state:\t%s\n", facts.State)
+	fmt.Fprintf(w, "  ips:\t%s\n", strings.Join(facts.IPs, ", "))
+}
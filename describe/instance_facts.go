@@ -0,0 +1,179 @@
+package describe
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+
+	triton "github.com/joyent/triton-go"
+	"github.com/joyent/triton-go/authentication"
+	tritonCompute "github.com/joyent/triton-go/compute"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"golang.org/x/oauth2/google"
+	gcpCompute "google.golang.org/api/compute/v1"
+)
+
+// Live facts about an instance, looked up from the cloud provider API.
+type instanceFacts struct {
+	ID    string
+	State string
+	IPs   []string
+}
+
+// Looks up the instance with the given name using the credentials stored in the module config.
+// Returns nil if live facts aren't supported for the provider.
+func getInstanceFacts(conf config.Config, currentState state.State, provider, instanceName string, cfg map[string]interface{}) (*instanceFacts, error) {
+	get := func(key string) string {
+		value, _ := cfg[key].(string)
+		return value
+	}
+
+	switch provider {
+	case "triton":
+		return getTritonInstanceFacts(instanceName, get("triton_account"), get("triton_key_path"), get("triton_key_id"), get("triton_url"))
+	case "aws":
+		// The credentials may reference the temporary credentials of an IAM role
+		creds, err := shell.AWSCredentials(conf, currentState, get("aws_access_key"), get("aws_secret_key"), get("aws_session_token"))
+		if err != nil {
+			return nil, err
+		}
+		return getAWSInstanceFacts(instanceName, creds.AccessKey, creds.SecretKey, creds.SessionToken, get("aws_region"))
+	case "gcp":
+		return getGCPInstanceFacts(instanceName, get("gcp_path_to_credentials"), get("gcp_project_id"), get("gcp_instance_zone"))
+	default:
+		return nil, nil
+	}
+}
+
+func getTritonInstanceFacts(instanceName, tritonAccount, tritonKeyPath, tritonKeyID, tritonURL string) (*instanceFacts, error) {
+	keyMaterial, err := ioutil.ReadFile(tritonKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeySignerInput := authentication.PrivateKeySignerInput{
+		KeyID:              tritonKeyID,
+		PrivateKeyMaterial: keyMaterial,
+		AccountName:        tritonAccount,
+	}
+	sshKeySigner, err := authentication.NewPrivateKeySigner(privateKeySignerInput)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &triton.ClientConfig{
+		TritonURL:   tritonURL,
+		AccountName: tritonAccount,
+		Signers:     []authentication.Signer{sshKeySigner},
+	}
+
+	tritonComputeClient, err := tritonCompute.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := tritonComputeClient.Instances().List(context.Background(), &tritonCompute.ListInstancesInput{Name: instanceName})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("No Triton instance named '%s' was found.", instanceName)
+	}
+
+	return &instanceFacts{
+		ID:    instances[0].ID,
+		State: instances[0].State,
+		IPs:   instances[0].IPs,
+	}, nil
+}
+
+func getAWSInstanceFacts(instanceName, awsAccessKey, awsSecretKey, awsSessionToken, awsRegion string) (*instanceFacts, error) {
+	creds := credentials.NewStaticCredentials(awsAccessKey, awsSecretKey, awsSessionToken)
+
+	awsConfig := aws.NewConfig().WithCredentials(creds).WithRegion(awsRegion)
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	ec2Client := ec2.New(sess)
+
+	describeInstancesInput := ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:Name"),
+				Values: []*string{aws.String(instanceName)},
+			},
+		},
+	}
+	describeInstancesResponse, err := ec2Client.DescribeInstances(&describeInstancesInput)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reservation := range describeInstancesResponse.Reservations {
+		for _, instance := range reservation.Instances {
+			if aws.StringValue(instance.State.Name) == ec2.InstanceStateNameTerminated {
+				continue
+			}
+
+			facts := &instanceFacts{
+				ID:    aws.StringValue(instance.InstanceId),
+				State: aws.StringValue(instance.State.Name),
+			}
+			if instance.PublicIpAddress != nil {
+				facts.IPs = append(facts.IPs, aws.StringValue(instance.PublicIpAddress))
+			}
+			if instance.PrivateIpAddress != nil {
+				facts.IPs = append(facts.IPs, aws.StringValue(instance.PrivateIpAddress))
+			}
+			return facts, nil
+		}
+	}
+
+	return nil, fmt.Errorf("No AWS instance named '%s' was found.", instanceName)
+}
+
+func getGCPInstanceFacts(instanceName, gcpPathToCredentials, gcpProjectID, gcpInstanceZone string) (*instanceFacts, error) {
+	gcpCredentials, err := ioutil.ReadFile(gcpPathToCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtCfg, err := google.JWTConfigFromJSON(gcpCredentials, "https://www.googleapis.com/auth/compute.readonly")
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := gcpCompute.New(jwtCfg.Client(context.Background()))
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := service.Instances.Get(gcpProjectID, gcpInstanceZone, instanceName).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	facts := &instanceFacts{
+		ID:    fmt.Sprintf("%d", instance.Id),
+		State: instance.Status,
+	}
+	for _, networkInterface := range instance.NetworkInterfaces {
+		for _, accessConfig := range networkInterface.AccessConfigs {
+			if accessConfig.NatIP != "" {
+				facts.IPs = append(facts.IPs, accessConfig.NatIP)
+			}
+		}
+		facts.IPs = append(facts.IPs, networkInterface.NetworkIP)
+	}
+
+	return facts, nil
+}
@@ -0,0 +1,32 @@
+package describe
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// Manager modules are sourced from `terraform/modules/{provider}-rancher`
+var managerSourceRegexp = regexp.MustCompile(`terraform/modules/([a-z-]+)-rancher\?`)
+
+// DescribeManager prints the stored config, terraform outputs and live facts of a cluster manager.
+func DescribeManager(conf config.Config, remoteBackend backend.Backend, name string) error {
+	currentState, err := getClusterManagerState(remoteBackend, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Cluster Manager: %s\n", currentState.Name)
+	printModule(conf, currentState, "cluster-manager")
+
+	cfg := currentState.GetMap("module.cluster-manager")
+	provider := "unknown"
+	if match := managerSourceRegexp.FindStringSubmatch(currentState.Get("module.cluster-manager.source")); match != nil {
+		provider = match[1]
+	}
+	printInstanceFacts(conf, currentState, provider, currentState.Get("module.cluster-manager.name"), cfg)
+
+	return nil
+}
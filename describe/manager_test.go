@@ -0,0 +1,56 @@
+package describe
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/backend/mocks"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/spf13/viper"
+)
+
+func TestDescribeManagerNoClusterManager(t *testing.T) {
+	conf := config.New()
+	viper.Reset()
+
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{}, nil)
+
+	expected := "No cluster managers."
+
+	err := DescribeManager(conf, localBackend, "")
+	if expected != err.Error() {
+		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
+	}
+}
+
+func TestDescribeManagerMissingClusterManager(t *testing.T) {
+	conf := config.New()
+	viper.Reset()
+	viper.Set("non-interactive", true)
+
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{"dev-manager", "beta-manager"}, nil)
+
+	expected := "cluster_manager must be specified"
+
+	err := DescribeManager(conf, localBackend, "")
+	if expected != err.Error() {
+		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
+	}
+}
+
+func TestDescribeManagerNotExist(t *testing.T) {
+	conf := config.New()
+	viper.Reset()
+	viper.Set("non-interactive", true)
+
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{"dev-manager", "beta-manager"}, nil)
+
+	expected := "Selected cluster manager 'prod-manager' does not exist."
+
+	err := DescribeManager(conf, localBackend, "prod-manager")
+	if expected != err.Error() {
+		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
+	}
+}
@@ -0,0 +1,85 @@
+package describe
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/viper"
+)
+
+// DescribeNode prints the stored config, terraform outputs and live facts of a node.
+func DescribeNode(conf config.Config, remoteBackend backend.Backend, name string) error {
+	nonInteractiveMode := viper.GetBool("non-interactive")
+	currentState, err := getClusterManagerState(remoteBackend, "")
+	if err != nil {
+		return err
+	}
+
+	clusterKey, err := getClusterKey(currentState, "")
+	if err != nil {
+		return err
+	}
+
+	nodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) == 0 {
+		return fmt.Errorf("No nodes.")
+	}
+
+	nodeHostname := name
+	if nodeHostname != "" {
+		// Name was given as an argument
+	} else if viper.IsSet("hostname") {
+		nodeHostname = viper.GetString("hostname")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("hostname must be specified"))
+	} else {
+		nodeNames := make([]string, 0, len(nodes))
+		for name := range nodes {
+			nodeNames = append(nodeNames, name)
+		}
+		sort.Strings(nodeNames)
+		prompt := promptui.Select{
+			Label: "Node to describe",
+			Items: nodeNames,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf("%s {{ . | underline }}", promptui.IconSelect),
+				Inactive: " {{ . }}",
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Node:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		nodeHostname = value
+	}
+
+	nodeKey, ok := nodes[nodeHostname]
+	if !ok {
+		return fmt.Errorf("A node named '%s', does not exist.", nodeHostname)
+	}
+
+	fmt.Printf("Node: %s\n", nodeHostname)
+	fmt.Printf("Cluster: %s\n", currentState.Get(fmt.Sprintf("module.%s.name", clusterKey)))
+	fmt.Printf("Cluster Manager: %s\n", currentState.Name)
+	printModule(conf, currentState, nodeKey)
+
+	// Node keys are `node_{provider}_{clusterName}_{hostname}`
+	provider := strings.Split(nodeKey, "_")[1]
+	printInstanceFacts(conf, currentState, provider, nodeHostname, currentState.GetMap(fmt.Sprintf("module.%s", nodeKey)))
+
+	return nil
+}
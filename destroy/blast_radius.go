@@ -0,0 +1,107 @@
+package destroy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// Returns what destroying the cluster destroys along with it, its nodes and addons.
+func clusterBlastRadius(currentState state.State, clusterName, clusterKey string) (string, error) {
+	dependents, err := clusterDependents(currentState, clusterKey)
+	if err != nil {
+		return "", err
+	}
+
+	if dependents == "" {
+		return fmt.Sprintf("Destroying cluster %q destroys no other resources.\n", clusterName), nil
+	}
+
+	return fmt.Sprintf("Destroying cluster %q also destroys:\n  %s\n", clusterName, dependents), nil
+}
+
+// Returns what destroying the cluster manager destroys along with it, its clusters with their
+// nodes and addons.
+func managerBlastRadius(currentState state.State) (string, error) {
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return "", err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Sprintf("Destroying cluster manager %q destroys no clusters.\n", currentState.Name), nil
+	}
+
+	clusterNames := sortedKeys(clusters)
+	lines := []string{}
+	for _, clusterName := range clusterNames {
+		dependents, err := clusterDependents(currentState, clusters[clusterName])
+		if err != nil {
+			return "", err
+		}
+
+		line := fmt.Sprintf("cluster %q", clusterName)
+		if dependents != "" {
+			line = fmt.Sprintf("%s with %s", line, dependents)
+		}
+		lines = append(lines, line)
+	}
+
+	return fmt.Sprintf("Destroying cluster manager %q also destroys %s:\n  %s\n", currentState.Name, pluralize(len(clusters), "cluster"), strings.Join(lines, "\n  ")), nil
+}
+
+// Returns the clusters that are kept running when the cluster manager is destroyed with
+// --orphan-clusters.
+func managerOrphans(currentState state.State) (string, error) {
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return "", err
+	}
+
+	if len(clusters) == 0 {
+		return fmt.Sprintf("Destroying cluster manager %q orphans no clusters.\n", currentState.Name), nil
+	}
+
+	return fmt.Sprintf("Destroying cluster manager %q keeps %s running without it, terraform stops managing them:\n  %s\n", currentState.Name, pluralize(len(clusters), "cluster"), strings.Join(sortedKeys(clusters), "\n  ")), nil
+}
+
+// Returns the nodes and addons of the cluster, e.g. `2 nodes (dev-e-1, dev-w-1), 1 addon (ingress-lb)`.
+func clusterDependents(currentState state.State, clusterKey string) (string, error) {
+	nodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return "", err
+	}
+
+	addons, err := currentState.Addons(clusterKey)
+	if err != nil {
+		return "", err
+	}
+
+	dependents := []string{}
+	if len(nodes) > 0 {
+		dependents = append(dependents, fmt.Sprintf("%s (%s)", pluralize(len(nodes), "node"), strings.Join(sortedKeys(nodes), ", ")))
+	}
+	if len(addons) > 0 {
+		dependents = append(dependents, fmt.Sprintf("%s (%s)", pluralize(len(addons), "addon"), strings.Join(sortedKeys(addons), ", ")))
+	}
+
+	return strings.Join(dependents, ", "), nil
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+package destroy
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+var mockBlastRadiusState = []byte(`{
+	"module":{
+		"cluster-manager":{"name":"dev-manager"},
+		"cluster_triton_dev":{"name":"dev"},
+		"node_triton_dev_dev-w-1":{"hostname":"dev-w-1"},
+		"node_triton_dev_dev-e-1":{"hostname":"dev-e-1"},
+		"addon_triton_dev_ingress-lb":{"name":"ingress-lb"},
+		"cluster_aws_beta":{"name":"beta"}
+	}
+}`)
+
+func TestClusterBlastRadius(t *testing.T) {
+	currentState, err := state.New("dev-manager", mockBlastRadiusState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Destroying cluster \"dev\" also destroys:\n  2 nodes (dev-e-1, dev-w-1), 1 addon (ingress-lb)\n"
+	blastRadius, err := clusterBlastRadius(currentState, "dev", "cluster_triton_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blastRadius != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, blastRadius)
+	}
+
+	expected = "Destroying cluster \"beta\" destroys no other resources.\n"
+	blastRadius, err = clusterBlastRadius(currentState, "beta", "cluster_aws_beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blastRadius != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, blastRadius)
+	}
+}
+
+func TestManagerBlastRadius(t *testing.T) {
+	currentState, err := state.New("dev-manager", mockBlastRadiusState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Destroying cluster manager \"dev-manager\" also destroys 2 clusters:\n  cluster \"beta\"\n  cluster \"dev\" with 2 nodes (dev-e-1, dev-w-1), 1 addon (ingress-lb)\n"
+	blastRadius, err := managerBlastRadius(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blastRadius != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, blastRadius)
+	}
+}
+
+func TestManagerOrphans(t *testing.T) {
+	currentState, err := state.New("dev-manager", mockBlastRadiusState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Destroying cluster manager \"dev-manager\" keeps 2 clusters running without it, terraform stops managing them:\n  beta\n  dev\n"
+	orphans, err := managerOrphans(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orphans != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, orphans)
+	}
+}
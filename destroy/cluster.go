@@ -6,15 +6,16 @@ import (
 	"sort"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
-func DeleteCluster(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func DeleteCluster(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	clusterManagers, err := remoteBackend.States()
 	if err != nil {
 		return err
@@ -25,10 +26,10 @@ func DeleteCluster(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterManager := ""
-	if viper.IsSet("cluster_manager") {
-		selectedClusterManager = viper.GetString("cluster_manager")
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_manager must be specified")
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Cluster Manager",
@@ -74,8 +75,8 @@ func DeleteCluster(remoteBackend backend.Backend) error {
 
 	selectedClusterKey := ""
 	clusterName := ""
-	if viper.IsSet("cluster_name") {
-		clusterName = viper.GetString("cluster_name")
+	if conf.IsSet("cluster_name") {
+		clusterName = conf.GetString("cluster_name")
 		clusterKey, ok := clusters[clusterName]
 		if !ok {
 			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
@@ -83,7 +84,7 @@ func DeleteCluster(remoteBackend backend.Backend) error {
 
 		selectedClusterKey = clusterKey
 	} else if nonInteractiveMode {
-		return errors.New("cluster_name must be specified")
+		return util.ConfigError(errors.New("cluster_name must be specified"))
 	} else {
 		clusterNames := make([]string, 0, len(clusters))
 		for name := range clusters {
@@ -109,21 +110,12 @@ func DeleteCluster(remoteBackend backend.Backend) error {
 		selectedClusterKey = clusters[value]
 	}
 
-	// Confirmation
-	if !nonInteractiveMode {
-		label := fmt.Sprintf("Are you sure you want to destroy %q", clusterName)
-		selected := fmt.Sprintf("Destroy %q", clusterName)
-		confirmed, err := util.PromptForConfirmation(label, selected)
-		if err != nil {
-			return err
-		}
-		if !confirmed {
-			fmt.Println("Destroy cluster canceled.")
-			return nil
-		}
+	nodes, err := state.Nodes(selectedClusterKey)
+	if err != nil {
+		return err
 	}
 
-	nodes, err := state.Nodes(selectedClusterKey)
+	addons, err := state.Addons(selectedClusterKey)
 	if err != nil {
 		return err
 	}
@@ -137,12 +129,42 @@ func DeleteCluster(remoteBackend backend.Backend) error {
 		args = append(args, fmt.Sprintf("-target=module.%s", node))
 	}
 
+	// Delete all addons in the selected cluster
+	for _, addon := range addons {
+		args = append(args, fmt.Sprintf("-target=module.%s", addon))
+	}
+
+	// Only show what would be destroyed
+	if conf.GetBool("dry_run") {
+		return dryRun(conf, state, args)
+	}
+
+	// Confirmation, showing everything that gets destroyed with the cluster
+	if !nonInteractiveMode {
+		blastRadius, err := clusterBlastRadius(state, clusterName, selectedClusterKey)
+		if err != nil {
+			return err
+		}
+		fmt.Print(blastRadius)
+
+		confirmed, err := util.PromptForNameConfirmation("cluster", clusterName)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Destroy cluster canceled.")
+			return nil
+		}
+	}
+
 	// Run terraform destroy
-	err = shell.RunTerraformDestroyWithState(state, args)
+	err = shell.RunTerraformDestroyWithState(conf, state, args)
 	if err != nil {
 		return err
 	}
 
+	deleteTritonSSHKey(state, selectedClusterKey)
+
 	// Remove cluster from terraform config
 	err = state.Delete(fmt.Sprintf("module.%s", selectedClusterKey))
 	if err != nil {
@@ -157,6 +179,14 @@ func DeleteCluster(remoteBackend backend.Backend) error {
 		}
 	}
 
+	// Remove all addons associated to this cluster from terraform config
+	for _, addon := range addons {
+		err = state.Delete(fmt.Sprintf("module.%s", addon))
+		if err != nil {
+			return err
+		}
+	}
+
 	// After terraform succeeds, commit state
 	err = remoteBackend.PersistState(state)
 	if err != nil {
@@ -165,3 +195,22 @@ func DeleteCluster(remoteBackend backend.Backend) error {
 
 	return nil
 }
+
+// Removes the SSH key generated for a destroyed Triton cluster from the keys of its account. The
+// cluster is already gone, so a failure is only reported.
+func deleteTritonSSHKey(currentState state.State, clusterKey string) {
+	sshKey, ok := currentState.SSHKey(clusterKey)
+	if !ok || sshKey.TritonKeyName == "" {
+		return
+	}
+
+	err := util.DeleteTritonAccountKey(util.TritonAccount{
+		Account: currentState.Get(fmt.Sprintf("module.%s.triton_account", clusterKey)),
+		KeyID:   currentState.Get(fmt.Sprintf("module.%s.triton_key_id", clusterKey)),
+		KeyPath: currentState.Get(fmt.Sprintf("module.%s.triton_key_path", clusterKey)),
+		URL:     currentState.Get(fmt.Sprintf("module.%s.triton_url", clusterKey)),
+	}, sshKey.TritonKeyName)
+	if err != nil {
+		fmt.Printf("Warning: %s\n", err)
+	}
+}
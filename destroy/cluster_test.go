@@ -4,8 +4,8 @@ import (
 	"testing"
 
 	"github.com/joyent/triton-kubernetes/backend/mocks"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
-	"github.com/spf13/viper"
 )
 
 var mockClusters = []byte(`{
@@ -17,53 +17,53 @@ var mockClusters = []byte(`{
 }`)
 
 func TestDeleteClusterNoClusterManager(t *testing.T) {
-
+	conf := config.New()
 	localBackend := &mocks.Backend{}
 	localBackend.On("States").Return([]string{}, nil)
 
 	expected := "No cluster managers."
 
-	err := DeleteCluster(localBackend)
+	err := DeleteCluster(conf, localBackend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteClusterMissingClusterManager(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
+	conf := config.New()
+	conf.Set("non-interactive", true)
 
 	localBackend := &mocks.Backend{}
 	localBackend.On("States").Return([]string{"dev-manager", "beta-manager"}, nil)
 
 	expected := "cluster_manager must be specified"
 
-	err := DeleteCluster(localBackend)
+	err := DeleteCluster(conf, localBackend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteClusterManagerNotExist(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "prod-manager")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "prod-manager")
 
 	localBackend := &mocks.Backend{}
 	localBackend.On("States").Return([]string{"dev-manager", "beta-manager"}, nil)
 
 	expected := "Selected cluster manager 'prod-manager' does not exist."
 
-	err := DeleteCluster(localBackend)
+	err := DeleteCluster(conf, localBackend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteClusterMustSpecifyClusterName(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
 
 	stateObj, _ := state.New("ClusterState", mockClusters)
 
@@ -73,17 +73,17 @@ func TestDeleteClusterMustSpecifyClusterName(t *testing.T) {
 
 	expected := "cluster_name must be specified"
 
-	err := DeleteCluster(backend)
+	err := DeleteCluster(conf, backend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteClusterNotExist(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
-	viper.Set("cluster_name", "cluster_alpha")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
+	conf.Set("cluster_name", "cluster_alpha")
 
 	stateObj, _ := state.New("ClusterState", mockClusters)
 
@@ -93,7 +93,7 @@ func TestDeleteClusterNotExist(t *testing.T) {
 
 	expected := "A cluster named 'cluster_alpha', does not exist."
 
-	err := DeleteCluster(backend)
+	err := DeleteCluster(conf, backend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
@@ -0,0 +1,139 @@
+package destroy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// The module of a resource address, e.g. node_triton_dev_dev-w-1 in
+// module.node_triton_dev_dev-w-1.triton_machine.host
+var moduleAddressRegexp = regexp.MustCompile(`^module\.([^.]+)\.(.+)$`)
+
+// Kinds of modules, in the order they're listed by a dry run
+var moduleKinds = []string{"cluster manager", "cluster", "node", "addon", "module"}
+
+// A module of the state and the resources the destroy plan removes from it.
+type plannedModule struct {
+	Kind      string
+	Label     string
+	Resources []string
+}
+
+// Shows what terraform would destroy with the given arguments, without destroying anything.
+func dryRun(conf config.Config, currentState state.State, args []string) error {
+	changes, summary, err := shell.PlanTerraformDestroyWithState(conf, currentState, args)
+	if err != nil {
+		return err
+	}
+
+	dryRunSummary, err := destroyPlanSummary(currentState, changes, summary)
+	if err != nil {
+		return err
+	}
+	fmt.Print(dryRunSummary)
+
+	return shell.ErrPlanNotApplied
+}
+
+// Returns the resources of the destroy plan grouped by the cluster manager, cluster, node or
+// addon they belong to.
+func destroyPlanSummary(currentState state.State, changes []shell.PlanChange, summary string) (string, error) {
+	labels, err := moduleLabels(currentState)
+	if err != nil {
+		return "", err
+	}
+
+	modules := map[string]*plannedModule{}
+	for _, change := range changes {
+		if change.Action != "destroy" {
+			continue
+		}
+
+		moduleKey, resource := "", change.Address
+		if match := moduleAddressRegexp.FindStringSubmatch(change.Address); match != nil {
+			moduleKey, resource = match[1], match[2]
+		}
+
+		module, ok := modules[moduleKey]
+		if !ok {
+			module, ok = labels[moduleKey]
+			if !ok {
+				module = &plannedModule{Kind: "module", Label: fmt.Sprintf("module %q", moduleKey)}
+			}
+			modules[moduleKey] = module
+		}
+		module.Resources = append(module.Resources, resource)
+	}
+
+	if len(modules) == 0 {
+		return "Dry run: terraform would destroy no resources.\n", nil
+	}
+
+	order := map[string]int{}
+	for i, kind := range moduleKinds {
+		order[kind] = i
+	}
+	sorted := make([]*plannedModule, 0, len(modules))
+	for _, module := range modules {
+		sorted = append(sorted, module)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Kind != sorted[j].Kind {
+			return order[sorted[i].Kind] < order[sorted[j].Kind]
+		}
+		return sorted[i].Label < sorted[j].Label
+	})
+
+	lines := []string{"Dry run: terraform would destroy"}
+	for _, module := range sorted {
+		lines = append(lines, fmt.Sprintf("  %s, %s:", module.Label, pluralize(len(module.Resources), "resource")))
+		for _, resource := range module.Resources {
+			lines = append(lines, "    "+resource)
+		}
+	}
+	if summary != "" {
+		lines = append(lines, summary)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// Returns the modules of the state keyed by module name, labeled by what they are, e.g.
+// node "dev-w-1" of cluster "dev".
+func moduleLabels(currentState state.State) (map[string]*plannedModule, error) {
+	labels := map[string]*plannedModule{
+		"cluster-manager": {Kind: "cluster manager", Label: fmt.Sprintf("cluster manager %q", currentState.Name)},
+	}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return nil, err
+	}
+
+	for clusterName, clusterKey := range clusters {
+		labels[clusterKey] = &plannedModule{Kind: "cluster", Label: fmt.Sprintf("cluster %q", clusterName)}
+
+		nodes, err := currentState.Nodes(clusterKey)
+		if err != nil {
+			return nil, err
+		}
+		for hostname, nodeKey := range nodes {
+			labels[nodeKey] = &plannedModule{Kind: "node", Label: fmt.Sprintf("node %q of cluster %q", hostname, clusterName)}
+		}
+
+		addons, err := currentState.Addons(clusterKey)
+		if err != nil {
+			return nil, err
+		}
+		for addonName, addonKey := range addons {
+			labels[addonKey] = &plannedModule{Kind: "addon", Label: fmt.Sprintf("addon %q of cluster %q", addonName, clusterName)}
+		}
+	}
+
+	return labels, nil
+}
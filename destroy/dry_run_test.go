@@ -0,0 +1,55 @@
+package destroy
+
+import (
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestDestroyPlanSummary(t *testing.T) {
+	currentState, err := state.New("dev-manager", mockBlastRadiusState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := []shell.PlanChange{
+		{Action: "destroy", Address: "module.addon_triton_dev_ingress-lb.null_resource.install"},
+		{Action: "destroy", Address: "module.cluster-manager.triton_machine.rancher_master"},
+		{Action: "destroy", Address: "module.cluster_triton_dev.rancher_cluster.cluster"},
+		{Action: "destroy", Address: "module.node_triton_dev_dev-w-1.null_resource.install_rancher_agent"},
+		{Action: "destroy", Address: "module.node_triton_dev_dev-w-1.triton_machine.host"},
+		{Action: "destroy", Address: "module.unknown.null_resource.x"},
+	}
+
+	expected := `Dry run: terraform would destroy
+  cluster manager "dev-manager", 1 resource:
+    triton_machine.rancher_master
+  cluster "dev", 1 resource:
+    rancher_cluster.cluster
+  node "dev-w-1" of cluster "dev", 2 resources:
+    null_resource.install_rancher_agent
+    triton_machine.host
+  addon "ingress-lb" of cluster "dev", 1 resource:
+    null_resource.install
+  module "unknown", 1 resource:
+    null_resource.x
+Plan: 0 to add, 0 to change, 6 to destroy.
+`
+	summary, err := destroyPlanSummary(currentState, changes, "Plan: 0 to add, 0 to change, 6 to destroy.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, summary)
+	}
+
+	expected = "Dry run: terraform would destroy no resources.\n"
+	summary, err = destroyPlanSummary(currentState, []shell.PlanChange{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, summary)
+	}
+}
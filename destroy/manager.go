@@ -6,15 +6,15 @@ import (
 	"sort"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/shell"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
-func DeleteManager(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func DeleteManager(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	clusterManagers, err := remoteBackend.States()
 	if err != nil {
 		return err
@@ -25,10 +25,10 @@ func DeleteManager(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterManager := ""
-	if viper.IsSet("cluster_manager") {
-		selectedClusterManager = viper.GetString("cluster_manager")
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_manager must be specified")
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
 	} else {
 		sort.Strings(clusterManagers)
 		prompt := promptui.Select{
@@ -67,11 +67,32 @@ func DeleteManager(remoteBackend backend.Backend) error {
 		return err
 	}
 
+	// Orphaning changes the terraform state, which a plan must not do
+	orphan := conf.GetBool("orphan_clusters")
+	if orphan && conf.GetBool("plan_only") {
+		return errors.New("--orphan-clusters can't be used with --plan-only")
+	}
+	if orphan && conf.GetBool("dry_run") {
+		return errors.New("--orphan-clusters can't be used with --dry-run")
+	}
+
+	// Only show what would be destroyed
+	if conf.GetBool("dry_run") {
+		return dryRun(conf, state, []string{})
+	}
+
 	if !nonInteractiveMode {
-		// Confirmation
-		label := fmt.Sprintf("Are you sure you want to destroy %q", selectedClusterManager)
-		selected := fmt.Sprintf("Destroy %q", selectedClusterManager)
-		confirmed, err := util.PromptForConfirmation(label, selected)
+		// Confirmation, showing everything that gets destroyed with the cluster manager
+		blastRadius, err := managerBlastRadius(state)
+		if orphan {
+			blastRadius, err = managerOrphans(state)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Print(blastRadius)
+
+		confirmed, err := util.PromptForNameConfirmation("cluster manager", selectedClusterManager)
 		if err != nil {
 			return err
 		}
@@ -81,12 +102,28 @@ func DeleteManager(remoteBackend backend.Backend) error {
 		}
 	}
 
+	// Keep the clusters running, terraform then only destroys the cluster manager
+	if orphan {
+		err = orphanClusters(conf, remoteBackend, state)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Run Terraform destroy
-	err = shell.RunTerraformDestroyWithState(state, []string{})
+	err = shell.RunTerraformDestroyWithState(conf, state, []string{})
 	if err != nil {
 		return err
 	}
 
+	clusters, err := state.Clusters()
+	if err != nil {
+		return err
+	}
+	for _, clusterKey := range clusters {
+		deleteTritonSSHKey(state, clusterKey)
+	}
+
 	// After terraform succeeds, delete remote state
 	err = remoteBackend.DeleteState(selectedClusterManager)
 	if err != nil {
@@ -6,15 +6,15 @@ import (
 	"sort"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/shell"
 	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
-func DeleteNode(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func DeleteNode(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	clusterManagers, err := remoteBackend.States()
 	if err != nil {
 		return err
@@ -25,10 +25,10 @@ func DeleteNode(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterManager := ""
-	if viper.IsSet("cluster_manager") {
-		selectedClusterManager = viper.GetString("cluster_manager")
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_manager must be specified")
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Cluster Manager",
@@ -73,8 +73,8 @@ func DeleteNode(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterKey := ""
-	if viper.IsSet("cluster_name") {
-		clusterName := viper.GetString("cluster_name")
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
 		clusterKey, ok := clusters[clusterName]
 		if !ok {
 			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
@@ -82,7 +82,7 @@ func DeleteNode(remoteBackend backend.Backend) error {
 
 		selectedClusterKey = clusterKey
 	} else if nonInteractiveMode {
-		return errors.New("cluster_name must be specified")
+		return util.ConfigError(errors.New("cluster_name must be specified"))
 	} else {
 		clusterNames := make([]string, 0, len(clusters))
 		for name := range clusters {
@@ -114,8 +114,8 @@ func DeleteNode(remoteBackend backend.Backend) error {
 
 	selectedNodeKey := ""
 	nodeHostname := ""
-	if viper.IsSet("hostname") {
-		nodeHostname = viper.GetString("hostname")
+	if conf.IsSet("hostname") {
+		nodeHostname = conf.GetString("hostname")
 		nodeKey, ok := nodes[nodeHostname]
 		if !ok {
 			return fmt.Errorf("A node named '%s', does not exist.", nodeHostname)
@@ -123,7 +123,7 @@ func DeleteNode(remoteBackend backend.Backend) error {
 
 		selectedNodeKey = nodeKey
 	} else if nonInteractiveMode {
-		return errors.New("hostname must be specified")
+		return util.ConfigError(errors.New("hostname must be specified"))
 	} else {
 		nodeNames := make([]string, 0, len(nodes))
 		for name := range nodes {
@@ -149,11 +149,43 @@ func DeleteNode(remoteBackend backend.Backend) error {
 		selectedNodeKey = nodes[value]
 	}
 
+	// Refuse to break etcd quorum or remove the last control plane node
+	if !conf.GetBool("force") {
+		etcdNodes, err := state.NodesWithRole(selectedClusterKey, "etcd")
+		if err != nil {
+			return err
+		}
+		controlNodes, err := state.NodesWithRole(selectedClusterKey, "control")
+		if err != nil {
+			return err
+		}
+
+		etcdRemoved := 0
+		if _, ok := etcdNodes[nodeHostname]; ok {
+			etcdRemoved = 1
+		}
+		controlRemoved := 0
+		if _, ok := controlNodes[nodeHostname]; ok {
+			controlRemoved = 1
+		}
+
+		operation := fmt.Sprintf("Destroying node %q", nodeHostname)
+		err = util.CheckNodeRemoval(operation, len(etcdNodes), len(controlNodes), etcdRemoved, controlRemoved)
+		if err != nil {
+			return err
+		}
+	}
+
+	targetArg := fmt.Sprintf("-target=module.%s", selectedNodeKey)
+
+	// Only show what would be destroyed
+	if conf.GetBool("dry_run") {
+		return dryRun(conf, state, []string{targetArg})
+	}
+
 	if !nonInteractiveMode {
 		// Confirmation
-		label := fmt.Sprintf("Are you sure you want to destroy %q", nodeHostname)
-		selected := fmt.Sprintf("Destroy %q", nodeHostname)
-		confirmed, err := util.PromptForConfirmation(label, selected)
+		confirmed, err := util.PromptForNameConfirmation("node", nodeHostname)
 		if err != nil {
 			return err
 		}
@@ -164,8 +196,7 @@ func DeleteNode(remoteBackend backend.Backend) error {
 	}
 
 	// Run terraform destroy
-	targetArg := fmt.Sprintf("-target=module.%s", selectedNodeKey)
-	err = shell.RunTerraformDestroyWithState(state, []string{targetArg})
+	err = shell.RunTerraformDestroyWithState(conf, state, []string{targetArg})
 	if err != nil {
 		return err
 	}
@@ -176,6 +207,12 @@ func DeleteNode(remoteBackend backend.Backend) error {
 		return err
 	}
 
+	// The node's pool has one node less
+	err = removeFromNodePool(state, selectedClusterKey, nodeHostname)
+	if err != nil {
+		return err
+	}
+
 	// After terraform succeeds, commit state
 	err = remoteBackend.PersistState(state)
 	if err != nil {
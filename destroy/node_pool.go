@@ -0,0 +1,32 @@
+package destroy
+
+import (
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Decrements the node count of the pool of the destroyed node, and removes the pool once it
+// has no nodes left.
+func removeFromNodePool(currentState state.State, clusterKey, hostname string) error {
+	poolName := util.NodeHostnameSuffixRegexp.ReplaceAllString(hostname, "")
+	if poolName == hostname {
+		return nil
+	}
+
+	pools, err := currentState.NodePools(clusterKey)
+	if err != nil {
+		return err
+	}
+	pool, ok := pools[poolName]
+	if !ok {
+		return nil
+	}
+
+	if pool.Count <= 1 {
+		currentState.DeleteNodePool(clusterKey, poolName)
+		return nil
+	}
+
+	pool.Count--
+	return currentState.SetNodePool(clusterKey, poolName, pool)
+}
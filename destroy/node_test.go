@@ -4,8 +4,8 @@ import (
 	"testing"
 
 	"github.com/joyent/triton-kubernetes/backend/mocks"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
-	"github.com/spf13/viper"
 )
 
 var mockNodeHost = []byte(`{
@@ -15,52 +15,52 @@ var mockNodeHost = []byte(`{
 }`)
 
 func TestDeleteNodeNoClusterManager(t *testing.T) {
-
+	conf := config.New()
 	localBackend := &mocks.Backend{}
 	localBackend.On("States").Return([]string{}, nil)
 
 	expected := "No cluster managers, please create a cluster manager before creating a kubernetes node."
 
-	err := DeleteNode(localBackend)
+	err := DeleteNode(conf, localBackend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteNodeMissingClusterManager(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
+	conf := config.New()
+	conf.Set("non-interactive", true)
 
 	localBackend := &mocks.Backend{}
 	localBackend.On("States").Return([]string{"dev-manager", "beta-manager"}, nil)
 
 	expected := "cluster_manager must be specified"
 
-	err := DeleteNode(localBackend)
+	err := DeleteNode(conf, localBackend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteNodeManagerNotExist(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "prod-manager")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "prod-manager")
 
 	localBackend := &mocks.Backend{}
 	localBackend.On("States").Return([]string{"dev-manager", "beta-manager"}, nil)
 
 	expected := "Selected cluster manager 'prod-manager' does not exist."
 
-	err := DeleteNode(localBackend)
+	err := DeleteNode(conf, localBackend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 func TestDeleteNodeMustSpecifyClusterName(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
 
 	stateObj, _ := state.New("NodeState", mockClusters)
 
@@ -70,17 +70,17 @@ func TestDeleteNodeMustSpecifyClusterName(t *testing.T) {
 
 	expected := "cluster_name must be specified"
 
-	err := DeleteNode(backend)
+	err := DeleteNode(conf, backend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteNodeClusterNotExist(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
-	viper.Set("cluster_name", "cluster_alpha")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
+	conf.Set("cluster_name", "cluster_alpha")
 
 	stateObj, _ := state.New("NodeState", mockClusters)
 
@@ -90,17 +90,17 @@ func TestDeleteNodeClusterNotExist(t *testing.T) {
 
 	expected := "A cluster named 'cluster_alpha', does not exist."
 
-	err := DeleteNode(backend)
+	err := DeleteNode(conf, backend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteNodeHostNameMustSpecified(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
-	viper.Set("cluster_name", "dev_cluster")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
+	conf.Set("cluster_name", "dev_cluster")
 
 	stateObj, _ := state.New("NodeState", mockNodeHost)
 
@@ -110,18 +110,18 @@ func TestDeleteNodeHostNameMustSpecified(t *testing.T) {
 
 	expected := "hostname must be specified"
 
-	err := DeleteNode(backend)
+	err := DeleteNode(conf, backend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
 
 func TestDeleteNodeNotExist(t *testing.T) {
-	viper.Reset()
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
-	viper.Set("cluster_name", "dev_cluster")
-	viper.Set("hostname", "dev_node_host")
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
+	conf.Set("cluster_name", "dev_cluster")
+	conf.Set("hostname", "dev_node_host")
 
 	stateObj, _ := state.New("NodeState", mockNodeHost)
 
@@ -131,8 +131,40 @@ func TestDeleteNodeNotExist(t *testing.T) {
 
 	expected := "A node named 'dev_node_host', does not exist."
 
-	err := DeleteNode(backend)
+	err := DeleteNode(conf, backend)
 	if expected != err.Error() {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
 }
+
+func TestRemoveFromNodePool(t *testing.T) {
+	currentState, err := state.New("test", mockNodeHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentState.SetNodePool("cluster_triton_dev-cluster", "dev-w", state.NodePool{Count: 2})
+
+	err = removeFromNodePool(currentState, "cluster_triton_dev-cluster", "dev-w-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools, _ := currentState.NodePools("cluster_triton_dev-cluster")
+	if pools["dev-w"].Count != 1 {
+		t.Errorf("Wrong output, expected 1 node, received %d", pools["dev-w"].Count)
+	}
+
+	// Nodes outside of a pool are ignored
+	err = removeFromNodePool(currentState, "cluster_triton_dev-cluster", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = removeFromNodePool(currentState, "cluster_triton_dev-cluster", "dev-w-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools, _ = currentState.NodePools("cluster_triton_dev-cluster")
+	if _, ok := pools["dev-w"]; ok {
+		t.Error("Expected the empty node pool to be removed")
+	}
+}
@@ -0,0 +1,134 @@
+package destroy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Keeps the clusters of a cluster manager running when it's destroyed. Their kube-admin kubeconfigs,
+// which reach the clusters without Rancher, are saved to orphan_kubeconfig_dir, the current
+// directory by default, while Rancher can still read them. Their Rancher agents are removed and
+// terraform forgets the clusters, their nodes and addons, so that destroying the cluster manager
+// doesn't destroy them.
+func orphanClusters(conf config.Config, remoteBackend backend.Backend, currentState state.State) error {
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return nil
+	}
+
+	kubeconfigDir := "."
+	if conf.IsSet("orphan_kubeconfig_dir") {
+		kubeconfigDir = conf.GetString("orphan_kubeconfig_dir")
+	}
+	kubeconfigDir, err = homedir.Expand(kubeconfigDir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(kubeconfigDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// Save every kubeconfig before changing anything
+	var client *rancher.Client
+	clusterIDs := map[string]string{}
+	for _, clusterName := range sortedKeys(clusters) {
+		var clusterID string
+		client, clusterID, err = rancher.NewClusterClientFromState(conf, currentState, clusters[clusterName])
+		if err != nil {
+			return err
+		}
+		clusterIDs[clusterName] = clusterID
+
+		kubeconfig, err := client.DirectKubeconfig(clusterID)
+		if err != nil {
+			return err
+		}
+
+		kubeconfigPath := filepath.Join(kubeconfigDir, fmt.Sprintf("%s.kubeconfig", clusterName))
+		err = ioutil.WriteFile(kubeconfigPath, []byte(kubeconfig), 0600)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved the kubeconfig of cluster %s to %s.\n", clusterName, kubeconfigPath)
+	}
+
+	// Agents left behind would keep trying to reach the destroyed cluster manager
+	for _, clusterName := range sortedKeys(clusterIDs) {
+		err := client.RemoveAgents(clusterIDs[clusterName])
+		if err != nil {
+			fmt.Printf("Unable to remove the Rancher agents of cluster %s, delete its cattle-system namespace: %v\n", clusterName, err)
+		}
+	}
+
+	moduleKeys, err := orphanedModules(currentState)
+	if err != nil {
+		return err
+	}
+
+	addresses := []string{}
+	for _, moduleKey := range moduleKeys {
+		addresses = append(addresses, "module."+moduleKey)
+	}
+	err = shell.RunTerraformStateRmWithState(conf, currentState, addresses)
+	if err != nil {
+		return err
+	}
+
+	for _, moduleKey := range moduleKeys {
+		err = currentState.Delete("module." + moduleKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Terraform no longer knows the clusters, the configuration must not either
+	return remoteBackend.PersistState(currentState)
+}
+
+// Returns the keys of the modules of every cluster of the cluster manager, with their nodes and
+// addons, sorted.
+func orphanedModules(currentState state.State) ([]string, error) {
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return nil, err
+	}
+
+	moduleKeys := []string{}
+	for _, clusterKey := range clusters {
+		moduleKeys = append(moduleKeys, clusterKey)
+
+		nodes, err := currentState.Nodes(clusterKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, nodeKey := range nodes {
+			moduleKeys = append(moduleKeys, nodeKey)
+		}
+
+		addons, err := currentState.Addons(clusterKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, addonKey := range addons {
+			moduleKeys = append(moduleKeys, addonKey)
+		}
+	}
+	sort.Strings(moduleKeys)
+
+	return moduleKeys, nil
+}
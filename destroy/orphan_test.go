@@ -0,0 +1,30 @@
+package destroy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestOrphanedModules(t *testing.T) {
+	currentState, err := state.New("dev-manager", mockBlastRadiusState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"addon_triton_dev_ingress-lb",
+		"cluster_aws_beta",
+		"cluster_triton_dev",
+		"node_triton_dev_dev-e-1",
+		"node_triton_dev_dev-w-1",
+	}
+	moduleKeys, err := orphanedModules(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(moduleKeys, expected) {
+		t.Errorf("Wrong output, expected %v, received %v", expected, moduleKeys)
+	}
+}
@@ -0,0 +1,12 @@
+package main
+
+# Nodes may only use the instance types approved by the platform team.
+
+approved_aws_instance_types = {"t2.medium", "t2.large", "m5.large", "m5.xlarge"}
+
+deny[msg] {
+	node := input.module[name]
+	node.aws_instance_type
+	not approved_aws_instance_types[node.aws_instance_type]
+	msg = sprintf("%s uses aws_instance_type '%s', which isn't approved", [name, node.aws_instance_type])
+}
@@ -0,0 +1,10 @@
+package main
+
+# Triton nodes may not be attached to the public network.
+
+deny[msg] {
+	node := input.module[name]
+	network := node.triton_network_names[_]
+	lower(network) == "joyent-sdc-public"
+	msg = sprintf("%s is attached to the public network '%s'", [name, network])
+}
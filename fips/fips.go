@@ -0,0 +1,80 @@
+// Package fips implements the FIPS mode of triton-kubernetes, for government users on AWS
+// GovCloud and Azure Government.
+//
+// In FIPS mode, TLS connections are restricted to FIPS-approved versions, cipher suites and
+// curves and always verify certificates, SSH keys must use FIPS-approved algorithms, and the
+// terraform configuration is checked before apply to only use FedRAMP authorized clouds.
+//
+// FIPS mode is enabled with Enable, or by building with GOEXPERIMENT=boringcrypto, which
+// also replaces the Go crypto with the FIPS validated BoringCrypto module.
+package fips
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Smallest RSA modulus approved for digital signatures
+const minRSAKeyBits = 2048
+
+var enabled = builtIn
+
+// Enable turns FIPS mode on for the rest of the process. Like the crypto module it enforces,
+// FIPS mode applies to the whole process: HTTPS requests made with the default transport, e.g.
+// by the AWS SDK, are restricted as well.
+func Enable() {
+	enabled = true
+
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.TLSClientConfig = TLSConfig()
+	}
+}
+
+// Enabled returns whether FIPS mode is on.
+func Enabled() bool {
+	return enabled
+}
+
+// TLSConfig returns a client TLS configuration only negotiating FIPS-approved parameters.
+// Certificates are verified against the system roots, or the bundle in SSL_CERT_FILE.
+func TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		},
+		CurvePreferences: []tls.CurveID{tls.CurveP256, tls.CurveP384},
+	}
+}
+
+// ValidateSSHPublicKey verifies the key uses a FIPS-approved signature algorithm: RSA of at
+// least 2048 bits or ECDSA on a NIST curve. Ed25519 and DSA keys are refused.
+func ValidateSSHPublicKey(key ssh.PublicKey) error {
+	switch key.Type() {
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		return nil
+	case ssh.KeyAlgoRSA:
+		cryptoKey, ok := key.(ssh.CryptoPublicKey)
+		if !ok {
+			return fmt.Errorf("Unable to read the size of RSA key")
+		}
+		rsaKey, ok := cryptoKey.CryptoPublicKey().(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Unable to read the size of RSA key")
+		}
+		if rsaKey.N.BitLen() < minRSAKeyBits {
+			return fmt.Errorf("%d bit RSA SSH keys aren't allowed in FIPS mode, use at least %d bits", rsaKey.N.BitLen(), minRSAKeyBits)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s SSH keys aren't allowed in FIPS mode, use an RSA or ECDSA key", key.Type())
+}
@@ -0,0 +1,10 @@
+//go:build boringcrypto
+// +build boringcrypto
+
+package fips
+
+// Restricts every TLS configuration of the process to FIPS-approved settings
+import _ "crypto/tls/fipsonly"
+
+// Binaries built with BoringCrypto always run in FIPS mode
+const builtIn = true
@@ -0,0 +1,111 @@
+package fips
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/state"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+func newSSHPublicKey(t *testing.T, key interface{}) ssh.PublicKey {
+	publicKey, err := ssh.NewPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey
+}
+
+func TestValidateSSHPublicKey(t *testing.T) {
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaP256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Name        string
+		Key         ssh.PublicKey
+		ExpectError bool
+	}{
+		{"rsa-1024", newSSHPublicKey(t, &rsa1024.PublicKey), true},
+		{"rsa-2048", newSSHPublicKey(t, &rsa2048.PublicKey), false},
+		{"ecdsa-p256", newSSHPublicKey(t, &ecdsaP256.PublicKey), false},
+		{"ed25519", newSSHPublicKey(t, ed25519Key), true},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateSSHPublicKey(tc.Key)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for %s", tc.Name)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.Name, err)
+		}
+	}
+}
+
+func TestValidateState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-fips-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ecdsaP256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPath := filepath.Join(dir, "id_ecdsa.pub")
+	err = ioutil.WriteFile(publicKeyPath, ssh.MarshalAuthorizedKey(newSSHPublicKey(t, &ecdsaP256.PublicKey)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Module      string
+		ExpectError bool
+	}{
+		{`{"source":"github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher?ref=master","aws_region":"us-gov-west-1","aws_public_key_path":"` + publicKeyPath + `"}`, false},
+		{`{"source":"github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher?ref=master","aws_region":"us-east-1"}`, true},
+		{`{"source":"github.com/joyent/triton-kubernetes//terraform/modules/azure-rancher-k8s","azure_environment":"government"}`, false},
+		{`{"source":"github.com/joyent/triton-kubernetes//terraform/modules/azure-rancher-k8s","azure_environment":"public"}`, true},
+		{`{"source":"github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher"}`, true},
+		{`{"source":"github.com/joyent/triton-kubernetes//terraform/modules/k8s-cert-manager"}`, false},
+		{`{"source":"github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher","aws_public_key_path":"` + filepath.Join(dir, "missing.pub") + `"}`, true},
+	}
+
+	for _, tc := range testCases {
+		currentState, err := state.New("gov-manager", []byte(`{"module":{"cluster-manager":`+tc.Module+`}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ValidateState(currentState)
+		if tc.ExpectError && err == nil {
+			t.Errorf("Expected an error for %s", tc.Module)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("Unexpected error for %s: %v", tc.Module, err)
+		}
+	}
+}
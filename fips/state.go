@@ -0,0 +1,147 @@
+package fips
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/state"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/ssh"
+)
+
+// Terraform modules with a FedRAMP authorized deployment target. Triton and GCP have no
+// government regions, bare metal, vSphere, Proxmox VE, Nutanix AHV and libvirt hosts are the
+// operator's own.
+var authorizedModulePrefixes = []string{"aws-", "azure-", "bare-metal-", "vsphere-", "proxmox-", "nutanix-", "libvirt-", "k8s-"}
+
+const govCloudRegionPrefix = "us-gov-"
+
+const azureGovernmentEnvironment = "government"
+
+// ValidateState verifies every module of the terraform configuration can be deployed in FIPS
+// mode: it deploys to AWS GovCloud, Azure Government or the operator's own hosts, and its SSH
+// keys use FIPS-approved algorithms.
+func ValidateState(currentState state.State) error {
+	config := struct {
+		Module map[string]map[string]interface{} `json:"module"`
+	}{}
+	err := json.Unmarshal(currentState.Bytes(), &config)
+	if err != nil {
+		return err
+	}
+
+	moduleKeys := make([]string, 0, len(config.Module))
+	for key := range config.Module {
+		moduleKeys = append(moduleKeys, key)
+	}
+	sort.Strings(moduleKeys)
+
+	for _, moduleKey := range moduleKeys {
+		err = validateModule(moduleKey, config.Module[moduleKey])
+		if err != nil {
+			return fmt.Errorf("FIPS mode: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func validateModule(moduleKey string, module map[string]interface{}) error {
+	if source, ok := module["source"].(string); ok {
+		modulePath := source
+		if i := strings.Index(modulePath, "?"); i >= 0 {
+			modulePath = modulePath[:i]
+		}
+		moduleName := modulePath[strings.LastIndex(modulePath, "/")+1:]
+
+		authorized := false
+		for _, prefix := range authorizedModulePrefixes {
+			if strings.HasPrefix(moduleName, prefix) {
+				authorized = true
+				break
+			}
+		}
+		if !authorized {
+			return fmt.Errorf("'%s' uses terraform module '%s', only AWS GovCloud, Azure Government, bare metal, vSphere, Proxmox VE, Nutanix AHV and libvirt can be used", moduleKey, moduleName)
+		}
+	}
+
+	for _, key := range []string{"aws_region", "route53_region"} {
+		if region, ok := module[key].(string); ok && region != "" && !strings.HasPrefix(region, govCloudRegionPrefix) {
+			return fmt.Errorf("'%s' uses %s '%s', only AWS GovCloud regions (%s*) can be used", moduleKey, key, region, govCloudRegionPrefix)
+		}
+	}
+
+	if environment, ok := module["azure_environment"].(string); ok && environment != azureGovernmentEnvironment {
+		return fmt.Errorf("'%s' uses azure_environment '%s', only '%s' can be used", moduleKey, environment, azureGovernmentEnvironment)
+	}
+
+	for key, value := range module {
+		path, ok := value.(string)
+		if !ok || path == "" {
+			continue
+		}
+
+		if strings.HasSuffix(key, "_public_key_path") {
+			err := validatePublicKeyFile(path)
+			if err != nil {
+				return fmt.Errorf("'%s' %s: %v", moduleKey, key, err)
+			}
+		} else if strings.HasSuffix(key, "key_path") {
+			err := validatePrivateKeyFile(path)
+			if err != nil {
+				return fmt.Errorf("'%s' %s: %v", moduleKey, key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validatePublicKeyFile(path string) error {
+	content, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey(content)
+	if err != nil {
+		return fmt.Errorf("Unable to parse public key '%s': %v", path, err)
+	}
+
+	return ValidateSSHPublicKey(key)
+}
+
+// Private keys protected by a passphrase can't be read without prompting, terraform fails
+// on them anyway, so they are skipped.
+func validatePrivateKeyFile(path string) error {
+	content, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	signer, err := ssh.ParsePrivateKey(content)
+	if err != nil {
+		return nil
+	}
+
+	return ValidateSSHPublicKey(signer.PublicKey())
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	expandedPath, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(expandedPath)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read key '%s': %v", path, err)
+	}
+
+	return content, nil
+}
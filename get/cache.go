@@ -0,0 +1,96 @@
+package get
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joyent/triton-kubernetes/backend/cache"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// Prints the terraform outputs of a module and caches them. When terraform can't read them,
+// e.g. because the backend is unreachable, the outputs cached by the last successful run are
+// printed instead, marked as stale. initErr is the error of terraform init, if it failed.
+func printModuleOutputs(conf config.Config, shellOptions *shell.ShellOptions, initErr error, currentState state.State, moduleKey string) error {
+	outputCache, err := cache.NewCache(conf)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("outputs_%s_%s", currentState.Name, moduleKey)
+
+	err = initErr
+	if err == nil {
+		output, outputErr := shell.RunShellCommandWithOutput(shellOptions, "terraform", "output", "-module", moduleKey)
+		if outputErr == nil {
+			outputCache.Save(key, string(output))
+			fmt.Print(string(output))
+			return nil
+		}
+		err = outputErr
+	}
+
+	cachedOutput := ""
+	fetchedAt, cacheErr := outputCache.Load(key, &cachedOutput)
+	if cacheErr != nil {
+		return err
+	}
+
+	cache.PrintStaleWarning(fmt.Sprintf("outputs of %s", moduleKey), fetchedAt, err)
+	fmt.Print(cachedOutput)
+	return nil
+}
+
+// Prints the state of the cluster's nodes in Rancher and caches it. When Rancher is
+// unreachable, the last cached states are printed instead, marked as stale. Node health is
+// informational, so failing to get it doesn't fail the command.
+func printNodeHealth(conf config.Config, currentState state.State, clusterKey string) {
+	nodes, err := getCachedRancherNodes(conf, currentState, clusterKey)
+	if err != nil {
+		fmt.Printf("Node health is unavailable: %v\n", err)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tSTATE")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "%s\t%s\n", node.Hostname, node.State)
+	}
+	w.Flush()
+}
+
+// Returns the nodes of the cluster registered in Rancher and caches them. When Rancher is
+// unreachable, the last cached nodes are returned instead, with a warning that they're stale.
+func getCachedRancherNodes(conf config.Config, currentState state.State, clusterKey string) ([]rancher.Node, error) {
+	outputCache, err := cache.NewCache(conf)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("health_%s_%s", currentState.Name, clusterKey)
+
+	nodes, err := getRancherNodes(conf, currentState, clusterKey)
+	if err == nil {
+		outputCache.Save(key, nodes)
+		return nodes, nil
+	}
+
+	fetchedAt, cacheErr := outputCache.Load(key, &nodes)
+	if cacheErr != nil {
+		return nil, err
+	}
+	cache.PrintStaleWarning("node health", fetchedAt, err)
+	return nodes, nil
+}
+
+// Returns the nodes of the cluster registered in Rancher.
+func getRancherNodes(conf config.Config, currentState state.State, clusterKey string) ([]rancher.Node, error) {
+	client, rancherClusterID, err := rancher.NewClusterClientFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Nodes(rancherClusterID)
+}
@@ -4,18 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"sort"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
-func GetCluster(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func GetCluster(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	clusterManagers, err := remoteBackend.States()
 	if err != nil {
 		return err
@@ -26,10 +26,10 @@ func GetCluster(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterManager := ""
-	if viper.IsSet("cluster_manager") {
-		selectedClusterManager = viper.GetString("cluster_manager")
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_manager must be specified")
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Cluster Manager",
@@ -72,8 +72,8 @@ func GetCluster(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterKey := ""
-	if viper.IsSet("cluster_name") {
-		clusterName := viper.GetString("cluster_name")
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
 		clusterKey, ok := clusters[clusterName]
 		if !ok {
 			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
@@ -81,7 +81,7 @@ func GetCluster(remoteBackend backend.Backend) error {
 
 		selectedClusterKey = clusterKey
 	} else if nonInteractiveMode {
-		return errors.New("cluster_name must be specified")
+		return util.ConfigError(errors.New("cluster_name must be specified"))
 	} else {
 		clusterNames := make([]string, 0, len(clusters))
 		for name := range clusters {
@@ -106,16 +106,16 @@ func GetCluster(remoteBackend backend.Backend) error {
 		selectedClusterKey = clusters[value]
 	}
 
-	// Create a temporary directory
-	tempDir, err := ioutil.TempDir("", "triton-kubernetes-")
+	// Create a working directory
+	tempDir, cleanup, err := shell.NewWorkingDir(conf)
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tempDir)
+	defer cleanup()
 
 	// Save the terraform config to the temporary directory
 	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
-	err = ioutil.WriteFile(jsonPath, state.Bytes(), 0644)
+	err = ioutil.WriteFile(jsonPath, state.Bytes(), 0600)
 	if err != nil {
 		return err
 	}
@@ -125,17 +125,29 @@ func GetCluster(remoteBackend backend.Backend) error {
 		WorkingDir: tempDir,
 	}
 
-	// Run terraform init
-	err = shell.RunShellCommand(&shellOptions, "terraform", "init", "-force-copy")
+	// Run terraform init, printing cached outputs if it fails
+	initErr := shell.RunShellCommand(&shellOptions, "terraform", "init", "-force-copy")
+
+	// Run terraform output
+	err = printModuleOutputs(conf, &shellOptions, initErr, state, selectedClusterKey)
 	if err != nil {
 		return err
 	}
 
-	// Run terraform output
-	err = shell.RunShellCommand(&shellOptions, "terraform", "output", "-module", selectedClusterKey)
+	// Show the address of the cluster's ingress load balancer, if it has one
+	addons, err := state.Addons(selectedClusterKey)
 	if err != nil {
 		return err
 	}
+	if ingressLoadBalancerKey, ok := addons["ingress-lb"]; ok {
+		err = printModuleOutputs(conf, &shellOptions, initErr, state, ingressLoadBalancerKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Show the state of the nodes in Rancher
+	printNodeHealth(conf, state, selectedClusterKey)
 
 	return nil
 }
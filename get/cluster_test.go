@@ -1,18 +1,19 @@
 package get
 
 import (
-	"testing"
 	"github.com/joyent/triton-kubernetes/backend/mocks"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
-	"github.com/spf13/viper"
+	"testing"
 )
 
 func TestGetClusterNoClusterManager(t *testing.T) {
+	conf := config.New()
 	localBackend := &mocks.Backend{}
 
 	localBackend.On("States").Return([]string{}, nil)
 
-	err := GetCluster(localBackend)
+	err := GetCluster(conf, localBackend)
 
 	expected := "No cluster managers."
 
@@ -21,16 +22,15 @@ func TestGetClusterNoClusterManager(t *testing.T) {
 	}
 }
 
-func TestMissingClusterManagerNonInteractiveMode(t *testing.T){
-	viper.Set("non-interactive", true)
-
-	defer viper.Reset()
+func TestMissingClusterManagerNonInteractiveMode(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
 
 	localBackend := &mocks.Backend{}
 
 	localBackend.On("States").Return([]string{"dev-manager", "test-manager"}, nil)
 
-	err := GetCluster(localBackend)
+	err := GetCluster(conf, localBackend)
 
 	expected := "cluster_manager must be specified"
 
@@ -40,17 +40,16 @@ func TestMissingClusterManagerNonInteractiveMode(t *testing.T){
 
 }
 
-func TestUnidentifiedClusterManagerNonInteractiveMode(t *testing.T){
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "xyz")
-
-	defer viper.Reset()
+func TestUnidentifiedClusterManagerNonInteractiveMode(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "xyz")
 
 	localBackend := &mocks.Backend{}
 
 	localBackend.On("States").Return([]string{"dev-manager", "test-manager"}, nil)
 
-	err := GetCluster(localBackend)
+	err := GetCluster(conf, localBackend)
 
 	expected := "Selected cluster manager 'xyz' does not exist."
 
@@ -60,11 +59,10 @@ func TestUnidentifiedClusterManagerNonInteractiveMode(t *testing.T){
 
 }
 
-func TestNoCluster(t *testing.T){
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
-
-	defer viper.Reset()
+func TestNoCluster(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
 
 	stateObj, _ := state.New("ClusterState", []byte(`{
 		"module":{}
@@ -76,7 +74,7 @@ func TestNoCluster(t *testing.T){
 
 	clusterManagerBackend.On("State", "dev-manager").Return(stateObj, nil)
 
-	err:= GetCluster(clusterManagerBackend)
+	err := GetCluster(conf, clusterManagerBackend)
 
 	expected := "No clusters."
 
@@ -86,11 +84,10 @@ func TestNoCluster(t *testing.T){
 
 }
 
-func TestNoClusterNameNonInterative(t *testing.T){
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
-
-	defer viper.Reset()
+func TestNoClusterNameNonInterative(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
 
 	stateObj, _ := state.New("ClusterState", []byte(`{
 		"module":{
@@ -106,7 +103,7 @@ func TestNoClusterNameNonInterative(t *testing.T){
 
 	clusterManagerBackend.On("State", "dev-manager").Return(stateObj, nil)
 
-	err:= GetCluster(clusterManagerBackend)
+	err := GetCluster(conf, clusterManagerBackend)
 
 	expected := "cluster_name must be specified"
 
@@ -116,12 +113,11 @@ func TestNoClusterNameNonInterative(t *testing.T){
 
 }
 
-func TestUnidentifiedCluster(t *testing.T){
-	viper.Set("non-interactive", true)
-	viper.Set("cluster_manager", "dev-manager")
-	viper.Set("cluster_name", "cluster_xyz")
-
-	defer viper.Reset()
+func TestUnidentifiedCluster(t *testing.T) {
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("cluster_manager", "dev-manager")
+	conf.Set("cluster_name", "cluster_xyz")
 
 	stateObj, _ := state.New("ClusterState", []byte(`{
 		"module":{
@@ -137,7 +133,7 @@ func TestUnidentifiedCluster(t *testing.T){
 
 	clusterManagerBackend.On("State", "dev-manager").Return(stateObj, nil)
 
-	err:= GetCluster(clusterManagerBackend)
+	err := GetCluster(conf, clusterManagerBackend)
 
 	expected := "A cluster named 'cluster_xyz', does not exist."
 
@@ -146,5 +142,3 @@ func TestUnidentifiedCluster(t *testing.T){
 	}
 
 }
-
-
@@ -0,0 +1,131 @@
+package get
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/journal"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Number of events shown unless events_limit is set
+const defaultEventsLimit = 20
+
+// GetEvents prints the most recent operations run on a cluster manager, optionally only those
+// targeting cluster_name.
+func GetEvents(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	limit := defaultEventsLimit
+	if conf.IsSet("events_limit") {
+		limit = conf.GetInt("events_limit")
+	}
+
+	events, err := Events(remoteBackend, selectedClusterManager, conf.GetString("cluster_name"), limit)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Println("No events.")
+		return nil
+	}
+
+	printEvents(os.Stdout, events)
+	return nil
+}
+
+// Returns the last limit events, of the given cluster if it isn't empty. A limit of 0 or less
+// returns every event.
+func filterEvents(events []journal.Event, cluster string, limit int) []journal.Event {
+	filtered := []journal.Event{}
+	for _, event := range events {
+		if cluster == "" || containsString(event.Clusters, cluster) {
+			filtered = append(filtered, event)
+		}
+	}
+
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+	return filtered
+}
+
+// Prints the events oldest first, with one line per change.
+func printEvents(w io.Writer, events []journal.Event) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tDURATION\tUSER\tCOMMAND\tRESULT\tCHANGES")
+	for _, event := range events {
+		details := append([]string{}, event.Changes...)
+		if event.Error != "" {
+			details = append(details, "error: "+strings.Replace(event.Error, "\n", " ", -1))
+		}
+		if len(details) == 0 {
+			details = []string{"-"}
+		}
+
+		started := event.StartedAt.Local().Format("2006-01-02 15:04")
+		duration := event.EndedAt.Sub(event.StartedAt).Round(time.Second)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", started, duration, event.User, event.Command, event.Result, details[0])
+		for _, detail := range details[1:] {
+			fmt.Fprintf(tw, "\t\t\t\t\t%s\n", detail)
+		}
+	}
+	tw.Flush()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
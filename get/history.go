@@ -0,0 +1,98 @@
+package get
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/journal"
+)
+
+// GetHistory prints the audit log of a cluster manager: the full record of its most recent
+// operations, optionally only those targeting cluster_name, as text, JSON or YAML. With
+// history_versions, it prints the versions of the cluster manager kept by the backend instead.
+func GetHistory(conf config.Config, remoteBackend backend.Backend) error {
+	format, err := getOutputFormat(conf)
+	if err != nil {
+		return err
+	}
+
+	selectedClusterManager, err := selectClusterManager(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	if conf.GetBool("history_versions") {
+		versions, err := backend.StateVersions(remoteBackend, selectedClusterManager)
+		if err != nil {
+			return err
+		}
+
+		return printFormatted(os.Stdout, format, versions, func(w *tabwriter.Writer) {
+			printStateVersions(w, versions)
+		})
+	}
+
+	limit := defaultEventsLimit
+	if conf.IsSet("events_limit") {
+		limit = conf.GetInt("events_limit")
+	}
+
+	events, err := Events(remoteBackend, selectedClusterManager, conf.GetString("cluster_name"), limit)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 && format == "table" {
+		fmt.Println("No events.")
+		return nil
+	}
+
+	return printFormatted(os.Stdout, format, events, func(w *tabwriter.Writer) {
+		printHistory(w, events)
+	})
+}
+
+// Prints a line per version, newest first.
+func printStateVersions(w io.Writer, versions []backend.StateVersion) {
+	fmt.Fprintln(w, "VERSION\tSAVED")
+	for _, version := range versions {
+		fmt.Fprintf(w, "%s\t%s\n", version.ID, version.Saved.Local().Format(time.RFC3339))
+	}
+}
+
+// Prints a block per event, oldest first, with its changes and config diff.
+func printHistory(w io.Writer, events []journal.Event) {
+	for i, event := range events {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "%s  %s\n", event.StartedAt.Local().Format(time.RFC3339), event.Command)
+		fmt.Fprintf(w, "  User:\t%s\n", event.User)
+		fmt.Fprintf(w, "  Duration:\t%s\n", event.EndedAt.Sub(event.StartedAt).Round(time.Second))
+		fmt.Fprintf(w, "  Result:\t%s\n", event.Result)
+		if event.TerraformExitStatus != nil {
+			fmt.Fprintf(w, "  Terraform exit status:\t%d\n", *event.TerraformExitStatus)
+		}
+		if len(event.Clusters) > 0 {
+			fmt.Fprintf(w, "  Clusters:\t%s\n", strings.Join(event.Clusters, ", "))
+		}
+		if event.Error != "" {
+			fmt.Fprintf(w, "  Error:\t%s\n", strings.Replace(event.Error, "\n", " ", -1))
+		}
+		for _, change := range event.Changes {
+			fmt.Fprintf(w, "  Change:\t%s\n", change)
+		}
+		if len(event.ConfigDiff) > 0 {
+			fmt.Fprintln(w, "  Config diff:")
+			for _, line := range event.ConfigDiff {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+}
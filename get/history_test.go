@@ -0,0 +1,49 @@
+package get
+
+import (
+	"bytes"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/journal"
+)
+
+func TestPrintHistory(t *testing.T) {
+	startedAt := time.Date(2018, 5, 1, 10, 0, 0, 0, time.Local)
+	exitStatus := 1
+	events := []journal.Event{
+		{
+			Command:             "scale nodepool dev-w",
+			User:                "ops@bastion",
+			StartedAt:           startedAt,
+			EndedAt:             startedAt.Add(90 * time.Second),
+			Result:              journal.ResultFailed,
+			Error:               "exit status 1",
+			Clusters:            []string{"dev"},
+			Changes:             []string{"changed 1 node of cluster dev: dev-w"},
+			ConfigDiff:          []string{`~ module.node_aws_dev_dev-w.aws_asg_desired_capacity: "3" -> "5"`},
+			TerraformExitStatus: &exitStatus,
+		},
+	}
+
+	out := bytes.Buffer{}
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	printHistory(w, events)
+	w.Flush()
+
+	expected := startedAt.Format(time.RFC3339) + `  scale nodepool dev-w
+  User:                   ops@bastion
+  Duration:               1m30s
+  Result:                 failed
+  Terraform exit status:  1
+  Clusters:               dev
+  Error:                  exit status 1
+  Change:                 changed 1 node of cluster dev: dev-w
+  Config diff:
+    ~ module.node_aws_dev_dev-w.aws_asg_desired_capacity: "3" -> "5"
+`
+	if out.String() != expected {
+		t.Errorf("Wrong output, expected\n%s\nreceived\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,358 @@
+package get
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Formats get inventory prints in
+var inventoryFormats = []string{"json", "ini"}
+
+// Settings of the manager, cluster and node modules holding the user and the private key the
+// machines are reached with over SSH
+var (
+	inventoryUserSettings = []string{"ssh_user", "triton_ssh_user", "aws_ssh_user", "azure_ssh_user", "gcp_ssh_user", "openstack_ssh_user", "libvirt_ssh_user", "nutanix_ssh_user", "proxmox_ssh_user"}
+	inventoryKeySettings  = []string{"key_path", "triton_key_path", "aws_private_key_path", "azure_private_key_path", "gcp_private_key_path", "digitalocean_private_key_path", "equinix_metal_private_key_path", "openstack_private_key_path", "libvirt_key_path", "nutanix_key_path", "proxmox_key_path"}
+)
+
+// Ansible group names may only have letters, digits and underscores
+var inventoryGroupNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Inventory is an Ansible inventory of the machines of cluster managers and their clusters.
+type Inventory struct {
+	Groups   map[string]*InventoryGroup
+	HostVars map[string]map[string]string
+}
+
+// InventoryGroup is a group of an Inventory, its hosts and the groups it's made of.
+type InventoryGroup struct {
+	Hosts    []string `json:"hosts,omitempty"`
+	Children []string `json:"children,omitempty"`
+}
+
+// A machine of an inventory before it's named, nodes are named after their hostname unless
+// another cluster has a node of the same name. The first of its groups is a child of the group
+// of the cluster manager, the others of the first.
+type inventoryHost struct {
+	hostname      string
+	qualifiedName string
+	managerGroup  string
+	groups        []string
+	vars          map[string]string
+}
+
+// GetInventory prints an Ansible inventory of the cluster managers of the backend, or of
+// cluster_manager, in the JSON of dynamic inventory scripts or as an ini file.
+//
+// A cluster manager {manager} is the group {manager}, made of {manager}_manager, the hosts
+// running Rancher, and a group {manager}_{cluster} of each cluster. The nodes of a cluster are
+// also in {manager}_{cluster}_etcd, _control and _worker by role, and the instances of its node
+// pools in {manager}_{cluster}_node_pool. Hosts have ansible_host, ansible_user and
+// ansible_ssh_private_key_file when they're known.
+func GetInventory(conf config.Config, remoteBackend backend.Backend) error {
+	format := "json"
+	if conf.IsSet("inventory_format") {
+		format = conf.GetString("inventory_format")
+		if !containsString(inventoryFormats, format) {
+			return util.ConfigError(fmt.Errorf("Invalid inventory_format '%s', must be json or ini.", format))
+		}
+	}
+
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+	if conf.IsSet("cluster_manager") {
+		clusterManagers = []string{conf.GetString("cluster_manager")}
+	}
+	sort.Strings(clusterManagers)
+
+	hosts := []inventoryHost{}
+	for _, manager := range clusterManagers {
+		currentState, err := managerState(remoteBackend, manager)
+		if err != nil {
+			return err
+		}
+
+		managerHosts, err := inventoryManagerHosts(conf, currentState)
+		if err != nil {
+			return err
+		}
+		hosts = append(hosts, managerHosts...)
+
+		clusters, err := currentState.Clusters()
+		if err != nil {
+			return err
+		}
+		for _, name := range sortedClusterNames(clusters) {
+			nodeHosts, err := inventoryNodeHosts(conf, currentState, name, clusters[name])
+			if err != nil {
+				// The rest of the fleet can still be configured
+				fmt.Fprintf(os.Stderr, "Warning: the nodes of cluster '%s' of cluster manager '%s' are left out: %s\n", name, manager, err)
+				continue
+			}
+			hosts = append(hosts, nodeHosts...)
+		}
+	}
+
+	inventory := newInventory(hosts)
+	if format == "ini" {
+		return inventory.WriteINI(os.Stdout)
+	}
+
+	content, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(content))
+	return err
+}
+
+// Returns the hosts running Rancher: the hosts of an HA cluster manager, or the host of its
+// rancher_url.
+func inventoryManagerHosts(conf config.Config, currentState state.State) ([]inventoryHost, error) {
+	outputs, err := shell.RunTerraformOutputWithState(conf, currentState, "cluster-manager")
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+	if ips, ok := outputs["rancher_host_ips"].([]interface{}); ok {
+		for _, ip := range ips {
+			if address, ok := ip.(string); ok && address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		rancherURL, _ := outputs["rancher_url"].(string)
+		parsedURL, err := url.Parse(rancherURL)
+		if err != nil || parsedURL.Hostname() == "" {
+			return nil, fmt.Errorf("Unable to find the address of cluster manager '%s', its rancher_url is '%s'.", currentState.Name, rancherURL)
+		}
+		addresses = append(addresses, parsedURL.Hostname())
+	}
+
+	user, keyPath := inventorySSHSettings(currentState, "cluster-manager")
+	group := inventoryGroupName(currentState.Name, "manager")
+	hosts := []inventoryHost{}
+	for i, address := range addresses {
+		name := fmt.Sprintf("%s-rancher", currentState.Name)
+		if len(addresses) > 1 {
+			name = fmt.Sprintf("%s-rancher-%d", currentState.Name, i+1)
+		}
+		hosts = append(hosts, inventoryHost{
+			hostname:      name,
+			qualifiedName: name,
+			managerGroup:  inventoryGroupName(currentState.Name),
+			groups:        []string{group},
+			vars:          inventoryHostVars(address, user, keyPath),
+		})
+	}
+	return hosts, nil
+}
+
+// Returns the nodes of a cluster registered in Rancher. Nodes that never registered have no
+// address to reach them at.
+func inventoryNodeHosts(conf config.Config, currentState state.State, clusterName, clusterKey string) ([]inventoryHost, error) {
+	rancherNodes, err := getCachedRancherNodes(conf, currentState, clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stateNodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes use the key generated for their cluster, or else the user and key of their cluster
+	clusterUser, clusterKeyPath := inventorySSHSettings(currentState, clusterKey)
+	if sshKey, ok := currentState.SSHKey(clusterKey); ok {
+		clusterKeyPath = sshKey.PrivateKeyPath
+	}
+
+	clusterGroup := inventoryGroupName(currentState.Name, clusterName)
+	hosts := []inventoryHost{}
+	for _, node := range rancherNodes {
+		address := node.ExternalIPAddress
+		if address == "" {
+			address = node.IPAddress
+		}
+		if address == "" {
+			continue
+		}
+
+		groups := []string{clusterGroup}
+		for _, role := range nodeRoles(node) {
+			groups = append(groups, inventoryGroupName(currentState.Name, clusterName, role))
+		}
+
+		user, keyPath := clusterUser, clusterKeyPath
+		if nodeKey, ok := stateNodes[node.Hostname]; ok {
+			nodeUser, nodeKeyPath := inventorySSHSettings(currentState, nodeKey)
+			if nodeUser != "" {
+				user = nodeUser
+			}
+			if nodeKeyPath != "" {
+				keyPath = nodeKeyPath
+			}
+		} else {
+			// The instances of node pools are named by the cloud provider
+			groups = append(groups, inventoryGroupName(currentState.Name, clusterName, "node_pool"))
+		}
+
+		hosts = append(hosts, inventoryHost{
+			hostname:      node.Hostname,
+			qualifiedName: fmt.Sprintf("%s-%s-%s", currentState.Name, clusterName, node.Hostname),
+			managerGroup:  inventoryGroupName(currentState.Name),
+			groups:        groups,
+			vars:          inventoryHostVars(address, user, keyPath),
+		})
+	}
+	return hosts, nil
+}
+
+// Returns the SSH user and private key path of a module, empty if the module has none.
+func inventorySSHSettings(currentState state.State, moduleKey string) (user, keyPath string) {
+	for _, setting := range inventoryUserSettings {
+		if value := currentState.Get(fmt.Sprintf("module.%s.%s", moduleKey, setting)); value != "" {
+			user = value
+			break
+		}
+	}
+	for _, setting := range inventoryKeySettings {
+		if value := currentState.Get(fmt.Sprintf("module.%s.%s", moduleKey, setting)); value != "" {
+			keyPath = value
+			break
+		}
+	}
+	return user, keyPath
+}
+
+func inventoryHostVars(address, user, keyPath string) map[string]string {
+	vars := map[string]string{"ansible_host": address}
+	if user != "" {
+		vars["ansible_user"] = user
+	}
+	if keyPath != "" {
+		vars["ansible_ssh_private_key_file"] = keyPath
+	}
+	return vars
+}
+
+func inventoryGroupName(parts ...string) string {
+	return inventoryGroupNameRegexp.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+// Returns the inventory of the hosts.
+func newInventory(hosts []inventoryHost) Inventory {
+	hostnameCounts := map[string]int{}
+	for _, host := range hosts {
+		hostnameCounts[host.hostname]++
+	}
+
+	inventory := Inventory{
+		Groups:   map[string]*InventoryGroup{},
+		HostVars: map[string]map[string]string{},
+	}
+	for _, host := range hosts {
+		name := host.hostname
+		if hostnameCounts[name] > 1 {
+			name = host.qualifiedName
+		}
+		inventory.HostVars[name] = host.vars
+
+		for i, group := range host.groups {
+			inventory.group(group).Hosts = append(inventory.group(group).Hosts, name)
+			if i == 0 {
+				inventory.addChild(host.managerGroup, group)
+			} else {
+				inventory.addChild(host.groups[0], group)
+			}
+		}
+	}
+	return inventory
+}
+
+func (inventory Inventory) group(name string) *InventoryGroup {
+	group, ok := inventory.Groups[name]
+	if !ok {
+		group = &InventoryGroup{}
+		inventory.Groups[name] = group
+	}
+	return group
+}
+
+func (inventory Inventory) addChild(parent, child string) {
+	group := inventory.group(parent)
+	if !containsString(group.Children, child) {
+		group.Children = append(group.Children, child)
+	}
+}
+
+// MarshalJSON returns the inventory as printed by a dynamic inventory script for --list.
+func (inventory Inventory) MarshalJSON() ([]byte, error) {
+	value := map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"hostvars": inventory.HostVars,
+		},
+	}
+	for name, group := range inventory.Groups {
+		value[name] = group
+	}
+	return json.Marshal(value)
+}
+
+// WriteINI writes the inventory as an ini inventory file.
+func (inventory Inventory) WriteINI(out io.Writer) error {
+	names := make([]string, 0, len(inventory.Groups))
+	for name := range inventory.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := inventory.Groups[name]
+		if len(group.Hosts) > 0 {
+			fmt.Fprintf(out, "[%s]\n", name)
+			for _, host := range group.Hosts {
+				fmt.Fprintln(out, inventoryINIHost(host, inventory.HostVars[host]))
+			}
+			fmt.Fprintln(out)
+		}
+		if len(group.Children) > 0 {
+			fmt.Fprintf(out, "[%s:children]\n", name)
+			for _, child := range group.Children {
+				fmt.Fprintln(out, child)
+			}
+			fmt.Fprintln(out)
+		}
+	}
+	return nil
+}
+
+func inventoryINIHost(name string, vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	line := name
+	for _, key := range keys {
+		line += fmt.Sprintf(" %s=%s", key, vars[key])
+	}
+	return line
+}
@@ -0,0 +1,114 @@
+package get
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func testInventoryHosts() []inventoryHost {
+	return []inventoryHost{
+		{
+			hostname:      "dev-manager-rancher",
+			qualifiedName: "dev-manager-rancher",
+			managerGroup:  "dev_manager",
+			groups:        []string{"dev_manager_manager"},
+			vars:          map[string]string{"ansible_host": "1.2.3.4", "ansible_user": "ubuntu"},
+		},
+		{
+			hostname:      "w-1",
+			qualifiedName: "dev-manager-blue-w-1",
+			managerGroup:  "dev_manager",
+			groups:        []string{"dev_manager_blue", "dev_manager_blue_worker"},
+			vars:          map[string]string{"ansible_host": "10.0.0.2"},
+		},
+		{
+			hostname:      "w-1",
+			qualifiedName: "dev-manager-green-w-1",
+			managerGroup:  "dev_manager",
+			groups:        []string{"dev_manager_green", "dev_manager_green_worker", "dev_manager_green_node_pool"},
+			vars:          map[string]string{"ansible_host": "10.0.1.2"},
+		},
+	}
+}
+
+func TestInventoryJSON(t *testing.T) {
+	content, err := json.Marshal(newInventory(testInventoryHosts()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hostnames of several clusters are qualified with the cluster manager and cluster
+	expected := `{"_meta":{"hostvars":{"dev-manager-blue-w-1":{"ansible_host":"10.0.0.2"},"dev-manager-green-w-1":{"ansible_host":"10.0.1.2"},"dev-manager-rancher":{"ansible_host":"1.2.3.4","ansible_user":"ubuntu"}}},` +
+		`"dev_manager":{"children":["dev_manager_manager","dev_manager_blue","dev_manager_green"]},` +
+		`"dev_manager_blue":{"hosts":["dev-manager-blue-w-1"],"children":["dev_manager_blue_worker"]},` +
+		`"dev_manager_blue_worker":{"hosts":["dev-manager-blue-w-1"]},` +
+		`"dev_manager_green":{"hosts":["dev-manager-green-w-1"],"children":["dev_manager_green_worker","dev_manager_green_node_pool"]},` +
+		`"dev_manager_green_node_pool":{"hosts":["dev-manager-green-w-1"]},` +
+		`"dev_manager_green_worker":{"hosts":["dev-manager-green-w-1"]},` +
+		`"dev_manager_manager":{"hosts":["dev-manager-rancher"]}}`
+	if string(content) != expected {
+		t.Errorf("Wrong output, expected %s, received %s", expected, content)
+	}
+}
+
+func TestInventoryINI(t *testing.T) {
+	hosts := testInventoryHosts()[:2]
+
+	out := bytes.Buffer{}
+	err := newInventory(hosts).WriteINI(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[dev_manager:children]
+dev_manager_manager
+dev_manager_blue
+
+[dev_manager_blue]
+w-1 ansible_host=10.0.0.2
+
+[dev_manager_blue:children]
+dev_manager_blue_worker
+
+[dev_manager_blue_worker]
+w-1 ansible_host=10.0.0.2
+
+[dev_manager_manager]
+dev-manager-rancher ansible_host=1.2.3.4 ansible_user=ubuntu
+
+`
+	if out.String() != expected {
+		t.Errorf("Wrong output, expected %s, received %s", expected, out.String())
+	}
+}
+
+func TestInventorySSHSettings(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module": {
+		"cluster-manager": {"aws_ssh_user": "ubuntu", "aws_public_key_path": "~/.ssh/id_rsa.pub", "aws_private_key_path": "~/.ssh/id_rsa"},
+		"node_bare-metal_dev_w-1": {"ssh_user": "centos", "key_path": "~/.ssh/bare_metal"},
+		"node_aws_dev_w-2": {"aws_key_name": "dev"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		moduleKey       string
+		expectedUser    string
+		expectedKeyPath string
+	}{
+		{"cluster-manager", "ubuntu", "~/.ssh/id_rsa"},
+		{"node_bare-metal_dev_w-1", "centos", "~/.ssh/bare_metal"},
+		{"node_aws_dev_w-2", "", ""},
+	}
+
+	for _, test := range tests {
+		user, keyPath := inventorySSHSettings(currentState, test.moduleKey)
+		if user != test.expectedUser || keyPath != test.expectedKeyPath {
+			t.Errorf("Wrong output for %s, expected %s %s, received %s %s", test.moduleKey, test.expectedUser, test.expectedKeyPath, user, keyPath)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package get
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Formats get clusters and get nodes print in, table unless get_output is set
+var outputFormats = []string{"table", "json", "yaml"}
+
+// Cluster modules are sourced from `terraform/modules/{provider}-rancher-k8s`
+var clusterSourceRegexp = regexp.MustCompile(`terraform/modules/([a-z-]+)-rancher-k8s\?`)
+
+// Node pool modules are backed by an instance group whose instances register themselves
+var nodePoolSourceRegexp = regexp.MustCompile(`terraform/modules/[a-z-]+-rancher-k8s-(asg|vmss|mig)\?`)
+
+// ClusterStatus is a cluster of a cluster manager, as stored in the state and as seen by Rancher.
+type ClusterStatus struct {
+	Name              string `json:"name" yaml:"name"`
+	Provider          string `json:"provider" yaml:"provider"`
+	RancherClusterID  string `json:"rancher_cluster_id,omitempty" yaml:"rancher_cluster_id,omitempty"`
+	State             string `json:"state" yaml:"state"`
+	KubernetesVersion string `json:"k8s_version,omitempty" yaml:"k8s_version,omitempty"`
+	Nodes             int    `json:"nodes" yaml:"nodes"`
+}
+
+// NodeStatus is a node of a cluster, as stored in the state and as seen by Rancher. Nodes of the
+// state that aren't registered in Rancher have no Rancher fields.
+type NodeStatus struct {
+	Hostname          string   `json:"hostname" yaml:"hostname"`
+	Roles             []string `json:"roles" yaml:"roles"`
+	State             string   `json:"state" yaml:"state"`
+	KubernetesVersion string   `json:"k8s_version,omitempty" yaml:"k8s_version,omitempty"`
+	IPAddress         string   `json:"ip_address,omitempty" yaml:"ip_address,omitempty"`
+	ExternalIPAddress string   `json:"external_ip_address,omitempty" yaml:"external_ip_address,omitempty"`
+	LastHeartbeat     string   `json:"last_heartbeat,omitempty" yaml:"last_heartbeat,omitempty"`
+}
+
+// GetClusters prints the clusters of a cluster manager with their live state in Rancher.
+func GetClusters(conf config.Config, remoteBackend backend.Backend) error {
+	format, err := getOutputFormat(conf)
+	if err != nil {
+		return err
+	}
+
+	selectedClusterManager, err := selectClusterManager(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := ClusterStatuses(conf, remoteBackend, selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	return printFormatted(os.Stdout, format, clusters, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "NAME\tPROVIDER\tSTATE\tK8S VERSION\tNODES")
+		for _, cluster := range clusters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", cluster.Name, cluster.Provider, cluster.State, cluster.KubernetesVersion, cluster.Nodes)
+		}
+	})
+}
+
+// GetNodes prints the nodes of a cluster with their live state in Rancher.
+func GetNodes(conf config.Config, remoteBackend backend.Backend) error {
+	format, err := getOutputFormat(conf)
+	if err != nil {
+		return err
+	}
+
+	selectedClusterManager, err := selectClusterManager(conf, remoteBackend)
+	if err != nil {
+		return err
+	}
+
+	clusterNames, err := Clusters(remoteBackend, selectedClusterManager)
+	if err != nil {
+		return err
+	}
+	if len(clusterNames) == 0 {
+		return fmt.Errorf("No clusters.")
+	}
+
+	selectedCluster := ""
+	if conf.IsSet("cluster_name") {
+		selectedCluster = conf.GetString("cluster_name")
+	} else if conf.GetBool("non-interactive") {
+		return util.ConfigError(errors.New("cluster_name must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster",
+			Items: clusterNames,
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		selectedCluster = value
+	}
+
+	nodes, err := NodeStatuses(conf, remoteBackend, selectedClusterManager, selectedCluster)
+	if err != nil {
+		return err
+	}
+
+	return printFormatted(os.Stdout, format, nodes, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "HOSTNAME\tROLES\tSTATE\tK8S VERSION\tIP\tEXTERNAL IP\tLAST HEARTBEAT")
+		for _, node := range nodes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", node.Hostname, strings.Join(node.Roles, ","), node.State, node.KubernetesVersion, node.IPAddress, node.ExternalIPAddress, node.LastHeartbeat)
+		}
+	})
+}
+
+// ClusterStatuses returns the clusters of a cluster manager, sorted by name, with their state,
+// Kubernetes version and number of nodes in Rancher. Clusters Rancher has no id for are
+// "not created".
+func ClusterStatuses(conf config.Config, remoteBackend backend.Backend, manager string) ([]ClusterStatus, error) {
+	currentState, err := managerState(remoteBackend, manager)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) == 0 {
+		return []ClusterStatus{}, nil
+	}
+
+	client, err := rancher.NewClientFromState(conf, currentState)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ClusterStatus{}
+	for _, name := range sortedClusterNames(clusters) {
+		clusterKey := clusters[name]
+		status := ClusterStatus{
+			Name:     name,
+			Provider: "unknown",
+			State:    "not created",
+		}
+		if match := clusterSourceRegexp.FindStringSubmatch(currentState.Get(fmt.Sprintf("module.%s.source", clusterKey))); match != nil {
+			status.Provider = match[1]
+		}
+
+		clusterOutputs, err := shell.RunTerraformOutputWithState(conf, currentState, clusterKey)
+		if err != nil {
+			return nil, err
+		}
+		status.RancherClusterID, _ = clusterOutputs["rancher_cluster_id"].(string)
+		if status.RancherClusterID == "" {
+			result = append(result, status)
+			continue
+		}
+
+		cluster, err := client.Cluster(status.RancherClusterID)
+		if err != nil {
+			return nil, err
+		}
+		status.State = cluster.State
+		if cluster.Version != nil {
+			status.KubernetesVersion = cluster.Version.GitVersion
+		} else if cluster.RKEConfig != nil {
+			status.KubernetesVersion = cluster.RKEConfig.KubernetesVersion
+		}
+
+		nodes, err := client.Nodes(status.RancherClusterID)
+		if err != nil {
+			return nil, err
+		}
+		status.Nodes = len(nodes)
+
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+// NodeStatuses returns the nodes of a cluster, sorted by hostname: the nodes registered in
+// Rancher with their live state, followed by the nodes of the state Rancher doesn't know of,
+// which are "not registered". When Rancher can't be reached, the nodes it last returned are
+// used, with a warning that they're stale.
+func NodeStatuses(conf config.Config, remoteBackend backend.Backend, manager, cluster string) ([]NodeStatus, error) {
+	currentState, clusterKey, err := clusterState(remoteBackend, manager, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	rancherNodes, err := getCachedRancherNodes(conf, currentState, clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	stateNodes, err := currentState.Nodes(clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeStatuses(currentState, stateNodes, rancherNodes), nil
+}
+
+func nodeStatuses(currentState state.State, stateNodes map[string]string, rancherNodes []rancher.Node) []NodeStatus {
+	result := []NodeStatus{}
+	registered := map[string]bool{}
+	for _, node := range rancherNodes {
+		registered[node.Hostname] = true
+		result = append(result, NodeStatus{
+			Hostname:          node.Hostname,
+			Roles:             nodeRoles(node),
+			State:             node.State,
+			KubernetesVersion: node.KubernetesVersion(),
+			IPAddress:         node.IPAddress,
+			ExternalIPAddress: node.ExternalIPAddress,
+			LastHeartbeat:     node.LastHeartbeat(),
+		})
+	}
+
+	for hostname, nodeKey := range stateNodes {
+		// The instances of node pools are named by the cloud provider
+		if registered[hostname] || nodePoolSourceRegexp.MatchString(currentState.Get(fmt.Sprintf("module.%s.source", nodeKey))) {
+			continue
+		}
+		result = append(result, NodeStatus{
+			Hostname: hostname,
+			Roles:    []string{},
+			State:    "not registered",
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Hostname < result[j].Hostname
+	})
+	return result
+}
+
+func nodeRoles(node rancher.Node) []string {
+	roles := []string{}
+	if node.ControlPlane {
+		roles = append(roles, "control")
+	}
+	if node.Etcd {
+		roles = append(roles, "etcd")
+	}
+	if node.Worker {
+		roles = append(roles, "worker")
+	}
+	return roles
+}
+
+// Prints value as JSON or YAML, or as the table printTable writes.
+func printFormatted(out io.Writer, format string, value interface{}, printTable func(w *tabwriter.Writer)) error {
+	switch format {
+	case "json":
+		content, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(content))
+		return err
+	case "yaml":
+		content, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(content)
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	printTable(w)
+	return w.Flush()
+}
+
+func getOutputFormat(conf config.Config) (string, error) {
+	if !conf.IsSet("get_output") {
+		return "table", nil
+	}
+
+	format := conf.GetString("get_output")
+	if !containsString(outputFormats, format) {
+		return "", util.ConfigError(fmt.Errorf("Invalid output format '%s', must be table, json or yaml.", format))
+	}
+	return format, nil
+}
+
+func selectClusterManager(conf config.Config, remoteBackend backend.Backend) (string, error) {
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return "", err
+	}
+
+	if len(clusterManagers) == 0 {
+		return "", fmt.Errorf("No cluster managers.")
+	}
+
+	if conf.IsSet("cluster_manager") {
+		return conf.GetString("cluster_manager"), nil
+	} else if conf.GetBool("non-interactive") {
+		return "", util.ConfigError(errors.New("cluster_manager must be specified"))
+	}
+
+	prompt := promptui.Select{
+		Label: "Cluster Manager",
+		Items: clusterManagers,
+	}
+
+	_, value, err := prompt.Run()
+	return value, err
+}
+
+func sortedClusterNames(clusters map[string]string) []string {
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,100 @@
+package get
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+func TestNodeStatuses(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module": {
+		"node_triton_dev_dev-w-1": {"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher-k8s-host?ref=master"},
+		"node_triton_dev_dev-w-2": {"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher-k8s-host?ref=master"},
+		"node_aws_dev_dev-pool": {"source": "github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher-k8s-asg?ref=master"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stateNodes := map[string]string{
+		"dev-w-1":  "node_triton_dev_dev-w-1",
+		"dev-w-2":  "node_triton_dev_dev-w-2",
+		"dev-pool": "node_aws_dev_dev-pool",
+	}
+	rancherNodes := []rancher.Node{
+		{
+			Hostname:     "dev-w-1",
+			State:        "active",
+			ControlPlane: true,
+			Etcd:         true,
+			IPAddress:    "10.0.0.2",
+			Info:         &rancher.NodeInfo{},
+			Conditions:   []rancher.NodeCondition{{Type: "Ready", Status: "True", LastHeartbeatTime: "2018-05-01T10:00:00Z"}},
+		},
+		{Hostname: "dev-pool-i-0abc", State: "active", Worker: true},
+	}
+	rancherNodes[0].Info.Kubernetes.KubeletVersion = "v1.15.12"
+
+	statuses := nodeStatuses(currentState, stateNodes, rancherNodes)
+
+	expected := []string{
+		"dev-pool-i-0abc [worker] active  ",
+		"dev-w-1 [control etcd] active v1.15.12 10.0.0.2 2018-05-01T10:00:00Z",
+		"dev-w-2 [] not registered  ",
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Wrong output, expected %d nodes, received %+v", len(expected), statuses)
+	}
+	for i, status := range statuses {
+		actual := fmt.Sprintf("%s %v %s %s %s", status.Hostname, status.Roles, status.State, status.KubernetesVersion, status.IPAddress)
+		if status.LastHeartbeat != "" {
+			actual += " " + status.LastHeartbeat
+		}
+		if actual != expected[i] {
+			t.Errorf("Wrong output, expected '%s', received '%s'", expected[i], actual)
+		}
+	}
+}
+
+func TestPrintFormatted(t *testing.T) {
+	clusters := []ClusterStatus{{Name: "dev", Provider: "triton", State: "active", KubernetesVersion: "v1.15.12", Nodes: 3}}
+	printTable := func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "NAME\tNODES")
+		fmt.Fprintf(w, "%s\t%d\n", clusters[0].Name, clusters[0].Nodes)
+	}
+
+	for format, expected := range map[string]string{
+		"json":  "[\n  {\n    \"name\": \"dev\",\n    \"provider\": \"triton\",\n    \"state\": \"active\",\n    \"k8s_version\": \"v1.15.12\",\n    \"nodes\": 3\n  }\n]\n",
+		"yaml":  "- name: dev\n  provider: triton\n  state: active\n  k8s_version: v1.15.12\n  nodes: 3\n",
+		"table": "NAME  NODES\ndev   3\n",
+	} {
+		var out bytes.Buffer
+		err := printFormatted(&out, format, clusters, printTable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != expected {
+			t.Errorf("Wrong %s output, expected %q, received %q", format, expected, out.String())
+		}
+	}
+}
+
+func TestGetOutputFormat(t *testing.T) {
+	conf := config.New()
+	format, err := getOutputFormat(conf)
+	if err != nil || format != "table" {
+		t.Errorf("Expected the table format by default, received %s, %v", format, err)
+	}
+
+	conf.Set("get_output", "xml")
+	_, err = getOutputFormat(conf)
+	expected := "Invalid output format 'xml', must be table, json or yaml."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected '%s', received %v", expected, err)
+	}
+}
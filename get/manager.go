@@ -4,17 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
 
 	"github.com/manifoldco/promptui"
-	"github.com/spf13/viper"
 )
 
-func GetManager(remoteBackend backend.Backend) error {
-	nonInteractiveMode := viper.GetBool("non-interactive")
+func GetManager(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
 	clusterManagers, err := remoteBackend.States()
 	if err != nil {
 		return err
@@ -25,10 +25,10 @@ func GetManager(remoteBackend backend.Backend) error {
 	}
 
 	selectedClusterManager := ""
-	if viper.IsSet("cluster_manager") {
-		selectedClusterManager = viper.GetString("cluster_manager")
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
 	} else if nonInteractiveMode {
-		return errors.New("cluster_manager must be specified")
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
 	} else {
 		prompt := promptui.Select{
 			Label: "Cluster Manager",
@@ -60,16 +60,16 @@ func GetManager(remoteBackend backend.Backend) error {
 		return err
 	}
 
-	// Create a temporary directory
-	tempDir, err := ioutil.TempDir("", "triton-kubernetes-")
+	// Create a working directory
+	tempDir, cleanup, err := shell.NewWorkingDir(conf)
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tempDir)
+	defer cleanup()
 
 	// Save the terraform config to the temporary directory
 	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
-	err = ioutil.WriteFile(jsonPath, state.Bytes(), 0644)
+	err = ioutil.WriteFile(jsonPath, state.Bytes(), 0600)
 	if err != nil {
 		return err
 	}
@@ -79,17 +79,9 @@ func GetManager(remoteBackend backend.Backend) error {
 		WorkingDir: tempDir,
 	}
 
-	// Run terraform init
-	err = shell.RunShellCommand(&shellOptions, "terraform", "init", "-force-copy")
-	if err != nil {
-		return err
-	}
+	// Run terraform init, printing cached outputs if it fails
+	initErr := shell.RunShellCommand(&shellOptions, "terraform", "init", "-force-copy")
 
 	// Run terraform output
-	err = shell.RunShellCommand(&shellOptions, "terraform", "output", "-module", "cluster-manager")
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return printModuleOutputs(conf, &shellOptions, initErr, state, "cluster-manager")
 }
@@ -0,0 +1,111 @@
+package get
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/journal"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// The functions below return what the Get commands print. They never prompt, so programs
+// embedding triton-kubernetes can use them.
+
+// Clusters returns the names of the clusters of a cluster manager, sorted.
+func Clusters(remoteBackend backend.Backend, manager string) ([]string, error) {
+	currentState, err := managerState(remoteBackend, manager)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ManagerOutputs returns the terraform outputs of a cluster manager, e.g. rancher_url.
+func ManagerOutputs(conf config.Config, remoteBackend backend.Backend, manager string) (map[string]interface{}, error) {
+	currentState, err := managerState(remoteBackend, manager)
+	if err != nil {
+		return nil, err
+	}
+	return shell.RunTerraformOutputWithState(conf, currentState, "cluster-manager")
+}
+
+// ClusterOutputs returns the terraform outputs of a cluster, e.g. rancher_cluster_id.
+func ClusterOutputs(conf config.Config, remoteBackend backend.Backend, manager, cluster string) (map[string]interface{}, error) {
+	currentState, clusterKey, err := clusterState(remoteBackend, manager, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return shell.RunTerraformOutputWithState(conf, currentState, clusterKey)
+}
+
+// ClusterNodes returns the nodes of a cluster registered in Rancher, with their state.
+func ClusterNodes(conf config.Config, remoteBackend backend.Backend, manager, cluster string) ([]rancher.Node, error) {
+	currentState, clusterKey, err := clusterState(remoteBackend, manager, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return getRancherNodes(conf, currentState, clusterKey)
+}
+
+// Events returns the last limit operations run on a cluster manager, of the given cluster if
+// it isn't empty. A limit of 0 or less returns every operation.
+func Events(remoteBackend backend.Backend, manager, cluster string, limit int) ([]journal.Event, error) {
+	currentState, err := managerState(remoteBackend, manager)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := journal.Events(currentState)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterEvents(events, cluster, limit), nil
+}
+
+func managerState(remoteBackend backend.Backend, manager string) (state.State, error) {
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return state.State{}, err
+	}
+
+	if !containsString(clusterManagers, manager) {
+		return state.State{}, fmt.Errorf("Selected cluster manager '%s' does not exist.", manager)
+	}
+
+	return remoteBackend.State(manager)
+}
+
+func clusterState(remoteBackend backend.Backend, manager, cluster string) (state.State, string, error) {
+	currentState, err := managerState(remoteBackend, manager)
+	if err != nil {
+		return state.State{}, "", err
+	}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return state.State{}, "", err
+	}
+
+	clusterKey, ok := clusters[cluster]
+	if !ok {
+		return state.State{}, "", fmt.Errorf("A cluster named '%s', does not exist.", cluster)
+	}
+
+	return currentState, clusterKey, nil
+}
@@ -0,0 +1,85 @@
+package get
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// GetTerraformBackend prints the terraform backend block that keeps the terraform state of a
+// cluster manager, e.g. the bucket and key on S3, so terraform can be run against the state the
+// team shares. `tf_config_format` selects json (the default) or hcl.
+func GetTerraformBackend(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	serializer, err := state.NewSerializer(conf.GetString("tf_config_format"))
+	if err != nil {
+		return err
+	}
+
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	// Only the backend block is printed, not the rest of the cluster manager's config
+	backendState, err := state.New(selectedClusterManager, []byte("{}"))
+	if err != nil {
+		return err
+	}
+
+	err = backendState.SetTerraformBackendConfig(remoteBackend.StateTerraformConfig(selectedClusterManager))
+	if err != nil {
+		return err
+	}
+
+	raw, err := serializer.Serialize(&backendState)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(raw)
+	return err
+}
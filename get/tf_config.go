@@ -0,0 +1,98 @@
+package get
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// GetTerraformConfig prints the terraform configuration of a cluster manager, or writes it to
+// the `tf_config_dir` directory. `tf_config_format` selects json (the default) or hcl.
+func GetTerraformConfig(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+
+	serializer, err := state.NewSerializer(conf.GetString("tf_config_format"))
+	if err != nil {
+		return err
+	}
+
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	raw, err := serializer.Serialize(&currentState)
+	if err != nil {
+		return err
+	}
+
+	outputDir := conf.GetString("tf_config_dir")
+	if outputDir == "" {
+		_, err = os.Stdout.Write(raw)
+		return err
+	}
+
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	outputPath := filepath.Join(outputDir, serializer.Filename())
+	err = ioutil.WriteFile(outputPath, raw, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Terraform config written to %s\n", outputPath)
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package journal
+
+import (
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// trackingBackend remembers the states an operation read and persisted through it.
+type trackingBackend struct {
+	backend backend.Backend
+
+	// Names of the states read, in order
+	order []string
+	// States as they were first read, and as they were last persisted
+	read      map[string][]byte
+	persisted map[string][]byte
+	deleted   map[string]bool
+}
+
+func newTrackingBackend(remoteBackend backend.Backend) *trackingBackend {
+	return &trackingBackend{
+		backend:   remoteBackend,
+		read:      map[string][]byte{},
+		persisted: map[string][]byte{},
+		deleted:   map[string]bool{},
+	}
+}
+
+func (backend *trackingBackend) State(name string) (state.State, error) {
+	currentState, err := backend.backend.State(name)
+	if err != nil {
+		return currentState, err
+	}
+
+	if _, ok := backend.read[name]; !ok {
+		backend.order = append(backend.order, name)
+		backend.read[name] = currentState.Bytes()
+	}
+
+	return currentState, nil
+}
+
+func (backend *trackingBackend) DeleteState(name string) error {
+	err := backend.backend.DeleteState(name)
+	if err != nil {
+		return err
+	}
+
+	backend.deleted[name] = true
+	return nil
+}
+
+func (backend *trackingBackend) PersistState(currentState state.State) error {
+	err := backend.backend.PersistState(currentState)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := backend.read[currentState.Name]; !ok {
+		backend.order = append(backend.order, currentState.Name)
+		backend.read[currentState.Name] = []byte("{}")
+	}
+	backend.persisted[currentState.Name] = currentState.Bytes()
+	return nil
+}
+
+func (backend *trackingBackend) States() ([]string, error) {
+	return backend.backend.States()
+}
+
+func (backend *trackingBackend) StateTerraformConfig(name string) (string, interface{}) {
+	return backend.backend.StateTerraformConfig(name)
+}
@@ -0,0 +1,157 @@
+package journal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// What a module of the state is
+type moduleInfo struct {
+	// cluster manager, cluster, node, addon or module
+	kind    string
+	name    string
+	cluster string
+}
+
+// A group of modules of one kind that were added, removed or changed in one cluster
+type changeGroup struct {
+	verb    string
+	kind    string
+	cluster string
+}
+
+// Returns the changes between two versions of a state, e.g. "added 3 nodes to cluster
+// prod-eu: prod-eu-w-4, prod-eu-w-5, prod-eu-w-6", and the names of the clusters changed.
+func describeChanges(name string, before, after []byte) ([]string, []string) {
+	beforeState, err := state.New(name, before)
+	if err != nil {
+		return nil, nil
+	}
+	afterState, err := state.New(name, after)
+	if err != nil {
+		return nil, nil
+	}
+
+	beforeModules := beforeState.GetMap("module")
+	afterModules := afterState.GetMap("module")
+
+	// Removed modules are only described by the old state
+	info := map[string]moduleInfo{}
+	indexModules(beforeState, info)
+	indexModules(afterState, info)
+
+	groups := map[changeGroup][]string{}
+	addChange := func(verb, key string) {
+		module, ok := info[key]
+		if !ok {
+			module = moduleInfo{kind: "module", name: key}
+		}
+		group := changeGroup{verb: verb, kind: module.kind, cluster: module.cluster}
+		groups[group] = append(groups[group], module.name)
+	}
+
+	for key, afterModule := range afterModules {
+		beforeModule, ok := beforeModules[key]
+		if !ok {
+			addChange("added", key)
+		} else if !reflect.DeepEqual(beforeModule, afterModule) {
+			addChange("changed", key)
+		}
+	}
+	for key := range beforeModules {
+		if _, ok := afterModules[key]; !ok {
+			addChange("removed", key)
+		}
+	}
+
+	changes := []string{}
+	clusterSet := map[string]bool{}
+	for group, names := range groups {
+		sort.Strings(names)
+		changes = append(changes, formatChange(group, names))
+		if group.cluster != "" {
+			clusterSet[group.cluster] = true
+		}
+	}
+	sort.Strings(changes)
+
+	// Settings outside of the modules, e.g. a rotated Rancher API token
+	if len(changes) == 0 && !reflect.DeepEqual(getSettings(beforeState), getSettings(afterState)) {
+		changes = append(changes, "changed cluster manager settings")
+	}
+
+	clusters := []string{}
+	for cluster := range clusterSet {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	return changes, clusters
+}
+
+// Adds the cluster manager, clusters, nodes and addons of the state to info, by module key.
+func indexModules(currentState state.State, info map[string]moduleInfo) {
+	if currentState.GetMap("module") == nil {
+		return
+	}
+
+	info["cluster-manager"] = moduleInfo{kind: "cluster manager", name: currentState.Name}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return
+	}
+	for clusterName, clusterKey := range clusters {
+		info[clusterKey] = moduleInfo{kind: "cluster", name: clusterName, cluster: clusterName}
+
+		nodes, err := currentState.Nodes(clusterKey)
+		if err == nil {
+			for hostname, nodeKey := range nodes {
+				info[nodeKey] = moduleInfo{kind: "node", name: hostname, cluster: clusterName}
+			}
+		}
+
+		addons, err := currentState.Addons(clusterKey)
+		if err == nil {
+			for addonName, addonKey := range addons {
+				info[addonKey] = moduleInfo{kind: "addon", name: addonName, cluster: clusterName}
+			}
+		}
+	}
+}
+
+func formatChange(group changeGroup, names []string) string {
+	switch group.kind {
+	case "cluster manager":
+		return fmt.Sprintf("%s cluster manager", group.verb)
+	case "cluster":
+		return fmt.Sprintf("%s cluster %s", group.verb, group.cluster)
+	}
+
+	preposition := map[string]string{"added": "to", "removed": "from", "changed": "of"}[group.verb]
+	target := ""
+	if group.cluster != "" {
+		target = fmt.Sprintf(" %s cluster %s", preposition, group.cluster)
+	}
+
+	kind := group.kind
+	if len(names) != 1 {
+		kind += "s"
+	}
+
+	return fmt.Sprintf("%s %d %s%s: %s", group.verb, len(names), kind, target, strings.Join(names, ", "))
+}
+
+// Returns the settings kept in the locals of the state, without the journal.
+func getSettings(currentState state.State) map[string]interface{} {
+	copied, err := state.New(currentState.Name, currentState.Bytes())
+	if err != nil {
+		return nil
+	}
+	copied.Delete("locals.triton_kubernetes_events")
+	return copied.GetMap("locals")
+}
@@ -0,0 +1,89 @@
+package journal
+
+import (
+	"reflect"
+	"testing"
+)
+
+const baseState = `{"module":{
+	"cluster-manager":{"name":"dev-manager"},
+	"cluster_triton_dev":{"name":"dev"},
+	"node_triton_dev_dev-w-1":{"hostname":"dev-w-1","count":"1"}
+}}`
+
+func TestDescribeChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		changes  []string
+		clusters []string
+	}{
+		{
+			name:   "added nodes",
+			before: baseState,
+			after: `{"module":{
+				"cluster-manager":{"name":"dev-manager"},
+				"cluster_triton_dev":{"name":"dev"},
+				"node_triton_dev_dev-w-1":{"hostname":"dev-w-1","count":"1"},
+				"node_triton_dev_dev-w-2":{"hostname":"dev-w-2","count":"1"},
+				"node_triton_dev_dev-w-3":{"hostname":"dev-w-3","count":"1"}
+			}}`,
+			changes:  []string{"added 2 nodes to cluster dev: dev-w-2, dev-w-3"},
+			clusters: []string{"dev"},
+		},
+		{
+			name:   "changed node",
+			before: baseState,
+			after: `{"module":{
+				"cluster-manager":{"name":"dev-manager"},
+				"cluster_triton_dev":{"name":"dev"},
+				"node_triton_dev_dev-w-1":{"hostname":"dev-w-1","count":"2"}
+			}}`,
+			changes:  []string{"changed 1 node of cluster dev: dev-w-1"},
+			clusters: []string{"dev"},
+		},
+		{
+			name:     "removed cluster",
+			before:   baseState,
+			after:    `{"module":{"cluster-manager":{"name":"dev-manager"}}}`,
+			changes:  []string{"removed 1 node from cluster dev: dev-w-1", "removed cluster dev"},
+			clusters: []string{"dev"},
+		},
+		{
+			name:     "new cluster manager",
+			before:   `{}`,
+			after:    `{"module":{"cluster-manager":{"name":"dev-manager"}}}`,
+			changes:  []string{"added cluster manager"},
+			clusters: []string{},
+		},
+		{
+			name:   "changed settings",
+			before: baseState,
+			after: `{"module":{
+				"cluster-manager":{"name":"dev-manager"},
+				"cluster_triton_dev":{"name":"dev"},
+				"node_triton_dev_dev-w-1":{"hostname":"dev-w-1","count":"1"}
+			},"locals":{"triton_kubernetes_rancher_api_token":"token"}}`,
+			changes:  []string{"changed cluster manager settings"},
+			clusters: []string{},
+		},
+		{
+			name:     "no changes",
+			before:   baseState,
+			after:    baseState,
+			changes:  []string{},
+			clusters: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		changes, clusters := describeChanges("dev-manager", []byte(test.before), []byte(test.after))
+		if !reflect.DeepEqual(changes, test.changes) {
+			t.Errorf("%s: expected changes %q, got %q", test.name, test.changes, changes)
+		}
+		if !reflect.DeepEqual(clusters, test.clusters) {
+			t.Errorf("%s: expected clusters %q, got %q", test.name, test.clusters, clusters)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"sort"
+
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// The config diff of an event is cut at this many lines, and its values at this many
+// characters, the journal is part of a state that is read by every command
+const (
+	maxConfigDiffLines   = 50
+	maxConfigDiffValue   = 80
+	redactedConfigValue  = "[REDACTED]"
+	configDiffTruncation = "..."
+)
+
+// Returns the changes between the module variables of two versions of a state, one line per
+// module added or removed and per variable changed, e.g.
+// `~ module.cluster_triton_dev.k8s_version: "v1.9.5-rancher1-1" -> "v1.10.0-rancher1-1"`.
+// Secret values are redacted.
+func configDiff(name string, before, after []byte) []string {
+	beforeState, err := state.New(name, before)
+	if err != nil {
+		return nil
+	}
+	afterState, err := state.New(name, after)
+	if err != nil {
+		return nil
+	}
+
+	beforeModules := beforeState.GetMap("module")
+	afterModules := afterState.GetMap("module")
+
+	keys := []string{}
+	for key := range beforeModules {
+		keys = append(keys, key)
+	}
+	for key := range afterModules {
+		if _, ok := beforeModules[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := []string{}
+	for _, key := range keys {
+		beforeModule, inBefore := beforeModules[key].(map[string]interface{})
+		afterModule, inAfter := afterModules[key].(map[string]interface{})
+		switch {
+		case !inBefore:
+			lines = append(lines, fmt.Sprintf("+ module.%s", key))
+		case !inAfter:
+			lines = append(lines, fmt.Sprintf("- module.%s", key))
+		default:
+			lines = append(lines, moduleDiff(key, beforeModule, afterModule)...)
+		}
+	}
+
+	if len(lines) > maxConfigDiffLines {
+		more := len(lines) - maxConfigDiffLines
+		lines = append(lines[:maxConfigDiffLines], fmt.Sprintf("%s and %d more", configDiffTruncation, more))
+	}
+	return lines
+}
+
+func moduleDiff(key string, before, after map[string]interface{}) []string {
+	variables := []string{}
+	for variable := range before {
+		variables = append(variables, variable)
+	}
+	for variable := range after {
+		if _, ok := before[variable]; !ok {
+			variables = append(variables, variable)
+		}
+	}
+	sort.Strings(variables)
+
+	lines := []string{}
+	for _, variable := range variables {
+		beforeValue, inBefore := before[variable]
+		afterValue, inAfter := after[variable]
+		path := fmt.Sprintf("module.%s.%s", key, variable)
+		switch {
+		case !inBefore:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", path, formatConfigValue(variable, afterValue)))
+		case !inAfter:
+			lines = append(lines, fmt.Sprintf("- %s", path))
+		case !reflect.DeepEqual(beforeValue, afterValue):
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", path, formatConfigValue(variable, beforeValue), formatConfigValue(variable, afterValue)))
+		}
+	}
+	return lines
+}
+
+func formatConfigValue(variable string, value interface{}) string {
+	if state.IsSensitiveKey(variable) {
+		return redactedConfigValue
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	formatted := string(raw)
+	if len(formatted) > maxConfigDiffValue {
+		formatted = formatted[:maxConfigDiffValue] + configDiffTruncation
+	}
+	return formatted
+}
+
+// Returns the exit status of terraform for an operation that failed with err, or 0 if it
+// succeeded. Failures that didn't come from terraform, e.g. of packer or kubectl, have none.
+func terraformExitStatus(err error) *int {
+	status := 0
+	if err == nil {
+		return &status
+	}
+
+	var terraformErr *shell.TerraformError
+	var exitErr *exec.ExitError
+	if errors.As(err, &terraformErr) && errors.As(terraformErr.Err, &exitErr) {
+		status = exitErr.ExitCode()
+		return &status
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package journal
+
+import (
+	"fmt"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+func TestConfigDiff(t *testing.T) {
+	before := `{"module":{
+		"cluster-manager":{"name":"dev-manager","rancher_admin_password":"old-password"},
+		"cluster_triton_dev":{"name":"dev","k8s_version":"v1.9.5-rancher1-1","triton_url":"https://us-east-1.api.joyent.com"},
+		"node_triton_dev_dev-w-1":{"hostname":"dev-w-1","count":"1"}
+	}}`
+	after := `{"module":{
+		"cluster-manager":{"name":"dev-manager","rancher_admin_password":"new-password"},
+		"cluster_triton_dev":{"name":"dev","k8s_version":"v1.10.0-rancher1-1"},
+		"node_triton_dev_dev-w-2":{"hostname":"dev-w-2","count":"1"}
+	}}`
+
+	expected := []string{
+		`~ module.cluster-manager.rancher_admin_password: [REDACTED] -> [REDACTED]`,
+		`~ module.cluster_triton_dev.k8s_version: "v1.9.5-rancher1-1" -> "v1.10.0-rancher1-1"`,
+		`- module.cluster_triton_dev.triton_url`,
+		`- module.node_triton_dev_dev-w-1`,
+		`+ module.node_triton_dev_dev-w-2`,
+	}
+	diff := configDiff("dev-manager", []byte(before), []byte(after))
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Wrong output, expected %q, received %q", expected, diff)
+	}
+
+	if diff := configDiff("dev-manager", []byte(baseState), []byte(baseState)); len(diff) != 0 {
+		t.Errorf("Expected no diff, received %q", diff)
+	}
+}
+
+func TestTerraformExitStatus(t *testing.T) {
+	if status := terraformExitStatus(nil); status == nil || *status != 0 {
+		t.Errorf("Expected 0 for a success, received %v", status)
+	}
+
+	// A failed apply, as create returns it after saving its checkpoint
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	applyErr := fmt.Errorf("%w\nThe state of cluster manager 'dev-manager' was saved.", &shell.ApplyError{Err: &shell.TerraformError{Err: exitErr}})
+	if status := terraformExitStatus(applyErr); status == nil || *status != 3 {
+		t.Errorf("Expected 3, received %v", status)
+	}
+	if code := util.ExitCode(applyErr); code != util.ExitCodeTerraform {
+		t.Errorf("Expected exit code %d, received %d", util.ExitCodeTerraform, code)
+	}
+
+	if status := terraformExitStatus(util.ConfigError(exec.ErrNotFound)); status != nil {
+		t.Errorf("Expected no exit status for a failure before terraform ran, received %d", *status)
+	}
+
+	// Other commands, e.g. packer, fail with an exit status too
+	if status := terraformExitStatus(fmt.Errorf("packer build failed: %w", exitErr)); status != nil {
+		t.Errorf("Expected no exit status for a failure of another command, received %d", *status)
+	}
+}
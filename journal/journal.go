@@ -0,0 +1,197 @@
+// Package journal records the operations run on cluster managers: who ran which command,
+// when, whether it succeeded and what it changed, down to the configuration changes. Events are
+// stored in the state of the cluster manager they changed, so every backend keeps them and
+// everyone sharing the backend sees them, making the journal an audit log of the cluster
+// manager.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Older events are dropped, the journal is part of a state that is read by every command
+const maxEvents = 200
+
+// Counts the terraform runs of the process, replaced in tests
+var countTerraformRuns = shell.TerraformRuns
+
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+)
+
+// Event is an operation run on a cluster manager.
+type Event struct {
+	Command   string    `json:"command"`
+	User      string    `json:"user"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+	// Clusters the operation targeted
+	Clusters []string `json:"clusters,omitempty"`
+	// What the operation changed in the state, e.g. "added 3 nodes to cluster prod-eu: ..."
+	Changes []string `json:"changes,omitempty"`
+	// The module variables the operation changed, with secrets redacted, e.g.
+	// `~ module.node_triton_dev_dev-w-1.triton_machine_package: "k4-highcpu-kvm-1.75G" -> ...`
+	ConfigDiff []string `json:"config_diff,omitempty"`
+	// The exit status of terraform, 0 when the operation succeeded, unset when it didn't run
+	// terraform or failed before terraform ran
+	TerraformExitStatus *int `json:"terraform_exit_status,omitempty"`
+}
+
+// Run runs an operation with a backend that tracks the states it reads and persists, then
+// records it in the journal of every cluster manager it targeted. Operations that neither
+// changed anything nor failed, e.g. canceled ones or terraform plans that weren't applied,
+// aren't recorded. Neither are operations interrupted at a prompt before they changed anything,
+// they return a util.InterruptedError listing what they did change. Failing to record an
+// event doesn't fail the operation.
+func Run(conf config.Config, remoteBackend backend.Backend, command string, operation func(backend.Backend) error) error {
+	tracker := newTrackingBackend(remoteBackend)
+
+	terraformRuns := countTerraformRuns()
+	startedAt := time.Now().UTC()
+	err := operation(tracker)
+	endedAt := time.Now().UTC()
+	terraformRan := countTerraformRuns() > terraformRuns
+
+	interrupted := util.IsInterrupt(err)
+	interruptedChanges := []string{}
+
+	for _, name := range tracker.order {
+		if tracker.deleted[name] {
+			// The journal was deleted along with the cluster manager
+			continue
+		}
+
+		changes, clusters, diff := []string{}, []string{}, []string{}
+		if persisted, ok := tracker.persisted[name]; ok {
+			changes, clusters = describeChanges(name, tracker.read[name], persisted)
+			diff = configDiff(name, tracker.read[name], persisted)
+		}
+		if (err == nil || errors.Is(err, shell.ErrPlanNotApplied) || interrupted) && len(changes) == 0 {
+			continue
+		}
+		if interrupted {
+			for _, change := range changes {
+				interruptedChanges = append(interruptedChanges, fmt.Sprintf("%s: %s", name, change))
+			}
+		}
+		if len(clusters) == 0 && conf.GetString("cluster_name") != "" {
+			clusters = []string{conf.GetString("cluster_name")}
+		}
+
+		event := Event{
+			Command:   command,
+			User:      currentUser(),
+			StartedAt: startedAt,
+			EndedAt:   endedAt,
+			Result:    ResultSucceeded,
+			Clusters:  clusters,
+			Changes:   changes,
+		}
+		if len(diff) > 0 {
+			event.ConfigDiff = diff
+		}
+		if interrupted {
+			event.Result = ResultFailed
+			event.Error = "Interrupted"
+		} else if err != nil {
+			event.Result = ResultFailed
+			event.Error = err.Error()
+			if terraformRan {
+				event.TerraformExitStatus = terraformExitStatus(err)
+			}
+		} else if terraformRan {
+			event.TerraformExitStatus = terraformExitStatus(nil)
+		}
+
+		recordErr := record(remoteBackend, name, event)
+		if recordErr != nil {
+			fmt.Printf("Unable to record the operation in the journal of cluster manager '%s': %v\n", name, recordErr)
+		}
+	}
+
+	if interrupted {
+		return &util.InterruptedError{Changes: interruptedChanges}
+	}
+
+	return err
+}
+
+// Events returns the journal of a cluster manager, oldest event first.
+func Events(currentState state.State) ([]Event, error) {
+	raw, err := json.Marshal(currentState.Events())
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	err = json.Unmarshal(raw, &events)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Appends the event to the journal of the stored state. The state is read again so that
+// changes the operation didn't persist, e.g. because terraform failed, are never persisted.
+func record(remoteBackend backend.Backend, name string, event Event) error {
+	currentState, err := remoteBackend.State(name)
+	if err != nil {
+		return err
+	}
+
+	// A cluster manager that failed to be created has no state to keep a journal in
+	if len(currentState.GetMap("module")) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	err = json.Unmarshal(raw, &value)
+	if err != nil {
+		return err
+	}
+
+	events := append(currentState.Events(), value)
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+
+	err = currentState.SetEvents(events)
+	if err != nil {
+		return err
+	}
+
+	return remoteBackend.PersistState(currentState)
+}
+
+// Returns who runs the CLI, as user@host.
+func currentUser() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return name
+	}
+	return fmt.Sprintf("%s@%s", name, host)
+}
@@ -0,0 +1,274 @@
+package journal
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+// memoryBackend keeps states in memory.
+type memoryBackend struct {
+	states map[string][]byte
+}
+
+func (backend *memoryBackend) State(name string) (state.State, error) {
+	content, ok := backend.states[name]
+	if !ok {
+		content = []byte("{}")
+	}
+	return state.New(name, content)
+}
+
+func (backend *memoryBackend) DeleteState(name string) error {
+	delete(backend.states, name)
+	return nil
+}
+
+func (backend *memoryBackend) PersistState(currentState state.State) error {
+	backend.states[currentState.Name] = currentState.Bytes()
+	return nil
+}
+
+func (backend *memoryBackend) States() ([]string, error) {
+	names := []string{}
+	for name := range backend.states {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (backend *memoryBackend) StateTerraformConfig(name string) (string, interface{}) {
+	return "", nil
+}
+
+func getEvents(t *testing.T, remoteBackend backend.Backend, name string) []Event {
+	currentState, err := remoteBackend.State(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := Events(currentState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// Replaces the terraform runs of the process with the returned counter until the test ends.
+func fakeTerraformRuns(t *testing.T) *int {
+	runs := 0
+	countTerraformRuns = func() int {
+		return runs
+	}
+	t.Cleanup(func() {
+		countTerraformRuns = shell.TerraformRuns
+	})
+	return &runs
+}
+
+func TestRun(t *testing.T) {
+	remoteBackend := &memoryBackend{states: map[string][]byte{"dev-manager": []byte(baseState)}}
+	conf := config.New()
+	terraformRuns := fakeTerraformRuns(t)
+
+	addNode := func(b backend.Backend) error {
+		currentState, err := b.State("dev-manager")
+		if err != nil {
+			return err
+		}
+		err = currentState.AddNode("cluster_triton_dev", "dev-w-2", map[string]interface{}{"hostname": "dev-w-2"})
+		if err != nil {
+			return err
+		}
+		// Counted as the shell does when it runs terraform
+		*terraformRuns++
+		return b.PersistState(currentState)
+	}
+	err := Run(conf, remoteBackend, "create node", addNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Operations that don't change anything aren't recorded
+	err = Run(conf, remoteBackend, "get cluster", func(b backend.Backend) error {
+		_, err := b.State("dev-manager")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Set("cluster_name", "dev")
+	expectedErr := errors.New("terraform apply failed")
+	err = Run(conf, remoteBackend, "scale nodepool dev-w", func(b backend.Backend) error {
+		_, err := b.State("dev-manager")
+		if err != nil {
+			return err
+		}
+		return expectedErr
+	})
+	if err != expectedErr {
+		t.Fatalf("expected error %v, got %v", expectedErr, err)
+	}
+
+	events := getEvents(t, remoteBackend, "dev-manager")
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	if events[0].Command != "create node" || events[0].Result != ResultSucceeded {
+		t.Errorf("unexpected first event %+v", events[0])
+	}
+	if len(events[0].Changes) != 1 || events[0].Changes[0] != "added 1 node to cluster dev: dev-w-2" {
+		t.Errorf("unexpected changes %q", events[0].Changes)
+	}
+	if len(events[0].ConfigDiff) != 1 || events[0].ConfigDiff[0] != "+ module.node_triton_dev_dev-w-2" {
+		t.Errorf("unexpected config diff %q", events[0].ConfigDiff)
+	}
+	if events[0].TerraformExitStatus == nil || *events[0].TerraformExitStatus != 0 {
+		t.Errorf("expected terraform exit status 0, got %v", events[0].TerraformExitStatus)
+	}
+
+	if events[1].Command != "scale nodepool dev-w" || events[1].Result != ResultFailed || events[1].Error != expectedErr.Error() {
+		t.Errorf("unexpected second event %+v", events[1])
+	}
+	if len(events[1].Clusters) != 1 || events[1].Clusters[0] != "dev" {
+		t.Errorf("expected the failed event to target cluster dev, got %q", events[1].Clusters)
+	}
+	if events[1].TerraformExitStatus != nil {
+		t.Errorf("expected no terraform exit status, got %d", *events[1].TerraformExitStatus)
+	}
+}
+
+func TestRunWithoutTerraform(t *testing.T) {
+	remoteBackend := &memoryBackend{states: map[string][]byte{"dev-manager": []byte(baseState)}}
+
+	// An operation that changes the state without running terraform
+	err := Run(config.New(), remoteBackend, "create node", func(b backend.Backend) error {
+		currentState, err := b.State("dev-manager")
+		if err != nil {
+			return err
+		}
+		err = currentState.AddNode("cluster_triton_dev", "dev-w-2", map[string]interface{}{"hostname": "dev-w-2"})
+		if err != nil {
+			return err
+		}
+		return b.PersistState(currentState)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := getEvents(t, remoteBackend, "dev-manager")
+	if len(events) != 1 || events[0].Result != ResultSucceeded {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if events[0].TerraformExitStatus != nil {
+		t.Errorf("expected no terraform exit status, got %d", *events[0].TerraformExitStatus)
+	}
+}
+
+func TestRunFailedCommand(t *testing.T) {
+	remoteBackend := &memoryBackend{states: map[string][]byte{"dev-manager": []byte(baseState)}}
+	terraformRuns := fakeTerraformRuns(t)
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+
+	run := func(failure error) Event {
+		err := Run(config.New(), remoteBackend, "build image", func(b backend.Backend) error {
+			_, err := b.State("dev-manager")
+			if err != nil {
+				return err
+			}
+			// Terraform runs before the command that fails
+			*terraformRuns++
+			return failure
+		})
+		if err != failure {
+			t.Fatalf("expected error %v, got %v", failure, err)
+		}
+		events := getEvents(t, remoteBackend, "dev-manager")
+		return events[len(events)-1]
+	}
+
+	// e.g. packer
+	if event := run(exitErr); event.TerraformExitStatus != nil {
+		t.Errorf("expected no terraform exit status for another command, got %d", *event.TerraformExitStatus)
+	}
+	if event := run(&shell.TerraformError{Err: exitErr}); event.TerraformExitStatus == nil || *event.TerraformExitStatus != 3 {
+		t.Errorf("expected terraform exit status 3, got %v", event.TerraformExitStatus)
+	}
+}
+
+func TestRunDeletedManager(t *testing.T) {
+	remoteBackend := &memoryBackend{states: map[string][]byte{"dev-manager": []byte(baseState)}}
+
+	err := Run(config.New(), remoteBackend, "destroy manager", func(b backend.Backend) error {
+		_, err := b.State("dev-manager")
+		if err != nil {
+			return err
+		}
+		return b.DeleteState("dev-manager")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := remoteBackend.states["dev-manager"]; ok {
+		t.Error("expected the journal not to recreate a deleted cluster manager")
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	remoteBackend := &memoryBackend{states: map[string][]byte{"dev-manager": []byte(baseState)}}
+	conf := config.New()
+
+	// Nothing is recorded when a prompt is interrupted before anything was persisted
+	err := Run(conf, remoteBackend, "create node", func(b backend.Backend) error {
+		currentState, err := b.State("dev-manager")
+		if err != nil {
+			return err
+		}
+		currentState.AddNode("cluster_triton_dev", "dev-w-2", map[string]interface{}{"hostname": "dev-w-2"})
+		return promptui.ErrInterrupt
+	})
+	interruptedErr, ok := err.(*util.InterruptedError)
+	if !ok || len(interruptedErr.Changes) != 0 {
+		t.Fatalf("expected an interrupt without changes, got %v", err)
+	}
+	if events := getEvents(t, remoteBackend, "dev-manager"); len(events) != 0 {
+		t.Fatalf("expected no events, got %+v", events)
+	}
+
+	// Changes persisted before the interrupt are listed and recorded
+	err = Run(conf, remoteBackend, "create cluster", func(b backend.Backend) error {
+		currentState, err := b.State("dev-manager")
+		if err != nil {
+			return err
+		}
+		err = currentState.AddNode("cluster_triton_dev", "dev-w-2", map[string]interface{}{"hostname": "dev-w-2"})
+		if err != nil {
+			return err
+		}
+		err = b.PersistState(currentState)
+		if err != nil {
+			return err
+		}
+		return promptui.ErrInterrupt
+	})
+	interruptedErr, ok = err.(*util.InterruptedError)
+	if !ok || len(interruptedErr.Changes) != 1 || interruptedErr.Changes[0] != "dev-manager: added 1 node to cluster dev: dev-w-2" {
+		t.Fatalf("expected an interrupt with the added node, got %v", err)
+	}
+
+	events := getEvents(t, remoteBackend, "dev-manager")
+	if len(events) != 1 || events[0].Result != ResultFailed || events[0].Error != "Interrupted" {
+		t.Fatalf("expected an interrupted event, got %+v", events)
+	}
+}
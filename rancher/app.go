@@ -0,0 +1,141 @@
+package rancher
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// App is a catalog app installed in a project.
+type App struct {
+	ID              string            `json:"id,omitempty"`
+	Name            string            `json:"name"`
+	State           string            `json:"state,omitempty"`
+	ProjectID       string            `json:"projectId,omitempty"`
+	TargetNamespace string            `json:"targetNamespace,omitempty"`
+	ExternalID      string            `json:"externalId"`
+	Answers         map[string]string `json:"answers,omitempty"`
+	Links           map[string]string `json:"links,omitempty"`
+	Actions         map[string]string `json:"actions,omitempty"`
+}
+
+// Template is an app template of a catalog.
+type Template struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	CatalogID      string            `json:"catalogId"`
+	DefaultVersion string            `json:"defaultVersion"`
+	VersionLinks   map[string]string `json:"versionLinks"`
+}
+
+type project struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type appUpgradeInput struct {
+	ExternalID string            `json:"externalId"`
+	Answers    map[string]string `json:"answers,omitempty"`
+}
+
+// CatalogExternalID returns the externalId Rancher uses to refer to a version of a catalog template.
+func CatalogExternalID(catalog, template, version string) string {
+	return fmt.Sprintf("catalog://?catalog=%s&template=%s&version=%s", catalog, template, version)
+}
+
+// DefaultProjectID returns the id of the Default project of the given cluster, which
+// catalog apps are installed into.
+func (c *Client) DefaultProjectID(clusterID string) (string, error) {
+	return c.ProjectID(clusterID, "Default")
+}
+
+// ProjectID returns the id of the project of the given cluster with the given name.
+func (c *Client) ProjectID(clusterID, name string) (string, error) {
+	query := url.Values{}
+	query.Set("clusterId", clusterID)
+	query.Set("name", name)
+
+	projects := []project{}
+	err := c.list("/v3/projects?"+query.Encode(), &projects)
+	if err != nil {
+		return "", err
+	}
+
+	if len(projects) == 0 {
+		return "", fmt.Errorf("Cluster '%s' has no %s project", clusterID, name)
+	}
+
+	return projects[0].ID, nil
+}
+
+// Templates returns the app templates of the given catalog.
+func (c *Client) Templates(catalog string) ([]Template, error) {
+	query := url.Values{}
+	query.Set("catalogId", catalog)
+
+	templates := []Template{}
+	err := c.list("/v3/templates?"+query.Encode(), &templates)
+	if err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// Template returns the given template of a catalog.
+func (c *Client) Template(catalog, name string) (Template, error) {
+	template := Template{}
+	err := c.do(http.MethodGet, fmt.Sprintf("/v3/templates/%s-%s", catalog, name), nil, &template)
+	return template, err
+}
+
+// Apps returns the apps installed in the given project.
+func (c *Client) Apps(projectID string) ([]App, error) {
+	apps := []App{}
+	err := c.list(fmt.Sprintf("/v3/projects/%s/apps", projectID), &apps)
+	if err != nil {
+		return nil, err
+	}
+
+	return apps, nil
+}
+
+// App returns the app of the given project with the given name.
+func (c *Client) App(projectID, name string) (App, error) {
+	apps, err := c.Apps(projectID)
+	if err != nil {
+		return App{}, err
+	}
+
+	for _, app := range apps {
+		if app.Name == name {
+			return app, nil
+		}
+	}
+
+	return App{}, fmt.Errorf("An app named '%s', does not exist.", name)
+}
+
+// InstallApp installs the app into the given project.
+func (c *Client) InstallApp(projectID string, app App) (App, error) {
+	app.ProjectID = projectID
+
+	installed := App{}
+	err := c.do(http.MethodPost, fmt.Sprintf("/v3/projects/%s/app", projectID), &app, &installed)
+	return installed, err
+}
+
+// UpgradeApp upgrades the app to the given catalog template version and answers.
+func (c *Client) UpgradeApp(app App, externalID string, answers map[string]string) error {
+	upgradeURL, ok := app.Actions["upgrade"]
+	if !ok {
+		return fmt.Errorf("App '%s' can't be upgraded while it is %s", app.Name, app.State)
+	}
+
+	return c.do(http.MethodPost, upgradeURL, &appUpgradeInput{ExternalID: externalID, Answers: answers}, nil)
+}
+
+// DeleteApp removes the app and the resources it created.
+func (c *Client) DeleteApp(app App) error {
+	return c.do(http.MethodDelete, app.Links["self"], nil, nil)
+}
@@ -0,0 +1,99 @@
+package rancher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDefaultProjectID(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey, secretKey, _ := r.BasicAuth()
+		if accessKey != "access" || secretKey != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v3/projects" || r.URL.Query().Get("clusterId") != "c-abcde" || r.URL.Query().Get("name") != "Default" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"data": [{"id": "c-abcde:p-12345", "name": "Default"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	projectID, err := client.DefaultProjectID("c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if projectID != "c-abcde:p-12345" {
+		t.Errorf("Wrong output, expected c-abcde:p-12345, received %s", projectID)
+	}
+}
+
+func TestInstallApp(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/projects/c-abcde:p-12345/app" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+
+		app := App{}
+		err := json.NewDecoder(r.Body).Decode(&app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if app.ProjectID != "c-abcde:p-12345" || app.ExternalID != "catalog://?catalog=library&template=wordpress&version=2.1.10" {
+			t.Errorf("Unexpected app %+v", app)
+		}
+
+		app.ID = "p-12345:" + app.Name
+		app.State = "installing"
+		json.NewEncoder(w).Encode(&app)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	app, err := client.InstallApp("c-abcde:p-12345", App{
+		Name:       "blog",
+		ExternalID: CatalogExternalID("library", "wordpress", "2.1.10"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.ID != "p-12345:blog" || app.State != "installing" {
+		t.Errorf("Unexpected app %+v", app)
+	}
+}
+
+func TestAppNotFound(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"name": "blog"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	_, err := client.App("c-abcde:p-12345", "shop")
+
+	expected := "An app named 'shop', does not exist."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"type": "error", "code": "NotUnique", "message": "app name already exists"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	_, err := client.InstallApp("c-abcde:p-12345", App{Name: "blog"})
+
+	expected := "Rancher API POST /v3/projects/c-abcde:p-12345/app failed: NotUnique: app name already exists"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
@@ -0,0 +1,122 @@
+package rancher
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/fips"
+)
+
+// Client is a minimal client for the Rancher v3 API of a cluster manager.
+type Client struct {
+	URL       string
+	AccessKey string
+	SecretKey string
+
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the Rancher API at url. Cluster managers use a
+// self-signed certificate, so the certificate isn't verified, except in FIPS mode.
+func NewClient(url, accessKey, secretKey string) *Client {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if fips.Enabled() {
+		tlsConfig = fips.TLSConfig()
+	}
+
+	return &Client{
+		URL:       strings.TrimSuffix(url, "/"),
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+}
+
+type collection struct {
+	Data json.RawMessage `json:"data"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// do sends the request to path, which is either relative to the API URL or a full
+// link returned by the API, and decodes the response into out if it isn't nil.
+func (c *Client) do(method, path string, in, out interface{}) error {
+	url := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		url = c.URL + path
+	}
+
+	var body io.Reader
+	if in != nil {
+		content, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.AccessKey, c.SecretKey)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		rancherErr := apiError{}
+		if json.Unmarshal(content, &rancherErr) == nil && rancherErr.Message != "" {
+			return fmt.Errorf("Rancher API %s %s failed: %s: %s", method, path, rancherErr.Code, rancherErr.Message)
+		}
+		return fmt.Errorf("Rancher API %s %s failed: %s", method, path, resp.Status)
+	}
+
+	if out == nil || len(content) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(content, out)
+}
+
+// list decodes the data of the collection at path into out.
+func (c *Client) list(path string, out interface{}) error {
+	result := collection{}
+	err := c.do(http.MethodGet, path, nil, &result)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(result.Data, out)
+}
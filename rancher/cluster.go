@@ -0,0 +1,267 @@
+package rancher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Cluster is a kubernetes cluster managed by Rancher.
+type Cluster struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	State   string            `json:"state"`
+	Links   map[string]string `json:"links,omitempty"`
+	Actions map[string]string `json:"actions,omitempty"`
+
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+
+	RKEConfig *RKEConfig      `json:"rancherKubernetesEngineConfig,omitempty"`
+	Version   *ClusterVersion `json:"version,omitempty"`
+}
+
+// RKEConfig is the Rancher Kubernetes Engine config of a cluster created by Rancher.
+type RKEConfig struct {
+	KubernetesVersion string `json:"kubernetesVersion"`
+}
+
+// ClusterVersion is the Kubernetes version a cluster runs, e.g. v1.10.1.
+type ClusterVersion struct {
+	GitVersion string `json:"gitVersion"`
+}
+
+// Cluster returns the cluster with the given id.
+func (c *Client) Cluster(clusterID string) (Cluster, error) {
+	cluster := Cluster{}
+	err := c.do(http.MethodGet, "/v3/clusters/"+clusterID, nil, &cluster)
+	return cluster, err
+}
+
+// BackupEtcd takes a snapshot of the etcd of the cluster, which Rancher stores on the etcd
+// nodes or in the S3 bucket configured for the cluster.
+func (c *Client) BackupEtcd(cluster Cluster) error {
+	backupURL, ok := cluster.Actions["backupEtcd"]
+	if !ok {
+		return errors.New("Rancher can't snapshot the etcd of this cluster, etcd snapshots require Rancher 2.2 or later")
+	}
+
+	return c.do(http.MethodPost, backupURL, nil, nil)
+}
+
+type generateKubeconfigOutput struct {
+	Config string `json:"config"`
+}
+
+// GenerateKubeconfig returns a kubeconfig of the cluster for the user of the API keys. Its
+// contexts go through Rancher, except those of the authorized cluster endpoints of the cluster.
+func (c *Client) GenerateKubeconfig(cluster Cluster) (string, error) {
+	generateURL, ok := cluster.Actions["generateKubeconfig"]
+	if !ok {
+		return "", fmt.Errorf("Rancher can't generate a kubeconfig of cluster '%s'", cluster.Name)
+	}
+
+	output := generateKubeconfigOutput{}
+	err := c.do(http.MethodPost, generateURL, nil, &output)
+	if err != nil {
+		return "", err
+	}
+
+	return output.Config, nil
+}
+
+// The state RKE keeps in the cluster, from Rancher 2.2, which has the kubeconfig of the kube-admin
+// user RKE created the cluster with
+const fullClusterStatePath = "/k8s/clusters/%s/api/v1/namespaces/kube-system/configmaps/full-cluster-state"
+
+// RKE kubeconfigs reach the API server at the node RKE ran on
+var kubeconfigServerRegexp = regexp.MustCompile(`(?m)^(\s*server:\s*).*$`)
+
+type configMap struct {
+	Data map[string]string `json:"data"`
+}
+
+type fullClusterState struct {
+	CurrentState struct {
+		CertificatesBundle map[string]struct {
+			Config string `json:"config"`
+		} `json:"certificatesBundle"`
+	} `json:"currentState"`
+}
+
+// DirectKubeconfig returns the kubeconfig of the kube-admin user RKE created the cluster with. It
+// reaches the API server of a control plane node directly, so it keeps working without Rancher.
+func (c *Client) DirectKubeconfig(clusterID string) (string, error) {
+	stateConfigMap := configMap{}
+	err := c.do(http.MethodGet, fmt.Sprintf(fullClusterStatePath, clusterID), nil, &stateConfigMap)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read the RKE state of cluster '%s', it requires Rancher 2.2 or later: %s", clusterID, err)
+	}
+
+	rkeState := fullClusterState{}
+	err = json.Unmarshal([]byte(stateConfigMap.Data["full-cluster-state"]), &rkeState)
+	if err != nil {
+		return "", fmt.Errorf("Invalid RKE state of cluster '%s': %s", clusterID, err)
+	}
+	kubeconfig := rkeState.CurrentState.CertificatesBundle["kube-admin"].Config
+	if kubeconfig == "" {
+		return "", fmt.Errorf("The RKE state of cluster '%s' has no kube-admin kubeconfig", clusterID)
+	}
+
+	nodes, err := c.Nodes(clusterID)
+	if err != nil {
+		return "", err
+	}
+	address := ""
+	for _, node := range nodes {
+		if !node.ControlPlane {
+			continue
+		}
+		address = node.ExternalIPAddress
+		if address == "" {
+			address = node.IPAddress
+		}
+		if address != "" {
+			break
+		}
+	}
+	if address == "" {
+		return "", fmt.Errorf("Cluster '%s' has no control plane node with an address", clusterID)
+	}
+
+	return kubeconfigServerRegexp.ReplaceAllString(kubeconfig, fmt.Sprintf(`${1}"https://%s:6443"`, address)), nil
+}
+
+// RemoveAgents deletes the cattle-system namespace of the cluster through the Rancher proxy,
+// which removes the agents that connect the cluster to Rancher. The cluster keeps running.
+func (c *Client) RemoveAgents(clusterID string) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/k8s/clusters/%s/api/v1/namespaces/cattle-system", clusterID), nil, nil)
+}
+
+// UpgradeStrategy is how the nodes of a cluster are upgraded to a new Kubernetes version, a
+// few at a time. Rancher versions before 2.4 upgrade every node at once and ignore it.
+type UpgradeStrategy struct {
+	// Number or percentage of worker nodes upgraded at the same time
+	MaxUnavailableWorker string `json:"maxUnavailableWorker"`
+	// Number or percentage of control plane nodes upgraded at the same time
+	MaxUnavailableControlplane string `json:"maxUnavailableControlplane"`
+	// Whether worker nodes are drained before they are upgraded, rather than only cordoned
+	Drain bool `json:"drain"`
+}
+
+type setting struct {
+	Value string `json:"value"`
+}
+
+// How often the state of an upgrading cluster is checked
+var clusterUpgradePollInterval = 15 * time.Second
+
+// Digit groups of a Kubernetes version, e.g. 1, 10, 0, 1 and 1 in v1.10.0-rancher1-1
+var kubernetesVersionNumberRegexp = regexp.MustCompile(`\d+`)
+
+// KubernetesVersions returns the Kubernetes versions the cluster manager can create and
+// upgrade clusters to, newest first.
+func (c *Client) KubernetesVersions() ([]string, error) {
+	versions := []string{}
+
+	// Rancher 2.3 and later list the supported versions
+	current := setting{}
+	err := c.do(http.MethodGet, "/v3/settings/k8s-versions-current", nil, &current)
+	if err == nil && current.Value != "" {
+		for _, version := range strings.Split(current.Value, ",") {
+			if version = strings.TrimSpace(version); version != "" {
+				versions = append(versions, version)
+			}
+		}
+	} else {
+		// Earlier versions map each version to its system images
+		images := setting{}
+		err = c.do(http.MethodGet, "/v3/settings/k8s-version-to-images", nil, &images)
+		if err != nil {
+			return nil, err
+		}
+
+		versionImages := map[string]interface{}{}
+		err = json.Unmarshal([]byte(images.Value), &versionImages)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Kubernetes versions setting: %v", err)
+		}
+		for version := range versionImages {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareKubernetesVersions(versions[i], versions[j]) > 0
+	})
+	return versions, nil
+}
+
+// CompareKubernetesVersions returns a negative number when a is older than b, a positive one
+// when it is newer and 0 when they're the same.
+func CompareKubernetesVersions(a, b string) int {
+	numbersA := kubernetesVersionNumberRegexp.FindAllString(a, -1)
+	numbersB := kubernetesVersionNumberRegexp.FindAllString(b, -1)
+	for i := 0; i < len(numbersA) && i < len(numbersB); i++ {
+		numA, _ := strconv.Atoi(numbersA[i])
+		numB, _ := strconv.Atoi(numbersB[i])
+		if numA != numB {
+			return numA - numB
+		}
+	}
+	return len(numbersA) - len(numbersB)
+}
+
+// UpgradeKubernetes changes the Kubernetes version of the cluster, which Rancher then rolls
+// out to its nodes with the given strategy. The rest of the cluster's config is left as is.
+func (c *Client) UpgradeKubernetes(cluster Cluster, version string, strategy UpgradeStrategy) error {
+	selfURL, ok := cluster.Links["self"]
+	if !ok {
+		selfURL = "/v3/clusters/" + cluster.ID
+	}
+
+	// The cluster is updated as a whole, so it is read as is rather than into a Cluster
+	raw := map[string]interface{}{}
+	err := c.do(http.MethodGet, selfURL, nil, &raw)
+	if err != nil {
+		return err
+	}
+
+	rkeConfig, ok := raw["rancherKubernetesEngineConfig"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Cluster '%s' wasn't created by Rancher, its Kubernetes version can't be changed", cluster.Name)
+	}
+	rkeConfig["kubernetesVersion"] = version
+	rkeConfig["upgradeStrategy"] = strategy
+
+	return c.do(http.MethodPut, selfURL, raw, nil)
+}
+
+// WaitForKubernetesVersion waits until the cluster is active and runs the given version, or the
+// timeout expires.
+func (c *Client) WaitForKubernetesVersion(clusterID, version string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		cluster, err := c.Cluster(clusterID)
+		if err != nil {
+			return err
+		}
+
+		if cluster.State == "active" && cluster.Version != nil && strings.HasPrefix(version, cluster.Version.GitVersion+"-") {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return util.TimeoutError(fmt.Errorf("Cluster '%s' wasn't upgraded to %s after %s, it is %s", cluster.Name, version, timeout, cluster.State))
+		}
+
+		time.Sleep(clusterUpgradePollInterval)
+	}
+}
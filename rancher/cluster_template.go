@@ -0,0 +1,110 @@
+package rancher
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ClusterTemplate is a Rancher cluster template, also known as an RKE template. Its revisions
+// hold the configs clusters are created from. Cluster templates require Rancher 2.3 or later.
+type ClusterTemplate struct {
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	Description       string            `json:"description"`
+	DefaultRevisionID string            `json:"defaultRevisionId"`
+	Links             map[string]string `json:"links,omitempty"`
+}
+
+// ClusterTemplateRevision is a revision of a cluster template. Its cluster config is the
+// config of the clusters created from it, e.g. their rancherKubernetesEngineConfig.
+type ClusterTemplateRevision struct {
+	ID                string                 `json:"id"`
+	Name              string                 `json:"name"`
+	ClusterTemplateID string                 `json:"clusterTemplateId"`
+	Enabled           bool                   `json:"enabled"`
+	ClusterConfig     map[string]interface{} `json:"clusterConfig"`
+}
+
+type clusterTemplateInput struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type clusterTemplateRevisionInput struct {
+	Type              string                 `json:"type"`
+	Name              string                 `json:"name"`
+	ClusterTemplateID string                 `json:"clusterTemplateId"`
+	Enabled           bool                   `json:"enabled"`
+	ClusterConfig     map[string]interface{} `json:"clusterConfig"`
+}
+
+// ClusterTemplates returns the cluster templates the user of the API keys can use.
+func (c *Client) ClusterTemplates() ([]ClusterTemplate, error) {
+	templates := []ClusterTemplate{}
+	err := c.list("/v3/clustertemplates", &templates)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the cluster templates, they require Rancher 2.3 or later: %s", err)
+	}
+
+	return templates, nil
+}
+
+// ClusterTemplateRevisions returns the revisions of the given cluster template.
+func (c *Client) ClusterTemplateRevisions(templateID string) ([]ClusterTemplateRevision, error) {
+	query := url.Values{}
+	query.Set("clusterTemplateId", templateID)
+
+	revisions := []ClusterTemplateRevision{}
+	err := c.list("/v3/clustertemplaterevisions?"+query.Encode(), &revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// CreateClusterTemplate creates a cluster template, which has no revisions yet.
+func (c *Client) CreateClusterTemplate(name, description string) (ClusterTemplate, error) {
+	template := ClusterTemplate{}
+	err := c.do(http.MethodPost, "/v3/clustertemplates", &clusterTemplateInput{Type: "clusterTemplate", Name: name, Description: description}, &template)
+	if err != nil {
+		return ClusterTemplate{}, err
+	}
+
+	return template, nil
+}
+
+// CreateClusterTemplateRevision adds a revision with the given cluster config to the cluster
+// template, and makes it the revision clusters are created from by default.
+func (c *Client) CreateClusterTemplateRevision(template ClusterTemplate, name string, clusterConfig map[string]interface{}) (ClusterTemplateRevision, error) {
+	revision := ClusterTemplateRevision{}
+	err := c.do(http.MethodPost, "/v3/clustertemplaterevisions", &clusterTemplateRevisionInput{
+		Type:              "clusterTemplateRevision",
+		Name:              name,
+		ClusterTemplateID: template.ID,
+		Enabled:           true,
+		ClusterConfig:     clusterConfig,
+	}, &revision)
+	if err != nil {
+		return ClusterTemplateRevision{}, err
+	}
+
+	selfURL, ok := template.Links["self"]
+	if !ok {
+		selfURL = "/v3/clustertemplates/" + template.ID
+	}
+	err = c.do(http.MethodPut, selfURL, map[string]string{"defaultRevisionId": revision.ID}, nil)
+	if err != nil {
+		return ClusterTemplateRevision{}, err
+	}
+
+	return revision, nil
+}
+
+// EnforceClusterTemplates sets whether users other than admins can only create clusters from
+// cluster templates.
+func (c *Client) EnforceClusterTemplates(enforce bool) error {
+	return c.do(http.MethodPut, "/v3/settings/cluster-template-enforcement", setting{Value: fmt.Sprint(enforce)}, nil)
+}
@@ -0,0 +1,86 @@
+package rancher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClusterTemplates(t *testing.T) {
+	requests := []string{}
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), body))
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/clustertemplates":
+			fmt.Fprint(w, `{"data": [{"id": "ct-abcde", "name": "hardened", "defaultRevisionId": "ctr-1"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/clustertemplaterevisions":
+			fmt.Fprint(w, `{"data": [{"id": "ctr-1", "name": "v1", "clusterTemplateId": "ct-abcde", "enabled": true}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/clustertemplates":
+			fmt.Fprintf(w, `{"id": "ct-fghij", "name": "restricted", "links": {"self": "%s/v3/clustertemplates/ct-fghij"}}`, server.URL)
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/clustertemplaterevisions":
+			fmt.Fprint(w, `{"id": "ctr-2", "name": "v1", "clusterTemplateId": "ct-fghij", "enabled": true}`)
+		case r.Method == http.MethodPut:
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token-abc", "secret")
+	templates, err := client.ClusterTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates[0].Name != "hardened" || templates[0].DefaultRevisionID != "ctr-1" {
+		t.Errorf("Unexpected templates %+v", templates)
+	}
+
+	revisions, err := client.ClusterTemplateRevisions("ct-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].ID != "ctr-1" || !revisions[0].Enabled {
+		t.Errorf("Unexpected revisions %+v", revisions)
+	}
+
+	template, err := client.CreateClusterTemplate("restricted", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusterConfig := map[string]interface{}{
+		"rancherKubernetesEngineConfig": map[string]interface{}{"kubernetesVersion": "v1.17.4-rancher1-2"},
+	}
+	revision, err := client.CreateClusterTemplateRevision(template, "v1", clusterConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.ID != "ctr-2" {
+		t.Errorf("Unexpected revision %+v", revision)
+	}
+
+	err = client.EnforceClusterTemplates(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"GET /v3/clustertemplates ",
+		"GET /v3/clustertemplaterevisions?clusterTemplateId=ct-abcde ",
+		`POST /v3/clustertemplates {"type":"clusterTemplate","name":"restricted"}`,
+		`POST /v3/clustertemplaterevisions {"type":"clusterTemplateRevision","name":"v1","clusterTemplateId":"ct-fghij","enabled":true,"clusterConfig":{"rancherKubernetesEngineConfig":{"kubernetesVersion":"v1.17.4-rancher1-2"}}}`,
+		`PUT /v3/clustertemplates/ct-fghij {"defaultRevisionId":"ctr-2"}`,
+		`PUT /v3/settings/cluster-template-enforcement {"value":"true"}`,
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		got, _ := json.MarshalIndent(requests, "", "  ")
+		t.Errorf("Unexpected requests %s", got)
+	}
+}
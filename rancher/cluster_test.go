@@ -0,0 +1,230 @@
+package rancher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackupEtcd(t *testing.T) {
+	backedUp := false
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/clusters/c-abcde":
+			fmt.Fprintf(w, `{"id": "c-abcde", "name": "dev", "state": "active", "actions": {"backupEtcd": "%s/v3/clusters/c-abcde?action=backupEtcd"}}`, server.URL)
+		case r.Method == http.MethodPost && r.URL.Query().Get("action") == "backupEtcd":
+			backedUp = true
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	cluster, err := client.Cluster("c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.BackupEtcd(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !backedUp {
+		t.Error("Expected the backupEtcd action to be called")
+	}
+
+	err = client.BackupEtcd(Cluster{ID: "c-fghij"})
+	if err == nil {
+		t.Error("Expected an error for a cluster without the backupEtcd action")
+	}
+}
+
+func TestGenerateKubeconfigAndRemoveAgents(t *testing.T) {
+	agentsRemoved := false
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Query().Get("action") == "generateKubeconfig":
+			fmt.Fprint(w, `{"config": "apiVersion: v1\nkind: Config\n"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/k8s/clusters/c-abcde/api/v1/namespaces/cattle-system":
+			agentsRemoved = true
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	cluster := Cluster{
+		ID:      "c-abcde",
+		Name:    "dev",
+		Actions: map[string]string{"generateKubeconfig": server.URL + "/v3/clusters/c-abcde?action=generateKubeconfig"},
+	}
+
+	kubeconfig, err := client.GenerateKubeconfig(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubeconfig != "apiVersion: v1\nkind: Config\n" {
+		t.Errorf("Unexpected kubeconfig %q", kubeconfig)
+	}
+
+	err = client.RemoveAgents(cluster.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !agentsRemoved {
+		t.Error("Expected the cattle-system namespace to be deleted")
+	}
+}
+
+func TestDirectKubeconfig(t *testing.T) {
+	rkeState := `{"currentState":{"certificatesBundle":{"kube-admin":{"config":"apiVersion: v1\nclusters:\n- cluster:\n    server: \"https://10.0.0.5:6443\"\n  name: \"local\"\n"}}}}`
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/k8s/clusters/c-abcde/api/v1/namespaces/kube-system/configmaps/full-cluster-state":
+			content, _ := json.Marshal(map[string]interface{}{"data": map[string]string{"full-cluster-state": rkeState}})
+			w.Write(content)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/nodes":
+			fmt.Fprint(w, `{"data": [
+				{"id": "c-abcde:m-1", "worker": true, "ipAddress": "10.0.0.4", "externalIpAddress": "203.0.113.4"},
+				{"id": "c-abcde:m-2", "controlPlane": true, "ipAddress": "10.0.0.5", "externalIpAddress": "203.0.113.5"}
+			]}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	kubeconfig, err := client.DirectKubeconfig("c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "apiVersion: v1\nclusters:\n- cluster:\n    server: \"https://203.0.113.5:6443\"\n  name: \"local\"\n"
+	if kubeconfig != expected {
+		t.Errorf("Expected %q, got %q", expected, kubeconfig)
+	}
+}
+
+func TestKubernetesVersions(t *testing.T) {
+	current := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/settings/k8s-versions-current":
+			if !current {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, `{"value": "v1.9.5-rancher1-1,v1.10.12-rancher1-1,v1.10.5-rancher1-2"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/settings/k8s-version-to-images":
+			fmt.Fprint(w, `{"value": "{\"v1.8.10-rancher1-1\": {}, \"v1.10.0-rancher1-1\": {}}"}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	versions, err := client.KubernetesVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"v1.10.12-rancher1-1", "v1.10.5-rancher1-2", "v1.9.5-rancher1-1"}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Wrong output, expected %v, received %v", expected, versions)
+	}
+
+	// Rancher 2.0 to 2.2
+	current = false
+	versions, err = client.KubernetesVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"v1.10.0-rancher1-1", "v1.8.10-rancher1-1"}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Wrong output, expected %v, received %v", expected, versions)
+	}
+}
+
+func TestUpgradeKubernetes(t *testing.T) {
+	clusterUpgradePollInterval = time.Millisecond
+
+	updated := map[string]interface{}{}
+	polls := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/clusters/c-abcde" && len(updated) == 0:
+			fmt.Fprint(w, `{"id": "c-abcde", "name": "dev", "state": "active", "rancherKubernetesEngineConfig": {"kubernetesVersion": "v1.9.5-rancher1-1", "network": {"plugin": "canal"}}, "version": {"gitVersion": "v1.9.5"}}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/v3/clusters/c-abcde":
+			err := json.NewDecoder(r.Body).Decode(&updated)
+			if err != nil {
+				t.Fatal(err)
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/clusters/c-abcde":
+			polls++
+			if polls == 1 {
+				fmt.Fprint(w, `{"id": "c-abcde", "name": "dev", "state": "updating", "version": {"gitVersion": "v1.9.5"}}`)
+			} else {
+				fmt.Fprint(w, `{"id": "c-abcde", "name": "dev", "state": "active", "version": {"gitVersion": "v1.10.5"}}`)
+			}
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	cluster, err := client.Cluster("c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cluster.RKEConfig == nil || cluster.RKEConfig.KubernetesVersion != "v1.9.5-rancher1-1" {
+		t.Fatalf("Unexpected cluster %+v", cluster)
+	}
+
+	strategy := UpgradeStrategy{MaxUnavailableWorker: "10%", MaxUnavailableControlplane: "1", Drain: true}
+	err = client.UpgradeKubernetes(cluster, "v1.10.5-rancher1-2", strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rkeConfig := updated["rancherKubernetesEngineConfig"].(map[string]interface{})
+	if rkeConfig["kubernetesVersion"] != "v1.10.5-rancher1-2" {
+		t.Errorf("Wrong output, expected v1.10.5-rancher1-2, received %v", rkeConfig["kubernetesVersion"])
+	}
+	if rkeConfig["network"] == nil {
+		t.Error("Expected the rest of the config to be kept")
+	}
+	upgradeStrategy := rkeConfig["upgradeStrategy"].(map[string]interface{})
+	if upgradeStrategy["maxUnavailableWorker"] != "10%" || upgradeStrategy["drain"] != true {
+		t.Errorf("Unexpected upgrade strategy %v", upgradeStrategy)
+	}
+
+	err = client.WaitForKubernetesVersion("c-abcde", "v1.10.5-rancher1-2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 2 {
+		t.Errorf("Wrong output, expected 2 polls, received %d", polls)
+	}
+}
+
+func TestCompareKubernetesVersions(t *testing.T) {
+	if CompareKubernetesVersions("v1.10.0-rancher1-1", "v1.9.5-rancher1-1") <= 0 {
+		t.Error("Expected v1.10.0-rancher1-1 to be newer than v1.9.5-rancher1-1")
+	}
+	if CompareKubernetesVersions("v1.10.5-rancher1-1", "v1.10.5-rancher1-2") >= 0 {
+		t.Error("Expected v1.10.5-rancher1-1 to be older than v1.10.5-rancher1-2")
+	}
+	if CompareKubernetesVersions("v1.10.5-rancher1-1", "v1.10.5-rancher1-1") != 0 {
+		t.Error("Expected the same versions to be equal")
+	}
+}
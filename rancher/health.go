@@ -0,0 +1,79 @@
+package rancher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// How long to wait for the host of a cluster manager to accept a connection
+var hostDialTimeout = 10 * time.Second
+
+// ClusterCondition is a condition of a cluster, e.g. whether its agent is connected.
+type ClusterCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// HostReachable returns an error unless the host of the API URL accepts TCP connections, which
+// tells a cluster manager whose machine is down from one whose Rancher server is down.
+func (c *Client) HostReachable() error {
+	apiURL, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+
+	port := apiURL.Port()
+	if port == "" {
+		port = "443"
+		if apiURL.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(apiURL.Hostname(), port), hostDialTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Ping returns an error unless the Rancher server answers its health endpoint, which doesn't
+// require credentials.
+func (c *Client) Ping() error {
+	resp, err := c.httpClient.Get(c.URL + "/ping")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(content)) != "pong" {
+		return fmt.Errorf("Rancher /ping returned %s", resp.Status)
+	}
+	return nil
+}
+
+// AgentConnected returns whether the cluster agent is connected to Rancher and, when it isn't,
+// the reason Rancher gives. Rancher 2.4 and later have a Connected condition, older versions
+// report disconnected agents in the Ready condition.
+func (cluster Cluster) AgentConnected() (bool, string) {
+	for _, condition := range cluster.Conditions {
+		if condition.Type == "Connected" && condition.Status == "False" {
+			return false, condition.Message
+		}
+		if condition.Type == "Ready" && condition.Status == "False" && strings.Contains(condition.Message, "not connected") {
+			return false, condition.Message
+		}
+	}
+	return true, ""
+}
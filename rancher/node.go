@@ -0,0 +1,140 @@
+package rancher
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Node is a node registered in a cluster.
+type Node struct {
+	ID           string            `json:"id"`
+	Hostname     string            `json:"hostname"`
+	State        string            `json:"state"`
+	ClusterID    string            `json:"clusterId"`
+	Etcd         bool              `json:"etcd"`
+	ControlPlane bool              `json:"controlPlane"`
+	Worker       bool              `json:"worker"`
+	Links        map[string]string `json:"links,omitempty"`
+	Actions      map[string]string `json:"actions,omitempty"`
+
+	IPAddress         string          `json:"ipAddress,omitempty"`
+	ExternalIPAddress string          `json:"externalIpAddress,omitempty"`
+	Info              *NodeInfo       `json:"info,omitempty"`
+	Conditions        []NodeCondition `json:"conditions,omitempty"`
+}
+
+// NodeInfo is what the Rancher agent reports about a node.
+type NodeInfo struct {
+	Kubernetes struct {
+		KubeletVersion string `json:"kubeletVersion"`
+	} `json:"kubernetes"`
+}
+
+// NodeCondition is a condition of a node reported by its kubelet, e.g. Ready.
+type NodeCondition struct {
+	Type              string `json:"type"`
+	Status            string `json:"status"`
+	LastHeartbeatTime string `json:"lastHeartbeatTime,omitempty"`
+}
+
+// KubernetesVersion returns the version of the kubelet of the node, if it's known.
+func (n Node) KubernetesVersion() string {
+	if n.Info == nil {
+		return ""
+	}
+	return n.Info.Kubernetes.KubeletVersion
+}
+
+// LastHeartbeat returns when the kubelet of the node last reported it was ready, if it has.
+func (n Node) LastHeartbeat() string {
+	for _, condition := range n.Conditions {
+		if condition.Type == "Ready" {
+			return condition.LastHeartbeatTime
+		}
+	}
+	return ""
+}
+
+type nodeDrainInput struct {
+	DeleteLocalData  bool `json:"deleteLocalData"`
+	Force            bool `json:"force"`
+	IgnoreDaemonSets bool `json:"ignoreDaemonSets"`
+	GracePeriod      int  `json:"gracePeriod"`
+	Timeout          int  `json:"timeout"`
+}
+
+type nodeCordonInput struct {
+	Unschedulable bool `json:"unschedulable"`
+}
+
+// How often the state of a draining node is checked
+var nodeDrainPollInterval = 5 * time.Second
+
+// Nodes returns the nodes registered in the given cluster.
+func (c *Client) Nodes(clusterID string) ([]Node, error) {
+	query := url.Values{}
+	query.Set("clusterId", clusterID)
+
+	nodes := []Node{}
+	err := c.list("/v3/nodes?"+query.Encode(), &nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// DeleteNode removes the node from its cluster.
+func (c *Client) DeleteNode(node Node) error {
+	removeURL, ok := node.Links["remove"]
+	if !ok {
+		return fmt.Errorf("Node '%s' can't be removed while it is %s", node.Hostname, node.State)
+	}
+
+	return c.do(http.MethodDelete, removeURL, nil, nil)
+}
+
+// DrainNode evicts the pods of the node and waits until it is drained or the timeout expires.
+// Rancher versions without the drain action only cordon the node, so no new pods are
+// scheduled on it.
+func (c *Client) DrainNode(node Node, timeout time.Duration) error {
+	drainURL, ok := node.Actions["drain"]
+	if !ok {
+		return c.do(http.MethodPut, node.Links["self"], &nodeCordonInput{Unschedulable: true}, nil)
+	}
+
+	input := nodeDrainInput{
+		DeleteLocalData:  true,
+		Force:            true,
+		IgnoreDaemonSets: true,
+		GracePeriod:      -1,
+		Timeout:          int(timeout.Seconds()),
+	}
+	err := c.do(http.MethodPost, drainURL, &input, nil)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		current := Node{}
+		err = c.do(http.MethodGet, node.Links["self"], nil, &current)
+		if err != nil {
+			return err
+		}
+
+		if current.State == "drained" {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return util.TimeoutError(fmt.Errorf("Node '%s' wasn't drained after %s, it is %s", node.Hostname, timeout, current.State))
+		}
+
+		time.Sleep(nodeDrainPollInterval)
+	}
+}
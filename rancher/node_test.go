@@ -0,0 +1,92 @@
+package rancher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNodes(t *testing.T) {
+	deleted := ""
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/nodes":
+			if r.URL.Query().Get("clusterId") != "c-abcde" {
+				t.Errorf("Unexpected request %s", r.URL)
+			}
+			fmt.Fprintf(w, `{"data": [{"id": "c-abcde:m-1", "hostname": "w-i-0abc", "state": "unavailable", "links": {"remove": "%s/v3/nodes/c-abcde:m-1"}}]}`, server.URL)
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	nodes, err := client.Nodes("c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nodes) != 1 || nodes[0].Hostname != "w-i-0abc" {
+		t.Fatalf("Unexpected nodes %+v", nodes)
+	}
+
+	err = client.DeleteNode(nodes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted != "/v3/nodes/c-abcde:m-1" {
+		t.Errorf("Wrong output, expected /v3/nodes/c-abcde:m-1, received %s", deleted)
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	nodeDrainPollInterval = time.Millisecond
+
+	polls := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/nodes/c-abcde:m-1" && r.URL.Query().Get("action") == "drain":
+			input := nodeDrainInput{}
+			err := json.NewDecoder(r.Body).Decode(&input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !input.IgnoreDaemonSets || input.Timeout != 60 {
+				t.Errorf("Unexpected drain input %+v", input)
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/nodes/c-abcde:m-1":
+			polls++
+			state := "draining"
+			if polls == 2 {
+				state = "drained"
+			}
+			fmt.Fprintf(w, `{"id": "c-abcde:m-1", "hostname": "w-000001", "state": "%s"}`, state)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	err := client.DrainNode(Node{
+		ID:       "c-abcde:m-1",
+		Hostname: "w-000001",
+		Links:    map[string]string{"self": server.URL + "/v3/nodes/c-abcde:m-1"},
+		Actions:  map[string]string{"drain": server.URL + "/v3/nodes/c-abcde:m-1?action=drain"},
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if polls != 2 {
+		t.Errorf("Wrong output, expected 2 polls, received %d", polls)
+	}
+}
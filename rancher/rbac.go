@@ -0,0 +1,198 @@
+package rancher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// User is a user of the cluster manager, local or from an authentication provider.
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// Principal is a user or group of an authentication provider, e.g.
+// github_team://1234 or activedirectory_group://CN=devs,DC=example,DC=com.
+type Principal struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	LoginName     string `json:"loginName"`
+	PrincipalType string `json:"principalType"`
+}
+
+// RoleTemplate is a role that's granted in a cluster or in a project, e.g. cluster-member or
+// project-owner.
+type RoleTemplate struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Context string `json:"context"`
+}
+
+// RoleTemplateBinding grants a role to a user or group, in a cluster when ClusterID is set
+// and in a project when ProjectID is set. Users are given by id, or by principal id, e.g.
+// github_user://1234, so users of an authentication provider can be granted roles before they
+// first log in.
+type RoleTemplateBinding struct {
+	ID               string            `json:"id,omitempty"`
+	ClusterID        string            `json:"clusterId,omitempty"`
+	ProjectID        string            `json:"projectId,omitempty"`
+	RoleTemplateID   string            `json:"roleTemplateId"`
+	UserID           string            `json:"userId,omitempty"`
+	UserPrincipalID  string            `json:"userPrincipalId,omitempty"`
+	GroupPrincipalID string            `json:"groupPrincipalId,omitempty"`
+	Links            map[string]string `json:"links,omitempty"`
+}
+
+type principalSearchInput struct {
+	Name          string `json:"name"`
+	PrincipalType string `json:"principalType"`
+}
+
+// User returns the user with the given username.
+func (c *Client) User(username string) (User, error) {
+	query := url.Values{}
+	query.Set("username", username)
+
+	users := []User{}
+	err := c.list("/v3/users?"+query.Encode(), &users)
+	if err != nil {
+		return User{}, err
+	}
+
+	for _, user := range users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return User{}, fmt.Errorf("A user named '%s', does not exist.", username)
+}
+
+// GroupPrincipalID returns the principal id of the given group. A principal id is returned as
+// is, a name is searched for with the authentication provider, which must find exactly one
+// group of that name.
+func (c *Client) GroupPrincipalID(group string) (string, error) {
+	if strings.Contains(group, "://") {
+		return group, nil
+	}
+
+	result := collection{}
+	err := c.do(http.MethodPost, "/v3/principals?action=search", &principalSearchInput{Name: group, PrincipalType: "group"}, &result)
+	if err != nil {
+		return "", err
+	}
+
+	principals := []Principal{}
+	if len(result.Data) > 0 {
+		err = json.Unmarshal(result.Data, &principals)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The search matches names by prefix
+	ids := []string{}
+	for _, principal := range principals {
+		if principal.PrincipalType == "group" && (principal.Name == group || principal.LoginName == group) {
+			ids = append(ids, principal.ID)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("A group named '%s', does not exist.", group)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("More than one group is named '%s', use the principal id of the group: %s", group, strings.Join(ids, ", "))
+	}
+}
+
+// RoleTemplate returns the role with the given id, e.g. cluster-member, or name, e.g.
+// Cluster Member.
+func (c *Client) RoleTemplate(role string) (RoleTemplate, error) {
+	roleTemplates := []RoleTemplate{}
+	err := c.list("/v3/roleTemplates", &roleTemplates)
+	if err != nil {
+		return RoleTemplate{}, err
+	}
+
+	for _, roleTemplate := range roleTemplates {
+		if roleTemplate.ID == role || roleTemplate.Name == role {
+			return roleTemplate, nil
+		}
+	}
+
+	return RoleTemplate{}, fmt.Errorf("A role named '%s', does not exist.", role)
+}
+
+// Returns the path of the collection of the binding's cluster or project bindings.
+func roleTemplateBindingsPath(binding RoleTemplateBinding) string {
+	if binding.ProjectID != "" {
+		return "/v3/projectroletemplatebindings"
+	}
+	return "/v3/clusterroletemplatebindings"
+}
+
+// RoleTemplateBindings returns the bindings that grant the same role to the same user or
+// group in the same cluster or project as the given binding.
+func (c *Client) RoleTemplateBindings(binding RoleTemplateBinding) ([]RoleTemplateBinding, error) {
+	query := url.Values{}
+	if binding.ProjectID != "" {
+		query.Set("projectId", binding.ProjectID)
+	} else {
+		query.Set("clusterId", binding.ClusterID)
+	}
+	query.Set("roleTemplateId", binding.RoleTemplateID)
+	if binding.UserID != "" {
+		query.Set("userId", binding.UserID)
+	}
+	if binding.UserPrincipalID != "" {
+		query.Set("userPrincipalId", binding.UserPrincipalID)
+	}
+	if binding.GroupPrincipalID != "" {
+		query.Set("groupPrincipalId", binding.GroupPrincipalID)
+	}
+
+	bindings := []RoleTemplateBinding{}
+	err := c.list(roleTemplateBindingsPath(binding)+"?"+query.Encode(), &bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filters the API doesn't know are ignored, so the bindings are checked again. Rancher sets
+	// the user id of bindings created for a user principal, they're matched by principal id.
+	matching := []RoleTemplateBinding{}
+	for _, b := range bindings {
+		sameUser := b.UserID == binding.UserID
+		if binding.UserPrincipalID != "" {
+			sameUser = b.UserPrincipalID == binding.UserPrincipalID
+		}
+		if b.ClusterID == binding.ClusterID && b.ProjectID == binding.ProjectID && b.RoleTemplateID == binding.RoleTemplateID &&
+			sameUser && b.GroupPrincipalID == binding.GroupPrincipalID {
+			matching = append(matching, b)
+		}
+	}
+	return matching, nil
+}
+
+// CreateRoleTemplateBinding grants the binding's role.
+func (c *Client) CreateRoleTemplateBinding(binding RoleTemplateBinding) (RoleTemplateBinding, error) {
+	created := RoleTemplateBinding{}
+	err := c.do(http.MethodPost, roleTemplateBindingsPath(binding), &binding, &created)
+	return created, err
+}
+
+// DeleteRoleTemplateBinding revokes the binding's role.
+func (c *Client) DeleteRoleTemplateBinding(binding RoleTemplateBinding) error {
+	removeURL, ok := binding.Links["remove"]
+	if !ok {
+		removeURL = roleTemplateBindingsPath(binding) + "/" + binding.ID
+	}
+
+	return c.do(http.MethodDelete, removeURL, nil, nil)
+}
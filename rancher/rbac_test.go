@@ -0,0 +1,150 @@
+package rancher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroupPrincipalID(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/principals" || r.URL.Query().Get("action") != "search" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+
+		search := principalSearchInput{}
+		json.NewDecoder(r.Body).Decode(&search)
+		if search.PrincipalType != "group" {
+			t.Errorf("Expected a search for groups, received %+v", search)
+		}
+
+		// The search matches by prefix
+		w.Write([]byte(`{"data": [
+			{"id": "github_team://1", "name": "devs", "principalType": "group"},
+			{"id": "github_team://2", "name": "devs-admins", "principalType": "group"},
+			{"id": "github_team://3", "name": "ops", "loginName": "ops", "principalType": "group"},
+			{"id": "github_team://4", "name": "ops", "principalType": "group"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	id, err := client.GroupPrincipalID("devs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "github_team://1" {
+		t.Errorf("Wrong output, expected github_team://1, received %s", id)
+	}
+
+	_, err = client.GroupPrincipalID("ops")
+	if err == nil || !strings.Contains(err.Error(), "github_team://3, github_team://4") {
+		t.Errorf("Expected an error listing both ops groups, received %v", err)
+	}
+
+	_, err = client.GroupPrincipalID("qa")
+	if err == nil {
+		t.Error("Expected an error for a group that doesn't exist")
+	}
+
+	// Principal ids are used as is
+	id, err = client.GroupPrincipalID("activedirectory_group://CN=qa,DC=example,DC=com")
+	if err != nil || id != "activedirectory_group://CN=qa,DC=example,DC=com" {
+		t.Errorf("Expected the principal id, received %s, %v", id, err)
+	}
+}
+
+func TestRoleTemplateBindings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/projectroletemplatebindings" || r.URL.Query().Get("projectId") != "c-abcde:p-12345" || r.URL.Query().Get("userId") != "u-bob" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+
+		// An API that ignores the filters returns other bindings too
+		w.Write([]byte(`{"data": [
+			{"id": "p-12345:prtb-1", "projectId": "c-abcde:p-12345", "roleTemplateId": "project-member", "userId": "u-bob"},
+			{"id": "p-12345:prtb-2", "projectId": "c-abcde:p-12345", "roleTemplateId": "project-owner", "userId": "u-bob"},
+			{"id": "p-12345:prtb-3", "projectId": "c-abcde:p-12345", "roleTemplateId": "project-member", "userId": "u-alice"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	bindings, err := client.RoleTemplateBindings(RoleTemplateBinding{
+		ProjectID:      "c-abcde:p-12345",
+		RoleTemplateID: "project-member",
+		UserID:         "u-bob",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bindings) != 1 || bindings[0].ID != "p-12345:prtb-1" {
+		t.Errorf("Wrong output, expected [p-12345:prtb-1], received %+v", bindings)
+	}
+}
+
+func TestRoleTemplateBindingsUserPrincipal(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("userPrincipalId") != "github_user://1234" || r.URL.Query().Get("userId") != "" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+
+		w.Write([]byte(`{"data": [
+			{"id": "c-abcde:crtb-1", "clusterId": "c-abcde", "roleTemplateId": "cluster-member", "userId": "u-bob", "userPrincipalId": "github_user://1234"},
+			{"id": "c-abcde:crtb-2", "clusterId": "c-abcde", "roleTemplateId": "cluster-member", "userId": "u-alice", "userPrincipalId": "github_user://5678"}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	bindings, err := client.RoleTemplateBindings(RoleTemplateBinding{
+		ClusterID:       "c-abcde",
+		RoleTemplateID:  "cluster-member",
+		UserPrincipalID: "github_user://1234",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bindings) != 1 || bindings[0].ID != "c-abcde:crtb-1" {
+		t.Errorf("Wrong output, expected [c-abcde:crtb-1], received %+v", bindings)
+	}
+}
+
+func TestCreateClusterRoleTemplateBinding(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/clusterroletemplatebindings" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+
+		binding := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&binding)
+		if binding["clusterId"] != "c-abcde" || binding["roleTemplateId"] != "cluster-member" || binding["groupPrincipalId"] != "github_team://1" {
+			t.Errorf("Unexpected binding %v", binding)
+		}
+		if _, ok := binding["userId"]; ok {
+			t.Errorf("Expected no userId for a group, received %v", binding)
+		}
+
+		binding["id"] = "c-abcde:crtb-1"
+		json.NewEncoder(w).Encode(binding)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "access", "secret")
+	binding, err := client.CreateRoleTemplateBinding(RoleTemplateBinding{
+		ClusterID:        "c-abcde",
+		RoleTemplateID:   "cluster-member",
+		GroupPrincipalID: "github_team://1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if binding.ID != "c-abcde:crtb-1" {
+		t.Errorf("Wrong output, expected c-abcde:crtb-1, received %s", binding.ID)
+	}
+}
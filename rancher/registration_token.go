@@ -0,0 +1,74 @@
+package rancher
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// NodeRegistrationTokenLabel labels the registration tokens issued to the nodes triton-kubernetes
+// adds, so they aren't taken for the cluster's own token.
+const NodeRegistrationTokenLabel = "triton-kubernetes.joyent.com/nodes"
+
+// ClusterRegistrationToken is a token nodes register with a cluster with.
+type ClusterRegistrationToken struct {
+	ID        string            `json:"id"`
+	ClusterID string            `json:"clusterId"`
+	Token     string            `json:"token"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type clusterRegistrationTokenInput struct {
+	Type      string            `json:"type"`
+	ClusterID string            `json:"clusterId"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// ClusterRegistrationTokens returns the registration tokens of the given cluster.
+func (c *Client) ClusterRegistrationTokens(clusterID string) ([]ClusterRegistrationToken, error) {
+	query := url.Values{}
+	query.Set("clusterId", clusterID)
+
+	tokens := []ClusterRegistrationToken{}
+	err := c.list("/v3/clusterregistrationtokens?"+query.Encode(), &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// CreateClusterRegistrationToken creates a new registration token for the nodes of the given
+// cluster, labeled with NodeRegistrationTokenLabel. It's valid until it's deleted.
+func (c *Client) CreateClusterRegistrationToken(clusterID string) (ClusterRegistrationToken, error) {
+	token := ClusterRegistrationToken{}
+	input := &clusterRegistrationTokenInput{
+		Type:      "clusterRegistrationToken",
+		ClusterID: clusterID,
+		Labels:    map[string]string{NodeRegistrationTokenLabel: "true"},
+	}
+	err := c.do(http.MethodPost, "/v3/clusterregistrationtokens", input, &token)
+	if err != nil {
+		return ClusterRegistrationToken{}, err
+	}
+
+	// The token value is generated asynchronously by some Rancher versions
+	if token.Token == "" && token.ID != "" {
+		err = c.do(http.MethodGet, "/v3/clusterregistrationtokens/"+token.ID, nil, &token)
+		if err != nil {
+			return ClusterRegistrationToken{}, err
+		}
+	}
+
+	if token.ID == "" || token.Token == "" {
+		return ClusterRegistrationToken{}, errors.New("Rancher API didn't return the created registration token")
+	}
+
+	return token, nil
+}
+
+// DeleteClusterRegistrationToken deletes the registration token with the given id, nodes can't
+// register with it anymore.
+func (c *Client) DeleteClusterRegistrationToken(id string) error {
+	return c.do(http.MethodDelete, "/v3/clusterregistrationtokens/"+id, nil, nil)
+}
@@ -0,0 +1,63 @@
+package rancher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClusterRegistrationToken(t *testing.T) {
+	deleted := ""
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/clusterregistrationtokens":
+			body, _ := ioutil.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"clusterId":"c-abcde"`) || !strings.Contains(string(body), `"labels":{"triton-kubernetes.joyent.com/nodes":"true"}`) {
+				t.Errorf("Unexpected body %s", body)
+			}
+			fmt.Fprint(w, `{"id": "c-abcde:crt-xyz", "clusterId": "c-abcde"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/clusterregistrationtokens":
+			if r.URL.Query().Get("clusterId") != "c-abcde" {
+				t.Errorf("Unexpected query %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"data": [{"id": "c-abcde:default-token", "clusterId": "c-abcde", "token": "d3fault"}, {"id": "c-abcde:crt-xyz", "clusterId": "c-abcde", "token": "s3cr3t"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/clusterregistrationtokens/c-abcde:crt-xyz":
+			fmt.Fprint(w, `{"id": "c-abcde:crt-xyz", "clusterId": "c-abcde", "token": "s3cr3t"}`)
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token-abc", "secret")
+	token, err := client.CreateClusterRegistrationToken("c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.ID != "c-abcde:crt-xyz" || token.Token != "s3cr3t" {
+		t.Errorf("Unexpected token %+v", token)
+	}
+
+	tokens, err := client.ClusterRegistrationTokens("c-abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[1].ID != token.ID || tokens[1].Token != token.Token {
+		t.Errorf("Unexpected tokens %+v", tokens)
+	}
+
+	err = client.DeleteClusterRegistrationToken(token.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted != "/v3/clusterregistrationtokens/c-abcde:crt-xyz" {
+		t.Errorf("Wrong output, expected /v3/clusterregistrationtokens/c-abcde:crt-xyz, received %s", deleted)
+	}
+}
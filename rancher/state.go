@@ -0,0 +1,87 @@
+package rancher
+
+import (
+	"fmt"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// NewClientFromState returns a client for the Rancher API of the cluster manager of the given
+// state. The API URL is a terraform output of the cluster manager, the credentials are the
+// rotated token stored encrypted in the state, or the token created with the cluster manager.
+func NewClientFromState(conf config.Config, currentState state.State) (*Client, error) {
+	managerOutputs, err := shell.RunTerraformOutputWithState(conf, currentState, "cluster-manager")
+	if err != nil {
+		return nil, err
+	}
+
+	accessKey, secretKey, err := shell.RancherAPIKeys(conf, currentState)
+	if err != nil {
+		return nil, err
+	}
+	if accessKey != "" {
+		managerOutputs["rancher_access_key"] = accessKey
+		managerOutputs["rancher_secret_key"] = secretKey
+	}
+
+	return newClientFromOutputs(currentState.Name, managerOutputs)
+}
+
+// NewClusterClientFromState returns a client for the Rancher API of the cluster manager of the
+// given state and the Rancher id of the cluster, which is a terraform output of the cluster.
+func NewClusterClientFromState(conf config.Config, currentState state.State, clusterKey string) (*Client, string, error) {
+	client, err := NewClientFromState(conf, currentState)
+	if err != nil {
+		return nil, "", err
+	}
+
+	clusterID, err := ClusterIDFromState(conf, currentState, clusterKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, clusterID, nil
+}
+
+// ClusterIDFromState returns the Rancher id of the cluster of the given state.
+func ClusterIDFromState(conf config.Config, currentState state.State, clusterKey string) (string, error) {
+	clusterOutputs, err := shell.RunTerraformOutputWithState(conf, currentState, clusterKey)
+	if err != nil {
+		return "", err
+	}
+
+	clusterName := clusterKey
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return "", err
+	}
+	for name, key := range clusters {
+		if key == clusterKey {
+			clusterName = name
+		}
+	}
+
+	return clusterIDFromOutputs(clusterName, clusterOutputs)
+}
+
+func newClientFromOutputs(clusterManager string, managerOutputs map[string]interface{}) (*Client, error) {
+	rancherURL, _ := managerOutputs["rancher_url"].(string)
+	rancherAccessKey, _ := managerOutputs["rancher_access_key"].(string)
+	rancherSecretKey, _ := managerOutputs["rancher_secret_key"].(string)
+	if rancherURL == "" {
+		return nil, fmt.Errorf("Cluster manager '%s' has no Rancher API outputs, it may not have been created successfully.", clusterManager)
+	}
+
+	return NewClient(rancherURL, rancherAccessKey, rancherSecretKey), nil
+}
+
+func clusterIDFromOutputs(clusterName string, clusterOutputs map[string]interface{}) (string, error) {
+	clusterID, _ := clusterOutputs["rancher_cluster_id"].(string)
+	if clusterID == "" {
+		return "", fmt.Errorf("Cluster '%s' has no Rancher cluster id, it may not have been created successfully.", clusterName)
+	}
+
+	return clusterID, nil
+}
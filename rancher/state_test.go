@@ -0,0 +1,45 @@
+package rancher
+
+import (
+	"testing"
+)
+
+func TestNewClientFromOutputs(t *testing.T) {
+	client, err := newClientFromOutputs("dev", map[string]interface{}{
+		"rancher_url":        "https://10.0.0.1/",
+		"rancher_access_key": "token-abc",
+		"rancher_secret_key": "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.URL != "https://10.0.0.1" || client.AccessKey != "token-abc" || client.SecretKey != "secret" {
+		t.Errorf("Unexpected client %+v", client)
+	}
+}
+
+func TestNewClientFromOutputsMissingURL(t *testing.T) {
+	_, err := newClientFromOutputs("dev", map[string]interface{}{})
+
+	expected := "Cluster manager 'dev' has no Rancher API outputs, it may not have been created successfully."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected '%s', received %v", expected, err)
+	}
+}
+
+func TestClusterIDFromOutputs(t *testing.T) {
+	clusterID, err := clusterIDFromOutputs("dev-cluster", map[string]interface{}{"rancher_cluster_id": "c-abc12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clusterID != "c-abc12" {
+		t.Errorf("Wrong output, expected c-abc12, received %s", clusterID)
+	}
+
+	_, err = clusterIDFromOutputs("dev-cluster", map[string]interface{}{})
+	expected := "Cluster 'dev-cluster' has no Rancher cluster id, it may not have been created successfully."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected '%s', received %v", expected, err)
+	}
+}
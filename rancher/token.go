@@ -0,0 +1,55 @@
+package rancher
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Token is an API token. The secret value is only returned when the token is created.
+type Token struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Token       string `json:"token,omitempty"`
+}
+
+type tokenInput struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	TTL         int    `json:"ttl"`
+}
+
+// AccessKey returns the access key of the token, which is its name.
+func (t Token) AccessKey() string {
+	return t.Name
+}
+
+// SecretKey returns the secret key of a created token.
+func (t Token) SecretKey() string {
+	parts := strings.SplitN(t.Token, ":", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// CreateToken creates an API token for the user of the client's token, which doesn't expire.
+func (c *Client) CreateToken(description string) (Token, error) {
+	token := Token{}
+	err := c.do(http.MethodPost, "/v3/tokens", &tokenInput{Type: "token", Description: description}, &token)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if token.Name == "" || token.SecretKey() == "" {
+		return Token{}, errors.New("Rancher API didn't return the created token")
+	}
+
+	return token, nil
+}
+
+// DeleteToken deletes the API token with the given access key, which invalidates it.
+func (c *Client) DeleteToken(accessKey string) error {
+	return c.do(http.MethodDelete, "/v3/tokens/"+accessKey, nil, nil)
+}
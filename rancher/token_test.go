@@ -0,0 +1,47 @@
+package rancher
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateToken(t *testing.T) {
+	deleted := ""
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKey, secretKey, _ := r.BasicAuth()
+		if accessKey != "token-old" || secretKey != "secret" {
+			t.Errorf("Unexpected credentials %s:%s", accessKey, secretKey)
+		}
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/tokens":
+			fmt.Fprint(w, `{"id": "token-new", "name": "token-new", "token": "token-new:n3w"}`)
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token-old", "secret")
+	token, err := client.CreateToken("rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessKey() != "token-new" || token.SecretKey() != "n3w" {
+		t.Errorf("Unexpected token %+v", token)
+	}
+
+	err = client.DeleteToken("token-old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted != "/v3/tokens/token-old" {
+		t.Errorf("Wrong output, expected /v3/tokens/token-old, received %s", deleted)
+	}
+}
@@ -0,0 +1,319 @@
+// Package sdk creates, destroys and reads cluster managers, clusters and nodes from Go programs.
+//
+// The functions run the same flows as the triton-kubernetes CLI in non-interactive mode: they
+// never prompt, and every setting the CLI would prompt for must be given in the spec. Settings
+// use the keys of the silent install yaml (see docs/guide/silent-install-yaml.md), e.g.
+//
+//	client := sdk.New(remoteBackend)
+//	err := client.CreateManager(ctx, sdk.ManagerSpec{
+//		Name:          "dev-manager",
+//		CloudProvider: "triton",
+//		Settings: map[string]interface{}{
+//			"triton_account": "myaccount",
+//			"triton_key_path": "~/.ssh/id_rsa",
+//		},
+//	})
+//
+// Operations are independent of each other and of the CLI: each one builds its own Config from
+// the spec. Operations that change a cluster manager lock its state like the CLI does, and fail
+// while someone else holds the lock. They are recorded in the journal of the cluster manager as
+// "sdk create cluster", "sdk destroy node", etc.
+package sdk
+
+import (
+	"context"
+	"errors"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/create"
+	"github.com/joyent/triton-kubernetes/destroy"
+	"github.com/joyent/triton-kubernetes/get"
+	"github.com/joyent/triton-kubernetes/journal"
+	"github.com/joyent/triton-kubernetes/rancher"
+)
+
+// ManagerSpec describes a cluster manager.
+type ManagerSpec struct {
+	Name string
+	// One of triton, aws, gcp, azure, digitalocean, equinixmetal, openstack, baremetal, proxmox, nutanix or libvirt.
+	CloudProvider string
+	// Provider and Rancher settings, keyed like the silent install yaml.
+	Settings map[string]interface{}
+}
+
+// ClusterSpec describes a kubernetes cluster and the nodes created with it.
+type ClusterSpec struct {
+	Manager string
+	Name    string
+	// One of triton, aws, gcp, azure, digitalocean, equinixmetal, openstack, baremetal, vsphere, proxmox, nutanix or libvirt.
+	CloudProvider string
+	Settings      map[string]interface{}
+	Nodes         []NodeSpec
+}
+
+// NodeSpec describes a node, or Count nodes, of a cluster. Manager and Cluster are ignored in
+// the Nodes of a ClusterSpec.
+type NodeSpec struct {
+	Manager  string
+	Cluster  string
+	Hostname string
+	// Any combination of etcd, control and worker, e.g. "control,etcd".
+	HostLabel string
+	Count     int
+	Settings  map[string]interface{}
+}
+
+// Client runs operations against the state stored in a backend.
+type Client struct {
+	backend backend.Backend
+}
+
+// New returns a Client storing state in the given backend, e.g. the one returned by local.New().
+func New(remoteBackend backend.Backend) *Client {
+	return &Client{backend: remoteBackend}
+}
+
+// CreateManager creates a cluster manager.
+func (c *Client) CreateManager(ctx context.Context, spec ManagerSpec) error {
+	if spec.Name == "" {
+		return errors.New("Manager name must be specified")
+	}
+	if spec.CloudProvider == "" {
+		return errors.New("Manager cloud provider must be specified")
+	}
+
+	conf := newConfig(spec.Settings)
+	conf.Set("name", spec.Name)
+	conf.Set("manager_cloud_provider", spec.CloudProvider)
+
+	return c.run(ctx, conf, "create manager", func(remoteBackend backend.Backend) error {
+		return create.NewManager(conf, remoteBackend)
+	})
+}
+
+// CreateCluster creates a kubernetes cluster in an existing cluster manager, along with the
+// nodes of the spec.
+func (c *Client) CreateCluster(ctx context.Context, spec ClusterSpec) error {
+	if spec.Manager == "" {
+		return errors.New("Cluster manager must be specified")
+	}
+	if spec.Name == "" {
+		return errors.New("Cluster name must be specified")
+	}
+	if spec.CloudProvider == "" {
+		return errors.New("Cluster cloud provider must be specified")
+	}
+
+	conf := newConfig(spec.Settings)
+	conf.Set("cluster_manager", spec.Manager)
+	conf.Set("name", spec.Name)
+	conf.Set("cluster_cloud_provider", spec.CloudProvider)
+
+	if len(spec.Nodes) > 0 {
+		nodes := []interface{}{}
+		for _, node := range spec.Nodes {
+			nodeSettings, err := node.settings()
+			if err != nil {
+				return err
+			}
+
+			// The cluster flow reads nodes in the format of the yaml decoder
+			nodeToAdd := map[interface{}]interface{}{}
+			for key, value := range nodeSettings {
+				nodeToAdd[key] = value
+			}
+			nodes = append(nodes, nodeToAdd)
+		}
+		conf.Set("nodes", nodes)
+	}
+
+	return c.run(ctx, conf, "create cluster", func(remoteBackend backend.Backend) error {
+		return create.NewCluster(conf, remoteBackend)
+	})
+}
+
+// AddNode adds nodes to an existing cluster.
+func (c *Client) AddNode(ctx context.Context, spec NodeSpec) error {
+	if spec.Manager == "" {
+		return errors.New("Cluster manager must be specified")
+	}
+	if spec.Cluster == "" {
+		return errors.New("Cluster name must be specified")
+	}
+
+	nodeSettings, err := spec.settings()
+	if err != nil {
+		return err
+	}
+
+	conf := newConfig(nodeSettings)
+	conf.Set("cluster_manager", spec.Manager)
+	conf.Set("cluster_name", spec.Cluster)
+
+	return c.run(ctx, conf, "create node", func(remoteBackend backend.Backend) error {
+		return create.NewNode(conf, remoteBackend)
+	})
+}
+
+// DestroyManager destroys a cluster manager and all of its clusters.
+func (c *Client) DestroyManager(ctx context.Context, manager string) error {
+	if manager == "" {
+		return errors.New("Cluster manager must be specified")
+	}
+
+	conf := newConfig(nil)
+	conf.Set("cluster_manager", manager)
+
+	return c.run(ctx, conf, "destroy manager", func(remoteBackend backend.Backend) error {
+		return destroy.DeleteManager(conf, remoteBackend)
+	})
+}
+
+// DestroyCluster destroys a cluster and its nodes.
+func (c *Client) DestroyCluster(ctx context.Context, manager, cluster string) error {
+	if manager == "" {
+		return errors.New("Cluster manager must be specified")
+	}
+	if cluster == "" {
+		return errors.New("Cluster name must be specified")
+	}
+
+	conf := newConfig(nil)
+	conf.Set("cluster_manager", manager)
+	conf.Set("cluster_name", cluster)
+
+	return c.run(ctx, conf, "destroy cluster", func(remoteBackend backend.Backend) error {
+		return destroy.DeleteCluster(conf, remoteBackend)
+	})
+}
+
+// DestroyNode destroys a node of a cluster. Unless force is true, nodes whose removal would
+// break etcd quorum or remove the last control plane node are refused.
+func (c *Client) DestroyNode(ctx context.Context, manager, cluster, hostname string, force bool) error {
+	if manager == "" {
+		return errors.New("Cluster manager must be specified")
+	}
+	if cluster == "" {
+		return errors.New("Cluster name must be specified")
+	}
+	if hostname == "" {
+		return errors.New("Hostname must be specified")
+	}
+
+	conf := newConfig(nil)
+	conf.Set("cluster_manager", manager)
+	conf.Set("cluster_name", cluster)
+	conf.Set("hostname", hostname)
+	conf.Set("force", force)
+
+	return c.run(ctx, conf, "destroy node", func(remoteBackend backend.Backend) error {
+		return destroy.DeleteNode(conf, remoteBackend)
+	})
+}
+
+// Managers returns the names of the cluster managers stored in the backend.
+func (c *Client) Managers(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.backend.States()
+}
+
+// Clusters returns the names of the clusters of a cluster manager, sorted.
+func (c *Client) Clusters(ctx context.Context, manager string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return get.Clusters(c.backend, manager)
+}
+
+// ManagerOutputs returns the terraform outputs of a cluster manager, e.g. rancher_url.
+func (c *Client) ManagerOutputs(ctx context.Context, manager string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return get.ManagerOutputs(newConfig(nil), backend.NewTerraformConfigBackend(c.backend), manager)
+}
+
+// ClusterOutputs returns the terraform outputs of a cluster, e.g. rancher_cluster_id.
+func (c *Client) ClusterOutputs(ctx context.Context, manager, cluster string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return get.ClusterOutputs(newConfig(nil), backend.NewTerraformConfigBackend(c.backend), manager, cluster)
+}
+
+// Nodes returns the nodes of a cluster registered in Rancher, with their state.
+func (c *Client) Nodes(ctx context.Context, manager, cluster string) ([]rancher.Node, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return get.ClusterNodes(newConfig(nil), backend.NewTerraformConfigBackend(c.backend), manager, cluster)
+}
+
+// Events returns the last limit operations run on a cluster manager, of the given cluster if
+// it isn't empty. A limit of 0 or less returns every operation.
+func (c *Client) Events(ctx context.Context, manager, cluster string, limit int) ([]journal.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return get.Events(c.backend, manager, cluster, limit)
+}
+
+// Returns the settings of the node, including the settings set through the fields of the spec.
+func (spec NodeSpec) settings() (map[string]interface{}, error) {
+	if spec.Hostname == "" {
+		return nil, errors.New("Node hostname must be specified")
+	}
+	if spec.HostLabel == "" {
+		return nil, errors.New("Node host label must be specified")
+	}
+	if spec.Count < 0 {
+		return nil, errors.New("Node count cannot be negative")
+	}
+
+	count := spec.Count
+	if count == 0 {
+		count = 1
+	}
+
+	settings := map[string]interface{}{}
+	for key, value := range spec.Settings {
+		settings[key] = value
+	}
+	settings["hostname"] = spec.Hostname
+	settings["rancher_host_label"] = spec.HostLabel
+	settings["node_count"] = count
+
+	return settings, nil
+}
+
+// Returns a non-interactive Config holding the given settings.
+func newConfig(settings map[string]interface{}) config.Config {
+	conf := config.New()
+	for key, value := range settings {
+		conf.Set(key, value)
+	}
+	conf.Set("non-interactive", true)
+	return conf
+}
+
+// Runs the operation unless the context is already done, locking every state it reads or
+// changes until it returns and recording it in their journals like the CLI does. Operations are
+// not interrupted once terraform is running, since that would leave the state out of sync with
+// the infrastructure.
+func (c *Client) run(ctx context.Context, conf config.Config, operation string, run func(backend.Backend) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	command := "sdk " + operation
+	lockingBackend := backend.NewLockingBackend(c.backend, command, false)
+	err := journal.Run(conf, backend.NewTerraformConfigBackend(lockingBackend), command, run)
+	unlockErr := lockingBackend.Unlock()
+	if err != nil {
+		return err
+	}
+	return unlockErr
+}
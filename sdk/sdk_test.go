@@ -0,0 +1,160 @@
+package sdk
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/backend/mocks"
+	"github.com/joyent/triton-kubernetes/journal"
+	"github.com/joyent/triton-kubernetes/state"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateManagerMissingName(t *testing.T) {
+	client := New(&mocks.Backend{})
+
+	expected := "Manager name must be specified"
+
+	err := client.CreateManager(context.Background(), ManagerSpec{CloudProvider: "triton"})
+	if err == nil || expected != err.Error() {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestAddNodeMissingHostLabel(t *testing.T) {
+	client := New(&mocks.Backend{})
+
+	expected := "Node host label must be specified"
+
+	err := client.AddNode(context.Background(), NodeSpec{Manager: "dev-manager", Cluster: "dev-cluster", Hostname: "dev-w"})
+	if err == nil || expected != err.Error() {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestDestroyManagerNotExist(t *testing.T) {
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{"dev-manager", "beta-manager"}, nil)
+	client := New(localBackend)
+
+	expected := "Selected cluster manager 'prod-manager' does not exist."
+
+	err := client.DestroyManager(context.Background(), "prod-manager")
+	if err == nil || expected != err.Error() {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestDestroyClusterCanceled(t *testing.T) {
+	client := New(&mocks.Backend{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.DestroyCluster(ctx, "dev-manager", "dev-cluster")
+	if err != context.Canceled {
+		t.Errorf("Wrong output, expected %v, received %v", context.Canceled, err)
+	}
+}
+
+func TestNodeSpecSettings(t *testing.T) {
+	spec := NodeSpec{
+		Hostname:  "dev-w",
+		HostLabel: "worker",
+		Settings: map[string]interface{}{
+			"triton_machine_package": "k4-highcpu-kvm-1.75G",
+			"hostname":               "ignored",
+		},
+	}
+
+	settings, err := spec.settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"triton_machine_package": "k4-highcpu-kvm-1.75G",
+		"hostname":               "dev-w",
+		"rancher_host_label":     "worker",
+		"node_count":             1,
+	}
+	if len(settings) != len(expected) {
+		t.Errorf("Wrong output, expected %v, received %v", expected, settings)
+	}
+	for key, value := range expected {
+		if settings[key] != value {
+			t.Errorf("Wrong output, expected %v, received %v", expected, settings)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module": {
+		"cluster_triton_prod": {"name": "prod"},
+		"cluster_aws_dev": {"name": "dev"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{"dev-manager"}, nil)
+	localBackend.On("State", "dev-manager").Return(currentState, nil)
+	client := New(localBackend)
+
+	clusters, err := client.Clusters(context.Background(), "dev-manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(clusters, []string{"dev", "prod"}) {
+		t.Errorf("Wrong output, expected [dev prod], received %v", clusters)
+	}
+}
+
+func TestClusterOutputsNotExist(t *testing.T) {
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{"dev-manager"}, nil)
+	localBackend.On("State", "dev-manager").Return(state.State{}, nil)
+	client := New(localBackend)
+
+	expected := "Selected cluster manager 'prod-manager' does not exist."
+
+	_, err := client.ClusterOutputs(context.Background(), "prod-manager", "dev")
+	if err == nil || expected != err.Error() {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestDestroyClusterJournaled(t *testing.T) {
+	currentState, err := state.New("dev-manager", []byte(`{"module":{"cluster-manager":{"name":"dev-manager"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	localBackend := &mocks.Backend{}
+	localBackend.On("States").Return([]string{"dev-manager"}, nil)
+	localBackend.On("State", "dev-manager").Return(currentState, nil)
+	localBackend.On("StateTerraformConfig", "dev-manager").Return("terraform.backend.local", map[string]interface{}{"path": "terraform.tfstate"})
+	var persisted state.State
+	localBackend.On("PersistState", mock.Anything).Run(func(args mock.Arguments) {
+		persisted = args.Get(0).(state.State)
+	}).Return(nil)
+	client := New(localBackend)
+
+	expected := "A cluster named 'dev-cluster', does not exist."
+
+	err = client.DestroyCluster(context.Background(), "dev-manager", "dev-cluster")
+	if err == nil || expected != err.Error() {
+		t.Fatalf("Wrong output, expected %s, received %v", expected, err)
+	}
+
+	// The failure is recorded like the CLI records it
+	events, err := journal.Events(persisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Command != "sdk destroy cluster" || events[0].Result != journal.ResultFailed || events[0].Error != expected {
+		t.Errorf("Wrong output, expected a failed sdk destroy cluster event, received %+v", events)
+	}
+}
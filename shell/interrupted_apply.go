@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// Data sources are read, not created, e.g. "module.cluster-manager.data.triton_image.image"
+var dataSourceAddressRegexp = regexp.MustCompile(`^(module\.[^.]+\.)*data\.`)
+
+// Returns the addresses of the resources in the state of an initialized working directory,
+// without data sources.
+func terraformStateList(options *ShellOptions) ([]string, error) {
+	output, err := RunShellCommandWithOutput(options, "terraform", "state", "list")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStateList(string(output)), nil
+}
+
+func parseStateList(output string) []string {
+	addresses := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		address := strings.TrimSpace(line)
+		if address == "" || dataSourceAddressRegexp.MatchString(address) {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// Returns the addresses in current that aren't in previous, in the order of current.
+func createdResources(previous, current []string) []string {
+	existing := map[string]bool{}
+	for _, address := range previous {
+		existing[address] = true
+	}
+
+	created := []string{}
+	for _, address := range current {
+		if !existing[address] {
+			created = append(created, address)
+		}
+	}
+	return created
+}
+
+// Called when terraform apply was interrupted with Ctrl-C, which terraform also gets, stopping
+// once what it's changing is done. The resources it created are billable, so destroying them is
+// offered right away, or done with destroy_on_interrupt in non-interactive mode. When they're
+// destroyed, an interrupt is returned, as if the apply never ran. Otherwise the returned error
+// fails the apply, which keeps the working directory so it can be resumed.
+func cleanUpInterruptedApply(options *ShellOptions, previous []string, previousErr error) error {
+	if previousErr != nil {
+		return fmt.Errorf("terraform apply was interrupted, the resources it created are unknown: %v", previousErr)
+	}
+
+	current, err := terraformStateList(options)
+	if err != nil {
+		return fmt.Errorf("terraform apply was interrupted, the resources it created are unknown: %v", err)
+	}
+
+	created := createdResources(previous, current)
+	if len(created) == 0 {
+		return errors.New("terraform apply was interrupted before it created any resources.")
+	}
+
+	fmt.Printf("terraform apply was interrupted after creating %d resources:\n", len(created))
+	for _, address := range created {
+		fmt.Printf("  %s\n", address)
+	}
+
+	conf := options.config()
+	destroy := conf.GetBool("destroy_on_interrupt")
+	if !destroy && !conf.GetBool("non-interactive") {
+		destroy, err = util.PromptForConfirmation("Destroy the resources created before the interrupt", "Destroy")
+		if err != nil {
+			return fmt.Errorf("terraform apply was interrupted, the resources it created were kept: %v", err)
+		}
+	}
+	if !destroy {
+		return errors.New("terraform apply was interrupted, the resources it created were kept.")
+	}
+
+	args := []string{"destroy", "-force"}
+	for _, address := range created {
+		args = append(args, "-target="+address)
+	}
+	err = runTerraformWithProgress(options, args...)
+	if err != nil {
+		return fmt.Errorf("terraform apply was interrupted and destroying the resources it created failed: %v", err)
+	}
+
+	return &util.InterruptedError{}
+}
@@ -0,0 +1,103 @@
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/spf13/viper"
+)
+
+// Prints the state list of the working directory and records the arguments of destroy
+const fakeTerraformScript = `#!/bin/sh
+case "$1" in
+version) echo "Terraform v0.11.14" ;;
+state) cat state.txt ;;
+destroy) echo "$@" > destroy.txt ;;
+esac
+`
+
+func TestCreatedResources(t *testing.T) {
+	previous := parseStateList(`module.cluster-manager.triton_machine.rancher_master
+module.cluster-manager.data.triton_image.image
+`)
+	current := parseStateList(`data.external.rancher_server
+module.cluster-manager.triton_machine.rancher_master
+module.cluster-manager.data.triton_image.image
+module.node_triton_dev_dev-w-1.triton_machine.host
+module.node_triton_dev_dev-w-1.data.template_file.install_rancher_agent
+module.data.triton_machine.host
+`)
+
+	expected := []string{"module.node_triton_dev_dev-w-1.triton_machine.host", "module.data.triton_machine.host"}
+	if created := createdResources(previous, current); !reflect.DeepEqual(created, expected) {
+		t.Errorf("Expected %v, got %v", expected, created)
+	}
+}
+
+func TestCleanUpInterruptedApply(t *testing.T) {
+	binDir, err := ioutil.TempDir("", "triton-kubernetes-bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(binDir)
+	err = ioutil.WriteFile(filepath.Join(binDir, "terraform"), []byte(fakeTerraformScript), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	workingDir, err := ioutil.TempDir("", "triton-kubernetes-workdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workingDir)
+	err = ioutil.WriteFile(filepath.Join(workingDir, "state.txt"), []byte("module.a.triton_machine.host\nmodule.b.triton_machine.host\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := config.New()
+	conf.Set("non-interactive", true)
+	conf.Set("log_level", "quiet")
+	options := &ShellOptions{Config: conf, WorkingDir: workingDir}
+	previous := []string{"module.a.triton_machine.host"}
+
+	defer viper.Reset()
+	// Only the settings of the options apply
+	viper.Set("destroy_on_interrupt", true)
+
+	// The resources are kept unless destroy_on_interrupt is set
+	err = cleanUpInterruptedApply(options, previous, nil)
+	if err == nil || err.Error() != "terraform apply was interrupted, the resources it created were kept." {
+		t.Errorf("Expected the resources to be kept, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workingDir, "destroy.txt")); err == nil {
+		t.Error("Expected nothing to be destroyed")
+	}
+
+	conf.Set("destroy_on_interrupt", true)
+	err = cleanUpInterruptedApply(options, previous, nil)
+	if !util.IsInterrupt(err) {
+		t.Errorf("Expected an interrupt once the resources are destroyed, got %v", err)
+	}
+	args, err := ioutil.ReadFile(filepath.Join(workingDir, "destroy.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(string(args)), "-force -target=module.b.triton_machine.host") {
+		t.Errorf("Expected a destroy of the created resource, got %s", args)
+	}
+
+	// Nothing is destroyed when the resources that existed before are unknown
+	err = cleanUpInterruptedApply(options, nil, os.ErrNotExist)
+	if err == nil || !strings.HasPrefix(err.Error(), "terraform apply was interrupted, the resources it created are unknown") {
+		t.Errorf("Expected the created resources to be unknown, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// LogLevel is how much of terraform's output is printed.
+type LogLevel int
+
+const (
+	// LogQuiet only prints failures and errors
+	LogQuiet LogLevel = iota
+	// LogNormal prints a line when each resource starts and finishes changing
+	LogNormal
+	// LogVerbose prints terraform's output as is
+	LogVerbose
+)
+
+// Returns the log level conf sets with --quiet, --verbose or log_level, LogNormal by default.
+func logLevel(conf config.Config) LogLevel {
+	switch {
+	case conf.GetBool("verbose") || conf.GetString("log_level") == "verbose":
+		return LogVerbose
+	case conf.GetBool("quiet") || conf.GetString("log_level") == "quiet":
+		return LogQuiet
+	}
+	return LogNormal
+}
+
+// ResourceEvent is a change in the progress of a resource terraform applies, e.g. created
+// after 1m2s.
+type ResourceEvent struct {
+	// creating, created, updating, updated, destroying, destroyed, progress or failed
+	Action  string
+	Address string
+	// How long the change has taken, as printed by terraform
+	Elapsed string
+}
+
+// Logger prints the progress of terraform at a log level.
+type Logger struct {
+	level LogLevel
+	out   io.Writer
+	err   io.Writer
+}
+
+// NewLogger returns a logger printing progress to stdout and errors to stderr.
+func NewLogger(level LogLevel) *Logger {
+	return &Logger{level: level, out: os.Stdout, err: os.Stderr}
+}
+
+// Resource prints the event, failures at any level and the other events from LogNormal.
+// Progress is only printed once a minute, so long changes show they're still going.
+func (logger *Logger) Resource(event ResourceEvent) {
+	if event.Action != "failed" && logger.level < LogNormal {
+		return
+	}
+	if event.Action == "progress" && !isWholeMinute(event.Elapsed) {
+		return
+	}
+
+	line := fmt.Sprintf("  %-10s %s", event.Action, event.Address)
+	if event.Elapsed != "" {
+		line += fmt.Sprintf(" (%s)", event.Elapsed)
+	}
+	fmt.Fprintln(logger.out, line)
+}
+
+// Summary prints terraform's summary of what it changed from LogNormal.
+func (logger *Logger) Summary(message string) {
+	if logger.level < LogNormal {
+		return
+	}
+	fmt.Fprintln(logger.out, message)
+}
+
+// Error prints an error reported by terraform at any level.
+func (logger *Logger) Error(message string) {
+	fmt.Fprintln(logger.err, message)
+}
+
+// Returns whether terraform's elapsed time, e.g. 2m0s or 120s, is a whole number of minutes.
+func isWholeMinute(elapsed string) bool {
+	var minutes, seconds int
+	if n, _ := fmt.Sscanf(elapsed, "%dm%ds", &minutes, &seconds); n == 2 {
+		return seconds == 0
+	}
+	if n, _ := fmt.Sscanf(elapsed, "%ds", &seconds); n == 1 {
+		return seconds > 0 && seconds%60 == 0
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+)
+
+// ErrPlanNotApplied is returned when a terraform plan was only previewed with plan_only, or
+// when it was declined. Nothing was changed.
+var ErrPlanNotApplied = errors.New("The terraform plan was not applied.")
+
+const planFileName = "triton-kubernetes.tfplan"
+
+// Actions of a plan, in the order they're printed
+var planActions = []string{"create", "update", "replace", "destroy"}
+
+var (
+	// Terraform 0.11 prints a resource per line prefixed by its action, e.g.
+	// "-/+ module.node_triton_dev_dev-w-1.triton_machine.host (new resource required)"
+	planResourceRegexp = regexp.MustCompile(`^\s*(\+|-|~|-/\+)\s+(\S+)`)
+	// Terraform 0.12 and later print a comment per resource, e.g.
+	// "# module.node_triton_dev_dev-w-1.triton_machine.host must be replaced"
+	planCommentRegexp = regexp.MustCompile(`^\s*# (\S+) (will be created|will be updated in-place|must be replaced|will be destroyed)`)
+	planSummaryRegexp = regexp.MustCompile(`^Plan: .*`)
+)
+
+// PlanChange is a resource that a terraform plan changes.
+type PlanChange struct {
+	Action  string
+	Address string
+}
+
+// Returns whether terraform applies and destroys are planned first: with plan_only the plan is
+// only shown, with confirm_plan it's shown and applied once confirmed.
+func planMode(conf config.Config) bool {
+	return conf.GetBool("plan_only") || conf.GetBool("confirm_plan")
+}
+
+// Runs terraform plan in an initialized working directory, shows what it changes and applies
+// it unless plan_only is set or it's declined.
+func planAndApply(shellOptions *ShellOptions, destroy bool, args []string) error {
+	planArgs := []string{"plan", "-input=false", "-no-color", "-out=" + planFileName}
+	if destroy {
+		planArgs = append(planArgs, "-destroy")
+	}
+
+	output, err := RunShellCommandWithOutput(shellOptions, "terraform", append(planArgs, args...)...)
+	if err != nil {
+		return err
+	}
+
+	changes, summary := parsePlan(string(output))
+	printPlan(changes, summary)
+
+	conf := shellOptions.config()
+	if conf.GetBool("plan_only") {
+		return ErrPlanNotApplied
+	}
+
+	if len(changes) > 0 {
+		if conf.GetBool("non-interactive") {
+			return errors.New("confirm_plan requires interactive mode, use plan_only to preview changes in non-interactive mode")
+		}
+
+		confirmed, err := util.PromptForConfirmation("Apply this plan", "Apply")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return ErrPlanNotApplied
+		}
+	}
+
+	// Applying the saved plan guarantees nothing but what was shown is changed
+	return runTerraformWithProgress(shellOptions, "apply", "-input=false", planFileName)
+}
+
+// Returns the resources changed by the output of terraform plan, sorted by action and address,
+// and its summary line.
+func parsePlan(output string) ([]PlanChange, string) {
+	resourceChanges := []PlanChange{}
+	commentChanges := []PlanChange{}
+	summary := ""
+
+	for _, line := range strings.Split(output, "\n") {
+		if match := planCommentRegexp.FindStringSubmatch(line); match != nil {
+			action := map[string]string{
+				"will be created":          "create",
+				"will be updated in-place": "update",
+				"must be replaced":         "replace",
+				"will be destroyed":        "destroy",
+			}[match[2]]
+			commentChanges = append(commentChanges, PlanChange{Action: action, Address: match[1]})
+		} else if match := planResourceRegexp.FindStringSubmatch(line); match != nil {
+			action := map[string]string{"+": "create", "~": "update", "-/+": "replace", "-": "destroy"}[match[1]]
+			resourceChanges = append(resourceChanges, PlanChange{Action: action, Address: match[2]})
+		} else if match := planSummaryRegexp.FindString(strings.TrimSpace(line)); match != "" {
+			summary = match
+		}
+	}
+
+	// The attributes of resources are prefixed by their action too since terraform 0.12
+	changes := resourceChanges
+	if len(commentChanges) > 0 {
+		changes = commentChanges
+	}
+
+	order := map[string]int{}
+	for i, action := range planActions {
+		order[action] = i
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Action != changes[j].Action {
+			return order[changes[i].Action] < order[changes[j].Action]
+		}
+		return changes[i].Address < changes[j].Address
+	})
+
+	return changes, summary
+}
+
+func printPlan(changes []PlanChange, summary string) {
+	if len(changes) == 0 {
+		fmt.Println("Terraform plan: no changes.")
+		return
+	}
+
+	fmt.Println("Terraform plan:")
+	for _, change := range changes {
+		fmt.Printf("  %-8s %s\n", change.Action, change.Address)
+	}
+	if summary != "" {
+		fmt.Println(summary)
+	}
+}
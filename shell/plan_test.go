@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+
+	"github.com/spf13/viper"
+)
+
+func TestParsePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		changes []PlanChange
+		summary string
+	}{
+		{
+			name: "terraform 0.11",
+			output: `An execution plan has been generated and is shown below.
+
+  + module.node_triton_dev_dev-w-2.triton_machine.host
+      id:                   <computed>
+      name:                 "dev-w-2"
+
+  ~ module.cluster_triton_dev.rancher_cluster.cluster
+      name:                 "dev" => "dev"
+
+-/+ module.node_triton_dev_dev-w-1.triton_machine.host (new resource required)
+      id:                   "1234" => <computed> (forces new resource)
+
+  - module.node_triton_dev_dev-w-3.triton_machine.host
+
+ <= module.node_triton_dev_dev-w-2.data.template_file.install_rancher_agent
+
+Plan: 2 to add, 1 to change, 2 to destroy.
+`,
+			changes: []PlanChange{
+				{"create", "module.node_triton_dev_dev-w-2.triton_machine.host"},
+				{"update", "module.cluster_triton_dev.rancher_cluster.cluster"},
+				{"replace", "module.node_triton_dev_dev-w-1.triton_machine.host"},
+				{"destroy", "module.node_triton_dev_dev-w-3.triton_machine.host"},
+			},
+			summary: "Plan: 2 to add, 1 to change, 2 to destroy.",
+		},
+		{
+			name: "terraform 0.12",
+			output: `  # module.node_triton_dev_dev-w-2.triton_machine.host will be created
+  + resource "triton_machine" "host" {
+      + id   = (known after apply)
+      + name = "dev-w-2"
+    }
+
+  # module.node_triton_dev_dev-w-1.triton_machine.host must be replaced
+-/+ resource "triton_machine" "host" {
+    }
+
+Plan: 2 to add, 0 to change, 1 to destroy.
+`,
+			changes: []PlanChange{
+				{"create", "module.node_triton_dev_dev-w-2.triton_machine.host"},
+				{"replace", "module.node_triton_dev_dev-w-1.triton_machine.host"},
+			},
+			summary: "Plan: 2 to add, 0 to change, 1 to destroy.",
+		},
+		{
+			name:    "no changes",
+			output:  "No changes. Infrastructure is up-to-date.\n",
+			changes: []PlanChange{},
+		},
+	}
+
+	for _, test := range tests {
+		changes, summary := parsePlan(test.output)
+		if !reflect.DeepEqual(changes, test.changes) {
+			t.Errorf("%s: expected changes %v, got %v", test.name, test.changes, changes)
+		}
+		if summary != test.summary {
+			t.Errorf("%s: expected summary %q, got %q", test.name, test.summary, summary)
+		}
+	}
+}
+
+func TestPlanMode(t *testing.T) {
+	// Settings of the global viper don't apply to operations with their own Config
+	viper.Set("plan_only", true)
+	defer viper.Reset()
+
+	conf := config.New()
+	if planMode(conf) {
+		t.Error("Expected plan mode to be off without plan_only or confirm_plan")
+	}
+
+	conf.Set("confirm_plan", true)
+	if !planMode(conf) {
+		t.Error("Expected plan mode with confirm_plan")
+	}
+}
@@ -0,0 +1,136 @@
+package shell
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Values shorter than this aren't masked, they'd mask unrelated output.
+const minSensitiveValueLength = 6
+
+// Returns the secret values of the given state that terraform may print: the values of the
+// module variables named like secrets and of the TF_VAR_ environment variables.
+func sensitiveValues(currentState state.State, env []string) []string {
+	values := []string{}
+	for _, module := range currentState.GetMap("module") {
+		variables, ok := module.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range variables {
+			value, ok := value.(string)
+			if ok && state.IsSensitiveKey(key) {
+				values = append(values, value)
+			}
+		}
+	}
+
+	for _, variable := range env {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[0], "TF_VAR_") {
+			values = append(values, parts[1])
+		}
+	}
+
+	return redactableValues(values)
+}
+
+// Returns the values worth masking, longest first so a secret containing another is masked
+// whole. Interpolations, e.g. ${module.cluster-manager.rancher_secret_key}, aren't secrets.
+// The lines of multi-line values, e.g. private keys, are masked too, since terraform prints
+// them a line at a time.
+func redactableValues(values []string) []string {
+	seen := map[string]bool{}
+	redactable := []string{}
+	add := func(value string) {
+		value = strings.TrimSpace(value)
+		if len(value) < minSensitiveValueLength || seen[value] || strings.HasPrefix(value, "${") {
+			return
+		}
+		seen[value] = true
+		redactable = append(redactable, value)
+	}
+
+	for _, value := range values {
+		add(value)
+		if strings.Contains(value, "\n") {
+			for _, line := range strings.Split(value, "\n") {
+				add(line)
+			}
+		}
+	}
+
+	sort.SliceStable(redactable, func(i, j int) bool {
+		return len(redactable[i]) > len(redactable[j])
+	})
+	return redactable
+}
+
+// Returns s with the given values masked.
+func redact(s string, values []string) string {
+	for _, value := range values {
+		s = strings.Replace(s, value, redactedValue, -1)
+	}
+	return s
+}
+
+// redactingWriter masks secrets in what's written to it. The end of a write that may be the
+// start of a secret is held back until the next write, or Flush.
+type redactingWriter struct {
+	w       io.Writer
+	values  []string
+	pending []byte
+}
+
+func newRedactingWriter(w io.Writer, values []string) *redactingWriter {
+	return &redactingWriter{w: w, values: values}
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	rw.pending = append(rw.pending, p...)
+
+	held := rw.partialSecretLength()
+	out := rw.pending[:len(rw.pending)-held]
+	if len(out) > 0 {
+		_, err := io.WriteString(rw.w, redact(string(out), rw.values))
+		if err != nil {
+			return 0, err
+		}
+	}
+	rw.pending = append([]byte{}, rw.pending[len(rw.pending)-held:]...)
+
+	return len(p), nil
+}
+
+// Flush writes what's held back.
+func (rw *redactingWriter) Flush() error {
+	if len(rw.pending) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(rw.w, redact(string(rw.pending), rw.values))
+	rw.pending = nil
+	return err
+}
+
+// Returns the length of the longest end of the pending output that's the start of a secret.
+func (rw *redactingWriter) partialSecretLength() int {
+	longest := 0
+	for _, value := range rw.values {
+		from := len(rw.pending) - len(value) + 1
+		if from < 0 {
+			from = 0
+		}
+		for i := from; i < len(rw.pending)-longest; i++ {
+			if rw.pending[i] == value[0] && strings.HasPrefix(value, string(rw.pending[i:])) {
+				longest = len(rw.pending) - i
+				break
+			}
+		}
+	}
+	return longest
+}
@@ -0,0 +1,41 @@
+package shell
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/state"
+)
+
+// RunConftestWithState evaluates the terraform config of the given state against the
+// Rego policies in policyPath. Policy violations are printed and returned as an error.
+func RunConftestWithState(conf config.Config, currentState state.State, policyPath string) error {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Save the terraform config to the temporary directory
+	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	// Use temporary directory as working directory
+	shellOptions := ShellOptions{
+		Config:     conf,
+		WorkingDir: tempDir,
+	}
+
+	// Run conftest against every policy namespace, so policies don't need to be in package main
+	err = RunShellCommand(&shellOptions, "conftest", "test", "--all-namespaces", "--policy", policyPath, "main.tf.json")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// RunPackerBuild builds the given packer template and returns the ids of the artifacts it
+// created, e.g. `us-west-2:ami-0def3275` for an AMI. The messages of packer are printed as it
+// builds.
+func RunPackerBuild(conf config.Config, template []byte) ([]string, error) {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// The template holds the credentials of the cloud provider
+	templatePath := filepath.Join(tempDir, "template.json")
+	err = ioutil.WriteFile(templatePath, template, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("packer", "build", "-machine-readable", templatePath)
+	cmd.Dir = tempDir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	artifactIDs := []string{}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		messageType, data := parsePackerMessage(scanner.Text())
+		switch {
+		case messageType == "ui" && len(data) == 2:
+			fmt.Println(data[1])
+		case messageType == "artifact" && len(data) == 3 && data[1] == "id":
+			artifactIDs = append(artifactIDs, data[2])
+		}
+	}
+
+	err = cmd.Wait()
+	if err != nil {
+		return nil, fmt.Errorf("packer build failed: %v", err)
+	}
+	if len(artifactIDs) == 0 {
+		return nil, fmt.Errorf("packer build did not create an image")
+	}
+
+	return artifactIDs, nil
+}
+
+// Returns the type and data of a line of packer's machine readable output,
+// `{timestamp},{target},{type},{data...}`, with the commas and newlines of the data unescaped.
+func parsePackerMessage(line string) (string, []string) {
+	fields := strings.Split(line, ",")
+	if len(fields) < 3 {
+		return "", nil
+	}
+
+	data := fields[3:]
+	for i, value := range data {
+		value = strings.Replace(value, "%!(PACKER_COMMA)", ",", -1)
+		value = strings.Replace(value, `\n`, "\n", -1)
+		value = strings.Replace(value, `\r`, "\r", -1)
+		data[i] = value
+	}
+
+	return fields[2], data
+}
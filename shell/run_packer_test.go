@@ -0,0 +1,26 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePackerMessage(t *testing.T) {
+	tests := []struct {
+		line        string
+		messageType string
+		data        []string
+	}{
+		{"1538040436,,ui,say,==> amazon-ebs: Creating the AMI%!(PACKER_COMMA) this may take a while", "ui", []string{"say", "==> amazon-ebs: Creating the AMI, this may take a while"}},
+		{"1538040436,amazon-ebs,artifact,0,id,us-west-2:ami-0def3275", "artifact", []string{"0", "id", "us-west-2:ami-0def3275"}},
+		{"1538040436,,ui,say,Build 'amazon-ebs' finished.\\n", "ui", []string{"say", "Build 'amazon-ebs' finished.\n"}},
+		{"invalid", "", nil},
+	}
+
+	for _, test := range tests {
+		messageType, data := parsePackerMessage(test.line)
+		if messageType != test.messageType || !reflect.DeepEqual(data, test.data) {
+			t.Errorf("parsePackerMessage(%q), got: %q %q, want: %q %q", test.line, messageType, data, test.messageType, test.data)
+		}
+	}
+}
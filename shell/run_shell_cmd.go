@@ -1,29 +1,144 @@
 package shell
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"sync/atomic"
 )
 
+// TerraformError is returned when terraform exits with an error status. It unwraps to the
+// failure of the command, an *exec.ExitError.
+type TerraformError struct {
+	Err error
+}
+
+func (e *TerraformError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TerraformError) Unwrap() error {
+	return e.Err
+}
+
+// The terraform runs of the process, so the journal can tell the operations that ran terraform
+// from those that didn't
+var terraformRuns int64
+
+// TerraformRuns returns how many times terraform was run by the process.
+func TerraformRuns() int {
+	return int(atomic.LoadInt64(&terraformRuns))
+}
+
+// Counts a run of the command if it's terraform. Checking its version doesn't count.
+func countTerraformRun(command string, args []string) {
+	if command == "terraform" && (len(args) == 0 || args[0] != "version") {
+		atomic.AddInt64(&terraformRuns, 1)
+	}
+}
+
+// Returns the failure of the command as a *TerraformError if the command is terraform and it
+// exited with an error status.
+func commandFailure(command string, err error) error {
+	var exitErr *exec.ExitError
+	if command == "terraform" && errors.As(err, &exitErr) {
+		return &TerraformError{Err: err}
+	}
+	return err
+}
+
 func RunShellCommand(options *ShellOptions, command string, args ...string) error {
-	cmd := exec.Command(command, args...)
+	path, err := commandPath(options, command)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if options != nil {
 		cmd.Dir = options.WorkingDir
+		if len(options.Env) > 0 {
+			cmd.Env = append(os.Environ(), options.Env...)
+		}
+		if len(options.Redact) > 0 {
+			stdout := newRedactingWriter(os.Stdout, options.Redact)
+			stderr := newRedactingWriter(os.Stderr, options.Redact)
+			defer stdout.Flush()
+			defer stderr.Flush()
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+		}
 	}
 
-	err := cmd.Start()
+	err = cmd.Start()
 	if err != nil {
 		return err
 	}
+	countTerraformRun(command, args)
 
 	err = cmd.Wait()
 	if err != nil {
-		return err
+		return commandFailure(command, err)
 	}
 
 	return nil
 }
+
+// The failure of a command run with RunShellCommandWithOutput, its message includes the output
+// of the command. It unwraps to the *exec.ExitError of the command.
+type commandError struct {
+	message string
+	err     error
+}
+
+func (e *commandError) Error() string {
+	return e.message
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// RunShellCommandWithOutput runs the command and returns what it wrote to stdout.
+// Stderr is included in the returned error if the command fails, with the secrets of the
+// options masked. Stdout is returned as is, callers parse it.
+func RunShellCommandWithOutput(options *ShellOptions, command string, args ...string) ([]byte, error) {
+	path, err := commandPath(options, command)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if options != nil {
+		cmd.Dir = options.WorkingDir
+		if len(options.Env) > 0 {
+			cmd.Env = append(os.Environ(), options.Env...)
+		}
+	}
+
+	err = cmd.Start()
+	if err == nil {
+		countTerraformRun(command, args)
+		err = cmd.Wait()
+	}
+	if err != nil {
+		message := fmt.Sprintf("%s %s failed: %v\n%s", command, strings.Join(args, " "), err, stderr.String())
+		if options != nil {
+			message = redact(message, options.Redact)
+		}
+		return nil, commandFailure(command, &commandError{message: message, err: err})
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+package shell
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestTerraformError(t *testing.T) {
+	binDir, err := ioutil.TempDir("", "triton-kubernetes-bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(binDir)
+	err = ioutil.WriteFile(filepath.Join(binDir, "terraform"), []byte("#!/bin/sh\nexit 3\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	options := &ShellOptions{Config: config.New()}
+	runs := TerraformRuns()
+	_, err = RunShellCommandWithOutput(options, "terraform", "init")
+	var terraformErr *TerraformError
+	var exitErr *exec.ExitError
+	if !errors.As(err, &terraformErr) || !errors.As(terraformErr, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("Expected a TerraformError with exit status 3, received %#v", err)
+	}
+	if TerraformRuns() != runs+1 {
+		t.Errorf("Expected the run of terraform to be counted, received %d runs", TerraformRuns()-runs)
+	}
+
+	// Checking the version isn't a run
+	RunShellCommandWithOutput(options, "terraform", "version")
+	if TerraformRuns() != runs+1 {
+		t.Errorf("Expected terraform version not to be counted, received %d runs", TerraformRuns()-runs)
+	}
+
+	// Other commands fail with their own error
+	err = RunShellCommand(options, "sh", "-c", "exit 3")
+	if !errors.As(err, &exitErr) || errors.As(err, &terraformErr) {
+		t.Errorf("Expected an exec.ExitError, received %#v", err)
+	}
+}
@@ -1,80 +1,596 @@
 package shell
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
 
+	"github.com/joyent/triton-kubernetes/config"
 	"github.com/joyent/triton-kubernetes/state"
+	"github.com/joyent/triton-kubernetes/util"
 )
 
-func RunTerraformApplyWithState(state state.State) error {
-	// Create a temporary directory
-	tempDir, err := ioutil.TempDir("", "triton-kubernetes-")
+// ApplyError is returned when terraform apply fails. With workdir_keep, WorkingDir is the working
+// directory that was kept, so the apply can be resumed with the modules and providers it already
+// downloaded. The CLI exits with util.ExitCodeTerraform.
+type ApplyError struct {
+	Err        error
+	WorkingDir string
+}
+
+func (e *ApplyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ApplyError) ExitCode() int {
+	return util.ExitCodeTerraform
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+func RunTerraformApplyWithState(conf config.Config, state state.State, args []string) error {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		return err
+	}
+
+	return runTerraformApply(conf, state, tempDir, cleanup, args)
+}
+
+// ResumeTerraformApplyWithState runs terraform apply again in the working directory kept by a
+// failed apply, or in a new one if none was kept or it's gone, e.g. when resuming from another
+// machine.
+func ResumeTerraformApplyWithState(conf config.Config, state state.State, workingDir string, args []string) error {
+	if workingDir == "" {
+		return RunTerraformApplyWithState(conf, state, args)
+	}
+	if _, err := os.Stat(workingDir); err != nil {
+		return RunTerraformApplyWithState(conf, state, args)
+	}
+
+	return runTerraformApply(conf, state, workingDir, workingDirCleanup(conf, workingDir), args)
+}
+
+// Runs terraform apply in the given working directory, which is cleaned up once it's done.
+func runTerraformApply(conf config.Config, state state.State, workingDir string, cleanup func(), args []string) (err error) {
+	defer cleanup()
+
+	// Save the terraform config to the working directory
+	jsonPath := fmt.Sprintf("%s/%s", workingDir, "main.tf.json")
+	err = ioutil.WriteFile(jsonPath, state.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	env, err := terraformRunEnv(conf, state)
+	if err != nil {
+		return err
+	}
+
+	// Use the working directory
+	shellOptions := ShellOptions{
+		Config:     conf,
+		WorkingDir: workingDir,
+		Env:        env,
+		Redact:     sensitiveValues(state, env),
+	}
+
+	// Run terraform init
+	err = runTerraformInit(&shellOptions)
+	if err != nil {
+		return util.TerraformError(err)
+	}
+
+	// The resources in the state before the apply, to tell those it created if it's interrupted
+	previous, previousErr := terraformStateList(&shellOptions)
+
+	// Ctrl-C reaches terraform too, which stops gracefully, so it's handled once terraform exits
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	// Show the plan first if asked to
+	if planMode(shellOptions.Config) {
+		err = planAndApply(&shellOptions, false, args)
+	} else {
+		// Run terraform apply
+		allArgs := append([]string{"apply", "-auto-approve"}, args...)
+		err = runTerraformWithProgress(&shellOptions, allArgs...)
+	}
+	if err != nil && err != ErrPlanNotApplied && !util.IsInterrupt(err) && len(interrupts) > 0 {
+		err = cleanUpInterruptedApply(&shellOptions, previous, previousErr)
+	}
+	// Nothing was applied when the plan was declined, or its confirmation interrupted
+	if err != nil && err != ErrPlanNotApplied && !util.IsInterrupt(err) {
+		applyErr := &ApplyError{Err: err}
+		if conf.GetBool("workdir_keep") {
+			applyErr.WorkingDir = workingDir
+		}
+		return applyErr
+	}
+
+	return err
+}
+
+func RunTerraformDestroyWithState(conf config.Config, currentState state.State, args []string) error {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tempDir)
+	defer cleanup()
 
 	// Save the terraform config to the temporary directory
 	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
-	err = ioutil.WriteFile(jsonPath, state.Bytes(), 0644)
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	env, err := terraformRunEnv(conf, currentState)
 	if err != nil {
 		return err
 	}
 
 	// Use temporary directory as working directory
 	shellOptions := ShellOptions{
+		Config:     conf,
 		WorkingDir: tempDir,
+		Env:        env,
+		Redact:     sensitiveValues(currentState, env),
 	}
 
 	// Run terraform init
-	err = RunShellCommand(&shellOptions, "terraform", "init", "-force-copy")
+	err = runTerraformInit(&shellOptions)
 	if err != nil {
+		return util.TerraformError(err)
+	}
+
+	// Show the plan first if asked to
+	if planMode(shellOptions.Config) {
+		err = planAndApply(&shellOptions, true, args)
+		if err != nil && err != ErrPlanNotApplied && !util.IsInterrupt(err) {
+			return util.TerraformError(err)
+		}
 		return err
 	}
 
-	// Run terraform apply
-	err = RunShellCommand(&shellOptions, "terraform", "apply", "-auto-approve")
+	// Run terraform destroy
+	allArgs := append([]string{"destroy", "-force"}, args...)
+	err = runTerraformWithProgress(&shellOptions, allArgs...)
 	if err != nil {
-		return err
+		return util.TerraformError(err)
 	}
 
 	return nil
 }
 
-func RunTerraformDestroyWithState(currentState state.State, args []string) error {
-	// Create a temporary directory
-	tempDir, err := ioutil.TempDir("", "triton-kubernetes-")
+// PlanTerraformDestroyWithState returns the resources that RunTerraformDestroyWithState would
+// destroy with the same arguments, and the summary line of the plan. Nothing is changed.
+func PlanTerraformDestroyWithState(conf config.Config, currentState state.State, args []string) ([]PlanChange, string, error) {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
-	defer os.RemoveAll(tempDir)
+	defer cleanup()
 
 	// Save the terraform config to the temporary directory
 	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
-	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0644)
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+
+	env, err := terraformRunEnv(conf, currentState)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Use temporary directory as working directory
+	shellOptions := ShellOptions{
+		Config:     conf,
+		WorkingDir: tempDir,
+		Env:        env,
+		Redact:     sensitiveValues(currentState, env),
+	}
+
+	// Run terraform init
+	err = runTerraformInit(&shellOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Run terraform plan -destroy
+	allArgs := append([]string{"plan", "-destroy", "-input=false", "-no-color"}, args...)
+	output, err := RunShellCommandWithOutput(&shellOptions, "terraform", allArgs...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	changes, summary := parsePlan(string(output))
+	return changes, summary, nil
+}
+
+// RunTerraformOutputWithState returns the outputs of the given module, keyed by output name.
+func RunTerraformOutputWithState(conf config.Config, currentState state.State, moduleName string) (map[string]interface{}, error) {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Save the terraform config to the temporary directory
+	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := terraformEnv(conf, currentState)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use temporary directory as working directory
+	shellOptions := ShellOptions{
+		Config:     conf,
+		WorkingDir: tempDir,
+		Env:        env,
+		Redact:     sensitiveValues(currentState, env),
+	}
+
+	// Run terraform init
+	_, err = RunShellCommandWithOutput(&shellOptions, "terraform", "init", "-force-copy", "-input=false")
+	if err != nil {
+		return nil, err
+	}
+
+	// Run terraform output
+	output, err := RunShellCommandWithOutput(&shellOptions, "terraform", "output", "-json", "-module", moduleName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outputs are formatted as {"name": {"sensitive": false, "type": "string", "value": "..."}}
+	rawOutputs := map[string]struct {
+		Value interface{} `json:"value"`
+	}{}
+	err = json.Unmarshal(output, &rawOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]interface{}{}
+	for name, rawOutput := range rawOutputs {
+		outputs[name] = rawOutput.Value
+	}
+
+	return outputs, nil
+}
+
+// RunTerraformStatePullWithState returns the raw terraform state stored in the backend of the given state.
+func RunTerraformStatePullWithState(conf config.Config, currentState state.State) ([]byte, error) {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Save the terraform config to the temporary directory
+	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := terraformEnv(conf, currentState)
+	if err != nil {
+		return nil, err
 	}
 
 	// Use temporary directory as working directory
 	shellOptions := ShellOptions{
+		Config:     conf,
 		WorkingDir: tempDir,
+		Env:        env,
+		Redact:     sensitiveValues(currentState, env),
 	}
 
 	// Run terraform init
-	err = RunShellCommand(&shellOptions, "terraform", "init", "-force-copy")
+	_, err = RunShellCommandWithOutput(&shellOptions, "terraform", "init", "-force-copy", "-input=false")
+	if err != nil {
+		return nil, err
+	}
+
+	// Run terraform state pull
+	return RunShellCommandWithOutput(&shellOptions, "terraform", "state", "pull")
+}
+
+// RunTerraformStateRmWithState removes resources from the terraform state of the given state,
+// without destroying them. Terraform stops managing them.
+func RunTerraformStateRmWithState(conf config.Config, currentState state.State, addresses []string) error {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
 	if err != nil {
 		return err
 	}
+	defer cleanup()
 
-	// Run terraform destroy
-	allArgs := append([]string{"destroy", "-force"}, args...)
-	err = RunShellCommand(&shellOptions, "terraform", allArgs...)
+	// Save the terraform config to the working directory
+	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	env, err := terraformEnv(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	shellOptions := ShellOptions{
+		Config:     conf,
+		WorkingDir: tempDir,
+		Env:        env,
+		Redact:     sensitiveValues(currentState, env),
+	}
+
+	// Run terraform init
+	err = runTerraformInit(&shellOptions)
+	if err != nil {
+		return err
+	}
+
+	// Run terraform state rm
+	return RunShellCommand(&shellOptions, "terraform", append([]string{"state", "rm"}, addresses...)...)
+}
+
+// RunTerraformStatePushWithState overwrites the terraform state of the given state with rawState,
+// a terraform state as returned by RunTerraformStatePullWithState, e.g. to restore a cluster
+// manager in another backend.
+func RunTerraformStatePushWithState(conf config.Config, currentState state.State, rawState []byte) error {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Save the terraform config and the state to push to the working directory
+	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	statePath := fmt.Sprintf("%s/%s", tempDir, "pushed.tfstate")
+	err = ioutil.WriteFile(statePath, rawState, 0600)
+	if err != nil {
+		return err
+	}
+
+	env, err := terraformEnv(conf, currentState)
+	if err != nil {
+		return err
+	}
+
+	shellOptions := ShellOptions{
+		Config:     conf,
+		WorkingDir: tempDir,
+		Env:        env,
+		Redact:     sensitiveValues(currentState, env),
+	}
+
+	// Run terraform init
+	err = runTerraformInit(&shellOptions)
+	if err != nil {
+		return err
+	}
+
+	// Run terraform state push
+	return RunShellCommand(&shellOptions, "terraform", "state", "push", statePath)
+}
+
+// RunTerraformRefreshWithState refreshes the terraform state of the given state against the real
+// infrastructure, which updates the outputs stored in the backend. It returns the raw terraform
+// state from before and after the refresh, with the secrets of the state masked. No
+// infrastructure is changed.
+func RunTerraformRefreshWithState(conf config.Config, currentState state.State) ([]byte, []byte, error) {
+	// Create a working directory
+	tempDir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+
+	// Save the terraform config to the working directory
+	jsonPath := fmt.Sprintf("%s/%s", tempDir, "main.tf.json")
+	err = ioutil.WriteFile(jsonPath, currentState.Bytes(), 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env, err := terraformRunEnv(conf, currentState)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shellOptions := ShellOptions{
+		Config:     conf,
+		WorkingDir: tempDir,
+		Env:        env,
+		Redact:     sensitiveValues(currentState, env),
+	}
+
+	// Run terraform init
+	err = runTerraformInit(&shellOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before, err := RunShellCommandWithOutput(&shellOptions, "terraform", "state", "pull")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Run terraform refresh
+	err = RunShellCommand(&shellOptions, "terraform", "refresh", "-input=false")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	after, err := RunShellCommandWithOutput(&shellOptions, "terraform", "state", "pull")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The states hold the secrets of the config, e.g. in the user data of instances
+	before = []byte(redact(string(before), shellOptions.Redact))
+	after = []byte(redact(string(after), shellOptions.Redact))
+
+	return before, after, nil
+}
+
+// Returns the environment variables of the root variables whose values are stored encrypted in the
+// state, the Rancher API token and k3s token of the cluster manager and the secrets encryption
+// configs of clusters, of the temporary credentials of the IAM roles AWS modules assume, which are
+// never stored, the azurerm provider's opt-in to its newer VM resources, and the environment
+// variables of the terraform backend, e.g. its credentials.
+func terraformEnv(conf config.Config, currentState state.State) ([]string, error) {
+	env := []string{}
+
+	accessKey, secretKey, err := RancherAPIKeys(conf, currentState)
+	if err != nil {
+		return nil, err
+	}
+	if accessKey != "" {
+		env = append(env,
+			"TF_VAR_rancher_access_key="+accessKey,
+			"TF_VAR_rancher_secret_key="+secretKey,
+		)
+	}
+
+	for clusterKey, encryptedConfig := range currentState.SecretsEncryptionConfigs() {
+		secretsEncryptionConfig, err := util.DecryptSecret(conf, encryptedConfig)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decrypt the secrets encryption config of cluster '%s': %s", clusterKey, err)
+		}
+		env = append(env, fmt.Sprintf("TF_VAR_k8s_secrets_encryption_config_%s=%s", clusterKey, secretsEncryptionConfig))
+	}
+
+	if encryptedToken := currentState.ManagerHAToken(); encryptedToken != "" {
+		token, err := util.DecryptSecret(conf, encryptedToken)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decrypt the k3s token of the cluster manager: %s", err)
+		}
+		env = append(env, "TF_VAR_manager_ha_token="+token)
+	}
+
+	// The role is assumed again on every run, its credentials expire after an hour
+	for moduleKey := range currentState.AWSRoleKeys() {
+		creds, err := assumeModuleAWSRole(conf, currentState, moduleKey)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env,
+			fmt.Sprintf("TF_VAR_aws_access_key_%s=%s", moduleKey, creds.AccessKey),
+			fmt.Sprintf("TF_VAR_aws_secret_key_%s=%s", moduleKey, creds.SecretKey),
+			fmt.Sprintf("TF_VAR_aws_session_token_%s=%s", moduleKey, creds.SessionToken),
+		)
+	}
+
+	// Spot VMs of Azure nodes are created with the VM and scale set resources of the 2.0 azurerm
+	// provider, which 1.x providers only register when asked to. They're needed as long as the
+	// terraform state has such resources, also to destroy them.
+	env = append(env, "ARM_PROVIDER_TWOPOINTZERO_RESOURCES=true")
+
+	env = append(env, currentState.TerraformBackendEnv()...)
+
+	return env, nil
+}
+
+// Returns the environment of a terraform plan or apply, once the backend of the state was given
+// it, e.g. for remote runs in Terraform Cloud, which don't get the environment terraform is run with.
+func terraformRunEnv(conf config.Config, currentState state.State) ([]string, error) {
+	env, err := terraformEnv(conf, currentState)
+	if err != nil {
+		return nil, err
+	}
+
+	err = currentState.PrepareRun(env)
+	if err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// RancherAPIKeys returns the access and secret keys of the Rancher API token stored encrypted in
+// the state, or empty keys if the token was never rotated and is a terraform output of the
+// cluster manager.
+func RancherAPIKeys(conf config.Config, currentState state.State) (string, string, error) {
+	encryptedToken := currentState.RancherAPIToken()
+	if encryptedToken == "" {
+		return "", "", nil
+	}
+
+	token, err := util.DecryptSecret(conf, encryptedToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Rancher API tokens are {access key}:{secret key}
+	parts := strings.SplitN(token, ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("Invalid Rancher API token in the state of cluster manager '%s'", currentState.Name)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// AWSCredentials returns the AWS credentials of a module as they're stored, or the temporary
+// credentials of the IAM role they reference, see state.SetAWSRoleKeys.
+func AWSCredentials(conf config.Config, currentState state.State, accessKey, secretKey, sessionToken string) (util.AWSCredentials, error) {
+	for moduleKey := range currentState.AWSRoleKeys() {
+		if accessKey == fmt.Sprintf("${var.aws_access_key_%s}", moduleKey) {
+			return assumeModuleAWSRole(conf, currentState, moduleKey)
+		}
+	}
+
+	return util.AWSCredentials{AccessKey: accessKey, SecretKey: secretKey, SessionToken: sessionToken}, nil
+}
+
+// Assumes the IAM role of the given module with the keys stored for it.
+func assumeModuleAWSRole(conf config.Config, currentState state.State, moduleKey string) (util.AWSCredentials, error) {
+	keys := currentState.AWSRoleKeys()[moduleKey]
+	secretKey, err := util.DecryptSecret(conf, keys.SecretKey)
+	if err != nil {
+		return util.AWSCredentials{}, fmt.Errorf("Unable to decrypt the AWS secret key of module '%s': %s", moduleKey, err)
+	}
+
+	get := func(key string) string {
+		return currentState.Get(fmt.Sprintf("module.%s.%s", moduleKey, key))
+	}
+	role := util.AWSRole{
+		ARN:         get("aws_role_arn"),
+		ExternalID:  get("aws_external_id"),
+		SessionName: get("aws_role_session_name"),
+		MFASerial:   get("aws_mfa_serial"),
+	}
+
+	creds, err := util.AssumeAWSRole(conf, keys.AccessKey, secretKey, role)
+	if err != nil {
+		return util.AWSCredentials{}, fmt.Errorf("Unable to assume AWS role '%s' of module '%s': %s", role.ARN, moduleKey, err)
+	}
+
+	return creds, nil
 }
@@ -1,5 +1,23 @@
 package shell
 
+import (
+	"github.com/joyent/triton-kubernetes/config"
+)
+
 type ShellOptions struct {
+	// Settings of the operation running the command, e.g. plan_only or terraform_version
+	Config     config.Config
 	WorkingDir string
+	// Environment variables set in addition to the environment of this process
+	Env []string
+	// Secrets masked in the output of the command and in its errors
+	Redact []string
+}
+
+// Returns the settings of the options, an empty Config when there are none.
+func (options *ShellOptions) config() config.Config {
+	if options == nil || options.Config == nil {
+		return config.New()
+	}
+	return options.Config
 }
@@ -0,0 +1,166 @@
+package shell
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/util"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Pinned terraform versions are downloaded to terraformBinDirectory, which the local backend
+// doesn't list as a state.
+const terraformBinDirectory = "~/.triton-kubernetes/bin"
+
+// HashiCorp's release server, a variable so tests can serve releases
+var terraformReleasesURL = "https://releases.hashicorp.com/terraform"
+
+var terraformVersionSettingRegexp = regexp.MustCompile(`^\d+\.\d+\.\d+(-[a-z0-9]+)?$`)
+
+// Serializes installs, so concurrent operations don't download the same version twice
+var terraformInstallMutex sync.Mutex
+
+// Returns the terraform binary to run for conf: the version pinned by terraform_version,
+// downloaded to terraformBinDirectory the first time it's used, or terraform from the PATH.
+func terraformBinary(conf config.Config) (string, error) {
+	version := conf.GetString("terraform_version")
+	if version == "" {
+		return "terraform", nil
+	}
+
+	// The release server is trusted for nothing, the archive must match a checksum the user
+	// got from HashiCorp
+	checksum := conf.GetString("terraform_sha256")
+	if checksum == "" {
+		return "", util.ConfigError(errors.New("terraform_sha256 must be specified"))
+	}
+
+	dir, err := homedir.Expand(terraformBinDirectory)
+	if err != nil {
+		return "", err
+	}
+
+	terraformInstallMutex.Lock()
+	defer terraformInstallMutex.Unlock()
+	return installTerraform(dir, version, checksum)
+}
+
+// Returns the binary to run for the command, which is the pinned terraform binary of the
+// options for terraform.
+func commandPath(options *ShellOptions, command string) (string, error) {
+	if command != "terraform" {
+		return command, nil
+	}
+	return terraformBinary(options.config())
+}
+
+// Downloads terraform version to dir, unless it's already there, and returns its path. The
+// release archive is verified against checksum, a SHA-256 hex digest.
+func installTerraform(dir, version, checksum string) (string, error) {
+	if !terraformVersionSettingRegexp.MatchString(version) {
+		return "", fmt.Errorf("terraform_version must be a terraform version, e.g. 0.11.14. Found '%s'.", version)
+	}
+
+	binaryName := "terraform"
+	binaryPath := filepath.Join(dir, fmt.Sprintf("terraform_%s", version))
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+		binaryPath += ".exe"
+	}
+
+	// Binaries are only written once verified
+	if _, err := os.Stat(binaryPath); err == nil {
+		return binaryPath, nil
+	}
+
+	archiveName := fmt.Sprintf("terraform_%s_%s_%s.zip", version, runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(os.Stderr, "Downloading terraform %s to %s\n", version, dir)
+	archive, err := download(fmt.Sprintf("%s/%s/%s", terraformReleasesURL, version, archiveName))
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(archive)
+	actual := hex.EncodeToString(digest[:])
+	if !strings.EqualFold(actual, strings.TrimSpace(checksum)) {
+		return "", fmt.Errorf("The SHA-256 checksum of %s is %s, expected %s. The download is corrupted or was tampered with.", archiveName, actual, checksum)
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return "", err
+	}
+	for _, file := range zipReader.File {
+		if file.Name != binaryName {
+			continue
+		}
+
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return "", err
+		}
+		err = extractFile(file, binaryPath)
+		if err != nil {
+			return "", err
+		}
+		return binaryPath, nil
+	}
+
+	return "", fmt.Errorf("%s has no %s binary.", archiveName, binaryName)
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to download %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Extracts the file to path through a temporary file, so an interrupted extraction doesn't
+// leave a partial binary behind.
+func extractFile(file *zip.File, path string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := path + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	closeErr := dst.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
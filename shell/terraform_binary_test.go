@@ -0,0 +1,135 @@
+package shell
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+// Serves a terraform release whose binary is a shell script, returns the server and the
+// checksum of the release archive.
+func newTerraformReleaseServer(t *testing.T, version string) (*httptest.Server, string) {
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	binaryName := "terraform"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	w, err := zipWriter.Create(binaryName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(w, "#!/bin/sh\necho Terraform v"+version+"\n")
+	zipWriter.Close()
+
+	digest := sha256.Sum256(archive.Bytes())
+	checksum := hex.EncodeToString(digest[:])
+	archiveName := fmt.Sprintf("terraform_%s_%s_%s.zip", version, runtime.GOOS, runtime.GOARCH)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/%s/%s", version, archiveName):
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, checksum
+}
+
+func TestInstallTerraform(t *testing.T) {
+	server, checksum := newTerraformReleaseServer(t, "0.11.14")
+	defer server.Close()
+	originalURL := terraformReleasesURL
+	terraformReleasesURL = server.URL
+	defer func() { terraformReleasesURL = originalURL }()
+
+	dir, err := ioutil.TempDir("", "triton-kubernetes-bin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := installTerraform(filepath.Join(dir, "bin"), "0.11.14", checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "Terraform v0.11.14") {
+		t.Errorf("Unexpected binary %s", content)
+	}
+
+	// The installed binary is used without downloading it again
+	server.Close()
+	reinstalledPath, err := installTerraform(filepath.Join(dir, "bin"), "0.11.14", checksum)
+	if err != nil || reinstalledPath != path {
+		t.Errorf("Expected %s to be reused, received %s, %v", path, reinstalledPath, err)
+	}
+}
+
+func TestInstallTerraformChecksumMismatch(t *testing.T) {
+	server, checksum := newTerraformReleaseServer(t, "0.11.14")
+	defer server.Close()
+	originalURL := terraformReleasesURL
+	terraformReleasesURL = server.URL
+	defer func() { terraformReleasesURL = originalURL }()
+
+	dir, err := ioutil.TempDir("", "triton-kubernetes-bin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wrongChecksum := strings.Repeat("0", len(checksum))
+	_, err = installTerraform(dir, "0.11.14", wrongChecksum)
+	if err == nil || !strings.Contains(err.Error(), "expected "+wrongChecksum) {
+		t.Errorf("Expected a checksum error, received %v", err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("Expected nothing to be written, found %d files", len(files))
+	}
+}
+
+func TestInstallTerraformInvalidVersion(t *testing.T) {
+	_, err := installTerraform("/nonexistent", "latest", "0000")
+
+	expected := "terraform_version must be a terraform version, e.g. 0.11.14. Found 'latest'."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected '%s', received %v", expected, err)
+	}
+}
+
+func TestTerraformBinary(t *testing.T) {
+	conf := config.New()
+
+	path, err := terraformBinary(conf)
+	if err != nil || path != "terraform" {
+		t.Errorf("Expected terraform from the PATH, received %s, %v", path, err)
+	}
+
+	// Settings are read from the Config of each run
+	conf.Set("terraform_version", "0.11.14")
+	_, err = terraformBinary(conf)
+	expected := "terraform_sha256 must be specified"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected '%s', received %v", expected, err)
+	}
+}
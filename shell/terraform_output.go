@@ -0,0 +1,232 @@
+package shell
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// Terraform prints a line per change of a resource, e.g.
+	// "module.node_triton_dev_dev-w-1.triton_machine.host: Creation complete after 1m2s (ID: 1234)"
+	// or "module.node_triton_dev_dev-w-1.triton_machine.host: Still creating... (10s elapsed)"
+	terraformResourceRegexp = regexp.MustCompile(`^(\S+): (Creating|Creation complete|Modifying|Modifications complete|Destroying|Destruction complete|Still creating|Still modifying|Still destroying)(?:\.\.\.)?(?: after (\S+))?(?: \((\S+) elapsed\))?`)
+	terraformSummaryRegexp  = regexp.MustCompile(`^(Apply|Destroy) complete! .*`)
+	terraformVersionRegexp  = regexp.MustCompile(`Terraform v(\d+)\.(\d+)\.(\d+)`)
+)
+
+var terraformResourceActions = map[string]string{
+	"Creating":               "creating",
+	"Creation complete":      "created",
+	"Modifying":              "updating",
+	"Modifications complete": "updated",
+	"Destroying":             "destroying",
+	"Destruction complete":   "destroyed",
+	"Still creating":         "progress",
+	"Still modifying":        "progress",
+	"Still destroying":       "progress",
+}
+
+// Actions of the hooks of terraform's JSON output, by whether the change is complete
+var terraformJSONActions = map[bool]map[string]string{
+	false: {"create": "creating", "update": "updating", "delete": "destroying", "replace": "replacing", "read": "reading"},
+	true:  {"create": "created", "update": "updated", "delete": "destroyed", "replace": "replaced", "read": "read"},
+}
+
+// terraformMessage is a line of terraform's JSON output.
+type terraformMessage struct {
+	Level   string `json:"@level"`
+	Message string `json:"@message"`
+	Type    string `json:"type"`
+	Hook    struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+		Action         string `json:"action"`
+		ElapsedSeconds int    `json:"elapsed_seconds"`
+	} `json:"hook"`
+	Diagnostic struct {
+		Summary string `json:"summary"`
+		Detail  string `json:"detail"`
+	} `json:"diagnostic"`
+}
+
+var (
+	terraformJSONMutex     sync.Mutex
+	terraformJSONSupported = map[string]bool{}
+)
+
+// Returns whether the terraform binary at path streams its apply as JSON, since terraform 0.15.3.
+// The answer is cached per binary.
+func terraformSupportsJSON(options *ShellOptions, path string) bool {
+	terraformJSONMutex.Lock()
+	defer terraformJSONMutex.Unlock()
+
+	supported, ok := terraformJSONSupported[path]
+	if ok {
+		return supported
+	}
+
+	output, err := RunShellCommandWithOutput(&ShellOptions{Config: options.config()}, "terraform", "version")
+	supported = err == nil && isJSONTerraformVersion(string(output))
+	terraformJSONSupported[path] = supported
+	return supported
+}
+
+func isJSONTerraformVersion(output string) bool {
+	match := terraformVersionRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return false
+	}
+
+	version := [3]int{}
+	for i := range version {
+		version[i], _ = strconv.Atoi(match[i+1])
+	}
+	minimum := [3]int{0, 15, 3}
+	for i := range version {
+		if version[i] != minimum[i] {
+			return version[i] > minimum[i]
+		}
+	}
+	return true
+}
+
+// Runs terraform init, only printing its output at LogVerbose or when it fails.
+func runTerraformInit(options *ShellOptions) error {
+	if logLevel(options.config()) == LogVerbose {
+		return RunShellCommand(options, "terraform", "init", "-force-copy")
+	}
+
+	_, err := RunShellCommandWithOutput(options, "terraform", "init", "-force-copy", "-input=false", "-no-color")
+	return err
+}
+
+// Runs a terraform apply or destroy, printing a progress line per resource instead of its
+// output, unless the log level is LogVerbose. The output is parsed from JSON when terraform
+// supports it.
+func runTerraformWithProgress(options *ShellOptions, args ...string) error {
+	level := logLevel(options.config())
+	if level == LogVerbose {
+		return RunShellCommand(options, "terraform", args...)
+	}
+
+	// The flags go right after the subcommand, terraform stops parsing flags at a plan file.
+	// Terraform can't prompt for input, its output isn't printed.
+	path, err := commandPath(options, "terraform")
+	if err != nil {
+		return err
+	}
+
+	outputFlag := "-no-color"
+	useJSON := terraformSupportsJSON(options, path)
+	if useJSON {
+		outputFlag = "-json"
+	}
+	args = append([]string{args[0], outputFlag, "-input=false"}, args[1:]...)
+
+	logger := NewLogger(level)
+
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = os.Stderr
+	if options != nil {
+		cmd.Dir = options.WorkingDir
+		if len(options.Env) > 0 {
+			cmd.Env = append(os.Environ(), options.Env...)
+		}
+		if len(options.Redact) > 0 {
+			stderr := newRedactingWriter(os.Stderr, options.Redact)
+			defer stderr.Flush()
+			cmd.Stderr = stderr
+			// The logger writes whole lines
+			logger.out = newRedactingWriter(logger.out, options.Redact)
+			logger.err = newRedactingWriter(logger.err, options.Redact)
+		}
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	countTerraformRun("terraform", args)
+
+	logTerraformOutput(logger, stdout, useJSON)
+
+	return commandFailure("terraform", cmd.Wait())
+}
+
+// Logs each line of terraform's output.
+func logTerraformOutput(logger *Logger, output io.Reader, isJSON bool) {
+	scanner := bufio.NewScanner(output)
+	// Diagnostics may be long
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if isJSON {
+			logTerraformJSONLine(logger, scanner.Text())
+		} else {
+			logTerraformLine(logger, scanner.Text())
+		}
+	}
+}
+
+func logTerraformLine(logger *Logger, line string) {
+	if match := terraformResourceRegexp.FindStringSubmatch(line); match != nil {
+		elapsed := match[3]
+		if elapsed == "" {
+			elapsed = match[4]
+		}
+		logger.Resource(ResourceEvent{Action: terraformResourceActions[match[2]], Address: match[1], Elapsed: elapsed})
+	} else if summary := terraformSummaryRegexp.FindString(line); summary != "" {
+		logger.Summary(summary)
+	}
+}
+
+func logTerraformJSONLine(logger *Logger, line string) {
+	message := terraformMessage{}
+	err := json.Unmarshal([]byte(line), &message)
+	if err != nil {
+		// Not every line is JSON, e.g. when terraform crashes
+		logger.Error(line)
+		return
+	}
+
+	elapsed := ""
+	if message.Hook.ElapsedSeconds > 0 {
+		elapsed = (time.Duration(message.Hook.ElapsedSeconds) * time.Second).String()
+	}
+	address := message.Hook.Resource.Addr
+
+	switch message.Type {
+	case "apply_start":
+		logger.Resource(ResourceEvent{Action: terraformJSONActions[false][message.Hook.Action], Address: address})
+	case "apply_progress":
+		logger.Resource(ResourceEvent{Action: "progress", Address: address, Elapsed: elapsed})
+	case "apply_complete":
+		logger.Resource(ResourceEvent{Action: terraformJSONActions[true][message.Hook.Action], Address: address, Elapsed: elapsed})
+	case "apply_errored":
+		logger.Resource(ResourceEvent{Action: "failed", Address: address, Elapsed: elapsed})
+	case "change_summary":
+		logger.Summary(message.Message)
+	case "diagnostic":
+		if message.Level != "error" {
+			return
+		}
+		if message.Diagnostic.Detail != "" {
+			logger.Error(fmt.Sprintf("Error: %s\n%s", message.Diagnostic.Summary, message.Diagnostic.Detail))
+		} else {
+			logger.Error(fmt.Sprintf("Error: %s", message.Diagnostic.Summary))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+
+	"github.com/spf13/viper"
+)
+
+func newTestLogger(level LogLevel) (*Logger, *bytes.Buffer, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	return &Logger{level: level, out: out, err: errOut}, out, errOut
+}
+
+func TestLogTerraformOutput(t *testing.T) {
+	output := `module.node_triton_dev_dev-w-1.triton_machine.host: Creating...
+  name:                 "" => "dev-w-1"
+module.node_triton_dev_dev-w-1.triton_machine.host: Still creating... (10s elapsed)
+module.node_triton_dev_dev-w-1.triton_machine.host: Still creating... (1m0s elapsed)
+module.node_triton_dev_dev-w-1.triton_machine.host: Creation complete after 1m2s (ID: 1234)
+module.node_triton_dev_dev-w-2.triton_machine.host: Destroying... [id=5678]
+module.node_triton_dev_dev-w-2.triton_machine.host: Destruction complete after 5s
+
+Apply complete! Resources: 1 added, 0 changed, 1 destroyed.
+`
+	logger, out, _ := newTestLogger(LogNormal)
+	logTerraformOutput(logger, strings.NewReader(output), false)
+
+	expected := `  creating   module.node_triton_dev_dev-w-1.triton_machine.host
+  progress   module.node_triton_dev_dev-w-1.triton_machine.host (1m0s)
+  created    module.node_triton_dev_dev-w-1.triton_machine.host (1m2s)
+  destroying module.node_triton_dev_dev-w-2.triton_machine.host
+  destroyed  module.node_triton_dev_dev-w-2.triton_machine.host (5s)
+Apply complete! Resources: 1 added, 0 changed, 1 destroyed.
+`
+	if out.String() != expected {
+		t.Errorf("output, got:\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
+
+func TestLogTerraformJSONOutput(t *testing.T) {
+	output := `{"@level":"info","@message":"Terraform 1.0.0","type":"version"}
+{"@level":"info","@message":"module.node_triton_dev_dev-w-1.triton_machine.host: Creating...","type":"apply_start","hook":{"resource":{"addr":"module.node_triton_dev_dev-w-1.triton_machine.host"},"action":"create"}}
+{"@level":"info","@message":"module.node_triton_dev_dev-w-1.triton_machine.host: Still creating... [1m0s elapsed]","type":"apply_progress","hook":{"resource":{"addr":"module.node_triton_dev_dev-w-1.triton_machine.host"},"action":"create","elapsed_seconds":60}}
+{"@level":"error","@message":"module.node_triton_dev_dev-w-1.triton_machine.host: Creation errored after 1m5s","type":"apply_errored","hook":{"resource":{"addr":"module.node_triton_dev_dev-w-1.triton_machine.host"},"action":"create","elapsed_seconds":65}}
+{"@level":"error","@message":"Error: quota exceeded","type":"diagnostic","diagnostic":{"severity":"error","summary":"quota exceeded","detail":""}}
+{"@level":"warn","@message":"Warning: deprecated","type":"diagnostic","diagnostic":{"severity":"warning","summary":"deprecated","detail":""}}
+`
+	logger, out, errOut := newTestLogger(LogQuiet)
+	logTerraformOutput(logger, strings.NewReader(output), true)
+
+	expected := "  failed     module.node_triton_dev_dev-w-1.triton_machine.host (1m5s)\n"
+	if out.String() != expected {
+		t.Errorf("output, got:\n%s\nwant:\n%s", out.String(), expected)
+	}
+	if errOut.String() != "Error: quota exceeded\n" {
+		t.Errorf("errors, got:\n%s", errOut.String())
+	}
+}
+
+func TestIsJSONTerraformVersion(t *testing.T) {
+	tests := map[string]bool{
+		"Terraform v0.11.2":                          false,
+		"Terraform v0.15.2\non linux_amd64":          false,
+		"Terraform v0.15.3":                          true,
+		"Terraform v1.0.0\n\nYour version is recent": true,
+		"command not found":                          false,
+	}
+
+	for output, expected := range tests {
+		if isJSONTerraformVersion(output) != expected {
+			t.Errorf("isJSONTerraformVersion(%q), want: %v", output, expected)
+		}
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	// Settings of the global viper don't apply to operations with their own Config
+	viper.Set("verbose", true)
+	defer viper.Reset()
+
+	conf := config.New()
+	if logLevel(conf) != LogNormal {
+		t.Error("Expected LogNormal without quiet, verbose or log_level")
+	}
+
+	conf.Set("log_level", "quiet")
+	if logLevel(conf) != LogQuiet {
+		t.Error("Expected LogQuiet with log_level quiet")
+	}
+}
@@ -0,0 +1,50 @@
+package shell
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/joyent/triton-kubernetes/config"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// NewWorkingDir creates a working directory for terraform, and returns it along with the function
+// that removes it. Working directories are created under workdir_root, e.g. on a larger or
+// encrypted volume, and default to the system temporary directory. They contain the terraform
+// configuration and its credentials, so they're only readable by the user and removed unless
+// workdir_keep is set, e.g. for debugging.
+func NewWorkingDir(conf config.Config) (string, func(), error) {
+	root := ""
+	if conf.IsSet("workdir_root") {
+		expandedRoot, err := homedir.Expand(conf.GetString("workdir_root"))
+		if err != nil {
+			return "", nil, err
+		}
+
+		err = os.MkdirAll(expandedRoot, 0700)
+		if err != nil {
+			return "", nil, fmt.Errorf("Unable to create workdir_root '%s': %v", expandedRoot, err)
+		}
+		root = expandedRoot
+	}
+
+	dir, err := ioutil.TempDir(root, "triton-kubernetes-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	return dir, workingDirCleanup(conf, dir), nil
+}
+
+// Returns the function that removes the given working directory, unless workdir_keep is set.
+func workingDirCleanup(conf config.Config, dir string) func() {
+	return func() {
+		if conf.GetBool("workdir_keep") {
+			fmt.Printf("Kept working directory %s\n", dir)
+			return
+		}
+		os.RemoveAll(dir)
+	}
+}
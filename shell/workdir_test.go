@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/config"
+)
+
+func TestNewWorkingDir(t *testing.T) {
+	conf := config.New()
+	root, err := ioutil.TempDir("", "triton-kubernetes-workdir-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	conf.Set("workdir_root", filepath.Join(root, "nested"))
+
+	dir, cleanup, err := NewWorkingDir(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(dir) != filepath.Join(root, "nested") {
+		t.Errorf("Expected the working directory to be created under workdir_root, got %s", dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected the working directory to be only accessible by the user, got %v, %v", info, err)
+	}
+
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the working directory to be removed, got %v", err)
+	}
+
+	conf.Set("workdir_keep", true)
+	dir, cleanup, err = NewWorkingDir(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("Expected the working directory to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Serializer renders a state as a terraform configuration file.
+type Serializer interface {
+	// Filename is the name terraform expects the rendered configuration to have
+	Filename() string
+	Serialize(state *State) ([]byte, error)
+}
+
+// NewSerializer returns the serializer for the given format, which is json or hcl.
+// Terraform is always run against the json format, hcl is meant for exporting a
+// human editable configuration.
+func NewSerializer(format string) (Serializer, error) {
+	switch format {
+	case "", "json":
+		return jsonSerializer{}, nil
+	case "hcl":
+		return hclSerializer{}, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported state format '%s', must be json or hcl", format)
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Filename() string {
+	return "main.tf.json"
+}
+
+func (jsonSerializer) Serialize(state *State) ([]byte, error) {
+	return state.Bytes(), nil
+}
+
+type hclSerializer struct{}
+
+func (hclSerializer) Filename() string {
+	return "main.tf"
+}
+
+// Number of block labels of each top level key of the terraform JSON syntax.
+// Keys not listed here are rendered as attributes.
+var hclBlockLabels = map[string]int{
+	"data":      2,
+	"locals":    0,
+	"module":    1,
+	"output":    1,
+	"provider":  1,
+	"resource":  2,
+	"terraform": 0,
+	"variable":  1,
+}
+
+var hclIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+func (hclSerializer) Serialize(state *State) ([]byte, error) {
+	// Values may have been set as structs, decode them from their JSON representation
+	decoder := json.NewDecoder(bytes.NewReader(state.Bytes()))
+	decoder.UseNumber()
+
+	var root map[string]interface{}
+	err := decoder.Decode(&root)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for i, key := range sortedKeys(root) {
+		labels, ok := hclBlockLabels[key]
+		if !ok {
+			writeHCLAttribute(&buf, 0, key, root[key])
+			continue
+		}
+
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+
+		body, ok := root[key].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Could not render '%s' as HCL, it is not an object", key)
+		}
+
+		err = writeHCLBlocks(&buf, key, nil, labels, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeHCLBlocks writes one block for each object nested labels deep in body.
+func writeHCLBlocks(buf *bytes.Buffer, blockType string, labels []string, remaining int, body map[string]interface{}) error {
+	if remaining > 0 {
+		for i, key := range sortedKeys(body) {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+
+			child, ok := body[key].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("Could not render %s '%s' as HCL, it is not an object", blockType, key)
+			}
+
+			err := writeHCLBlocks(buf, blockType, append(labels, key), remaining-1, child)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	buf.WriteString(blockType)
+	for _, label := range labels {
+		buf.WriteString(" ")
+		buf.WriteString(hclString(label))
+	}
+	buf.WriteString(" {\n")
+
+	for _, key := range sortedKeys(body) {
+		// The backend of the terraform block is a block itself
+		backends, ok := body[key].(map[string]interface{})
+		if blockType == "terraform" && key == "backend" && ok {
+			for _, backendType := range sortedKeys(backends) {
+				backend, ok := backends[backendType].(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("Could not render backend '%s' as HCL, it is not an object", backendType)
+				}
+
+				buf.WriteString("  backend " + hclString(backendType) + " {\n")
+				for _, backendKey := range sortedKeys(backend) {
+					writeHCLAttribute(buf, 2, backendKey, backend[backendKey])
+				}
+				buf.WriteString("  }\n")
+			}
+			continue
+		}
+
+		writeHCLAttribute(buf, 1, key, body[key])
+	}
+
+	buf.WriteString("}\n")
+	return nil
+}
+
+func writeHCLAttribute(buf *bytes.Buffer, depth int, key string, value interface{}) {
+	buf.WriteString(strings.Repeat("  ", depth))
+	buf.WriteString(hclKey(key))
+	buf.WriteString(" = ")
+	writeHCLValue(buf, depth, value)
+	buf.WriteString("\n")
+}
+
+func writeHCLValue(buf *bytes.Buffer, depth int, value interface{}) {
+	indent := strings.Repeat("  ", depth)
+
+	switch value := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		fmt.Fprintf(buf, "%t", value)
+	case json.Number:
+		buf.WriteString(value.String())
+	case string:
+		buf.WriteString(hclString(value))
+	case []interface{}:
+		if len(value) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+
+		buf.WriteString("[\n")
+		for _, item := range value {
+			buf.WriteString(indent + "  ")
+			writeHCLValue(buf, depth+1, item)
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(indent + "]")
+	case map[string]interface{}:
+		if len(value) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+
+		buf.WriteString("{\n")
+		for _, key := range sortedKeys(value) {
+			writeHCLAttribute(buf, depth+1, key, value[key])
+		}
+		buf.WriteString(indent + "}")
+	}
+}
+
+func hclKey(key string) string {
+	if hclIdentifier.MatchString(key) {
+		return key
+	}
+
+	return hclString(key)
+}
+
+// hclString quotes a string. Interpolations like ${var.name} are kept as is, since
+// terraform reads JSON strings as templates too.
+func hclString(value string) string {
+	var buf bytes.Buffer
+	buf.WriteString(`"`)
+	for _, r := range value {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteString(`"`)
+
+	return buf.String()
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
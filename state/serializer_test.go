@@ -0,0 +1,90 @@
+package state
+
+import (
+	"testing"
+)
+
+func TestNewSerializer(t *testing.T) {
+	serializer, err := NewSerializer("json")
+	if err != nil {
+		t.Error(err)
+	}
+	if serializer.Filename() != "main.tf.json" {
+		t.Errorf("filename, got: %s, want: %s.", serializer.Filename(), "main.tf.json")
+	}
+
+	serializer, err = NewSerializer("hcl")
+	if err != nil {
+		t.Error(err)
+	}
+	if serializer.Filename() != "main.tf" {
+		t.Errorf("filename, got: %s, want: %s.", serializer.Filename(), "main.tf")
+	}
+
+	_, err = NewSerializer("yaml")
+	expected := "Unsupported state format 'yaml', must be json or hcl"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestHCLSerialize(t *testing.T) {
+	stateObj, err := New("HCLState", []byte(`{
+		"terraform": {"backend": {"local": {"path": "/tmp/terraform.tfstate"}}},
+		"module": {
+			"cluster-manager": {"source": "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher", "name": "dev", "master_triton_machine_count": 1},
+			"cluster_triton_dev": {"name": "dev", "triton_key_path": "${var.key}", "triton_network_names": ["Joyent-SDC-Public"], "rancher_host_labels": {"control": "true"}}
+		},
+		"locals": {"triton_kubernetes_created_at": {"cluster-manager": "2018-01-01T00:00:00Z"}}
+	}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	serializer, _ := NewSerializer("hcl")
+	raw, err := serializer.Serialize(&stateObj)
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := `locals {
+  triton_kubernetes_created_at = {
+    cluster-manager = "2018-01-01T00:00:00Z"
+  }
+}
+
+module "cluster-manager" {
+  master_triton_machine_count = 1
+  name = "dev"
+  source = "github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher"
+}
+
+module "cluster_triton_dev" {
+  name = "dev"
+  rancher_host_labels = {
+    control = "true"
+  }
+  triton_key_path = "${var.key}"
+  triton_network_names = [
+    "Joyent-SDC-Public",
+  ]
+}
+
+terraform {
+  backend "local" {
+    path = "/tmp/terraform.tfstate"
+  }
+}
+`
+	if string(raw) != expected {
+		t.Errorf("hcl output, got:\n%s\nwant:\n%s", string(raw), expected)
+	}
+}
+
+func TestHCLString(t *testing.T) {
+	quoted := hclString("line \"one\"\nC:\\path")
+	expected := `"line \"one\"\nC:\\path"`
+	if quoted != expected {
+		t.Errorf("quoted string, got: %s, want: %s.", quoted, expected)
+	}
+}
@@ -1,15 +1,26 @@
 package state
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Jeffail/gabs"
 )
 
+// Parts of the names of module variables whose values are secret, e.g. rancher_secret_key,
+// azure_client_secret or openstack_password.
+var sensitiveKeyParts = []string{"password", "secret", "token", "api_key", "private_key"}
+
 type State struct {
 	Name       string
 	configJSON *gabs.Container
+
+	// Set by the backend the state is read from, never persisted
+	terraformBackendEnv []string
+	prepareRun          func(env []string) error
 }
 
 func New(name string, raw []byte) (State, error) {
@@ -33,16 +44,284 @@ func (state *State) Get(path string) string {
 	return value
 }
 
+// GetInt returns the number at the given path, or 0 if there is none.
+func (state *State) GetInt(path string) int {
+	switch value := state.configJSON.Path(path).Data().(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	}
+
+	return 0
+}
+
+// Set sets the value at the given path.
+func (state *State) Set(path string, value interface{}) error {
+	_, err := state.configJSON.SetP(value, path)
+	return err
+}
+
+// GetMap returns the object at the given path, or nil if there is none.
+func (state *State) GetMap(path string) map[string]interface{} {
+	value, ok := state.configJSON.Path(path).Data().(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	return value
+}
+
+// Creation timestamps are stored at path `locals.triton_kubernetes_created_at.{moduleKey}`.
+// Terraform rejects unknown root level keys, unused locals are ignored. Modules are set again
+// whenever they change, the timestamp is only set the first time.
+func (state *State) setCreatedAt(moduleKey string) error {
+	if state.CreatedAt(moduleKey) != "" {
+		return nil
+	}
+
+	_, err := state.configJSON.Set(time.Now().UTC().Format(time.RFC3339), "locals", "triton_kubernetes_created_at", moduleKey)
+	return err
+}
+
+// CreatedAt returns when the given module was added to the state, or an empty string if unknown.
+func (state *State) CreatedAt(moduleKey string) string {
+	value, ok := state.configJSON.Search("locals", "triton_kubernetes_created_at", moduleKey).Data().(string)
+	if !ok {
+		return ""
+	}
+
+	return value
+}
+
+// Nodes whose terraform apply failed are marked at path `locals.triton_kubernetes_failed_nodes.{nodeKey}`,
+// so they can be retried.
+func (state *State) SetNodeFailed(nodeKey string, failed bool) error {
+	if !failed {
+		// The node may never have been marked
+		state.configJSON.Delete("locals", "triton_kubernetes_failed_nodes", nodeKey)
+		return nil
+	}
+
+	_, err := state.configJSON.Set(true, "locals", "triton_kubernetes_failed_nodes", nodeKey)
+	return err
+}
+
+// Returns map of node name to node key for the nodes of a cluster that are marked as failed
+func (state *State) FailedNodes(clusterKey string) (map[string]string, error) {
+	nodes, err := state.Nodes(clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{}
+	for name, key := range nodes {
+		failed, ok := state.configJSON.Search("locals", "triton_kubernetes_failed_nodes", key).Data().(bool)
+		if ok && failed {
+			result[name] = key
+		}
+	}
+
+	return result, nil
+}
+
+// The Rancher API token of the cluster manager is stored encrypted at path
+// `locals.triton_kubernetes_rancher_api_token`. The decrypted token is kept out of the terraform
+// state: the modules calling the Rancher API get empty rancher_access_key and rancher_secret_key
+// variables, and read it from the TF_VAR_rancher_access_key and TF_VAR_rancher_secret_key
+// environment variables terraform runs with.
+func (state *State) SetRancherAPIToken(encryptedToken string) error {
+	_, err := state.configJSON.Set(encryptedToken, "locals", "triton_kubernetes_rancher_api_token")
+	if err != nil {
+		return err
+	}
+
+	children, err := state.configJSON.S("module").ChildrenMap()
+	if err != nil {
+		return err
+	}
+
+	// The modules added before the token was rotated use the token created with the cluster manager
+	for moduleKey, child := range children {
+		for _, key := range []string{"rancher_access_key", "rancher_secret_key"} {
+			if child.Path(key).Data() != fmt.Sprintf("${module.cluster-manager.%s}", key) {
+				continue
+			}
+
+			_, err = state.configJSON.Set("", "module", moduleKey, key)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RancherAPIKeyReferences returns the rancher_access_key and rancher_secret_key variables of a new
+// module calling the Rancher API: the token created with the cluster manager, or empty once the
+// token is rotated, see SetRancherAPIToken.
+func (state *State) RancherAPIKeyReferences() (string, string) {
+	if state.RancherAPIToken() != "" {
+		return "", ""
+	}
+
+	return "${module.cluster-manager.rancher_access_key}", "${module.cluster-manager.rancher_secret_key}"
+}
+
+// RancherAPIToken returns the encrypted Rancher API token, or an empty string if the state
+// doesn't have one.
+func (state *State) RancherAPIToken() string {
+	value, ok := state.configJSON.Search("locals", "triton_kubernetes_rancher_api_token").Data().(string)
+	if !ok {
+		return ""
+	}
+
+	return value
+}
+
+// The secrets encryption config of a cluster's API server is stored encrypted at path
+// `locals.triton_kubernetes_secrets_encryption_configs.{clusterKey}`, the plaintext config is
+// removed from the cluster module. The control nodes of the cluster read the decrypted config from
+// the root k8s_secrets_encryption_config_{clusterKey} variable, which is set when terraform runs.
+func (state *State) SetSecretsEncryptionConfig(clusterKey, encryptedConfig string) error {
+	_, err := state.configJSON.Set(encryptedConfig, "locals", "triton_kubernetes_secrets_encryption_configs", clusterKey)
+	if err != nil {
+		return err
+	}
+
+	key := "k8s_secrets_encryption_config_" + clusterKey
+	variable := map[string]interface{}{"description": "Decrypted from locals.triton_kubernetes_secrets_encryption_configs." + clusterKey}
+	_, err = state.configJSON.Set(variable, "variable", key)
+	if err != nil {
+		return err
+	}
+
+	// The config is only known to the nodes, it never goes through a module output
+	state.configJSON.Delete("module", clusterKey, "k8s_secrets_encryption_config")
+	return nil
+}
+
+// Returns map of cluster key to encrypted secrets encryption config for the clusters that encrypt
+// secrets at rest
+func (state *State) SecretsEncryptionConfigs() map[string]string {
+	result := map[string]string{}
+
+	children, err := state.configJSON.Search("locals", "triton_kubernetes_secrets_encryption_configs").ChildrenMap()
+	if err != nil {
+		// No cluster encrypts secrets
+		return result
+	}
+
+	for clusterKey, child := range children {
+		if value, ok := child.Data().(string); ok {
+			result[clusterKey] = value
+		}
+	}
+
+	return result
+}
+
+// The secret the hosts of an HA cluster manager join its k3s cluster with is stored encrypted at
+// path `locals.triton_kubernetes_manager_ha_token`, the plaintext token is replaced in the cluster
+// manager's module by a reference to the root manager_ha_token variable, which is set when
+// terraform runs.
+func (state *State) SetManagerHAToken(encryptedToken string) error {
+	_, err := state.configJSON.Set(encryptedToken, "locals", "triton_kubernetes_manager_ha_token")
+	if err != nil {
+		return err
+	}
+
+	variable := map[string]interface{}{"description": "Decrypted from locals.triton_kubernetes_manager_ha_token"}
+	_, err = state.configJSON.Set(variable, "variable", "manager_ha_token")
+	if err != nil {
+		return err
+	}
+
+	_, err = state.configJSON.Set("${var.manager_ha_token}", "module", "cluster-manager", "manager_ha_token")
+	return err
+}
+
+// ManagerHAToken returns the encrypted k3s token of an HA cluster manager, or an empty string if
+// the cluster manager has a single host.
+func (state *State) ManagerHAToken() string {
+	value, ok := state.configJSON.Search("locals", "triton_kubernetes_manager_ha_token").Data().(string)
+	if !ok {
+		return ""
+	}
+
+	return value
+}
+
+// AWSRoleKeys are the keys of the user that assumes the IAM role of an AWS module.
+type AWSRoleKeys struct {
+	AccessKey string
+	SecretKey string
+}
+
+// The keys of the user assuming the IAM role of an AWS module are stored at path
+// `locals.triton_kubernetes_aws_role_keys.{moduleKey}`, the secret key encrypted. The role's
+// temporary credentials expire, so they're never stored: the module's aws_access_key,
+// aws_secret_key and aws_session_token reference the root aws_*_{moduleKey} variables, which are
+// set when terraform runs. Modules using the same credentials copy the references.
+func (state *State) SetAWSRoleKeys(moduleKey, accessKey, encryptedSecretKey string) error {
+	keys := map[string]interface{}{"access_key": accessKey, "secret_key": encryptedSecretKey}
+	_, err := state.configJSON.Set(keys, "locals", "triton_kubernetes_aws_role_keys", moduleKey)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{"aws_access_key", "aws_secret_key", "aws_session_token"} {
+		variable := map[string]interface{}{"description": "Assumed with locals.triton_kubernetes_aws_role_keys." + moduleKey}
+		_, err = state.configJSON.Set(variable, "variable", key+"_"+moduleKey)
+		if err != nil {
+			return err
+		}
+
+		_, err = state.configJSON.Set(fmt.Sprintf("${var.%s_%s}", key, moduleKey), "module", moduleKey, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns map of module key to the keys assuming the module's IAM role, for the AWS modules
+// that assume one
+func (state *State) AWSRoleKeys() map[string]AWSRoleKeys {
+	result := map[string]AWSRoleKeys{}
+
+	children, err := state.configJSON.Search("locals", "triton_kubernetes_aws_role_keys").ChildrenMap()
+	if err != nil {
+		// No module assumes a role
+		return result
+	}
+
+	for moduleKey, child := range children {
+		accessKey, _ := child.Path("access_key").Data().(string)
+		secretKey, _ := child.Path("secret_key").Data().(string)
+		result[moduleKey] = AWSRoleKeys{AccessKey: accessKey, SecretKey: secretKey}
+	}
+
+	return result
+}
+
 func (state *State) SetManager(obj interface{}) error {
 	_, err := state.configJSON.SetP(obj, "module.cluster-manager")
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return state.setCreatedAt("cluster-manager")
 }
 
+// SetTerraformBackendConfig sets the terraform backend block, replacing the previous one even
+// if it's of another type, as terraform only accepts one.
 func (state *State) SetTerraformBackendConfig(tfBackendPath string, tfBackendObj interface{}) error {
+	// The state may not have a backend block yet
+	state.configJSON.Delete("terraform", "backend")
+
 	_, err := state.configJSON.SetP(tfBackendObj, tfBackendPath)
 	if err != nil {
 		return err
@@ -51,14 +330,62 @@ func (state *State) SetTerraformBackendConfig(tfBackendPath string, tfBackendObj
 	return nil
 }
 
+// SetTerraformRun sets what terraform needs at run time from the backend keeping the state: the
+// environment variables its terraform backend reads, e.g. credentials, and a function given the
+// environment of every run before it starts, e.g. for backends running terraform remotely.
+// Neither is persisted.
+func (state *State) SetTerraformRun(env []string, prepareRun func(env []string) error) {
+	state.terraformBackendEnv = env
+	state.prepareRun = prepareRun
+}
+
+// TerraformBackendEnv returns the KEY=value environment variables of the terraform backend of the
+// state, or nil if it reads none.
+func (state *State) TerraformBackendEnv() []string {
+	return state.terraformBackendEnv
+}
+
+// PrepareRun passes env, the environment of a terraform run of the state, to the backend keeping
+// the state before the run starts.
+func (state *State) PrepareRun(env []string) error {
+	if state.prepareRun == nil {
+		return nil
+	}
+
+	return state.prepareRun(env)
+}
+
+// Reload parses the config of the state again, keeping what was set by SetTerraformRun.
+func (state *State) Reload() error {
+	config, err := gabs.ParseJSON(state.Bytes())
+	if err != nil {
+		return err
+	}
+
+	state.configJSON = config
+	return nil
+}
+
 // Clusters are stored at path `module.cluster_{provider}_{clusterName}`
 func (state *State) AddCluster(provider, name string, obj interface{}) error {
-	_, err := state.configJSON.SetP(obj, fmt.Sprintf("module.cluster_%s_%s", provider, name))
+	// Store the cluster as JSON values, so its settings can be read with Get while its nodes
+	// are added
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	err = json.Unmarshal(raw, &value)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = state.configJSON.SetP(value, fmt.Sprintf("module.cluster_%s_%s", provider, name))
+	if err != nil {
+		return err
+	}
+
+	return state.setCreatedAt(fmt.Sprintf("cluster_%s_%s", provider, name))
 }
 
 // Nodes are stored at path `module.node_{provider}_{clusterName}_{nodeName}`
@@ -73,18 +400,376 @@ func (state *State) AddNode(clusterKey, name string, obj interface{}) error {
 		return err
 	}
 
-	return nil
+	return state.setCreatedAt(fmt.Sprintf("node_%s_%s_%s", provider, clusterName, name))
+}
+
+// Addons are stored at path `module.addon_{provider}_{clusterName}_{addonName}`
+func (state *State) AddAddon(clusterKey, name string, obj interface{}) error {
+	provider, clusterName, err := getClusterKeyParts(clusterKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = state.configJSON.SetP(obj, fmt.Sprintf("module.addon_%s_%s_%s", provider, clusterName, name))
+	if err != nil {
+		return err
+	}
+
+	return state.setCreatedAt(fmt.Sprintf("addon_%s_%s_%s", provider, clusterName, name))
+}
+
+// Monthly budgets of clusters are stored at path `locals.triton_kubernetes_monthly_budget.{clusterKey}`.
+func (state *State) SetMonthlyBudget(clusterKey string, budget float64) error {
+	_, err := state.configJSON.Set(budget, "locals", "triton_kubernetes_monthly_budget", clusterKey)
+	return err
+}
+
+// MonthlyBudget returns the monthly budget of the cluster, and false if it has none.
+func (state *State) MonthlyBudget(clusterKey string) (float64, bool) {
+	value, ok := state.configJSON.Search("locals", "triton_kubernetes_monthly_budget", clusterKey).Data().(float64)
+	return value, ok
+}
+
+// NodePool is a group of nodes of a cluster created together, whose hostnames are the pool
+// name followed by a number e.g. dev-w-1. Count is the number of nodes the pool should have.
+type NodePool struct {
+	Count int `json:"count"`
+}
+
+// Node pools are stored at path `locals.triton_kubernetes_node_pools.{clusterKey}.{poolName}`.
+// Node pools backed by an instance group are a single node module and aren't stored there.
+func (state *State) SetNodePool(clusterKey, name string, pool NodePool) error {
+	value := map[string]interface{}{"count": pool.Count}
+	_, err := state.configJSON.Set(value, "locals", "triton_kubernetes_node_pools", clusterKey, name)
+	return err
+}
+
+// DeleteNodePool removes the node pool from the state, its nodes are left alone.
+func (state *State) DeleteNodePool(clusterKey, name string) {
+	// The pool may never have been stored
+	state.configJSON.Delete("locals", "triton_kubernetes_node_pools", clusterKey, name)
+}
+
+// Returns map of pool name to node pool for the node pools of a cluster
+func (state *State) NodePools(clusterKey string) (map[string]NodePool, error) {
+	result := map[string]NodePool{}
+
+	children, err := state.configJSON.Search("locals", "triton_kubernetes_node_pools", clusterKey).ChildrenMap()
+	if err != nil {
+		// The cluster has no node pools
+		return result, nil
+	}
+
+	for name, child := range children {
+		pool := NodePool{}
+		err = json.Unmarshal(child.Bytes(), &pool)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid node pool '%s': %s", name, err)
+		}
+		result[name] = pool
+	}
+
+	return result, nil
+}
+
+// Image is a node image built for a cluster, in the format of `upgrade nodes --image`, e.g.
+// {name}@{version} on Triton or an AMI id on AWS.
+type Image struct {
+	Image   string `json:"image"`
+	BuiltAt string `json:"built_at"`
+}
+
+// Images are stored at path `locals.triton_kubernetes_images.{clusterKey}.{imageName}`, an image
+// built again under the same name replaces the previous one.
+func (state *State) SetImage(clusterKey, name string, image Image) error {
+	value := map[string]interface{}{"image": image.Image, "built_at": image.BuiltAt}
+	_, err := state.configJSON.Set(value, "locals", "triton_kubernetes_images", clusterKey, name)
+	return err
+}
+
+// Returns map of image name to image for the images built for a cluster
+func (state *State) Images(clusterKey string) (map[string]Image, error) {
+	result := map[string]Image{}
+
+	children, err := state.configJSON.Search("locals", "triton_kubernetes_images", clusterKey).ChildrenMap()
+	if err != nil {
+		// No image was built for the cluster
+		return result, nil
+	}
+
+	for name, child := range children {
+		image := Image{}
+		err = json.Unmarshal(child.Bytes(), &image)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid image '%s': %s", name, err)
+		}
+		result[name] = image
+	}
+
+	return result, nil
+}
+
+// SSHKey is an SSH key pair generated for a cluster. TritonKeyName is the name it's registered
+// under with the Triton account, AWS clusters register it as their key pair.
+type SSHKey struct {
+	PrivateKeyPath string `json:"private_key_path"`
+	PublicKeyPath  string `json:"public_key_path"`
+	Fingerprint    string `json:"fingerprint"`
+	TritonKeyName  string `json:"triton_key_name,omitempty"`
+}
+
+// SSH keys are stored at path `locals.triton_kubernetes_ssh_keys.{clusterKey}`.
+func (state *State) SetSSHKey(clusterKey string, key SSHKey) error {
+	value := map[string]interface{}{
+		"private_key_path": key.PrivateKeyPath,
+		"public_key_path":  key.PublicKeyPath,
+		"fingerprint":      key.Fingerprint,
+	}
+	if key.TritonKeyName != "" {
+		value["triton_key_name"] = key.TritonKeyName
+	}
+
+	_, err := state.configJSON.Set(value, "locals", "triton_kubernetes_ssh_keys", clusterKey)
+	return err
+}
+
+// SSHKey returns the SSH key pair generated for the cluster, and false if it has none.
+func (state *State) SSHKey(clusterKey string) (SSHKey, bool) {
+	key := SSHKey{}
+	container := state.configJSON.Search("locals", "triton_kubernetes_ssh_keys", clusterKey)
+	if container.Data() == nil {
+		return key, false
+	}
+
+	err := json.Unmarshal(container.Bytes(), &key)
+	if err != nil {
+		return key, false
+	}
+
+	return key, true
+}
+
+// Checkpoint is a terraform apply that failed. The configuration it applied is persisted along
+// with it, so the resources terraform did create aren't orphaned, and the apply can be resumed.
+type Checkpoint struct {
+	Operation  string   `json:"operation"`
+	Error      string   `json:"error"`
+	FailedAt   string   `json:"failed_at"`
+	Args       []string `json:"args,omitempty"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+}
+
+// The checkpoint of the last failed apply is stored at path `locals.triton_kubernetes_checkpoint`.
+func (state *State) SetCheckpoint(checkpoint Checkpoint) error {
+	value := map[string]interface{}{
+		"operation": checkpoint.Operation,
+		"error":     checkpoint.Error,
+		"failed_at": checkpoint.FailedAt,
+	}
+	if len(checkpoint.Args) > 0 {
+		value["args"] = checkpoint.Args
+	}
+	if checkpoint.WorkingDir != "" {
+		value["working_dir"] = checkpoint.WorkingDir
+	}
+
+	_, err := state.configJSON.Set(value, "locals", "triton_kubernetes_checkpoint")
+	return err
+}
+
+// Checkpoint returns the checkpoint of the last failed apply, and false if there's none.
+func (state *State) Checkpoint() (Checkpoint, bool) {
+	checkpoint := Checkpoint{}
+	container := state.configJSON.Search("locals", "triton_kubernetes_checkpoint")
+	if container.Data() == nil {
+		return checkpoint, false
+	}
+
+	err := json.Unmarshal(container.Bytes(), &checkpoint)
+	if err != nil {
+		return checkpoint, false
+	}
+
+	return checkpoint, true
+}
+
+// ClearCheckpoint removes the checkpoint, once an apply of the whole configuration succeeded.
+func (state *State) ClearCheckpoint() {
+	// There may be no checkpoint
+	state.configJSON.Delete("locals", "triton_kubernetes_checkpoint")
+}
+
+// The operation journal is stored at path `locals.triton_kubernetes_events`, oldest event first.
+func (state *State) SetEvents(events []interface{}) error {
+	_, err := state.configJSON.Set(events, "locals", "triton_kubernetes_events")
+	return err
+}
+
+// Events returns the events of the operation journal, oldest first.
+func (state *State) Events() []interface{} {
+	events, ok := state.configJSON.Search("locals", "triton_kubernetes_events").Data().([]interface{})
+	if !ok {
+		return []interface{}{}
+	}
+
+	return events
+}
+
+// MergeModuleVariables sets the variables of the module, at path `module.{moduleKey}`, that
+// aren't set yet. The names of the variables that were already set, and kept, are returned
+// sorted. The module source can't be changed.
+func (state *State) MergeModuleVariables(moduleKey string, variables map[string]interface{}) ([]string, error) {
+	module, err := state.moduleVariables(moduleKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := []string{}
+	for name, value := range variables {
+		if name == "source" {
+			kept = append(kept, name)
+			continue
+		}
+
+		if current, ok := module[name]; ok && current != nil && current != "" {
+			kept = append(kept, name)
+			continue
+		}
+		module[name] = value
+	}
+
+	_, err = state.configJSON.Set(module, "module", moduleKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(kept)
+	return kept, nil
+}
+
+// SetModuleVariables sets the variables of the module, at path `module.{moduleKey}`, replacing
+// the values they have. The module source can't be changed.
+func (state *State) SetModuleVariables(moduleKey string, variables map[string]interface{}) error {
+	module, err := state.moduleVariables(moduleKey)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range variables {
+		if name == "source" {
+			return fmt.Errorf("The source of module '%s' can't be changed.", moduleKey)
+		}
+		module[name] = value
+	}
+
+	_, err = state.configJSON.Set(module, "module", moduleKey)
+	return err
+}
+
+// ModuleSource returns the source of the module at path `module.{moduleKey}`.
+func (state *State) ModuleSource(moduleKey string) (string, error) {
+	module, err := state.moduleVariables(moduleKey)
+	if err != nil {
+		return "", err
+	}
+
+	source, _ := module["source"].(string)
+	return source, nil
+}
+
+// Modules may have been added as structs, their variables are read as JSON values
+func (state *State) moduleVariables(moduleKey string) (map[string]interface{}, error) {
+	obj := state.configJSON.Search("module", moduleKey).Data()
+	if obj == nil {
+		return nil, fmt.Errorf("Module '%s' does not exist.", moduleKey)
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	module := map[string]interface{}{}
+	err = json.Unmarshal(raw, &module)
+	if err != nil {
+		return nil, err
+	}
+
+	return module, nil
 }
 
+// Delete removes the given path. Deleting a module also removes its creation timestamp, failed
+// mark, budget, node pools, images, SSH key, secrets encryption config and the keys
+// assuming its IAM role.
 func (state *State) Delete(path string) error {
 	err := state.configJSON.DeleteP(path)
 	if err != nil {
 		return err
 	}
 
+	if strings.HasPrefix(path, "module.") {
+		// The module may predate creation timestamps
+		state.configJSON.Delete("locals", "triton_kubernetes_created_at", strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("locals", "triton_kubernetes_failed_nodes", strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("locals", "triton_kubernetes_monthly_budget", strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("locals", "triton_kubernetes_node_pools", strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("locals", "triton_kubernetes_images", strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("locals", "triton_kubernetes_ssh_keys", strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("locals", "triton_kubernetes_secrets_encryption_configs", strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("variable", "k8s_secrets_encryption_config_"+strings.TrimPrefix(path, "module."))
+		state.configJSON.Delete("locals", "triton_kubernetes_aws_role_keys", strings.TrimPrefix(path, "module."))
+		for _, key := range []string{"aws_access_key", "aws_secret_key", "aws_session_token"} {
+			state.configJSON.Delete("variable", key+"_"+strings.TrimPrefix(path, "module."))
+		}
+	}
+
 	return nil
 }
 
+// WithoutSecrets returns a copy of the state without the values of the module variables and
+// terraform backend settings that are secret.
+func (state *State) WithoutSecrets() (State, error) {
+	copied, err := New(state.Name, state.Bytes())
+	if err != nil {
+		return State{}, err
+	}
+
+	// Module keys may contain dots, so the paths are given as hierarchies
+	sections := [][]string{}
+	for key := range copied.GetMap("module") {
+		sections = append(sections, []string{"module", key})
+	}
+	for key := range copied.GetMap("terraform.backend") {
+		sections = append(sections, []string{"terraform", "backend", key})
+	}
+	for _, path := range sections {
+		variables, ok := copied.configJSON.Search(path...).Data().(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range variables {
+			if IsSensitiveKey(key) {
+				copied.configJSON.Delete(append(path, key)...)
+			}
+		}
+	}
+
+	return copied, nil
+}
+
+// IsSensitiveKey returns whether a module variable holds a secret. Paths to files holding
+// secrets, e.g. triton_key_path, don't.
+func IsSensitiveKey(key string) bool {
+	if strings.HasSuffix(key, "_path") {
+		return false
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
 func (state *State) Bytes() []byte {
 	return state.configJSON.BytesIndent("", "\t")
 }
@@ -146,6 +831,50 @@ func (state *State) Nodes(clusterKey string) (map[string]string, error) {
 	return result, nil
 }
 
+// Returns map of node name to node key for the nodes of a cluster with the given role,
+// which is etcd, control or worker.
+func (state *State) NodesWithRole(clusterKey, role string) (map[string]string, error) {
+	nodes, err := state.Nodes(clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{}
+	for name, key := range nodes {
+		if state.Get(fmt.Sprintf("module.%s.rancher_host_labels.%s", key, role)) == "true" {
+			result[name] = key
+		}
+	}
+
+	return result, nil
+}
+
+// Returns map of addon name to addon key for all addons in a cluster
+// Addons are stored at path `module.addon_{provider}_{clusterName}_{addonName}`
+func (state *State) Addons(clusterKey string) (map[string]string, error) {
+	result := map[string]string{}
+
+	provider, name, err := getClusterKeyParts(clusterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	addonPrefix := fmt.Sprintf("addon_%s_%s_", provider, name)
+
+	children, err := state.configJSON.S("module").ChildrenMap()
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range children {
+		if strings.Index(key, addonPrefix) == 0 {
+			result[key[len(addonPrefix):]] = key
+		}
+	}
+
+	return result, nil
+}
+
 func getClusterKeyParts(clusterKey string) (provider, name string, err error) {
 	parts := strings.Split(clusterKey, "_")
 	if len(parts) < 3 {
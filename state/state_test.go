@@ -1,6 +1,7 @@
 package state
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -17,6 +18,28 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetInt(t *testing.T) {
+	stateObj, err := New("GetState", []byte(`{"module":{"node_aws_dev_w":{"aws_asg_desired_capacity":3}}}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	capacity := stateObj.GetInt("module.node_aws_dev_w.aws_asg_desired_capacity")
+	if capacity != 3 {
+		t.Errorf("value in state object, got: %d, want: %d.", capacity, 3)
+	}
+
+	err = stateObj.Set("module.node_aws_dev_w.aws_asg_desired_capacity", 5)
+	if err != nil {
+		t.Error(err)
+	}
+
+	capacity = stateObj.GetInt("module.node_aws_dev_w.aws_asg_desired_capacity")
+	if capacity != 5 {
+		t.Errorf("value in state object, got: %d, want: %d.", capacity, 5)
+	}
+}
+
 func TestSetManager(t *testing.T) {
 	stateObj, err := New("AddState", []byte(`{}`))
 	if err != nil {
@@ -69,6 +92,26 @@ func TestAddCluster(t *testing.T) {
 	}
 }
 
+func TestAddClusterStruct(t *testing.T) {
+	stateObj, err := New("AddState", []byte(`{}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	cluster := struct {
+		Field string `json:"field"`
+	}{"test"}
+	err = stateObj.AddCluster("aws", "name", &cluster)
+	if err != nil {
+		t.Error(err)
+	}
+
+	value := stateObj.Get("module.cluster_aws_name.field")
+	if value != "test" {
+		t.Errorf("value in state object, got: %s, want: %s", value, "test")
+	}
+}
+
 func TestAddNode(t *testing.T) {
 	stateObj, err := New("AddState", []byte(`{}`))
 	if err != nil {
@@ -86,7 +129,111 @@ func TestAddNode(t *testing.T) {
 	}
 }
 
+func TestAddAddon(t *testing.T) {
+	stateObj, err := New("AddState", []byte(`{}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = stateObj.AddAddon("cluster_aws_cluster-name", "cert-manager", map[string]interface{}{"field": "test"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	notEmptyPath := stateObj.Get("module.addon_aws_cluster-name_cert-manager.field")
+	if notEmptyPath != "test" {
+		t.Errorf("value in state object, got: %s, want: %s", notEmptyPath, "test")
+	}
+}
+
 // Delete test
+func TestMergeModuleVariables(t *testing.T) {
+	stateObj, err := New("MergeModuleVariablesState", []byte(`{}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = stateObj.AddNode("cluster_triton_cluster-name", "w-1", struct {
+		Source   string `json:"source"`
+		Hostname string `json:"hostname"`
+		Package  string `json:"triton_machine_package"`
+	}{"./modules/triton-rancher-k8s-host", "w-1", ""})
+	if err != nil {
+		t.Error(err)
+	}
+
+	kept, err := stateObj.MergeModuleVariables("node_triton_cluster-name_w-1", map[string]interface{}{
+		"source":                 "./other",
+		"hostname":               "other",
+		"triton_machine_package": "k4-highcpu-kvm-1.75G",
+		"triton_tags":            map[string]interface{}{"env": "prod"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(kept) != 2 || kept[0] != "hostname" || kept[1] != "source" {
+		t.Errorf("kept variables, got: %v, want: hostname and source", kept)
+	}
+	if value := stateObj.Get("module.node_triton_cluster-name_w-1.hostname"); value != "w-1" {
+		t.Errorf("value in state object, got: %s, want: w-1", value)
+	}
+	if value := stateObj.Get("module.node_triton_cluster-name_w-1.triton_machine_package"); value != "k4-highcpu-kvm-1.75G" {
+		t.Errorf("value in state object, got: %s, want: k4-highcpu-kvm-1.75G", value)
+	}
+	if value := stateObj.Get("module.node_triton_cluster-name_w-1.triton_tags.env"); value != "prod" {
+		t.Errorf("value in state object, got: %s, want: prod", value)
+	}
+
+	_, err = stateObj.MergeModuleVariables("node_triton_cluster-name_w-2", map[string]interface{}{})
+	if err == nil {
+		t.Error("Expected an error for a module that does not exist")
+	}
+}
+
+func TestSetModuleVariables(t *testing.T) {
+	stateObj, err := New("SetModuleVariablesState", []byte(`{}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = stateObj.AddNode("cluster_aws_cluster-name", "w-1", struct {
+		Source    string `json:"source"`
+		AccessKey string `json:"aws_access_key"`
+	}{"./modules/aws-rancher-k8s-host", "ASIAOLD"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = stateObj.SetModuleVariables("node_aws_cluster-name_w-1", map[string]interface{}{
+		"aws_access_key":    "ASIANEW",
+		"aws_session_token": "token",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if value := stateObj.Get("module.node_aws_cluster-name_w-1.aws_access_key"); value != "ASIANEW" {
+		t.Errorf("value in state object, got: %s, want: ASIANEW", value)
+	}
+	if value := stateObj.Get("module.node_aws_cluster-name_w-1.aws_session_token"); value != "token" {
+		t.Errorf("value in state object, got: %s, want: token", value)
+	}
+
+	err = stateObj.SetModuleVariables("node_aws_cluster-name_w-1", map[string]interface{}{"source": "./other"})
+	if err == nil {
+		t.Error("Expected an error for changing the source")
+	}
+	if value := stateObj.Get("module.node_aws_cluster-name_w-1.source"); value != "./modules/aws-rancher-k8s-host" {
+		t.Errorf("value in state object, got: %s, want: ./modules/aws-rancher-k8s-host", value)
+	}
+
+	err = stateObj.SetModuleVariables("node_aws_cluster-name_w-2", map[string]interface{}{})
+	if err == nil {
+		t.Error("Expected an error for a module that does not exist")
+	}
+}
+
 func TestDelete(t *testing.T) {
 	stateObj, err := New("DelState", []byte(`{"config":{"triton":{"key":"55fd4s","url":"https://api.storage.com"}}}`))
 	if err != nil {
@@ -104,6 +251,88 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestMonthlyBudget(t *testing.T) {
+	stateObj, err := New("BudgetState", []byte(`{"module":{"cluster_aws_dev":{"name":"dev"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := stateObj.MonthlyBudget("cluster_aws_dev"); ok {
+		t.Error("expected no budget")
+	}
+
+	err = stateObj.SetMonthlyBudget("cluster_aws_dev", 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget, ok := stateObj.MonthlyBudget("cluster_aws_dev")
+	if !ok || budget != 250 {
+		t.Errorf("value in state object, got: %v, want: %v.", budget, 250)
+	}
+
+	err = stateObj.Delete("module.cluster_aws_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := stateObj.MonthlyBudget("cluster_aws_dev"); ok {
+		t.Error("expected the budget to be deleted with the cluster")
+	}
+}
+
+func TestNodePools(t *testing.T) {
+	stateObj, err := New("NodePoolState", []byte(`{"module":{"cluster_triton_dev":{"name":"dev"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err := stateObj.NodePools("cluster_triton_dev")
+	if err != nil || len(pools) != 0 {
+		t.Errorf("expected no node pools, got: %v, %v", pools, err)
+	}
+
+	pool := NodePool{Count: 3}
+	err = stateObj.SetNodePool("cluster_triton_dev", "dev-w", pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stateObj.SetNodePool("cluster_triton_dev", "dev-e", NodePool{Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Node pools read back the same after the state is serialized
+	stateObj, err = New("NodePoolState", stateObj.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err = stateObj.NodePools("cluster_triton_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 2 || pools["dev-w"] != pool {
+		t.Errorf("value in state object, got: %v, want: %v.", pools["dev-w"], pool)
+	}
+
+	stateObj.DeleteNodePool("cluster_triton_dev", "dev-e")
+	pools, _ = stateObj.NodePools("cluster_triton_dev")
+	if _, ok := pools["dev-e"]; ok {
+		t.Error("expected node pool dev-e to be deleted")
+	}
+
+	err = stateObj.Delete("module.cluster_triton_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pools, _ = stateObj.NodePools("cluster_triton_dev")
+	if len(pools) != 0 {
+		t.Error("expected the node pools to be deleted with the cluster")
+	}
+}
+
 // GetClusters test
 func TestGetClusters(t *testing.T) {
 	stateObj, err := New("ClusterState", []byte(`{
@@ -188,3 +417,419 @@ func TestGetNodesWithoutCloudProvider(t *testing.T) {
 	}
 
 }
+
+func TestGetAddons(t *testing.T) {
+	stateObj, err := New("AddonState", []byte(`{
+    "module":{
+      "addon_triton_dev-cluster_cert-manager":{"source":"cert-manager"},
+      "addon_aws_dev-cluster_cert-manager":{"source":"cert-manager"},
+      "node_triton_dev-cluster_1":{"hostname":"dev-worker1"}
+    }
+    }`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	addonMap, err := stateObj.Addons("cluster_triton_dev-cluster")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if addonMap["cert-manager"] != "addon_triton_dev-cluster_cert-manager" || len(addonMap) != 1 {
+		t.Errorf("wrong addons: %v", addonMap)
+	}
+}
+
+func TestCreatedAt(t *testing.T) {
+	stateObj, err := New("CreatedAtState", []byte(`{}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = stateObj.AddNode("cluster_aws_cluster-name", "node-name", map[string]interface{}{"field": "test"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if stateObj.CreatedAt("node_aws_cluster-name_node-name") == "" {
+		t.Error("expected node creation timestamp to be set")
+	}
+
+	err = stateObj.Delete("module.node_aws_cluster-name_node-name")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if createdAt := stateObj.CreatedAt("node_aws_cluster-name_node-name"); createdAt != "" {
+		t.Errorf("value in state object, got: %s, want: %s", createdAt, "")
+	}
+
+	// Changing a module keeps its creation timestamp
+	stateObj, err = New("CreatedAtState", []byte(`{"locals":{"triton_kubernetes_created_at":{"cluster-manager":"2018-04-01T10:00:00Z"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stateObj.SetManager(map[string]interface{}{"name": "CreatedAtState"})
+	if err != nil {
+		t.Error(err)
+	}
+	if createdAt := stateObj.CreatedAt("cluster-manager"); createdAt != "2018-04-01T10:00:00Z" {
+		t.Errorf("value in state object, got: %s, want: %s", createdAt, "2018-04-01T10:00:00Z")
+	}
+}
+
+func TestFailedNodes(t *testing.T) {
+	stateObj, err := New("FailedNodesState", []byte(`{}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, name := range []string{"node-1", "node-2"} {
+		err = stateObj.AddNode("cluster_aws_cluster-name", name, map[string]interface{}{"hostname": name})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	err = stateObj.SetNodeFailed("node_aws_cluster-name_node-2", true)
+	if err != nil {
+		t.Error(err)
+	}
+
+	failedNodes, err := stateObj.FailedNodes("cluster_aws_cluster-name")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(failedNodes) != 1 || failedNodes["node-2"] != "node_aws_cluster-name_node-2" {
+		t.Errorf("unexpected failed nodes, got: %v", failedNodes)
+	}
+
+	err = stateObj.SetNodeFailed("node_aws_cluster-name_node-2", false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	failedNodes, err = stateObj.FailedNodes("cluster_aws_cluster-name")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(failedNodes) != 0 {
+		t.Errorf("unexpected failed nodes, got: %v", failedNodes)
+	}
+}
+
+func TestNodesWithRole(t *testing.T) {
+	stateObj, err := New("NodesWithRoleState", []byte(`{"module":{
+		"node_aws_cluster-name_e-1":{"hostname":"e-1","rancher_host_labels":{"etcd":"true"}},
+		"node_aws_cluster-name_e-2":{"hostname":"e-2","rancher_host_labels":{"etcd":"true"}},
+		"node_aws_cluster-name_w-1":{"hostname":"w-1","rancher_host_labels":{"worker":"true"}}
+	}}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	etcdNodes, err := stateObj.NodesWithRole("cluster_aws_cluster-name", "etcd")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(etcdNodes) != 2 || etcdNodes["e-1"] != "node_aws_cluster-name_e-1" {
+		t.Errorf("value in state object, got: %v, want: e-1 and e-2", etcdNodes)
+	}
+
+	controlNodes, err := stateObj.NodesWithRole("cluster_aws_cluster-name", "control")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(controlNodes) != 0 {
+		t.Errorf("value in state object, got: %v, want: no nodes", controlNodes)
+	}
+}
+
+func TestRancherAPIToken(t *testing.T) {
+	stateObj, err := New("RancherAPITokenState", []byte(`{"module":{"cluster-manager":{"name":"manager"},"cluster_triton_dev":{"name":"dev","rancher_access_key":"${module.cluster-manager.rancher_access_key}","rancher_secret_key":"${module.cluster-manager.rancher_secret_key}"}}}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if token := stateObj.RancherAPIToken(); token != "" {
+		t.Errorf("value in state object, got: %s, want: %s", token, "")
+	}
+
+	if accessKey, _ := stateObj.RancherAPIKeyReferences(); accessKey != "${module.cluster-manager.rancher_access_key}" {
+		t.Errorf("value in state object, got: %s, want: %s", accessKey, "${module.cluster-manager.rancher_access_key}")
+	}
+
+	err = stateObj.SetRancherAPIToken("encrypted:v1:abc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if token := stateObj.RancherAPIToken(); token != "encrypted:v1:abc" {
+		t.Errorf("value in state object, got: %s, want: %s", token, "encrypted:v1:abc")
+	}
+
+	// The decrypted token is read from the environment, it isn't passed to the modules
+	if key := stateObj.Get("module.cluster_triton_dev.rancher_secret_key"); key != "" {
+		t.Errorf("value in state object, got: %s, want: %s", key, "")
+	}
+
+	if accessKey, secretKey := stateObj.RancherAPIKeyReferences(); accessKey != "" || secretKey != "" {
+		t.Errorf("value in state object, got: %s and %s, want: empty keys", accessKey, secretKey)
+	}
+}
+
+func TestManagerHAToken(t *testing.T) {
+	stateObj, err := New("ManagerHAState", []byte(`{"module":{"cluster-manager":{"name":"dev","manager_host_count":3,"manager_ha_token":"abc"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token := stateObj.ManagerHAToken(); token != "" {
+		t.Errorf("value in state object, got: %s, want: %s", token, "")
+	}
+
+	err = stateObj.SetManagerHAToken("encrypted:v1:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token := stateObj.ManagerHAToken(); token != "encrypted:v1:abc" {
+		t.Errorf("value in state object, got: %s, want: %s", token, "encrypted:v1:abc")
+	}
+	if token := stateObj.Get("module.cluster-manager.manager_ha_token"); token != "${var.manager_ha_token}" {
+		t.Errorf("value in state object, got: %s, want: %s", token, "${var.manager_ha_token}")
+	}
+	if stateObj.GetMap("variable.manager_ha_token") == nil {
+		t.Error("expected the variable of the token to be declared")
+	}
+}
+
+func TestSecretsEncryptionConfigs(t *testing.T) {
+	stateObj, err := New("SecretsEncryptionState", []byte(`{"module":{"cluster_aws_dev":{"name":"dev","k8s_secrets_encryption_config":"a2V5"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if configs := stateObj.SecretsEncryptionConfigs(); len(configs) != 0 {
+		t.Errorf("value in state object, got: %v, want: no secrets encryption configs", configs)
+	}
+
+	err = stateObj.SetSecretsEncryptionConfig("cluster_aws_dev", "encrypted:v1:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config := stateObj.SecretsEncryptionConfigs()["cluster_aws_dev"]; config != "encrypted:v1:abc" {
+		t.Errorf("value in state object, got: %s, want: %s", config, "encrypted:v1:abc")
+	}
+
+	if config := stateObj.Get("module.cluster_aws_dev.k8s_secrets_encryption_config"); config != "" {
+		t.Errorf("value in state object, got: %s, want: %s", config, "")
+	}
+	if stateObj.GetMap("variable.k8s_secrets_encryption_config_cluster_aws_dev") == nil {
+		t.Error("expected the variable of the cluster to be declared")
+	}
+
+	err = stateObj.Delete("module.cluster_aws_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configs := stateObj.SecretsEncryptionConfigs(); len(configs) != 0 {
+		t.Errorf("value in state object, got: %v, want: no secrets encryption configs", configs)
+	}
+	if stateObj.GetMap("variable.k8s_secrets_encryption_config_cluster_aws_dev") != nil {
+		t.Error("expected the variable of the deleted cluster to be removed")
+	}
+}
+
+func TestAWSRoleKeys(t *testing.T) {
+	stateObj, err := New("AWSRoleState", []byte(`{"module":{"cluster_aws_dev":{"name":"dev","aws_access_key":"AKIAEXAMPLE","aws_secret_key":"secret"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := stateObj.AWSRoleKeys(); len(keys) != 0 {
+		t.Errorf("value in state object, got: %v, want: no AWS role keys", keys)
+	}
+
+	err = stateObj.SetAWSRoleKeys("cluster_aws_dev", "AKIAEXAMPLE", "encrypted:v1:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := AWSRoleKeys{AccessKey: "AKIAEXAMPLE", SecretKey: "encrypted:v1:abc"}
+	if keys := stateObj.AWSRoleKeys()["cluster_aws_dev"]; keys != want {
+		t.Errorf("value in state object, got: %v, want: %v", keys, want)
+	}
+
+	for _, key := range []string{"aws_access_key", "aws_secret_key", "aws_session_token"} {
+		want := fmt.Sprintf("${var.%s_cluster_aws_dev}", key)
+		if value := stateObj.Get("module.cluster_aws_dev." + key); value != want {
+			t.Errorf("value in state object, got: %s, want: %s", value, want)
+		}
+	}
+
+	err = stateObj.Delete("module.cluster_aws_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := stateObj.AWSRoleKeys(); len(keys) != 0 {
+		t.Errorf("value in state object, got: %v, want: no AWS role keys", keys)
+	}
+	if stateObj.GetMap("variable.aws_access_key_cluster_aws_dev") != nil {
+		t.Error("expected the variables of the deleted module to be removed")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	stateObj, err := New("CheckpointState", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := stateObj.Checkpoint(); ok {
+		t.Error("expected no checkpoint")
+	}
+
+	want := Checkpoint{
+		Operation:  "create node dev-w-1",
+		Error:      "Error applying plan",
+		FailedAt:   "2018-05-01T10:00:00Z",
+		Args:       []string{"-target=module.node_triton_dev_dev-w-1"},
+		WorkingDir: "/tmp/triton-kubernetes-1",
+	}
+	err = stateObj.SetCheckpoint(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, ok := stateObj.Checkpoint()
+	if !ok {
+		t.Fatal("expected a checkpoint")
+	}
+	if checkpoint.Operation != want.Operation || checkpoint.FailedAt != want.FailedAt || len(checkpoint.Args) != 1 || checkpoint.WorkingDir != want.WorkingDir {
+		t.Errorf("value in state object, got: %+v, want: %+v", checkpoint, want)
+	}
+
+	stateObj.ClearCheckpoint()
+	if _, ok := stateObj.Checkpoint(); ok {
+		t.Error("expected the checkpoint to be cleared")
+	}
+}
+
+func TestImages(t *testing.T) {
+	stateObj, err := New("ImageState", []byte(`{"module":{"cluster_aws_dev":{"name":"dev"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := stateObj.Images("cluster_aws_dev")
+	if err != nil || len(images) != 0 {
+		t.Errorf("expected no images, got: %v, %v", images, err)
+	}
+
+	err = stateObj.SetImage("cluster_aws_dev", "k8s-node", Image{Image: "ami-0def3275", BuiltAt: "2018-01-01T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := Image{Image: "ami-0abc1234", BuiltAt: "2018-02-01T00:00:00Z"}
+	err = stateObj.SetImage("cluster_aws_dev", "k8s-node", image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Images read back the same after the state is serialized
+	stateObj, err = New("ImageState", stateObj.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images, err = stateObj.Images("cluster_aws_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images["k8s-node"] != image {
+		t.Errorf("value in state object, got: %v, want: %v.", images["k8s-node"], image)
+	}
+
+	err = stateObj.Delete("module.cluster_aws_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, _ = stateObj.Images("cluster_aws_dev")
+	if len(images) != 0 {
+		t.Errorf("expected the images to be deleted with the cluster, got: %v", images)
+	}
+}
+
+func TestSSHKey(t *testing.T) {
+	stateObj, err := New("SSHKeyState", []byte(`{"module":{"cluster_triton_dev":{"name":"dev"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := stateObj.SSHKey("cluster_triton_dev"); ok {
+		t.Error("expected no SSH key")
+	}
+
+	key := SSHKey{
+		PrivateKeyPath: "/home/ops/.triton-kubernetes/keys/manager_dev",
+		PublicKeyPath:  "/home/ops/.triton-kubernetes/keys/manager_dev.pub",
+		Fingerprint:    "c1:5c:8e:0c:5a:3c:6b:39:7d:0f:4e:64:a2:93:31:f4",
+		TritonKeyName:  "triton-kubernetes-manager-dev",
+	}
+	err = stateObj.SetSSHKey("cluster_triton_dev", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key reads back the same after the state is serialized
+	stateObj, err = New("SSHKeyState", stateObj.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, ok := stateObj.SSHKey("cluster_triton_dev"); !ok || actual != key {
+		t.Errorf("value in state object, got: %v, want: %v.", actual, key)
+	}
+
+	err = stateObj.Delete("module.cluster_triton_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stateObj.SSHKey("cluster_triton_dev"); ok {
+		t.Error("expected the SSH key to be deleted with the cluster")
+	}
+}
+
+func TestWithoutSecrets(t *testing.T) {
+	stateObj, err := New("dev-manager", []byte(`{
+		"module": {
+			"cluster-manager": {"name": "dev-manager", "rancher_admin_password": "pa$$word", "triton_key_path": "~/.ssh/id_rsa"},
+			"cluster_aws_dev": {"aws_access_key": "AKIA", "aws_secret_key": "s3cret"}
+		},
+		"terraform": {"backend": {"s3": {"bucket": "states", "secret_key": "s3cret"}}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withoutSecrets, err := stateObj.WithoutSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"module.cluster-manager.rancher_admin_password", "module.cluster_aws_dev.aws_secret_key", "terraform.backend.s3.secret_key"} {
+		if value := withoutSecrets.Get(path); value != "" {
+			t.Errorf("Expected %s to be left out, received %q", path, value)
+		}
+	}
+	for path, expected := range map[string]string{"module.cluster-manager.name": "dev-manager", "module.cluster-manager.triton_key_path": "~/.ssh/id_rsa", "module.cluster_aws_dev.aws_access_key": "AKIA", "terraform.backend.s3.bucket": "states"} {
+		if value := withoutSecrets.Get(path); value != expected {
+			t.Errorf("Wrong value of %s, expected %q, received %q", path, expected, value)
+		}
+	}
+
+	if value := stateObj.Get("module.cluster_aws_dev.aws_secret_key"); value != "s3cret" {
+		t.Errorf("Expected the state itself to keep its secrets, received %q", value)
+	}
+}
@@ -0,0 +1,201 @@
+// Package status checks the health of a cluster manager and its clusters by probing Rancher,
+// rather than trusting that their terraform state exists.
+package status
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/config"
+	"github.com/joyent/triton-kubernetes/rancher"
+	"github.com/joyent/triton-kubernetes/shell"
+	"github.com/joyent/triton-kubernetes/util"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	HealthHealthy = "healthy"
+	// The host of the cluster manager doesn't accept connections
+	HealthUnreachable = "unreachable"
+	// The host of the cluster manager is up, but Rancher doesn't answer its health endpoint
+	HealthRancherDown = "host up, Rancher down"
+	// Rancher is up, but the agent of the cluster isn't connected to it
+	HealthAgentDisconnected = "agent disconnected"
+	// The cluster is connected but not active, e.g. provisioning or updating
+	HealthUnhealthy = "unhealthy"
+	// The health of clusters is unknown while their cluster manager is down
+	HealthUnknown = "unknown"
+)
+
+// Health is the health of a cluster manager or cluster.
+type Health struct {
+	Name    string
+	Health  string
+	Details string
+}
+
+// Status prints the health of a cluster manager and of its clusters, or only of cluster_name
+// if it's set. It returns an error unless all of them are healthy, so it can be scripted.
+func Status(conf config.Config, remoteBackend backend.Backend) error {
+	nonInteractiveMode := conf.GetBool("non-interactive")
+	clusterManagers, err := remoteBackend.States()
+	if err != nil {
+		return err
+	}
+
+	if len(clusterManagers) == 0 {
+		return fmt.Errorf("No cluster managers.")
+	}
+
+	selectedClusterManager := ""
+	if conf.IsSet("cluster_manager") {
+		selectedClusterManager = conf.GetString("cluster_manager")
+	} else if nonInteractiveMode {
+		return util.ConfigError(errors.New("cluster_manager must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Cluster Manager",
+			Items: clusterManagers,
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+
+		selectedClusterManager = value
+	}
+
+	// Verify selected cluster manager exists
+	found := false
+	for _, clusterManager := range clusterManagers {
+		if selectedClusterManager == clusterManager {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Selected cluster manager '%s' does not exist.", selectedClusterManager)
+	}
+
+	currentState, err := remoteBackend.State(selectedClusterManager)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := currentState.Clusters()
+	if err != nil {
+		return err
+	}
+
+	clusterNames := []string{}
+	if conf.IsSet("cluster_name") {
+		clusterName := conf.GetString("cluster_name")
+		if _, ok := clusters[clusterName]; !ok {
+			return fmt.Errorf("A cluster named '%s', does not exist.", clusterName)
+		}
+		clusterNames = append(clusterNames, clusterName)
+	} else {
+		for name := range clusters {
+			clusterNames = append(clusterNames, name)
+		}
+		sort.Strings(clusterNames)
+	}
+
+	client, err := rancher.NewClientFromState(conf, currentState)
+	if err != nil {
+		return err
+	}
+	manager := checkManager(client, currentState.Name)
+
+	results := []Health{}
+	for _, clusterName := range clusterNames {
+		if manager.Health != HealthHealthy {
+			results = append(results, Health{Name: clusterName, Health: HealthUnknown, Details: "cluster manager is down"})
+			continue
+		}
+
+		clusterOutputs, err := shell.RunTerraformOutputWithState(conf, currentState, clusters[clusterName])
+		if err != nil {
+			return err
+		}
+		rancherClusterID, _ := clusterOutputs["rancher_cluster_id"].(string)
+		if rancherClusterID == "" {
+			results = append(results, Health{Name: clusterName, Health: HealthUnknown, Details: "no Rancher cluster id, it may not have been created successfully"})
+			continue
+		}
+
+		results = append(results, checkCluster(client, clusterName, rancherClusterID))
+	}
+
+	printHealth(manager, client.URL, results)
+
+	if manager.Health != HealthHealthy {
+		return fmt.Errorf("Cluster manager '%s' is not healthy.", currentState.Name)
+	}
+	for _, result := range results {
+		if result.Health != HealthHealthy {
+			return fmt.Errorf("Cluster manager '%s' has clusters that are not healthy.", currentState.Name)
+		}
+	}
+
+	return nil
+}
+
+// Probes the host of the cluster manager, then the Rancher health endpoint.
+func checkManager(client *rancher.Client, name string) Health {
+	err := client.HostReachable()
+	if err != nil {
+		return Health{Name: name, Health: HealthUnreachable, Details: err.Error()}
+	}
+
+	err = client.Ping()
+	if err != nil {
+		return Health{Name: name, Health: HealthRancherDown, Details: err.Error()}
+	}
+
+	return Health{Name: name, Health: HealthHealthy}
+}
+
+// Checks that the agent of a cluster is connected to Rancher and that the cluster is active.
+func checkCluster(client *rancher.Client, name, clusterID string) Health {
+	cluster, err := client.Cluster(clusterID)
+	if err != nil {
+		return Health{Name: name, Health: HealthUnknown, Details: err.Error()}
+	}
+
+	connected, reason := cluster.AgentConnected()
+	if !connected {
+		return Health{Name: name, Health: HealthAgentDisconnected, Details: reason}
+	}
+
+	if cluster.State != "active" {
+		return Health{Name: name, Health: HealthUnhealthy, Details: fmt.Sprintf("cluster is %s", cluster.State)}
+	}
+
+	return Health{Name: name, Health: HealthHealthy}
+}
+
+func printHealth(manager Health, rancherURL string, clusters []Health) {
+	fmt.Printf("Cluster manager %s (%s): %s\n", manager.Name, rancherURL, manager.Health)
+	if manager.Details != "" {
+		fmt.Printf("  %s\n", manager.Details)
+	}
+
+	if len(clusters) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLUSTER\tHEALTH\tDETAILS")
+	for _, cluster := range clusters {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", cluster.Name, cluster.Health, cluster.Details)
+	}
+	w.Flush()
+}
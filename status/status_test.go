@@ -0,0 +1,77 @@
+package status
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joyent/triton-kubernetes/rancher"
+)
+
+func TestCheckManager(t *testing.T) {
+	rancherUp := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ping" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		if !rancherUp {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "pong")
+	}))
+
+	client := rancher.NewClient(server.URL, "access", "secret")
+	if health := checkManager(client, "dev-manager"); health.Health != HealthHealthy {
+		t.Errorf("Expected a healthy cluster manager, got %+v", health)
+	}
+
+	rancherUp = false
+	if health := checkManager(client, "dev-manager"); health.Health != HealthRancherDown {
+		t.Errorf("Expected Rancher to be down, got %+v", health)
+	}
+
+	server.Close()
+	if health := checkManager(client, "dev-manager"); health.Health != HealthUnreachable {
+		t.Errorf("Expected an unreachable cluster manager, got %+v", health)
+	}
+}
+
+func TestCheckCluster(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/clusters/c-healthy":
+			fmt.Fprint(w, `{"id": "c-healthy", "state": "active", "conditions": [{"type": "Ready", "status": "True"}]}`)
+		case "/v3/clusters/c-disconnected":
+			fmt.Fprint(w, `{"id": "c-disconnected", "state": "unavailable", "conditions": [{"type": "Ready", "status": "False", "message": "Cluster agent is not connected"}]}`)
+		case "/v3/clusters/c-connected-condition":
+			fmt.Fprint(w, `{"id": "c-connected-condition", "state": "active", "conditions": [{"type": "Connected", "status": "False"}]}`)
+		case "/v3/clusters/c-updating":
+			fmt.Fprint(w, `{"id": "c-updating", "state": "updating"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := rancher.NewClient(server.URL, "access", "secret")
+
+	tests := []struct {
+		clusterID string
+		health    string
+	}{
+		{"c-healthy", HealthHealthy},
+		{"c-disconnected", HealthAgentDisconnected},
+		{"c-connected-condition", HealthAgentDisconnected},
+		{"c-updating", HealthUnhealthy},
+		{"c-missing", HealthUnknown},
+	}
+
+	for _, test := range tests {
+		health := checkCluster(client, "dev", test.clusterID)
+		if health.Health != test.health {
+			t.Errorf("%s: expected %s, got %+v", test.clusterID, test.health, health)
+		}
+	}
+}
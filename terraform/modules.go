@@ -0,0 +1,99 @@
+// Package terraform holds the terraform modules the cluster managers, clusters and nodes are
+// generated from, and exposes what the binary needs to know about them.
+package terraform
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+//go:embed modules/*/variables.tf
+var modules embed.FS
+
+// ModuleVariables returns the names of the variables declared by a module, sorted. The module
+// is given by its path in the repository, e.g. terraform/modules/triton-rancher, or by a module
+// source holding it, e.g. github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher?ref=master.
+func ModuleVariables(module string) ([]string, error) {
+	items, err := moduleVariableItems(module)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := []string{}
+	for name := range items {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return variables, nil
+}
+
+// ModuleVariableDefault returns the default value of a variable declared by a module, and
+// whether the variable has a string, number or bool default.
+func ModuleVariableDefault(module, name string) (string, bool, error) {
+	items, err := moduleVariableItems(module)
+	if err != nil {
+		return "", false, err
+	}
+
+	item, ok := items[name]
+	if !ok {
+		return "", false, fmt.Errorf("Terraform module '%s' doesn't declare variable '%s'.", module, name)
+	}
+	object, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return "", false, nil
+	}
+	defaults := object.List.Filter("default").Items
+	if len(defaults) == 0 {
+		return "", false, nil
+	}
+	literal, ok := defaults[0].Val.(*ast.LiteralType)
+	if !ok {
+		return "", false, nil
+	}
+	return fmt.Sprint(literal.Token.Value()), true, nil
+}
+
+// Returns the variable blocks of a module by variable name
+func moduleVariableItems(module string) (map[string]*ast.ObjectItem, error) {
+	modulePath := module
+	if i := strings.Index(modulePath, "//"); i >= 0 {
+		modulePath = modulePath[i+2:]
+	}
+	if i := strings.Index(modulePath, "?"); i >= 0 {
+		modulePath = modulePath[:i]
+	}
+	modulePath = strings.TrimPrefix(strings.Trim(modulePath, "/"), "terraform/")
+
+	content, err := modules.ReadFile(path.Join(modulePath, "variables.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("Unknown terraform module '%s'.", module)
+	}
+
+	file, err := hcl.ParseBytes(content)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("Invalid variables of terraform module '%s'.", module)
+	}
+
+	items := map[string]*ast.ObjectItem{}
+	for _, item := range list.Filter("variable").Items {
+		if len(item.Keys) == 0 {
+			continue
+		}
+		name := item.Keys[0].Token.Value()
+		if name, ok := name.(string); ok {
+			items[name] = item
+		}
+	}
+	return items, nil
+}
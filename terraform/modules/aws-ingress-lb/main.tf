@@ -0,0 +1,74 @@
+provider "aws" {
+  access_key = "${var.aws_access_key}"
+  secret_key = "${var.aws_secret_key}"
+  token      = "${var.aws_session_token}"
+  region     = "${var.aws_region}"
+}
+
+data "aws_subnet" "cluster" {
+  id = "${var.aws_subnet_id}"
+}
+
+# Network load balancer in front of the ingress controller of the cluster's worker nodes,
+# the worker nodes register themselves with the target groups.
+resource "aws_lb" "ingress" {
+  name               = "${var.name}-ingress"
+  load_balancer_type = "network"
+  subnets            = ["${var.aws_subnet_id}"]
+}
+
+resource "aws_lb_target_group" "http" {
+  name     = "${var.name}-ingress-http"
+  port     = 80
+  protocol = "TCP"
+  vpc_id   = "${data.aws_subnet.cluster.vpc_id}"
+}
+
+resource "aws_lb_target_group" "https" {
+  name     = "${var.name}-ingress-https"
+  port     = 443
+  protocol = "TCP"
+  vpc_id   = "${data.aws_subnet.cluster.vpc_id}"
+}
+
+resource "aws_lb_listener" "http" {
+  load_balancer_arn = "${aws_lb.ingress.arn}"
+  port              = 80
+  protocol          = "TCP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = "${aws_lb_target_group.http.arn}"
+  }
+}
+
+resource "aws_lb_listener" "https" {
+  load_balancer_arn = "${aws_lb.ingress.arn}"
+  port              = 443
+  protocol          = "TCP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = "${aws_lb_target_group.https.arn}"
+  }
+}
+
+# Network load balancers keep the client's address, so the worker nodes must accept
+# ingress traffic from anywhere
+resource "aws_security_group_rule" "http" {
+  type              = "ingress"
+  from_port         = 80
+  to_port           = 80
+  protocol          = "tcp"
+  cidr_blocks       = ["0.0.0.0/0"]
+  security_group_id = "${var.aws_security_group_id}"
+}
+
+resource "aws_security_group_rule" "https" {
+  type              = "ingress"
+  from_port         = 443
+  to_port           = 443
+  protocol          = "tcp"
+  cidr_blocks       = ["0.0.0.0/0"]
+  security_group_id = "${var.aws_security_group_id}"
+}
@@ -0,0 +1,11 @@
+output "ingress_address" {
+  value = "${aws_lb.ingress.dns_name}"
+}
+
+output "aws_ingress_http_target_group_arn" {
+  value = "${aws_lb_target_group.http.arn}"
+}
+
+output "aws_ingress_https_target_group_arn" {
+  value = "${aws_lb_target_group.https.arn}"
+}
@@ -0,0 +1,48 @@
+variable "name" {
+  description = "Name of the cluster, used as prefix of the load balancer's name."
+}
+
+variable "aws_access_key" {
+  description = "AWS access key"
+}
+
+variable "aws_secret_key" {
+  description = "AWS secret access key"
+}
+
+variable "aws_session_token" {
+  default     = ""
+  description = "AWS session token of temporary credentials, those of the IAM role the keys reference"
+}
+
+variable "aws_role_arn" {
+  default     = ""
+  description = "IAM role assumed before every terraform run, the keys reference its temporary credentials"
+}
+
+variable "aws_external_id" {
+  default     = ""
+  description = "External ID the IAM role is assumed with"
+}
+
+variable "aws_role_session_name" {
+  default     = ""
+  description = "Session name the IAM role is assumed with"
+}
+
+variable "aws_mfa_serial" {
+  default     = ""
+  description = "MFA device the IAM role is assumed with"
+}
+
+variable "aws_region" {
+  description = "AWS region of the cluster"
+}
+
+variable "aws_subnet_id" {
+  description = "The AWS subnet id of the cluster's nodes."
+}
+
+variable "aws_security_group_id" {
+  description = "The AWS security group id of the cluster's nodes."
+}
@@ -0,0 +1,117 @@
+provider "aws" {
+  access_key = "${var.aws_access_key}"
+  secret_key = "${var.aws_secret_key}"
+  token      = "${var.aws_session_token}"
+  region     = "${var.aws_region}"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+  asg_name          = "${var.aws_asg_name != "" ? var.aws_asg_name : var.hostname}"
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    hostname                  = "${var.hostname}"
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+  }
+}
+
+resource "aws_launch_template" "pool" {
+  count = "${var.aws_spot == "true" ? 0 : 1}"
+
+  name_prefix            = "${var.hostname}-"
+  image_id               = "${var.aws_ami_id}"
+  instance_type          = "${var.aws_instance_type}"
+  key_name               = "${var.aws_key_name}"
+  vpc_security_group_ids = ["${var.aws_security_group_id}"]
+
+  user_data = "${base64encode(data.template_file.install_rancher_agent.rendered)}"
+
+  tag_specifications {
+    resource_type = "instance"
+
+    tags = {
+      role = "${local.rancher_node_role}"
+    }
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+# An empty max_price caps the price at the on-demand price of the instance type
+resource "aws_launch_template" "pool_spot" {
+  count = "${var.aws_spot == "true" ? 1 : 0}"
+
+  name_prefix            = "${var.hostname}-"
+  image_id               = "${var.aws_ami_id}"
+  instance_type          = "${var.aws_instance_type}"
+  key_name               = "${var.aws_key_name}"
+  vpc_security_group_ids = ["${var.aws_security_group_id}"]
+
+  user_data = "${base64encode(data.template_file.install_rancher_agent.rendered)}"
+
+  instance_market_options {
+    market_type = "spot"
+
+    spot_options {
+      max_price          = "${var.aws_spot_max_price}"
+      spot_instance_type = "one-time"
+    }
+  }
+
+  tag_specifications {
+    resource_type = "instance"
+
+    tags = {
+      role = "${local.rancher_node_role}"
+    }
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+resource "aws_autoscaling_group" "pool" {
+  name              = "${local.asg_name}"
+  min_size          = "${var.aws_asg_min_size}"
+  max_size          = "${var.aws_asg_max_size}"
+  desired_capacity  = "${var.aws_asg_desired_capacity}"
+  target_group_arns = ["${var.aws_ingress_target_group_arns}"]
+
+  # Terraform conditionals can't return lists, the subnets are joined and split again
+  vpc_zone_identifier = ["${split(",", length(var.aws_subnet_ids) > 0 ? join(",", var.aws_subnet_ids) : var.aws_subnet_id)}"]
+
+  launch_template = {
+    id      = "${element(concat(aws_launch_template.pool.*.id, aws_launch_template.pool_spot.*.id), 0)}"
+    version = "$Latest"
+  }
+
+  tag {
+    key                 = "Name"
+    value               = "${var.hostname}"
+    propagate_at_launch = true
+  }
+}
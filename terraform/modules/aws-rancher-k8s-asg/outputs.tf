@@ -0,0 +1,3 @@
+output "aws_autoscaling_group_name" {
+  value = "${aws_autoscaling_group.pool.name}"
+}
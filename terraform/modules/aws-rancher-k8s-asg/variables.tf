@@ -0,0 +1,172 @@
+variable "hostname" {
+  description = "Hostname prefix of the instances, each instance is named {hostname}-{instance id}."
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_cluster_registration_token" {}
+
+variable "rancher_cluster_ca_checksum" {}
+
+variable "rancher_host_labels" {
+  type        = "map"
+  description = "A map of key/value pairs that get passed to the rancher agent on the host."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "aws_access_key" {
+  description = "AWS access key"
+}
+
+variable "aws_secret_key" {
+  description = "AWS secret access key"
+}
+
+variable "aws_session_token" {
+  default     = ""
+  description = "AWS session token of temporary credentials, those of the IAM role the keys reference"
+}
+
+variable "aws_role_arn" {
+  default     = ""
+  description = "IAM role assumed before every terraform run, the keys reference its temporary credentials"
+}
+
+variable "aws_external_id" {
+  default     = ""
+  description = "External ID the IAM role is assumed with"
+}
+
+variable "aws_role_session_name" {
+  default     = ""
+  description = "Session name the IAM role is assumed with"
+}
+
+variable "aws_mfa_serial" {
+  default     = ""
+  description = "MFA device the IAM role is assumed with"
+}
+
+variable "aws_region" {
+  description = "AWS region to host your network"
+}
+
+variable "aws_ami_id" {
+  description = "Base AMI to launch the instances with"
+}
+
+variable "aws_instance_type" {
+  default     = "t2.micro"
+  description = "The AWS instance type to use for Kubernetes compute node(s). Defaults to t2.micro."
+}
+
+variable "aws_subnet_id" {
+  description = "The AWS subnet id to deploy the instance to."
+}
+
+variable "aws_subnet_ids" {
+  type        = "list"
+  default     = []
+  description = "The AWS subnet ids of the availability zones the group spans, used instead of aws_subnet_id."
+}
+
+variable "aws_security_group_id" {
+  description = "The AWS subnet id to deploy the instance to."
+}
+
+variable "aws_key_name" {
+  description = "The AWS key name to use to deploy the instance."
+}
+
+variable "aws_asg_name" {
+  default     = ""
+  description = "Name of the Auto Scaling Group, unique in the region. Defaults to the hostname."
+}
+
+variable "aws_asg_min_size" {
+  description = "Minimum number of instances in the Auto Scaling Group."
+}
+
+variable "aws_asg_max_size" {
+  description = "Maximum number of instances in the Auto Scaling Group."
+}
+
+variable "aws_asg_desired_capacity" {
+  description = "Number of instances the Auto Scaling Group should run."
+}
+
+variable "aws_spot" {
+  default     = "false"
+  description = "Whether the nodes are spot instances. Only set on worker nodes."
+}
+
+variable "aws_spot_max_price" {
+  default     = ""
+  description = "Maximum hourly price of the spot instances in USD. Defaults to the on-demand price."
+}
+
+variable "aws_ingress_load_balancer" {
+  default     = "false"
+  description = "Whether the nodes are added to the target groups of the cluster's ingress load balancer."
+}
+
+variable "aws_ingress_target_group_arns" {
+  type        = "list"
+  default     = []
+  description = "The http and https target groups of the cluster's ingress load balancer. Only set on worker nodes."
+}
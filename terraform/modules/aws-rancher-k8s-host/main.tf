@@ -1,11 +1,16 @@
 provider "aws" {
   access_key = "${var.aws_access_key}"
   secret_key = "${var.aws_secret_key}"
+  token      = "${var.aws_session_token}"
   region     = "${var.aws_region}"
 }
 
 locals {
   rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+
+  # The host is either an on-demand instance or the instance of a spot request
+  instance_id       = "${element(concat(aws_instance.host.*.id, aws_spot_instance_request.host.*.spot_instance_id), 0)}"
+  availability_zone = "${element(concat(aws_instance.host.*.availability_zone, aws_spot_instance_request.host.*.availability_zone), 0)}"
 }
 
 data "template_file" "install_rancher_agent" {
@@ -25,18 +30,54 @@ data "template_file" "install_rancher_agent" {
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
 
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+
     volume_device_name = "${var.ebs_volume_device_name}"
     volume_mount_path  = "${var.ebs_volume_mount_path}"
   }
 }
 
 resource "aws_instance" "host" {
+  count = "${var.aws_spot == "true" ? 0 : 1}"
+
+  ami                    = "${var.aws_ami_id}"
+  instance_type          = "${var.aws_instance_type}"
+  subnet_id              = "${var.aws_subnet_id}"
+  vpc_security_group_ids = ["${var.aws_security_group_id}"]
+  key_name               = "${var.aws_key_name}"
+
+  tags = {
+    Name = "${var.hostname}"
+  }
+
+  user_data = "${data.template_file.install_rancher_agent.rendered}"
+}
+
+# An empty spot_price caps the price at the on-demand price of the instance type
+resource "aws_spot_instance_request" "host" {
+  count = "${var.aws_spot == "true" ? 1 : 0}"
+
   ami                    = "${var.aws_ami_id}"
   instance_type          = "${var.aws_instance_type}"
   subnet_id              = "${var.aws_subnet_id}"
   vpc_security_group_ids = ["${var.aws_security_group_id}"]
   key_name               = "${var.aws_key_name}"
 
+  spot_price           = "${var.aws_spot_max_price}"
+  spot_type            = "one-time"
+  wait_for_fulfillment = true
+
   tags = {
     Name = "${var.hostname}"
   }
@@ -47,7 +88,7 @@ resource "aws_instance" "host" {
 resource "aws_ebs_volume" "host_volume" {
   count = "${var.ebs_volume_device_name != "" ? 1 : 0}"
 
-  availability_zone = "${aws_instance.host.availability_zone}"
+  availability_zone = "${local.availability_zone}"
   type              = "${var.ebs_volume_type}"
   size              = "${var.ebs_volume_size}"
 
@@ -64,5 +105,52 @@ resource "aws_volume_attachment" "host_volume_attachment" {
 
   device_name = "${var.ebs_volume_device_name}"
   volume_id   = "${aws_ebs_volume.host_volume.id}"
-  instance_id = "${aws_instance.host.id}"
+  instance_id = "${local.instance_id}"
+}
+
+# Worker nodes are added to the cluster's ingress load balancer, if it has one. The count
+# can't depend on the target groups, which aren't known until they are created.
+resource "aws_lb_target_group_attachment" "ingress" {
+  count = "${var.aws_ingress_load_balancer == "true" ? 2 : 0}"
+
+  target_group_arn = "${element(var.aws_ingress_target_group_arns, count.index)}"
+  target_id        = "${local.instance_id}"
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    instance_id = "${local.instance_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.aws_ssh_user}"
+    host        = "${element(concat(aws_instance.host.*.public_ip, aws_spot_instance_request.host.*.public_ip), 0)}"
+    private_key = "${file(var.aws_private_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
 }
@@ -35,6 +35,55 @@ variable "rancher_registry_password" {
   description = "The password to use."
 }
 
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "k8s_audit_policy" {
+  default     = ""
+  description = "The base64 encoded Kubernetes API server audit policy, written to control nodes of clusters with an audit log."
+}
+
+variable "k8s_secrets_encryption_config" {
+  default     = ""
+  description = "The base64 encoded encryption config of the Kubernetes API server, copied over SSH to control nodes of clusters encrypting secrets at rest."
+}
+
+variable "k8s_kms_plugin_image" {
+  default     = ""
+  description = "The image of the KMS plugin run on control nodes of clusters encrypting secrets with kms."
+}
+
+variable "k8s_kms_plugin_args" {
+  default     = ""
+  description = "The arguments of the KMS plugin."
+}
+
 variable "docker_engine_install_url" {
   default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
   description = "The URL to the shell script to install the docker engine."
@@ -48,6 +97,31 @@ variable "aws_secret_key" {
   description = "AWS secret access key"
 }
 
+variable "aws_session_token" {
+  default     = ""
+  description = "AWS session token of temporary credentials, those of the IAM role the keys reference"
+}
+
+variable "aws_role_arn" {
+  default     = ""
+  description = "IAM role assumed before every terraform run, the keys reference its temporary credentials"
+}
+
+variable "aws_external_id" {
+  default     = ""
+  description = "External ID the IAM role is assumed with"
+}
+
+variable "aws_role_session_name" {
+  default     = ""
+  description = "Session name the IAM role is assumed with"
+}
+
+variable "aws_mfa_serial" {
+  default     = ""
+  description = "MFA device the IAM role is assumed with"
+}
+
 variable "aws_region" {
   description = "AWS region to host your network"
 }
@@ -65,6 +139,11 @@ variable "aws_subnet_id" {
   description = "The AWS subnet id to deploy the instance to."
 }
 
+variable "aws_availability_zone" {
+  default     = ""
+  description = "The availability zone of aws_subnet_id, when the cluster spreads nodes across availability zones."
+}
+
 variable "aws_security_group_id" {
   description = "The AWS subnet id to deploy the instance to."
 }
@@ -92,3 +171,34 @@ variable "ebs_volume_size" {
   default     = ""
   description = "The size of the volume, in GiBs."
 }
+
+variable "aws_spot" {
+  default     = "false"
+  description = "Whether the nodes are spot instances. Only set on worker nodes."
+}
+
+variable "aws_spot_max_price" {
+  default     = ""
+  description = "Maximum hourly price of the spot instances in USD. Defaults to the on-demand price."
+}
+
+variable "aws_ingress_load_balancer" {
+  default     = "false"
+  description = "Whether the nodes are added to the target groups of the cluster's ingress load balancer."
+}
+
+variable "aws_ingress_target_group_arns" {
+  type        = "list"
+  default     = []
+  description = "The http and https target groups of the cluster's ingress load balancer. Only set on worker nodes."
+}
+
+variable "aws_ssh_user" {
+  default     = "ubuntu"
+  description = "The SSH user the encryption config of the Kubernetes API server is copied to control nodes with."
+}
+
+variable "aws_private_key_path" {
+  default     = ""
+  description = "The path to the private key of aws_key_name, used to copy the encryption config of the Kubernetes API server to control nodes."
+}
@@ -8,9 +8,25 @@ data "external" "rancher_cluster" {
     name                  = "${var.name}"
     k8s_version           = "${var.k8s_version}"
     k8s_network_provider  = "${var.k8s_network_provider}"
+    k8s_network_mtu       = "${var.k8s_network_mtu}"
+    k8s_network_backend   = "${var.k8s_network_backend}"
     k8s_registry          = "${var.k8s_registry}"
     k8s_registry_username = "${var.k8s_registry_username}"
     k8s_registry_password = "${var.k8s_registry_password}"
+
+    k8s_nodelocal_dns                = "${var.k8s_nodelocal_dns}"
+    k8s_coredns_min_replicas         = "${var.k8s_coredns_min_replicas}"
+    k8s_coredns_upstream_nameservers = "${var.k8s_coredns_upstream_nameservers}"
+
+    k8s_audit_log             = "${var.k8s_audit_log}"
+    k8s_audit_log_max_age     = "${var.k8s_audit_log_max_age}"
+    k8s_audit_log_max_backups = "${var.k8s_audit_log_max_backups}"
+    k8s_audit_log_max_size    = "${var.k8s_audit_log_max_size}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption}"
+
+    rancher_cluster_template_id          = "${var.rancher_cluster_template_id}"
+    rancher_cluster_template_revision_id = "${var.rancher_cluster_template_revision_id}"
   }
 }
 
@@ -18,6 +34,7 @@ data "external" "rancher_cluster" {
 provider "aws" {
   access_key = "${var.aws_access_key}"
   secret_key = "${var.aws_secret_key}"
+  token      = "${var.aws_session_token}"
   region     = "${var.aws_region}"
 }
 
@@ -59,6 +76,28 @@ resource "aws_route_table_association" "public" {
   route_table_id = "${aws_route_table.public.id}"
 }
 
+# Nodes spread across availability zones go in a subnet of their zone
+resource "aws_subnet" "zone" {
+  count = "${length(compact(split(",", var.aws_availability_zones)))}"
+
+  vpc_id                  = "${aws_vpc.default.id}"
+  cidr_block              = "${element(split(",", var.aws_zone_subnet_cidrs), count.index)}"
+  availability_zone       = "${element(split(",", var.aws_availability_zones), count.index)}"
+  map_public_ip_on_launch = true
+  depends_on              = ["aws_internet_gateway.default"]
+
+  tags {
+    Name = "public-${element(split(",", var.aws_availability_zones), count.index)}"
+  }
+}
+
+resource "aws_route_table_association" "zone" {
+  count = "${length(compact(split(",", var.aws_availability_zones)))}"
+
+  subnet_id      = "${element(aws_subnet.zone.*.id, count.index)}"
+  route_table_id = "${aws_route_table.public.id}"
+}
+
 resource "aws_key_pair" "deployer" {
   // Only attempt to create the key pair if the public key was provided
   count = "${var.aws_public_key_path != "" ? 1 : 0}"
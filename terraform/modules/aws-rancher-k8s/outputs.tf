@@ -14,6 +14,10 @@ output "aws_subnet_id" {
   value = "${aws_subnet.public.id}"
 }
 
+output "aws_zone_subnet_ids" {
+  value = "${zipmap(aws_subnet.zone.*.availability_zone, aws_subnet.zone.*.id)}"
+}
+
 output "aws_security_group_id" {
   value = "${aws_security_group.rke_ports.id}"
 }
@@ -21,3 +25,15 @@ output "aws_security_group_id" {
 output "aws_key_name" {
   value = "${var.aws_key_name}"
 }
+
+output "k8s_audit_policy" {
+  value = "${var.k8s_audit_policy}"
+}
+
+output "k8s_kms_plugin_image" {
+  value = "${var.k8s_kms_plugin_image}"
+}
+
+output "k8s_kms_plugin_args" {
+  value = "${var.k8s_kms_plugin_args}"
+}
@@ -1,6 +1,7 @@
 provider "aws" {
   access_key = "${var.aws_access_key}"
   secret_key = "${var.aws_secret_key}"
+  token      = "${var.aws_session_token}"
   region     = "${var.aws_region}"
 }
 
@@ -41,6 +42,28 @@ resource "aws_route_table_association" "public" {
   route_table_id = "${aws_route_table.public.id}"
 }
 
+# The hosts of an HA manager each have a subnet in their own availability zone
+resource "aws_subnet" "ha" {
+  count = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+
+  vpc_id                  = "${aws_vpc.default.id}"
+  cidr_block              = "${element(split(",", var.aws_ha_subnet_cidrs), count.index)}"
+  availability_zone       = "${element(split(",", var.aws_ha_availability_zones), count.index)}"
+  map_public_ip_on_launch = true
+  depends_on              = ["aws_internet_gateway.default"]
+
+  tags {
+    Name = "${var.name}-${count.index + 1}"
+  }
+}
+
+resource "aws_route_table_association" "ha" {
+  count = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+
+  subnet_id      = "${element(aws_subnet.ha.*.id, count.index)}"
+  route_table_id = "${aws_route_table.public.id}"
+}
+
 resource "aws_key_pair" "deployer" {
   // Only attempt to create the key pair if the public key was provided
   count = "${var.aws_public_key_path != "" ? 1 : 0}"
@@ -64,15 +87,15 @@ resource "aws_security_group" "rke_ports" {
   }
 
   ingress {
-    from_port   = "80"          # Rancher UI
-    to_port     = "80"
+    from_port   = "${var.rancher_http_port}" # Rancher UI
+    to_port     = "${var.rancher_http_port}"
     protocol    = "tcp"
     cidr_blocks = ["0.0.0.0/0"]
   }
 
   ingress {
-    from_port   = "443"         # Rancher UI
-    to_port     = "443"
+    from_port   = "${var.rancher_https_port}" # Rancher UI
+    to_port     = "${var.rancher_https_port}"
     protocol    = "tcp"
     cidr_blocks = ["0.0.0.0/0"]
   }
@@ -86,24 +109,35 @@ resource "aws_security_group" "rke_ports" {
 }
 
 resource "aws_instance" "host" {
+  count = "${var.manager_host_count}"
+
   ami                    = "${var.aws_ami_id}"
   instance_type          = "${var.aws_instance_type}"
-  subnet_id              = "${aws_subnet.public.id}"
+  subnet_id              = "${var.manager_host_count > 1 ? element(concat(aws_subnet.ha.*.id, list("")), count.index) : aws_subnet.public.id}"
   vpc_security_group_ids = ["${aws_security_group.rke_ports.id}"]
   key_name               = "${var.aws_key_name}"
 
   tags = {
-    Name = "${var.name}"
+    Name = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}"
   }
 
-  user_data = "${data.template_file.install_docker.rendered}"
+  # The hosts of an HA manager run Rancher on k3s rather than docker
+  user_data = "${var.manager_host_count > 1 ? "" : data.template_file.install_docker.rendered}"
 }
 
 locals {
-  rancher_master_id = "${aws_instance.host.id}"
-  rancher_master_ip = "${aws_instance.host.public_ip}"
+  # The first host sets up Rancher, and keeps its API key
+  rancher_master_id = "${aws_instance.host.0.id}"
+  rancher_master_ip = "${aws_instance.host.0.public_ip}"
   ssh_user          = "${var.aws_ssh_user}"
   key_path          = "${var.aws_private_key_path}"
+
+  # Rancher as seen from the master, and from everything else. Behind the ingress controller
+  # of an HA manager, only its load balancer reaches Rancher.
+  rancher_lb_host    = "${element(concat(aws_lb.rancher.*.dns_name, list("")), 0)}"
+  rancher_direct_url = "https://${var.manager_host_count > 1 ? local.rancher_lb_host : local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_local_url  = "${var.manager_host_count > 1 ? local.rancher_direct_url : var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
 }
 
 data "template_file" "install_docker" {
@@ -127,10 +161,18 @@ data "template_file" "install_rancher_master" {
     rancher_registry          = "${var.rancher_registry}"
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
   }
 }
 
 resource "null_resource" "install_rancher_master" {
+  count = "${var.manager_host_count > 1 ? 0 : 1}"
+
   # Changes to any instance of the cluster requires re-provisioning
   triggers {
     rancher_master_id = "${local.rancher_master_id}"
@@ -150,20 +192,220 @@ resource "null_resource" "install_rancher_master" {
   }
 }
 
+# With manager_host_count 3, Rancher follows its high availability reference: the hosts form a
+# k3s cluster sharing its embedded etcd, Rancher runs a replica on each of them, and a network
+# load balancer in front of their ingress controller is where Rancher is reached.
+resource "aws_lb" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name               = "${var.name}-rancher"
+  load_balancer_type = "network"
+  subnets            = ["${aws_subnet.ha.*.id}"]
+
+  # Each zone's node of the load balancer also sends traffic to the hosts in the other zones
+  enable_cross_zone_load_balancing = true
+}
+
+resource "aws_lb_target_group" "rancher_http" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name     = "${var.name}-rancher-http"
+  port     = 80
+  protocol = "TCP"
+  vpc_id   = "${aws_vpc.default.id}"
+}
+
+resource "aws_lb_target_group" "rancher_https" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name     = "${var.name}-rancher-https"
+  port     = 443
+  protocol = "TCP"
+  vpc_id   = "${aws_vpc.default.id}"
+}
+
+resource "aws_lb_listener" "rancher_http" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  load_balancer_arn = "${aws_lb.rancher.arn}"
+  port              = "${var.rancher_http_port}"
+  protocol          = "TCP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = "${aws_lb_target_group.rancher_http.arn}"
+  }
+}
+
+resource "aws_lb_listener" "rancher_https" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  load_balancer_arn = "${aws_lb.rancher.arn}"
+  port              = "${var.rancher_https_port}"
+  protocol          = "TCP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = "${aws_lb_target_group.rancher_https.arn}"
+  }
+}
+
+resource "aws_lb_target_group_attachment" "rancher_http" {
+  count = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+
+  target_group_arn = "${aws_lb_target_group.rancher_http.arn}"
+  target_id        = "${element(aws_instance.host.*.id, count.index)}"
+  port             = 80
+}
+
+resource "aws_lb_target_group_attachment" "rancher_https" {
+  count = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+
+  target_group_arn = "${aws_lb_target_group.rancher_https.arn}"
+  target_id        = "${element(aws_instance.host.*.id, count.index)}"
+  port             = 443
+}
+
+# Network load balancers keep the client's address, so the hosts accept ingress traffic from
+# anywhere, unless the ports of the load balancer already do
+resource "aws_security_group_rule" "rancher_ha_http" {
+  count = "${var.manager_host_count > 1 && var.rancher_http_port != "80" ? 1 : 0}"
+
+  type              = "ingress"
+  from_port         = 80
+  to_port           = 80
+  protocol          = "tcp"
+  cidr_blocks       = ["0.0.0.0/0"]
+  security_group_id = "${aws_security_group.rke_ports.id}"
+}
+
+resource "aws_security_group_rule" "rancher_ha_https" {
+  count = "${var.manager_host_count > 1 && var.rancher_https_port != "443" ? 1 : 0}"
+
+  type              = "ingress"
+  from_port         = 443
+  to_port           = 443
+  protocol          = "tcp"
+  cidr_blocks       = ["0.0.0.0/0"]
+  security_group_id = "${aws_security_group.rke_ports.id}"
+}
+
+# The k3s API, etcd, kubelet and overlay network between the hosts
+resource "aws_security_group_rule" "rancher_ha_hosts" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  type              = "ingress"
+  from_port         = 0
+  to_port           = 0
+  protocol          = "-1"
+  self              = true
+  security_group_id = "${aws_security_group.rke_ports.id}"
+}
+
+data "template_file" "install_k3s" {
+  count    = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+  template = "${file("${path.module}/files/install_k3s.sh.tpl")}"
+
+  vars {
+    k3s_version = "${var.k3s_version}"
+
+    # The first host starts the etcd cluster the others join
+    k3s_server_args = "${count.index == 0 ? "--cluster-init" : "--server https://${aws_instance.host.0.private_ip}:6443"}"
+  }
+}
+
+resource "null_resource" "install_k3s_first" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${data.template_file.install_k3s.0.rendered}
+      EOF
+  }
+}
+
+resource "null_resource" "install_k3s_others" {
+  count      = "${var.manager_host_count > 1 ? var.manager_host_count - 1 : 0}"
+  depends_on = ["null_resource.install_k3s_first"]
+
+  triggers {
+    host_id = "${element(aws_instance.host.*.id, count.index + 1)}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${element(aws_instance.host.*.public_ip, count.index + 1)}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${element(data.template_file.install_k3s.*.rendered, count.index + 1)}
+      EOF
+  }
+}
+
+data "template_file" "install_rancher_ha" {
+  template = "${file("${path.module}/files/install_rancher_ha.sh.tpl")}"
+
+  vars {
+    manager_host_count    = "${var.manager_host_count}"
+    rancher_hostname      = "${local.rancher_lb_host}"
+    rancher_chart_version = "${var.rancher_chart_version}"
+    cert_manager_version  = "${var.cert_manager_version}"
+  }
+}
+
+resource "null_resource" "install_rancher_ha" {
+  count      = "${var.manager_host_count > 1 ? 1 : 0}"
+  depends_on = ["null_resource.install_k3s_others", "aws_lb_listener.rancher_https"]
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_ha.rendered}
+      EOF
+  }
+}
+
 data "template_file" "setup_rancher_k8s" {
   template = "${file("${path.module}/files/setup_rancher.sh.tpl")}"
 
   vars {
     name                  = "${var.name}"
-    rancher_host          = "https://127.0.0.1"
-    host_registration_url = "https://${local.rancher_master_ip}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
 
     rancher_admin_password = "${var.rancher_admin_password}"
   }
 }
 
 resource "null_resource" "setup_rancher_k8s" {
-  depends_on = ["null_resource.install_rancher_master"]
+  depends_on = ["null_resource.install_rancher_master", "null_resource.install_rancher_ha"]
 
   # Changes to any instance of the cluster requires re-provisioning
   triggers {
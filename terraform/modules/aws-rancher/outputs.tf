@@ -1,11 +1,21 @@
 output "rancher_url" {
-  value = "https://${local.rancher_master_ip}"
+  value = "${local.rancher_url}"
 }
 
 output "rancher_access_key" {
-  value = "${chomp(module.rancher_access_key.stdout)}"
+  value     = "${chomp(module.rancher_access_key.stdout)}"
+  sensitive = true
 }
 
 output "rancher_secret_key" {
-  value = "${chomp(module.rancher_secret_key.stdout)}"
+  value     = "${chomp(module.rancher_secret_key.stdout)}"
+  sensitive = true
+}
+
+output "manager_host_count" {
+  value = "${var.manager_host_count}"
+}
+
+output "rancher_host_ips" {
+  value = ["${aws_instance.host.*.public_ip}"]
 }
@@ -36,6 +36,51 @@ variable "rancher_registry_password" {
   description = "The password to use."
 }
 
+variable "rancher_external_url" {
+  default     = ""
+  description = "URL of Rancher through an existing load balancer or reverse proxy, e.g. https://rancher.example.com:8443. Nodes register with it. Defaults to the master's IP address on rancher_https_port."
+}
+
+variable "rancher_https_port" {
+  default     = "443"
+  description = "The port the master serves Rancher on over HTTPS."
+}
+
+variable "rancher_http_port" {
+  default     = "80"
+  description = "The port the master serves Rancher on over HTTP."
+}
+
+variable "rancher_tls_termination" {
+  default     = "rancher"
+  description = "Where TLS is terminated, rancher or proxy. With proxy, the proxy forwards HTTP requests to rancher_http_port with the X-Forwarded-Proto header."
+}
+
+variable "manager_host_count" {
+  default     = "1"
+  description = "The number of hosts running Rancher, 1 or 3. With 3, Rancher runs on a k3s cluster whose hosts share its etcd, behind a network load balancer nodes register through."
+}
+
+variable "manager_ha_token" {
+  default     = ""
+  description = "The secret the hosts join the k3s cluster with when manager_host_count is 3."
+}
+
+variable "k3s_version" {
+  default     = "v1.20.6+k3s1"
+  description = "The k3s version the hosts run when manager_host_count is 3."
+}
+
+variable "rancher_chart_version" {
+  default     = "2.5.8"
+  description = "The version of the Rancher Helm chart installed when manager_host_count is 3, rancher_server_image is only used by a single host."
+}
+
+variable "cert_manager_version" {
+  default     = "v1.0.4"
+  description = "The version of cert-manager issuing Rancher's certificate when manager_host_count is 3."
+}
+
 variable "aws_access_key" {
   description = "AWS access key"
 }
@@ -44,6 +89,31 @@ variable "aws_secret_key" {
   description = "AWS secret access key"
 }
 
+variable "aws_session_token" {
+  default     = ""
+  description = "AWS session token of temporary credentials, those of the IAM role the keys reference"
+}
+
+variable "aws_role_arn" {
+  default     = ""
+  description = "IAM role assumed before every terraform run, the keys reference its temporary credentials"
+}
+
+variable "aws_external_id" {
+  default     = ""
+  description = "External ID the IAM role is assumed with"
+}
+
+variable "aws_role_session_name" {
+  default     = ""
+  description = "Session name the IAM role is assumed with"
+}
+
+variable "aws_mfa_serial" {
+  default     = ""
+  description = "MFA device the IAM role is assumed with"
+}
+
 variable "aws_region" {
   description = "AWS region to host your network"
 }
@@ -58,6 +128,16 @@ variable "aws_subnet_cidr" {
   default     = "10.0.2.0/24"
 }
 
+variable "aws_ha_availability_zones" {
+  default     = ""
+  description = "Comma separated availability zone of each host when manager_host_count is 3."
+}
+
+variable "aws_ha_subnet_cidrs" {
+  default     = ""
+  description = "Comma separated CIDR of the subnet of each host when manager_host_count is 3."
+}
+
 variable "aws_ami_id" {
   description = "Base AMI to launch the instances with"
 }
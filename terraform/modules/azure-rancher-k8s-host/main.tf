@@ -4,10 +4,19 @@ provider "azurerm" {
   client_secret   = "${var.azure_client_secret}"
   tenant_id       = "${var.azure_tenant_id}"
   environment     = "${var.azure_environment}"
+
+  # Spot VMs need the azurerm_linux_virtual_machine resource, which 1.x providers only register
+  # with ARM_PROVIDER_TWOPOINTZERO_RESOURCES set
+  version = "~> 1.44"
 }
 
 locals {
   rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+
+  # Terraform evaluates both sides of a conditional, so file() reads /dev/null when the key of an
+  # Azure SSH public key resource is given instead of a path
+  public_key_path = "${var.azure_public_key_path == "" ? "/dev/null" : var.azure_public_key_path}"
+  public_key      = "${var.azure_public_key == "" ? file(local.public_key_path) : var.azure_public_key}"
 }
 
 data "template_file" "install_rancher_agent" {
@@ -27,6 +36,19 @@ data "template_file" "install_rancher_agent" {
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
 
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+
     disk_mount_path = "${var.azure_disk_mount_path}"
   }
 }
@@ -65,6 +87,8 @@ resource "azurerm_managed_disk" "host_disk" {
 }
 
 resource "azurerm_virtual_machine" "host" {
+  count = "${var.azure_priority == "Spot" ? 0 : 1}"
+
   name                  = "${var.hostname}"
   location              = "${var.azure_location}"
   resource_group_name   = "${var.azure_resource_group_name}"
@@ -112,7 +136,103 @@ resource "azurerm_virtual_machine" "host" {
 
     ssh_keys {
       path     = "/home/${var.azure_ssh_user}/.ssh/authorized_keys"
-      key_data = "${file(var.azure_public_key_path)}"
+      key_data = "${local.public_key}"
     }
   }
 }
+
+# Spot VMs can only be created with the newer VM resource, they're evicted when Azure needs the
+# capacity back or the price goes above azure_max_bid_price. Evicted VMs are deallocated, the only
+# eviction policy of single VMs, and keep their disks until they're started again.
+resource "azurerm_linux_virtual_machine" "spot_host" {
+  count = "${var.azure_priority == "Spot" ? 1 : 0}"
+
+  name                  = "${var.hostname}"
+  computer_name         = "${var.hostname}"
+  location              = "${var.azure_location}"
+  resource_group_name   = "${var.azure_resource_group_name}"
+  network_interface_ids = ["${azurerm_network_interface.nic.id}"]
+  size                  = "${var.azure_size}"
+
+  priority        = "Spot"
+  eviction_policy = "Deallocate"
+  max_bid_price   = "${var.azure_max_bid_price}"
+
+  source_image_reference {
+    publisher = "${var.azure_image_publisher}"
+    offer     = "${var.azure_image_offer}"
+    sku       = "${var.azure_image_sku}"
+    version   = "${var.azure_image_version}"
+  }
+
+  os_disk {
+    name                 = "${var.hostname}-osdisk"
+    caching              = "ReadWrite"
+    storage_account_type = "Standard_LRS"
+  }
+
+  admin_username = "${var.azure_ssh_user}"
+  custom_data    = "${base64encode(data.template_file.install_rancher_agent.rendered)}"
+
+  admin_ssh_key {
+    username   = "${var.azure_ssh_user}"
+    public_key = "${local.public_key}"
+  }
+}
+
+# The disk is attached once the spot VM is created, the install script waits for it
+resource "azurerm_virtual_machine_data_disk_attachment" "spot_host_disk" {
+  count = "${var.azure_priority == "Spot" && var.azure_disk_mount_path != "" ? 1 : 0}"
+
+  managed_disk_id    = "${element(concat(azurerm_managed_disk.host_disk.*.id, list("")), 0)}"
+  virtual_machine_id = "${element(concat(azurerm_linux_virtual_machine.spot_host.*.id, list("")), 0)}"
+  lun                = 0
+  caching            = "ReadWrite"
+}
+
+# The public IP is allocated when the VM starts, it's only known once the VM is created
+data "azurerm_public_ip" "public_ip" {
+  count      = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+  depends_on = ["azurerm_virtual_machine.host", "azurerm_linux_virtual_machine.spot_host"]
+
+  name                = "${azurerm_public_ip.public_ip.name}"
+  resource_group_name = "${var.azure_resource_group_name}"
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    azure_vm_id = "${element(concat(azurerm_virtual_machine.host.*.id, azurerm_linux_virtual_machine.spot_host.*.id), 0)}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.azure_ssh_user}"
+    host        = "${data.azurerm_public_ip.public_ip.ip_address}"
+    private_key = "${file(var.azure_private_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
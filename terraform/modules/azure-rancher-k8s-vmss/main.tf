@@ -0,0 +1,163 @@
+provider "azurerm" {
+  subscription_id = "${var.azure_subscription_id}"
+  client_id       = "${var.azure_client_id}"
+  client_secret   = "${var.azure_client_secret}"
+  tenant_id       = "${var.azure_tenant_id}"
+  environment     = "${var.azure_environment}"
+
+  # Spot instances need the azurerm_linux_virtual_machine_scale_set resource, which 1.x providers
+  # only register with ARM_PROVIDER_TWOPOINTZERO_RESOURCES set
+  version = "~> 1.44"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+  vmss_name         = "${var.azure_vmss_name != "" ? var.azure_vmss_name : var.hostname}"
+
+  # Terraform evaluates both sides of a conditional, so file() reads /dev/null when the key of an
+  # Azure SSH public key resource is given instead of a path
+  public_key_path = "${var.azure_public_key_path == "" ? "/dev/null" : var.azure_public_key_path}"
+  public_key      = "${var.azure_public_key == "" ? file(local.public_key_path) : var.azure_public_key}"
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+  }
+}
+
+resource "azurerm_virtual_machine_scale_set" "pool" {
+  count = "${var.azure_priority == "Spot" ? 0 : 1}"
+
+  name                = "${local.vmss_name}"
+  location            = "${var.azure_location}"
+  resource_group_name = "${var.azure_resource_group_name}"
+
+  # Model changes are not rolled out to running instances, which would restart their workloads
+  upgrade_policy_mode = "Manual"
+
+  # Overprovisioned instances would register with Rancher before being deleted
+  overprovision = false
+
+  sku {
+    name     = "${var.azure_size}"
+    tier     = "Standard"
+    capacity = "${var.azure_vmss_capacity}"
+  }
+
+  storage_profile_image_reference {
+    publisher = "${var.azure_image_publisher}"
+    offer     = "${var.azure_image_offer}"
+    sku       = "${var.azure_image_sku}"
+    version   = "${var.azure_image_version}"
+  }
+
+  storage_profile_os_disk {
+    name              = ""
+    caching           = "ReadWrite"
+    create_option     = "FromImage"
+    managed_disk_type = "Standard_LRS"
+  }
+
+  os_profile {
+    computer_name_prefix = "${var.hostname}-"
+    admin_username       = "${var.azure_ssh_user}"
+    custom_data          = "${data.template_file.install_rancher_agent.rendered}"
+  }
+
+  os_profile_linux_config {
+    disable_password_authentication = true
+
+    ssh_keys {
+      path     = "/home/${var.azure_ssh_user}/.ssh/authorized_keys"
+      key_data = "${local.public_key}"
+    }
+  }
+
+  network_profile {
+    name                      = "${var.hostname}"
+    primary                   = true
+    network_security_group_id = "${var.azure_network_security_group_id}"
+
+    ip_configuration {
+      name      = "${var.hostname}"
+      primary   = true
+      subnet_id = "${var.azure_subnet_id}"
+    }
+  }
+}
+
+# Spot instances can only be created with the newer scale set resource, they're evicted when Azure
+# needs the capacity back or the price goes above azure_max_bid_price
+resource "azurerm_linux_virtual_machine_scale_set" "spot_pool" {
+  count = "${var.azure_priority == "Spot" ? 1 : 0}"
+
+  name                = "${local.vmss_name}"
+  location            = "${var.azure_location}"
+  resource_group_name = "${var.azure_resource_group_name}"
+  sku                 = "${var.azure_size}"
+  instances           = "${var.azure_vmss_capacity}"
+
+  # Model changes are not rolled out to running instances, which would restart their workloads
+  upgrade_mode = "Manual"
+
+  # Overprovisioned instances would register with Rancher before being deleted
+  overprovision = false
+
+  # Evicted instances are deleted, the scale set gets new ones when capacity is back
+  priority        = "Spot"
+  eviction_policy = "Delete"
+  max_bid_price   = "${var.azure_max_bid_price}"
+
+  source_image_reference {
+    publisher = "${var.azure_image_publisher}"
+    offer     = "${var.azure_image_offer}"
+    sku       = "${var.azure_image_sku}"
+    version   = "${var.azure_image_version}"
+  }
+
+  os_disk {
+    caching              = "ReadWrite"
+    storage_account_type = "Standard_LRS"
+  }
+
+  computer_name_prefix = "${var.hostname}-"
+  admin_username       = "${var.azure_ssh_user}"
+  custom_data          = "${base64encode(data.template_file.install_rancher_agent.rendered)}"
+
+  admin_ssh_key {
+    username   = "${var.azure_ssh_user}"
+    public_key = "${local.public_key}"
+  }
+
+  network_interface {
+    name                      = "${var.hostname}"
+    primary                   = true
+    network_security_group_id = "${var.azure_network_security_group_id}"
+
+    ip_configuration {
+      name      = "${var.hostname}"
+      primary   = true
+      subnet_id = "${var.azure_subnet_id}"
+    }
+  }
+}
@@ -0,0 +1,3 @@
+output "azure_vmss_name" {
+  value = "${element(concat(azurerm_virtual_machine_scale_set.pool.*.name, azurerm_linux_virtual_machine_scale_set.spot_pool.*.name), 0)}"
+}
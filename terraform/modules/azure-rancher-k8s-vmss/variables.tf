@@ -0,0 +1,142 @@
+variable "hostname" {
+  description = "Computer name prefix of the instances, Azure names each instance {hostname}-{instance id}."
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_cluster_registration_token" {}
+
+variable "rancher_cluster_ca_checksum" {}
+
+variable "rancher_host_labels" {
+  type        = "map"
+  description = "A map of key/value pairs that get passed to the rancher agent on the host."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "azure_subscription_id" {}
+
+variable "azure_client_id" {}
+
+variable "azure_client_secret" {}
+
+variable "azure_tenant_id" {}
+
+variable "azure_environment" {
+  default = "public"
+}
+
+variable "azure_location" {}
+
+variable "azure_resource_group_name" {}
+
+variable "azure_network_security_group_id" {}
+
+variable "azure_subnet_id" {}
+
+variable "azure_size" {
+  default = "Standard_A0"
+}
+
+variable "azure_image_publisher" {
+  default = "Canonical"
+}
+
+variable "azure_image_offer" {
+  default = "UbuntuServer"
+}
+
+variable "azure_image_sku" {
+  default = "16.04-LTS"
+}
+
+variable "azure_image_version" {
+  default = "latest"
+}
+
+variable "azure_ssh_user" {
+  default = "root"
+}
+
+variable "azure_public_key_path" {
+  default = "~/.ssh/id_rsa.pub"
+}
+
+variable "azure_public_key" {
+  default     = ""
+  description = "The public key of an Azure SSH public key resource, used instead of the key at azure_public_key_path."
+}
+
+variable "azure_vmss_name" {
+  default     = ""
+  description = "Name of the scale set, unique in the resource group. Defaults to the hostname."
+}
+
+variable "azure_vmss_capacity" {
+  description = "Number of instances in the scale set."
+}
+
+variable "azure_priority" {
+  default     = "Regular"
+  description = "The priority of the instances of the scale set, Regular or Spot."
+}
+
+variable "azure_max_bid_price" {
+  default     = "-1"
+  description = "The maximum hourly price in USD of a spot instance, -1 to pay at most the price of a regular instance."
+}
@@ -21,3 +21,15 @@ output "azure_network_security_group_id" {
 output "azure_subnet_id" {
   value = "${azurerm_subnet.subnet.id}"
 }
+
+output "k8s_audit_policy" {
+  value = "${var.k8s_audit_policy}"
+}
+
+output "k8s_kms_plugin_image" {
+  value = "${var.k8s_kms_plugin_image}"
+}
+
+output "k8s_kms_plugin_args" {
+  value = "${var.k8s_kms_plugin_args}"
+}
@@ -0,0 +1,34 @@
+#!/bin/bash
+
+export KUBECONFIG=/etc/rancher/k3s/k3s.yaml
+
+# Wait for every host to join the k3s cluster
+printf 'Waiting for the manager hosts to be ready'
+until [ "$(k3s kubectl get nodes --no-headers | grep -c ' Ready ')" -ge ${manager_host_count} ]; do
+	printf '.'
+	sleep 5
+done
+
+# Install helm
+curl -sfL https://raw.githubusercontent.com/helm/helm/master/scripts/get-helm-3 | bash
+
+# cert-manager issues the certificate Rancher serves
+helm repo add jetstack https://charts.jetstack.io
+helm repo add rancher-stable https://releases.rancher.com/server-charts/stable
+helm repo update
+
+k3s kubectl create namespace cert-manager
+helm install cert-manager jetstack/cert-manager \
+	--namespace cert-manager \
+	--version ${cert_manager_version} \
+	--set installCRDs=true \
+	--wait
+
+# A Rancher replica on each host, reached through the load balancer
+k3s kubectl create namespace cattle-system
+helm install rancher rancher-stable/rancher \
+	--namespace cattle-system \
+	--version ${rancher_chart_version} \
+	--set hostname=${rancher_hostname} \
+	--set replicas=${manager_host_count}
+k3s kubectl -n cattle-system rollout status deploy/rancher
@@ -6,21 +6,28 @@ provider "azurerm" {
   environment     = "${var.azure_environment}"
 }
 
+# A resource group is created for the cluster manager, unless it goes in an existing one
 resource "azurerm_resource_group" "resource_group" {
+  count = "${var.azure_resource_group_name == "" ? 1 : 0}"
+
   name     = "${var.name}-resource_group"
   location = "${var.azure_location}"
 }
 
+locals {
+  resource_group_name = "${var.azure_resource_group_name != "" ? var.azure_resource_group_name : join("", azurerm_resource_group.resource_group.*.name)}"
+}
+
 resource "azurerm_virtual_network" "vnet" {
   name                = "${var.azure_virtual_network_name}"
   address_space       = ["${var.azure_virtual_network_address_space}"]
   location            = "${var.azure_location}"
-  resource_group_name = "${azurerm_resource_group.resource_group.name}"
+  resource_group_name = "${local.resource_group_name}"
 }
 
 resource "azurerm_subnet" "subnet" {
   name                 = "${var.azure_subnet_name}"
-  resource_group_name  = "${azurerm_resource_group.resource_group.name}"
+  resource_group_name  = "${local.resource_group_name}"
   virtual_network_name = "${azurerm_virtual_network.vnet.name}"
   address_prefix       = "${var.azure_subnet_address_prefix}"
 }
@@ -28,7 +35,7 @@ resource "azurerm_subnet" "subnet" {
 resource "azurerm_network_security_group" "firewall" {
   name                = "${var.azurerm_network_security_group_name}"
   location            = "${var.azure_location}"
-  resource_group_name = "${azurerm_resource_group.resource_group.name}"
+  resource_group_name = "${local.resource_group_name}"
 }
 
 # Firewall requirements taken from:
@@ -42,28 +49,50 @@ resource "azurerm_network_security_rule" "rancher_ports" {
 
   source_port_ranges = [
     "22",  # SSH
-    "80",  # Rancher UI
-    "443", # Rancher UI
+    "${var.rancher_http_port}",  # Rancher UI
+    "${var.rancher_https_port}", # Rancher UI
   ]
 
   destination_port_range      = "*"
   source_address_prefix       = "*"
   destination_address_prefix  = "*"
-  resource_group_name         = "${azurerm_resource_group.resource_group.name}"
+  resource_group_name         = "${local.resource_group_name}"
+  network_security_group_name = "${azurerm_network_security_group.firewall.name}"
+}
+
+# Ingress to the hosts of an HA manager from its load balancer, which keeps the client's address
+resource "azurerm_network_security_rule" "rancher_ha" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name      = "rancher_ha"
+  priority  = 1001
+  direction = "Inbound"
+  access    = "Allow"
+  protocol  = "Tcp"
+
+  source_port_range           = "*"
+  destination_port_ranges     = ["80", "443"]
+  source_address_prefix       = "*"
+  destination_address_prefix  = "*"
+  resource_group_name         = "${local.resource_group_name}"
   network_security_group_name = "${azurerm_network_security_group.firewall.name}"
 }
 
 resource "azurerm_public_ip" "public_ip" {
-  name                         = "${var.name}"
+  count = "${var.manager_host_count}"
+
+  name                         = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}"
   location                     = "${var.azure_location}"
-  resource_group_name          = "${azurerm_resource_group.resource_group.name}"
+  resource_group_name          = "${local.resource_group_name}"
   public_ip_address_allocation = "static"
 }
 
 resource "azurerm_network_interface" "nic" {
-  name                = "${var.name}"
+  count = "${var.manager_host_count}"
+
+  name                = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}"
   location            = "${var.azure_location}"
-  resource_group_name = "${azurerm_resource_group.resource_group.name}"
+  resource_group_name = "${local.resource_group_name}"
 
   network_security_group_id = "${azurerm_network_security_group.firewall.id}"
 
@@ -71,16 +100,31 @@ resource "azurerm_network_interface" "nic" {
     name                          = "testconfiguration1"
     subnet_id                     = "${azurerm_subnet.subnet.id}"
     private_ip_address_allocation = "dynamic"
-    public_ip_address_id          = "${azurerm_public_ip.public_ip.id}"
+    public_ip_address_id          = "${element(azurerm_public_ip.public_ip.*.id, count.index)}"
   }
 }
 
+# The hosts of an HA manager are spread across fault and update domains
+resource "azurerm_availability_set" "ha" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name                = "${var.name}-rancher"
+  location            = "${var.azure_location}"
+  resource_group_name = "${local.resource_group_name}"
+  managed             = true
+
+  platform_fault_domain_count = 2
+}
+
 resource "azurerm_virtual_machine" "host" {
-  name                  = "${var.name}"
+  count = "${var.manager_host_count}"
+
+  name                  = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}"
   location              = "${var.azure_location}"
-  resource_group_name   = "${azurerm_resource_group.resource_group.name}"
-  network_interface_ids = ["${azurerm_network_interface.nic.id}"]
+  resource_group_name   = "${local.resource_group_name}"
+  network_interface_ids = ["${element(azurerm_network_interface.nic.*.id, count.index)}"]
   vm_size               = "${var.azure_size}"
+  availability_set_id   = "${var.manager_host_count > 1 ? element(concat(azurerm_availability_set.ha.*.id, list("")), 0) : ""}"
 
   delete_os_disk_on_termination = true
 
@@ -92,16 +136,18 @@ resource "azurerm_virtual_machine" "host" {
   }
 
   storage_os_disk {
-    name              = "${var.name}-osdisk"
+    name              = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}-osdisk"
     caching           = "ReadWrite"
     create_option     = "FromImage"
     managed_disk_type = "Standard_LRS"
   }
 
   os_profile {
-    computer_name  = "${var.name}"
+    computer_name  = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}"
     admin_username = "${var.azure_ssh_user}"
-    custom_data    = "${data.template_file.install_docker.rendered}"
+
+    # The hosts of an HA manager run Rancher on k3s rather than docker
+    custom_data = "${var.manager_host_count > 1 ? "" : data.template_file.install_docker.rendered}"
   }
 
   os_profile_linux_config {
@@ -115,17 +161,27 @@ resource "azurerm_virtual_machine" "host" {
 }
 
 data "azurerm_public_ip" "public_ip" {
+  count      = "${var.manager_host_count}"
   depends_on = ["azurerm_public_ip.public_ip"]
 
-  name                = "${azurerm_public_ip.public_ip.name}"
-  resource_group_name = "${azurerm_resource_group.resource_group.name}"
+  name                = "${element(azurerm_public_ip.public_ip.*.name, count.index)}"
+  resource_group_name = "${local.resource_group_name}"
 }
 
 locals {
-  rancher_master_id = "${azurerm_virtual_machine.host.id}"
-  rancher_master_ip = "${data.azurerm_public_ip.public_ip.ip_address}"
+  # The first host sets up Rancher, and keeps its API key
+  rancher_master_id = "${azurerm_virtual_machine.host.0.id}"
+  rancher_master_ip = "${data.azurerm_public_ip.public_ip.0.ip_address}"
   ssh_user          = "${var.azure_ssh_user}"
   key_path          = "${var.azure_private_key_path}"
+
+  # Rancher as seen from the master, and from everything else. Behind the ingress controller
+  # of an HA manager, only its load balancer reaches Rancher. Its address has no DNS name,
+  # the ingress is for the sslip.io name resolving to it.
+  rancher_lb_host    = "${var.manager_host_count > 1 ? "${element(concat(azurerm_public_ip.rancher.*.ip_address, list("")), 0)}.sslip.io" : ""}"
+  rancher_direct_url = "https://${var.manager_host_count > 1 ? local.rancher_lb_host : local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_local_url  = "${var.manager_host_count > 1 ? local.rancher_direct_url : var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
 }
 
 data "template_file" "install_docker" {
@@ -149,10 +205,18 @@ data "template_file" "install_rancher_master" {
     rancher_registry          = "${var.rancher_registry}"
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
   }
 }
 
 resource "null_resource" "install_rancher_master" {
+  count = "${var.manager_host_count > 1 ? 0 : 1}"
+
   # Changes to any instance of the cluster requires re-provisioning
   triggers {
     rancher_master_id = "${local.rancher_master_id}"
@@ -172,20 +236,192 @@ resource "null_resource" "install_rancher_master" {
   }
 }
 
+# With manager_host_count 3, Rancher follows its high availability reference: the hosts form a
+# k3s cluster sharing its embedded etcd, Rancher runs a replica on each of them, and a load
+# balancer in front of their ingress controller is where Rancher is reached.
+resource "azurerm_public_ip" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name                         = "${var.name}-rancher"
+  location                     = "${var.azure_location}"
+  resource_group_name          = "${local.resource_group_name}"
+  public_ip_address_allocation = "static"
+}
+
+resource "azurerm_lb" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name                = "${var.name}-rancher"
+  location            = "${var.azure_location}"
+  resource_group_name = "${local.resource_group_name}"
+
+  frontend_ip_configuration {
+    name                 = "rancher"
+    public_ip_address_id = "${azurerm_public_ip.rancher.id}"
+  }
+}
+
+resource "azurerm_lb_backend_address_pool" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name                = "rancher"
+  resource_group_name = "${local.resource_group_name}"
+  loadbalancer_id     = "${azurerm_lb.rancher.id}"
+}
+
+resource "azurerm_network_interface_backend_address_pool_association" "rancher" {
+  count = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+
+  network_interface_id    = "${element(azurerm_network_interface.nic.*.id, count.index)}"
+  ip_configuration_name   = "testconfiguration1"
+  backend_address_pool_id = "${azurerm_lb_backend_address_pool.rancher.id}"
+}
+
+resource "azurerm_lb_probe" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name                = "rancher-https"
+  resource_group_name = "${local.resource_group_name}"
+  loadbalancer_id     = "${azurerm_lb.rancher.id}"
+  protocol            = "Tcp"
+  port                = 443
+}
+
+resource "azurerm_lb_rule" "rancher_http" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name                           = "rancher-http"
+  resource_group_name            = "${local.resource_group_name}"
+  loadbalancer_id                = "${azurerm_lb.rancher.id}"
+  protocol                       = "Tcp"
+  frontend_port                  = "${var.rancher_http_port}"
+  backend_port                   = 80
+  frontend_ip_configuration_name = "rancher"
+  backend_address_pool_id        = "${azurerm_lb_backend_address_pool.rancher.id}"
+  probe_id                       = "${azurerm_lb_probe.rancher.id}"
+}
+
+resource "azurerm_lb_rule" "rancher_https" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name                           = "rancher-https"
+  resource_group_name            = "${local.resource_group_name}"
+  loadbalancer_id                = "${azurerm_lb.rancher.id}"
+  protocol                       = "Tcp"
+  frontend_port                  = "${var.rancher_https_port}"
+  backend_port                   = 443
+  frontend_ip_configuration_name = "rancher"
+  backend_address_pool_id        = "${azurerm_lb_backend_address_pool.rancher.id}"
+  probe_id                       = "${azurerm_lb_probe.rancher.id}"
+}
+
+data "template_file" "install_k3s" {
+  count    = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+  template = "${file("${path.module}/files/install_k3s.sh.tpl")}"
+
+  vars {
+    k3s_version = "${var.k3s_version}"
+
+    # The first host starts the etcd cluster the others join
+    k3s_server_args = "${count.index == 0 ? "--cluster-init" : "--server https://${azurerm_network_interface.nic.0.private_ip_address}:6443"}"
+  }
+}
+
+resource "null_resource" "install_k3s_first" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${data.template_file.install_k3s.0.rendered}
+      EOF
+  }
+}
+
+resource "null_resource" "install_k3s_others" {
+  count      = "${var.manager_host_count > 1 ? var.manager_host_count - 1 : 0}"
+  depends_on = ["null_resource.install_k3s_first"]
+
+  triggers {
+    host_id = "${element(azurerm_virtual_machine.host.*.id, count.index + 1)}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${element(data.azurerm_public_ip.public_ip.*.ip_address, count.index + 1)}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${element(data.template_file.install_k3s.*.rendered, count.index + 1)}
+      EOF
+  }
+}
+
+data "template_file" "install_rancher_ha" {
+  template = "${file("${path.module}/files/install_rancher_ha.sh.tpl")}"
+
+  vars {
+    manager_host_count    = "${var.manager_host_count}"
+    rancher_hostname      = "${local.rancher_lb_host}"
+    rancher_chart_version = "${var.rancher_chart_version}"
+    cert_manager_version  = "${var.cert_manager_version}"
+  }
+}
+
+# Azure's load balancer drops the flows it would send back to the host they come from, so the
+# first host reaches Rancher through its own ingress controller
+resource "null_resource" "install_rancher_ha" {
+  count      = "${var.manager_host_count > 1 ? 1 : 0}"
+  depends_on = ["null_resource.install_k3s_others", "azurerm_lb_rule.rancher_https", "azurerm_network_interface_backend_address_pool_association.rancher"]
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      echo '127.0.0.1 ${local.rancher_lb_host}' | sudo tee -a /etc/hosts > /dev/null
+      ${data.template_file.install_rancher_ha.rendered}
+      EOF
+  }
+}
+
 data "template_file" "setup_rancher_k8s" {
   template = "${file("${path.module}/files/setup_rancher.sh.tpl")}"
 
   vars {
     name                  = "${var.name}"
-    rancher_host          = "https://127.0.0.1"
-    host_registration_url = "https://${local.rancher_master_ip}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
 
     rancher_admin_password = "${var.rancher_admin_password}"
   }
 }
 
 resource "null_resource" "setup_rancher_k8s" {
-  depends_on = ["null_resource.install_rancher_master"]
+  depends_on = ["null_resource.install_rancher_master", "null_resource.install_rancher_ha"]
 
   # Changes to any instance of the cluster requires re-provisioning
   triggers {
@@ -8,7 +8,63 @@ if [ -n "$(command -v firewalld)" ]; then
 	sudo systemctl disable firewalld.service
 fi
 
-sudo curl ${docker_engine_install_url} | sh
+# Configure timezone and NTP servers, clock skew breaks TLS and etcd
+if [ "${timezone}" != "" ]; then
+	sudo timedatectl set-timezone ${timezone}
+fi
+if [ "${ntp_servers}" != "" ]; then
+	if [ -n "$(command -v chronyd)" ]; then
+		sudo sed -i '/^server /d; /^pool /d' /etc/chrony.conf
+		for ntp_server in ${ntp_servers}; do
+			echo "server $ntp_server iburst" | sudo tee -a /etc/chrony.conf > /dev/null
+		done
+		sudo systemctl restart chronyd.service
+	else
+		printf "[Time]\nNTP=${ntp_servers}\n" | sudo tee /etc/systemd/timesyncd.conf > /dev/null
+		sudo timedatectl set-ntp true
+		sudo systemctl restart systemd-timesyncd.service
+	fi
+fi
+
+# Prepare the kernel for Kubernetes: the kubelet doesn't start with swap enabled, and pod
+# networking needs bridged traffic to go through iptables and IP forwarding
+sudo swapoff -a
+sudo sed -i '/\sswap\s/s/^\([^#]\)/#\1/' /etc/fstab
+for kernel_module in br_netfilter overlay; do
+	sudo modprobe $kernel_module
+	echo $kernel_module | sudo tee /etc/modules-load.d/$kernel_module.conf > /dev/null
+done
+printf "net.bridge.bridge-nf-call-iptables = 1\nnet.bridge.bridge-nf-call-ip6tables = 1\nnet.ipv4.ip_forward = 1\n" | sudo tee /etc/sysctl.d/90-kubernetes.conf > /dev/null
+if [ "${sysctls}" != "" ]; then
+	printf "%s\n" "${sysctls}" | sudo tee /etc/sysctl.d/91-kubernetes-extra.conf > /dev/null
+fi
+sudo sysctl --system > /dev/null
+
+# Write the Kubernetes API server audit policy, the API server writes its audit log to /var/log/kube-audit
+if [ "${k8s_audit_policy}" != "" ]; then
+	sudo mkdir -p /etc/kubernetes /var/log/kube-audit
+	echo "${k8s_audit_policy}" | base64 -d | sudo tee /etc/kubernetes/audit-policy.yaml > /dev/null
+fi
+
+# Wait for the Kubernetes API server encryption config, it holds the key secrets are encrypted with
+# and is copied over SSH so it's never part of the user data
+if [ "${k8s_secrets_encryption}" = "true" ]; then
+	for i in $(seq 1 60); do
+		if sudo test -f /etc/kubernetes/encryption-config.yaml; then
+			break
+		fi
+		sleep 10
+	done
+	if ! sudo test -f /etc/kubernetes/encryption-config.yaml; then
+		echo "The Kubernetes API server encryption config wasn't copied to /etc/kubernetes/encryption-config.yaml, check that this node accepts SSH connections." | sudo tee /var/log/triton-kubernetes-secrets-encryption.log >&2
+		exit 1
+	fi
+fi
+
+# Golden images built by `triton-kubernetes build image` already have Docker
+if [ -z "$(command -v docker)" ]; then
+	sudo curl ${docker_engine_install_url} | sh
+fi
 sudo service docker stop
 sudo bash -c 'echo "{
   \"storage-driver\": \"overlay2\"
@@ -22,5 +78,40 @@ if [ "${rancher_registry_username}" != "" ]; then
 	sudo docker login -u ${rancher_registry_username} -p ${rancher_registry_password} ${rancher_registry}
 fi
 
+# Run the KMS plugin the API server encrypts secrets with, before the API server starts
+if [ "${k8s_kms_plugin_image}" != "" ]; then
+	sudo mkdir -p /var/run/kmsplugin
+	sudo docker run -d --restart=unless-stopped --name kms-plugin -v /var/run/kmsplugin:/var/run/kmsplugin ${k8s_kms_plugin_image} ${k8s_kms_plugin_args}
+fi
+
+# Verify the Rancher manager is reachable before registering this node
+rancher_reachable=false
+for i in $(seq 1 30); do
+	if curl --silent --insecure --max-time 10 --output /dev/null ${rancher_api_url}/ping; then
+		rancher_reachable=true
+		break
+	fi
+	sleep 10
+done
+if [ "$rancher_reachable" != true ]; then
+	echo "Unable to reach the Rancher manager at ${rancher_api_url}, check that this network allows outbound traffic to the port of that URL." | sudo tee /var/log/triton-kubernetes-connectivity.log >&2
+	exit 1
+fi
+
 # Run Rancher agent container
-sudo docker run -d --privileged --restart=unless-stopped --net=host -v /etc/kubernetes:/etc/kubernetes -v /var/run:/var/run ${rancher_agent_image} --server ${rancher_api_url} --token ${rancher_cluster_registration_token} --ca-checksum ${rancher_cluster_ca_checksum} --${rancher_node_role}
\ No newline at end of file
+# Rancher has no CA certificates when TLS is terminated by a proxy with a trusted certificate
+ca_checksum_args=''
+if [ -n "${rancher_cluster_ca_checksum}" ]; then
+	ca_checksum_args='--ca-checksum ${rancher_cluster_ca_checksum}'
+fi
+
+# Reserve resources for Kubernetes and system daemons, pods are only scheduled on what remains
+node_args=''
+if [ -n "${kube_reserved}" ]; then
+	node_args="$node_args --kubelet-arg kube-reserved=${kube_reserved}"
+fi
+if [ -n "${system_reserved}" ]; then
+	node_args="$node_args --kubelet-arg system-reserved=${system_reserved}"
+fi
+
+sudo docker run -d --privileged --restart=unless-stopped --net=host -v /etc/kubernetes:/etc/kubernetes -v /var/run:/var/run ${rancher_agent_image} --server ${rancher_api_url} --token ${rancher_cluster_registration_token} $ca_checksum_args $node_args --${rancher_node_role}
\ No newline at end of file
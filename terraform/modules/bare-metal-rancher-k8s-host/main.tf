@@ -18,6 +18,19 @@ data "template_file" "install_rancher_agent" {
     rancher_registry          = "${var.rancher_registry}"
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
   }
 }
 
@@ -40,3 +53,42 @@ resource "null_resource" "install_rancher_agent" {
       EOF
   }
 }
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    host = "${var.host}"
+  }
+
+  connection {
+    type         = "ssh"
+    user         = "${var.ssh_user}"
+    bastion_host = "${var.bastion_host}"
+    host         = "${var.host}"
+    private_key  = "${file(var.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
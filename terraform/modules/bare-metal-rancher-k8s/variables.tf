@@ -7,11 +7,23 @@ variable "rancher_api_url" {
 }
 
 variable "rancher_access_key" {
-  description = ""
+  default     = ""
+  description = "Read from TF_VAR_rancher_access_key when empty."
 }
 
 variable "rancher_secret_key" {
-  description = ""
+  default     = ""
+  description = "Read from TF_VAR_rancher_secret_key when empty."
+}
+
+variable "rancher_cluster_template_id" {
+  default     = ""
+  description = "The Rancher cluster template the cluster is created from. Empty to create the cluster from the k8s_* variables."
+}
+
+variable "rancher_cluster_template_revision_id" {
+  default     = ""
+  description = "The revision of rancher_cluster_template_id the cluster is created from."
 }
 
 variable k8s_version {
@@ -22,6 +34,31 @@ variable k8s_network_provider {
   default = "flannel"
 }
 
+variable "k8s_network_mtu" {
+  default     = ""
+  description = "The MTU of the pod network. Leave empty for the network provider's default."
+}
+
+variable "k8s_network_backend" {
+  default     = ""
+  description = "The flannel backend, vxlan or host-gw. Leave empty for the default, vxlan."
+}
+
+variable "k8s_nodelocal_dns" {
+  default     = "false"
+  description = "Whether pods resolve names through NodeLocal DNSCache, a DNS cache on every node."
+}
+
+variable "k8s_coredns_min_replicas" {
+  default     = ""
+  description = "The minimum number of CoreDNS replicas. Leave empty for Rancher's default."
+}
+
+variable "k8s_coredns_upstream_nameservers" {
+  default     = ""
+  description = "The comma separated IP addresses of the nameservers CoreDNS forwards to. Leave empty for the nameservers of the nodes."
+}
+
 variable "rancher_registry" {
   default     = ""
   description = "The docker registry to use for Rancher images"
@@ -51,3 +88,43 @@ variable "k8s_registry_password" {
   default     = ""
   description = "The password to use."
 }
+
+variable "k8s_audit_log" {
+  default     = "false"
+  description = "Whether the Kubernetes API server writes an audit log to /var/log/kube-audit on the control nodes."
+}
+
+variable "k8s_audit_policy" {
+  default     = ""
+  description = "The base64 encoded audit policy, written to the control nodes."
+}
+
+variable "k8s_audit_log_max_age" {
+  default     = "30"
+  description = "The number of days to keep audit log files."
+}
+
+variable "k8s_audit_log_max_backups" {
+  default     = "10"
+  description = "The number of audit log files to keep."
+}
+
+variable "k8s_audit_log_max_size" {
+  default     = "100"
+  description = "The size in megabytes of an audit log file before it is rotated."
+}
+
+variable "k8s_secrets_encryption" {
+  default     = ""
+  description = "The provider the Kubernetes API server encrypts secrets in etcd with, aescbc, secretbox or kms. Empty to store secrets unencrypted."
+}
+
+variable "k8s_kms_plugin_image" {
+  default     = ""
+  description = "The image of the KMS plugin run on the control nodes, when secrets are encrypted with kms."
+}
+
+variable "k8s_kms_plugin_args" {
+  default     = ""
+  description = "The arguments of the KMS plugin, e.g. the key of the cloud KMS to encrypt with."
+}
@@ -3,6 +3,11 @@ locals {
   rancher_master_ip = "${var.host}"
   ssh_user          = "${var.ssh_user}"
   key_path          = "${var.key_path}"
+
+  # Rancher as seen from the master, and from everything else
+  rancher_local_url  = "${var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_direct_url = "https://${local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
 }
 
 data "template_file" "install_docker" {
@@ -46,6 +51,12 @@ data "template_file" "install_rancher_master" {
     rancher_registry          = "${var.rancher_registry}"
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
   }
 }
 
@@ -76,8 +87,8 @@ data "template_file" "setup_rancher_k8s" {
 
   vars {
     name                  = "${var.name}"
-    rancher_host          = "https://127.0.0.1"
-    host_registration_url = "https://${local.rancher_master_ip}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
 
     rancher_admin_password = "${var.rancher_admin_password}"
   }
@@ -36,6 +36,26 @@ variable "rancher_registry_password" {
   description = "The password to use."
 }
 
+variable "rancher_external_url" {
+  default     = ""
+  description = "URL of Rancher through an existing load balancer or reverse proxy, e.g. https://rancher.example.com:8443. Nodes register with it. Defaults to the master's IP address on rancher_https_port."
+}
+
+variable "rancher_https_port" {
+  default     = "443"
+  description = "The port the master serves Rancher on over HTTPS."
+}
+
+variable "rancher_http_port" {
+  default     = "80"
+  description = "The port the master serves Rancher on over HTTP."
+}
+
+variable "rancher_tls_termination" {
+  default     = "rancher"
+  description = "Where TLS is terminated, rancher or proxy. With proxy, the proxy forwards HTTP requests to rancher_http_port with the X-Forwarded-Proto header."
+}
+
 variable "ssh_user" {
   default     = "ubuntu"
   description = ""
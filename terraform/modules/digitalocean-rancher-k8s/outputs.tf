@@ -0,0 +1,27 @@
+output "rancher_cluster_id" {
+  value = "${data.external.rancher_cluster.result.cluster_id}"
+}
+
+output "rancher_cluster_registration_token" {
+  value = "${data.external.rancher_cluster.result.registration_token}"
+}
+
+output "rancher_cluster_ca_checksum" {
+  value = "${data.external.rancher_cluster.result.ca_checksum}"
+}
+
+output "digitalocean_node_tag" {
+  value = "${digitalocean_tag.nodes.name}"
+}
+
+output "k8s_audit_policy" {
+  value = "${var.k8s_audit_policy}"
+}
+
+output "k8s_kms_plugin_image" {
+  value = "${var.k8s_kms_plugin_image}"
+}
+
+output "k8s_kms_plugin_args" {
+  value = "${var.k8s_kms_plugin_args}"
+}
@@ -0,0 +1,331 @@
+provider "digitalocean" {
+  token = "${var.digitalocean_api_token}"
+}
+
+# Firewall requirements taken from:
+# https://rancher.com/docs/rancher/v2.0/en/quick-start-guide/
+resource "digitalocean_firewall" "rancher_master_ports" {
+  name        = "${var.name}-rancher-master-ports"
+  droplet_ids = ["${digitalocean_droplet.rancher_master.*.id}"]
+
+  inbound_rule {
+    protocol         = "tcp"
+    port_range       = "22"
+    source_addresses = ["0.0.0.0/0", "::/0"]
+  }
+
+  inbound_rule {
+    protocol         = "tcp"
+    port_range       = "${var.rancher_http_port}"
+    source_addresses = ["0.0.0.0/0", "::/0"]
+  }
+
+  inbound_rule {
+    protocol         = "tcp"
+    port_range       = "${var.rancher_https_port}"
+    source_addresses = ["0.0.0.0/0", "::/0"]
+  }
+
+  outbound_rule {
+    protocol              = "tcp"
+    port_range            = "1-65535"
+    destination_addresses = ["0.0.0.0/0", "::/0"]
+  }
+
+  outbound_rule {
+    protocol              = "udp"
+    port_range            = "1-65535"
+    destination_addresses = ["0.0.0.0/0", "::/0"]
+  }
+}
+
+# The hosts of an HA manager reach each other over the private network, and its ingress
+# controller is reached through the load balancer
+resource "digitalocean_firewall" "rancher_ha" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name        = "${var.name}-rancher-ha"
+  droplet_ids = ["${digitalocean_droplet.rancher_master.*.id}"]
+
+  inbound_rule {
+    protocol           = "tcp"
+    port_range         = "1-65535"
+    source_droplet_ids = ["${digitalocean_droplet.rancher_master.*.id}"]
+  }
+
+  inbound_rule {
+    protocol           = "udp"
+    port_range         = "1-65535"
+    source_droplet_ids = ["${digitalocean_droplet.rancher_master.*.id}"]
+  }
+
+  inbound_rule {
+    protocol                  = "tcp"
+    port_range                = "80"
+    source_load_balancer_uids = ["${digitalocean_loadbalancer.rancher.id}"]
+  }
+
+  inbound_rule {
+    protocol                  = "tcp"
+    port_range                = "443"
+    source_load_balancer_uids = ["${digitalocean_loadbalancer.rancher.id}"]
+  }
+}
+
+resource "digitalocean_droplet" "rancher_master" {
+  count = "${var.manager_host_count}"
+
+  name               = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}"
+  region             = "${var.digitalocean_region}"
+  size               = "${var.digitalocean_droplet_size}"
+  image              = "${var.digitalocean_image}"
+  ssh_keys           = ["${var.digitalocean_ssh_key_fingerprint}"]
+  private_networking = "${var.manager_host_count > 1}"
+
+  # The hosts of an HA manager run Rancher on k3s rather than docker
+  user_data = "${var.manager_host_count > 1 ? "" : data.template_file.install_docker.rendered}"
+}
+
+locals {
+  # The first host sets up Rancher, and keeps its API key
+  rancher_master_id = "${digitalocean_droplet.rancher_master.0.id}"
+  rancher_master_ip = "${digitalocean_droplet.rancher_master.0.ipv4_address}"
+  ssh_user          = "root"
+  key_path          = "${var.digitalocean_private_key_path}"
+
+  # Rancher as seen from the master, and from everything else. Behind the ingress controller
+  # of an HA manager, only its load balancer reaches Rancher. Its address has no DNS name,
+  # the ingress is for the sslip.io name resolving to it.
+  rancher_lb_host    = "${var.manager_host_count > 1 ? "${element(concat(digitalocean_loadbalancer.rancher.*.ip, list("")), 0)}.sslip.io" : ""}"
+  rancher_direct_url = "https://${var.manager_host_count > 1 ? local.rancher_lb_host : local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_local_url  = "${var.manager_host_count > 1 ? local.rancher_direct_url : var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
+}
+
+data "template_file" "install_docker" {
+  template = "${file("${path.module}/files/install_docker_rancher.sh.tpl")}"
+
+  vars {
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+  }
+}
+
+data "template_file" "install_rancher_master" {
+  template = "${file("${path.module}/files/install_rancher_master.sh.tpl")}"
+
+  vars {
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
+  }
+}
+
+resource "null_resource" "install_rancher_master" {
+  count = "${var.manager_host_count > 1 ? 0 : 1}"
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_master.rendered}
+      EOF
+  }
+}
+
+# With manager_host_count 3, Rancher follows its high availability reference: the hosts form a
+# k3s cluster sharing its embedded etcd, Rancher runs a replica on each of them, and a load
+# balancer in front of their ingress controller is where Rancher is reached.
+resource "digitalocean_loadbalancer" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name        = "${var.name}-rancher"
+  region      = "${var.digitalocean_region}"
+  droplet_ids = ["${digitalocean_droplet.rancher_master.*.id}"]
+
+  forwarding_rule {
+    entry_port      = "${var.rancher_http_port}"
+    entry_protocol  = "tcp"
+    target_port     = 80
+    target_protocol = "tcp"
+  }
+
+  forwarding_rule {
+    entry_port      = "${var.rancher_https_port}"
+    entry_protocol  = "tcp"
+    target_port     = 443
+    target_protocol = "tcp"
+  }
+
+  healthcheck {
+    port     = 443
+    protocol = "tcp"
+  }
+}
+
+data "template_file" "install_k3s" {
+  count    = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+  template = "${file("${path.module}/files/install_k3s.sh.tpl")}"
+
+  vars {
+    k3s_version = "${var.k3s_version}"
+
+    # The first host starts the etcd cluster the others join
+    k3s_server_args = "${count.index == 0 ? "--cluster-init" : "--server https://${digitalocean_droplet.rancher_master.0.ipv4_address_private}:6443"}"
+  }
+}
+
+resource "null_resource" "install_k3s_first" {
+  count      = "${var.manager_host_count > 1 ? 1 : 0}"
+  depends_on = ["digitalocean_firewall.rancher_ha"]
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${data.template_file.install_k3s.0.rendered}
+      EOF
+  }
+}
+
+resource "null_resource" "install_k3s_others" {
+  count      = "${var.manager_host_count > 1 ? var.manager_host_count - 1 : 0}"
+  depends_on = ["null_resource.install_k3s_first"]
+
+  triggers {
+    host_id = "${element(digitalocean_droplet.rancher_master.*.id, count.index + 1)}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${element(digitalocean_droplet.rancher_master.*.ipv4_address, count.index + 1)}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${element(data.template_file.install_k3s.*.rendered, count.index + 1)}
+      EOF
+  }
+}
+
+data "template_file" "install_rancher_ha" {
+  template = "${file("${path.module}/files/install_rancher_ha.sh.tpl")}"
+
+  vars {
+    manager_host_count    = "${var.manager_host_count}"
+    rancher_hostname      = "${local.rancher_lb_host}"
+    rancher_chart_version = "${var.rancher_chart_version}"
+    cert_manager_version  = "${var.cert_manager_version}"
+  }
+}
+
+resource "null_resource" "install_rancher_ha" {
+  count      = "${var.manager_host_count > 1 ? 1 : 0}"
+  depends_on = ["null_resource.install_k3s_others"]
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_ha.rendered}
+      EOF
+  }
+}
+
+data "template_file" "setup_rancher_k8s" {
+  template = "${file("${path.module}/files/setup_rancher.sh.tpl")}"
+
+  vars {
+    name                  = "${var.name}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
+
+    rancher_admin_password = "${var.rancher_admin_password}"
+  }
+}
+
+resource "null_resource" "setup_rancher_k8s" {
+  depends_on = ["null_resource.install_rancher_master", "null_resource.install_rancher_ha"]
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.setup_rancher_k8s.rendered}
+      EOF
+  }
+}
+
+// The setup_rancher_k8s script will have stored a file with an api key
+// We need to retrieve the contents of that file and output it.
+// This is a hack to get around the Terraform Rancher provider not having resources for api keys.
+module "rancher_access_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .name'"
+}
+
+module "rancher_secret_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .token | cut -d: -f2'"
+}
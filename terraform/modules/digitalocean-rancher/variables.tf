@@ -0,0 +1,109 @@
+variable "name" {
+  description = "Human readable name used as prefix to generated names."
+}
+
+variable "rancher_admin_password" {
+  description = "The Rancher admin password"
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "rancher_server_image" {
+  default     = "rancher/server:v2.0.0-beta2"
+  description = "The Rancher Server image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher server and agent images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "rancher_external_url" {
+  default     = ""
+  description = "URL of Rancher through an existing load balancer or reverse proxy, e.g. https://rancher.example.com:8443. Nodes register with it. Defaults to the master's IP address on rancher_https_port."
+}
+
+variable "rancher_https_port" {
+  default     = "443"
+  description = "The port the master serves Rancher on over HTTPS."
+}
+
+variable "rancher_http_port" {
+  default     = "80"
+  description = "The port the master serves Rancher on over HTTP."
+}
+
+variable "rancher_tls_termination" {
+  default     = "rancher"
+  description = "Where TLS is terminated, rancher or proxy. With proxy, the proxy forwards HTTP requests to rancher_http_port with the X-Forwarded-Proto header."
+}
+
+variable "manager_host_count" {
+  default     = "1"
+  description = "The number of hosts running Rancher, 1 or 3. With 3, Rancher runs on a k3s cluster whose hosts share its etcd, behind a load balancer nodes register through."
+}
+
+variable "manager_ha_token" {
+  default     = ""
+  description = "The secret the hosts join the k3s cluster with when manager_host_count is 3."
+}
+
+variable "k3s_version" {
+  default     = "v1.20.6+k3s1"
+  description = "The k3s version the hosts run when manager_host_count is 3."
+}
+
+variable "rancher_chart_version" {
+  default     = "2.5.8"
+  description = "The version of the Rancher Helm chart installed when manager_host_count is 3, rancher_server_image is only used by a single host."
+}
+
+variable "cert_manager_version" {
+  default     = "v1.0.4"
+  description = "The version of cert-manager issuing Rancher's certificate when manager_host_count is 3."
+}
+
+variable "digitalocean_api_token" {
+  description = "The DigitalOcean API token."
+}
+
+variable "digitalocean_region" {
+  description = "The DigitalOcean region to create the droplet in, e.g. nyc3."
+}
+
+variable "digitalocean_droplet_size" {
+  default     = "s-2vcpu-4gb"
+  description = "The size of the droplet."
+}
+
+variable "digitalocean_image" {
+  default     = "ubuntu-16-04-x64"
+  description = "The slug of the droplet's image."
+}
+
+variable "digitalocean_ssh_key_fingerprint" {
+  description = "The fingerprint of an SSH key of the DigitalOcean account, the droplet's root user logs in with it."
+}
+
+variable "digitalocean_private_key_path" {
+  description = "Path to the private key of the SSH key."
+  default     = "~/.ssh/id_rsa"
+}
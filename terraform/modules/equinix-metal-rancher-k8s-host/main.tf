@@ -0,0 +1,89 @@
+provider "packet" {
+  auth_token = "${var.equinix_metal_api_token}"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    hostname                  = "${var.hostname}"
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+  }
+}
+
+# The SSH keys of the project and of its members are added to the device's root user
+resource "packet_device" "host" {
+  hostname         = "${var.hostname}"
+  project_id       = "${var.equinix_metal_project_id}"
+  metro            = "${var.equinix_metal_metro}"
+  plan             = "${var.equinix_metal_plan}"
+  operating_system = "${var.equinix_metal_operating_system}"
+  billing_cycle    = "hourly"
+
+  user_data = "${data.template_file.install_rancher_agent.rendered}"
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    device_id = "${packet_device.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "root"
+    host        = "${packet_device.host.access_public_ipv4}"
+    private_key = "${file(var.equinix_metal_private_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
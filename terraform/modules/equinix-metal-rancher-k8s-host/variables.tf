@@ -0,0 +1,117 @@
+variable "hostname" {
+  description = ""
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_cluster_registration_token" {}
+
+variable "rancher_cluster_ca_checksum" {}
+
+variable "rancher_host_labels" {
+  type        = "map"
+  description = "A map of key/value pairs that get passed to the rancher agent on the host."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "k8s_audit_policy" {
+  default     = ""
+  description = "The base64 encoded Kubernetes API server audit policy, written to control nodes of clusters with an audit log."
+}
+
+variable "k8s_secrets_encryption_config" {
+  default     = ""
+  description = "The base64 encoded encryption config of the Kubernetes API server, copied over SSH to control nodes of clusters encrypting secrets at rest."
+}
+
+variable "k8s_kms_plugin_image" {
+  default     = ""
+  description = "The image of the KMS plugin run on control nodes of clusters encrypting secrets with kms."
+}
+
+variable "k8s_kms_plugin_args" {
+  default     = ""
+  description = "The arguments of the KMS plugin."
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "equinix_metal_api_token" {
+  description = "The Equinix Metal API token."
+}
+
+variable "equinix_metal_project_id" {
+  description = "The ID of the Equinix Metal project to create the device in."
+}
+
+variable "equinix_metal_metro" {
+  description = "The Equinix Metal metro to create the device in, e.g. da."
+}
+
+variable "equinix_metal_plan" {
+  default     = "c3.small.x86"
+  description = "The plan of the device."
+}
+
+variable "equinix_metal_operating_system" {
+  default     = "ubuntu_20_04"
+  description = "The slug of the device's operating system."
+}
+
+variable "equinix_metal_private_key_path" {
+  default     = ""
+  description = "The path to a private key authorized on the project, used to copy the encryption config of the Kubernetes API server to control nodes."
+}
@@ -0,0 +1,31 @@
+data "external" "rancher_cluster" {
+  program = ["bash", "${path.module}/files/rancher_cluster.sh"]
+
+  query = {
+    rancher_api_url       = "${var.rancher_api_url}"
+    rancher_access_key    = "${var.rancher_access_key}"
+    rancher_secret_key    = "${var.rancher_secret_key}"
+    name                  = "${var.name}"
+    k8s_version           = "${var.k8s_version}"
+    k8s_network_provider  = "${var.k8s_network_provider}"
+    k8s_network_mtu       = "${var.k8s_network_mtu}"
+    k8s_network_backend   = "${var.k8s_network_backend}"
+    k8s_registry          = "${var.k8s_registry}"
+    k8s_registry_username = "${var.k8s_registry_username}"
+    k8s_registry_password = "${var.k8s_registry_password}"
+
+    k8s_nodelocal_dns                = "${var.k8s_nodelocal_dns}"
+    k8s_coredns_min_replicas         = "${var.k8s_coredns_min_replicas}"
+    k8s_coredns_upstream_nameservers = "${var.k8s_coredns_upstream_nameservers}"
+
+    k8s_audit_log             = "${var.k8s_audit_log}"
+    k8s_audit_log_max_age     = "${var.k8s_audit_log_max_age}"
+    k8s_audit_log_max_backups = "${var.k8s_audit_log_max_backups}"
+    k8s_audit_log_max_size    = "${var.k8s_audit_log_max_size}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption}"
+
+    rancher_cluster_template_id          = "${var.rancher_cluster_template_id}"
+    rancher_cluster_template_revision_id = "${var.rancher_cluster_template_revision_id}"
+  }
+}
@@ -0,0 +1,84 @@
+variable "name" {
+  description = "Human readable name used as prefix to generated names."
+}
+
+variable "rancher_admin_password" {
+  description = "The Rancher admin password"
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "rancher_server_image" {
+  default     = "rancher/server:v2.0.0-beta2"
+  description = "The Rancher Server image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher server and agent images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "rancher_external_url" {
+  default     = ""
+  description = "URL of Rancher through an existing load balancer or reverse proxy, e.g. https://rancher.example.com:8443. Nodes register with it. Defaults to the master's IP address on rancher_https_port."
+}
+
+variable "rancher_https_port" {
+  default     = "443"
+  description = "The port the master serves Rancher on over HTTPS."
+}
+
+variable "rancher_http_port" {
+  default     = "80"
+  description = "The port the master serves Rancher on over HTTP."
+}
+
+variable "rancher_tls_termination" {
+  default     = "rancher"
+  description = "Where TLS is terminated, rancher or proxy. With proxy, the proxy forwards HTTP requests to rancher_http_port with the X-Forwarded-Proto header."
+}
+
+variable "equinix_metal_api_token" {
+  description = "The Equinix Metal API token."
+}
+
+variable "equinix_metal_project_id" {
+  description = "The ID of the Equinix Metal project to create the device in."
+}
+
+variable "equinix_metal_metro" {
+  description = "The Equinix Metal metro to create the device in, e.g. da."
+}
+
+variable "equinix_metal_plan" {
+  default     = "c3.small.x86"
+  description = "The plan of the device."
+}
+
+variable "equinix_metal_operating_system" {
+  default     = "ubuntu_20_04"
+  description = "The slug of the device's operating system."
+}
+
+variable "equinix_metal_private_key_path" {
+  description = "Path to the private key of an SSH key of the project, the device's root user logs in with it."
+  default     = "~/.ssh/id_rsa"
+}
@@ -25,6 +25,19 @@ data "template_file" "install_rancher_agent" {
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
 
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+
     disk_mount_path = "${var.gcp_disk_mount_path}"
   }
 }
@@ -60,6 +73,12 @@ resource "google_compute_instance" "host" {
   service_account {
     scopes = ["https://www.googleapis.com/auth/cloud-platform"]
   }
+
+  # Only control nodes of clusters encrypting secrets at rest are logged into
+  metadata {
+    sshKeys = "${var.gcp_public_key == "" ? "" : "${var.gcp_ssh_user}:${var.gcp_public_key}"}"
+  }
+
   metadata_startup_script = "${data.template_file.install_rancher_agent.rendered}"
 }
 
@@ -71,3 +90,41 @@ resource "google_compute_disk" "host_volume" {
   zone = "${var.gcp_instance_zone}"
   size = "${var.gcp_disk_size}"
 }
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    instance_id = "${google_compute_instance.host.instance_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.gcp_ssh_user}"
+    host        = "${google_compute_instance.host.network_interface.0.access_config.0.assigned_nat_ip}"
+    private_key = "${file(var.gcp_private_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
@@ -35,6 +35,55 @@ variable "rancher_registry_password" {
   description = "The password to use."
 }
 
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "k8s_audit_policy" {
+  default     = ""
+  description = "The base64 encoded Kubernetes API server audit policy, written to control nodes of clusters with an audit log."
+}
+
+variable "k8s_secrets_encryption_config" {
+  default     = ""
+  description = "The base64 encoded encryption config of the Kubernetes API server, copied over SSH to control nodes of clusters encrypting secrets at rest."
+}
+
+variable "k8s_kms_plugin_image" {
+  default     = ""
+  description = "The image of the KMS plugin run on control nodes of clusters encrypting secrets with kms."
+}
+
+variable "k8s_kms_plugin_args" {
+  default     = ""
+  description = "The arguments of the KMS plugin."
+}
+
 variable "docker_engine_install_url" {
   default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
   description = "The URL to the shell script to install the docker engine."
@@ -88,3 +137,18 @@ variable "gcp_disk_mount_path" {
   default     = ""
   description = "The mount path"
 }
+
+variable "gcp_ssh_user" {
+  default     = "ubuntu"
+  description = "The SSH user the encryption config of the Kubernetes API server is copied to control nodes with."
+}
+
+variable "gcp_public_key" {
+  default     = ""
+  description = "The public key authorized for gcp_ssh_user on control nodes of clusters encrypting secrets at rest."
+}
+
+variable "gcp_private_key_path" {
+  default     = ""
+  description = "The path to the private key of gcp_public_key, used to copy the encryption config of the Kubernetes API server to control nodes."
+}
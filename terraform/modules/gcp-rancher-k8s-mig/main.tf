@@ -0,0 +1,153 @@
+provider "google" {
+  credentials = "${file("${var.gcp_path_to_credentials}")}"
+  project     = "${var.gcp_project_id}"
+  region      = "${var.gcp_compute_region}"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+  }
+}
+
+resource "google_compute_instance_template" "pool" {
+  name_prefix  = "${var.hostname}-"
+  machine_type = "${var.gcp_machine_type}"
+  project      = "${var.gcp_project_id}"
+
+  tags = ["${var.gcp_compute_firewall_host_tag}"]
+
+  disk {
+    source_image = "${var.gcp_image}"
+    auto_delete  = true
+    boot         = true
+  }
+
+  network_interface {
+    network = "${var.gcp_compute_network_name}"
+
+    access_config {
+      // Ephemeral IP
+    }
+  }
+
+  service_account {
+    scopes = ["https://www.googleapis.com/auth/cloud-platform"]
+  }
+
+  metadata_startup_script = "${data.template_file.install_rancher_agent.rendered}"
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+resource "google_compute_health_check" "pool" {
+  name    = "${var.hostname}"
+  project = "${var.gcp_project_id}"
+
+  check_interval_sec  = 10
+  timeout_sec         = 5
+  healthy_threshold   = 2
+  unhealthy_threshold = 3
+
+  tcp_health_check {
+    port = "${var.gcp_health_check_port}"
+  }
+}
+
+# Health checks are sent from Google's probers, which the cluster's firewall doesn't allow
+resource "google_compute_firewall" "health_check" {
+  name          = "${var.hostname}-health-check"
+  network       = "${var.gcp_compute_network_name}"
+  project       = "${var.gcp_project_id}"
+  source_ranges = ["35.191.0.0/16", "130.211.0.0/22"]
+  target_tags   = ["${var.gcp_compute_firewall_host_tag}"]
+
+  allow {
+    protocol = "tcp"
+    ports    = ["${var.gcp_health_check_port}"]
+  }
+}
+
+# The target size must not be set when an autoscaler sets it, so only one of these exists
+resource "google_compute_instance_group_manager" "pool" {
+  count = "${var.gcp_autoscaling ? 0 : 1}"
+
+  name               = "${var.hostname}"
+  base_instance_name = "${var.hostname}"
+  instance_template  = "${google_compute_instance_template.pool.self_link}"
+  zone               = "${var.gcp_instance_zone}"
+  project            = "${var.gcp_project_id}"
+
+  # The template changes when the pool's registration token is replaced, running instances are
+  # already registered
+  update_strategy = "NONE"
+  target_size        = "${var.gcp_mig_target_size}"
+
+  auto_healing_policies {
+    health_check      = "${google_compute_health_check.pool.self_link}"
+    initial_delay_sec = "${var.gcp_health_check_initial_delay}"
+  }
+}
+
+resource "google_compute_instance_group_manager" "autoscaled_pool" {
+  count = "${var.gcp_autoscaling ? 1 : 0}"
+
+  name               = "${var.hostname}"
+  base_instance_name = "${var.hostname}"
+  instance_template  = "${google_compute_instance_template.pool.self_link}"
+  zone               = "${var.gcp_instance_zone}"
+  project            = "${var.gcp_project_id}"
+
+  # The template changes when the pool's registration token is replaced, running instances are
+  # already registered
+  update_strategy = "NONE"
+
+  auto_healing_policies {
+    health_check      = "${google_compute_health_check.pool.self_link}"
+    initial_delay_sec = "${var.gcp_health_check_initial_delay}"
+  }
+}
+
+resource "google_compute_autoscaler" "pool" {
+  count = "${var.gcp_autoscaling ? 1 : 0}"
+
+  name    = "${var.hostname}"
+  zone    = "${var.gcp_instance_zone}"
+  project = "${var.gcp_project_id}"
+  target  = "${google_compute_instance_group_manager.autoscaled_pool.self_link}"
+
+  autoscaling_policy {
+    min_replicas    = "${var.gcp_autoscaler_min_replicas}"
+    max_replicas    = "${var.gcp_autoscaler_max_replicas}"
+    cooldown_period = "${var.gcp_autoscaler_cooldown_period}"
+
+    cpu_utilization {
+      target = "${var.gcp_autoscaler_cpu_target}"
+    }
+  }
+}
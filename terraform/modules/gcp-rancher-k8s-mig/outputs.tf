@@ -0,0 +1,3 @@
+output "gcp_instance_group_manager_name" {
+  value = "${var.hostname}"
+}
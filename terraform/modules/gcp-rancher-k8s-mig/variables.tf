@@ -0,0 +1,143 @@
+variable "hostname" {
+  description = "Base instance name, GCP names each instance {hostname}-{4 random characters}."
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_cluster_registration_token" {}
+
+variable "rancher_cluster_ca_checksum" {}
+
+variable "rancher_host_labels" {
+  type        = "map"
+  description = "A map of key/value pairs that get passed to the rancher agent on the host."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "gcp_path_to_credentials" {
+  description = "Location of GCP JSON credentials file."
+}
+
+variable "gcp_compute_region" {
+  description = "GCP region to host your network"
+}
+
+variable "gcp_project_id" {
+  description = "GCP project ID that will be running the instances and managing the network"
+}
+
+variable gcp_machine_type {
+  default     = "n1-standard-1"
+  description = "GCP machine type to launch the instance with"
+}
+
+variable "gcp_instance_zone" {
+  description = "Zone to deploy GCP machine in"
+}
+
+variable "gcp_image" {
+  description = "GCP image to be used for instance"
+  default     = "ubuntu-1604-xenial-v20171121a"
+}
+
+variable "gcp_compute_network_name" {
+  description = "Network to deploy GCP machine in"
+}
+
+variable "gcp_compute_firewall_host_tag" {
+  description = "Tag that should be applied to nodes so the firewall source rules can be applied"
+}
+
+variable "gcp_mig_target_size" {
+  description = "Number of instances in the managed instance group, ignored when autoscaling."
+}
+
+variable "gcp_autoscaling" {
+  default     = false
+  description = "Whether the size of the managed instance group is set by an autoscaler."
+}
+
+variable "gcp_autoscaler_min_replicas" {
+  default     = 1
+  description = "Minimum number of instances the autoscaler keeps."
+}
+
+variable "gcp_autoscaler_max_replicas" {
+  default     = 1
+  description = "Maximum number of instances the autoscaler creates."
+}
+
+variable "gcp_autoscaler_cpu_target" {
+  default     = 0.6
+  description = "Average CPU utilization of the instances the autoscaler maintains."
+}
+
+variable "gcp_autoscaler_cooldown_period" {
+  default     = 60
+  description = "Seconds the autoscaler waits before collecting information from a new instance."
+}
+
+variable "gcp_health_check_port" {
+  default     = 10250
+  description = "TCP port checked to determine if an instance is healthy, defaults to the kubelet API."
+}
+
+variable "gcp_health_check_initial_delay" {
+  default     = 600
+  description = "Seconds a new instance has to install docker and join the cluster before it is health checked."
+}
@@ -10,7 +10,12 @@ set -e
 # Extract arguments from the input into shell variables.
 # jq will ensure that the values are properly quoted
 # and escaped for consumption by the shell.
-eval "$(jq -r '@sh "rancher_api_url=\(.rancher_api_url) rancher_access_key=\(.rancher_access_key) rancher_secret_key=\(.rancher_secret_key) name=\(.name) k8s_version=\(.k8s_version) k8s_network_provider=\(.k8s_network_provider) k8s_registry=\(.k8s_registry) k8s_registry_username=\(.k8s_registry_username) k8s_registry_password=\(.k8s_registry_password)"')"
+eval "$(jq -r '@sh "rancher_api_url=\(.rancher_api_url) rancher_access_key=\(.rancher_access_key) rancher_secret_key=\(.rancher_secret_key) name=\(.name) k8s_version=\(.k8s_version) k8s_network_provider=\(.k8s_network_provider) k8s_network_mtu=\(.k8s_network_mtu) k8s_network_backend=\(.k8s_network_backend) k8s_registry=\(.k8s_registry) k8s_registry_username=\(.k8s_registry_username) k8s_registry_password=\(.k8s_registry_password) k8s_nodelocal_dns=\(.k8s_nodelocal_dns) k8s_coredns_min_replicas=\(.k8s_coredns_min_replicas) k8s_coredns_upstream_nameservers=\(.k8s_coredns_upstream_nameservers) k8s_audit_log=\(.k8s_audit_log) k8s_audit_log_max_age=\(.k8s_audit_log_max_age) k8s_audit_log_max_backups=\(.k8s_audit_log_max_backups) k8s_audit_log_max_size=\(.k8s_audit_log_max_size) k8s_secrets_encryption=\(.k8s_secrets_encryption) rancher_cluster_template_id=\(.rancher_cluster_template_id) rancher_cluster_template_revision_id=\(.rancher_cluster_template_revision_id)"')"
+
+# Rotated Rancher API tokens are kept out of the terraform state, terraform runs with them in
+# its environment instead of passing them to the module
+rancher_access_key=${rancher_access_key:-$TF_VAR_rancher_access_key}
+rancher_secret_key=${rancher_secret_key:-$TF_VAR_rancher_secret_key}
 
 cluster_id=''
 cluster_already_existed=false
@@ -30,6 +35,68 @@ else
 		k8s_registry_json=',"privateRegistries":[{"url":"'$k8s_registry'","user":"'$k8s_registry_username'","password":"'$k8s_registry_password'"}]'
 	fi
 
+	# Overlays need an MTU below the MTU of the nodes' interfaces
+	k8s_network_json=''
+	if [ "$k8s_network_mtu" != "" ]; then
+		k8s_network_json=',"mtu":'$k8s_network_mtu
+	fi
+	if [ "$k8s_network_backend" != "" ]; then
+		k8s_network_json=$k8s_network_json',"options":{"flannel_backend_type":"'$k8s_network_backend'"}'
+	fi
+
+	# NodeLocal DNSCache listens on a link-local address on every node. The CoreDNS autoscaler
+	# needs all of its parameters, the others are Rancher's defaults.
+	k8s_dns_json=''
+	if [ "$k8s_nodelocal_dns" == "true" ]; then
+		k8s_dns_json=',"nodelocal":{"ipAddress":"169.254.20.10"}'
+	fi
+	if [ "$k8s_coredns_min_replicas" != "" ]; then
+		k8s_dns_json=$k8s_dns_json',"linearAutoscalerParams":{"min":'$k8s_coredns_min_replicas',"coresPerReplica":128,"nodesPerReplica":4,"preventSinglePointFailure":true}'
+	fi
+	if [ "$k8s_coredns_upstream_nameservers" != "" ]; then
+		k8s_dns_json=$k8s_dns_json',"upstreamnameservers":'$(echo "$k8s_coredns_upstream_nameservers" | jq -R -c 'split(",")')
+	fi
+	if [ "$k8s_dns_json" != "" ]; then
+		k8s_dns_json=',"dns":{"type":"dnsConfig","provider":"coredns"'$k8s_dns_json'}'
+	fi
+
+	# The audit policy is written to /etc/kubernetes/audit-policy.yaml by the control nodes,
+	# /etc/kubernetes is mounted into the API server container
+	k8s_api_extra_args=''
+	k8s_api_extra_binds=''
+	if [ "$k8s_audit_log" == "true" ]; then
+		k8s_api_extra_args=',"audit-policy-file":"/etc/kubernetes/audit-policy.yaml","audit-log-path":"/var/log/kube-audit/audit.log","audit-log-format":"json","audit-log-maxage":"'$k8s_audit_log_max_age'","audit-log-maxbackup":"'$k8s_audit_log_max_backups'","audit-log-maxsize":"'$k8s_audit_log_max_size'"'
+		k8s_api_extra_binds=',"/var/log/kube-audit:/var/log/kube-audit"'
+	fi
+
+	# The encryption config is written to /etc/kubernetes/encryption-config.yaml by the control nodes,
+	# the KMS plugin listens on a socket in /var/run/kmsplugin
+	if [ "$k8s_secrets_encryption" != "" ]; then
+		k8s_encryption_provider_config_arg='encryption-provider-config'
+		if [[ "$k8s_version" =~ ^v1\.([0-9]|1[0-2])\. ]]; then
+			k8s_encryption_provider_config_arg='experimental-encryption-provider-config'
+		fi
+		k8s_api_extra_args=$k8s_api_extra_args',"'$k8s_encryption_provider_config_arg'":"/etc/kubernetes/encryption-config.yaml"'
+		if [ "$k8s_secrets_encryption" == "kms" ]; then
+			k8s_api_extra_binds=$k8s_api_extra_binds',"/var/run/kmsplugin:/var/run/kmsplugin"'
+		fi
+	fi
+
+	k8s_api_json=''
+	if [ "$k8s_api_extra_args" != "" ]; then
+		k8s_api_json=',"extraArgs":{'${k8s_api_extra_args#,}'}'
+	fi
+	if [ "$k8s_api_extra_binds" != "" ]; then
+		k8s_api_json=$k8s_api_json',"extraBinds":['${k8s_api_extra_binds#,}']'
+	fi
+
+	cluster_json='{"type":"cluster","googleKubernetesEngineConfig":null,"name":"'$name'","rancherKubernetesEngineConfig":{"ignoreDockerVersion":false,"sshAgentAuth":false,"type":"rancherKubernetesEngineConfig","kubernetesVersion":"'$k8s_version'","authentication":{"type":"authnConfig","strategy":"x509"},"network":{"type":"networkConfig","plugin":"'$k8s_network_provider'"'$k8s_network_json'}'$k8s_dns_json',"services":{"type":"rkeConfigServices","kubeApi":{"podSecurityPolicy":false,"type":"kubeAPIService"'$k8s_api_json'}}'$k8s_registry_json'},"id":""}'
+
+	# Clusters created from a cluster template get their config from its revision
+	if [ "$rancher_cluster_template_revision_id" != "" ]; then
+		cluster_json='{"type":"cluster","name":"'$name'","clusterTemplateId":"'$rancher_cluster_template_id'","clusterTemplateRevisionId":"'$rancher_cluster_template_revision_id'"}'
+	fi
+
 	# Create cluster
 	cluster_response=$(curl -X POST \
 		--silent \
@@ -37,7 +104,7 @@ else
 		-u $rancher_access_key:$rancher_secret_key \
 		-H 'Accept: application/json' \
 		-H 'Content-Type: application/json' \
-		-d '{"type":"cluster","googleKubernetesEngineConfig":null,"name":"'$name'","rancherKubernetesEngineConfig":{"ignoreDockerVersion":false,"sshAgentAuth":false,"type":"rancherKubernetesEngineConfig","kubernetesVersion":"'$k8s_version'","authentication":{"type":"authnConfig","strategy":"x509"},"network":{"type":"networkConfig","plugin":"'$k8s_network_provider'"},"services":{"type":"rkeConfigServices","kubeApi":{"podSecurityPolicy":false,"type":"kubeAPIService"}}'$k8s_registry_json'},"id":""}' \
+		-d "$cluster_json" \
 		"$rancher_api_url/v3/cluster")
 	cluster_id=$(echo $cluster_response | jq -r '.id')
 fi
@@ -47,7 +114,8 @@ if [ "$cluster_id" == "" ] || [ "$cluster_id" == "null" ]; then
 	exit 1
 fi
 
-# Cluster registration token
+# Cluster registration token. The tokens triton-kubernetes issues to the nodes it adds are
+# labeled, they're deleted once the nodes are active.
 registration_token='';
 if [ "$cluster_already_existed" == true ]; then
 	# Get existing registration token
@@ -58,9 +126,11 @@ if [ "$cluster_already_existed" == true ]; then
 		-H 'Accept: application/json' \
 		"$rancher_api_url/v3/clusters/$cluster_id/clusterregistrationtokens")
 
-	registration_token=$(echo $get_registration_token_response | jq -r '.data[0].token')
-else
-	# Create cluster registration token
+	registration_token=$(echo $get_registration_token_response | jq -r '[.data[] | select(.labels["triton-kubernetes.joyent.com/nodes"] == null)][0].token')
+fi
+if [ "$registration_token" == "" ] || [ "$registration_token" == "null" ]; then
+	# Create cluster registration token, the previous one is deleted once the nodes created
+	# with the cluster are active
 	create_registration_token_response=$(curl -X POST \
 		--silent \
 		--insecure \
@@ -85,7 +155,11 @@ cacerts_response=$(curl -X GET \
 	-u $rancher_access_key:$rancher_secret_key \
 	-H 'Accept: application/json' \
 	"$rancher_api_url/v3/settings/cacerts")
-ca_checksum=$(echo $cacerts_response | jq -r .value | shasum -a 256 | awk '{ print $1 }')
+# Rancher has no CA certificates when TLS is terminated by a proxy
+ca_checksum=''
+if [ "$(echo $cacerts_response | jq -r '.value // ""')" != "" ]; then
+	ca_checksum=$(echo $cacerts_response | jq -r .value | shasum -a 256 | awk '{ print $1 }')
+fi
 
 # Safely produce a JSON object containing the result value.
 # jq will ensure that the value is properly quoted
@@ -17,3 +17,15 @@ output "gcp_compute_network_name" {
 output "gcp_compute_firewall_host_tag" {
   value = "${var.name}-nodes"
 }
+
+output "k8s_audit_policy" {
+  value = "${var.k8s_audit_policy}"
+}
+
+output "k8s_kms_plugin_image" {
+  value = "${var.k8s_kms_plugin_image}"
+}
+
+output "k8s_kms_plugin_args" {
+  value = "${var.k8s_kms_plugin_args}"
+}
@@ -0,0 +1,5 @@
+#!/bin/bash
+
+# Install k3s as a server, sharing the etcd of the manager's k3s cluster. K3S_TOKEN is exported by
+# the provisioner, which keeps it out of the terraform state.
+curl -sfL https://get.k3s.io | INSTALL_K3S_VERSION='${k3s_version}' sh -s - server ${k3s_server_args} --write-kubeconfig-mode 644
@@ -15,4 +15,4 @@ while [ -z "$(sudo docker images -q ${rancher_server_image})" ]; do
 done
 
 # Run Rancher docker container
-sudo docker run -d --restart=unless-stopped -p 80:80 -p 443:443 ${rancher_server_image}
+sudo docker run -d --restart=unless-stopped -p ${rancher_http_port}:80 -p ${rancher_https_port}:443 ${rancher_server_image} ${rancher_server_args}
@@ -2,7 +2,7 @@
 
 # Wait for Rancher UI to boot
 printf 'Waiting for Rancher to start'
-until $(curl --output /dev/null --silent --head --insecure --fail ${rancher_host}); do
+until $(curl --output /dev/null --silent --head --insecure --fail -H 'X-Forwarded-Proto: https' ${rancher_host}); do
     printf '.'
     sleep 5
 done
@@ -12,6 +12,7 @@ sudo apt-get install jq -y || sudo yum install jq -y
 # Login as default admin user
 login_response=$(curl -X POST \
 	--insecure \
+	-H 'X-Forwarded-Proto: https' \
 	-d '{"description":"Initial Token", "password":"admin", "ttl": 60000, "username":"admin"}' \
 	'${rancher_host}/v3-public/localProviders/local?action=login')
 initial_token=$(echo $login_response | jq -r '.token')
@@ -19,6 +20,7 @@ initial_token=$(echo $login_response | jq -r '.token')
 # Create token
 token_response=$(curl -X POST \
 	--insecure \
+	-H 'X-Forwarded-Proto: https' \
 	-u $initial_token \
 	-H 'Accept: application/json' \
 	-H 'Content-Type: application/json' \
@@ -31,6 +33,7 @@ secret_key=$(echo $token_response | jq -r '.token' | cut -d: -f2)
 # Change default admin password
 curl -X POST \
 	--insecure \
+	-H 'X-Forwarded-Proto: https' \
 	-u $access_key:$secret_key \
 	-H 'Accept: application/json' \
 	-H 'Content-Type: application/json' \
@@ -40,6 +43,7 @@ curl -X POST \
 # Setup server url
 curl -X PUT \
 	--insecure \
+	-H 'X-Forwarded-Proto: https' \
 	-u $access_key:$secret_key \
 	-H 'Accept: application/json' \
 	-H 'Content-Type: application/json' \
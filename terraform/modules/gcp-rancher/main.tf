@@ -21,17 +21,43 @@ resource "google_compute_firewall" "rancher_master_ports" {
 
     ports = [
       "22",  # SSH
-      "80",  # Rancher UI
-      "443", # Rancher UI
+      "${var.rancher_http_port}",  # Rancher UI
+      "${var.rancher_https_port}", # Rancher UI
     ]
   }
 }
 
+# The k3s API, etcd, kubelet and overlay network between the hosts of an HA manager
+resource "google_compute_firewall" "rancher_ha_hosts" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name        = "${var.name}-rancher-ha-hosts"
+  network     = "${google_compute_network.default.name}"
+  source_tags = ["${var.name}-rancher"]
+  target_tags = ["${var.name}-rancher"]
+
+  allow {
+    protocol = "tcp"
+  }
+
+  allow {
+    protocol = "udp"
+  }
+}
+
+# The hosts of an HA manager are each in their own zone of the region
+data "google_compute_zones" "available" {
+  region = "${var.gcp_compute_region}"
+}
+
 resource "google_compute_instance" "rancher_master" {
-  name         = "${var.name}"
+  count = "${var.manager_host_count}"
+
+  name         = "${var.manager_host_count > 1 ? "${var.name}-${count.index + 1}" : var.name}"
   machine_type = "${var.gcp_machine_type}"
-  zone         = "${var.gcp_instance_zone}"
+  zone         = "${var.manager_host_count > 1 ? element(data.google_compute_zones.available.names, count.index) : var.gcp_instance_zone}"
   project      = "${var.gcp_project_id}"
+  tags         = ["${var.name}-rancher"]
 
   boot_disk {
     initialize_params {
@@ -55,14 +81,24 @@ resource "google_compute_instance" "rancher_master" {
     scopes = ["https://www.googleapis.com/auth/cloud-platform"]
   }
 
-  metadata_startup_script = "${data.template_file.install_docker.rendered}"
+  # The hosts of an HA manager run Rancher on k3s rather than docker
+  metadata_startup_script = "${var.manager_host_count > 1 ? "" : data.template_file.install_docker.rendered}"
 }
 
 locals {
-  rancher_master_id = "${google_compute_instance.rancher_master.instance_id}"
-  rancher_master_ip = "${google_compute_instance.rancher_master.network_interface.0.access_config.0.assigned_nat_ip}"
+  # The first host sets up Rancher, and keeps its API key
+  rancher_master_id = "${google_compute_instance.rancher_master.0.instance_id}"
+  rancher_master_ip = "${google_compute_instance.rancher_master.0.network_interface.0.access_config.0.assigned_nat_ip}"
   ssh_user          = "${var.gcp_ssh_user}"
   key_path          = "${var.gcp_private_key_path}"
+
+  # Rancher as seen from the master, and from everything else. Behind the ingress controller
+  # of an HA manager, only its load balancer reaches Rancher. Its address has no DNS name,
+  # the ingress is for the sslip.io name resolving to it.
+  rancher_lb_host    = "${var.manager_host_count > 1 ? "${element(concat(google_compute_address.rancher.*.address, list("")), 0)}.sslip.io" : ""}"
+  rancher_direct_url = "https://${var.manager_host_count > 1 ? local.rancher_lb_host : local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_local_url  = "${var.manager_host_count > 1 ? local.rancher_direct_url : var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
 }
 
 data "template_file" "install_docker" {
@@ -86,10 +122,18 @@ data "template_file" "install_rancher_master" {
     rancher_registry          = "${var.rancher_registry}"
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
   }
 }
 
 resource "null_resource" "install_rancher_master" {
+  count = "${var.manager_host_count > 1 ? 0 : 1}"
+
   # Changes to any instance of the cluster requires re-provisioning
   triggers {
     rancher_master_id = "${local.rancher_master_id}"
@@ -109,20 +153,154 @@ resource "null_resource" "install_rancher_master" {
   }
 }
 
+# With manager_host_count 3, Rancher follows its high availability reference: the hosts form a
+# k3s cluster sharing its embedded etcd, Rancher runs a replica on each of them, and a network
+# load balancer in front of their ingress controller is where Rancher is reached. It forwards
+# the ports it receives, so Rancher is on the ingress controller's 443 and 80.
+resource "google_compute_address" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name   = "${var.name}-rancher"
+  region = "${var.gcp_compute_region}"
+}
+
+# Target pools only take HTTP health checks, which the ingress controller answers for Rancher's
+# hostname alone, so every host gets traffic
+resource "google_compute_target_pool" "rancher" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name      = "${var.name}-rancher"
+  region    = "${var.gcp_compute_region}"
+  instances = ["${formatlist("%s/%s", google_compute_instance.rancher_master.*.zone, google_compute_instance.rancher_master.*.name)}"]
+}
+
+resource "google_compute_forwarding_rule" "rancher_http" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name        = "${var.name}-rancher-http"
+  region      = "${var.gcp_compute_region}"
+  ip_address  = "${google_compute_address.rancher.address}"
+  ip_protocol = "TCP"
+  port_range  = "80"
+  target      = "${google_compute_target_pool.rancher.self_link}"
+}
+
+resource "google_compute_forwarding_rule" "rancher_https" {
+  count = "${var.manager_host_count > 1 ? 1 : 0}"
+
+  name        = "${var.name}-rancher-https"
+  region      = "${var.gcp_compute_region}"
+  ip_address  = "${google_compute_address.rancher.address}"
+  ip_protocol = "TCP"
+  port_range  = "443"
+  target      = "${google_compute_target_pool.rancher.self_link}"
+}
+
+data "template_file" "install_k3s" {
+  count    = "${var.manager_host_count > 1 ? var.manager_host_count : 0}"
+  template = "${file("${path.module}/files/install_k3s.sh.tpl")}"
+
+  vars {
+    k3s_version = "${var.k3s_version}"
+
+    # The first host starts the etcd cluster the others join
+    k3s_server_args = "${count.index == 0 ? "--cluster-init" : "--server https://${google_compute_instance.rancher_master.0.network_interface.0.network_ip}:6443"}"
+  }
+}
+
+resource "null_resource" "install_k3s_first" {
+  count      = "${var.manager_host_count > 1 ? 1 : 0}"
+  depends_on = ["google_compute_firewall.rancher_ha_hosts"]
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${data.template_file.install_k3s.0.rendered}
+      EOF
+  }
+}
+
+resource "null_resource" "install_k3s_others" {
+  count      = "${var.manager_host_count > 1 ? var.manager_host_count - 1 : 0}"
+  depends_on = ["null_resource.install_k3s_first"]
+
+  triggers {
+    host_id = "${element(google_compute_instance.rancher_master.*.instance_id, count.index + 1)}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${element(google_compute_instance.rancher_master.*.network_interface.0.access_config.0.assigned_nat_ip, count.index + 1)}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      export K3S_TOKEN='${var.manager_ha_token}'
+      ${element(data.template_file.install_k3s.*.rendered, count.index + 1)}
+      EOF
+  }
+}
+
+data "template_file" "install_rancher_ha" {
+  template = "${file("${path.module}/files/install_rancher_ha.sh.tpl")}"
+
+  vars {
+    manager_host_count    = "${var.manager_host_count}"
+    rancher_hostname      = "${local.rancher_lb_host}"
+    rancher_chart_version = "${var.rancher_chart_version}"
+    cert_manager_version  = "${var.cert_manager_version}"
+  }
+}
+
+resource "null_resource" "install_rancher_ha" {
+  count      = "${var.manager_host_count > 1 ? 1 : 0}"
+  depends_on = ["null_resource.install_k3s_others", "google_compute_forwarding_rule.rancher_https"]
+
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_ha.rendered}
+      EOF
+  }
+}
+
 data "template_file" "setup_rancher_k8s" {
   template = "${file("${path.module}/files/setup_rancher.sh.tpl")}"
 
   vars {
     name                  = "${var.name}"
-    rancher_host          = "https://127.0.0.1"
-    host_registration_url = "https://${local.rancher_master_ip}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
 
     rancher_admin_password = "${var.rancher_admin_password}"
   }
 }
 
 resource "null_resource" "setup_rancher_k8s" {
-  depends_on = ["null_resource.install_rancher_master"]
+  depends_on = ["null_resource.install_rancher_master", "null_resource.install_rancher_ha"]
 
   # Changes to any instance of the cluster requires re-provisioning
   triggers {
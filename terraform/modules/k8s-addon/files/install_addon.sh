@@ -0,0 +1,69 @@
+#!/bin/bash
+
+# Installs an addon into a Rancher managed Kubernetes cluster, by applying its manifests
+# with kubectl or installing its Helm chart. kubectl and helm talk to the cluster through
+# a kubeconfig generated by the Rancher API.
+
+# Exit if any of the intermediate steps fail
+set -e
+
+# Rotated Rancher API tokens are kept out of the terraform state, terraform runs with them in
+# its environment instead of passing them to the module
+rancher_access_key=${rancher_access_key:-$TF_VAR_rancher_access_key}
+rancher_secret_key=${rancher_secret_key:-$TF_VAR_rancher_secret_key}
+
+kubeconfig=$(mktemp)
+trap "rm -f $kubeconfig" EXIT
+
+# Wait for the cluster to become active, nodes are registered in parallel with this module
+echo "Waiting for cluster $rancher_cluster_id to become active..."
+for i in $(seq 1 120); do
+	cluster_state=$(curl -X GET \
+		--silent \
+		--insecure \
+		-u $rancher_access_key:$rancher_secret_key \
+		-H 'Accept: application/json' \
+		"$rancher_api_url/v3/clusters/$rancher_cluster_id" | jq -r '.state')
+	if [ "$cluster_state" == "active" ]; then
+		break
+	fi
+	sleep 15
+done
+
+if [ "$cluster_state" != "active" ]; then
+	echo "Cluster $rancher_cluster_id did not become active!" >&2
+	exit 1
+fi
+
+# Generate kubeconfig
+curl -X POST \
+	--silent \
+	--insecure \
+	-u $rancher_access_key:$rancher_secret_key \
+	-H 'Accept: application/json' \
+	"$rancher_api_url/v3/clusters/$rancher_cluster_id?action=generateKubeconfig" | jq -r '.config' > $kubeconfig
+
+if [ -n "$manifest_url" ]; then
+	echo "Applying $name from $manifest_url..."
+	kubectl --kubeconfig $kubeconfig apply -f "$manifest_url"
+	exit 0
+fi
+
+if [ -z "$helm_chart" ]; then
+	echo "Addon $name has neither manifests nor a Helm chart!" >&2
+	exit 1
+fi
+
+helm_args="--kubeconfig $kubeconfig --namespace $helm_namespace --create-namespace --wait"
+if [ -n "$helm_repo" ]; then
+	helm_args="$helm_args --repo $helm_repo"
+fi
+if [ -n "$helm_version" ]; then
+	helm_args="$helm_args --version $helm_version"
+fi
+if [ -n "$helm_values" ]; then
+	helm_args="$helm_args --set $helm_values"
+fi
+
+echo "Installing $name from Helm chart $helm_chart..."
+helm upgrade --install $name $helm_chart $helm_args
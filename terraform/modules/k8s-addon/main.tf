@@ -0,0 +1,28 @@
+resource "null_resource" "install_addon" {
+  # Re-install when the cluster or what is installed changes
+  triggers {
+    rancher_cluster_id = "${var.rancher_cluster_id}"
+    manifest_url       = "${var.manifest_url}"
+    helm_chart         = "${var.helm_chart}"
+    helm_version       = "${var.helm_version}"
+    helm_values        = "${join(",", formatlist("%s=%s", keys(var.helm_values), values(var.helm_values)))}"
+  }
+
+  provisioner "local-exec" {
+    command = "bash ${path.module}/files/install_addon.sh"
+
+    environment {
+      rancher_api_url    = "${var.rancher_api_url}"
+      rancher_access_key = "${var.rancher_access_key}"
+      rancher_secret_key = "${var.rancher_secret_key}"
+      rancher_cluster_id = "${var.rancher_cluster_id}"
+      name               = "${var.name}"
+      manifest_url       = "${var.manifest_url}"
+      helm_repo          = "${var.helm_repo}"
+      helm_chart         = "${var.helm_chart}"
+      helm_version       = "${var.helm_version}"
+      helm_namespace     = "${var.helm_namespace}"
+      helm_values        = "${join(",", formatlist("%s=%s", keys(var.helm_values), values(var.helm_values)))}"
+    }
+  }
+}
@@ -0,0 +1,52 @@
+variable "name" {
+  description = "Name of the addon, and of its Helm release."
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_access_key" {
+  default     = ""
+  description = "Read from TF_VAR_rancher_access_key when empty."
+}
+
+variable "rancher_secret_key" {
+  default     = ""
+  description = "Read from TF_VAR_rancher_secret_key when empty."
+}
+
+variable "rancher_cluster_id" {
+  description = "The id of the Rancher cluster to install the addon in."
+}
+
+variable "manifest_url" {
+  default     = ""
+  description = "URL of the manifests of the addon, applied with kubectl. Either manifest_url or helm_chart is set."
+}
+
+variable "helm_repo" {
+  default     = ""
+  description = "URL of the Helm chart repository of helm_chart."
+}
+
+variable "helm_chart" {
+  default     = ""
+  description = "The Helm chart of the addon, installed with Helm 3."
+}
+
+variable "helm_version" {
+  default     = ""
+  description = "The version of helm_chart. Defaults to the latest version."
+}
+
+variable "helm_namespace" {
+  default     = "default"
+  description = "The namespace the Helm release is installed in, it is created if it doesn't exist."
+}
+
+variable "helm_values" {
+  type        = "map"
+  default     = {}
+  description = "Values of the Helm chart, set with --set."
+}
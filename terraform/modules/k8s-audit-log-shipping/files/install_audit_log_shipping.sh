@@ -0,0 +1,229 @@
+#!/bin/bash
+
+# Installs a fluent-bit DaemonSet on the control nodes of a Rancher managed Kubernetes
+# cluster, which ships the API server's audit log from /var/log/kube-audit to S3,
+# Elasticsearch or Manta. fluent-bit has no Manta output, so for Manta it writes to a file
+# that a sidecar uploads. kubectl talks to the cluster through a kubeconfig generated by
+# the Rancher API.
+
+# Exit if any of the intermediate steps fail
+set -e
+
+# Rotated Rancher API tokens are kept out of the terraform state, terraform runs with them in
+# its environment instead of passing them to the module
+rancher_access_key=${rancher_access_key:-$TF_VAR_rancher_access_key}
+rancher_secret_key=${rancher_secret_key:-$TF_VAR_rancher_secret_key}
+
+script_dir=$(dirname "$0")
+namespace=kube-audit
+
+kubeconfig=$(mktemp)
+trap "rm -f $kubeconfig" EXIT
+
+# Wait for the cluster to become active, nodes are registered in parallel with this module
+echo "Waiting for cluster $rancher_cluster_id to become active..."
+for i in $(seq 1 120); do
+	cluster_state=$(curl -X GET \
+		--silent \
+		--insecure \
+		-u $rancher_access_key:$rancher_secret_key \
+		-H 'Accept: application/json' \
+		"$rancher_api_url/v3/clusters/$rancher_cluster_id" | jq -r '.state')
+	if [ "$cluster_state" == "active" ]; then
+		break
+	fi
+	sleep 15
+done
+
+if [ "$cluster_state" != "active" ]; then
+	echo "Cluster $rancher_cluster_id did not become active!" >&2
+	exit 1
+fi
+
+# Generate kubeconfig
+curl -X POST \
+	--silent \
+	--insecure \
+	-u $rancher_access_key:$rancher_secret_key \
+	-H 'Accept: application/json' \
+	"$rancher_api_url/v3/clusters/$rancher_cluster_id?action=generateKubeconfig" | jq -r '.config' > $kubeconfig
+
+kubectl --kubeconfig $kubeconfig create namespace $namespace --dry-run -o yaml | kubectl --kubeconfig $kubeconfig apply -f -
+
+# Build the fluent-bit output, credentials are passed to fluent-bit as environment variables
+output=''
+uploader=''
+uploader_volumes=''
+if [ "$audit_log_destination" == "s3" ]; then
+	kubectl --kubeconfig $kubeconfig -n $namespace create secret generic audit-log-shipping \
+		--from-literal=AWS_ACCESS_KEY_ID="$audit_log_s3_access_key" \
+		--from-literal=AWS_SECRET_ACCESS_KEY="$audit_log_s3_secret_key" \
+		--dry-run -o yaml | kubectl --kubeconfig $kubeconfig apply -f -
+	output="
+[OUTPUT]
+    Name            s3
+    Match           *
+    bucket          $audit_log_s3_bucket
+    region          $audit_log_s3_region
+    s3_key_format   /kube-audit/$rancher_cluster_id/\${NODE_NAME}/%Y/%m/%d/%H-%M-%S
+    total_file_size 50M
+    upload_timeout  10m"
+elif [ "$audit_log_destination" == "elasticsearch" ]; then
+	kubectl --kubeconfig $kubeconfig -n $namespace create secret generic audit-log-shipping \
+		--from-literal=ES_USERNAME="$audit_log_elasticsearch_username" \
+		--from-literal=ES_PASSWORD="$audit_log_elasticsearch_password" \
+		--dry-run -o yaml | kubectl --kubeconfig $kubeconfig apply -f -
+	tls=Off
+	if [ "$audit_log_elasticsearch_tls" == "true" ]; then
+		tls=On
+	fi
+	output="
+[OUTPUT]
+    Name            es
+    Match           *
+    Host            $audit_log_elasticsearch_host
+    Port            $audit_log_elasticsearch_port
+    tls             $tls
+    Logstash_Format On
+    Logstash_Prefix kube-audit"
+	if [ "$audit_log_elasticsearch_username" != "" ]; then
+		output="$output
+    HTTP_User       \${ES_USERNAME}
+    HTTP_Passwd     \${ES_PASSWORD}"
+	fi
+elif [ "$audit_log_destination" == "manta" ]; then
+	kubectl --kubeconfig $kubeconfig -n $namespace create secret generic audit-log-manta-key \
+		--from-file=id_rsa="$audit_log_manta_key_path" \
+		--dry-run -o yaml | kubectl --kubeconfig $kubeconfig apply -f -
+	kubectl --kubeconfig $kubeconfig -n $namespace create configmap audit-log-manta-upload \
+		--from-file=manta_upload.sh="$script_dir/manta_upload.sh" \
+		--dry-run -o yaml | kubectl --kubeconfig $kubeconfig apply -f -
+	output="
+[OUTPUT]
+    Name   file
+    Match  *
+    Path   /buffer
+    File   audit.log
+    Format plain"
+	uploader="
+      - name: manta-upload
+        image: alpine:3.12
+        command: [\"sh\", \"/scripts/manta_upload.sh\"]
+        env:
+        - name: NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+        - name: MANTA_URL
+          value: \"$audit_log_manta_url\"
+        - name: MANTA_ACCOUNT
+          value: \"$audit_log_manta_account\"
+        - name: MANTA_KEY_ID
+          value: \"$audit_log_manta_key_id\"
+        - name: MANTA_PATH
+          value: \"$audit_log_manta_path\"
+        volumeMounts:
+        - name: buffer
+          mountPath: /buffer
+        - name: manta-key
+          mountPath: /manta
+          readOnly: true
+        - name: manta-upload
+          mountPath: /scripts"
+	uploader_volumes="
+      - name: manta-key
+        secret:
+          secretName: audit-log-manta-key
+          defaultMode: 0400
+      - name: manta-upload
+        configMap:
+          name: audit-log-manta-upload"
+else
+	echo "Unsupported audit log destination $audit_log_destination!" >&2
+	exit 1
+fi
+
+cat <<CONFIG | kubectl --kubeconfig $kubeconfig apply -f -
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: audit-log-shipping
+  namespace: $namespace
+data:
+  fluent-bit.conf: |
+    [SERVICE]
+        Flush        5
+        Parsers_File parsers.conf
+
+    [INPUT]
+        Name             tail
+        Path             /var/log/kube-audit/audit.log
+        Parser           json
+        DB               /var/lib/fluent-bit/kube-audit.db
+        Tag              kube-audit
+        Mem_Buf_Limit    5MB
+        Refresh_Interval 10
+$(echo "$output" | sed 's/^/    /')
+CONFIG
+
+# Audit logs are only written on the control nodes
+cat <<DAEMONSET | kubectl --kubeconfig $kubeconfig apply -f -
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: audit-log-shipping
+  namespace: $namespace
+spec:
+  selector:
+    matchLabels:
+      app: audit-log-shipping
+  template:
+    metadata:
+      labels:
+        app: audit-log-shipping
+      annotations:
+        triton-kubernetes/config-checksum: "$(echo "$output" | shasum -a 256 | awk '{ print $1 }')"
+    spec:
+      nodeSelector:
+        node-role.kubernetes.io/controlplane: "true"
+      tolerations:
+      - operator: Exists
+      containers:
+      - name: fluent-bit
+        image: fluent/fluent-bit:$fluent_bit_version
+        env:
+        - name: NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+        envFrom:
+        - secretRef:
+            name: audit-log-shipping
+            optional: true
+        volumeMounts:
+        - name: audit-log
+          mountPath: /var/log/kube-audit
+          readOnly: true
+        - name: state
+          mountPath: /var/lib/fluent-bit
+        - name: buffer
+          mountPath: /buffer
+        - name: config
+          mountPath: /fluent-bit/etc/fluent-bit.conf
+          subPath: fluent-bit.conf$uploader
+      volumes:
+      - name: audit-log
+        hostPath:
+          path: /var/log/kube-audit
+      - name: state
+        hostPath:
+          path: /var/lib/kube-audit-shipping/state
+      - name: buffer
+        hostPath:
+          path: /var/lib/kube-audit-shipping/buffer
+      - name: config
+        configMap:
+          name: audit-log-shipping$uploader_volumes
+DAEMONSET
+
+kubectl --kubeconfig $kubeconfig -n $namespace rollout status daemonset/audit-log-shipping
@@ -0,0 +1,56 @@
+#!/bin/sh
+
+# Uploads the audit log fluent-bit writes to /buffer/audit.log to Manta every 5 minutes,
+# into a directory per node. Requests are signed with the account's RSA key, see
+# https://apidocs.joyent.com/manta/api.html#authentication
+
+apk add --no-cache curl openssl > /dev/null
+
+# manta_request METHOD PATH CONTENT_TYPE [FILE]
+manta_request() {
+	now=$(date -u "+%a, %d %b %Y %H:%M:%S GMT")
+	signature=$(printf "date: %s" "$now" | openssl dgst -sha256 -sign /manta/id_rsa | openssl enc -e -a | tr -d '\n')
+
+	curl --silent --show-error --fail -X "$1" \
+		-H "date: $now" \
+		-H "Authorization: Signature keyId=\"/$MANTA_ACCOUNT/keys/$MANTA_KEY_ID\",algorithm=\"rsa-sha256\",signature=\"$signature\"" \
+		-H "content-type: $3" \
+		${4:+--data-binary @$4} \
+		"$MANTA_URL$2" > /dev/null
+}
+
+create_directories() {
+	directory=""
+	for part in $(echo "$MANTA_PATH/$NODE_NAME" | tr '/' ' '); do
+		directory="$directory/$part"
+		# The account's top level directories always exist
+		case "$directory" in
+		"/$MANTA_ACCOUNT" | "/$MANTA_ACCOUNT/stor" | "/$MANTA_ACCOUNT/public")
+			continue
+			;;
+		esac
+		manta_request PUT "$directory" "application/json; type=directory" || return 1
+	done
+}
+
+until create_directories; do
+	echo "Unable to create $MANTA_PATH/$NODE_NAME, retrying in 30 seconds." >&2
+	sleep 30
+done
+
+while true; do
+	sleep 300
+
+	# fluent-bit opens the file on every flush, so it continues with a new file
+	if [ -s /buffer/audit.log ]; then
+		mv /buffer/audit.log "/buffer/audit-$(date -u +%Y%m%dT%H%M%SZ).log"
+	fi
+
+	# Files that failed to upload are retried next time
+	for file in /buffer/audit-*.log; do
+		[ -e "$file" ] || continue
+		if manta_request PUT "$MANTA_PATH/$NODE_NAME/$(basename "$file")" "application/x-json-stream" "$file"; then
+			rm "$file"
+		fi
+	done
+done
@@ -0,0 +1,38 @@
+resource "null_resource" "install_audit_log_shipping" {
+  # Re-install when the cluster, the fluent-bit version or the destination changes
+  triggers {
+    rancher_cluster_id           = "${var.rancher_cluster_id}"
+    fluent_bit_version           = "${var.fluent_bit_version}"
+    audit_log_destination        = "${var.audit_log_destination}"
+    audit_log_s3_bucket          = "${var.audit_log_s3_bucket}"
+    audit_log_elasticsearch_host = "${var.audit_log_elasticsearch_host}"
+    audit_log_manta_path         = "${var.audit_log_manta_path}"
+  }
+
+  provisioner "local-exec" {
+    command = "bash ${path.module}/files/install_audit_log_shipping.sh"
+
+    environment {
+      rancher_api_url                  = "${var.rancher_api_url}"
+      rancher_access_key               = "${var.rancher_access_key}"
+      rancher_secret_key               = "${var.rancher_secret_key}"
+      rancher_cluster_id               = "${var.rancher_cluster_id}"
+      fluent_bit_version               = "${var.fluent_bit_version}"
+      audit_log_destination            = "${var.audit_log_destination}"
+      audit_log_s3_bucket              = "${var.audit_log_s3_bucket}"
+      audit_log_s3_region              = "${var.audit_log_s3_region}"
+      audit_log_s3_access_key          = "${var.audit_log_s3_access_key}"
+      audit_log_s3_secret_key          = "${var.audit_log_s3_secret_key}"
+      audit_log_elasticsearch_host     = "${var.audit_log_elasticsearch_host}"
+      audit_log_elasticsearch_port     = "${var.audit_log_elasticsearch_port}"
+      audit_log_elasticsearch_tls      = "${var.audit_log_elasticsearch_tls}"
+      audit_log_elasticsearch_username = "${var.audit_log_elasticsearch_username}"
+      audit_log_elasticsearch_password = "${var.audit_log_elasticsearch_password}"
+      audit_log_manta_url              = "${var.audit_log_manta_url}"
+      audit_log_manta_account          = "${var.audit_log_manta_account}"
+      audit_log_manta_key_path         = "${var.audit_log_manta_key_path}"
+      audit_log_manta_key_id           = "${var.audit_log_manta_key_id}"
+      audit_log_manta_path             = "${var.audit_log_manta_path}"
+    }
+  }
+}
@@ -0,0 +1,96 @@
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_access_key" {
+  default     = ""
+  description = "Read from TF_VAR_rancher_access_key when empty."
+}
+
+variable "rancher_secret_key" {
+  default     = ""
+  description = "Read from TF_VAR_rancher_secret_key when empty."
+}
+
+variable "rancher_cluster_id" {
+  description = "The id of the Rancher cluster to ship the audit log of."
+}
+
+variable "fluent_bit_version" {
+  default     = "1.6.10"
+  description = "The fluent-bit image version to run on the control nodes."
+}
+
+variable "audit_log_destination" {
+  description = "Where to ship the audit log. Options are s3, manta and elasticsearch."
+}
+
+variable "audit_log_s3_bucket" {
+  default     = ""
+  description = "The S3 bucket the audit log is uploaded to."
+}
+
+variable "audit_log_s3_region" {
+  default     = ""
+  description = "The region of the S3 bucket."
+}
+
+variable "audit_log_s3_access_key" {
+  default     = ""
+  description = "AWS access key with permissions to put objects in the S3 bucket."
+}
+
+variable "audit_log_s3_secret_key" {
+  default     = ""
+  description = "AWS secret key with permissions to put objects in the S3 bucket."
+}
+
+variable "audit_log_elasticsearch_host" {
+  default     = ""
+  description = "The Elasticsearch host the audit log is indexed by."
+}
+
+variable "audit_log_elasticsearch_port" {
+  default     = "9200"
+  description = "The Elasticsearch port."
+}
+
+variable "audit_log_elasticsearch_tls" {
+  default     = "false"
+  description = "Whether to connect to Elasticsearch with TLS."
+}
+
+variable "audit_log_elasticsearch_username" {
+  default     = ""
+  description = "Elasticsearch HTTP basic auth username, if any."
+}
+
+variable "audit_log_elasticsearch_password" {
+  default     = ""
+  description = "Elasticsearch HTTP basic auth password, if any."
+}
+
+variable "audit_log_manta_url" {
+  default     = "https://us-east.manta.joyent.com"
+  description = "The Manta URL."
+}
+
+variable "audit_log_manta_account" {
+  default     = ""
+  description = "The Manta account the audit log is stored in."
+}
+
+variable "audit_log_manta_key_path" {
+  default     = ""
+  description = "Path to the private key of the Manta account, it is stored in a secret in the cluster."
+}
+
+variable "audit_log_manta_key_id" {
+  default     = ""
+  description = "The fingerprint of the Manta key."
+}
+
+variable "audit_log_manta_path" {
+  default     = ""
+  description = "The Manta directory the audit log is uploaded to, in a directory per control node."
+}
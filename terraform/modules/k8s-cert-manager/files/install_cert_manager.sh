@@ -0,0 +1,109 @@
+#!/bin/bash
+
+# Installs cert-manager into a Rancher managed Kubernetes cluster and optionally
+# configures a Let's Encrypt ClusterIssuer. kubectl talks to the cluster through
+# a kubeconfig generated by the Rancher API.
+
+# Exit if any of the intermediate steps fail
+set -e
+
+# Rotated Rancher API tokens are kept out of the terraform state, terraform runs with them in
+# its environment instead of passing them to the module
+rancher_access_key=${rancher_access_key:-$TF_VAR_rancher_access_key}
+rancher_secret_key=${rancher_secret_key:-$TF_VAR_rancher_secret_key}
+
+kubeconfig=$(mktemp)
+trap "rm -f $kubeconfig" EXIT
+
+# Wait for the cluster to become active, nodes are registered in parallel with this module
+echo "Waiting for cluster $rancher_cluster_id to become active..."
+for i in $(seq 1 120); do
+	cluster_state=$(curl -X GET \
+		--silent \
+		--insecure \
+		-u $rancher_access_key:$rancher_secret_key \
+		-H 'Accept: application/json' \
+		"$rancher_api_url/v3/clusters/$rancher_cluster_id" | jq -r '.state')
+	if [ "$cluster_state" == "active" ]; then
+		break
+	fi
+	sleep 15
+done
+
+if [ "$cluster_state" != "active" ]; then
+	echo "Cluster $rancher_cluster_id did not become active!" >&2
+	exit 1
+fi
+
+# Generate kubeconfig
+curl -X POST \
+	--silent \
+	--insecure \
+	-u $rancher_access_key:$rancher_secret_key \
+	-H 'Accept: application/json' \
+	"$rancher_api_url/v3/clusters/$rancher_cluster_id?action=generateKubeconfig" | jq -r '.config' > $kubeconfig
+
+# Install cert-manager
+kubectl --kubeconfig $kubeconfig apply -f "https://github.com/jetstack/cert-manager/releases/download/$cert_manager_version/cert-manager.yaml"
+kubectl --kubeconfig $kubeconfig -n cert-manager rollout status deployment/cert-manager
+
+if [ "$letsencrypt_challenge" == "none" ]; then
+	exit 0
+fi
+
+# Build the ACME challenge solver
+solver=''
+if [ "$letsencrypt_challenge" == "http01" ]; then
+	solver='
+    http01: {}'
+elif [ "$letsencrypt_challenge" == "dns01" ]; then
+	secret_name="$name-$letsencrypt_dns_provider"
+	if [ "$letsencrypt_dns_provider" == "route53" ]; then
+		kubectl --kubeconfig $kubeconfig -n cert-manager create secret generic $secret_name \
+			--from-literal=secret-access-key="$route53_secret_key" \
+			--dry-run -o yaml | kubectl --kubeconfig $kubeconfig apply -f -
+		solver='
+    dns01:
+      providers:
+      - name: route53
+        route53:
+          region: '$route53_region'
+          accessKeyID: '$route53_access_key'
+          secretAccessKeySecretRef:
+            name: '$secret_name'
+            key: secret-access-key'
+	elif [ "$letsencrypt_dns_provider" == "cloudflare" ]; then
+		kubectl --kubeconfig $kubeconfig -n cert-manager create secret generic $secret_name \
+			--from-literal=api-key="$cloudflare_api_key" \
+			--dry-run -o yaml | kubectl --kubeconfig $kubeconfig apply -f -
+		solver='
+    dns01:
+      providers:
+      - name: cloudflare
+        cloudflare:
+          email: '$cloudflare_email'
+          apiKeySecretRef:
+            name: '$secret_name'
+            key: api-key'
+	else
+		echo "Unsupported DNS provider $letsencrypt_dns_provider!" >&2
+		exit 1
+	fi
+else
+	echo "Unsupported ACME challenge $letsencrypt_challenge!" >&2
+	exit 1
+fi
+
+# Create the ClusterIssuer
+cat <<ISSUER | kubectl --kubeconfig $kubeconfig apply -f -
+apiVersion: certmanager.k8s.io/v1alpha1
+kind: ClusterIssuer
+metadata:
+  name: $name
+spec:
+  acme:
+    server: $letsencrypt_server
+    email: $letsencrypt_email
+    privateKeySecretRef:
+      name: $name-account-key$solver
+ISSUER
@@ -0,0 +1,32 @@
+resource "null_resource" "install_cert_manager" {
+  # Re-install when the cluster, the cert-manager version or the issuer changes
+  triggers {
+    rancher_cluster_id       = "${var.rancher_cluster_id}"
+    cert_manager_version     = "${var.cert_manager_version}"
+    letsencrypt_challenge    = "${var.letsencrypt_challenge}"
+    letsencrypt_environment  = "${var.letsencrypt_environment}"
+    letsencrypt_dns_provider = "${var.letsencrypt_dns_provider}"
+  }
+
+  provisioner "local-exec" {
+    command = "bash ${path.module}/files/install_cert_manager.sh"
+
+    environment {
+      rancher_api_url          = "${var.rancher_api_url}"
+      rancher_access_key       = "${var.rancher_access_key}"
+      rancher_secret_key       = "${var.rancher_secret_key}"
+      rancher_cluster_id       = "${var.rancher_cluster_id}"
+      cert_manager_version     = "${var.cert_manager_version}"
+      name                     = "${var.name}"
+      letsencrypt_challenge    = "${var.letsencrypt_challenge}"
+      letsencrypt_email        = "${var.letsencrypt_email}"
+      letsencrypt_server       = "${var.letsencrypt_environment == "production" ? "https://acme-v02.api.letsencrypt.org/directory" : "https://acme-staging-v02.api.letsencrypt.org/directory"}"
+      letsencrypt_dns_provider = "${var.letsencrypt_dns_provider}"
+      route53_access_key       = "${var.route53_access_key}"
+      route53_secret_key       = "${var.route53_secret_key}"
+      route53_region           = "${var.route53_region}"
+      cloudflare_email         = "${var.cloudflare_email}"
+      cloudflare_api_key       = "${var.cloudflare_api_key}"
+    }
+  }
+}
@@ -0,0 +1,3 @@
+output "cluster_issuer" {
+  value = "${var.letsencrypt_challenge == "none" ? "" : var.name}"
+}
@@ -0,0 +1,72 @@
+variable "name" {
+  default     = "letsencrypt"
+  description = "Name of the ClusterIssuer that is created."
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_access_key" {
+  default     = ""
+  description = "Read from TF_VAR_rancher_access_key when empty."
+}
+
+variable "rancher_secret_key" {
+  default     = ""
+  description = "Read from TF_VAR_rancher_secret_key when empty."
+}
+
+variable "rancher_cluster_id" {
+  description = "The id of the Rancher cluster to install cert-manager in."
+}
+
+variable "cert_manager_version" {
+  default     = "v0.5.2"
+  description = "The cert-manager release to install."
+}
+
+variable "letsencrypt_challenge" {
+  default     = "none"
+  description = "The ACME challenge used by the Let's Encrypt ClusterIssuer. Options are none, http01 and dns01. With none, no ClusterIssuer is created."
+}
+
+variable "letsencrypt_email" {
+  default     = ""
+  description = "Email address used for the Let's Encrypt ACME registration."
+}
+
+variable "letsencrypt_environment" {
+  default     = "staging"
+  description = "Which Let's Encrypt environment to issue certificates from. Options are staging and production."
+}
+
+variable "letsencrypt_dns_provider" {
+  default     = "route53"
+  description = "The DNS provider used to solve dns01 challenges. Options are route53 and cloudflare."
+}
+
+variable "route53_access_key" {
+  default     = ""
+  description = "AWS access key with permissions to update Route53 records."
+}
+
+variable "route53_secret_key" {
+  default     = ""
+  description = "AWS secret key with permissions to update Route53 records."
+}
+
+variable "route53_region" {
+  default     = "us-east-1"
+  description = "AWS region used for the Route53 API."
+}
+
+variable "cloudflare_email" {
+  default     = ""
+  description = "Email address of the Cloudflare account."
+}
+
+variable "cloudflare_api_key" {
+  default     = ""
+  description = "Cloudflare API key with permissions to update DNS records."
+}
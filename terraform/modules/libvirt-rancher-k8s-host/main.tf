@@ -0,0 +1,143 @@
+provider "libvirt" {
+  uri = "${var.libvirt_uri}"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    hostname                  = "${var.hostname}"
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+  }
+}
+
+resource "libvirt_volume" "disk" {
+  name           = "${var.hostname}.qcow2"
+  pool           = "${var.libvirt_pool_name}"
+  base_volume_id = "${var.libvirt_base_volume_id}"
+  size           = "${var.libvirt_disk_size * 1024 * 1024 * 1024}"
+}
+
+data "template_file" "cloud_init" {
+  template = "${file("${path.module}/files/cloud_init.cfg.tpl")}"
+
+  vars {
+    hostname   = "${var.hostname}"
+    ssh_user   = "${var.libvirt_ssh_user}"
+    public_key = "${chomp(file("${var.libvirt_key_path}.pub"))}"
+  }
+}
+
+resource "libvirt_cloudinit_disk" "cloud_init" {
+  name      = "${var.hostname}-cloudinit.iso"
+  pool      = "${var.libvirt_pool_name}"
+  user_data = "${data.template_file.cloud_init.rendered}"
+}
+
+resource "libvirt_domain" "host" {
+  name   = "${var.hostname}"
+  vcpu   = "${var.libvirt_vcpu}"
+  memory = "${var.libvirt_memory}"
+
+  cloudinit = "${libvirt_cloudinit_disk.cloud_init.id}"
+
+  disk {
+    volume_id = "${libvirt_volume.disk.id}"
+  }
+
+  network_interface {
+    network_name   = "${var.libvirt_network_name}"
+    wait_for_lease = true
+  }
+
+  # Cloud images log to the serial console
+  console {
+    type        = "pty"
+    target_port = "0"
+    target_type = "serial"
+  }
+}
+
+resource "null_resource" "install_rancher_agent" {
+  triggers {
+    libvirt_domain_id = "${libvirt_domain.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.libvirt_ssh_user}"
+    host        = "${libvirt_domain.host.network_interface.0.addresses.0}"
+    private_key = "${file(var.libvirt_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_agent.rendered}
+      EOF
+  }
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    libvirt_domain_id = "${libvirt_domain.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.libvirt_ssh_user}"
+    host        = "${libvirt_domain.host.network_interface.0.addresses.0}"
+    private_key = "${file(var.libvirt_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
@@ -0,0 +1,8 @@
+#cloud-config
+hostname: ${hostname}
+users:
+  - name: ${ssh_user}
+    sudo: ALL=(ALL) NOPASSWD:ALL
+    shell: /bin/bash
+    ssh_authorized_keys:
+      - ${public_key}
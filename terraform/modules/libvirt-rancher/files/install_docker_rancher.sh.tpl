@@ -0,0 +1,27 @@
+#!/bin/bash
+
+# Install Docker
+sudo curl "${docker_engine_install_url}" | sh
+
+# Needed on CentOS, TODO: Replace firewalld with iptables.
+sudo service firewalld stop
+
+sudo service docker stop
+DOCKER_SERVICE=$(systemctl status docker.service --no-pager | grep Loaded | sed 's~\(.*\)loaded (\(.*\)docker.service\(.*\)$~\2docker.service~g')
+sed 's~ExecStart=/usr/bin/dockerd -H\(.*\)~ExecStart=/usr/bin/dockerd --graph="/mnt/docker" -H\1~g' $DOCKER_SERVICE > /home/ubuntu/docker.conf && sudo mv /home/ubuntu/docker.conf $DOCKER_SERVICE
+sudo mkdir /mnt/docker
+sudo bash -c "mv /var/lib/docker/* /mnt/docker/"
+sudo rm -rf /var/lib/docker
+sudo bash -c 'echo "{
+  \"storage-driver\": \"overlay2\"
+}" > /etc/docker/daemon.json'
+sudo systemctl daemon-reload
+sudo systemctl restart docker
+
+# Run docker login if requested
+if [ "${rancher_registry_username}" != "" ]; then
+	sudo docker login -u ${rancher_registry_username} -p ${rancher_registry_password} ${rancher_registry}
+fi
+
+# Pull the rancher_server_image in preparation of running it
+sudo docker pull ${rancher_server_image}
\ No newline at end of file
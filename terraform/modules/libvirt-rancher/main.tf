@@ -0,0 +1,196 @@
+provider "libvirt" {
+  uri = "${var.libvirt_uri}"
+}
+
+resource "libvirt_volume" "base" {
+  name   = "${var.name}-manager-base.qcow2"
+  pool   = "${var.libvirt_pool_name}"
+  source = "${var.libvirt_image_source}"
+  format = "qcow2"
+}
+
+resource "libvirt_volume" "rancher_master" {
+  name           = "${var.name}-manager.qcow2"
+  pool           = "${var.libvirt_pool_name}"
+  base_volume_id = "${libvirt_volume.base.id}"
+  size           = "${var.master_libvirt_disk_size * 1024 * 1024 * 1024}"
+}
+
+data "template_file" "cloud_init" {
+  template = "${file("${path.module}/files/cloud_init.cfg.tpl")}"
+
+  vars {
+    hostname   = "${var.name}"
+    ssh_user   = "${var.libvirt_ssh_user}"
+    public_key = "${chomp(file("${var.libvirt_key_path}.pub"))}"
+  }
+}
+
+resource "libvirt_cloudinit_disk" "rancher_master" {
+  name      = "${var.name}-manager-cloudinit.iso"
+  pool      = "${var.libvirt_pool_name}"
+  user_data = "${data.template_file.cloud_init.rendered}"
+}
+
+resource "libvirt_domain" "rancher_master" {
+  name   = "${var.name}"
+  vcpu   = "${var.master_libvirt_vcpu}"
+  memory = "${var.master_libvirt_memory}"
+
+  cloudinit = "${libvirt_cloudinit_disk.rancher_master.id}"
+
+  disk {
+    volume_id = "${libvirt_volume.rancher_master.id}"
+  }
+
+  network_interface {
+    network_name   = "${var.libvirt_network_name}"
+    wait_for_lease = true
+  }
+
+  # Cloud images log to the serial console
+  console {
+    type        = "pty"
+    target_port = "0"
+    target_type = "serial"
+  }
+}
+
+locals {
+  rancher_master_id = "${libvirt_domain.rancher_master.id}"
+  rancher_master_ip = "${libvirt_domain.rancher_master.network_interface.0.addresses.0}"
+  ssh_user          = "${var.libvirt_ssh_user}"
+  key_path          = "${var.libvirt_key_path}"
+
+  # Rancher as seen from the master, and from everything else
+  rancher_local_url  = "${var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_direct_url = "https://${local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
+}
+
+data "template_file" "install_docker" {
+  template = "${file("${path.module}/files/install_docker_rancher.sh.tpl")}"
+
+  vars {
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+  }
+}
+
+resource "null_resource" "install_docker" {
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_docker.rendered}
+      EOF
+  }
+}
+
+data "template_file" "install_rancher_master" {
+  template = "${file("${path.module}/files/install_rancher_master.sh.tpl")}"
+
+  vars {
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
+  }
+}
+
+resource "null_resource" "install_rancher_master" {
+  depends_on = ["null_resource.install_docker"]
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_master.rendered}
+      EOF
+  }
+}
+
+data "template_file" "setup_rancher_k8s" {
+  template = "${file("${path.module}/files/setup_rancher.sh.tpl")}"
+
+  vars {
+    name                  = "${var.name}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
+
+    rancher_admin_password = "${var.rancher_admin_password}"
+  }
+}
+
+resource "null_resource" "setup_rancher_k8s" {
+  depends_on = ["null_resource.install_rancher_master"]
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.setup_rancher_k8s.rendered}
+      EOF
+  }
+}
+
+// The setup_rancher_k8s script will have stored a file with an api key
+// We need to retrieve the contents of that file and output it.
+// This is a hack to get around the Terraform Rancher provider not having resources for api keys.
+module "rancher_access_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .name'"
+}
+
+module "rancher_secret_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .token | cut -d: -f2'"
+}
@@ -0,0 +1,135 @@
+provider "nutanix" {
+  endpoint = "${var.nutanix_endpoint}"
+  port     = "${var.nutanix_port}"
+  username = "${var.nutanix_username}"
+  password = "${var.nutanix_password}"
+  insecure = "${var.nutanix_insecure}"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+
+  cloud_init = <<EOF
+#cloud-config
+hostname: ${var.hostname}
+users:
+  - name: ${var.nutanix_ssh_user}
+    sudo: ALL=(ALL) NOPASSWD:ALL
+    shell: /bin/bash
+    ssh_authorized_keys:
+      - ${chomp(file("${var.nutanix_key_path}.pub"))}
+EOF
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    hostname                  = "${var.hostname}"
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+  }
+}
+
+// The disk is a clone of the image, resized. cloud-init creates the SSH user.
+resource "nutanix_virtual_machine" "host" {
+  name                 = "${var.hostname}"
+  cluster_uuid         = "${var.nutanix_cluster_uuid}"
+  num_sockets          = "${var.nutanix_vcpus}"
+  num_vcpus_per_socket = 1
+  memory_size_mib      = "${var.nutanix_memory}"
+
+  disk_list = [{
+    data_source_reference = {
+      kind = "image"
+      uuid = "${var.nutanix_image_uuid}"
+    }
+
+    disk_size_mib = "${var.nutanix_disk_size * 1024}"
+  }]
+
+  nic_list = [{
+    subnet_uuid = "${var.nutanix_subnet_uuid}"
+  }]
+
+  guest_customization_cloud_init_user_data = "${base64encode(local.cloud_init)}"
+}
+
+resource "null_resource" "install_rancher_agent" {
+  triggers {
+    nutanix_vm_id = "${nutanix_virtual_machine.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.nutanix_ssh_user}"
+    host        = "${nutanix_virtual_machine.host.nic_list_status.0.ip_endpoint_list.0.ip}"
+    private_key = "${file(var.nutanix_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_agent.rendered}
+      EOF
+  }
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    nutanix_vm_id = "${nutanix_virtual_machine.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.nutanix_ssh_user}"
+    host        = "${nutanix_virtual_machine.host.nic_list_status.0.ip_endpoint_list.0.ip}"
+    private_key = "${file(var.nutanix_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
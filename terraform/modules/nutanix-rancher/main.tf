@@ -0,0 +1,181 @@
+provider "nutanix" {
+  endpoint = "${var.nutanix_endpoint}"
+  port     = "${var.nutanix_port}"
+  username = "${var.nutanix_username}"
+  password = "${var.nutanix_password}"
+  insecure = "${var.nutanix_insecure}"
+}
+
+// The disk is a clone of the image, resized. cloud-init creates the SSH user.
+resource "nutanix_virtual_machine" "rancher_master" {
+  name                 = "${var.name}"
+  cluster_uuid         = "${var.nutanix_cluster_uuid}"
+  num_sockets          = "${var.master_nutanix_vcpus}"
+  num_vcpus_per_socket = 1
+  memory_size_mib      = "${var.master_nutanix_memory}"
+
+  disk_list = [{
+    data_source_reference = {
+      kind = "image"
+      uuid = "${var.nutanix_image_uuid}"
+    }
+
+    disk_size_mib = "${var.master_nutanix_disk_size * 1024}"
+  }]
+
+  nic_list = [{
+    subnet_uuid = "${var.nutanix_subnet_uuid}"
+  }]
+
+  guest_customization_cloud_init_user_data = "${base64encode(local.cloud_init)}"
+}
+
+locals {
+  rancher_master_id = "${nutanix_virtual_machine.rancher_master.id}"
+  rancher_master_ip = "${nutanix_virtual_machine.rancher_master.nic_list_status.0.ip_endpoint_list.0.ip}"
+  ssh_user          = "${var.nutanix_ssh_user}"
+  key_path          = "${var.nutanix_key_path}"
+
+  cloud_init = <<EOF
+#cloud-config
+hostname: ${var.name}
+users:
+  - name: ${var.nutanix_ssh_user}
+    sudo: ALL=(ALL) NOPASSWD:ALL
+    shell: /bin/bash
+    ssh_authorized_keys:
+      - ${chomp(file("${var.nutanix_key_path}.pub"))}
+EOF
+
+  # Rancher as seen from the master, and from everything else
+  rancher_local_url  = "${var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_direct_url = "https://${local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
+}
+
+data "template_file" "install_docker" {
+  template = "${file("${path.module}/files/install_docker_rancher.sh.tpl")}"
+
+  vars {
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+  }
+}
+
+resource "null_resource" "install_docker" {
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_docker.rendered}
+      EOF
+  }
+}
+
+data "template_file" "install_rancher_master" {
+  template = "${file("${path.module}/files/install_rancher_master.sh.tpl")}"
+
+  vars {
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
+  }
+}
+
+resource "null_resource" "install_rancher_master" {
+  depends_on = ["null_resource.install_docker"]
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_master.rendered}
+      EOF
+  }
+}
+
+data "template_file" "setup_rancher_k8s" {
+  template = "${file("${path.module}/files/setup_rancher.sh.tpl")}"
+
+  vars {
+    name                  = "${var.name}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
+
+    rancher_admin_password = "${var.rancher_admin_password}"
+  }
+}
+
+resource "null_resource" "setup_rancher_k8s" {
+  depends_on = ["null_resource.install_rancher_master"]
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.setup_rancher_k8s.rendered}
+      EOF
+  }
+}
+
+// The setup_rancher_k8s script will have stored a file with an api key
+// We need to retrieve the contents of that file and output it.
+// This is a hack to get around the Terraform Rancher provider not having resources for api keys.
+module "rancher_access_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .name'"
+}
+
+module "rancher_secret_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .token | cut -d: -f2'"
+}
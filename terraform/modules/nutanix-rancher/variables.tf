@@ -0,0 +1,116 @@
+variable "name" {
+  description = "Human readable name used as prefix to generated names."
+}
+
+variable "rancher_admin_password" {
+  description = "The Rancher admin password"
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "rancher_server_image" {
+  default     = "rancher/server:v2.0.0-beta2"
+  description = "The Rancher Server image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher server and agent images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "rancher_external_url" {
+  default     = ""
+  description = "URL of Rancher through an existing load balancer or reverse proxy, e.g. https://rancher.example.com:8443. Nodes register with it. Defaults to the master's IP address on rancher_https_port."
+}
+
+variable "rancher_https_port" {
+  default     = "443"
+  description = "The port the master serves Rancher on over HTTPS."
+}
+
+variable "rancher_http_port" {
+  default     = "80"
+  description = "The port the master serves Rancher on over HTTP."
+}
+
+variable "rancher_tls_termination" {
+  default     = "rancher"
+  description = "Where TLS is terminated, rancher or proxy. With proxy, the proxy forwards HTTP requests to rancher_http_port with the X-Forwarded-Proto header."
+}
+
+variable "nutanix_endpoint" {
+  description = "The address of Prism Central, e.g. pc.example.com."
+}
+
+variable "nutanix_port" {
+  default     = "9440"
+  description = "The port of the Prism Central API."
+}
+
+variable "nutanix_username" {
+  description = "The Prism Central user the VMs are created as."
+}
+
+variable "nutanix_password" {
+  description = "The password of the Prism Central user."
+}
+
+variable "nutanix_insecure" {
+  default     = "false"
+  description = "Whether to skip verifying the certificate of Prism Central, e.g. when it's self-signed."
+}
+
+variable "nutanix_cluster_uuid" {
+  description = "The UUID of the AHV cluster the VM runs on."
+}
+
+variable "nutanix_subnet_uuid" {
+  description = "The UUID of the subnet the VM's network interface is attached to. The VM gets its address with DHCP or IPAM."
+}
+
+variable "nutanix_image_uuid" {
+  description = "The UUID of the disk image the VM's disk is cloned from. It must have cloud-init installed."
+}
+
+variable "nutanix_ssh_user" {
+  default     = "ubuntu"
+  description = "The user cloud-init creates and terraform connects as."
+}
+
+variable "nutanix_key_path" {
+  default     = "~/.ssh/id_rsa"
+  description = "The path to the private key used to connect to the VM. The public key is read from the same path with a .pub extension."
+}
+
+variable "master_nutanix_vcpus" {
+  default     = "2"
+  description = "The number of vCPUs of the Rancher master VM."
+}
+
+variable "master_nutanix_memory" {
+  default     = "4096"
+  description = "The memory of the Rancher master VM, in MiB."
+}
+
+variable "master_nutanix_disk_size" {
+  default     = "40"
+  description = "The disk size of the Rancher master VM, in GiB. It must be at least the size of the image."
+}
@@ -0,0 +1,110 @@
+provider "openstack" {
+  auth_url    = "${var.openstack_auth_url}"
+  user_name   = "${var.openstack_user_name}"
+  password    = "${var.openstack_password}"
+  tenant_name = "${var.openstack_tenant_name}"
+  domain_name = "${var.openstack_domain_name}"
+  region      = "${var.openstack_region}"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    hostname                  = "${var.hostname}"
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+  }
+}
+
+resource "openstack_compute_instance_v2" "host" {
+  name        = "${var.hostname}"
+  flavor_name = "${var.openstack_flavor_name}"
+  image_name  = "${var.openstack_image_name}"
+  key_pair    = "${var.openstack_key_pair}"
+
+  # Puts the instance in the cluster's security group
+  security_groups = ["${var.openstack_secgroup_name}"]
+
+  network {
+    name = "${var.openstack_network_name}"
+  }
+
+  user_data = "${data.template_file.install_rancher_agent.rendered}"
+}
+
+# Ingress and NodePort Services are reached at the floating IP when a pool is given
+resource "openstack_networking_floatingip_v2" "host" {
+  count = "${var.openstack_floating_ip_pool == "" ? 0 : 1}"
+  pool  = "${var.openstack_floating_ip_pool}"
+}
+
+resource "openstack_compute_floatingip_associate_v2" "host" {
+  count       = "${var.openstack_floating_ip_pool == "" ? 0 : 1}"
+  floating_ip = "${openstack_networking_floatingip_v2.host.address}"
+  instance_id = "${openstack_compute_instance_v2.host.id}"
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    instance_id = "${openstack_compute_instance_v2.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.openstack_ssh_user}"
+    host        = "${var.openstack_floating_ip_pool == "" ? openstack_compute_instance_v2.host.access_ip_v4 : element(concat(openstack_networking_floatingip_v2.host.*.address, list("")), 0)}"
+    private_key = "${file(var.openstack_private_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
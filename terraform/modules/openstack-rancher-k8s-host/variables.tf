@@ -0,0 +1,150 @@
+variable "hostname" {
+  description = ""
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_cluster_registration_token" {}
+
+variable "rancher_cluster_ca_checksum" {}
+
+variable "rancher_host_labels" {
+  type        = "map"
+  description = "A map of key/value pairs that get passed to the rancher agent on the host."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "k8s_audit_policy" {
+  default     = ""
+  description = "The base64 encoded Kubernetes API server audit policy, written to control nodes of clusters with an audit log."
+}
+
+variable "k8s_secrets_encryption_config" {
+  default     = ""
+  description = "The base64 encoded encryption config of the Kubernetes API server, copied over SSH to control nodes of clusters encrypting secrets at rest."
+}
+
+variable "k8s_kms_plugin_image" {
+  default     = ""
+  description = "The image of the KMS plugin run on control nodes of clusters encrypting secrets with kms."
+}
+
+variable "k8s_kms_plugin_args" {
+  default     = ""
+  description = "The arguments of the KMS plugin."
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "openstack_auth_url" {
+  description = "The Identity (Keystone) v3 endpoint, e.g. https://openstack.example.com:5000/v3."
+}
+
+variable "openstack_user_name" {
+  description = "The OpenStack user name."
+}
+
+variable "openstack_password" {
+  description = "The password of the OpenStack user."
+}
+
+variable "openstack_tenant_name" {
+  description = "The OpenStack project (tenant) the instances are created in."
+}
+
+variable "openstack_domain_name" {
+  default     = "Default"
+  description = "The domain of the OpenStack user and project."
+}
+
+variable "openstack_region" {
+  description = "The OpenStack region, e.g. RegionOne."
+}
+
+variable "openstack_flavor_name" {
+  description = "The flavor of the instance."
+}
+
+variable "openstack_image_name" {
+  description = "The name of the instance's image, e.g. ubuntu-16.04."
+}
+
+variable "openstack_network_name" {
+  description = "The network the instance is attached to."
+}
+
+variable "openstack_floating_ip_pool" {
+  default     = ""
+  description = "The external network a floating IP of the instance is allocated from. Without one the instance is reached at its fixed IP."
+}
+
+variable "openstack_key_pair" {
+  description = "The name of a key pair of the OpenStack project, the instance's SSH user logs in with it."
+}
+
+variable "openstack_secgroup_name" {
+  description = "The security group of the cluster's nodes."
+}
+
+variable "openstack_ssh_user" {
+  default     = "ubuntu"
+  description = "The SSH user the encryption config of the Kubernetes API server is copied to control nodes with."
+}
+
+variable "openstack_private_key_path" {
+  default     = ""
+  description = "The path to the private key of openstack_key_pair, used to copy the encryption config of the Kubernetes API server to control nodes."
+}
@@ -0,0 +1,114 @@
+data "external" "rancher_cluster" {
+  program = ["bash", "${path.module}/files/rancher_cluster.sh"]
+
+  query = {
+    rancher_api_url       = "${var.rancher_api_url}"
+    rancher_access_key    = "${var.rancher_access_key}"
+    rancher_secret_key    = "${var.rancher_secret_key}"
+    name                  = "${var.name}"
+    k8s_version           = "${var.k8s_version}"
+    k8s_network_provider  = "${var.k8s_network_provider}"
+    k8s_network_mtu       = "${var.k8s_network_mtu}"
+    k8s_network_backend   = "${var.k8s_network_backend}"
+    k8s_registry          = "${var.k8s_registry}"
+    k8s_registry_username = "${var.k8s_registry_username}"
+    k8s_registry_password = "${var.k8s_registry_password}"
+
+    k8s_nodelocal_dns                = "${var.k8s_nodelocal_dns}"
+    k8s_coredns_min_replicas         = "${var.k8s_coredns_min_replicas}"
+    k8s_coredns_upstream_nameservers = "${var.k8s_coredns_upstream_nameservers}"
+
+    k8s_audit_log             = "${var.k8s_audit_log}"
+    k8s_audit_log_max_age     = "${var.k8s_audit_log_max_age}"
+    k8s_audit_log_max_backups = "${var.k8s_audit_log_max_backups}"
+    k8s_audit_log_max_size    = "${var.k8s_audit_log_max_size}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption}"
+
+    rancher_cluster_template_id          = "${var.rancher_cluster_template_id}"
+    rancher_cluster_template_revision_id = "${var.rancher_cluster_template_revision_id}"
+  }
+}
+
+provider "openstack" {
+  auth_url    = "${var.openstack_auth_url}"
+  user_name   = "${var.openstack_user_name}"
+  password    = "${var.openstack_password}"
+  tenant_name = "${var.openstack_tenant_name}"
+  domain_name = "${var.openstack_domain_name}"
+  region      = "${var.openstack_region}"
+}
+
+# Firewall requirements taken from:
+# https://rancher.com/docs/rancher/v2.0/en/quick-start-guide/
+resource "openstack_networking_secgroup_v2" "rke_ports" {
+  name        = "${var.name}-rke-ports"
+  description = "Kubernetes cluster ${var.name}"
+}
+
+# Nodes reach each other on any port, e.g. etcd, the kubelet API and the overlay network
+resource "openstack_networking_secgroup_rule_v2" "rke_nodes_tcp" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  remote_group_id   = "${openstack_networking_secgroup_v2.rke_ports.id}"
+  security_group_id = "${openstack_networking_secgroup_v2.rke_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rke_nodes_udp" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "udp"
+  remote_group_id   = "${openstack_networking_secgroup_v2.rke_ports.id}"
+  security_group_id = "${openstack_networking_secgroup_v2.rke_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rke_ssh" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = 22 # SSH
+  port_range_max    = 22
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rke_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rke_http" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = 80 # Ingress
+  port_range_max    = 80
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rke_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rke_https" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = 443 # Ingress
+  port_range_max    = 443
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rke_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rke_kube_api" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = 6443 # Kubernetes API server
+  port_range_max    = 6443
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rke_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rke_node_ports" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = 30000 # NodePort Services
+  port_range_max    = 32767
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rke_ports.id}"
+}
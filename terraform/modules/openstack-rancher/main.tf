@@ -0,0 +1,191 @@
+provider "openstack" {
+  auth_url    = "${var.openstack_auth_url}"
+  user_name   = "${var.openstack_user_name}"
+  password    = "${var.openstack_password}"
+  tenant_name = "${var.openstack_tenant_name}"
+  domain_name = "${var.openstack_domain_name}"
+  region      = "${var.openstack_region}"
+}
+
+# Firewall requirements taken from:
+# https://rancher.com/docs/rancher/v2.0/en/quick-start-guide/
+resource "openstack_networking_secgroup_v2" "rancher_master_ports" {
+  name        = "${var.name}-rancher-master-ports"
+  description = "Rancher manager ${var.name}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rancher_master_ssh" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = 22
+  port_range_max    = 22
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rancher_master_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rancher_master_http" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = "${var.rancher_http_port}"
+  port_range_max    = "${var.rancher_http_port}"
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rancher_master_ports.id}"
+}
+
+resource "openstack_networking_secgroup_rule_v2" "rancher_master_https" {
+  direction         = "ingress"
+  ethertype         = "IPv4"
+  protocol          = "tcp"
+  port_range_min    = "${var.rancher_https_port}"
+  port_range_max    = "${var.rancher_https_port}"
+  remote_ip_prefix  = "0.0.0.0/0"
+  security_group_id = "${openstack_networking_secgroup_v2.rancher_master_ports.id}"
+}
+
+resource "openstack_compute_instance_v2" "rancher_master" {
+  name            = "${var.name}"
+  flavor_name     = "${var.openstack_flavor_name}"
+  image_name      = "${var.openstack_image_name}"
+  key_pair        = "${var.openstack_key_pair}"
+  security_groups = ["${openstack_networking_secgroup_v2.rancher_master_ports.name}"]
+
+  network {
+    name = "${var.openstack_network_name}"
+  }
+
+  user_data = "${data.template_file.install_docker.rendered}"
+}
+
+# The manager is reached at a floating IP when a pool is given, and at its fixed IP otherwise
+resource "openstack_networking_floatingip_v2" "rancher_master" {
+  count = "${var.openstack_floating_ip_pool == "" ? 0 : 1}"
+  pool  = "${var.openstack_floating_ip_pool}"
+}
+
+resource "openstack_compute_floatingip_associate_v2" "rancher_master" {
+  count       = "${var.openstack_floating_ip_pool == "" ? 0 : 1}"
+  floating_ip = "${openstack_networking_floatingip_v2.rancher_master.address}"
+  instance_id = "${openstack_compute_instance_v2.rancher_master.id}"
+}
+
+locals {
+  rancher_master_id = "${openstack_compute_instance_v2.rancher_master.id}"
+  rancher_master_ip = "${var.openstack_floating_ip_pool == "" ? openstack_compute_instance_v2.rancher_master.access_ip_v4 : element(concat(openstack_networking_floatingip_v2.rancher_master.*.address, list("")), 0)}"
+  ssh_user          = "${var.openstack_ssh_user}"
+  key_path          = "${var.openstack_private_key_path}"
+
+  # Rancher as seen from the master, and from everything else
+  rancher_local_url  = "${var.rancher_tls_termination == "proxy" ? "http://127.0.0.1:${var.rancher_http_port}" : "https://127.0.0.1:${var.rancher_https_port}"}"
+  rancher_direct_url = "https://${local.rancher_master_ip}${var.rancher_https_port == "443" ? "" : ":${var.rancher_https_port}"}"
+  rancher_url        = "${var.rancher_external_url != "" ? var.rancher_external_url : local.rancher_direct_url}"
+}
+
+data "template_file" "install_docker" {
+  template = "${file("${path.module}/files/install_docker_rancher.sh.tpl")}"
+
+  vars {
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+  }
+}
+
+data "template_file" "install_rancher_master" {
+  template = "${file("${path.module}/files/install_rancher_master.sh.tpl")}"
+
+  vars {
+    rancher_server_image      = "${var.rancher_server_image}"
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    rancher_https_port = "${var.rancher_https_port}"
+    rancher_http_port  = "${var.rancher_http_port}"
+
+    # Without its own certificates, Rancher relies on X-Forwarded-Proto to tell HTTPS requests
+    rancher_server_args = "${var.rancher_tls_termination == "proxy" ? "--no-cacerts" : ""}"
+  }
+}
+
+resource "null_resource" "install_rancher_master" {
+  # The floating IP must be associated before the manager is reached at it
+  depends_on = ["openstack_compute_floatingip_associate_v2.rancher_master"]
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_master.rendered}
+      EOF
+  }
+}
+
+data "template_file" "setup_rancher_k8s" {
+  template = "${file("${path.module}/files/setup_rancher.sh.tpl")}"
+
+  vars {
+    name                  = "${var.name}"
+    rancher_host          = "${local.rancher_local_url}"
+    host_registration_url = "${local.rancher_url}"
+
+    rancher_admin_password = "${var.rancher_admin_password}"
+  }
+}
+
+resource "null_resource" "setup_rancher_k8s" {
+  depends_on = ["null_resource.install_rancher_master"]
+
+  # Changes to any instance of the cluster requires re-provisioning
+  triggers {
+    rancher_master_id = "${local.rancher_master_id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${local.ssh_user}"
+    host        = "${local.rancher_master_ip}"
+    private_key = "${file(local.key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.setup_rancher_k8s.rendered}
+      EOF
+  }
+}
+
+// The setup_rancher_k8s script will have stored a file with an api key
+// We need to retrieve the contents of that file and output it.
+// This is a hack to get around the Terraform Rancher provider not having resources for api keys.
+module "rancher_access_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .name'"
+}
+
+module "rancher_secret_key" {
+  source  = "matti/outputs/shell"
+  version = "0.0.1"
+
+  // We ssh into the remote box and cat the file.
+  // We echo the output from null_resource.setup_rancher_k8s to setup an implicit dependency.
+  command = "ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -i ${local.key_path} ${local.ssh_user}@${local.rancher_master_ip} 'echo ${null_resource.setup_rancher_k8s.id} > /dev/null; cat ~/rancher_api_key | jq -r .token | cut -d: -f2'"
+}
@@ -0,0 +1,113 @@
+variable "name" {
+  description = "Human readable name used as prefix to generated names."
+}
+
+variable "rancher_admin_password" {
+  description = "The Rancher admin password"
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "rancher_server_image" {
+  default     = "rancher/server:v2.0.0-beta2"
+  description = "The Rancher Server image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher server and agent images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "rancher_external_url" {
+  default     = ""
+  description = "URL of Rancher through an existing load balancer or reverse proxy, e.g. https://rancher.example.com:8443. Nodes register with it. Defaults to the master's IP address on rancher_https_port."
+}
+
+variable "rancher_https_port" {
+  default     = "443"
+  description = "The port the master serves Rancher on over HTTPS."
+}
+
+variable "rancher_http_port" {
+  default     = "80"
+  description = "The port the master serves Rancher on over HTTP."
+}
+
+variable "rancher_tls_termination" {
+  default     = "rancher"
+  description = "Where TLS is terminated, rancher or proxy. With proxy, the proxy forwards HTTP requests to rancher_http_port with the X-Forwarded-Proto header."
+}
+
+variable "openstack_auth_url" {
+  description = "The Identity (Keystone) v3 endpoint, e.g. https://openstack.example.com:5000/v3."
+}
+
+variable "openstack_user_name" {
+  description = "The OpenStack user name."
+}
+
+variable "openstack_password" {
+  description = "The password of the OpenStack user."
+}
+
+variable "openstack_tenant_name" {
+  description = "The OpenStack project (tenant) the instances are created in."
+}
+
+variable "openstack_domain_name" {
+  default     = "Default"
+  description = "The domain of the OpenStack user and project."
+}
+
+variable "openstack_region" {
+  description = "The OpenStack region, e.g. RegionOne."
+}
+
+variable "openstack_flavor_name" {
+  description = "The flavor of the instance."
+}
+
+variable "openstack_image_name" {
+  description = "The name of the instance's image, e.g. ubuntu-16.04."
+}
+
+variable "openstack_network_name" {
+  description = "The network the instance is attached to."
+}
+
+variable "openstack_floating_ip_pool" {
+  default     = ""
+  description = "The external network a floating IP of the instance is allocated from. Without one the instance is reached at its fixed IP."
+}
+
+variable "openstack_key_pair" {
+  description = "The name of a key pair of the OpenStack project, the instance's SSH user logs in with it."
+}
+
+variable "openstack_ssh_user" {
+  default     = "ubuntu"
+  description = "The user of the image the manager is set up as over SSH."
+}
+
+variable "openstack_private_key_path" {
+  description = "Path to the private key of the key pair."
+  default     = "~/.ssh/id_rsa"
+}
@@ -0,0 +1,127 @@
+provider "proxmox" {
+  pm_api_url          = "${var.proxmox_api_url}"
+  pm_api_token_id     = "${var.proxmox_api_token_id}"
+  pm_api_token_secret = "${var.proxmox_api_token_secret}"
+  pm_tls_insecure     = "${var.proxmox_tls_insecure}"
+}
+
+locals {
+  rancher_node_role = "${element(keys(var.rancher_host_labels), 0)}"
+}
+
+data "template_file" "install_rancher_agent" {
+  template = "${file("${path.module}/files/install_rancher_agent.sh.tpl")}"
+
+  vars {
+    hostname                  = "${var.hostname}"
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+
+    rancher_api_url                    = "${var.rancher_api_url}"
+    rancher_cluster_registration_token = "${var.rancher_cluster_registration_token}"
+    rancher_cluster_ca_checksum        = "${var.rancher_cluster_ca_checksum}"
+    rancher_node_role                  = "${local.rancher_node_role == "control" ? "controlplane" : local.rancher_node_role}"
+    rancher_agent_image                = "${var.rancher_agent_image}"
+
+    rancher_registry          = "${var.rancher_registry}"
+    rancher_registry_username = "${var.rancher_registry_username}"
+    rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+  }
+}
+
+// A full clone of the template, configured by cloud-init. The QEMU guest agent reports its address.
+resource "proxmox_vm_qemu" "host" {
+  name        = "${var.hostname}"
+  target_node = "${var.proxmox_node}"
+  clone       = "${var.proxmox_template_name}"
+  full_clone  = true
+  agent       = 1
+
+  cores  = "${var.proxmox_cores}"
+  memory = "${var.proxmox_memory}"
+
+  disk {
+    type    = "scsi"
+    storage = "${var.proxmox_storage}"
+    size    = "${var.proxmox_disk_size}G"
+  }
+
+  network {
+    model  = "virtio"
+    bridge = "${var.proxmox_bridge}"
+  }
+
+  os_type   = "cloud-init"
+  ipconfig0 = "ip=dhcp"
+  ciuser    = "${var.proxmox_ssh_user}"
+  sshkeys   = "${file("${var.proxmox_key_path}.pub")}"
+}
+
+resource "null_resource" "install_rancher_agent" {
+  triggers {
+    proxmox_vm_id = "${proxmox_vm_qemu.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.proxmox_ssh_user}"
+    host        = "${proxmox_vm_qemu.host.default_ipv4_address}"
+    private_key = "${file(var.proxmox_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = <<EOF
+      ${data.template_file.install_rancher_agent.rendered}
+      EOF
+  }
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    proxmox_vm_id = "${proxmox_vm_qemu.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.proxmox_ssh_user}"
+    host        = "${proxmox_vm_qemu.host.default_ipv4_address}"
+    private_key = "${file(var.proxmox_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
+  }
+}
@@ -0,0 +1,149 @@
+variable "hostname" {
+  description = ""
+}
+
+variable "rancher_api_url" {
+  description = ""
+}
+
+variable "rancher_cluster_registration_token" {}
+
+variable "rancher_cluster_ca_checksum" {}
+
+variable "rancher_host_labels" {
+  type        = "map"
+  description = "A map of key/value pairs that get passed to the rancher agent on the host."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "k8s_audit_policy" {
+  default     = ""
+  description = "The base64 encoded Kubernetes API server audit policy, written to control nodes of clusters with an audit log."
+}
+
+variable "k8s_secrets_encryption_config" {
+  default     = ""
+  description = "The base64 encoded encryption config of the Kubernetes API server, copied over SSH to control nodes of clusters encrypting secrets at rest."
+}
+
+variable "k8s_kms_plugin_image" {
+  default     = ""
+  description = "The image of the KMS plugin run on control nodes of clusters encrypting secrets with kms."
+}
+
+variable "k8s_kms_plugin_args" {
+  default     = ""
+  description = "The arguments of the KMS plugin."
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "proxmox_api_url" {
+  description = "The URL of the Proxmox VE API, e.g. https://pve.example.com:8006/api2/json."
+}
+
+variable "proxmox_api_token_id" {
+  description = "The id of the Proxmox API token, {user}@{realm}!{token name}."
+}
+
+variable "proxmox_api_token_secret" {
+  description = "The secret of the Proxmox API token."
+}
+
+variable "proxmox_tls_insecure" {
+  default     = "false"
+  description = "Whether to skip verifying the certificate of the Proxmox API, e.g. when it's self-signed."
+}
+
+variable "proxmox_node" {
+  description = "The Proxmox node the VM runs on."
+}
+
+variable "proxmox_template_name" {
+  description = "The name of the template the VM is cloned from. It must have cloud-init and the QEMU guest agent installed."
+}
+
+variable "proxmox_storage" {
+  description = "The storage the VM disk is created in."
+}
+
+variable "proxmox_bridge" {
+  default     = "vmbr0"
+  description = "The bridge the VM's network interface is attached to. The VM gets its address with DHCP."
+}
+
+variable "proxmox_cores" {
+  default     = "2"
+  description = "The number of CPU cores of the VM."
+}
+
+variable "proxmox_memory" {
+  default     = "2048"
+  description = "The memory of the VM, in megabytes."
+}
+
+variable "proxmox_disk_size" {
+  default     = "20"
+  description = "The disk size of the VM, in gigabytes."
+}
+
+variable "proxmox_ssh_user" {
+  default     = "ubuntu"
+  description = "The user cloud-init creates and terraform connects as."
+}
+
+variable "proxmox_key_path" {
+  default     = "~/.ssh/id_rsa"
+  description = "The path to the private key used to connect to the VM. The public key is read from the same path with a .pub extension."
+}
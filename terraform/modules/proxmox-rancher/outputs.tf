@@ -0,0 +1,13 @@
+output "rancher_url" {
+  value = "${local.rancher_url}"
+}
+
+output "rancher_access_key" {
+  value     = "${chomp(module.rancher_access_key.stdout)}"
+  sensitive = true
+}
+
+output "rancher_secret_key" {
+  value     = "${chomp(module.rancher_secret_key.stdout)}"
+  sensitive = true
+}
@@ -0,0 +1,116 @@
+variable "name" {
+  description = "Human readable name used as prefix to generated names."
+}
+
+variable "rancher_admin_password" {
+  description = "The Rancher admin password"
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
+
+variable "rancher_server_image" {
+  default     = "rancher/server:v2.0.0-beta2"
+  description = "The Rancher Server image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_agent_image" {
+  default     = "rancher/agent:v2.0.0-beta2"
+  description = "The Rancher Agent image to use, can be a url to a private registry leverage docker_login_* variables to authenticate to registry."
+}
+
+variable "rancher_registry" {
+  default     = ""
+  description = "The docker registry to use for rancher server and agent images"
+}
+
+variable "rancher_registry_username" {
+  default     = ""
+  description = "The username to login as."
+}
+
+variable "rancher_registry_password" {
+  default     = ""
+  description = "The password to use."
+}
+
+variable "rancher_external_url" {
+  default     = ""
+  description = "URL of Rancher through an existing load balancer or reverse proxy, e.g. https://rancher.example.com:8443. Nodes register with it. Defaults to the master's IP address on rancher_https_port."
+}
+
+variable "rancher_https_port" {
+  default     = "443"
+  description = "The port the master serves Rancher on over HTTPS."
+}
+
+variable "rancher_http_port" {
+  default     = "80"
+  description = "The port the master serves Rancher on over HTTP."
+}
+
+variable "rancher_tls_termination" {
+  default     = "rancher"
+  description = "Where TLS is terminated, rancher or proxy. With proxy, the proxy forwards HTTP requests to rancher_http_port with the X-Forwarded-Proto header."
+}
+
+variable "proxmox_api_url" {
+  description = "The URL of the Proxmox VE API, e.g. https://pve.example.com:8006/api2/json."
+}
+
+variable "proxmox_api_token_id" {
+  description = "The id of the Proxmox API token, {user}@{realm}!{token name}."
+}
+
+variable "proxmox_api_token_secret" {
+  description = "The secret of the Proxmox API token."
+}
+
+variable "proxmox_tls_insecure" {
+  default     = "false"
+  description = "Whether to skip verifying the certificate of the Proxmox API, e.g. when it's self-signed."
+}
+
+variable "proxmox_node" {
+  description = "The Proxmox node the VM runs on."
+}
+
+variable "proxmox_template_name" {
+  description = "The name of the template the VM is cloned from. It must have cloud-init and the QEMU guest agent installed."
+}
+
+variable "proxmox_storage" {
+  description = "The storage the VM disk is created in."
+}
+
+variable "proxmox_bridge" {
+  default     = "vmbr0"
+  description = "The bridge the VM's network interface is attached to. The VM gets its address with DHCP."
+}
+
+variable "proxmox_ssh_user" {
+  default     = "ubuntu"
+  description = "The user cloud-init creates and terraform connects as."
+}
+
+variable "proxmox_key_path" {
+  default     = "~/.ssh/id_rsa"
+  description = "The path to the private key used to connect to the VM. The public key is read from the same path with a .pub extension."
+}
+
+variable "master_proxmox_cores" {
+  default     = "2"
+  description = "The number of CPU cores of the Rancher master VM."
+}
+
+variable "master_proxmox_memory" {
+  default     = "4096"
+  description = "The memory of the Rancher master VM, in megabytes."
+}
+
+variable "master_proxmox_disk_size" {
+  default     = "20"
+  description = "The disk size of the Rancher master VM, in gigabytes."
+}
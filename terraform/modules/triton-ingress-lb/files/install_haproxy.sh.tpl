@@ -0,0 +1,46 @@
+#!/bin/sh
+# Runs HAProxy in front of the ingress controller of the cluster's worker nodes. The worker
+# nodes are discovered with CNS, so nodes that are added or removed later are picked up.
+
+sudo hostnamectl set-hostname ${hostname}
+
+sudo curl ${docker_engine_install_url} | sh
+
+# HAProxy resolves the CNS name with the instance's resolvers
+nameserver=$(grep -m 1 '^nameserver' /etc/resolv.conf | awk '{print $2}')
+
+sudo mkdir -p /etc/haproxy
+sudo tee /etc/haproxy/haproxy.cfg > /dev/null <<HAPROXY
+global
+	maxconn 20000
+
+resolvers cns
+	nameserver dns1 $nameserver:53
+	hold valid 10s
+
+defaults
+	mode tcp
+	timeout connect 5s
+	timeout client 1m
+	timeout server 1m
+
+frontend http
+	bind *:80
+	default_backend http
+
+frontend https
+	bind *:443
+	default_backend https
+
+backend http
+	balance roundrobin
+	server-template worker ${haproxy_max_backends} ${backend_service_name}:80 check resolvers cns init-addr none
+
+backend https
+	balance roundrobin
+	server-template worker ${haproxy_max_backends} ${backend_service_name}:443 check resolvers cns init-addr none
+HAPROXY
+
+sudo docker run -d --name haproxy --restart always --net host \
+	-v /etc/haproxy:/usr/local/etc/haproxy:ro \
+	${haproxy_image}
@@ -0,0 +1,50 @@
+provider "triton" {
+  version = "~> 0.4.2"
+
+  account      = "${var.triton_account}"
+  key_material = "${file(var.triton_key_path)}"
+  key_id       = "${var.triton_key_id}"
+  url          = "${var.triton_url}"
+}
+
+data "triton_network" "networks" {
+  count = "${length(var.triton_network_names)}"
+  name  = "${element(var.triton_network_names, count.index)}"
+}
+
+data "triton_image" "image" {
+  name    = "${var.triton_image_name}"
+  version = "${var.triton_image_version}"
+}
+
+data "triton_account" "main" {}
+
+data "triton_datacenter" "current" {}
+
+data "template_file" "install_haproxy" {
+  template = "${file("${path.module}/files/install_haproxy.sh.tpl")}"
+
+  vars {
+    hostname                  = "${var.name}-ingress-lb"
+    docker_engine_install_url = "${var.docker_engine_install_url}"
+    haproxy_image             = "${var.haproxy_image}"
+    haproxy_max_backends      = "${var.haproxy_max_backends}"
+
+    # The worker nodes of the cluster share this CNS service name
+    backend_service_name = "${var.triton_cns_service}.svc.${data.triton_account.main.id}.${data.triton_datacenter.current.name}.${var.triton_cns_suffix}"
+  }
+}
+
+resource "triton_machine" "ingress_lb" {
+  package = "${var.triton_machine_package}"
+  image   = "${data.triton_image.image.id}"
+  name    = "${var.name}-ingress-lb"
+
+  user_script = "${data.template_file.install_haproxy.rendered}"
+
+  networks = ["${data.triton_network.networks.*.id}"]
+
+  tags = {
+    role = "ingress-lb"
+  }
+}
@@ -0,0 +1,3 @@
+output "ingress_address" {
+  value = "${triton_machine.ingress_lb.primaryip}"
+}
@@ -0,0 +1,68 @@
+variable "name" {
+  description = "Name of the cluster, used as prefix of the load balancer's name."
+}
+
+variable "triton_account" {
+  description = "The Triton account name, usually the username of your root user."
+}
+
+variable "triton_key_path" {
+  description = "The path to a private key that is authorized to communicate with the Triton API."
+}
+
+variable "triton_key_id" {
+  description = "The md5 fingerprint of the key at triton_key_path. Obtained by running `ssh-keygen -E md5 -lf ~/path/to.key`"
+}
+
+variable "triton_url" {
+  default     = ""
+  description = "The CloudAPI endpoint URL. e.g. https://us-west-1.api.joyent.com"
+}
+
+variable "triton_network_names" {
+  type        = "list"
+  description = "List of Triton network names that the load balancer should be attached to. The first one should be public, and one must be shared with the worker nodes."
+
+  default = [
+    "Joyent-SDC-Public",
+  ]
+}
+
+variable "triton_image_name" {
+  default     = "ubuntu-certified-16.04"
+  description = "The name of the Triton image to use."
+}
+
+variable "triton_image_version" {
+  default     = "20170619.1"
+  description = "The version/tag of the Triton image to use."
+}
+
+variable "triton_machine_package" {
+  default     = "k4-highcpu-kvm-1.75G"
+  description = "The Triton machine package to use for the load balancer."
+}
+
+variable "triton_cns_service" {
+  description = "The CNS service name of the cluster's worker nodes."
+}
+
+variable "triton_cns_suffix" {
+  default     = "cns.joyent.com"
+  description = "The DNS suffix of the data center's CNS names for instances' private addresses."
+}
+
+variable "haproxy_image" {
+  default     = "haproxy:1.8"
+  description = "The HAProxy docker image to run."
+}
+
+variable "haproxy_max_backends" {
+  default     = "32"
+  description = "The maximum number of worker nodes the load balancer sends traffic to."
+}
+
+variable "docker_engine_install_url" {
+  default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
+  description = "The URL to the shell script to install the docker engine."
+}
@@ -37,6 +37,22 @@ data "template_file" "install_rancher_agent" {
     rancher_registry          = "${var.rancher_registry}"
     rancher_registry_username = "${var.rancher_registry_username}"
     rancher_registry_password = "${var.rancher_registry_password}"
+
+    ntp_servers = "${join(" ", var.ntp_servers)}"
+    timezone    = "${var.timezone}"
+    sysctls     = "${join("\n", formatlist("%s = %s", keys(var.sysctls), values(var.sysctls)))}"
+
+    kube_reserved   = "${join(",", formatlist("%s=%s", keys(var.kube_reserved), values(var.kube_reserved)))}"
+    system_reserved = "${join(",", formatlist("%s=%s", keys(var.system_reserved), values(var.system_reserved)))}"
+
+    k8s_audit_policy = "${var.k8s_audit_policy}"
+
+    k8s_secrets_encryption = "${var.k8s_secrets_encryption_config != "" ? "true" : "false"}"
+    k8s_kms_plugin_image   = "${var.k8s_kms_plugin_image}"
+    k8s_kms_plugin_args    = "${var.k8s_kms_plugin_args}"
+
+    hugepages     = "${var.triton_hugepages}"
+    isolated_cpus = "${var.triton_isolated_cpus}"
   }
 }
 
@@ -50,12 +66,51 @@ resource "triton_machine" "host" {
   networks = ["${data.triton_network.networks.*.id}"]
 
   cns = {
-    services = ["${element(keys(var.rancher_host_labels), 0)}.${var.hostname}"]
+    # Worker nodes also join the CNS service of the cluster's ingress load balancer
+    services = ["${compact(list(format("%s.%s", local.rancher_node_role, var.hostname), var.triton_ingress_cns_service))}"]
   }
 
   affinity = ["role!=~${element(keys(var.rancher_host_labels), 0)}"]
 
-  tags = {
-    role = "${element(keys(var.rancher_host_labels), 0)}"
+  tags = "${merge(var.triton_tags, map("role", element(keys(var.rancher_host_labels), 0)))}"
+
+  metadata = "${var.triton_metadata}"
+}
+
+# The encryption config holds the key secrets are encrypted with. It's copied over SSH rather than
+# rendered into the user data, which terraform keeps in its state.
+resource "null_resource" "k8s_secrets_encryption_config" {
+  count = "${var.k8s_secrets_encryption_config == "" ? 0 : 1}"
+
+  triggers {
+    triton_machine_id = "${triton_machine.host.id}"
+  }
+
+  connection {
+    type        = "ssh"
+    user        = "${var.triton_ssh_user}"
+    host        = "${triton_machine.host.primaryip}"
+    private_key = "${file(var.triton_ssh_key_path != "" ? var.triton_ssh_key_path : var.triton_key_path)}"
+  }
+
+  provisioner "remote-exec" {
+    inline = [
+      "umask 077 && mkdir -p /tmp/triton-kubernetes",
+    ]
+  }
+
+  provisioner "file" {
+    content     = "${base64decode(var.k8s_secrets_encryption_config)}"
+    destination = "/tmp/triton-kubernetes/encryption-config.yaml"
+  }
+
+  # The file is moved into place in one step, the install script waits for it
+  provisioner "remote-exec" {
+    inline = [
+      "sudo mkdir -p /etc/kubernetes",
+      "sudo install -m 600 -o root -g root /tmp/triton-kubernetes/encryption-config.yaml /etc/kubernetes/.encryption-config.yaml",
+      "sudo mv /etc/kubernetes/.encryption-config.yaml /etc/kubernetes/encryption-config.yaml",
+      "rm -rf /tmp/triton-kubernetes",
+    ]
   }
 }
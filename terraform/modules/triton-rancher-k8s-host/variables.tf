@@ -35,6 +35,55 @@ variable "rancher_registry_password" {
   description = "The password to use."
 }
 
+variable "ntp_servers" {
+  type        = "list"
+  default     = []
+  description = "List of NTP servers the node(s) should synchronize their clocks with. The image defaults are used when empty."
+}
+
+variable "timezone" {
+  default     = ""
+  description = "The timezone to set on the node(s), e.g. America/Vancouver. The image default is used when empty."
+}
+
+variable "sysctls" {
+  type        = "map"
+  default     = {}
+  description = "Extra sysctls to set on the node(s), in addition to the ones Kubernetes requires, e.g. {\"vm.max_map_count\" = \"262144\"}."
+}
+
+variable "kube_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for Kubernetes daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "system_reserved" {
+  type        = "map"
+  default     = {}
+  description = "Resources the kubelet reserves for system daemons on the node(s), e.g. {cpu = \"250m\", memory = \"512Mi\"}."
+}
+
+variable "k8s_audit_policy" {
+  default     = ""
+  description = "The base64 encoded Kubernetes API server audit policy, written to control nodes of clusters with an audit log."
+}
+
+variable "k8s_secrets_encryption_config" {
+  default     = ""
+  description = "The base64 encoded encryption config of the Kubernetes API server, copied over SSH to control nodes of clusters encrypting secrets at rest."
+}
+
+variable "k8s_kms_plugin_image" {
+  default     = ""
+  description = "The image of the KMS plugin run on control nodes of clusters encrypting secrets with kms."
+}
+
+variable "k8s_kms_plugin_args" {
+  default     = ""
+  description = "The arguments of the KMS plugin."
+}
+
 variable "docker_engine_install_url" {
   default     = "https://raw.githubusercontent.com/joyent/triton-kubernetes/master/scripts/docker/17.03.sh"
   description = "The URL to the shell script to install the docker engine."
@@ -55,6 +104,11 @@ variable "triton_key_id" {
   description = "The md5 fingerprint of the key at triton_key_path. Obtained by running `ssh-keygen -E md5 -lf ~/path/to.key`"
 }
 
+variable "triton_ssh_key_path" {
+  default     = ""
+  description = "The private key provisioners connect to the host with, e.g. the key generated for the cluster. Defaults to triton_key_path."
+}
+
 variable "triton_url" {
   default     = ""
   description = "The CloudAPI endpoint URL. e.g. https://us-west-1.api.joyent.com"
@@ -88,3 +142,30 @@ variable "triton_machine_package" {
   default     = "k4-highcpu-kvm-1.75G"
   description = "The Triton machine package to use for this host. Defaults to k4-highcpu-kvm-1.75G."
 }
+
+variable "triton_tags" {
+  type        = "map"
+  default     = {}
+  description = "Additional tags to set on the host, e.g. for CNS or operational tooling. The role tag is always set to the host's rancher host label."
+}
+
+variable "triton_metadata" {
+  type        = "map"
+  default     = {}
+  description = "Additional metadata to set on the host."
+}
+
+variable "triton_hugepages" {
+  default     = 0
+  description = "Number of 2 MiB hugepages to reserve on the host. Requires a KVM machine package."
+}
+
+variable "triton_isolated_cpus" {
+  default     = ""
+  description = "CPUs to isolate from the kernel scheduler for pinned workloads, e.g. 2-3. Requires a KVM machine package."
+}
+
+variable "triton_ingress_cns_service" {
+  default     = ""
+  description = "The CNS service name the ingress load balancer of the cluster sends traffic to. Only set on worker nodes."
+}
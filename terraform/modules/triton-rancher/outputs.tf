@@ -1,11 +1,13 @@
 output "rancher_url" {
-  value = "https://${local.rancher_master_ip}"
+  value = "${local.rancher_url}"
 }
 
 output "rancher_access_key" {
-  value = "${chomp(module.rancher_access_key.stdout)}"
+  value     = "${chomp(module.rancher_access_key.stdout)}"
+  sensitive = true
 }
 
 output "rancher_secret_key" {
-  value = "${chomp(module.rancher_secret_key.stdout)}"
+  value     = "${chomp(module.rancher_secret_key.stdout)}"
+  sensitive = true
 }
@@ -25,3 +25,15 @@ output "vsphere_resource_pool_name" {
 output "vsphere_network_name" {
   value = "${var.vsphere_network_name}"
 }
+
+output "k8s_audit_policy" {
+  value = "${var.k8s_audit_policy}"
+}
+
+output "k8s_kms_plugin_image" {
+  value = "${var.k8s_kms_plugin_image}"
+}
+
+output "k8s_kms_plugin_args" {
+  value = "${var.k8s_kms_plugin_args}"
+}
@@ -0,0 +1,75 @@
+package terraform
+
+import (
+	"testing"
+)
+
+func TestModuleVariables(t *testing.T) {
+	for _, module := range []string{
+		"terraform/modules/triton-rancher",
+		"github.com/joyent/triton-kubernetes//terraform/modules/triton-rancher?ref=master",
+	} {
+		variables, err := ModuleVariables(module)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		declared := map[string]bool{}
+		for _, variable := range variables {
+			declared[variable] = true
+		}
+		for _, variable := range []string{"name", "triton_account", "docker_engine_install_url"} {
+			if !declared[variable] {
+				t.Errorf("%s: expected variable %s to be declared, got %v", module, variable, variables)
+			}
+		}
+		if declared["source"] {
+			t.Errorf("%s: expected only variables, got %v", module, variables)
+		}
+	}
+
+	_, err := ModuleVariables("terraform/modules/missing")
+	if err == nil {
+		t.Error("Expected an error for an unknown module")
+	}
+}
+
+func TestAllModuleVariables(t *testing.T) {
+	entries, err := modules.ReadDir("modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		variables, err := ModuleVariables("terraform/modules/" + entry.Name())
+		if err != nil {
+			t.Errorf("%s: %v", entry.Name(), err)
+		} else if len(variables) == 0 {
+			t.Errorf("%s: expected variables", entry.Name())
+		}
+	}
+}
+
+func TestModuleVariableDefault(t *testing.T) {
+	for _, tc := range []struct {
+		module, name, expected string
+		ok                     bool
+	}{
+		{"terraform/modules/triton-rancher", "rancher_server_image", "rancher/server:v2.0.0-beta2", true},
+		{"github.com/joyent/triton-kubernetes//terraform/modules/aws-rancher?ref=master", "rancher_chart_version", "2.5.8", true},
+		{"terraform/modules/triton-rancher", "name", "", false},
+	} {
+		value, ok, err := ModuleVariableDefault(tc.module, tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != tc.expected || ok != tc.ok {
+			t.Errorf("%s %s: expected '%s' %t, got '%s' %t", tc.module, tc.name, tc.expected, tc.ok, value, ok)
+		}
+	}
+
+	_, _, err := ModuleVariableDefault("terraform/modules/triton-rancher", "missing")
+	if err == nil {
+		t.Error("Expected an error for an undeclared variable")
+	}
+}
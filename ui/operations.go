@@ -0,0 +1,191 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+
+	"github.com/joyent/triton-kubernetes/util"
+
+	homedir "github.com/mitchellh/go-homedir"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// A form field, its key is the config key of the setting
+type field struct {
+	Key     string
+	Label   string
+	Type    string // text, number, select or checkbox
+	Options []string
+}
+
+// An operation runs a triton-kubernetes command in non-interactive mode with the settings of
+// its form.
+type operation struct {
+	Name        string
+	Title       string
+	Args        []string
+	Destructive bool
+	Fields      []field
+}
+
+var (
+	clusterManagerField = field{Key: "cluster_manager", Label: "Cluster manager", Type: "text"}
+	clusterNameField    = field{Key: "cluster_name", Label: "Cluster", Type: "text"}
+	forceField          = field{Key: "force", Label: "Remove even if it breaks etcd quorum or removes the last control plane node", Type: "checkbox"}
+)
+
+var operations = []operation{
+	{
+		Name:  "create-cluster",
+		Title: "Create a cluster",
+		Args:  []string{"create", "cluster"},
+		Fields: []field{
+			clusterManagerField,
+			{Key: "name", Label: "Cluster name", Type: "text"},
+			{Key: "cluster_cloud_provider", Label: "Cloud provider", Type: "select", Options: []string{"triton", "aws", "gcp", "azure", "digitalocean", "equinixmetal", "openstack", "proxmox", "nutanix", "libvirt"}},
+		},
+	},
+	{
+		Name:  "create-node",
+		Title: "Add nodes to a cluster",
+		Args:  []string{"create", "node"},
+		Fields: []field{
+			clusterManagerField,
+			clusterNameField,
+			{Key: "rancher_host_label", Label: "Type of node", Type: "select", Options: []string{"worker", "etcd", "control"}},
+			{Key: "hostname", Label: "Hostname prefix", Type: "text"},
+			{Key: "node_count", Label: "Number of nodes", Type: "number"},
+		},
+	},
+	{
+		Name:  "get-cluster",
+		Title: "Get a cluster",
+		Args:  []string{"get", "cluster"},
+		Fields: []field{
+			clusterManagerField,
+			clusterNameField,
+		},
+	},
+	{
+		Name:  "scale-nodepool",
+		Title: "Scale a node pool",
+		Args:  []string{"scale", "nodepool"},
+		Fields: []field{
+			clusterManagerField,
+			clusterNameField,
+			{Key: "node_pool", Label: "Node pool", Type: "text"},
+			{Key: "node_count", Label: "Number of nodes", Type: "number"},
+			forceField,
+		},
+	},
+	{
+		Name:        "destroy-node",
+		Title:       "Destroy a node",
+		Args:        []string{"destroy", "node"},
+		Destructive: true,
+		Fields: []field{
+			clusterManagerField,
+			clusterNameField,
+			{Key: "hostname", Label: "Node", Type: "text"},
+			forceField,
+		},
+	},
+	{
+		Name:        "destroy-cluster",
+		Title:       "Destroy a cluster",
+		Args:        []string{"destroy", "cluster"},
+		Destructive: true,
+		Fields: []field{
+			clusterManagerField,
+			clusterNameField,
+		},
+	},
+}
+
+// Fields every form has in addition to the fields of its operation
+var backendProviderField = field{Key: "backend_provider", Label: "Backend", Type: "select", Options: []string{"local", "manta", "git"}}
+
+const (
+	configFileKey         = "config_file"
+	additionalSettingsKey = "additional_settings"
+)
+
+func findOperation(name string) (operation, bool) {
+	for _, op := range operations {
+		if op.Name == name {
+			return op, true
+		}
+	}
+	return operation{}, false
+}
+
+// Returns the settings of a submitted form. The config file is read first, then overridden
+// by the additional settings (YAML) and the fields of the form. Empty fields are left out so
+// the config file can provide them.
+func buildSettings(op operation, form url.Values) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+
+	if path := form.Get(configFileKey); path != "" {
+		expandedPath, err := homedir.Expand(path)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadFile(expandedPath)
+		if err != nil {
+			return nil, err
+		}
+
+		err = yaml.Unmarshal(content, &settings)
+		if err != nil {
+			return nil, util.ConfigError(fmt.Errorf("Invalid config file '%s': %s", path, err))
+		}
+	}
+
+	if additional := form.Get(additionalSettingsKey); additional != "" {
+		additionalSettings := map[string]interface{}{}
+		err := yaml.Unmarshal([]byte(additional), &additionalSettings)
+		if err != nil {
+			return nil, util.ConfigError(fmt.Errorf("Invalid additional settings: %s", err))
+		}
+		for key, value := range additionalSettings {
+			settings[key] = value
+		}
+	}
+
+	for _, f := range append([]field{backendProviderField}, op.Fields...) {
+		value := form.Get(f.Key)
+		if value == "" {
+			continue
+		}
+
+		switch f.Type {
+		case "number":
+			num, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a valid number. Found '%s'.", f.Key, value)
+			}
+			settings[f.Key] = num
+		case "checkbox":
+			settings[f.Key] = true
+		case "select":
+			valid := false
+			for _, option := range f.Options {
+				if value == option {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, util.ConfigError(fmt.Errorf("Invalid %s '%s'.", f.Key, value))
+			}
+			settings[f.Key] = value
+		default:
+			settings[f.Key] = value
+		}
+	}
+
+	return settings, nil
+}
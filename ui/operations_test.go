@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-ui-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(configPath, []byte("backend_provider: manta\ncluster_manager: dev-manager\nnode_count: 1\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op, _ := findOperation("scale-nodepool")
+	form := url.Values{
+		"config_file":         {configPath},
+		"additional_settings": {"node_pool: dev-w\ncluster_name: from-yaml"},
+		"cluster_name":        {"dev-cluster"},
+		"node_count":          {"3"},
+		"force":               {"true"},
+		"backend_provider":    {""},
+	}
+
+	settings, err := buildSettings(op, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"backend_provider": "manta",
+		"cluster_manager":  "dev-manager",
+		"cluster_name":     "dev-cluster",
+		"node_pool":        "dev-w",
+		"node_count":       3,
+		"force":            true,
+	}
+	for key, value := range expected {
+		if settings[key] != value {
+			t.Errorf("Wrong %s, expected %v, received %v", key, value, settings[key])
+		}
+	}
+}
+
+func TestBuildSettingsInvalid(t *testing.T) {
+	op, _ := findOperation("create-node")
+
+	invalidForms := []url.Values{
+		{"node_count": {"three"}},
+		{"rancher_host_label": {"master"}},
+		{"additional_settings": {"not: [valid"}},
+		{"config_file": {"/does/not/exist.yaml"}},
+	}
+	for _, form := range invalidForms {
+		_, err := buildSettings(op, form)
+		if err == nil {
+			t.Errorf("Expected an error for %v", form)
+		}
+	}
+}
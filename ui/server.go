@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The web interface runs the commands of the executable being run, as if they were run in a
+// terminal with --non-interactive and a config file built from the submitted form.
+type server struct {
+	address    string
+	token      string
+	executable string
+
+	// Operations run one at a time, they share the backend
+	running chan struct{}
+}
+
+// Serve serves the web interface on the given port of the loopback interface.
+func Serve(port int) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	s := &server{
+		address:    fmt.Sprintf("127.0.0.1:%d", port),
+		token:      token,
+		executable: executable,
+		running:    make(chan struct{}, 1),
+	}
+
+	fmt.Printf("Serving the web interface on http://%s, press Ctrl+C to stop.\n", s.address)
+	return http.ListenAndServe(s.address, s.handler())
+}
+
+// Returns a random token that forms must include, so other web sites can't submit them.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.HandleFunc("/run", s.run)
+	return mux
+}
+
+// Requests must be addressed to the loopback interface, which prevents DNS rebinding.
+func (s *server) validHost(r *http.Request) bool {
+	_, port, _ := net.SplitHostPort(s.address)
+	return r.Host == s.address || r.Host == "localhost:"+port
+}
+
+func (s *server) index(w http.ResponseWriter, r *http.Request) {
+	if !s.validHost(r) {
+		http.Error(w, "Invalid host", http.StatusForbidden)
+		return
+	}
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := struct {
+		Token           string
+		BackendProvider field
+		Operations      []operation
+	}{s.token, backendProviderField, operations}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := indexTemplate.Execute(w, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) run(w http.ResponseWriter, r *http.Request) {
+	if !s.validHost(r) {
+		http.Error(w, "Invalid host", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.PostFormValue("token")), []byte(s.token)) != 1 {
+		http.Error(w, "Invalid token, reload the page", http.StatusForbidden)
+		return
+	}
+
+	op, ok := findOperation(r.PostFormValue("operation"))
+	if !ok {
+		http.Error(w, "Unknown operation", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := buildSettings(op, r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	configDir, err := ioutil.TempDir("", "triton-kubernetes-ui")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(configDir)
+
+	// The settings may contain credentials
+	configPath := filepath.Join(configDir, "config.yaml")
+	content, err := yaml.Marshal(settings)
+	if err == nil {
+		err = ioutil.WriteFile(configPath, content, 0600)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case s.running <- struct{}{}:
+		defer func() { <-s.running }()
+	default:
+		http.Error(w, "Another operation is running, wait for it to finish.", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	output := &flushWriter{w: w}
+	fmt.Fprintf(output, "$ triton-kubernetes %s\n\n", strings.Join(op.Args, " "))
+
+	args := append(append([]string{}, op.Args...), "--non-interactive", "--config", configPath)
+	cmd := exec.Command(s.executable, args...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err = cmd.Run()
+	if err != nil {
+		fmt.Fprintf(output, "\n%s failed: %s\n", op.Title, err)
+		return
+	}
+	fmt.Fprintf(output, "\n%s finished.\n", op.Title)
+}
+
+// Writes command output to the response as it arrives.
+type flushWriter struct {
+	w  http.ResponseWriter
+	mu sync.Mutex
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	n, err := fw.w.Write(p)
+	if flusher, ok := fw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
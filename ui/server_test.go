@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *server {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo is not available")
+	}
+
+	return &server{
+		address:    "127.0.0.1:8080",
+		token:      "test-token",
+		executable: echo,
+		running:    make(chan struct{}, 1),
+	}
+}
+
+func postRun(s *server, host string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "http://"+host+"/run", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRun(t *testing.T) {
+	s := newTestServer(t)
+
+	recorder := postRun(s, "localhost:8080", url.Values{
+		"token":        {"test-token"},
+		"operation":    {"get-cluster"},
+		"cluster_name": {"dev-cluster"},
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Wrong status, expected %d, received %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	// echo prints the arguments the command was run with
+	body := recorder.Body.String()
+	if !strings.Contains(body, "get cluster --non-interactive --config ") {
+		t.Errorf("Command not run with a config file in non-interactive mode: %s", body)
+	}
+}
+
+func TestRunRejectsRequests(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		host     string
+		form     url.Values
+		expected int
+	}{
+		{"localhost:8080", url.Values{"operation": {"get-cluster"}}, http.StatusForbidden},
+		{"localhost:8080", url.Values{"token": {"wrong"}, "operation": {"get-cluster"}}, http.StatusForbidden},
+		{"attacker.example.com:8080", url.Values{"token": {"test-token"}, "operation": {"get-cluster"}}, http.StatusForbidden},
+		{"127.0.0.1:8080", url.Values{"token": {"test-token"}, "operation": {"unknown"}}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		recorder := postRun(s, test.host, test.form)
+		if recorder.Code != test.expected {
+			t.Errorf("Wrong status for %s %v, expected %d, received %d", test.host, test.form, test.expected, recorder.Code)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest("GET", "http://127.0.0.1:8080/", nil)
+	recorder := httptest.NewRecorder()
+	s.handler().ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Wrong status, expected %d, received %d", http.StatusOK, recorder.Code)
+	}
+	for _, op := range operations {
+		if !strings.Contains(recorder.Body.String(), op.Title) {
+			t.Errorf("Form for %q missing", op.Title)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"html/template"
+)
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>triton-kubernetes</title>
+<style>
+body { font-family: sans-serif; margin: 2em; max-width: 60em; }
+details { border: 1px solid #ccc; border-radius: 4px; margin-bottom: 1em; padding: 0.5em 1em; }
+summary { cursor: pointer; font-weight: bold; }
+label { display: block; margin-top: 0.8em; }
+input[type=text], input[type=number], select, textarea { display: block; width: 100%; box-sizing: border-box; }
+button { margin-top: 1em; }
+iframe { width: 100%; height: 30em; border: 1px solid #ccc; }
+.hint { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>triton-kubernetes</h1>
+<p class="hint">Settings left empty are read from the config file, if one is given. Settings the forms don't have, such as cloud credentials, can be given as YAML additional settings, with the keys of the silent install documentation.</p>
+{{range .Operations}}
+<details>
+<summary>{{.Title}}</summary>
+<form method="post" action="/run" target="output"{{if .Destructive}} onsubmit="return confirm('{{.Title}}? This can not be undone.')"{{end}}>
+<input type="hidden" name="token" value="{{$.Token}}">
+<input type="hidden" name="operation" value="{{.Name}}">
+{{with $.BackendProvider}}<label>{{.Label}}<select name="{{.Key}}">{{range .Options}}<option>{{.}}</option>{{end}}<option value="">(from config file)</option></select></label>{{end}}
+<label>Config file<input type="text" name="config_file" placeholder="~/triton-kubernetes.yaml"></label>
+{{range .Fields}}
+{{if eq .Type "select"}}<label>{{.Label}}<select name="{{.Key}}"><option value=""></option>{{range .Options}}<option>{{.}}</option>{{end}}</select></label>
+{{else if eq .Type "checkbox"}}<label><input type="checkbox" name="{{.Key}}" value="true"> {{.Label}}</label>
+{{else}}<label>{{.Label}}<input type="{{.Type}}" name="{{.Key}}"></label>
+{{end}}
+{{end}}
+<label>Additional settings (YAML)<textarea name="additional_settings" rows="4"></textarea></label>
+<button type="submit">{{.Title}}</button>
+</form>
+</details>
+{{end}}
+<h2>Output</h2>
+<iframe name="output"></iframe>
+</body>
+</html>
+`))
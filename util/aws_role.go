@@ -0,0 +1,115 @@
+package util
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/manifoldco/promptui"
+)
+
+var awsMFATokenRegexp = regexp.MustCompile(`^\d{6}$`)
+
+// AWSRole is an IAM role assumed with the keys of a user.
+type AWSRole struct {
+	ARN         string
+	ExternalID  string
+	SessionName string
+	MFASerial   string
+}
+
+// AWSCredentials are the temporary credentials of an assumed AWSRole.
+type AWSCredentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// Settings aws_mfa_token is read from, e.g. a config.Config.
+type awsRoleSettings interface {
+	IsSet(key string) bool
+	GetString(key string) string
+	GetBool(key string) bool
+}
+
+// MFA tokens can only be used once, so the roles assumed are reused for the rest of the run, e.g.
+// by every terraform invocation of a command. Keyed by the user's access key and the role.
+var assumedAWSRoles = map[string]AWSCredentials{}
+
+// AssumeAWSRole assumes the role with the given keys, with an MFA token, aws_mfa_token, when the
+// role has an MFA device. The temporary credentials expire, they're never stored.
+func AssumeAWSRole(conf awsRoleSettings, accessKey, secretKey string, role AWSRole) (AWSCredentials, error) {
+	assumedKey := strings.Join([]string{accessKey, role.ARN, role.ExternalID, role.SessionName, role.MFASerial}, "|")
+	if assumed, ok := assumedAWSRoles[assumedKey]; ok {
+		return assumed, nil
+	}
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(role.ARN),
+		RoleSessionName: aws.String(role.SessionName),
+	}
+	if role.ExternalID != "" {
+		input.ExternalId = aws.String(role.ExternalID)
+	}
+
+	if role.MFASerial != "" {
+		mfaToken := ""
+		if conf.IsSet("aws_mfa_token") {
+			mfaToken = conf.GetString("aws_mfa_token")
+		} else if conf.GetBool("non-interactive") {
+			return AWSCredentials{}, ConfigError(errors.New("aws_mfa_token must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label:    "AWS MFA Token of " + role.MFASerial,
+				Validate: ValidateAWSMFAToken,
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return AWSCredentials{}, err
+			}
+			mfaToken = result
+		}
+
+		err := ValidateAWSMFAToken(mfaToken)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		input.SerialNumber = aws.String(role.MFASerial)
+		input.TokenCode = aws.String(mfaToken)
+	}
+
+	awsConfig := aws.NewConfig().
+		WithCredentials(credentials.NewStaticCredentials(accessKey, secretKey, "")).
+		WithRegion(endpoints.UsEast1RegionID)
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	output, err := sts.New(sess).AssumeRole(input)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	assumed := AWSCredentials{
+		AccessKey:    aws.StringValue(output.Credentials.AccessKeyId),
+		SecretKey:    aws.StringValue(output.Credentials.SecretAccessKey),
+		SessionToken: aws.StringValue(output.Credentials.SessionToken),
+	}
+	assumedAWSRoles[assumedKey] = assumed
+	return assumed, nil
+}
+
+// ValidateAWSMFAToken checks that input is the code of an MFA device.
+func ValidateAWSMFAToken(input string) error {
+	if !awsMFATokenRegexp.MatchString(input) {
+		return errors.New("aws_mfa_token must be the 6 digit code of the MFA device")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateAWSMFAToken(t *testing.T) {
+	testCases := []struct {
+		input string
+		valid bool
+	}{
+		{"123456", true},
+		{"12345", false},
+		{"1234567", false},
+		{"12345a", false},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateAWSMFAToken(tc.input)
+		if (err == nil) != tc.valid {
+			t.Errorf("Wrong result for %q, expected valid: %v, received %v", tc.input, tc.valid, err)
+		}
+	}
+}
+
+func TestAssumeAWSRoleWithoutMFAToken(t *testing.T) {
+	conf := viper.New()
+	conf.Set("non-interactive", true)
+	role := AWSRole{ARN: "arn:aws:iam::123456789012:role/deploy", SessionName: "triton-kubernetes", MFASerial: "arn:aws:iam::123456789012:mfa/jane"}
+
+	_, err := AssumeAWSRole(conf, "AKIAEXAMPLE", "secret", role)
+	if err == nil || err.Error() != "aws_mfa_token must be specified" {
+		t.Errorf("Expected aws_mfa_token to be required, received %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/spf13/viper"
+)
+
+const (
+	awsSSMReferencePrefix            = "aws-ssm://"
+	awsSecretsManagerReferencePrefix = "aws-sm://"
+)
+
+// Overridden in tests
+var awsSecretEndpoint = ""
+
+// A config value that references an AWS SSM Parameter Store parameter, aws-ssm://{name}, or a
+// Secrets Manager secret, aws-sm://{id}#{key}. The key picks a field of a JSON secret.
+// Both accept a ?region= query, otherwise the region of the ambient AWS config is used.
+type awsSecretReference struct {
+	Service string
+	Name    string
+	Key     string
+	Region  string
+}
+
+// ResolveAWSSecretReferences replaces config values referencing AWS SSM parameters or Secrets
+// Manager secrets with their value, fetched with the ambient AWS credentials (environment,
+// shared credentials file or instance role).
+func ResolveAWSSecretReferences() error {
+	cache := map[awsSecretReference]string{}
+	fetch := func(ref awsSecretReference) (string, error) {
+		if value, ok := cache[ref]; ok {
+			return value, nil
+		}
+
+		value, err := fetchAWSSecret(ref)
+		if err != nil {
+			return "", err
+		}
+		cache[ref] = value
+
+		return value, nil
+	}
+
+	for _, key := range viper.AllKeys() {
+		value, changed, err := resolveAWSSecretReferences(viper.Get(key), fetch)
+		if err != nil {
+			return fmt.Errorf("Unable to resolve %s: %s", key, err)
+		}
+		if changed {
+			viper.Set(key, value)
+		}
+	}
+
+	return nil
+}
+
+// Replaces the references in a config value, including those nested in lists and maps
+// such as the entries of nodes.
+func resolveAWSSecretReferences(value interface{}, fetch func(awsSecretReference) (string, error)) (interface{}, bool, error) {
+	switch value := value.(type) {
+	case string:
+		ref, ok, err := parseAWSSecretReference(value)
+		if !ok || err != nil {
+			return value, false, err
+		}
+
+		secret, err := fetch(ref)
+		if err != nil {
+			return value, false, err
+		}
+
+		return secret, true, nil
+	case []interface{}:
+		changed := false
+		for i, item := range value {
+			resolved, itemChanged, err := resolveAWSSecretReferences(item, fetch)
+			if err != nil {
+				return value, false, err
+			}
+			value[i] = resolved
+			changed = changed || itemChanged
+		}
+
+		return value, changed, nil
+	case map[interface{}]interface{}:
+		changed := false
+		for key, item := range value {
+			resolved, itemChanged, err := resolveAWSSecretReferences(item, fetch)
+			if err != nil {
+				return value, false, err
+			}
+			value[key] = resolved
+			changed = changed || itemChanged
+		}
+
+		return value, changed, nil
+	case map[string]interface{}:
+		changed := false
+		for key, item := range value {
+			resolved, itemChanged, err := resolveAWSSecretReferences(item, fetch)
+			if err != nil {
+				return value, false, err
+			}
+			value[key] = resolved
+			changed = changed || itemChanged
+		}
+
+		return value, changed, nil
+	}
+
+	return value, false, nil
+}
+
+// Returns false if the value isn't a reference.
+func parseAWSSecretReference(reference string) (awsSecretReference, bool, error) {
+	ref := awsSecretReference{}
+	value := reference
+	switch {
+	case strings.HasPrefix(reference, awsSSMReferencePrefix):
+		ref.Service = "ssm"
+		value = strings.TrimPrefix(value, awsSSMReferencePrefix)
+	case strings.HasPrefix(reference, awsSecretsManagerReferencePrefix):
+		ref.Service = "secretsmanager"
+		value = strings.TrimPrefix(value, awsSecretsManagerReferencePrefix)
+	default:
+		return ref, false, nil
+	}
+
+	if i := strings.Index(value, "#"); i >= 0 {
+		ref.Key = value[i+1:]
+		value = value[:i]
+	}
+	if i := strings.Index(value, "?"); i >= 0 {
+		query, err := url.ParseQuery(value[i+1:])
+		if err != nil {
+			return ref, true, err
+		}
+		ref.Region = query.Get("region")
+		value = value[:i]
+	}
+	ref.Name = value
+
+	if ref.Name == "" {
+		return ref, true, fmt.Errorf("Reference '%s' has no name", reference)
+	}
+	if ref.Service == "ssm" && ref.Key != "" {
+		return ref, true, fmt.Errorf("Reference to SSM parameter '%s' can't pick a key, only Secrets Manager secrets can", ref.Name)
+	}
+
+	// ARNs include their region, e.g. arn:aws:secretsmanager:us-east-1:123456789012:secret:name
+	if ref.Region == "" && strings.HasPrefix(ref.Name, "arn:") {
+		parts := strings.Split(ref.Name, ":")
+		if len(parts) > 3 {
+			ref.Region = parts[3]
+		}
+	}
+
+	return ref, true, nil
+}
+
+// Calls GetParameter or GetSecretValue with the ambient AWS credentials.
+func fetchAWSSecret(ref awsSecretReference) (string, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	region := ref.Region
+	if region == "" {
+		region = aws.StringValue(sess.Config.Region)
+	}
+	if region == "" {
+		return "", fmt.Errorf("No AWS region for '%s', set AWS_REGION or add ?region= to the reference", ref.Name)
+	}
+	awsConfig := aws.NewConfig().WithRegion(region)
+	if awsSecretEndpoint != "" {
+		awsConfig.WithEndpoint(awsSecretEndpoint)
+	}
+
+	if ref.Service == "ssm" {
+		output, err := ssm.New(sess, awsConfig).GetParameter(&ssm.GetParameterInput{
+			Name:           aws.String(ref.Name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", awsSecretError(ref, err)
+		}
+
+		return aws.StringValue(output.Parameter.Value), nil
+	}
+
+	output, err := secretsmanager.New(sess, awsConfig).GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(ref.Name),
+	})
+	if err != nil {
+		return "", awsSecretError(ref, err)
+	}
+
+	return awsSecretField(ref, aws.StringValue(output.SecretString))
+}
+
+func awsSecretError(ref awsSecretReference, err error) error {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return fmt.Errorf("Unable to get '%s' from AWS: %s %s", ref.Name, awsErr.Code(), awsErr.Message())
+	}
+	return fmt.Errorf("Unable to get '%s' from AWS: %s", ref.Name, err)
+}
+
+// Returns the secret, or the field of the key of a JSON secret.
+func awsSecretField(ref awsSecretReference, secret string) (string, error) {
+	if ref.Key == "" {
+		return secret, nil
+	}
+
+	fields := map[string]interface{}{}
+	err := json.Unmarshal([]byte(secret), &fields)
+	if err != nil {
+		return "", fmt.Errorf("Secret '%s' is not a JSON object, it has no key '%s'", ref.Name, ref.Key)
+	}
+	field, ok := fields[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("Secret '%s' has no key '%s'", ref.Name, ref.Key)
+	}
+
+	return fmt.Sprintf("%v", field), nil
+}
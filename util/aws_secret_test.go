@@ -0,0 +1,128 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var parseAWSSecretReferenceTestCases = []struct {
+	Value       string
+	Expected    awsSecretReference
+	IsReference bool
+	ExpectError bool
+}{
+	{"s3cret", awsSecretReference{}, false, false},
+	{"aws-ssm:///prod/rancher/password", awsSecretReference{Service: "ssm", Name: "/prod/rancher/password"}, true, false},
+	{"aws-ssm://rancher-password?region=us-west-2", awsSecretReference{Service: "ssm", Name: "rancher-password", Region: "us-west-2"}, true, false},
+	{"aws-sm://prod/aws?region=eu-west-1#secret_key", awsSecretReference{Service: "secretsmanager", Name: "prod/aws", Key: "secret_key", Region: "eu-west-1"}, true, false},
+	{"aws-sm://arn:aws:secretsmanager:us-east-1:123456789012:secret:prod-aws", awsSecretReference{Service: "secretsmanager", Name: "arn:aws:secretsmanager:us-east-1:123456789012:secret:prod-aws", Region: "us-east-1"}, true, false},
+	{"aws-ssm://rancher-password#key", awsSecretReference{}, true, true},
+	{"aws-sm://", awsSecretReference{}, true, true},
+}
+
+func TestParseAWSSecretReference(t *testing.T) {
+	for _, tc := range parseAWSSecretReferenceTestCases {
+		ref, ok, err := parseAWSSecretReference(tc.Value)
+		if ok != tc.IsReference {
+			t.Errorf("Wrong output for %q, expected reference %t, received %t", tc.Value, tc.IsReference, ok)
+		}
+		if tc.ExpectError {
+			if err == nil {
+				t.Errorf("Expected an error for %q", tc.Value)
+			}
+			continue
+		}
+		if err != nil || ref != tc.Expected {
+			t.Errorf("Wrong output for %q, expected %+v, received %+v, %v", tc.Value, tc.Expected, ref, err)
+		}
+	}
+}
+
+func TestResolveAWSSecretReferences(t *testing.T) {
+	fetch := func(ref awsSecretReference) (string, error) {
+		if ref.Name == "missing" {
+			return "", errors.New("ParameterNotFound")
+		}
+		return "resolved-" + ref.Name, nil
+	}
+
+	nodes := []interface{}{
+		map[interface{}]interface{}{"hostname": "dev-w", "aws_secret_key": "aws-sm://prod/aws#secret_key"},
+	}
+	value, changed, err := resolveAWSSecretReferences(nodes, fetch)
+	if err != nil || !changed {
+		t.Fatalf("Expected the nodes to be resolved, received %t, %v", changed, err)
+	}
+	node := value.([]interface{})[0].(map[interface{}]interface{})
+	if node["aws_secret_key"] != "resolved-prod/aws" || node["hostname"] != "dev-w" {
+		t.Errorf("Wrong output, received %v", node)
+	}
+
+	_, changed, err = resolveAWSSecretReferences("plain", fetch)
+	if err != nil || changed {
+		t.Errorf("Expected plain value to be unchanged, received %t, %v", changed, err)
+	}
+
+	_, _, err = resolveAWSSecretReferences("aws-ssm://missing", fetch)
+	if err == nil {
+		t.Error("Expected an error for a missing parameter")
+	}
+}
+
+func TestFetchAWSSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKIATEST/") {
+			t.Errorf("Expected a request signed with the ambient credentials, received %q", r.Header.Get("Authorization"))
+		}
+
+		input := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&input)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.GetParameter":
+			if input["Name"] != "/prod/rancher/password" || input["WithDecryption"] != true {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"ParameterNotFound","message":""}`)
+				return
+			}
+			fmt.Fprint(w, `{"Parameter":{"Name":"/prod/rancher/password","Value":"pa$$word"}}`)
+		case "secretsmanager.GetSecretValue":
+			fmt.Fprint(w, `{"SecretString":"{\"access_key\":\"AKIA\",\"secret_key\":\"s3cret\"}"}`)
+		default:
+			t.Errorf("Unexpected target %q", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+	defer server.Close()
+
+	originalEndpoint := awsSecretEndpoint
+	awsSecretEndpoint = server.URL
+	defer func() { awsSecretEndpoint = originalEndpoint }()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIATEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-west-2")
+
+	value, err := fetchAWSSecret(awsSecretReference{Service: "secretsmanager", Name: "prod/aws", Key: "secret_key"})
+	if err != nil || value != "s3cret" {
+		t.Errorf("Wrong output, expected %q, received %q, %v", "s3cret", value, err)
+	}
+
+	_, err = fetchAWSSecret(awsSecretReference{Service: "secretsmanager", Name: "prod/aws", Key: "token"})
+	if err == nil || err.Error() != "Secret 'prod/aws' has no key 'token'" {
+		t.Errorf("Expected an error for a missing key, received %v", err)
+	}
+
+	value, err = fetchAWSSecret(awsSecretReference{Service: "ssm", Name: "/prod/rancher/password"})
+	if err != nil || value != "pa$$word" {
+		t.Errorf("Wrong output, expected %q, received %q, %v", "pa$$word", value, err)
+	}
+
+	_, err = fetchAWSSecret(awsSecretReference{Service: "ssm", Name: "/prod/rancher/missing"})
+	if err == nil || !strings.Contains(err.Error(), "ParameterNotFound") {
+		t.Errorf("Expected the AWS error code, received %v", err)
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"strings"
 
 	"github.com/joyent/triton-kubernetes/backend"
+	"github.com/joyent/triton-kubernetes/backend/gcs"
+	"github.com/joyent/triton-kubernetes/backend/git"
 	"github.com/joyent/triton-kubernetes/backend/local"
 	"github.com/joyent/triton-kubernetes/backend/manta"
+	"github.com/joyent/triton-kubernetes/backend/s3"
+	"github.com/joyent/triton-kubernetes/backend/tfc"
 
 	"github.com/manifoldco/promptui"
 	homedir "github.com/mitchellh/go-homedir"
@@ -18,16 +22,22 @@ import (
 func PromptForBackend() (backend.Backend, error) {
 	nonInteractiveMode := viper.GetBool("non-interactive")
 
+	// The settings of a backend of the backends section, when one is named
+	v, err := getBackendSettings(viper.GetViper(), nonInteractiveMode)
+	if err != nil {
+		return nil, err
+	}
+
 	// Ask user what backend to use
 	selectedBackendProvider := ""
-	if viper.IsSet("backend_provider") {
-		selectedBackendProvider = viper.GetString("backend_provider")
+	if v.IsSet("backend_provider") {
+		selectedBackendProvider = v.GetString("backend_provider")
 	} else if nonInteractiveMode {
 		return nil, errors.New("backend_provider must be provided")
 	} else {
 		prompt := promptui.Select{
 			Label: "Backend to persist data",
-			Items: []string{"Local", "Manta"},
+			Items: []string{"Local", "Manta", "Git", "S3", "GCS", "TFC"},
 			Templates: &promptui.SelectTemplates{
 				Label:    "{{ . }}?",
 				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
@@ -48,12 +58,18 @@ func PromptForBackend() (backend.Backend, error) {
 	case "local":
 		return local.New()
 	case "manta":
+		// Triton account, key and URL can come from a profile of the triton CLI
+		err := ApplyTritonProfile(v, nonInteractiveMode)
+		if err != nil {
+			return nil, err
+		}
+
 		// Triton Account
 		tritonAccount := ""
-		if viper.IsSet("triton_account") {
-			tritonAccount = viper.GetString("triton_account")
+		if v.IsSet("triton_account") {
+			tritonAccount = v.GetString("triton_account")
 		} else if nonInteractiveMode {
-			return nil, errors.New("triton_account must be specified")
+			return nil, ConfigError(errors.New("triton_account must be specified"))
 		} else {
 			prompt := promptui.Prompt{
 				Label: "Triton Account Name",
@@ -72,12 +88,54 @@ func PromptForBackend() (backend.Backend, error) {
 			tritonAccount = result
 		}
 
+		// Manta Auth, a token or a key
+		mantaAuth := "key"
+		if v.IsSet("manta_auth") {
+			mantaAuth = v.GetString("manta_auth")
+		}
+		if mantaAuth != "key" && mantaAuth != "token" {
+			return nil, ConfigError(fmt.Errorf("Invalid manta_auth '%s', must be 'key' or 'token'.", mantaAuth))
+		}
+
+		mantaToken := ""
+		if mantaAuth == "token" {
+			if v.IsSet("manta_token") {
+				mantaToken = v.GetString("manta_token")
+			} else if nonInteractiveMode {
+				return nil, ConfigError(errors.New("manta_token must be specified"))
+			} else {
+				prompt := promptui.Prompt{
+					Label: "Manta Token",
+					Mask:  '*',
+					Validate: func(input string) error {
+						if len(input) == 0 {
+							return errors.New("Invalid Manta Token")
+						}
+						return nil
+					},
+				}
+
+				result, err := prompt.Run()
+				if err != nil {
+					return nil, err
+				}
+				mantaToken = result
+			}
+
+			// Terraform doesn't support tokens, it signs with this key held by the SSH agent
+			if !v.IsSet("triton_key_id") {
+				return nil, ConfigError(errors.New("triton_key_id must be specified"))
+			}
+		}
+
 		// Triton Key Path
 		rawTritonKeyPath := ""
-		if viper.IsSet("triton_key_path") {
-			rawTritonKeyPath = viper.GetString("triton_key_path")
+		if mantaAuth == "token" {
+			// Requests are authenticated with the token
+		} else if v.IsSet("triton_key_path") {
+			rawTritonKeyPath = v.GetString("triton_key_path")
 		} else if nonInteractiveMode {
-			return nil, errors.New("triton_key_path must be specified")
+			return nil, ConfigError(errors.New("triton_key_path must be specified"))
 		} else {
 			prompt := promptui.Prompt{
 				Label: "Triton Key Path",
@@ -111,12 +169,10 @@ func PromptForBackend() (backend.Backend, error) {
 		}
 		tritonKeyPath := expandedTritonKeyPath
 
-		// Triton Key ID
-		tritonKeyID := ""
-		if viper.IsSet("triton_key_id") {
-			tritonKeyID = viper.GetString("triton_key_id")
-		} else {
-			keyID, err := GetPublicKeyFingerprintFromPrivateKey(tritonKeyPath)
+		// Triton Key ID, an MD5 or SHA256 fingerprint of the key or computed from it
+		tritonKeyID := v.GetString("triton_key_id")
+		if tritonKeyID != "" || !v.IsSet("triton_key_id") {
+			keyID, err := TritonKeyID("triton_key_id", tritonKeyID, tritonKeyPath)
 			if err != nil {
 				return nil, err
 			}
@@ -125,10 +181,10 @@ func PromptForBackend() (backend.Backend, error) {
 
 		// Triton URL
 		tritonURL := ""
-		if viper.IsSet("triton_url") {
-			tritonURL = viper.GetString("triton_url")
+		if v.IsSet("triton_url") {
+			tritonURL = v.GetString("triton_url")
 		} else if nonInteractiveMode {
-			return nil, errors.New("triton_url must be specified")
+			return nil, ConfigError(errors.New("triton_url must be specified"))
 		} else {
 			prompt := promptui.Prompt{
 				Label:   "Triton URL",
@@ -144,10 +200,10 @@ func PromptForBackend() (backend.Backend, error) {
 
 		// Manta URL
 		mantaURL := ""
-		if viper.IsSet("manta_url") {
-			mantaURL = viper.GetString("manta_url")
+		if v.IsSet("manta_url") {
+			mantaURL = v.GetString("manta_url")
 		} else if nonInteractiveMode {
-			return nil, errors.New("manta_url must be specified")
+			return nil, ConfigError(errors.New("manta_url must be specified"))
 		} else {
 			prompt := promptui.Prompt{
 				Label:   "Manta URL",
@@ -161,7 +217,215 @@ func PromptForBackend() (backend.Backend, error) {
 			mantaURL = result
 		}
 
-		return manta.New(tritonAccount, tritonKeyPath, tritonKeyID, tritonURL, mantaURL)
+		// Settings of on-prem Manta deployments, only read from the config
+		options := manta.Options{
+			User:                  v.GetString("manta_user"),
+			InsecureSkipTLSVerify: v.GetBool("manta_insecure_skip_tls_verify"),
+			Roles:                 v.GetStringSlice("manta_roles"),
+			RoleTags:              v.GetStringSlice("manta_role_tags"),
+			Token:                 mantaToken,
+		}
+
+		return manta.New(tritonAccount, tritonKeyPath, tritonKeyID, tritonURL, mantaURL, options)
+	case "git":
+		// Git Remote URL
+		gitRemoteURL := ""
+		if v.IsSet("git_remote_url") {
+			gitRemoteURL = v.GetString("git_remote_url")
+		} else if nonInteractiveMode {
+			return nil, ConfigError(errors.New("git_remote_url must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label: "Git Repository URL",
+				Validate: func(input string) error {
+					if len(input) == 0 {
+						return errors.New("Invalid Git Repository URL")
+					}
+					return nil
+				},
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return nil, err
+			}
+			gitRemoteURL = result
+		}
+
+		// Git Branch
+		gitBranch := "master"
+		if v.IsSet("git_branch") {
+			gitBranch = v.GetString("git_branch")
+		} else if !nonInteractiveMode {
+			prompt := promptui.Prompt{
+				Label:   "Git Branch",
+				Default: gitBranch,
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return nil, err
+			}
+			gitBranch = result
+		}
+
+		// Local clone of the repository
+		gitLocalPath := "~/.triton-kubernetes-git"
+		if v.IsSet("git_local_path") {
+			gitLocalPath = v.GetString("git_local_path")
+		}
+
+		return git.New(gitRemoteURL, gitBranch, gitLocalPath)
+	case "s3":
+		// S3 Bucket
+		s3Bucket := ""
+		if v.IsSet("s3_bucket") {
+			s3Bucket = v.GetString("s3_bucket")
+		} else if nonInteractiveMode {
+			return nil, ConfigError(errors.New("s3_bucket must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label: "S3 Bucket",
+				Validate: func(input string) error {
+					if len(input) == 0 {
+						return errors.New("Invalid S3 Bucket")
+					}
+					return nil
+				},
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return nil, err
+			}
+			s3Bucket = result
+		}
+
+		// S3 Region
+		s3Region := ""
+		if v.IsSet("s3_region") {
+			s3Region = v.GetString("s3_region")
+		} else if nonInteractiveMode {
+			return nil, ConfigError(errors.New("s3_region must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label:   "S3 Region",
+				Default: "us-east-1",
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return nil, err
+			}
+			s3Region = result
+		}
+
+		// The ambient AWS credentials are used unless keys are given, the other settings are
+		// optional and only read from the config
+		options := s3.Options{
+			Prefix:        v.GetString("s3_prefix"),
+			AccessKey:     v.GetString("s3_access_key"),
+			SecretKey:     v.GetString("s3_secret_key"),
+			DynamoDBTable: v.GetString("s3_dynamodb_table"),
+			Endpoint:      v.GetString("s3_endpoint"),
+			SSE:           v.GetString("s3_sse"),
+			KMSKeyID:      v.GetString("s3_kms_key_id"),
+		}
+
+		return s3.New(s3Bucket, s3Region, options)
+	case "gcs":
+		// GCS Bucket
+		gcsBucket := ""
+		if v.IsSet("gcs_bucket") {
+			gcsBucket = v.GetString("gcs_bucket")
+		} else if nonInteractiveMode {
+			return nil, ConfigError(errors.New("gcs_bucket must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label: "GCS Bucket",
+				Validate: func(input string) error {
+					if len(input) == 0 {
+						return errors.New("Invalid GCS Bucket")
+					}
+					return nil
+				},
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return nil, err
+			}
+			gcsBucket = result
+		}
+
+		// The application default credentials are used unless a service account key is given,
+		// the other settings are optional and only read from the config
+		options := gcs.Options{
+			Prefix:          v.GetString("gcs_prefix"),
+			CredentialsPath: v.GetString("gcs_credentials_path"),
+			Endpoint:        v.GetString("gcs_endpoint"),
+		}
+
+		return gcs.New(gcsBucket, options)
+	case "tfc":
+		// Terraform Cloud Organization
+		tfcOrganization := ""
+		if v.IsSet("tfc_organization") {
+			tfcOrganization = v.GetString("tfc_organization")
+		} else if nonInteractiveMode {
+			return nil, ConfigError(errors.New("tfc_organization must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label: "Terraform Cloud Organization",
+				Validate: func(input string) error {
+					if len(input) == 0 {
+						return errors.New("Invalid Terraform Cloud Organization")
+					}
+					return nil
+				},
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return nil, err
+			}
+			tfcOrganization = result
+		}
+
+		// Terraform Cloud Token
+		tfcToken := ""
+		if v.IsSet("tfc_token") {
+			tfcToken = v.GetString("tfc_token")
+		} else if nonInteractiveMode {
+			return nil, ConfigError(errors.New("tfc_token must be specified"))
+		} else {
+			prompt := promptui.Prompt{
+				Label: "Terraform Cloud API Token",
+				Mask:  '*',
+				Validate: func(input string) error {
+					if len(input) == 0 {
+						return errors.New("Invalid Terraform Cloud API Token")
+					}
+					return nil
+				},
+			}
+
+			result, err := prompt.Run()
+			if err != nil {
+				return nil, err
+			}
+			tfcToken = result
+		}
+
+		// The other settings are optional and only read from the config
+		options := tfc.Options{
+			Hostname:        v.GetString("tfc_hostname"),
+			WorkspacePrefix: v.GetString("tfc_workspace_prefix"),
+			ExecutionMode:   v.GetString("tfc_execution_mode"),
+			EnvVars:         v.GetStringSlice("tfc_env_vars"),
+		}
+
+		return tfc.New(tfcOrganization, tfcToken, options)
 	}
 
 	return nil, fmt.Errorf("Unsupported backend provider '%s'", selectedBackendProvider)
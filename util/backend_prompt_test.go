@@ -8,13 +8,13 @@ import (
 
 func TestBackendPromptWithUnsupportedBackendProviderNonInteractiveMode(t *testing.T) {
 	viper.Set("non-interactive", true)
-	viper.Set("backend_provider", "S3")
+	viper.Set("backend_provider", "azure-blob")
 
 	defer viper.Reset()
 
 	_,err:=PromptForBackend()
 
-	expected:= "Unsupported backend provider 'S3'"
+	expected:= "Unsupported backend provider 'azure-blob'"
 
 	if err.Error() != expected {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
@@ -105,4 +105,75 @@ func TestNoMantaURLForNonInteractiveMode (t *testing.T) {
 	if err.Error() != expected {
 		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
 	}
-}
\ No newline at end of file
+}
+func TestBackendPromptWithNoGitRemoteURLNonInteractiveMode(t *testing.T) {
+	viper.Set("non-interactive", true)
+	viper.Set("backend_provider", "git")
+
+	defer viper.Reset()
+
+	_, err := PromptForBackend()
+
+	expected := "git_remote_url must be specified"
+
+	if err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
+	}
+}
+
+func TestBackendPromptWithNoS3BucketNonInteractiveMode(t *testing.T) {
+	viper.Set("non-interactive", true)
+	viper.Set("backend_provider", "s3")
+
+	defer viper.Reset()
+
+	_, err := PromptForBackend()
+
+	expected := "s3_bucket must be specified"
+
+	if err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
+	}
+}
+
+func TestBackendPromptWithMantaTokenNonInteractiveMode(t *testing.T) {
+	viper.Set("non-interactive", true)
+	viper.Set("backend_provider", "manta")
+	viper.Set("triton_account", "xyz")
+	viper.Set("manta_auth", "token")
+
+	defer viper.Reset()
+
+	_, err := PromptForBackend()
+
+	expected := "manta_token must be specified"
+
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+
+	viper.Set("manta_token", "c2VjcmV0")
+
+	_, err = PromptForBackend()
+
+	expected = "triton_key_id must be specified"
+
+	if err == nil || err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %v", expected, err)
+	}
+}
+
+func TestBackendPromptWithNoGCSBucketNonInteractiveMode(t *testing.T) {
+	viper.Set("non-interactive", true)
+	viper.Set("backend_provider", "gcs")
+
+	defer viper.Reset()
+
+	_,err:=PromptForBackend()
+
+	expected:= "gcs_bucket must be specified"
+
+	if err.Error() != expected {
+		t.Errorf("Wrong output, expected %s, received %s", expected, err.Error())
+	}
+}
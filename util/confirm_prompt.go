@@ -33,3 +33,18 @@ func PromptForConfirmation(label, selected string) (bool, error) {
 
 	return confirmOptions[i].Value, nil
 }
+
+// Returns true if the user types the name of the resource, like a repository deletion on GitHub.
+// Anything else cancels the operation.
+func PromptForNameConfirmation(resource, name string) (bool, error) {
+	prompt := promptui.Prompt{
+		Label: fmt.Sprintf("Type the name of the %s, %q, to confirm", resource, name),
+	}
+
+	result, err := prompt.Run()
+	if err != nil {
+		return false, err
+	}
+
+	return result == name, nil
+}
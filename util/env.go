@@ -0,0 +1,42 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var envPlaceholderRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Replaces ${VAR} placeholders with the value of the environment variable VAR, so secrets can
+// be kept out of config files. Unlike os.ExpandEnv, a lone $ (e.g. in a password) is left as is.
+// Variables that aren't set are a config error, rather than silently becoming empty values.
+func ExpandEnvPlaceholders(content []byte) ([]byte, error) {
+	missing := []string{}
+	expanded := envPlaceholderRegexp.ReplaceAllFunc(content, func(placeholder []byte) []byte {
+		name := string(envPlaceholderRegexp.FindSubmatch(placeholder)[1])
+		value, ok := os.LookupEnv(name)
+		if !ok && !containsString(missing, name) {
+			missing = append(missing, name)
+		}
+		return []byte(value)
+	})
+
+	if len(missing) == 1 {
+		return nil, ConfigError(fmt.Errorf("The environment variable %s of the config placeholder ${%s} isn't set.", missing[0], missing[0]))
+	}
+	if len(missing) > 1 {
+		return nil, ConfigError(fmt.Errorf("The environment variables %s of config placeholders aren't set.", strings.Join(missing, ", ")))
+	}
+	return expanded, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandEnvPlaceholders(t *testing.T) {
+	os.Setenv("TK_TEST_SECRET", "s3cret")
+	defer os.Unsetenv("TK_TEST_SECRET")
+	os.Setenv("TK_TEST_EMPTY", "")
+	defer os.Unsetenv("TK_TEST_EMPTY")
+
+	input := "aws_secret_key: ${TK_TEST_SECRET}\nrancher_admin_password: pa$$word\nempty: \"${TK_TEST_EMPTY}\"\n"
+	expected := "aws_secret_key: s3cret\nrancher_admin_password: pa$$word\nempty: \"\"\n"
+
+	output, err := ExpandEnvPlaceholders([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != expected {
+		t.Errorf("Wrong output, expected %q, received %q", expected, output)
+	}
+}
+
+func TestExpandEnvPlaceholdersUnset(t *testing.T) {
+	os.Unsetenv("TK_TEST_UNSET")
+	os.Unsetenv("TK_TEST_OTHER_UNSET")
+
+	_, err := ExpandEnvPlaceholders([]byte("a: ${TK_TEST_UNSET}\nb: ${TK_TEST_UNSET}\n"))
+	expected := "The environment variable TK_TEST_UNSET of the config placeholder ${TK_TEST_UNSET} isn't set."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, received %v", expected, err)
+	}
+	if ExitCode(err) != ExitCodeConfig {
+		t.Errorf("Expected a config error, received exit code %d", ExitCode(err))
+	}
+
+	_, err = ExpandEnvPlaceholders([]byte("a: ${TK_TEST_UNSET}\nb: ${TK_TEST_OTHER_UNSET}\n"))
+	expected = "The environment variables TK_TEST_UNSET, TK_TEST_OTHER_UNSET of config placeholders aren't set."
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, received %v", expected, err)
+	}
+}
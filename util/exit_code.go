@@ -0,0 +1,88 @@
+package util
+
+import "errors"
+
+// Exit codes of triton-kubernetes, so that automation can tell failures apart. They're part of
+// the interface of the CLI and keep their meaning between releases.
+const (
+	// ExitCodeError is any failure without a more specific exit code.
+	ExitCodeError = 1
+	// ExitCodeConfig is a missing or invalid setting, config file or command line.
+	ExitCodeConfig = 2
+	// ExitCodeAuth is a cloud provider rejecting the credentials.
+	ExitCodeAuth = 3
+	// ExitCodeTerraform is terraform failing.
+	ExitCodeTerraform = 4
+	// ExitCodeTimeout is Rancher or the nodes not getting to the expected state in time.
+	ExitCodeTimeout = 5
+	// ExitCodePartialSuccess is an operation that made some of its changes, e.g. created some
+	// of the new nodes.
+	ExitCodePartialSuccess = 6
+	// ExitCodeInterrupted is an interrupt with Ctrl-C, as for any interrupted process.
+	ExitCodeInterrupted = 130
+)
+
+// CodedError is an error with the exit code the CLI exits with when a command fails with it.
+type CodedError struct {
+	Err  error
+	Code int
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+// ExitCode returns the exit code of the error.
+func (e *CodedError) ExitCode() int {
+	return e.Code
+}
+
+// Unwrap returns the error that was marked.
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError marks err as a missing or invalid setting.
+func ConfigError(err error) error {
+	return &CodedError{Err: err, Code: ExitCodeConfig}
+}
+
+// AuthError marks err as credentials rejected by a cloud provider.
+func AuthError(err error) error {
+	return &CodedError{Err: err, Code: ExitCodeAuth}
+}
+
+// TerraformError marks err as a terraform failure.
+func TerraformError(err error) error {
+	return &CodedError{Err: err, Code: ExitCodeTerraform}
+}
+
+// TimeoutError marks err as a wait that timed out.
+func TimeoutError(err error) error {
+	return &CodedError{Err: err, Code: ExitCodeTimeout}
+}
+
+// PartialSuccessError marks err as the failure of an operation that made some of its changes.
+func PartialSuccessError(err error) error {
+	return &CodedError{Err: err, Code: ExitCodePartialSuccess}
+}
+
+// ExitCode returns the exit code the CLI exits with when a command fails with err, 0 for nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if IsInterrupt(err) {
+		return ExitCodeInterrupted
+	}
+
+	// The outermost error with an exit code of the chain of wrapped errors
+	var coded interface {
+		ExitCode() int
+	}
+	if errors.As(err, &coded) {
+		return coded.ExitCode()
+	}
+
+	return ExitCodeError
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/manifoldco/promptui"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{nil, 0},
+		{errors.New("Unable to reach Rancher."), ExitCodeError},
+		{ConfigError(errors.New("aws_region must be specified")), ExitCodeConfig},
+		{ConfigError(errors.New("Invalid manager_host_count '2', must be 1 or 3.")), ExitCodeConfig},
+		// The code isn't guessed from the message
+		{errors.New("Invalid RKE state of cluster 'c-abcde'"), ExitCodeError},
+		// Wrapped errors keep their code
+		{fmt.Errorf("%w\nThe state of cluster manager 'dev' was saved.", TerraformError(errors.New("exit status 1"))), ExitCodeTerraform},
+		{fmt.Errorf("Unable to create node: %w", &InterruptedError{}), ExitCodeInterrupted},
+		{ConfigError(errors.New("yaml: line 2: mapping values are not allowed")), ExitCodeConfig},
+		{AuthError(errors.New("AWS rejected aws_access_key")), ExitCodeAuth},
+		{TerraformError(errors.New("exit status 1")), ExitCodeTerraform},
+		{TimeoutError(errors.New("Only 2 of 3 nodes became active after 15m0s")), ExitCodeTimeout},
+		{PartialSuccessError(errors.New("1 of 3 new nodes failed")), ExitCodePartialSuccess},
+		{promptui.ErrInterrupt, ExitCodeInterrupted},
+		{&InterruptedError{}, ExitCodeInterrupted},
+	}
+
+	for _, test := range tests {
+		if code := ExitCode(test.err); code != test.expected {
+			t.Errorf("Expected exit code %d for %v, got %d", test.expected, test.err, code)
+		}
+	}
+
+	// The message of a marked error is kept
+	err := AuthError(errors.New("AWS rejected aws_access_key"))
+	if err.Error() != "AWS rejected aws_access_key" {
+		t.Errorf("Unexpected message %s", err.Error())
+	}
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// InterruptedError is returned by operations interrupted at a prompt, with what they changed
+// before the interrupt. Changes that weren't persisted yet are discarded.
+type InterruptedError struct {
+	Changes []string
+}
+
+func (e *InterruptedError) Error() string {
+	if len(e.Changes) == 0 {
+		return "Interrupted, nothing was changed."
+	}
+
+	return "Interrupted, after the following changes were made:\n  " + strings.Join(e.Changes, "\n  ")
+}
+
+// IsInterrupt returns true if the error is from interrupting a prompt, with Ctrl-C or Ctrl-D.
+func IsInterrupt(err error) bool {
+	var interrupted *InterruptedError
+	if errors.As(err, &interrupted) {
+		return true
+	}
+
+	return errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF)
+}
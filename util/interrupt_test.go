@@ -0,0 +1,35 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/manifoldco/promptui"
+)
+
+func TestIsInterrupt(t *testing.T) {
+	for _, err := range []error{promptui.ErrInterrupt, promptui.ErrEOF, &InterruptedError{}} {
+		if !IsInterrupt(err) {
+			t.Errorf("Expected %v to be an interrupt", err)
+		}
+	}
+
+	for _, err := range []error{nil, promptui.ErrAbort, errors.New("^C")} {
+		if IsInterrupt(err) {
+			t.Errorf("Expected %v not to be an interrupt", err)
+		}
+	}
+}
+
+func TestInterruptedError(t *testing.T) {
+	err := &InterruptedError{}
+	if err.Error() != "Interrupted, nothing was changed." {
+		t.Errorf("Unexpected message %s", err.Error())
+	}
+
+	err = &InterruptedError{Changes: []string{"dev-manager: created cluster manager"}}
+	if !strings.HasSuffix(err.Error(), "\n  dev-manager: created cluster manager") {
+		t.Errorf("Expected the changes to be listed, got %s", err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// Returns the backend settings, e.g. backend_provider and s3_bucket, of the backend named by the
+// backend setting or --backend, one of those in the backends section of the config. They're
+// isolated from the rest of the config: neither the top level backend settings nor settings
+// shared with cloud providers, e.g. triton_account, are read or changed. The top level settings
+// are returned when no backend is named. In interactive mode, without top level backend
+// settings, one of the backends is selected.
+func getBackendSettings(v *viper.Viper, nonInteractiveMode bool) (*viper.Viper, error) {
+	backends := v.GetStringMap("backends")
+	names := []string{}
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	name := ""
+	if v.IsSet("backend") {
+		// Keys of the config are case insensitive
+		name = strings.ToLower(v.GetString("backend"))
+	} else if len(backends) == 0 || v.IsSet("backend_provider") {
+		return v, nil
+	} else if nonInteractiveMode {
+		return nil, ConfigError(errors.New("backend must be specified"))
+	} else {
+		prompt := promptui.Select{
+			Label: "Backend",
+			Items: names,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Backend:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		name = value
+	}
+
+	rawSettings, ok := backends[name]
+	if !ok {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("Backend '%s' does not exist, the config has no backends.", name)
+		}
+		return nil, fmt.Errorf("Backend '%s' does not exist, the backends are %s.", name, strings.Join(names, ", "))
+	}
+	settings, err := cast.ToStringMapE(rawSettings)
+	if err != nil {
+		return nil, ConfigError(fmt.Errorf("Invalid settings of backend '%s': %v", name, err))
+	}
+	if _, ok := settings["backend_provider"]; !ok {
+		return nil, ConfigError(fmt.Errorf("backend_provider must be specified for backend '%s'", name))
+	}
+
+	backendSettings := viper.New()
+	for key, value := range settings {
+		backendSettings.Set(key, value)
+	}
+
+	return backendSettings, nil
+}
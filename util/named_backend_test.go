@@ -0,0 +1,96 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetBackendSettings(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("backend_provider", "s3")
+	viper.Set("s3_dynamodb_table", "dev-locks")
+	viper.Set("triton_account", "dev")
+	viper.Set("backends", map[string]interface{}{
+		"prod":  map[interface{}]interface{}{"backend_provider": "s3", "s3_bucket": "prod-state", "s3_region": "us-west-2"},
+		"manta": map[string]interface{}{"backend_provider": "manta", "triton_account": "ops"},
+	})
+
+	// The top level backend is used unless one is named
+	v, err := getBackendSettings(viper.GetViper(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GetString("backend_provider") != "s3" || v.GetString("s3_dynamodb_table") != "dev-locks" || v.IsSet("s3_bucket") {
+		t.Errorf("Expected the top level backend, got %s", v.GetString("backend_provider"))
+	}
+
+	// Top level backend settings don't leak into a named backend
+	viper.Set("backend", "Prod")
+	v, err = getBackendSettings(viper.GetViper(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GetString("backend_provider") != "s3" || v.GetString("s3_bucket") != "prod-state" || v.GetString("s3_region") != "us-west-2" {
+		t.Errorf("Expected the settings of the prod backend, got %s %s", v.GetString("backend_provider"), v.GetString("s3_bucket"))
+	}
+	if v.IsSet("s3_dynamodb_table") || v.IsSet("triton_account") {
+		t.Errorf("Expected only the settings of the prod backend, got s3_dynamodb_table %s and triton_account %s", v.GetString("s3_dynamodb_table"), v.GetString("triton_account"))
+	}
+
+	// Settings shared with the Triton provider are left alone
+	viper.Set("backend", "manta")
+	v, err = getBackendSettings(viper.GetViper(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GetString("triton_account") != "ops" || viper.GetString("triton_account") != "dev" {
+		t.Errorf("Expected triton_account ops for the backend and dev for the provider, got %s and %s", v.GetString("triton_account"), viper.GetString("triton_account"))
+	}
+	if viper.GetString("backend_provider") != "s3" {
+		t.Errorf("Expected the top level backend_provider to be left alone, got %s", viper.GetString("backend_provider"))
+	}
+}
+
+func TestGetBackendSettingsErrors(t *testing.T) {
+	tests := []struct {
+		testName string
+		settings map[string]interface{}
+		expected string
+	}{
+		{
+			"Missing backend",
+			map[string]interface{}{"backend": "staging", "backends": map[string]interface{}{"dev": map[string]interface{}{"backend_provider": "local"}, "prod": map[string]interface{}{"backend_provider": "local"}}},
+			"Backend 'staging' does not exist, the backends are dev, prod.",
+		},
+		{
+			"No backends",
+			map[string]interface{}{"backend": "staging"},
+			"Backend 'staging' does not exist, the config has no backends.",
+		},
+		{
+			"Unnamed backend",
+			map[string]interface{}{"backends": map[string]interface{}{"dev": map[string]interface{}{"backend_provider": "local"}}},
+			"backend must be specified",
+		},
+		{
+			"Backend without provider",
+			map[string]interface{}{"backend": "dev", "backends": map[string]interface{}{"dev": map[string]interface{}{"s3_bucket": "dev-state"}}},
+			"backend_provider must be specified for backend 'dev'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			defer viper.Reset()
+			for key, value := range test.settings {
+				viper.Set(key, value)
+			}
+
+			_, err := getBackendSettings(viper.GetViper(), true)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("Wrong output, expected %s, received %v", test.expected, err)
+			}
+		})
+	}
+}
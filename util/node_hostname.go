@@ -0,0 +1,7 @@
+package util
+
+import "regexp"
+
+// NodeHostnameSuffixRegexp matches the number of a node hostname. Hostnames of nodes are
+// `{hostname prefix}-{number}`, nodes sharing a prefix are a pool named after the prefix.
+var NodeHostnameSuffixRegexp = regexp.MustCompile(`-\d+$`)
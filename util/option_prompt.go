@@ -0,0 +1,109 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+type promptSettings interface {
+	IsSet(key string) bool
+	GetString(key string) string
+	GetBool(key string) bool
+}
+
+// PromptOption is a choice of PromptForOption, e.g. backed by the API of a cloud provider. Value
+// is what's stored in the config and Label what the user sees. Name, if any, is what the entity
+// is called, which can be set instead of the value, e.g. of a UUID, when it's unique.
+type PromptOption struct {
+	Value string
+	Name  string
+	Label string
+}
+
+// PromptForOption returns the value of the given key, which must be one of the options. The user
+// selects one when the key isn't set.
+func PromptForOption(conf promptSettings, key, label string, options []PromptOption) (string, error) {
+	if conf.IsSet(key) {
+		value := conf.GetString(key)
+		matches := []string{}
+		for _, option := range options {
+			if option.Value == value {
+				return value, nil
+			}
+			if option.Name != "" && option.Name == value {
+				matches = append(matches, option.Value)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return "", fmt.Errorf("Selected %s '%s' does not exist.", label, value)
+		case 1:
+			return matches[0], nil
+		default:
+			return "", fmt.Errorf("More than one %s is named '%s', set %s to one of: %s", label, value, key, strings.Join(matches, ", "))
+		}
+	} else if conf.GetBool("non-interactive") {
+		return "", ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	if len(options) == 0 {
+		return "", fmt.Errorf("No %s available", label)
+	}
+
+	searcher := func(input string, index int) bool {
+		name := strings.Replace(strings.ToLower(options[index].Label), " ", "", -1)
+		input = strings.Replace(strings.ToLower(input), " ", "", -1)
+
+		return strings.Contains(name, input)
+	}
+
+	prompt := promptui.Select{
+		Label: label,
+		Items: options,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ .Label }}?",
+			Active:   fmt.Sprintf(`%s {{ .Label | underline }}`, promptui.IconSelect),
+			Inactive: `  {{ .Label }}`,
+			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "%s:" | bold}} {{ .Label }}`, promptui.IconGood, label),
+		},
+		Searcher: searcher,
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return options[i].Value, nil
+}
+
+// PromptForValue returns the value of the given key. The user types it, masked if it's a secret,
+// when the key isn't set, and the default is used in non-interactive mode.
+func PromptForValue(conf promptSettings, key, label, defaultValue string, secret bool) (string, error) {
+	if conf.IsSet(key) {
+		return conf.GetString(key), nil
+	} else if defaultValue != "" && conf.GetBool("non-interactive") {
+		return defaultValue, nil
+	} else if conf.GetBool("non-interactive") {
+		return "", ConfigError(fmt.Errorf("%s must be specified", key))
+	}
+
+	prompt := promptui.Prompt{
+		Label:   label,
+		Default: defaultValue,
+		Validate: func(input string) error {
+			if input == "" {
+				return fmt.Errorf("%s cannot be blank", label)
+			}
+			return nil
+		},
+	}
+	if secret {
+		prompt.Mask = '*'
+	}
+
+	return prompt.Run()
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPromptForOption(t *testing.T) {
+	options := []PromptOption{
+		{Value: "0b3b2f4c", Name: "default", Label: "default (0b3b2f4c)"},
+		{Value: "6f1d8a90", Name: "prod", Label: "prod (6f1d8a90)"},
+		{Value: "9c2e4d17", Name: "prod", Label: "prod (9c2e4d17)"},
+	}
+
+	tests := []struct {
+		value    string
+		expected string
+		err      string
+	}{
+		{value: "6f1d8a90", expected: "6f1d8a90"},
+		{value: "default", expected: "0b3b2f4c"},
+		{value: "prod", err: "More than one Subnet is named 'prod', set subnet to one of: 6f1d8a90, 9c2e4d17"},
+		{value: "missing", err: "Selected Subnet 'missing' does not exist."},
+	}
+	for _, test := range tests {
+		settings := viper.New()
+		settings.Set("subnet", test.value)
+
+		value, err := PromptForOption(settings, "subnet", "Subnet", options)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: expected error %q, got %v", test.value, test.err, err)
+			}
+			continue
+		}
+		if err != nil || value != test.expected {
+			t.Errorf("%s: expected %s, got %s %v", test.value, test.expected, value, err)
+		}
+	}
+
+	settings := viper.New()
+	settings.Set("non-interactive", true)
+	_, err := PromptForOption(settings, "subnet", "Subnet", options)
+	if err == nil || !strings.Contains(err.Error(), "subnet must be specified") || ExitCode(err) != ExitCodeConfig {
+		t.Errorf("Expected a config error for a missing setting, got %v", err)
+	}
+}
+
+func TestPromptForValue(t *testing.T) {
+	settings := viper.New()
+	settings.Set("non-interactive", true)
+
+	value, err := PromptForValue(settings, "region", "Region", "RegionOne", false)
+	if err != nil || value != "RegionOne" {
+		t.Errorf("Expected the default in non-interactive mode, got %s %v", value, err)
+	}
+
+	_, err = PromptForValue(settings, "user", "User", "", false)
+	if err == nil || !strings.Contains(err.Error(), "user must be specified") {
+		t.Errorf("Expected an error for a missing setting without default, got %v", err)
+	}
+
+	settings.Set("user", "admin")
+	value, err = PromptForValue(settings, "user", "User", "", false)
+	if err != nil || value != "admin" {
+		t.Errorf("Expected the setting, got %s %v", value, err)
+	}
+}
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"fmt"
+)
+
+// CheckNodeRemoval returns an error explaining why removing the given number of etcd and
+// control plane nodes from a cluster would break it, or nil if it is safe. operation describes
+// what removes the nodes, e.g. `Destroying "node-1"`.
+func CheckNodeRemoval(operation string, etcdNodes, controlNodes, etcdRemoved, controlRemoved int) error {
+	// etcd needs a majority of its members to accept writes
+	quorum := etcdNodes/2 + 1
+	if etcdRemoved > 0 && etcdNodes-etcdRemoved < quorum {
+		return fmt.Errorf("%s would leave %d of %d etcd members, below the quorum of %d. Without a quorum etcd can't accept writes and the cluster stops working. Add etcd nodes first, or use --force to remove them anyway.",
+			operation, etcdNodes-etcdRemoved, etcdNodes, quorum)
+	}
+
+	if controlRemoved > 0 && controlNodes-controlRemoved < 1 {
+		return fmt.Errorf("%s would remove the last control plane node, which runs the Kubernetes API. Add a control node first, or use --force to remove it anyway.", operation)
+	}
+
+	return nil
+}
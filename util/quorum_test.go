@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestCheckNodeRemoval(t *testing.T) {
+	tests := []struct {
+		name                       string
+		etcdNodes, controlNodes    int
+		etcdRemoved, controlRemove int
+		expectError                bool
+	}{
+		{"remove one of three etcd members", 3, 1, 1, 0, false},
+		{"remove two of three etcd members", 3, 1, 2, 0, true},
+		{"remove one of two etcd members", 2, 1, 1, 0, true},
+		{"remove the only etcd member", 1, 1, 1, 0, true},
+		{"remove one of two control nodes", 3, 2, 0, 1, false},
+		{"remove the last control node", 3, 1, 0, 1, true},
+		{"remove workers", 1, 1, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		err := CheckNodeRemoval("Destroying", test.etcdNodes, test.controlNodes, test.etcdRemoved, test.controlRemove)
+		if test.expectError && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		} else if !test.expectError && err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+		}
+	}
+}
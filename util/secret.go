@@ -0,0 +1,131 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	encryptedSecretPrefix = "encrypted:v1:"
+
+	// Used when state_encryption_key isn't set, created the first time a secret is encrypted
+	stateEncryptionKeyPath = "~/.triton-kubernetes/state_encryption_key"
+)
+
+// Settings that state_encryption_key is read from, e.g. a config.Config.
+type secretSettings interface {
+	GetString(key string) string
+}
+
+// EncryptSecret encrypts a secret before it is stored in the state, with AES-256-GCM and the
+// state encryption key of conf.
+func EncryptSecret(conf secretSettings, plaintext string) (string, error) {
+	gcm, err := stateEncryptionCipher(conf, true)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts a secret encrypted by EncryptSecret, with the state encryption key of conf.
+func DecryptSecret(conf secretSettings, ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, encryptedSecretPrefix) {
+		return "", errors.New("Secret is not encrypted by triton-kubernetes")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, encryptedSecretPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := stateEncryptionCipher(conf, false)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("Secret is truncated")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("Unable to decrypt secret, state_encryption_key doesn't match the key it was encrypted with")
+	}
+
+	return string(plaintext), nil
+}
+
+// The key is read from state_encryption_key (or the STATE_ENCRYPTION_KEY environment
+// variable), then from the key file. When create is true a missing key file is generated.
+func stateEncryptionCipher(conf secretSettings, create bool) (cipher.AEAD, error) {
+	key := conf.GetString("state_encryption_key")
+	if key == "" {
+		expandedKeyPath, err := homedir.Expand(stateEncryptionKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadFile(expandedKeyPath)
+		if os.IsNotExist(err) && create {
+			content, err = newStateEncryptionKeyFile(expandedKeyPath)
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("The state contains encrypted secrets, set state_encryption_key or copy %s from the machine that encrypted them", stateEncryptionKeyPath)
+			}
+			return nil, err
+		}
+		key = strings.TrimSpace(string(content))
+	}
+
+	// Any passphrase can be used as the key
+	hashedKey := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(hashedKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func newStateEncryptionKeyFile(path string) ([]byte, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	content := []byte(hex.EncodeToString(b))
+	err = ioutil.WriteFile(path, content, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Generated a state encryption key in %s, it is needed to use this cluster manager from other machines.\n", stateEncryptionKeyPath)
+	return content, nil
+}
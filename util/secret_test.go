@@ -0,0 +1,45 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	conf := viper.New()
+	conf.Set("state_encryption_key", "correct horse battery staple")
+
+	encrypted, err := EncryptSecret(conf, "token-abcde:s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, encryptedSecretPrefix) || strings.Contains(encrypted, "s3cret") {
+		t.Errorf("Expected an encrypted secret, received %q", encrypted)
+	}
+
+	decrypted, err := DecryptSecret(conf, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted != "token-abcde:s3cret" {
+		t.Errorf("Wrong output, expected %q, received %q", "token-abcde:s3cret", decrypted)
+	}
+
+	conf.Set("state_encryption_key", "another key")
+	_, err = DecryptSecret(conf, encrypted)
+	if err == nil {
+		t.Error("Expected an error decrypting with another key")
+	}
+}
+
+func TestDecryptSecretRequiresEncryptedSecret(t *testing.T) {
+	conf := viper.New()
+	conf.Set("state_encryption_key", "correct horse battery staple")
+
+	_, err := DecryptSecret(conf, "token-abcde:s3cret")
+	if err == nil {
+		t.Error("Expected an error for a plain text secret")
+	}
+}
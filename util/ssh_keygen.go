@@ -0,0 +1,117 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joyent/triton-kubernetes/fips"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+const rsaKeyBits = 4096
+
+// GenerateSSHKeyPair creates an SSH key pair of the given type, ed25519 or rsa, at
+// privateKeyPath and privateKeyPath.pub, and returns the path of the public key. The directory is
+// created if needed and only readable by the user, existing keys aren't overwritten.
+func GenerateSSHKeyPair(keyType, privateKeyPath, comment string) (string, error) {
+	publicKeyPath := privateKeyPath + ".pub"
+	for _, path := range []string{privateKeyPath, publicKeyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return "", fmt.Errorf("Unable to generate an SSH key pair, %s already exists.", path)
+		}
+	}
+
+	var publicKey interface{}
+	var privateKeyPEM *pem.Block
+	switch keyType {
+	case "rsa":
+		rsaKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return "", err
+		}
+		publicKey = &rsaKey.PublicKey
+		privateKeyPEM = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+	case "ed25519":
+		if fips.Enabled() {
+			return "", fmt.Errorf("ed25519 SSH keys aren't allowed in FIPS mode, use an rsa key")
+		}
+		ed25519PublicKey, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		publicKey = ed25519PublicKey
+		privateKeyPEM = &pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: marshalED25519PrivateKey(ed25519PublicKey, ed25519Key, comment)}
+	default:
+		return "", ConfigError(fmt.Errorf("Invalid ssh_key_type '%s', must be 'rsa' or 'ed25519'.", keyType))
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	authorizedKey := ssh.MarshalAuthorizedKey(sshPublicKey)
+	if comment != "" {
+		authorizedKey = append(authorizedKey[:len(authorizedKey)-1], []byte(" "+comment+"\n")...)
+	}
+
+	err = os.MkdirAll(filepath.Dir(privateKeyPath), 0700)
+	if err != nil {
+		return "", err
+	}
+	err = ioutil.WriteFile(privateKeyPath, pem.EncodeToMemory(privateKeyPEM), 0600)
+	if err != nil {
+		return "", err
+	}
+	err = ioutil.WriteFile(publicKeyPath, authorizedKey, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return publicKeyPath, nil
+}
+
+// Returns an unencrypted ed25519 key in the openssh-key-v1 format, the only format OpenSSH
+// reads ed25519 keys in. See https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key
+func marshalED25519PrivateKey(publicKey ed25519.PublicKey, privateKey ed25519.PrivateKey, comment string) []byte {
+	// The check ints let OpenSSH tell a wrong passphrase, they're random and equal
+	checkBytes := make([]byte, 4)
+	rand.Read(checkBytes)
+	check := binary.BigEndian.Uint32(checkBytes)
+
+	privateBlock := ssh.Marshal(struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+		Pub     []byte
+		Priv    []byte
+		Comment string
+	}{check, check, ssh.KeyAlgoED25519, publicKey, privateKey, comment})
+	// Padded to the 8 byte block size of the "none" cipher with 1, 2, 3...
+	for i := 1; len(privateBlock)%8 != 0; i++ {
+		privateBlock = append(privateBlock, byte(i))
+	}
+
+	sshPublicKey := ssh.Marshal(struct {
+		Keytype string
+		Pub     []byte
+	}{ssh.KeyAlgoED25519, publicKey})
+
+	key := append([]byte("openssh-key-v1"), 0)
+	return append(key, ssh.Marshal(struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{"none", "none", "", 1, sshPublicKey, privateBlock})...)
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateSSHKeyPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, keyType := range []string{"rsa", "ed25519"} {
+		privateKeyPath := filepath.Join(dir, "keys", "dev_"+keyType)
+		publicKeyPath, err := GenerateSSHKeyPair(keyType, privateKeyPath, "triton-kubernetes dev")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The private key is readable by ssh, and its public key is the one written next to it
+		privateKeyPublicKey, err := readPrivateKeyPublicKey(privateKeyPath)
+		if err != nil {
+			t.Fatalf("Unable to read the %s private key: %v", keyType, err)
+		}
+		rawPublicKey, err := ioutil.ReadFile(publicKeyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKey, comment, _, _, err := ssh.ParseAuthorizedKey(rawPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(publicKey.Marshal()) != string(privateKeyPublicKey.Marshal()) {
+			t.Errorf("The %s public key isn't the public key of the private key", keyType)
+		}
+		if comment != "triton-kubernetes dev" {
+			t.Errorf("Unexpected comment %q", comment)
+		}
+
+		info, err := os.Stat(privateKeyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected the %s private key to be only readable by the user, got %v", keyType, info.Mode())
+		}
+
+		// Existing keys are kept
+		_, err = GenerateSSHKeyPair(keyType, privateKeyPath, "")
+		if err == nil || !strings.HasSuffix(err.Error(), "already exists.") {
+			t.Errorf("Expected the existing %s key to be kept, got %v", keyType, err)
+		}
+	}
+
+	_, err = GenerateSSHKeyPair("dsa", filepath.Join(dir, "dsa"), "")
+	if err == nil || err.Error() != "Invalid ssh_key_type 'dsa', must be 'rsa' or 'ed25519'." {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -1,22 +1,111 @@
 package util
 
 import (
-	"crypto/md5"
 	"fmt"
 	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/joyent/triton-kubernetes/fips"
 
 	"github.com/manifoldco/promptui"
 	"golang.org/x/crypto/ssh"
 )
 
+var (
+	// e.g. c1:5c:8e:0c:5a:3c:6b:39:7d:0f:4e:64:a2:93:31:f4
+	md5FingerprintRegexp = regexp.MustCompile(`^([0-9a-f]{2}:){15}[0-9a-f]{2}$`)
+	// e.g. SHA256:uc8IWGtOoL8dmvJOq8vFi1ekR/v5mGGHQvrDrVNXBC4, as printed by ssh-keygen -l
+	sha256FingerprintRegexp = regexp.MustCompile(`^SHA256:[A-Za-z0-9+/]{43}$`)
+)
+
 // GetPublicKeyFingerprintFromPrivateKey takes in location of a private key and returns the md5 fingerprint
 func GetPublicKeyFingerprintFromPrivateKey(privateKeyPath string) (string, error) {
-	var fingerprint string
-	var err error
+	publicKey, err := readPrivateKeyPublicKey(privateKeyPath)
+	if err != nil {
+		return "", err
+	}
+	if fips.Enabled() {
+		err = fips.ValidateSSHPublicKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Triton identifies keys by their MD5 fingerprint, it isn't used for security
+	return ssh.FingerprintLegacyMD5(publicKey), nil
+}
 
+// Returns true if fingerprint is the fingerprint of the key: its MD5 fingerprint, with or without
+// the MD5: prefix and in either case, or its SHA256 fingerprint. Only the fingerprint of that
+// kind is computed.
+func fingerprintMatches(fingerprint string, publicKey ssh.PublicKey) bool {
+	if strings.HasPrefix(fingerprint, "SHA256:") {
+		return fingerprint == ssh.FingerprintSHA256(publicKey)
+	}
+	return strings.EqualFold(strings.TrimPrefix(fingerprint, "MD5:"), ssh.FingerprintLegacyMD5(publicKey))
+}
+
+// TritonKeyID returns the MD5 fingerprint Triton identifies the key at privateKeyPath by, given
+// the value of the keyIDSetting setting. The value may be the MD5 fingerprint of the key, with or
+// without the MD5: prefix, or its SHA256 fingerprint as printed by ssh-keygen -l. It's checked
+// against the key, so the fingerprint of another key fails here rather than in Triton. Without a
+// value, the fingerprint is computed from the key.
+func TritonKeyID(keyIDSetting, keyID, privateKeyPath string) (string, error) {
+	if keyID == "" {
+		return GetPublicKeyFingerprintFromPrivateKey(privateKeyPath)
+	}
+
+	keyID = strings.TrimPrefix(keyID, "MD5:")
+	if md5FingerprintRegexp.MatchString(strings.ToLower(keyID)) {
+		keyID = strings.ToLower(keyID)
+	} else if !sha256FingerprintRegexp.MatchString(keyID) {
+		return "", ConfigError(fmt.Errorf("Invalid %s '%s', must be the MD5 fingerprint of the key e.g. 'c1:5c:8e:0c:5a:3c:6b:39:7d:0f:4e:64:a2:93:31:f4' or its SHA256 fingerprint e.g. 'SHA256:uc8IWGtOoL8dmvJOq8vFi1ekR/v5mGGHQvrDrVNXBC4'.", keyIDSetting, keyID))
+	}
+
+	// Keys only held by the SSH agent can't be checked, Triton needs their MD5 fingerprint
+	if privateKeyPath == "" {
+		if strings.HasPrefix(keyID, "SHA256:") {
+			return "", fmt.Errorf("%s must be the MD5 fingerprint of the key when there is no key file to compute it from.", keyIDSetting)
+		}
+		return keyID, nil
+	}
+
+	publicKey, err := readPublicKey(privateKeyPath)
+	if err != nil {
+		return "", err
+	}
+	if fips.Enabled() {
+		err = fips.ValidateSSHPublicKey(publicKey)
+		if err != nil {
+			return "", err
+		}
+	}
+	md5Fingerprint := ssh.FingerprintLegacyMD5(publicKey)
+	if !fingerprintMatches(keyID, publicKey) {
+		return "", fmt.Errorf("%s '%s' isn't the fingerprint of the key %s, which is %s. Leave %s unset to use the fingerprint of the key.", keyIDSetting, keyID, privateKeyPath, md5Fingerprint, keyIDSetting)
+	}
+
+	return md5Fingerprint, nil
+}
+
+// Returns the public key of a private key, read from its .pub file if there is one so encrypted
+// private keys aren't decrypted.
+func readPublicKey(privateKeyPath string) (ssh.PublicKey, error) {
+	if content, err := ioutil.ReadFile(privateKeyPath + ".pub"); err == nil {
+		if publicKey, _, _, _, err := ssh.ParseAuthorizedKey(content); err == nil {
+			return publicKey, nil
+		}
+	}
+
+	return readPrivateKeyPublicKey(privateKeyPath)
+}
+
+// Returns the public key of a private key, asking for the password of encrypted keys.
+func readPrivateKeyPublicKey(privateKeyPath string) (ssh.PublicKey, error) {
 	key, err := ioutil.ReadFile(privateKeyPath)
 	if err != nil {
-		return "", fmt.Errorf("Unable to read private key: %v", err)
+		return nil, fmt.Errorf("Unable to read private key: %v", err)
 	}
 	signer, err := ssh.ParsePrivateKey(key)
 	if err != nil {
@@ -27,16 +116,9 @@ func GetPublicKeyFingerprintFromPrivateKey(privateKeyPath string) (string, error
 		password, _ := prompt.Run()
 		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(password))
 		if err != nil {
-			return "", fmt.Errorf("Unable to parse private key: %v", err)
-		}
-	}
-	h := md5.New()
-	h.Write(signer.PublicKey().Marshal())
-	for i, b := range h.Sum(nil) {
-		fingerprint += fmt.Sprintf("%02x", b)
-		if i < len(h.Sum(nil))-1 {
-			fingerprint += fmt.Sprintf(":")
+			return nil, fmt.Errorf("Unable to parse private key: %v", err)
 		}
 	}
-	return fingerprint, err
+
+	return signer.PublicKey(), nil
 }
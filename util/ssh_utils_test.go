@@ -0,0 +1,54 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestTritonKeyID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	publicKey := writeTestSSHKey(t, dir)
+	keyPath := filepath.Join(dir, "id_rsa")
+	expected := ssh.FingerprintLegacyMD5(publicKey)
+
+	for _, keyID := range []string{expected, "MD5:" + expected, strings.ToUpper(expected), ssh.FingerprintSHA256(publicKey)} {
+		output, err := TritonKeyID("triton_key_id", keyID, keyPath)
+		if err != nil || output != expected {
+			t.Errorf("Expected %s for %s, got %q, %v", expected, keyID, output, err)
+		}
+	}
+
+	// Keys only held by the SSH agent have no file to check against
+	output, err := TritonKeyID("triton_key_id", expected, "")
+	if err != nil || output != expected {
+		t.Errorf("Expected %s without a key file, got %q, %v", expected, output, err)
+	}
+
+	otherKey := "c1:5c:8e:0c:5a:3c:6b:39:7d:0f:4e:64:a2:93:31:f4"
+	testCases := []struct {
+		keyID    string
+		keyPath  string
+		expected string
+	}{
+		{"c1:5c:8e:0c", keyPath, "Invalid triton_key_id 'c1:5c:8e:0c', must be the MD5 fingerprint"},
+		{"uc8IWGtOoL8dmvJOq8vFi1ekR/v5mGGHQvrDrVNXBC4", keyPath, "Invalid triton_key_id"},
+		{otherKey, keyPath, "triton_key_id '" + otherKey + "' isn't the fingerprint of the key " + keyPath + ", which is " + expected + "."},
+		{ssh.FingerprintSHA256(publicKey), "", "triton_key_id must be the MD5 fingerprint of the key when there is no key file to compute it from."},
+	}
+	for _, tc := range testCases {
+		_, err := TritonKeyID("triton_key_id", tc.keyID, tc.keyPath)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+			t.Errorf("Wrong error for %q, expected %q, received %v", tc.keyID, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	triton "github.com/joyent/triton-go"
+	"github.com/joyent/triton-go/authentication"
+	"github.com/joyent/triton-go/client"
+	"github.com/joyent/triton-go/compute"
+)
+
+// TritonAccount is the account, and the key it's accessed with, of a Triton cluster.
+type TritonAccount struct {
+	Account string
+	KeyID   string
+	KeyPath string
+	URL     string
+}
+
+// Returns a client of the account. The vendored triton-go has no account client, so account
+// keys are managed with the compute client's connection.
+func (a TritonAccount) client() (*compute.ComputeClient, error) {
+	keyMaterial, err := ioutil.ReadFile(a.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := authentication.NewPrivateKeySigner(authentication.PrivateKeySignerInput{
+		KeyID:              a.KeyID,
+		PrivateKeyMaterial: keyMaterial,
+		AccountName:        a.Account,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return compute.NewClient(&triton.ClientConfig{
+		TritonURL:   a.URL,
+		AccountName: a.Account,
+		Signers:     []authentication.Signer{signer},
+	})
+}
+
+// RegisterTritonAccountKey adds the public key, in authorized_keys format, to the keys of the
+// account under the given name. Machines created afterwards accept it for root.
+func RegisterTritonAccountKey(account TritonAccount, name, publicKey string) error {
+	computeClient, err := account.client()
+	if err != nil {
+		return err
+	}
+
+	reqInputs := client.RequestInput{
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("/%s/keys", account.Account),
+		Body: map[string]string{
+			"name": name,
+			"key":  publicKey,
+		},
+	}
+	respReader, err := computeClient.Client.ExecuteRequest(context.Background(), reqInputs)
+	if respReader != nil {
+		defer respReader.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to add the SSH key '%s' to Triton account '%s': %s", name, account.Account, err)
+	}
+	return nil
+}
+
+// DeleteTritonAccountKey removes the key with the given name from the keys of the account.
+func DeleteTritonAccountKey(account TritonAccount, name string) error {
+	computeClient, err := account.client()
+	if err != nil {
+		return err
+	}
+
+	reqInputs := client.RequestInput{
+		Method: http.MethodDelete,
+		Path:   fmt.Sprintf("/%s/keys/%s", account.Account, name),
+	}
+	respReader, err := computeClient.Client.ExecuteRequest(context.Background(), reqInputs)
+	if respReader != nil {
+		defer respReader.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to delete the SSH key '%s' of Triton account '%s': %s", name, account.Account, err)
+	}
+	return nil
+}
+
+// TritonAccountKeyExists returns whether the account has a key with the given name.
+func TritonAccountKeyExists(account TritonAccount, name string) (bool, error) {
+	computeClient, err := account.client()
+	if err != nil {
+		return false, err
+	}
+
+	reqInputs := client.RequestInput{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("/%s/keys/%s", account.Account, name),
+	}
+	respReader, err := computeClient.Client.ExecuteRequest(context.Background(), reqInputs)
+	if respReader != nil {
+		defer respReader.Close()
+	}
+	var tritonErr *client.TritonError
+	if errors.As(err, &tritonErr) && tritonErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Unable to get the SSH key '%s' of Triton account '%s': %s", name, account.Account, err)
+	}
+	return true, nil
+}
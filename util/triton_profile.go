@@ -0,0 +1,197 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	homedir "github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultTritonProfilesDir = "~/.triton/profiles.d"
+
+	enterTritonSettingsManually = "Enter manually"
+)
+
+// Where the keys of triton profiles are looked for
+var sshDir = "~/.ssh"
+
+// TritonProfile is a profile of the triton CLI, stored as JSON in ~/.triton/profiles.d.
+type TritonProfile struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Account  string `json:"account"`
+	KeyID    string `json:"keyId"`
+	User     string `json:"user,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+}
+
+// Settings that a triton profile is applied to, e.g. a config.Config or the global viper.
+type tritonProfileSettings interface {
+	IsSet(key string) bool
+	GetString(key string) string
+	Set(key string, value interface{})
+}
+
+// LoadTritonProfiles returns the profiles of the triton CLI in dir, sorted by name.
+func LoadTritonProfiles(dir string) ([]TritonProfile, error) {
+	expandedDir, err := homedir.Expand(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(expandedDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []TritonProfile{}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		profile := TritonProfile{}
+		err = json.Unmarshal(content, &profile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read triton profile '%s': %v", path, err)
+		}
+		if profile.Name == "" {
+			profile.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles, nil
+}
+
+// ApplyTritonProfile sets triton_account, triton_url, triton_key_id and triton_key_path from
+// the triton CLI profile named by triton_profile, a given triton_key_path is kept. In interactive
+// mode, when triton_account isn't set, a profile can be selected instead of typing the settings.
+func ApplyTritonProfile(settings tritonProfileSettings, nonInteractiveMode bool) error {
+	if settings.IsSet("triton_account") && !settings.IsSet("triton_profile") {
+		return nil
+	}
+
+	profilesDir := defaultTritonProfilesDir
+	if settings.IsSet("triton_profiles_dir") {
+		profilesDir = settings.GetString("triton_profiles_dir")
+	}
+	profiles, err := LoadTritonProfiles(profilesDir)
+	if err != nil {
+		return err
+	}
+
+	profileName := ""
+	if settings.IsSet("triton_profile") {
+		profileName = settings.GetString("triton_profile")
+	} else if nonInteractiveMode || len(profiles) == 0 {
+		return nil
+	} else {
+		items := []string{enterTritonSettingsManually}
+		for _, profile := range profiles {
+			items = append(items, profile.Name)
+		}
+
+		prompt := promptui.Select{
+			Label: "Triton Profile",
+			Items: items,
+			Templates: &promptui.SelectTemplates{
+				Label:    "{{ . }}?",
+				Active:   fmt.Sprintf(`%s {{ . | underline }}`, promptui.IconSelect),
+				Inactive: `  {{ . }}`,
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Triton Profile:" | bold}} {{ . }}`, promptui.IconGood),
+			},
+		}
+
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		if value == enterTritonSettingsManually {
+			return nil
+		}
+		profileName = value
+	}
+
+	var selected *TritonProfile
+	for i := range profiles {
+		if profiles[i].Name == profileName {
+			selected = &profiles[i]
+			break
+		}
+	}
+	if selected == nil {
+		return fmt.Errorf("Triton profile '%s' does not exist in %s", profileName, profilesDir)
+	}
+	if selected.Account == "" || selected.URL == "" || selected.KeyID == "" {
+		return fmt.Errorf("Triton profile '%s' must have an account, a url and a keyId", profileName)
+	}
+
+	settings.Set("triton_account", selected.Account)
+	settings.Set("triton_url", selected.URL)
+
+	// The key of the profile is found by its fingerprint, unless its path is given. Without an
+	// MD5 fingerprint, triton_key_id is computed from the given key.
+	if !settings.IsSet("triton_key_path") {
+		keyPath, keyID, err := findSSHKey(sshDir, selected.KeyID)
+		if err != nil {
+			return fmt.Errorf("Triton profile '%s': %v", profileName, err)
+		}
+		settings.Set("triton_key_path", keyPath)
+		settings.Set("triton_key_id", keyID)
+	} else if !settings.IsSet("triton_key_id") && !strings.HasPrefix(selected.KeyID, "SHA256:") {
+		settings.Set("triton_key_id", strings.ToLower(strings.TrimPrefix(selected.KeyID, "MD5:")))
+	}
+
+	return nil
+}
+
+// Returns the path of the private key in dir whose public key has the given fingerprint, MD5
+// or SHA256 as printed by ssh-keygen, and its MD5 fingerprint, which Triton identifies keys by.
+// Public keys are read from the .pub files, so encrypted private keys aren't decrypted.
+func findSSHKey(dir, fingerprint string) (string, string, error) {
+	expandedDir, err := homedir.Expand(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(expandedDir, "*.pub"))
+	if err != nil {
+		return "", "", err
+	}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey(content)
+		if err != nil {
+			continue
+		}
+
+		if !fingerprintMatches(fingerprint, publicKey) {
+			continue
+		}
+
+		privateKeyPath := strings.TrimSuffix(path, ".pub")
+		if _, err := os.Stat(privateKeyPath); err != nil {
+			return "", "", fmt.Errorf("The private key of %s is missing", path)
+		}
+		return privateKeyPath, ssh.FingerprintLegacyMD5(publicKey), nil
+	}
+
+	return "", "", fmt.Errorf("No public key in %s has fingerprint %s, set triton_key_path", dir, fingerprint)
+}
@@ -0,0 +1,113 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+)
+
+func writeTestSSHKey(t *testing.T, dir string) ssh.PublicKey {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the public key is read, the private key just has to exist
+	err = ioutil.WriteFile(filepath.Join(dir, "id_rsa"), []byte("private"), 0600)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dir, "id_rsa.pub"), ssh.MarshalAuthorizedKey(publicKey), 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return publicKey
+}
+
+func TestApplyTritonProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triton-kubernetes-profiles-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	publicKey := writeTestSSHKey(t, dir)
+	defer func(previous string) { sshDir = previous }(sshDir)
+	sshDir = dir
+
+	profiles := map[string]string{
+		"dev.json":     `{"name": "dev", "url": "https://us-west-1.api.joyent.com", "account": "dev-account", "keyId": "` + ssh.FingerprintLegacyMD5(publicKey) + `"}`,
+		"prod.json":    `{"name": "prod", "url": "https://us-east-1.api.joyent.com", "account": "prod-account", "keyId": "` + ssh.FingerprintSHA256(publicKey) + `"}`,
+		"staging.json": `{"name": "staging", "url": "https://us-central-1.api.joyent.com", "account": "staging-account", "keyId": "MD5:` + strings.ToUpper(ssh.FingerprintLegacyMD5(publicKey)) + `"}`,
+		"lab.json":     `{"name": "lab", "url": "https://lab.example.com", "account": "lab", "keyId": "SHA256:unknown"}`,
+		"invalid.json": `{"name": "invalid", "account": "invalid"}`,
+	}
+	for name, content := range profiles {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		profile     string
+		account     string
+		url         string
+		expectError bool
+	}{
+		{"dev", "dev-account", "https://us-west-1.api.joyent.com", false},
+		{"prod", "prod-account", "https://us-east-1.api.joyent.com", false},
+		{"staging", "staging-account", "https://us-central-1.api.joyent.com", false},
+		{"lab", "", "", true},
+		{"invalid", "", "", true},
+		{"missing", "", "", true},
+	}
+
+	for _, test := range tests {
+		settings := viper.New()
+		settings.Set("triton_profiles_dir", dir)
+		settings.Set("triton_profile", test.profile)
+
+		err := ApplyTritonProfile(settings, true)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.profile)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.profile, err)
+			continue
+		}
+
+		if settings.GetString("triton_account") != test.account || settings.GetString("triton_url") != test.url {
+			t.Errorf("%s: unexpected account %s and url %s", test.profile, settings.GetString("triton_account"), settings.GetString("triton_url"))
+		}
+		if settings.GetString("triton_key_path") != filepath.Join(dir, "id_rsa") {
+			t.Errorf("%s: unexpected key path %s", test.profile, settings.GetString("triton_key_path"))
+		}
+		if settings.GetString("triton_key_id") != ssh.FingerprintLegacyMD5(publicKey) {
+			t.Errorf("%s: expected the MD5 fingerprint of the key, got %s", test.profile, settings.GetString("triton_key_id"))
+		}
+	}
+
+	// Settings that are already set aren't replaced by a profile in non-interactive mode
+	settings := viper.New()
+	settings.Set("triton_profiles_dir", dir)
+	settings.Set("triton_account", "other")
+	err = ApplyTritonProfile(settings, true)
+	if err != nil || settings.IsSet("triton_url") {
+		t.Errorf("Expected no profile to be applied, got %v and url %s", err, settings.GetString("triton_url"))
+	}
+}